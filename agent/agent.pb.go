@@ -24,10 +24,67 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// AlgorithmType is the runtime the agent runs an algorithm with.
+type AlgorithmType int32
+
+const (
+	AlgorithmType_ALGORITHM_TYPE_UNSPECIFIED AlgorithmType = 0 // runs the algorithm as a binary.
+	AlgorithmType_ALGORITHM_TYPE_BINARY      AlgorithmType = 1
+	AlgorithmType_ALGORITHM_TYPE_PYTHON      AlgorithmType = 2
+	AlgorithmType_ALGORITHM_TYPE_WASM        AlgorithmType = 3
+	AlgorithmType_ALGORITHM_TYPE_DOCKER      AlgorithmType = 4
+)
+
+// Enum value maps for AlgorithmType.
+var (
+	AlgorithmType_name = map[int32]string{
+		0: "ALGORITHM_TYPE_UNSPECIFIED",
+		1: "ALGORITHM_TYPE_BINARY",
+		2: "ALGORITHM_TYPE_PYTHON",
+		3: "ALGORITHM_TYPE_WASM",
+		4: "ALGORITHM_TYPE_DOCKER",
+	}
+	AlgorithmType_value = map[string]int32{
+		"ALGORITHM_TYPE_UNSPECIFIED": 0,
+		"ALGORITHM_TYPE_BINARY":      1,
+		"ALGORITHM_TYPE_PYTHON":      2,
+		"ALGORITHM_TYPE_WASM":        3,
+		"ALGORITHM_TYPE_DOCKER":      4,
+	}
+)
+
+func (x AlgorithmType) Enum() *AlgorithmType {
+	p := new(AlgorithmType)
+	*p = x
+	return p
+}
+
+func (x AlgorithmType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AlgorithmType) Descriptor() protoreflect.EnumDescriptor {
+	return file_agent_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (AlgorithmType) Type() protoreflect.EnumType {
+	return &file_agent_agent_proto_enumTypes[0]
+}
+
+func (x AlgorithmType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AlgorithmType.Descriptor instead.
+func (AlgorithmType) EnumDescriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{0}
+}
+
 type AlgoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Algorithm     []byte                 `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
 	Requirements  []byte                 `protobuf:"bytes,2,opt,name=requirements,proto3" json:"requirements,omitempty"`
+	Spec          *AlgorithmSpec         `protobuf:"bytes,3,opt,name=spec,proto3" json:"spec,omitempty"` // sent with the first message.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -76,6 +133,92 @@ func (x *AlgoRequest) GetRequirements() []byte {
 	return nil
 }
 
+func (x *AlgoRequest) GetSpec() *AlgorithmSpec {
+	if x != nil {
+		return x.Spec
+	}
+	return nil
+}
+
+// AlgorithmSpec describes how the agent runs the uploaded algorithm.
+type AlgorithmSpec struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  AlgorithmType          `protobuf:"varint,1,opt,name=type,proto3,enum=agent.AlgorithmType" json:"type,omitempty"`
+	// exported function of a wasm module, or command of a docker image, run
+	// instead of the default one.
+	Entrypoint        string   `protobuf:"bytes,2,opt,name=entrypoint,proto3" json:"entrypoint,omitempty"`
+	Args              []string `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	Runtime           string   `protobuf:"bytes,4,opt,name=runtime,proto3" json:"runtime,omitempty"`                                                // interpreter of python algorithms, python3 by default.
+	MinRuntimeVersion string   `protobuf:"bytes,5,opt,name=min_runtime_version,json=minRuntimeVersion,proto3" json:"min_runtime_version,omitempty"` // lowest runtime version the algorithm supports, e.g. 3.10.
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AlgorithmSpec) Reset() {
+	*x = AlgorithmSpec{}
+	mi := &file_agent_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlgorithmSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlgorithmSpec) ProtoMessage() {}
+
+func (x *AlgorithmSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlgorithmSpec.ProtoReflect.Descriptor instead.
+func (*AlgorithmSpec) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AlgorithmSpec) GetType() AlgorithmType {
+	if x != nil {
+		return x.Type
+	}
+	return AlgorithmType_ALGORITHM_TYPE_UNSPECIFIED
+}
+
+func (x *AlgorithmSpec) GetEntrypoint() string {
+	if x != nil {
+		return x.Entrypoint
+	}
+	return ""
+}
+
+func (x *AlgorithmSpec) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *AlgorithmSpec) GetRuntime() string {
+	if x != nil {
+		return x.Runtime
+	}
+	return ""
+}
+
+func (x *AlgorithmSpec) GetMinRuntimeVersion() string {
+	if x != nil {
+		return x.MinRuntimeVersion
+	}
+	return ""
+}
+
 type AlgoResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -84,7 +227,7 @@ type AlgoResponse struct {
 
 func (x *AlgoResponse) Reset() {
 	*x = AlgoResponse{}
-	mi := &file_agent_agent_proto_msgTypes[1]
+	mi := &file_agent_agent_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -96,7 +239,7 @@ func (x *AlgoResponse) String() string {
 func (*AlgoResponse) ProtoMessage() {}
 
 func (x *AlgoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[1]
+	mi := &file_agent_agent_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -109,7 +252,146 @@ func (x *AlgoResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AlgoResponse.ProtoReflect.Descriptor instead.
 func (*AlgoResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{1}
+	return file_agent_agent_proto_rawDescGZIP(), []int{2}
+}
+
+// ResumableAlgoRequest carries a chunk of an algorithm upload that can be
+// resumed. The first message of a stream only sets upload_id so the agent can
+// report how many bytes it already holds for that upload.
+type ResumableAlgoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Algorithm     []byte                 `protobuf:"bytes,3,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Requirements  []byte                 `protobuf:"bytes,4,opt,name=requirements,proto3" json:"requirements,omitempty"` // sent with the last message.
+	IsLast        bool                   `protobuf:"varint,5,opt,name=is_last,json=isLast,proto3" json:"is_last,omitempty"`
+	Cancel        bool                   `protobuf:"varint,6,opt,name=cancel,proto3" json:"cancel,omitempty"` // aborts the upload, the agent drops the bytes it received.
+	Spec          *AlgorithmSpec         `protobuf:"bytes,7,opt,name=spec,proto3" json:"spec,omitempty"`      // sent with the last message.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumableAlgoRequest) Reset() {
+	*x = ResumableAlgoRequest{}
+	mi := &file_agent_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumableAlgoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumableAlgoRequest) ProtoMessage() {}
+
+func (x *ResumableAlgoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumableAlgoRequest.ProtoReflect.Descriptor instead.
+func (*ResumableAlgoRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResumableAlgoRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *ResumableAlgoRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ResumableAlgoRequest) GetAlgorithm() []byte {
+	if x != nil {
+		return x.Algorithm
+	}
+	return nil
+}
+
+func (x *ResumableAlgoRequest) GetRequirements() []byte {
+	if x != nil {
+		return x.Requirements
+	}
+	return nil
+}
+
+func (x *ResumableAlgoRequest) GetIsLast() bool {
+	if x != nil {
+		return x.IsLast
+	}
+	return false
+}
+
+func (x *ResumableAlgoRequest) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
+func (x *ResumableAlgoRequest) GetSpec() *AlgorithmSpec {
+	if x != nil {
+		return x.Spec
+	}
+	return nil
+}
+
+type ResumableAlgoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"` // number of algorithm bytes acknowledged so far.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumableAlgoResponse) Reset() {
+	*x = ResumableAlgoResponse{}
+	mi := &file_agent_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumableAlgoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumableAlgoResponse) ProtoMessage() {}
+
+func (x *ResumableAlgoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumableAlgoResponse.ProtoReflect.Descriptor instead.
+func (*ResumableAlgoResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ResumableAlgoResponse) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
 }
 
 type DataRequest struct {
@@ -122,7 +404,7 @@ type DataRequest struct {
 
 func (x *DataRequest) Reset() {
 	*x = DataRequest{}
-	mi := &file_agent_agent_proto_msgTypes[2]
+	mi := &file_agent_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -134,7 +416,7 @@ func (x *DataRequest) String() string {
 func (*DataRequest) ProtoMessage() {}
 
 func (x *DataRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[2]
+	mi := &file_agent_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -147,7 +429,7 @@ func (x *DataRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DataRequest.ProtoReflect.Descriptor instead.
 func (*DataRequest) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{2}
+	return file_agent_agent_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *DataRequest) GetDataset() []byte {
@@ -165,14 +447,16 @@ func (x *DataRequest) GetFilename() string {
 }
 
 type DataResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Filenames, or hex encoded hashes of unnamed datasets, the manifest still expects.
+	MissingDatasets []string `protobuf:"bytes,1,rep,name=missing_datasets,json=missingDatasets,proto3" json:"missing_datasets,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *DataResponse) Reset() {
 	*x = DataResponse{}
-	mi := &file_agent_agent_proto_msgTypes[3]
+	mi := &file_agent_agent_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -184,7 +468,7 @@ func (x *DataResponse) String() string {
 func (*DataResponse) ProtoMessage() {}
 
 func (x *DataResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[3]
+	mi := &file_agent_agent_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -197,7 +481,14 @@ func (x *DataResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DataResponse.ProtoReflect.Descriptor instead.
 func (*DataResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{3}
+	return file_agent_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DataResponse) GetMissingDatasets() []string {
+	if x != nil {
+		return x.MissingDatasets
+	}
+	return nil
 }
 
 type ResultRequest struct {
@@ -208,7 +499,7 @@ type ResultRequest struct {
 
 func (x *ResultRequest) Reset() {
 	*x = ResultRequest{}
-	mi := &file_agent_agent_proto_msgTypes[4]
+	mi := &file_agent_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -220,7 +511,7 @@ func (x *ResultRequest) String() string {
 func (*ResultRequest) ProtoMessage() {}
 
 func (x *ResultRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[4]
+	mi := &file_agent_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -233,7 +524,7 @@ func (x *ResultRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResultRequest.ProtoReflect.Descriptor instead.
 func (*ResultRequest) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{4}
+	return file_agent_agent_proto_rawDescGZIP(), []int{7}
 }
 
 type ResultResponse struct {
@@ -245,7 +536,7 @@ type ResultResponse struct {
 
 func (x *ResultResponse) Reset() {
 	*x = ResultResponse{}
-	mi := &file_agent_agent_proto_msgTypes[5]
+	mi := &file_agent_agent_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -257,7 +548,7 @@ func (x *ResultResponse) String() string {
 func (*ResultResponse) ProtoMessage() {}
 
 func (x *ResultResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[5]
+	mi := &file_agent_agent_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -270,7 +561,7 @@ func (x *ResultResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResultResponse.ProtoReflect.Descriptor instead.
 func (*ResultResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{5}
+	return file_agent_agent_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ResultResponse) GetFile() []byte {
@@ -291,7 +582,7 @@ type AttestationRequest struct {
 
 func (x *AttestationRequest) Reset() {
 	*x = AttestationRequest{}
-	mi := &file_agent_agent_proto_msgTypes[6]
+	mi := &file_agent_agent_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -303,7 +594,7 @@ func (x *AttestationRequest) String() string {
 func (*AttestationRequest) ProtoMessage() {}
 
 func (x *AttestationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[6]
+	mi := &file_agent_agent_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,7 +607,7 @@ func (x *AttestationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationRequest.ProtoReflect.Descriptor instead.
 func (*AttestationRequest) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{6}
+	return file_agent_agent_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *AttestationRequest) GetTeeNonce() []byte {
@@ -349,7 +640,7 @@ type AttestationResponse struct {
 
 func (x *AttestationResponse) Reset() {
 	*x = AttestationResponse{}
-	mi := &file_agent_agent_proto_msgTypes[7]
+	mi := &file_agent_agent_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -361,7 +652,7 @@ func (x *AttestationResponse) String() string {
 func (*AttestationResponse) ProtoMessage() {}
 
 func (x *AttestationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[7]
+	mi := &file_agent_agent_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -374,7 +665,7 @@ func (x *AttestationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationResponse.ProtoReflect.Descriptor instead.
 func (*AttestationResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{7}
+	return file_agent_agent_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *AttestationResponse) GetFile() []byte {
@@ -392,7 +683,7 @@ type IMAMeasurementsRequest struct {
 
 func (x *IMAMeasurementsRequest) Reset() {
 	*x = IMAMeasurementsRequest{}
-	mi := &file_agent_agent_proto_msgTypes[8]
+	mi := &file_agent_agent_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -404,7 +695,7 @@ func (x *IMAMeasurementsRequest) String() string {
 func (*IMAMeasurementsRequest) ProtoMessage() {}
 
 func (x *IMAMeasurementsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[8]
+	mi := &file_agent_agent_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -417,7 +708,7 @@ func (x *IMAMeasurementsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IMAMeasurementsRequest.ProtoReflect.Descriptor instead.
 func (*IMAMeasurementsRequest) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{8}
+	return file_agent_agent_proto_rawDescGZIP(), []int{11}
 }
 
 type IMAMeasurementsResponse struct {
@@ -430,7 +721,7 @@ type IMAMeasurementsResponse struct {
 
 func (x *IMAMeasurementsResponse) Reset() {
 	*x = IMAMeasurementsResponse{}
-	mi := &file_agent_agent_proto_msgTypes[9]
+	mi := &file_agent_agent_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -442,7 +733,7 @@ func (x *IMAMeasurementsResponse) String() string {
 func (*IMAMeasurementsResponse) ProtoMessage() {}
 
 func (x *IMAMeasurementsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[9]
+	mi := &file_agent_agent_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -455,7 +746,7 @@ func (x *IMAMeasurementsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IMAMeasurementsResponse.ProtoReflect.Descriptor instead.
 func (*IMAMeasurementsResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{9}
+	return file_agent_agent_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *IMAMeasurementsResponse) GetFile() []byte {
@@ -482,7 +773,7 @@ type AttestationTokenRequest struct {
 
 func (x *AttestationTokenRequest) Reset() {
 	*x = AttestationTokenRequest{}
-	mi := &file_agent_agent_proto_msgTypes[10]
+	mi := &file_agent_agent_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -494,7 +785,7 @@ func (x *AttestationTokenRequest) String() string {
 func (*AttestationTokenRequest) ProtoMessage() {}
 
 func (x *AttestationTokenRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[10]
+	mi := &file_agent_agent_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -507,7 +798,7 @@ func (x *AttestationTokenRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationTokenRequest.ProtoReflect.Descriptor instead.
 func (*AttestationTokenRequest) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{10}
+	return file_agent_agent_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *AttestationTokenRequest) GetTokenNonce() []byte {
@@ -533,7 +824,7 @@ type AttestationTokenResponse struct {
 
 func (x *AttestationTokenResponse) Reset() {
 	*x = AttestationTokenResponse{}
-	mi := &file_agent_agent_proto_msgTypes[11]
+	mi := &file_agent_agent_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -545,7 +836,7 @@ func (x *AttestationTokenResponse) String() string {
 func (*AttestationTokenResponse) ProtoMessage() {}
 
 func (x *AttestationTokenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_agent_proto_msgTypes[11]
+	mi := &file_agent_agent_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -558,7 +849,7 @@ func (x *AttestationTokenResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationTokenResponse.ProtoReflect.Descriptor instead.
 func (*AttestationTokenResponse) Descriptor() ([]byte, []int) {
-	return file_agent_agent_proto_rawDescGZIP(), []int{11}
+	return file_agent_agent_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *AttestationTokenResponse) GetFile() []byte {
@@ -568,19 +859,593 @@ func (x *AttestationTokenResponse) GetFile() []byte {
 	return nil
 }
 
-var File_agent_agent_proto protoreflect.FileDescriptor
+type CapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_agent_agent_proto_rawDesc = "" +
-	"\n" +
-	"\x11agent/agent.proto\x12\x05agent\"O\n" +
-	"\vAlgoRequest\x12\x1c\n" +
-	"\talgorithm\x18\x01 \x01(\fR\talgorithm\x12\"\n" +
-	"\frequirements\x18\x02 \x01(\fR\frequirements\"\x0e\n" +
-	"\fAlgoResponse\"C\n" +
+func (x *CapabilitiesRequest) Reset() {
+	*x = CapabilitiesRequest{}
+	mi := &file_agent_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesRequest) ProtoMessage() {}
+
+func (x *CapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*CapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{15}
+}
+
+// CapabilitiesResponse advertises the gRPC message size limits of the agent,
+// so clients can size the messages they send and accept, and the algorithm
+// runtimes of the agent.
+type CapabilitiesResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MaxRecvMsgSize    int64                  `protobuf:"varint,1,opt,name=max_recv_msg_size,json=maxRecvMsgSize,proto3" json:"max_recv_msg_size,omitempty"` // largest message in bytes the agent accepts.
+	MaxSendMsgSize    int64                  `protobuf:"varint,2,opt,name=max_send_msg_size,json=maxSendMsgSize,proto3" json:"max_send_msg_size,omitempty"` // largest message in bytes the agent sends.
+	AlgorithmRuntimes []*AlgorithmRuntime    `protobuf:"bytes,3,rep,name=algorithm_runtimes,json=algorithmRuntimes,proto3" json:"algorithm_runtimes,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CapabilitiesResponse) Reset() {
+	*x = CapabilitiesResponse{}
+	mi := &file_agent_agent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesResponse) ProtoMessage() {}
+
+func (x *CapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CapabilitiesResponse) GetMaxRecvMsgSize() int64 {
+	if x != nil {
+		return x.MaxRecvMsgSize
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetMaxSendMsgSize() int64 {
+	if x != nil {
+		return x.MaxSendMsgSize
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetAlgorithmRuntimes() []*AlgorithmRuntime {
+	if x != nil {
+		return x.AlgorithmRuntimes
+	}
+	return nil
+}
+
+// AlgorithmRuntime is an algorithm type the agent runs and the version of its runtime.
+type AlgorithmRuntime struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          AlgorithmType          `protobuf:"varint,1,opt,name=type,proto3,enum=agent.AlgorithmType" json:"type,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"` // empty for runtimes without a version, e.g. binaries.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlgorithmRuntime) Reset() {
+	*x = AlgorithmRuntime{}
+	mi := &file_agent_agent_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlgorithmRuntime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlgorithmRuntime) ProtoMessage() {}
+
+func (x *AlgorithmRuntime) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlgorithmRuntime.ProtoReflect.Descriptor instead.
+func (*AlgorithmRuntime) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *AlgorithmRuntime) GetType() AlgorithmType {
+	if x != nil {
+		return x.Type
+	}
+	return AlgorithmType_ALGORITHM_TYPE_UNSPECIFIED
+}
+
+func (x *AlgorithmRuntime) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// StopRequest cancels the running computation, the agent then waits for a new manifest.
+type StopRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// shutdown drains the agent instead: new uploads are rejected, the running
+	// algorithm gets the shutdown grace period to end and the agent exits.
+	Shutdown      bool `protobuf:"varint,1,opt,name=shutdown,proto3" json:"shutdown,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopRequest) Reset() {
+	*x = StopRequest{}
+	mi := &file_agent_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRequest) ProtoMessage() {}
+
+func (x *StopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRequest.ProtoReflect.Descriptor instead.
+func (*StopRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *StopRequest) GetShutdown() bool {
+	if x != nil {
+		return x.Shutdown
+	}
+	return false
+}
+
+type StopResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopResponse) Reset() {
+	*x = StopResponse{}
+	mi := &file_agent_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopResponse) ProtoMessage() {}
+
+func (x *StopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopResponse.ProtoReflect.Descriptor instead.
+func (*StopResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{19}
+}
+
+// RestoreRequest restores the algorithm working directory from the last checkpoint of the computation.
+type RestoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"` // sequence of the last checkpoint the owner saw saved, older checkpoints are refused.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreRequest) Reset() {
+	*x = RestoreRequest{}
+	mi := &file_agent_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreRequest) ProtoMessage() {}
+
+func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreRequest.ProtoReflect.Descriptor instead.
+func (*RestoreRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RestoreRequest) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+type RestoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreResponse) Reset() {
+	*x = RestoreResponse{}
+	mi := &file_agent_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreResponse) ProtoMessage() {}
+
+func (x *RestoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreResponse.ProtoReflect.Descriptor instead.
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{21}
+}
+
+// ApproveAttestationRequest releases the algorithm and datasets uploads of a computation that requires the attestation approval of its owner.
+type ApproveAttestationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Signature     []byte                 `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"` // signature of the computation owner over the approval signing bytes of the manifest and report.
+	Report        []byte                 `protobuf:"bytes,2,opt,name=report,proto3" json:"report,omitempty"`       // attestation report of the agent the computation owner verified.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveAttestationRequest) Reset() {
+	*x = ApproveAttestationRequest{}
+	mi := &file_agent_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveAttestationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveAttestationRequest) ProtoMessage() {}
+
+func (x *ApproveAttestationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveAttestationRequest.ProtoReflect.Descriptor instead.
+func (*ApproveAttestationRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ApproveAttestationRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *ApproveAttestationRequest) GetReport() []byte {
+	if x != nil {
+		return x.Report
+	}
+	return nil
+}
+
+type ApproveAttestationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveAttestationResponse) Reset() {
+	*x = ApproveAttestationResponse{}
+	mi := &file_agent_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveAttestationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveAttestationResponse) ProtoMessage() {}
+
+func (x *ApproveAttestationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveAttestationResponse.ProtoReflect.Descriptor instead.
+func (*ApproveAttestationResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{23}
+}
+
+// SecretsRequest provisions the runtime secrets of the computation owner, which the algorithm reads while it runs.
+type SecretsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secrets       []*RuntimeSecret       `protobuf:"bytes,1,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"` // signature of the computation owner over the secrets signing bytes.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretsRequest) Reset() {
+	*x = SecretsRequest{}
+	mi := &file_agent_agent_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretsRequest) ProtoMessage() {}
+
+func (x *SecretsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretsRequest.ProtoReflect.Descriptor instead.
+func (*SecretsRequest) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *SecretsRequest) GetSecrets() []*RuntimeSecret {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+func (x *SecretsRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type RuntimeSecret struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // file name of the secret in the secrets directory, and its variable name when env is set.
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Env           bool                   `protobuf:"varint,3,opt,name=env,proto3" json:"env,omitempty"` // exposes the secret as an environment variable of the algorithm instead of a file.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuntimeSecret) Reset() {
+	*x = RuntimeSecret{}
+	mi := &file_agent_agent_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuntimeSecret) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuntimeSecret) ProtoMessage() {}
+
+func (x *RuntimeSecret) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuntimeSecret.ProtoReflect.Descriptor instead.
+func (*RuntimeSecret) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *RuntimeSecret) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RuntimeSecret) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *RuntimeSecret) GetEnv() bool {
+	if x != nil {
+		return x.Env
+	}
+	return false
+}
+
+type SecretsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretsResponse) Reset() {
+	*x = SecretsResponse{}
+	mi := &file_agent_agent_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretsResponse) ProtoMessage() {}
+
+func (x *SecretsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_agent_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretsResponse.ProtoReflect.Descriptor instead.
+func (*SecretsResponse) Descriptor() ([]byte, []int) {
+	return file_agent_agent_proto_rawDescGZIP(), []int{26}
+}
+
+var File_agent_agent_proto protoreflect.FileDescriptor
+
+const file_agent_agent_proto_rawDesc = "" +
+	"\n" +
+	"\x11agent/agent.proto\x12\x05agent\"y\n" +
+	"\vAlgoRequest\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\fR\talgorithm\x12\"\n" +
+	"\frequirements\x18\x02 \x01(\fR\frequirements\x12(\n" +
+	"\x04spec\x18\x03 \x01(\v2\x14.agent.AlgorithmSpecR\x04spec\"\xb7\x01\n" +
+	"\rAlgorithmSpec\x12(\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x14.agent.AlgorithmTypeR\x04type\x12\x1e\n" +
+	"\n" +
+	"entrypoint\x18\x02 \x01(\tR\n" +
+	"entrypoint\x12\x12\n" +
+	"\x04args\x18\x03 \x03(\tR\x04args\x12\x18\n" +
+	"\aruntime\x18\x04 \x01(\tR\aruntime\x12.\n" +
+	"\x13min_runtime_version\x18\x05 \x01(\tR\x11minRuntimeVersion\"\x0e\n" +
+	"\fAlgoResponse\"\xe8\x01\n" +
+	"\x14ResumableAlgoRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x1c\n" +
+	"\talgorithm\x18\x03 \x01(\fR\talgorithm\x12\"\n" +
+	"\frequirements\x18\x04 \x01(\fR\frequirements\x12\x17\n" +
+	"\ais_last\x18\x05 \x01(\bR\x06isLast\x12\x16\n" +
+	"\x06cancel\x18\x06 \x01(\bR\x06cancel\x12(\n" +
+	"\x04spec\x18\a \x01(\v2\x14.agent.AlgorithmSpecR\x04spec\"/\n" +
+	"\x15ResumableAlgoResponse\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\"C\n" +
 	"\vDataRequest\x12\x18\n" +
 	"\adataset\x18\x01 \x01(\fR\adataset\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\"\x0e\n" +
-	"\fDataResponse\"\x0f\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\"9\n" +
+	"\fDataResponse\x12)\n" +
+	"\x10missing_datasets\x18\x01 \x03(\tR\x0fmissingDatasets\"\x0f\n" +
 	"\rResultRequest\"$\n" +
 	"\x0eResultResponse\x12\x12\n" +
 	"\x04file\x18\x01 \x01(\fR\x04file\"b\n" +
@@ -600,14 +1465,52 @@ const file_agent_agent_proto_rawDesc = "" +
 	"tokenNonce\x12\x12\n" +
 	"\x04type\x18\x03 \x01(\x05R\x04type\".\n" +
 	"\x18AttestationTokenResponse\x12\x12\n" +
-	"\x04file\x18\x01 \x01(\fR\x04file2\xaf\x03\n" +
+	"\x04file\x18\x01 \x01(\fR\x04file\"\x15\n" +
+	"\x13CapabilitiesRequest\"\xb4\x01\n" +
+	"\x14CapabilitiesResponse\x12)\n" +
+	"\x11max_recv_msg_size\x18\x01 \x01(\x03R\x0emaxRecvMsgSize\x12)\n" +
+	"\x11max_send_msg_size\x18\x02 \x01(\x03R\x0emaxSendMsgSize\x12F\n" +
+	"\x12algorithm_runtimes\x18\x03 \x03(\v2\x17.agent.AlgorithmRuntimeR\x11algorithmRuntimes\"V\n" +
+	"\x10AlgorithmRuntime\x12(\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x14.agent.AlgorithmTypeR\x04type\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\")\n" +
+	"\vStopRequest\x12\x1a\n" +
+	"\bshutdown\x18\x01 \x01(\bR\bshutdown\"\x0e\n" +
+	"\fStopResponse\",\n" +
+	"\x0eRestoreRequest\x12\x1a\n" +
+	"\bsequence\x18\x02 \x01(\x04R\bsequence\"\x11\n" +
+	"\x0fRestoreResponse\"Q\n" +
+	"\x19ApproveAttestationRequest\x12\x1c\n" +
+	"\tsignature\x18\x01 \x01(\fR\tsignature\x12\x16\n" +
+	"\x06report\x18\x02 \x01(\fR\x06report\"\x1c\n" +
+	"\x1aApproveAttestationResponse\"^\n" +
+	"\x0eSecretsRequest\x12.\n" +
+	"\asecrets\x18\x01 \x03(\v2\x14.agent.RuntimeSecretR\asecrets\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"K\n" +
+	"\rRuntimeSecret\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x10\n" +
+	"\x03env\x18\x03 \x01(\bR\x03env\"\x11\n" +
+	"\x0fSecretsResponse*\x99\x01\n" +
+	"\rAlgorithmType\x12\x1e\n" +
+	"\x1aALGORITHM_TYPE_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15ALGORITHM_TYPE_BINARY\x10\x01\x12\x19\n" +
+	"\x15ALGORITHM_TYPE_PYTHON\x10\x02\x12\x17\n" +
+	"\x13ALGORITHM_TYPE_WASM\x10\x03\x12\x19\n" +
+	"\x15ALGORITHM_TYPE_DOCKER\x10\x042\xd4\x06\n" +
 	"\fAgentService\x123\n" +
 	"\x04Algo\x12\x12.agent.AlgoRequest\x1a\x13.agent.AlgoResponse\"\x00(\x01\x123\n" +
 	"\x04Data\x12\x12.agent.DataRequest\x1a\x13.agent.DataResponse\"\x00(\x01\x129\n" +
 	"\x06Result\x12\x14.agent.ResultRequest\x1a\x15.agent.ResultResponse\"\x000\x01\x12H\n" +
 	"\vAttestation\x12\x19.agent.AttestationRequest\x1a\x1a.agent.AttestationResponse\"\x000\x01\x12T\n" +
 	"\x0fIMAMeasurements\x12\x1d.agent.IMAMeasurementsRequest\x1a\x1e.agent.IMAMeasurementsResponse\"\x000\x01\x12Z\n" +
-	"\x15AzureAttestationToken\x12\x1e.agent.AttestationTokenRequest\x1a\x1f.agent.AttestationTokenResponse\"\x00B\tZ\a./agentb\x06proto3"
+	"\x15AzureAttestationToken\x12\x1e.agent.AttestationTokenRequest\x1a\x1f.agent.AttestationTokenResponse\"\x00\x12P\n" +
+	"\rResumableAlgo\x12\x1b.agent.ResumableAlgoRequest\x1a\x1c.agent.ResumableAlgoResponse\"\x00(\x010\x01\x12I\n" +
+	"\fCapabilities\x12\x1a.agent.CapabilitiesRequest\x1a\x1b.agent.CapabilitiesResponse\"\x00\x121\n" +
+	"\x04Stop\x12\x12.agent.StopRequest\x1a\x13.agent.StopResponse\"\x00\x12:\n" +
+	"\aRestore\x12\x15.agent.RestoreRequest\x1a\x16.agent.RestoreResponse\"\x00\x12[\n" +
+	"\x12ApproveAttestation\x12 .agent.ApproveAttestationRequest\x1a!.agent.ApproveAttestationResponse\"\x00\x12:\n" +
+	"\aSecrets\x12\x15.agent.SecretsRequest\x1a\x16.agent.SecretsResponse\"\x00B\tZ\a./agentb\x06proto3"
 
 var (
 	file_agent_agent_proto_rawDescOnce sync.Once
@@ -621,39 +1524,74 @@ func file_agent_agent_proto_rawDescGZIP() []byte {
 	return file_agent_agent_proto_rawDescData
 }
 
-var file_agent_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_agent_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_agent_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_agent_agent_proto_goTypes = []any{
-	(*AlgoRequest)(nil),              // 0: agent.AlgoRequest
-	(*AlgoResponse)(nil),             // 1: agent.AlgoResponse
-	(*DataRequest)(nil),              // 2: agent.DataRequest
-	(*DataResponse)(nil),             // 3: agent.DataResponse
-	(*ResultRequest)(nil),            // 4: agent.ResultRequest
-	(*ResultResponse)(nil),           // 5: agent.ResultResponse
-	(*AttestationRequest)(nil),       // 6: agent.AttestationRequest
-	(*AttestationResponse)(nil),      // 7: agent.AttestationResponse
-	(*IMAMeasurementsRequest)(nil),   // 8: agent.IMAMeasurementsRequest
-	(*IMAMeasurementsResponse)(nil),  // 9: agent.IMAMeasurementsResponse
-	(*AttestationTokenRequest)(nil),  // 10: agent.AttestationTokenRequest
-	(*AttestationTokenResponse)(nil), // 11: agent.AttestationTokenResponse
+	(AlgorithmType)(0),                 // 0: agent.AlgorithmType
+	(*AlgoRequest)(nil),                // 1: agent.AlgoRequest
+	(*AlgorithmSpec)(nil),              // 2: agent.AlgorithmSpec
+	(*AlgoResponse)(nil),               // 3: agent.AlgoResponse
+	(*ResumableAlgoRequest)(nil),       // 4: agent.ResumableAlgoRequest
+	(*ResumableAlgoResponse)(nil),      // 5: agent.ResumableAlgoResponse
+	(*DataRequest)(nil),                // 6: agent.DataRequest
+	(*DataResponse)(nil),               // 7: agent.DataResponse
+	(*ResultRequest)(nil),              // 8: agent.ResultRequest
+	(*ResultResponse)(nil),             // 9: agent.ResultResponse
+	(*AttestationRequest)(nil),         // 10: agent.AttestationRequest
+	(*AttestationResponse)(nil),        // 11: agent.AttestationResponse
+	(*IMAMeasurementsRequest)(nil),     // 12: agent.IMAMeasurementsRequest
+	(*IMAMeasurementsResponse)(nil),    // 13: agent.IMAMeasurementsResponse
+	(*AttestationTokenRequest)(nil),    // 14: agent.AttestationTokenRequest
+	(*AttestationTokenResponse)(nil),   // 15: agent.AttestationTokenResponse
+	(*CapabilitiesRequest)(nil),        // 16: agent.CapabilitiesRequest
+	(*CapabilitiesResponse)(nil),       // 17: agent.CapabilitiesResponse
+	(*AlgorithmRuntime)(nil),           // 18: agent.AlgorithmRuntime
+	(*StopRequest)(nil),                // 19: agent.StopRequest
+	(*StopResponse)(nil),               // 20: agent.StopResponse
+	(*RestoreRequest)(nil),             // 21: agent.RestoreRequest
+	(*RestoreResponse)(nil),            // 22: agent.RestoreResponse
+	(*ApproveAttestationRequest)(nil),  // 23: agent.ApproveAttestationRequest
+	(*ApproveAttestationResponse)(nil), // 24: agent.ApproveAttestationResponse
+	(*SecretsRequest)(nil),             // 25: agent.SecretsRequest
+	(*RuntimeSecret)(nil),              // 26: agent.RuntimeSecret
+	(*SecretsResponse)(nil),            // 27: agent.SecretsResponse
 }
 var file_agent_agent_proto_depIdxs = []int32{
-	0,  // 0: agent.AgentService.Algo:input_type -> agent.AlgoRequest
-	2,  // 1: agent.AgentService.Data:input_type -> agent.DataRequest
-	4,  // 2: agent.AgentService.Result:input_type -> agent.ResultRequest
-	6,  // 3: agent.AgentService.Attestation:input_type -> agent.AttestationRequest
-	8,  // 4: agent.AgentService.IMAMeasurements:input_type -> agent.IMAMeasurementsRequest
-	10, // 5: agent.AgentService.AzureAttestationToken:input_type -> agent.AttestationTokenRequest
-	1,  // 6: agent.AgentService.Algo:output_type -> agent.AlgoResponse
-	3,  // 7: agent.AgentService.Data:output_type -> agent.DataResponse
-	5,  // 8: agent.AgentService.Result:output_type -> agent.ResultResponse
-	7,  // 9: agent.AgentService.Attestation:output_type -> agent.AttestationResponse
-	9,  // 10: agent.AgentService.IMAMeasurements:output_type -> agent.IMAMeasurementsResponse
-	11, // 11: agent.AgentService.AzureAttestationToken:output_type -> agent.AttestationTokenResponse
-	6,  // [6:12] is the sub-list for method output_type
-	0,  // [0:6] is the sub-list for method input_type
-	0,  // [0:0] is the sub-list for extension type_name
-	0,  // [0:0] is the sub-list for extension extendee
-	0,  // [0:0] is the sub-list for field type_name
+	2,  // 0: agent.AlgoRequest.spec:type_name -> agent.AlgorithmSpec
+	0,  // 1: agent.AlgorithmSpec.type:type_name -> agent.AlgorithmType
+	2,  // 2: agent.ResumableAlgoRequest.spec:type_name -> agent.AlgorithmSpec
+	18, // 3: agent.CapabilitiesResponse.algorithm_runtimes:type_name -> agent.AlgorithmRuntime
+	0,  // 4: agent.AlgorithmRuntime.type:type_name -> agent.AlgorithmType
+	26, // 5: agent.SecretsRequest.secrets:type_name -> agent.RuntimeSecret
+	1,  // 6: agent.AgentService.Algo:input_type -> agent.AlgoRequest
+	6,  // 7: agent.AgentService.Data:input_type -> agent.DataRequest
+	8,  // 8: agent.AgentService.Result:input_type -> agent.ResultRequest
+	10, // 9: agent.AgentService.Attestation:input_type -> agent.AttestationRequest
+	12, // 10: agent.AgentService.IMAMeasurements:input_type -> agent.IMAMeasurementsRequest
+	14, // 11: agent.AgentService.AzureAttestationToken:input_type -> agent.AttestationTokenRequest
+	4,  // 12: agent.AgentService.ResumableAlgo:input_type -> agent.ResumableAlgoRequest
+	16, // 13: agent.AgentService.Capabilities:input_type -> agent.CapabilitiesRequest
+	19, // 14: agent.AgentService.Stop:input_type -> agent.StopRequest
+	21, // 15: agent.AgentService.Restore:input_type -> agent.RestoreRequest
+	23, // 16: agent.AgentService.ApproveAttestation:input_type -> agent.ApproveAttestationRequest
+	25, // 17: agent.AgentService.Secrets:input_type -> agent.SecretsRequest
+	3,  // 18: agent.AgentService.Algo:output_type -> agent.AlgoResponse
+	7,  // 19: agent.AgentService.Data:output_type -> agent.DataResponse
+	9,  // 20: agent.AgentService.Result:output_type -> agent.ResultResponse
+	11, // 21: agent.AgentService.Attestation:output_type -> agent.AttestationResponse
+	13, // 22: agent.AgentService.IMAMeasurements:output_type -> agent.IMAMeasurementsResponse
+	15, // 23: agent.AgentService.AzureAttestationToken:output_type -> agent.AttestationTokenResponse
+	5,  // 24: agent.AgentService.ResumableAlgo:output_type -> agent.ResumableAlgoResponse
+	17, // 25: agent.AgentService.Capabilities:output_type -> agent.CapabilitiesResponse
+	20, // 26: agent.AgentService.Stop:output_type -> agent.StopResponse
+	22, // 27: agent.AgentService.Restore:output_type -> agent.RestoreResponse
+	24, // 28: agent.AgentService.ApproveAttestation:output_type -> agent.ApproveAttestationResponse
+	27, // 29: agent.AgentService.Secrets:output_type -> agent.SecretsResponse
+	18, // [18:30] is the sub-list for method output_type
+	6,  // [6:18] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_agent_agent_proto_init() }
@@ -666,13 +1604,14 @@ func file_agent_agent_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_agent_proto_rawDesc), len(file_agent_agent_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   12,
+			NumEnums:      1,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_agent_agent_proto_goTypes,
 		DependencyIndexes: file_agent_agent_proto_depIdxs,
+		EnumInfos:         file_agent_agent_proto_enumTypes,
 		MessageInfos:      file_agent_agent_proto_msgTypes,
 	}.Build()
 	File_agent_agent_proto = out.File
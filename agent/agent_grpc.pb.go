@@ -28,6 +28,12 @@ const (
 	AgentService_Attestation_FullMethodName           = "/agent.AgentService/Attestation"
 	AgentService_IMAMeasurements_FullMethodName       = "/agent.AgentService/IMAMeasurements"
 	AgentService_AzureAttestationToken_FullMethodName = "/agent.AgentService/AzureAttestationToken"
+	AgentService_ResumableAlgo_FullMethodName         = "/agent.AgentService/ResumableAlgo"
+	AgentService_Capabilities_FullMethodName          = "/agent.AgentService/Capabilities"
+	AgentService_Stop_FullMethodName                  = "/agent.AgentService/Stop"
+	AgentService_Restore_FullMethodName               = "/agent.AgentService/Restore"
+	AgentService_ApproveAttestation_FullMethodName    = "/agent.AgentService/ApproveAttestation"
+	AgentService_Secrets_FullMethodName               = "/agent.AgentService/Secrets"
 )
 
 // AgentServiceClient is the client API for AgentService service.
@@ -40,6 +46,12 @@ type AgentServiceClient interface {
 	Attestation(ctx context.Context, in *AttestationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AttestationResponse], error)
 	IMAMeasurements(ctx context.Context, in *IMAMeasurementsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[IMAMeasurementsResponse], error)
 	AzureAttestationToken(ctx context.Context, in *AttestationTokenRequest, opts ...grpc.CallOption) (*AttestationTokenResponse, error)
+	ResumableAlgo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ResumableAlgoRequest, ResumableAlgoResponse], error)
+	Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error)
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error)
+	ApproveAttestation(ctx context.Context, in *ApproveAttestationRequest, opts ...grpc.CallOption) (*ApproveAttestationResponse, error)
+	Secrets(ctx context.Context, in *SecretsRequest, opts ...grpc.CallOption) (*SecretsResponse, error)
 }
 
 type agentServiceClient struct {
@@ -143,6 +155,69 @@ func (c *agentServiceClient) AzureAttestationToken(ctx context.Context, in *Atte
 	return out, nil
 }
 
+func (c *agentServiceClient) ResumableAlgo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ResumableAlgoRequest, ResumableAlgoResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[5], AgentService_ResumableAlgo_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ResumableAlgoRequest, ResumableAlgoResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_ResumableAlgoClient = grpc.BidiStreamingClient[ResumableAlgoRequest, ResumableAlgoResponse]
+
+func (c *agentServiceClient) Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, AgentService_Capabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopResponse)
+	err := c.cc.Invoke(ctx, AgentService_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreResponse)
+	err := c.cc.Invoke(ctx, AgentService_Restore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ApproveAttestation(ctx context.Context, in *ApproveAttestationRequest, opts ...grpc.CallOption) (*ApproveAttestationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApproveAttestationResponse)
+	err := c.cc.Invoke(ctx, AgentService_ApproveAttestation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) Secrets(ctx context.Context, in *SecretsRequest, opts ...grpc.CallOption) (*SecretsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SecretsResponse)
+	err := c.cc.Invoke(ctx, AgentService_Secrets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 // All implementations must embed UnimplementedAgentServiceServer
 // for forward compatibility.
@@ -153,6 +228,12 @@ type AgentServiceServer interface {
 	Attestation(*AttestationRequest, grpc.ServerStreamingServer[AttestationResponse]) error
 	IMAMeasurements(*IMAMeasurementsRequest, grpc.ServerStreamingServer[IMAMeasurementsResponse]) error
 	AzureAttestationToken(context.Context, *AttestationTokenRequest) (*AttestationTokenResponse, error)
+	ResumableAlgo(grpc.BidiStreamingServer[ResumableAlgoRequest, ResumableAlgoResponse]) error
+	Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
+	Stop(context.Context, *StopRequest) (*StopResponse, error)
+	Restore(context.Context, *RestoreRequest) (*RestoreResponse, error)
+	ApproveAttestation(context.Context, *ApproveAttestationRequest) (*ApproveAttestationResponse, error)
+	Secrets(context.Context, *SecretsRequest) (*SecretsResponse, error)
 	mustEmbedUnimplementedAgentServiceServer()
 }
 
@@ -181,6 +262,24 @@ func (UnimplementedAgentServiceServer) IMAMeasurements(*IMAMeasurementsRequest,
 func (UnimplementedAgentServiceServer) AzureAttestationToken(context.Context, *AttestationTokenRequest) (*AttestationTokenResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AzureAttestationToken not implemented")
 }
+func (UnimplementedAgentServiceServer) ResumableAlgo(grpc.BidiStreamingServer[ResumableAlgoRequest, ResumableAlgoResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ResumableAlgo not implemented")
+}
+func (UnimplementedAgentServiceServer) Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
+func (UnimplementedAgentServiceServer) Stop(context.Context, *StopRequest) (*StopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedAgentServiceServer) Restore(context.Context, *RestoreRequest) (*RestoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedAgentServiceServer) ApproveAttestation(context.Context, *ApproveAttestationRequest) (*ApproveAttestationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveAttestation not implemented")
+}
+func (UnimplementedAgentServiceServer) Secrets(context.Context, *SecretsRequest) (*SecretsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Secrets not implemented")
+}
 func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
 func (UnimplementedAgentServiceServer) testEmbeddedByValue()                      {}
 
@@ -267,6 +366,103 @@ func _AgentService_AzureAttestationToken_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_ResumableAlgo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).ResumableAlgo(&grpc.GenericServerStream[ResumableAlgoRequest, ResumableAlgoResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AgentService_ResumableAlgoServer = grpc.BidiStreamingServer[ResumableAlgoRequest, ResumableAlgoResponse]
+
+func _AgentService_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_Capabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Capabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Stop(ctx, req.(*StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_Restore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ApproveAttestation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveAttestationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ApproveAttestation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_ApproveAttestation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ApproveAttestation(ctx, req.(*ApproveAttestationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_Secrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Secrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_Secrets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Secrets(ctx, req.(*SecretsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AgentService_ServiceDesc is the grpc.ServiceDesc for AgentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -278,6 +474,26 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AzureAttestationToken",
 			Handler:    _AgentService_AzureAttestationToken_Handler,
 		},
+		{
+			MethodName: "Capabilities",
+			Handler:    _AgentService_Capabilities_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _AgentService_Stop_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _AgentService_Restore_Handler,
+		},
+		{
+			MethodName: "ApproveAttestation",
+			Handler:    _AgentService_ApproveAttestation_Handler,
+		},
+		{
+			MethodName: "Secrets",
+			Handler:    _AgentService_Secrets_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -305,6 +521,12 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _AgentService_IMAMeasurements_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ResumableAlgo",
+			Handler:       _AgentService_ResumableAlgo_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "agent/agent.proto",
 }
@@ -2,47 +2,33 @@
 // SPDX-License-Identifier: Apache-2.0
 package algorithm
 
-import (
-	"context"
-
-	"google.golang.org/grpc/metadata"
-)
-
 type AlgorithType string
 
 const (
-	AlgoTypeBin    AlgorithType = "bin"
-	AlgoTypePython AlgorithType = "python"
-	AlgoTypeWasm   AlgorithType = "wasm"
-	AlgoTypeDocker AlgorithType = "docker"
-	AlgoTypeKey                 = "algo_type"
-	AlgoArgsKey                 = "algo_args"
-
-	ResultsDir     = "results"
-	DatasetsDir    = "datasets"
-	AlgoWorkingDir = "/cocos"
+	AlgoTypeBin              AlgorithType = "bin"
+	AlgoTypePython           AlgorithType = "python"
+	AlgoTypeWasm             AlgorithType = "wasm"
+	AlgoTypeDocker           AlgorithType = "docker"
+	AlgoTypeKey                           = "algo_type"
+	AlgoArgsKey                           = "algo_args"
+	AlgoEntrypointKey                     = "algo_entrypoint"
+	AlgoMinRuntimeVersionKey              = "algo_min_runtime_version"
+
+	ResultsDir  = "results"
+	DatasetsDir = "datasets"
+	WorkDir     = "work"
+
+	// DatasetsDirEnv holds the absolute path of the datasets directory in the algorithm environment.
+	DatasetsDirEnv = "COCOS_DATASETS_DIR"
+	// ResultsDirEnv holds the absolute path of the results directory in the algorithm environment.
+	ResultsDirEnv = "COCOS_RESULTS_DIR"
+	// WorkDirEnv holds the absolute path of the working directory the agent checkpoints in the algorithm environment.
+	WorkDirEnv = "COCOS_WORK_DIR"
 )
 
-func AlgorithmTypeToContext(ctx context.Context, algoType string) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, AlgoTypeKey, algoType)
-}
-
-func AlgorithmTypeFromContext(ctx context.Context) string {
-	return metadata.ValueFromIncomingContext(ctx, AlgoTypeKey)[0]
-}
-
-func AlgorithmArgsToContext(ctx context.Context, algoArgs []string) context.Context {
-	for _, arg := range algoArgs {
-		ctx = metadata.AppendToOutgoingContext(ctx, AlgoArgsKey, arg)
-	}
-	return ctx
-}
-
-func AlgorithmArgsFromContext(ctx context.Context) []string {
-	return metadata.ValueFromIncomingContext(ctx, AlgoArgsKey)
-}
-
 // Algorithm is an interface that specifies the API for an algorithm.
+// Runtimes capture the standard output and error of the algorithm with the
+// logging writers, which log them and report standard error as events.
 type Algorithm interface {
 	// Run executes the algorithm and returns the result.
 	Run() error
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 
 	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/cgroup"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events"
 )
@@ -20,48 +21,50 @@ type binary struct {
 	stderr   io.Writer
 	stdout   io.Writer
 	args     []string
+	sandbox  algorithm.Sandbox
 	cmd      *exec.Cmd
+	group    *cgroup.Group
 }
 
-func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, algoFile string, args []string, cmpID string) algorithm.Algorithm {
+// NewAlgorithm returns a binary algorithm, which runs in the sandbox and in the
+// cgroup group when it is not nil. Its output is also written to output when it
+// is not nil.
+func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, algoFile string, args []string, cmpID string, sandbox algorithm.Sandbox, group *cgroup.Group, output logging.Output) algorithm.Algorithm {
+	stdout, stderr := logging.Streams(output)
+
 	return &binary{
 		algoFile: algoFile,
-		stderr:   &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID},
-		stdout:   &logging.Stdout{Logger: logger},
+		stderr:   &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID, Stream: stderr},
+		stdout:   &logging.Stdout{Logger: logger, Stream: stdout},
 		args:     args,
+		sandbox:  sandbox,
+		group:    group,
 	}
 }
 
 func (b *binary) Run() error {
-	b.cmd = exec.Command(b.algoFile, b.args...)
+	defer logging.Flush(b.stdout, b.stderr)
+
+	b.cmd = algorithm.Command(b.algoFile, b.args...)
+	b.cmd.Dir = b.sandbox.Root
+	b.cmd.Env = b.sandbox.Environ()
 	b.cmd.Stderr = b.stderr
 	b.cmd.Stdout = b.stdout
+	b.group.Attach(b.cmd)
 
 	if err := b.cmd.Start(); err != nil {
 		return fmt.Errorf("error starting algorithm: %v", err)
 	}
 
 	if err := b.cmd.Wait(); err != nil {
-		return fmt.Errorf("algorithm execution error: %v", err)
+		return fmt.Errorf("algorithm execution error: %w", err)
 	}
 
 	return nil
 }
 
 func (b *binary) Stop() error {
-	if b.cmd == nil {
-		return nil
-	}
-
-	if b.cmd.ProcessState != nil && b.cmd.ProcessState.Exited() {
-		return nil
-	}
-
-	if b.cmd.Process == nil {
-		return nil
-	}
-
-	if err := b.cmd.Process.Kill(); err != nil {
+	if err := algorithm.KillGroup(b.cmd); err != nil {
 		return fmt.Errorf("error stopping algorithm: %v", err)
 	}
 
@@ -6,8 +6,11 @@ import (
 	"bytes"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
 )
@@ -18,7 +21,7 @@ func TestNewAlgorithm(t *testing.T) {
 	algoFile := "/path/to/algo"
 	args := []string{"arg1", "arg2"}
 
-	algo := NewAlgorithm(logger, eventsSvc, algoFile, args, "")
+	algo := NewAlgorithm(logger, eventsSvc, algoFile, args, "", algorithm.Sandbox{}, nil, nil)
 
 	b, ok := algo.(*binary)
 	if !ok {
@@ -74,7 +77,7 @@ func TestBinaryRun(t *testing.T) {
 			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 			eventsSvc := new(mocks.Service)
 
-			b := NewAlgorithm(logger, eventsSvc, tt.algoFile, tt.args, "").(*binary)
+			b := NewAlgorithm(logger, eventsSvc, tt.algoFile, tt.args, "", algorithm.Sandbox{}, nil, nil).(*binary)
 
 			var stdout, stderr bytes.Buffer
 			b.stdout = &stdout
@@ -98,3 +101,29 @@ func TestBinaryRun(t *testing.T) {
 		})
 	}
 }
+
+func TestBinaryRunEnviron(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eventsSvc := new(mocks.Service)
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandbox := algorithm.Sandbox{Root: root}
+
+	b := NewAlgorithm(logger, eventsSvc, "sh", []string{"-c", "echo $" + algorithm.DatasetsDirEnv + " $TMPDIR; pwd -P"}, "", sandbox, nil, nil).(*binary)
+
+	var stdout, stderr bytes.Buffer
+	b.stdout = &stdout
+	b.stderr = &stderr
+
+	if err := b.Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := sandbox.Datasets() + " " + sandbox.Tmp() + "\n" + root
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("Expected sandbox environment %q, got %q", want, got)
+	}
+}
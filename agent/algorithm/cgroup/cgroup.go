@@ -0,0 +1,219 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cgroup confines algorithm processes to a cgroup v2 group with CPU
+// and memory limits.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// parent is the cgroup under the root that holds the algorithm groups.
+	parent = "cocos"
+	// cpuPeriod is the cpu.max period in microseconds.
+	cpuPeriod = 100000
+	// minCPUQuota is the smallest cpu.max quota the kernel accepts, in microseconds.
+	minCPUQuota = 1000
+
+	removeAttempts = 50
+	removeBackoff  = 10 * time.Millisecond
+)
+
+var (
+	// ErrUnavailable indicates the agent does not run on a cgroup v2 unified hierarchy.
+	ErrUnavailable = errors.New("cgroup v2 is not available")
+	// ErrInvalidCPUs indicates a CPU limit that is not a positive number of CPUs.
+	ErrInvalidCPUs = errors.New("invalid cpu limit")
+	// ErrInvalidName indicates a group name that is not a single path element.
+	ErrInvalidName = errors.New("invalid cgroup name")
+)
+
+var (
+	// root is the mount point of the cgroup v2 unified hierarchy.
+	root = "/sys/fs/cgroup"
+	// mkdir and rmdir create and remove groups, the kernel populates and
+	// removes their interface files.
+	mkdir = os.Mkdir
+	rmdir = os.Remove
+)
+
+// Limits are the resource limits of a group, zero values leave a resource unbounded.
+type Limits struct {
+	// CPUs is the CPU bandwidth of the group in CPUs, e.g. 1.5.
+	CPUs float64
+	// MemoryBytes bounds the memory of the group, swap is disabled when it is set.
+	MemoryBytes uint64
+}
+
+// Validate checks the limits can be written to the cgroup interface files.
+func (l Limits) Validate() error {
+	if math.IsNaN(l.CPUs) || math.IsInf(l.CPUs, 0) || l.CPUs < 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidCPUs, l.CPUs)
+	}
+	if l.CPUs > 0 && l.CPUs*cpuPeriod < minCPUQuota {
+		return fmt.Errorf("%w: %v is below %v", ErrInvalidCPUs, l.CPUs, float64(minCPUQuota)/cpuPeriod)
+	}
+
+	return nil
+}
+
+// Group is a cgroup v2 group algorithm processes are started in.
+type Group struct {
+	path string
+	dir  *os.File
+}
+
+// New creates the group name with the limits, enabling the CPU and memory
+// controllers for the groups of the agent. The name must be a single path
+// element, so that the group is created under the groups of the agent.
+func New(name string, limits Limits) (*Group, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	parentPath := filepath.Join(root, parent)
+	if err := mkdir(parentPath, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("error creating cgroup %s: %w", parentPath, err)
+	}
+
+	for _, dir := range []string{root, parentPath} {
+		if err := write(dir, "cgroup.subtree_control", "+cpu +memory"); err != nil {
+			return nil, fmt.Errorf("error enabling cgroup controllers: %w", err)
+		}
+	}
+
+	g := &Group{path: filepath.Join(parentPath, name)}
+	if err := mkdir(g.path, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cgroup %s: %w", g.path, err)
+	}
+
+	if err := g.limit(limits); err != nil {
+		_ = rmdir(g.path)
+		return nil, err
+	}
+
+	dir, err := os.Open(g.path)
+	if err != nil {
+		_ = rmdir(g.path)
+		return nil, fmt.Errorf("error opening cgroup %s: %w", g.path, err)
+	}
+	g.dir = dir
+
+	return g, nil
+}
+
+func (g *Group) limit(limits Limits) error {
+	if limits.CPUs > 0 {
+		quota := int64(limits.CPUs * cpuPeriod)
+		if err := write(g.path, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return fmt.Errorf("error setting cpu limit: %w", err)
+		}
+	}
+
+	if limits.MemoryBytes > 0 {
+		if err := write(g.path, "memory.max", strconv.FormatUint(limits.MemoryBytes, 10)); err != nil {
+			return fmt.Errorf("error setting memory limit: %w", err)
+		}
+		if err := write(g.path, "memory.swap.max", "0"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error disabling swap: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Attach makes the command start in the group. The process is cloned directly
+// into the group, so the limits apply before the algorithm is executed.
+func (g *Group) Attach(cmd *exec.Cmd) {
+	if g == nil {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(g.dir.Fd())
+}
+
+// OOMKills returns the number of group processes killed for exceeding the memory limit.
+func (g *Group) OOMKills() (uint64, error) {
+	file, err := os.Open(filepath.Join(g.path, "memory.events"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "oom_kill" {
+			return strconv.ParseUint(value, 10, 64)
+		}
+	}
+
+	return 0, scanner.Err()
+}
+
+// Close kills the processes left in the group and removes it.
+func (g *Group) Close() error {
+	if g == nil {
+		return nil
+	}
+
+	if err := write(g.path, "cgroup.kill", "1"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error killing cgroup processes: %w", err)
+	}
+
+	if err := g.dir.Close(); err != nil {
+		return err
+	}
+
+	// Killed processes leave the group asynchronously, which keeps it busy.
+	var err error
+	for range removeAttempts {
+		if err = rmdir(g.path); err == nil || errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if !errors.Is(err, syscall.EBUSY) {
+			break
+		}
+		time.Sleep(removeBackoff)
+	}
+
+	return fmt.Errorf("error removing cgroup %s: %w", g.path, err)
+}
+
+// write writes a cgroup interface file, which the kernel creates with the group.
+func write(dir, file, value string) error {
+	f, err := os.OpenFile(filepath.Join(dir, file), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
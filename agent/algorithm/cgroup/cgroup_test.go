@@ -0,0 +1,160 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cgroup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var interfaceFiles = []string{"cgroup.subtree_control", "cgroup.kill", "cpu.max", "memory.max", "memory.swap.max", "memory.events"}
+
+// fakeHierarchy replaces the cgroup hierarchy with a directory whose groups
+// are created with their interface files, as the kernel does.
+func fakeHierarchy(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu memory"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), nil, 0o644))
+
+	prevRoot, prevMkdir, prevRmdir := root, mkdir, rmdir
+	root = dir
+	mkdir = func(path string, perm os.FileMode) error {
+		if err := os.Mkdir(path, perm); err != nil {
+			return err
+		}
+		for _, file := range interfaceFiles {
+			if err := os.WriteFile(filepath.Join(path, file), nil, 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	rmdir = os.RemoveAll
+	t.Cleanup(func() { root, mkdir, rmdir = prevRoot, prevMkdir, prevRmdir })
+
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		desc   string
+		limits Limits
+		cpuMax string
+		memMax string
+		err    error
+	}{
+		{
+			desc:   "cpu and memory limits",
+			limits: Limits{CPUs: 1.5, MemoryBytes: 512 << 20},
+			cpuMax: "150000 100000",
+			memMax: "536870912",
+		},
+		{
+			desc:   "memory limit only",
+			limits: Limits{MemoryBytes: 1 << 30},
+			memMax: "1073741824",
+		},
+		{
+			desc:   "no limits",
+			limits: Limits{},
+		},
+		{
+			desc:   "negative cpu limit",
+			limits: Limits{CPUs: -1},
+			err:    ErrInvalidCPUs,
+		},
+		{
+			desc:   "cpu limit below the minimum quota",
+			limits: Limits{CPUs: 0.001},
+			err:    ErrInvalidCPUs,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := fakeHierarchy(t)
+
+			g, err := New("cmp1", tc.limits)
+			assert.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			path := filepath.Join(dir, parent, "cmp1")
+			assert.Equal(t, "+cpu +memory", readFile(t, filepath.Join(dir, "cgroup.subtree_control")))
+			assert.Equal(t, "+cpu +memory", readFile(t, filepath.Join(dir, parent, "cgroup.subtree_control")))
+			assert.Equal(t, tc.cpuMax, readFile(t, filepath.Join(path, "cpu.max")))
+			assert.Equal(t, tc.memMax, readFile(t, filepath.Join(path, "memory.max")))
+
+			require.NoError(t, g.Close())
+			assert.NoDirExists(t, path)
+		})
+	}
+}
+
+func TestNewInvalidName(t *testing.T) {
+	dir := fakeHierarchy(t)
+
+	for _, name := range []string{"", ".", "..", "../cmp1", "cmp/1"} {
+		_, err := New(name, Limits{MemoryBytes: 1 << 20})
+		assert.ErrorIs(t, err, ErrInvalidName, name)
+	}
+	assert.NoDirExists(t, filepath.Join(dir, parent))
+}
+
+func TestNewUnavailable(t *testing.T) {
+	fakeHierarchy(t)
+	root = t.TempDir()
+
+	_, err := New("cmp1", Limits{MemoryBytes: 1 << 20})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestOOMKills(t *testing.T) {
+	dir := fakeHierarchy(t)
+
+	g, err := New("cmp1", Limits{MemoryBytes: 1 << 20})
+	require.NoError(t, err)
+	defer g.Close()
+
+	kills, err := g.OOMKills()
+	require.NoError(t, err)
+	assert.Zero(t, kills)
+
+	events := "low 0\nhigh 0\nmax 4\noom 2\noom_kill 2\noom_group_kill 0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, parent, "cmp1", "memory.events"), []byte(events), 0o644))
+
+	kills, err = g.OOMKills()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), kills)
+}
+
+func TestAttach(t *testing.T) {
+	fakeHierarchy(t)
+
+	g, err := New("cmp1", Limits{})
+	require.NoError(t, err)
+	defer g.Close()
+
+	cmd := exec.Command("true")
+	g.Attach(cmd)
+	require.NotNil(t, cmd.SysProcAttr)
+	assert.True(t, cmd.SysProcAttr.UseCgroupFD)
+	assert.Equal(t, int(g.dir.Fd()), cmd.SysProcAttr.CgroupFD)
+
+	var nilGroup *Group
+	other := exec.Command("true")
+	nilGroup.Attach(other)
+	assert.Nil(t, other.SysProcAttr)
+}
@@ -9,7 +9,6 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"path"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
@@ -24,29 +23,44 @@ const (
 	containerName     = "agent_container"
 	datasetsMountPath = "/cocos/datasets"
 	resultsMountPath  = "/cocos/results"
+	workMountPath     = "/cocos/work"
+	tmpMountPath      = "/cocos/tmp"
+	secretsMountPath  = "/cocos/secrets"
 )
 
 var _ algorithm.Algorithm = (*docker)(nil)
 
 type docker struct {
-	algoFile string
-	logger   *slog.Logger
-	stderr   io.Writer
-	stdout   io.Writer
+	algoFile   string
+	entrypoint string
+	args       []string
+	sandbox    algorithm.Sandbox
+	logger     *slog.Logger
+	stderr     io.Writer
+	stdout     io.Writer
 }
 
-func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, algoFile, cmpID string) algorithm.Algorithm {
+// NewAlgorithm returns a docker algorithm, whose container has the sandbox
+// directories mounted. Its output is also written to output when it is not nil.
+func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, entrypoint string, args []string, algoFile, cmpID string, sandbox algorithm.Sandbox, output logging.Output) algorithm.Algorithm {
+	stdout, stderr := logging.Streams(output)
+
 	d := &docker{
-		algoFile: algoFile,
-		logger:   logger,
-		stderr:   &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID},
-		stdout:   &logging.Stdout{Logger: logger},
+		algoFile:   algoFile,
+		entrypoint: entrypoint,
+		args:       args,
+		sandbox:    sandbox,
+		logger:     logger,
+		stderr:     &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID, Stream: stderr},
+		stdout:     &logging.Stdout{Logger: logger, Stream: stdout},
 	}
 
 	return d
 }
 
 func (d *docker) Run() error {
+	defer logging.Flush(d.stdout, d.stderr)
+
 	// Create a new Docker client.
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -89,24 +103,8 @@ func (d *docker) Run() error {
 	}
 
 	// Create and start the container.
-	respContainer, err := cli.ContainerCreate(ctx, &container.Config{
-		Image:        dockerImageName,
-		Tty:          true,
-		AttachStdout: true,
-		AttachStderr: true,
-	}, &container.HostConfig{
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: path.Join(algorithm.AlgoWorkingDir, algorithm.DatasetsDir),
-				Target: datasetsMountPath,
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: path.Join(algorithm.AlgoWorkingDir, algorithm.ResultsDir),
-				Target: resultsMountPath,
-			},
-		},
+	respContainer, err := cli.ContainerCreate(ctx, d.containerConfig(dockerImageName), &container.HostConfig{
+		Mounts: d.mounts(),
 	}, nil, nil, containerName)
 	if err != nil {
 		return fmt.Errorf("could not create a Docker container: %v", err)
@@ -180,3 +178,71 @@ func (d *docker) Stop() error {
 	// To be supported later.
 	return nil
 }
+
+// containerConfig returns the configuration of the algorithm container, whose
+// environment holds the paths the sandbox directories are mounted at. The
+// entrypoint and arguments of the spec replace those of the image when set.
+// mounts binds the sandbox directories into the container, and the secret
+// files read-only when secrets were provisioned.
+func (d *docker) mounts() []mount.Mount {
+	mounts := []mount.Mount{
+		{
+			Type:   mount.TypeBind,
+			Source: d.sandbox.Datasets(),
+			Target: datasetsMountPath,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: d.sandbox.Results(),
+			Target: resultsMountPath,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: d.sandbox.Work(),
+			Target: workMountPath,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: d.sandbox.Tmp(),
+			Target: tmpMountPath,
+		},
+	}
+	if d.sandbox.HasSecretFiles() {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   d.sandbox.SecretFiles(),
+			Target:   secretsMountPath,
+			ReadOnly: true,
+		})
+	}
+
+	return mounts
+}
+
+func (d *docker) containerConfig(image string) *container.Config {
+	cfg := &container.Config{
+		Image:        image,
+		Tty:          true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env: []string{
+			algorithm.DatasetsDirEnv + "=" + datasetsMountPath,
+			algorithm.ResultsDirEnv + "=" + resultsMountPath,
+			algorithm.WorkDirEnv + "=" + workMountPath,
+			algorithm.TmpDirEnv + "=" + tmpMountPath,
+		},
+	}
+	if d.sandbox.HasSecretFiles() {
+		cfg.Env = append(cfg.Env, algorithm.SecretsDirEnv+"="+secretsMountPath)
+	}
+	cfg.Env = append(cfg.Env, d.sandbox.Env...)
+	cfg.Env = append(cfg.Env, d.sandbox.SecretsEnviron()...)
+	if d.entrypoint != "" {
+		cfg.Entrypoint = []string{d.entrypoint}
+	}
+	if len(d.args) > 0 {
+		cfg.Cmd = d.args
+	}
+
+	return cfg
+}
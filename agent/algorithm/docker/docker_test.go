@@ -5,9 +5,12 @@ package docker
 import (
 	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
 )
@@ -18,7 +21,7 @@ func TestNewAlgorithm(t *testing.T) {
 	eventsSvc := new(mocks.Service)
 	algoFile := "/path/to/algo.tar"
 
-	algo := NewAlgorithm(logger, eventsSvc, algoFile, "")
+	algo := NewAlgorithm(logger, eventsSvc, "", nil, algoFile, "", algorithm.Sandbox{}, nil)
 
 	d, ok := algo.(*docker)
 	assert.True(t, ok, "NewAlgorithm should return a *docker")
@@ -27,3 +30,38 @@ func TestNewAlgorithm(t *testing.T) {
 	assert.IsType(t, &logging.Stderr{}, d.stderr, "stderr should be of type *algorithm.Stderr")
 	assert.IsType(t, &logging.Stdout{}, d.stdout, "stdout should be of type *algorithm.Stdout")
 }
+
+func TestContainerConfig(t *testing.T) {
+	d := NewAlgorithm(slog.Default(), new(mocks.Service), "", nil, "algo.tar", "", algorithm.Sandbox{}, nil).(*docker)
+	cfg := d.containerConfig("image")
+	assert.Equal(t, "image", cfg.Image)
+	assert.Empty(t, cfg.Entrypoint, "the image entrypoint is kept")
+	assert.Empty(t, cfg.Cmd, "the image command is kept")
+	assert.Contains(t, cfg.Env, algorithm.DatasetsDirEnv+"="+datasetsMountPath)
+
+	d = NewAlgorithm(slog.Default(), new(mocks.Service), "/bin/train", []string{"--epochs", "2"}, "algo.tar", "", algorithm.Sandbox{}, nil).(*docker)
+	cfg = d.containerConfig("image")
+	assert.Equal(t, []string{"/bin/train"}, []string(cfg.Entrypoint))
+	assert.Equal(t, []string{"--epochs", "2"}, []string(cfg.Cmd))
+}
+
+func TestSecretsMount(t *testing.T) {
+	sandbox := algorithm.Sandbox{Root: t.TempDir()}
+	d := NewAlgorithm(slog.Default(), new(mocks.Service), "", nil, "algo.tar", "", sandbox, nil).(*docker)
+	assert.Len(t, d.mounts(), 4, "secrets are not mounted before they are provisioned")
+	assert.NotContains(t, d.containerConfig("image").Env, algorithm.SecretsDirEnv+"="+secretsMountPath)
+
+	require.NoError(t, os.MkdirAll(sandbox.SecretFiles(), 0o700))
+	require.NoError(t, os.MkdirAll(sandbox.SecretEnv(), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(sandbox.SecretEnv(), "API_TOKEN"), []byte("token"), 0o600))
+
+	mounts := d.mounts()
+	require.Len(t, mounts, 5)
+	assert.Equal(t, sandbox.SecretFiles(), mounts[4].Source)
+	assert.Equal(t, secretsMountPath, mounts[4].Target)
+	assert.True(t, mounts[4].ReadOnly)
+
+	env := d.containerConfig("image").Env
+	assert.Contains(t, env, algorithm.SecretsDirEnv+"="+secretsMountPath)
+	assert.Contains(t, env, "API_TOKEN=token")
+}
@@ -4,11 +4,18 @@ package logging
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdh"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"strings"
+	"sync"
 
 	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
 )
 
 var (
@@ -17,61 +24,387 @@ var (
 )
 
 const (
-	bufSize       = 1024
-	algorithmRun  = "AlgorithmRun"
+	// DefaultLimit is the number of bytes of output logged per stream when no limit is set.
+	DefaultLimit int64 = 10 << 20
+	// maxLineSize bounds the memory held for a line, longer lines are split.
+	maxLineSize   = 64 << 10
 	warningStatus = "Warning"
+	// maxEncryptedChunk bounds the output encrypted in a line of an encrypted
+	// output, so that a line fits in a streamed log record.
+	maxEncryptedChunk = 32 << 10
+	// OutputKey is the attribute that marks the log records holding lines of
+	// the algorithm output, see WithoutOutput.
+	OutputKey = "algorithm_output"
 )
 
+// Output receives the raw output streams of an algorithm, e.g. to stream them to the manager.
+type Output interface {
+	Stdout() io.Writer
+	Stderr() io.Writer
+}
+
+// Streams returns the stdout and stderr writers of output, nil if output is nil.
+func Streams(output Output) (io.Writer, io.Writer) {
+	if output == nil {
+		return nil, nil
+	}
+
+	return output.Stdout(), output.Stderr()
+}
+
+// Encrypt returns an output that encrypts the output streams with the X25519
+// public key before writing them to output. Every write is encrypted in chunks
+// of up to 32 KiB, each written as a line holding its base64 ciphertext, see
+// DecryptLine. Output that cannot be encrypted is dropped. It returns nil if
+// output is nil.
+func Encrypt(output Output, key *ecdh.PublicKey) Output {
+	if output == nil {
+		return nil
+	}
+
+	return &encryptedOutput{output: output, key: key}
+}
+
+type encryptedOutput struct {
+	output Output
+	key    *ecdh.PublicKey
+}
+
+func (e *encryptedOutput) Stdout() io.Writer {
+	return e.writer(e.output.Stdout())
+}
+
+func (e *encryptedOutput) Stderr() io.Writer {
+	return e.writer(e.output.Stderr())
+}
+
+// writer returns the writer encrypting what is written to w, nil if w is nil.
+func (e *encryptedOutput) writer(w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+
+	return &encryptedWriter{w: w, key: e.key}
+}
+
+type encryptedWriter struct {
+	w   io.Writer
+	key *ecdh.PublicKey
+}
+
+// Write implements io.Writer.
+func (e *encryptedWriter) Write(p []byte) (int, error) {
+	for data := p; len(data) > 0; {
+		n := min(len(data), maxEncryptedChunk)
+		ciphertext, err := encryption.Encrypt(e.key, data[:n])
+		if err != nil {
+			return len(p), nil
+		}
+		line := base64.StdEncoding.AppendEncode(nil, ciphertext)
+		if _, err := e.w.Write(append(line, '\n')); err != nil {
+			return 0, err
+		}
+		data = data[n:]
+	}
+
+	return len(p), nil
+}
+
+// DecryptLine decrypts a line of an encrypted output with the private key
+// matching the encryption key.
+func DecryptLine(key *ecdh.PrivateKey, line []byte) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.AppendDecode(nil, bytes.TrimSpace(line))
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.Decrypt(key, ciphertext)
+}
+
+// TeeStderr returns an output whose standard error is also written to w, e.g.
+// to keep its tail, and whose standard output is the one of output, if any.
+func TeeStderr(output Output, w io.Writer) Output {
+	return &teeStderr{output: output, w: w}
+}
+
+type teeStderr struct {
+	output Output
+	w      io.Writer
+}
+
+func (t *teeStderr) Stdout() io.Writer {
+	if t.output == nil {
+		return nil
+	}
+
+	return t.output.Stdout()
+}
+
+func (t *teeStderr) Stderr() io.Writer {
+	if t.output == nil {
+		return t.w
+	}
+
+	if stderr := t.output.Stderr(); stderr != nil {
+		return io.MultiWriter(stderr, t.w)
+	}
+
+	return t.w
+}
+
+// Tail keeps the last Size bytes written to it.
+type Tail struct {
+	// Size is the number of bytes kept.
+	Size int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write implements io.Writer.
+func (t *Tail) Write(p []byte) (n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(p) >= t.Size {
+		t.buf = append(t.buf[:0], p[len(p)-t.Size:]...)
+		return len(p), nil
+	}
+
+	if over := len(t.buf) + len(p) - t.Size; over > 0 {
+		t.buf = t.buf[:copy(t.buf, t.buf[over:])]
+	}
+	t.buf = append(t.buf, p...)
+
+	return len(p), nil
+}
+
+// String returns the bytes kept.
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.buf)
+}
+
+// Reset discards the bytes kept.
+func (t *Tail) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = t.buf[:0]
+}
+
+// Stdout logs the standard output of an algorithm line by line, up to Limit bytes.
 type Stdout struct {
 	Logger *slog.Logger
+	// Limit is the number of bytes logged before the output is truncated, DefaultLimit if zero.
+	Limit int64
+	// Stream receives the output as is when it is not nil, regardless of Limit.
+	Stream io.Writer
+
+	lines lineBuffer
 }
 
 // Write implements io.Writer.
 func (s *Stdout) Write(p []byte) (n int, err error) {
-	inBuf := bytes.NewBuffer(p)
+	if s.Stream != nil {
+		_, _ = s.Stream.Write(p)
+	}
 
-	buf := make([]byte, bufSize)
+	lines, truncated := s.lines.write(p, s.Limit)
+	s.log(lines, truncated)
 
-	for {
-		n, err := inBuf.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return len(p) - inBuf.Len(), err
-		}
+	return len(p), nil
+}
+
+// Flush logs the last line of the output if it does not end with a newline.
+func (s *Stdout) Flush() {
+	s.log(s.lines.flush(s.Limit))
+}
 
-		s.Logger.Debug(string(buf[:n]))
+func (s *Stdout) log(lines []string, truncated bool) {
+	for _, line := range lines {
+		s.Logger.Debug(line, slog.Bool(OutputKey, true))
 	}
 
-	return len(p), nil
+	if truncated {
+		s.Logger.Warn(truncationMarker(s.Limit))
+	}
 }
 
+// Stderr logs the standard error of an algorithm line by line, up to Limit bytes,
+// and reports the logged lines as algorithm events.
 type Stderr struct {
 	Logger   *slog.Logger
 	EventSvc events.Service
 	CmpID    string
+	// Limit is the number of bytes logged before the output is truncated, DefaultLimit if zero.
+	Limit int64
+	// Stream receives the output as is when it is not nil, regardless of Limit.
+	Stream io.Writer
+
+	lines lineBuffer
 }
 
 // Write implements io.Writer.
 func (s *Stderr) Write(p []byte) (n int, err error) {
-	inBuf := bytes.NewBuffer(p)
+	if s.Stream != nil {
+		_, _ = s.Stream.Write(p)
+	}
+
+	lines, truncated := s.lines.write(p, s.Limit)
+	s.log(lines, truncated)
+
+	return len(p), nil
+}
+
+// Flush logs the last line of the output if it does not end with a newline.
+func (s *Stderr) Flush() {
+	s.log(s.lines.flush(s.Limit))
+}
+
+func (s *Stderr) log(lines []string, truncated bool) {
+	for _, line := range lines {
+		s.Logger.Error(line, slog.Bool(OutputKey, true))
+	}
+
+	if len(lines) > 0 {
+		details, _ := json.Marshal(map[string]string{"output": strings.Join(lines, "\n")})
+		s.EventSvc.SendEvent(s.CmpID, events.AlgorithmRun, warningStatus, details)
+	}
+
+	if truncated {
+		marker := truncationMarker(s.Limit)
+		s.Logger.Warn(marker)
+
+		details, _ := json.Marshal(map[string]string{"output": marker})
+		s.EventSvc.SendEvent(s.CmpID, events.AlgorithmRun, warningStatus, details)
+	}
+}
+
+// WithoutOutput returns a handler that drops the log records holding algorithm
+// output and passes the others to h. Logs leave the agent in the clear, the
+// algorithm output may only leave it in events, which the manifest encrypts.
+func WithoutOutput(h slog.Handler) slog.Handler {
+	return &withoutOutput{Handler: h}
+}
+
+type withoutOutput struct {
+	slog.Handler
+}
+
+func (h *withoutOutput) Handle(ctx context.Context, r slog.Record) error {
+	output := false
+	r.Attrs(func(a slog.Attr) bool {
+		output = a.Key == OutputKey
+		return !output
+	})
+	if output {
+		return nil
+	}
 
-	buf := make([]byte, bufSize)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *withoutOutput) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &withoutOutput{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *withoutOutput) WithGroup(name string) slog.Handler {
+	return &withoutOutput{Handler: h.Handler.WithGroup(name)}
+}
+
+// Flush flushes the writers that buffer a partial line, other writers are ignored.
+func Flush(writers ...io.Writer) {
+	for _, w := range writers {
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+}
 
+// lineBuffer splits a stream into lines, bounding the memory held for a partial
+// line and the total size of the lines returned for the stream.
+type lineBuffer struct {
+	mu        sync.Mutex
+	pending   []byte
+	size      int64
+	truncated bool
+}
+
+// write buffers p and returns its complete lines, and whether the stream was truncated by this write.
+func (b *lineBuffer) write(p []byte, limit int64) ([]string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated {
+		return nil, false
+	}
+
+	b.pending = append(b.pending, p...)
+
+	var lines []string
+	start := 0
 	for {
-		n, err := inBuf.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return len(p) - inBuf.Len(), err
+		rest := b.pending[start:]
+		i := bytes.IndexByte(rest, '\n')
+		end, next := i, i+1
+		switch {
+		case i > maxLineSize, i < 0 && len(rest) >= maxLineSize:
+			end, next = maxLineSize, maxLineSize
+		case i < 0:
+			b.pending = b.pending[:copy(b.pending, rest)]
+			return lines, false
 		}
 
-		s.Logger.Error(string(buf[:n]))
+		line := string(rest[:end])
+		if !b.add(line, limit) {
+			return lines, true
+		}
+		lines = append(lines, line)
+		start += next
 	}
+}
 
-	s.EventSvc.SendEvent(s.CmpID, algorithmRun, warningStatus, json.RawMessage{})
+// flush returns the partial line left in the buffer.
+func (b *lineBuffer) flush(limit int64) ([]string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	return len(p), nil
+	if b.truncated || len(b.pending) == 0 {
+		return nil, false
+	}
+
+	line := string(b.pending)
+	if !b.add(line, limit) {
+		return nil, true
+	}
+	b.pending = b.pending[:0]
+
+	return []string{line}, false
+}
+
+// add accounts the line in the stream size, truncating the stream if it exceeds the limit.
+func (b *lineBuffer) add(line string, limit int64) bool {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	if b.size+int64(len(line)) > limit {
+		b.truncated = true
+		b.pending = nil
+		return false
+	}
+	b.size += int64(len(line)) + 1
+
+	return true
+}
+
+func truncationMarker(limit int64) string {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	return fmt.Sprintf("[output truncated after %d bytes]", limit)
 }
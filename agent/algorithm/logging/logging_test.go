@@ -3,12 +3,19 @@
 package logging
 
 import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"strings"
 	"testing"
 
 	mglog "github.com/absmach/supermq/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
 	"github.com/ultravioletrs/cocos/pkg/manager"
 )
@@ -16,71 +23,303 @@ import (
 func TestStdoutWrite(t *testing.T) {
 	tests := []struct {
 		name     string
-		input    string
+		writes   []string
+		limit    int64
 		expected []string
 	}{
 		{
 			name:     "Single line",
-			input:    "Hello, World!",
+			writes:   []string{"Hello, World!\n"},
 			expected: []string{"Hello, World!"},
 		},
 		{
 			name:     "Multiple lines",
-			input:    "Line 1\nLine 2\nLine 3",
-			expected: []string{"Line 1\nLine 2\nLine 3"},
+			writes:   []string{"Line 1\nLine 2\nLine 3\n"},
+			expected: []string{"Line 1", "Line 2", "Line 3"},
 		},
 		{
-			name:     "Long input",
-			input:    strings.Repeat("a", bufSize+100),
-			expected: []string{strings.Repeat("a", bufSize), strings.Repeat("a", 100)},
+			name:     "Line split across writes",
+			writes:   []string{"Li", "ne 1\nLi", "ne 2\n"},
+			expected: []string{"Line 1", "Line 2"},
+		},
+		{
+			name:     "Partial last line",
+			writes:   []string{"Line 1\nLine 2"},
+			expected: []string{"Line 1", "Line 2"},
+		},
+		{
+			name:     "Long line",
+			writes:   []string{strings.Repeat("a", maxLineSize+100) + "\n"},
+			expected: []string{strings.Repeat("a", maxLineSize), strings.Repeat("a", 100)},
+		},
+		{
+			name:     "Truncated output",
+			writes:   []string{"Line 1\nLine 2\n", "Line 3\nLine 4\n"},
+			limit:    14,
+			expected: []string{"Line 1", "Line 2", "[output truncated after 14 bytes]"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			stdout := &Stdout{Logger: mglog.NewMock()}
-			n, err := stdout.Write([]byte(tt.input))
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: messageOnly}))
+
+			stdout := &Stdout{Logger: logger, Limit: tt.limit}
+			for _, w := range tt.writes {
+				n, err := stdout.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			stdout.Flush()
 
-			assert.NoError(t, err)
-			assert.Equal(t, len(tt.input), n)
+			assert.Equal(t, tt.expected, loggedMessages(buf.String()))
 		})
 	}
 }
 
 func TestStderrWrite(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		expected []string
+		name    string
+		writes  []string
+		limit   int64
+		outputs []string
 	}{
 		{
-			name:     "Single line",
-			input:    "Error: Something went wrong",
-			expected: []string{"Error: Something went wrong"},
+			name:    "Single line",
+			writes:  []string{"Error: Something went wrong\n"},
+			outputs: []string{"Error: Something went wrong"},
 		},
 		{
-			name:     "Multiple lines",
-			input:    "Error 1\nError 2\nError 3",
-			expected: []string{"Error 1\nError 2\nError 3"},
+			name:    "Multiple lines",
+			writes:  []string{"Error 1\nError 2\nError 3\n"},
+			outputs: []string{"Error 1\nError 2\nError 3"},
 		},
 		{
-			name:     "Long input",
-			input:    strings.Repeat("e", bufSize+100),
-			expected: []string{strings.Repeat("e", bufSize), strings.Repeat("e", 100)},
+			name:    "Partial last line",
+			writes:  []string{"Error 1\nError 2"},
+			outputs: []string{"Error 1", "Error 2"},
+		},
+		{
+			name:    "Truncated output",
+			writes:  []string{"Error 1\n", strings.Repeat("e", 100) + "\n", "Error 3\n"},
+			limit:   50,
+			outputs: []string{"Error 1", "[output truncated after 50 bytes]"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var outputs []string
 			mockEventService := mocks.NewService(t)
-			mockEventService.On("SendEvent", mock.Anything, "AlgorithmRun", manager.Warning.String(), mock.Anything).Return(nil)
+			mockEventService.On("SendEvent", mock.Anything, "AlgorithmRun", manager.Warning.String(), mock.Anything).
+				Run(func(args mock.Arguments) {
+					var details map[string]string
+					assert.NoError(t, json.Unmarshal(args.Get(3).(json.RawMessage), &details))
+					outputs = append(outputs, details["output"])
+				}).Return(nil)
+
+			stderr := &Stderr{Logger: mglog.NewMock(), EventSvc: mockEventService, Limit: tt.limit}
+			for _, w := range tt.writes {
+				n, err := stderr.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			stderr.Flush()
+
+			assert.Equal(t, tt.outputs, outputs)
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: messageOnly}))
+
+	stdout := &Stdout{Logger: logger}
+	_, err := stdout.Write([]byte("partial"))
+	assert.NoError(t, err)
+	assert.Empty(t, loggedMessages(buf.String()))
+
+	var other bytes.Buffer
+	Flush(stdout, &other)
+	assert.Equal(t, []string{"partial"}, loggedMessages(buf.String()))
+}
+
+type testOutput struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+func (o *testOutput) Stdout() io.Writer { return &o.stdout }
+
+func (o *testOutput) Stderr() io.Writer { return &o.stderr }
+
+func TestStream(t *testing.T) {
+	stdoutStream, stderrStream := Streams(nil)
+	assert.Nil(t, stdoutStream)
+	assert.Nil(t, stderrStream)
+
+	output := &testOutput{}
+	stdoutStream, stderrStream = Streams(output)
+
+	mockEventService := mocks.NewService(t)
+	mockEventService.On("SendEvent", mock.Anything, "AlgorithmRun", manager.Warning.String(), mock.Anything).Return(nil)
+
+	stdout := &Stdout{Logger: mglog.NewMock(), Limit: 8, Stream: stdoutStream}
+	stderr := &Stderr{Logger: mglog.NewMock(), EventSvc: mockEventService, Stream: stderrStream}
+
+	_, err := stdout.Write([]byte("epoch 1\nepoch 2\n"))
+	assert.NoError(t, err)
+	_, err = stderr.Write([]byte("warning\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "epoch 1\nepoch 2\n", output.stdout.String(), "streamed output is not truncated")
+	assert.Equal(t, "warning\n", output.stderr.String())
+}
+
+func TestTeeStderr(t *testing.T) {
+	var tail bytes.Buffer
+
+	output := TeeStderr(nil, &tail)
+	assert.Nil(t, output.Stdout())
+	_, err := output.Stderr().Write([]byte("warning\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "warning\n", tail.String())
 
-			stderr := &Stderr{Logger: mglog.NewMock(), EventSvc: mockEventService}
-			n, err := stderr.Write([]byte(tt.input))
+	tail.Reset()
+	streams := &testOutput{}
+	output = TeeStderr(streams, &tail)
+	_, err = output.Stdout().Write([]byte("epoch 1\n"))
+	assert.NoError(t, err)
+	_, err = output.Stderr().Write([]byte("warning\n"))
+	assert.NoError(t, err)
 
-			assert.NoError(t, err)
-			assert.Equal(t, len(tt.input), n)
-			mockEventService.AssertExpectations(t)
+	assert.Equal(t, "epoch 1\n", streams.stdout.String())
+	assert.Equal(t, "warning\n", streams.stderr.String())
+	assert.Equal(t, "warning\n", tail.String(), "standard output is not teed")
+}
+
+func TestTail(t *testing.T) {
+	cases := []struct {
+		desc   string
+		writes []string
+		tail   string
+	}{
+		{
+			desc: "no output",
+		},
+		{
+			desc:   "output shorter than the tail",
+			writes: []string{"abc", "de"},
+			tail:   "abcde",
+		},
+		{
+			desc:   "writes overflowing the tail",
+			writes: []string{"abcd", "ef", "ghi"},
+			tail:   "defghi",
+		},
+		{
+			desc:   "write longer than the tail",
+			writes: []string{"ab", "cdefghijk"},
+			tail:   "fghijk",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tail := &Tail{Size: 6}
+			for _, w := range tc.writes {
+				n, err := tail.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			assert.Equal(t, tc.tail, tail.String())
+
+			tail.Reset()
+			assert.Empty(t, tail.String())
 		})
 	}
 }
+
+func messageOnly(_ []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.MessageKey {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func loggedMessages(out string) []string {
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		msg := strings.TrimPrefix(line, "msg=")
+		if unquoted, err := unquote(msg); err == nil {
+			msg = unquoted
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func unquote(s string) (string, error) {
+	var v string
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func TestWithoutOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(WithoutOutput(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: messageOnly})))
+
+	stdout := &Stdout{Logger: logger, Limit: 8}
+	_, err := stdout.Write([]byte("secret 1\nsecret 2\n"))
+	assert.NoError(t, err)
+	logger.With("computation", "cmp").Info("algorithm started")
+
+	assert.Equal(t, []string{"[output truncated after 8 bytes]", "algorithm started"}, loggedMessages(buf.String()))
+}
+
+func TestEncrypt(t *testing.T) {
+	assert.Nil(t, Encrypt(nil, nil))
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	output := &testOutput{}
+	encrypted := Encrypt(output, key.PublicKey())
+
+	large := strings.Repeat("a", maxEncryptedChunk+10)
+	_, err = encrypted.Stdout().Write([]byte("epoch 1\n"))
+	require.NoError(t, err)
+	_, err = encrypted.Stdout().Write([]byte(large))
+	require.NoError(t, err)
+	_, err = encrypted.Stderr().Write([]byte("warning\n"))
+	require.NoError(t, err)
+
+	assert.NotContains(t, output.stdout.String(), "epoch")
+
+	decrypt := func(stream string) string {
+		var plaintext []byte
+		for _, line := range strings.SplitAfter(stream, "\n") {
+			if line == "" {
+				continue
+			}
+			assert.True(t, strings.HasSuffix(line, "\n"), "every chunk is a line")
+			data, err := DecryptLine(key, []byte(line))
+			require.NoError(t, err)
+			plaintext = append(plaintext, data...)
+		}
+		return string(plaintext)
+	}
+	assert.Equal(t, 3, strings.Count(output.stdout.String(), "\n"), "large writes are split")
+	assert.Equal(t, "epoch 1\n"+large, decrypt(output.stdout.String()))
+	assert.Equal(t, "warning\n", decrypt(output.stderr.String()))
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = DecryptLine(other, []byte(strings.SplitAfter(output.stderr.String(), "\n")[0]))
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package algorithm
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// Command returns the command of an algorithm process. The process is started
+// in its own process group, so that KillGroup also stops the processes the
+// algorithm spawned.
+func Command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	return cmd
+}
+
+// KillGroup kills the process group of a running command started with Command.
+func KillGroup(cmd *exec.Cmd) error {
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
+		return nil
+	}
+
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+
+	return nil
+}
@@ -3,18 +3,18 @@
 package python
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/cgroup"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events"
-	"google.golang.org/grpc/metadata"
 )
 
 const (
@@ -22,12 +22,19 @@ const (
 	PyRuntimeKey = "python_runtime"
 )
 
-func PythonRunTimeToContext(ctx context.Context, runtime string) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, PyRuntimeKey, runtime)
-}
+// Version returns the version of the python interpreter runtime, e.g. 3.11.4.
+func Version(runtime string) (string, error) {
+	out, err := exec.Command(runtime, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting %s version: %w", runtime, err)
+	}
 
-func PythonRunTimeFromContext(ctx context.Context) string {
-	return metadata.ValueFromIncomingContext(ctx, PyRuntimeKey)[0]
+	version, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "Python ")
+	if !ok {
+		return "", fmt.Errorf("unexpected %s version output %q", runtime, out)
+	}
+
+	return version, nil
 }
 
 var _ algorithm.Algorithm = (*python)(nil)
@@ -39,16 +46,26 @@ type python struct {
 	runtime          string
 	requirementsFile string
 	args             []string
+	sandbox          algorithm.Sandbox
 	cmd              *exec.Cmd
+	group            *cgroup.Group
 }
 
-func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, runtime, requirementsFile, algoFile string, args []string, cmpID string) algorithm.Algorithm {
+// NewAlgorithm returns a python algorithm, which runs in the sandbox and in the
+// cgroup group when it is not nil. The requirements are installed in a virtual
+// environment in the sandbox, outside of the group. Its output is also written
+// to output when it is not nil.
+func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, runtime, requirementsFile, algoFile string, args []string, cmpID string, sandbox algorithm.Sandbox, group *cgroup.Group, output logging.Output) algorithm.Algorithm {
+	stdout, stderr := logging.Streams(output)
+
 	p := &python{
 		algoFile:         algoFile,
-		stderr:           &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID},
-		stdout:           &logging.Stdout{Logger: logger},
+		stderr:           &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID, Stream: stderr},
+		stdout:           &logging.Stdout{Logger: logger, Stream: stdout},
 		requirementsFile: requirementsFile,
 		args:             args,
+		sandbox:          sandbox,
+		group:            group,
 	}
 	if runtime != "" {
 		p.runtime = runtime
@@ -58,9 +75,20 @@ func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, runtime, requir
 	return p
 }
 
-func (p *python) Run() error {
-	venvPath := "venv"
+func (p *python) Run() (err error) {
+	defer logging.Flush(p.stdout, p.stderr)
+
+	env := p.sandbox.Environ()
+
+	venvPath := filepath.Join(p.sandbox.Tmp(), "venv")
+	defer func() {
+		if rerr := os.RemoveAll(venvPath); rerr != nil && err == nil {
+			err = fmt.Errorf("error removing virtual environment: %v", rerr)
+		}
+	}()
+
 	createVenvCmd := exec.Command(p.runtime, "-m", "venv", venvPath)
+	createVenvCmd.Env = env
 	createVenvCmd.Stderr = p.stderr
 	createVenvCmd.Stdout = p.stdout
 	if err := createVenvCmd.Run(); err != nil {
@@ -70,6 +98,7 @@ func (p *python) Run() error {
 	pythonPath := filepath.Join(venvPath, "bin", "python")
 
 	updatePipCmd := exec.Command(pythonPath, "-m", "pip", "install", "--upgrade", "pip")
+	updatePipCmd.Env = env
 	updatePipCmd.Stderr = p.stderr
 	updatePipCmd.Stdout = p.stdout
 	if err := updatePipCmd.Run(); err != nil {
@@ -78,6 +107,7 @@ func (p *python) Run() error {
 
 	if p.requirementsFile != "" {
 		rcmd := exec.Command(pythonPath, "-m", "pip", "install", "-r", p.requirementsFile)
+		rcmd.Env = env
 		rcmd.Stderr = p.stderr
 		rcmd.Stdout = p.stdout
 		if err := rcmd.Run(); err != nil {
@@ -86,39 +116,26 @@ func (p *python) Run() error {
 	}
 
 	args := append([]string{p.algoFile}, p.args...)
-	p.cmd = exec.Command(pythonPath, args...)
+	p.cmd = algorithm.Command(pythonPath, args...)
+	p.cmd.Dir = p.sandbox.Root
+	p.cmd.Env = env
 	p.cmd.Stderr = p.stderr
 	p.cmd.Stdout = p.stdout
+	p.group.Attach(p.cmd)
 
 	if err := p.cmd.Start(); err != nil {
 		return fmt.Errorf("error starting algorithm: %v", err)
 	}
 
 	if err := p.cmd.Wait(); err != nil {
-		return fmt.Errorf("algorithm execution error: %v", err)
-	}
-
-	if err := os.RemoveAll(venvPath); err != nil {
-		return fmt.Errorf("error removing virtual environment: %v", err)
+		return fmt.Errorf("algorithm execution error: %w", err)
 	}
 
 	return nil
 }
 
 func (p *python) Stop() error {
-	if p.cmd == nil {
-		return nil
-	}
-
-	if p.cmd.ProcessState != nil && p.cmd.ProcessState.Exited() {
-		return nil
-	}
-
-	if p.cmd.Process == nil {
-		return nil
-	}
-
-	if err := p.cmd.Process.Kill(); err != nil {
+	if err := algorithm.KillGroup(p.cmd); err != nil {
 		return fmt.Errorf("error stopping algorithm: %v", err)
 	}
 
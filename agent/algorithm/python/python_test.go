@@ -4,42 +4,36 @@ package python
 
 import (
 	"bytes"
-	"context"
 	"io"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
-	"google.golang.org/grpc/metadata"
 )
 
 const runtime = "python3"
 
-func TestPythonRunTimeToContext(t *testing.T) {
-	ctx := context.Background()
-	newCtx := PythonRunTimeToContext(ctx, runtime)
-
-	md, ok := metadata.FromOutgoingContext(newCtx)
-	if !ok {
-		t.Fatal("Expected metadata in context")
+func TestVersion(t *testing.T) {
+	if _, err := exec.LookPath(runtime); err != nil {
+		t.Skipf("%s is not installed", runtime)
 	}
 
-	values := md.Get(PyRuntimeKey)
-	if len(values) != 1 || values[0] != runtime {
-		t.Errorf("Expected runtime %s, got %v", runtime, values)
+	version, err := Version(runtime)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(version, "3.") {
+		t.Errorf("Expected a python 3 version, got %s", version)
 	}
-}
-
-func TestPythonRunTimeFromContext(t *testing.T) {
-	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PyRuntimeKey, runtime))
 
-	got := PythonRunTimeFromContext(ctx)
-	if got != runtime {
-		t.Errorf("Expected runtime %s, got %s", runtime, got)
+	if _, err := Version("python3.missing"); err == nil {
+		t.Error("Expected an error for a missing interpreter")
 	}
 }
 
@@ -50,7 +44,7 @@ func TestNewAlgorithm(t *testing.T) {
 	algoFile := "algorithm.py"
 	args := []string{"--arg1", "value1"}
 
-	algo := NewAlgorithm(logger, eventsSvc, runtime, requirementsFile, algoFile, args, "")
+	algo := NewAlgorithm(logger, eventsSvc, runtime, requirementsFile, algoFile, args, "", algorithm.Sandbox{}, nil, nil)
 
 	p, ok := algo.(*python)
 	if !ok {
@@ -93,6 +87,7 @@ func TestRun(t *testing.T) {
 		stderr:   io.MultiWriter(&stderr, &logging.Stderr{Logger: slog.Default(), EventSvc: eventsSvc}),
 		stdout:   io.MultiWriter(&stdout, &logging.Stdout{Logger: slog.Default()}),
 		runtime:  "python3",
+		sandbox:  algorithm.Sandbox{Root: tmpDir},
 	}
 
 	err = algo.Run()
@@ -135,6 +130,7 @@ func TestRunWithRequirements(t *testing.T) {
 		stderr:           io.MultiWriter(&stderr, &logging.Stderr{Logger: slog.Default(), EventSvc: eventsSvc}),
 		stdout:           io.MultiWriter(&stdout, &logging.Stdout{Logger: slog.Default()}),
 		runtime:          "python3",
+		sandbox:          algorithm.Sandbox{Root: tmpDir},
 	}
 
 	err = algo.Run()
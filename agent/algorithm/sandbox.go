@@ -0,0 +1,229 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package algorithm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// AlgoDir holds the algorithm and its requirements in the sandbox.
+	AlgoDir = "algo"
+	// TmpDir holds the temporary files of the algorithm in the sandbox.
+	TmpDir = "tmp"
+	// SecretsDir holds the runtime secrets of the computation owner in the sandbox.
+	SecretsDir = "secrets"
+
+	// SandboxDirEnv holds the absolute path of the sandbox in the algorithm environment.
+	SandboxDirEnv = "COCOS_SANDBOX_DIR"
+	// AlgoDirEnv holds the absolute path of the algorithm directory in the algorithm environment.
+	AlgoDirEnv = "COCOS_ALGO_DIR"
+	// TmpDirEnv holds the absolute path of the temporary directory in the algorithm environment.
+	TmpDirEnv = "COCOS_TMP_DIR"
+	// SecretsDirEnv holds the absolute path of the secret files in the algorithm
+	// environment, it is only set when secrets were provisioned.
+	SecretsDirEnv = "COCOS_SECRETS_DIR"
+
+	sandboxPermission = 0o700
+	shredBufferSize   = 1 << 20
+)
+
+// Sandbox is the working directory of a computation. Its datasets, algorithm,
+// results, working and temporary directories are only accessible to the user
+// the agent runs as, and no computation shares a directory with another.
+type Sandbox struct {
+	Root string
+	// Env holds the environment variables of the computation manifest, in the
+	// NAME=value form, the algorithm runs with.
+	Env []string
+}
+
+// NewSandbox returns the sandbox of the computation in dir. Sandboxes are named
+// after the hash of the computation ID, so any ID maps to a directory of its own.
+func NewSandbox(dir, computationID string) (Sandbox, error) {
+	sum := sha3.Sum256([]byte(computationID))
+
+	root, err := filepath.Abs(filepath.Join(dir, hex.EncodeToString(sum[:16])))
+	if err != nil {
+		return Sandbox{}, err
+	}
+
+	return Sandbox{Root: root}, nil
+}
+
+// Datasets returns the datasets directory of the sandbox.
+func (s Sandbox) Datasets() string {
+	return filepath.Join(s.Root, DatasetsDir)
+}
+
+// Results returns the results directory of the sandbox.
+func (s Sandbox) Results() string {
+	return filepath.Join(s.Root, ResultsDir)
+}
+
+// Work returns the working directory of the sandbox the agent checkpoints.
+func (s Sandbox) Work() string {
+	return filepath.Join(s.Root, WorkDir)
+}
+
+// Algo returns the algorithm directory of the sandbox.
+func (s Sandbox) Algo() string {
+	return filepath.Join(s.Root, AlgoDir)
+}
+
+// Tmp returns the temporary directory of the sandbox.
+func (s Sandbox) Tmp() string {
+	return filepath.Join(s.Root, TmpDir)
+}
+
+// Secrets returns the directory of the sandbox the agent mounts the runtime
+// secrets storage on.
+func (s Sandbox) Secrets() string {
+	return filepath.Join(s.Root, SecretsDir)
+}
+
+// SecretFiles returns the directory of the secrets exposed to the algorithm as files.
+func (s Sandbox) SecretFiles() string {
+	return filepath.Join(s.Secrets(), "files")
+}
+
+// SecretEnv returns the directory of the secrets exposed to the algorithm as
+// environment variables, one file per variable.
+func (s Sandbox) SecretEnv() string {
+	return filepath.Join(s.Secrets(), "env")
+}
+
+// HasSecretFiles reports whether secrets were provisioned as files.
+func (s Sandbox) HasSecretFiles() bool {
+	info, err := os.Stat(s.SecretFiles())
+	return err == nil && info.IsDir()
+}
+
+// SecretsEnviron returns the secrets exposed as environment variables, in
+// the NAME=value form, nil when none were provisioned.
+func (s Sandbox) SecretsEnviron() []string {
+	entries, err := os.ReadDir(s.SecretEnv())
+	if err != nil {
+		return nil
+	}
+
+	var env []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(s.SecretEnv(), e.Name()))
+		if err != nil {
+			continue
+		}
+		env = append(env, e.Name()+"="+string(value))
+	}
+
+	return env
+}
+
+// Create creates the sandbox with its algorithm and temporary directories,
+// the datasets and results directories are created by the computation storage.
+func (s Sandbox) Create() error {
+	for _, dir := range []string{s.Root, s.Algo(), s.Tmp()} {
+		if err := os.MkdirAll(dir, sandboxPermission); err != nil {
+			return err
+		}
+		// MkdirAll keeps the permissions of existing directories.
+		if err := os.Chmod(dir, sandboxPermission); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Environ returns the environment algorithm processes run with, which exposes
+// the sandbox directories at well-known variables so algorithms do not depend
+// on the agent working directory. Temporary files are kept in the sandbox.
+// The environment variables of the manifest follow, and the provisioned
+// secrets, which are read when it is called, come last.
+func (s Sandbox) Environ() []string {
+	env := append(os.Environ(),
+		SandboxDirEnv+"="+s.Root,
+		DatasetsDirEnv+"="+s.Datasets(),
+		ResultsDirEnv+"="+s.Results(),
+		WorkDirEnv+"="+s.Work(),
+		AlgoDirEnv+"="+s.Algo(),
+		TmpDirEnv+"="+s.Tmp(),
+		"TMPDIR="+s.Tmp(),
+	)
+	if s.HasSecretFiles() {
+		env = append(env, SecretsDirEnv+"="+s.SecretFiles())
+	}
+	env = append(env, s.Env...)
+
+	return append(env, s.SecretsEnviron()...)
+}
+
+// Shred overwrites every regular file under dir with random data, so that
+// removing them does not leave their content on the disk. Missing directories
+// are ignored. Overwriting is best effort on file systems that do not write
+// in place.
+func Shred(dir string) error {
+	buf := make([]byte, shredBufferSize)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		return shredFile(path, buf)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func shredFile(path string, buf []byte) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	// Read-only files, e.g. staged datasets, are overwritten as well.
+	if err := os.Chmod(path, 0o600); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	for remaining := info.Size(); remaining > 0; {
+		n := min(remaining, int64(len(buf)))
+		if _, err := f.Write(buf[:n]); err != nil {
+			f.Close()
+			return err
+		}
+		remaining -= n
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,123 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package algorithm_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+func TestNewSandbox(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	a, err := algorithm.NewSandbox("computations", "computation-a")
+	require.NoError(t, err)
+	b, err := algorithm.NewSandbox("computations", "computation-b")
+	require.NoError(t, err)
+	escaping, err := algorithm.NewSandbox("computations", "../../etc")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(wd, "computations"), filepath.Dir(a.Root), "relative directories are resolved")
+	assert.NotEqual(t, a.Root, b.Root, "computations do not share a sandbox")
+	assert.Equal(t, filepath.Join(wd, "computations"), filepath.Dir(escaping.Root), "IDs do not escape the sandboxes directory")
+
+	again, err := algorithm.NewSandbox("computations", "computation-a")
+	require.NoError(t, err)
+	assert.Equal(t, a, again, "a restarted agent finds the sandbox of the computation")
+}
+
+func TestSandboxCreate(t *testing.T) {
+	sandbox := algorithm.Sandbox{Root: filepath.Join(t.TempDir(), "sandbox")}
+	require.NoError(t, sandbox.Create())
+
+	for _, dir := range []string{sandbox.Root, sandbox.Algo(), sandbox.Tmp()} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm(), dir)
+	}
+
+	require.NoError(t, os.Chmod(sandbox.Root, 0o755))
+	require.NoError(t, sandbox.Create())
+	info, err := os.Stat(sandbox.Root)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm(), "permissions of an existing sandbox are restricted")
+}
+
+func TestSandboxEnviron(t *testing.T) {
+	t.Setenv("COCOS_TEST_VAR", "value")
+
+	sandbox := algorithm.Sandbox{Root: "/cocos/computations/1"}
+	env := sandbox.Environ()
+
+	assert.True(t, slices.Contains(env, "COCOS_TEST_VAR=value"))
+	assert.True(t, slices.Contains(env, algorithm.SandboxDirEnv+"=/cocos/computations/1"))
+	assert.True(t, slices.Contains(env, algorithm.DatasetsDirEnv+"=/cocos/computations/1/datasets"))
+	assert.True(t, slices.Contains(env, algorithm.ResultsDirEnv+"=/cocos/computations/1/results"))
+	assert.True(t, slices.Contains(env, algorithm.WorkDirEnv+"=/cocos/computations/1/work"))
+	assert.True(t, slices.Contains(env, algorithm.AlgoDirEnv+"=/cocos/computations/1/algo"))
+	assert.True(t, slices.Contains(env, algorithm.TmpDirEnv+"=/cocos/computations/1/tmp"))
+	assert.True(t, slices.Contains(env, "TMPDIR=/cocos/computations/1/tmp"))
+	assert.False(t, slices.ContainsFunc(env, func(kv string) bool { return strings.HasPrefix(kv, algorithm.SecretsDirEnv+"=") }), "no secrets were provisioned")
+}
+
+func TestSandboxManifestEnviron(t *testing.T) {
+	sandbox := algorithm.Sandbox{Root: t.TempDir(), Env: []string{"EPOCHS=2", "SHARED=manifest"}}
+	require.NoError(t, os.MkdirAll(sandbox.SecretEnv(), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(sandbox.SecretEnv(), "SHARED"), []byte("secret"), 0o600))
+
+	env := sandbox.Environ()
+
+	assert.True(t, slices.Contains(env, "EPOCHS=2"))
+	// Secrets come last, so they take precedence over the manifest variables.
+	assert.Greater(t, slices.Index(env, "SHARED=secret"), slices.Index(env, "SHARED=manifest"))
+}
+
+func TestSandboxSecrets(t *testing.T) {
+	sandbox := algorithm.Sandbox{Root: t.TempDir()}
+	assert.False(t, sandbox.HasSecretFiles())
+	assert.Nil(t, sandbox.SecretsEnviron())
+
+	require.NoError(t, os.MkdirAll(sandbox.SecretFiles(), 0o700))
+	require.NoError(t, os.MkdirAll(sandbox.SecretEnv(), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(sandbox.SecretEnv(), "API_TOKEN"), []byte("token"), 0o600))
+
+	assert.True(t, sandbox.HasSecretFiles())
+	assert.Equal(t, []string{"API_TOKEN=token"}, sandbox.SecretsEnviron())
+
+	env := sandbox.Environ()
+	assert.True(t, slices.Contains(env, algorithm.SecretsDirEnv+"="+sandbox.SecretFiles()))
+	assert.True(t, slices.Contains(env, "API_TOKEN=token"))
+}
+
+func TestShred(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("secret"), 500_000)
+
+	files := []string{filepath.Join(dir, "dataset.csv"), filepath.Join(dir, "nested", "model.bin")}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+	for _, file := range files {
+		require.NoError(t, os.WriteFile(file, data, 0o644))
+	}
+	require.NoError(t, os.Chmod(files[0], 0o444))
+	require.NoError(t, os.Symlink(files[0], filepath.Join(dir, "link")))
+
+	require.NoError(t, algorithm.Shred(dir))
+
+	for _, file := range files {
+		shredded, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Len(t, shredded, len(data), "files keep their size")
+		assert.False(t, bytes.Contains(shredded, []byte("secret")), "%s was not overwritten", file)
+	}
+
+	assert.NoError(t, algorithm.Shred(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidSpec indicates an algorithm spec the agent cannot run.
+	ErrInvalidSpec = errors.New("invalid algorithm spec")
+	// ErrInvalidArg indicates an algorithm argument outside of the allowlist.
+	ErrInvalidArg = errors.New("invalid algorithm argument")
+	// ErrInvalidEnv indicates an algorithm environment variable outside of the allowlist.
+	ErrInvalidEnv = errors.New("invalid algorithm environment variable")
+
+	// pythonRuntimePattern matches the python interpreters algorithms may request.
+	pythonRuntimePattern = regexp.MustCompile(`^python3(\.[0-9]+)?$`)
+	versionPattern       = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+	// valuePattern matches the arguments and environment variable values algorithms
+	// may be given, which exclude control characters and shell metacharacters.
+	valuePattern = regexp.MustCompile(`^[A-Za-z0-9 _.,:;=/+@%#~^-]{0,1000}$`)
+	// envNamePattern matches the environment variable names algorithms may be given.
+	envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,127}$`)
+	// reservedEnvPrefixes are the prefixes of the environment variables the agent
+	// sets or that change how the algorithm process is loaded.
+	reservedEnvPrefixes = []string{"COCOS_", "LD_", "DYLD_"}
+	// reservedEnvNames are the environment variables the agent or the runtimes rely on.
+	reservedEnvNames = []string{"PATH", "TMPDIR", "HOME", "PYTHONPATH", "PYTHONHOME", "VIRTUAL_ENV"}
+)
+
+// Types are the algorithm types the agent runs.
+var Types = []AlgorithType{AlgoTypeBin, AlgoTypePython, AlgoTypeWasm, AlgoTypeDocker}
+
+// Spec describes how the agent runs an uploaded algorithm.
+type Spec struct {
+	Type AlgorithType
+	// Entrypoint is the exported function of a wasm module, or the command of
+	// a docker image, run instead of the default one.
+	Entrypoint string
+	Args       []string
+	// Runtime is the interpreter of python algorithms.
+	Runtime string
+	// MinRuntimeVersion is the lowest runtime version the algorithm supports.
+	MinRuntimeVersion string
+}
+
+// Validate checks that the spec only sets the fields its algorithm type uses.
+func (s Spec) Validate() error {
+	if !slices.Contains(Types, s.Type) {
+		return fmt.Errorf("%w: unknown algorithm type %q", ErrInvalidSpec, s.Type)
+	}
+
+	if s.Entrypoint != "" && s.Type != AlgoTypeWasm && s.Type != AlgoTypeDocker {
+		return fmt.Errorf("%w: %s algorithms have no entrypoint", ErrInvalidSpec, s.Type)
+	}
+
+	if (s.Runtime != "" || s.MinRuntimeVersion != "") && s.Type != AlgoTypePython {
+		return fmt.Errorf("%w: only python algorithms select a runtime", ErrInvalidSpec)
+	}
+
+	if s.Runtime != "" && !pythonRuntimePattern.MatchString(s.Runtime) {
+		return fmt.Errorf("%w: runtime %q is not a python3 interpreter", ErrInvalidSpec, s.Runtime)
+	}
+
+	if s.MinRuntimeVersion != "" && !versionPattern.MatchString(s.MinRuntimeVersion) {
+		return fmt.Errorf("%w: runtime version %q is not a dotted version number", ErrInvalidSpec, s.MinRuntimeVersion)
+	}
+
+	return nil
+}
+
+// ValidateArgs checks that every argument matches the allowlist pattern.
+func ValidateArgs(args []string) error {
+	for i, arg := range args {
+		if !valuePattern.MatchString(arg) {
+			return fmt.Errorf("%w: argument %d %q", ErrInvalidArg, i, arg)
+		}
+	}
+
+	return nil
+}
+
+// ValidateEnv checks that every environment variable has an allowed name, which
+// the agent does not reserve, and a value matching the allowlist pattern.
+func ValidateEnv(env map[string]string) error {
+	for name, value := range env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: name %q", ErrInvalidEnv, name)
+		}
+
+		upper := strings.ToUpper(name)
+		if slices.Contains(reservedEnvNames, upper) || slices.ContainsFunc(reservedEnvPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(upper, prefix)
+		}) {
+			return fmt.Errorf("%w: %s is reserved", ErrInvalidEnv, name)
+		}
+
+		if !valuePattern.MatchString(value) {
+			return fmt.Errorf("%w: value of %s", ErrInvalidEnv, name)
+		}
+	}
+
+	return nil
+}
+
+// Environ returns the environment variables in the NAME=value form, sorted by name.
+func Environ(env map[string]string) []string {
+	environ := make([]string, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		environ = append(environ, name+"="+env[name])
+	}
+
+	return environ
+}
+
+// VersionAtLeast reports whether the dotted version is min or newer, missing
+// components count as zero. Components after the leading digits, e.g. the rc1
+// of 3.13.0rc1, are ignored.
+func VersionAtLeast(version, min string) bool {
+	v, m := strings.Split(version, "."), strings.Split(min, ".")
+	for i := range max(len(v), len(m)) {
+		a, b := versionComponent(v, i), versionComponent(m, i)
+		if a != b {
+			return a > b
+		}
+	}
+
+	return true
+}
+
+func versionComponent(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+
+	digits := strings.IndexFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' })
+	if digits < 0 {
+		digits = len(parts[i])
+	}
+	n, _ := strconv.Atoi(parts[i][:digits])
+
+	return n
+}
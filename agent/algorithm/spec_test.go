@@ -0,0 +1,139 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package algorithm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+func TestSpecValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		spec algorithm.Spec
+		err  error
+	}{
+		{
+			name: "binary",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypeBin, Args: []string{"--epochs", "2"}},
+		},
+		{
+			name: "python with runtime",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypePython, Runtime: "python3.11", MinRuntimeVersion: "3.10"},
+		},
+		{
+			name: "wasm with entrypoint",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypeWasm, Entrypoint: "train"},
+		},
+		{
+			name: "docker with entrypoint",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypeDocker, Entrypoint: "/app/train"},
+		},
+		{
+			name: "unknown type",
+			spec: algorithm.Spec{Type: "java"},
+			err:  algorithm.ErrInvalidSpec,
+		},
+		{
+			name: "binary with entrypoint",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypeBin, Entrypoint: "main"},
+			err:  algorithm.ErrInvalidSpec,
+		},
+		{
+			name: "wasm with runtime",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypeWasm, Runtime: "python3"},
+			err:  algorithm.ErrInvalidSpec,
+		},
+		{
+			name: "python with other interpreter",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypePython, Runtime: "/bin/sh"},
+			err:  algorithm.ErrInvalidSpec,
+		},
+		{
+			name: "python with malformed version",
+			spec: algorithm.Spec{Type: algorithm.AlgoTypePython, MinRuntimeVersion: "3.x"},
+			err:  algorithm.ErrInvalidSpec,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.spec.Validate()
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		version string
+		min     string
+		ok      bool
+	}{
+		{version: "3.11.4", min: "3.10", ok: true},
+		{version: "3.10", min: "3.10.0", ok: true},
+		{version: "3.9.18", min: "3.10", ok: false},
+		{version: "3.13.0rc1", min: "3.13", ok: true},
+		{version: "4", min: "3.12", ok: true},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.ok, algorithm.VersionAtLeast(tc.version, tc.min), "%s >= %s", tc.version, tc.min)
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "no arguments"},
+		{name: "flags and values", args: []string{"--epochs=2", "--lr", "0.01", "model/v1.bin", "a b"}},
+		{name: "shell substitution", args: []string{"$(reboot)"}, err: algorithm.ErrInvalidArg},
+		{name: "quote", args: []string{`--name="x"`}, err: algorithm.ErrInvalidArg},
+		{name: "newline", args: []string{"a\nb"}, err: algorithm.ErrInvalidArg},
+		{name: "too long", args: []string{strings.Repeat("a", 1001)}, err: algorithm.ErrInvalidArg},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := algorithm.ValidateArgs(tc.args)
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		err  error
+	}{
+		{name: "no variables"},
+		{name: "variables", env: map[string]string{"EPOCHS": "2", "model_name": "resnet-50"}},
+		{name: "empty value", env: map[string]string{"DEBUG": ""}},
+		{name: "invalid name", env: map[string]string{"1EPOCHS": "2"}, err: algorithm.ErrInvalidEnv},
+		{name: "name with equal sign", env: map[string]string{"A=B": "2"}, err: algorithm.ErrInvalidEnv},
+		{name: "agent variable", env: map[string]string{algorithm.ResultsDirEnv: "/"}, err: algorithm.ErrInvalidEnv},
+		{name: "loader variable", env: map[string]string{"LD_PRELOAD": "/tmp/x.so"}, err: algorithm.ErrInvalidEnv},
+		{name: "reserved variable", env: map[string]string{"path": "/tmp"}, err: algorithm.ErrInvalidEnv},
+		{name: "invalid value", env: map[string]string{"EPOCHS": "`reboot`"}, err: algorithm.ErrInvalidEnv},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := algorithm.ValidateEnv(tc.env)
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	assert.Equal(t, []string{"A=1", "B=", "C=x=y"}, algorithm.Environ(map[string]string{"C": "x=y", "A": "1", "B": ""}))
+	assert.Empty(t, algorithm.Environ(nil))
+}
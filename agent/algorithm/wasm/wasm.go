@@ -3,72 +3,221 @@
 package wasm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os/exec"
-
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
 	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events"
 )
 
-const wasmRuntime = "wasmedge"
+const (
+	// Guest paths of the directories exposed to the module. The results directory
+	// is the guest root so modules written for the wasmedge runtime keep writing
+	// their results to the current directory.
+	guestResultsDir  = "/"
+	guestDatasetsDir = "/datasets"
+	guestWorkDir     = "/work"
+	guestTmpDir      = "/tmp"
+	guestSecretsDir  = "/secrets"
+
+	pageSize     = 64 << 10
+	maxPages     = 1 << 16
+	pagesPerMiB  = (1 << 20) / pageSize
+	algoFileName = "algo"
+
+	// initializeFunction initializes WASI reactor modules before their entrypoint runs.
+	initializeFunction = "_initialize"
+)
+
+var (
+	// ErrTimeout indicates the module ran past its execution time limit.
+	ErrTimeout = errors.New("algorithm exceeded its execution time limit")
 
-var mapDirOption = []string{"--dir", ".:" + algorithm.ResultsDir}
+	_ algorithm.Algorithm = (*wasm)(nil)
+)
 
-var _ algorithm.Algorithm = (*wasm)(nil)
+// Limits bound the resources a module can use, zero values keep the runtime defaults.
+type Limits struct {
+	// MaxMemoryMB is the size the module memory can grow to.
+	MaxMemoryMB uint32
+	// Timeout is the execution time budget of the module.
+	Timeout time.Duration
+}
 
+// memoryLimitPages returns the memory limit in wasm pages, 0 when unset.
+func (l Limits) memoryLimitPages() uint32 {
+	pages := uint64(l.MaxMemoryMB) * pagesPerMiB
+	if pages > maxPages {
+		return maxPages
+	}
+
+	return uint32(pages)
+}
+
+// wasm runs WASI modules in the agent process with the wazero runtime, so they
+// are sandboxed without cgo or an external runtime in the guest image.
 type wasm struct {
-	algoFile string
-	stderr   io.Writer
-	stdout   io.Writer
-	args     []string
-	cmd      *exec.Cmd
+	algoFile   string
+	stderr     io.Writer
+	stdout     io.Writer
+	entrypoint string
+	args       []string
+	limits     Limits
+	sandbox    algorithm.Sandbox
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped bool
 }
 
-func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, args []string, algoFile, cmpID string) algorithm.Algorithm {
+// NewAlgorithm returns a wasm algorithm, which runs the exported entrypoint
+// function instead of _start when it is not empty. The module only sees the
+// directories of the sandbox.
+func NewAlgorithm(logger *slog.Logger, eventsSvc events.Service, entrypoint string, args []string, algoFile, cmpID string, sandbox algorithm.Sandbox, limits Limits, output logging.Output) algorithm.Algorithm {
+	stdout, stderr := logging.Streams(output)
+
 	return &wasm{
-		algoFile: algoFile,
-		stderr:   &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID},
-		stdout:   &logging.Stdout{Logger: logger},
-		args:     args,
+		algoFile:   algoFile,
+		stderr:     &logging.Stderr{Logger: logger, EventSvc: eventsSvc, CmpID: cmpID, Stream: stderr},
+		stdout:     &logging.Stdout{Logger: logger, Stream: stdout},
+		entrypoint: entrypoint,
+		args:       args,
+		limits:     limits,
+		sandbox:    sandbox,
 	}
 }
 
 func (w *wasm) Run() error {
-	args := append(mapDirOption, w.algoFile)
-	args = append(args, w.args...)
-	w.cmd = exec.Command(wasmRuntime, args...)
-	w.cmd.Stderr = w.stderr
-	w.cmd.Stdout = w.stdout
+	defer logging.Flush(w.stdout, w.stderr)
 
-	if err := w.cmd.Start(); err != nil {
-		return fmt.Errorf("error starting algorithm: %v", err)
+	ctx, err := w.start()
+	if err != nil {
+		return err
 	}
+	defer w.cancel()
 
-	if err := w.cmd.Wait(); err != nil {
-		return fmt.Errorf("algorithm execution error: %v", err)
+	code, err := os.ReadFile(w.algoFile)
+	if err != nil {
+		return fmt.Errorf("error reading algorithm: %v", err)
 	}
 
-	return nil
+	cfg := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if pages := w.limits.memoryLimitPages(); pages > 0 {
+		cfg = cfg.WithMemoryLimitPages(pages)
+	}
+
+	rt := wazero.NewRuntimeWithConfig(ctx, cfg)
+	defer rt.Close(context.Background())
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return fmt.Errorf("error compiling algorithm: %v", err)
+	}
+
+	fsCfg := wazero.NewFSConfig().
+		WithDirMount(w.sandbox.Results(), guestResultsDir).
+		WithReadOnlyDirMount(w.sandbox.Datasets(), guestDatasetsDir).
+		WithDirMount(w.sandbox.Work(), guestWorkDir).
+		WithDirMount(w.sandbox.Tmp(), guestTmpDir)
+	// Secret files are read-only, and only mounted once they were provisioned.
+	if w.sandbox.HasSecretFiles() {
+		fsCfg = fsCfg.WithReadOnlyDirMount(w.sandbox.SecretFiles(), guestSecretsDir)
+	}
+
+	modCfg := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{algoFileName}, w.args...)...).
+		WithEnv(algorithm.DatasetsDirEnv, guestDatasetsDir).
+		WithEnv(algorithm.ResultsDirEnv, guestResultsDir).
+		WithEnv(algorithm.WorkDirEnv, guestWorkDir).
+		WithEnv(algorithm.TmpDirEnv, guestTmpDir).
+		WithStdout(w.stdout).
+		WithStderr(w.stderr).
+		WithFSConfig(fsCfg).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep()
+
+	if w.sandbox.HasSecretFiles() {
+		modCfg = modCfg.WithEnv(algorithm.SecretsDirEnv, guestSecretsDir)
+	}
+	for _, kv := range slices.Concat(w.sandbox.Env, w.sandbox.SecretsEnviron()) {
+		name, value, _ := strings.Cut(kv, "=")
+		modCfg = modCfg.WithEnv(name, value)
+	}
+
+	if w.entrypoint != "" {
+		if _, ok := compiled.ExportedFunctions()[w.entrypoint]; !ok {
+			return fmt.Errorf("error running algorithm: module does not export %s", w.entrypoint)
+		}
+		modCfg = modCfg.WithStartFunctions(initializeFunction, w.entrypoint)
+	}
+
+	mod, err := rt.InstantiateModule(ctx, compiled, modCfg)
+	if mod != nil {
+		defer mod.Close(context.Background())
+	}
+
+	return w.result(ctx, err)
 }
 
-func (w *wasm) Stop() error {
-	if w.cmd == nil {
-		return nil
+// start creates the execution context of the module, bounded by its time limit.
+func (w *wasm) start() (context.Context, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped {
+		return nil, fmt.Errorf("algorithm execution error: algorithm stopped")
 	}
 
-	if w.cmd.ProcessState != nil && w.cmd.ProcessState.Exited() {
-		return nil
+	ctx := context.Background()
+	if w.limits.Timeout > 0 {
+		ctx, w.cancel = context.WithTimeout(ctx, w.limits.Timeout)
+	} else {
+		ctx, w.cancel = context.WithCancel(ctx)
 	}
 
-	if w.cmd.Process == nil {
+	return ctx, nil
+}
+
+func (w *wasm) result(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
 		return nil
 	}
 
-	if err := w.cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("error stopping algorithm: %v", err)
+	if err != nil {
+		return fmt.Errorf("algorithm execution error: %v", err)
+	}
+
+	return nil
+}
+
+func (w *wasm) Stop() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	if w.cancel != nil {
+		w.cancel()
 	}
 
 	return nil
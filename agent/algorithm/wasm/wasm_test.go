@@ -3,22 +3,122 @@
 package wasm
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"log/slog"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
 )
 
+const (
+	fdStdout = 1
+	fdStderr = 2
+)
+
+// Function bodies of the _start function of the test modules.
+var (
+	emptyBody = []byte{}
+	// loop forever
+	loopBody = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b}
+	// if memory.grow(100) == -1 { unreachable }
+	growBody = []byte{0x41, 0xe4, 0x00, 0x40, 0x00, 0x41, 0x7f, 0x46, 0x04, 0x40, 0x00, 0x0b}
+	// proc_exit(3)
+	exitBody = []byte{0x41, 0x03, 0x10, 0x01}
+)
+
+// writeBody returns a _start body writing the module data to the file descriptor.
+func writeBody(fd byte) []byte {
+	// drop(fd_write(fd, iovs=0, iovs_len=1, nwritten=8))
+	return []byte{0x41, fd, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a}
+}
+
+// module assembles a WASI command whose _start function runs body. It imports
+// fd_write and proc_exit, and holds an iovec pointing to out in its memory.
+func module(body []byte, out string) []byte {
+	return exportingModule("_start", body, out)
+}
+
+// exportingModule assembles a module like module whose function runs body
+// and is exported as export.
+func exportingModule(export string, body []byte, out string) []byte {
+	section := func(id byte, content ...[]byte) []byte {
+		c := bytes.Join(content, nil)
+		return append(append([]byte{id}, uleb(uint32(len(c)))...), c...)
+	}
+	name := func(s string) []byte {
+		return append(uleb(uint32(len(s))), s...)
+	}
+	wasi := name("wasi_snapshot_preview1")
+
+	data := make([]byte, 16, 16+len(out))
+	binary.LittleEndian.PutUint32(data[0:], 16)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(out)))
+	data = append(data, out...)
+
+	code := append([]byte{0x00}, body...)
+	code = append(code, 0x0b)
+
+	return bytes.Join([][]byte{
+		{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		section(0x01, []byte{0x03,
+			0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, // (i32, i32, i32, i32) -> i32
+			0x60, 0x01, 0x7f, 0x00, // (i32) -> ()
+			0x60, 0x00, 0x00, // () -> ()
+		}),
+		section(0x02, []byte{0x02}, wasi, name("fd_write"), []byte{0x00, 0x00}, wasi, name("proc_exit"), []byte{0x00, 0x01}),
+		section(0x03, []byte{0x01, 0x02}),
+		section(0x05, []byte{0x01, 0x00, 0x01}),
+		section(0x07, []byte{0x02}, name(export), []byte{0x00, 0x02}, name("memory"), []byte{0x02, 0x00}),
+		section(0x0a, []byte{0x01}, uleb(uint32(len(code))), code),
+		section(0x0b, []byte{0x01, 0x00, 0x41, 0x00, 0x0b}, uleb(uint32(len(data))), data),
+	}, nil)
+}
+
+func uleb(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// writeModule writes the module to the algorithm directory of a new sandbox.
+func writeModule(t *testing.T, code []byte) (string, algorithm.Sandbox) {
+	sandbox := algorithm.Sandbox{Root: t.TempDir()}
+	require.NoError(t, sandbox.Create())
+	require.NoError(t, os.Mkdir(sandbox.Results(), 0o755))
+	require.NoError(t, os.Mkdir(sandbox.Datasets(), 0o755))
+	require.NoError(t, os.Mkdir(sandbox.Work(), 0o755))
+
+	path := filepath.Join(sandbox.Algo(), "algo.wasm")
+	require.NoError(t, os.WriteFile(path, code, 0o644))
+
+	return path, sandbox
+}
+
 func TestNewAlgorithm(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	eventsSvc := new(mocks.Service)
 	algoFile := "test.wasm"
 	args := []string{"arg1", "arg2"}
+	limits := Limits{MaxMemoryMB: 64, Timeout: time.Minute}
 
-	algo := NewAlgorithm(logger, eventsSvc, args, algoFile, "")
+	algo := NewAlgorithm(logger, eventsSvc, "", args, algoFile, "", algorithm.Sandbox{}, limits, nil)
 
 	w, ok := algo.(*wasm)
 	if !ok {
@@ -33,6 +133,10 @@ func TestNewAlgorithm(t *testing.T) {
 		t.Errorf("Expected %d args, got %d", len(args), len(w.args))
 	}
 
+	if w.limits != limits {
+		t.Errorf("Expected limits to be %v, got %v", limits, w.limits)
+	}
+
 	_, ok = w.stderr.(*logging.Stderr)
 	if !ok {
 		t.Errorf("Expected stderr to be *algorithm.Stderr")
@@ -44,46 +148,120 @@ func TestNewAlgorithm(t *testing.T) {
 	}
 }
 
-func TestRunError(t *testing.T) {
-	// Mock exec.Command to return an error
-	execCommand = mockExecCommandError
-	defer func() { execCommand = exec.Command }()
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name       string
+		code       []byte
+		entrypoint string
+		limits     Limits
+		stdout     string
+		stderr     string
+		err        string
+	}{
+		{
+			name: "empty module",
+			code: module(emptyBody, ""),
+		},
+		{
+			name:   "standard output",
+			code:   module(writeBody(fdStdout), "hello from wasm\n"),
+			stdout: "hello from wasm",
+		},
+		{
+			name:   "standard error",
+			code:   module(writeBody(fdStderr), "wasm warning\n"),
+			stderr: "wasm warning",
+		},
+		{
+			name: "non-zero exit code",
+			code: module(exitBody, ""),
+			err:  "algorithm execution error: module closed with exit_code(3)",
+		},
+		{
+			name:   "memory limit",
+			code:   module(growBody, ""),
+			limits: Limits{MaxMemoryMB: 1},
+			err:    "unreachable",
+		},
+		{
+			name: "memory within default limit",
+			code: module(growBody, ""),
+		},
+		{
+			name:   "timeout",
+			code:   module(loopBody, ""),
+			limits: Limits{Timeout: 100 * time.Millisecond},
+			err:    ErrTimeout.Error(),
+		},
+		{
+			name:       "entrypoint",
+			code:       exportingModule("train", writeBody(fdStdout), "training started\n"),
+			entrypoint: "train",
+			stdout:     "training started",
+		},
+		{
+			name:       "entrypoint not exported",
+			code:       module(writeBody(fdStdout), "hello from wasm\n"),
+			entrypoint: "train",
+			err:        "module does not export train",
+		},
+		{
+			name: "invalid module",
+			code: []byte("not wasm"),
+			err:  "error compiling algorithm",
+		},
+	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	eventsSvc := new(mocks.Service)
-	algoFile := "test.wasm"
-	args := []string{"arg1", "arg2"}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			algoFile, sandbox := writeModule(t, tc.code)
 
-	w := NewAlgorithm(logger, eventsSvc, args, algoFile, "").(*wasm)
+			var stdout bytes.Buffer
+			eventsSvc := new(mocks.Service)
+			eventsSvc.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	err := w.Run()
-	if err == nil {
-		t.Errorf("Run() should have returned an error")
+			logger := slog.New(slog.NewTextHandler(&stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			w := NewAlgorithm(logger, eventsSvc, tc.entrypoint, nil, algoFile, "cmp", sandbox, tc.limits, nil)
+
+			err := w.Run()
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.stdout != "" {
+				assert.Contains(t, stdout.String(), "level=DEBUG msg=\""+tc.stdout+"\"")
+			}
+			if tc.stderr != "" {
+				assert.Contains(t, stdout.String(), "level=ERROR msg=\""+tc.stderr+"\"")
+				eventsSvc.AssertCalled(t, "SendEvent", "cmp", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
 	}
 }
 
-func mockExecCommand(command string, args ...string) *exec.Cmd {
-	cs := []string{"-test.run=TestHelperProcess", "--", command}
-	cs = append(cs, args...)
-	cmd := exec.Command(os.Args[0], cs...)
-	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
-	return cmd
-}
+func TestStop(t *testing.T) {
+	algoFile, sandbox := writeModule(t, module(loopBody, ""))
 
-func mockExecCommandError(command string, args ...string) *exec.Cmd {
-	cmd := mockExecCommand(command, args...)
-	cmd.Env = append(cmd.Env, "GO_WANT_HELPER_PROCESS_ERROR=1")
-	return cmd
-}
+	w := NewAlgorithm(slog.Default(), new(mocks.Service), "", nil, algoFile, "", sandbox, Limits{}, nil)
 
-func TestHelperProcess(t *testing.T) {
-	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
-		return
-	}
-	if os.Getenv("GO_WANT_HELPER_PROCESS_ERROR") == "1" {
-		os.Exit(1)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, w.Stop())
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrTimeout))
+	case <-time.After(5 * time.Second):
+		t.Fatal("algorithm did not stop")
 	}
-	os.Exit(0)
-}
 
-var execCommand = exec.Command
+	err := w.Run()
+	assert.True(t, strings.Contains(err.Error(), "algorithm stopped"))
+}
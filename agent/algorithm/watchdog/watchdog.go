@@ -0,0 +1,231 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watchdog detects algorithm processes that are alive but stuck. It
+// samples the CPU time and the bytes written by the processes the agent
+// started from procfs, and reports them once both stopped changing for the
+// configured idle period.
+package watchdog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStackLines bounds the kernel stack frames reported per process.
+const maxStackLines = 32
+
+// procDir is the procfs mount the processes are sampled from.
+var procDir = "/proc"
+
+// Config is the idle period after which processes are reported as hung.
+type Config struct {
+	// Idle is how long the processes may use no CPU and write no output.
+	Idle time.Duration
+	// Interval is the sampling interval, a quarter of Idle if zero.
+	Interval time.Duration
+}
+
+// Process is the state of a process reported as hung, in the spirit of what
+// ps, /proc/<pid>/stack and strace -p would show.
+type Process struct {
+	PID     int    `json:"pid"`
+	PPID    int    `json:"ppid"`
+	Command string `json:"command"`
+	// State is the scheduler state, e.g. S for sleeping or D for uninterruptible wait.
+	State string `json:"state"`
+	// WaitChannel is the kernel function the process is blocked in.
+	WaitChannel string `json:"wchan,omitempty"`
+	// Syscall is the system call the process is blocked in, with its arguments.
+	Syscall string `json:"syscall,omitempty"`
+	// Stack is the kernel stack of the process, only readable by root.
+	Stack []string `json:"stack,omitempty"`
+}
+
+type process struct {
+	pid, ppid int
+	command   string
+	state     string
+	cpu       uint64
+}
+
+// Watch samples the descendants of the root process until ctx is done and
+// calls hung with their state once they used no CPU and wrote nothing for
+// cfg.Idle. It reports again only after the processes were active in between.
+// Processes are not sampled while root has no descendants, e.g. while an
+// algorithm runs inside root or in a container.
+func Watch(ctx context.Context, root int, cfg Config, hung func(idle time.Duration, procs []Process)) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = cfg.Idle / 4
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := total(descendants(root))
+	active := time.Now()
+	reported := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		procs := descendants(root)
+		cur, ok := total(procs)
+		if !ok || cur != last {
+			last = cur
+			active = time.Now()
+			reported = false
+			continue
+		}
+
+		if idle := time.Since(active); !reported && idle >= cfg.Idle {
+			reported = true
+			hung(idle, diagnose(procs))
+		}
+	}
+}
+
+// activity is the CPU time and the bytes written by a set of processes.
+type activity struct {
+	cpu, written uint64
+}
+
+func total(procs []process) (activity, bool) {
+	var a activity
+	for _, p := range procs {
+		a.cpu += p.cpu
+		a.written += written(p.pid)
+	}
+
+	return a, len(procs) > 0
+}
+
+// descendants returns the processes started by root, directly or not.
+func descendants(root int) []process {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil
+	}
+
+	children := make(map[int][]process)
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+
+		p, err := readStat(pid)
+		if err != nil {
+			continue
+		}
+		children[p.ppid] = append(children[p.ppid], p)
+	}
+
+	var procs []process
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+
+		for _, c := range children[pid] {
+			procs = append(procs, c)
+			queue = append(queue, c.pid)
+		}
+	}
+
+	return procs
+}
+
+// readStat parses /proc/<pid>/stat, whose command is in parentheses and may
+// itself contain spaces and parentheses.
+func readStat(pid int) (process, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return process{}, err
+	}
+
+	open, end := bytes.IndexByte(data, '('), bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return process{}, os.ErrInvalid
+	}
+
+	// Fields from the state onwards: state, ppid, ... utime (14), stime, cutime, cstime.
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 15 {
+		return process{}, os.ErrInvalid
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return process{}, err
+	}
+
+	p := process{pid: pid, ppid: ppid, command: string(data[open+1 : end]), state: fields[0]}
+	for _, f := range fields[11:15] {
+		ticks, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return process{}, err
+		}
+		p.cpu += ticks
+	}
+
+	return p, nil
+}
+
+// written returns the bytes the process wrote to files, pipes and sockets.
+func written(pid int) uint64 {
+	f, err := os.Open(filepath.Join(procDir, strconv.Itoa(pid), "io"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v, ok := strings.CutPrefix(s.Text(), "wchar:"); ok {
+			n, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			return n
+		}
+	}
+
+	return 0
+}
+
+func diagnose(procs []process) []Process {
+	diags := make([]Process, 0, len(procs))
+	for _, p := range procs {
+		dir := filepath.Join(procDir, strconv.Itoa(p.pid))
+
+		d := Process{PID: p.pid, PPID: p.ppid, Command: p.command, State: p.state}
+		if wchan, err := os.ReadFile(filepath.Join(dir, "wchan")); err == nil && string(wchan) != "0" {
+			d.WaitChannel = strings.TrimSpace(string(wchan))
+		}
+		if syscall, err := os.ReadFile(filepath.Join(dir, "syscall")); err == nil {
+			d.Syscall = strings.TrimSpace(string(syscall))
+		}
+		if stack, err := os.ReadFile(filepath.Join(dir, "stack")); err == nil {
+			lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+			if len(lines) > maxStackLines {
+				lines = lines[:maxStackLines]
+			}
+			if len(lines) > 0 && lines[0] != "" {
+				d.Stack = lines
+			}
+		}
+
+		diags = append(diags, d)
+	}
+
+	return diags
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package watchdog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	cases := []struct {
+		desc string
+		cmd  []string
+		hung bool
+	}{
+		{
+			desc: "idle process",
+			cmd:  []string{"sleep", "30"},
+			hung: true,
+		},
+		{
+			desc: "busy process",
+			cmd:  []string{"sh", "-c", "while :; do :; done"},
+		},
+		{
+			desc: "process writing output",
+			cmd:  []string{"sh", "-c", "while :; do echo out; sleep 0.01; done"},
+		},
+		{
+			desc: "no process",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var pid int
+			if len(tc.cmd) > 0 {
+				cmd := exec.Command(tc.cmd[0], tc.cmd[1:]...)
+				require.NoError(t, cmd.Start())
+				t.Cleanup(func() {
+					_ = cmd.Process.Kill()
+					_ = cmd.Wait()
+				})
+				pid = cmd.Process.Pid
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			reports := make(chan []Process, 1)
+			Watch(ctx, os.Getpid(), Config{Idle: 300 * time.Millisecond, Interval: 50 * time.Millisecond}, func(idle time.Duration, procs []Process) {
+				assert.GreaterOrEqual(t, idle, 300*time.Millisecond)
+				reports <- procs
+			})
+
+			if !tc.hung {
+				assert.Empty(t, reports)
+				return
+			}
+
+			require.Len(t, reports, 1)
+			procs := <-reports
+			require.Len(t, procs, 1)
+			assert.Equal(t, pid, procs[0].PID)
+			assert.Equal(t, os.Getpid(), procs[0].PPID)
+			assert.Equal(t, "sleep", procs[0].Command)
+			assert.Equal(t, "S", procs[0].State)
+		})
+	}
+}
+
+func TestReadStat(t *testing.T) {
+	procDir = t.TempDir()
+	t.Cleanup(func() { procDir = "/proc" })
+
+	cases := []struct {
+		desc string
+		stat string
+		proc process
+		err  bool
+	}{
+		{
+			desc: "command with spaces and parentheses",
+			stat: "42 (my (algo) x) R 7 42 42 0 -1 4194304 100 0 0 0 11 5 2 1 20 0 1 0 100 0 0",
+			proc: process{pid: 42, ppid: 7, command: "my (algo) x", state: "R", cpu: 19},
+		},
+		{
+			desc: "truncated stat",
+			stat: "42 (algo) R 7 42",
+			err:  true,
+		},
+		{
+			desc: "missing command",
+			stat: "42 algo R 7",
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.NoError(t, os.MkdirAll(filepath.Join(procDir, "42"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(procDir, "42", "stat"), []byte(tc.stat), 0o644))
+
+			proc, err := readStat(42)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.proc, proc)
+		})
+	}
+}
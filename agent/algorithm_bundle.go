@@ -0,0 +1,111 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+// bundleDirName is the directory of the algorithm directory a bundle is extracted to.
+const bundleDirName = "bundle"
+
+// ErrInvalidAlgorithmBundle indicates an algorithm bundle that is not a tar
+// archive, cannot be safely extracted within the manifest limits, or lacks its
+// entrypoint, or a manifest bundle whose entrypoint is not inside the bundle.
+var ErrInvalidAlgorithmBundle = errors.New("invalid algorithm bundle")
+
+// validateAlgorithmBundle checks the entrypoint of the manifest bundle is a path inside the bundle.
+func validateAlgorithmBundle(cmp Computation) error {
+	bundle := cmp.Algorithm.Bundle
+	if bundle == nil {
+		return nil
+	}
+
+	if _, err := bundleEntrypoint(bundle.Entrypoint); err != nil {
+		return errors.Wrap(ErrInvalidAlgorithmBundle, err)
+	}
+
+	return nil
+}
+
+// bundleEntrypoint returns the entrypoint as a path relative to the bundle
+// directory, or an error if it is empty or escapes it.
+func bundleEntrypoint(entrypoint string) (string, error) {
+	path := filepath.FromSlash(entrypoint)
+	if entrypoint == "" || strings.ContainsRune(entrypoint, '\\') || !filepath.IsLocal(path) {
+		return "", fmt.Errorf("entrypoint %q is not a path inside the bundle", entrypoint)
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// extractBundle extracts the algorithm bundle into the bundle directory of the
+// algorithm directory and returns the path of its entrypoint. The bundle is
+// verified before any of it is written, and nothing is left behind when it is
+// rejected. It must be called with the service mutex held.
+func (as *agentService) extractBundle(data []byte, algoType algorithm.AlgorithType) (string, error) {
+	bundle := as.computation.Algorithm.Bundle
+
+	// Bundles run their entrypoint as a process, wasm modules and docker images are single files.
+	if algoType != algorithm.AlgoTypeBin && algoType != algorithm.AlgoTypePython {
+		return "", errors.Wrap(ErrInvalidAlgorithmBundle, fmt.Errorf("%s algorithms cannot be bundled", algoType))
+	}
+
+	switch internal.DetectArchive(data) {
+	case internal.ArchiveTar, internal.ArchiveTarGzip, internal.ArchiveTarZstd:
+	default:
+		return "", errors.Wrap(ErrInvalidAlgorithmBundle, fmt.Errorf("not a tar, tar.gz or tar.zst archive"))
+	}
+
+	entrypoint, err := bundleEntrypoint(bundle.Entrypoint)
+	if err != nil {
+		return "", errors.Wrap(ErrInvalidAlgorithmBundle, err)
+	}
+
+	limits := internal.ArchiveLimits{MaxSize: bundle.MaxSizeMB << 20, MaxFiles: bundle.MaxFiles}
+	if _, err := internal.ExtractArchive(data, "", limits); err != nil {
+		return "", errors.Wrap(ErrInvalidAlgorithmBundle, err)
+	}
+
+	// A bundle left behind by an upload that failed after its extraction is replaced.
+	dir := filepath.Join(as.sandbox.Algo(), bundleDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("error removing bundle directory: %w", err)
+	}
+	if err := os.Mkdir(dir, sandboxDirPermission); err != nil {
+		return "", fmt.Errorf("error creating bundle directory: %w", err)
+	}
+
+	info, err := internal.ExtractArchive(data, dir, limits)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("error extracting bundle: %w", err)
+	}
+
+	path := filepath.Join(dir, entrypoint)
+	if fi, err := os.Lstat(path); err != nil || !fi.Mode().IsRegular() {
+		os.RemoveAll(dir)
+		return "", errors.Wrap(ErrInvalidAlgorithmBundle, fmt.Errorf("entrypoint %s is not a file of the bundle", bundle.Entrypoint))
+	}
+
+	if algoType == algorithm.AlgoTypeBin {
+		if err := os.Chmod(path, algoFilePermission); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("error changing file permissions: %v", err)
+		}
+	}
+
+	contentHash := hex.EncodeToString(info.ContentHash[:])
+	as.lineage.algorithmExtracted(contentHash)
+	as.logger.Info("algorithm bundle extracted", "computation", as.computation.ID, "entrypoint", bundle.Entrypoint, "format", info.Format, "files", info.Files, "size", info.Size)
+
+	return path, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestValidateAlgorithmBundle(t *testing.T) {
+	cases := []struct {
+		desc   string
+		bundle *AlgorithmBundle
+		err    error
+	}{
+		{desc: "no bundle"},
+		{desc: "nested entrypoint", bundle: &AlgorithmBundle{Entrypoint: "src/train.py"}},
+		{desc: "no entrypoint", bundle: &AlgorithmBundle{}, err: ErrInvalidAlgorithmBundle},
+		{desc: "absolute entrypoint", bundle: &AlgorithmBundle{Entrypoint: "/bin/sh"}, err: ErrInvalidAlgorithmBundle},
+		{desc: "entrypoint outside of the bundle", bundle: &AlgorithmBundle{Entrypoint: "../algorithm"}, err: ErrInvalidAlgorithmBundle},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateAlgorithmBundle(Computation{Algorithm: Algorithm{Bundle: tc.bundle}})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestAlgoBundle(t *testing.T) {
+	files := map[string]string{
+		"src/train.py":     "print('training')",
+		"model/weights.pt": "weights",
+		"requirements.txt": "numpy",
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	cases := []struct {
+		desc     string
+		data     []byte
+		algoType algorithm.AlgorithType
+		bundle   AlgorithmBundle
+		err      error
+	}{
+		{
+			desc:     "python bundle",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypePython,
+			bundle:   AlgorithmBundle{Entrypoint: "src/train.py", MaxSizeMB: 1, MaxFiles: 3},
+		},
+		{
+			desc:     "binary bundle",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypeBin,
+			bundle:   AlgorithmBundle{Entrypoint: "src/train.py"},
+		},
+		{
+			desc:     "missing entrypoint",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypePython,
+			bundle:   AlgorithmBundle{Entrypoint: "train.py"},
+			err:      ErrInvalidAlgorithmBundle,
+		},
+		{
+			desc:     "directory entrypoint",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypePython,
+			bundle:   AlgorithmBundle{Entrypoint: "src"},
+			err:      ErrInvalidAlgorithmBundle,
+		},
+		{
+			desc:     "too many files",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypePython,
+			bundle:   AlgorithmBundle{Entrypoint: "src/train.py", MaxFiles: 2},
+			err:      ErrInvalidAlgorithmBundle,
+		},
+		{
+			desc:     "not a tar archive",
+			data:     []byte("print('training')"),
+			algoType: algorithm.AlgoTypePython,
+			bundle:   AlgorithmBundle{Entrypoint: "src/train.py"},
+			err:      ErrInvalidAlgorithmBundle,
+		},
+		{
+			desc:     "wasm bundle",
+			data:     buf.Bytes(),
+			algoType: algorithm.AlgoTypeWasm,
+			bundle:   AlgorithmBundle{Entrypoint: "src/train.py"},
+			err:      ErrInvalidAlgorithmBundle,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sandbox := algorithm.Sandbox{Root: t.TempDir()}
+			require.NoError(t, sandbox.Create())
+
+			cmp := Computation{
+				ID:        "1",
+				Algorithm: Algorithm{Hash: sha3.Sum256(tc.data), Bundle: &tc.bundle},
+			}
+
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(ReceivingAlgorithm)
+			sm.On("SendEvent", AlgorithmReceived).Return()
+
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				eventSvc:    new(mocks.Service),
+				computation: cmp,
+				sandbox:     sandbox,
+				lineage:     newLineage(cmp),
+			}
+
+			err := svc.Algo(context.Background(), Algorithm{Algorithm: tc.data, Spec: algorithm.Spec{Type: tc.algoType}})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+
+			dir := filepath.Join(sandbox.Algo(), bundleDirName)
+			if tc.err != nil {
+				assert.Nil(t, svc.algorithm)
+				assert.NoDirExists(t, dir, "rejected bundles leave nothing behind")
+				return
+			}
+
+			require.NotNil(t, svc.algorithm)
+			assert.Equal(t, filepath.Join(dir, "src", "train.py"), svc.algoSpec.Path)
+			weights, err := os.ReadFile(filepath.Join(dir, "model", "weights.pt"))
+			require.NoError(t, err)
+			assert.Equal(t, "weights", string(weights))
+			assert.NotEmpty(t, svc.lineage.Algorithm.ContentHash)
+
+			info, err := os.Stat(svc.algoSpec.Path)
+			require.NoError(t, err)
+			if tc.algoType == algorithm.AlgoTypeBin {
+				assert.Equal(t, os.FileMode(algoFilePermission), info.Mode().Perm())
+			} else {
+				assert.Equal(t, filepath.Join(dir, requirementsFileName), svc.algoSpec.Requirements, "bundle requirements are installed")
+			}
+		})
+	}
+}
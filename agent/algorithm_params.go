@@ -0,0 +1,57 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+// validateAlgorithmParams checks that the declared algorithm type is known and
+// that the arguments and environment variables of the algorithm and its steps
+// match the allowlist.
+func validateAlgorithmParams(cmp Computation) error {
+	if t := cmp.Algorithm.Type; t != "" && !slices.Contains(algorithm.Types, t) {
+		return errors.Wrap(ErrInvalidAlgorithmParams, fmt.Errorf("unknown algorithm type %q", t))
+	}
+
+	if err := algorithm.ValidateArgs(cmp.Algorithm.Args); err != nil {
+		return errors.Wrap(ErrInvalidAlgorithmParams, err)
+	}
+
+	if err := algorithm.ValidateEnv(cmp.Algorithm.Env); err != nil {
+		return errors.Wrap(ErrInvalidAlgorithmParams, err)
+	}
+
+	for _, step := range cmp.Algorithm.Steps {
+		if err := algorithm.ValidateArgs(step.Args); err != nil {
+			return errors.Wrap(ErrInvalidAlgorithmParams, fmt.Errorf("step %s: %w", step.Name, err))
+		}
+	}
+
+	return nil
+}
+
+// runParams returns the details of the run started event, which hold the
+// arguments and environment variables the algorithm runs with for
+// auditability. Steps run with their own arguments after the manifest ones.
+func (as *agentService) runParams() json.RawMessage {
+	args := as.computation.Algorithm.Args
+	if len(as.computation.Algorithm.Steps) == 0 {
+		args = slices.Concat(args, as.algoSpec.Args)
+	}
+	if len(args) == 0 && len(as.computation.Algorithm.Env) == 0 {
+		return json.RawMessage{}
+	}
+
+	details, _ := json.Marshal(struct {
+		Args []string          `json:"args,omitempty"`
+		Env  map[string]string `json:"env,omitempty"`
+	}{args, as.computation.Algorithm.Env})
+
+	return details
+}
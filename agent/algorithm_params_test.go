@@ -0,0 +1,91 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+func TestValidateAlgorithmParams(t *testing.T) {
+	cases := []struct {
+		desc string
+		cmp  Computation
+		err  error
+	}{
+		{
+			desc: "no parameters",
+			cmp:  Computation{},
+		},
+		{
+			desc: "allowed parameters",
+			cmp: Computation{Algorithm: Algorithm{
+				Args:  []string{"--epochs", "2"},
+				Env:   map[string]string{"MODEL": "resnet-50"},
+				Steps: []Step{{Name: "train", Args: []string{"--lr=0.01"}}},
+			}},
+		},
+		{
+			desc: "invalid argument",
+			cmp:  Computation{Algorithm: Algorithm{Args: []string{"$(id)"}}},
+			err:  algorithm.ErrInvalidArg,
+		},
+		{
+			desc: "reserved environment variable",
+			cmp:  Computation{Algorithm: Algorithm{Env: map[string]string{"LD_PRELOAD": "/tmp/x.so"}}},
+			err:  algorithm.ErrInvalidEnv,
+		},
+		{
+			desc: "invalid step argument",
+			cmp:  Computation{Algorithm: Algorithm{Steps: []Step{{Name: "train", Args: []string{"a|b"}}}}},
+			err:  algorithm.ErrInvalidArg,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateAlgorithmParams(tc.cmp)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Contains(err, ErrInvalidAlgorithmParams), "expected %v got %v", ErrInvalidAlgorithmParams, err)
+			assert.ErrorContains(t, err, tc.err.Error())
+		})
+	}
+}
+
+func TestRunParams(t *testing.T) {
+	cases := []struct {
+		desc    string
+		algo    Algorithm
+		spec    algorithmSpec
+		details string
+	}{
+		{
+			desc: "no parameters",
+		},
+		{
+			desc:    "manifest and uploaded arguments",
+			algo:    Algorithm{Args: []string{"--epochs", "2"}, Env: map[string]string{"MODEL": "resnet-50"}},
+			spec:    algorithmSpec{Args: []string{"--verbose"}},
+			details: `{"args":["--epochs","2","--verbose"],"env":{"MODEL":"resnet-50"}}`,
+		},
+		{
+			desc:    "steps use their own arguments",
+			algo:    Algorithm{Args: []string{"--epochs", "2"}, Steps: []Step{{Name: "train", Args: []string{"--lr=0.01"}}}},
+			spec:    algorithmSpec{Args: []string{"--verbose"}},
+			details: `{"args":["--epochs","2"]}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			as := &agentService{computation: Computation{Algorithm: tc.algo}, algoSpec: tc.spec}
+			assert.Equal(t, tc.details, string(as.runParams()))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+var algorithmTypes = map[AlgorithmType]algorithm.AlgorithType{
+	AlgorithmType_ALGORITHM_TYPE_BINARY: algorithm.AlgoTypeBin,
+	AlgorithmType_ALGORITHM_TYPE_PYTHON: algorithm.AlgoTypePython,
+	AlgorithmType_ALGORITHM_TYPE_WASM:   algorithm.AlgoTypeWasm,
+	AlgorithmType_ALGORITHM_TYPE_DOCKER: algorithm.AlgoTypeDocker,
+}
+
+// NewAlgorithmSpec returns the message of the algorithm spec.
+func NewAlgorithmSpec(spec algorithm.Spec) (*AlgorithmSpec, error) {
+	msg := &AlgorithmSpec{
+		Entrypoint:        spec.Entrypoint,
+		Args:              spec.Args,
+		Runtime:           spec.Runtime,
+		MinRuntimeVersion: spec.MinRuntimeVersion,
+	}
+
+	if spec.Type == "" {
+		return msg, nil
+	}
+	for t, name := range algorithmTypes {
+		if name == spec.Type {
+			msg.Type = t
+			return msg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown algorithm type %q", algorithm.ErrInvalidSpec, spec.Type)
+}
+
+// ToSpec returns the algorithm spec of the message. Algorithms of an
+// unspecified type run as binaries, and types unknown to the agent fail its
+// validation.
+func (x *AlgorithmSpec) ToSpec() algorithm.Spec {
+	if x == nil {
+		return algorithm.Spec{Type: algorithm.AlgoTypeBin}
+	}
+
+	spec := algorithm.Spec{
+		Type:              algorithmTypes[x.GetType()],
+		Entrypoint:        x.GetEntrypoint(),
+		Args:              x.GetArgs(),
+		Runtime:           x.GetRuntime(),
+		MinRuntimeVersion: x.GetMinRuntimeVersion(),
+	}
+	switch {
+	case x.GetType() == AlgorithmType_ALGORITHM_TYPE_UNSPECIFIED:
+		spec.Type = algorithm.AlgoTypeBin
+	case spec.Type == "":
+		spec.Type = algorithm.AlgorithType(x.GetType().String())
+	}
+
+	return spec
+}
@@ -0,0 +1,31 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+func TestAlgorithmSpec(t *testing.T) {
+	for _, algoType := range algorithm.Types {
+		spec := algorithm.Spec{Type: algoType, Args: []string{"--epochs", "2"}}
+		msg, err := NewAlgorithmSpec(spec)
+		require.NoError(t, err)
+		assert.Equal(t, spec, msg.ToSpec())
+	}
+
+	_, err := NewAlgorithmSpec(algorithm.Spec{Type: "java"})
+	assert.True(t, errors.Contains(err, algorithm.ErrInvalidSpec), "expected %v, got %v", algorithm.ErrInvalidSpec, err)
+
+	var empty *AlgorithmSpec
+	assert.Equal(t, algorithm.AlgoTypeBin, empty.ToSpec().Type, "algorithms without a spec run as binaries")
+	assert.Equal(t, algorithm.AlgoTypeBin, (&AlgorithmSpec{}).ToSpec().Type)
+
+	unknown := (&AlgorithmSpec{Type: AlgorithmType(42)}).ToSpec()
+	assert.Error(t, unknown.Validate(), "types unknown to the agent are rejected")
+}
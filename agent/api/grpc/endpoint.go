@@ -18,7 +18,7 @@ func algoEndpoint(svc agent.Service) endpoint.Endpoint {
 			return algoRes{}, err
 		}
 
-		algo := agent.Algorithm{Algorithm: req.Algorithm, Requirements: req.Requirements}
+		algo := agent.Algorithm{Algorithm: req.Algorithm, Requirements: req.Requirements, Spec: req.Spec}
 
 		err := svc.Algo(ctx, algo)
 		if err != nil {
@@ -44,7 +44,19 @@ func dataEndpoint(svc agent.Service) endpoint.Endpoint {
 			return dataRes{}, err
 		}
 
-		return dataRes{}, nil
+		var missing []string
+		for _, d := range svc.Datasets() {
+			if d.Received {
+				continue
+			}
+			if d.Filename != "" {
+				missing = append(missing, d.Filename)
+				continue
+			}
+			missing = append(missing, d.Hash)
+		}
+
+		return dataRes{MissingDatasets: missing}, nil
 	}
 }
 
@@ -64,6 +76,78 @@ func resultEndpoint(svc agent.Service) endpoint.Endpoint {
 	}
 }
 
+func stopEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(stopReq)
+
+		if err := req.validate(); err != nil {
+			return stopRes{}, err
+		}
+
+		if req.Shutdown {
+			if err := svc.Shutdown(ctx); err != nil {
+				return stopRes{}, err
+			}
+
+			return stopRes{}, nil
+		}
+
+		if err := svc.StopComputation(ctx); err != nil {
+			return stopRes{}, err
+		}
+
+		return stopRes{}, nil
+	}
+}
+
+func restoreEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(restoreReq)
+
+		if err := req.validate(); err != nil {
+			return restoreRes{}, err
+		}
+
+		if err := svc.Restore(ctx, req.Sequence); err != nil {
+			return restoreRes{}, err
+		}
+
+		return restoreRes{}, nil
+	}
+}
+
+func approveAttestationEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(approveAttestationReq)
+
+		if err := req.validate(); err != nil {
+			return approveAttestationRes{}, err
+		}
+
+		if err := svc.ApproveAttestation(ctx, req.Report, req.Signature); err != nil {
+			return approveAttestationRes{}, err
+		}
+
+		return approveAttestationRes{}, nil
+	}
+}
+
+func secretsEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(secretsReq)
+
+		if err := req.validate(); err != nil {
+			return secretsRes{}, err
+		}
+
+		if err := svc.Secrets(ctx, req.Secrets, req.Signature); err != nil {
+			return secretsRes{}, err
+		}
+
+		return secretsRes{}, nil
+	}
+}
+
 func attestationEndpoint(svc agent.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
 		req := request.(attestationReq)
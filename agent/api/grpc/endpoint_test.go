@@ -5,6 +5,7 @@ package grpc
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -60,11 +61,13 @@ func TestDataEndpoint(t *testing.T) {
 	tests := []struct {
 		name        string
 		req         dataReq
+		missing     []string
 		expectedErr bool
 	}{
 		{
-			name: "Success",
-			req:  dataReq{Dataset: []byte("dataset")},
+			name:    "Success",
+			req:     dataReq{Dataset: []byte("dataset")},
+			missing: []string{"provider-b.csv", "ab12"},
 		},
 		{
 			name:        "Validation Error",
@@ -84,12 +87,20 @@ func TestDataEndpoint(t *testing.T) {
 				svc.On("Data", context.Background(), agent.Dataset{Dataset: tt.req.Dataset}).Return(errors.New("")).Once()
 			} else {
 				svc.On("Data", context.Background(), agent.Dataset{Dataset: tt.req.Dataset}).Return(nil).Once()
+				svc.On("Datasets").Return([]agent.DatasetStatus{
+					{Index: 0, Filename: "provider-a.csv", Received: true},
+					{Index: 1, Filename: "provider-b.csv"},
+					{Index: 2, Hash: "ab12"},
+				}).Once()
 			}
 			endpoint := dataEndpoint(svc)
-			_, err := endpoint(context.Background(), tt.req)
+			res, err := endpoint(context.Background(), tt.req)
 			if (err != nil) != tt.expectedErr {
 				t.Errorf("dataEndpoint() error = %v, expectedErr %v", err, tt.expectedErr)
 			}
+			if err == nil && !slices.Equal(res.(dataRes).MissingDatasets, tt.missing) {
+				t.Errorf("dataEndpoint() missing datasets = %v, expected %v", res.(dataRes).MissingDatasets, tt.missing)
+			}
 		})
 	}
 }
@@ -0,0 +1,24 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+// HealthCheck returns the health check of the agent service, which is serving
+// while the computation waits for the algorithm, datasets or result consumers.
+// The service is not serving before a computation is received, while it runs
+// and once it failed.
+func HealthCheck(svc agent.Service) func() error {
+	return func() error {
+		switch state := svc.State(); state {
+		case agent.ReceivingAlgorithm.String(), agent.ReceivingData.String(), agent.ConsumingResults.String(), agent.Complete.String():
+			return nil
+		default:
+			return fmt.Errorf("computation is %s", state)
+		}
+	}
+}
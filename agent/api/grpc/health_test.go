@@ -0,0 +1,41 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/mocks"
+)
+
+func TestHealthCheck(t *testing.T) {
+	cases := []struct {
+		state   agent.AgentState
+		serving bool
+	}{
+		{state: agent.Idle},
+		{state: agent.ReceivingManifest},
+		{state: agent.ReceivingAlgorithm, serving: true},
+		{state: agent.ReceivingData, serving: true},
+		{state: agent.Running},
+		{state: agent.ConsumingResults, serving: true},
+		{state: agent.Complete, serving: true},
+		{state: agent.Failed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.state.String(), func(t *testing.T) {
+			svc := new(mocks.Service)
+			svc.On("State").Return(tc.state.String())
+
+			err := HealthCheck(svc)()
+			if tc.serving {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.state.String())
+		})
+	}
+}
@@ -4,6 +4,7 @@ package grpc
 
 import (
 	"context"
+	"strings"
 
 	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/agent/auth"
@@ -12,6 +13,33 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// publicRole marks agent methods that can be called without a manifest role.
+const publicRole auth.UserRole = ""
+
+var agentServicePrefix = "/" + agent.AgentService_ServiceDesc.ServiceName + "/"
+
+// methodRoles is the authorization matrix of the agent service, mapping every
+// method to the only role allowed to call it. Attestation methods are public
+// because verifiers fetch the attestation to decide whether to trust the agent
+// before any manifest key is used, and so are the capabilities clients read
+// before uploading. Attestation approvals and secrets carry the signature of
+// the computation owner, which the service verifies. Agent methods missing from
+// the matrix are denied.
+var methodRoles = map[string]auth.UserRole{
+	agent.AgentService_Algo_FullMethodName:                  auth.AlgorithmProviderRole,
+	agent.AgentService_ResumableAlgo_FullMethodName:         auth.AlgorithmProviderRole,
+	agent.AgentService_Data_FullMethodName:                  auth.DataProviderRole,
+	agent.AgentService_Result_FullMethodName:                auth.ConsumerRole,
+	agent.AgentService_Stop_FullMethodName:                  auth.AlgorithmProviderRole,
+	agent.AgentService_Restore_FullMethodName:               auth.AlgorithmProviderRole,
+	agent.AgentService_Attestation_FullMethodName:           publicRole,
+	agent.AgentService_IMAMeasurements_FullMethodName:       publicRole,
+	agent.AgentService_AzureAttestationToken_FullMethodName: publicRole,
+	agent.AgentService_Capabilities_FullMethodName:          publicRole,
+	agent.AgentService_ApproveAttestation_FullMethodName:    publicRole,
+	agent.AgentService_Secrets_FullMethodName:               publicRole,
+}
+
 type authInterceptor struct {
 	auth auth.Authenticator
 }
@@ -30,45 +58,48 @@ func NewAuthInterceptor(authSvc auth.Authenticator) (grpc.UnaryServerInterceptor
 	return ai.AuthUnaryInterceptor(), ai.AuthStreamInterceptor()
 }
 
+// authorize authenticates the caller with the role the method is allowed for.
+// Methods of services other than the agent, such as health checks, are not authorized.
+func (s *authInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, agentServicePrefix) {
+		return ctx, nil
+	}
+
+	role, ok := methodRoles[method]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not allowed", method)
+	}
+
+	if role == publicRole {
+		return ctx, nil
+	}
+
+	ctx, err := s.auth.AuthenticateUser(ctx, role)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err.Error())
+	}
+
+	return ctx, nil
+}
+
 func (s *authInterceptor) AuthStreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		switch info.FullMethod {
-		case agent.AgentService_Algo_FullMethodName:
-			if _, err := s.auth.AuthenticateUser(stream.Context(), auth.AlgorithmProviderRole); err != nil {
-				return status.Errorf(codes.Unauthenticated, "%v", err.Error())
-			}
-			return handler(srv, stream)
-		case agent.AgentService_Data_FullMethodName:
-			ctx, err := s.auth.AuthenticateUser(stream.Context(), auth.DataProviderRole)
-			if err != nil {
-				return status.Errorf(codes.Unauthenticated, "%s", err.Error())
-			}
-			wrapped := &wrappedServerStream{ServerStream: stream, ctx: ctx}
-			return handler(srv, wrapped)
-		case agent.AgentService_Result_FullMethodName:
-			ctx, err := s.auth.AuthenticateUser(stream.Context(), auth.ConsumerRole)
-			if err != nil {
-				return status.Errorf(codes.Unauthenticated, "%v", err.Error())
-			}
-			wrapped := &wrappedServerStream{ServerStream: stream, ctx: ctx}
-			return handler(srv, wrapped)
-		default:
-			return handler(srv, stream)
+		ctx, err := s.authorize(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
 		}
+
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
 	}
 }
 
 func (s *authInterceptor) AuthUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		switch info.FullMethod {
-		case agent.AgentService_Result_FullMethodName:
-			ctx, err := s.auth.AuthenticateUser(ctx, auth.ConsumerRole)
-			if err != nil {
-				return nil, status.Errorf(codes.Unauthenticated, "%v", err.Error())
-			}
-			return handler(ctx, req)
-		default:
-			return handler(ctx, req)
+		ctx, err := s.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
 		}
+
+		return handler(ctx, req)
 	}
 }
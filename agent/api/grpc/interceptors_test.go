@@ -11,7 +11,9 @@ import (
 	"github.com/ultravioletrs/cocos/agent/auth"
 	"github.com/ultravioletrs/cocos/agent/auth/mocks"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestAuthUnaryInterceptor(t *testing.T) {
@@ -37,6 +39,27 @@ func TestAuthUnaryInterceptor(t *testing.T) {
 			role:       auth.ConsumerRole,
 			wantErr:    true,
 		},
+		{
+			name:       "authorized stop method",
+			authorized: true,
+			method:     agent.AgentService_Stop_FullMethodName,
+			role:       auth.AlgorithmProviderRole,
+			wantErr:    false,
+		},
+		{
+			name:       "unauthorized stop method",
+			authorized: false,
+			method:     agent.AgentService_Stop_FullMethodName,
+			role:       auth.AlgorithmProviderRole,
+			wantErr:    true,
+		},
+		{
+			name:       "authorized restore method",
+			authorized: true,
+			method:     agent.AgentService_Restore_FullMethodName,
+			role:       auth.AlgorithmProviderRole,
+			wantErr:    false,
+		},
 		{
 			name:       "other method",
 			authorized: false,
@@ -150,3 +173,63 @@ type mockServerStream struct {
 func (m *mockServerStream) Context() context.Context {
 	return m.ctx
 }
+
+func TestAuthorizationMatrix(t *testing.T) {
+	roles := []auth.UserRole{auth.AlgorithmProviderRole, auth.DataProviderRole, auth.ConsumerRole}
+
+	var methods []string
+	for _, m := range agent.AgentService_ServiceDesc.Methods {
+		methods = append(methods, agentServicePrefix+m.MethodName)
+	}
+	for _, s := range agent.AgentService_ServiceDesc.Streams {
+		methods = append(methods, agentServicePrefix+s.StreamName)
+	}
+
+	for _, method := range methods {
+		allowed, ok := methodRoles[method]
+		if !ok {
+			t.Errorf("method %s is missing from the authorization matrix", method)
+			continue
+		}
+
+		for _, role := range roles {
+			t.Run(method+" "+string(role), func(t *testing.T) {
+				authmock := new(mocks.Authenticator)
+				authmock.On("AuthenticateUser", mock.Anything, mock.Anything).Return(func(ctx context.Context, r auth.UserRole) (context.Context, error) {
+					if r != role {
+						return ctx, auth.ErrSignatureVerificationFailed
+					}
+					return ctx, nil
+				})
+
+				unaryInt, streamInt := NewAuthInterceptor(authmock)
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs())
+
+				_, unaryErr := unaryInt(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+					return nil, nil
+				})
+				streamErr := streamInt(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(srv any, stream grpc.ServerStream) error {
+					return nil
+				})
+
+				wantAllowed := allowed == publicRole || allowed == role
+				for _, err := range []error{unaryErr, streamErr} {
+					if wantAllowed && err != nil {
+						t.Errorf("expected %s to be allowed for %s, got %v", method, role, err)
+					}
+					if !wantAllowed && status.Code(err) != codes.Unauthenticated {
+						t.Errorf("expected %s to be denied for %s, got %v", method, role, err)
+					}
+				}
+			})
+		}
+	}
+
+	unaryInt, _ := NewAuthInterceptor(new(mocks.Authenticator))
+	_, err := unaryInt(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: agentServicePrefix + "Logs"}, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected undeclared agent method to be denied, got %v", err)
+	}
+}
@@ -5,6 +5,8 @@ package grpc
 import (
 	"errors"
 
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
@@ -13,6 +15,7 @@ import (
 type algoReq struct {
 	Algorithm    []byte `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
 	Requirements []byte
+	Spec         algorithm.Spec
 }
 
 func (req algoReq) validate() error {
@@ -41,6 +44,54 @@ func (req resultReq) validate() error {
 	return nil
 }
 
+type stopReq struct {
+	Shutdown bool
+}
+
+func (req stopReq) validate() error {
+	return nil
+}
+
+type restoreReq struct {
+	Sequence uint64
+}
+
+func (req restoreReq) validate() error {
+	return nil
+}
+
+type approveAttestationReq struct {
+	Report    []byte
+	Signature []byte
+}
+
+func (req approveAttestationReq) validate() error {
+	if len(req.Report) == 0 {
+		return errors.New("attestation report is required")
+	}
+	if len(req.Signature) == 0 {
+		return errors.New("signature is required")
+	}
+
+	return nil
+}
+
+type secretsReq struct {
+	Secrets   []agent.Secret
+	Signature []byte
+}
+
+func (req secretsReq) validate() error {
+	if len(req.Secrets) == 0 {
+		return errors.New("secrets are required")
+	}
+	if len(req.Signature) == 0 {
+		return errors.New("signature is required")
+	}
+
+	return nil
+}
+
 type attestationReq struct {
 	TeeNonce  [quoteprovider.Nonce]byte
 	VtpmNonce [vtpm.Nonce]byte
@@ -4,12 +4,22 @@ package grpc
 
 type algoRes struct{}
 
-type dataRes struct{}
+type dataRes struct {
+	MissingDatasets []string
+}
 
 type resultRes struct {
 	File []byte
 }
 
+type stopRes struct{}
+
+type restoreRes struct{}
+
+type approveAttestationRes struct{}
+
+type secretsRes struct{}
+
 type attestationRes struct {
 	File []byte
 }
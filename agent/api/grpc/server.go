@@ -10,12 +10,15 @@ import (
 	"io"
 	"strconv"
 
+	smqerrors "github.com/absmach/supermq/pkg/errors"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/transport/grpc"
 	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/auth"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/server"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -36,9 +39,22 @@ var _ agent.AgentServiceServer = (*grpcServer)(nil)
 
 type grpcServer struct {
 	handlers map[string]grpc.Handler
+	uploads  *uploads
+	limits   server.MessageLimits
 	agent.UnimplementedAgentServiceServer
 }
 
+// ServerOption configures optional behavior of the agent gRPC server.
+type ServerOption func(*grpcServer)
+
+// WithMessageLimits makes the server advertise the message size limits of the
+// gRPC server it is registered on, the gRPC defaults are advertised otherwise.
+func WithMessageLimits(limits server.MessageLimits) ServerOption {
+	return func(s *grpcServer) {
+		s.limits = limits
+	}
+}
+
 type endpointConfig struct {
 	endpoint       func(agent.Service) endpoint.Endpoint
 	decodeRequest  grpc.DecodeRequestFunc
@@ -46,7 +62,7 @@ type endpointConfig struct {
 }
 
 // NewServer returns new AgentServiceServer instance.
-func NewServer(svc agent.Service) agent.AgentServiceServer {
+func NewServer(svc agent.Service, opts ...ServerOption) agent.AgentServiceServer {
 	// Define endpoint configurations
 	endpoints := map[string]endpointConfig{
 		"algo": {
@@ -64,6 +80,26 @@ func NewServer(svc agent.Service) agent.AgentServiceServer {
 			decodeRequest:  decodeResultRequest,
 			encodeResponse: encodeResultResponse,
 		},
+		"stop": {
+			endpoint:       stopEndpoint,
+			decodeRequest:  decodeStopRequest,
+			encodeResponse: encodeStopResponse,
+		},
+		"restore": {
+			endpoint:       restoreEndpoint,
+			decodeRequest:  decodeRestoreRequest,
+			encodeResponse: encodeRestoreResponse,
+		},
+		"approveAttestation": {
+			endpoint:       approveAttestationEndpoint,
+			decodeRequest:  decodeApproveAttestationRequest,
+			encodeResponse: encodeApproveAttestationResponse,
+		},
+		"secrets": {
+			endpoint:       secretsEndpoint,
+			decodeRequest:  decodeSecretsRequest,
+			encodeResponse: encodeSecretsResponse,
+		},
 		"attestation": {
 			endpoint:       attestationEndpoint,
 			decodeRequest:  decodeAttestationRequest,
@@ -91,16 +127,27 @@ func NewServer(svc agent.Service) agent.AgentServiceServer {
 		)
 	}
 
-	return &grpcServer{
+	s := &grpcServer{
 		handlers: handlers,
+		uploads:  newUploads(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func decodeAlgoRequest(_ context.Context, grpcReq any) (any, error) {
+func decodeAlgoRequest(ctx context.Context, grpcReq any) (any, error) {
 	req := grpcReq.(*agent.AlgoRequest)
+	if err := auth.VerifyBody(ctx, req.Algorithm, req.Requirements); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
 	return algoReq{
 		Algorithm:    req.Algorithm,
 		Requirements: req.Requirements,
+		Spec:         req.Spec.ToSpec(),
 	}, nil
 }
 
@@ -108,8 +155,12 @@ func encodeAlgoResponse(_ context.Context, response any) (any, error) {
 	return &agent.AlgoResponse{}, nil
 }
 
-func decodeDataRequest(_ context.Context, grpcReq any) (any, error) {
+func decodeDataRequest(ctx context.Context, grpcReq any) (any, error) {
 	req := grpcReq.(*agent.DataRequest)
+	if err := auth.VerifyBody(ctx, req.Dataset, []byte(req.Filename)); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
 	return dataReq{
 		Dataset:  req.Dataset,
 		Filename: req.Filename,
@@ -117,10 +168,15 @@ func decodeDataRequest(_ context.Context, grpcReq any) (any, error) {
 }
 
 func encodeDataResponse(_ context.Context, response any) (any, error) {
-	return &agent.DataResponse{}, nil
+	res := response.(dataRes)
+	return &agent.DataResponse{MissingDatasets: res.MissingDatasets}, nil
 }
 
-func decodeResultRequest(_ context.Context, grpcReq any) (any, error) {
+func decodeResultRequest(ctx context.Context, grpcReq any) (any, error) {
+	if err := auth.VerifyBody(ctx); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
 	return resultReq{}, nil
 }
 
@@ -131,6 +187,69 @@ func encodeResultResponse(_ context.Context, response any) (any, error) {
 	}, nil
 }
 
+// decodeStopRequest verifies the body digest of a shutdown over the shutdown
+// flag, and the one of a plain stop over no parts.
+func decodeStopRequest(ctx context.Context, grpcReq any) (any, error) {
+	req := grpcReq.(*agent.StopRequest)
+
+	var parts [][]byte
+	if req.Shutdown {
+		parts = append(parts, []byte(strconv.FormatBool(req.Shutdown)))
+	}
+	if err := auth.VerifyBody(ctx, parts...); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return stopReq{Shutdown: req.Shutdown}, nil
+}
+
+func encodeStopResponse(_ context.Context, _ any) (any, error) {
+	return &agent.StopResponse{}, nil
+}
+
+func decodeRestoreRequest(ctx context.Context, grpcReq any) (any, error) {
+	req := grpcReq.(*agent.RestoreRequest)
+
+	if err := auth.VerifyBody(ctx, []byte(strconv.FormatUint(req.Sequence, 10))); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return restoreReq{Sequence: req.Sequence}, nil
+}
+
+func encodeRestoreResponse(_ context.Context, _ any) (any, error) {
+	return &agent.RestoreResponse{}, nil
+}
+
+// decodeApproveAttestationRequest needs no body digest, the approval is
+// authenticated by its own signature.
+func decodeApproveAttestationRequest(_ context.Context, grpcReq any) (any, error) {
+	req := grpcReq.(*agent.ApproveAttestationRequest)
+
+	return approveAttestationReq{Report: req.Report, Signature: req.Signature}, nil
+}
+
+func encodeApproveAttestationResponse(_ context.Context, _ any) (any, error) {
+	return &agent.ApproveAttestationResponse{}, nil
+}
+
+// decodeSecretsRequest needs no body digest, the secrets are authenticated by
+// the signature of the computation owner.
+func decodeSecretsRequest(_ context.Context, grpcReq any) (any, error) {
+	req := grpcReq.(*agent.SecretsRequest)
+
+	secrets := make([]agent.Secret, len(req.Secrets))
+	for i, s := range req.Secrets {
+		secrets[i] = agent.Secret{Name: s.Name, Value: s.Value, Env: s.Env}
+	}
+
+	return secretsReq{Secrets: secrets, Signature: req.Signature}, nil
+}
+
+func encodeSecretsResponse(_ context.Context, _ any) (any, error) {
+	return &agent.SecretsResponse{}, nil
+}
+
 func validateNonce(nonce []byte, maxLen int, target any) error {
 	if len(nonce) > maxLen {
 		switch maxLen {
@@ -283,9 +402,11 @@ func receiveStreamingData(getData func() ([]byte, string, error)) ([]byte, strin
 	return data, filename, nil
 }
 
-// Algo implements agent.AgentServiceServer.
+// Algo implements agent.AgentServiceServer. An algorithm bundle that cannot be
+// safely extracted fails with InvalidArgument, and an algorithm uploaded while
+// the agent shuts down with Unavailable.
 func (s *grpcServer) Algo(stream agent.AgentService_AlgoServer) error {
-	algoFile, reqFile, err := s.receiveAlgoData(stream)
+	algoFile, reqFile, spec, err := s.receiveAlgoData(stream)
 	if err != nil {
 		return err
 	}
@@ -293,31 +414,131 @@ func (s *grpcServer) Algo(stream agent.AgentService_AlgoServer) error {
 	_, res, err := s.handlers["algo"].ServeGRPC(stream.Context(), &agent.AlgoRequest{
 		Algorithm:    algoFile,
 		Requirements: reqFile,
+		Spec:         spec,
 	})
-	if err != nil {
+	switch {
+	case smqerrors.Contains(err, agent.ErrInvalidAlgorithmBundle):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case smqerrors.Contains(err, agent.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	case err != nil:
 		return err
 	}
 
 	return stream.SendAndClose(res.(*agent.AlgoResponse))
 }
 
-func (s *grpcServer) receiveAlgoData(stream agent.AgentService_AlgoServer) ([]byte, []byte, error) {
+func (s *grpcServer) receiveAlgoData(stream agent.AgentService_AlgoServer) ([]byte, []byte, *agent.AlgorithmSpec, error) {
 	var algoFile, reqFile []byte
+	var spec *agent.AlgorithmSpec
 	for {
 		chunk, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, nil, status.Error(codes.Internal, err.Error())
+			return nil, nil, nil, status.Error(codes.Internal, err.Error())
 		}
 		algoFile = append(algoFile, chunk.Algorithm...)
 		reqFile = append(reqFile, chunk.Requirements...)
+		if chunk.Spec != nil {
+			spec = chunk.Spec
+		}
 	}
-	return algoFile, reqFile, nil
+	return algoFile, reqFile, spec, nil
 }
 
-// Data implements agent.AgentServiceServer.
+// ResumableAlgo implements agent.AgentServiceServer.
+// Every chunk is acknowledged with the number of bytes received so far, and
+// partial uploads are kept so a new stream can continue from that offset.
+// A message that cancels the upload drops the partial upload and aborts the
+// stream still receiving it.
+func (s *grpcServer) ResumableAlgo(stream agent.AgentService_ResumableAlgoServer) error {
+	handshake, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	id := handshake.UploadId
+	if id == "" {
+		return status.Error(codes.InvalidArgument, ErrMissingUploadID.Error())
+	}
+
+	if handshake.Cancel {
+		return s.cancelUpload(stream, id)
+	}
+
+	ctx, closeSession := s.uploads.open(stream.Context(), id)
+	defer closeSession()
+
+	if err := stream.Send(&agent.ResumableAlgoResponse{Offset: s.uploads.offset(id)}); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		if chunk.Cancel {
+			return s.cancelUpload(stream, id)
+		}
+
+		offset, err := s.uploads.append(ctx, id, chunk.Offset, chunk.Algorithm)
+		switch {
+		case errors.Is(err, ErrUploadCanceled):
+			return status.Error(codes.Canceled, err.Error())
+		case errors.Is(err, ErrUploadsFull):
+			return status.Error(codes.ResourceExhausted, err.Error())
+		case err != nil:
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+
+		if chunk.IsLast {
+			_, _, err := s.handlers["algo"].ServeGRPC(ctx, &agent.AlgoRequest{
+				Algorithm:    s.uploads.take(id),
+				Requirements: chunk.Requirements,
+				Spec:         chunk.Spec,
+			})
+			switch {
+			case smqerrors.Contains(err, agent.ErrInvalidAlgorithmBundle):
+				return status.Error(codes.InvalidArgument, err.Error())
+			case smqerrors.Contains(err, agent.ErrShuttingDown):
+				return status.Error(codes.Unavailable, err.Error())
+			case err != nil:
+				return err
+			}
+		}
+
+		if err := stream.Send(&agent.ResumableAlgoResponse{Offset: offset}); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		if chunk.IsLast {
+			return nil
+		}
+	}
+}
+
+// cancelUpload drops the partial upload and acknowledges the cancellation with a zero offset.
+func (s *grpcServer) cancelUpload(stream agent.AgentService_ResumableAlgoServer, id string) error {
+	s.uploads.cancel(id)
+
+	if err := stream.Send(&agent.ResumableAlgoResponse{}); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
+
+// Data implements agent.AgentServiceServer. A dataset that does not fit in
+// the tmpfs budget of the computation storage fails with ResourceExhausted,
+// an archive that cannot be safely extracted with InvalidArgument, and a
+// dataset uploaded while the agent shuts down with Unavailable.
 func (s *grpcServer) Data(stream agent.AgentService_DataServer) error {
 	dataFile, filename, err := receiveStreamingData(func() ([]byte, string, error) {
 		chunk, err := stream.Recv()
@@ -334,15 +555,25 @@ func (s *grpcServer) Data(stream agent.AgentService_DataServer) error {
 		Dataset:  dataFile,
 		Filename: filename,
 	})
-	if err != nil {
+	switch {
+	case smqerrors.Contains(err, agent.ErrStorageExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case smqerrors.Contains(err, agent.ErrInvalidArchive):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case smqerrors.Contains(err, agent.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	case err != nil:
 		return err
 	}
 
 	return stream.SendAndClose(res.(*agent.DataResponse))
 }
 
+// Result implements agent.AgentServiceServer. A computation whose algorithm
+// exceeded its max runtime fails with DeadlineExceeded, and one whose algorithm
+// exceeded its resource limits with ResourceExhausted.
 func (s *grpcServer) Result(req *agent.ResultRequest, stream agent.AgentService_ResultServer) error {
-	return s.streamingHandler(
+	err := s.streamingHandler(
 		stream.Context(),
 		"result",
 		req,
@@ -354,6 +585,14 @@ func (s *grpcServer) Result(req *agent.ResultRequest, stream agent.AgentService_
 			return res.(*agent.ResultResponse).File
 		},
 	)
+	switch {
+	case smqerrors.Contains(err, agent.ErrRunTimeout):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case smqerrors.Contains(err, agent.ErrResourceExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return err
 }
 
 func (s *grpcServer) Attestation(req *agent.AttestationRequest, stream agent.AgentService_AttestationServer) error {
@@ -410,6 +649,57 @@ func (s *grpcServer) AzureAttestationToken(ctx context.Context, req *agent.Attes
 	return rr, nil
 }
 
+// Stop implements agent.AgentServiceServer.
+func (s *grpcServer) Stop(ctx context.Context, req *agent.StopRequest) (*agent.StopResponse, error) {
+	_, res, err := s.handlers["stop"].ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*agent.StopResponse), nil
+}
+
+// Restore implements agent.AgentServiceServer.
+func (s *grpcServer) Restore(ctx context.Context, req *agent.RestoreRequest) (*agent.RestoreResponse, error) {
+	_, res, err := s.handlers["restore"].ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*agent.RestoreResponse), nil
+}
+
+// ApproveAttestation implements agent.AgentServiceServer.
+func (s *grpcServer) ApproveAttestation(ctx context.Context, req *agent.ApproveAttestationRequest) (*agent.ApproveAttestationResponse, error) {
+	_, res, err := s.handlers["approveAttestation"].ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*agent.ApproveAttestationResponse), nil
+}
+
+// Secrets implements agent.AgentServiceServer.
+func (s *grpcServer) Secrets(ctx context.Context, req *agent.SecretsRequest) (*agent.SecretsResponse, error) {
+	_, res, err := s.handlers["secrets"].ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*agent.SecretsResponse), nil
+}
+
+// Capabilities advertises the message size limits of the agent, so that
+// clients split uploads into messages the agent accepts, and the algorithm
+// runtimes it runs uploads with.
+func (s *grpcServer) Capabilities(ctx context.Context, req *agent.CapabilitiesRequest) (*agent.CapabilitiesResponse, error) {
+	return &agent.CapabilitiesResponse{
+		MaxRecvMsgSize:    int64(s.limits.Recv()),
+		MaxSendMsgSize:    int64(s.limits.Send()),
+		AlgorithmRuntimes: agent.AlgorithmRuntimes(),
+	}, nil
+}
+
 func (s *grpcServer) streamDualBuffers(
 	buf1, buf2 *bytes.Buffer,
 	sendFn func([]byte, []byte) error,
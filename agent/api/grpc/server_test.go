@@ -4,18 +4,33 @@ package grpc
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"io"
+	"strconv"
 	"testing"
+	"time"
 
+	smqerrors "github.com/absmach/supermq/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/auth"
 	"github.com/ultravioletrs/cocos/agent/mocks"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type MockAgentService_AlgoServer struct {
@@ -125,10 +140,10 @@ func TestNewServer(t *testing.T) {
 	grpcServer, ok := server.(*grpcServer)
 	assert.True(t, ok)
 	assert.NotNil(t, grpcServer.handlers)
-	assert.Len(t, grpcServer.handlers, 6) // Should have 6 handlers
+	assert.Len(t, grpcServer.handlers, 10) // Should have 10 handlers
 
 	// Check that all expected handlers are present
-	expectedHandlers := []string{"algo", "data", "result", "attestation", "imaMeasurements", "azureAttestationToken"}
+	expectedHandlers := []string{"algo", "data", "result", "stop", "restore", "approveAttestation", "secrets", "attestation", "imaMeasurements", "azureAttestationToken"}
 	for _, handler := range expectedHandlers {
 		assert.Contains(t, grpcServer.handlers, handler)
 		assert.NotNil(t, grpcServer.handlers[handler])
@@ -139,12 +154,19 @@ func TestAlgo(t *testing.T) {
 	mockService := new(mocks.Service)
 	server := NewServer(mockService)
 
+	spec := &agent.AlgorithmSpec{Type: agent.AlgorithmType_ALGORITHM_TYPE_PYTHON, Args: []string{"--epochs", "2"}, MinRuntimeVersion: "3.10"}
+
 	mockStream := &MockAgentService_AlgoServer{ctx: context.Background()}
+	mockStream.On("Recv").Return(&agent.AlgoRequest{Spec: spec}, nil).Once()
 	mockStream.On("Recv").Return(&agent.AlgoRequest{Algorithm: []byte("algo"), Requirements: []byte("req")}, nil).Once()
 	mockStream.On("Recv").Return(&agent.AlgoRequest{}, io.EOF).Once()
 	mockStream.On("SendAndClose", &agent.AlgoResponse{}).Return(nil).Once()
 
-	mockService.On("Algo", context.Background(), agent.Algorithm{Algorithm: []byte("algo"), Requirements: []byte("req")}).Return(nil)
+	mockService.On("Algo", context.Background(), agent.Algorithm{
+		Algorithm:    []byte("algo"),
+		Requirements: []byte("req"),
+		Spec:         algorithm.Spec{Type: algorithm.AlgoTypePython, Args: []string{"--epochs", "2"}, MinRuntimeVersion: "3.10"},
+	}).Return(nil)
 
 	err := server.Algo(mockStream)
 	assert.NoError(t, err)
@@ -163,7 +185,7 @@ func TestAlgoWithMultipleChunks(t *testing.T) {
 	mockStream.On("Recv").Return(&agent.AlgoRequest{}, io.EOF).Once()
 	mockStream.On("SendAndClose", &agent.AlgoResponse{}).Return(nil).Once()
 
-	mockService.On("Algo", context.Background(), agent.Algorithm{Algorithm: []byte("algo2"), Requirements: []byte("req2")}).Return(nil)
+	mockService.On("Algo", context.Background(), agent.Algorithm{Algorithm: []byte("algo2"), Requirements: []byte("req2"), Spec: algorithm.Spec{Type: algorithm.AlgoTypeBin}}).Return(nil)
 
 	err := server.Algo(mockStream)
 	assert.NoError(t, err)
@@ -179,9 +201,10 @@ func TestData(t *testing.T) {
 	mockStream := &MockAgentService_DataServer{ctx: context.Background()}
 	mockStream.On("Recv").Return(&agent.DataRequest{Dataset: []byte("data"), Filename: "test.txt"}, nil).Once()
 	mockStream.On("Recv").Return(&agent.DataRequest{}, io.EOF).Once()
-	mockStream.On("SendAndClose", &agent.DataResponse{}).Return(nil).Once()
+	mockStream.On("SendAndClose", &agent.DataResponse{MissingDatasets: []string{"other.txt"}}).Return(nil).Once()
 
 	mockService.On("Data", context.Background(), agent.Dataset{Dataset: []byte("data"), Filename: "test.txt"}).Return(nil)
+	mockService.On("Datasets").Return([]agent.DatasetStatus{{Index: 0, Filename: "test.txt", Received: true}, {Index: 1, Filename: "other.txt"}})
 
 	err := server.Data(mockStream)
 	assert.NoError(t, err)
@@ -190,6 +213,78 @@ func TestData(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestDataStorageExceeded(t *testing.T) {
+	mockService := new(mocks.Service)
+	server := NewServer(mockService)
+
+	mockStream := &MockAgentService_DataServer{ctx: context.Background()}
+	mockStream.On("Recv").Return(&agent.DataRequest{Dataset: []byte("data"), Filename: "test.txt"}, nil).Once()
+	mockStream.On("Recv").Return(&agent.DataRequest{}, io.EOF).Once()
+
+	mockService.On("Data", context.Background(), agent.Dataset{Dataset: []byte("data"), Filename: "test.txt"}).Return(agent.ErrStorageExceeded)
+
+	err := server.Data(mockStream)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	mockStream.AssertNotCalled(t, "SendAndClose", mock.Anything)
+}
+
+func TestDataInvalidArchive(t *testing.T) {
+	mockService := new(mocks.Service)
+	server := NewServer(mockService)
+
+	mockStream := &MockAgentService_DataServer{ctx: context.Background()}
+	mockStream.On("Recv").Return(&agent.DataRequest{Dataset: []byte("data"), Filename: "test.zip"}, nil).Once()
+	mockStream.On("Recv").Return(&agent.DataRequest{}, io.EOF).Once()
+
+	mockService.On("Data", context.Background(), agent.Dataset{Dataset: []byte("data"), Filename: "test.zip"}).Return(agent.ErrInvalidArchive)
+
+	err := server.Data(mockStream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockStream.AssertNotCalled(t, "SendAndClose", mock.Anything)
+}
+
+func TestAlgoInvalidBundle(t *testing.T) {
+	mockService := new(mocks.Service)
+	server := NewServer(mockService)
+
+	mockStream := &MockAgentService_AlgoServer{ctx: context.Background()}
+	mockStream.On("Recv").Return(&agent.AlgoRequest{Algorithm: []byte("algo")}, nil).Once()
+	mockStream.On("Recv").Return(&agent.AlgoRequest{}, io.EOF).Once()
+
+	mockService.On("Algo", mock.Anything, mock.Anything).Return(agent.ErrInvalidAlgorithmBundle)
+
+	err := server.Algo(mockStream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockStream.AssertNotCalled(t, "SendAndClose", mock.Anything)
+}
+
+func TestUploadShuttingDown(t *testing.T) {
+	mockService := new(mocks.Service)
+	server := NewServer(mockService)
+	mockService.On("Algo", mock.Anything, mock.Anything).Return(agent.ErrShuttingDown)
+	mockService.On("Data", mock.Anything, mock.Anything).Return(agent.ErrShuttingDown)
+
+	algoStream := &MockAgentService_AlgoServer{ctx: context.Background()}
+	algoStream.On("Recv").Return(&agent.AlgoRequest{Algorithm: []byte("algo")}, nil).Once()
+	algoStream.On("Recv").Return(&agent.AlgoRequest{}, io.EOF).Once()
+
+	err := server.Algo(algoStream)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	dataStream := &MockAgentService_DataServer{ctx: context.Background()}
+	dataStream.On("Recv").Return(&agent.DataRequest{Dataset: []byte("data"), Filename: "test.txt"}, nil).Once()
+	dataStream.On("Recv").Return(&agent.DataRequest{}, io.EOF).Once()
+
+	err = server.Data(dataStream)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	algoStream.AssertNotCalled(t, "SendAndClose", mock.Anything)
+	dataStream.AssertNotCalled(t, "SendAndClose", mock.Anything)
+}
+
 func TestResult(t *testing.T) {
 	mockService := new(mocks.Service)
 	server := NewServer(mockService)
@@ -214,6 +309,188 @@ func TestResult(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestResultRunFailureStatus(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+		code codes.Code
+	}{
+		{
+			desc: "algorithm exceeded its max runtime",
+			err:  agent.ErrRunTimeout,
+			code: codes.DeadlineExceeded,
+		},
+		{
+			desc: "algorithm exceeded its resource limits",
+			err:  agent.ErrResourceExceeded,
+			code: codes.ResourceExhausted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockService := new(mocks.Service)
+			server := NewServer(mockService)
+
+			mockStream := &MockAgentService_ResultServer{ctx: context.Background()}
+			mockService.On("Result", mock.Anything).Return(nil, smqerrors.Wrap(tc.err, errors.New("signal: killed")))
+
+			err := server.Result(&agent.ResultRequest{}, mockStream)
+			assert.Equal(t, tc.code, status.Code(err))
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	cases := []struct {
+		desc     string
+		shutdown bool
+		method   string
+		err      error
+	}{
+		{
+			desc:   "stop computation",
+			method: "StopComputation",
+		},
+		{
+			desc:   "stop computation failure",
+			method: "StopComputation",
+			err:    agent.ErrStateNotReady,
+		},
+		{
+			desc:     "shut down agent",
+			shutdown: true,
+			method:   "Shutdown",
+		},
+		{
+			desc:     "shut down agent failure",
+			shutdown: true,
+			method:   "Shutdown",
+			err:      errors.New("failed to drain"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockService := new(mocks.Service)
+			server := NewServer(mockService)
+
+			mockService.On(tc.method, mock.Anything).Return(tc.err)
+
+			res, err := server.Stop(context.Background(), &agent.StopRequest{Shutdown: tc.shutdown})
+			assert.True(t, smqerrors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err == nil {
+				assert.NotNil(t, res)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	cases := []struct {
+		desc string
+		req  *agent.RestoreRequest
+		err  error
+	}{
+		{
+			desc: "restore checkpoint",
+			req:  &agent.RestoreRequest{Sequence: 3},
+		},
+		{
+			desc: "restore any checkpoint",
+			req:  &agent.RestoreRequest{},
+		},
+		{
+			desc: "restore stale checkpoint",
+			req:  &agent.RestoreRequest{Sequence: 4},
+			err:  agent.ErrCheckpointStale,
+		},
+		{
+			desc: "restore checkpoint failure",
+			req:  &agent.RestoreRequest{Sequence: 3},
+			err:  agent.ErrCheckpointNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockService := new(mocks.Service)
+			server := NewServer(mockService)
+
+			mockService.On("Restore", mock.Anything, tc.req.Sequence).Return(tc.err).Maybe()
+
+			res, err := server.Restore(context.Background(), tc.req)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, res)
+			} else {
+				assert.ErrorContains(t, err, tc.err.Error())
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApproveAttestation(t *testing.T) {
+	signature := []byte("signature")
+	report := []byte("report")
+
+	cases := []struct {
+		desc string
+		req  *agent.ApproveAttestationRequest
+		err  error
+	}{
+		{
+			desc: "approve attestation",
+			req:  &agent.ApproveAttestationRequest{Report: report, Signature: signature},
+		},
+		{
+			desc: "approve attestation with invalid signature",
+			req:  &agent.ApproveAttestationRequest{Report: report, Signature: signature},
+			err:  agent.ErrApprovalSignature,
+		},
+		{
+			desc: "approve attestation of another agent",
+			req:  &agent.ApproveAttestationRequest{Report: report, Signature: signature},
+			err:  agent.ErrApprovalReport,
+		},
+		{
+			desc: "approve attestation without signature",
+			req:  &agent.ApproveAttestationRequest{Report: report},
+			err:  errors.New("signature is required"),
+		},
+		{
+			desc: "approve attestation without report",
+			req:  &agent.ApproveAttestationRequest{Signature: signature},
+			err:  errors.New("attestation report is required"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockService := new(mocks.Service)
+			server := NewServer(mockService)
+
+			mockService.On("ApproveAttestation", mock.Anything, report, signature).Return(tc.err).Maybe()
+
+			res, err := server.ApproveAttestation(context.Background(), tc.req)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, res)
+			} else {
+				assert.ErrorContains(t, err, tc.err.Error())
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestAttestation(t *testing.T) {
 	mockService := new(mocks.Service)
 	server := NewServer(mockService)
@@ -353,7 +630,53 @@ func TestDecodeAlgoRequest(t *testing.T) {
 	req := &agent.AlgoRequest{Algorithm: []byte("algo"), Requirements: []byte("req")}
 	decoded, err := decodeAlgoRequest(context.Background(), req)
 	assert.NoError(t, err)
-	assert.Equal(t, algoReq{Algorithm: []byte("algo"), Requirements: []byte("req")}, decoded)
+	assert.Equal(t, algoReq{Algorithm: []byte("algo"), Requirements: []byte("req"), Spec: algorithm.Spec{Type: algorithm.AlgoTypeBin}}, decoded)
+}
+
+func TestDecodeAlgoRequestSignedBody(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	userKey, err := x509.MarshalPKIXPublicKey(pubKey)
+	require.NoError(t, err)
+
+	authSvc, err := auth.New(agent.Computation{Algorithm: agent.Algorithm{UserKey: userKey}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		req  *agent.AlgoRequest
+		err  bool
+	}{
+		{
+			name: "signed body",
+			req:  &agent.AlgoRequest{Algorithm: []byte("algo"), Requirements: []byte("req")},
+		},
+		{
+			name: "tampered body",
+			req:  &agent.AlgoRequest{Algorithm: []byte("other"), Requirements: []byte("req")},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			digest := auth.BodyDigest([]byte("algo"), []byte("req"))
+			signature, err := privKey.Sign(rand.Reader, auth.SigningPayload(auth.AlgorithmProviderRole, timestamp, digest), crypto.Hash(0))
+			require.NoError(t, err)
+
+			md := metadata.New(map[string]string{
+				auth.SignatureMetadataKey:  base64.StdEncoding.EncodeToString(signature),
+				auth.TimestampMetadataKey:  timestamp,
+				auth.BodyDigestMetadataKey: digest,
+			})
+			ctx, err := authSvc.AuthenticateUser(metadata.NewIncomingContext(context.Background(), md), auth.AlgorithmProviderRole)
+			require.NoError(t, err)
+
+			_, err = decodeAlgoRequest(ctx, tc.req)
+			assert.Equal(t, tc.err, err != nil, "unexpected error: %v", err)
+		})
+	}
 }
 
 func TestEncodeAlgoResponse(t *testing.T) {
@@ -498,3 +821,82 @@ func TestDataWithStreamError(t *testing.T) {
 
 	mockStream.AssertExpectations(t)
 }
+
+func TestCapabilities(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []ServerOption
+		expected *agent.CapabilitiesResponse
+	}{
+		{
+			name:     "default limits",
+			expected: &agent.CapabilitiesResponse{MaxRecvMsgSize: server.DefaultMaxRecvMsgSize, MaxSendMsgSize: server.DefaultMaxSendMsgSize, AlgorithmRuntimes: agent.AlgorithmRuntimes()},
+		},
+		{
+			name:     "configured limits",
+			opts:     []ServerOption{WithMessageLimits(server.MessageLimits{MaxRecvMsgSize: 16 << 20, MaxSendMsgSize: 8 << 20})},
+			expected: &agent.CapabilitiesResponse{MaxRecvMsgSize: 16 << 20, MaxSendMsgSize: 8 << 20, AlgorithmRuntimes: agent.AlgorithmRuntimes()},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(new(mocks.Service), tc.opts...)
+
+			res, err := s.Capabilities(context.Background(), &agent.CapabilitiesRequest{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	signature := []byte("signature")
+	secrets := []agent.Secret{{Name: "api_token", Value: []byte("token")}, {Name: "MODEL_KEY", Value: []byte("key"), Env: true}}
+	reqSecrets := []*agent.RuntimeSecret{{Name: "api_token", Value: []byte("token")}, {Name: "MODEL_KEY", Value: []byte("key"), Env: true}}
+
+	cases := []struct {
+		desc string
+		req  *agent.SecretsRequest
+		err  error
+	}{
+		{
+			desc: "provision secrets",
+			req:  &agent.SecretsRequest{Secrets: reqSecrets, Signature: signature},
+		},
+		{
+			desc: "provision secrets with invalid signature",
+			req:  &agent.SecretsRequest{Secrets: reqSecrets, Signature: signature},
+			err:  agent.ErrSecretsSignature,
+		},
+		{
+			desc: "provision secrets without signature",
+			req:  &agent.SecretsRequest{Secrets: reqSecrets},
+			err:  errors.New("signature is required"),
+		},
+		{
+			desc: "provision no secrets",
+			req:  &agent.SecretsRequest{Signature: signature},
+			err:  errors.New("secrets are required"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockService := new(mocks.Service)
+			server := NewServer(mockService)
+
+			mockService.On("Secrets", mock.Anything, secrets, signature).Return(tc.err).Maybe()
+
+			res, err := server.Secrets(context.Background(), tc.req)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, res)
+			} else {
+				assert.ErrorContains(t, err, tc.err.Error())
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reasons an upload is throttled for, reported to the throttle callback.
+const (
+	ThrottleConcurrency = "concurrency"
+	ThrottleRate        = "rate"
+)
+
+// uploadMethods are the streams that upload the algorithm and datasets into the enclave.
+var uploadMethods = map[string]bool{
+	agent.AgentService_Algo_FullMethodName:          true,
+	agent.AgentService_ResumableAlgo_FullMethodName: true,
+	agent.AgentService_Data_FullMethodName:          true,
+}
+
+// ThrottleFunc is called with the upload method and the reason when an upload is throttled.
+type ThrottleFunc func(method, reason string)
+
+type uploadThrottle struct {
+	limits     server.UploadLimits
+	slots      chan struct{}
+	onThrottle ThrottleFunc
+	mu         sync.Mutex
+	conns      map[string]*connRate
+}
+
+// connRate paces the uploads of a connection, the next message is received
+// once the previous ones were delivered at the upload rate.
+type connRate struct {
+	mu      sync.Mutex
+	next    time.Time
+	streams int
+}
+
+// NewUploadThrottle returns an interceptor that bounds the upload streams served
+// at once and the rate each connection uploads at, so that a party cannot
+// starve the enclave memory. Uploads over the concurrency limit fail with
+// RESOURCE_EXHAUSTED, while uploads over the rate are slowed down.
+func NewUploadThrottle(limits server.UploadLimits, onThrottle ThrottleFunc) grpc.StreamServerInterceptor {
+	t := &uploadThrottle{
+		limits:     limits,
+		onThrottle: onThrottle,
+		conns:      make(map[string]*connRate),
+	}
+	if limits.MaxConcurrentUploads > 0 {
+		t.slots = make(chan struct{}, limits.MaxConcurrentUploads)
+	}
+
+	return t.intercept
+}
+
+func (t *uploadThrottle) intercept(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !uploadMethods[info.FullMethod] {
+		return handler(srv, stream)
+	}
+
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+			defer func() { <-t.slots }()
+		default:
+			t.throttled(info.FullMethod, ThrottleConcurrency)
+			return status.Errorf(codes.ResourceExhausted, "agent serves at most %d uploads at once", t.limits.MaxConcurrentUploads)
+		}
+	}
+
+	if t.limits.MaxUploadRate <= 0 {
+		return handler(srv, stream)
+	}
+
+	addr := connAddr(stream.Context())
+	conn := t.acquire(addr)
+	defer t.release(addr)
+
+	return handler(srv, &throttledStream{ServerStream: stream, throttle: t, conn: conn, method: info.FullMethod})
+}
+
+func (t *uploadThrottle) throttled(method, reason string) {
+	if t.onThrottle != nil {
+		t.onThrottle(method, reason)
+	}
+}
+
+// acquire returns the rate of the connection, shared by its concurrent uploads.
+func (t *uploadThrottle) acquire(addr string) *connRate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conn, ok := t.conns[addr]
+	if !ok {
+		conn = &connRate{}
+		t.conns[addr] = conn
+	}
+	conn.streams++
+
+	return conn
+}
+
+func (t *uploadThrottle) release(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conn := t.conns[addr]
+	conn.streams--
+	if conn.streams == 0 {
+		delete(t.conns, addr)
+	}
+}
+
+// reserve returns how long a message of n bytes must wait to be received at the rate.
+func (c *connRate) reserve(n, rate int) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.next.Before(now) {
+		c.next = now
+	}
+	delay := c.next.Sub(now)
+	c.next = c.next.Add(time.Duration(n) * time.Second / time.Duration(rate))
+
+	return delay
+}
+
+type throttledStream struct {
+	grpc.ServerStream
+	throttle  *uploadThrottle
+	conn      *connRate
+	method    string
+	throttled bool
+}
+
+func (s *throttledStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	delay := s.conn.reserve(proto.Size(msg), s.throttle.limits.MaxUploadRate)
+	if delay <= 0 {
+		return nil
+	}
+
+	// The upload is reported once, not for every message it is slowed down on.
+	if !s.throttled {
+		s.throttled = true
+		s.throttle.throttled(s.method, ThrottleRate)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-s.Context().Done():
+		return status.FromContextError(s.Context().Err()).Err()
+	}
+}
+
+// connAddr identifies the connection of the stream by the address of the peer.
+func connAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
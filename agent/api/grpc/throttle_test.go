@@ -0,0 +1,136 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// uploadStream receives dataset chunks of the given size from a peer.
+type uploadStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	chunk int
+}
+
+func (s *uploadStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *uploadStream) RecvMsg(m any) error {
+	proto.Merge(m.(proto.Message), &agent.DataRequest{Dataset: make([]byte, s.chunk)})
+	return nil
+}
+
+func peerContext(ctx context.Context, port int) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 2, 2), Port: port}})
+}
+
+// throttleEvents records the throttled uploads.
+type throttleEvents struct {
+	mu     sync.Mutex
+	events [][2]string
+}
+
+func (e *throttleEvents) record(method, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, [2]string{method, reason})
+}
+
+func (e *throttleEvents) list() [][2]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.events
+}
+
+func TestUploadThrottleConcurrency(t *testing.T) {
+	var events throttleEvents
+	intercept := NewUploadThrottle(server.UploadLimits{MaxConcurrentUploads: 1}, events.record)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		stream := &uploadStream{ctx: peerContext(context.Background(), 1)}
+		done <- intercept(nil, stream, &grpc.StreamServerInfo{FullMethod: agent.AgentService_Algo_FullMethodName}, func(srv any, stream grpc.ServerStream) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	handled := false
+	handler := func(srv any, stream grpc.ServerStream) error {
+		handled = true
+		return nil
+	}
+	stream := &uploadStream{ctx: peerContext(context.Background(), 2)}
+
+	err := intercept(nil, stream, &grpc.StreamServerInfo{FullMethod: agent.AgentService_Data_FullMethodName}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.False(t, handled)
+	assert.Equal(t, [][2]string{{agent.AgentService_Data_FullMethodName, ThrottleConcurrency}}, events.list())
+
+	err = intercept(nil, stream, &grpc.StreamServerInfo{FullMethod: agent.AgentService_Result_FullMethodName}, handler)
+	assert.NoError(t, err)
+	assert.True(t, handled, "downloads are not limited")
+
+	close(release)
+	require.NoError(t, <-done)
+
+	handled = false
+	err = intercept(nil, stream, &grpc.StreamServerInfo{FullMethod: agent.AgentService_Data_FullMethodName}, handler)
+	assert.NoError(t, err)
+	assert.True(t, handled, "the slot of a finished upload is released")
+}
+
+func TestUploadThrottleRate(t *testing.T) {
+	const (
+		chunk = 100
+		rate  = 1000
+	)
+	size := proto.Size(&agent.DataRequest{Dataset: make([]byte, chunk)})
+
+	var events throttleEvents
+	intercept := NewUploadThrottle(server.UploadLimits{MaxUploadRate: rate}, events.record)
+	info := &grpc.StreamServerInfo{FullMethod: agent.AgentService_Data_FullMethodName}
+
+	receive := func(stream grpc.ServerStream, n int) error {
+		for range n {
+			if err := stream.RecvMsg(new(agent.DataRequest)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := time.Now()
+	err := intercept(nil, &uploadStream{ctx: peerContext(context.Background(), 1), chunk: chunk}, info, func(srv any, stream grpc.ServerStream) error {
+		return receive(stream, 3)
+	})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Duration(2*size)*time.Second/rate, "the first two chunks are delivered at the rate")
+	assert.Equal(t, [][2]string{{agent.AgentService_Data_FullMethodName, ThrottleRate}}, events.list(), "an upload is reported once")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = intercept(nil, &uploadStream{ctx: peerContext(ctx, 2), chunk: chunk}, info, func(srv any, stream grpc.ServerStream) error {
+		return receive(stream, 2)
+	})
+	assert.Equal(t, codes.Canceled, status.Code(err), "throttled uploads stop with their stream")
+}
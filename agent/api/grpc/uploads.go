@@ -0,0 +1,174 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// uploadTTL is how long a partial upload no stream sends is kept.
+	uploadTTL = 30 * time.Minute
+	// maxPartialBytes bounds the bytes of all the partial uploads kept in memory.
+	maxPartialBytes = 512 << 20
+)
+
+var (
+	ErrMissingUploadID = errors.New("missing upload id")
+	ErrUploadOffset    = errors.New("chunk offset does not match received bytes")
+	ErrUploadCanceled  = errors.New("upload canceled")
+	// ErrUploadsFull indicates a chunk that does not fit in the bytes kept for partial uploads.
+	ErrUploadsFull = errors.New("partial uploads exceed the memory the agent keeps for them")
+)
+
+// uploads keeps partially received algorithms keyed by upload ID so that an
+// interrupted upload can be resumed on a new stream. Uploads no stream sent
+// for ttl are dropped, and the bytes of all uploads are bounded by maxSize.
+type uploads struct {
+	mu       sync.Mutex
+	partial  map[string]*partialUpload
+	sessions map[string]*uploadSession
+	size     int64
+	ttl      time.Duration
+	maxSize  int64
+	now      func() time.Time
+}
+
+// partialUpload holds the bytes of an upload received so far.
+type partialUpload struct {
+	data    []byte
+	updated time.Time
+}
+
+// uploadSession is the stream currently receiving an upload.
+type uploadSession struct {
+	cancel context.CancelFunc
+}
+
+func newUploads() *uploads {
+	return &uploads{
+		partial:  make(map[string]*partialUpload),
+		sessions: make(map[string]*uploadSession),
+		ttl:      uploadTTL,
+		maxSize:  maxPartialBytes,
+		now:      time.Now,
+	}
+}
+
+func (u *uploads) offset(id string) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.expire()
+
+	return u.received(id)
+}
+
+// open registers the stream receiving the upload. The returned context is
+// canceled once the upload is canceled, and close releases the session.
+func (u *uploads) open(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &uploadSession{cancel: cancel}
+
+	u.mu.Lock()
+	u.sessions[id] = s
+	u.mu.Unlock()
+
+	return ctx, func() {
+		u.mu.Lock()
+		if u.sessions[id] == s {
+			delete(u.sessions, id)
+		}
+		// The upload expires ttl after its stream closed.
+		if p, ok := u.partial[id]; ok {
+			p.updated = u.now()
+		}
+		u.mu.Unlock()
+		cancel()
+	}
+}
+
+// append adds data at offset and returns the new number of received bytes.
+// Data received by the stream of a canceled upload is dropped.
+func (u *uploads) append(ctx context.Context, id string, offset int64, data []byte) (int64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.expire()
+
+	received := u.received(id)
+	if ctx.Err() != nil {
+		return received, ErrUploadCanceled
+	}
+	if offset != received {
+		return received, ErrUploadOffset
+	}
+	if u.size+int64(len(data)) > u.maxSize {
+		return received, ErrUploadsFull
+	}
+
+	p, ok := u.partial[id]
+	if !ok {
+		p = &partialUpload{}
+		u.partial[id] = p
+	}
+	p.data = append(p.data, data...)
+	p.updated = u.now()
+	u.size += int64(len(data))
+
+	return int64(len(p.data)), nil
+}
+
+func (u *uploads) take(id string) []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	p, ok := u.partial[id]
+	if !ok {
+		return nil
+	}
+	u.drop(id)
+
+	return p.data
+}
+
+// cancel aborts the upload, its stream is canceled and the received bytes are dropped.
+func (u *uploads) cancel(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if s, ok := u.sessions[id]; ok {
+		s.cancel()
+		delete(u.sessions, id)
+	}
+	u.drop(id)
+}
+
+func (u *uploads) received(id string) int64 {
+	if p, ok := u.partial[id]; ok {
+		return int64(len(p.data))
+	}
+
+	return 0
+}
+
+// expire drops the uploads no stream is sending that were last updated more
+// than ttl ago, callers hold u.mu.
+func (u *uploads) expire() {
+	for id, p := range u.partial {
+		if _, sending := u.sessions[id]; !sending && u.now().Sub(p.updated) > u.ttl {
+			u.drop(id)
+		}
+	}
+}
+
+// drop removes the upload, callers hold u.mu.
+func (u *uploads) drop(id string) {
+	if p, ok := u.partial[id]; ok {
+		u.size -= int64(len(p.data))
+		delete(u.partial, id)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploads(t *testing.T) {
+	u := newUploads()
+	ctx := context.Background()
+
+	assert.Equal(t, int64(0), u.offset("id"))
+
+	offset, err := u.append(ctx, "id", 0, []byte("algo"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), offset)
+
+	offset, err = u.append(ctx, "id", 2, []byte("rithm"))
+	assert.ErrorIs(t, err, ErrUploadOffset)
+	assert.Equal(t, int64(4), offset)
+
+	offset, err = u.append(ctx, "id", 4, []byte("rithm"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), offset)
+	assert.Equal(t, int64(9), u.offset("id"))
+
+	assert.Equal(t, []byte("algorithm"), u.take("id"))
+	assert.Equal(t, int64(0), u.offset("id"))
+}
+
+func TestUploadsCancel(t *testing.T) {
+	u := newUploads()
+
+	ctx, closeSession := u.open(context.Background(), "id")
+	defer closeSession()
+
+	_, err := u.append(ctx, "id", 0, []byte("algo"))
+	assert.NoError(t, err)
+
+	u.cancel("id")
+	assert.ErrorIs(t, ctx.Err(), context.Canceled, "the stream receiving the upload is aborted")
+	assert.Equal(t, int64(0), u.offset("id"))
+	assert.Empty(t, u.sessions)
+
+	offset, err := u.append(ctx, "id", 0, []byte("algo"))
+	assert.ErrorIs(t, err, ErrUploadCanceled)
+	assert.Equal(t, int64(0), offset)
+
+	resumed, closeResumed := u.open(context.Background(), "id")
+	_, err = u.append(resumed, "id", 0, []byte("algo"))
+	assert.NoError(t, err)
+	closeResumed()
+	assert.Empty(t, u.sessions)
+	assert.Equal(t, int64(4), u.offset("id"), "closing a session keeps the upload resumable")
+}
+
+func TestUploadsExpire(t *testing.T) {
+	u := newUploads()
+	now := time.Now()
+	u.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := u.append(ctx, "idle", 0, []byte("algo"))
+	assert.NoError(t, err)
+
+	sending, closeSession := u.open(ctx, "sending")
+	_, err = u.append(sending, "sending", 0, []byte("algo"))
+	assert.NoError(t, err)
+
+	now = now.Add(uploadTTL + time.Second)
+	assert.Equal(t, int64(0), u.offset("idle"), "an abandoned upload expires")
+	assert.Equal(t, int64(4), u.offset("sending"), "an upload with an open stream is kept")
+
+	closeSession()
+	now = now.Add(uploadTTL / 2)
+	assert.Equal(t, int64(4), u.offset("sending"), "the upload expires ttl after its stream closed")
+	now = now.Add(uploadTTL)
+	assert.Equal(t, int64(0), u.offset("sending"))
+	assert.Zero(t, u.size)
+}
+
+func TestUploadsFull(t *testing.T) {
+	u := newUploads()
+	u.maxSize = 8
+	ctx := context.Background()
+
+	_, err := u.append(ctx, "a", 0, []byte("algo"))
+	assert.NoError(t, err)
+	_, err = u.append(ctx, "b", 0, []byte("algo"))
+	assert.NoError(t, err)
+
+	offset, err := u.append(ctx, "a", 4, []byte("rithm"))
+	assert.ErrorIs(t, err, ErrUploadsFull)
+	assert.Equal(t, int64(4), offset)
+
+	u.take("b")
+	offset, err = u.append(ctx, "a", 4, []byte("rithm"))
+	assert.ErrorIs(t, err, ErrUploadsFull, "the received bytes of the upload count towards the cap")
+	assert.Equal(t, int64(4), offset)
+
+	u.cancel("a")
+	assert.Zero(t, u.size)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/auth"
+)
+
+func algoEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(algoReq)
+
+		if err := req.validate(); err != nil {
+			return algoRes{}, errors.Wrap(ErrMalformedRequest, err)
+		}
+
+		if err := auth.VerifyBody(ctx, req.Algorithm, req.Requirements); err != nil {
+			return algoRes{}, err
+		}
+
+		algo := agent.Algorithm{Algorithm: req.Algorithm, Requirements: req.Requirements, Spec: req.Spec}
+
+		if err := svc.Algo(ctx, algo); err != nil {
+			return algoRes{}, err
+		}
+
+		return algoRes{}, nil
+	}
+}
+
+func dataEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(dataReq)
+
+		if err := req.validate(); err != nil {
+			return dataRes{}, errors.Wrap(ErrMalformedRequest, err)
+		}
+
+		if err := auth.VerifyBody(ctx, req.Dataset, []byte(req.Filename)); err != nil {
+			return dataRes{}, err
+		}
+
+		if req.Decompress {
+			ctx = appendMetadata(ctx, agent.DecompressKey, strconv.FormatBool(req.Decompress))
+		}
+
+		dataset := agent.Dataset{Dataset: req.Dataset, Filename: req.Filename}
+
+		if err := svc.Data(ctx, dataset); err != nil {
+			return dataRes{}, err
+		}
+
+		return dataRes{}, nil
+	}
+}
+
+func resultEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(resultReq)
+
+		if err := req.validate(); err != nil {
+			return fileRes{}, errors.Wrap(ErrMalformedRequest, err)
+		}
+
+		if err := auth.VerifyBody(ctx); err != nil {
+			return fileRes{}, err
+		}
+
+		file, err := svc.Result(ctx)
+		if err != nil {
+			return fileRes{}, err
+		}
+
+		return fileRes{File: file}, nil
+	}
+}
+
+func attestationEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(attestationReq)
+
+		if err := req.validate(); err != nil {
+			return fileRes{}, errors.Wrap(ErrMalformedRequest, err)
+		}
+
+		if req.Refresh {
+			ctx = appendMetadata(ctx, agent.RefreshAttestationKey, strconv.FormatBool(req.Refresh))
+		}
+
+		file, err := svc.Attestation(ctx, req.TeeNonce, req.VtpmNonce, req.AttType)
+		if err != nil {
+			return fileRes{}, err
+		}
+
+		return fileRes{File: file}, nil
+	}
+}
+
+func stateEndpoint(svc agent.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req := request.(stateReq)
+
+		if err := req.validate(); err != nil {
+			return stateRes{}, errors.Wrap(ErrMalformedRequest, err)
+		}
+
+		return stateRes{State: svc.State(), Datasets: svc.Datasets()}, nil
+	}
+}
+
+// authorize verifies the request signature for the given role before
+// handing the request over to the wrapped endpoint.
+func authorize(authSvc auth.Authenticator, role auth.UserRole) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (any, error) {
+			ctx, err := authSvc.AuthenticateUser(ctx, role)
+			if err != nil {
+				return nil, err
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
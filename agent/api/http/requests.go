@@ -0,0 +1,70 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"errors"
+
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+)
+
+type algoReq struct {
+	Algorithm    []byte
+	Requirements []byte
+	Spec         algorithm.Spec
+}
+
+func (req algoReq) validate() error {
+	if len(req.Algorithm) == 0 {
+		return errors.New("algorithm binary is required")
+	}
+
+	return nil
+}
+
+type dataReq struct {
+	Dataset    []byte
+	Filename   string
+	Decompress bool
+}
+
+func (req dataReq) validate() error {
+	if len(req.Dataset) == 0 {
+		return errors.New("dataset is required")
+	}
+
+	return nil
+}
+
+type resultReq struct{}
+
+func (req resultReq) validate() error {
+	// No request parameters to validate, so no validation logic needed
+	return nil
+}
+
+type attestationReq struct {
+	TeeNonce  [quoteprovider.Nonce]byte
+	VtpmNonce [vtpm.Nonce]byte
+	AttType   attestation.PlatformType
+	Refresh   bool
+}
+
+func (req attestationReq) validate() error {
+	switch req.AttType {
+	case attestation.SNP, attestation.VTPM, attestation.SNPvTPM, attestation.TDX:
+		return nil
+	default:
+		return errors.New("invalid attestation type")
+	}
+}
+
+type stateReq struct{}
+
+func (req stateReq) validate() error {
+	// No request parameters to validate, so no validation logic needed
+	return nil
+}
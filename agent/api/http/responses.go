@@ -0,0 +1,34 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+type algoRes struct{}
+
+func (res algoRes) Code() int {
+	return http.StatusCreated
+}
+
+type dataRes struct{}
+
+func (res dataRes) Code() int {
+	return http.StatusCreated
+}
+
+type fileRes struct {
+	File []byte
+}
+
+type stateRes struct {
+	State    string                `json:"state"`
+	Datasets []agent.DatasetStatus `json:"datasets,omitempty"`
+}
+
+func (res stateRes) Code() int {
+	return http.StatusOK
+}
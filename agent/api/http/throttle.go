@@ -0,0 +1,165 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	agentgrpc "github.com/ultravioletrs/cocos/agent/api/grpc"
+	"github.com/ultravioletrs/cocos/pkg/server"
+)
+
+// maxUploadSize bounds the body of an upload request, as much as the partial
+// uploads the gRPC API keeps. It is a variable so tests can lower it.
+var maxUploadSize int64 = 512 << 20
+
+type uploadThrottle struct {
+	limits     server.UploadLimits
+	slots      chan struct{}
+	onThrottle agentgrpc.ThrottleFunc
+	mu         sync.Mutex
+	clients    map[string]*clientRate
+}
+
+// clientRate paces the uploads of a client, the next bytes are read once the
+// previous ones were read at the upload rate.
+type clientRate struct {
+	mu      sync.Mutex
+	next    time.Time
+	uploads int
+}
+
+func newUploadThrottle(limits server.UploadLimits, onThrottle agentgrpc.ThrottleFunc) *uploadThrottle {
+	t := &uploadThrottle{
+		limits:     limits,
+		onThrottle: onThrottle,
+		clients:    make(map[string]*clientRate),
+	}
+	if limits.MaxConcurrentUploads > 0 {
+		t.slots = make(chan struct{}, limits.MaxConcurrentUploads)
+	}
+
+	return t
+}
+
+// limit bounds the body of the upload requests of the method, the gRPC method
+// name throttled uploads are reported with, and throttles them as the gRPC
+// upload throttle does: uploads over the concurrency limit fail with 429 Too
+// Many Requests, while uploads over the rate are read slower.
+func (t *uploadThrottle) limit(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+		if t.slots != nil {
+			select {
+			case t.slots <- struct{}{}:
+				defer func() { <-t.slots }()
+			default:
+				t.throttled(method, agentgrpc.ThrottleConcurrency)
+				encodeError(r.Context(), errors.Wrap(ErrTooManyUploads, fmt.Errorf("agent serves at most %d uploads at once", t.limits.MaxConcurrentUploads)), w)
+				return
+			}
+		}
+
+		if t.limits.MaxUploadRate <= 0 {
+			next(w, r)
+			return
+		}
+
+		client := t.acquire(r.RemoteAddr)
+		defer t.release(r.RemoteAddr)
+
+		r.Body = &throttledBody{ReadCloser: r.Body, ctx: r.Context(), throttle: t, client: client, method: method}
+		next(w, r)
+	}
+}
+
+func (t *uploadThrottle) throttled(method, reason string) {
+	if t.onThrottle != nil {
+		t.onThrottle(method, reason)
+	}
+}
+
+// acquire returns the rate of the client, shared by its concurrent uploads.
+func (t *uploadThrottle) acquire(addr string) *clientRate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	client, ok := t.clients[addr]
+	if !ok {
+		client = &clientRate{}
+		t.clients[addr] = client
+	}
+	client.uploads++
+
+	return client
+}
+
+func (t *uploadThrottle) release(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	client := t.clients[addr]
+	client.uploads--
+	if client.uploads == 0 {
+		delete(t.clients, addr)
+	}
+}
+
+// reserve returns how long n bytes must wait to be read at the rate.
+func (c *clientRate) reserve(n, rate int) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.next.Before(now) {
+		c.next = now
+	}
+	delay := c.next.Sub(now)
+	c.next = c.next.Add(time.Duration(n) * time.Second / time.Duration(rate))
+
+	return delay
+}
+
+type throttledBody struct {
+	io.ReadCloser
+	ctx       context.Context
+	throttle  *uploadThrottle
+	client    *clientRate
+	method    string
+	throttled bool
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	delay := b.client.reserve(n, b.throttle.limits.MaxUploadRate)
+	if delay <= 0 {
+		return n, err
+	}
+
+	// The upload is reported once, not for every read it is slowed down on.
+	if !b.throttled {
+		b.throttled = true
+		b.throttle.throttled(b.method, agentgrpc.ThrottleRate)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return n, err
+	case <-b.ctx.Done():
+		return n, b.ctx.Err()
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/absmach/supermq"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/go-chi/chi/v5"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/python"
+	agentgrpc "github.com/ultravioletrs/cocos/agent/api/grpc"
+	"github.com/ultravioletrs/cocos/agent/auth"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/server"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	contentType       = "Content-Type"
+	jsonContentType   = "application/json"
+	octetContentType  = "application/octet-stream"
+	algorithmField    = "algorithm"
+	requirementsField = "requirements"
+	datasetField      = "dataset"
+	filenameField     = "filename"
+	maxMemory         = 32 << 20
+)
+
+var (
+	// ErrMalformedRequest indicates the HTTP request body could not be decoded.
+	ErrMalformedRequest = errors.New("malformed request body")
+	// ErrUnsupportedContentType indicates an unexpected request content type.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrNonceLength indicates the provided nonce exceeds the allowed length.
+	ErrNonceLength = errors.New("malformed nonce, exceeds allowed length")
+	// ErrUploadTooLarge indicates an upload request body over the upload size limit.
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum upload size")
+	// ErrTooManyUploads indicates an upload while the agent serves the maximum number of uploads.
+	ErrTooManyUploads = errors.New("too many concurrent uploads")
+)
+
+// HandlerOption configures optional behavior of the agent HTTP handler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	uploads    server.UploadLimits
+	onThrottle agentgrpc.ThrottleFunc
+}
+
+// WithUploadLimits bounds the uploads served at once and the rate each client
+// uploads at, reporting throttled uploads to onThrottle, as the gRPC upload
+// throttle does. Unset limits do not apply.
+func WithUploadLimits(limits server.UploadLimits, onThrottle agentgrpc.ThrottleFunc) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.uploads = limits
+		cfg.onThrottle = onThrottle
+	}
+}
+
+type attestationBody struct {
+	TeeNonce  string `json:"tee_nonce"`
+	VtpmNonce string `json:"vtpm_nonce"`
+	Type      int    `json:"type"`
+	Refresh   bool   `json:"refresh"`
+}
+
+// rangeHeadersKey holds the range headers of the request in its context.
+type rangeHeadersKey struct{}
+
+type errorRes struct {
+	Error string `json:"error"`
+}
+
+// MakeHandler returns a HTTP handler for the agent API endpoints. The handler
+// exposes the same operations as the gRPC API so that the enclave can be
+// reached by plain HTTP clients. Upload request bodies are bounded to 512 MiB.
+func MakeHandler(svc agent.Service, authSvc auth.Authenticator, svcName, instanceID string, handlerOpts ...HandlerOption) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerBefore(metadataFromHeaders, rangeFromHeaders),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	var cfg handlerConfig
+	for _, opt := range handlerOpts {
+		opt(&cfg)
+	}
+	throttle := newUploadThrottle(cfg.uploads, cfg.onThrottle)
+
+	r := chi.NewRouter()
+
+	r.Post("/algo", throttle.limit(agent.AgentService_Algo_FullMethodName, kithttp.NewServer(
+		authorize(authSvc, auth.AlgorithmProviderRole)(algoEndpoint(svc)),
+		decodeAlgoRequest,
+		encodeResponse,
+		opts...,
+	).ServeHTTP))
+
+	r.Post("/data", throttle.limit(agent.AgentService_Data_FullMethodName, kithttp.NewServer(
+		authorize(authSvc, auth.DataProviderRole)(dataEndpoint(svc)),
+		decodeDataRequest,
+		encodeResponse,
+		opts...,
+	).ServeHTTP))
+
+	r.Get("/result", kithttp.NewServer(
+		authorize(authSvc, auth.ConsumerRole)(resultEndpoint(svc)),
+		decodeResultRequest,
+		encodeFileResponse,
+		opts...,
+	).ServeHTTP)
+
+	r.Post("/attestation", kithttp.NewServer(
+		attestationEndpoint(svc),
+		decodeAttestationRequest,
+		encodeFileResponse,
+		opts...,
+	).ServeHTTP)
+
+	r.Get("/state", kithttp.NewServer(
+		stateEndpoint(svc),
+		decodeStateRequest,
+		encodeResponse,
+		opts...,
+	).ServeHTTP)
+
+	r.Get("/health", supermq.Health(svcName, instanceID))
+	r.Handle("/metrics", promhttp.Handler())
+
+	return r
+}
+
+// metadataFromHeaders copies the authentication headers into incoming gRPC
+// metadata so the same authenticator serves both transports.
+func metadataFromHeaders(ctx context.Context, r *http.Request) context.Context {
+	md := metadata.MD{}
+
+	if user := r.Header.Get(auth.UserMetadataKey); user != "" {
+		md.Set(auth.UserMetadataKey, user)
+	}
+
+	for _, key := range []string{auth.SignatureMetadataKey, auth.TimestampMetadataKey, auth.BodyDigestMetadataKey} {
+		if value := r.Header.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// rangeFromHeaders keeps the range headers of the request, so file responses
+// can be served partially to clients resuming a download.
+func rangeFromHeaders(ctx context.Context, r *http.Request) context.Context {
+	header := http.Header{}
+	for _, key := range []string{"Range", "If-Range"} {
+		if value := r.Header.Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+
+	return context.WithValue(ctx, rangeHeadersKey{}, header)
+}
+
+// appendMetadata adds key/value pairs to the incoming metadata, mirroring what
+// gRPC clients send alongside their requests.
+func appendMetadata(ctx context.Context, kv ...string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		md.Append(kv[i], kv[i+1])
+	}
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func decodeAlgoRequest(_ context.Context, r *http.Request) (any, error) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return nil, multipartError(err)
+	}
+
+	algo, err := readFormFile(r.MultipartForm, algorithmField)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements, err := readFormFile(r.MultipartForm, requirementsField)
+	if err != nil {
+		return nil, err
+	}
+
+	return algoReq{
+		Algorithm:    algo,
+		Requirements: requirements,
+		Spec: algorithm.Spec{
+			Type:              algorithm.AlgorithType(r.FormValue(algorithm.AlgoTypeKey)),
+			Entrypoint:        r.FormValue(algorithm.AlgoEntrypointKey),
+			Args:              r.MultipartForm.Value[algorithm.AlgoArgsKey],
+			Runtime:           r.FormValue(python.PyRuntimeKey),
+			MinRuntimeVersion: r.FormValue(algorithm.AlgoMinRuntimeVersionKey),
+		},
+	}, nil
+}
+
+func decodeDataRequest(_ context.Context, r *http.Request) (any, error) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return nil, multipartError(err)
+	}
+
+	dataset, err := readFormFile(r.MultipartForm, datasetField)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := r.FormValue(filenameField)
+	if filename == "" {
+		if files := r.MultipartForm.File[datasetField]; len(files) > 0 {
+			filename = files[0].Filename
+		}
+	}
+
+	var decompress bool
+	if val := r.FormValue(agent.DecompressKey); val != "" {
+		if decompress, err = strconv.ParseBool(val); err != nil {
+			return nil, errors.Wrap(ErrMalformedRequest, err)
+		}
+	}
+
+	return dataReq{
+		Dataset:    dataset,
+		Filename:   filename,
+		Decompress: decompress,
+	}, nil
+}
+
+func decodeResultRequest(_ context.Context, _ *http.Request) (any, error) {
+	return resultReq{}, nil
+}
+
+func decodeStateRequest(_ context.Context, _ *http.Request) (any, error) {
+	return stateReq{}, nil
+}
+
+func decodeAttestationRequest(_ context.Context, r *http.Request) (any, error) {
+	if r.Header.Get(contentType) != jsonContentType {
+		return nil, ErrUnsupportedContentType
+	}
+
+	var body attestationBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(ErrMalformedRequest, err)
+	}
+
+	req := attestationReq{AttType: attestation.PlatformType(body.Type), Refresh: body.Refresh}
+
+	teeNonce, err := base64.StdEncoding.DecodeString(body.TeeNonce)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedRequest, err)
+	}
+	if len(teeNonce) > quoteprovider.Nonce {
+		return nil, ErrNonceLength
+	}
+	copy(req.TeeNonce[:], teeNonce)
+
+	vtpmNonce, err := base64.StdEncoding.DecodeString(body.VtpmNonce)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedRequest, err)
+	}
+	if len(vtpmNonce) > vtpm.Nonce {
+		return nil, ErrNonceLength
+	}
+	copy(req.VtpmNonce[:], vtpmNonce)
+
+	return req, nil
+}
+
+// multipartError classifies the error parsing a multipart upload request.
+func multipartError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if stderrors.As(err, &maxBytesErr) {
+		return errors.Wrap(ErrUploadTooLarge, err)
+	}
+
+	return errors.Wrap(ErrUnsupportedContentType, err)
+}
+
+func readFormFile(form *multipart.Form, field string) ([]byte, error) {
+	files := form.File[field]
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	f, err := files[0].Open()
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedRequest, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformedRequest, err)
+	}
+
+	return data, nil
+}
+
+func encodeResponse(_ context.Context, w http.ResponseWriter, response any) error {
+	w.Header().Set(contentType, jsonContentType)
+
+	if ar, ok := response.(interface{ Code() int }); ok {
+		w.WriteHeader(ar.Code())
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// encodeFileResponse writes the file, or the byte range the request asked for.
+// The ETag is the hash of the file, so a range is only served if the file did
+// not change since the client started downloading it.
+func encodeFileResponse(ctx context.Context, w http.ResponseWriter, response any) error {
+	res := response.(fileRes)
+
+	sum := sha256.Sum256(res.File)
+	w.Header().Set(contentType, octetContentType)
+	w.Header().Set("ETag", strconv.Quote(hex.EncodeToString(sum[:])))
+
+	header, ok := ctx.Value(rangeHeadersKey{}).(http.Header)
+	if !ok {
+		header = http.Header{}
+	}
+
+	http.ServeContent(w, &http.Request{Method: http.MethodGet, Header: header}, "", time.Time{}, bytes.NewReader(res.File))
+
+	return nil
+}
+
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	w.Header().Set(contentType, jsonContentType)
+
+	switch {
+	case errors.Contains(err, ErrUploadTooLarge):
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errors.Contains(err, ErrTooManyUploads):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case errors.Contains(err, ErrUnsupportedContentType):
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	case errors.Contains(err, ErrMalformedRequest),
+		errors.Contains(err, ErrNonceLength),
+		errors.Contains(err, agent.ErrHashMismatch),
+		errors.Contains(err, agent.ErrFileNameMismatch),
+		errors.Contains(err, agent.ErrInvalidArchive),
+		errors.Contains(err, agent.ErrUndeclaredDataset),
+		errors.Contains(err, agent.ErrMalformedEntity),
+		errors.Contains(err, agent.ErrInvalidAlgorithmSpec),
+		errors.Contains(err, agent.ErrInvalidAlgorithmBundle),
+		errors.Contains(err, agent.ErrUnsupportedRuntime):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Contains(err, auth.ErrMissingMetadata),
+		errors.Contains(err, auth.ErrInvalidMetadata),
+		errors.Contains(err, auth.ErrSignatureVerificationFailed),
+		errors.Contains(err, auth.ErrRequestExpired),
+		errors.Contains(err, auth.ErrBodyDigestMismatch),
+		errors.Contains(err, agent.ErrUndeclaredConsumer),
+		errors.Contains(err, agent.ErrDatasetProviderMismatch):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Contains(err, agent.ErrStateNotReady),
+		errors.Contains(err, agent.ErrAlreadyAssigned),
+		errors.Contains(err, agent.ErrResultsNotReady),
+		errors.Contains(err, agent.ErrAllManifestItemsReceived),
+		errors.Contains(err, agent.ErrDatasetReceived):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Contains(err, agent.ErrStorageExceeded):
+		w.WriteHeader(http.StatusInsufficientStorage)
+	case errors.Contains(err, agent.ErrShuttingDown):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if encErr := json.NewEncoder(w).Encode(errorRes{Error: err.Error()}); encErr != nil {
+		w.Header().Set(contentType, "text/plain")
+		fmt.Fprint(w, err.Error())
+	}
+}
@@ -0,0 +1,493 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/python"
+	agentgrpc "github.com/ultravioletrs/cocos/agent/api/grpc"
+	"github.com/ultravioletrs/cocos/agent/auth"
+	authmocks "github.com/ultravioletrs/cocos/agent/auth/mocks"
+	"github.com/ultravioletrs/cocos/agent/mocks"
+	"github.com/ultravioletrs/cocos/pkg/server"
+)
+
+func newServer() (*httptest.Server, *mocks.Service, *authmocks.Authenticator) {
+	svc := new(mocks.Service)
+	authSvc := new(authmocks.Authenticator)
+
+	return httptest.NewServer(MakeHandler(svc, authSvc, "agent", "test")), svc, authSvc
+}
+
+func multipartBody(t *testing.T, files, fields map[string]string) (io.Reader, string) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	for name, content := range files {
+		fw, err := w.CreateFormFile(name, name+".txt")
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	for name, value := range fields {
+		assert.NoError(t, w.WriteField(name, value))
+	}
+
+	assert.NoError(t, w.Close())
+
+	return body, w.FormDataContentType()
+}
+
+func TestAlgo(t *testing.T) {
+	ts, svc, authSvc := newServer()
+	defer ts.Close()
+
+	cases := []struct {
+		desc    string
+		files   map[string]string
+		fields  map[string]string
+		spec    algorithm.Spec
+		authErr error
+		svcErr  error
+		status  int
+	}{
+		{
+			desc:   "upload algorithm successfully",
+			files:  map[string]string{algorithmField: "algo"},
+			status: http.StatusCreated,
+		},
+		{
+			desc:  "upload algorithm with spec",
+			files: map[string]string{algorithmField: "algo"},
+			fields: map[string]string{
+				algorithm.AlgoTypeKey:              string(algorithm.AlgoTypePython),
+				python.PyRuntimeKey:                "python3.11",
+				algorithm.AlgoMinRuntimeVersionKey: "3.10",
+			},
+			spec:   algorithm.Spec{Type: algorithm.AlgoTypePython, Runtime: "python3.11", MinRuntimeVersion: "3.10"},
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "upload algorithm with invalid spec",
+			files:  map[string]string{algorithmField: "algo"},
+			fields: map[string]string{algorithm.AlgoEntrypointKey: "main"},
+			spec:   algorithm.Spec{Entrypoint: "main"},
+			svcErr: agent.ErrInvalidAlgorithmSpec,
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "upload algorithm without file",
+			files:  map[string]string{},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:    "upload algorithm with failed authentication",
+			files:   map[string]string{algorithmField: "algo"},
+			authErr: auth.ErrSignatureVerificationFailed,
+			status:  http.StatusUnauthorized,
+		},
+		{
+			desc:   "upload algorithm in wrong state",
+			files:  map[string]string{algorithmField: "algo"},
+			svcErr: agent.ErrStateNotReady,
+			status: http.StatusConflict,
+		},
+		{
+			desc:   "upload algorithm while shutting down",
+			files:  map[string]string{algorithmField: "algo"},
+			svcErr: agent.ErrShuttingDown,
+			status: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			authCall := authSvc.On("AuthenticateUser", mock.Anything, auth.AlgorithmProviderRole).Return(context.Background(), tc.authErr)
+			var spec algorithm.Spec
+			svcCall := svc.On("Algo", mock.Anything, mock.Anything).Return(tc.svcErr).Run(func(args mock.Arguments) {
+				spec = args.Get(1).(agent.Algorithm).Spec
+			})
+
+			body, ct := multipartBody(t, tc.files, tc.fields)
+			res, err := http.Post(ts.URL+"/algo", ct, body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, res.StatusCode, tc.desc)
+			assert.Equal(t, tc.spec, spec, tc.desc)
+			res.Body.Close()
+
+			authCall.Unset()
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestData(t *testing.T) {
+	ts, svc, authSvc := newServer()
+	defer ts.Close()
+
+	cases := []struct {
+		desc   string
+		files  map[string]string
+		fields map[string]string
+		svcErr error
+		status int
+	}{
+		{
+			desc:   "upload dataset successfully",
+			files:  map[string]string{datasetField: "data"},
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "upload dataset with decompress",
+			files:  map[string]string{datasetField: "data"},
+			fields: map[string]string{agent.DecompressKey: "true"},
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "upload dataset with invalid decompress",
+			files:  map[string]string{datasetField: "data"},
+			fields: map[string]string{agent.DecompressKey: "invalid"},
+			status: http.StatusBadRequest,
+		},
+		{
+			desc:   "upload dataset with hash mismatch",
+			files:  map[string]string{datasetField: "data"},
+			svcErr: agent.ErrHashMismatch,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			authCall := authSvc.On("AuthenticateUser", mock.Anything, auth.DataProviderRole).Return(context.Background(), nil)
+			svcCall := svc.On("Data", mock.Anything, mock.Anything).Return(tc.svcErr)
+
+			body, ct := multipartBody(t, tc.files, tc.fields)
+			res, err := http.Post(ts.URL+"/data", ct, body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, res.StatusCode, tc.desc)
+			res.Body.Close()
+
+			authCall.Unset()
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	ts, svc, authSvc := newServer()
+	defer ts.Close()
+
+	result := []byte("result")
+	sum := sha256.Sum256(result)
+	etag := strconv.Quote(hex.EncodeToString(sum[:]))
+
+	cases := []struct {
+		desc    string
+		result  []byte
+		headers map[string]string
+		svcErr  error
+		status  int
+		body    []byte
+	}{
+		{
+			desc:   "fetch result successfully",
+			result: result,
+			status: http.StatusOK,
+			body:   result,
+		},
+		{
+			desc:    "fetch result range",
+			result:  result,
+			headers: map[string]string{"Range": "bytes=2-", "If-Range": etag},
+			status:  http.StatusPartialContent,
+			body:    []byte("sult"),
+		},
+		{
+			desc:    "fetch range of a changed result",
+			result:  result,
+			headers: map[string]string{"Range": "bytes=2-", "If-Range": `"stale"`},
+			status:  http.StatusOK,
+			body:    result,
+		},
+		{
+			desc:   "fetch result before it is ready",
+			svcErr: agent.ErrResultsNotReady,
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			authCall := authSvc.On("AuthenticateUser", mock.Anything, auth.ConsumerRole).Return(context.Background(), nil)
+			svcCall := svc.On("Result", mock.Anything).Return(tc.result, tc.svcErr)
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/result", nil)
+			assert.NoError(t, err)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, res.StatusCode, tc.desc)
+			if tc.svcErr == nil {
+				data, err := io.ReadAll(res.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.body, data)
+				assert.Equal(t, etag, res.Header.Get("ETag"))
+			}
+			res.Body.Close()
+
+			authCall.Unset()
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestAttestation(t *testing.T) {
+	ts, svc, _ := newServer()
+	defer ts.Close()
+
+	cases := []struct {
+		desc        string
+		body        string
+		contentType string
+		status      int
+	}{
+		{
+			desc:        "fetch attestation successfully",
+			body:        `{"tee_nonce":"","vtpm_nonce":"","type":0}`,
+			contentType: jsonContentType,
+			status:      http.StatusOK,
+		},
+		{
+			desc:        "fetch attestation with invalid content type",
+			body:        `{}`,
+			contentType: "text/plain",
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			desc:        "fetch attestation with malformed body",
+			body:        `{`,
+			contentType: jsonContentType,
+			status:      http.StatusBadRequest,
+		},
+		{
+			desc:        "fetch attestation with invalid nonce",
+			body:        `{"tee_nonce":"!!!"}`,
+			contentType: jsonContentType,
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			svcCall := svc.On("Attestation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]byte("report"), nil)
+
+			res, err := http.Post(ts.URL+"/attestation", tc.contentType, strings.NewReader(tc.body))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, res.StatusCode, tc.desc)
+			res.Body.Close()
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestAttestationRefresh(t *testing.T) {
+	ts, svc, _ := newServer()
+	defer ts.Close()
+
+	svc.On("Attestation", mock.MatchedBy(agent.RefreshAttestationFromContext), mock.Anything, mock.Anything, mock.Anything).Return([]byte("report"), nil)
+
+	res, err := http.Post(ts.URL+"/attestation", jsonContentType, strings.NewReader(`{"tee_nonce":"","vtpm_nonce":"","type":0,"refresh":true}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+	svc.AssertExpectations(t)
+}
+
+func TestState(t *testing.T) {
+	ts, svc, _ := newServer()
+	defer ts.Close()
+
+	svc.On("State").Return("ReceivingData")
+	svc.On("Datasets").Return([]agent.DatasetStatus{{Index: 0, Filename: "provider-a.csv", Hash: "ab12", Received: true}, {Index: 1, Filename: "provider-b.csv", Hash: "cd34"}})
+
+	res, err := http.Get(ts.URL + "/state")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var body stateRes
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, "ReceivingData", body.State)
+	assert.Len(t, body.Datasets, 2)
+	assert.False(t, body.Datasets[1].Received)
+}
+
+func TestMetrics(t *testing.T) {
+	ts, _, _ := newServer()
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/metrics")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	defer func(size int64) { maxUploadSize = size }(maxUploadSize)
+	maxUploadSize = 1024
+
+	ts, svc, authSvc := newServer()
+	defer ts.Close()
+
+	authSvc.On("AuthenticateUser", mock.Anything, mock.Anything).Return(context.Background(), nil)
+	svc.On("Algo", mock.Anything, mock.Anything).Return(nil)
+	svc.On("Data", mock.Anything, mock.Anything).Return(nil)
+
+	cases := []struct {
+		desc   string
+		path   string
+		field  string
+		size   int
+		status int
+	}{
+		{
+			desc:   "algorithm within the limit",
+			path:   "/algo",
+			field:  algorithmField,
+			size:   512,
+			status: http.StatusCreated,
+		},
+		{
+			desc:   "algorithm over the limit",
+			path:   "/algo",
+			field:  algorithmField,
+			size:   4096,
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:   "dataset over the limit",
+			path:   "/data",
+			field:  datasetField,
+			size:   4096,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			body, ct := multipartBody(t, map[string]string{tc.field: strings.Repeat("a", tc.size)}, nil)
+			res, err := http.Post(ts.URL+tc.path, ct, body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, res.StatusCode)
+			res.Body.Close()
+		})
+	}
+
+	svc.AssertNumberOfCalls(t, "Algo", 1)
+	svc.AssertNotCalled(t, "Data", mock.Anything, mock.Anything)
+}
+
+func TestUploadThrottle(t *testing.T) {
+	type throttled struct{ method, reason string }
+
+	newThrottledServer := func(limits server.UploadLimits) (*httptest.Server, *mocks.Service, chan throttled) {
+		svc := new(mocks.Service)
+		authSvc := new(authmocks.Authenticator)
+		authSvc.On("AuthenticateUser", mock.Anything, mock.Anything).Return(context.Background(), nil)
+
+		reports := make(chan throttled, 10)
+		handler := MakeHandler(svc, authSvc, "agent", "test", WithUploadLimits(limits, func(method, reason string) {
+			reports <- throttled{method, reason}
+		}))
+
+		return httptest.NewServer(handler), svc, reports
+	}
+
+	t.Run("concurrency", func(t *testing.T) {
+		ts, svc, reports := newThrottledServer(server.UploadLimits{MaxConcurrentUploads: 1})
+		defer ts.Close()
+
+		started, release := make(chan struct{}), make(chan struct{})
+		svc.On("Algo", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+			close(started)
+			<-release
+		}).Return(nil)
+		svc.On("Data", mock.Anything, mock.Anything).Return(nil)
+
+		done := make(chan int)
+		go func() {
+			body, ct := multipartBody(t, map[string]string{algorithmField: "algo"}, nil)
+			res, err := http.Post(ts.URL+"/algo", ct, body)
+			assert.NoError(t, err)
+			res.Body.Close()
+			done <- res.StatusCode
+		}()
+		<-started
+
+		body, ct := multipartBody(t, map[string]string{datasetField: "data"}, nil)
+		res, err := http.Post(ts.URL+"/data", ct, body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		res.Body.Close()
+		assert.Equal(t, throttled{agent.AgentService_Data_FullMethodName, agentgrpc.ThrottleConcurrency}, <-reports)
+
+		close(release)
+		assert.Equal(t, http.StatusCreated, <-done)
+
+		body, ct = multipartBody(t, map[string]string{datasetField: "data"}, nil)
+		res, err = http.Post(ts.URL+"/data", ct, body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode, "the upload is accepted once the other one finished")
+		res.Body.Close()
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		ts, svc, reports := newThrottledServer(server.UploadLimits{MaxUploadRate: 16 << 10})
+		defer ts.Close()
+
+		var received int
+		svc.On("Data", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			received = len(args.Get(1).(agent.Dataset).Dataset)
+		}).Return(nil)
+
+		body, ct := multipartBody(t, map[string]string{datasetField: strings.Repeat("a", 48<<10)}, nil)
+		start := time.Now()
+		res, err := http.Post(ts.URL+"/data", ct, body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		res.Body.Close()
+
+		assert.GreaterOrEqual(t, time.Since(start), time.Second, "the upload is read at the rate")
+		assert.Equal(t, 48<<10, received, "the upload is slowed down, not truncated")
+		assert.Equal(t, throttled{agent.AgentService_Data_FullMethodName, agentgrpc.ThrottleRate}, <-reports)
+		assert.Empty(t, reports, "the upload is reported once")
+	})
+}
@@ -38,6 +38,15 @@ func (lm *loggingMiddleware) State() (state string) {
 	return lm.svc.State()
 }
 
+// Datasets implements agent.Service.
+func (lm *loggingMiddleware) Datasets() (datasets []agent.DatasetStatus) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Datasets took %s to complete with %d datasets", time.Since(begin), len(datasets))
+		lm.logger.Debug(message)
+	}(time.Now())
+	return lm.svc.Datasets()
+}
+
 // InitComputation implements agent.Service.
 func (lm *loggingMiddleware) InitComputation(ctx context.Context, cmp agent.Computation) (err error) {
 	defer func(begin time.Time) {
@@ -66,6 +75,48 @@ func (lm *loggingMiddleware) StopComputation(ctx context.Context) (err error) {
 	return lm.svc.StopComputation(ctx)
 }
 
+// Restore implements agent.Service.
+func (lm *loggingMiddleware) Restore(ctx context.Context, sequence uint64) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Restore took %s to complete", time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s", message, err))
+			return
+		}
+		lm.logger.Info(fmt.Sprintf("%s without errors", message))
+	}(time.Now())
+
+	return lm.svc.Restore(ctx, sequence)
+}
+
+// ApproveAttestation implements agent.Service.
+func (lm *loggingMiddleware) ApproveAttestation(ctx context.Context, report, signature []byte) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method ApproveAttestation took %s to complete", time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s", message, err))
+			return
+		}
+		lm.logger.Info(fmt.Sprintf("%s without errors", message))
+	}(time.Now())
+
+	return lm.svc.ApproveAttestation(ctx, report, signature)
+}
+
+// Secrets implements agent.Service.
+func (lm *loggingMiddleware) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Secrets for %d secrets took %s to complete", len(secrets), time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s", message, err))
+			return
+		}
+		lm.logger.Info(fmt.Sprintf("%s without errors", message))
+	}(time.Now())
+
+	return lm.svc.Secrets(ctx, secrets, signature)
+}
+
 func (lm *loggingMiddleware) Algo(ctx context.Context, algorithm agent.Algorithm) (err error) {
 	defer func(begin time.Time) {
 		message := fmt.Sprintf("Method Algo took %s to complete", time.Since(begin))
@@ -92,6 +143,19 @@ func (lm *loggingMiddleware) Data(ctx context.Context, dataset agent.Dataset) (e
 	return lm.svc.Data(ctx, dataset)
 }
 
+func (lm *loggingMiddleware) AttachDatasetDisk(ctx context.Context, dir string) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method AttachDatasetDisk for %s took %s to complete", dir, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s", message, err))
+			return
+		}
+		lm.logger.Info(fmt.Sprintf("%s without errors", message))
+	}(time.Now())
+
+	return lm.svc.AttachDatasetDisk(ctx, dir)
+}
+
 func (lm *loggingMiddleware) Result(ctx context.Context) (response []byte, err error) {
 	defer func(begin time.Time) {
 		message := fmt.Sprintf("Method Result took %s to complete", time.Since(begin))
@@ -143,3 +207,17 @@ func (lm *loggingMiddleware) AzureAttestationToken(ctx context.Context, nonce [v
 
 	return lm.svc.AzureAttestationToken(ctx, nonce)
 }
+
+// Shutdown implements agent.Service.
+func (lm *loggingMiddleware) Shutdown(ctx context.Context) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Shutdown took %s to complete", time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s", message, err))
+			return
+		}
+		lm.logger.Info(fmt.Sprintf("%s without errors", message))
+	}(time.Now())
+
+	return lm.svc.Shutdown(ctx)
+}
@@ -19,18 +19,22 @@ import (
 var _ agent.Service = (*metricsMiddleware)(nil)
 
 type metricsMiddleware struct {
-	counter metrics.Counter
-	latency metrics.Histogram
-	svc     agent.Service
+	counter     metrics.Counter
+	latency     metrics.Histogram
+	uploads     metrics.Counter
+	uploadBytes metrics.Counter
+	svc         agent.Service
 }
 
 // MetricsMiddleware instruments core service by tracking request count and
-// latency.
-func MetricsMiddleware(svc agent.Service, counter metrics.Counter, latency metrics.Histogram) agent.Service {
+// latency, and the number and size of the accepted algorithm and dataset uploads.
+func MetricsMiddleware(svc agent.Service, counter metrics.Counter, latency metrics.Histogram, uploads, uploadBytes metrics.Counter) agent.Service {
 	return &metricsMiddleware{
-		counter: counter,
-		latency: latency,
-		svc:     svc,
+		counter:     counter,
+		latency:     latency,
+		uploads:     uploads,
+		uploadBytes: uploadBytes,
+		svc:         svc,
 	}
 }
 
@@ -44,6 +48,16 @@ func (ms *metricsMiddleware) State() string {
 	return ms.svc.State()
 }
 
+// Datasets implements agent.Service.
+func (ms *metricsMiddleware) Datasets() []agent.DatasetStatus {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "datasets").Add(1)
+		ms.latency.With("method", "datasets").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Datasets()
+}
+
 // InitComputation implements agent.Service.
 func (ms *metricsMiddleware) InitComputation(ctx context.Context, cmp agent.Computation) error {
 	defer func(begin time.Time) {
@@ -64,13 +78,50 @@ func (ms *metricsMiddleware) StopComputation(ctx context.Context) error {
 	return ms.svc.StopComputation(ctx)
 }
 
+// Restore implements agent.Service.
+func (ms *metricsMiddleware) Restore(ctx context.Context, sequence uint64) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "restore").Add(1)
+		ms.latency.With("method", "restore").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Restore(ctx, sequence)
+}
+
+// ApproveAttestation implements agent.Service.
+func (ms *metricsMiddleware) ApproveAttestation(ctx context.Context, report, signature []byte) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "approve_attestation").Add(1)
+		ms.latency.With("method", "approve_attestation").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.ApproveAttestation(ctx, report, signature)
+}
+
+// Secrets implements agent.Service.
+func (ms *metricsMiddleware) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "secrets").Add(1)
+		ms.latency.With("method", "secrets").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Secrets(ctx, secrets, signature)
+}
+
 func (ms *metricsMiddleware) Algo(ctx context.Context, algorithm agent.Algorithm) error {
 	defer func(begin time.Time) {
 		ms.counter.With("method", "algo").Add(1)
 		ms.latency.With("method", "algo").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return ms.svc.Algo(ctx, algorithm)
+	if err := ms.svc.Algo(ctx, algorithm); err != nil {
+		return err
+	}
+
+	ms.uploads.With("kind", "algorithm").Add(1)
+	ms.uploadBytes.With("kind", "algorithm").Add(float64(len(algorithm.Algorithm) + len(algorithm.Requirements)))
+
+	return nil
 }
 
 func (ms *metricsMiddleware) Data(ctx context.Context, dataset agent.Dataset) error {
@@ -79,7 +130,23 @@ func (ms *metricsMiddleware) Data(ctx context.Context, dataset agent.Dataset) er
 		ms.latency.With("method", "data").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return ms.svc.Data(ctx, dataset)
+	if err := ms.svc.Data(ctx, dataset); err != nil {
+		return err
+	}
+
+	ms.uploads.With("kind", "dataset").Add(1)
+	ms.uploadBytes.With("kind", "dataset").Add(float64(len(dataset.Dataset)))
+
+	return nil
+}
+
+func (ms *metricsMiddleware) AttachDatasetDisk(ctx context.Context, dir string) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "attach_dataset_disk").Add(1)
+		ms.latency.With("method", "attach_dataset_disk").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.AttachDatasetDisk(ctx, dir)
 }
 
 func (ms *metricsMiddleware) Result(ctx context.Context) ([]byte, error) {
@@ -117,3 +184,13 @@ func (ms *metricsMiddleware) IMAMeasurements(ctx context.Context) ([]byte, []byt
 
 	return ms.svc.IMAMeasurements(ctx)
 }
+
+// Shutdown implements agent.Service.
+func (ms *metricsMiddleware) Shutdown(ctx context.Context) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "shutdown").Add(1)
+		ms.latency.With("method", "shutdown").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Shutdown(ctx)
+}
@@ -0,0 +1,168 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/events"
+)
+
+const (
+	// approvalContext separates attestation approvals from the other signatures
+	// made with the key of the computation owner.
+	approvalContext = "cocos-attestation-approval"
+
+	// servedReportsSize bounds the number of reports the agent remembers for
+	// approvals, the oldest one is forgotten first.
+	servedReportsSize = 32
+)
+
+var (
+	// ErrInvalidApprovalKey indicates a manifest attestation approval key that is neither Ed25519 nor ECDSA.
+	ErrInvalidApprovalKey = errors.New("invalid attestation approval key")
+	// ErrApprovalNotRequired indicates an approval for a computation that does not require one.
+	ErrApprovalNotRequired = errors.New("computation does not require attestation approval")
+	// ErrApprovalSignature indicates an approval not signed by the manifest attestation approval key.
+	ErrApprovalSignature = errors.New("attestation approval is not signed by the computation owner")
+	// ErrAttestationNotApproved indicates an upload before the computation owner approved the attestation.
+	ErrAttestationNotApproved = errors.New("computation is waiting for the attestation approval of its owner")
+	// ErrApprovalReport indicates an approval of an attestation report the agent did not produce.
+	ErrApprovalReport = errors.New("approved attestation report was not produced by this agent")
+)
+
+// ApprovalSigningBytes returns the bytes the computation owner signs to
+// approve the attestation report: the approval context, the computation ID,
+// the hex encoded SHA-256 digest of the manifest SigningBytes and the hex
+// encoded SHA-256 digest of the report, one per line.
+func ApprovalSigningBytes(cmp Computation, report []byte) ([]byte, error) {
+	data, err := cmp.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	reportDigest := sha256.Sum256(report)
+
+	return []byte(approvalContext + "\n" + cmp.ID + "\n" + hex.EncodeToString(digest[:]) + "\n" + hex.EncodeToString(reportDigest[:])), nil
+}
+
+// SignApproval approves the attestation report of the agent running the
+// computation with an Ed25519 or ECDSA private key.
+func SignApproval(cmp Computation, report []byte, key crypto.Signer) ([]byte, error) {
+	data, err := ApprovalSigningBytes(cmp, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return sign(data, key)
+}
+
+// servedReports remembers the digests of the last attestation reports the
+// agent produced, so an approval relayed from another CVM running the same
+// manifest is refused.
+type servedReports struct {
+	mu      sync.Mutex
+	digests [][sha256.Size]byte
+}
+
+func (sr *servedReports) add(report []byte) {
+	digest := sha256.Sum256(report)
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if slices.Contains(sr.digests, digest) {
+		return
+	}
+	if len(sr.digests) == servedReportsSize {
+		sr.digests = sr.digests[1:]
+	}
+	sr.digests = append(sr.digests, digest)
+}
+
+func (sr *servedReports) contains(report []byte) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	return slices.Contains(sr.digests, sha256.Sum256(report))
+}
+
+// approvalKey parses the manifest attestation approval key, nil if the
+// computation does not require an approval.
+func approvalKey(cmp Computation) (crypto.PublicKey, error) {
+	if cmp.AttestationApproval == nil {
+		return nil, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(cmp.AttestationApproval.Key)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidApprovalKey, err)
+	}
+
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.Wrap(ErrInvalidApprovalKey, ErrUnsupportedKey)
+	}
+}
+
+// ApproveAttestation releases the algorithm and datasets of a computation
+// that requires the approval of its owner, once the signature over a report
+// the agent produced is verified.
+func (as *agentService) ApproveAttestation(ctx context.Context, report, signature []byte) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if !as.assigned {
+		return ErrStateNotReady
+	}
+
+	// The key was validated when the manifest was received.
+	key, _ := approvalKey(as.computation)
+	if key == nil {
+		return ErrApprovalNotRequired
+	}
+
+	if !as.reports.contains(report) {
+		return ErrApprovalReport
+	}
+
+	data, err := ApprovalSigningBytes(as.computation, report)
+	if err != nil {
+		return err
+	}
+	if !verifySignature(data, signature, []crypto.PublicKey{key}) {
+		return ErrApprovalSignature
+	}
+
+	if as.approved {
+		return nil
+	}
+	as.approved = true
+	as.persist(as.sm.GetState())
+
+	as.eventSvc.SendEvent(as.computation.ID, events.AttestationApproved, InProgress.String(), json.RawMessage{})
+
+	return nil
+}
+
+// checkApproved reports whether the computation may receive its algorithm and
+// datasets. It must be called with the service mutex held.
+func (as *agentService) checkApproved() error {
+	if as.computation.AttestationApproval != nil && !as.approved {
+		return ErrAttestationNotApproved
+	}
+
+	return nil
+}
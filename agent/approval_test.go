@@ -0,0 +1,257 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+)
+
+func TestApprovalKey(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edKey, err := x509.MarshalPKIXPublicKey(edPub)
+	require.NoError(t, err)
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := x509.MarshalPKIXPublicKey(&ecPriv.PublicKey)
+	require.NoError(t, err)
+
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaKey, err := x509.MarshalPKIXPublicKey(&rsaPriv.PublicKey)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc     string
+		approval *AttestationApproval
+		required bool
+		err      error
+	}{
+		{
+			desc: "approval not required",
+		},
+		{
+			desc:     "ed25519 key",
+			approval: &AttestationApproval{Key: edKey},
+			required: true,
+		},
+		{
+			desc:     "ecdsa key",
+			approval: &AttestationApproval{Key: ecKey},
+			required: true,
+		},
+		{
+			desc:     "rsa key",
+			approval: &AttestationApproval{Key: rsaKey},
+			err:      ErrInvalidApprovalKey,
+		},
+		{
+			desc:     "invalid key",
+			approval: &AttestationApproval{Key: []byte("invalid")},
+			err:      ErrInvalidApprovalKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			key, err := approvalKey(Computation{AttestationApproval: tc.approval})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.required, key != nil)
+		})
+	}
+}
+
+func TestApproveAttestation(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cmp := Computation{ID: "1", Name: "sample computation", AttestationApproval: &AttestationApproval{Key: key}}
+	report := []byte("attestation report")
+	otherReport := []byte("attestation report of another agent")
+
+	signature, err := SignApproval(cmp, report, priv)
+	require.NoError(t, err)
+	otherSignature, err := SignApproval(cmp, report, otherPriv)
+	require.NoError(t, err)
+	renamed := cmp
+	renamed.Name = "other computation"
+	renamedSignature, err := SignApproval(renamed, report, priv)
+	require.NoError(t, err)
+	otherReportSignature, err := SignApproval(cmp, otherReport, priv)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc        string
+		computation Computation
+		assigned    bool
+		report      []byte
+		signature   []byte
+		err         error
+	}{
+		{
+			desc:        "approve attestation",
+			computation: cmp,
+			assigned:    true,
+			report:      report,
+			signature:   signature,
+		},
+		{
+			desc:        "computation not assigned",
+			computation: cmp,
+			report:      report,
+			signature:   signature,
+			err:         ErrStateNotReady,
+		},
+		{
+			desc:        "approval not required",
+			computation: Computation{ID: "1"},
+			assigned:    true,
+			report:      report,
+			signature:   signature,
+			err:         ErrApprovalNotRequired,
+		},
+		{
+			desc:        "signed by another key",
+			computation: cmp,
+			assigned:    true,
+			report:      report,
+			signature:   otherSignature,
+			err:         ErrApprovalSignature,
+		},
+		{
+			desc:        "signed for another manifest",
+			computation: cmp,
+			assigned:    true,
+			report:      report,
+			signature:   renamedSignature,
+			err:         ErrApprovalSignature,
+		},
+		{
+			desc:        "report not produced by the agent",
+			computation: cmp,
+			assigned:    true,
+			report:      otherReport,
+			signature:   otherReportSignature,
+			err:         ErrApprovalReport,
+		},
+		{
+			desc:        "signed for another report",
+			computation: cmp,
+			assigned:    true,
+			report:      report,
+			signature:   otherReportSignature,
+			err:         ErrApprovalSignature,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(ReceivingAlgorithm)
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "1", events.AttestationApproved, InProgress.String(), mock.Anything).Return().Maybe()
+
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: tc.computation,
+				assigned:    tc.assigned,
+			}
+			svc.reports.add(report)
+
+			err := svc.ApproveAttestation(context.Background(), tc.report, tc.signature)
+			assert.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, svc.checkApproved())
+			assert.ErrorIs(t, svc.Algo(context.Background(), Algorithm{}), ErrHashMismatch, "the algorithm is accepted once approved")
+			eventSvc.AssertNumberOfCalls(t, "SendEvent", 1)
+		})
+	}
+}
+
+func TestServedReports(t *testing.T) {
+	var reports servedReports
+	for i := range servedReportsSize + 1 {
+		reports.add([]byte{byte(i)})
+	}
+	reports.add([]byte{servedReportsSize})
+
+	assert.False(t, reports.contains([]byte{0}), "the oldest report is forgotten")
+	assert.True(t, reports.contains([]byte{1}))
+	assert.True(t, reports.contains([]byte{servedReportsSize}))
+	assert.False(t, reports.contains([]byte("unknown")))
+	assert.Len(t, reports.digests, servedReportsSize, "a report served again is remembered once")
+}
+
+func TestUploadsWaitForApproval(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	cmp := Computation{ID: "1", AttestationApproval: &AttestationApproval{Key: key}, Datasets: []Dataset{{Filename: "data.csv"}}}
+
+	cases := []struct {
+		desc   string
+		state  AgentState
+		upload func(svc *agentService) error
+	}{
+		{
+			desc:  "algorithm",
+			state: ReceivingAlgorithm,
+			upload: func(svc *agentService) error {
+				return svc.Algo(context.Background(), Algorithm{Algorithm: []byte("algorithm")})
+			},
+		},
+		{
+			desc:  "dataset",
+			state: ReceivingData,
+			upload: func(svc *agentService) error {
+				return svc.Data(context.Background(), Dataset{Dataset: []byte("data"), Filename: "data.csv"})
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(tc.state)
+
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				computation: cmp,
+				assigned:    true,
+				received:    make([]bool, len(cmp.Datasets)),
+			}
+
+			assert.ErrorIs(t, tc.upload(svc), ErrAttestationNotApproved)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	attestation_client "github.com/ultravioletrs/cocos/pkg/clients/grpc/attestation"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RefreshAttestationKey is the request metadata that bypasses the
+	// attestation cache, so the report is generated again.
+	RefreshAttestationKey = "refresh-attestation"
+
+	// attestationCacheSize bounds the number of cached reports, the oldest
+	// one is evicted first.
+	attestationCacheSize = 32
+
+	attestationCacheHit  = "hit"
+	attestationCacheMiss = "miss"
+)
+
+// attestationKey identifies a report by the data it binds.
+type attestationKey struct {
+	reportData [64]byte
+	nonce      [32]byte
+	attType    attestation.PlatformType
+}
+
+type cachedAttestation struct {
+	report    []byte
+	createdAt time.Time
+}
+
+type attestationCache struct {
+	attestation_client.Client
+	maxAge  time.Duration
+	lookups metrics.Counter
+	group   singleflight.Group
+
+	mu      sync.Mutex
+	reports map[attestationKey]cachedAttestation
+}
+
+// NewAttestationCache returns an attestation client serving reports generated
+// by client for the same report data, vTPM nonce and platform for maxAge,
+// and generating a report only once for concurrent requests of the same one.
+// Cache lookups are counted with the hit or miss result. Caching is disabled
+// when maxAge is not positive.
+func NewAttestationCache(client attestation_client.Client, maxAge time.Duration, lookups metrics.Counter) attestation_client.Client {
+	if maxAge <= 0 {
+		return client
+	}
+
+	return &attestationCache{
+		Client:  client,
+		maxAge:  maxAge,
+		lookups: lookups,
+		reports: make(map[attestationKey]cachedAttestation),
+	}
+}
+
+// GetAttestation returns the cached report unless it is older than the max
+// age or the request metadata asks for a refresh.
+func (c *attestationCache) GetAttestation(ctx context.Context, reportData [64]byte, nonce [32]byte, attType attestation.PlatformType) ([]byte, error) {
+	key := attestationKey{reportData: reportData, nonce: nonce, attType: attType}
+
+	if !RefreshAttestationFromContext(ctx) {
+		if report, ok := c.get(key); ok {
+			c.lookups.With("result", attestationCacheHit).Add(1)
+			return report, nil
+		}
+	}
+	c.lookups.With("result", attestationCacheMiss).Add(1)
+
+	report, err, _ := c.group.Do(fmt.Sprintf("%x/%x/%d", reportData, nonce, attType), func() (any, error) {
+		report, err := c.Client.GetAttestation(ctx, reportData, nonce, attType)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, report)
+
+		return report, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report.([]byte), nil
+}
+
+func (c *attestationCache) get(key attestationKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.reports[key]
+	if !ok || time.Since(cached.createdAt) > c.maxAge {
+		return nil, false
+	}
+
+	return cached.report, true
+}
+
+func (c *attestationCache) put(key attestationKey, report []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, cached := range c.reports {
+		if time.Since(cached.createdAt) > c.maxAge {
+			delete(c.reports, k)
+		}
+	}
+
+	if _, ok := c.reports[key]; !ok && len(c.reports) >= attestationCacheSize {
+		var oldest attestationKey
+		var oldestAt time.Time
+		for k, cached := range c.reports {
+			if oldestAt.IsZero() || cached.createdAt.Before(oldestAt) {
+				oldest, oldestAt = k, cached.createdAt
+			}
+		}
+		delete(c.reports, oldest)
+	}
+
+	c.reports[key] = cachedAttestation{report: report, createdAt: time.Now()}
+}
+
+// RefreshAttestationFromContext reports whether the incoming request metadata
+// asks for the attestation report to be generated again.
+func RefreshAttestationFromContext(ctx context.Context) bool {
+	vals := metadata.ValueFromIncomingContext(ctx, RefreshAttestationKey)
+	if len(vals) == 0 {
+		return false
+	}
+
+	return vals[0] == "true"
+}
+
+// RefreshAttestationToContext sets the outgoing request metadata asking the
+// agent to generate the attestation report again instead of serving a cached one.
+func RefreshAttestationToContext(ctx context.Context, refresh bool) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, RefreshAttestationKey, fmt.Sprintf("%t", refresh))
+}
@@ -0,0 +1,166 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"google.golang.org/grpc/metadata"
+)
+
+// lookupCounter counts the cache lookups by result.
+type lookupCounter struct {
+	mu      *sync.Mutex
+	result  string
+	lookups map[string]int
+}
+
+func newLookupCounter() *lookupCounter {
+	return &lookupCounter{mu: &sync.Mutex{}, lookups: make(map[string]int)}
+}
+
+func (c *lookupCounter) With(labelValues ...string) metrics.Counter {
+	return &lookupCounter{mu: c.mu, result: labelValues[len(labelValues)-1], lookups: c.lookups}
+}
+
+func (c *lookupCounter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups[c.result] += int(delta)
+}
+
+func (c *lookupCounter) count(result string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookups[result]
+}
+
+// refreshContext returns the context of a request whose metadata asks for a refresh.
+func refreshContext() context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(RefreshAttestationKey, "true"))
+}
+
+func TestAttestationCache(t *testing.T) {
+	reportData := [64]byte{1}
+	nonce := [32]byte{2}
+
+	cases := []struct {
+		desc    string
+		maxAge  time.Duration
+		wait    time.Duration
+		ctx     context.Context
+		second  [64]byte
+		fetches int
+		hits    int
+	}{
+		{
+			desc:    "same report data",
+			maxAge:  time.Minute,
+			ctx:     context.Background(),
+			second:  reportData,
+			fetches: 1,
+			hits:    1,
+		},
+		{
+			desc:    "other report data",
+			maxAge:  time.Minute,
+			ctx:     context.Background(),
+			second:  [64]byte{3},
+			fetches: 2,
+		},
+		{
+			desc:    "expired report",
+			maxAge:  10 * time.Millisecond,
+			wait:    20 * time.Millisecond,
+			ctx:     context.Background(),
+			second:  reportData,
+			fetches: 2,
+		},
+		{
+			desc:    "forced refresh",
+			maxAge:  time.Minute,
+			ctx:     refreshContext(),
+			second:  reportData,
+			fetches: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client := new(MockAttestationClient)
+			client.On("GetAttestation", mock.Anything, mock.Anything, nonce, attestation.SNP).Return([]byte("report"), nil)
+			lookups := newLookupCounter()
+
+			cache := NewAttestationCache(client, tc.maxAge, lookups)
+
+			report, err := cache.GetAttestation(context.Background(), reportData, nonce, attestation.SNP)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("report"), report)
+
+			time.Sleep(tc.wait)
+			report, err = cache.GetAttestation(tc.ctx, tc.second, nonce, attestation.SNP)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("report"), report)
+
+			client.AssertNumberOfCalls(t, "GetAttestation", tc.fetches)
+			assert.Equal(t, tc.hits, lookups.count(attestationCacheHit))
+			assert.Equal(t, 2-tc.hits, lookups.count(attestationCacheMiss))
+		})
+	}
+}
+
+func TestAttestationCacheErrors(t *testing.T) {
+	errQuote := errors.New("quote provider unavailable")
+
+	client := new(MockAttestationClient)
+	client.On("GetAttestation", mock.Anything, mock.Anything, mock.Anything, attestation.SNP).Return([]byte(nil), errQuote).Once()
+	client.On("GetAttestation", mock.Anything, mock.Anything, mock.Anything, attestation.SNP).Return([]byte("report"), nil).Once()
+
+	cache := NewAttestationCache(client, time.Minute, discard.NewCounter())
+
+	_, err := cache.GetAttestation(context.Background(), [64]byte{1}, [32]byte{}, attestation.SNP)
+	assert.ErrorIs(t, err, errQuote)
+
+	report, err := cache.GetAttestation(context.Background(), [64]byte{1}, [32]byte{}, attestation.SNP)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("report"), report, "failures are not cached")
+}
+
+func TestAttestationCacheEviction(t *testing.T) {
+	client := new(MockAttestationClient)
+	client.On("GetAttestation", mock.Anything, mock.Anything, mock.Anything, attestation.SNP).Return([]byte("report"), nil)
+
+	cache := NewAttestationCache(client, time.Minute, discard.NewCounter()).(*attestationCache)
+	for i := range attestationCacheSize + 1 {
+		_, err := cache.GetAttestation(context.Background(), [64]byte{byte(i)}, [32]byte{}, attestation.SNP)
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, cache.reports, attestationCacheSize)
+	_, ok := cache.get(attestationKey{reportData: [64]byte{0}, attType: attestation.SNP})
+	assert.False(t, ok, "the oldest report is evicted")
+}
+
+func TestAttestationCacheDisabled(t *testing.T) {
+	client := new(MockAttestationClient)
+	assert.Same(t, client, NewAttestationCache(client, 0, discard.NewCounter()))
+}
+
+func TestRefreshAttestationContext(t *testing.T) {
+	assert.False(t, RefreshAttestationFromContext(context.Background()))
+	assert.True(t, RefreshAttestationFromContext(refreshContext()))
+
+	md, ok := metadata.FromOutgoingContext(RefreshAttestationToContext(context.Background(), true))
+	require.True(t, ok)
+	assert.Equal(t, []string{"true"}, md.Get(RefreshAttestationKey))
+}
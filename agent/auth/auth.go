@@ -12,6 +12,10 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/ultravioletrs/cocos/agent"
@@ -25,17 +29,27 @@ type UserRole string
 const (
 	UserMetadataKey                = "user-id"
 	SignatureMetadataKey           = "signature"
+	TimestampMetadataKey           = "timestamp"
+	BodyDigestMetadataKey          = "body-digest"
 	ConsumerRole          UserRole = "consumer"
 	DataProviderRole      UserRole = "data-provider"
 	AlgorithmProviderRole UserRole = "algorithm-provider"
 )
 
+// MaxRequestAge is how far the timestamp of a signed request may be from the
+// agent clock, which bounds the window in which a request can be replayed.
+const MaxRequestAge = 5 * time.Minute
+
 var (
 	ErrMissingMetadata             = errors.New("missing metadata")
 	ErrInvalidMetadata             = errors.New("invalid metadata")
 	ErrSignatureVerificationFailed = errors.New("signature verification failed")
+	ErrRequestExpired              = errors.New("signed request timestamp is outside the accepted window")
+	ErrBodyDigestMismatch          = errors.New("request body does not match its signed digest")
 )
 
+type bodyDigestKey struct{}
+
 type Authenticator interface {
 	AuthenticateUser(ctx context.Context, role UserRole) (context.Context, error)
 }
@@ -90,17 +104,78 @@ func New(manifest agent.Computation) (Authenticator, error) {
 	return s, nil
 }
 
-func extractSignature(md metadata.MD) (string, error) {
-	signature := md.Get(SignatureMetadataKey)
-	if len(signature) != 1 {
+// BodyDigest returns the hex encoded digest of a request body made of parts,
+// the SHA-256 hash of the concatenated SHA-256 hashes of every part.
+func BodyDigest(parts ...[]byte) string {
+	digest := sha256.New()
+	for _, part := range parts {
+		sum := sha256.Sum256(part)
+		digest.Write(sum[:])
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// ReaderDigest returns the BodyDigest of the parts read from readers.
+func ReaderDigest(parts ...io.Reader) (string, error) {
+	digest := sha256.New()
+	for _, part := range parts {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, part); err != nil {
+			return "", err
+		}
+		digest.Write(hash.Sum(nil))
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// SigningPayload returns the bytes a caller signs with its manifest key: the
+// role, the Unix timestamp of the request and the digest of its body.
+func SigningPayload(role UserRole, timestamp, digest string) []byte {
+	return []byte(string(role) + "\n" + timestamp + "\n" + digest)
+}
+
+// VerifyBody checks that the request body parts match the digest signed by
+// the authenticated caller. Requests that were not authenticated carry no digest.
+func VerifyBody(ctx context.Context, parts ...[]byte) error {
+	digest, ok := ctx.Value(bodyDigestKey{}).(string)
+	if !ok {
+		return nil
+	}
+
+	if BodyDigest(parts...) != digest {
+		return ErrBodyDigestMismatch
+	}
+
+	return nil
+}
+
+func extractMetadata(md metadata.MD, key string) (string, error) {
+	values := md.Get(key)
+	if len(values) != 1 {
 		return "", status.Errorf(codes.Unauthenticated, "invalid metadata")
 	}
 
-	return signature[0], nil
+	return values[0], nil
+}
+
+func checkTimestamp(timestamp string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Wrap(ErrInvalidMetadata, err)
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > MaxRequestAge || age < -MaxRequestAge {
+		return ErrRequestExpired
+	}
+
+	return nil
 }
 
-func verifySignature(role UserRole, signature string, publicKey any) error {
-	hash := sha256.Sum256([]byte(role))
+func verifySignature(payload []byte, signature string, publicKey any) error {
+	hash := sha256.Sum256(payload)
 	sigByte, err := base64.StdEncoding.DecodeString(signature)
 	if err != nil {
 		return err
@@ -117,7 +192,7 @@ func verifySignature(role UserRole, signature string, publicKey any) error {
 	case *ecdsa.PublicKey:
 		ok = ecdsa.VerifyASN1(publicKey, hash[:], sigByte)
 	case ed25519.PublicKey:
-		ok = ed25519.Verify(publicKey, []byte(role), sigByte)
+		ok = ed25519.Verify(publicKey, payload, sigByte)
 	}
 
 	if !ok {
@@ -127,31 +202,46 @@ func verifySignature(role UserRole, signature string, publicKey any) error {
 	return nil
 }
 
+// AuthenticateUser verifies that the request is signed by a key of the role
+// and recent, and records the signed body digest for VerifyBody.
 func (s *service) AuthenticateUser(ctx context.Context, role UserRole) (context.Context, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return nil, ErrMissingMetadata
 	}
-	signature, err := extractSignature(md)
-	if err != nil {
-		return nil, errors.Wrap(err, ErrInvalidMetadata)
+
+	var values [3]string
+	for i, key := range []string{SignatureMetadataKey, TimestampMetadataKey, BodyDigestMetadataKey} {
+		value, err := extractMetadata(md, key)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrInvalidMetadata)
+		}
+		values[i] = value
 	}
+	signature, timestamp, digest := values[0], values[1], values[2]
+
+	if err := checkTimestamp(timestamp, time.Now()); err != nil {
+		return nil, err
+	}
+
+	payload := SigningPayload(role, timestamp, digest)
+	ctx = context.WithValue(ctx, bodyDigestKey{}, digest)
 
 	switch role {
 	case ConsumerRole:
 		for i, rc := range s.resultConsumers {
-			if err := verifySignature(role, signature, rc); err == nil {
+			if err := verifySignature(payload, signature, rc); err == nil {
 				return agent.IndexToContext(ctx, i), nil
 			}
 		}
 	case DataProviderRole:
-		for _, dp := range s.datasetProviders {
-			if err := verifySignature(role, signature, dp); err == nil {
-				return ctx, nil
+		for i, dp := range s.datasetProviders {
+			if err := verifySignature(payload, signature, dp); err == nil {
+				return agent.IndexToContext(ctx, i), nil
 			}
 		}
 	case AlgorithmProviderRole:
-		if err := verifySignature(role, signature, s.algorithmProvider); err == nil {
+		if err := verifySignature(payload, signature, s.algorithmProvider); err == nil {
 			return ctx, nil
 		}
 	}
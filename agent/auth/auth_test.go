@@ -13,7 +13,10 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -52,10 +55,15 @@ func TestAuthenticateUser(t *testing.T) {
 		t.Fatalf("failed to create authenticator: %v", err)
 	}
 
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	digest := BodyDigest([]byte("algorithm"))
+
 	testCases := []struct {
 		name        string
 		role        UserRole
 		key         any
+		timestamp   string
+		md          func(signature, timestamp string) metadata.MD
 		expectedErr error
 	}{
 		{
@@ -89,34 +97,84 @@ func TestAuthenticateUser(t *testing.T) {
 			expectedErr: ErrSignatureVerificationFailed,
 		},
 		{
-			name:        "missing signature",
-			role:        ConsumerRole,
-			key:         resultConsumerKey,
+			name: "tampered body digest",
+			role: AlgorithmProviderRole,
+			key:  algorithmProviderKey,
+			md: func(signature, timestamp string) metadata.MD {
+				return metadata.Pairs(SignatureMetadataKey, signature, TimestampMetadataKey, timestamp, BodyDigestMetadataKey, BodyDigest([]byte("other")))
+			},
+			expectedErr: ErrSignatureVerificationFailed,
+		},
+		{
+			name:        "expired request",
+			role:        AlgorithmProviderRole,
+			key:         algorithmProviderKey,
+			timestamp:   strconv.FormatInt(time.Now().Add(-2*MaxRequestAge).Unix(), 10),
+			expectedErr: ErrRequestExpired,
+		},
+		{
+			name:        "request from the future",
+			role:        AlgorithmProviderRole,
+			key:         algorithmProviderKey,
+			timestamp:   strconv.FormatInt(time.Now().Add(2*MaxRequestAge).Unix(), 10),
+			expectedErr: ErrRequestExpired,
+		},
+		{
+			name:        "malformed timestamp",
+			role:        AlgorithmProviderRole,
+			key:         algorithmProviderKey,
+			timestamp:   "yesterday",
 			expectedErr: ErrInvalidMetadata,
 		},
 		{
-			name:        "missing metadata",
-			role:        ConsumerRole,
-			key:         resultConsumerKey,
+			name: "missing body digest",
+			role: AlgorithmProviderRole,
+			key:  algorithmProviderKey,
+			md: func(signature, timestamp string) metadata.MD {
+				return metadata.Pairs(SignatureMetadataKey, signature, TimestampMetadataKey, timestamp)
+			},
+			expectedErr: ErrInvalidMetadata,
+		},
+		{
+			name: "missing signature",
+			role: ConsumerRole,
+			key:  resultConsumerKey,
+			md: func(string, string) metadata.MD {
+				return metadata.Pairs()
+			},
+			expectedErr: ErrInvalidMetadata,
+		},
+		{
+			name: "missing metadata",
+			role: ConsumerRole,
+			key:  resultConsumerKey,
+			md: func(string, string) metadata.MD {
+				return nil
+			},
 			expectedErr: ErrMissingMetadata,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			signature, err := signRole(tc.role, tc.key)
+			timestamp := tc.timestamp
+			if timestamp == "" {
+				timestamp = now
+			}
+
+			signature, err := signRequest(tc.role, timestamp, digest, tc.key)
 			if err != nil {
-				t.Fatalf("failed to sign role: %v", err)
+				t.Fatalf("failed to sign request: %v", err)
 			}
 
-			ctx := context.Background()
+			md := metadata.Pairs(SignatureMetadataKey, signature, TimestampMetadataKey, timestamp, BodyDigestMetadataKey, digest)
+			if tc.md != nil {
+				md = tc.md(signature, timestamp)
+			}
 
-			switch tc.name {
-			case "missing signature":
-				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs())
-			case "missing metadata":
-			default:
-				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(SignatureMetadataKey, signature))
+			ctx := context.Background()
+			if md != nil {
+				ctx = metadata.NewIncomingContext(ctx, md)
 			}
 
 			ctx, err = auth.AuthenticateUser(ctx, tc.role)
@@ -124,26 +182,43 @@ func TestAuthenticateUser(t *testing.T) {
 
 			if err == nil {
 				switch id, ok := agent.IndexFromContext(ctx); {
-				case tc.role == ConsumerRole:
+				case tc.role == ConsumerRole, tc.role == DataProviderRole:
 					assert.True(t, ok, "expected index in context")
 					assert.Equal(t, 0, id, "expected index 0 in context")
 				default:
 					assert.False(t, ok, "expected no index in context")
 				}
+
+				assert.NoError(t, VerifyBody(ctx, []byte("algorithm")))
+				assert.True(t, errors.Contains(VerifyBody(ctx, []byte("tampered")), ErrBodyDigestMismatch))
 			}
 		})
 	}
 }
 
-func signRole(role UserRole, key crypto.PrivateKey) (string, error) {
+func TestBodyDigest(t *testing.T) {
+	digest := BodyDigest([]byte("algorithm"), []byte("requirements"))
+
+	fromReaders, err := ReaderDigest(strings.NewReader("algorithm"), strings.NewReader("requirements"))
+	require.NoError(t, err)
+	assert.Equal(t, digest, fromReaders)
+
+	assert.NotEqual(t, digest, BodyDigest([]byte("algorithmrequirements")), "parts are not concatenated")
+	assert.NotEqual(t, digest, BodyDigest([]byte("algorithm"), []byte("requirement"), []byte("s")))
+	assert.NoError(t, VerifyBody(context.Background(), []byte("unauthenticated")), "unauthenticated requests carry no digest")
+}
+
+func signRequest(role UserRole, timestamp, digest string, key crypto.PrivateKey) (string, error) {
+	payload := SigningPayload(role, timestamp, digest)
+
 	var signature []byte
 	var err error
 
 	switch k := key.(type) {
 	case ed25519.PrivateKey:
-		signature, err = k.Sign(rand.Reader, []byte(role), crypto.Hash(0))
+		signature, err = k.Sign(rand.Reader, payload, crypto.Hash(0))
 	case *rsa.PrivateKey, *ecdsa.PrivateKey:
-		hash := sha256.Sum256([]byte(role))
+		hash := sha256.Sum256(payload)
 		signer := key.(crypto.Signer)
 		signature, err = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
 	default:
@@ -0,0 +1,344 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+const (
+	checkpointExt = ".ckpt"
+	// checkpointKeySize is the size of the AES-256 key checkpoints are sealed with.
+	checkpointKeySize = 32
+	// checkpointSeqSize is the size of the big endian sequence checkpoints start with.
+	checkpointSeqSize = 8
+)
+
+// checkpointDir is where checkpoints are written, the mount point of the
+// checkpoint disk the host shares with the CVM.
+var checkpointDir = "/var/lib/cocos/checkpoints"
+
+var (
+	// ErrInvalidCheckpoint indicates a manifest checkpoint without a positive interval or a valid key secret name.
+	ErrInvalidCheckpoint = errors.New("invalid computation checkpoint")
+	// ErrCheckpointDisabled indicates a restore for a computation that is not checkpointed.
+	ErrCheckpointDisabled = errors.New("computation checkpoints are disabled")
+	// ErrCheckpointNotFound indicates there is no checkpoint of the computation to restore.
+	ErrCheckpointNotFound = errors.New("computation checkpoint not found")
+	// ErrCheckpointKey indicates a checkpoint key secret that is not provisioned or is not a base64 encoded 32-byte key.
+	ErrCheckpointKey = errors.New("invalid computation checkpoint key")
+	// ErrCheckpointCorrupted indicates a checkpoint that cannot be authenticated or extracted.
+	ErrCheckpointCorrupted = errors.New("computation checkpoint is corrupted")
+	// ErrCheckpointStale indicates a checkpoint older than the sequence the owner restores.
+	ErrCheckpointStale = errors.New("computation checkpoint is older than the requested sequence")
+)
+
+// checkpointConfig parses the manifest checkpoint interval and checks the
+// name of its key secret, a zero interval means the computation is not checkpointed.
+func checkpointConfig(cmp Computation) (time.Duration, error) {
+	if cmp.Checkpoint == nil {
+		return 0, nil
+	}
+
+	if cmp.Checkpoint.Interval == "" {
+		return 0, errors.Wrap(ErrInvalidCheckpoint, fmt.Errorf("interval is required"))
+	}
+	interval, err := positiveDuration(cmp.Checkpoint.Interval, ErrInvalidCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	if !secretNameRegexp.MatchString(cmp.Checkpoint.KeySecret) {
+		return 0, errors.Wrap(ErrInvalidCheckpoint, fmt.Errorf("key secret name %q", cmp.Checkpoint.KeySecret))
+	}
+	if cmp.AttestationApproval == nil {
+		return 0, errors.Wrap(ErrInvalidCheckpoint, errors.New("the key secret requires an attestation approval key"))
+	}
+
+	return interval, nil
+}
+
+// checkpointKey returns the AEAD checkpoints are sealed with, keyed with the
+// owner secret named in the manifest checkpoint, nil if the computation is
+// not checkpointed.
+func (as *agentService) checkpointKey() (cipher.AEAD, error) {
+	if as.computation.Checkpoint == nil {
+		return nil, nil
+	}
+
+	secret, err := as.readSecret(as.computation.Checkpoint.KeySecret)
+	if err != nil {
+		return nil, errors.Wrap(ErrCheckpointKey, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, errors.Wrap(ErrCheckpointKey, err)
+	}
+	if len(key) != checkpointKeySize {
+		return nil, errors.Wrap(ErrCheckpointKey, fmt.Errorf("key must be %d bytes", checkpointKeySize))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(ErrCheckpointKey, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(ErrCheckpointKey, err)
+	}
+
+	return aead, nil
+}
+
+func checkpointPath(cmpID string) string {
+	return filepath.Join(checkpointDir, cmpID+checkpointExt)
+}
+
+// checkpointAAD binds a checkpoint to its computation and sequence, so the
+// host can neither swap the checkpoints of computations nor renumber them.
+func checkpointAAD(cmpID string, seq uint64) []byte {
+	return fmt.Appendf(nil, "cocos-checkpoint\n%s\n%d", cmpID, seq)
+}
+
+// sealCheckpoint returns the archive sealed with the key, preceded by its
+// sequence and nonce.
+func sealCheckpoint(key cipher.AEAD, cmpID string, seq uint64, archive []byte) ([]byte, error) {
+	out := make([]byte, checkpointSeqSize+key.NonceSize(), checkpointSeqSize+key.NonceSize()+len(archive)+key.Overhead())
+	binary.BigEndian.PutUint64(out, seq)
+	if _, err := rand.Read(out[checkpointSeqSize:]); err != nil {
+		return nil, err
+	}
+
+	return key.Seal(out, out[checkpointSeqSize:], archive, checkpointAAD(cmpID, seq)), nil
+}
+
+// openCheckpoint authenticates the checkpoint of the computation with the key
+// and returns its sequence and archive.
+func openCheckpoint(key cipher.AEAD, cmpID string, data []byte) (uint64, []byte, error) {
+	if len(data) < checkpointSeqSize+key.NonceSize() {
+		return 0, nil, errors.New("checkpoint is too short")
+	}
+
+	seq := binary.BigEndian.Uint64(data)
+	nonce, sealed := data[checkpointSeqSize:checkpointSeqSize+key.NonceSize()], data[checkpointSeqSize+key.NonceSize():]
+	archive, err := key.Open(nil, nonce, sealed, checkpointAAD(cmpID, seq))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return seq, archive, nil
+}
+
+// lastCheckpointSeq returns the sequence of the saved checkpoint of the
+// computation, 0 if there is none or it cannot be authenticated.
+func lastCheckpointSeq(key cipher.AEAD, cmpID string) uint64 {
+	data, err := os.ReadFile(checkpointPath(cmpID))
+	if err != nil {
+		return 0
+	}
+	seq, _, err := openCheckpoint(key, cmpID, data)
+	if err != nil {
+		return 0
+	}
+
+	return seq
+}
+
+// checkpointPeriodically saves the algorithm working directory every manifest
+// checkpoint interval while the algorithm runs, sealed with the key. The
+// returned function stops the checkpoints, waiting for the one in progress.
+func (as *agentService) checkpointPeriodically(key cipher.AEAD) func() {
+	// The checkpoint was validated when the manifest was received.
+	interval, _ := checkpointConfig(as.computation)
+	if interval == 0 || key == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	cmpID := as.computation.ID
+	workDir := as.sandbox.Work()
+	// Sequences keep growing across runs and boots, so the owner can refuse
+	// a checkpoint the host rolled back.
+	seq := max(as.checkpointSeq, lastCheckpointSeq(key, cmpID))
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			size, err := saveCheckpoint(cmpID, workDir, key, seq+1)
+			if err != nil {
+				as.logger.Warn("failed to checkpoint algorithm working directory", "computation", cmpID, "error", err)
+				continue
+			}
+			seq++
+
+			details, _ := json.Marshal(map[string]string{"size": strconv.Itoa(size), "sequence": strconv.FormatUint(seq, 10)})
+			as.eventSvc.SendEvent(cmpID, events.CheckpointSaved, InProgress.String(), details)
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		as.checkpointSeq = seq
+	}
+}
+
+// saveCheckpoint archives the working directory sealed with the key and
+// replaces the previous checkpoint of the computation with it, returning its size.
+func saveCheckpoint(cmpID, workDir string, key cipher.AEAD, seq uint64) (int, error) {
+	archive, err := internal.ZipDirectoryParallel(workDir, "", 0)
+	if err != nil {
+		return 0, fmt.Errorf("error archiving working directory: %v", err)
+	}
+
+	data, err := sealCheckpoint(key, cmpID, seq, archive)
+	if err != nil {
+		return 0, fmt.Errorf("error sealing checkpoint: %v", err)
+	}
+
+	if err := os.MkdirAll(checkpointDir, 0o700); err != nil {
+		return 0, fmt.Errorf("error creating checkpoint directory: %v", err)
+	}
+
+	// The checkpoint is written aside and renamed, so a crash never leaves a partial one.
+	tmp, err := os.CreateTemp(checkpointDir, cmpID+"-*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("error creating checkpoint file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("error writing checkpoint: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("error syncing checkpoint: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("error closing checkpoint: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), checkpointPath(cmpID)); err != nil {
+		return 0, fmt.Errorf("error replacing checkpoint: %v", err)
+	}
+
+	return len(data), nil
+}
+
+// removeCheckpoint removes the checkpoint of a computation that completed.
+func (as *agentService) removeCheckpoint(cmpID string) {
+	if err := os.Remove(checkpointPath(cmpID)); err != nil && !os.IsNotExist(err) {
+		as.logger.Warn("failed to remove computation checkpoint", "computation", cmpID, "error", err)
+	}
+}
+
+// Restore extracts the last checkpoint of the computation into the algorithm
+// working directory once it is authenticated with the owner checkpoint key,
+// refusing checkpoints older than the sequence. It is accepted until the
+// algorithm starts running.
+func (as *agentService) Restore(ctx context.Context, sequence uint64) error {
+	switch as.sm.GetState() {
+	case ReceivingAlgorithm, ReceivingData:
+	default:
+		return ErrStateNotReady
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if _, err := checkpointConfig(as.computation); err != nil {
+		return err
+	}
+	if as.computation.Checkpoint == nil {
+		return ErrCheckpointDisabled
+	}
+
+	key, err := as.checkpointKey()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(checkpointPath(as.computation.ID))
+	if os.IsNotExist(err) {
+		return ErrCheckpointNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error reading checkpoint: %v", err)
+	}
+
+	seq, archive, err := openCheckpoint(key, as.computation.ID, data)
+	if err != nil {
+		return errors.Wrap(ErrCheckpointCorrupted, err)
+	}
+	if seq < sequence {
+		return errors.Wrap(ErrCheckpointStale, fmt.Errorf("checkpoint %d is older than %d", seq, sequence))
+	}
+
+	// The algorithm can read the key secret and seal a checkpoint itself, so
+	// its entries must not escape the working directory.
+	if err := checkArchivePaths(archive); err != nil {
+		return errors.Wrap(ErrCheckpointCorrupted, err)
+	}
+
+	workDir := as.sandbox.Work()
+	if err := as.dataStorage().Remove(workDir); err != nil {
+		return fmt.Errorf("error removing working directory: %v", err)
+	}
+	if err := as.createScratch(workDir); err != nil {
+		return fmt.Errorf("error creating working directory: %v", err)
+	}
+	if err := internal.UnzipFromMemory(archive, workDir); err != nil {
+		return errors.Wrap(ErrCheckpointCorrupted, err)
+	}
+	as.checkpointSeq = seq
+
+	details, _ := json.Marshal(map[string]string{"size": strconv.Itoa(len(data)), "sequence": strconv.FormatUint(seq, 10)})
+	as.eventSvc.SendEvent(as.computation.ID, events.CheckpointRestored, InProgress.String(), details)
+
+	return nil
+}
+
+// checkArchivePaths checks that every entry of the zip archive is a local path.
+func checkArchivePaths(archive []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range reader.File {
+		if !filepath.IsLocal(file.Name) {
+			return fmt.Errorf("entry %s escapes the working directory", file.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	algomocks "github.com/ultravioletrs/cocos/agent/algorithm/mocks"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+)
+
+// setupCheckpoints runs the test in a temporary working directory with its own checkpoint directory.
+func setupCheckpoints(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	defer func(dir string) { t.Cleanup(func() { checkpointDir = dir }) }(checkpointDir)
+	checkpointDir = filepath.Join(t.TempDir(), "checkpoints")
+}
+
+const checkpointSecret = "CHECKPOINT_KEY"
+
+// provisionCheckpointKey provisions a random checkpoint key secret in the
+// sandbox and returns the AEAD it keys.
+func provisionCheckpointKey(t *testing.T, sandbox algorithm.Sandbox) cipher.AEAD {
+	key := make([]byte, checkpointKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(sandbox.SecretEnv(), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(sandbox.SecretEnv(), checkpointSecret), []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	return aead
+}
+
+func TestCheckpointConfig(t *testing.T) {
+	approval := &AttestationApproval{Key: []byte("key")}
+
+	cases := []struct {
+		desc       string
+		checkpoint *Checkpoint
+		approval   *AttestationApproval
+		interval   time.Duration
+		err        error
+	}{
+		{
+			desc: "checkpoints disabled",
+		},
+		{
+			desc:       "valid checkpoint",
+			checkpoint: &Checkpoint{Interval: "10m", KeySecret: checkpointSecret},
+			approval:   approval,
+			interval:   10 * time.Minute,
+		},
+		{
+			desc:       "missing interval",
+			checkpoint: &Checkpoint{KeySecret: checkpointSecret},
+			approval:   approval,
+			err:        ErrInvalidCheckpoint,
+		},
+		{
+			desc:       "negative interval",
+			checkpoint: &Checkpoint{Interval: "-1m", KeySecret: checkpointSecret},
+			approval:   approval,
+			err:        ErrInvalidCheckpoint,
+		},
+		{
+			desc:       "missing key secret",
+			checkpoint: &Checkpoint{Interval: "10m"},
+			approval:   approval,
+			err:        ErrInvalidCheckpoint,
+		},
+		{
+			desc:       "invalid key secret name",
+			checkpoint: &Checkpoint{Interval: "10m", KeySecret: "checkpoint-key"},
+			approval:   approval,
+			err:        ErrInvalidCheckpoint,
+		},
+		{
+			desc:       "key secret without attestation approval",
+			checkpoint: &Checkpoint{Interval: "10m", KeySecret: checkpointSecret},
+			err:        ErrInvalidCheckpoint,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			interval, err := checkpointConfig(Computation{Checkpoint: tc.checkpoint, AttestationApproval: tc.approval})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.interval, interval)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	checkpoint := &Checkpoint{Interval: "10m", KeySecret: checkpointSecret}
+
+	escaping := new(bytes.Buffer)
+	zw := zip.NewWriter(escaping)
+	_, err := zw.Create("../escape")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	otherKey := func() cipher.AEAD {
+		block, err := aes.NewCipher(make([]byte, checkpointKeySize))
+		require.NoError(t, err)
+		aead, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+		return aead
+	}()
+
+	cases := []struct {
+		desc       string
+		state      AgentState
+		checkpoint *Checkpoint
+		secret     string
+		saved      func(key cipher.AEAD) []byte
+		sequence   uint64
+		err        error
+	}{
+		{
+			desc:       "restore checkpoint",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			sequence:   3,
+		},
+		{
+			desc:       "restore while receiving data",
+			state:      ReceivingData,
+			checkpoint: checkpoint,
+		},
+		{
+			desc:       "algorithm running",
+			state:      Running,
+			checkpoint: checkpoint,
+			err:        ErrStateNotReady,
+		},
+		{
+			desc:  "checkpoints disabled",
+			state: ReceivingAlgorithm,
+			err:   ErrCheckpointDisabled,
+		},
+		{
+			desc:       "key secret not provisioned",
+			state:      ReceivingAlgorithm,
+			checkpoint: &Checkpoint{Interval: "10m", KeySecret: "OTHER_KEY"},
+			err:        ErrCheckpointKey,
+		},
+		{
+			desc:       "invalid key secret",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			secret:     base64.StdEncoding.EncodeToString([]byte("short")),
+			err:        ErrCheckpointKey,
+		},
+		{
+			desc:       "no checkpoint",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved:      func(cipher.AEAD) []byte { return []byte{} },
+			err:        ErrCheckpointNotFound,
+		},
+		{
+			desc:       "corrupted checkpoint",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved:      func(cipher.AEAD) []byte { return []byte("corrupted") },
+			err:        ErrCheckpointCorrupted,
+		},
+		{
+			desc:       "checkpoint sealed with another key",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved: func(cipher.AEAD) []byte {
+				data, err := sealCheckpoint(otherKey, "1", 3, escaping.Bytes())
+				require.NoError(t, err)
+				return data
+			},
+			err: ErrCheckpointCorrupted,
+		},
+		{
+			desc:       "checkpoint of another computation",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved: func(key cipher.AEAD) []byte {
+				data, err := sealCheckpoint(key, "2", 3, escaping.Bytes())
+				require.NoError(t, err)
+				return data
+			},
+			err: ErrCheckpointCorrupted,
+		},
+		{
+			desc:       "renumbered checkpoint",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved: func(key cipher.AEAD) []byte {
+				data, err := sealCheckpoint(key, "1", 2, escaping.Bytes())
+				require.NoError(t, err)
+				data[checkpointSeqSize-1] = 3
+				return data
+			},
+			err: ErrCheckpointCorrupted,
+		},
+		{
+			desc:       "stale checkpoint",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			sequence:   4,
+			err:        ErrCheckpointStale,
+		},
+		{
+			desc:       "checkpoint escaping the working directory",
+			state:      ReceivingAlgorithm,
+			checkpoint: checkpoint,
+			saved: func(key cipher.AEAD) []byte {
+				data, err := sealCheckpoint(key, "1", 3, escaping.Bytes())
+				require.NoError(t, err)
+				return data
+			},
+			err: ErrCheckpointCorrupted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			setupCheckpoints(t)
+
+			sandbox := algorithm.Sandbox{}
+			key := provisionCheckpointKey(t, sandbox)
+			if tc.secret != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(sandbox.SecretEnv(), checkpointSecret), []byte(tc.secret), 0o600))
+			}
+
+			require.NoError(t, os.MkdirAll(filepath.Join(algorithm.WorkDir, "model"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(algorithm.WorkDir, "model", "epoch"), []byte("7"), 0o644))
+
+			switch {
+			case tc.saved == nil:
+				_, err := saveCheckpoint("1", algorithm.WorkDir, key, 3)
+				require.NoError(t, err)
+			case len(tc.saved(key)) > 0:
+				require.NoError(t, os.MkdirAll(checkpointDir, 0o700))
+				require.NoError(t, os.WriteFile(checkpointPath("1"), tc.saved(key), 0o600))
+			}
+			require.NoError(t, os.RemoveAll(algorithm.WorkDir))
+
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(tc.state)
+
+			evts := new(mocks.Service)
+			evts.On("SendEvent", "1", events.CheckpointRestored, InProgress.String(), mock.Anything).Return()
+
+			svc := &agentService{
+				sm:          sm,
+				eventSvc:    evts,
+				logger:      mglog.NewMock(),
+				sandbox:     sandbox,
+				computation: Computation{ID: "1", Checkpoint: tc.checkpoint, AttestationApproval: &AttestationApproval{Key: []byte("key")}},
+			}
+
+			err := svc.Restore(context.Background(), tc.sequence)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				evts.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			epoch, err := os.ReadFile(filepath.Join(algorithm.WorkDir, "model", "epoch"))
+			require.NoError(t, err)
+			assert.Equal(t, "7", string(epoch))
+			assert.Equal(t, uint64(3), svc.checkpointSeq)
+			evts.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunComputationCheckpoints(t *testing.T) {
+	cases := []struct {
+		desc   string
+		runErr error
+		kept   bool
+	}{
+		{
+			desc: "checkpoint removed once the run succeeds",
+		},
+		{
+			desc:   "checkpoint kept when the run fails",
+			runErr: errors.New("algorithm failed"),
+			kept:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmp := testComputation(t)
+			cmp.Checkpoint = &Checkpoint{Interval: "20ms", KeySecret: checkpointSecret}
+			cmp.AttestationApproval = &AttestationApproval{Key: []byte("key")}
+
+			setupCheckpoints(t)
+			key := provisionCheckpointKey(t, algorithm.Sandbox{})
+			// A checkpoint saved before the agent restarted, the sequence continues from it.
+			_, err := saveCheckpoint(cmp.ID, t.TempDir(), key, 5)
+			require.NoError(t, err)
+
+			evts := new(mocks.Service)
+			evts.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+			sm := new(smmocks.StateMachine)
+			sm.On("SendEvent", mock.Anything).Return()
+
+			algo := new(algomocks.Algorithm)
+			algo.On("Run").Run(func(mock.Arguments) {
+				require.NoError(t, os.WriteFile(filepath.Join(algorithm.WorkDir, "state"), []byte("epoch 1"), 0o644))
+				time.Sleep(100 * time.Millisecond)
+			}).Return(tc.runErr)
+
+			svc := &agentService{
+				sm:          sm,
+				eventSvc:    evts,
+				logger:      mglog.NewMock(),
+				algorithm:   algo,
+				computation: cmp,
+				traceCtx:    context.Background(),
+			}
+			svc.lineage = newLineage(cmp)
+
+			svc.runComputation(Running)
+
+			evts.AssertCalled(t, "SendEvent", cmp.ID, events.CheckpointSaved, InProgress.String(), mock.Anything)
+			assert.NoDirExists(t, algorithm.WorkDir)
+
+			data, err := os.ReadFile(checkpointPath(cmp.ID))
+			if !tc.kept {
+				assert.True(t, os.IsNotExist(err), "expected the checkpoint to be removed, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			seq, archive, err := openCheckpoint(key, cmp.ID, data)
+			require.NoError(t, err)
+			assert.Greater(t, seq, uint64(5))
+			assert.Equal(t, seq, svc.checkpointSeq)
+			reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+			require.NoError(t, err)
+			require.Len(t, reader.File, 1)
+			assert.Equal(t, "state", reader.File[0].Name)
+		})
+	}
+}
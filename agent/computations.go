@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -19,6 +20,7 @@ type AgentConfig struct {
 	ServerCAFile string `json:"server_ca_file,omitempty"`
 	ClientCAFile string `json:"client_ca_file,omitempty"`
 	AttestedTls  bool   `json:"attested_tls,omitempty"`
+	HTTPPort     string `json:"http_port,omitempty"`
 }
 
 type Computation struct {
@@ -28,10 +30,81 @@ type Computation struct {
 	Datasets        Datasets         `json:"datasets,omitempty"`
 	Algorithm       Algorithm        `json:"algorithm,omitempty"`
 	ResultConsumers []ResultConsumer `json:"result_consumers,omitempty"`
+	// ResultCodec compresses the result archive, deflate (default) or zstd.
+	ResultCodec string `json:"result_codec,omitempty"`
+	// Version is the manifest schema version, see ManifestVersion.
+	Version uint32 `json:"version,omitempty"`
+	// TTL is how long the computation may live once the manifest is received, e.g. "2h", unlimited if empty.
+	TTL string `json:"ttl,omitempty"`
+	// MaxRuntime is how long the algorithm may run, e.g. "30m", unlimited if empty.
+	MaxRuntime string `json:"max_runtime,omitempty"`
+	// EventEncryption encrypts sensitive event detail fields for the computation owner.
+	EventEncryption *EventEncryption `json:"event_encryption,omitempty"`
+	// Checkpoint periodically saves the algorithm working directory so a re-launched CVM can resume the run.
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+	// DatasetNaming sets the names datasets appear under in the datasets directory, see DatasetNamingUpload.
+	DatasetNaming string `json:"dataset_naming,omitempty"`
+	// AttestationApproval holds back the algorithm and datasets until the computation owner approves the attestation.
+	AttestationApproval *AttestationApproval `json:"attestation_approval,omitempty"`
+	// Storage selects where datasets, results and intermediate files are kept, on the disk of the CVM if nil.
+	Storage *Storage `json:"storage,omitempty"`
+	// ResultSink uploads the encrypted results to an object storage once the algorithm succeeds.
+	ResultSink *ResultSink `json:"result_sink,omitempty"`
+	// Signature is an Ed25519 or ECDSA signature over the manifest, see SigningBytes.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// EventEncryption lists the event detail fields encrypted with the X25519
+// public Key before events leave the agent, every field if Fields is empty.
+// StreamOutput streams the algorithm output to the manager, encrypted with Key.
+type EventEncryption struct {
+	Key          []byte   `json:"key,omitempty"`
+	Fields       []string `json:"fields,omitempty"`
+	StreamOutput bool     `json:"stream_output,omitempty"`
+}
+
+// Checkpoint saves the algorithm working directory every Interval, e.g. "10m",
+// sealed with the base64 encoded 32-byte key held by the owner secret named KeySecret.
+type Checkpoint struct {
+	Interval  string `json:"interval,omitempty"`
+	KeySecret string `json:"key_secret,omitempty"`
+}
+
+// AttestationApproval requires the computation owner to approve the agent
+// attestation with the PKIX encoded Ed25519 or ECDSA public Key, see
+// ApprovalSigningBytes, before the algorithm and datasets are accepted.
+type AttestationApproval struct {
+	Key []byte `json:"key,omitempty"`
+}
+
+// Storage keeps datasets, results and the intermediate files of the algorithm
+// on the Type storage, disk, tmpfs or block, see the storage package. SizeMB
+// bounds each tmpfs directory, half of the CVM memory if 0.
+type Storage struct {
+	Type   string `json:"type,omitempty"`
+	SizeMB uint64 `json:"size_mb,omitempty"`
+}
+
+// ResultSink uploads the results, encrypted with the X25519 public
+// EncryptionKey, to the Bucket of an S3-compatible object storage at Endpoint
+// under Key, <computation id>/results.zip.enc if empty. The upload is signed
+// with the credentials held by the owner secrets named AccessKeySecret and
+// SecretKeySecret, see Secrets, and is anonymous if they are empty.
+type ResultSink struct {
+	Type            string `json:"type,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	Key             string `json:"key,omitempty"`
+	EncryptionKey   []byte `json:"encryption_key,omitempty"`
+	AccessKeySecret string `json:"access_key_secret,omitempty"`
+	SecretKeySecret string `json:"secret_key_secret,omitempty"`
 }
 
 type ResultConsumer struct {
 	UserKey []byte `json:"user_key,omitempty"`
+	// EncryptionKey is an optional X25519 public key the result is encrypted with for this consumer.
+	EncryptionKey []byte `json:"encryption_key,omitempty"`
 }
 
 func (d *Datasets) String() string {
@@ -47,15 +120,88 @@ type Dataset struct {
 	Hash     [32]byte `json:"hash,omitempty"`
 	UserKey  []byte   `json:"user_key,omitempty"`
 	Filename string   `json:"filename,omitempty"`
+	// Archive bounds what the dataset expands to when it is uploaded as an archive to decompress.
+	Archive *DatasetArchive `json:"archive,omitempty"`
+}
+
+// DatasetArchive are the extraction limits of a dataset archive, zero values
+// leave a limit unbounded.
+type DatasetArchive struct {
+	MaxSizeMB uint64 `json:"max_size_mb,omitempty"`
+	MaxFiles  uint64 `json:"max_files,omitempty"`
 }
 
 type Datasets []Dataset
 
+// DatasetStatus reports whether a dataset declared in the computation manifest was delivered.
+type DatasetStatus struct {
+	Index    int    `json:"index"`
+	Filename string `json:"filename,omitempty"`
+	Hash     string `json:"hash"`
+	Received bool   `json:"received"`
+}
+
 type Algorithm struct {
 	Algorithm    []byte   `json:"-"`
 	Hash         [32]byte `json:"hash,omitempty"`
 	UserKey      []byte   `json:"user_key,omitempty"`
 	Requirements []byte   `json:"-"`
+	// Spec describes how the uploaded algorithm runs.
+	Spec algorithm.Spec `json:"-"`
+	// Args are passed to the algorithm before the uploaded or step arguments.
+	Args []string `json:"args,omitempty"`
+	// Env are the environment variables the algorithm runs with.
+	Env   map[string]string `json:"env,omitempty"`
+	Steps []Step            `json:"steps,omitempty"`
+	// WasmLimits bound the resources of wasm algorithms.
+	WasmLimits *WasmLimits `json:"wasm_limits,omitempty"`
+	// Watchdog reports, and optionally stops, algorithms that stopped making progress.
+	Watchdog *Watchdog `json:"watchdog,omitempty"`
+	// Resources bound the resources of bin and python algorithms, Type must declare one of them.
+	Resources *Resources `json:"resources,omitempty"`
+	// Bundle declares an algorithm uploaded as a tar archive of several files.
+	Bundle *AlgorithmBundle `json:"bundle,omitempty"`
+	// Type is the type the algorithm must be uploaded as, any type when empty.
+	Type algorithm.AlgorithType `json:"type,omitempty"`
+}
+
+// AlgorithmBundle declares the file of a bundled algorithm that runs and the
+// extraction limits of the bundle, zero values leave a limit unbounded.
+type AlgorithmBundle struct {
+	// Entrypoint is the slash separated path of the file that runs inside the bundle.
+	Entrypoint string `json:"entrypoint"`
+	MaxSizeMB  uint64 `json:"max_size_mb,omitempty"`
+	MaxFiles   uint64 `json:"max_files,omitempty"`
+}
+
+// WasmLimits are the resource limits of a wasm algorithm, zero values keep the runtime defaults.
+// The wasm runtime does not meter instructions, execution is bounded by time instead.
+type WasmLimits struct {
+	MaxMemoryMB    uint32 `json:"max_memory_mb,omitempty"`
+	TimeoutSeconds uint32 `json:"timeout_seconds,omitempty"`
+}
+
+// Resources are the CPU, memory and disk limits of the algorithm processes,
+// zero values leave a resource unbounded. DiskMB bounds the size of the results directory.
+type Resources struct {
+	CPUs     float64 `json:"cpus,omitempty"`
+	MemoryMB uint64  `json:"memory_mb,omitempty"`
+	DiskMB   uint64  `json:"disk_mb,omitempty"`
+}
+
+// Watchdog is the policy for algorithm processes that used no CPU and wrote no
+// output for IdleSeconds, zero disables the watchdog.
+type Watchdog struct {
+	IdleSeconds uint32 `json:"idle_seconds,omitempty"`
+	Kill        bool   `json:"kill,omitempty"`
+}
+
+// Step is a component of the algorithm that runs with only the datasets it
+// lists, referenced by dataset filename, in its datasets directory.
+type Step struct {
+	Name     string   `json:"name,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	Datasets []string `json:"datasets,omitempty"`
 }
 
 type ManifestIndexKey struct{}
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
+	"github.com/go-kit/kit/metrics"
 	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/cvms"
 	"github.com/ultravioletrs/cocos/agent/cvms/api/grpc/storage"
 	"github.com/ultravioletrs/cocos/agent/cvms/server"
@@ -47,11 +49,14 @@ type CVMSClient struct {
 	storage       storage.Storage
 	reconnectFn   func(context.Context) (grpc.Client, cvms.Service_ProcessClient, error)
 	grpcClient    grpc.Client
+	resent        metrics.Counter
+	flushes       chan chan struct{}
 }
 
-// NewClient returns new gRPC client instance.
-func NewClient(stream cvms.Service_ProcessClient, svc agent.Service, messageQueue chan *cvms.ClientStreamMessage, logger *slog.Logger, sp server.AgentServer, storageDir string, reconnectFn func(context.Context) (grpc.Client, cvms.Service_ProcessClient, error), grpcClient grpc.Client) (*CVMSClient, error) {
-	store, err := storage.NewFileStorage(storageDir)
+// NewClient returns new gRPC client instance. The resent counter tracks the
+// messages sent again to the manager after a failed send.
+func NewClient(stream cvms.Service_ProcessClient, svc agent.Service, messageQueue chan *cvms.ClientStreamMessage, logger *slog.Logger, sp server.AgentServer, storageDir string, reconnectFn func(context.Context) (grpc.Client, cvms.Service_ProcessClient, error), grpcClient grpc.Client, resent metrics.Counter) (*CVMSClient, error) {
+	store, err := storage.NewFileStorage(storageDir, storage.DefaultCapacity)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +71,8 @@ func NewClient(stream cvms.Service_ProcessClient, svc agent.Service, messageQueu
 		storage:       store,
 		reconnectFn:   reconnectFn,
 		grpcClient:    grpcClient,
+		resent:        resent,
+		flushes:       make(chan chan struct{}),
 	}, nil
 }
 
@@ -77,9 +84,13 @@ func (client *CVMSClient) Process(ctx context.Context, cancel context.CancelFunc
 		}
 
 		client.logger.Info("Connection lost, attempting to reconnect...", "error", err)
+
+		// Messages queued while disconnected are stored and replayed once reconnected.
+		stopBuffering := client.bufferMessages()
 		time.Sleep(reconnectInterval)
 
 		grpcClient, stream, err := client.reconnectFn(ctx)
+		stopBuffering()
 		if err != nil {
 			client.logger.Error("Failed to reconnect", "error", err)
 			continue
@@ -136,13 +147,85 @@ func (client *CVMSClient) handleOutgoingMessages(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case msg := <-client.messageQueue:
-			if err := client.sendStreamMessage(msg); err != nil {
-				if err := client.storage.Add(msg); err != nil {
-					client.logger.Error("Failed to store pending message", "error", err)
-				}
-				client.logger.Error("Failed to send message, stored for retry", "error", err)
+			client.sendOrStoreMessage(msg)
+		case done := <-client.flushes:
+			client.drainQueue(client.sendOrStoreMessage)
+			close(done)
+		}
+	}
+}
+
+// Flush returns once the messages queued before it was called are sent, or
+// stored for the next connection when the agent is disconnected, or when ctx
+// is done.
+func (client *CVMSClient) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	select {
+	case client.flushes <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainQueue hands the queued messages to handle in order, until the queue is empty.
+func (client *CVMSClient) drainQueue(handle func(*cvms.ClientStreamMessage)) {
+	for {
+		select {
+		case msg := <-client.messageQueue:
+			handle(msg)
+		default:
+			return
+		}
+	}
+}
+
+// bufferMessages stores the messages queued while the agent is disconnected,
+// so that senders do not block on a full queue and the messages survive an
+// agent restart. The returned function stops buffering, messages still queued
+// are sent after the stored ones.
+func (client *CVMSClient) bufferMessages() func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case msg := <-client.messageQueue:
+				client.storeMessage(msg)
+			case flushed := <-client.flushes:
+				client.drainQueue(client.storeMessage)
+				close(flushed)
 			}
 		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (client *CVMSClient) sendOrStoreMessage(msg *cvms.ClientStreamMessage) {
+	if err := client.sendStreamMessage(msg); err != nil {
+		client.storeMessage(msg)
+		client.logger.Error("Failed to send message, stored for retry", "error", err)
+	}
+}
+
+func (client *CVMSClient) storeMessage(msg *cvms.ClientStreamMessage) {
+	if err := client.storage.Add(msg); err != nil {
+		client.logger.Error("Failed to store pending message", "error", err)
 	}
 }
 
@@ -153,18 +236,24 @@ func (client *CVMSClient) sendStreamMessage(msg *cvms.ClientStreamMessage) error
 	return client.stream.Send(msg)
 }
 
+// sendPendingMessages replays the stored messages in order. Replaying stops at
+// the first failure and the messages left are kept for the next connection.
 func (client *CVMSClient) sendPendingMessages(pending []storage.Message) {
-	for _, pm := range pending {
+	for i, pm := range pending {
+		client.resent.Add(1)
 		if err := client.sendStreamMessage(pm.Message); err != nil {
-			if err := client.storage.Add(pm.Message); err != nil {
-				client.logger.Error("Failed to store pending message", "error", err)
-			}
 			client.logger.Error("Failed to resend pending message", "error", err)
-		} else {
-			client.logger.Info("Successfully resent pending message")
+			if err := client.storage.Save(pending[i:]); err != nil {
+				client.logger.Error("Failed to store pending messages", "error", err)
+			}
+			return
 		}
 	}
 
+	if len(pending) > 0 {
+		client.logger.Info("Successfully resent pending messages", "count", len(pending))
+	}
+
 	if err := client.storage.Clear(); err != nil {
 		client.logger.Error("Failed to clear pending messages", "error", err)
 	}
@@ -221,33 +310,138 @@ func (client *CVMSClient) handleRunReqChunks(ctx context.Context, msg *cvms.Serv
 
 func (client *CVMSClient) executeRun(ctx context.Context, runReq *cvms.ComputationRunReq) {
 	ac := agent.Computation{
-		ID:          runReq.Id,
-		Name:        runReq.Name,
-		Description: runReq.Description,
+		ID:            runReq.Id,
+		Name:          runReq.Name,
+		Description:   runReq.Description,
+		Signature:     runReq.Signature,
+		ResultCodec:   runReq.ResultCodec,
+		Version:       runReq.Version,
+		TTL:           runReq.Ttl,
+		MaxRuntime:    runReq.MaxRuntime,
+		DatasetNaming: runReq.DatasetNaming,
+	}
+
+	if enc := runReq.EventEncryption; enc != nil {
+		ac.EventEncryption = &agent.EventEncryption{
+			Key:          enc.Key,
+			Fields:       enc.Fields,
+			StreamOutput: enc.StreamOutput,
+		}
+	}
+
+	if approval := runReq.AttestationApproval; approval != nil {
+		ac.AttestationApproval = &agent.AttestationApproval{Key: approval.Key}
+	}
+
+	if st := runReq.Storage; st != nil {
+		ac.Storage = &agent.Storage{
+			Type:   st.Type,
+			SizeMB: st.SizeMb,
+		}
+	}
+
+	if rs := runReq.ResultSink; rs != nil {
+		ac.ResultSink = &agent.ResultSink{
+			Type:            rs.Type,
+			Endpoint:        rs.Endpoint,
+			Region:          rs.Region,
+			Bucket:          rs.Bucket,
+			Key:             rs.Key,
+			EncryptionKey:   rs.EncryptionKey,
+			AccessKeySecret: rs.AccessKeySecret,
+			SecretKeySecret: rs.SecretKeySecret,
+		}
+	}
+
+	if cp := runReq.Checkpoint; cp != nil {
+		ac.Checkpoint = &agent.Checkpoint{
+			Interval:  cp.Interval,
+			KeySecret: cp.KeySecret,
+		}
 	}
 
 	if runReq.Algorithm != nil {
 		ac.Algorithm = agent.Algorithm{
 			Hash:    [32]byte(runReq.Algorithm.Hash),
 			UserKey: runReq.Algorithm.UserKey,
+			Args:    runReq.Algorithm.Args,
+			Env:     runReq.Algorithm.Env,
 		}
+
+		for _, step := range runReq.Algorithm.Steps {
+			ac.Algorithm.Steps = append(ac.Algorithm.Steps, agent.Step{
+				Name:     step.Name,
+				Args:     step.Args,
+				Datasets: step.Datasets,
+			})
+		}
+
+		if limits := runReq.Algorithm.WasmLimits; limits != nil {
+			ac.Algorithm.WasmLimits = &agent.WasmLimits{
+				MaxMemoryMB:    limits.MaxMemoryMb,
+				TimeoutSeconds: limits.TimeoutSeconds,
+			}
+		}
+
+		if wd := runReq.Algorithm.Watchdog; wd != nil {
+			ac.Algorithm.Watchdog = &agent.Watchdog{
+				IdleSeconds: wd.IdleSeconds,
+				Kill:        wd.Kill,
+			}
+		}
+
+		if res := runReq.Algorithm.Resources; res != nil {
+			ac.Algorithm.Resources = &agent.Resources{
+				CPUs:     res.Cpus,
+				MemoryMB: res.MemoryMb,
+				DiskMB:   res.DiskMb,
+			}
+		}
+
+		if bundle := runReq.Algorithm.Bundle; bundle != nil {
+			ac.Algorithm.Bundle = &agent.AlgorithmBundle{
+				Entrypoint: bundle.Entrypoint,
+				MaxSizeMB:  bundle.MaxSizeMb,
+				MaxFiles:   bundle.MaxFiles,
+			}
+		}
+
+		ac.Algorithm.Type = algorithm.AlgorithType(runReq.Algorithm.Type)
 	}
 
 	for _, ds := range runReq.Datasets {
-		ac.Datasets = append(ac.Datasets, agent.Dataset{
-			Hash:    [32]byte(ds.Hash),
-			UserKey: ds.UserKey,
-		})
+		dataset := agent.Dataset{
+			UserKey:  ds.UserKey,
+			Filename: ds.Filename,
+		}
+		// Hash-less datasets are left to the agent to accept or reject.
+		if len(ds.Hash) > 0 {
+			dataset.Hash = [32]byte(ds.Hash)
+		}
+		if archive := ds.Archive; archive != nil {
+			dataset.Archive = &agent.DatasetArchive{
+				MaxSizeMB: archive.MaxSizeMb,
+				MaxFiles:  archive.MaxFiles,
+			}
+		}
+		ac.Datasets = append(ac.Datasets, dataset)
 	}
 
 	for _, rc := range runReq.ResultConsumers {
 		ac.ResultConsumers = append(ac.ResultConsumers, agent.ResultConsumer{
-			UserKey: rc.UserKey,
+			UserKey:       rc.UserKey,
+			EncryptionKey: rc.EncryptionKey,
 		})
 	}
 
 	if err := client.svc.InitComputation(ctx, ac); err != nil {
 		client.logger.Warn(err.Error())
+		// The manager is told about conflicting runs, the agent keeps running the computation assigned first.
+		if errors.Contains(err, agent.ErrAlreadyAssigned) {
+			client.sendMessage(&cvms.ClientStreamMessage{Message: &cvms.ClientStreamMessage_RunRes{
+				RunRes: &cvms.RunResponse{ComputationId: runReq.Id, Error: err.Error()},
+			}})
+		}
 		return
 	}
 
@@ -273,6 +467,7 @@ func (client *CVMSClient) executeRun(ctx context.Context, runReq *cvms.Computati
 		ServerCAFile: runReq.AgentConfig.ServerCaFile,
 		ClientCAFile: runReq.AgentConfig.ClientCaFile,
 		AttestedTls:  runReq.AgentConfig.AttestedTls,
+		HTTPPort:     runReq.AgentConfig.HttpPort,
 	}, ac); err != nil {
 		client.logger.Warn(err.Error())
 		runRes.RunRes.Error = err.Error()
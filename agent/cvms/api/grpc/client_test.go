@@ -4,12 +4,17 @@ package grpc
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"testing"
 	"time"
 
 	mglog "github.com/absmach/supermq/logger"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/agent/cvms"
 	servermocks "github.com/ultravioletrs/cocos/agent/cvms/server/mocks"
 	"github.com/ultravioletrs/cocos/agent/mocks"
@@ -121,7 +126,7 @@ func TestManagerClient_Process(t *testing.T) {
 
 			grpcClient := new(clientmocks.Client)
 
-			client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient)
+			client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient, discard.NewCounter())
 			assert.NoError(t, err)
 
 			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
@@ -143,6 +148,144 @@ func TestManagerClient_Process(t *testing.T) {
 	}
 }
 
+func TestManagerClient_bufferMessages(t *testing.T) {
+	messageQueue := make(chan *cvms.ClientStreamMessage)
+	client, err := NewClient(new(mockStream), new(mocks.Service), messageQueue, mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+	require.NoError(t, err)
+
+	stop := client.bufferMessages()
+	for _, id := range []string{"event-1", "event-2"} {
+		select {
+		case messageQueue <- agentEvent(id):
+		case <-time.After(time.Second):
+			t.Fatal("sending an event blocked while disconnected")
+		}
+	}
+	stop()
+
+	pending, err := client.storage.Load()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "event-1", pending[0].Message.GetAgentEvent().Id)
+	assert.Equal(t, "event-2", pending[1].Message.GetAgentEvent().Id)
+}
+
+func TestManagerClient_sendPendingMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		failAt    string
+		sent      []string
+		remaining []string
+	}{
+		{
+			name: "replay all messages in order",
+			sent: []string{"event-1", "event-2", "event-3"},
+		},
+		{
+			name:      "keep the messages left after a failure",
+			failAt:    "event-2",
+			sent:      []string{"event-1"},
+			remaining: []string{"event-2", "event-3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := new(mockStream)
+			var sent []string
+			stream.On("Send", mock.MatchedBy(func(msg *cvms.ClientStreamMessage) bool {
+				return msg.GetAgentEvent().Id == tt.failAt
+			})).Return(assert.AnError)
+			stream.On("Send", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				sent = append(sent, args.Get(0).(*cvms.ClientStreamMessage).GetAgentEvent().Id)
+			})
+
+			client, err := NewClient(stream, new(mocks.Service), make(chan *cvms.ClientStreamMessage), mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+			require.NoError(t, err)
+			for _, id := range []string{"event-1", "event-2", "event-3"} {
+				require.NoError(t, client.storage.Add(agentEvent(id)))
+			}
+
+			pending, err := client.storage.Load()
+			require.NoError(t, err)
+			client.sendPendingMessages(pending)
+			assert.Equal(t, tt.sent, sent)
+
+			pending, err = client.storage.Load()
+			require.NoError(t, err)
+			var remaining []string
+			for _, pm := range pending {
+				remaining = append(remaining, pm.Message.GetAgentEvent().Id)
+			}
+			assert.Equal(t, tt.remaining, remaining)
+		})
+	}
+}
+
+func TestManagerClient_Flush(t *testing.T) {
+	t.Run("send queued messages", func(t *testing.T) {
+		stream := new(mockStream)
+		var sent []string
+		stream.On("Send", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			sent = append(sent, args.Get(0).(*cvms.ClientStreamMessage).GetAgentEvent().Id)
+		})
+
+		messageQueue := make(chan *cvms.ClientStreamMessage, 2)
+		client, err := NewClient(stream, new(mocks.Service), messageQueue, mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = client.handleOutgoingMessages(ctx)
+		}()
+
+		messageQueue <- agentEvent("event-1")
+		messageQueue <- agentEvent("event-2")
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), time.Second)
+		defer flushCancel()
+		require.NoError(t, client.Flush(flushCtx))
+		cancel()
+		<-done
+
+		assert.Equal(t, []string{"event-1", "event-2"}, sent)
+	})
+
+	t.Run("store queued messages while disconnected", func(t *testing.T) {
+		messageQueue := make(chan *cvms.ClientStreamMessage, 2)
+		client, err := NewClient(new(mockStream), new(mocks.Service), messageQueue, mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+		require.NoError(t, err)
+
+		stop := client.bufferMessages()
+		messageQueue <- agentEvent("event-1")
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), time.Second)
+		defer flushCancel()
+		require.NoError(t, client.Flush(flushCtx))
+		stop()
+
+		pending, err := client.storage.Load()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, "event-1", pending[0].Message.GetAgentEvent().Id)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		client, err := NewClient(new(mockStream), new(mocks.Service), make(chan *cvms.ClientStreamMessage), mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+		require.NoError(t, err)
+
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer flushCancel()
+		assert.ErrorIs(t, client.Flush(flushCtx), context.DeadlineExceeded)
+	})
+}
+
+func agentEvent(id string) *cvms.ClientStreamMessage {
+	return &cvms.ClientStreamMessage{Message: &cvms.ClientStreamMessage_AgentEvent{AgentEvent: &cvms.AgentEvent{Id: id}}}
+}
+
 func TestManagerClient_handleRunReqChunks(t *testing.T) {
 	mockStream := new(mockStream)
 	mockSvc := new(mocks.Service)
@@ -151,24 +294,35 @@ func TestManagerClient_handleRunReqChunks(t *testing.T) {
 	logger := mglog.NewMock()
 	grpcClient := new(clientmocks.Client)
 
-	client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient)
+	client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient, discard.NewCounter())
 	assert.NoError(t, err)
 
 	runReq := &cvms.ComputationRunReq{
 		Id: "test-id",
 		Datasets: []*cvms.Dataset{
 			{
-				Hash: sha3.New256().Sum([]byte("test-dataset")),
+				Hash:    sha3.New256().Sum([]byte("test-dataset")),
+				Archive: &cvms.DatasetArchive{MaxSizeMb: 64, MaxFiles: 100},
 			},
 		},
 		Algorithm: &cvms.Algorithm{
-			Hash: sha3.New256().Sum([]byte("test-algorithm")),
+			Hash:       sha3.New256().Sum([]byte("test-algorithm")),
+			WasmLimits: &cvms.WasmLimits{MaxMemoryMb: 128, TimeoutSeconds: 60},
+			Watchdog:   &cvms.Watchdog{IdleSeconds: 300, Kill: true},
+			Resources:  &cvms.Resources{Cpus: 2, MemoryMb: 1024, DiskMb: 512},
+			Bundle:     &cvms.AlgorithmBundle{Entrypoint: "src/train.py", MaxSizeMb: 256, MaxFiles: 10},
+			Args:       []string{"--epochs", "2"},
+			Env:        map[string]string{"MODEL": "resnet-50"},
 		},
 		ResultConsumers: []*cvms.ResultConsumer{
 			{
 				UserKey: []byte("test-consumer"),
 			},
 		},
+		EventEncryption: &cvms.EventEncryption{Key: []byte("owner-key"), Fields: []string{"output"}},
+		Checkpoint:      &cvms.Checkpoint{Interval: "10m", KeySecret: "CHECKPOINT_KEY"},
+		DatasetNaming:   agent.DatasetNamingOrdered,
+		ResultSink:      &cvms.ResultSink{Endpoint: "https://s3.eu-west-1.amazonaws.com", Bucket: "results", AccessKeySecret: "S3_KEY", SecretKeySecret: "S3_SECRET"},
 	}
 	runReqBytes, _ := proto.Marshal(runReq)
 
@@ -187,7 +341,18 @@ func TestManagerClient_handleRunReqChunks(t *testing.T) {
 		},
 	}
 
-	mockSvc.On("InitComputation", mock.Anything, mock.Anything).Return(nil)
+	mockSvc.On("InitComputation", mock.Anything, mock.MatchedBy(func(cmp agent.Computation) bool {
+		return cmp.Algorithm.WasmLimits != nil && *cmp.Algorithm.WasmLimits == agent.WasmLimits{MaxMemoryMB: 128, TimeoutSeconds: 60} &&
+			cmp.Algorithm.Watchdog != nil && *cmp.Algorithm.Watchdog == agent.Watchdog{IdleSeconds: 300, Kill: true} &&
+			cmp.Algorithm.Resources != nil && *cmp.Algorithm.Resources == agent.Resources{CPUs: 2, MemoryMB: 1024, DiskMB: 512} &&
+			cmp.Algorithm.Bundle != nil && *cmp.Algorithm.Bundle == agent.AlgorithmBundle{Entrypoint: "src/train.py", MaxSizeMB: 256, MaxFiles: 10} &&
+			slices.Equal(cmp.Algorithm.Args, []string{"--epochs", "2"}) && maps.Equal(cmp.Algorithm.Env, map[string]string{"MODEL": "resnet-50"}) &&
+			cmp.EventEncryption != nil && string(cmp.EventEncryption.Key) == "owner-key" && slices.Equal(cmp.EventEncryption.Fields, []string{"output"}) &&
+			cmp.Checkpoint != nil && cmp.Checkpoint.Interval == "10m" && cmp.Checkpoint.KeySecret == "CHECKPOINT_KEY" &&
+			cmp.DatasetNaming == agent.DatasetNamingOrdered &&
+			cmp.Datasets[0].Archive != nil && *cmp.Datasets[0].Archive == agent.DatasetArchive{MaxSizeMB: 64, MaxFiles: 100} &&
+			cmp.ResultSink != nil && cmp.ResultSink.Bucket == "results" && cmp.ResultSink.AccessKeySecret == "S3_KEY" && cmp.ResultSink.SecretKeySecret == "S3_SECRET"
+	})).Return(nil)
 	mockServerSvc.On("Start", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 	err = client.handleRunReqChunks(context.Background(), chunk1)
@@ -208,6 +373,28 @@ func TestManagerClient_handleRunReqChunks(t *testing.T) {
 	assert.Equal(t, "test-id", runRes.RunRes.ComputationId)
 }
 
+func TestManagerClient_executeRunAlreadyAssigned(t *testing.T) {
+	mockSvc := new(mocks.Service)
+	mockServerSvc := new(servermocks.AgentServer)
+	messageQueue := make(chan *cvms.ClientStreamMessage, 10)
+
+	client, err := NewClient(new(mockStream), mockSvc, messageQueue, mglog.NewMock(), mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, new(clientmocks.Client), discard.NewCounter())
+	assert.NoError(t, err)
+
+	mockSvc.On("InitComputation", mock.Anything, mock.Anything).Return(agent.ErrAlreadyAssigned)
+
+	client.executeRun(context.Background(), &cvms.ComputationRunReq{Id: "conflicting-id"})
+
+	mockServerSvc.AssertNotCalled(t, "Start", mock.Anything, mock.Anything, mock.Anything)
+	assert.Len(t, messageQueue, 1)
+
+	msg := <-messageQueue
+	runRes, ok := msg.Message.(*cvms.ClientStreamMessage_RunRes)
+	assert.True(t, ok)
+	assert.Equal(t, "conflicting-id", runRes.RunRes.ComputationId)
+	assert.Equal(t, agent.ErrAlreadyAssigned.Error(), runRes.RunRes.Error)
+}
+
 func TestManagerClient_handleStopComputation(t *testing.T) {
 	mockStream := new(mockStream)
 	mockSvc := new(mocks.Service)
@@ -216,7 +403,7 @@ func TestManagerClient_handleStopComputation(t *testing.T) {
 	logger := mglog.NewMock()
 	grpcClient := new(clientmocks.Client)
 
-	client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient)
+	client, err := NewClient(mockStream, mockSvc, messageQueue, logger, mockServerSvc, t.TempDir(), func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) { return nil, nil, nil }, grpcClient, discard.NewCounter())
 	assert.NoError(t, err)
 
 	stopReq := &cvms.ServerStreamMessage_StopComputation{
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/ultravioletrs/cocos/agent/cvms"
@@ -24,6 +25,9 @@ var (
 const (
 	bufferSize    = 1024 * 1024 // 1 MB
 	runReqTimeout = 30 * time.Second
+	// recentEvents is the number of event IDs the server remembers to drop
+	// the copies of events agents replay after a reconnection.
+	recentEvents = 10000
 )
 
 type SendFunc func(*cvms.ServerStreamMessage) error
@@ -32,6 +36,7 @@ type grpcServer struct {
 	cvms.UnimplementedServiceServer
 	incoming chan *cvms.ClientStreamMessage
 	svc      Service
+	events   *eventIDs
 }
 
 type Service interface {
@@ -43,6 +48,7 @@ func NewServer(incoming chan *cvms.ClientStreamMessage, svc Service) cvms.Servic
 	return &grpcServer{
 		incoming: incoming,
 		svc:      svc,
+		events:   newEventIDs(recentEvents),
 	}
 }
 
@@ -64,6 +70,9 @@ func (s *grpcServer) Process(stream cvms.Service_ProcessServer) error {
 				if err != nil {
 					return err
 				}
+				if event := req.GetAgentEvent(); event != nil && event.Id != "" && s.events.seen(event.Id) {
+					continue
+				}
 				s.incoming <- req
 			}
 		}
@@ -131,3 +140,37 @@ func (s *grpcServer) sendRunReqInChunks(stream cvms.Service_ProcessServer, runRe
 
 	return nil
 }
+
+// eventIDs remembers the IDs of the last events received, across the
+// connections of all agents.
+type eventIDs struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newEventIDs(size int) *eventIDs {
+	return &eventIDs{
+		ids:   make(map[string]struct{}, size),
+		order: make([]string, size),
+	}
+}
+
+// seen records the event ID and reports whether it was received before. The
+// oldest ID is forgotten once the server remembers as many as it can.
+func (e *eventIDs) seen(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.ids[id]; ok {
+		return true
+	}
+
+	delete(e.ids, e.order[e.next])
+	e.order[e.next] = id
+	e.next = (e.next + 1) % len(e.order)
+	e.ids[id] = struct{}{}
+
+	return false
+}
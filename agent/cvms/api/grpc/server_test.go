@@ -110,6 +110,49 @@ func TestGrpcServer_Process(t *testing.T) {
 	}
 }
 
+func TestGrpcServer_ProcessDropsReplayedEvents(t *testing.T) {
+	incoming := make(chan *cvms.ClientStreamMessage, 3)
+	mockSvc := new(mockService)
+	server := NewServer(incoming, mockSvc).(*grpcServer)
+
+	event := func(id string) *cvms.ClientStreamMessage {
+		return &cvms.ClientStreamMessage{Message: &cvms.ClientStreamMessage_AgentEvent{AgentEvent: &cvms.AgentEvent{Id: id}}}
+	}
+
+	mockStream := new(mockServerStream)
+	mockStream.On("Context").Return(peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     mockAddr{},
+		AuthInfo: mockAuthInfo{},
+	}))
+	mockStream.On("Recv").Return(event("event-1"), nil).Once()
+	mockStream.On("Recv").Return(event("event-1"), nil).Once()
+	mockStream.On("Recv").Return(event(""), nil).Once()
+	mockStream.On("Recv").Return(event("event-2"), nil).Once()
+	mockStream.On("Recv").Return(&cvms.ClientStreamMessage{}, errors.New("recv error"))
+	mockSvc.On("Run", mock.Anything, "test", mock.Anything, mock.AnythingOfType("mockAuthInfo")).Return()
+
+	err := server.Process(mockStream)
+	assert.Error(t, err)
+	close(incoming)
+
+	var ids []string
+	for mes := range incoming {
+		ids = append(ids, mes.GetAgentEvent().Id)
+	}
+	assert.Equal(t, []string{"event-1", "", "event-2"}, ids, "events without an ID are never dropped")
+}
+
+func TestEventIDs(t *testing.T) {
+	ids := newEventIDs(2)
+
+	assert.False(t, ids.seen("a"))
+	assert.True(t, ids.seen("a"))
+	assert.False(t, ids.seen("b"))
+	assert.False(t, ids.seen("c"))
+	assert.False(t, ids.seen("a"), "the oldest ID is forgotten")
+	assert.True(t, ids.seen("c"))
+}
+
 func TestGrpcServer_sendRunReqInChunks(t *testing.T) {
 	incoming := make(chan *cvms.ClientStreamMessage)
 	mockSvc := new(mockService)
@@ -3,21 +3,64 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/ultravioletrs/cocos/agent/cvms"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// DefaultCapacity is the number of pending messages kept by default, older
+// messages are dropped once it is reached.
+const DefaultCapacity = 10000
+
 // Message represents a pending message with its timestamp.
 type Message struct {
 	Message *cvms.ClientStreamMessage
 	Time    time.Time
 }
 
+// messageJSON is the stored form of a Message, the message is encoded with
+// protojson since encoding/json cannot decode its oneof back.
+type messageJSON struct {
+	Message json.RawMessage
+	Time    time.Time
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	data := []byte("null")
+	if m.Message != nil {
+		var err error
+		if data, err = protojson.Marshal(m.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(messageJSON{Message: data, Time: m.Time})
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var mj messageJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return err
+	}
+
+	m.Time = mj.Time
+	m.Message = nil
+	if len(mj.Message) == 0 || string(mj.Message) == "null" {
+		return nil
+	}
+
+	m.Message = &cvms.ClientStreamMessage{}
+
+	return protojson.Unmarshal(mj.Message, m.Message)
+}
+
 // Storage defines the interface for message persistence operations.
 type Storage interface {
 	// Load retrieves all pending messages from storage.
@@ -26,29 +69,49 @@ type Storage interface {
 	// Save persists the given messages to storage.
 	Save(messages []Message) error
 
-	// Add appends a new message to storage.
+	// Add appends a new message to storage, dropping the oldest message when
+	// the storage is full.
 	Add(msg *cvms.ClientStreamMessage) error
 
 	// Clear removes all messages from storage.
 	Clear() error
 }
 
-// FileStorage implements Storage interface using file-based persistence.
+// FileStorage implements Storage interface using file-based persistence. It
+// is a ring buffer of the last capacity messages, so messages queued while
+// the agent is disconnected for long cannot fill the disk. Messages are
+// appended to a log, one JSON line each, which is compacted to the pending
+// messages once it holds twice the capacity, and when the messages are saved
+// or cleared after a replay. Compactions replace the log atomically, and a
+// message torn by a crash while it was appended is dropped on load.
 type FileStorage struct {
-	mu   sync.Mutex
-	path string
-	msgs []Message
+	mu       sync.Mutex
+	path     string
+	capacity int
+	msgs     []Message
+	// lines is the number of messages in the log, including dropped ones.
+	lines int
 }
 
-// NewFileStorage creates a new file-based storage instance.
-func NewFileStorage(storageDir string) (*FileStorage, error) {
+// legacyFile is the JSON array the pending messages were kept in before the
+// log, it is moved to the log on load.
+const legacyFile = "pending_messages.json"
+
+// NewFileStorage creates a new file-based storage instance keeping up to
+// capacity messages, DefaultCapacity if capacity is not positive.
+func NewFileStorage(storageDir string, capacity int) (*FileStorage, error) {
 	if err := os.MkdirAll(storageDir, 0o755); err != nil {
 		return nil, err
 	}
 
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
 	return &FileStorage{
-		path: filepath.Join(storageDir, "pending_messages.json"),
-		msgs: make([]Message, 0),
+		path:     filepath.Join(storageDir, "pending_messages.log"),
+		capacity: capacity,
+		msgs:     make([]Message, 0),
 	}, nil
 }
 
@@ -56,17 +119,32 @@ func (fs *FileStorage) Load() ([]Message, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	data, err := os.ReadFile(fs.path)
-	if os.IsNotExist(err) {
-		return nil, nil
-	}
+	legacy, err := fs.loadLegacy()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(data, &fs.msgs); err != nil {
+	msgs, torn, err := fs.read()
+	if err != nil {
 		return nil, err
 	}
+	fs.msgs = fs.trim(append(legacy, msgs...))
+	fs.lines = len(msgs)
+
+	if legacy != nil || torn {
+		if err := fs.write(fs.msgs); err != nil {
+			return nil, err
+		}
+	}
+	if legacy != nil {
+		if err := os.Remove(filepath.Join(filepath.Dir(fs.path), legacyFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fs.msgs) == 0 {
+		return nil, nil
+	}
 
 	return fs.msgs, nil
 }
@@ -75,37 +153,168 @@ func (fs *FileStorage) Save(messages []Message) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	fs.msgs = messages
-
-	data, err := json.Marshal(messages)
-	if err != nil {
+	msgs := fs.trim(messages)
+	if err := fs.write(msgs); err != nil {
 		return err
 	}
+	fs.msgs = msgs
 
-	return os.WriteFile(fs.path, data, 0o644)
+	return nil
 }
 
 func (fs *FileStorage) Add(msg *cvms.ClientStreamMessage) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	fs.msgs = append(fs.msgs, Message{
+	m := Message{
 		Message: msg,
 		Time:    time.Now(),
-	})
+	}
+	msgs := fs.trim(append(fs.msgs, m))
+
+	if fs.lines+1 > 2*fs.capacity {
+		if err := fs.write(msgs); err != nil {
+			return err
+		}
+		fs.msgs = msgs
+
+		return nil
+	}
 
-	data, err := json.Marshal(fs.msgs)
+	line, err := json.Marshal(m)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(fs.path, data, 0o644)
+	f, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fs.msgs = msgs
+	fs.lines++
+
+	return nil
 }
 
 func (fs *FileStorage) Clear() error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
+	if err := fs.write(nil); err != nil {
+		return err
+	}
 	fs.msgs = make([]Message, 0)
-	return os.WriteFile(fs.path, []byte("[]"), 0o644)
+
+	return nil
+}
+
+// read returns the messages of the log, and whether its last message was
+// torn, i.e. not terminated by a newline. Other malformed messages fail the
+// load.
+func (fs *FileStorage) read() ([]Message, bool, error) {
+	f, err := os.Open(fs.path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var msgs []Message
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return msgs, len(line) > 0, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		var m Message
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, false, err
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// loadLegacy returns the messages of the legacy file, nil if there is none.
+func (fs *FileStorage) loadLegacy() ([]Message, error) {
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(fs.path), legacyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := []Message{}
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+// write replaces the log with the messages. The messages are written to a
+// temporary file that is renamed over the log, so a crash leaves either log.
+func (fs *FileStorage) write(msgs []Message) error {
+	f, err := os.CreateTemp(filepath.Dir(fs.path), filepath.Base(fs.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	for _, m := range msgs {
+		line, err := json.Marshal(m)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), fs.path); err != nil {
+		return err
+	}
+	fs.lines = len(msgs)
+
+	return nil
+}
+
+// trim drops the oldest messages beyond the capacity of the storage.
+func (fs *FileStorage) trim(msgs []Message) []Message {
+	if len(msgs) <= fs.capacity {
+		return msgs
+	}
+
+	return msgs[len(msgs)-fs.capacity:]
 }
@@ -3,6 +3,7 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -58,7 +59,7 @@ func TestNewFileStorage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			storage, err := NewFileStorage(tt.storageDir)
+			storage, err := NewFileStorage(tt.storageDir, DefaultCapacity)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -66,7 +67,7 @@ func TestNewFileStorage(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.NotNil(t, storage)
-				assert.Equal(t, filepath.Join(tt.storageDir, "pending_messages.json"), storage.path)
+				assert.Equal(t, filepath.Join(tt.storageDir, "pending_messages.log"), storage.path)
 				assert.Empty(t, storage.msgs)
 			}
 		})
@@ -92,7 +93,7 @@ func TestFileStorage_Load(t *testing.T) {
 		{
 			name: "load from empty file",
 			setupFile: func(path string) error {
-				return os.WriteFile(path, []byte("[]"), 0o644)
+				return os.WriteFile(path, nil, 0o644)
 			},
 			expectedMsgs: 0,
 			expectError:  false,
@@ -100,17 +101,33 @@ func TestFileStorage_Load(t *testing.T) {
 		{
 			name: "load from corrupted file",
 			setupFile: func(path string) error {
-				return os.WriteFile(path, []byte("invalid json"), 0o644)
+				return os.WriteFile(path, []byte("invalid json\n"), 0o644)
 			},
 			expectedMsgs: 0,
 			expectError:  true,
 		},
+		{
+			name: "load drops a torn message",
+			setupFile: func(path string) error {
+				return os.WriteFile(path, []byte(`{"Message":null,"Time":"2025-01-01T00:00:00Z"}`+"\n"+`{"Message":{"runR`), 0o644)
+			},
+			expectedMsgs: 1,
+			expectError:  false,
+		},
+		{
+			name: "load from legacy file",
+			setupFile: func(path string) error {
+				return os.WriteFile(filepath.Join(filepath.Dir(path), legacyFile), []byte(`[{"Message":null,"Time":"2025-01-01T00:00:00Z"}]`), 0o644)
+			},
+			expectedMsgs: 1,
+			expectError:  false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tmpDir := createTempDir(t)
-			storage, err := NewFileStorage(tmpDir)
+			storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 			require.NoError(t, err)
 
 			err = tt.setupFile(storage.path)
@@ -168,7 +185,7 @@ func TestFileStorage_Save(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tmpDir := createTempDir(t)
-			storage, err := NewFileStorage(tmpDir)
+			storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 			require.NoError(t, err)
 
 			err = storage.Save(tt.messages)
@@ -228,7 +245,7 @@ func TestFileStorage_Add(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tmpDir := createTempDir(t)
-			storage, err := NewFileStorage(tmpDir)
+			storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 			require.NoError(t, err)
 
 			// Setup initial messages
@@ -294,7 +311,7 @@ func TestFileStorage_Clear(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tmpDir := createTempDir(t)
-			storage, err := NewFileStorage(tmpDir)
+			storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 			require.NoError(t, err)
 
 			// Setup initial messages
@@ -313,10 +330,10 @@ func TestFileStorage_Clear(t *testing.T) {
 				// Verify internal state is cleared
 				assert.Empty(t, storage.msgs)
 
-				// Verify file contains empty array
+				// Verify file is empty
 				data, err := os.ReadFile(storage.path)
 				assert.NoError(t, err)
-				assert.Equal(t, "[]", string(data))
+				assert.Empty(t, data)
 			}
 		})
 	}
@@ -324,7 +341,7 @@ func TestFileStorage_Clear(t *testing.T) {
 
 func TestFileStorage_ConcurrentAccess(t *testing.T) {
 	tmpDir := createTempDir(t)
-	storage, err := NewFileStorage(tmpDir)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 	require.NoError(t, err)
 
 	// Test concurrent Add operations
@@ -354,7 +371,7 @@ func TestFileStorage_ConcurrentAccess(t *testing.T) {
 
 func TestFileStorage_IntegrationFlow(t *testing.T) {
 	tmpDir := createTempDir(t)
-	storage, err := NewFileStorage(tmpDir)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 	require.NoError(t, err)
 
 	// Test full workflow
@@ -403,9 +420,95 @@ func TestFileStorage_IntegrationFlow(t *testing.T) {
 	assert.Empty(t, msgs)
 }
 
+func TestFileStorage_Capacity(t *testing.T) {
+	storage, err := NewFileStorage(createTempDir(t), 2)
+	require.NoError(t, err)
+
+	for _, id := range []string{"message1", "message2", "message3"} {
+		require.NoError(t, storage.Add(createTestMessage(id)))
+	}
+
+	msgs, err := storage.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2, "the oldest message is dropped")
+	assert.Equal(t, "message2", msgs[0].Message.GetRunRes().ComputationId)
+	assert.Equal(t, "message3", msgs[1].Message.GetRunRes().ComputationId)
+}
+
+func TestFileStorage_Compaction(t *testing.T) {
+	storage, err := NewFileStorage(createTempDir(t), 2)
+	require.NoError(t, err)
+
+	for i := range 10 {
+		require.NoError(t, storage.Add(createTestMessage(fmt.Sprintf("message%d", i))))
+		assert.LessOrEqual(t, storage.lines, 4, "the log is compacted once it holds twice the capacity")
+	}
+
+	reloaded, err := NewFileStorage(filepath.Dir(storage.path), 2)
+	require.NoError(t, err)
+
+	msgs, err := reloaded.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "message8", msgs[0].Message.GetRunRes().ComputationId)
+	assert.Equal(t, "message9", msgs[1].Message.GetRunRes().ComputationId)
+}
+
+func TestFileStorage_TornAppend(t *testing.T) {
+	tmpDir := createTempDir(t)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
+	require.NoError(t, err)
+	require.NoError(t, storage.Add(createTestMessage("message1")))
+
+	// A crash while a message was appended leaves a partial line.
+	f, err := os.OpenFile(storage.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"Message":{"ru`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reloaded, err := NewFileStorage(tmpDir, DefaultCapacity)
+	require.NoError(t, err)
+	msgs, err := reloaded.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	// Messages appended after the load are not mixed with the torn one.
+	require.NoError(t, reloaded.Add(createTestMessage("message2")))
+	msgs, err = reloaded.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "message2", msgs[1].Message.GetRunRes().ComputationId)
+}
+
+func TestFileStorage_Reload(t *testing.T) {
+	tmpDir := createTempDir(t)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
+	require.NoError(t, err)
+
+	event := &cvms.ClientStreamMessage{
+		Message: &cvms.ClientStreamMessage_AgentEvent{
+			AgentEvent: &cvms.AgentEvent{Id: "event-1", EventType: "running", ComputationId: "1"},
+		},
+	}
+	require.NoError(t, storage.Add(createTestMessage("message1")))
+	require.NoError(t, storage.Add(event))
+
+	// A restarted agent loads the messages queued by the previous one.
+	reloaded, err := NewFileStorage(tmpDir, DefaultCapacity)
+	require.NoError(t, err)
+
+	msgs, err := reloaded.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "message1", msgs[0].Message.GetRunRes().ComputationId)
+	assert.Equal(t, "event-1", msgs[1].Message.GetAgentEvent().Id)
+	assert.Equal(t, "running", msgs[1].Message.GetAgentEvent().EventType)
+}
+
 func TestFileStorage_FilePermissions(t *testing.T) {
 	tmpDir := createTempDir(t)
-	storage, err := NewFileStorage(tmpDir)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 	require.NoError(t, err)
 
 	// Add a message to create the file
@@ -421,7 +524,7 @@ func TestFileStorage_FilePermissions(t *testing.T) {
 
 func TestFileStorage_ErrorHandling(t *testing.T) {
 	tmpDir := createTempDir(t)
-	storage, err := NewFileStorage(tmpDir)
+	storage, err := NewFileStorage(tmpDir, DefaultCapacity)
 	require.NoError(t, err)
 
 	// Make directory read-only to trigger write errors
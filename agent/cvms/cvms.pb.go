@@ -277,6 +277,9 @@ type AgentEvent struct {
 	Details       []byte                 `protobuf:"bytes,4,opt,name=details,proto3" json:"details,omitempty"`
 	Originator    string                 `protobuf:"bytes,5,opt,name=originator,proto3" json:"originator,omitempty"`
 	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	// id identifies the event, events replayed after a reconnection keep it so
+	// that duplicates can be dropped.
+	Id            string `protobuf:"bytes,7,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -353,6 +356,13 @@ func (x *AgentEvent) GetStatus() string {
 	return ""
 }
 
+func (x *AgentEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
 type AgentLog struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
@@ -818,16 +828,27 @@ func (x *RunReqChunks) GetIsLast() bool {
 }
 
 type ComputationRunReq struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Description     string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	Datasets        []*Dataset             `protobuf:"bytes,4,rep,name=datasets,proto3" json:"datasets,omitempty"`
-	Algorithm       *Algorithm             `protobuf:"bytes,5,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
-	ResultConsumers []*ResultConsumer      `protobuf:"bytes,6,rep,name=result_consumers,json=resultConsumers,proto3" json:"result_consumers,omitempty"`
-	AgentConfig     *AgentConfig           `protobuf:"bytes,7,opt,name=agent_config,json=agentConfig,proto3" json:"agent_config,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Id                  string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description         string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Datasets            []*Dataset             `protobuf:"bytes,4,rep,name=datasets,proto3" json:"datasets,omitempty"`
+	Algorithm           *Algorithm             `protobuf:"bytes,5,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	ResultConsumers     []*ResultConsumer      `protobuf:"bytes,6,rep,name=result_consumers,json=resultConsumers,proto3" json:"result_consumers,omitempty"`
+	AgentConfig         *AgentConfig           `protobuf:"bytes,7,opt,name=agent_config,json=agentConfig,proto3" json:"agent_config,omitempty"`
+	Signature           []byte                 `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`                        // signature over the manifest by a key trusted by the agent.
+	ResultCodec         string                 `protobuf:"bytes,9,opt,name=result_codec,json=resultCodec,proto3" json:"result_codec,omitempty"` // compression codec of the result archive, deflate or zstd.
+	Version             uint32                 `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`                          // manifest schema version, 2 requires every role to be bound to a key.
+	Ttl                 string                 `protobuf:"bytes,11,opt,name=ttl,proto3" json:"ttl,omitempty"`                                   // lifetime of the computation once the manifest is received, e.g. "2h".
+	MaxRuntime          string                 `protobuf:"bytes,12,opt,name=max_runtime,json=maxRuntime,proto3" json:"max_runtime,omitempty"`   // how long the algorithm may run, e.g. "30m".
+	EventEncryption     *EventEncryption       `protobuf:"bytes,13,opt,name=event_encryption,json=eventEncryption,proto3" json:"event_encryption,omitempty"`
+	Checkpoint          *Checkpoint            `protobuf:"bytes,14,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	DatasetNaming       string                 `protobuf:"bytes,15,opt,name=dataset_naming,json=datasetNaming,proto3" json:"dataset_naming,omitempty"` // names datasets appear under for the algorithm: upload, manifest or ordered.
+	AttestationApproval *AttestationApproval   `protobuf:"bytes,16,opt,name=attestation_approval,json=attestationApproval,proto3" json:"attestation_approval,omitempty"`
+	Storage             *Storage               `protobuf:"bytes,17,opt,name=storage,proto3" json:"storage,omitempty"`
+	ResultSink          *ResultSink            `protobuf:"bytes,18,opt,name=result_sink,json=resultSink,proto3" json:"result_sink,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *ComputationRunReq) Reset() {
@@ -909,27 +930,111 @@ func (x *ComputationRunReq) GetAgentConfig() *AgentConfig {
 	return nil
 }
 
-type ResultConsumer struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserKey       []byte                 `protobuf:"bytes,1,opt,name=userKey,proto3" json:"userKey,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ComputationRunReq) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
 }
 
-func (x *ResultConsumer) Reset() {
-	*x = ResultConsumer{}
+func (x *ComputationRunReq) GetResultCodec() string {
+	if x != nil {
+		return x.ResultCodec
+	}
+	return ""
+}
+
+func (x *ComputationRunReq) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ComputationRunReq) GetTtl() string {
+	if x != nil {
+		return x.Ttl
+	}
+	return ""
+}
+
+func (x *ComputationRunReq) GetMaxRuntime() string {
+	if x != nil {
+		return x.MaxRuntime
+	}
+	return ""
+}
+
+func (x *ComputationRunReq) GetEventEncryption() *EventEncryption {
+	if x != nil {
+		return x.EventEncryption
+	}
+	return nil
+}
+
+func (x *ComputationRunReq) GetCheckpoint() *Checkpoint {
+	if x != nil {
+		return x.Checkpoint
+	}
+	return nil
+}
+
+func (x *ComputationRunReq) GetDatasetNaming() string {
+	if x != nil {
+		return x.DatasetNaming
+	}
+	return ""
+}
+
+func (x *ComputationRunReq) GetAttestationApproval() *AttestationApproval {
+	if x != nil {
+		return x.AttestationApproval
+	}
+	return nil
+}
+
+func (x *ComputationRunReq) GetStorage() *Storage {
+	if x != nil {
+		return x.Storage
+	}
+	return nil
+}
+
+func (x *ComputationRunReq) GetResultSink() *ResultSink {
+	if x != nil {
+		return x.ResultSink
+	}
+	return nil
+}
+
+type ResultSink struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Type            string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`         // object storage the results are uploaded to, s3.
+	Endpoint        string                 `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"` // http or https URL of the object storage.
+	Region          string                 `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty"`
+	Bucket          string                 `protobuf:"bytes,4,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Key             string                 `protobuf:"bytes,5,opt,name=key,proto3" json:"key,omitempty"`                                                  // object key, <computation id>/results.zip.enc if empty.
+	EncryptionKey   []byte                 `protobuf:"bytes,6,opt,name=encryption_key,json=encryptionKey,proto3" json:"encryption_key,omitempty"`         // X25519 public key the results are encrypted with.
+	AccessKeySecret string                 `protobuf:"bytes,7,opt,name=access_key_secret,json=accessKeySecret,proto3" json:"access_key_secret,omitempty"` // name of the owner secret holding the access key ID.
+	SecretKeySecret string                 `protobuf:"bytes,8,opt,name=secret_key_secret,json=secretKeySecret,proto3" json:"secret_key_secret,omitempty"` // name of the owner secret holding the secret access key.
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ResultSink) Reset() {
+	*x = ResultSink{}
 	mi := &file_agent_cvms_cvms_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResultConsumer) String() string {
+func (x *ResultSink) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResultConsumer) ProtoMessage() {}
+func (*ResultSink) ProtoMessage() {}
 
-func (x *ResultConsumer) ProtoReflect() protoreflect.Message {
+func (x *ResultSink) ProtoReflect() protoreflect.Message {
 	mi := &file_agent_cvms_cvms_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -941,41 +1046,89 @@ func (x *ResultConsumer) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResultConsumer.ProtoReflect.Descriptor instead.
-func (*ResultConsumer) Descriptor() ([]byte, []int) {
+// Deprecated: Use ResultSink.ProtoReflect.Descriptor instead.
+func (*ResultSink) Descriptor() ([]byte, []int) {
 	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *ResultConsumer) GetUserKey() []byte {
+func (x *ResultSink) GetType() string {
 	if x != nil {
-		return x.UserKey
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ResultSink) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *ResultSink) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *ResultSink) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *ResultSink) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ResultSink) GetEncryptionKey() []byte {
+	if x != nil {
+		return x.EncryptionKey
 	}
 	return nil
 }
 
-type Dataset struct {
+func (x *ResultSink) GetAccessKeySecret() string {
+	if x != nil {
+		return x.AccessKeySecret
+	}
+	return ""
+}
+
+func (x *ResultSink) GetSecretKeySecret() string {
+	if x != nil {
+		return x.SecretKeySecret
+	}
+	return ""
+}
+
+type Storage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"` // should be sha3.Sum256, 32 byte length.
-	UserKey       []byte                 `protobuf:"bytes,2,opt,name=userKey,proto3" json:"userKey,omitempty"`
-	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`                    // where datasets and results are kept: disk, tmpfs or block.
+	SizeMb        uint64                 `protobuf:"varint,2,opt,name=size_mb,json=sizeMb,proto3" json:"size_mb,omitempty"` // size limit of each tmpfs directory in MiB.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Dataset) Reset() {
-	*x = Dataset{}
+func (x *Storage) Reset() {
+	*x = Storage{}
 	mi := &file_agent_cvms_cvms_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Dataset) String() string {
+func (x *Storage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Dataset) ProtoMessage() {}
+func (*Storage) ProtoMessage() {}
 
-func (x *Dataset) ProtoReflect() protoreflect.Message {
+func (x *Storage) ProtoReflect() protoreflect.Message {
 	mi := &file_agent_cvms_cvms_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -987,54 +1140,46 @@ func (x *Dataset) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Dataset.ProtoReflect.Descriptor instead.
-func (*Dataset) Descriptor() ([]byte, []int) {
+// Deprecated: Use Storage.ProtoReflect.Descriptor instead.
+func (*Storage) Descriptor() ([]byte, []int) {
 	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *Dataset) GetHash() []byte {
-	if x != nil {
-		return x.Hash
-	}
-	return nil
-}
-
-func (x *Dataset) GetUserKey() []byte {
+func (x *Storage) GetType() string {
 	if x != nil {
-		return x.UserKey
+		return x.Type
 	}
-	return nil
+	return ""
 }
 
-func (x *Dataset) GetFilename() string {
+func (x *Storage) GetSizeMb() uint64 {
 	if x != nil {
-		return x.Filename
+		return x.SizeMb
 	}
-	return ""
+	return 0
 }
 
-type Algorithm struct {
+type AttestationApproval struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"` // should be sha3.Sum256, 32 byte length.
-	UserKey       []byte                 `protobuf:"bytes,2,opt,name=userKey,proto3" json:"userKey,omitempty"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"` // PKIX Ed25519 or ECDSA public key of the computation owner who approves the attestation.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Algorithm) Reset() {
-	*x = Algorithm{}
+func (x *AttestationApproval) Reset() {
+	*x = AttestationApproval{}
 	mi := &file_agent_cvms_cvms_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Algorithm) String() string {
+func (x *AttestationApproval) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Algorithm) ProtoMessage() {}
+func (*AttestationApproval) ProtoMessage() {}
 
-func (x *Algorithm) ProtoReflect() protoreflect.Message {
+func (x *AttestationApproval) ProtoReflect() protoreflect.Message {
 	mi := &file_agent_cvms_cvms_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1046,52 +1191,40 @@ func (x *Algorithm) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Algorithm.ProtoReflect.Descriptor instead.
-func (*Algorithm) Descriptor() ([]byte, []int) {
+// Deprecated: Use AttestationApproval.ProtoReflect.Descriptor instead.
+func (*AttestationApproval) Descriptor() ([]byte, []int) {
 	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *Algorithm) GetHash() []byte {
-	if x != nil {
-		return x.Hash
-	}
-	return nil
-}
-
-func (x *Algorithm) GetUserKey() []byte {
+func (x *AttestationApproval) GetKey() []byte {
 	if x != nil {
-		return x.UserKey
+		return x.Key
 	}
 	return nil
 }
 
-type AgentConfig struct {
+type Checkpoint struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Port          string                 `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
-	CertFile      string                 `protobuf:"bytes,2,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`
-	KeyFile       string                 `protobuf:"bytes,3,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`
-	ClientCaFile  string                 `protobuf:"bytes,4,opt,name=client_ca_file,json=clientCaFile,proto3" json:"client_ca_file,omitempty"`
-	ServerCaFile  string                 `protobuf:"bytes,5,opt,name=server_ca_file,json=serverCaFile,proto3" json:"server_ca_file,omitempty"`
-	LogLevel      string                 `protobuf:"bytes,6,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
-	AttestedTls   bool                   `protobuf:"varint,7,opt,name=attested_tls,json=attestedTls,proto3" json:"attested_tls,omitempty"`
+	Interval      string                 `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`                    // how often the algorithm working directory is saved, e.g. "10m".
+	KeySecret     string                 `protobuf:"bytes,3,opt,name=key_secret,json=keySecret,proto3" json:"key_secret,omitempty"` // name of the owner secret holding the base64 encoded 32-byte key the checkpoints are sealed with.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AgentConfig) Reset() {
-	*x = AgentConfig{}
+func (x *Checkpoint) Reset() {
+	*x = Checkpoint{}
 	mi := &file_agent_cvms_cvms_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AgentConfig) String() string {
+func (x *Checkpoint) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AgentConfig) ProtoMessage() {}
+func (*Checkpoint) ProtoMessage() {}
 
-func (x *AgentConfig) ProtoReflect() protoreflect.Message {
+func (x *Checkpoint) ProtoReflect() protoreflect.Message {
 	mi := &file_agent_cvms_cvms_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1103,83 +1236,108 @@ func (x *AgentConfig) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AgentConfig.ProtoReflect.Descriptor instead.
-func (*AgentConfig) Descriptor() ([]byte, []int) {
+// Deprecated: Use Checkpoint.ProtoReflect.Descriptor instead.
+func (*Checkpoint) Descriptor() ([]byte, []int) {
 	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *AgentConfig) GetPort() string {
+func (x *Checkpoint) GetInterval() string {
 	if x != nil {
-		return x.Port
+		return x.Interval
 	}
 	return ""
 }
 
-func (x *AgentConfig) GetCertFile() string {
+func (x *Checkpoint) GetKeySecret() string {
 	if x != nil {
-		return x.CertFile
+		return x.KeySecret
 	}
 	return ""
 }
 
-func (x *AgentConfig) GetKeyFile() string {
-	if x != nil {
-		return x.KeyFile
-	}
-	return ""
+type EventEncryption struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`                                        // X25519 public key of the computation owner.
+	Fields        []string               `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`                                  // event detail fields to encrypt, every field if empty.
+	StreamOutput  bool                   `protobuf:"varint,3,opt,name=stream_output,json=streamOutput,proto3" json:"stream_output,omitempty"` // streams the algorithm output to the manager encrypted with the key.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AgentConfig) GetClientCaFile() string {
+func (x *EventEncryption) Reset() {
+	*x = EventEncryption{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventEncryption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventEncryption) ProtoMessage() {}
+
+func (x *EventEncryption) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[16]
 	if x != nil {
-		return x.ClientCaFile
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *AgentConfig) GetServerCaFile() string {
+// Deprecated: Use EventEncryption.ProtoReflect.Descriptor instead.
+func (*EventEncryption) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *EventEncryption) GetKey() []byte {
 	if x != nil {
-		return x.ServerCaFile
+		return x.Key
 	}
-	return ""
+	return nil
 }
 
-func (x *AgentConfig) GetLogLevel() string {
+func (x *EventEncryption) GetFields() []string {
 	if x != nil {
-		return x.LogLevel
+		return x.Fields
 	}
-	return ""
+	return nil
 }
 
-func (x *AgentConfig) GetAttestedTls() bool {
+func (x *EventEncryption) GetStreamOutput() bool {
 	if x != nil {
-		return x.AttestedTls
+		return x.StreamOutput
 	}
 	return false
 }
 
-type AttestationResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	File             []byte                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
-	CertSerialNumber string                 `protobuf:"bytes,2,opt,name=certSerialNumber,proto3" json:"certSerialNumber,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+type ResultConsumer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserKey       []byte                 `protobuf:"bytes,1,opt,name=userKey,proto3" json:"userKey,omitempty"`
+	EncryptionKey []byte                 `protobuf:"bytes,2,opt,name=encryptionKey,proto3" json:"encryptionKey,omitempty"` // optional X25519 public key used to encrypt the result.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AttestationResponse) Reset() {
-	*x = AttestationResponse{}
-	mi := &file_agent_cvms_cvms_proto_msgTypes[16]
+func (x *ResultConsumer) Reset() {
+	*x = ResultConsumer{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AttestationResponse) String() string {
+func (x *ResultConsumer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AttestationResponse) ProtoMessage() {}
+func (*ResultConsumer) ProtoMessage() {}
 
-func (x *AttestationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_cvms_cvms_proto_msgTypes[16]
+func (x *ResultConsumer) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1190,23 +1348,697 @@ func (x *AttestationResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AttestationResponse.ProtoReflect.Descriptor instead.
-func (*AttestationResponse) Descriptor() ([]byte, []int) {
-	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use ResultConsumer.ProtoReflect.Descriptor instead.
+func (*ResultConsumer) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *AttestationResponse) GetFile() []byte {
+func (x *ResultConsumer) GetUserKey() []byte {
 	if x != nil {
-		return x.File
+		return x.UserKey
 	}
 	return nil
 }
 
-func (x *AttestationResponse) GetCertSerialNumber() string {
+func (x *ResultConsumer) GetEncryptionKey() []byte {
 	if x != nil {
-		return x.CertSerialNumber
+		return x.EncryptionKey
 	}
-	return ""
+	return nil
+}
+
+type Dataset struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"` // should be sha3.Sum256, 32 byte length.
+	UserKey       []byte                 `protobuf:"bytes,2,opt,name=userKey,proto3" json:"userKey,omitempty"`
+	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	Archive       *DatasetArchive        `protobuf:"bytes,4,opt,name=archive,proto3" json:"archive,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Dataset) Reset() {
+	*x = Dataset{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dataset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dataset) ProtoMessage() {}
+
+func (x *Dataset) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dataset.ProtoReflect.Descriptor instead.
+func (*Dataset) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Dataset) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Dataset) GetUserKey() []byte {
+	if x != nil {
+		return x.UserKey
+	}
+	return nil
+}
+
+func (x *Dataset) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Dataset) GetArchive() *DatasetArchive {
+	if x != nil {
+		return x.Archive
+	}
+	return nil
+}
+
+type DatasetArchive struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxSizeMb     uint64                 `protobuf:"varint,1,opt,name=max_size_mb,json=maxSizeMb,proto3" json:"max_size_mb,omitempty"` // total size of the extracted files, 0 leaves it unbounded.
+	MaxFiles      uint64                 `protobuf:"varint,2,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`      // number of extracted files, 0 leaves it unbounded.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatasetArchive) Reset() {
+	*x = DatasetArchive{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatasetArchive) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatasetArchive) ProtoMessage() {}
+
+func (x *DatasetArchive) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatasetArchive.ProtoReflect.Descriptor instead.
+func (*DatasetArchive) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DatasetArchive) GetMaxSizeMb() uint64 {
+	if x != nil {
+		return x.MaxSizeMb
+	}
+	return 0
+}
+
+func (x *DatasetArchive) GetMaxFiles() uint64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+type Algorithm struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"` // should be sha3.Sum256, 32 byte length.
+	UserKey       []byte                 `protobuf:"bytes,2,opt,name=userKey,proto3" json:"userKey,omitempty"`
+	Steps         []*Step                `protobuf:"bytes,3,rep,name=steps,proto3" json:"steps,omitempty"`
+	WasmLimits    *WasmLimits            `protobuf:"bytes,4,opt,name=wasm_limits,json=wasmLimits,proto3" json:"wasm_limits,omitempty"`
+	Watchdog      *Watchdog              `protobuf:"bytes,5,opt,name=watchdog,proto3" json:"watchdog,omitempty"`
+	Resources     *Resources             `protobuf:"bytes,6,opt,name=resources,proto3" json:"resources,omitempty"`
+	Args          []string               `protobuf:"bytes,7,rep,name=args,proto3" json:"args,omitempty"`                                                                         // passed to the algorithm before the uploaded or step arguments.
+	Env           map[string]string      `protobuf:"bytes,8,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // environment variables the algorithm runs with.
+	Bundle        *AlgorithmBundle       `protobuf:"bytes,9,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	Type          string                 `protobuf:"bytes,10,opt,name=type,proto3" json:"type,omitempty"` // type the algorithm must be uploaded as, e.g. bin or python, any type when empty.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Algorithm) Reset() {
+	*x = Algorithm{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Algorithm) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Algorithm) ProtoMessage() {}
+
+func (x *Algorithm) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Algorithm.ProtoReflect.Descriptor instead.
+func (*Algorithm) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Algorithm) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Algorithm) GetUserKey() []byte {
+	if x != nil {
+		return x.UserKey
+	}
+	return nil
+}
+
+func (x *Algorithm) GetSteps() []*Step {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *Algorithm) GetWasmLimits() *WasmLimits {
+	if x != nil {
+		return x.WasmLimits
+	}
+	return nil
+}
+
+func (x *Algorithm) GetWatchdog() *Watchdog {
+	if x != nil {
+		return x.Watchdog
+	}
+	return nil
+}
+
+func (x *Algorithm) GetResources() *Resources {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *Algorithm) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *Algorithm) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *Algorithm) GetBundle() *AlgorithmBundle {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+func (x *Algorithm) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+// AlgorithmBundle declares an algorithm uploaded as a tar archive of several
+// files, e.g. a script with its model weights and configuration.
+type AlgorithmBundle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entrypoint    string                 `protobuf:"bytes,1,opt,name=entrypoint,proto3" json:"entrypoint,omitempty"`                   // slash separated path of the file run inside the archive.
+	MaxSizeMb     uint64                 `protobuf:"varint,2,opt,name=max_size_mb,json=maxSizeMb,proto3" json:"max_size_mb,omitempty"` // total size of the extracted files, 0 leaves it unbounded.
+	MaxFiles      uint64                 `protobuf:"varint,3,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`      // number of extracted files, 0 leaves it unbounded.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlgorithmBundle) Reset() {
+	*x = AlgorithmBundle{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlgorithmBundle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlgorithmBundle) ProtoMessage() {}
+
+func (x *AlgorithmBundle) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlgorithmBundle.ProtoReflect.Descriptor instead.
+func (*AlgorithmBundle) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *AlgorithmBundle) GetEntrypoint() string {
+	if x != nil {
+		return x.Entrypoint
+	}
+	return ""
+}
+
+func (x *AlgorithmBundle) GetMaxSizeMb() uint64 {
+	if x != nil {
+		return x.MaxSizeMb
+	}
+	return 0
+}
+
+func (x *AlgorithmBundle) GetMaxFiles() uint64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+type WasmLimits struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MaxMemoryMb    uint32                 `protobuf:"varint,1,opt,name=max_memory_mb,json=maxMemoryMb,proto3" json:"max_memory_mb,omitempty"`        // memory the module can grow to, 0 keeps the runtime default.
+	TimeoutSeconds uint32                 `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // execution time budget, 0 disables it.
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WasmLimits) Reset() {
+	*x = WasmLimits{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WasmLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WasmLimits) ProtoMessage() {}
+
+func (x *WasmLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WasmLimits.ProtoReflect.Descriptor instead.
+func (*WasmLimits) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WasmLimits) GetMaxMemoryMb() uint32 {
+	if x != nil {
+		return x.MaxMemoryMb
+	}
+	return 0
+}
+
+func (x *WasmLimits) GetTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+type Resources struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cpus          float64                `protobuf:"fixed64,1,opt,name=cpus,proto3" json:"cpus,omitempty"`                        // CPU bandwidth in CPUs, 0 leaves it unbounded.
+	MemoryMb      uint64                 `protobuf:"varint,2,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"` // memory of the algorithm processes, 0 leaves it unbounded.
+	DiskMb        uint64                 `protobuf:"varint,3,opt,name=disk_mb,json=diskMb,proto3" json:"disk_mb,omitempty"`       // size of the results directory, 0 leaves it unbounded.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Resources) Reset() {
+	*x = Resources{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Resources) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Resources) ProtoMessage() {}
+
+func (x *Resources) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Resources.ProtoReflect.Descriptor instead.
+func (*Resources) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Resources) GetCpus() float64 {
+	if x != nil {
+		return x.Cpus
+	}
+	return 0
+}
+
+func (x *Resources) GetMemoryMb() uint64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *Resources) GetDiskMb() uint64 {
+	if x != nil {
+		return x.DiskMb
+	}
+	return 0
+}
+
+type Watchdog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IdleSeconds   uint32                 `protobuf:"varint,1,opt,name=idle_seconds,json=idleSeconds,proto3" json:"idle_seconds,omitempty"` // time without CPU use or output after which the algorithm is possibly hung, 0 disables it.
+	Kill          bool                   `protobuf:"varint,2,opt,name=kill,proto3" json:"kill,omitempty"`                                  // whether to stop a possibly hung algorithm, failing the computation.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Watchdog) Reset() {
+	*x = Watchdog{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Watchdog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Watchdog) ProtoMessage() {}
+
+func (x *Watchdog) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Watchdog.ProtoReflect.Descriptor instead.
+func (*Watchdog) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Watchdog) GetIdleSeconds() uint32 {
+	if x != nil {
+		return x.IdleSeconds
+	}
+	return 0
+}
+
+func (x *Watchdog) GetKill() bool {
+	if x != nil {
+		return x.Kill
+	}
+	return false
+}
+
+type Step struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Args          []string               `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	Datasets      []string               `protobuf:"bytes,3,rep,name=datasets,proto3" json:"datasets,omitempty"` // filenames of the datasets the step may read.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Step) Reset() {
+	*x = Step{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Step) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Step) ProtoMessage() {}
+
+func (x *Step) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Step.ProtoReflect.Descriptor instead.
+func (*Step) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *Step) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Step) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *Step) GetDatasets() []string {
+	if x != nil {
+		return x.Datasets
+	}
+	return nil
+}
+
+type AgentConfig struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          string                 `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
+	CertFile      string                 `protobuf:"bytes,2,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`
+	KeyFile       string                 `protobuf:"bytes,3,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`
+	ClientCaFile  string                 `protobuf:"bytes,4,opt,name=client_ca_file,json=clientCaFile,proto3" json:"client_ca_file,omitempty"`
+	ServerCaFile  string                 `protobuf:"bytes,5,opt,name=server_ca_file,json=serverCaFile,proto3" json:"server_ca_file,omitempty"`
+	LogLevel      string                 `protobuf:"bytes,6,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
+	AttestedTls   bool                   `protobuf:"varint,7,opt,name=attested_tls,json=attestedTls,proto3" json:"attested_tls,omitempty"`
+	HttpPort      string                 `protobuf:"bytes,8,opt,name=http_port,json=httpPort,proto3" json:"http_port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentConfig) Reset() {
+	*x = AgentConfig{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentConfig) ProtoMessage() {}
+
+func (x *AgentConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentConfig.ProtoReflect.Descriptor instead.
+func (*AgentConfig) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AgentConfig) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetCertFile() string {
+	if x != nil {
+		return x.CertFile
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetKeyFile() string {
+	if x != nil {
+		return x.KeyFile
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetClientCaFile() string {
+	if x != nil {
+		return x.ClientCaFile
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetServerCaFile() string {
+	if x != nil {
+		return x.ServerCaFile
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetLogLevel() string {
+	if x != nil {
+		return x.LogLevel
+	}
+	return ""
+}
+
+func (x *AgentConfig) GetAttestedTls() bool {
+	if x != nil {
+		return x.AttestedTls
+	}
+	return false
+}
+
+func (x *AgentConfig) GetHttpPort() string {
+	if x != nil {
+		return x.HttpPort
+	}
+	return ""
+}
+
+type AttestationResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	File             []byte                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	CertSerialNumber string                 `protobuf:"bytes,2,opt,name=certSerialNumber,proto3" json:"certSerialNumber,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AttestationResponse) Reset() {
+	*x = AttestationResponse{}
+	mi := &file_agent_cvms_cvms_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttestationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttestationResponse) ProtoMessage() {}
+
+func (x *AttestationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_cvms_cvms_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttestationResponse.ProtoReflect.Descriptor instead.
+func (*AttestationResponse) Descriptor() ([]byte, []int) {
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *AttestationResponse) GetFile() []byte {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *AttestationResponse) GetCertSerialNumber() string {
+	if x != nil {
+		return x.CertSerialNumber
+	}
+	return ""
 }
 
 type AzureAttestationToken struct {
@@ -1219,7 +2051,7 @@ type AzureAttestationToken struct {
 
 func (x *AzureAttestationToken) Reset() {
 	*x = AzureAttestationToken{}
-	mi := &file_agent_cvms_cvms_proto_msgTypes[17]
+	mi := &file_agent_cvms_cvms_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1231,7 +2063,7 @@ func (x *AzureAttestationToken) String() string {
 func (*AzureAttestationToken) ProtoMessage() {}
 
 func (x *AzureAttestationToken) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_cvms_cvms_proto_msgTypes[17]
+	mi := &file_agent_cvms_cvms_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1244,7 +2076,7 @@ func (x *AzureAttestationToken) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AzureAttestationToken.ProtoReflect.Descriptor instead.
 func (*AzureAttestationToken) Descriptor() ([]byte, []int) {
-	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{17}
+	return file_agent_cvms_cvms_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *AzureAttestationToken) GetFile() []byte {
@@ -1278,7 +2110,7 @@ const file_agent_cvms_cvms_proto_rawDesc = "" +
 	"\amessage\x18\x02 \x01(\tR\amessage\"J\n" +
 	"\vRunResponse\x12%\n" +
 	"\x0ecomputation_id\x18\x01 \x01(\tR\rcomputationId\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"\xde\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xee\x01\n" +
 	"\n" +
 	"AgentEvent\x12\x1d\n" +
 	"\n" +
@@ -1289,7 +2121,8 @@ const file_agent_cvms_cvms_proto_rawDesc = "" +
 	"\n" +
 	"originator\x18\x05 \x01(\tR\n" +
 	"originator\x12\x16\n" +
-	"\x06status\x18\x06 \x01(\tR\x06status\"\x9b\x01\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x0e\n" +
+	"\x02id\x18\a \x01(\tR\x02id\"\x9b\x01\n" +
 	"\bAgentLog\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12%\n" +
 	"\x0ecomputation_id\x18\x02 \x01(\tR\rcomputationId\x12\x14\n" +
@@ -1317,7 +2150,7 @@ const file_agent_cvms_cvms_proto_rawDesc = "" +
 	"\fRunReqChunks\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12\x0e\n" +
 	"\x02id\x18\x02 \x01(\tR\x02id\x12\x17\n" +
-	"\ais_last\x18\x03 \x01(\bR\x06isLast\"\xaa\x02\n" +
+	"\ais_last\x18\x03 \x01(\bR\x06isLast\"\xfd\x05\n" +
 	"\x11ComputationRunReq\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
@@ -1325,16 +2158,96 @@ const file_agent_cvms_cvms_proto_rawDesc = "" +
 	"\bdatasets\x18\x04 \x03(\v2\r.cvms.DatasetR\bdatasets\x12-\n" +
 	"\talgorithm\x18\x05 \x01(\v2\x0f.cvms.AlgorithmR\talgorithm\x12?\n" +
 	"\x10result_consumers\x18\x06 \x03(\v2\x14.cvms.ResultConsumerR\x0fresultConsumers\x124\n" +
-	"\fagent_config\x18\a \x01(\v2\x11.cvms.AgentConfigR\vagentConfig\"*\n" +
+	"\fagent_config\x18\a \x01(\v2\x11.cvms.AgentConfigR\vagentConfig\x12\x1c\n" +
+	"\tsignature\x18\b \x01(\fR\tsignature\x12!\n" +
+	"\fresult_codec\x18\t \x01(\tR\vresultCodec\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\rR\aversion\x12\x10\n" +
+	"\x03ttl\x18\v \x01(\tR\x03ttl\x12\x1f\n" +
+	"\vmax_runtime\x18\f \x01(\tR\n" +
+	"maxRuntime\x12@\n" +
+	"\x10event_encryption\x18\r \x01(\v2\x15.cvms.EventEncryptionR\x0feventEncryption\x120\n" +
+	"\n" +
+	"checkpoint\x18\x0e \x01(\v2\x10.cvms.CheckpointR\n" +
+	"checkpoint\x12%\n" +
+	"\x0edataset_naming\x18\x0f \x01(\tR\rdatasetNaming\x12L\n" +
+	"\x14attestation_approval\x18\x10 \x01(\v2\x19.cvms.AttestationApprovalR\x13attestationApproval\x12'\n" +
+	"\astorage\x18\x11 \x01(\v2\r.cvms.StorageR\astorage\x121\n" +
+	"\vresult_sink\x18\x12 \x01(\v2\x10.cvms.ResultSinkR\n" +
+	"resultSink\"\xfd\x01\n" +
+	"\n" +
+	"ResultSink\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1a\n" +
+	"\bendpoint\x18\x02 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06region\x18\x03 \x01(\tR\x06region\x12\x16\n" +
+	"\x06bucket\x18\x04 \x01(\tR\x06bucket\x12\x10\n" +
+	"\x03key\x18\x05 \x01(\tR\x03key\x12%\n" +
+	"\x0eencryption_key\x18\x06 \x01(\fR\rencryptionKey\x12*\n" +
+	"\x11access_key_secret\x18\a \x01(\tR\x0faccessKeySecret\x12*\n" +
+	"\x11secret_key_secret\x18\b \x01(\tR\x0fsecretKeySecret\"6\n" +
+	"\aStorage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x17\n" +
+	"\asize_mb\x18\x02 \x01(\x04R\x06sizeMb\"'\n" +
+	"\x13AttestationApproval\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\"G\n" +
+	"\n" +
+	"Checkpoint\x12\x1a\n" +
+	"\binterval\x18\x01 \x01(\tR\binterval\x12\x1d\n" +
+	"\n" +
+	"key_secret\x18\x03 \x01(\tR\tkeySecret\"`\n" +
+	"\x0fEventEncryption\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x16\n" +
+	"\x06fields\x18\x02 \x03(\tR\x06fields\x12#\n" +
+	"\rstream_output\x18\x03 \x01(\bR\fstreamOutput\"P\n" +
 	"\x0eResultConsumer\x12\x18\n" +
-	"\auserKey\x18\x01 \x01(\fR\auserKey\"S\n" +
+	"\auserKey\x18\x01 \x01(\fR\auserKey\x12$\n" +
+	"\rencryptionKey\x18\x02 \x01(\fR\rencryptionKey\"\x83\x01\n" +
 	"\aDataset\x12\x12\n" +
 	"\x04hash\x18\x01 \x01(\fR\x04hash\x12\x18\n" +
 	"\auserKey\x18\x02 \x01(\fR\auserKey\x12\x1a\n" +
-	"\bfilename\x18\x03 \x01(\tR\bfilename\"9\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12.\n" +
+	"\aarchive\x18\x04 \x01(\v2\x14.cvms.DatasetArchiveR\aarchive\"M\n" +
+	"\x0eDatasetArchive\x12\x1e\n" +
+	"\vmax_size_mb\x18\x01 \x01(\x04R\tmaxSizeMb\x12\x1b\n" +
+	"\tmax_files\x18\x02 \x01(\x04R\bmaxFiles\"\xa4\x03\n" +
 	"\tAlgorithm\x12\x12\n" +
 	"\x04hash\x18\x01 \x01(\fR\x04hash\x12\x18\n" +
-	"\auserKey\x18\x02 \x01(\fR\auserKey\"\xe5\x01\n" +
+	"\auserKey\x18\x02 \x01(\fR\auserKey\x12 \n" +
+	"\x05steps\x18\x03 \x03(\v2\n" +
+	".cvms.StepR\x05steps\x121\n" +
+	"\vwasm_limits\x18\x04 \x01(\v2\x10.cvms.WasmLimitsR\n" +
+	"wasmLimits\x12*\n" +
+	"\bwatchdog\x18\x05 \x01(\v2\x0e.cvms.WatchdogR\bwatchdog\x12-\n" +
+	"\tresources\x18\x06 \x01(\v2\x0f.cvms.ResourcesR\tresources\x12\x12\n" +
+	"\x04args\x18\a \x03(\tR\x04args\x12*\n" +
+	"\x03env\x18\b \x03(\v2\x18.cvms.Algorithm.EnvEntryR\x03env\x12-\n" +
+	"\x06bundle\x18\t \x01(\v2\x15.cvms.AlgorithmBundleR\x06bundle\x12\x12\n" +
+	"\x04type\x18\n" +
+	" \x01(\tR\x04type\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"n\n" +
+	"\x0fAlgorithmBundle\x12\x1e\n" +
+	"\n" +
+	"entrypoint\x18\x01 \x01(\tR\n" +
+	"entrypoint\x12\x1e\n" +
+	"\vmax_size_mb\x18\x02 \x01(\x04R\tmaxSizeMb\x12\x1b\n" +
+	"\tmax_files\x18\x03 \x01(\x04R\bmaxFiles\"Y\n" +
+	"\n" +
+	"WasmLimits\x12\"\n" +
+	"\rmax_memory_mb\x18\x01 \x01(\rR\vmaxMemoryMb\x12'\n" +
+	"\x0ftimeout_seconds\x18\x02 \x01(\rR\x0etimeoutSeconds\"U\n" +
+	"\tResources\x12\x12\n" +
+	"\x04cpus\x18\x01 \x01(\x01R\x04cpus\x12\x1b\n" +
+	"\tmemory_mb\x18\x02 \x01(\x04R\bmemoryMb\x12\x17\n" +
+	"\adisk_mb\x18\x03 \x01(\x04R\x06diskMb\"A\n" +
+	"\bWatchdog\x12!\n" +
+	"\fidle_seconds\x18\x01 \x01(\rR\vidleSeconds\x12\x12\n" +
+	"\x04kill\x18\x02 \x01(\bR\x04kill\"J\n" +
+	"\x04Step\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x02 \x03(\tR\x04args\x12\x1a\n" +
+	"\bdatasets\x18\x03 \x03(\tR\bdatasets\"\x82\x02\n" +
 	"\vAgentConfig\x12\x12\n" +
 	"\x04port\x18\x01 \x01(\tR\x04port\x12\x1b\n" +
 	"\tcert_file\x18\x02 \x01(\tR\bcertFile\x12\x19\n" +
@@ -1342,7 +2255,8 @@ const file_agent_cvms_cvms_proto_rawDesc = "" +
 	"\x0eclient_ca_file\x18\x04 \x01(\tR\fclientCaFile\x12$\n" +
 	"\x0eserver_ca_file\x18\x05 \x01(\tR\fserverCaFile\x12\x1b\n" +
 	"\tlog_level\x18\x06 \x01(\tR\blogLevel\x12!\n" +
-	"\fattested_tls\x18\a \x01(\bR\vattestedTls\"U\n" +
+	"\fattested_tls\x18\a \x01(\bR\vattestedTls\x12\x1b\n" +
+	"\thttp_port\x18\b \x01(\tR\bhttpPort\"U\n" +
 	"\x13AttestationResponse\x12\x12\n" +
 	"\x04file\x18\x01 \x01(\fR\x04file\x12*\n" +
 	"\x10certSerialNumber\x18\x02 \x01(\tR\x10certSerialNumber\"W\n" +
@@ -1364,7 +2278,7 @@ func file_agent_cvms_cvms_proto_rawDescGZIP() []byte {
 	return file_agent_cvms_cvms_proto_rawDescData
 }
 
-var file_agent_cvms_cvms_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_agent_cvms_cvms_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
 var file_agent_cvms_cvms_proto_goTypes = []any{
 	(*AgentStateReq)(nil),           // 0: cvms.AgentStateReq
 	(*AgentStateRes)(nil),           // 1: cvms.AgentStateRes
@@ -1378,40 +2292,64 @@ var file_agent_cvms_cvms_proto_goTypes = []any{
 	(*DisconnectReq)(nil),           // 9: cvms.DisconnectReq
 	(*RunReqChunks)(nil),            // 10: cvms.RunReqChunks
 	(*ComputationRunReq)(nil),       // 11: cvms.ComputationRunReq
-	(*ResultConsumer)(nil),          // 12: cvms.ResultConsumer
-	(*Dataset)(nil),                 // 13: cvms.Dataset
-	(*Algorithm)(nil),               // 14: cvms.Algorithm
-	(*AgentConfig)(nil),             // 15: cvms.AgentConfig
-	(*AttestationResponse)(nil),     // 16: cvms.AttestationResponse
-	(*AzureAttestationToken)(nil),   // 17: cvms.azureAttestationToken
-	(*timestamppb.Timestamp)(nil),   // 18: google.protobuf.Timestamp
+	(*ResultSink)(nil),              // 12: cvms.ResultSink
+	(*Storage)(nil),                 // 13: cvms.Storage
+	(*AttestationApproval)(nil),     // 14: cvms.AttestationApproval
+	(*Checkpoint)(nil),              // 15: cvms.Checkpoint
+	(*EventEncryption)(nil),         // 16: cvms.EventEncryption
+	(*ResultConsumer)(nil),          // 17: cvms.ResultConsumer
+	(*Dataset)(nil),                 // 18: cvms.Dataset
+	(*DatasetArchive)(nil),          // 19: cvms.DatasetArchive
+	(*Algorithm)(nil),               // 20: cvms.Algorithm
+	(*AlgorithmBundle)(nil),         // 21: cvms.AlgorithmBundle
+	(*WasmLimits)(nil),              // 22: cvms.WasmLimits
+	(*Resources)(nil),               // 23: cvms.Resources
+	(*Watchdog)(nil),                // 24: cvms.Watchdog
+	(*Step)(nil),                    // 25: cvms.Step
+	(*AgentConfig)(nil),             // 26: cvms.AgentConfig
+	(*AttestationResponse)(nil),     // 27: cvms.AttestationResponse
+	(*AzureAttestationToken)(nil),   // 28: cvms.azureAttestationToken
+	nil,                             // 29: cvms.Algorithm.EnvEntry
+	(*timestamppb.Timestamp)(nil),   // 30: google.protobuf.Timestamp
 }
 var file_agent_cvms_cvms_proto_depIdxs = []int32{
-	18, // 0: cvms.AgentEvent.timestamp:type_name -> google.protobuf.Timestamp
-	18, // 1: cvms.AgentLog.timestamp:type_name -> google.protobuf.Timestamp
+	30, // 0: cvms.AgentEvent.timestamp:type_name -> google.protobuf.Timestamp
+	30, // 1: cvms.AgentLog.timestamp:type_name -> google.protobuf.Timestamp
 	6,  // 2: cvms.ClientStreamMessage.agent_log:type_name -> cvms.AgentLog
 	5,  // 3: cvms.ClientStreamMessage.agent_event:type_name -> cvms.AgentEvent
 	4,  // 4: cvms.ClientStreamMessage.run_res:type_name -> cvms.RunResponse
 	3,  // 5: cvms.ClientStreamMessage.stopComputationRes:type_name -> cvms.StopComputationResponse
 	1,  // 6: cvms.ClientStreamMessage.agentStateRes:type_name -> cvms.AgentStateRes
-	16, // 7: cvms.ClientStreamMessage.vTPMattestationReport:type_name -> cvms.AttestationResponse
-	17, // 8: cvms.ClientStreamMessage.azureAttestationToken:type_name -> cvms.azureAttestationToken
+	27, // 7: cvms.ClientStreamMessage.vTPMattestationReport:type_name -> cvms.AttestationResponse
+	28, // 8: cvms.ClientStreamMessage.azureAttestationToken:type_name -> cvms.azureAttestationToken
 	10, // 9: cvms.ServerStreamMessage.runReqChunks:type_name -> cvms.RunReqChunks
 	11, // 10: cvms.ServerStreamMessage.runReq:type_name -> cvms.ComputationRunReq
 	2,  // 11: cvms.ServerStreamMessage.stopComputation:type_name -> cvms.StopComputation
 	0,  // 12: cvms.ServerStreamMessage.agentStateReq:type_name -> cvms.AgentStateReq
 	9,  // 13: cvms.ServerStreamMessage.disconnectReq:type_name -> cvms.DisconnectReq
-	13, // 14: cvms.ComputationRunReq.datasets:type_name -> cvms.Dataset
-	14, // 15: cvms.ComputationRunReq.algorithm:type_name -> cvms.Algorithm
-	12, // 16: cvms.ComputationRunReq.result_consumers:type_name -> cvms.ResultConsumer
-	15, // 17: cvms.ComputationRunReq.agent_config:type_name -> cvms.AgentConfig
-	7,  // 18: cvms.Service.Process:input_type -> cvms.ClientStreamMessage
-	8,  // 19: cvms.Service.Process:output_type -> cvms.ServerStreamMessage
-	19, // [19:20] is the sub-list for method output_type
-	18, // [18:19] is the sub-list for method input_type
-	18, // [18:18] is the sub-list for extension type_name
-	18, // [18:18] is the sub-list for extension extendee
-	0,  // [0:18] is the sub-list for field type_name
+	18, // 14: cvms.ComputationRunReq.datasets:type_name -> cvms.Dataset
+	20, // 15: cvms.ComputationRunReq.algorithm:type_name -> cvms.Algorithm
+	17, // 16: cvms.ComputationRunReq.result_consumers:type_name -> cvms.ResultConsumer
+	26, // 17: cvms.ComputationRunReq.agent_config:type_name -> cvms.AgentConfig
+	16, // 18: cvms.ComputationRunReq.event_encryption:type_name -> cvms.EventEncryption
+	15, // 19: cvms.ComputationRunReq.checkpoint:type_name -> cvms.Checkpoint
+	14, // 20: cvms.ComputationRunReq.attestation_approval:type_name -> cvms.AttestationApproval
+	13, // 21: cvms.ComputationRunReq.storage:type_name -> cvms.Storage
+	12, // 22: cvms.ComputationRunReq.result_sink:type_name -> cvms.ResultSink
+	19, // 23: cvms.Dataset.archive:type_name -> cvms.DatasetArchive
+	25, // 24: cvms.Algorithm.steps:type_name -> cvms.Step
+	22, // 25: cvms.Algorithm.wasm_limits:type_name -> cvms.WasmLimits
+	24, // 26: cvms.Algorithm.watchdog:type_name -> cvms.Watchdog
+	23, // 27: cvms.Algorithm.resources:type_name -> cvms.Resources
+	29, // 28: cvms.Algorithm.env:type_name -> cvms.Algorithm.EnvEntry
+	21, // 29: cvms.Algorithm.bundle:type_name -> cvms.AlgorithmBundle
+	7,  // 30: cvms.Service.Process:input_type -> cvms.ClientStreamMessage
+	8,  // 31: cvms.Service.Process:output_type -> cvms.ServerStreamMessage
+	31, // [31:32] is the sub-list for method output_type
+	30, // [30:31] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
 }
 
 func init() { file_agent_cvms_cvms_proto_init() }
@@ -1441,7 +2379,7 @@ func file_agent_cvms_cvms_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_cvms_cvms_proto_rawDesc), len(file_agent_cvms_cvms_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   18,
+			NumMessages:   30,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
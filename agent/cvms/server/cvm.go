@@ -5,60 +5,134 @@ package server
 
 import (
 	context "context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path"
 
 	"github.com/ultravioletrs/cocos/agent"
 	agentgrpc "github.com/ultravioletrs/cocos/agent/api/grpc"
+	agenthttp "github.com/ultravioletrs/cocos/agent/api/http"
 	"github.com/ultravioletrs/cocos/agent/auth"
+	"github.com/ultravioletrs/cocos/agent/events"
 	"github.com/ultravioletrs/cocos/pkg/atls"
 	"github.com/ultravioletrs/cocos/pkg/server"
 	grpcserver "github.com/ultravioletrs/cocos/pkg/server/grpc"
+	httpserver "github.com/ultravioletrs/cocos/pkg/server/http"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
 const (
-	svcName        = "agent"
-	defSvcGRPCPort = "7002"
+	svcName = "agent"
+	// DefaultPort is the port the agent serves computations on when the manifest sets none.
+	DefaultPort = "7002"
 )
 
+// TLSMode is the transport security the agent requires the manifests to serve computations with.
+type TLSMode string
+
+const (
+	// TLSModeManifest serves computations with the transport security their manifest configures.
+	TLSModeManifest TLSMode = "manifest"
+	// TLSModeTLS rejects manifests serving computations without TLS.
+	TLSModeTLS TLSMode = "tls"
+	// TLSModeAttested rejects manifests serving computations without attested TLS.
+	TLSModeAttested TLSMode = "attested"
+)
+
+// ErrTLSMode indicates a manifest serving the computation with a weaker transport security than the agent requires.
+var ErrTLSMode = errors.New("manifest agent configuration does not meet the agent TLS mode")
+
+// Valid reports whether the TLS mode is known, the empty mode is TLSModeManifest.
+func (m TLSMode) Valid() bool {
+	switch m {
+	case "", TLSModeManifest, TLSModeTLS, TLSModeAttested:
+		return true
+	default:
+		return false
+	}
+}
+
+// check returns an error if the manifest agent configuration serves the computation with a weaker transport security than the mode.
+func (m TLSMode) check(cfg agent.AgentConfig) error {
+	tls := cfg.AttestedTls || (cfg.CertFile != "" && cfg.KeyFile != "")
+	switch {
+	case m == TLSModeTLS && !tls:
+		return fmt.Errorf("%w: %s requires TLS", ErrTLSMode, m)
+	case m == TLSModeAttested && !cfg.AttestedTls:
+		return fmt.Errorf("%w: %s requires attested TLS", ErrTLSMode, m)
+	default:
+		return nil
+	}
+}
+
 type AgentServer interface {
 	Start(cfg agent.AgentConfig, cmp agent.Computation) error
 	Stop() error
 }
 
+// Config is the configuration computations are served with.
+type Config struct {
+	// Host is the address the computations are served on.
+	Host string
+	// Port is the port computations are served on unless their manifest sets
+	// one, DefaultPort when it is empty.
+	Port string
+	// TLSMode is the transport security the manifests must serve the
+	// computations with.
+	TLSMode TLSMode
+	// Limits bounds the gRPC messages.
+	Limits server.MessageLimits
+	// Uploads throttles the algorithm and dataset uploads.
+	Uploads server.UploadLimits
+}
+
 type agentServer struct {
 	gs           server.Server
+	hs           server.Server
 	logger       *slog.Logger
 	svc          agent.Service
-	host         string
+	cfg          Config
+	eventSvc     events.Service
 	certProvider atls.CertificateProvider
 }
 
-func NewServer(logger *slog.Logger, svc agent.Service, host string, certProvider atls.CertificateProvider) AgentServer {
+// NewServer returns the agent server serving computations with cfg, which
+// publishes the throttling of uploads over the upload limits with eventSvc.
+func NewServer(logger *slog.Logger, svc agent.Service, cfg Config, eventSvc events.Service, certProvider atls.CertificateProvider) AgentServer {
 	return &agentServer{
 		logger:       logger,
 		svc:          svc,
-		host:         host,
+		cfg:          cfg,
+		eventSvc:     eventSvc,
 		certProvider: certProvider,
 	}
 }
 
 func (as *agentServer) Start(cfg agent.AgentConfig, cmp agent.Computation) error {
+	if err := as.cfg.TLSMode.check(cfg); err != nil {
+		return err
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = as.cfg.Port
+	}
 	if cfg.Port == "" {
-		cfg.Port = defSvcGRPCPort
+		cfg.Port = DefaultPort
 	}
 
 	agentGrpcServerConfig := server.AgentConfig{
 		ServerConfig: server.ServerConfig{
 			Config: server.Config{
-				Host:         as.host,
-				Port:         cfg.Port,
-				CertFile:     cfg.CertFile,
-				KeyFile:      cfg.KeyFile,
-				ServerCAFile: cfg.ServerCAFile,
-				ClientCAFile: cfg.ClientCAFile,
+				Host:          as.cfg.Host,
+				Port:          cfg.Port,
+				CertFile:      cfg.CertFile,
+				KeyFile:       cfg.KeyFile,
+				ServerCAFile:  cfg.ServerCAFile,
+				ClientCAFile:  cfg.ClientCAFile,
+				MessageLimits: as.cfg.Limits,
 			},
 		},
 		AttestedTLS: cfg.AttestedTls,
@@ -66,7 +140,7 @@ func (as *agentServer) Start(cfg agent.AgentConfig, cmp agent.Computation) error
 
 	registerAgentServiceServer := func(srv *grpc.Server) {
 		reflection.Register(srv)
-		agent.RegisterAgentServiceServer(srv, agentgrpc.NewServer(as.svc))
+		agent.RegisterAgentServiceServer(srv, agentgrpc.NewServer(as.svc, agentgrpc.WithMessageLimits(as.cfg.Limits)))
 	}
 
 	authSvc, err := auth.New(cmp)
@@ -77,7 +151,14 @@ func (as *agentServer) Start(cfg agent.AgentConfig, cmp agent.Computation) error
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	as.gs = grpcserver.New(ctx, cancel, svcName, agentGrpcServerConfig, registerAgentServiceServer, as.logger, authSvc, as.certProvider)
+	throttle := agentgrpc.NewUploadThrottle(as.cfg.Uploads, func(method, reason string) {
+		as.uploadThrottled(cmp.ID, method, reason)
+	})
+
+	as.gs = grpcserver.New(ctx, cancel, svcName, agentGrpcServerConfig, registerAgentServiceServer, as.logger, authSvc, as.certProvider,
+		grpcserver.WithServerOptions(grpc.ChainStreamInterceptor(throttle)),
+		grpcserver.WithHealthCheck(agent.AgentService_ServiceDesc.ServiceName, agentgrpc.HealthCheck(as.svc)),
+	)
 
 	go func() {
 		err := as.gs.Start()
@@ -86,10 +167,53 @@ func (as *agentServer) Start(cfg agent.AgentConfig, cmp agent.Computation) error
 		}
 	}()
 
+	if cfg.HTTPPort != "" {
+		agentHTTPServerConfig := agentGrpcServerConfig
+		agentHTTPServerConfig.Port = cfg.HTTPPort
+
+		httpCtx, httpCancel := context.WithCancel(context.Background())
+
+		as.hs = httpserver.NewServer(httpCtx, httpCancel, svcName, agentHTTPServerConfig, agenthttp.MakeHandler(as.svc, authSvc, svcName, cmp.ID, agenthttp.WithUploadLimits(as.cfg.Uploads, func(method, reason string) {
+			as.uploadThrottled(cmp.ID, method, reason)
+		})), as.logger, as.certProvider)
+
+		go func() {
+			err := as.hs.Start()
+			if err != nil {
+				as.logger.Error(fmt.Sprintf("failed to start http server %s", err.Error()))
+			}
+		}()
+	}
+
 	return nil
 }
 
+// uploadThrottled reports an upload throttled for the reason.
+func (as *agentServer) uploadThrottled(cmpID, method, reason string) {
+	method = path.Base(method)
+	as.logger.Warn(fmt.Sprintf("%s upload throttled: %s limit reached", method, reason))
+
+	if as.eventSvc == nil {
+		return
+	}
+
+	details, err := json.Marshal(map[string]string{"method": method, "reason": reason})
+	if err != nil {
+		as.logger.Warn(fmt.Sprintf("failed to marshal upload throttling details: %s", err))
+		return
+	}
+
+	as.eventSvc.SendEvent(cmpID, events.UploadThrottled, agent.Warning.String(), details)
+}
+
 func (as *agentServer) Stop() error {
+	if as.hs != nil {
+		if err := as.hs.Stop(); err != nil {
+			return err
+		}
+		as.hs = nil
+	}
+
 	if as.gs == nil {
 		return nil
 	}
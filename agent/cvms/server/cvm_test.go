@@ -21,6 +21,7 @@ import (
 func setupTest(t *testing.T) (*slog.Logger, *mocks.Service, string, []byte) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	mockSvc := new(mocks.Service)
+	mockSvc.On("State").Return(agent.ReceivingAlgorithm.String()).Maybe()
 	host := "localhost"
 
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -70,7 +71,7 @@ func TestNewServer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewServer(tt.logger, tt.svc, tt.host, nil)
+			server := NewServer(tt.logger, tt.svc, Config{Host: tt.host}, nil, nil)
 
 			assert.NotNil(t, server)
 
@@ -78,7 +79,7 @@ func TestNewServer(t *testing.T) {
 			assert.True(t, ok)
 			assert.Equal(t, tt.logger, agentSrv.logger)
 			assert.Equal(t, tt.svc, agentSrv.svc)
-			assert.Equal(t, tt.host, agentSrv.host)
+			assert.Equal(t, tt.host, agentSrv.cfg.Host)
 		})
 	}
 }
@@ -197,7 +198,7 @@ func TestAgentServer_Start(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMocks(svc)
 
-			server := NewServer(logger, svc, host, nil)
+			server := NewServer(logger, svc, Config{Host: host}, nil, nil)
 
 			err := server.Start(tt.cfg, tt.cmp)
 
@@ -273,7 +274,7 @@ func TestAgentServer_Stop(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewServer(logger, svc, host, nil)
+			server := NewServer(logger, svc, Config{Host: host}, nil, nil)
 
 			err := tt.setupServer(server)
 			if err != nil {
@@ -301,7 +302,7 @@ func TestAgentServer_Stop(t *testing.T) {
 
 func TestAgentServer_StopMultipleTimes(t *testing.T) {
 	logger, svc, host, pubKey := setupTest(t)
-	server := NewServer(logger, svc, host, nil)
+	server := NewServer(logger, svc, Config{Host: host}, nil, nil)
 
 	// Start the server
 	cfg := agent.AgentConfig{Port: "7005"}
@@ -345,7 +346,7 @@ func TestAgentServer_StopMultipleTimes(t *testing.T) {
 
 func TestAgentServer_StartAfterStop(t *testing.T) {
 	logger, svc, host, pubKey := setupTest(t)
-	server := NewServer(logger, svc, host, nil)
+	server := NewServer(logger, svc, Config{Host: host}, nil, nil)
 
 	cfg := agent.AgentConfig{Port: "7006"}
 	cmp := agent.Computation{
@@ -498,7 +499,7 @@ func TestAgentServer_ConfigValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewServer(logger, svc, host, nil)
+			server := NewServer(logger, svc, Config{Host: host}, nil, nil)
 
 			err := server.Start(tt.config, tt.cmp)
 
@@ -526,5 +527,48 @@ func TestAgentServer_ConfigValidation(t *testing.T) {
 
 func TestConstants(t *testing.T) {
 	assert.Equal(t, "agent", svcName)
-	assert.Equal(t, "7002", defSvcGRPCPort)
+	assert.Equal(t, "7002", DefaultPort)
+}
+
+func TestTLSMode(t *testing.T) {
+	plain := agent.AgentConfig{}
+	tls := agent.AgentConfig{CertFile: "cert.pem", KeyFile: "key.pem"}
+	attested := agent.AgentConfig{AttestedTls: true}
+
+	tests := []struct {
+		name  string
+		mode  TLSMode
+		cfg   agent.AgentConfig
+		valid bool
+		err   error
+	}{
+		{name: "default mode serves plaintext", mode: "", cfg: plain, valid: true},
+		{name: "manifest mode serves plaintext", mode: TLSModeManifest, cfg: plain, valid: true},
+		{name: "tls mode rejects plaintext", mode: TLSModeTLS, cfg: plain, valid: true, err: ErrTLSMode},
+		{name: "tls mode serves tls", mode: TLSModeTLS, cfg: tls, valid: true},
+		{name: "tls mode serves attested tls", mode: TLSModeTLS, cfg: attested, valid: true},
+		{name: "attested mode rejects tls", mode: TLSModeAttested, cfg: tls, valid: true, err: ErrTLSMode},
+		{name: "attested mode serves attested tls", mode: TLSModeAttested, cfg: attested, valid: true},
+		{name: "unknown mode", mode: "mtls"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, tt.mode.Valid())
+			if !tt.valid {
+				return
+			}
+			assert.ErrorIs(t, tt.mode.check(tt.cfg), tt.err)
+		})
+	}
+}
+
+func TestAgentServer_StartTLSMode(t *testing.T) {
+	logger, svc, host, _ := setupTest(t)
+
+	server := NewServer(logger, svc, Config{Host: host, TLSMode: TLSModeAttested}, nil, nil)
+
+	err := server.Start(agent.AgentConfig{Port: "7005"}, agent.Computation{ID: "plaintext-computation"})
+	assert.ErrorIs(t, err, ErrTLSMode)
+	assert.Nil(t, server.(*agentServer).gs, "rejected computations are not served")
 }
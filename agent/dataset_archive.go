@@ -0,0 +1,68 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+// ErrInvalidArchive indicates a dataset to decompress that is not a supported
+// archive, has unsafe entries or expands past the limits of the manifest.
+var ErrInvalidArchive = errors.New("invalid dataset archive")
+
+// archiveDetails are the details of the DatasetExtracted event.
+type archiveDetails struct {
+	Dataset     string `json:"dataset"`
+	Index       int    `json:"index"`
+	Format      string `json:"format"`
+	ArchiveHash string `json:"archive_hash"`
+	ContentHash string `json:"content_hash"`
+	Files       uint64 `json:"files"`
+	Size        uint64 `json:"size"`
+}
+
+// archiveLimits returns the extraction limits the manifest sets for the dataset.
+func archiveLimits(d Dataset) internal.ArchiveLimits {
+	if d.Archive == nil {
+		return internal.ArchiveLimits{}
+	}
+
+	return internal.ArchiveLimits{MaxSize: d.Archive.MaxSizeMB << 20, MaxFiles: d.Archive.MaxFiles}
+}
+
+// verifyArchive checks that the dataset at the manifest index is an archive
+// that can be safely extracted within its limits, without writing any of it.
+func (as *agentService) verifyArchive(index int, data []byte) (internal.ArchiveInfo, error) {
+	info, err := internal.ExtractArchive(data, "", archiveLimits(as.computation.Datasets[index]))
+	if err != nil {
+		return internal.ArchiveInfo{}, errors.Wrap(ErrInvalidArchive, err)
+	}
+
+	return info, nil
+}
+
+// reportExtracted publishes the hashes of the archive and of the content it
+// was extracted to, and records the content hash in the lineage.
+func (as *agentService) reportExtracted(index int, name string, info internal.ArchiveInfo) {
+	contentHash := hex.EncodeToString(info.ContentHash[:])
+	as.lineage.datasetExtracted(index, contentHash)
+
+	details := archiveDetails{
+		Dataset:     name,
+		Index:       index,
+		Format:      info.Format,
+		ArchiveHash: hex.EncodeToString(as.computation.Datasets[index].Hash[:]),
+		ContentHash: contentHash,
+		Files:       info.Files,
+		Size:        info.Size,
+	}
+	as.logger.Info("dataset archive extracted", "computation", as.computation.ID, "dataset", name, "format", info.Format, "files", info.Files, "size", info.Size)
+
+	raw, _ := json.Marshal(details)
+	as.eventSvc.SendEvent(as.computation.ID, events.DatasetExtracted, InProgress.String(), raw)
+}
@@ -0,0 +1,159 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/grpc/metadata"
+)
+
+func tarGzip(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func TestDataArchive(t *testing.T) {
+	archive := tarGzip(t, map[string]string{"part.csv": "compressed"})
+	unsafe := tarGzip(t, map[string]string{"../escape.csv": "compressed"})
+
+	cases := []struct {
+		desc    string
+		data    []byte
+		archive *DatasetArchive
+		err     error
+	}{
+		{
+			desc:    "extract archive",
+			data:    archive,
+			archive: &DatasetArchive{MaxSizeMB: 1, MaxFiles: 1},
+		},
+		{
+			desc: "zip slip",
+			data: unsafe,
+			err:  ErrInvalidArchive,
+		},
+		{
+			desc: "not an archive",
+			data: []byte("plain"),
+			err:  ErrInvalidArchive,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			require.NoError(t, os.Mkdir(algorithm.DatasetsDir, 0o755))
+
+			hash := sha3.Sum256(tc.data)
+			cmp := Computation{
+				ID:            "1",
+				DatasetNaming: DatasetNamingManifest,
+				Datasets:      []Dataset{{Hash: hash, Filename: "data.tar.gz", Archive: tc.archive}},
+			}
+
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(ReceivingData)
+			sm.On("SendEvent", DataReceived).Return()
+
+			var details archiveDetails
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "1", events.DatasetExtracted, InProgress.String(), mock.Anything).Return().Run(func(args mock.Arguments) {
+				require.NoError(t, json.Unmarshal(args.Get(3).(json.RawMessage), &details))
+			})
+
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: cmp,
+				received:    make([]bool, 1),
+				lineage:     newLineage(cmp),
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(DecompressKey, "true"))
+			err := svc.Data(ctx, Dataset{Dataset: tc.data, Filename: "data.tar.gz"})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				assert.False(t, svc.received[0])
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				entries, err := os.ReadDir(algorithm.DatasetsDir)
+				require.NoError(t, err)
+				assert.Empty(t, entries, "rejected archives leave nothing behind")
+				assert.NoFileExists(t, "escape.csv")
+				return
+			}
+
+			part, err := os.ReadFile(filepath.Join(algorithm.DatasetsDir, "data", "part.csv"))
+			require.NoError(t, err)
+			assert.Equal(t, "compressed", string(part))
+
+			assert.Equal(t, "data.tar.gz", details.Dataset)
+			assert.Equal(t, "tar.gz", details.Format)
+			assert.Equal(t, hex.EncodeToString(hash[:]), details.ArchiveHash)
+			assert.Equal(t, uint64(1), details.Files)
+			assert.Equal(t, uint64(len("compressed")), details.Size)
+			assert.NotEmpty(t, details.ContentHash)
+			assert.Equal(t, details.ContentHash, svc.lineage.Datasets[0].ContentHash)
+		})
+	}
+}
+
+func TestDataArchiveLimit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.Mkdir(algorithm.DatasetsDir, 0o755))
+
+	data := tarGzip(t, map[string]string{"a.csv": "a", "b.csv": "b"})
+	cmp := Computation{
+		ID:       "1",
+		Datasets: []Dataset{{Hash: sha3.Sum256(data), Archive: &DatasetArchive{MaxFiles: 1}}},
+	}
+
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(ReceivingData)
+
+	svc := &agentService{
+		sm:          sm,
+		logger:      mglog.NewMock(),
+		eventSvc:    new(mocks.Service),
+		computation: cmp,
+		received:    make([]bool, 1),
+		lineage:     newLineage(cmp),
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(DecompressKey, "true"))
+	err := svc.Data(ctx, Dataset{Dataset: data, Filename: "data.tar.gz"})
+	assert.True(t, errors.Contains(err, ErrInvalidArchive), "expected %v, got %v", ErrInvalidArchive, err)
+
+	entries, err := os.ReadDir(algorithm.DatasetsDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
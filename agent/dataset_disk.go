@@ -0,0 +1,150 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const diskDatasetTmpPattern = ".disk-dataset-*"
+
+// AttachDatasetDisk registers the declared datasets found in the directory a
+// hot-added dataset disk is mounted on. Files are copied into the computation
+// while they are hashed, so the registered copy is the one verified against the
+// manifest and later changes to the disk image cannot reach the algorithm.
+// Files that do not match a pending dataset are skipped.
+func (as *agentService) AttachDatasetDisk(ctx context.Context, dir string) error {
+	if as.isShuttingDown() {
+		return ErrShuttingDown
+	}
+	if as.sm.GetState() != ReceivingData {
+		return ErrStateNotReady
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if err := as.checkApproved(); err != nil {
+		return err
+	}
+	if !slices.Contains(as.received, false) {
+		return ErrAllManifestItemsReceived
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading dataset disk: %v", err)
+	}
+
+	dst := as.sandbox.Datasets()
+	if as.datasets != nil {
+		dst = as.datasets.dir
+	}
+
+	var registered int
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("error reading dataset %s: %v", entry.Name(), err)
+		}
+		size := uint64(info.Size())
+		if err := as.reserveStorage(dst, entry.Name(), size); err != nil {
+			return err
+		}
+
+		tmp, hash, err := copyHashed(filepath.Join(dir, entry.Name()), dst)
+		if err != nil {
+			return fmt.Errorf("error copying dataset %s: %w", entry.Name(), as.storageError(entry.Name(), size, err))
+		}
+
+		index := as.pendingDataset(hash, entry.Name())
+		if index < 0 {
+			as.logger.Warn("skipping undeclared file on dataset disk", "file", entry.Name())
+			os.Remove(tmp)
+			continue
+		}
+
+		name := datasetName(as.computation, index, entry.Name())
+		if as.datasets != nil {
+			as.datasets.register(entry.Name(), name, false)
+			name = entry.Name()
+		}
+
+		if err := os.Rename(tmp, filepath.Join(dst, name)); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("error storing dataset %s: %v", entry.Name(), err)
+		}
+
+		as.received[index] = true
+		as.lineage.datasetReceived(index, DatasetAttached, hash)
+		registered++
+	}
+
+	if registered == 0 {
+		return ErrUndeclaredDataset
+	}
+	as.persist(ReceivingData)
+
+	if !slices.Contains(as.received, false) {
+		defer as.sm.SendEvent(DataReceived)
+	}
+
+	return nil
+}
+
+// pendingDataset returns the index of the undelivered manifest dataset with the
+// hash and filename, or the hash-less one with the filename, or -1 if there is none.
+func (as *agentService) pendingDataset(hash [32]byte, filename string) int {
+	for i, d := range as.computation.Datasets {
+		if as.received[i] || (hash != d.Hash && !unhashed(d)) {
+			continue
+		}
+		if d.Filename != "" && d.Filename != filename {
+			continue
+		}
+		return i
+	}
+
+	return -1
+}
+
+// copyHashed copies the file to a temporary file in dir and returns its path and the hash of the copied bytes.
+func copyHashed(src, dir string) (string, [32]byte, error) {
+	var hash [32]byte
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", hash, err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(dir, diskDatasetTmpPattern)
+	if err != nil {
+		return "", hash, err
+	}
+
+	h := sha3.New256()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", hash, err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", hash, err
+	}
+
+	copy(hash[:], h.Sum(nil))
+
+	return out.Name(), hash, nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestAttachDatasetDisk(t *testing.T) {
+	datasetA := []byte("disk dataset a")
+	datasetB := []byte("disk dataset b")
+	datasetC := []byte("upload dataset c")
+
+	cmp := Computation{
+		ID: "1",
+		Datasets: []Dataset{
+			{Hash: sha3.Sum256(datasetA), Filename: "a.csv"},
+			{Hash: sha3.Sum256(datasetB)},
+			{Hash: sha3.Sum256(datasetC), Filename: "c.csv"},
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(algorithm.DatasetsDir, 0o755))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(algorithm.DatasetsDir)
+	})
+
+	writeDisk := func(files map[string][]byte) string {
+		dir := t.TempDir()
+		for name, data := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+		}
+		return dir
+	}
+
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(ReceivingData)
+	sm.On("SendEvent", DataReceived).Return().Once()
+
+	svc := &agentService{sm: sm, logger: mglog.NewMock(), computation: cmp, received: make([]bool, len(cmp.Datasets))}
+
+	err := svc.AttachDatasetDisk(context.Background(), writeDisk(map[string][]byte{"renamed.csv": datasetA, "notes.txt": []byte("other")}))
+	assert.True(t, errors.Contains(err, ErrUndeclaredDataset), "expected %v, got %v", ErrUndeclaredDataset, err)
+
+	err = svc.AttachDatasetDisk(context.Background(), writeDisk(map[string][]byte{"a.csv": datasetA, "b.bin": datasetB, "notes.txt": []byte("other")}))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(algorithm.DatasetsDir, "a.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, datasetA, data)
+	assert.FileExists(t, filepath.Join(algorithm.DatasetsDir, "b.bin"))
+	assert.NoFileExists(t, filepath.Join(algorithm.DatasetsDir, "notes.txt"))
+
+	entries, err := os.ReadDir(algorithm.DatasetsDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	statuses := svc.Datasets()
+	assert.True(t, statuses[0].Received)
+	assert.True(t, statuses[1].Received)
+	assert.False(t, statuses[2].Received)
+	sm.AssertNotCalled(t, "SendEvent", DataReceived)
+
+	err = svc.AttachDatasetDisk(context.Background(), t.TempDir()+"/missing")
+	assert.Error(t, err)
+
+	err = svc.Data(context.Background(), Dataset{Dataset: datasetC, Filename: "c.csv"})
+	require.NoError(t, err)
+	sm.AssertCalled(t, "SendEvent", DataReceived)
+
+	err = svc.AttachDatasetDisk(context.Background(), writeDisk(map[string][]byte{"c.csv": datasetC}))
+	assert.True(t, errors.Contains(err, ErrAllManifestItemsReceived), "expected %v, got %v", ErrAllManifestItemsReceived, err)
+}
+
+func TestAttachDatasetDiskState(t *testing.T) {
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(Running)
+
+	svc := &agentService{sm: sm}
+
+	err := svc.AttachDatasetDisk(context.Background(), t.TempDir())
+	assert.True(t, errors.Contains(err, ErrStateNotReady), "expected %v, got %v", ErrStateNotReady, err)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+// ErrMissingDatasetHash indicates a manifest dataset without a SHA3-256 hash,
+// which the agent only accepts in hash-less development mode.
+var ErrMissingDatasetHash = errors.New("dataset hash missing from computation manifest")
+
+// validateDatasetHashes checks that every manifest dataset declares its hash,
+// or, when hash-less datasets are allowed, its filename, which the uploads are
+// matched by instead.
+func validateDatasetHashes(cmp Computation, allowUnhashed bool) error {
+	for i, d := range cmp.Datasets {
+		if !unhashed(d) {
+			continue
+		}
+		if !allowUnhashed {
+			return errors.Wrap(ErrMissingDatasetHash, fmt.Errorf("dataset %d", i))
+		}
+		if d.Filename == "" {
+			return errors.Wrap(ErrMissingDatasetHash, fmt.Errorf("dataset %d declares neither a hash nor a filename", i))
+		}
+	}
+
+	return nil
+}
+
+// unhashed reports whether the manifest dataset declares no hash.
+func unhashed(d Dataset) bool {
+	return d.Hash == [32]byte{}
+}
+
+// matchDataset returns the index of the manifest dataset an upload with the
+// hash and filename delivers. Uploads are matched by hash first, then by the
+// filename of a hash-less dataset. An upload named after a pending dataset with
+// another hash is rejected with ErrHashMismatch. It must be called with the
+// service mutex held.
+func (as *agentService) matchDataset(hash [32]byte, filename string) (int, error) {
+	index := -1
+	for i, d := range as.computation.Datasets {
+		if hash != d.Hash {
+			continue
+		}
+		index = i
+		if !as.received[i] {
+			break
+		}
+	}
+
+	if index >= 0 {
+		if as.received[index] {
+			return -1, ErrDatasetReceived
+		}
+		return index, nil
+	}
+
+	for i, d := range as.computation.Datasets {
+		if as.received[i] || filename == "" || d.Filename != filename {
+			continue
+		}
+		if unhashed(d) {
+			return i, nil
+		}
+
+		return -1, errors.Wrap(ErrHashMismatch, fmt.Errorf("dataset %s: manifest sha3-256 %x, received %x", filename, d.Hash, hash))
+	}
+
+	return -1, ErrUndeclaredDataset
+}
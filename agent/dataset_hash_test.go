@@ -0,0 +1,95 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestValidateDatasetHashes(t *testing.T) {
+	cases := []struct {
+		desc          string
+		datasets      Datasets
+		allowUnhashed bool
+		err           error
+	}{
+		{
+			desc:     "hashed datasets",
+			datasets: Datasets{{Hash: [32]byte{1}, Filename: "a.csv"}, {Hash: [32]byte{2}}},
+		},
+		{
+			desc:     "hash-less dataset",
+			datasets: Datasets{{Hash: [32]byte{1}}, {Filename: "b.csv"}},
+			err:      ErrMissingDatasetHash,
+		},
+		{
+			desc:          "hash-less dataset in development mode",
+			datasets:      Datasets{{Hash: [32]byte{1}}, {Filename: "b.csv"}},
+			allowUnhashed: true,
+		},
+		{
+			desc:          "hash-less dataset without a filename",
+			datasets:      Datasets{{}},
+			allowUnhashed: true,
+			err:           ErrMissingDatasetHash,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateDatasetHashes(Computation{Datasets: tc.datasets}, tc.allowUnhashed)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v got %v", tc.err, err)
+		})
+	}
+}
+
+func TestDataUnhashed(t *testing.T) {
+	hashed := []byte("hashed dataset")
+	unhashedData := []byte("hash-less dataset")
+
+	cmp := Computation{
+		ID: "1",
+		Datasets: []Dataset{
+			{Hash: sha3.Sum256(hashed), Filename: "a.csv"},
+			{Filename: "b.csv"},
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(algorithm.DatasetsDir, 0o755))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(algorithm.DatasetsDir)
+	})
+
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(ReceivingData)
+	sm.On("SendEvent", DataReceived).Return().Once()
+
+	svc := &agentService{sm: sm, computation: cmp, received: make([]bool, len(cmp.Datasets)), lineage: newLineage(cmp)}
+
+	err := svc.Data(context.Background(), Dataset{Dataset: unhashedData, Filename: "a.csv"})
+	assert.True(t, errors.Contains(err, ErrHashMismatch), "expected %v, got %v", ErrHashMismatch, err)
+
+	err = svc.Data(context.Background(), Dataset{Dataset: unhashedData, Filename: "c.csv"})
+	assert.True(t, errors.Contains(err, ErrUndeclaredDataset), "expected %v, got %v", ErrUndeclaredDataset, err)
+
+	err = svc.Data(context.Background(), Dataset{Dataset: unhashedData, Filename: "b.csv"})
+	require.NoError(t, err)
+	sm.AssertNotCalled(t, "SendEvent", DataReceived)
+
+	digest := sha3.Sum256(unhashedData)
+	assert.Equal(t, hex.EncodeToString(digest[:]), svc.lineage.Datasets[1].Hash, "the lineage records the received hash")
+
+	err = svc.Data(context.Background(), Dataset{Dataset: hashed, Filename: "a.csv"})
+	require.NoError(t, err)
+	sm.AssertCalled(t, "SendEvent", DataReceived)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+// Dataset naming contracts of the manifest, the names datasets appear under in
+// the datasets directory of the algorithm.
+const (
+	// DatasetNamingUpload keeps the filename a dataset was uploaded with, the
+	// manifest filename when it declares one. Compressed datasets are extracted
+	// into the datasets directory. It is the default.
+	DatasetNamingUpload = "upload"
+	// DatasetNamingManifest names every dataset after its manifest filename,
+	// dataset-<index> when it declares none. Compressed datasets are extracted
+	// into a directory named after the dataset without its extension.
+	DatasetNamingManifest = "manifest"
+	// DatasetNamingOrdered names datasets as DatasetNamingManifest, prefixed with
+	// their zero padded manifest index, so that sorting the datasets directory
+	// lists them in manifest order.
+	DatasetNamingOrdered = "ordered"
+)
+
+// ErrInvalidDatasetNaming indicates an unknown dataset naming contract, or dataset filenames that do not satisfy it.
+var ErrInvalidDatasetNaming = errors.New("invalid dataset naming")
+
+// validateDatasetNaming checks the manifest dataset naming contract, that
+// dataset filenames cannot escape the datasets directory and, when the manifest
+// names the datasets, that no two datasets share a name.
+func validateDatasetNaming(cmp Computation) error {
+	switch cmp.DatasetNaming {
+	case "", DatasetNamingUpload, DatasetNamingManifest, DatasetNamingOrdered:
+	default:
+		return errors.Wrap(ErrInvalidDatasetNaming, fmt.Errorf("unknown naming %q", cmp.DatasetNaming))
+	}
+
+	names := make(map[string]int, len(cmp.Datasets))
+	for i, d := range cmp.Datasets {
+		if d.Filename != "" && !validDatasetFilename(d.Filename) {
+			return errors.Wrap(ErrInvalidDatasetNaming, fmt.Errorf("dataset %d: filename %q is not a base name", i, d.Filename))
+		}
+		if !manifestNamed(cmp) {
+			continue
+		}
+
+		name := datasetName(cmp, i, d.Filename)
+		if j, ok := names[name]; ok {
+			return errors.Wrap(ErrInvalidDatasetNaming, fmt.Errorf("datasets %d and %d are both named %s", j, i, name))
+		}
+		names[name] = i
+	}
+
+	return nil
+}
+
+// validDatasetFilename reports whether the filename names a file directly in the datasets directory.
+func validDatasetFilename(filename string) bool {
+	return filename != "." && filename != ".." && filepath.Base(filename) == filename && !strings.ContainsRune(filename, '\\')
+}
+
+// manifestNamed reports whether the manifest, rather than the uploads, names the datasets.
+func manifestNamed(cmp Computation) bool {
+	return cmp.DatasetNaming == DatasetNamingManifest || cmp.DatasetNaming == DatasetNamingOrdered
+}
+
+// datasetName returns the name the manifest dataset at index appears under in
+// the datasets directory, given the filename it was delivered with.
+func datasetName(cmp Computation, index int, filename string) string {
+	name := cmp.Datasets[index].Filename
+
+	switch cmp.DatasetNaming {
+	case DatasetNamingManifest, DatasetNamingOrdered:
+		if name == "" {
+			name = fmt.Sprintf("dataset-%d", index)
+		}
+	default:
+		if name == "" {
+			name = filename
+		}
+		return filepath.Base(name)
+	}
+
+	if cmp.DatasetNaming == DatasetNamingOrdered {
+		width := len(strconv.Itoa(len(cmp.Datasets) - 1))
+		name = fmt.Sprintf("%0*d-%s", width, index, name)
+	}
+
+	return name
+}
+
+// writeDataset places the dataset in the datasets directory dir under name, or
+// extracts it when it is an archive to decompress, into a directory named after
+// the dataset without its archive extension if nested. Archives are checked
+// against their manifest limits with verifyArchive when they are received.
+func writeDataset(dir, name string, data []byte, decompress, nested bool) error {
+	if !decompress {
+		return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+	}
+
+	if nested {
+		dir = filepath.Join(dir, internal.ArchiveName(name))
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	_, err := internal.ExtractArchive(data, dir, internal.ArchiveLimits{})
+
+	return err
+}
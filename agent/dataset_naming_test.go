@@ -0,0 +1,210 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValidateDatasetNaming(t *testing.T) {
+	cases := []struct {
+		desc     string
+		naming   string
+		datasets []Dataset
+		err      error
+	}{
+		{
+			desc:     "default naming",
+			datasets: []Dataset{{Filename: "a.csv"}, {}},
+		},
+		{
+			desc:     "ordered naming",
+			naming:   DatasetNamingOrdered,
+			datasets: []Dataset{{Filename: "a.csv"}, {Filename: "a.csv"}},
+		},
+		{
+			desc:   "unknown naming",
+			naming: "random",
+			err:    ErrInvalidDatasetNaming,
+		},
+		{
+			desc:     "filename escaping the datasets directory",
+			datasets: []Dataset{{Filename: "../a.csv"}},
+			err:      ErrInvalidDatasetNaming,
+		},
+		{
+			desc:     "filename in a subdirectory",
+			naming:   DatasetNamingManifest,
+			datasets: []Dataset{{Filename: "dir/a.csv"}},
+			err:      ErrInvalidDatasetNaming,
+		},
+		{
+			desc:     "duplicate manifest names",
+			naming:   DatasetNamingManifest,
+			datasets: []Dataset{{Filename: "a.csv"}, {Filename: "a.csv"}},
+			err:      ErrInvalidDatasetNaming,
+		},
+		{
+			desc:     "filename colliding with a generated name",
+			naming:   DatasetNamingManifest,
+			datasets: []Dataset{{}, {Filename: "dataset-0"}},
+			err:      ErrInvalidDatasetNaming,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateDatasetNaming(Computation{DatasetNaming: tc.naming, Datasets: tc.datasets})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestDatasetName(t *testing.T) {
+	datasets := make([]Dataset, 11)
+	datasets[3].Filename = "b.csv"
+
+	cases := []struct {
+		desc     string
+		naming   string
+		index    int
+		filename string
+		name     string
+	}{
+		{
+			desc:     "upload naming of a named dataset",
+			index:    3,
+			filename: "b.csv",
+			name:     "b.csv",
+		},
+		{
+			desc:     "upload naming of an unnamed dataset",
+			naming:   DatasetNamingUpload,
+			index:    4,
+			filename: "../upload.csv",
+			name:     "upload.csv",
+		},
+		{
+			desc:     "manifest naming of a named dataset",
+			naming:   DatasetNamingManifest,
+			index:    3,
+			filename: "b.csv",
+			name:     "b.csv",
+		},
+		{
+			desc:     "manifest naming of an unnamed dataset",
+			naming:   DatasetNamingManifest,
+			index:    4,
+			filename: "upload.csv",
+			name:     "dataset-4",
+		},
+		{
+			desc:     "ordered naming of a named dataset",
+			naming:   DatasetNamingOrdered,
+			index:    3,
+			filename: "b.csv",
+			name:     "03-b.csv",
+		},
+		{
+			desc:     "ordered naming of an unnamed dataset",
+			naming:   DatasetNamingOrdered,
+			index:    10,
+			filename: "upload.csv",
+			name:     "10-dataset-10",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmp := Computation{DatasetNaming: tc.naming, Datasets: datasets}
+			assert.Equal(t, tc.name, datasetName(cmp, tc.index, tc.filename))
+		})
+	}
+}
+
+func TestDataOrderedNaming(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.Mkdir(algorithm.DatasetsDir, 0o755))
+
+	archive := new(bytes.Buffer)
+	zw := zip.NewWriter(archive)
+	w, err := zw.Create("part.csv")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("compressed"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	uploads := []struct {
+		data       []byte
+		filename   string
+		decompress bool
+	}{
+		{data: []byte("third"), filename: "upload.csv"},
+		{data: archive.Bytes(), filename: "b.zip", decompress: true},
+		{data: []byte("first"), filename: "a.csv"},
+	}
+
+	cmp := Computation{
+		ID:            "1",
+		DatasetNaming: DatasetNamingOrdered,
+		Datasets: []Dataset{
+			{Hash: sha3.Sum256([]byte("first")), Filename: "a.csv"},
+			{Hash: sha3.Sum256(archive.Bytes()), Filename: "b.zip"},
+			{Hash: sha3.Sum256([]byte("third"))},
+		},
+	}
+
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(ReceivingData)
+	sm.On("SendEvent", DataReceived).Return()
+
+	eventSvc := new(mocks.Service)
+	eventSvc.On("SendEvent", "1", events.DatasetExtracted, InProgress.String(), mock.Anything).Return()
+
+	svc := &agentService{
+		sm:          sm,
+		logger:      mglog.NewMock(),
+		eventSvc:    eventSvc,
+		computation: cmp,
+		received:    make([]bool, len(cmp.Datasets)),
+		lineage:     newLineage(cmp),
+	}
+
+	for _, u := range uploads {
+		ctx := context.Background()
+		if u.decompress {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(DecompressKey, "true"))
+		}
+		require.NoError(t, svc.Data(ctx, Dataset{Dataset: u.data, Filename: u.filename}))
+	}
+	sm.AssertCalled(t, "SendEvent", mock.Anything)
+
+	entries, err := os.ReadDir(algorithm.DatasetsDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"0-a.csv", "1-b", "2-dataset-2"}, names)
+
+	part, err := os.ReadFile(filepath.Join(algorithm.DatasetsDir, "1-b", "part.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "compressed", string(part))
+}
@@ -0,0 +1,6 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package datasetdisk detects dataset disks hot-added to the CVM by the manager,
+// mounts them read-only and registers the datasets they hold with the agent.
+package datasetdisk
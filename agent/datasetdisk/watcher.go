@@ -0,0 +1,134 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package datasetdisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+const (
+	// SerialPrefix is the serial prefix of the virtio disks the manager hot-adds for datasets.
+	SerialPrefix = "cocos-dataset-"
+
+	defaultInterval = 2 * time.Second
+	mountFlags      = syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC
+)
+
+// filesystems are tried in order when mounting a dataset disk.
+var filesystems = []string{"ext4", "xfs", "iso9660", "vfat"}
+
+// Watcher polls the block devices of the CVM for new dataset disks.
+type Watcher struct {
+	svc      agent.Service
+	logger   *slog.Logger
+	interval time.Duration
+	sysBlock string
+	devDir   string
+	mountDir string
+	mount    func(source, target string) error
+	unmount  func(target string) error
+	seen     map[string]bool
+}
+
+// NewWatcher returns a watcher that mounts dataset disks under mountDir.
+func NewWatcher(svc agent.Service, logger *slog.Logger, mountDir string) *Watcher {
+	return &Watcher{
+		svc:      svc,
+		logger:   logger,
+		interval: defaultInterval,
+		sysBlock: "/sys/block",
+		devDir:   "/dev",
+		mountDir: mountDir,
+		mount:    mountReadOnly,
+		unmount:  func(target string) error { return syscall.Unmount(target, 0) },
+		seen:     make(map[string]bool),
+	}
+}
+
+// Run scans for dataset disks until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan registers the dataset disks that appeared since the last scan.
+// A disk is handled once, failures are logged and the disk can be re-attached under a new serial.
+func (w *Watcher) scan(ctx context.Context) {
+	devices, err := os.ReadDir(w.sysBlock)
+	if err != nil {
+		w.logger.Warn("failed to list block devices", "error", err)
+		return
+	}
+
+	for _, dev := range devices {
+		serial, err := os.ReadFile(filepath.Join(w.sysBlock, dev.Name(), "serial"))
+		if err != nil {
+			continue
+		}
+
+		id := strings.TrimSpace(string(serial))
+		if !strings.HasPrefix(id, SerialPrefix) || w.seen[id] {
+			continue
+		}
+		w.seen[id] = true
+
+		if err := w.attach(ctx, dev.Name(), id); err != nil {
+			w.logger.Error("failed to attach dataset disk", "device", dev.Name(), "serial", id, "error", err)
+			continue
+		}
+		w.logger.Info("attached dataset disk", "device", dev.Name(), "serial", id)
+	}
+}
+
+func (w *Watcher) attach(ctx context.Context, dev, serial string) error {
+	target := filepath.Join(w.mountDir, serial)
+	if err := os.MkdirAll(target, 0o700); err != nil {
+		return err
+	}
+	defer os.Remove(target)
+
+	if err := w.mount(filepath.Join(w.devDir, dev), target); err != nil {
+		return err
+	}
+	// Datasets are copied into the computation, the disk is not needed once they are registered.
+	defer func() {
+		if err := w.unmount(target); err != nil {
+			w.logger.Warn("failed to unmount dataset disk", "target", target, "error", err)
+		}
+	}()
+
+	return w.svc.AttachDatasetDisk(ctx, target)
+}
+
+func mountReadOnly(source, target string) error {
+	var errs []error
+	for _, fs := range filesystems {
+		err := syscall.Mount(source, target, fs, mountFlags, "")
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", fs, err))
+	}
+
+	return fmt.Errorf("failed to mount %s: %w", source, errors.Join(errs...))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package datasetdisk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/mocks"
+)
+
+func TestScan(t *testing.T) {
+	sysBlock := t.TempDir()
+	mountDir := t.TempDir()
+
+	addDisk := func(dev, serial string) {
+		dir := filepath.Join(sysBlock, dev)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		if serial != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "serial"), []byte(serial+"\n"), 0o444))
+		}
+	}
+
+	addDisk("vda", "rootfs")
+	addDisk("vdb", "cocos-dataset-0")
+	addDisk("loop0", "")
+
+	svc := new(mocks.Service)
+	svc.On("AttachDatasetDisk", mock.Anything, filepath.Join(mountDir, "cocos-dataset-0")).Return(nil).Once()
+	svc.On("AttachDatasetDisk", mock.Anything, filepath.Join(mountDir, "cocos-dataset-1")).Return(assert.AnError).Once()
+
+	var mounted, unmounted []string
+	w := NewWatcher(svc, mglog.NewMock(), mountDir)
+	w.sysBlock = sysBlock
+	w.mount = func(source, target string) error {
+		mounted = append(mounted, source)
+		return nil
+	}
+	w.unmount = func(target string) error {
+		unmounted = append(unmounted, target)
+		return nil
+	}
+
+	w.scan(context.Background())
+	assert.Equal(t, []string{"/dev/vdb"}, mounted)
+	assert.Equal(t, []string{filepath.Join(mountDir, "cocos-dataset-0")}, unmounted)
+	assert.NoDirExists(t, filepath.Join(mountDir, "cocos-dataset-0"))
+
+	// Disks are attached once, new disks are picked up by later scans and failures are not retried.
+	addDisk("vdc", "cocos-dataset-1")
+	w.scan(context.Background())
+	w.scan(context.Background())
+	assert.Equal(t, []string{"/dev/vdb", "/dev/vdc"}, mounted)
+	assert.Len(t, unmounted, 2)
+
+	// A disk that cannot be mounted is not registered.
+	addDisk("vdd", "cocos-dataset-2")
+	w.mount = func(source, target string) error { return assert.AnError }
+	w.scan(context.Background())
+
+	svc.AssertExpectations(t)
+}
+
+func TestRun(t *testing.T) {
+	w := NewWatcher(new(mocks.Service), mglog.NewMock(), t.TempDir())
+	w.sysBlock = filepath.Join(t.TempDir(), "missing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, w.Run(ctx))
+}
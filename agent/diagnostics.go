@@ -0,0 +1,262 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/statemachine"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// diagnosticsTransitions, diagnosticsEvents and diagnosticsLogs bound the
+	// state transitions, events and log records a diagnostic snapshot holds.
+	diagnosticsTransitions = 32
+	diagnosticsEvents      = 64
+	diagnosticsLogs        = 128
+	// diagnosticsLogLength is the length log records are truncated to.
+	diagnosticsLogLength = 512
+	// diagnosticsTimeout bounds the delivery of a diagnostic snapshot to the manager.
+	diagnosticsTimeout = 10 * time.Second
+)
+
+// DiagnosticsSender delivers a diagnostic snapshot to the manager.
+type DiagnosticsSender func(ctx context.Context, snapshot []byte) error
+
+// Diagnostics is the snapshot the agent captures when a computation run fails,
+// for operators to investigate the failure. It is redacted: it never holds the
+// algorithm, datasets or results, the details of events nor the algorithm output.
+type Diagnostics struct {
+	ComputationID string                 `json:"computation_id"`
+	CapturedAt    time.Time              `json:"captured_at"`
+	State         string                 `json:"state"`
+	Error         string                 `json:"error,omitempty"`
+	Transitions   []DiagnosticTransition `json:"transitions"`
+	Events        []DiagnosticEvent      `json:"events"`
+	Resources     DiagnosticResources    `json:"resources"`
+	Logs          []DiagnosticLog        `json:"logs"`
+}
+
+// DiagnosticTransition is a transition of the agent state machine.
+type DiagnosticTransition struct {
+	Time  time.Time `json:"time"`
+	From  string    `json:"from"`
+	Event string    `json:"event"`
+	To    string    `json:"to"`
+}
+
+// DiagnosticEvent is an event the agent sent, without its details.
+type DiagnosticEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Status string    `json:"status"`
+}
+
+// DiagnosticResources are the resources used when the snapshot was captured.
+type DiagnosticResources struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapBytes    uint64 `json:"heap_bytes"`
+	ResultsBytes uint64 `json:"results_bytes"`
+	OOMKills     uint64 `json:"oom_kills,omitempty"`
+}
+
+// DiagnosticLog is an agent log record, truncated to diagnosticsLogLength.
+type DiagnosticLog struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// diagnostics records the recent history of the agent diagnostic snapshots are captured from.
+type diagnostics struct {
+	mu          sync.Mutex
+	transitions []DiagnosticTransition
+	events      []DiagnosticEvent
+	logs        []DiagnosticLog
+}
+
+func (d *diagnostics) transition(t statemachine.Transition) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.transitions = appendBounded(d.transitions, DiagnosticTransition{
+		Time:  time.Now(),
+		From:  t.From.String(),
+		Event: t.Event.String(),
+		To:    t.To.String(),
+	}, diagnosticsTransitions)
+}
+
+func (d *diagnostics) event(event, status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.events = appendBounded(d.events, DiagnosticEvent{Time: time.Now(), Event: event, Status: status}, diagnosticsEvents)
+}
+
+func (d *diagnostics) log(r slog.Record, attrs []slog.Attr) {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, " %s", attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		fmt.Fprintf(&b, " %s", attr)
+		return b.Len() < diagnosticsLogLength
+	})
+
+	message := b.String()
+	if len(message) > diagnosticsLogLength {
+		message = strings.ToValidUTF8(message[:diagnosticsLogLength], "")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.logs = appendBounded(d.logs, DiagnosticLog{Time: r.Time, Level: r.Level.String(), Message: message}, diagnosticsLogs)
+}
+
+// snapshot returns the recorded history of the computation, in its current state and failed with err.
+func (d *diagnostics) snapshot(cmpID, state string, err error) Diagnostics {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := Diagnostics{
+		ComputationID: cmpID,
+		CapturedAt:    time.Now(),
+		State:         state,
+		Transitions:   append([]DiagnosticTransition{}, d.transitions...),
+		Events:        append([]DiagnosticEvent{}, d.events...),
+		Logs:          append([]DiagnosticLog{}, d.logs...),
+	}
+	if err != nil {
+		snapshot.Error = err.Error()
+	}
+
+	return snapshot
+}
+
+// appendBounded appends v to s and drops the oldest elements beyond n.
+func appendBounded[T any](s []T, v T, n int) []T {
+	s = append(s, v)
+	if len(s) > n {
+		s = s[len(s)-n:]
+	}
+
+	return s
+}
+
+var _ events.Service = (*diagnosticEvents)(nil)
+
+// diagnosticEvents records the type and status of the events sent through svc.
+type diagnosticEvents struct {
+	svc events.Service
+	d   *diagnostics
+}
+
+func (de *diagnosticEvents) SendEvent(cmpID, event, status string, details json.RawMessage) {
+	de.d.event(event, status)
+	de.svc.SendEvent(cmpID, event, status, details)
+}
+
+var _ slog.Handler = (*diagnosticsHandler)(nil)
+
+// diagnosticsHandler records the agent log records handled by the wrapped handler.
+type diagnosticsHandler struct {
+	slog.Handler
+	d     *diagnostics
+	attrs []slog.Attr
+}
+
+func (h *diagnosticsHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.d.log(r, h.attrs)
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *diagnosticsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &diagnosticsHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		d:       h.d,
+		attrs:   append(append([]slog.Attr{}, h.attrs...), attrs...),
+	}
+}
+
+func (h *diagnosticsHandler) WithGroup(name string) slog.Handler {
+	return &diagnosticsHandler{Handler: h.Handler.WithGroup(name), d: h.d, attrs: h.attrs}
+}
+
+// algorithmLogger returns the logger of the algorithm output, which is kept
+// out of the logs and diagnostic snapshots since it may reveal the datasets.
+func (as *agentService) algorithmLogger() *slog.Logger {
+	h := as.logger.Handler()
+	if d, ok := h.(*diagnosticsHandler); ok {
+		h = d.Handler
+	}
+
+	return slog.New(logging.WithoutOutput(h))
+}
+
+// reportDiagnostics captures a diagnostic snapshot of the failed run and sends
+// it to the manager in the background, giving up after diagnosticsTimeout.
+// The snapshot carries the trace of the run ctx holds.
+func (as *agentService) reportDiagnostics(ctx context.Context) {
+	if as.diagnostics == nil || as.sendDiagnostics == nil {
+		return
+	}
+
+	snapshot := as.diagnostics.snapshot(as.computation.ID, as.sm.GetState().String(), as.runError)
+	snapshot.Resources = as.resourceUsage()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		as.logger.Warn(fmt.Sprintf("failed to marshal diagnostic snapshot: %s", err.Error()))
+		return
+	}
+
+	send := as.sendDiagnostics
+	sc := trace.SpanContextFromContext(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(trace.ContextWithSpanContext(context.Background(), sc), diagnosticsTimeout)
+		defer cancel()
+
+		if err := send(ctx, data); err != nil {
+			as.logger.Warn(fmt.Sprintf("failed to send diagnostic snapshot: %s", err.Error()))
+		}
+	}()
+}
+
+// resourceUsage returns the resources used by the agent and the algorithm.
+func (as *agentService) resourceUsage() DiagnosticResources {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	usage := DiagnosticResources{
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  mem.HeapAlloc,
+	}
+	if size, err := dirSize(as.sandbox.Results()); err == nil {
+		usage.ResultsBytes = size
+	}
+	if as.cgroup != nil {
+		if kills, err := as.cgroup.OOMKills(); err == nil {
+			usage.OOMKills = kills
+		}
+	}
+
+	return usage
+}
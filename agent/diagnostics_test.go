@@ -0,0 +1,114 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	"github.com/ultravioletrs/cocos/agent/statemachine"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDiagnosticsSnapshot(t *testing.T) {
+	d := &diagnostics{}
+
+	for range diagnosticsTransitions + 1 {
+		d.transition(statemachine.Transition{From: ReceivingAlgorithm, Event: AlgorithmReceived, To: Running})
+	}
+	d.transition(statemachine.Transition{From: Running, Event: RunFailed, To: Failed})
+
+	logger := slog.New(&diagnosticsHandler{Handler: slog.NewTextHandler(io.Discard, nil), d: d}).With("computation", "1")
+	logger.Warn("failed to run computation", "error", "exit status 1")
+	logger.Info(strings.Repeat("a", 2*diagnosticsLogLength))
+
+	snapshot := d.snapshot("1", Failed.String(), errors.New("exit status 1"))
+
+	assert.Equal(t, "1", snapshot.ComputationID)
+	assert.Equal(t, "Failed", snapshot.State)
+	assert.Equal(t, "exit status 1", snapshot.Error)
+
+	require.Len(t, snapshot.Transitions, diagnosticsTransitions)
+	last := snapshot.Transitions[len(snapshot.Transitions)-1]
+	assert.Equal(t, DiagnosticTransition{Time: last.Time, From: "Running", Event: "RunFailed", To: "Failed"}, last)
+
+	require.Len(t, snapshot.Logs, 2)
+	assert.Equal(t, "WARN", snapshot.Logs[0].Level)
+	assert.Equal(t, "failed to run computation computation=1 error=exit status 1", snapshot.Logs[0].Message)
+	assert.Len(t, snapshot.Logs[1].Message, diagnosticsLogLength)
+}
+
+func TestReportDiagnostics(t *testing.T) {
+	cases := []struct {
+		desc    string
+		sendErr error
+	}{
+		{
+			desc: "snapshot delivered",
+		},
+		{
+			desc:    "snapshot not delivered",
+			sendErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			evts := new(mocks.Service)
+			evts.On("SendEvent", "1", "AlgorithmRun", "Warning", json.RawMessage(`{"output":"secret row"}`)).Return()
+
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(Running)
+
+			sent := make(chan []byte, 1)
+			traced := make(chan trace.SpanContext, 1)
+			runCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{2},
+				TraceFlags: trace.FlagsSampled,
+			}))
+			d := &diagnostics{}
+			svc := &agentService{
+				sm:          sm,
+				computation: Computation{ID: "1"},
+				eventSvc:    &diagnosticEvents{svc: evts, d: d},
+				logger:      slog.New(&diagnosticsHandler{Handler: slog.NewTextHandler(io.Discard, nil), d: d}),
+				runError:    errors.New("exit status 1"),
+				diagnostics: d,
+				sendDiagnostics: func(ctx context.Context, snapshot []byte) error {
+					traced <- trace.SpanContextFromContext(ctx)
+					sent <- snapshot
+					return tc.sendErr
+				},
+			}
+
+			// The algorithm output and event details may reveal the datasets.
+			svc.algorithmLogger().Error("secret row")
+			svc.eventSvc.SendEvent("1", "AlgorithmRun", "Warning", json.RawMessage(`{"output":"secret row"}`))
+
+			svc.reportDiagnostics(runCtx)
+
+			data := <-sent
+			assert.Equal(t, trace.SpanContextFromContext(runCtx), <-traced, "snapshots carry the trace of the run")
+			assert.NotContains(t, string(data), "secret row")
+
+			var snapshot Diagnostics
+			require.NoError(t, json.Unmarshal(data, &snapshot))
+			assert.Equal(t, "1", snapshot.ComputationID)
+			assert.Equal(t, "Running", snapshot.State)
+			assert.Equal(t, "exit status 1", snapshot.Error)
+			require.Len(t, snapshot.Events, 1)
+			assert.Equal(t, DiagnosticEvent{Time: snapshot.Events[0].Time, Event: "AlgorithmRun", Status: "Warning"}, snapshot.Events[0])
+			assert.Positive(t, snapshot.Resources.Goroutines)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"crypto/ecdh"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+)
+
+const (
+	// EncryptedFieldsKey lists the encrypted fields of event details.
+	EncryptedFieldsKey = "encrypted_fields"
+	// detailsField holds the encrypted details that are not a JSON object.
+	detailsField = "details"
+)
+
+var errEncryptedFields = errors.New("invalid encrypted event detail fields")
+
+var _ Service = (*encryptedService)(nil)
+
+type encryptedService struct {
+	svc    Service
+	key    *ecdh.PublicKey
+	fields []string
+}
+
+// NewEncrypted returns a service that encrypts the detail fields of events with
+// the X25519 public key before sending them with svc, every field if fields is
+// empty. The event type, status and computation ID are kept in the clear for routing.
+func NewEncrypted(svc Service, key *ecdh.PublicKey, fields []string) Service {
+	return &encryptedService{
+		svc:    svc,
+		key:    key,
+		fields: fields,
+	}
+}
+
+func (s *encryptedService) SendEvent(cmpID, event, status string, details json.RawMessage) {
+	encrypted, err := EncryptDetails(s.key, s.fields, details)
+	if err != nil {
+		// Details that cannot be encrypted never leave the agent.
+		encrypted = json.RawMessage{}
+	}
+
+	s.svc.SendEvent(cmpID, event, status, encrypted)
+}
+
+// EncryptDetails replaces the detail fields with their ciphertexts and lists
+// them under EncryptedFieldsKey. Details that are not a JSON object are
+// encrypted as a whole under the details field unless fields are listed.
+func EncryptDetails(key *ecdh.PublicKey, fields []string, details json.RawMessage) (json.RawMessage, error) {
+	if len(details) == 0 {
+		return details, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(details, &obj); err != nil || obj == nil {
+		if len(fields) > 0 {
+			return details, nil
+		}
+		obj = map[string]json.RawMessage{detailsField: details}
+	}
+
+	var encrypted []string
+	for name, value := range obj {
+		if len(fields) > 0 && !slices.Contains(fields, name) {
+			continue
+		}
+
+		ciphertext, err := encryption.Encrypt(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if obj[name], err = json.Marshal(ciphertext); err != nil {
+			return nil, err
+		}
+		encrypted = append(encrypted, name)
+	}
+
+	if len(encrypted) == 0 {
+		return details, nil
+	}
+
+	slices.Sort(encrypted)
+	list, err := json.Marshal(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	obj[EncryptedFieldsKey] = list
+
+	return json.Marshal(obj)
+}
+
+// DecryptDetails reverses EncryptDetails with the private key matching the
+// encryption key, details encrypted as a whole are returned under the details field.
+func DecryptDetails(key *ecdh.PrivateKey, details json.RawMessage) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(details, &obj); err != nil {
+		return nil, err
+	}
+
+	list, ok := obj[EncryptedFieldsKey]
+	if !ok {
+		return details, nil
+	}
+
+	var encrypted []string
+	if err := json.Unmarshal(list, &encrypted); err != nil {
+		return nil, errors.Join(errEncryptedFields, err)
+	}
+	delete(obj, EncryptedFieldsKey)
+
+	for _, name := range encrypted {
+		var ciphertext []byte
+		if err := json.Unmarshal(obj[name], &ciphertext); err != nil {
+			return nil, errors.Join(errEncryptedFields, err)
+		}
+
+		plaintext, err := encryption.Decrypt(key, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = plaintext
+	}
+
+	return json.Marshal(obj)
+}
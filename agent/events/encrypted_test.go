@@ -0,0 +1,120 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+)
+
+func TestEncryptDetails(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc      string
+		fields    []string
+		details   json.RawMessage
+		encrypted []string
+		decrypted json.RawMessage
+	}{
+		{
+			desc:      "listed fields",
+			fields:    []string{"output"},
+			details:   json.RawMessage(`{"output":"secret","from":"Running"}`),
+			encrypted: []string{"output"},
+			decrypted: json.RawMessage(`{"output":"secret","from":"Running"}`),
+		},
+		{
+			desc:      "every field",
+			details:   json.RawMessage(`{"output":"secret","from":"Running"}`),
+			encrypted: []string{"from", "output"},
+			decrypted: json.RawMessage(`{"output":"secret","from":"Running"}`),
+		},
+		{
+			desc:    "no listed field in details",
+			fields:  []string{"error"},
+			details: json.RawMessage(`{"output":"public"}`),
+		},
+		{
+			desc:      "details that are not an object",
+			details:   json.RawMessage(`"secret"`),
+			encrypted: []string{detailsField},
+			decrypted: json.RawMessage(`{"details":"secret"}`),
+		},
+		{
+			desc:    "details that are not an object with listed fields",
+			fields:  []string{"output"},
+			details: json.RawMessage(`"public"`),
+		},
+		{
+			desc:    "empty details",
+			details: json.RawMessage{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			encrypted, err := EncryptDetails(key.PublicKey(), tc.fields, tc.details)
+			require.NoError(t, err)
+
+			if len(tc.encrypted) == 0 {
+				assert.Equal(t, string(tc.details), string(encrypted))
+				return
+			}
+
+			var obj map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(encrypted, &obj))
+			var list []string
+			require.NoError(t, json.Unmarshal(obj[EncryptedFieldsKey], &list))
+			assert.Equal(t, tc.encrypted, list)
+			assert.NotContains(t, string(encrypted), "secret")
+
+			decrypted, err := DecryptDetails(key, encrypted)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(tc.decrypted), string(decrypted))
+		})
+	}
+}
+
+func TestDecryptDetailsWrongKey(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	encrypted, err := EncryptDetails(key.PublicKey(), nil, json.RawMessage(`{"output":"secret"}`))
+	require.NoError(t, err)
+
+	_, err = DecryptDetails(other, encrypted)
+	assert.Error(t, err)
+}
+
+func TestEncryptedSendEvent(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	queue := make(chan *cvms.ClientStreamMessage, 1)
+	svc, err := New("test_service", queue)
+	require.NoError(t, err)
+
+	NewEncrypted(svc, key.PublicKey(), []string{"output"}).
+		SendEvent("testid", "AlgorithmRun", "Warning", json.RawMessage(`{"output":"secret"}`))
+
+	event := (<-queue).GetAgentEvent()
+	require.NotNil(t, event)
+	assert.Equal(t, "AlgorithmRun", event.EventType)
+	assert.Equal(t, "testid", event.ComputationId)
+	assert.Equal(t, "Warning", event.Status)
+	assert.NotContains(t, string(event.Details), "secret")
+
+	decrypted, err := DecryptDetails(key, event.Details)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"output":"secret"}`, string(decrypted))
+}
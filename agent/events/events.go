@@ -5,6 +5,7 @@ package events
 import (
 	"encoding/json"
 
+	"github.com/google/uuid"
 	"github.com/ultravioletrs/cocos/agent/cvms"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -25,10 +26,13 @@ func New(svc string, queue chan *cvms.ClientStreamMessage) (Service, error) {
 	}, nil
 }
 
+// SendEvent queues the event with a unique ID, so that the copies of an event
+// replayed after a reconnection can be dropped.
 func (s *service) SendEvent(cmpID, event, status string, details json.RawMessage) {
 	s.queue <- &cvms.ClientStreamMessage{
 		Message: &cvms.ClientStreamMessage_AgentEvent{
 			AgentEvent: &cvms.AgentEvent{
+				Id:            uuid.New().String(),
 				EventType:     event,
 				Timestamp:     timestamppb.Now(),
 				ComputationId: cmpID,
@@ -26,6 +26,7 @@ func TestSendEventSuccess(t *testing.T) {
 		assert.Equal(t, "testid", msg.GetAgentEvent().ComputationId)
 		assert.Equal(t, "test_service", msg.GetAgentEvent().Originator)
 		assert.Equal(t, "success", msg.GetAgentEvent().Status)
+		assert.NotEmpty(t, msg.GetAgentEvent().Id)
 
 		now := time.Now()
 		eventTimestamp := msg.GetAgentEvent().GetTimestamp().AsTime()
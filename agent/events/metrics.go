@@ -0,0 +1,56 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+var _ Service = (*metricsMiddleware)(nil)
+
+type metricsMiddleware struct {
+	mu      sync.Mutex
+	started map[string]time.Time
+	runtime metrics.Histogram
+	svc     Service
+}
+
+// MetricsMiddleware instruments the events service by tracking the runtime of
+// the algorithm, from the RunStarted event of a computation to the event that
+// ends its run, labelled with that event.
+func MetricsMiddleware(svc Service, runtime metrics.Histogram) Service {
+	return &metricsMiddleware{
+		started: make(map[string]time.Time),
+		runtime: runtime,
+		svc:     svc,
+	}
+}
+
+// SendEvent implements Service.
+func (ms *metricsMiddleware) SendEvent(cmpID, event, status string, details json.RawMessage) {
+	ms.observeRun(cmpID, event)
+
+	ms.svc.SendEvent(cmpID, event, status, details)
+}
+
+func (ms *metricsMiddleware) observeRun(cmpID, event string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	switch event {
+	case RunStarted:
+		ms.started[cmpID] = time.Now()
+	case RunFinished, Error, RunTimedOut, ResourceExceeded, Stopped:
+		begin, ok := ms.started[cmpID]
+		if !ok {
+			return
+		}
+		delete(ms.started, cmpID)
+
+		ms.runtime.With("event", event).Observe(time.Since(begin).Seconds())
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+)
+
+// runtimeHistogram records the label values of the observed runtimes.
+type runtimeHistogram struct {
+	labels   []string
+	observed *[][]string
+}
+
+func (h *runtimeHistogram) With(labelValues ...string) metrics.Histogram {
+	return &runtimeHistogram{labels: append(append([]string{}, h.labels...), labelValues...), observed: h.observed}
+}
+
+func (h *runtimeHistogram) Observe(float64) {
+	*h.observed = append(*h.observed, h.labels)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	cases := []struct {
+		desc     string
+		events   []string
+		observed [][]string
+	}{
+		{
+			desc:     "successful run",
+			events:   []string{ManifestReceived, RunStarted, RunFinished, ResultsConsumed},
+			observed: [][]string{{"event", RunFinished}},
+		},
+		{
+			desc:     "timed out run",
+			events:   []string{RunStarted, RunTimedOut, Error},
+			observed: [][]string{{"event", RunTimedOut}},
+		},
+		{
+			desc:   "stopped before the run",
+			events: []string{ManifestReceived, Stopped},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			svc := new(mocks.Service)
+			svc.On("SendEvent", "1", mock.Anything, "status", mock.Anything).Return()
+
+			var observed [][]string
+			ms := MetricsMiddleware(svc, &runtimeHistogram{observed: &observed})
+
+			for _, event := range tc.events {
+				ms.SendEvent("1", event, "status", json.RawMessage{})
+			}
+
+			assert.Equal(t, tc.observed, observed)
+			svc.AssertNumberOfCalls(t, "SendEvent", len(tc.events))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"encoding/json"
+)
+
+var _ Service = (*redactedService)(nil)
+
+type redactedService struct {
+	svc    Service
+	fields []string
+}
+
+// NewRedacted returns a service that removes the detail fields from events
+// before sending them with svc, e.g. the algorithm output when the manifest
+// does not encrypt event details. Details that are not a JSON object are sent as is.
+func NewRedacted(svc Service, fields []string) Service {
+	return &redactedService{
+		svc:    svc,
+		fields: fields,
+	}
+}
+
+func (s *redactedService) SendEvent(cmpID, event, status string, details json.RawMessage) {
+	s.svc.SendEvent(cmpID, event, status, RedactDetails(s.fields, details))
+}
+
+// RedactDetails removes the fields from details.
+func RedactDetails(fields []string, details json.RawMessage) json.RawMessage {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(details, &obj); err != nil || obj == nil {
+		return details
+	}
+
+	redacted := false
+	for _, name := range fields {
+		if _, ok := obj[name]; ok {
+			delete(obj, name)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return details
+	}
+
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return json.RawMessage{}
+	}
+
+	return out
+}
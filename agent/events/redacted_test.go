@@ -0,0 +1,45 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactDetails(t *testing.T) {
+	cases := []struct {
+		desc     string
+		details  json.RawMessage
+		redacted json.RawMessage
+	}{
+		{
+			desc:     "listed fields",
+			details:  json.RawMessage(`{"output":"secret","stderr":"secret","reason":"exit_code"}`),
+			redacted: json.RawMessage(`{"reason":"exit_code"}`),
+		},
+		{
+			desc:     "no listed field in details",
+			details:  json.RawMessage(`{"from":"Running"}`),
+			redacted: json.RawMessage(`{"from":"Running"}`),
+		},
+		{
+			desc:     "details that are not an object",
+			details:  json.RawMessage(`"public"`),
+			redacted: json.RawMessage(`"public"`),
+		},
+		{
+			desc:     "empty details",
+			details:  json.RawMessage{},
+			redacted: json.RawMessage{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, string(tc.redacted), string(RedactDetails([]string{"output", "stderr"}, tc.details)))
+		})
+	}
+}
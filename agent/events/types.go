@@ -0,0 +1,63 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package events
+
+// Event types published by the agent over the events stream. Every agent state
+// transition is reported with one of these types so consumers can follow a
+// computation without parsing free form messages.
+const (
+	// ManifestReceived is published once the computation manifest is accepted.
+	ManifestReceived = "ManifestReceived"
+	// AlgorithmReceived is published once the algorithm is uploaded and verified.
+	AlgorithmReceived = "AlgorithmReceived"
+	// DataReceived is published once all the datasets are uploaded and verified.
+	DataReceived = "DataReceived"
+	// RunStarted is published when the algorithm starts running.
+	RunStarted = "RunStarted"
+	// RunFinished is published when the algorithm run succeeds and results are ready.
+	RunFinished = "RunFinished"
+	// ResultsConsumed is published once every result consumer fetched the results.
+	ResultsConsumed = "ResultsConsumed"
+	// Error is published when the computation fails, details hold the error.
+	Error = "Error"
+	// RunTimedOut is published when the algorithm is stopped for exceeding the
+	// max runtime of the manifest.
+	RunTimedOut = "RunTimedOut"
+	// ResourceExceeded is published when the algorithm is terminated for
+	// exceeding its manifest resource limits.
+	ResourceExceeded = "ResourceExceeded"
+	// AttestationApproved is published when the computation owner approved
+	// the attestation, releasing the algorithm and datasets uploads.
+	AttestationApproved = "AttestationApproved"
+	// SecretsProvisioned is published when the computation owner provisioned
+	// runtime secrets, details hold their names.
+	SecretsProvisioned = "SecretsProvisioned"
+	// Stopped is published when the computation is stopped.
+	Stopped = "Stopped"
+	// AlgorithmRun is published by the algorithm runtime, e.g. on stderr output.
+	AlgorithmRun = "AlgorithmRun"
+	// AlgorithmFailed is published when the algorithm process fails, details
+	// hold the reason, the exit code or signal and the end of its stderr.
+	AlgorithmFailed = "AlgorithmFailed"
+	// PossiblyHung is published when the algorithm used no CPU and wrote no
+	// output for the watchdog idle period, details hold the process states.
+	PossiblyHung = "PossiblyHung"
+	// CheckpointSaved is published each time the algorithm working directory
+	// is checkpointed, details hold the checkpoint size.
+	CheckpointSaved = "CheckpointSaved"
+	// CheckpointRestored is published when the working directory of a previous
+	// run is restored from its checkpoint.
+	CheckpointRestored = "CheckpointRestored"
+	// UploadThrottled is published when an algorithm or dataset upload is
+	// rejected or slowed down by the agent upload limits.
+	UploadThrottled = "UploadThrottled"
+	// StorageExceeded is published when a dataset is rejected because it does
+	// not fit in the tmpfs budget of the computation storage.
+	StorageExceeded = "StorageExceeded"
+	// DatasetExtracted is published when an uploaded dataset archive is
+	// extracted, details hold the archive and extracted content hashes.
+	DatasetExtracted = "DatasetExtracted"
+	// AgentTerminated is the last event published before the agent exits,
+	// details hold the exit status of the computation.
+	AgentTerminated = "AgentTerminated"
+)
@@ -0,0 +1,105 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	stderrors "errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ultravioletrs/cocos/agent/events"
+	"golang.org/x/sys/unix"
+)
+
+// stderrTailSize is the number of bytes at the end of the algorithm standard
+// error reported when the algorithm fails.
+const stderrTailSize = 8 << 10
+
+// Reasons of the AlgorithmFailed event.
+const (
+	failureOOM      = "oom"
+	failureSignal   = "signal"
+	failureExitCode = "exit_code"
+	failureTimeout  = "timeout"
+)
+
+// vmstatFile holds the kernel event counters, oom_kill counts the processes
+// the OOM killer killed, in any cgroup.
+var vmstatFile = "/proc/vmstat"
+
+// failureDetails are the details of the AlgorithmFailed event.
+type failureDetails struct {
+	Reason   string `json:"reason"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+	// Stderr is the end of the standard error of the algorithm.
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// classifyFailure tells why the algorithm process failed with err. Algorithms
+// killed once the OOM killer ran since the run started are reported as out of
+// memory. It returns false for failures that are not an exit of the process,
+// e.g. an algorithm that could not be started.
+func classifyFailure(err error, expired bool, oomKills uint64) (failureDetails, bool) {
+	if expired {
+		return failureDetails{Reason: failureTimeout}, true
+	}
+
+	var exitErr *exec.ExitError
+	if !stderrors.As(err, &exitErr) {
+		return failureDetails{}, false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return failureDetails{Reason: failureExitCode, ExitCode: exitErr.ExitCode()}, true
+	}
+
+	details := failureDetails{Reason: failureSignal, Signal: unix.SignalName(status.Signal())}
+	if status.Signal() == syscall.SIGKILL && oomKills > 0 {
+		details.Reason = failureOOM
+	}
+
+	return details, true
+}
+
+// oomKills returns the number of processes the OOM killer killed since boot,
+// 0 if the kernel does not report it.
+func oomKills() uint64 {
+	file, err := os.Open(vmstatFile)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if ok && key == "oom_kill" {
+			kills, _ := strconv.ParseUint(value, 10, 64)
+			return kills
+		}
+	}
+
+	return 0
+}
+
+// reportFailure publishes an AlgorithmFailed event telling why the algorithm
+// failed with err, along with the end of its standard error.
+func (as *agentService) reportFailure(err error, expired bool, oomKillsBefore uint64) {
+	details, ok := classifyFailure(err, expired, oomKills()-oomKillsBefore)
+	if !ok {
+		return
+	}
+	details.Stderr = as.stderrTail.String()
+
+	as.logger.Warn("algorithm failed", "computation", as.computation.ID, "reason", details.Reason, "exit_code", details.ExitCode, "signal", details.Signal)
+
+	raw, _ := json.Marshal(details)
+	as.eventSvc.SendEvent(as.computation.ID, events.AlgorithmFailed, Failed.String(), raw)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+)
+
+// exitError runs the shell script and returns the error it exits with.
+func exitError(t *testing.T, script string) error {
+	t.Helper()
+
+	err := exec.Command("sh", "-c", script).Run()
+	require.Error(t, err)
+
+	return fmt.Errorf("algorithm execution error: %w", err)
+}
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		desc     string
+		err      error
+		expired  bool
+		oomKills uint64
+		details  failureDetails
+		ok       bool
+	}{
+		{
+			desc:    "nonzero exit code",
+			err:     exitError(t, "exit 3"),
+			details: failureDetails{Reason: failureExitCode, ExitCode: 3},
+			ok:      true,
+		},
+		{
+			desc:     "nonzero exit code with OOM kills",
+			err:      exitError(t, "exit 1"),
+			oomKills: 1,
+			details:  failureDetails{Reason: failureExitCode, ExitCode: 1},
+			ok:       true,
+		},
+		{
+			desc:    "killed by a signal",
+			err:     exitError(t, "kill -SEGV $$"),
+			details: failureDetails{Reason: failureSignal, Signal: "SIGSEGV"},
+			ok:      true,
+		},
+		{
+			desc:    "killed without OOM kills",
+			err:     exitError(t, "kill -KILL $$"),
+			details: failureDetails{Reason: failureSignal, Signal: "SIGKILL"},
+			ok:      true,
+		},
+		{
+			desc:     "killed by the OOM killer",
+			err:      exitError(t, "kill -KILL $$"),
+			oomKills: 1,
+			details:  failureDetails{Reason: failureOOM, Signal: "SIGKILL"},
+			ok:       true,
+		},
+		{
+			desc:    "timed out",
+			err:     errors.Wrap(ErrRunTimeout, exitError(t, "kill -KILL $$")),
+			expired: true,
+			details: failureDetails{Reason: failureTimeout},
+			ok:      true,
+		},
+		{
+			desc:    "timed out after exiting cleanly",
+			expired: true,
+			details: failureDetails{Reason: failureTimeout},
+			ok:      true,
+		},
+		{
+			desc: "not started",
+			err:  fmt.Errorf("error starting algorithm: %w", exec.ErrNotFound),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			details, ok := classifyFailure(tc.err, tc.expired, tc.oomKills)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.details, details)
+		})
+	}
+}
+
+func TestOOMKills(t *testing.T) {
+	prevFile := vmstatFile
+	t.Cleanup(func() { vmstatFile = prevFile })
+
+	vmstatFile = filepath.Join(t.TempDir(), "vmstat")
+	assert.Zero(t, oomKills(), "missing counters")
+
+	require.NoError(t, os.WriteFile(vmstatFile, []byte("nr_free_pages 1024\noom_kill 7\nnr_dirty 3\n"), 0o644))
+	assert.Equal(t, uint64(7), oomKills())
+}
+
+func TestReportFailure(t *testing.T) {
+	prevFile := vmstatFile
+	t.Cleanup(func() { vmstatFile = prevFile })
+	vmstatFile = filepath.Join(t.TempDir(), "vmstat")
+
+	reported := make(chan json.RawMessage, 1)
+	eventSvc := new(mocks.Service)
+	eventSvc.On("SendEvent", "cmp", events.AlgorithmFailed, Failed.String(), mock.Anything).
+		Run(func(args mock.Arguments) { reported <- args.Get(3).(json.RawMessage) }).Return()
+
+	as := &agentService{
+		logger:      mglog.NewMock(),
+		eventSvc:    eventSvc,
+		computation: Computation{ID: "cmp"},
+	}
+	as.stderrTail.Size = 16
+
+	_, err := as.stderrTail.Write([]byte("Traceback (most recent call last):\nMemoryError\n"))
+	require.NoError(t, err)
+
+	as.reportFailure(exitError(t, "exit 2"), false, 0)
+
+	var details failureDetails
+	require.NoError(t, json.Unmarshal(<-reported, &details))
+	assert.Equal(t, failureDetails{Reason: failureExitCode, ExitCode: 2, Stderr: "t):\nMemoryError\n"}, details)
+
+	as.reportFailure(exec.ErrNotFound, false, 0)
+	eventSvc.AssertNumberOfCalls(t, "SendEvent", 1)
+}
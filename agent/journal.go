@@ -0,0 +1,423 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/statemachine"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// ComputationRecoveredEvent reports a computation recovered from the journal after an agent restart.
+	ComputationRecoveredEvent = "ComputationRecovered"
+
+	journalFile = "journal"
+	resultFile  = "result"
+	// journalKeySize is the size of the AES-256 key the journal is encrypted with.
+	journalKeySize = 32
+)
+
+var (
+	// ErrJournalNotFound indicates there is no journaled computation to recover.
+	ErrJournalNotFound = errors.New("computation journal not found")
+	// ErrJournalCorrupted indicates a journal that cannot be decrypted or decoded.
+	ErrJournalCorrupted = errors.New("computation journal is corrupted")
+	// ErrJournalExpired indicates a journaled computation whose TTL expired while the agent was down.
+	ErrJournalExpired = errors.New("journaled computation expired")
+	// ErrJournalVolatileStorage indicates a journaled computation whose datasets were lost with the storage it kept them on.
+	ErrJournalVolatileStorage = errors.New("journaled computation datasets were kept on volatile storage")
+)
+
+// Journal persists the progress of the computation in a directory, so an agent
+// restarted in the same CVM recovers the computation instead of waiting for it
+// to be sent again. The journal and the result are encrypted with AES-GCM under
+// a key kept in a file that should be on a memory backed file system, such as
+// /run: the journal is then readable only until the CVM reboots.
+type Journal struct {
+	mu   sync.Mutex
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewJournal returns a journal kept in dir and encrypted with the key in
+// keyFile, which is generated if it does not exist.
+func NewJournal(dir, keyFile string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating journal directory: %v", err)
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		key, err = newJournalKey(keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading journal key: %v", err)
+	}
+	if len(key) != journalKeySize {
+		return nil, fmt.Errorf("journal key must be %d bytes", journalKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{dir: dir, aead: aead}, nil
+}
+
+func newJournalKey(keyFile string) ([]byte, error) {
+	key := make([]byte, journalKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o700); err != nil {
+		return nil, err
+	}
+
+	return key, os.WriteFile(keyFile, key, 0o600)
+}
+
+// journalRecord is the journaled progress of the computation.
+type journalRecord struct {
+	Computation     Computation   `json:"computation"`
+	AssignedAt      time.Time     `json:"assigned_at"`
+	State           string        `json:"state"`
+	Algorithm       algorithmSpec `json:"algorithm"`
+	Datasets        *storeRecord  `json:"datasets,omitempty"`
+	Received        []bool        `json:"received"`
+	Lineage         Lineage       `json:"lineage"`
+	RunError        string        `json:"run_error,omitempty"`
+	ResultsConsumed bool          `json:"results_consumed,omitempty"`
+	Approved        bool          `json:"approved,omitempty"`
+}
+
+// storeRecord is the journaled datasets store of an algorithm with steps.
+type storeRecord struct {
+	Dir        string            `json:"dir"`
+	Decompress map[string]bool   `json:"decompress"`
+	Names      map[string]string `json:"names"`
+	Nested     bool              `json:"nested"`
+}
+
+// save replaces the journaled progress with rec, and the journaled result with
+// result when it is not nil.
+func (j *Journal) save(rec journalRecord, result []byte) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// The result is written first, so a journal never refers to a missing result.
+	if result != nil {
+		if err := j.write(resultFile, result); err != nil {
+			return err
+		}
+	}
+
+	return j.write(journalFile, data)
+}
+
+// load returns the journaled progress and result.
+func (j *Journal) load() (journalRecord, []byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var rec journalRecord
+	data, err := j.read(journalFile)
+	if err != nil {
+		return rec, nil, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, nil, errors.Wrap(ErrJournalCorrupted, err)
+	}
+
+	result, err := j.read(resultFile)
+	if err != nil && !errors.Contains(err, ErrJournalNotFound) {
+		return rec, nil, err
+	}
+
+	return rec, result, nil
+}
+
+// clear removes the journaled progress and result.
+func (j *Journal) clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, name := range []string{journalFile, resultFile} {
+		if err := os.Remove(filepath.Join(j.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write encrypts the data to the named file. It is written aside and renamed,
+// so a crash never leaves a partial file.
+func (j *Journal) write(name string, data []byte) error {
+	nonce := make([]byte, j.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	// The file name is authenticated, so the journal and the result cannot be swapped.
+	sealed := j.aead.Seal(nonce, nonce, data, []byte(name))
+
+	tmp, err := os.CreateTemp(j.dir, name+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating journal file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing journal: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing journal: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing journal: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(j.dir, name))
+}
+
+func (j *Journal) read(name string) ([]byte, error) {
+	sealed, err := os.ReadFile(filepath.Join(j.dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrJournalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	size := j.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, ErrJournalCorrupted
+	}
+
+	data, err := j.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+	if err != nil {
+		// A journal of an earlier boot was encrypted with a key that is gone.
+		return nil, errors.Wrap(ErrJournalCorrupted, err)
+	}
+
+	return data, nil
+}
+
+// journalState returns the progress of the computation in the state. It must
+// be called with the service mutex held.
+func (as *agentService) journalState(state statemachine.State) journalRecord {
+	rec := journalRecord{
+		Computation:     as.computation,
+		AssignedAt:      as.assignedAt,
+		State:           state.String(),
+		Algorithm:       as.algoSpec,
+		Received:        as.received,
+		Lineage:         as.lineage,
+		ResultsConsumed: as.resultsConsumed,
+		Approved:        as.approved,
+	}
+	if as.datasets != nil {
+		rec.Datasets = &storeRecord{
+			Dir:        as.datasets.dir,
+			Decompress: as.datasets.decompress,
+			Names:      as.datasets.names,
+			Nested:     as.datasets.nested,
+		}
+	}
+	if as.runError != nil {
+		rec.RunError = as.runError.Error()
+	}
+
+	return rec
+}
+
+// persist journals the progress of the computation in the state, with the
+// result once the run ended. It must be called with the service mutex held.
+func (as *agentService) persist(state statemachine.State) {
+	if as.journal == nil || !as.assigned {
+		return
+	}
+
+	var result []byte
+	if state == ConsumingResults || state == Failed {
+		result = as.result
+	}
+
+	if err := as.journal.save(as.journalState(state), result); err != nil {
+		as.logger.Warn(fmt.Sprintf("failed to journal computation: %s", err.Error()))
+	}
+}
+
+// forget removes the journaled computation once it is stopped.
+func (as *agentService) forget() {
+	if as.journal == nil {
+		return
+	}
+
+	if err := as.journal.clear(); err != nil {
+		as.logger.Warn(fmt.Sprintf("failed to remove computation journal: %s", err.Error()))
+	}
+}
+
+// recover restores the journaled computation and returns the state the agent
+// resumes in, or nil if there is none to recover. A computation that cannot be
+// recovered is discarded, and the agent waits for a new manifest.
+func (as *agentService) recover() statemachine.State {
+	if as.journal == nil {
+		return nil
+	}
+
+	rec, result, err := as.journal.load()
+	if errors.Contains(err, ErrJournalNotFound) {
+		return nil
+	}
+
+	var state AgentState
+	if err == nil {
+		state, err = as.restore(rec, result)
+	}
+	if err != nil {
+		as.logger.Warn(fmt.Sprintf("discarding journaled computation: %s", err.Error()))
+		if err := as.closeStorage(); err != nil {
+			as.logger.Warn(fmt.Sprintf("failed to close computation storage: %s", err.Error()))
+		}
+		as.forget()
+		return nil
+	}
+
+	as.logger.Info("recovered journaled computation", "computation", rec.Computation.ID, "state", state.String())
+	details, _ := json.Marshal(map[string]string{"state": state.String()})
+	as.eventSvc.SendEvent(rec.Computation.ID, ComputationRecoveredEvent, InProgress.String(), details)
+
+	return state
+}
+
+// restore reassigns the journaled computation and reloads the algorithm, the
+// datasets and the result it received or produced.
+func (as *agentService) restore(rec journalRecord, result []byte) (AgentState, error) {
+	state, ok := parseAgentState(rec.State)
+	if !ok || state < ReceivingAlgorithm {
+		return 0, errors.Wrap(ErrJournalCorrupted, fmt.Errorf("unexpected state %q", rec.State))
+	}
+	if len(rec.Received) != len(rec.Computation.Datasets) {
+		return 0, errors.Wrap(ErrJournalCorrupted, fmt.Errorf("datasets do not match the manifest"))
+	}
+
+	// Tmpfs and block storage do not outlive the agent, the datasets they held are gone.
+	if (state == ReceivingData || state == Running) && volatileStorage(rec.Computation) {
+		return 0, ErrJournalVolatileStorage
+	}
+
+	// The manifest was validated when it was received.
+	ttl, _ := computationTTL(rec.Computation)
+	if ttl > 0 {
+		if ttl -= time.Since(rec.AssignedAt); ttl <= 0 {
+			return 0, ErrJournalExpired
+		}
+	}
+	eventKey, _ := eventEncryptionKey(rec.Computation)
+
+	if err := as.assign(context.Background(), rec.Computation, ttl, eventKey); err != nil {
+		return 0, err
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.assignedAt = rec.AssignedAt
+	as.received = rec.Received
+	as.lineage = rec.Lineage
+	as.resultsConsumed = rec.ResultsConsumed
+	as.approved = rec.Approved
+
+	switch state {
+	case ReceivingAlgorithm:
+	case ReceivingData, Running:
+		if err := as.reloadAlgorithm(rec); err != nil {
+			return 0, err
+		}
+		// The results of an interrupted run are discarded, the algorithm runs again.
+		if err := as.resultsStorage().Remove(as.sandbox.Results()); err != nil {
+			return 0, err
+		}
+	default:
+		// The datasets were removed once the run ended, only its outcome is left.
+		as.algoSpec = rec.Algorithm
+		as.result = result
+		if rec.RunError != "" {
+			as.runError = errors.New(rec.RunError)
+		}
+	}
+
+	return state, nil
+}
+
+// reloadAlgorithm reloads the journaled algorithm, verifying it was not
+// changed while the agent was down. It must be called with the service mutex held.
+func (as *agentService) reloadAlgorithm(rec journalRecord) error {
+	data, err := os.ReadFile(rec.Algorithm.Path)
+	if err != nil {
+		return fmt.Errorf("error reading algorithm: %v", err)
+	}
+	if sha3.Sum256(data) != rec.Computation.Algorithm.Hash {
+		return ErrHashMismatch
+	}
+
+	var store *datasetStore
+	if rec.Datasets != nil {
+		store = &datasetStore{
+			storage:    as.dataStorage(),
+			dir:        rec.Datasets.Dir,
+			datasets:   as.sandbox.Datasets(),
+			decompress: rec.Datasets.Decompress,
+			names:      rec.Datasets.Names,
+			nested:     rec.Datasets.Nested,
+		}
+		if store.decompress == nil {
+			store.decompress = make(map[string]bool)
+		}
+		if store.names == nil {
+			store.names = make(map[string]string)
+		}
+	}
+
+	if err := as.loadAlgorithm(rec.Algorithm, store); err != nil {
+		return err
+	}
+
+	return as.dataStorage().Create(as.sandbox.Datasets())
+}
+
+func parseAgentState(s string) (AgentState, bool) {
+	for state := Idle; state <= Failed; state++ {
+		if state.String() == s {
+			return state, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,214 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key", "journal.key")
+
+	j, err := NewJournal(filepath.Join(dir, "state"), keyFile)
+	require.NoError(t, err)
+
+	_, _, err = j.load()
+	assert.True(t, errors.Contains(err, ErrJournalNotFound), "expected %v, got %v", ErrJournalNotFound, err)
+
+	rec := journalRecord{
+		Computation: testComputation(t),
+		AssignedAt:  time.Now().UTC().Truncate(time.Second),
+		State:       ConsumingResults.String(),
+		Algorithm:   algorithmSpec{Path: "algo", Type: string(algorithm.AlgoTypeBin)},
+		Received:    []bool{true},
+	}
+	require.NoError(t, j.save(rec, []byte("result")))
+
+	data, err := os.ReadFile(filepath.Join(dir, "state", journalFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), rec.Computation.Name, "the journal is encrypted")
+
+	got, result, err := j.load()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("result"), result)
+	assert.Equal(t, rec.State, got.State)
+	assert.Equal(t, rec.Algorithm, got.Algorithm)
+	assert.Equal(t, rec.Computation.Algorithm.Hash, got.Computation.Algorithm.Hash)
+	assert.Equal(t, rec.Received, got.Received)
+
+	// A journal of an earlier boot cannot be decrypted once its key is gone.
+	require.NoError(t, os.Remove(keyFile))
+	reopened, err := NewJournal(filepath.Join(dir, "state"), keyFile)
+	require.NoError(t, err)
+	_, _, err = reopened.load()
+	assert.True(t, errors.Contains(err, ErrJournalCorrupted), "expected %v, got %v", ErrJournalCorrupted, err)
+
+	require.NoError(t, reopened.clear())
+	_, _, err = reopened.load()
+	assert.True(t, errors.Contains(err, ErrJournalNotFound), "expected %v, got %v", ErrJournalNotFound, err)
+}
+
+func TestJournalProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	j, err := NewJournal(dir, filepath.Join(dir, "journal.key"))
+	require.NoError(t, err)
+
+	evts := new(mocks.Service)
+	evts.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	svc := New(ctx, mglog.NewMock(), evts, new(MockAttestationClient), 0, nil, false, nil, nil, j)
+	require.NoError(t, svc.InitComputation(ctx, testComputation(t)))
+
+	assert.Eventually(t, func() bool {
+		rec, _, err := j.load()
+		return err == nil && rec.State == ReceivingAlgorithm.String()
+	}, time.Second, 10*time.Millisecond, "the received manifest is journaled")
+
+	require.NoError(t, svc.StopComputation(ctx))
+	_, _, err = j.load()
+	assert.True(t, errors.Contains(err, ErrJournalNotFound), "a stopped computation is forgotten")
+}
+
+func TestRecover(t *testing.T) {
+	algo := []byte("#!/bin/sh\nexit 0\n")
+	algoPath := filepath.Join(t.TempDir(), "algo")
+	require.NoError(t, os.WriteFile(algoPath, algo, algoFilePermission))
+
+	withDataset := testComputation(t)
+	withDataset.Algorithm.Hash = sha3.Sum256(algo)
+
+	noDatasets := withDataset
+	noDatasets.Datasets = nil
+
+	expiring := withDataset
+	expiring.TTL = "1m"
+
+	inMemory := withDataset
+	inMemory.Storage = &Storage{Type: storage.Tmpfs}
+
+	spec := algorithmSpec{Path: algoPath, Type: string(algorithm.AlgoTypeBin)}
+
+	cases := []struct {
+		desc   string
+		rec    *journalRecord
+		result []byte
+		state  AgentState
+		err    error
+	}{
+		{
+			desc:  "no journaled computation",
+			state: ReceivingManifest,
+		},
+		{
+			desc:  "computation waiting for the algorithm",
+			rec:   &journalRecord{Computation: withDataset, AssignedAt: time.Now(), State: ReceivingAlgorithm.String(), Received: []bool{false}},
+			state: ReceivingAlgorithm,
+		},
+		{
+			desc:  "computation waiting for datasets",
+			rec:   &journalRecord{Computation: withDataset, AssignedAt: time.Now(), State: ReceivingData.String(), Algorithm: spec, Received: []bool{false}},
+			state: ReceivingData,
+		},
+		{
+			desc:  "interrupted run",
+			rec:   &journalRecord{Computation: noDatasets, AssignedAt: time.Now(), State: Running.String(), Algorithm: spec, Received: []bool{}},
+			state: ConsumingResults,
+		},
+		{
+			desc:   "results waiting for consumers",
+			rec:    &journalRecord{Computation: withDataset, AssignedAt: time.Now(), State: ConsumingResults.String(), Algorithm: spec, Received: []bool{true}},
+			result: []byte("result"),
+			state:  ConsumingResults,
+		},
+		{
+			desc:  "failed run",
+			rec:   &journalRecord{Computation: withDataset, AssignedAt: time.Now(), State: Failed.String(), Algorithm: spec, Received: []bool{true}, RunError: "exit status 1"},
+			state: Failed,
+			err:   errors.New("exit status 1"),
+		},
+		{
+			desc:  "algorithm changed while the agent was down",
+			rec:   &journalRecord{Computation: testComputation(t), AssignedAt: time.Now(), State: ReceivingData.String(), Algorithm: spec, Received: []bool{false}},
+			state: ReceivingManifest,
+		},
+		{
+			desc:  "datasets kept in memory lost with the agent",
+			rec:   &journalRecord{Computation: inMemory, AssignedAt: time.Now(), State: ReceivingData.String(), Algorithm: spec, Received: []bool{false}},
+			state: ReceivingManifest,
+		},
+		{
+			desc:  "computation expired while the agent was down",
+			rec:   &journalRecord{Computation: expiring, AssignedAt: time.Now().Add(-time.Hour), State: ReceivingAlgorithm.String(), Received: []bool{false}},
+			state: ReceivingManifest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dir := t.TempDir()
+			j, err := NewJournal(dir, filepath.Join(dir, "journal.key"))
+			require.NoError(t, err)
+			if tc.rec != nil {
+				require.NoError(t, j.save(*tc.rec, tc.result))
+			}
+
+			evts := new(mocks.Service)
+			evts.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+			svc := New(ctx, mglog.NewMock(), evts, new(MockAttestationClient), 0, nil, false, nil, nil, j)
+			t.Cleanup(func() {
+				os.RemoveAll(algorithm.DatasetsDir)
+				os.RemoveAll(algorithm.ResultsDir)
+				os.RemoveAll(algorithm.WorkDir)
+			})
+
+			assert.Eventually(t, func() bool { return svc.State() == tc.state.String() }, 5*time.Second, 10*time.Millisecond, "expected state %s, got %s", tc.state, svc.State())
+
+			if tc.state == ReceivingManifest {
+				_, _, err := j.load()
+				assert.True(t, errors.Contains(err, ErrJournalNotFound), "an unrecoverable computation is discarded")
+				return
+			}
+
+			evts.AssertCalled(t, "SendEvent", tc.rec.Computation.ID, ComputationRecoveredEvent, InProgress.String(), mock.Anything)
+
+			if tc.state == ConsumingResults || tc.state == Failed {
+				result, err := svc.Result(IndexToContext(ctx, 0))
+				if tc.err != nil {
+					assert.EqualError(t, err, tc.err.Error())
+					return
+				}
+				require.NoError(t, err)
+				if tc.result != nil {
+					assert.Equal(t, tc.result, result)
+				}
+
+				assert.Eventually(t, func() bool {
+					rec, _, err := j.load()
+					return err == nil && rec.State == Complete.String()
+				}, time.Second, 10*time.Millisecond, "consumed results are journaled")
+			}
+		})
+	}
+}
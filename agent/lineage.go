@@ -0,0 +1,132 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// LineageFile is the result archive entry holding the lineage of the result.
+	LineageFile = "cocos-lineage.json"
+
+	// DatasetUploaded marks a dataset uploaded by its data provider.
+	DatasetUploaded = "upload"
+	// DatasetAttached marks a dataset delivered on a hot-added dataset disk.
+	DatasetAttached = "disk"
+)
+
+// Lineage records the inputs a computation result was produced from, so that
+// the provenance of the result can be verified from its signed result manifest.
+type Lineage struct {
+	ComputationID string `json:"computation_id"`
+	// ManifestVersion is the schema version of the computation manifest.
+	ManifestVersion uint32           `json:"manifest_version"`
+	Algorithm       AlgorithmLineage `json:"algorithm"`
+	Datasets        []DatasetLineage `json:"datasets"`
+}
+
+// AlgorithmLineage identifies the algorithm that produced the result.
+type AlgorithmLineage struct {
+	Hash string `json:"hash"`
+	// Provider is the fingerprint of the algorithm provider key, see KeyFingerprint.
+	Provider   string    `json:"provider"`
+	Type       string    `json:"type,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	// ContentHash is the hash of the content a bundled algorithm was extracted to, see internal.ArchiveInfo.
+	ContentHash string `json:"content_hash,omitempty"`
+}
+
+// DatasetLineage identifies a dataset the result was computed from by its
+// index in the computation manifest.
+type DatasetLineage struct {
+	Index    int    `json:"index"`
+	Filename string `json:"filename,omitempty"`
+	Hash     string `json:"hash"`
+	// ContentHash is the hash of the content an archive dataset was extracted to, see internal.ArchiveInfo.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Provider is the fingerprint of the data provider key, see KeyFingerprint.
+	Provider string `json:"provider"`
+	// Source is how the dataset was delivered, DatasetUploaded or DatasetAttached.
+	Source     string    `json:"source,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// KeyFingerprint returns the hex encoded SHA3-256 hash of a manifest public key.
+func KeyFingerprint(key []byte) string {
+	sum := sha3.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// newLineage returns the lineage of the computation before any input was received.
+func newLineage(cmp Computation) Lineage {
+	lineage := Lineage{
+		ComputationID:   cmp.ID,
+		ManifestVersion: cmp.Version,
+		Algorithm: AlgorithmLineage{
+			Hash:     hex.EncodeToString(cmp.Algorithm.Hash[:]),
+			Provider: KeyFingerprint(cmp.Algorithm.UserKey),
+		},
+		Datasets: make([]DatasetLineage, len(cmp.Datasets)),
+	}
+
+	for i, d := range cmp.Datasets {
+		lineage.Datasets[i] = DatasetLineage{
+			Index:    i,
+			Filename: d.Filename,
+			Hash:     hex.EncodeToString(d.Hash[:]),
+			Provider: KeyFingerprint(d.UserKey),
+		}
+	}
+
+	return lineage
+}
+
+// algorithmReceived records the delivery of the algorithm.
+func (l *Lineage) algorithmReceived(algoType string) {
+	l.Algorithm.Type = algoType
+	l.Algorithm.ReceivedAt = time.Now().UTC()
+}
+
+// algorithmExtracted records the content hash of the bundled algorithm.
+func (l *Lineage) algorithmExtracted(contentHash string) {
+	l.Algorithm.ContentHash = contentHash
+}
+
+// datasetReceived records the delivery of the dataset at the manifest index,
+// and the hash it was delivered with, which hash-less datasets do not declare.
+func (l *Lineage) datasetReceived(index int, source string, hash [32]byte) {
+	if index < 0 || index >= len(l.Datasets) {
+		return
+	}
+
+	l.Datasets[index].Hash = hex.EncodeToString(hash[:])
+	l.Datasets[index].Source = source
+	l.Datasets[index].ReceivedAt = time.Now().UTC()
+}
+
+// datasetExtracted records the content hash of the archive dataset at the manifest index.
+func (l *Lineage) datasetExtracted(index int, contentHash string) {
+	if index < 0 || index >= len(l.Datasets) {
+		return
+	}
+
+	l.Datasets[index].ContentHash = contentHash
+}
+
+// writeLineage writes the lineage to the results directory, replacing any
+// algorithm output with the same name.
+func writeLineage(dir string, lineage Lineage) error {
+	data, err := json.MarshalIndent(lineage, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, LineageFile), data, 0o644)
+}
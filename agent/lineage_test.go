@@ -0,0 +1,63 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineage(t *testing.T) {
+	cmp := Computation{
+		ID:        "cmp1",
+		Version:   ManifestVersion,
+		Algorithm: Algorithm{Hash: [32]byte{1}, UserKey: []byte("algo-key")},
+		Datasets: Datasets{
+			{Hash: [32]byte{2}, UserKey: []byte("data-key-a"), Filename: "a.csv"},
+			{Hash: [32]byte{3}, UserKey: []byte("data-key-b")},
+		},
+	}
+
+	lineage := newLineage(cmp)
+	assert.Equal(t, "cmp1", lineage.ComputationID)
+	assert.Equal(t, uint32(ManifestVersion), lineage.ManifestVersion)
+	assert.Equal(t, hex.EncodeToString(cmp.Algorithm.Hash[:]), lineage.Algorithm.Hash)
+	assert.Equal(t, KeyFingerprint([]byte("algo-key")), lineage.Algorithm.Provider)
+	require.Len(t, lineage.Datasets, 2)
+	assert.NotEqual(t, lineage.Datasets[0].Provider, lineage.Datasets[1].Provider)
+
+	before := time.Now().UTC()
+	lineage.algorithmReceived("python")
+	lineage.datasetReceived(0, DatasetUploaded, cmp.Datasets[0].Hash)
+	lineage.datasetReceived(1, DatasetAttached, cmp.Datasets[1].Hash)
+	lineage.datasetReceived(2, DatasetUploaded, [32]byte{4})
+
+	assert.Equal(t, "python", lineage.Algorithm.Type)
+	assert.False(t, lineage.Algorithm.ReceivedAt.Before(before))
+	assert.Equal(t, DatasetLineage{
+		Index:      0,
+		Filename:   "a.csv",
+		Hash:       hex.EncodeToString(cmp.Datasets[0].Hash[:]),
+		Provider:   KeyFingerprint([]byte("data-key-a")),
+		Source:     DatasetUploaded,
+		ReceivedAt: lineage.Datasets[0].ReceivedAt,
+	}, lineage.Datasets[0])
+	assert.Equal(t, DatasetAttached, lineage.Datasets[1].Source)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, LineageFile), []byte("algorithm output"), 0o644))
+	require.NoError(t, writeLineage(dir, lineage))
+
+	data, err := os.ReadFile(filepath.Join(dir, LineageFile))
+	require.NoError(t, err)
+	var written Lineage
+	require.NoError(t, json.Unmarshal(data, &written), "the lineage replaces algorithm output")
+	assert.Equal(t, lineage, written)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+const (
+	// ManifestVerificationEvent is the event published when a manifest is rejected.
+	ManifestVerificationEvent = "ManifestVerification"
+
+	// ManifestUnsigned is the error code of a manifest without a signature.
+	ManifestUnsigned = "manifest_unsigned"
+	// ManifestSignatureInvalid is the error code of a manifest not signed by a trusted key.
+	ManifestSignatureInvalid = "manifest_signature_invalid"
+)
+
+var (
+	// ErrManifestUnsigned indicates the computation manifest has no signature.
+	ErrManifestUnsigned = errors.New("computation manifest is not signed")
+	// ErrManifestSignature indicates the computation manifest signature does not match any trusted key.
+	ErrManifestSignature = errors.New("computation manifest is not signed by a trusted key")
+	// ErrTrustedKeys indicates the trusted manifest keys could not be loaded.
+	ErrTrustedKeys = errors.New("failed to load trusted manifest keys")
+	// ErrUnsupportedKey indicates a key that is neither Ed25519 nor ECDSA.
+	ErrUnsupportedKey = errors.New("unsupported manifest signing key, expected Ed25519 or ECDSA")
+)
+
+// SigningBytes returns the canonical form of the manifest that is signed,
+// the JSON encoding of the computation without its signature.
+func (c Computation) SigningBytes() ([]byte, error) {
+	c.Signature = nil
+	return json.Marshal(c)
+}
+
+// SignComputation signs the manifest with an Ed25519 or ECDSA private key.
+// ECDSA signatures are ASN.1 encoded over the SHA-256 digest of the manifest.
+func SignComputation(cmp Computation, key crypto.Signer) ([]byte, error) {
+	data, err := cmp.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return sign(data, key)
+}
+
+// sign signs data with an Ed25519 key, or with an ECDSA key over its SHA-256 digest.
+func sign(data []byte, key crypto.Signer) ([]byte, error) {
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		return key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// LoadTrustedKeys reads the PEM encoded Ed25519 and ECDSA public keys from path.
+func LoadTrustedKeys(path string) ([]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(ErrTrustedKeys, err)
+	}
+
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(ErrTrustedKeys, err)
+		}
+
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, errors.Wrap(ErrTrustedKeys, ErrUnsupportedKey)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.Wrap(ErrTrustedKeys, errors.New("no public keys found"))
+	}
+
+	return keys, nil
+}
+
+// verifyManifest checks that the manifest is signed by one of the trusted keys.
+func verifyManifest(cmp Computation, trustedKeys []crypto.PublicKey) error {
+	if len(cmp.Signature) == 0 {
+		return ErrManifestUnsigned
+	}
+
+	data, err := cmp.SigningBytes()
+	if err != nil {
+		return err
+	}
+
+	if !verifySignature(data, cmp.Signature, trustedKeys) {
+		return ErrManifestSignature
+	}
+
+	return nil
+}
+
+// verifySignature reports whether signature was produced over data by one of the keys.
+func verifySignature(data, signature []byte, keys []crypto.PublicKey) bool {
+	digest := sha256.Sum256(data)
+
+	for _, key := range keys {
+		switch key := key.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, data, signature) {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, digest[:], signature) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// manifestErrorCode maps a verification error to the code published in events.
+func manifestErrorCode(err error) string {
+	if errors.Contains(err, ErrManifestUnsigned) {
+		return ManifestUnsigned
+	}
+
+	return ManifestSignatureInvalid
+}
@@ -0,0 +1,175 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+)
+
+func writePublicKeys(t *testing.T, keys ...any) string {
+	var data []byte
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+
+	path := filepath.Join(t.TempDir(), "trusted.pem")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	return path
+}
+
+func TestSignAndVerifyManifest(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, untrusted, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	trusted := []crypto.PublicKey{edPub, &ecPriv.PublicKey}
+
+	cases := []struct {
+		desc   string
+		signer crypto.Signer
+		tamper bool
+		err    error
+	}{
+		{
+			desc:   "ed25519 signature",
+			signer: edPriv,
+		},
+		{
+			desc:   "ecdsa signature",
+			signer: ecPriv,
+		},
+		{
+			desc:   "untrusted key",
+			signer: untrusted,
+			err:    ErrManifestSignature,
+		},
+		{
+			desc:   "tampered manifest",
+			signer: edPriv,
+			tamper: true,
+			err:    ErrManifestSignature,
+		},
+		{
+			desc: "unsigned manifest",
+			err:  ErrManifestUnsigned,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmp := testComputation(t)
+			if tc.signer != nil {
+				cmp.Signature, err = SignComputation(cmp, tc.signer)
+				require.NoError(t, err)
+			}
+			if tc.tamper {
+				cmp.Name = "tampered"
+			}
+
+			err := verifyManifest(cmp, trusted)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keys, err := LoadTrustedKeys(writePublicKeys(t, edPub, &ecPriv.PublicKey))
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	_, err = LoadTrustedKeys(writePublicKeys(t, &rsaPriv.PublicKey))
+	assert.True(t, errors.Contains(err, ErrUnsupportedKey))
+
+	_, err = LoadTrustedKeys(writePublicKeys(t))
+	assert.True(t, errors.Contains(err, ErrTrustedKeys))
+
+	_, err = LoadTrustedKeys(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.True(t, errors.Contains(err, ErrTrustedKeys))
+}
+
+func TestInitComputationManifestVerification(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signed := testComputation(t)
+	signed.Signature, err = SignComputation(signed, edPriv)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc string
+		cmp  Computation
+		code string
+		err  error
+	}{
+		{
+			desc: "signed manifest",
+			cmp:  signed,
+		},
+		{
+			desc: "unsigned manifest",
+			cmp:  testComputation(t),
+			code: ManifestUnsigned,
+			err:  ErrManifestUnsigned,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var details json.RawMessage
+			events := new(mocks.Service)
+			events.On("SendEvent", mock.Anything, ManifestVerificationEvent, Failed.String(), mock.Anything).Return().Run(func(args mock.Arguments) {
+				details = args.Get(3).(json.RawMessage)
+			}).Maybe()
+			events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, []crypto.PublicKey{edPub}, false, nil, nil, nil)
+
+			err := svc.InitComputation(ctx, tc.cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+
+			if tc.code == "" {
+				assert.Nil(t, details)
+				return
+			}
+
+			var event map[string]string
+			require.NoError(t, json.Unmarshal(details, &event))
+			assert.Equal(t, tc.code, event["code"])
+		})
+	}
+}
@@ -99,6 +99,126 @@ func (_c *Service_Algo_Call) RunAndReturn(run func(ctx context.Context, algorith
 	return _c
 }
 
+// ApproveAttestation provides a mock function for the type Service
+func (_mock *Service) ApproveAttestation(ctx context.Context, report []byte, signature []byte) error {
+	ret := _mock.Called(ctx, report, signature)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApproveAttestation")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []byte, []byte) error); ok {
+		r0 = returnFunc(ctx, report, signature)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_ApproveAttestation_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApproveAttestation'
+type Service_ApproveAttestation_Call struct {
+	*mock.Call
+}
+
+// ApproveAttestation is a helper method to define mock.On call
+//   - ctx context.Context
+//   - report []byte
+//   - signature []byte
+func (_e *Service_Expecter) ApproveAttestation(ctx interface{}, report interface{}, signature interface{}) *Service_ApproveAttestation_Call {
+	return &Service_ApproveAttestation_Call{Call: _e.mock.On("ApproveAttestation", ctx, report, signature)}
+}
+
+func (_c *Service_ApproveAttestation_Call) Run(run func(ctx context.Context, report []byte, signature []byte)) *Service_ApproveAttestation_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []byte
+		if args[1] != nil {
+			arg1 = args[1].([]byte)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_ApproveAttestation_Call) Return(err error) *Service_ApproveAttestation_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_ApproveAttestation_Call) RunAndReturn(run func(ctx context.Context, report []byte, signature []byte) error) *Service_ApproveAttestation_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AttachDatasetDisk provides a mock function for the type Service
+func (_mock *Service) AttachDatasetDisk(ctx context.Context, dir string) error {
+	ret := _mock.Called(ctx, dir)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AttachDatasetDisk")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, dir)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_AttachDatasetDisk_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AttachDatasetDisk'
+type Service_AttachDatasetDisk_Call struct {
+	*mock.Call
+}
+
+// AttachDatasetDisk is a helper method to define mock.On call
+//   - ctx context.Context
+//   - dir string
+func (_e *Service_Expecter) AttachDatasetDisk(ctx interface{}, dir interface{}) *Service_AttachDatasetDisk_Call {
+	return &Service_AttachDatasetDisk_Call{Call: _e.mock.On("AttachDatasetDisk", ctx, dir)}
+}
+
+func (_c *Service_AttachDatasetDisk_Call) Run(run func(ctx context.Context, dir string)) *Service_AttachDatasetDisk_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_AttachDatasetDisk_Call) Return(err error) *Service_AttachDatasetDisk_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_AttachDatasetDisk_Call) RunAndReturn(run func(ctx context.Context, dir string) error) *Service_AttachDatasetDisk_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Attestation provides a mock function for the type Service
 func (_mock *Service) Attestation(ctx context.Context, reportData [64]byte, nonce [32]byte, attType attestation.PlatformType) ([]byte, error) {
 	ret := _mock.Called(ctx, reportData, nonce, attType)
@@ -304,6 +424,52 @@ func (_c *Service_Data_Call) RunAndReturn(run func(ctx context.Context, dataset
 	return _c
 }
 
+// Datasets provides a mock function for the type Service
+func (_mock *Service) Datasets() []agent.DatasetStatus {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Datasets")
+	}
+
+	var r0 []agent.DatasetStatus
+	if returnFunc, ok := ret.Get(0).(func() []agent.DatasetStatus); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]agent.DatasetStatus)
+		}
+	}
+	return r0
+}
+
+// Service_Datasets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Datasets'
+type Service_Datasets_Call struct {
+	*mock.Call
+}
+
+// Datasets is a helper method to define mock.On call
+func (_e *Service_Expecter) Datasets() *Service_Datasets_Call {
+	return &Service_Datasets_Call{Call: _e.mock.On("Datasets")}
+}
+
+func (_c *Service_Datasets_Call) Run(run func()) *Service_Datasets_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Service_Datasets_Call) Return(datasetStatuss []agent.DatasetStatus) *Service_Datasets_Call {
+	_c.Call.Return(datasetStatuss)
+	return _c
+}
+
+func (_c *Service_Datasets_Call) RunAndReturn(run func() []agent.DatasetStatus) *Service_Datasets_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // IMAMeasurements provides a mock function for the type Service
 func (_mock *Service) IMAMeasurements(ctx context.Context) ([]byte, []byte, error) {
 	ret := _mock.Called(ctx)
@@ -431,6 +597,63 @@ func (_c *Service_InitComputation_Call) RunAndReturn(run func(ctx context.Contex
 	return _c
 }
 
+// Restore provides a mock function for the type Service
+func (_mock *Service) Restore(ctx context.Context, sequence uint64) error {
+	ret := _mock.Called(ctx, sequence)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = returnFunc(ctx, sequence)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_Restore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Restore'
+type Service_Restore_Call struct {
+	*mock.Call
+}
+
+// Restore is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sequence uint64
+func (_e *Service_Expecter) Restore(ctx interface{}, sequence interface{}) *Service_Restore_Call {
+	return &Service_Restore_Call{Call: _e.mock.On("Restore", ctx, sequence)}
+}
+
+func (_c *Service_Restore_Call) Run(run func(ctx context.Context, sequence uint64)) *Service_Restore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint64
+		if args[1] != nil {
+			arg1 = args[1].(uint64)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Restore_Call) Return(err error) *Service_Restore_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_Restore_Call) RunAndReturn(run func(ctx context.Context, sequence uint64) error) *Service_Restore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Result provides a mock function for the type Service
 func (_mock *Service) Result(ctx context.Context) ([]byte, error) {
 	ret := _mock.Called(ctx)
@@ -493,6 +716,120 @@ func (_c *Service_Result_Call) RunAndReturn(run func(ctx context.Context) ([]byt
 	return _c
 }
 
+// Secrets provides a mock function for the type Service
+func (_mock *Service) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error {
+	ret := _mock.Called(ctx, secrets, signature)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Secrets")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []agent.Secret, []byte) error); ok {
+		r0 = returnFunc(ctx, secrets, signature)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_Secrets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Secrets'
+type Service_Secrets_Call struct {
+	*mock.Call
+}
+
+// Secrets is a helper method to define mock.On call
+//   - ctx context.Context
+//   - secrets []agent.Secret
+//   - signature []byte
+func (_e *Service_Expecter) Secrets(ctx interface{}, secrets interface{}, signature interface{}) *Service_Secrets_Call {
+	return &Service_Secrets_Call{Call: _e.mock.On("Secrets", ctx, secrets, signature)}
+}
+
+func (_c *Service_Secrets_Call) Run(run func(ctx context.Context, secrets []agent.Secret, signature []byte)) *Service_Secrets_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []agent.Secret
+		if args[1] != nil {
+			arg1 = args[1].([]agent.Secret)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Secrets_Call) Return(err error) *Service_Secrets_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_Secrets_Call) RunAndReturn(run func(ctx context.Context, secrets []agent.Secret, signature []byte) error) *Service_Secrets_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Shutdown provides a mock function for the type Service
+func (_mock *Service) Shutdown(ctx context.Context) error {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_Shutdown_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Shutdown'
+type Service_Shutdown_Call struct {
+	*mock.Call
+}
+
+// Shutdown is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Service_Expecter) Shutdown(ctx interface{}) *Service_Shutdown_Call {
+	return &Service_Shutdown_Call{Call: _e.mock.On("Shutdown", ctx)}
+}
+
+func (_c *Service_Shutdown_Call) Run(run func(ctx context.Context)) *Service_Shutdown_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Shutdown_Call) Return(err error) *Service_Shutdown_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_Shutdown_Call) RunAndReturn(run func(ctx context.Context) error) *Service_Shutdown_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // State provides a mock function for the type Service
 func (_mock *Service) State() string {
 	ret := _mock.Called()
@@ -0,0 +1,217 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/cgroup"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+const (
+	resourceMemory = "memory"
+	resourceDisk   = "disk"
+)
+
+var (
+	// ErrInvalidResources indicates manifest resource limits that cannot be applied.
+	ErrInvalidResources = errors.New("invalid algorithm resource limits")
+	// ErrResourcesUnsupported indicates resource limits for an algorithm type that does not run as a process.
+	ErrResourcesUnsupported = errors.New("resource limits are only supported for bin and python algorithms")
+	// ErrResourceExceeded indicates the algorithm was terminated for exceeding its resource limits.
+	ErrResourceExceeded = errors.New("algorithm exceeded its resource limits")
+)
+
+// cgroupNameLen bounds the part of the cgroup name taken from the computation ID.
+const cgroupNameLen = 32
+
+// newResultsStorage opens the tmpfs the results directory is mounted on to
+// bound its size, it is a variable so tests can run without privileges.
+var newResultsStorage = func(sizeMB uint64) (storage.Storage, error) {
+	return storage.New(storage.Tmpfs, sizeMB)
+}
+
+// resourceDetails are the details of the ResourceExceeded event.
+type resourceDetails struct {
+	Resource string `json:"resource"`
+	LimitMB  uint64 `json:"limit_mb"`
+	OOMKills uint64 `json:"oom_kills,omitempty"`
+}
+
+// validateResources checks the manifest resource limits can be applied to the
+// algorithm, which must be declared as a bin or python algorithm. Wasm modules
+// run inside the agent and docker containers in the docker daemon, outside of
+// the cgroup and results tmpfs the limits are enforced with.
+func validateResources(cmp Computation) error {
+	res := cmp.Algorithm.Resources
+	if res == nil || *res == (Resources{}) {
+		return nil
+	}
+
+	if t := cmp.Algorithm.Type; t != algorithm.AlgoTypeBin && t != algorithm.AlgoTypePython {
+		return errors.Wrap(ErrResourcesUnsupported, fmt.Errorf("algorithm type %q", t))
+	}
+	if cmp.Algorithm.WasmLimits != nil {
+		return errors.Wrap(ErrResourcesUnsupported, fmt.Errorf("wasm limits are set"))
+	}
+
+	if err := cgroupLimits(res).Validate(); err != nil {
+		return errors.Wrap(ErrInvalidResources, err)
+	}
+
+	return nil
+}
+
+func cgroupLimits(res *Resources) cgroup.Limits {
+	return cgroup.Limits{CPUs: res.CPUs, MemoryBytes: res.MemoryMB << 20}
+}
+
+// cgroupName returns the name of the cgroup of the computation. The ID comes
+// from the manifest, so only its path safe characters are kept and a hash of
+// it keeps the names of distinct IDs apart.
+func cgroupName(id string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, id)
+	if len(safe) > cgroupNameLen {
+		safe = safe[:cgroupNameLen]
+	}
+
+	sum := sha256.Sum256([]byte(id))
+
+	return fmt.Sprintf("%s-%x", safe, sum[:6])
+}
+
+// newCgroup creates the cgroup the algorithm processes run in, or returns nil
+// if the manifest sets no CPU or memory limit.
+func (as *agentService) newCgroup(algoType string) (*cgroup.Group, error) {
+	res := as.computation.Algorithm.Resources
+	if res == nil || *res == (Resources{}) {
+		return nil, nil
+	}
+
+	// Validated manifests pin the type already, this guards restored journals.
+	if algoType != string(algorithm.AlgoTypeBin) && algoType != string(algorithm.AlgoTypePython) {
+		return nil, errors.Wrap(ErrResourcesUnsupported, fmt.Errorf("%s algorithm", algoType))
+	}
+
+	if res.CPUs == 0 && res.MemoryMB == 0 {
+		return nil, nil
+	}
+
+	return cgroup.New(cgroupName(as.computation.ID), cgroupLimits(res))
+}
+
+// openResultsStorage opens the tmpfs that bounds the results directory to the
+// manifest disk limit, or returns nil if it sets none.
+func openResultsStorage(cmp Computation) (storage.Storage, error) {
+	res := cmp.Algorithm.Resources
+	if res == nil || res.DiskMB == 0 {
+		return nil, nil
+	}
+
+	return newResultsStorage(res.DiskMB)
+}
+
+// resultsStorage returns the storage of the results directory, the tmpfs of
+// the disk limit if the manifest sets one. It must be called with the service
+// mutex held.
+func (as *agentService) resultsStorage() storage.Storage {
+	if as.resultStore != nil {
+		return as.resultStore
+	}
+
+	return as.dataStorage()
+}
+
+// wipeResults shreds the results and removes the results directory. It must be
+// called with the service mutex held.
+func (as *agentService) wipeResults() error {
+	dir := as.sandbox.Results()
+	if err := algorithm.Shred(dir); err != nil {
+		return err
+	}
+
+	return as.resultsStorage().Remove(dir)
+}
+
+// resourcesExceeded reports whether the algorithm that exited filled the
+// results tmpfs of its disk limit, the kernel failing its writes past the
+// limit, or had processes killed for exceeding its memory limit.
+func (as *agentService) resourcesExceeded() bool {
+	res := as.computation.Algorithm.Resources
+	if res == nil {
+		return false
+	}
+
+	cmpID := as.computation.ID
+	exceeded := false
+
+	if res.DiskMB > 0 {
+		free, err := storageFree(as.sandbox.Results())
+		switch {
+		case err != nil:
+			as.logger.Warn("failed to read results directory usage", "computation", cmpID, "error", err)
+		case free == 0:
+			exceeded = true
+			as.reportResourceExceeded(resourceDetails{Resource: resourceDisk, LimitMB: res.DiskMB})
+		}
+	}
+
+	if as.cgroup != nil && res.MemoryMB > 0 {
+		kills, err := as.cgroup.OOMKills()
+		switch {
+		case err != nil:
+			as.logger.Warn("failed to read algorithm memory events", "computation", cmpID, "error", err)
+		case kills > 0:
+			exceeded = true
+			as.reportResourceExceeded(resourceDetails{Resource: resourceMemory, LimitMB: res.MemoryMB, OOMKills: kills})
+		}
+	}
+
+	return exceeded
+}
+
+func (as *agentService) reportResourceExceeded(details resourceDetails) {
+	as.logger.Warn("algorithm exceeded its resource limit", "computation", as.computation.ID, "resource", details.Resource, "limit_mb", details.LimitMB)
+
+	raw, _ := json.Marshal(details)
+	as.eventSvc.SendEvent(as.computation.ID, events.ResourceExceeded, Terminated.String(), raw)
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,212 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+func TestValidateResources(t *testing.T) {
+	cases := []struct {
+		desc       string
+		resources  *Resources
+		algoType   algorithm.AlgorithType
+		wasmLimits *WasmLimits
+		err        error
+	}{
+		{
+			desc: "no resources",
+		},
+		{
+			desc:      "no limits without a declared type",
+			resources: &Resources{},
+		},
+		{
+			desc:      "valid resources",
+			resources: &Resources{CPUs: 0.5, MemoryMB: 512, DiskMB: 1024},
+			algoType:  algorithm.AlgoTypeBin,
+		},
+		{
+			desc:      "python algorithm",
+			resources: &Resources{DiskMB: 1024},
+			algoType:  algorithm.AlgoTypePython,
+		},
+		{
+			desc:      "undeclared algorithm type",
+			resources: &Resources{MemoryMB: 512},
+			err:       ErrResourcesUnsupported,
+		},
+		{
+			desc:      "wasm algorithm",
+			resources: &Resources{MemoryMB: 512},
+			algoType:  algorithm.AlgoTypeWasm,
+			err:       ErrResourcesUnsupported,
+		},
+		{
+			desc:      "docker algorithm",
+			resources: &Resources{DiskMB: 512},
+			algoType:  algorithm.AlgoTypeDocker,
+			err:       ErrResourcesUnsupported,
+		},
+		{
+			desc:       "wasm limits",
+			resources:  &Resources{CPUs: 1},
+			algoType:   algorithm.AlgoTypeBin,
+			wasmLimits: &WasmLimits{MaxMemoryMB: 64},
+			err:        ErrResourcesUnsupported,
+		},
+		{
+			desc:      "negative cpus",
+			resources: &Resources{CPUs: -2},
+			algoType:  algorithm.AlgoTypeBin,
+			err:       ErrInvalidResources,
+		},
+		{
+			desc:      "cpus below the minimum quota",
+			resources: &Resources{CPUs: 0.001},
+			algoType:  algorithm.AlgoTypeBin,
+			err:       ErrInvalidResources,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateResources(Computation{Algorithm: Algorithm{Resources: tc.resources, Type: tc.algoType, WasmLimits: tc.wasmLimits}})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestCgroupName(t *testing.T) {
+	for _, id := range []string{"cmp", "../../memory", "a/b", "..", "", "ünïcode id", strings.Repeat("x", 100)} {
+		name := cgroupName(id)
+		assert.Regexp(t, `^[A-Za-z0-9_-]{0,32}-[0-9a-f]{12}$`, name, id)
+	}
+
+	assert.NotEqual(t, cgroupName("a/b"), cgroupName("a_b"), "distinct IDs get distinct names")
+	assert.Equal(t, cgroupName("cmp"), cgroupName("cmp"))
+}
+
+func TestNewCgroupWithoutLimits(t *testing.T) {
+	cases := []struct {
+		desc      string
+		resources *Resources
+		algoType  algorithm.AlgorithType
+		err       error
+	}{
+		{
+			desc:     "no resources",
+			algoType: algorithm.AlgoTypeWasm,
+		},
+		{
+			desc:      "disk limit only",
+			resources: &Resources{DiskMB: 10},
+			algoType:  algorithm.AlgoTypeBin,
+		},
+		{
+			desc:      "wasm algorithm",
+			resources: &Resources{MemoryMB: 10},
+			algoType:  algorithm.AlgoTypeWasm,
+			err:       ErrResourcesUnsupported,
+		},
+		{
+			desc:      "docker algorithm",
+			resources: &Resources{DiskMB: 10},
+			algoType:  algorithm.AlgoTypeDocker,
+			err:       ErrResourcesUnsupported,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			as := &agentService{computation: Computation{ID: "cmp", Algorithm: Algorithm{Resources: tc.resources}}}
+
+			group, err := as.newCgroup(string(tc.algoType))
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Nil(t, group)
+		})
+	}
+}
+
+func TestResourcesExceeded(t *testing.T) {
+	cases := []struct {
+		desc      string
+		resources *Resources
+		free      uint64
+		exceeded  bool
+	}{
+		{
+			desc: "no resources",
+		},
+		{
+			desc:      "results within the disk limit",
+			resources: &Resources{DiskMB: 4},
+			free:      2 << 20,
+		},
+		{
+			desc:      "results filled the disk limit",
+			resources: &Resources{DiskMB: 1},
+			exceeded:  true,
+		},
+	}
+
+	orig := storageFree
+	t.Cleanup(func() { storageFree = orig })
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			storageFree = func(string) (uint64, error) { return tc.free, nil }
+
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "cmp", events.ResourceExceeded, Terminated.String(), mock.Anything).Return()
+
+			as := &agentService{
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: Computation{ID: "cmp", Algorithm: Algorithm{Resources: tc.resources}},
+			}
+
+			assert.Equal(t, tc.exceeded, as.resourcesExceeded())
+			if !tc.exceeded {
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			raw := eventSvc.Calls[0].Arguments.Get(3).(json.RawMessage)
+			var details resourceDetails
+			require.NoError(t, json.Unmarshal(raw, &details))
+			assert.Equal(t, resourceDetails{Resource: resourceDisk, LimitMB: 1}, details)
+		})
+	}
+}
+
+func TestResultsStorage(t *testing.T) {
+	var sizes []uint64
+	orig := newResultsStorage
+	newResultsStorage = func(sizeMB uint64) (storage.Storage, error) {
+		sizes = append(sizes, sizeMB)
+		return storage.NewDisk(), nil
+	}
+	t.Cleanup(func() { newResultsStorage = orig })
+
+	st, err := openResultsStorage(Computation{Algorithm: Algorithm{Resources: &Resources{MemoryMB: 64}}})
+	require.NoError(t, err)
+	assert.Nil(t, st, "no tmpfs without a disk limit")
+
+	st, err = openResultsStorage(Computation{Algorithm: Algorithm{Resources: &Resources{DiskMB: 16}}})
+	require.NoError(t, err)
+	assert.NotNil(t, st)
+	assert.Equal(t, []uint64{16}, sizes, "the results tmpfs is sized to the disk limit")
+}
@@ -0,0 +1,345 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/internal"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"golang.org/x/crypto/sha3"
+)
+
+// ResultManifestFile is the name of the signed result manifest the agent adds
+// to the root of the result archive.
+const ResultManifestFile = "cocos-result-manifest.json"
+
+var (
+	// ErrResultArchive indicates the result archive could not be read.
+	ErrResultArchive = errors.New("failed to read result archive")
+	// ErrResultManifestSignature indicates the result manifest is not signed by the agent key.
+	ErrResultManifestSignature = errors.New("result manifest is not signed by the agent key")
+	// ErrResultManifestNotFound indicates a result archive without a result manifest.
+	ErrResultManifestNotFound = errors.New("result archive has no result manifest")
+)
+
+// ResultFile is the SHA3-256 hash and the size of a file in the result archive.
+type ResultFile struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// ResultManifest lists the files of a computation result archive with their
+// hashes, signed by the agent so the result can be verified after download.
+type ResultManifest struct {
+	ComputationID string `json:"computation_id"`
+	// Codec is the compression codec of the result archive.
+	Codec string       `json:"codec,omitempty"`
+	Files []ResultFile `json:"files"`
+	// Lineage records the inputs of the computation, read from the LineageFile of the archive.
+	Lineage *Lineage `json:"lineage,omitempty"`
+	// SigningKey is the PKIX, DER encoded public key the manifest is signed with,
+	// generated by the agent in the CVM.
+	SigningKey []byte `json:"signing_key,omitempty"`
+	// Attestation binds the signing key to the CVM the result was computed in.
+	Attestation *ResultAttestation `json:"attestation,omitempty"`
+	Signature   []byte             `json:"signature,omitempty"`
+}
+
+// ResultAttestation is an attestation report of the CVM whose report data is
+// the ResultReportData of the signing key of the result manifest.
+type ResultAttestation struct {
+	Platform attestation.PlatformType `json:"platform"`
+	Report   []byte                   `json:"report"`
+}
+
+// ResultReportData returns the report data that binds the signing key of a
+// result manifest to the computation, hashed the way attested TLS binds
+// certificate keys to a nonce.
+func ResultReportData(signingKey []byte, computationID string) [64]byte {
+	data := make([]byte, 0, len(signingKey)+len(computationID))
+	data = append(data, signingKey...)
+	data = append(data, computationID...)
+
+	return sha3.Sum512(data)
+}
+
+// NewResultManifest hashes every file of the zipped result archive and records
+// its codec and, when the archive holds one, the lineage written by the agent.
+func NewResultManifest(cmpID string, archive []byte) (ResultManifest, error) {
+	files, err := HashResultArchive(archive)
+	if err != nil {
+		return ResultManifest{}, err
+	}
+
+	codec, err := resultArchiveCodec(archive)
+	if err != nil {
+		return ResultManifest{}, err
+	}
+
+	lineage, err := resultArchiveLineage(archive)
+	if err != nil {
+		return ResultManifest{}, err
+	}
+
+	sizes, err := resultArchiveSizes(archive)
+	if err != nil {
+		return ResultManifest{}, err
+	}
+
+	manifest := ResultManifest{ComputationID: cmpID, Codec: codec, Lineage: lineage}
+	for path, hash := range files {
+		manifest.Files = append(manifest.Files, ResultFile{Path: path, Hash: hash, Size: sizes[path]})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	return manifest, nil
+}
+
+// HashResultArchive returns the hex encoded SHA3-256 hash of every file of a
+// zip archive but the result manifest, keyed by path.
+func HashResultArchive(archive []byte) (map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	files := make(map[string]string, len(reader.File))
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || f.Name == ResultManifestFile {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrap(ErrResultArchive, err)
+		}
+
+		hash := sha3.New256()
+		_, err = io.Copy(hash, rc)
+		rc.Close()
+		if err != nil {
+			return nil, errors.Wrap(ErrResultArchive, err)
+		}
+
+		files[f.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return files, nil
+}
+
+// resultArchiveCodec returns the codec of the first compressed file of a zip archive.
+func resultArchiveCodec(archive []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", errors.Wrap(ErrResultArchive, err)
+	}
+
+	for _, f := range reader.File {
+		if codec := internal.CodecForMethod(f.Method); codec != "" {
+			return codec, nil
+		}
+	}
+
+	return "", nil
+}
+
+// resultArchiveLineage returns the lineage of a result archive, or nil if it has none.
+func resultArchiveLineage(archive []byte) (*Lineage, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	f, err := reader.Open(LineageFile)
+	switch {
+	case errors.Contains(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+	defer f.Close()
+
+	var lineage Lineage
+	if err := json.NewDecoder(f).Decode(&lineage); err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	return &lineage, nil
+}
+
+// resultArchiveSizes returns the uncompressed size of every file of the archive, keyed by path.
+func resultArchiveSizes(archive []byte) (map[string]int64, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	sizes := make(map[string]int64, len(reader.File))
+	for _, f := range reader.File {
+		sizes[f.Name] = int64(f.UncompressedSize64)
+	}
+
+	return sizes, nil
+}
+
+// ReadResultManifest returns the result manifest the agent added to a result archive.
+func ReadResultManifest(archive []byte) (ResultManifest, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return ResultManifest{}, errors.Wrap(ErrResultArchive, err)
+	}
+
+	f, err := reader.Open(ResultManifestFile)
+	switch {
+	case errors.Contains(err, fs.ErrNotExist):
+		return ResultManifest{}, ErrResultManifestNotFound
+	case err != nil:
+		return ResultManifest{}, errors.Wrap(ErrResultArchive, err)
+	}
+	defer f.Close()
+
+	var manifest ResultManifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return ResultManifest{}, errors.Wrap(ErrResultArchive, err)
+	}
+
+	return manifest, nil
+}
+
+// addResultManifest returns the result archive with the manifest added to its
+// root, replacing any algorithm output with that name. The other files are
+// copied without being recompressed.
+func addResultManifest(archive []byte, manifest ResultManifest) ([]byte, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range reader.File {
+		if f.Name == ResultManifestFile {
+			continue
+		}
+		if err := w.Copy(f); err != nil {
+			return nil, errors.Wrap(ErrResultArchive, err)
+		}
+	}
+
+	fw, err := w.CreateHeader(&zip.FileHeader{Name: ResultManifestFile, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(ErrResultArchive, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SigningBytes returns the JSON encoding of the result manifest without its signature.
+func (m ResultManifest) SigningBytes() ([]byte, error) {
+	m.Signature = nil
+	return json.Marshal(m)
+}
+
+// SignResultManifest signs the result manifest with an Ed25519 or ECDSA private key.
+func SignResultManifest(m ResultManifest, key crypto.Signer) ([]byte, error) {
+	data, err := m.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return sign(data, key)
+}
+
+// VerifyResultManifest checks that the result manifest is signed by the agent key.
+func VerifyResultManifest(m ResultManifest, key crypto.PublicKey) error {
+	if len(m.Signature) == 0 {
+		return ErrResultManifestSignature
+	}
+
+	data, err := m.SigningBytes()
+	if err != nil {
+		return err
+	}
+
+	if !verifySignature(data, m.Signature, []crypto.PublicKey{key}) {
+		return ErrResultManifestSignature
+	}
+
+	return nil
+}
+
+// ccPlatform returns the confidential computing platform the results are attested on.
+var ccPlatform = attestation.CCPlatform
+
+// signResults adds a manifest of the result archive to it, signed with a key
+// generated for the computation and bound to the CVM by an attestation report.
+// Results are left unsigned on platforms without attested TLS support, whose
+// reports the CLI cannot verify.
+func (as *agentService) signResults(ctx context.Context, archive []byte) ([]byte, error) {
+	platform := ccPlatform()
+	switch platform {
+	case attestation.SNPvTPM, attestation.Azure, attestation.TDX:
+	default:
+		as.logger.Debug("results are not signed outside of an attested CVM")
+		return archive, nil
+	}
+
+	manifest, err := NewResultManifest(as.computation.ID, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key only lives for this function, nothing but the attested agent can sign with it.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	manifest.SigningKey, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	reportData := ResultReportData(manifest.SigningKey, manifest.ComputationID)
+	report, err := as.attestationClient.GetAttestation(ctx, reportData, [vtpm.Nonce]byte(reportData[:vtpm.Nonce]), platform)
+	if err != nil {
+		return nil, errors.Wrap(ErrAttestationFailed, err)
+	}
+	manifest.Attestation = &ResultAttestation{Platform: platform, Report: report}
+
+	manifest.Signature, err = SignResultManifest(manifest, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return addResultManifest(archive, manifest)
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/internal"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"golang.org/x/crypto/sha3"
+)
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	_, err := w.Create("results/empty/")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestNewResultManifest(t *testing.T) {
+	archive := zipFiles(t, map[string]string{"results/b.csv": "b", "results/a.csv": "a"})
+
+	manifest, err := NewResultManifest("cmp1", archive)
+	require.NoError(t, err)
+
+	hash := func(s string) string {
+		sum := sha3.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	assert.Equal(t, "cmp1", manifest.ComputationID)
+	assert.Equal(t, []ResultFile{
+		{Path: "results/a.csv", Hash: hash("a"), Size: 1},
+		{Path: "results/b.csv", Hash: hash("b"), Size: 1},
+	}, manifest.Files)
+
+	assert.Equal(t, internal.CodecDeflate, manifest.Codec)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("a"), 0o644))
+	compressed, err := internal.ZipDirectoryParallel(dir, internal.CodecZstd, 0)
+	require.NoError(t, err)
+	manifest, err = NewResultManifest("cmp1", compressed)
+	require.NoError(t, err)
+	assert.Equal(t, internal.CodecZstd, manifest.Codec)
+	assert.Equal(t, []ResultFile{{Path: "a.csv", Hash: hash("a"), Size: 1}}, manifest.Files)
+
+	_, err = NewResultManifest("cmp1", []byte("not a zip"))
+	assert.True(t, errors.Contains(err, ErrResultArchive))
+}
+
+func TestNewResultManifestLineage(t *testing.T) {
+	lineage := newLineage(Computation{ID: "cmp1", Datasets: Datasets{{Filename: "a.csv"}}})
+	lineage.datasetReceived(0, DatasetUploaded, [32]byte{1})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("a"), 0o644))
+	require.NoError(t, writeLineage(dir, lineage))
+	archive, err := internal.ZipDirectoryParallel(dir, internal.CodecDeflate, 0)
+	require.NoError(t, err)
+
+	manifest, err := NewResultManifest("cmp1", archive)
+	require.NoError(t, err)
+	require.NotNil(t, manifest.Lineage)
+	assert.Equal(t, lineage.Datasets[0].ReceivedAt.Unix(), manifest.Lineage.Datasets[0].ReceivedAt.Unix())
+	assert.Equal(t, DatasetUploaded, manifest.Lineage.Datasets[0].Source)
+	assert.Len(t, manifest.Files, 2, "the lineage file is part of the result")
+
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	manifest.Signature, err = SignResultManifest(manifest, edPriv)
+	require.NoError(t, err)
+
+	tampered := manifest
+	tampered.Lineage = &Lineage{ComputationID: "cmp1"}
+	err = VerifyResultManifest(tampered, edPriv.Public())
+	assert.True(t, errors.Contains(err, ErrResultManifestSignature), "the signature covers the lineage")
+
+	manifest, err = NewResultManifest("cmp1", zipFiles(t, map[string]string{"results/a.csv": "a"}))
+	require.NoError(t, err)
+	assert.Nil(t, manifest.Lineage)
+
+	_, err = NewResultManifest("cmp1", zipFiles(t, map[string]string{LineageFile: "not json"}))
+	assert.True(t, errors.Contains(err, ErrResultArchive))
+}
+
+func TestSignAndVerifyResultManifest(t *testing.T) {
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	manifest, err := NewResultManifest("cmp1", zipFiles(t, map[string]string{"results/a.csv": "a"}))
+	require.NoError(t, err)
+
+	unsigned := manifest
+	assert.True(t, errors.Contains(VerifyResultManifest(unsigned, &ecPriv.PublicKey), ErrResultManifestSignature))
+
+	manifest.Signature, err = SignResultManifest(manifest, ecPriv)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyResultManifest(manifest, &ecPriv.PublicKey))
+	assert.True(t, errors.Contains(VerifyResultManifest(manifest, edPub), ErrResultManifestSignature))
+
+	manifest.Signature, err = SignResultManifest(manifest, edPriv)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyResultManifest(manifest, edPub))
+
+	manifest.Files[0].Hash = "tampered"
+	assert.True(t, errors.Contains(VerifyResultManifest(manifest, edPub), ErrResultManifestSignature))
+}
+
+func TestSignResults(t *testing.T) {
+	t.Cleanup(func() { ccPlatform = attestation.CCPlatform })
+
+	archive := zipFiles(t, map[string]string{"results/a.csv": "a", ResultManifestFile: "forged by the algorithm"})
+
+	cases := []struct {
+		desc     string
+		platform attestation.PlatformType
+		report   []byte
+		err      error
+		signed   bool
+	}{
+		{
+			desc:     "attested CVM",
+			platform: attestation.SNPvTPM,
+			report:   []byte("report"),
+			signed:   true,
+		},
+		{
+			desc:     "attestation failure",
+			platform: attestation.TDX,
+			err:      ErrAttestationFailed,
+		},
+		{
+			desc:     "no confidential computing",
+			platform: attestation.NoCC,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ccPlatform = func() attestation.PlatformType { return tc.platform }
+
+			client := new(MockAttestationClient)
+			var attestErr error
+			if tc.err != nil {
+				attestErr = errors.New("attestation service unavailable")
+			}
+			client.On("GetAttestation", mock.Anything, mock.Anything, mock.Anything, tc.platform).Return(tc.report, attestErr)
+
+			svc := &agentService{computation: Computation{ID: "cmp1"}, attestationClient: client, logger: mglog.NewMock()}
+
+			signed, err := svc.signResults(context.Background(), archive)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+
+			if !tc.signed {
+				assert.Equal(t, archive, signed)
+				client.AssertNotCalled(t, "GetAttestation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			manifest, err := ReadResultManifest(signed)
+			require.NoError(t, err)
+			sum := sha3.Sum256([]byte("a"))
+			assert.Equal(t, []ResultFile{{Path: "results/a.csv", Hash: hex.EncodeToString(sum[:]), Size: 1}}, manifest.Files, "the manifest does not list itself")
+			assert.Equal(t, &ResultAttestation{Platform: tc.platform, Report: tc.report}, manifest.Attestation)
+
+			key, err := x509.ParsePKIXPublicKey(manifest.SigningKey)
+			require.NoError(t, err)
+			assert.NoError(t, VerifyResultManifest(manifest, key))
+
+			reportData := ResultReportData(manifest.SigningKey, "cmp1")
+			client.AssertCalled(t, "GetAttestation", mock.Anything, reportData, [vtpm.Nonce]byte(reportData[:vtpm.Nonce]), tc.platform)
+
+			files, err := HashResultArchive(signed)
+			require.NoError(t, err)
+			original, err := HashResultArchive(archive)
+			require.NoError(t, err)
+			assert.Equal(t, original, files, "results are copied unchanged")
+		})
+	}
+}
+
+func TestReadResultManifest(t *testing.T) {
+	_, err := ReadResultManifest(zipFiles(t, map[string]string{"results/a.csv": "a"}))
+	assert.True(t, errors.Contains(err, ErrResultManifestNotFound))
+
+	_, err = ReadResultManifest(zipFiles(t, map[string]string{ResultManifestFile: "not json"}))
+	assert.True(t, errors.Contains(err, ErrResultArchive))
+
+	_, err = ReadResultManifest([]byte("not a zip"))
+	assert.True(t, errors.Contains(err, ErrResultArchive))
+}
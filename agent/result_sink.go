@@ -0,0 +1,170 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/sink"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+	"golang.org/x/crypto/sha3"
+)
+
+// resultObjectName is the name of the uploaded results under the computation ID.
+const resultObjectName = "results.zip.enc"
+
+var (
+	// ErrInvalidResultSink indicates a result sink the agent cannot upload to.
+	ErrInvalidResultSink = errors.New("invalid result sink")
+	// ErrResultUpload indicates results the result sink did not accept.
+	ErrResultUpload = errors.New("failed to upload results")
+
+	// resultUploadAttempts, resultUploadBackoff and resultSinkClient are variables so tests can upload faster.
+	resultUploadAttempts = 3
+	resultUploadBackoff  = 5 * time.Second
+	resultSinkClient     = &http.Client{Timeout: time.Hour}
+)
+
+// resultUpload describes the results uploaded to the result sink.
+type resultUpload struct {
+	URL string
+	// Digest is the hex encoded SHA3-256 hash of the uploaded object.
+	Digest string
+	Size   int
+}
+
+// validateResultSink checks that the manifest result sink can be uploaded to
+// and that the secrets holding its credentials can be provisioned.
+func validateResultSink(cmp Computation) error {
+	rs := cmp.ResultSink
+	if rs == nil {
+		return nil
+	}
+
+	if err := resultSinkConfig(rs, sink.Credentials{}).Validate(); err != nil {
+		return errors.Wrap(ErrInvalidResultSink, err)
+	}
+	if _, err := encryption.ParsePublicKey(rs.EncryptionKey); err != nil {
+		return errors.Wrap(ErrInvalidResultSink, fmt.Errorf("encryption key: %w", err))
+	}
+
+	if rs.AccessKeySecret == "" && rs.SecretKeySecret == "" {
+		return nil
+	}
+	for _, name := range []string{rs.AccessKeySecret, rs.SecretKeySecret} {
+		if !secretNameRegexp.MatchString(name) {
+			return errors.Wrap(ErrInvalidResultSink, fmt.Errorf("credentials secret name %q", name))
+		}
+	}
+	if cmp.AttestationApproval == nil {
+		return errors.Wrap(ErrInvalidResultSink, errors.New("credentials secrets require an attestation approval key"))
+	}
+
+	return nil
+}
+
+func resultSinkConfig(rs *ResultSink, creds sink.Credentials) sink.Config {
+	return sink.Config{
+		Type:        rs.Type,
+		Endpoint:    rs.Endpoint,
+		Region:      rs.Region,
+		Bucket:      rs.Bucket,
+		Credentials: creds,
+	}
+}
+
+// newResultSink returns the sink of the manifest, nil if it has none, with
+// the credentials read from the owner secrets. It is called before the
+// algorithm runs, so that the algorithm cannot change the credentials.
+func (as *agentService) newResultSink() (sink.Sink, error) {
+	rs := as.computation.ResultSink
+	if rs == nil {
+		return nil, nil
+	}
+
+	var creds sink.Credentials
+	if rs.AccessKeySecret != "" {
+		var err error
+		if creds.AccessKeyID, err = as.readSecret(rs.AccessKeySecret); err != nil {
+			return nil, errors.Wrap(ErrInvalidResultSink, err)
+		}
+		if creds.SecretAccessKey, err = as.readSecret(rs.SecretKeySecret); err != nil {
+			return nil, errors.Wrap(ErrInvalidResultSink, err)
+		}
+	}
+
+	s, err := sink.New(resultSinkConfig(rs, creds), resultSinkClient)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResultSink, err)
+	}
+
+	return s, nil
+}
+
+// readSecret returns the value of the provisioned secret, whether it is
+// exposed as a file or as an environment variable.
+func (as *agentService) readSecret(name string) (string, error) {
+	for _, dir := range []string{as.sandbox.SecretFiles(), as.sandbox.SecretEnv()} {
+		value, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return strings.TrimSpace(string(value)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("secret %s is not provisioned", name)
+}
+
+// uploadResults encrypts the results for the computation owner and uploads
+// them to the result sink, retrying failed uploads.
+func (as *agentService) uploadResults(ctx context.Context, s sink.Sink, results []byte) (*resultUpload, error) {
+	rs := as.computation.ResultSink
+
+	// The key was validated when the manifest was received.
+	pub, err := encryption.ParsePublicKey(rs.EncryptionKey)
+	if err != nil {
+		return nil, errors.Wrap(ErrResultEncryption, err)
+	}
+	encrypted, err := encryption.Encrypt(pub, results)
+	if err != nil {
+		return nil, errors.Wrap(ErrResultEncryption, err)
+	}
+
+	key := rs.Key
+	if key == "" {
+		key = as.computation.ID + "/" + resultObjectName
+	}
+
+	var url string
+	for attempt := 1; ; attempt++ {
+		if url, err = s.Upload(ctx, key, encrypted); err == nil {
+			break
+		}
+		if attempt == resultUploadAttempts {
+			return nil, errors.Wrap(ErrResultUpload, err)
+		}
+
+		as.logger.Warn("result upload failed, retrying", "computation", as.computation.ID, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ErrResultUpload, ctx.Err())
+		case <-time.After(time.Duration(attempt) * resultUploadBackoff):
+		}
+	}
+
+	digest := sha3.Sum256(encrypted)
+	upload := &resultUpload{URL: url, Digest: hex.EncodeToString(digest[:]), Size: len(encrypted)}
+	as.logger.Info("results uploaded", "computation", as.computation.ID, "url", upload.URL, "digest", upload.Digest, "size", upload.Size)
+
+	return upload, nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+)
+
+// ErrRunTimeout indicates the algorithm was stopped once it ran for the manifest max runtime.
+var ErrRunTimeout = errors.New("algorithm exceeded its max runtime")
+
+// limitRuntime runs the algorithm under a deadline of the manifest max runtime
+// and stops it once the deadline expires. The returned function releases the
+// deadline and reports whether it expired.
+func (as *agentService) limitRuntime(alg algorithm.Algorithm) func() bool {
+	// The max runtime was validated when the manifest was received.
+	maxRuntime, _ := algorithmMaxRuntime(as.computation)
+	if maxRuntime == 0 {
+		return func() bool { return false }
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxRuntime)
+	done := make(chan struct{})
+	cmpID := as.computation.ID
+
+	var expired atomic.Bool
+	go func() {
+		defer close(done)
+
+		<-ctx.Done()
+		if !errors.Contains(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+
+		expired.Store(true)
+		as.logger.Warn("algorithm exceeded its max runtime", "computation", cmpID, "max_runtime", maxRuntime.String())
+
+		details, _ := json.Marshal(map[string]string{"max_runtime": maxRuntime.String()})
+		as.eventSvc.SendEvent(cmpID, events.RunTimedOut, Terminated.String(), details)
+
+		if err := alg.Stop(); err != nil {
+			as.logger.Warn("failed to stop timed out algorithm", "computation", cmpID, "error", err)
+		}
+	}()
+
+	return func() bool {
+		cancel()
+		<-done
+
+		return expired.Load()
+	}
+}
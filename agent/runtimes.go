@@ -0,0 +1,73 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/python"
+)
+
+const wazeroModule = "github.com/tetratelabs/wazero"
+
+// pythonVersion returns the version of a python interpreter, it is replaced in tests.
+var pythonVersion = python.Version
+
+// AlgorithmRuntimes returns the algorithm runtimes of the agent, advertised
+// in its capabilities. Python is only listed when its default interpreter is
+// installed.
+var AlgorithmRuntimes = sync.OnceValue(func() []*AlgorithmRuntime {
+	runtimes := []*AlgorithmRuntime{{Type: AlgorithmType_ALGORITHM_TYPE_BINARY}}
+
+	if version, err := pythonVersion(python.PyRuntime); err == nil {
+		runtimes = append(runtimes, &AlgorithmRuntime{Type: AlgorithmType_ALGORITHM_TYPE_PYTHON, Version: version})
+	}
+
+	return append(runtimes,
+		&AlgorithmRuntime{Type: AlgorithmType_ALGORITHM_TYPE_WASM, Version: moduleVersion(wazeroModule)},
+		&AlgorithmRuntime{Type: AlgorithmType_ALGORITHM_TYPE_DOCKER},
+	)
+})
+
+// checkRuntime checks that the runtime of the agent is at least the version the spec requires.
+func checkRuntime(spec algorithm.Spec) error {
+	if spec.MinRuntimeVersion == "" {
+		return nil
+	}
+
+	runtime := spec.Runtime
+	if runtime == "" {
+		runtime = python.PyRuntime
+	}
+
+	version, err := pythonVersion(runtime)
+	if err != nil {
+		return errors.Wrap(ErrUnsupportedRuntime, err)
+	}
+
+	if !algorithm.VersionAtLeast(version, spec.MinRuntimeVersion) {
+		return errors.Wrap(ErrUnsupportedRuntime, fmt.Errorf("%s is version %s, the algorithm requires %s or newer", runtime, version, spec.MinRuntimeVersion))
+	}
+
+	return nil
+}
+
+// moduleVersion returns the version of a module the agent is built with.
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			return dep.Version
+		}
+	}
+
+	return ""
+}
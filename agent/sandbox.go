@@ -0,0 +1,85 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+)
+
+const (
+	// sandboxDirPermission keeps the directories of the sandbox private to the agent.
+	sandboxDirPermission = 0o700
+
+	algoFileName         = "algorithm"
+	requirementsFileName = "requirements.txt"
+)
+
+// sandboxesDir holds the sandbox of every computation, relative to the agent
+// working directory.
+var sandboxesDir = "computations"
+
+// openSandbox creates the sandbox of the computation, or reopens the one a
+// restarted agent left behind.
+func openSandbox(cmp Computation) (algorithm.Sandbox, error) {
+	sandbox, err := algorithm.NewSandbox(sandboxesDir, cmp.ID)
+	if err != nil {
+		return algorithm.Sandbox{}, err
+	}
+
+	if err := sandbox.Create(); err != nil {
+		return algorithm.Sandbox{}, fmt.Errorf("error creating computation sandbox: %w", err)
+	}
+
+	return sandbox, nil
+}
+
+// wipe shreds the files under dir and removes it with the computation storage.
+// It must be called with the service mutex held.
+func (as *agentService) wipe(dir string) error {
+	if err := algorithm.Shred(dir); err != nil {
+		return err
+	}
+
+	return as.dataStorage().Remove(dir)
+}
+
+// wipeSandbox shreds every file of the computation sandbox and removes it,
+// once its results were retrieved or the computation was stopped. It must be
+// called with the service mutex held.
+func (as *agentService) wipeSandbox() error {
+	if as.sandbox.Root == "" {
+		return nil
+	}
+
+	if err := as.wipeSecrets(); err != nil {
+		return fmt.Errorf("error wiping secrets: %w", err)
+	}
+
+	// The directories the storage backs are released before the sandbox is removed.
+	if err := as.wipe(as.sandbox.Datasets()); err != nil {
+		return fmt.Errorf("error removing datasets directory: %w", err)
+	}
+	if err := as.wipeResults(); err != nil {
+		return fmt.Errorf("error removing results directory: %w", err)
+	}
+	if as.datasets != nil {
+		if err := as.wipe(as.datasets.dir); err != nil {
+			return fmt.Errorf("error removing datasets store: %w", err)
+		}
+	}
+	if err := as.wipe(as.sandbox.Work()); err != nil {
+		return fmt.Errorf("error removing working directory: %w", err)
+	}
+	if err := as.wipe(as.sandbox.Tmp()); err != nil {
+		return fmt.Errorf("error removing temporary directory: %w", err)
+	}
+
+	if err := algorithm.Shred(as.sandbox.Root); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(as.sandbox.Root)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "computations")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create sandboxes directory: %v\n", err)
+		os.Exit(1)
+	}
+	// Computations the tests run are not sandboxed in the package directory.
+	sandboxesDir = dir
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestOpenSandbox(t *testing.T) {
+	sandbox, err := openSandbox(Computation{ID: "sandboxed"})
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(sandbox.Root) })
+
+	assert.Equal(t, sandboxesDir, filepath.Dir(sandbox.Root))
+	for _, dir := range []string{sandbox.Root, sandbox.Algo(), sandbox.Tmp()} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(sandboxDirPermission), info.Mode().Perm(), dir)
+	}
+
+	other, err := openSandbox(Computation{ID: "other"})
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(other.Root) })
+	assert.NotEqual(t, sandbox.Root, other.Root)
+}
+
+func TestWipeSandbox(t *testing.T) {
+	sandbox, err := openSandbox(Computation{ID: "wiped"})
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("secret"), 1024)
+	files := []string{
+		filepath.Join(sandbox.Algo(), algoFileName),
+		filepath.Join(sandbox.Datasets(), "dataset.csv"),
+		filepath.Join(sandbox.Results(), "result.txt"),
+		filepath.Join(sandbox.Tmp(), "scratch"),
+	}
+	for _, file := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), sandboxDirPermission))
+		require.NoError(t, os.WriteFile(file, data, 0o600))
+	}
+
+	svc := &agentService{sandbox: sandbox}
+	require.NoError(t, svc.wipeSandbox())
+
+	_, err = os.Stat(sandbox.Root)
+	assert.True(t, os.IsNotExist(err), "sandbox %s was not removed", sandbox.Root)
+
+	assert.NoError(t, svc.wipeSandbox(), "wiping a removed sandbox succeeds")
+	assert.NoError(t, (&agentService{}).wipeSandbox(), "services without a computation have no sandbox")
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+const (
+	// ManifestVersion is the latest computation manifest schema version. Version 2
+	// manifests bind every participant role to a public key and every input to a hash.
+	ManifestVersion = 2
+
+	// ComputationExpiredEvent is the event published when the computation TTL expires.
+	ComputationExpiredEvent = "ComputationExpired"
+)
+
+var (
+	// ErrManifestVersion indicates a manifest schema version the agent does not support.
+	ErrManifestVersion = errors.New("unsupported computation manifest version")
+	// ErrUnboundRole indicates a manifest participant without a public key.
+	ErrUnboundRole = errors.New("computation manifest role is not bound to a public key")
+	// ErrMissingHash indicates a manifest algorithm or dataset without a hash.
+	ErrMissingHash = errors.New("computation manifest input has no hash")
+	// ErrInvalidTTL indicates a computation TTL that is not a positive duration.
+	ErrInvalidTTL = errors.New("invalid computation ttl")
+	// ErrInvalidMaxRuntime indicates an algorithm max runtime that is not a positive duration.
+	ErrInvalidMaxRuntime = errors.New("invalid algorithm max runtime")
+)
+
+// validateSchema checks the manifest against its schema version. Manifests
+// without a version predate roles and are accepted as they are, while version 2
+// manifests must declare the algorithm provider, every data provider and at
+// least one result consumer with their public keys, and the hash of every input.
+func validateSchema(cmp Computation) error {
+	switch cmp.Version {
+	case 0, 1:
+		return nil
+	case ManifestVersion:
+	default:
+		return errors.Wrap(ErrManifestVersion, fmt.Errorf("version %d", cmp.Version))
+	}
+
+	var empty [32]byte
+
+	if len(cmp.Algorithm.UserKey) == 0 {
+		return errors.Wrap(ErrUnboundRole, fmt.Errorf("algorithm provider"))
+	}
+	if cmp.Algorithm.Hash == empty {
+		return errors.Wrap(ErrMissingHash, fmt.Errorf("algorithm"))
+	}
+
+	for i, d := range cmp.Datasets {
+		if len(d.UserKey) == 0 {
+			return errors.Wrap(ErrUnboundRole, fmt.Errorf("data provider of dataset %d", i))
+		}
+		if d.Hash == empty {
+			return errors.Wrap(ErrMissingHash, fmt.Errorf("dataset %d", i))
+		}
+	}
+
+	if len(cmp.ResultConsumers) == 0 {
+		return errors.Wrap(ErrUnboundRole, fmt.Errorf("no result consumer"))
+	}
+	for i, rc := range cmp.ResultConsumers {
+		if len(rc.UserKey) == 0 {
+			return errors.Wrap(ErrUnboundRole, fmt.Errorf("result consumer %d", i))
+		}
+	}
+
+	return nil
+}
+
+// computationTTL parses the manifest TTL, 0 means the computation does not expire.
+func computationTTL(cmp Computation) (time.Duration, error) {
+	return positiveDuration(cmp.TTL, ErrInvalidTTL)
+}
+
+// algorithmMaxRuntime parses the manifest max runtime, 0 means the algorithm
+// may run as long as the computation lives.
+func algorithmMaxRuntime(cmp Computation) (time.Duration, error) {
+	return positiveDuration(cmp.MaxRuntime, ErrInvalidMaxRuntime)
+}
+
+// positiveDuration parses an optional manifest duration, 0 if it is empty.
+func positiveDuration(value string, errInvalid error) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrap(errInvalid, err)
+	}
+	if d <= 0 {
+		return 0, errors.Wrap(errInvalid, fmt.Errorf("%s is not positive", value))
+	}
+
+	return d, nil
+}
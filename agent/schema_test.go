@@ -0,0 +1,179 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSchema(t *testing.T) {
+	hash := [32]byte{1}
+	key := []byte("key")
+
+	valid := func() Computation {
+		return Computation{
+			Version:         ManifestVersion,
+			Datasets:        Datasets{{Hash: hash, UserKey: key}},
+			Algorithm:       Algorithm{Hash: hash, UserKey: key},
+			ResultConsumers: []ResultConsumer{{UserKey: key}},
+		}
+	}
+
+	cases := []struct {
+		desc   string
+		modify func(*Computation)
+		err    error
+	}{
+		{
+			desc:   "version 2 manifest",
+			modify: func(*Computation) {},
+		},
+		{
+			desc: "version 2 manifest without datasets",
+			modify: func(c *Computation) {
+				c.Datasets = nil
+			},
+		},
+		{
+			desc: "unversioned manifest without keys",
+			modify: func(c *Computation) {
+				*c = Computation{}
+			},
+		},
+		{
+			desc: "unsupported version",
+			modify: func(c *Computation) {
+				c.Version = ManifestVersion + 1
+			},
+			err: ErrManifestVersion,
+		},
+		{
+			desc: "algorithm provider without key",
+			modify: func(c *Computation) {
+				c.Algorithm.UserKey = nil
+			},
+			err: ErrUnboundRole,
+		},
+		{
+			desc: "algorithm without hash",
+			modify: func(c *Computation) {
+				c.Algorithm.Hash = [32]byte{}
+			},
+			err: ErrMissingHash,
+		},
+		{
+			desc: "data provider without key",
+			modify: func(c *Computation) {
+				c.Datasets[0].UserKey = nil
+			},
+			err: ErrUnboundRole,
+		},
+		{
+			desc: "dataset without hash",
+			modify: func(c *Computation) {
+				c.Datasets[0].Hash = [32]byte{}
+			},
+			err: ErrMissingHash,
+		},
+		{
+			desc: "no result consumer",
+			modify: func(c *Computation) {
+				c.ResultConsumers = nil
+			},
+			err: ErrUnboundRole,
+		},
+		{
+			desc: "result consumer without key",
+			modify: func(c *Computation) {
+				c.ResultConsumers = append(c.ResultConsumers, ResultConsumer{})
+			},
+			err: ErrUnboundRole,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmp := valid()
+			tc.modify(&cmp)
+
+			err := validateSchema(cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestComputationTTL(t *testing.T) {
+	cases := []struct {
+		desc string
+		ttl  string
+		want time.Duration
+		err  error
+	}{
+		{
+			desc: "no ttl",
+		},
+		{
+			desc: "valid ttl",
+			ttl:  "1h30m",
+			want: 90 * time.Minute,
+		},
+		{
+			desc: "malformed ttl",
+			ttl:  "one hour",
+			err:  ErrInvalidTTL,
+		},
+		{
+			desc: "negative ttl",
+			ttl:  "-1h",
+			err:  ErrInvalidTTL,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ttl, err := computationTTL(Computation{TTL: tc.ttl})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.want, ttl)
+		})
+	}
+}
+
+func TestAlgorithmMaxRuntime(t *testing.T) {
+	cases := []struct {
+		desc       string
+		maxRuntime string
+		want       time.Duration
+		err        error
+	}{
+		{
+			desc: "no max runtime",
+		},
+		{
+			desc:       "valid max runtime",
+			maxRuntime: "30m",
+			want:       30 * time.Minute,
+		},
+		{
+			desc:       "malformed max runtime",
+			maxRuntime: "half an hour",
+			err:        ErrInvalidMaxRuntime,
+		},
+		{
+			desc:       "zero max runtime",
+			maxRuntime: "0s",
+			err:        ErrInvalidMaxRuntime,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			maxRuntime, err := algorithmMaxRuntime(Computation{MaxRuntime: tc.maxRuntime})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.want, maxRuntime)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+const (
+	// secretsContext separates secrets provisioning from the other signatures
+	// made with the key of the computation owner.
+	secretsContext = "cocos-secrets"
+
+	// secretsStorageMB bounds the tmpfs the secrets are kept on.
+	secretsStorageMB = 16
+
+	secretFilePermission = 0o600
+)
+
+var (
+	// ErrSecretsNotAllowed indicates secrets for a computation without an attestation approval key.
+	ErrSecretsNotAllowed = errors.New("computation does not accept secrets")
+	// ErrSecretsSignature indicates secrets not signed by the manifest attestation approval key.
+	ErrSecretsSignature = errors.New("secrets are not signed by the computation owner")
+	// ErrInvalidSecret indicates a secret with an invalid or duplicate name, or an environment variable value holding a NUL byte.
+	ErrInvalidSecret = errors.New("invalid secret")
+
+	secretNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// newSecretsStorage is a variable so tests can keep secrets on the disk.
+	newSecretsStorage = func() (storage.Storage, error) {
+		return storage.New(storage.Tmpfs, secretsStorageMB)
+	}
+)
+
+// Secret is a runtime secret of the computation owner, e.g. an API token or a
+// model decryption key. It is exposed to the algorithm as a file named after
+// it in the secrets directory, or as an environment variable when Env is set.
+type Secret struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"`
+	Env   bool   `json:"env,omitempty"`
+}
+
+// SecretsSigningBytes returns the bytes the computation owner signs to
+// provision secrets: the secrets context, the computation ID, the hex encoded
+// SHA-256 digest of the manifest SigningBytes and the hex encoded SHA-256
+// digest of the secrets sorted by name, one per line.
+func SecretsSigningBytes(cmp Computation, secrets []Secret) ([]byte, error) {
+	data, err := cmp.SigningBytes()
+	if err != nil {
+		return nil, err
+	}
+	manifestDigest := sha256.Sum256(data)
+
+	sorted := slices.Clone(secrets)
+	slices.SortFunc(sorted, func(a, b Secret) int { return strings.Compare(a.Name, b.Name) })
+	data, err = json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+	secretsDigest := sha256.Sum256(data)
+
+	return []byte(secretsContext + "\n" + cmp.ID + "\n" + hex.EncodeToString(manifestDigest[:]) + "\n" + hex.EncodeToString(secretsDigest[:])), nil
+}
+
+// SignSecrets signs the secrets provisioned to the agent running the
+// computation with an Ed25519 or ECDSA private key.
+func SignSecrets(cmp Computation, secrets []Secret, key crypto.Signer) ([]byte, error) {
+	data, err := SecretsSigningBytes(cmp, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return sign(data, key)
+}
+
+// validateSecrets checks that the secret names are unique and can be used as
+// file and environment variable names.
+func validateSecrets(secrets []Secret) error {
+	if len(secrets) == 0 {
+		return errors.Wrap(ErrInvalidSecret, errors.New("no secrets"))
+	}
+
+	names := make(map[string]bool, len(secrets))
+	for _, s := range secrets {
+		switch {
+		case !secretNameRegexp.MatchString(s.Name):
+			return errors.Wrap(ErrInvalidSecret, fmt.Errorf("name %q", s.Name))
+		case names[s.Name]:
+			return errors.Wrap(ErrInvalidSecret, fmt.Errorf("duplicate name %q", s.Name))
+		case s.Env && strings.HasPrefix(s.Name, "COCOS_"):
+			return errors.Wrap(ErrInvalidSecret, fmt.Errorf("environment variable %s is reserved", s.Name))
+		case s.Env && bytes.IndexByte(s.Value, 0) >= 0:
+			return errors.Wrap(ErrInvalidSecret, fmt.Errorf("environment variable %s holds a NUL byte", s.Name))
+		}
+		names[s.Name] = true
+	}
+
+	return nil
+}
+
+// Secrets provisions the runtime secrets of the computation owner once the
+// owner approved the attestation, before the computation runs. Secrets are
+// kept on a tmpfs and replace the ones provisioned before, they are never
+// journaled and are wiped once the run is over.
+func (as *agentService) Secrets(ctx context.Context, secrets []Secret, signature []byte) error {
+	if err := validateSecrets(secrets); err != nil {
+		return err
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if !as.assigned {
+		return ErrStateNotReady
+	}
+	if state := as.sm.GetState(); state != ReceivingAlgorithm && state != ReceivingData {
+		return ErrStateNotReady
+	}
+
+	// The key was validated when the manifest was received.
+	key, _ := approvalKey(as.computation)
+	if key == nil {
+		return ErrSecretsNotAllowed
+	}
+
+	data, err := SecretsSigningBytes(as.computation, secrets)
+	if err != nil {
+		return err
+	}
+	if !verifySignature(data, signature, []crypto.PublicKey{key}) {
+		return ErrSecretsSignature
+	}
+	if !as.approved {
+		return ErrAttestationNotApproved
+	}
+
+	if err := as.writeSecrets(secrets); err != nil {
+		// Secrets written before the failure are not left behind.
+		if werr := as.wipeSecrets(); werr != nil {
+			as.logger.Warn(fmt.Sprintf("error wiping secrets: %s", werr.Error()))
+		}
+		return fmt.Errorf("error provisioning secrets: %w", err)
+	}
+
+	names := make([]string, len(secrets))
+	for i, s := range secrets {
+		names[i] = s.Name
+	}
+	details, _ := json.Marshal(map[string][]string{"secrets": names})
+	as.eventSvc.SendEvent(as.computation.ID, events.SecretsProvisioned, InProgress.String(), details)
+
+	return nil
+}
+
+// writeSecrets writes the secrets to the secrets storage of the sandbox,
+// mounting it on the first call. It must be called with the service mutex held.
+func (as *agentService) writeSecrets(secrets []Secret) error {
+	if as.secrets == nil {
+		st, err := newSecretsStorage()
+		if err != nil {
+			return err
+		}
+		if err := st.Create(as.sandbox.Secrets()); err != nil {
+			st.Close()
+			return err
+		}
+		as.secrets = st
+	}
+
+	for _, dir := range []string{as.sandbox.SecretFiles(), as.sandbox.SecretEnv()} {
+		if err := algorithm.Shred(dir); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	for _, dir := range []string{as.sandbox.Secrets(), as.sandbox.SecretFiles(), as.sandbox.SecretEnv()} {
+		if err := os.MkdirAll(dir, sandboxDirPermission); err != nil {
+			return err
+		}
+		if err := os.Chmod(dir, sandboxDirPermission); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range secrets {
+		dir := as.sandbox.SecretFiles()
+		if s.Env {
+			dir = as.sandbox.SecretEnv()
+		}
+		if err := os.WriteFile(filepath.Join(dir, s.Name), s.Value, secretFilePermission); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wipeSecrets shreds the provisioned secrets and unmounts their storage. It
+// must be called with the service mutex held, or by the computation run.
+func (as *agentService) wipeSecrets() error {
+	if as.secrets == nil {
+		return nil
+	}
+
+	if err := algorithm.Shred(as.sandbox.Secrets()); err != nil {
+		return err
+	}
+	if err := as.secrets.Remove(as.sandbox.Secrets()); err != nil {
+		return err
+	}
+
+	err := as.secrets.Close()
+	as.secrets = nil
+
+	return err
+}
@@ -0,0 +1,209 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+func TestValidateSecrets(t *testing.T) {
+	cases := []struct {
+		desc    string
+		secrets []Secret
+		err     error
+	}{
+		{
+			desc:    "file and environment variable secrets",
+			secrets: []Secret{{Name: "api_token", Value: []byte("token")}, {Name: "MODEL_KEY", Value: []byte("key"), Env: true}},
+		},
+		{
+			desc: "no secrets",
+			err:  ErrInvalidSecret,
+		},
+		{
+			desc:    "invalid name",
+			secrets: []Secret{{Name: "../token"}},
+			err:     ErrInvalidSecret,
+		},
+		{
+			desc:    "duplicate name",
+			secrets: []Secret{{Name: "token"}, {Name: "token", Env: true}},
+			err:     ErrInvalidSecret,
+		},
+		{
+			desc:    "reserved environment variable",
+			secrets: []Secret{{Name: "COCOS_DATASETS_DIR", Env: true}},
+			err:     ErrInvalidSecret,
+		},
+		{
+			desc:    "environment variable with a NUL byte",
+			secrets: []Secret{{Name: "KEY", Value: []byte{'a', 0}, Env: true}},
+			err:     ErrInvalidSecret,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateSecrets(tc.secrets)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestSecretsSigningBytes(t *testing.T) {
+	cmp := Computation{ID: "1", Name: "sample computation"}
+	a := Secret{Name: "a", Value: []byte("1")}
+	b := Secret{Name: "b", Value: []byte("2"), Env: true}
+
+	data, err := SecretsSigningBytes(cmp, []Secret{a, b})
+	require.NoError(t, err)
+
+	reordered, err := SecretsSigningBytes(cmp, []Secret{b, a})
+	require.NoError(t, err)
+	assert.Equal(t, data, reordered, "secrets are signed regardless of their order")
+
+	b.Value = []byte("3")
+	changed, err := SecretsSigningBytes(cmp, []Secret{a, b})
+	require.NoError(t, err)
+	assert.NotEqual(t, data, changed)
+}
+
+func TestSecrets(t *testing.T) {
+	newSecretsStorage = func() (storage.Storage, error) { return storage.NewDisk(), nil }
+	t.Cleanup(func() {
+		newSecretsStorage = func() (storage.Storage, error) { return storage.New(storage.Tmpfs, secretsStorageMB) }
+	})
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cmp := Computation{ID: "1", Name: "sample computation", AttestationApproval: &AttestationApproval{Key: key}}
+	secrets := []Secret{{Name: "api_token", Value: []byte("token")}, {Name: "MODEL_KEY", Value: []byte("key"), Env: true}}
+
+	signature, err := SignSecrets(cmp, secrets, priv)
+	require.NoError(t, err)
+	otherSignature, err := SignSecrets(cmp, secrets, otherPriv)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc        string
+		computation Computation
+		assigned    bool
+		approved    bool
+		state       AgentState
+		signature   []byte
+		err         error
+	}{
+		{
+			desc:        "provision secrets",
+			computation: cmp,
+			assigned:    true,
+			approved:    true,
+			state:       ReceivingData,
+			signature:   signature,
+		},
+		{
+			desc:        "computation not assigned",
+			computation: cmp,
+			state:       ReceivingAlgorithm,
+			signature:   signature,
+			err:         ErrStateNotReady,
+		},
+		{
+			desc:        "computation running",
+			computation: cmp,
+			assigned:    true,
+			approved:    true,
+			state:       Running,
+			signature:   signature,
+			err:         ErrStateNotReady,
+		},
+		{
+			desc:        "computation without owner key",
+			computation: Computation{ID: "1"},
+			assigned:    true,
+			state:       ReceivingAlgorithm,
+			signature:   signature,
+			err:         ErrSecretsNotAllowed,
+		},
+		{
+			desc:        "signed by another key",
+			computation: cmp,
+			assigned:    true,
+			approved:    true,
+			state:       ReceivingAlgorithm,
+			signature:   otherSignature,
+			err:         ErrSecretsSignature,
+		},
+		{
+			desc:        "attestation not approved",
+			computation: cmp,
+			assigned:    true,
+			state:       ReceivingAlgorithm,
+			signature:   signature,
+			err:         ErrAttestationNotApproved,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(tc.state)
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "1", events.SecretsProvisioned, InProgress.String(), mock.Anything).Return().Maybe()
+
+			sandbox := algorithm.Sandbox{Root: t.TempDir()}
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: tc.computation,
+				assigned:    tc.assigned,
+				approved:    tc.approved,
+				sandbox:     sandbox,
+			}
+
+			err := svc.Secrets(context.Background(), secrets, tc.signature)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				assert.NoDirExists(t, sandbox.Secrets())
+				return
+			}
+
+			token, err := os.ReadFile(filepath.Join(sandbox.SecretFiles(), "api_token"))
+			require.NoError(t, err)
+			assert.Equal(t, "token", string(token))
+			info, err := os.Stat(filepath.Join(sandbox.SecretFiles(), "api_token"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(secretFilePermission), info.Mode().Perm())
+			assert.NoFileExists(t, filepath.Join(sandbox.SecretFiles(), "MODEL_KEY"), "environment variables are not exposed as files")
+			assert.Equal(t, []string{"MODEL_KEY=key"}, sandbox.SecretsEnviron())
+
+			require.NoError(t, svc.wipeSecrets())
+			assert.NoDirExists(t, sandbox.Secrets())
+			assert.Nil(t, svc.secrets)
+		})
+	}
+}
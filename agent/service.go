@@ -4,29 +4,42 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdh"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	sync "sync"
 	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/ultravioletrs/cocos/agent/algorithm"
 	"github.com/ultravioletrs/cocos/agent/algorithm/binary"
+	"github.com/ultravioletrs/cocos/agent/algorithm/cgroup"
 	"github.com/ultravioletrs/cocos/agent/algorithm/docker"
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	"github.com/ultravioletrs/cocos/agent/algorithm/python"
 	"github.com/ultravioletrs/cocos/agent/algorithm/wasm"
 	"github.com/ultravioletrs/cocos/agent/events"
 	"github.com/ultravioletrs/cocos/agent/statemachine"
+	"github.com/ultravioletrs/cocos/agent/storage"
 	"github.com/ultravioletrs/cocos/internal"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 	attestation_client "github.com/ultravioletrs/cocos/pkg/clients/grpc/attestation"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -110,8 +123,30 @@ var (
 	ErrAttestationVTpmFailed = errors.New("failed to get vTPM quote")
 	// ErrFetchAzureToken azure token fetch failed.
 	ErrFetchAzureToken = errors.New("failed to get azure token")
+	// ErrUndeclaredStepDataset indicates an algorithm step references a dataset that is not declared in the manifest.
+	ErrUndeclaredStepDataset = errors.New("algorithm step references dataset not declared in computation manifest")
+	// ErrInvalidAlgorithmParams indicates manifest algorithm arguments or environment variables outside of the allowlist.
+	ErrInvalidAlgorithmParams = errors.New("invalid algorithm arguments or environment variables in computation manifest")
+	// ErrInvalidEncryptionKey indicates a result consumer encryption key is not a valid X25519 public key.
+	ErrInvalidEncryptionKey = errors.New("invalid result consumer encryption key")
+	// ErrInvalidEventEncryptionKey indicates an event encryption key is not a valid X25519 public key.
+	ErrInvalidEventEncryptionKey = errors.New("invalid event encryption key")
+	// ErrResultEncryption indicates the result could not be encrypted for the consumer.
+	ErrResultEncryption = errors.New("failed to encrypt result")
 	// ErrAttType indicates that the attestation type that is requested does not exist or is not supported.
 	ErrAttestationType = errors.New("attestation type does not exist or is not supported")
+	// ErrDatasetReceived indicates the dataset was already delivered by its provider.
+	ErrDatasetReceived = errors.New("dataset has already been received")
+	// ErrDatasetProviderMismatch indicates the dataset is declared for a different provider.
+	ErrDatasetProviderMismatch = errors.New("dataset is not declared for this data provider")
+	// ErrAlreadyAssigned indicates the agent already accepted a computation manifest.
+	ErrAlreadyAssigned = errors.New("agent is already assigned to a computation")
+	// ErrInvalidAlgorithmSpec indicates an algorithm spec the agent cannot run.
+	ErrInvalidAlgorithmSpec = errors.New("invalid algorithm spec")
+	// ErrUnsupportedRuntime indicates an algorithm requiring a runtime version the agent does not provide.
+	ErrUnsupportedRuntime = errors.New("algorithm runtime version is not supported")
+	// ErrShuttingDown indicates the agent is draining before it exits and accepts no new uploads.
+	ErrShuttingDown = errors.New("agent is shutting down")
 )
 
 // Service specifies an API that must be fullfiled by the domain service
@@ -121,11 +156,28 @@ type Service interface {
 	StopComputation(ctx context.Context) error
 	Algo(ctx context.Context, algorithm Algorithm) error
 	Data(ctx context.Context, dataset Dataset) error
+	// Restore extracts the last checkpoint of the computation into the algorithm
+	// working directory, refusing checkpoints older than the sequence.
+	Restore(ctx context.Context, sequence uint64) error
+	// ApproveAttestation records the approval of an attestation report of the
+	// agent signed by the computation owner, which releases the algorithm and
+	// datasets uploads.
+	ApproveAttestation(ctx context.Context, report, signature []byte) error
+	// Secrets provisions the runtime secrets signed by the computation owner,
+	// which the algorithm reads from memory while it runs.
+	Secrets(ctx context.Context, secrets []Secret, signature []byte) error
+	// AttachDatasetDisk registers the declared datasets found on a hot-added dataset disk mounted at dir.
+	AttachDatasetDisk(ctx context.Context, dir string) error
 	Result(ctx context.Context) ([]byte, error)
 	Attestation(ctx context.Context, reportData [quoteprovider.Nonce]byte, nonce [vtpm.Nonce]byte, attType attestation.PlatformType) ([]byte, error)
 	IMAMeasurements(ctx context.Context) ([]byte, []byte, error)
 	AzureAttestationToken(ctx context.Context, nonce [vtpm.Nonce]byte) ([]byte, error)
 	State() string
+	Datasets() []DatasetStatus
+	// Shutdown drains the agent before it exits: new uploads are rejected, the
+	// running algorithm is stopped if it does not end before ctx is done, and
+	// the termination of the agent is published.
+	Shutdown(ctx context.Context) error
 }
 
 type agentService struct {
@@ -141,22 +193,69 @@ type agentService struct {
 	resultsConsumed   bool                      // Indicates if the results have been consumed.
 	cancel            context.CancelFunc        // Cancels the computation context.
 	vmpl              int                       // VMPL at which the Agent is running.
+	datasets          *datasetStore             // Holds datasets outside the working directory when the algorithm has steps.
+	received          []bool                    // Tracks which manifest datasets have been delivered, by manifest index.
+	trustedKeys       []crypto.PublicKey        // Keys trusted to sign computation manifests, verification is disabled if empty.
+	unhashedDatasets  bool                      // Development mode accepting manifest datasets without a hash, matched by filename.
+	traceCtx          context.Context           // Carries the span of the manifest the computation run is traced under.
+	assigned          bool                      // Indicates a computation manifest was accepted, later ones are rejected.
+	expiry            *time.Timer               // Stops the computation once its TTL expires.
+	lineage           Lineage                   // Records the delivery of the manifest inputs, written with the results.
+	cgroup            *cgroup.Group             // Bounds the CPU and memory of the algorithm processes, nil without limits.
+	clearEvents       events.Service            // Publishes events in the clear while eventSvc encrypts or redacts their details.
+	stream            logging.Output            // Streams the algorithm output to the manager.
+	output            logging.Output            // Receives the algorithm output the manifest streams, and its stderr tail.
+	stderrTail        logging.Tail              // Keeps the end of the algorithm standard error, reported when the algorithm fails.
+	encryptedResults  map[int][]byte            // Results encrypted for each consumer, so repeated and resumed downloads get the same bytes.
+	diagnostics       *diagnostics              // Records the recent history diagnostic snapshots of failed runs are captured from.
+	sendDiagnostics   DiagnosticsSender         // Delivers diagnostic snapshots to the manager, nil if they are not collected.
+	journal           *Journal                  // Persists the computation progress for recovery after a restart, nil without journaling.
+	assignedAt        time.Time                 // When the computation manifest was accepted, the TTL counts from it.
+	algoSpec          algorithmSpec             // Describes how the received algorithm runs.
+	approved          bool                      // Whether the computation owner approved the attestation.
+	storage           storage.Storage           // Backs the datasets and results directories, as the manifest selects.
+	resultStore       storage.Storage           // Bounds the results directory to the manifest disk limit, if it sets one.
+	sandbox           algorithm.Sandbox         // Holds the directories of the computation, wiped once it is done.
+	secrets           storage.Storage           // Keeps the secrets of the computation owner in memory, nil until they are provisioned.
+	resultUpload      *resultUpload             // The results uploaded to the result sink, nil without a result sink.
+	checkpointSeq     uint64                    // The sequence of the last checkpoint saved or restored.
+	reports           servedReports             // The attestation reports the computation owner may approve.
+	shuttingDown      bool                      // Indicates the agent is draining before it exits, new uploads are rejected.
 }
 
+// tracer traces the computation run, spans are dropped unless a tracer provider is registered.
+var tracer = otel.Tracer("github.com/ultravioletrs/cocos/agent")
+
 var _ Service = (*agentService)(nil)
 
-// New instantiates the agent service implementation.
-func New(ctx context.Context, logger *slog.Logger, eventSvc events.Service, attestationClient attestation_client.Client, vmlp int) Service {
+// New instantiates the agent service implementation, the algorithm output is
+// also written to output, encrypted for the owner, when it is not nil and the
+// manifest opts in. A diagnostic snapshot of every
+// failed run is sent with sendDiagnostics when it is not nil. The progress of
+// the computation is journaled when journal is not nil, and the journaled
+// computation is recovered. Manifest datasets without a hash are only
+// accepted when allowUnhashedDatasets is set, for development.
+func New(ctx context.Context, logger *slog.Logger, eventSvc events.Service, attestationClient attestation_client.Client, vmlp int, trustedKeys []crypto.PublicKey, allowUnhashedDatasets bool, output logging.Output, sendDiagnostics DiagnosticsSender, journal *Journal) Service {
 	sm := statemachine.NewStateMachine(Idle)
 	ctx, cancel := context.WithCancel(ctx)
+	diag := &diagnostics{}
 	svc := &agentService{
 		sm:                sm,
-		eventSvc:          eventSvc,
+		eventSvc:          &diagnosticEvents{svc: eventSvc, d: diag},
 		attestationClient: attestationClient,
-		logger:            logger,
+		logger:            slog.New(&diagnosticsHandler{Handler: logger.Handler(), d: diag}),
 		cancel:            cancel,
 		vmpl:              vmlp,
+		trustedKeys:       trustedKeys,
+		unhashedDatasets:  allowUnhashedDatasets,
+		traceCtx:          context.Background(),
+		diagnostics:       diag,
+		sendDiagnostics:   sendDiagnostics,
+		journal:           journal,
 	}
+	svc.stderrTail.Size = stderrTailSize
+	svc.stream = output
+	svc.output = logging.TeeStderr(nil, &svc.stderrTail)
 
 	transitions := []statemachine.Transition{
 		{From: Idle, Event: Start, To: ReceivingManifest},
@@ -173,12 +272,13 @@ func New(ctx context.Context, logger *slog.Logger, eventSvc events.Service, atte
 		sm.AddTransition(t)
 	}
 
-	sm.SetAction(ReceivingAlgorithm, svc.publishEvent(InProgress.String()))
-	sm.SetAction(ReceivingData, svc.publishEvent(InProgress.String()))
 	sm.SetAction(Running, svc.runComputation)
-	sm.SetAction(ConsumingResults, svc.publishEvent(Ready.String()))
-	sm.SetAction(Complete, svc.publishEvent(Completed.String()))
-	sm.SetAction(Failed, svc.publishEvent(Failed.String()))
+	sm.OnTransition(svc.publishTransition)
+
+	recovered := svc.recover()
+	if recovered != nil {
+		sm.Reset(recovered)
+	}
 
 	go func() {
 		if err := sm.Start(ctx); err != nil {
@@ -187,7 +287,13 @@ func New(ctx context.Context, logger *slog.Logger, eventSvc events.Service, atte
 	}()
 
 	time.Sleep(100 * time.Millisecond)
-	sm.SendEvent(Start)
+	switch recovered {
+	case nil:
+		sm.SendEvent(Start)
+	case Running:
+		// The run the agent was restarted in is started again.
+		go svc.runComputation(Running)
+	}
 
 	time.Sleep(100 * time.Millisecond)
 
@@ -198,16 +304,192 @@ func (as *agentService) State() string {
 	return as.sm.GetState().String()
 }
 
+// Datasets returns the delivery status of every dataset declared in the computation manifest.
+func (as *agentService) Datasets() []DatasetStatus {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	statuses := make([]DatasetStatus, len(as.computation.Datasets))
+	for i, d := range as.computation.Datasets {
+		statuses[i] = DatasetStatus{
+			Index:    i,
+			Filename: d.Filename,
+			Hash:     hex.EncodeToString(d.Hash[:]),
+			Received: as.received[i],
+		}
+	}
+
+	return statuses
+}
+
 func (as *agentService) InitComputation(ctx context.Context, cmp Computation) error {
+	if as.isShuttingDown() {
+		return ErrShuttingDown
+	}
 	if as.sm.GetState() != ReceivingManifest {
+		// The agent leaves the state only after a manifest was assigned.
+		if as.isAssigned() {
+			return ErrAlreadyAssigned
+		}
 		return ErrStateNotReady
 	}
-	defer as.sm.SendEvent(ManifestReceived)
 
+	if len(as.trustedKeys) > 0 {
+		if err := verifyManifest(cmp, as.trustedKeys); err != nil {
+			details, _ := json.Marshal(map[string]string{"code": manifestErrorCode(err), "error": err.Error()})
+			as.eventSvc.SendEvent(cmp.ID, ManifestVerificationEvent, Failed.String(), details)
+			return err
+		}
+	}
+
+	if err := validateSchema(cmp); err != nil {
+		return err
+	}
+
+	ttl, err := computationTTL(cmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err := algorithmMaxRuntime(cmp); err != nil {
+		return err
+	}
+
+	if err := validateResources(cmp); err != nil {
+		return err
+	}
+
+	if _, err := checkpointConfig(cmp); err != nil {
+		return err
+	}
+
+	if err := validateDatasetNaming(cmp); err != nil {
+		return err
+	}
+
+	if err := validateDatasetHashes(cmp, as.unhashedDatasets); err != nil {
+		return err
+	}
+
+	if err := validateSteps(cmp); err != nil {
+		return err
+	}
+
+	if err := validateAlgorithmBundle(cmp); err != nil {
+		return err
+	}
+
+	if err := validateAlgorithmParams(cmp); err != nil {
+		return err
+	}
+
+	if err := validateStorage(cmp); err != nil {
+		return err
+	}
+
+	if err := validateResultConsumers(cmp); err != nil {
+		return err
+	}
+
+	if err := validateResultSink(cmp); err != nil {
+		return err
+	}
+
+	if _, err := approvalKey(cmp); err != nil {
+		return err
+	}
+
+	eventKey, err := eventEncryptionKey(cmp)
+	if err != nil {
+		return err
+	}
+
+	if err := internal.ValidateCodec(cmp.ResultCodec); err != nil {
+		return err
+	}
+
+	if err := as.assign(ctx, cmp, ttl, eventKey); err != nil {
+		return err
+	}
+
+	as.sm.SendEvent(ManifestReceived)
+
+	return nil
+}
+
+func (as *agentService) isAssigned() bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	return as.assigned
+}
+
+// assign makes the computation the one the agent runs. Manifests are validated
+// before they are assigned, so the first valid manifest wins when several
+// arrive concurrently and the others are rejected until the computation is stopped.
+// A computation with a TTL is stopped once it expires.
+func (as *agentService) assign(ctx context.Context, cmp Computation, ttl time.Duration, eventKey *ecdh.PublicKey) error {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
+	if as.assigned {
+		return ErrAlreadyAssigned
+	}
+
+	sandbox, err := openSandbox(cmp)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStorage(cmp)
+	if err != nil {
+		os.RemoveAll(sandbox.Root)
+		return err
+	}
+	resultStore, err := openResultsStorage(cmp)
+	if err != nil {
+		st.Close()
+		os.RemoveAll(sandbox.Root)
+		return err
+	}
+	sandbox.Env = algorithm.Environ(cmp.Algorithm.Env)
+	as.assigned = true
+	as.storage = st
+	as.resultStore = resultStore
+	as.sandbox = sandbox
+
 	as.computation = cmp
+	as.assignedAt = time.Now()
+	as.received = make([]bool, len(cmp.Datasets))
+	as.lineage = newLineage(cmp)
+	as.traceCtx = trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+
+	// The algorithm output only leaves the agent encrypted for the owner.
+	as.clearEvents = as.eventSvc
+	var stream logging.Output
+	if eventKey != nil {
+		as.eventSvc = events.NewEncrypted(as.eventSvc, eventKey, encryptedFields(cmp.EventEncryption.Fields))
+		if cmp.EventEncryption.StreamOutput {
+			stream = logging.Encrypt(as.stream, eventKey)
+		}
+	} else {
+		as.eventSvc = events.NewRedacted(as.eventSvc, outputFields)
+	}
+	as.output = logging.TeeStderr(stream, &as.stderrTail)
+
+	if ttl > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			as.mu.Lock()
+			defer as.mu.Unlock()
+
+			// The computation the timer was started for may have been stopped already.
+			if as.expiry == timer {
+				as.expire(ttl)
+			}
+		})
+		as.expiry = timer
+	}
 
 	transitions := []statemachine.Transition{}
 
@@ -229,29 +511,63 @@ func (as *agentService) StopComputation(ctx context.Context) error {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	as.eventSvc.SendEvent(as.computation.ID, "Stopped", "Stopped", json.RawMessage{})
+	return as.stop(ctx)
+}
+
+// expire stops the computation once its TTL expired. It must be called with
+// the service mutex held.
+func (as *agentService) expire(ttl time.Duration) {
+	as.logger.Warn("computation ttl expired", "computation", as.computation.ID, "ttl", ttl.String())
+	details, _ := json.Marshal(map[string]string{"ttl": ttl.String()})
+	as.eventSvc.SendEvent(as.computation.ID, ComputationExpiredEvent, Terminated.String(), details)
+
+	if err := as.stop(context.Background()); err != nil {
+		as.logger.Error("failed to stop expired computation", "error", err)
+	}
+}
+
+// stop stops the computation, wipes its sandbox and resets the agent to wait
+// for a new manifest. It must be called with the service mutex held.
+func (as *agentService) stop(ctx context.Context) error {
+	as.eventSvc.SendEvent(as.computation.ID, events.Stopped, Terminated.String(), json.RawMessage{})
 
 	as.cancel()
 
+	if as.expiry != nil {
+		as.expiry.Stop()
+		as.expiry = nil
+	}
+
 	if as.algorithm != nil {
 		if err := as.algorithm.Stop(); err != nil {
 			return fmt.Errorf("error stopping computation: %v", err)
 		}
 	}
 
-	if err := os.RemoveAll(algorithm.DatasetsDir); err != nil {
-		return fmt.Errorf("error removing datasets directory: %v", err)
+	if err := as.wipeSandbox(); err != nil {
+		return fmt.Errorf("error wiping computation sandbox: %v", err)
 	}
 
-	if err := os.RemoveAll(algorithm.ResultsDir); err != nil {
-		return fmt.Errorf("error removing results directory: %v", err)
+	if err := as.closeStorage(); err != nil {
+		return fmt.Errorf("error closing computation storage: %v", err)
 	}
 
 	as.sm.Reset(Idle)
+	as.forget()
 
 	as.computation = Computation{}
+	as.algoSpec = algorithmSpec{}
+	as.sandbox = algorithm.Sandbox{}
+	as.lineage = Lineage{}
+	as.assigned = false
+	as.approved = false
+	if as.clearEvents != nil {
+		as.eventSvc, as.clearEvents = as.clearEvents, nil
+	}
 	as.algorithm = nil
+	as.datasets = nil
 	as.result = nil
+	as.encryptedResults = nil
 	as.runError = nil
 	as.resultsConsumed = false
 
@@ -273,11 +589,17 @@ func (as *agentService) StopComputation(ctx context.Context) error {
 }
 
 func (as *agentService) Algo(ctx context.Context, algo Algorithm) error {
+	if as.isShuttingDown() {
+		return ErrShuttingDown
+	}
 	if as.sm.GetState() != ReceivingAlgorithm {
 		return ErrStateNotReady
 	}
 	as.mu.Lock()
 	defer as.mu.Unlock()
+	if err := as.checkApproved(); err != nil {
+		return err
+	}
 	if as.algorithm != nil {
 		return ErrAllManifestItemsReceived
 	}
@@ -288,42 +610,62 @@ func (as *agentService) Algo(ctx context.Context, algo Algorithm) error {
 		return ErrHashMismatch
 	}
 
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("error getting current directory: %v", err)
+	// Algorithms uploaded without a type run as the type the manifest
+	// declares, as binaries when it declares none.
+	declared := as.computation.Algorithm.Type
+	switch {
+	case algo.Spec.Type == "" && declared != "":
+		algo.Spec.Type = declared
+	case algo.Spec.Type == "":
+		algo.Spec.Type = algorithm.AlgoTypeBin
+	case declared != "" && algo.Spec.Type != declared:
+		return errors.Wrap(ErrInvalidAlgorithmSpec, fmt.Errorf("%s algorithm, the manifest declares %s", algo.Spec.Type, declared))
 	}
-
-	f, err := os.Create(filepath.Join(currentDir, "algo"))
-	if err != nil {
-		return fmt.Errorf("error creating algorithm file: %v", err)
+	if err := algo.Spec.Validate(); err != nil {
+		return errors.Wrap(ErrInvalidAlgorithmSpec, err)
 	}
-
-	if _, err := f.Write(algo.Algorithm); err != nil {
-		return fmt.Errorf("error writing algorithm to file: %v", err)
+	if err := checkRuntime(algo.Spec); err != nil {
+		return err
 	}
 
-	if err := os.Chmod(f.Name(), algoFilePermission); err != nil {
-		return fmt.Errorf("error changing file permissions: %v", err)
-	}
+	// Bundled algorithms run the entrypoint of the extracted bundle.
+	bundled := as.computation.Algorithm.Bundle != nil
+	var path string
+	if bundled {
+		var err error
+		if path, err = as.extractBundle(algo.Algorithm, algo.Spec.Type); err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Create(filepath.Join(as.sandbox.Algo(), algoFileName))
+		if err != nil {
+			return fmt.Errorf("error creating algorithm file: %v", err)
+		}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("error closing file: %v", err)
-	}
+		if _, err := f.Write(algo.Algorithm); err != nil {
+			return fmt.Errorf("error writing algorithm to file: %v", err)
+		}
 
-	algoType := algorithm.AlgorithmTypeFromContext(ctx)
-	if algoType == "" {
-		algoType = string(algorithm.AlgoTypeBin)
+		if err := os.Chmod(f.Name(), algoFilePermission); err != nil {
+			return fmt.Errorf("error changing file permissions: %v", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("error closing file: %v", err)
+		}
+		path = f.Name()
 	}
 
-	args := algorithm.AlgorithmArgsFromContext(ctx)
+	spec := algorithmSpec{
+		Path:       path,
+		Type:       string(algo.Spec.Type),
+		Entrypoint: algo.Spec.Entrypoint,
+		Args:       algo.Spec.Args,
+	}
 
-	switch algoType {
-	case string(algorithm.AlgoTypeBin):
-		as.algorithm = binary.NewAlgorithm(as.logger, as.eventSvc, f.Name(), args, as.computation.ID)
-	case string(algorithm.AlgoTypePython):
-		var requirementsFile string
+	if spec.Type == string(algorithm.AlgoTypePython) {
 		if len(algo.Requirements) > 0 {
-			fr, err := os.CreateTemp("", "requirements.txt")
+			fr, err := os.OpenFile(filepath.Join(as.sandbox.Algo(), requirementsFileName), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 			if err != nil {
 				return fmt.Errorf("error creating requirments file: %v", err)
 			}
@@ -334,76 +676,181 @@ func (as *agentService) Algo(ctx context.Context, algo Algorithm) error {
 			if err := fr.Close(); err != nil {
 				return fmt.Errorf("error closing file: %v", err)
 			}
-			requirementsFile = fr.Name()
+			spec.Requirements = fr.Name()
+		} else if bundled {
+			// Bundles without uploaded requirements install those at their root, if any.
+			reqPath := filepath.Join(as.sandbox.Algo(), bundleDirName, requirementsFileName)
+			if fi, err := os.Lstat(reqPath); err == nil && fi.Mode().IsRegular() {
+				spec.Requirements = reqPath
+			}
 		}
-		runtime := python.PythonRunTimeFromContext(ctx)
-		as.algorithm = python.NewAlgorithm(as.logger, as.eventSvc, runtime, requirementsFile, f.Name(), args, as.computation.ID)
-	case string(algorithm.AlgoTypeWasm):
-		as.algorithm = wasm.NewAlgorithm(as.logger, as.eventSvc, args, f.Name(), as.computation.ID)
-	case string(algorithm.AlgoTypeDocker):
-		as.algorithm = docker.NewAlgorithm(as.logger, as.eventSvc, f.Name(), as.computation.ID)
+		spec.Runtime = algo.Spec.Runtime
+	}
+
+	if err := as.loadAlgorithm(spec, nil); err != nil {
+		return err
 	}
 
-	if err := os.Mkdir(algorithm.DatasetsDir, 0o755); err != nil {
+	if err := as.dataStorage().Create(as.sandbox.Datasets()); err != nil {
 		return fmt.Errorf("error creating datasets directory: %v", err)
 	}
 
+	if err := as.createScratch(as.sandbox.Tmp()); err != nil {
+		return fmt.Errorf("error creating temporary directory: %v", err)
+	}
+
 	if as.algorithm != nil {
+		as.lineage.algorithmReceived(spec.Type)
 		as.sm.SendEvent(AlgorithmReceived)
 	}
 
 	return nil
 }
 
+// algorithmSpec describes how the received algorithm runs, it is journaled
+// so a restarted agent runs the same algorithm.
+type algorithmSpec struct {
+	Path         string   `json:"path"`
+	Type         string   `json:"type"`
+	Entrypoint   string   `json:"entrypoint,omitempty"`
+	Args         []string `json:"args,omitempty"`
+	Runtime      string   `json:"runtime,omitempty"`
+	Requirements string   `json:"requirements,omitempty"`
+}
+
+// loadAlgorithm prepares the algorithm the spec describes to run. An algorithm
+// with steps keeps its datasets in store, a new store is created if it is nil.
+// It must be called with the service mutex held.
+func (as *agentService) loadAlgorithm(spec algorithmSpec, store *datasetStore) error {
+	group, err := as.newCgroup(spec.Type)
+	if err != nil {
+		return fmt.Errorf("error creating algorithm cgroup: %w", err)
+	}
+	as.cgroup = group
+
+	// The manifest arguments come before the uploaded or step arguments.
+	newAlgorithm := func(args []string) algorithm.Algorithm {
+		args = slices.Concat(as.computation.Algorithm.Args, args)
+		switch spec.Type {
+		case string(algorithm.AlgoTypeBin):
+			return binary.NewAlgorithm(as.algorithmLogger(), as.eventSvc, spec.Path, args, as.computation.ID, as.sandbox, group, as.output)
+		case string(algorithm.AlgoTypePython):
+			return python.NewAlgorithm(as.algorithmLogger(), as.eventSvc, spec.Runtime, spec.Requirements, spec.Path, args, as.computation.ID, as.sandbox, group, as.output)
+		case string(algorithm.AlgoTypeWasm):
+			return wasm.NewAlgorithm(as.algorithmLogger(), as.eventSvc, spec.Entrypoint, args, spec.Path, as.computation.ID, as.sandbox, as.wasmLimits(), as.output)
+		case string(algorithm.AlgoTypeDocker):
+			return docker.NewAlgorithm(as.algorithmLogger(), as.eventSvc, spec.Entrypoint, args, spec.Path, as.computation.ID, as.sandbox, as.output)
+		}
+		return nil
+	}
+
+	as.algorithm = newAlgorithm(spec.Args)
+	as.algoSpec = spec
+
+	// Steps run the same algorithm once each, with access to only their own datasets.
+	if steps := as.computation.Algorithm.Steps; len(steps) > 0 && as.algorithm != nil {
+		if store == nil {
+			if store, err = newDatasetStore(as.dataStorage(), as.sandbox, manifestNamed(as.computation)); err != nil {
+				return fmt.Errorf("error creating datasets store: %v", err)
+			}
+		}
+		as.datasets = store
+		as.algorithm = newStepsAlgorithm(as.logger, steps, store, newAlgorithm)
+	}
+
+	return nil
+}
+
+// wasmLimits returns the wasm runtime limits declared in the computation manifest.
+func (as *agentService) wasmLimits() wasm.Limits {
+	limits := as.computation.Algorithm.WasmLimits
+	if limits == nil {
+		return wasm.Limits{}
+	}
+
+	return wasm.Limits{
+		MaxMemoryMB: limits.MaxMemoryMB,
+		Timeout:     time.Duration(limits.TimeoutSeconds) * time.Second,
+	}
+}
+
 func (as *agentService) Data(ctx context.Context, dataset Dataset) error {
+	if as.isShuttingDown() {
+		return ErrShuttingDown
+	}
 	if as.sm.GetState() != ReceivingData {
 		return ErrStateNotReady
 	}
 	as.mu.Lock()
 	defer as.mu.Unlock()
-	if len(as.computation.Datasets) == 0 {
+	if err := as.checkApproved(); err != nil {
+		return err
+	}
+	if !slices.Contains(as.received, false) {
 		return ErrAllManifestItemsReceived
 	}
 
 	hash := sha3.Sum256(dataset.Dataset)
 
-	matched := false
-	for i, d := range as.computation.Datasets {
-		if hash == d.Hash {
-			if d.Filename != "" && d.Filename != dataset.Filename {
-				return ErrFileNameMismatch
-			}
+	index, err := as.matchDataset(hash, dataset.Filename)
+	if err != nil {
+		return err
+	}
 
-			as.computation.Datasets = slices.Delete(as.computation.Datasets, i, i+1)
-
-			if DecompressFromContext(ctx) {
-				if err := internal.UnzipFromMemory(dataset.Dataset, algorithm.DatasetsDir); err != nil {
-					return fmt.Errorf("error decompressing dataset: %v", err)
-				}
-			} else {
-				f, err := os.Create(fmt.Sprintf("%s/%s", algorithm.DatasetsDir, dataset.Filename))
-				if err != nil {
-					return fmt.Errorf("error creating dataset file: %v", err)
-				}
-
-				if _, err := f.Write(dataset.Dataset); err != nil {
-					return fmt.Errorf("error writing dataset to file: %v", err)
-				}
-				if err := f.Close(); err != nil {
-					return fmt.Errorf("error closing file: %v", err)
-				}
-			}
+	declared := as.computation.Datasets[index]
+	if declared.Filename != "" && declared.Filename != dataset.Filename {
+		return ErrFileNameMismatch
+	}
 
-			matched = true
-			break
+	// The authenticated provider is identified by the index of its first dataset in the manifest.
+	if provider, ok := IndexFromContext(ctx); ok {
+		if provider < 0 || provider >= len(as.computation.Datasets) || !bytes.Equal(as.computation.Datasets[provider].UserKey, declared.UserKey) {
+			return ErrDatasetProviderMismatch
 		}
 	}
 
-	if !matched {
-		return ErrUndeclaredDataset
+	// The manifest naming contract, not the upload order or filename, decides where the algorithm finds the dataset.
+	name := datasetName(as.computation, index, dataset.Filename)
+	size := uint64(len(dataset.Dataset))
+
+	// Archives are verified before any of them is written, so that an unsafe archive leaves nothing behind.
+	decompress := DecompressFromContext(ctx)
+	var archive internal.ArchiveInfo
+	if decompress {
+		var err error
+		if archive, err = as.verifyArchive(index, dataset.Dataset); err != nil {
+			return err
+		}
 	}
 
-	if len(as.computation.Datasets) == 0 {
+	if as.datasets != nil {
+		if err := as.reserveStorage(as.datasets.dir, name, size); err != nil {
+			return err
+		}
+		if err := as.datasets.add(dataset.Filename, name, dataset.Dataset, decompress); err != nil {
+			return fmt.Errorf("error storing dataset: %w", as.storageError(name, size, err))
+		}
+	} else {
+		// Extracted archives take the size of their content.
+		if decompress {
+			size = archive.Size
+		}
+		if err := as.reserveStorage(as.sandbox.Datasets(), name, size); err != nil {
+			return err
+		}
+		if err := writeDataset(as.sandbox.Datasets(), name, dataset.Dataset, decompress, manifestNamed(as.computation)); err != nil {
+			return fmt.Errorf("error writing dataset: %w", as.storageError(name, size, err))
+		}
+	}
+
+	as.received[index] = true
+	as.lineage.datasetReceived(index, DatasetUploaded, hash)
+	if decompress {
+		as.reportExtracted(index, name, archive)
+	}
+	as.persist(ReceivingData)
+
+	if !slices.Contains(as.received, false) {
 		defer as.sm.SendEvent(DataReceived)
 	}
 
@@ -430,9 +877,38 @@ func (as *agentService) Result(ctx context.Context) ([]byte, error) {
 	if !as.resultsConsumed && currentState == ConsumingResults {
 		as.resultsConsumed = true
 		defer as.sm.SendEvent(ResultsConsumed)
+
+		// The results are kept in memory for later downloads, nothing is left in the sandbox.
+		if err := as.wipeSandbox(); err != nil {
+			as.logger.Warn(fmt.Sprintf("error wiping computation sandbox: %s", err.Error()))
+		}
+	}
+
+	encryptionKey := as.computation.ResultConsumers[index].EncryptionKey
+	if as.runError != nil || len(encryptionKey) == 0 {
+		return as.result, as.runError
+	}
+
+	if encrypted, ok := as.encryptedResults[index]; ok {
+		return encrypted, nil
 	}
 
-	return as.result, as.runError
+	pub, err := encryption.ParsePublicKey(encryptionKey)
+	if err != nil {
+		return []byte{}, errors.Wrap(ErrResultEncryption, err)
+	}
+
+	encrypted, err := encryption.Encrypt(pub, as.result)
+	if err != nil {
+		return []byte{}, errors.Wrap(ErrResultEncryption, err)
+	}
+
+	if as.encryptedResults == nil {
+		as.encryptedResults = make(map[int][]byte)
+	}
+	as.encryptedResults[index] = encrypted
+
+	return encrypted, nil
 }
 
 func (as *agentService) Attestation(ctx context.Context, reportData [quoteprovider.Nonce]byte, nonce [vtpm.Nonce]byte, attType attestation.PlatformType) ([]byte, error) {
@@ -440,6 +916,7 @@ func (as *agentService) Attestation(ctx context.Context, reportData [quoteprovid
 	if err != nil {
 		return []byte{}, errors.Wrap(ErrAttestationFailed, err)
 	}
+	as.reports.add(rawQuote)
 	return rawQuote, nil
 }
 
@@ -452,8 +929,12 @@ func (as *agentService) AzureAttestationToken(ctx context.Context, nonce [vtpm.N
 }
 
 func (as *agentService) runComputation(state statemachine.State) {
-	as.publishEvent(Starting.String())(state)
+	as.eventSvc.SendEvent(as.computation.ID, events.RunStarted, Starting.String(), as.runParams())
 	as.logger.Debug("computation run started")
+
+	ctx, span := tracer.Start(as.traceCtx, "run_computation", trace.WithAttributes(attribute.String("computation.id", as.computation.ID)))
+	defer func() { endSpan(span, as.runError) }()
+
 	defer func() {
 		if as.runError != nil {
 			as.sm.SendEvent(RunFailed)
@@ -462,47 +943,259 @@ func (as *agentService) runComputation(state statemachine.State) {
 		}
 	}()
 
-	if err := os.Mkdir(algorithm.ResultsDir, 0o755); err != nil {
+	if err := as.resultsStorage().Create(as.sandbox.Results()); err != nil {
 		as.runError = fmt.Errorf("error creating results directory: %s", err.Error())
 		as.logger.Warn(as.runError.Error())
-		as.publishEvent(Failed.String())(state)
+		return
+	}
+
+	// The working directory exists already when it was restored from a checkpoint.
+	if err := as.createScratch(as.sandbox.Work()); err != nil {
+		as.runError = fmt.Errorf("error creating working directory: %s", err.Error())
+		as.logger.Warn(as.runError.Error())
 		return
 	}
 
 	defer func() {
-		if err := os.RemoveAll(algorithm.ResultsDir); err != nil {
+		// The snapshot is captured before the run leftovers it measures are removed.
+		if as.runError != nil {
+			as.reportDiagnostics(ctx)
+		}
+		if err := as.wipeSecrets(); err != nil {
+			as.logger.Warn(fmt.Sprintf("error wiping secrets: %s", err.Error()))
+		}
+		if err := as.wipeResults(); err != nil {
 			as.logger.Warn(fmt.Sprintf("error removing results directory and its contents: %s", err.Error()))
 		}
-		if err := os.RemoveAll(algorithm.DatasetsDir); err != nil {
+		if err := as.wipe(as.sandbox.Work()); err != nil {
+			as.logger.Warn(fmt.Sprintf("error removing working directory and its contents: %s", err.Error()))
+		}
+		if err := as.wipe(as.sandbox.Datasets()); err != nil {
 			as.logger.Warn(fmt.Sprintf("error removing datasets directory and its contents: %s", err.Error()))
 		}
+		if as.datasets != nil {
+			if err := as.wipe(as.datasets.dir); err != nil {
+				as.logger.Warn(fmt.Sprintf("error removing datasets store and its contents: %s", err.Error()))
+			}
+		}
+		if err := as.cgroup.Close(); err != nil {
+			as.logger.Warn(fmt.Sprintf("error removing algorithm cgroup: %s", err.Error()))
+		}
 	}()
 
-	as.publishEvent(InProgress.String())(state)
-	if err := as.algorithm.Run(); err != nil {
+	resultSink, err := as.newResultSink()
+	if err != nil {
+		as.runError = err
+		as.logger.Warn(fmt.Sprintf("failed to set up result sink: %s", err.Error()))
+		return
+	}
+
+	// The checkpoint key is read before the algorithm runs, so that the algorithm cannot change it.
+	checkpointKey, err := as.checkpointKey()
+	if err != nil {
+		as.runError = err
+		as.logger.Warn(fmt.Sprintf("failed to read checkpoint key: %s", err.Error()))
+		return
+	}
+
+	_, execSpan := tracer.Start(ctx, "execute_algorithm")
+	stopWatchdog := as.watchAlgorithm(as.algorithm)
+	releaseDeadline := as.limitRuntime(as.algorithm)
+	stopCheckpoints := as.checkpointPeriodically(checkpointKey)
+	as.stderrTail.Reset()
+	oomKillsBefore := oomKills()
+	err = as.algorithm.Run()
+	stopCheckpoints()
+	// A stopped algorithm fails the run even if it exited cleanly.
+	expired, killed, exceeded := releaseDeadline(), stopWatchdog(), as.resourcesExceeded()
+	if err != nil || expired {
+		as.reportFailure(err, expired, oomKillsBefore)
+	}
+	switch {
+	case expired:
+		err = errors.Wrap(ErrRunTimeout, err)
+	case killed:
+		err = errors.Wrap(ErrAlgorithmHung, err)
+	case exceeded:
+		err = errors.Wrap(ErrResourceExceeded, err)
+	}
+	endSpan(execSpan, err)
+	if err != nil {
 		as.runError = err
 		as.logger.Warn(fmt.Sprintf("failed to run computation: %s", err.Error()))
-		as.publishEvent(Failed.String())(state)
 		return
 	}
 
-	results, err := internal.ZipDirectoryToMemory(algorithm.ResultsDir)
+	if err := writeLineage(as.sandbox.Results(), as.lineage); err != nil {
+		as.runError = err
+		as.logger.Warn(fmt.Sprintf("failed to write result lineage: %s", err.Error()))
+		return
+	}
+
+	// Result files are compressed in parallel by as many workers as there are vCPUs.
+	_, packSpan := tracer.Start(ctx, "package_results")
+	results, err := internal.ZipDirectoryParallel(as.sandbox.Results(), as.computation.ResultCodec, 0)
+	endSpan(packSpan, err)
 	if err != nil {
 		as.runError = err
 		as.logger.Warn(fmt.Sprintf("failed to zip results: %s", err.Error()))
-		as.publishEvent(Failed.String())(state)
 		return
 	}
 
-	as.publishEvent(Completed.String())(state)
+	_, signSpan := tracer.Start(ctx, "sign_results")
+	results, err = as.signResults(ctx, results)
+	endSpan(signSpan, err)
+	if err != nil {
+		as.runError = err
+		as.logger.Warn(fmt.Sprintf("failed to sign results: %s", err.Error()))
+		return
+	}
+
+	if resultSink != nil {
+		_, uploadSpan := tracer.Start(ctx, "upload_results")
+		as.resultUpload, err = as.uploadResults(ctx, resultSink, results)
+		endSpan(uploadSpan, err)
+		if err != nil {
+			as.runError = err
+			as.logger.Warn(fmt.Sprintf("failed to upload results: %s", err.Error()))
+			return
+		}
+	}
+
+	// The checkpoint of a failed run is kept so a re-launched CVM can resume it.
+	as.removeCheckpoint(as.computation.ID)
 
 	as.result = results
 }
 
-func (as *agentService) publishEvent(status string) statemachine.Action {
-	return func(state statemachine.State) {
-		as.eventSvc.SendEvent(as.computation.ID, state.String(), status, json.RawMessage{})
+// endSpan ends the span, marking it failed when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// validateSteps checks that every dataset referenced by an algorithm step is
+// declared in the manifest.
+func validateSteps(cmp Computation) error {
+	declared := make(map[string]bool, len(cmp.Datasets))
+	for _, d := range cmp.Datasets {
+		if d.Filename != "" {
+			declared[d.Filename] = true
+		}
+	}
+
+	for _, step := range cmp.Algorithm.Steps {
+		for _, name := range step.Datasets {
+			if !declared[name] {
+				return errors.Wrap(ErrUndeclaredStepDataset, fmt.Errorf("step %s: dataset %s", step.Name, name))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateResultConsumers checks that every result consumer encryption key is
+// a valid X25519 public key.
+func validateResultConsumers(cmp Computation) error {
+	for i, rc := range cmp.ResultConsumers {
+		if len(rc.EncryptionKey) == 0 {
+			continue
+		}
+		if _, err := encryption.ParsePublicKey(rc.EncryptionKey); err != nil {
+			return errors.Wrap(ErrInvalidEncryptionKey, fmt.Errorf("result consumer %d: %w", i, err))
+		}
 	}
+
+	return nil
+}
+
+// outputFields are the event detail fields that hold algorithm output, they are
+// encrypted even when the manifest lists other fields, and removed when it
+// does not encrypt event details.
+var outputFields = []string{"output", "stderr"}
+
+// encryptedFields returns the event detail fields the manifest encrypts along
+// with the output fields, nil if it encrypts every field.
+func encryptedFields(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	encrypted := slices.Clone(fields)
+	for _, field := range outputFields {
+		if !slices.Contains(encrypted, field) {
+			encrypted = append(encrypted, field)
+		}
+	}
+
+	return encrypted
+}
+
+// eventEncryptionKey parses the key event details are encrypted with, nil if
+// the manifest does not encrypt event details.
+func eventEncryptionKey(cmp Computation) (*ecdh.PublicKey, error) {
+	if cmp.EventEncryption == nil {
+		return nil, nil
+	}
+
+	key, err := encryption.ParsePublicKey(cmp.EventEncryption.Key)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidEventEncryptionKey, err)
+	}
+
+	return key, nil
+}
+
+// publishTransition reports a state transition as a typed agent event. The
+// details carry the states the agent moved between and, for failed runs, the error.
+func (as *agentService) publishTransition(t statemachine.Transition) {
+	as.diagnostics.transition(t)
+
+	var eventType string
+	var status string
+
+	switch t.Event {
+	case ManifestReceived:
+		eventType, status = events.ManifestReceived, InProgress.String()
+	case AlgorithmReceived:
+		eventType, status = events.AlgorithmReceived, InProgress.String()
+	case DataReceived:
+		eventType, status = events.DataReceived, InProgress.String()
+	case RunComplete:
+		eventType, status = events.RunFinished, Ready.String()
+	case RunFailed:
+		eventType, status = events.Error, Failed.String()
+	case ResultsConsumed:
+		eventType, status = events.ResultsConsumed, Completed.String()
+	default:
+		return
+	}
+
+	as.mu.Lock()
+	as.persist(t.To)
+	as.mu.Unlock()
+
+	details := map[string]string{"from": t.From.String(), "to": t.To.String()}
+	if t.Event == RunFailed && as.runError != nil {
+		details["error"] = as.runError.Error()
+	}
+	if t.Event == RunComplete && as.resultUpload != nil {
+		details["result_url"] = as.resultUpload.URL
+		details["result_digest"] = as.resultUpload.Digest
+		details["result_size"] = strconv.Itoa(as.resultUpload.Size)
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		as.logger.Warn(fmt.Sprintf("failed to marshal event details: %s", err.Error()))
+		data = json.RawMessage{}
+	}
+
+	as.eventSvc.SendEvent(as.computation.ID, eventType, status, data)
 }
 
 func (as *agentService) IMAMeasurements(ctx context.Context) ([]byte, []byte, error) {
@@ -3,12 +3,17 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdh"
 	"crypto/rand"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,16 +23,25 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
 	algomocks "github.com/ultravioletrs/cocos/agent/algorithm/mocks"
 	"github.com/ultravioletrs/cocos/agent/algorithm/python"
+	"github.com/ultravioletrs/cocos/agent/events"
 	"github.com/ultravioletrs/cocos/agent/events/mocks"
 	"github.com/ultravioletrs/cocos/agent/statemachine"
 	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+	"github.com/ultravioletrs/cocos/internal"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/sha3"
-	"google.golang.org/grpc/metadata"
 )
 
 var (
@@ -53,6 +67,7 @@ func TestAlgo(t *testing.T) {
 		err      error
 		algo     Algorithm
 		algoType string
+		declared algorithm.AlgorithType
 	}{
 		{
 			name: "Test Algo successfully",
@@ -100,22 +115,72 @@ func TestAlgo(t *testing.T) {
 			algoType: "docker",
 			err:      nil,
 		},
+		{
+			name: "Test Algo with minimum runtime version successfully",
+			algo: Algorithm{
+				Algorithm: algo,
+				Hash:      algoHash,
+				Spec:      algorithm.Spec{MinRuntimeVersion: "3.10"},
+			},
+			algoType: "python",
+			err:      nil,
+		},
 		{
 			name:     "Test algo hash mismatch",
 			algo:     Algorithm{},
 			algoType: "python",
 			err:      ErrHashMismatch,
 		},
+		{
+			name: "Test algo invalid spec",
+			algo: Algorithm{
+				Algorithm: algo,
+				Hash:      algoHash,
+				Spec:      algorithm.Spec{Runtime: python.PyRuntime},
+			},
+			algoType: "bin",
+			err:      ErrInvalidAlgorithmSpec,
+		},
+		{
+			name: "Test algo unsupported runtime version",
+			algo: Algorithm{
+				Algorithm: algo,
+				Hash:      algoHash,
+				Spec:      algorithm.Spec{MinRuntimeVersion: "3.12"},
+			},
+			algoType: "python",
+			err:      ErrUnsupportedRuntime,
+		},
+		{
+			name: "Test algo without type runs as the declared type",
+			algo: Algorithm{
+				Algorithm: algo,
+				Hash:      algoHash,
+			},
+			declared: algorithm.AlgoTypeBin,
+		},
+		{
+			name: "Test algo type does not match the manifest",
+			algo: Algorithm{
+				Algorithm: algo,
+				Hash:      algoHash,
+			},
+			algoType: "wasm",
+			declared: algorithm.AlgoTypeBin,
+			err:      ErrInvalidAlgorithmSpec,
+		},
 	}
 
+	pythonVersion = func(string) (string, error) { return "3.11.4", nil }
+	t.Cleanup(func() { pythonVersion = python.Version })
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			err = os.RemoveAll("datasets")
 			require.NoError(t, err)
 
-			ctx := metadata.NewIncomingContext(context.Background(),
-				metadata.Pairs(algorithm.AlgoTypeKey, tc.algoType, python.PyRuntimeKey, python.PyRuntime),
-			)
+			ctx := context.Background()
+			tc.algo.Spec.Type = algorithm.AlgorithType(tc.algoType)
 
 			events := new(mocks.Service)
 			events.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
@@ -123,9 +188,11 @@ func TestAlgo(t *testing.T) {
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 			client := new(MockAttestationClient)
-			svc := New(ctx, mglog.NewMock(), events, client, 0)
+			svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 
-			err := svc.InitComputation(ctx, testComputation(t))
+			cmp := testComputation(t)
+			cmp.Algorithm.Type = tc.declared
+			err := svc.InitComputation(ctx, cmp)
 			require.NoError(t, err)
 
 			time.Sleep(300 * time.Millisecond)
@@ -151,6 +218,7 @@ func TestData(t *testing.T) {
 	alg := Algorithm{
 		Hash:      algoHash,
 		Algorithm: algo,
+		Spec:      algorithm.Spec{Type: algorithm.AlgoTypePython},
 	}
 
 	data, err := os.ReadFile(dataPath)
@@ -190,21 +258,25 @@ func TestData(t *testing.T) {
 			err: ErrFileNameMismatch,
 		},
 		{
-			name: "Test dataset not declared in manifest",
+			name: "Test dataset hash does not match manifest",
 			data: Dataset{
+				Dataset:  []byte("tampered dataset"),
 				Filename: datasetFile,
 			},
+			err: ErrHashMismatch,
+		},
+		{
+			name: "Test dataset not declared in manifest",
+			data: Dataset{
+				Filename: "undeclared.csv",
+			},
 			err: ErrUndeclaredDataset,
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx := metadata.NewIncomingContext(context.Background(),
-				metadata.Pairs(
-					algorithm.AlgoTypeKey, "python",
-					python.PyRuntimeKey, python.PyRuntime),
-			)
+			ctx := context.Background()
 
 			events := new(mocks.Service)
 			events.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
@@ -217,7 +289,7 @@ func TestData(t *testing.T) {
 			defer cancel()
 
 			client := new(MockAttestationClient)
-			svc := New(ctx, mglog.NewMock(), events, client, 0)
+			svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 
 			err := svc.InitComputation(ctx, testComputation(t))
 			require.NoError(t, err)
@@ -241,13 +313,67 @@ func TestData(t *testing.T) {
 	}
 }
 
+func TestDataMultipleProviders(t *testing.T) {
+	datasetA := []byte("provider a dataset")
+	datasetB := []byte("provider b dataset")
+
+	cmp := Computation{
+		ID: "1",
+		Datasets: []Dataset{
+			{Hash: sha3.Sum256(datasetA), UserKey: []byte("provider-a"), Filename: "a.csv"},
+			{Hash: sha3.Sum256(datasetB), UserKey: []byte("provider-b"), Filename: "b.csv"},
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(algorithm.DatasetsDir, 0o755))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(algorithm.DatasetsDir)
+	})
+
+	sm := new(smmocks.StateMachine)
+	sm.On("GetState").Return(ReceivingData)
+	sm.On("SendEvent", DataReceived).Return().Once()
+
+	svc := &agentService{sm: sm, computation: cmp, received: make([]bool, len(cmp.Datasets))}
+
+	providerA := IndexToContext(context.Background(), 0)
+	providerB := IndexToContext(context.Background(), 1)
+
+	err := svc.Data(providerA, Dataset{Dataset: datasetB, Filename: "b.csv"})
+	assert.True(t, errors.Contains(err, ErrDatasetProviderMismatch), "expected %v, got %v", ErrDatasetProviderMismatch, err)
+
+	err = svc.Data(providerA, Dataset{Dataset: datasetA, Filename: "a.csv"})
+	require.NoError(t, err)
+
+	err = svc.Data(providerA, Dataset{Dataset: datasetA, Filename: "a.csv"})
+	assert.True(t, errors.Contains(err, ErrDatasetReceived), "expected %v, got %v", ErrDatasetReceived, err)
+
+	statuses := svc.Datasets()
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Received)
+	assert.False(t, statuses[1].Received)
+	assert.Equal(t, "b.csv", statuses[1].Filename)
+	sm.AssertNotCalled(t, "SendEvent", DataReceived)
+
+	err = svc.Data(providerB, Dataset{Dataset: datasetB, Filename: "b.csv"})
+	require.NoError(t, err)
+	sm.AssertCalled(t, "SendEvent", DataReceived)
+
+	err = svc.Data(providerB, Dataset{Dataset: datasetB, Filename: "b.csv"})
+	assert.True(t, errors.Contains(err, ErrAllManifestItemsReceived), "expected %v, got %v", ErrAllManifestItemsReceived, err)
+}
+
 func TestResult(t *testing.T) {
+	consumerKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
 	cases := []struct {
 		name     string
 		err      error
 		setup    func(svc *agentService)
 		ctxSetup func(ctx context.Context) context.Context
 		state    statemachine.State
+		validate func(t *testing.T, svc *agentService, result []byte)
 	}{
 		{
 			name: "Test results not ready",
@@ -278,6 +404,38 @@ func TestResult(t *testing.T) {
 			},
 			state: ConsumingResults,
 		},
+		{
+			name: "Test result encrypted for consumer",
+			err:  nil,
+			setup: func(svc *agentService) {
+				svc.computation.ResultConsumers = []ResultConsumer{{UserKey: []byte("key"), EncryptionKey: consumerKey.PublicKey().Bytes()}}
+				svc.result = []byte("result")
+			},
+			ctxSetup: func(ctx context.Context) context.Context {
+				return IndexToContext(ctx, 0)
+			},
+			state: ConsumingResults,
+			validate: func(t *testing.T, svc *agentService, result []byte) {
+				decrypted, err := encryption.Decrypt(consumerKey, result)
+				require.NoError(t, err)
+				assert.Equal(t, []byte("result"), decrypted)
+
+				again, err := svc.Result(IndexToContext(context.Background(), 0))
+				require.NoError(t, err)
+				assert.Equal(t, result, again, "a resumed download must get the same ciphertext")
+			},
+		},
+		{
+			name: "Test result encryption with invalid key",
+			err:  ErrResultEncryption,
+			setup: func(svc *agentService) {
+				svc.computation.ResultConsumers = []ResultConsumer{{UserKey: []byte("key"), EncryptionKey: []byte("invalid")}}
+			},
+			ctxSetup: func(ctx context.Context) context.Context {
+				return IndexToContext(ctx, 0)
+			},
+			state: Complete,
+		},
 	}
 
 	for _, tc := range cases {
@@ -285,9 +443,7 @@ func TestResult(t *testing.T) {
 		events.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx := metadata.NewIncomingContext(context.Background(),
-				metadata.Pairs(algorithm.AlgoTypeKey, "python", python.PyRuntimeKey, python.PyRuntime),
-			)
+			ctx := context.Background()
 
 			if tc.ctxSetup != nil {
 				ctx = tc.ctxSetup(ctx)
@@ -313,12 +469,15 @@ func TestResult(t *testing.T) {
 				}
 			}()
 			tc.setup(svc)
-			_, err := svc.Result(ctx)
+			result, err := svc.Result(ctx)
 			t.Cleanup(func() {
 				_ = os.RemoveAll("datasets")
 				_ = os.RemoveAll("results")
 			})
-			assert.ErrorIs(t, err, tc.err, "expected %v, got %v", tc.err, err)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.validate != nil {
+				tc.validate(t, svc, result)
+			}
 		})
 	}
 }
@@ -388,9 +547,7 @@ func TestAttestation(t *testing.T) {
 			events := new(mocks.Service)
 			events.EXPECT().SendEvent(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
-			ctx := metadata.NewIncomingContext(context.Background(),
-				metadata.Pairs(algorithm.AlgoTypeKey, "python", python.PyRuntimeKey, python.PyRuntime),
-			)
+			ctx := context.Background()
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 
@@ -400,10 +557,13 @@ func TestAttestation(t *testing.T) {
 			}
 			defer getQuote.Unset()
 
-			svc := New(ctx, mglog.NewMock(), events, client, 0)
+			svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 			time.Sleep(300 * time.Millisecond)
-			_, err := svc.Attestation(ctx, tc.reportData, tc.nonce, tc.platform)
+			report, err := svc.Attestation(ctx, tc.reportData, tc.nonce, tc.platform)
 			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err == nil {
+				assert.True(t, svc.(*agentService).reports.contains(report), "the owner may approve the served report")
+			}
 		})
 	}
 }
@@ -450,7 +610,7 @@ func TestAzureAttestationToken(t *testing.T) {
 
 			ctx := context.Background()
 
-			svc := New(ctx, mglog.NewMock(), events, client, 0)
+			svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 
 			_, err := svc.AzureAttestationToken(ctx, tc.nonce)
 			assert.True(t, errors.Contains(err, tc.err), "expected error %v, got %v", tc.err, err)
@@ -532,14 +692,14 @@ func TestStopComputation(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			events := new(mocks.Service)
-			events.On("SendEvent", mock.Anything, "Stopped", "Stopped", mock.Anything).Return()
+			events.On("SendEvent", mock.Anything, "Stopped", Terminated.String(), mock.Anything).Return()
 
 			ctx := context.Background()
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 
 			client := new(MockAttestationClient)
-			svc := New(ctx, mglog.NewMock(), events, client, 0).(*agentService)
+			svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil).(*agentService)
 
 			svc.computation = Computation{
 				ID:   "test-computation",
@@ -601,14 +761,11 @@ func TestStopComputationIntegration(t *testing.T) {
 	events := new(mocks.Service)
 	events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
-	ctx := metadata.NewIncomingContext(context.Background(),
-		metadata.Pairs(algorithm.AlgoTypeKey, "bin"),
-	)
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	client := new(MockAttestationClient)
-	svc := New(ctx, mglog.NewMock(), events, client, 0)
+	svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 
 	computation := Computation{
 		ID:   "integration-test",
@@ -647,7 +804,7 @@ func TestStopComputationConcurrent(t *testing.T) {
 	defer cancel()
 
 	client := new(MockAttestationClient)
-	svc := New(ctx, mglog.NewMock(), events, client, 0)
+	svc := New(ctx, mglog.NewMock(), events, client, 0, nil, false, nil, nil, nil)
 
 	svc.(*agentService).computation = Computation{
 		ID:   "concurrent-test",
@@ -674,3 +831,559 @@ func TestStopComputationConcurrent(t *testing.T) {
 
 	assert.True(t, len(errors) < numGoroutines, "All StopComputation calls failed")
 }
+
+func TestValidateResultConsumers(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		consumers []ResultConsumer
+		err       error
+	}{
+		{
+			name:      "consumer without encryption key",
+			consumers: []ResultConsumer{{UserKey: []byte("key")}},
+		},
+		{
+			name:      "consumer with valid encryption key",
+			consumers: []ResultConsumer{{UserKey: []byte("key"), EncryptionKey: key.PublicKey().Bytes()}},
+		},
+		{
+			name:      "consumer with invalid encryption key",
+			consumers: []ResultConsumer{{UserKey: []byte("key"), EncryptionKey: []byte("invalid")}},
+			err:       ErrInvalidEncryptionKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateResultConsumers(Computation{ResultConsumers: tc.consumers})
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestPublishTransition(t *testing.T) {
+	cases := []struct {
+		name       string
+		transition statemachine.Transition
+		runError   error
+		eventType  string
+		status     string
+		details    map[string]string
+	}{
+		{
+			name:       "manifest received",
+			transition: statemachine.Transition{From: ReceivingManifest, Event: ManifestReceived, To: ReceivingAlgorithm},
+			eventType:  "ManifestReceived",
+			status:     InProgress.String(),
+			details:    map[string]string{"from": "ReceivingManifest", "to": "ReceivingAlgorithm"},
+		},
+		{
+			name:       "algorithm received",
+			transition: statemachine.Transition{From: ReceivingAlgorithm, Event: AlgorithmReceived, To: ReceivingData},
+			eventType:  "AlgorithmReceived",
+			status:     InProgress.String(),
+			details:    map[string]string{"from": "ReceivingAlgorithm", "to": "ReceivingData"},
+		},
+		{
+			name:       "data received",
+			transition: statemachine.Transition{From: ReceivingData, Event: DataReceived, To: Running},
+			eventType:  "DataReceived",
+			status:     InProgress.String(),
+			details:    map[string]string{"from": "ReceivingData", "to": "Running"},
+		},
+		{
+			name:       "run finished",
+			transition: statemachine.Transition{From: Running, Event: RunComplete, To: ConsumingResults},
+			eventType:  "RunFinished",
+			status:     Ready.String(),
+			details:    map[string]string{"from": "Running", "to": "ConsumingResults"},
+		},
+		{
+			name:       "run failed",
+			transition: statemachine.Transition{From: Running, Event: RunFailed, To: Failed},
+			runError:   errors.New("algorithm exited with code 1"),
+			eventType:  "Error",
+			status:     Failed.String(),
+			details:    map[string]string{"from": "Running", "to": "Failed", "error": "algorithm exited with code 1"},
+		},
+		{
+			name:       "results consumed",
+			transition: statemachine.Transition{From: ConsumingResults, Event: ResultsConsumed, To: Complete},
+			eventType:  "ResultsConsumed",
+			status:     Completed.String(),
+			details:    map[string]string{"from": "ConsumingResults", "to": "Complete"},
+		},
+		{
+			name:       "start is not published",
+			transition: statemachine.Transition{From: Idle, Event: Start, To: ReceivingManifest},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := new(mocks.Service)
+			svc := &agentService{
+				eventSvc:    events,
+				logger:      mglog.NewMock(),
+				computation: Computation{ID: "test-computation"},
+				runError:    tc.runError,
+			}
+
+			var details json.RawMessage
+			if tc.eventType != "" {
+				events.On("SendEvent", "test-computation", tc.eventType, tc.status, mock.Anything).Return().Run(func(args mock.Arguments) {
+					details = args.Get(3).(json.RawMessage)
+				}).Once()
+			}
+
+			svc.publishTransition(tc.transition)
+
+			events.AssertExpectations(t)
+			if tc.eventType == "" {
+				events.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			var got map[string]string
+			require.NoError(t, json.Unmarshal(details, &got))
+			assert.Equal(t, tc.details, got)
+		})
+	}
+}
+
+func TestInitComputationResultCodec(t *testing.T) {
+	cases := []struct {
+		name  string
+		codec string
+		err   error
+	}{
+		{name: "default codec", codec: ""},
+		{name: "zstd codec", codec: internal.CodecZstd},
+		{name: "unsupported codec", codec: "lz4", err: internal.ErrUnsupportedCodec},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := new(mocks.Service)
+			events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, nil, false, nil, nil, nil)
+
+			cmp := testComputation(t)
+			cmp.ResultCodec = tc.codec
+
+			err := svc.InitComputation(ctx, cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestInitComputationStorage(t *testing.T) {
+	cases := []struct {
+		name    string
+		storage *Storage
+		err     error
+	}{
+		{name: "default storage"},
+		{name: "disk storage", storage: &Storage{Type: storage.Disk}},
+		{name: "sized disk storage", storage: &Storage{Type: storage.Disk, SizeMB: 512}, err: storage.ErrInvalidStorage},
+		{name: "unknown storage", storage: &Storage{Type: "nfs"}, err: storage.ErrInvalidStorage},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := new(mocks.Service)
+			events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, nil, false, nil, nil, nil)
+
+			cmp := testComputation(t)
+			cmp.Storage = tc.storage
+
+			err := svc.InitComputation(ctx, cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestInitComputationEventEncryption(t *testing.T) {
+	ownerKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		encryption *EventEncryption
+		encrypted  bool
+		err        error
+	}{
+		{
+			name: "events in the clear",
+		},
+		{
+			name:       "encrypted event details",
+			encryption: &EventEncryption{Key: ownerKey.PublicKey().Bytes(), Fields: []string{"to"}},
+			encrypted:  true,
+		},
+		{
+			name:       "invalid event encryption key",
+			encryption: &EventEncryption{Key: []byte("invalid")},
+			err:        ErrInvalidEventEncryptionKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			details := make(chan json.RawMessage, 1)
+			evts := new(mocks.Service)
+			evts.On("SendEvent", mock.Anything, events.ManifestReceived, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { details <- args.Get(3).(json.RawMessage) }).Return().Maybe()
+			evts.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			svc := New(ctx, mglog.NewMock(), evts, new(MockAttestationClient), 0, nil, false, nil, nil, nil)
+
+			cmp := testComputation(t)
+			cmp.EventEncryption = tc.encryption
+
+			err := svc.InitComputation(ctx, cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+
+			var published json.RawMessage
+			select {
+			case published = <-details:
+			case <-time.After(time.Second):
+				t.Fatal("manifest received event was not published")
+			}
+
+			var obj map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(published, &obj))
+			_, encrypted := obj[events.EncryptedFieldsKey]
+			assert.Equal(t, tc.encrypted, encrypted)
+			if tc.encrypted {
+				decrypted, err := events.DecryptDetails(ownerKey, published)
+				require.NoError(t, err)
+				assert.Contains(t, string(decrypted), ReceivingAlgorithm.String())
+			}
+		})
+	}
+}
+
+func TestAlgorithmOutputEvents(t *testing.T) {
+	ownerKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		encryption *EventEncryption
+		published  map[string]string
+	}{
+		{
+			name:      "output removed from events in the clear",
+			published: map[string]string{"reason": "exit_code"},
+		},
+		{
+			name:       "output encrypted with other listed fields",
+			encryption: &EventEncryption{Key: ownerKey.PublicKey().Bytes(), Fields: []string{"reason"}},
+			published:  map[string]string{"reason": "exit_code", "stderr": "secret", "output": "secret"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			details := make(chan json.RawMessage, 1)
+			evts := new(mocks.Service)
+			evts.On("SendEvent", mock.Anything, events.AlgorithmFailed, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { details <- args.Get(3).(json.RawMessage) }).Return()
+			evts.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			svc := New(ctx, mglog.NewMock(), evts, new(MockAttestationClient), 0, nil, false, nil, nil, nil).(*agentService)
+
+			cmp := testComputation(t)
+			cmp.EventEncryption = tc.encryption
+			require.NoError(t, svc.InitComputation(ctx, cmp))
+
+			svc.eventSvc.SendEvent(cmp.ID, events.AlgorithmFailed, Failed.String(), json.RawMessage(`{"reason":"exit_code","stderr":"secret","output":"secret"}`))
+
+			published := <-details
+			if tc.encryption != nil {
+				var obj map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal(published, &obj))
+				assert.JSONEq(t, `["output","reason","stderr"]`, string(obj[events.EncryptedFieldsKey]))
+
+				published, err = events.DecryptDetails(ownerKey, published)
+				require.NoError(t, err)
+			}
+
+			var obj map[string]string
+			require.NoError(t, json.Unmarshal(published, &obj))
+			assert.Equal(t, tc.published, obj)
+		})
+	}
+}
+
+type streamedOutput struct {
+	stdout, stderr bytes.Buffer
+}
+
+func (o *streamedOutput) Stdout() io.Writer { return &o.stdout }
+
+func (o *streamedOutput) Stderr() io.Writer { return &o.stderr }
+
+func TestOutputStreaming(t *testing.T) {
+	ownerKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		encryption *EventEncryption
+		streamed   bool
+	}{
+		{
+			name: "not streamed without event encryption",
+		},
+		{
+			name:       "not streamed unless the manifest opts in",
+			encryption: &EventEncryption{Key: ownerKey.PublicKey().Bytes()},
+		},
+		{
+			name:       "streamed encrypted for the owner",
+			encryption: &EventEncryption{Key: ownerKey.PublicKey().Bytes(), StreamOutput: true},
+			streamed:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			evts := new(mocks.Service)
+			evts.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+			output := &streamedOutput{}
+			svc := New(ctx, mglog.NewMock(), evts, new(MockAttestationClient), 0, nil, false, output, nil, nil).(*agentService)
+
+			cmp := testComputation(t)
+			cmp.EventEncryption = tc.encryption
+			require.NoError(t, svc.InitComputation(ctx, cmp))
+
+			stdout, stderr := logging.Streams(svc.output)
+			if stdout != nil {
+				_, err := stdout.Write([]byte("epoch 1\n"))
+				require.NoError(t, err)
+			}
+			_, err := stderr.Write([]byte("warning\n"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "warning\n", svc.stderrTail.String(), "the stderr tail is always kept")
+			if !tc.streamed {
+				assert.Nil(t, stdout)
+				assert.Empty(t, output.stdout.String()+output.stderr.String())
+				return
+			}
+
+			assert.NotContains(t, output.stdout.String(), "epoch")
+			plaintext, err := logging.DecryptLine(ownerKey, output.stdout.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, "epoch 1\n", string(plaintext))
+		})
+	}
+}
+
+func TestRunComputationSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	cases := []struct {
+		name   string
+		runErr error
+		spans  []string
+	}{
+		{
+			name:  "successful run",
+			spans: []string{"execute_algorithm", "package_results", "sign_results", "run_computation"},
+		},
+		{
+			name:   "failed run",
+			runErr: errors.New("algorithm failed"),
+			spans:  []string{"execute_algorithm", "run_computation"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := new(mocks.Service)
+			events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+			sm := new(smmocks.StateMachine)
+			sm.On("SendEvent", mock.Anything).Return()
+
+			algo := new(algomocks.Algorithm)
+			algo.On("Run").Return(tc.runErr)
+
+			parent := trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{2},
+				TraceFlags: trace.FlagsSampled,
+				Remote:     true,
+			})
+
+			svc := &agentService{
+				sm:          sm,
+				eventSvc:    events,
+				logger:      mglog.NewMock(),
+				algorithm:   algo,
+				computation: testComputation(t),
+				traceCtx:    trace.ContextWithRemoteSpanContext(context.Background(), parent),
+			}
+			svc.lineage = newLineage(svc.computation)
+
+			before := len(recorder.Ended())
+			svc.runComputation(Running)
+
+			ended := recorder.Ended()[before:]
+			names := make([]string, len(ended))
+			for i, span := range ended {
+				names[i] = span.Name()
+				assert.Equal(t, parent.TraceID(), span.SpanContext().TraceID(), "span %s is not in the manager trace", span.Name())
+			}
+			assert.Equal(t, tc.spans, names)
+
+			root := ended[len(ended)-1]
+			assert.Equal(t, parent.SpanID(), root.Parent().SpanID())
+			if tc.runErr != nil {
+				assert.Equal(t, codes.Error, root.Status().Code)
+				return
+			}
+
+			manifest, err := NewResultManifest(svc.computation.ID, svc.result)
+			require.NoError(t, err)
+			require.NotNil(t, manifest.Lineage, "results carry the computation lineage")
+			assert.Equal(t, svc.lineage, *manifest.Lineage)
+		})
+	}
+}
+
+func TestInitComputationAlreadyAssigned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := new(mocks.Service)
+	events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+	svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, nil, false, nil, nil, nil)
+
+	invalid := testComputation(t)
+	invalid.ResultCodec = "lz4"
+	err := svc.InitComputation(ctx, invalid)
+	assert.True(t, errors.Contains(err, internal.ErrUnsupportedCodec), "expected %v, got %v", internal.ErrUnsupportedCodec, err)
+
+	first := testComputation(t)
+	require.NoError(t, svc.InitComputation(ctx, first), "an invalid manifest does not assign the agent")
+
+	second := testComputation(t)
+	second.ID = "2"
+	err = svc.InitComputation(ctx, second)
+	assert.True(t, errors.Contains(err, ErrAlreadyAssigned), "expected %v, got %v", ErrAlreadyAssigned, err)
+
+	require.NoError(t, svc.StopComputation(ctx))
+	assert.NoError(t, svc.InitComputation(ctx, second), "a stopped computation frees the agent")
+}
+
+func TestInitComputationConcurrent(t *testing.T) {
+	const runs = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := new(mocks.Service)
+	events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+	svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, nil, false, nil, nil, nil).(*agentService)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, runs)
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			cmp := testComputation(t)
+			cmp.ID = fmt.Sprint(i)
+			<-start
+			errs[i] = svc.InitComputation(ctx, cmp)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		if err == nil {
+			assert.Equal(t, -1, winner, "manifests %d and %d were both accepted", winner, i)
+			winner = i
+			continue
+		}
+		assert.True(t, errors.Contains(err, ErrAlreadyAssigned), "expected %v, got %v", ErrAlreadyAssigned, err)
+	}
+	require.NotEqual(t, -1, winner, "no manifest was accepted")
+
+	svc.mu.Lock()
+	assert.Equal(t, fmt.Sprint(winner), svc.computation.ID)
+	svc.mu.Unlock()
+
+	assert.Eventually(t, func() bool { return svc.State() == ReceivingAlgorithm.String() }, time.Second, 10*time.Millisecond)
+}
+
+func TestInitComputationTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	expired := make(chan json.RawMessage, 1)
+	events := new(mocks.Service)
+	events.On("SendEvent", mock.Anything, ComputationExpiredEvent, Terminated.String(), mock.Anything).
+		Run(func(args mock.Arguments) { expired <- args.Get(3).(json.RawMessage) }).Return()
+	events.On("SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+	svc := New(ctx, mglog.NewMock(), events, new(MockAttestationClient), 0, nil, false, nil, nil, nil)
+
+	invalid := testComputation(t)
+	invalid.TTL = "0s"
+	err := svc.InitComputation(ctx, invalid)
+	assert.True(t, errors.Contains(err, ErrInvalidTTL), "expected %v, got %v", ErrInvalidTTL, err)
+
+	cmp := testComputation(t)
+	cmp.Version = ManifestVersion
+	cmp.TTL = "300ms"
+	require.NoError(t, svc.InitComputation(ctx, cmp))
+
+	select {
+	case details := <-expired:
+		assert.JSONEq(t, `{"ttl":"300ms"}`, string(details))
+	case <-time.After(5 * time.Second):
+		t.Fatal("computation did not expire")
+	}
+
+	assert.Eventually(t, func() bool {
+		return svc.State() == ReceivingManifest.String()
+	}, 5*time.Second, 50*time.Millisecond, "an expired computation frees the agent")
+	assert.NoError(t, svc.InitComputation(ctx, testComputation(t)))
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/statemachine"
+)
+
+// Exit statuses of the computation reported by the AgentTerminated event.
+const (
+	ExitSucceeded   = "succeeded"
+	ExitFailed      = "failed"
+	ExitInterrupted = "interrupted"
+	ExitNotRun      = "not_run"
+)
+
+var (
+	// drainPollInterval is how often the agent checks whether the algorithm
+	// run ended while it drains.
+	drainPollInterval = 100 * time.Millisecond
+	// algorithmStopTimeout bounds the wait for an algorithm stopped at the end
+	// of the grace period to end its run.
+	algorithmStopTimeout = 5 * time.Second
+)
+
+// Shutdown rejects new uploads and waits for the running algorithm to end. The
+// algorithm is stopped once ctx is done, which interrupts the computation.
+// The AgentTerminated event reporting the exit status is published last, later
+// calls return at once.
+func (as *agentService) Shutdown(ctx context.Context) error {
+	as.mu.Lock()
+	if as.shuttingDown {
+		as.mu.Unlock()
+		return nil
+	}
+	as.shuttingDown = true
+	as.mu.Unlock()
+
+	as.logger.Info("agent shutting down", "state", as.sm.GetState().String())
+
+	interrupted := !as.waitRun(ctx)
+	if interrupted {
+		as.logger.Warn("shutdown grace period expired, stopping the algorithm")
+
+		as.mu.Lock()
+		algo := as.algorithm
+		as.mu.Unlock()
+		if algo != nil {
+			if err := algo.Stop(); err != nil {
+				as.logger.Warn("failed to stop the algorithm", "error", err)
+			}
+		}
+
+		stopCtx, cancel := context.WithTimeout(context.Background(), algorithmStopTimeout)
+		defer cancel()
+		as.waitRun(stopCtx)
+	}
+
+	as.publishTermination(interrupted)
+
+	return nil
+}
+
+func (as *agentService) isShuttingDown() bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	return as.shuttingDown
+}
+
+// waitRun waits for the algorithm run to end, it reports false when ctx is done first.
+func (as *agentService) waitRun(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for as.sm.GetState() == Running {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+
+	return true
+}
+
+// publishTermination publishes the AgentTerminated event with the exit status
+// of the computation.
+func (as *agentService) publishTermination(interrupted bool) {
+	state := as.sm.GetState()
+
+	details := map[string]string{
+		"state":       state.String(),
+		"exit_status": exitStatus(state, interrupted),
+		"interrupted": strconv.FormatBool(interrupted),
+	}
+	if state == Failed && as.runError != nil {
+		details["error"] = as.runError.Error()
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		data = json.RawMessage{}
+	}
+
+	as.eventSvc.SendEvent(as.computation.ID, events.AgentTerminated, Terminated.String(), data)
+}
+
+func exitStatus(state statemachine.State, interrupted bool) string {
+	switch {
+	case interrupted:
+		return ExitInterrupted
+	case state == ConsumingResults || state == Complete:
+		return ExitSucceeded
+	case state == Failed:
+		return ExitFailed
+	default:
+		return ExitNotRun
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	algomocks "github.com/ultravioletrs/cocos/agent/algorithm/mocks"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	"github.com/ultravioletrs/cocos/agent/statemachine"
+)
+
+func TestShutdown(t *testing.T) {
+	pollInterval, stopTimeout := drainPollInterval, algorithmStopTimeout
+	drainPollInterval, algorithmStopTimeout = time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { drainPollInterval, algorithmStopTimeout = pollInterval, stopTimeout })
+
+	cases := []struct {
+		desc    string
+		state   AgentState
+		grace   time.Duration
+		runEnd  AgentState // the state a run ends in while the agent drains
+		stopEnd AgentState // the state a run ends in once the algorithm is stopped
+		stopErr error
+		details map[string]string
+	}{
+		{
+			desc:    "no computation run",
+			state:   ReceivingAlgorithm,
+			grace:   time.Second,
+			details: map[string]string{"state": ReceivingAlgorithm.String(), "exit_status": ExitNotRun, "interrupted": "false"},
+		},
+		{
+			desc:    "run ends within the grace period",
+			state:   Running,
+			grace:   time.Second,
+			runEnd:  ConsumingResults,
+			details: map[string]string{"state": ConsumingResults.String(), "exit_status": ExitSucceeded, "interrupted": "false"},
+		},
+		{
+			desc:    "run fails within the grace period",
+			state:   Running,
+			grace:   time.Second,
+			runEnd:  Failed,
+			details: map[string]string{"state": Failed.String(), "exit_status": ExitFailed, "interrupted": "false", "error": "algorithm failed"},
+		},
+		{
+			desc:    "algorithm stopped after the grace period",
+			state:   Running,
+			grace:   10 * time.Millisecond,
+			stopEnd: Failed,
+			details: map[string]string{"state": Failed.String(), "exit_status": ExitInterrupted, "interrupted": "true", "error": "algorithm failed"},
+		},
+		{
+			desc:    "algorithm does not stop",
+			state:   Running,
+			grace:   10 * time.Millisecond,
+			stopErr: fmt.Errorf("failed to kill process"),
+			details: map[string]string{"state": Running.String(), "exit_status": ExitInterrupted, "interrupted": "true"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sm := statemachine.NewStateMachine(tc.state)
+
+			var details map[string]string
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "1", events.AgentTerminated, Terminated.String(), mock.Anything).Return().Once().Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(3).(json.RawMessage), &details))
+			})
+
+			algo := new(algomocks.Algorithm)
+			algo.On("Stop").Return(tc.stopErr).Run(func(mock.Arguments) {
+				if tc.stopEnd != Idle {
+					sm.Reset(tc.stopEnd)
+				}
+			})
+
+			svc := &agentService{
+				sm:          sm,
+				eventSvc:    eventSvc,
+				logger:      mglog.NewMock(),
+				computation: Computation{ID: "1"},
+				algorithm:   algo,
+				runError:    fmt.Errorf("algorithm failed"),
+			}
+
+			if tc.runEnd != Idle {
+				go func() {
+					time.Sleep(5 * time.Millisecond)
+					sm.Reset(tc.runEnd)
+				}()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.grace)
+			defer cancel()
+			assert.NoError(t, svc.Shutdown(ctx))
+			assert.Equal(t, tc.details, details)
+
+			// Later calls neither drain again nor publish the termination again.
+			assert.NoError(t, svc.Shutdown(context.Background()))
+			eventSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShutdownRejectsUploads(t *testing.T) {
+	eventSvc := new(mocks.Service)
+	eventSvc.On("SendEvent", mock.Anything, events.AgentTerminated, Terminated.String(), mock.Anything).Return()
+
+	svc := &agentService{
+		sm:       statemachine.NewStateMachine(ReceivingData),
+		eventSvc: eventSvc,
+		logger:   mglog.NewMock(),
+	}
+	assert.NoError(t, svc.Shutdown(context.Background()))
+
+	ctx := context.Background()
+	assert.True(t, errors.Contains(svc.InitComputation(ctx, Computation{}), ErrShuttingDown))
+	assert.True(t, errors.Contains(svc.Algo(ctx, Algorithm{}), ErrShuttingDown))
+	assert.True(t, errors.Contains(svc.Data(ctx, Dataset{}), ErrShuttingDown))
+	assert.True(t, errors.Contains(svc.AttachDatasetDisk(ctx, t.TempDir()), ErrShuttingDown))
+}
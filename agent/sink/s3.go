@@ -0,0 +1,152 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegion = "us-east-1"
+	sigv4         = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+	// maxErrorBody is how much of the response of a rejected upload is reported.
+	maxErrorBody = 1024
+)
+
+// now is a variable so tests can sign requests at a fixed time.
+var now = time.Now
+
+type s3 struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	bucket   string
+	creds    Credentials
+}
+
+func newS3(cfg Config, client *http.Client) *s3 {
+	region := cfg.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	return &s3{
+		client:   client,
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/"),
+		region:   region,
+		bucket:   cfg.Bucket,
+		creds:    cfg.Credentials,
+	}
+}
+
+// Upload puts the object with a single request to the path-style URL of the
+// bucket, signed with AWS Signature Version 4 when credentials are set.
+func (s *s3) Upload(ctx context.Context, key string, data []byte) (string, error) {
+	objectURL := s.endpoint + "/" + uriEncode(s.bucket, true) + "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	if s.creds.AccessKeyID != "" {
+		payloadHash := sha256.Sum256(data)
+		signRequest(req, hex.EncodeToString(payloadHash[:]), s.creds, s.region, now().UTC())
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		return "", fmt.Errorf("%w: %s: %s", ErrUploadFailed, res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return objectURL, nil
+}
+
+// signRequest adds the AWS Signature Version 4 Authorization header to the
+// request, signing its host, its x-amz-* headers and the headers already set.
+func signRequest(req *http.Request, payloadHash string, creds Credentials, region string, t time.Time) {
+	amzDate := t.Format(amzDateFormat)
+	scope := t.Format("20060102") + "/" + region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigv4 + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{t.Format("20060102"), region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigv4, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes every byte of s but unreserved characters, and
+// slashes unless encodeSlash is set, as S3 canonical URIs require.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
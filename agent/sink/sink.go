@@ -0,0 +1,81 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sink uploads computation results to storage outside of the CVM, for
+// results too big to be pulled over the agent API.
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// TypeS3 uploads to a bucket of an S3-compatible object storage.
+const TypeS3 = "s3"
+
+var (
+	// ErrUnsupportedSink indicates a sink type other than s3.
+	ErrUnsupportedSink = errors.New("unsupported result sink, expected s3")
+	// ErrInvalidConfig indicates a sink without a valid endpoint or bucket.
+	ErrInvalidConfig = errors.New("invalid result sink configuration")
+	// ErrUploadFailed indicates an upload the storage rejected.
+	ErrUploadFailed = errors.New("result upload failed")
+)
+
+// Sink stores objects outside of the CVM.
+type Sink interface {
+	// Upload stores the data under the key and returns the URL of the object.
+	Upload(ctx context.Context, key string, data []byte) (string, error)
+}
+
+// Credentials sign the uploads, anonymous uploads are sent when they are empty.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Config selects the sink the results are uploaded to.
+type Config struct {
+	// Type is the sink type, TypeS3 if empty.
+	Type string
+	// Endpoint is the http or https URL of the object storage.
+	Endpoint string
+	// Region is the region uploads are signed for, us-east-1 if empty.
+	Region      string
+	Bucket      string
+	Credentials Credentials
+}
+
+// Validate checks the sink type, endpoint and bucket.
+func (cfg Config) Validate() error {
+	switch cfg.Type {
+	case "", TypeS3:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedSink, cfg.Type)
+	}
+
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: endpoint: %v", ErrInvalidConfig, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: endpoint %q is not an http or https URL", ErrInvalidConfig, cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return fmt.Errorf("%w: no bucket", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// New returns the sink of the configuration, sending its requests with the client.
+func New(cfg Config, client *http.Client) (Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newS3(cfg, client), nil
+}
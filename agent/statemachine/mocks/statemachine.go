@@ -127,6 +127,46 @@ func (_c *StateMachine_GetState_Call) RunAndReturn(run func() statemachine.State
 	return _c
 }
 
+// OnTransition provides a mock function for the type StateMachine
+func (_mock *StateMachine) OnTransition(hook statemachine.TransitionHook) {
+	_mock.Called(hook)
+	return
+}
+
+// StateMachine_OnTransition_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OnTransition'
+type StateMachine_OnTransition_Call struct {
+	*mock.Call
+}
+
+// OnTransition is a helper method to define mock.On call
+//   - hook statemachine.TransitionHook
+func (_e *StateMachine_Expecter) OnTransition(hook interface{}) *StateMachine_OnTransition_Call {
+	return &StateMachine_OnTransition_Call{Call: _e.mock.On("OnTransition", hook)}
+}
+
+func (_c *StateMachine_OnTransition_Call) Run(run func(hook statemachine.TransitionHook)) *StateMachine_OnTransition_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 statemachine.TransitionHook
+		if args[0] != nil {
+			arg0 = args[0].(statemachine.TransitionHook)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *StateMachine_OnTransition_Call) Return() *StateMachine_OnTransition_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *StateMachine_OnTransition_Call) RunAndReturn(run func(hook statemachine.TransitionHook)) *StateMachine_OnTransition_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Reset provides a mock function for the type StateMachine
 func (_mock *StateMachine) Reset(initialState statemachine.State) {
 	_mock.Called(initialState)
@@ -18,6 +18,9 @@ type Event interface {
 
 type Action func(State)
 
+// TransitionHook is called synchronously, in order, after every successful transition.
+type TransitionHook func(Transition)
+
 type Transition struct {
 	From  State
 	Event Event
@@ -27,6 +30,7 @@ type Transition struct {
 type StateMachine interface {
 	AddTransition(t Transition)
 	SetAction(state State, action Action)
+	OnTransition(hook TransitionHook)
 	GetState() State
 	SendEvent(event Event)
 	Start(ctx context.Context) error
@@ -38,6 +42,7 @@ type stateMachine struct {
 	currentState State
 	transitions  map[State]map[Event]State
 	actions      map[State]Action
+	hook         TransitionHook
 	eventChan    chan Event
 	resetChan    chan struct{}
 }
@@ -69,6 +74,13 @@ func (sm *stateMachine) SetAction(state State, action Action) {
 	sm.actions[state] = action
 }
 
+func (sm *stateMachine) OnTransition(hook TransitionHook) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.hook = hook
+}
+
 func (sm *stateMachine) GetState() State {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -137,8 +149,13 @@ func (sm *stateMachine) handleEvent(event Event) error {
 	sm.mu.Lock()
 	sm.currentState = nextState
 	action := sm.actions[nextState]
+	hook := sm.hook
 	sm.mu.Unlock()
 
+	if hook != nil {
+		hook(Transition{From: currentState, Event: event, To: nextState})
+	}
+
 	if action != nil {
 		go action(nextState)
 	}
@@ -571,6 +571,40 @@ func TestStateMachine_HandleEvent(t *testing.T) {
 	}
 }
 
+func TestStateMachine_OnTransition(t *testing.T) {
+	sm := NewStateMachine(StateIdle).(*stateMachine)
+	sm.AddTransition(Transition{From: StateIdle, Event: EventStart, To: StateRunning})
+	sm.AddTransition(Transition{From: StateRunning, Event: EventStop, To: StateStopped})
+
+	var transitions []Transition
+	sm.OnTransition(func(tr Transition) {
+		transitions = append(transitions, tr)
+	})
+
+	if err := sm.handleEvent(EventStart); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sm.handleEvent(EventPause); err == nil {
+		t.Fatal("Expected error for invalid transition")
+	}
+	if err := sm.handleEvent(EventStop); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Transition{
+		{From: StateIdle, Event: EventStart, To: StateRunning},
+		{From: StateRunning, Event: EventStop, To: StateStopped},
+	}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Hook called %d times, want %d", len(transitions), len(expected))
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Transition %d = %v, want %v", i, transitions[i], expected[i])
+		}
+	}
+}
+
 func TestStateMachine_SendEvent_ThreadSafety(t *testing.T) {
 	sm := NewStateMachine(StateIdle)
 	sm.AddTransition(Transition{From: StateIdle, Event: EventStart, To: StateRunning})
@@ -0,0 +1,173 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/storage"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+const (
+	datasetsStorePrefix = "cocos-datasets-"
+	stagedDatasetPerm   = 0o444
+)
+
+var _ algorithm.Algorithm = (*stepsAlgorithm)(nil)
+
+// datasetStore keeps received datasets outside of the datasets directory of
+// the sandbox so that the datasets directory of each step only holds the
+// datasets it lists. The store is readable by the user the steps run as, so
+// staging does not keep a step from reading the datasets of the others.
+type datasetStore struct {
+	storage storage.Storage
+	dir     string
+	// datasets is the directory the datasets of a step are staged in.
+	datasets   string
+	decompress map[string]bool
+	// names are the names datasets are staged under, by manifest filename.
+	names map[string]string
+	// nested extracts compressed datasets into a directory named after them.
+	nested bool
+}
+
+func newDatasetStore(st storage.Storage, sandbox algorithm.Sandbox, nested bool) (*datasetStore, error) {
+	// MkdirTemp creates the directory with 0700 permissions.
+	dir, err := os.MkdirTemp(sandbox.Root, datasetsStorePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// The storage backs the directory, so the datasets are kept where the manifest asks.
+	if err := st.Create(dir); err != nil {
+		os.Remove(dir)
+		return nil, err
+	}
+
+	return &datasetStore{storage: st, dir: dir, datasets: sandbox.Datasets(), decompress: make(map[string]bool), names: make(map[string]string), nested: nested}, nil
+}
+
+func (ds *datasetStore) add(filename, name string, data []byte, decompress bool) error {
+	if err := os.WriteFile(filepath.Join(ds.dir, filepath.Base(filename)), data, 0o600); err != nil {
+		return err
+	}
+	ds.register(filename, name, decompress)
+
+	return nil
+}
+
+// register records how a dataset stored under filename is staged.
+func (ds *datasetStore) register(filename, name string, decompress bool) {
+	ds.decompress[filename] = decompress
+	ds.names[filename] = name
+}
+
+// stage recreates the datasets directory with only the given datasets.
+func (ds *datasetStore) stage(datasets []string) error {
+	if err := ds.storage.Remove(ds.datasets); err != nil {
+		return err
+	}
+
+	if err := ds.storage.Create(ds.datasets); err != nil {
+		return err
+	}
+
+	for _, filename := range datasets {
+		src := filepath.Join(ds.dir, filepath.Base(filename))
+		name, ok := ds.names[filename]
+		if !ok {
+			name = filepath.Base(filename)
+		}
+
+		if ds.decompress[filename] {
+			data, err := os.ReadFile(src)
+			if err != nil {
+				return err
+			}
+			if err := writeDataset(ds.datasets, name, data, true, ds.nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		dst := filepath.Join(ds.datasets, name)
+		if err := internal.CopyFile(src, dst); err != nil {
+			return err
+		}
+		if err := os.Chmod(dst, stagedDatasetPerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stepsAlgorithm runs the algorithm once per manifest step, staging the
+// datasets each step lists before it starts.
+type stepsAlgorithm struct {
+	mu           sync.Mutex
+	logger       *slog.Logger
+	steps        []Step
+	store        *datasetStore
+	newAlgorithm func(args []string) algorithm.Algorithm
+	current      algorithm.Algorithm
+	stopped      bool
+}
+
+func newStepsAlgorithm(logger *slog.Logger, steps []Step, store *datasetStore, newAlgorithm func(args []string) algorithm.Algorithm) *stepsAlgorithm {
+	return &stepsAlgorithm{
+		logger:       logger,
+		steps:        steps,
+		store:        store,
+		newAlgorithm: newAlgorithm,
+	}
+}
+
+func (sa *stepsAlgorithm) Run() error {
+	defer func() {
+		if err := sa.store.storage.Remove(sa.store.datasets); err != nil {
+			sa.logger.Warn(fmt.Sprintf("error removing staged datasets: %s", err.Error()))
+		}
+	}()
+
+	for i, step := range sa.steps {
+		if err := sa.store.stage(step.Datasets); err != nil {
+			return fmt.Errorf("error staging datasets for step %d (%s): %v", i, step.Name, err)
+		}
+
+		sa.mu.Lock()
+		if sa.stopped {
+			sa.mu.Unlock()
+			return nil
+		}
+		sa.current = sa.newAlgorithm(step.Args)
+		current := sa.current
+		sa.mu.Unlock()
+
+		sa.logger.Debug(fmt.Sprintf("running step %d (%s) with datasets %v", i, step.Name, step.Datasets))
+
+		if err := current.Run(); err != nil {
+			return fmt.Errorf("step %d (%s) failed: %w", i, step.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (sa *stepsAlgorithm) Stop() error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.stopped = true
+	if sa.current == nil {
+		return nil
+	}
+
+	return sa.current.Stop()
+}
@@ -0,0 +1,128 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+func TestValidateSteps(t *testing.T) {
+	cases := []struct {
+		desc string
+		cmp  Computation
+		err  error
+	}{
+		{
+			desc: "no steps",
+			cmp:  Computation{Datasets: Datasets{{Filename: "a.csv"}}},
+		},
+		{
+			desc: "steps with declared datasets",
+			cmp: Computation{
+				Datasets:  Datasets{{Filename: "a.csv"}, {Filename: "b.csv"}},
+				Algorithm: Algorithm{Steps: []Step{{Name: "pre", Datasets: []string{"a.csv"}}, {Name: "train"}}},
+			},
+		},
+		{
+			desc: "step with undeclared dataset",
+			cmp: Computation{
+				Datasets:  Datasets{{Filename: "a.csv"}},
+				Algorithm: Algorithm{Steps: []Step{{Name: "pre", Datasets: []string{"b.csv"}}}},
+			},
+			err: ErrUndeclaredStepDataset,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateSteps(tc.cmp)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v got %v", tc.err, err)
+		})
+	}
+}
+
+func TestStepsAlgorithmRun(t *testing.T) {
+	sandbox := algorithm.Sandbox{Root: t.TempDir()}
+
+	store, err := newDatasetStore(storage.NewDisk(), sandbox, false)
+	require.NoError(t, err)
+
+	require.NoError(t, store.add("a.csv", "a.csv", []byte("provider a"), false))
+	require.NoError(t, store.add("b.csv", "b.csv", []byte("provider b"), false))
+
+	steps := []Step{
+		{Name: "preprocess", Args: []string{"--pre"}, Datasets: []string{"a.csv"}},
+		{Name: "train", Args: []string{"--train"}, Datasets: []string{"b.csv"}},
+	}
+
+	var seen [][]string
+	var args [][]string
+	newAlgorithm := func(a []string) algorithm.Algorithm {
+		args = append(args, a)
+		algo := mocks.NewAlgorithm(t)
+		algo.On("Run").Return(nil).Run(func(mock.Arguments) {
+			entries, err := os.ReadDir(sandbox.Datasets())
+			require.NoError(t, err)
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			seen = append(seen, names)
+		}).Once()
+		return algo
+	}
+
+	sa := newStepsAlgorithm(slog.Default(), steps, store, newAlgorithm)
+	require.NoError(t, sa.Run())
+
+	assert.Equal(t, [][]string{{"a.csv"}, {"b.csv"}}, seen)
+	assert.Equal(t, [][]string{{"--pre"}, {"--train"}}, args)
+
+	_, err = os.Stat(sandbox.Datasets())
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(store.dir, "b.csv"))
+	assert.NoError(t, err)
+}
+
+func TestStepsAlgorithmRunFailure(t *testing.T) {
+	store, err := newDatasetStore(storage.NewDisk(), algorithm.Sandbox{Root: t.TempDir()}, false)
+	require.NoError(t, err)
+
+	calls := 0
+	newAlgorithm := func(a []string) algorithm.Algorithm {
+		calls++
+		algo := mocks.NewAlgorithm(t)
+		algo.On("Run").Return(assert.AnError).Once()
+		return algo
+	}
+
+	sa := newStepsAlgorithm(slog.Default(), []Step{{Name: "first"}, {Name: "second"}}, store, newAlgorithm)
+	err = sa.Run()
+	assert.ErrorContains(t, err, assert.AnError.Error())
+	assert.Equal(t, 1, calls)
+}
+
+func TestStepsAlgorithmStop(t *testing.T) {
+	store, err := newDatasetStore(storage.NewDisk(), algorithm.Sandbox{Root: t.TempDir()}, false)
+	require.NoError(t, err)
+
+	sa := newStepsAlgorithm(slog.Default(), []Step{{Name: "first"}}, store, nil)
+	assert.NoError(t, sa.Stop())
+
+	algo := mocks.NewAlgorithm(t)
+	algo.On("Stop").Return(nil).Once()
+	sa.current = algo
+	assert.NoError(t, sa.Stop())
+}
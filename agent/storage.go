@@ -0,0 +1,144 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/storage"
+)
+
+// ErrStorageExceeded indicates a dataset that does not fit in the tmpfs budget of the computation storage.
+var ErrStorageExceeded = errors.New("dataset exceeds the computation storage budget")
+
+// storageFree is a variable so tests can fill the tmpfs budget.
+var storageFree = storage.Free
+
+// storageDetails are the details of the StorageExceeded event.
+type storageDetails struct {
+	Dataset   string `json:"dataset"`
+	Size      uint64 `json:"size"`
+	Available uint64 `json:"available"`
+	LimitMB   uint64 `json:"limit_mb,omitempty"`
+}
+
+// validateStorage checks the storage the manifest keeps datasets and results on.
+func validateStorage(cmp Computation) error {
+	if cmp.Storage == nil {
+		return nil
+	}
+
+	return storage.Validate(cmp.Storage.Type, cmp.Storage.SizeMB)
+}
+
+// volatileStorage reports whether the manifest keeps datasets and results on
+// a storage that is lost when the agent restarts.
+func volatileStorage(cmp Computation) bool {
+	return cmp.Storage != nil && cmp.Storage.Type != "" && cmp.Storage.Type != storage.Disk
+}
+
+// tmpfsStorage reports whether the manifest keeps datasets and results in memory.
+func tmpfsStorage(cmp Computation) bool {
+	return cmp.Storage != nil && cmp.Storage.Type == storage.Tmpfs
+}
+
+// openStorage opens the storage of the manifest, the disk if it declares none.
+func openStorage(cmp Computation) (storage.Storage, error) {
+	if cmp.Storage == nil {
+		return storage.NewDisk(), nil
+	}
+
+	return storage.New(cmp.Storage.Type, cmp.Storage.SizeMB)
+}
+
+// dataStorage returns the storage of the computation, the disk before a
+// manifest was assigned. It must be called with the service mutex held.
+func (as *agentService) dataStorage() storage.Storage {
+	if as.storage == nil {
+		return storage.NewDisk()
+	}
+
+	return as.storage
+}
+
+// closeStorage closes the storage of the computation, discarding the
+// directories it still backs. It must be called with the service mutex held.
+func (as *agentService) closeStorage() error {
+	var err error
+	if as.resultStore != nil {
+		err = as.resultStore.Close()
+		as.resultStore = nil
+	}
+	if as.storage != nil {
+		if serr := as.storage.Close(); err == nil {
+			err = serr
+		}
+		as.storage = nil
+	}
+
+	return err
+}
+
+// createScratch creates the working and temporary directories the algorithm
+// writes its intermediate files to. Storage other than the disk backs them
+// too, so that no plaintext derived from the datasets reaches the disk of the
+// CVM. It must be called with the service mutex held.
+func (as *agentService) createScratch(dir string) error {
+	if !volatileStorage(as.computation) {
+		return os.MkdirAll(dir, sandboxDirPermission)
+	}
+
+	if err := as.dataStorage().Create(dir); err != nil {
+		return err
+	}
+
+	// The mounted directory keeps the permissions of the rest of the sandbox.
+	return os.Chmod(dir, sandboxDirPermission)
+}
+
+// reserveStorage fails fast when a dataset of size bytes does not fit in what
+// is left of the tmpfs budget of dir, rather than filling the tmpfs while it
+// is written. It must be called with the service mutex held.
+func (as *agentService) reserveStorage(dir, dataset string, size uint64) error {
+	if !tmpfsStorage(as.computation) {
+		return nil
+	}
+
+	free, err := storageFree(dir)
+	if err != nil {
+		return fmt.Errorf("error reading storage budget: %v", err)
+	}
+	if size <= free {
+		return nil
+	}
+
+	as.reportStorageExceeded(dataset, size, free)
+
+	return ErrStorageExceeded
+}
+
+// storageError returns ErrStorageExceeded when writing the dataset ran the
+// storage out of space, e.g. when a compressed dataset expanded past the
+// budget, and err otherwise.
+func (as *agentService) storageError(dataset string, size uint64, err error) error {
+	if !tmpfsStorage(as.computation) || !errors.Contains(err, syscall.ENOSPC) {
+		return err
+	}
+
+	as.reportStorageExceeded(dataset, size, 0)
+
+	return ErrStorageExceeded
+}
+
+func (as *agentService) reportStorageExceeded(dataset string, size, available uint64) {
+	details := storageDetails{Dataset: dataset, Size: size, Available: available, LimitMB: as.computation.Storage.SizeMB}
+	as.logger.Warn("dataset exceeds the computation storage budget", "computation", as.computation.ID, "dataset", dataset, "size", size, "available", available)
+
+	raw, _ := json.Marshal(details)
+	as.eventSvc.SendEvent(as.computation.ID, events.StorageExceeded, Warning.String(), raw)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+const (
+	// DeviceSerial is the serial of the virtio disk block storage is kept on.
+	DeviceSerial = "cocos-storage"
+
+	mapperName   = "cocos-storage"
+	blockKeySize = 64
+	blockFlags   = syscall.MS_NOSUID | syscall.MS_NODEV
+)
+
+var (
+	sysBlock = "/sys/block"
+	devDir   = "/dev"
+
+	// runCommand runs the command with stdin, it is a variable so tests can stub it.
+	runCommand = func(stdin []byte, name string, args ...string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = bytes.NewReader(stdin)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(out)))
+		}
+
+		return nil
+	}
+)
+
+// block keeps the directories on the storage disk, encrypted with dm-crypt
+// under a random key that is handed to the kernel and never stored, so the
+// data cannot be read once the storage is closed. Directories are bind
+// mounted from the file system of the disk.
+type block struct {
+	mu   sync.Mutex
+	root string
+	next int
+	dirs map[string]string
+}
+
+func openBlock() (*block, error) {
+	device, err := findDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, blockKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := runCommand(key, "cryptsetup", "open", "--type", "plain", "--cipher", "aes-xts-plain64",
+		"--key-size", strconv.Itoa(blockKeySize*8), "--key-file", "-", device, mapperName); err != nil {
+		return nil, fmt.Errorf("error opening encrypted storage on %s: %w", device, err)
+	}
+	clear(key)
+
+	mapped := filepath.Join(devDir, "mapper", mapperName)
+	b := &block{dirs: make(map[string]string)}
+	if err := b.mountRoot(mapped); err != nil {
+		if cerr := runCommand(nil, "cryptsetup", "close", mapperName); cerr != nil {
+			return nil, fmt.Errorf("%w, closing encrypted storage: %v", err, cerr)
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *block) mountRoot(mapped string) error {
+	if err := runCommand(nil, "mkfs.ext4", "-q", "-F", mapped); err != nil {
+		return fmt.Errorf("error formatting encrypted storage: %w", err)
+	}
+
+	root, err := os.MkdirTemp("", mapperName+"-")
+	if err != nil {
+		return err
+	}
+
+	if err := mount(mapped, root, "ext4", blockFlags, ""); err != nil {
+		os.Remove(root)
+		return fmt.Errorf("error mounting encrypted storage: %w", err)
+	}
+	b.root = root
+
+	return nil
+}
+
+// findDevice returns the device of the disk with the storage serial.
+func findDevice() (string, error) {
+	devices, err := os.ReadDir(sysBlock)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dev := range devices {
+		serial, err := os.ReadFile(filepath.Join(sysBlock, dev.Name(), "serial"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(serial)) == DeviceSerial {
+			return filepath.Join(devDir, dev.Name()), nil
+		}
+	}
+
+	return "", errors.Wrap(ErrNoDevice, fmt.Errorf("no disk with serial %s", DeviceSerial))
+}
+
+func (b *block) Create(dir string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.dirs[dir]; ok {
+		return nil
+	}
+
+	src := filepath.Join(b.root, strconv.Itoa(b.next))
+	b.next++
+	if err := os.Mkdir(src, 0o755); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if err := mount(src, dir, "", syscall.MS_BIND, ""); err != nil {
+		os.Remove(src)
+		return fmt.Errorf("error mounting %s from encrypted storage: %w", dir, err)
+	}
+	b.dirs[dir] = src
+
+	return nil
+}
+
+func (b *block) Remove(dir string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remove(dir)
+}
+
+func (b *block) remove(dir string) error {
+	if src, ok := b.dirs[dir]; ok {
+		if err := unmount(dir); err != nil {
+			return fmt.Errorf("error unmounting %s: %w", dir, err)
+		}
+		delete(b.dirs, dir)
+
+		if err := os.RemoveAll(src); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func (b *block) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for dir := range b.dirs {
+		if err := b.remove(dir); err != nil {
+			return err
+		}
+	}
+
+	if err := unmount(b.root); err != nil {
+		return fmt.Errorf("error unmounting encrypted storage: %w", err)
+	}
+	if err := os.Remove(b.root); err != nil {
+		return err
+	}
+
+	// Closing the mapping discards the key, the data on the disk can no longer be decrypted.
+	return runCommand(nil, "cryptsetup", "close", mapperName)
+}
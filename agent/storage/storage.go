@@ -0,0 +1,118 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package storage backs the directories the agent keeps datasets and results
+// in, on the local disk, in memory or on an encrypted block device, as the
+// computation manifest selects.
+package storage
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+// Storage types of the computation manifest.
+const (
+	// Disk keeps the directories on the root file system of the CVM. It is the default.
+	Disk = "disk"
+	// Tmpfs keeps the directories in memory, so they are never written to a disk.
+	Tmpfs = "tmpfs"
+	// Block keeps the directories on a disk attached to the CVM, encrypted with
+	// a key that only lives in memory until the storage is closed.
+	Block = "block"
+)
+
+var (
+	// ErrInvalidStorage indicates an unknown storage type or an invalid storage size.
+	ErrInvalidStorage = errors.New("invalid computation storage")
+	// ErrNoDevice indicates that no storage disk is attached to the CVM.
+	ErrNoDevice = errors.New("no storage disk attached")
+)
+
+// Storage backs the directories of a computation.
+type Storage interface {
+	// Create creates the directory empty, backed by the storage.
+	Create(dir string) error
+	// Remove removes the directory and everything it holds.
+	Remove(dir string) error
+	// Close releases the storage once the computation is done, the directories
+	// that were not removed are lost.
+	Close() error
+}
+
+// Validate checks the storage type and size, sizeMB bounds tmpfs directories
+// and must be 0 for the other types.
+func Validate(typ string, sizeMB uint64) error {
+	switch typ {
+	case "", Disk, Block:
+		if sizeMB != 0 {
+			return errors.Wrap(ErrInvalidStorage, fmt.Errorf("size is only supported by %s storage", Tmpfs))
+		}
+	case Tmpfs:
+	default:
+		return errors.Wrap(ErrInvalidStorage, fmt.Errorf("unknown type %q", typ))
+	}
+
+	return nil
+}
+
+// New opens the storage of the type, the disk when typ is empty. Tmpfs
+// directories hold up to sizeMB each, half of the memory when it is 0.
+func New(typ string, sizeMB uint64) (Storage, error) {
+	if err := Validate(typ, sizeMB); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case Tmpfs:
+		return newTmpfs(sizeMB), nil
+	case Block:
+		b, err := openBlock()
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	default:
+		return NewDisk(), nil
+	}
+}
+
+// Free returns the bytes that can still be written to the file system dir is
+// on, which is the remaining budget of the directory when a tmpfs backs it.
+func Free(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := statfs(dir, &st); err != nil {
+		return 0, err
+	}
+
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// NewDisk returns the disk storage.
+func NewDisk() Storage {
+	return disk{}
+}
+
+type disk struct{}
+
+func (disk) Create(dir string) error {
+	return os.MkdirAll(dir, 0o755)
+}
+
+func (disk) Remove(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+func (disk) Close() error {
+	return nil
+}
+
+// mount, unmount and statfs are variables so tests can run without privileges.
+var (
+	mount   = syscall.Mount
+	unmount = func(target string) error { return syscall.Unmount(target, 0) }
+	statfs  = syscall.Statfs
+)
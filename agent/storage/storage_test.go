@@ -0,0 +1,205 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mountCall struct {
+	source, target, fstype, data string
+	flags                        uintptr
+}
+
+// stubMounts records mounts instead of performing them.
+func stubMounts(t *testing.T) (*[]mountCall, *[]string) {
+	var mounts []mountCall
+	var unmounts []string
+
+	origMount, origUnmount := mount, unmount
+	mount = func(source, target, fstype string, flags uintptr, data string) error {
+		mounts = append(mounts, mountCall{source: source, target: target, fstype: fstype, flags: flags, data: data})
+		return nil
+	}
+	unmount = func(target string) error {
+		unmounts = append(unmounts, target)
+		return nil
+	}
+	t.Cleanup(func() { mount, unmount = origMount, origUnmount })
+
+	return &mounts, &unmounts
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		typ    string
+		sizeMB uint64
+		err    error
+	}{
+		{typ: ""},
+		{typ: Disk},
+		{typ: Tmpfs},
+		{typ: Tmpfs, sizeMB: 512},
+		{typ: Block},
+		{typ: Disk, sizeMB: 512, err: ErrInvalidStorage},
+		{typ: Block, sizeMB: 512, err: ErrInvalidStorage},
+		{typ: "nfs", err: ErrInvalidStorage},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s %d", tc.typ, tc.sizeMB), func(t *testing.T) {
+			err := Validate(tc.typ, tc.sizeMB)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestDisk(t *testing.T) {
+	st, err := New("", 0)
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "datasets")
+	require.NoError(t, st.Create(dir))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte("a,b"), 0o644))
+
+	require.NoError(t, st.Remove(dir))
+	assert.NoDirExists(t, dir)
+	assert.NoError(t, st.Close())
+}
+
+func TestTmpfs(t *testing.T) {
+	mounts, unmounts := stubMounts(t)
+
+	st, err := New(Tmpfs, 256)
+	require.NoError(t, err)
+
+	base := t.TempDir()
+	datasets, results := filepath.Join(base, "datasets"), filepath.Join(base, "results")
+	require.NoError(t, st.Create(datasets))
+	require.NoError(t, st.Create(results))
+	require.NoError(t, st.Create(results), "creating a mounted directory again is a no-op")
+
+	require.Len(t, *mounts, 2)
+	assert.Equal(t, mountCall{source: "tmpfs", target: datasets, fstype: "tmpfs", flags: tmpfsFlags, data: "mode=0755,size=256m"}, (*mounts)[0])
+	assert.DirExists(t, datasets)
+
+	require.NoError(t, st.Remove(datasets))
+	assert.Equal(t, []string{datasets}, *unmounts)
+	assert.NoDirExists(t, datasets)
+
+	require.NoError(t, st.Close())
+	assert.Equal(t, []string{datasets, results}, *unmounts)
+	assert.NoDirExists(t, results)
+
+	unlimited, err := New(Tmpfs, 0)
+	require.NoError(t, err)
+	require.NoError(t, unlimited.Create(datasets))
+	assert.Equal(t, "mode=0755", (*mounts)[2].data)
+}
+
+func TestTmpfsRemoveUntracked(t *testing.T) {
+	_, unmounts := stubMounts(t)
+
+	st, err := New(Tmpfs, 0)
+	require.NoError(t, err)
+
+	stale := filepath.Join(t.TempDir(), "results")
+	require.NoError(t, os.Mkdir(stale, 0o755))
+	require.NoError(t, st.Remove(stale), "a tmpfs mounted by a previous agent is unmounted")
+	assert.Equal(t, []string{stale}, *unmounts)
+	assert.NoDirExists(t, stale)
+
+	unmount = func(string) error { return syscall.EINVAL }
+	plain := filepath.Join(t.TempDir(), "results")
+	require.NoError(t, os.Mkdir(plain, 0o755))
+	require.NoError(t, st.Remove(plain), "a directory that is not a mount point is removed")
+	assert.NoDirExists(t, plain)
+	require.NoError(t, st.Remove(plain), "a missing directory is not an error")
+
+	unmount = func(string) error { return syscall.EBUSY }
+	require.NoError(t, os.Mkdir(plain, 0o755))
+	assert.ErrorIs(t, st.Remove(plain), syscall.EBUSY)
+}
+
+func TestBlock(t *testing.T) {
+	mounts, unmounts := stubMounts(t)
+
+	sys := t.TempDir()
+	for dev, serial := range map[string]string{"vda": "root", "vdb": "cocos-dataset-1", "vdc": DeviceSerial + "\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sys, dev), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(sys, dev, "serial"), []byte(serial), 0o644))
+	}
+
+	var commands []string
+	var key []byte
+	origSys, origDev, origRun := sysBlock, devDir, runCommand
+	sysBlock, devDir = sys, "/dev"
+	runCommand = func(stdin []byte, name string, args ...string) error {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if len(stdin) > 0 {
+			key = append([]byte{}, stdin...)
+		}
+		return nil
+	}
+	t.Cleanup(func() { sysBlock, devDir, runCommand = origSys, origDev, origRun })
+
+	st, err := New(Block, 0)
+	require.NoError(t, err)
+	assert.Len(t, key, blockKeySize)
+	assert.Equal(t, []string{
+		"cryptsetup open --type plain --cipher aes-xts-plain64 --key-size 512 --key-file - /dev/vdc cocos-storage",
+		"mkfs.ext4 -q -F /dev/mapper/cocos-storage",
+	}, commands)
+
+	root := st.(*block).root
+	require.Len(t, *mounts, 1)
+	assert.Equal(t, mountCall{source: "/dev/mapper/cocos-storage", target: root, fstype: "ext4", flags: blockFlags}, (*mounts)[0])
+
+	results := filepath.Join(t.TempDir(), "results")
+	require.NoError(t, st.Create(results))
+	require.Len(t, *mounts, 2)
+	assert.Equal(t, mountCall{source: filepath.Join(root, "0"), target: results, flags: syscall.MS_BIND}, (*mounts)[1])
+	assert.DirExists(t, filepath.Join(root, "0"))
+
+	require.NoError(t, st.Close())
+	assert.Equal(t, []string{results, root}, *unmounts)
+	assert.NoDirExists(t, root)
+	assert.NoDirExists(t, results)
+	assert.Equal(t, "cryptsetup close cocos-storage", commands[len(commands)-1])
+
+	require.NoError(t, os.Remove(filepath.Join(sys, "vdc", "serial")))
+	_, err = New(Block, 0)
+	assert.True(t, errors.Contains(err, ErrNoDevice), "expected %v, got %v", ErrNoDevice, err)
+}
+
+func TestFree(t *testing.T) {
+	orig := statfs
+	t.Cleanup(func() { statfs = orig })
+
+	dir := t.TempDir()
+	statfs = func(path string, st *syscall.Statfs_t) error {
+		assert.Equal(t, dir, path)
+		st.Bavail, st.Bsize = 3, 4096
+		return nil
+	}
+	free, err := Free(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3*4096), free)
+
+	statfs = orig
+	_, err = Free(filepath.Join(dir, "missing"))
+	assert.True(t, errors.Contains(err, syscall.ENOENT))
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+const tmpfsFlags = syscall.MS_NOSUID | syscall.MS_NODEV
+
+// tmpfs mounts a tmpfs on every directory it creates.
+type tmpfs struct {
+	mu      sync.Mutex
+	sizeMB  uint64
+	mounted map[string]bool
+}
+
+func newTmpfs(sizeMB uint64) *tmpfs {
+	return &tmpfs{sizeMB: sizeMB, mounted: make(map[string]bool)}
+}
+
+func (t *tmpfs) Create(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.mounted[dir] {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	options := "mode=0755"
+	if t.sizeMB > 0 {
+		options += fmt.Sprintf(",size=%dm", t.sizeMB)
+	}
+	if err := mount("tmpfs", dir, "tmpfs", tmpfsFlags, options); err != nil {
+		return fmt.Errorf("error mounting tmpfs on %s: %w", dir, err)
+	}
+	t.mounted[dir] = true
+
+	return nil
+}
+
+func (t *tmpfs) Remove(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.remove(dir)
+}
+
+func (t *tmpfs) remove(dir string) error {
+	// A tmpfs a previous agent process mounted on dir is unmounted as well,
+	// dir is not a mount point when unmounting it fails with EINVAL.
+	if err := unmount(dir); err != nil && (t.mounted[dir] || !errors.Is(err, syscall.EINVAL) && !errors.Is(err, os.ErrNotExist)) {
+		return fmt.Errorf("error unmounting tmpfs from %s: %w", dir, err)
+	}
+	delete(t.mounted, dir)
+
+	return os.RemoveAll(dir)
+}
+
+func (t *tmpfs) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+	for dir := range t.mounted {
+		if err := t.remove(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
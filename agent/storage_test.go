@@ -0,0 +1,135 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+	smmocks "github.com/ultravioletrs/cocos/agent/statemachine/mocks"
+	"github.com/ultravioletrs/cocos/agent/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestDataStorageBudget(t *testing.T) {
+	small, large := []byte("small"), []byte("dataset larger than the budget")
+
+	orig := storageFree
+	t.Cleanup(func() { storageFree = orig })
+	storageFree = func(dir string) (uint64, error) {
+		return 16, nil
+	}
+
+	cases := []struct {
+		desc     string
+		storage  *Storage
+		data     []byte
+		exceeded bool
+	}{
+		{desc: "dataset within the tmpfs budget", storage: &Storage{Type: storage.Tmpfs, SizeMB: 1}, data: small},
+		{desc: "dataset exceeds the tmpfs budget", storage: &Storage{Type: storage.Tmpfs, SizeMB: 1}, data: large, exceeded: true},
+		{desc: "disk storage has no budget", data: large},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			require.NoError(t, os.Mkdir(algorithm.DatasetsDir, 0o755))
+
+			cmp := Computation{
+				ID:       "cmp",
+				Datasets: []Dataset{{Hash: sha3.Sum256(tc.data), Filename: "data.csv"}, {Filename: "other.csv"}},
+				Storage:  tc.storage,
+			}
+
+			sm := new(smmocks.StateMachine)
+			sm.On("GetState").Return(ReceivingData)
+
+			reported := make(chan json.RawMessage, 1)
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "cmp", events.StorageExceeded, Warning.String(), mock.Anything).
+				Run(func(args mock.Arguments) { reported <- args.Get(3).(json.RawMessage) }).Return()
+
+			svc := &agentService{
+				sm:          sm,
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: cmp,
+				received:    make([]bool, len(cmp.Datasets)),
+			}
+
+			err := svc.Data(context.Background(), Dataset{Dataset: tc.data, Filename: "data.csv"})
+			if !tc.exceeded {
+				require.NoError(t, err)
+				assert.FileExists(t, filepath.Join(algorithm.DatasetsDir, "data.csv"))
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.True(t, errors.Contains(err, ErrStorageExceeded), "expected %v, got %v", ErrStorageExceeded, err)
+			assert.NoFileExists(t, filepath.Join(algorithm.DatasetsDir, "data.csv"))
+			assert.False(t, svc.received[0])
+
+			var details storageDetails
+			require.NoError(t, json.Unmarshal(<-reported, &details))
+			assert.Equal(t, storageDetails{Dataset: "data.csv", Size: uint64(len(tc.data)), Available: 16, LimitMB: 1}, details)
+		})
+	}
+}
+
+func TestStorageError(t *testing.T) {
+	full := &os.PathError{Op: "write", Path: "datasets/data.csv", Err: syscall.ENOSPC}
+
+	eventSvc := new(mocks.Service)
+	eventSvc.On("SendEvent", "cmp", events.StorageExceeded, Warning.String(), mock.Anything).Return().Once()
+
+	svc := &agentService{
+		logger:      mglog.NewMock(),
+		eventSvc:    eventSvc,
+		computation: Computation{ID: "cmp", Storage: &Storage{Type: storage.Tmpfs}},
+	}
+
+	assert.Equal(t, ErrStorageExceeded, svc.storageError("data.csv", 10, fmt.Errorf("error unzipping: %w", full)))
+	other := errors.New("other")
+	assert.Equal(t, other, svc.storageError("data.csv", 10, other))
+
+	svc.computation.Storage = nil
+	assert.Equal(t, full, svc.storageError("data.csv", 10, full), "the disk running out of space is not a budget")
+	eventSvc.AssertExpectations(t)
+}
+
+func TestCreateScratch(t *testing.T) {
+	cases := []struct {
+		desc    string
+		storage *Storage
+	}{
+		{desc: "disk storage"},
+		{desc: "volatile storage", storage: &Storage{Type: storage.Tmpfs}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			svc := &agentService{computation: Computation{ID: "cmp", Storage: tc.storage}}
+
+			dir := filepath.Join(t.TempDir(), algorithm.WorkDir)
+			require.NoError(t, svc.createScratch(dir))
+
+			info, err := os.Stat(dir)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(sandboxDirPermission), info.Mode().Perm())
+		})
+	}
+}
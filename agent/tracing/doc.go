@@ -0,0 +1,9 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing provides tracing instrumentation for cocos agent service.
+//
+// This package provides tracing middleware for cocos agent service. Requests
+// that do not carry a trace context continue the trace of the manager request
+// that created the CVM, so a computation has a single end-to-end timeline.
+package tracing
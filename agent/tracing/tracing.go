@@ -0,0 +1,137 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package tracing
+
+import (
+	"context"
+
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ agent.Service = (*tracingMiddleware)(nil)
+
+type tracingMiddleware struct {
+	tracer trace.Tracer
+	parent func() trace.SpanContext
+	svc    agent.Service
+}
+
+// New returns a new agent service with tracing capabilities. Spans of requests
+// without a trace context are children of the span context parent returns.
+func New(svc agent.Service, tracer trace.Tracer, parent func() trace.SpanContext) agent.Service {
+	return &tracingMiddleware{tracer, parent, svc}
+}
+
+func (tm *tracingMiddleware) State() string {
+	return tm.svc.State()
+}
+
+func (tm *tracingMiddleware) Datasets() []agent.DatasetStatus {
+	return tm.svc.Datasets()
+}
+
+func (tm *tracingMiddleware) InitComputation(ctx context.Context, cmp agent.Computation) error {
+	ctx, span := tm.start(ctx, "process_manifest")
+	defer span.End()
+
+	return tm.svc.InitComputation(ctx, cmp)
+}
+
+func (tm *tracingMiddleware) StopComputation(ctx context.Context) error {
+	ctx, span := tm.start(ctx, "stop_computation")
+	defer span.End()
+
+	return tm.svc.StopComputation(ctx)
+}
+
+func (tm *tracingMiddleware) Restore(ctx context.Context, sequence uint64) error {
+	ctx, span := tm.start(ctx, "restore_checkpoint")
+	defer span.End()
+
+	return tm.svc.Restore(ctx, sequence)
+}
+
+func (tm *tracingMiddleware) ApproveAttestation(ctx context.Context, report, signature []byte) error {
+	ctx, span := tm.start(ctx, "approve_attestation")
+	defer span.End()
+
+	return tm.svc.ApproveAttestation(ctx, report, signature)
+}
+
+func (tm *tracingMiddleware) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error {
+	ctx, span := tm.start(ctx, "secrets")
+	defer span.End()
+
+	return tm.svc.Secrets(ctx, secrets, signature)
+}
+
+func (tm *tracingMiddleware) Algo(ctx context.Context, algorithm agent.Algorithm) error {
+	ctx, span := tm.start(ctx, "upload_algorithm")
+	defer span.End()
+
+	return tm.svc.Algo(ctx, algorithm)
+}
+
+func (tm *tracingMiddleware) Data(ctx context.Context, dataset agent.Dataset) error {
+	ctx, span := tm.start(ctx, "upload_dataset")
+	defer span.End()
+
+	return tm.svc.Data(ctx, dataset)
+}
+
+func (tm *tracingMiddleware) AttachDatasetDisk(ctx context.Context, dir string) error {
+	ctx, span := tm.start(ctx, "attach_dataset_disk")
+	defer span.End()
+
+	return tm.svc.AttachDatasetDisk(ctx, dir)
+}
+
+func (tm *tracingMiddleware) Result(ctx context.Context) ([]byte, error) {
+	ctx, span := tm.start(ctx, "download_result")
+	defer span.End()
+
+	return tm.svc.Result(ctx)
+}
+
+func (tm *tracingMiddleware) Attestation(ctx context.Context, reportData [quoteprovider.Nonce]byte, nonce [vtpm.Nonce]byte, attType attestation.PlatformType) ([]byte, error) {
+	ctx, span := tm.start(ctx, "attestation")
+	defer span.End()
+
+	return tm.svc.Attestation(ctx, reportData, nonce, attType)
+}
+
+func (tm *tracingMiddleware) IMAMeasurements(ctx context.Context) ([]byte, []byte, error) {
+	ctx, span := tm.start(ctx, "ima_measurements")
+	defer span.End()
+
+	return tm.svc.IMAMeasurements(ctx)
+}
+
+func (tm *tracingMiddleware) AzureAttestationToken(ctx context.Context, nonce [vtpm.Nonce]byte) ([]byte, error) {
+	ctx, span := tm.start(ctx, "azure_attestation_token")
+	defer span.End()
+
+	return tm.svc.AzureAttestationToken(ctx, nonce)
+}
+
+func (tm *tracingMiddleware) Shutdown(ctx context.Context) error {
+	ctx, span := tm.start(ctx, "shutdown")
+	defer span.End()
+
+	return tm.svc.Shutdown(ctx)
+}
+
+// start starts a span, continuing the computation trace when ctx carries no
+// sampled trace context. Requests from clients that do not propagate a trace
+// only carry the unsampled root span of the gRPC server, which is dropped.
+func (tm *tracingMiddleware) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsSampled() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, tm.parent())
+	}
+
+	return tm.tracer.Start(ctx, name)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/watchdog"
+	"github.com/ultravioletrs/cocos/agent/events"
+)
+
+// ErrAlgorithmHung indicates the watchdog stopped an algorithm that made no progress.
+var ErrAlgorithmHung = errors.New("algorithm stopped by watchdog after making no progress")
+
+// hungDetails are the details of the PossiblyHung event.
+type hungDetails struct {
+	Idle      string             `json:"idle"`
+	Killed    bool               `json:"killed"`
+	Processes []watchdog.Process `json:"processes"`
+}
+
+// watchAlgorithm watches the algorithm processes with the watchdog policy of
+// the manifest. The returned function stops watching and reports whether the
+// watchdog stopped the algorithm.
+func (as *agentService) watchAlgorithm(alg algorithm.Algorithm) func() bool {
+	policy := as.computation.Algorithm.Watchdog
+	if policy == nil || policy.IdleSeconds == 0 {
+		return func() bool { return false }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	cmpID := as.computation.ID
+	cfg := watchdog.Config{Idle: time.Duration(policy.IdleSeconds) * time.Second}
+
+	var killed atomic.Bool
+	go func() {
+		defer close(done)
+
+		// The algorithm runtimes run the algorithm as child processes of the agent.
+		watchdog.Watch(ctx, os.Getpid(), cfg, func(idle time.Duration, procs []watchdog.Process) {
+			as.logger.Warn("algorithm is possibly hung", "computation", cmpID, "idle", idle.String(), "processes", len(procs))
+
+			details, _ := json.Marshal(hungDetails{Idle: idle.String(), Killed: policy.Kill, Processes: procs})
+			as.eventSvc.SendEvent(cmpID, events.PossiblyHung, Warning.String(), details)
+
+			if !policy.Kill {
+				return
+			}
+
+			killed.Store(true)
+			if err := alg.Stop(); err != nil {
+				as.logger.Warn("failed to stop hung algorithm", "computation", cmpID, "error", err)
+			}
+		})
+	}()
+
+	return func() bool {
+		cancel()
+		<-done
+
+		return killed.Load()
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+	"time"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	algomocks "github.com/ultravioletrs/cocos/agent/algorithm/mocks"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/events/mocks"
+)
+
+func TestWatchAlgorithm(t *testing.T) {
+	cases := []struct {
+		desc     string
+		watchdog *Watchdog
+		hung     bool
+		killed   bool
+	}{
+		{
+			desc: "no watchdog",
+		},
+		{
+			desc:     "watchdog disabled",
+			watchdog: &Watchdog{Kill: true},
+		},
+		{
+			desc:     "report hung algorithm",
+			watchdog: &Watchdog{IdleSeconds: 1},
+			hung:     true,
+		},
+		{
+			desc:     "stop hung algorithm",
+			watchdog: &Watchdog{IdleSeconds: 1, Kill: true},
+			hung:     true,
+			killed:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmd := exec.Command("sleep", "30")
+			require.NoError(t, cmd.Start())
+			t.Cleanup(func() {
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+			})
+
+			reported := make(chan json.RawMessage, 1)
+			eventSvc := new(mocks.Service)
+			eventSvc.On("SendEvent", "cmp", events.PossiblyHung, Warning.String(), mock.Anything).
+				Run(func(args mock.Arguments) { reported <- args.Get(3).(json.RawMessage) }).Return()
+
+			alg := new(algomocks.Algorithm)
+			alg.On("Stop").Return(nil)
+
+			as := &agentService{
+				logger:      mglog.NewMock(),
+				eventSvc:    eventSvc,
+				computation: Computation{ID: "cmp", Algorithm: Algorithm{Watchdog: tc.watchdog}},
+			}
+
+			stop := as.watchAlgorithm(alg)
+
+			if !tc.hung {
+				assert.False(t, stop())
+				eventSvc.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			select {
+			case raw := <-reported:
+				var details hungDetails
+				require.NoError(t, json.Unmarshal(raw, &details))
+				assert.Equal(t, tc.killed, details.Killed)
+				require.NotEmpty(t, details.Processes)
+				assert.Equal(t, cmd.Process.Pid, details.Processes[0].PID)
+			case <-time.After(5 * time.Second):
+				t.Fatal("hung algorithm was not reported")
+			}
+
+			assert.Equal(t, tc.killed, stop())
+			if tc.killed {
+				alg.AssertCalled(t, "Stop")
+			} else {
+				alg.AssertNotCalled(t, "Stop")
+			}
+		})
+	}
+}
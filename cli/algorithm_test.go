@@ -15,6 +15,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const algorithmFile = "test_algo_file.py"
@@ -45,6 +47,8 @@ func generateRSAPrivateKeyFile(fileName string) error {
 }
 
 func TestAlgorithmCmd(t *testing.T) {
+	retryBackoff = 0
+
 	tests := []struct {
 		name           string
 		setupMock      func(*mocks.SDK)
@@ -57,7 +61,7 @@ func TestAlgorithmCmd(t *testing.T) {
 		{
 			name: "successful upload",
 			setupMock: func(m *mocks.SDK) {
-				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			setupFiles: func() error {
 				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
@@ -75,7 +79,7 @@ func TestAlgorithmCmd(t *testing.T) {
 		{
 			name: "missing algorithm file",
 			setupMock: func(m *mocks.SDK) {
-				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			args:           []string{"non_existent_algo_file.py", privateKeyFile},
 			expectedOutput: "Error reading algorithm file",
@@ -83,7 +87,7 @@ func TestAlgorithmCmd(t *testing.T) {
 		{
 			name: "missing private key file",
 			setupMock: func(m *mocks.SDK) {
-				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			setupFiles: func() error {
 				return os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644)
@@ -97,7 +101,7 @@ func TestAlgorithmCmd(t *testing.T) {
 		{
 			name: "upload failure",
 			setupMock: func(m *mocks.SDK) {
-				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("failed to upload algorithm due to error"))
+				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("failed to upload algorithm due to error"))
 			},
 			setupFiles: func() error {
 				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
@@ -115,7 +119,7 @@ func TestAlgorithmCmd(t *testing.T) {
 		{
 			name: "invalid private key",
 			setupMock: func(m *mocks.SDK) {
-				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				m.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			setupFiles: func() error {
 				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
@@ -136,6 +140,61 @@ func TestAlgorithmCmd(t *testing.T) {
 				os.Remove(privateKeyFile)
 			},
 		},
+		{
+			name: "successful resumable upload",
+			setupMock: func(m *mocks.SDK) {
+				m.On("ResumableAlgo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			},
+			setupFiles: func() error {
+				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
+					return err
+				}
+				return generateRSAPrivateKeyFile(privateKeyFile)
+			},
+			args:           []string{algorithmFile, privateKeyFile, "--resume"},
+			expectedOutput: "Successfully uploaded algorithm",
+			cleanup: func() {
+				os.Remove(privateKeyFile)
+				os.Remove(algorithmFile)
+			},
+		},
+		{
+			name: "resumable upload retried after dropped connection",
+			setupMock: func(m *mocks.SDK) {
+				m.On("ResumableAlgo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(status.Error(codes.Unavailable, "connection dropped")).Once()
+				m.On("ResumableAlgo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+			},
+			setupFiles: func() error {
+				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
+					return err
+				}
+				return generateRSAPrivateKeyFile(privateKeyFile)
+			},
+			args:           []string{algorithmFile, privateKeyFile, "--resume"},
+			expectedOutput: "retrying (1/3)",
+			cleanup: func() {
+				os.Remove(privateKeyFile)
+				os.Remove(algorithmFile)
+			},
+		},
+		{
+			name: "resumable upload exhausts retries",
+			setupMock: func(m *mocks.SDK) {
+				m.On("ResumableAlgo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(status.Error(codes.Unavailable, "connection dropped"))
+			},
+			setupFiles: func() error {
+				if err := os.WriteFile(algorithmFile, []byte("test algorithm"), 0o644); err != nil {
+					return err
+				}
+				return generateRSAPrivateKeyFile(privateKeyFile)
+			},
+			args:           []string{algorithmFile, privateKeyFile, "--resume", "--retries", "1"},
+			expectedOutput: "rerun with --resume",
+			cleanup: func() {
+				os.Remove(privateKeyFile)
+				os.Remove(algorithmFile)
+			},
+		},
 		{
 			name: "connection error",
 			setupMock: func(m *mocks.SDK) {
@@ -163,7 +222,7 @@ func TestAlgorithmCmd(t *testing.T) {
 				require.NoError(t, err)
 			}
 
-			cmd := testCLI.NewAlgorithmCmd()
+			cmd := testCLI.NewAlgorithmCmd(t.TempDir())
 			buf := new(bytes.Buffer)
 			cmd.SetOut(buf)
 			cmd.SetArgs(tt.args)
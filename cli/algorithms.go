@@ -3,15 +3,13 @@
 package cli
 
 import (
-	"context"
 	"encoding/pem"
 	"os"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/agent/algorithm"
-	"github.com/ultravioletrs/cocos/agent/algorithm/python"
-	"google.golang.org/grpc/metadata"
 )
 
 var (
@@ -19,9 +17,13 @@ var (
 	algoType         string
 	requirementsFile string
 	algoArgs         []string
+	algoEntrypoint   string
+	minRuntime       string
+	resumable        bool
+	uploadRetries    int
 )
 
-func (cli *CLI) NewAlgorithmCmd() *cobra.Command {
+func (cli *CLI) NewAlgorithmCmd(uploadStateDir string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "algo",
 		Short:   "Upload an algorithm binary",
@@ -33,6 +35,12 @@ func (cli *CLI) NewAlgorithmCmd() *cobra.Command {
 				return
 			}
 
+			spec, err := algorithmSpec()
+			if err != nil {
+				printError(cmd, "Error in algorithm spec: %v ❌ ", err)
+				return
+			}
+
 			algorithmFile := args[0]
 
 			cmd.Println("Uploading algorithm file:", algorithmFile)
@@ -69,9 +77,12 @@ func (cli *CLI) NewAlgorithmCmd() *cobra.Command {
 				return
 			}
 
-			ctx := metadata.NewOutgoingContext(cmd.Context(), metadata.New(make(map[string]string)))
-
-			if err := cli.agentSDK.Algo(addAlgoMetadata(ctx), algorithm, req, privKey); err != nil {
+			if resumable {
+				err = cli.uploadResumable(cmd, cmd.Context(), algorithm, req, spec, privKey, uploadStateDir)
+			} else {
+				err = cli.agentSDK.Algo(cmd.Context(), algorithm, req, spec, privKey)
+			}
+			if err != nil {
 				printError(cmd, "Failed to upload algorithm due to error: %v ❌ ", err)
 				return
 			}
@@ -81,16 +92,30 @@ func (cli *CLI) NewAlgorithmCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&algoType, "algorithm", "a", string(algorithm.AlgoTypeBin), "Algorithm type to run")
-	cmd.Flags().StringVar(&pythonRuntime, "python-runtime", python.PyRuntime, "Python runtime to use")
+	cmd.Flags().StringVar(&pythonRuntime, "python-runtime", "", "Python runtime to use, python3 if not set")
+	cmd.Flags().StringVar(&minRuntime, "min-runtime-version", "", "Lowest python runtime version the algorithm supports")
+	cmd.Flags().StringVar(&algoEntrypoint, "entrypoint", "", "Exported function of a wasm module or command of a docker image to run")
 	cmd.Flags().StringVarP(&requirementsFile, "requirements", "r", "", "Python requirements file")
 	cmd.Flags().StringArrayVar(&algoArgs, "args", []string{}, "Arguments to pass to the algorithm")
+	cmd.Flags().BoolVar(&resumable, "resume", false, "Upload in acknowledged chunks and resume from the last acknowledged chunk if the connection drops")
+	cmd.Flags().IntVar(&uploadRetries, "retries", 3, "Number of times a dropped resumable upload is retried")
 
 	return cmd
 }
 
-func addAlgoMetadata(ctx context.Context) context.Context {
-	ctx = algorithm.AlgorithmTypeToContext(ctx, algoType)
-	ctx = algorithm.AlgorithmArgsToContext(ctx, algoArgs)
-	ctx = python.PythonRunTimeToContext(ctx, pythonRuntime)
-	return ctx
+// algorithmSpec returns the validated spec of the algorithm flags.
+func algorithmSpec() (*agent.AlgorithmSpec, error) {
+	spec := algorithm.Spec{
+		Type:              algorithm.AlgorithType(algoType),
+		Entrypoint:        algoEntrypoint,
+		Args:              algoArgs,
+		Runtime:           pythonRuntime,
+		MinRuntimeVersion: minRuntime,
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return agent.NewAlgorithmSpec(spec)
 }
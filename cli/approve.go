@@ -0,0 +1,80 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+func (cli *CLI) NewApproveAttestationCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "approve <computation_manifest_file_path> <attestation_report_file_path> <private_key_file_path>",
+		Short:   "Approve the attestation report of the agent so that it accepts the algorithm and datasets",
+		Example: "approve manifest.json attestation.bin private.pem",
+		Args:    cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.connectErr != nil {
+				printError(cmd, "Failed to connect to agent: %v ❌ ", cli.connectErr)
+				return
+			}
+
+			manifestFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading manifest file: %v ❌ ", err)
+				return
+			}
+
+			var cmp agent.Computation
+			if err := json.Unmarshal(manifestFile, &cmp); err != nil {
+				printError(cmd, "Error decoding manifest: %v ❌ ", err)
+				return
+			}
+
+			report, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading attestation report file: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[2])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			signer, ok := privKey.(crypto.Signer)
+			if !ok {
+				printError(cmd, "Error signing approval: %v ❌ ", agent.ErrUnsupportedKey)
+				return
+			}
+
+			signature, err := agent.SignApproval(cmp, report, signer)
+			if err != nil {
+				printError(cmd, "Error signing approval: %v ❌ ", err)
+				return
+			}
+
+			if err := cli.agentSDK.ApproveAttestation(cmd.Context(), report, signature); err != nil {
+				printError(cmd, "Error approving attestation: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Attestation approved successfully! ✔"))
+		},
+	}
+}
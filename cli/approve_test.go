@@ -0,0 +1,123 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+)
+
+func TestApproveAttestationCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: ed25519KeyType, Bytes: der}), 0o600))
+
+	rsaKeyFile := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(rsaKeyFile))
+
+	manifestFile := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(manifestFile, []byte(`{"id":"1","name":"sample computation"}`), 0o644))
+
+	report := []byte("attestation report")
+	reportFile := filepath.Join(dir, "attestation.bin")
+	require.NoError(t, os.WriteFile(reportFile, report, 0o644))
+
+	signingBytes, err := agent.ApprovalSigningBytes(agent.Computation{ID: "1", Name: "sample computation"}, report)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc         string
+		manifestFile string
+		reportFile   string
+		keyFile      string
+		approveErr   error
+		connectErr   error
+		output       string
+	}{
+		{
+			desc:         "approve attestation",
+			manifestFile: manifestFile,
+			keyFile:      keyFile,
+			output:       "Attestation approved successfully",
+		},
+		{
+			desc:         "missing manifest file",
+			manifestFile: filepath.Join(dir, "missing.json"),
+			keyFile:      keyFile,
+			output:       "Error reading manifest file",
+		},
+		{
+			desc:         "missing attestation report file",
+			manifestFile: manifestFile,
+			reportFile:   filepath.Join(dir, "missing.bin"),
+			keyFile:      keyFile,
+			output:       "Error reading attestation report file",
+		},
+		{
+			desc:         "missing private key file",
+			manifestFile: manifestFile,
+			keyFile:      filepath.Join(dir, "missing.pem"),
+			output:       "Error reading private key file",
+		},
+		{
+			desc:         "unsupported private key",
+			manifestFile: manifestFile,
+			keyFile:      rsaKeyFile,
+			output:       "Error signing approval",
+		},
+		{
+			desc:         "approval failure",
+			manifestFile: manifestFile,
+			keyFile:      keyFile,
+			approveErr:   errors.New("attestation approval is not signed by the computation owner"),
+			output:       "attestation approval is not signed by the computation owner",
+		},
+		{
+			desc:         "connection error",
+			manifestFile: manifestFile,
+			keyFile:      keyFile,
+			connectErr:   errors.New("failed to connect to agent"),
+			output:       "Failed to connect to agent",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockSDK := new(mocks.SDK)
+			mockSDK.On("ApproveAttestation", mock.Anything, report, mock.MatchedBy(func(signature []byte) bool {
+				return ed25519.Verify(pub, signingBytes, signature)
+			})).Return(tc.approveErr)
+
+			testCLI := CLI{agentSDK: mockSDK, connectErr: tc.connectErr}
+
+			cmd := testCLI.NewApproveAttestationCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			if tc.reportFile == "" {
+				tc.reportFile = reportFile
+			}
+			cmd.SetArgs([]string{tc.manifestFile, tc.reportFile, tc.keyFile})
+			require.NoError(t, cmd.Execute())
+
+			assert.Contains(t, buf.String(), tc.output)
+		})
+	}
+}
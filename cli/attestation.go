@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	tpmAttest "github.com/google/go-tpm-tools/proto/attest"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/azure"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
@@ -74,6 +76,7 @@ var (
 	tokenNonce                    []byte
 	getTextProtoAttestationReport bool
 	getAzureTokenJWT              bool
+	refreshAttestation            bool
 	cloud                         string
 	reportData                    []byte
 	checkCrl                      bool
@@ -81,6 +84,12 @@ var (
 
 var errEmptyFile = errors.New("input file is empty")
 
+// savedAttestation is the attestation saved by get in the JSON and YAML output formats.
+type savedAttestation struct {
+	Type string `json:"type"`
+	File string `json:"file"`
+}
+
 func (cli *CLI) NewAttestationCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "attestation [command]",
@@ -217,14 +226,24 @@ func (cli *CLI) NewGetAttestationCmd() *cobra.Command {
 			var returnJsonAzureToken bool
 
 			if attestationType == AzureToken {
-				err := cli.agentSDK.AttestationToken(cmd.Context(), fixedVtpmNonceByte, int(attType), attestationFile)
+				err := withRetry(cmd, func() error {
+					if err := resetFile(attestationFile); err != nil {
+						return err
+					}
+					return cli.agentSDK.AttestationToken(cmd.Context(), fixedVtpmNonceByte, int(attType), attestationFile)
+				})
 				if err != nil {
 					printError(cmd, "Failed to get attestation token due to error: %v ❌", err)
 					return
 				}
 				returnJsonAzureToken = !getAzureTokenJWT
 			} else {
-				err := cli.agentSDK.Attestation(cmd.Context(), fixedReportData, fixedVtpmNonceByte, int(attType), attestationFile)
+				err := withRetry(cmd, func() error {
+					if err := resetFile(attestationFile); err != nil {
+						return err
+					}
+					return cli.agentSDK.Attestation(agent.RefreshAttestationToContext(cmd.Context(), refreshAttestation), fixedReportData, fixedVtpmNonceByte, int(attType), attestationFile)
+				})
 				if err != nil {
 					printError(cmd, "Failed to get attestation due to error: %v ❌", err)
 					return
@@ -278,7 +297,16 @@ func (cli *CLI) NewGetAttestationCmd() *cobra.Command {
 				}
 			}
 
-			cmd.Println("Attestation retrieved and saved successfully!")
+			absPath, err := filepath.Abs(filename)
+			if err != nil {
+				absPath = filename
+			}
+
+			if err := printOutput(cmd, savedAttestation{Type: attestationType, File: absPath}, func(w io.Writer) {
+				cmd.Println("Attestation retrieved and saved successfully!")
+			}); err != nil {
+				printError(cmd, "Error printing attestation: %v ❌ ", err)
+			}
 		},
 	}
 
@@ -287,6 +315,7 @@ func (cli *CLI) NewGetAttestationCmd() *cobra.Command {
 	cmd.Flags().BytesHexVar(&teeNonce, "tee", []byte{}, "Define the nonce for the SNP and TDX attestation report (must be used with attestation type snp, snp-vtpm, and tdx)")
 	cmd.Flags().BytesHexVar(&nonce, "vtpm", []byte{}, "Define the nonce for the vTPM attestation report (must be used with attestation type vtpm and snp-vtpm)")
 	cmd.Flags().BytesHexVar(&tokenNonce, "token", []byte{}, "Define the nonce for the Azure attestation token (must be used with attestation type azure-token)")
+	cmd.Flags().BoolVar(&refreshAttestation, "refresh", false, "Generate the attestation report again instead of getting the one the agent cached for the same nonces")
 
 	return cmd
 }
@@ -323,12 +352,12 @@ func (cli *CLI) NewValidateAttestationValidationCmd() *cobra.Command {
 		Example: `Based on mode:
 		validate <attestationreportfilepath> --report_data <reportdata> --product <product data> --platform <cc platform> //default
 		validate --mode snp <attestationreportfilepath> --report_data <reportdata> --product <product data>
-		validate --mode vtpm <attestationreportfilepath> --nonce <noncevalue> --format <formatvalue>  --output <outputvalue>
-		validate --mode snp-vtpm <attestationreportfilepath> --report_data <reportdata> --product <product data> --nonce <noncevalue> --format <formatvalue>  --output <outputvalue>
+		validate --mode vtpm <attestationreportfilepath> --nonce <noncevalue> --format <formatvalue>  --out-file <outputvalue>
+		validate --mode snp-vtpm <attestationreportfilepath> --report_data <reportdata> --product <product data> --nonce <noncevalue> --format <formatvalue>  --out-file <outputvalue>
 		validate --mode tdx <attestationreportfilepath> --report_data <reportdata>
 		validate --cloud none --mode snp <attestationreportfilepath> --report_data <reportdata> --product <product data>
-		validate --cloud azure --mode vtpm <attestationreportfilepath> --nonce <noncevalue> --format <formatvalue>  --output <outputvalue>
-		validate --cloud gcp --mode snp-vtpm <attestationreportfilepath> --report_data <reportdata> --product <product data> --nonce <noncevalue> --format <formatvalue>  --output <outputvalue>`,
+		validate --cloud azure --mode vtpm <attestationreportfilepath> --nonce <noncevalue> --format <formatvalue>  --out-file <outputvalue>
+		validate --cloud gcp --mode snp-vtpm <attestationreportfilepath> --report_data <reportdata> --product <product data> --nonce <noncevalue> --format <formatvalue>  --out-file <outputvalue>`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			mode, _ := cmd.Flags().GetString("mode")
 			if len(args) != 1 {
@@ -357,8 +386,8 @@ func (cli *CLI) NewValidateAttestationValidationCmd() *cobra.Command {
 				if err := cmd.MarkFlagRequired("format"); err != nil {
 					return fmt.Errorf("failed to mark 'format' as required for %s mode: %v", VTPM, err)
 				}
-				if err := cmd.MarkFlagRequired("output"); err != nil {
-					return fmt.Errorf("failed to mark 'output' as required for %s mode: %v", VTPM, err)
+				if err := cmd.MarkFlagRequired("out-file"); err != nil {
+					return fmt.Errorf("failed to mark 'out-file' as required for %s mode: %v", VTPM, err)
 				}
 			case VTPM:
 				if err := cmd.MarkFlagRequired("nonce"); err != nil {
@@ -367,8 +396,8 @@ func (cli *CLI) NewValidateAttestationValidationCmd() *cobra.Command {
 				if err := cmd.MarkFlagRequired("format"); err != nil {
 					return fmt.Errorf("failed to mark 'format' as required for %s mode: %v", VTPM, err)
 				}
-				if err := cmd.MarkFlagRequired("output"); err != nil {
-					return fmt.Errorf("failed to mark 'output' as required for %s mode: %v", VTPM, err)
+				if err := cmd.MarkFlagRequired("out-file"); err != nil {
+					return fmt.Errorf("failed to mark 'out-file' as required for %s mode: %v", VTPM, err)
 				}
 			case TDX:
 				if err := cmd.MarkFlagRequired("report_data"); err != nil {
@@ -461,7 +490,7 @@ func (cli *CLI) NewValidateAttestationValidationCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(
 		&output,
-		"output",
+		"out-file",
 		"",
 		"output file",
 	)
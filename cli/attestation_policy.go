@@ -15,12 +15,15 @@ import (
 
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/google/go-sev-guest/proto/check"
+	"github.com/google/go-sev-guest/proto/sevsnp"
 	"github.com/google/go-tpm-tools/proto/attest"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/azure"
 	"github.com/ultravioletrs/cocos/pkg/attestation/gcp"
+	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -51,6 +54,9 @@ var (
 	errAttestationPolicyField              = errors.New("the specified field type does not exist in the attestation policy")
 	errReadingManifestFile                 = errors.New("error while reading manifest file")
 	errDecodeHex                           = errors.New("error decoding hex string")
+	errReadingBackendInfoFile              = errors.New("error while reading the backend info file")
+	errReadingPCRFile                      = errors.New("error while reading the PCR values file")
+	errBackendPlatform                     = errors.New("attestation policies can only be created for sev-snp backends")
 	policy                          uint64 = 196639
 	isJsonAttestation               bool
 )
@@ -364,6 +370,30 @@ func (cli *CLI) NewExtendWithManifestCmd() *cobra.Command {
 	}
 }
 
+func (cli *CLI) NewCreatePolicyCmd() *cobra.Command {
+	var outputPath, pcrPath string
+
+	cmd := &cobra.Command{
+		Use:     "create <backend_info_file>",
+		Short:   "Create an attestation policy from the backend info printed by cocos-manager --backend-info",
+		Example: "create backend_info.json --out-file attestation_policy.json",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := createAttestationPolicy(args[0], pcrPath, outputPath); err != nil {
+				printError(cmd, "Error creating attestation policy: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println("Attestation policy file generated successfully ✅")
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "out-file", "o", "attestation_policy.json", "Path the attestation policy is written to")
+	cmd.Flags().StringVar(&pcrPath, "pcr", "", "Path to a JSON file with the expected vTPM PCR values")
+
+	return cmd
+}
+
 func changeAttestationConfiguration(fileName, base64Data string, expectedLength int, field fieldType) error {
 	data, err := base64.StdEncoding.DecodeString(base64Data)
 	if err != nil {
@@ -408,6 +438,74 @@ func changeAttestationConfiguration(fileName, base64Data string, expectedLength
 	return nil
 }
 
+// createAttestationPolicy writes the attestation policy that verifies the
+// attestations of the CVMs of the backend info to outputPath.
+func createAttestationPolicy(backendInfoPath, pcrPath, outputPath string) error {
+	data, err := os.ReadFile(backendInfoPath)
+	if err != nil {
+		return errors.Wrap(errReadingBackendInfoFile, err)
+	}
+
+	var info manager.BackendInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return errors.Wrap(errUnmarshalJSON, err)
+	}
+
+	if info.Platform != manager.BackendSEVSNP {
+		return fmt.Errorf("%w: %q", errBackendPlatform, info.Platform)
+	}
+
+	if len(info.Measurement) != measurementLength {
+		return errors.Wrap(errDataLength, fmt.Errorf("measurement must be %d bytes", measurementLength))
+	}
+
+	if len(info.HostData) != 0 && len(info.HostData) != hostDataLength {
+		return errors.Wrap(errDataLength, fmt.Errorf("host data must be %d bytes", hostDataLength))
+	}
+
+	ac := attestation.Config{
+		Config: &check.Config{
+			RootOfTrust: &check.RootOfTrust{Product: info.Product, ProductLine: info.Product, CheckCrl: true},
+			Policy: &check.Policy{
+				Measurement:      info.Measurement,
+				HostData:         info.HostData,
+				Policy:           info.GuestPolicy,
+				MinimumTcb:       info.MinimumTCB,
+				MinimumLaunchTcb: info.MinimumLaunchTCB,
+				MinimumBuild:     info.MinimumBuild,
+				MinimumVersion:   info.MinimumVersion,
+			},
+		},
+		PcrConfig: &attestation.PcrConfig{},
+	}
+
+	if product := quoteprovider.GetProductName(info.Product); product != sevsnp.SevProduct_SEV_PRODUCT_UNKNOWN {
+		ac.Config.Policy.Product = &sevsnp.SevProduct{Name: product}
+	}
+
+	if pcrPath != "" {
+		pcrs, err := os.ReadFile(pcrPath)
+		if err != nil {
+			return errors.Wrap(errReadingPCRFile, err)
+		}
+
+		if err := json.Unmarshal(pcrs, ac.PcrConfig); err != nil {
+			return errors.Wrap(errUnmarshalJSON, err)
+		}
+	}
+
+	policyJSON, err := vtpm.ConvertPolicyToJSON(&ac)
+	if err != nil {
+		return errors.Wrap(errMarshalJSON, err)
+	}
+
+	if err := os.WriteFile(outputPath, policyJSON, filePermission); err != nil {
+		return errors.Wrap(errWriteFile, err)
+	}
+
+	return nil
+}
+
 func extendWithManifest(attestationPolicyPath string, manifestPaths []string) error {
 	attestationConfig := attestation.Config{Config: &check.Config{RootOfTrust: &check.RootOfTrust{}, Policy: &check.Policy{}}, PcrConfig: &attestation.PcrConfig{}}
 
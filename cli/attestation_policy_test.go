@@ -5,12 +5,17 @@ package cli
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/absmach/supermq/pkg/errors"
 	"github.com/google/go-sev-guest/proto/check"
+	"github.com/google/go-sev-guest/proto/sevsnp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 )
@@ -468,3 +473,80 @@ func TestExtendWithManifestHandling(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestCreateAttestationPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	writeJSON := func(name string, v any) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		return path
+	}
+
+	info := manager.BackendInfo{
+		Platform:         manager.BackendSEVSNP,
+		Measurement:      bytes.Repeat([]byte{0xab}, measurementLength),
+		GuestPolicy:      196608,
+		MinimumTCB:       15352208179752599555,
+		MinimumLaunchTCB: 15352208179752599555,
+		MinimumBuild:     8,
+		MinimumVersion:   "1.55",
+		Product:          "Milan",
+		HostData:         bytes.Repeat([]byte{1}, hostDataLength),
+	}
+	shortMeasurement := info
+	shortMeasurement.Measurement = info.Measurement[:measurementLength-1]
+
+	pcrs := writeJSON("pcr_values.json", attestation.PcrConfig{PCRValues: attestation.PcrValues{Sha256: map[string]string{"16": "00"}}})
+
+	cases := []struct {
+		desc string
+		info any
+		pcr  string
+		err  error
+	}{
+		{desc: "SEV-SNP backend", info: info},
+		{desc: "SEV-SNP backend with PCR values", info: info, pcr: pcrs},
+		{desc: "TDX backend", info: manager.BackendInfo{Platform: manager.BackendTDX}, err: errBackendPlatform},
+		{desc: "invalid measurement", info: shortMeasurement, err: errDataLength},
+		{desc: "invalid backend info", info: "backend", err: errUnmarshalJSON},
+		{desc: "missing PCR values file", info: info, pcr: filepath.Join(dir, "missing.json"), err: errReadingPCRFile},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			output := filepath.Join(dir, "attestation_policy.json")
+			t.Cleanup(func() { os.Remove(output) })
+
+			err := createAttestationPolicy(writeJSON("backend_info.json", tc.info), tc.pcr, output)
+			if tc.err != nil {
+				assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+				assert.NoFileExists(t, output)
+				return
+			}
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(output)
+			require.NoError(t, err)
+
+			ap := attestation.Config{Config: &check.Config{RootOfTrust: &check.RootOfTrust{}, Policy: &check.Policy{}}, PcrConfig: &attestation.PcrConfig{}}
+			require.NoError(t, vtpm.ReadPolicyFromByte(content, &ap))
+
+			assert.Equal(t, info.Measurement, ap.Config.Policy.Measurement)
+			assert.Equal(t, info.HostData, ap.Config.Policy.HostData)
+			assert.Equal(t, info.GuestPolicy, ap.Config.Policy.Policy)
+			assert.Equal(t, info.MinimumTCB, ap.Config.Policy.MinimumTcb)
+			assert.Equal(t, info.MinimumLaunchTCB, ap.Config.Policy.MinimumLaunchTcb)
+			assert.Equal(t, info.MinimumBuild, ap.Config.Policy.MinimumBuild)
+			assert.Equal(t, info.MinimumVersion, ap.Config.Policy.MinimumVersion)
+			assert.Equal(t, sevsnp.SevProduct_SEV_PRODUCT_MILAN, ap.Config.Policy.Product.GetName())
+			assert.Equal(t, "Milan", ap.Config.RootOfTrust.ProductLine)
+
+			if tc.pcr != "" {
+				assert.Equal(t, "00", ap.PCRValues.Sha256["16"])
+			}
+		})
+	}
+}
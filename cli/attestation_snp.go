@@ -435,7 +435,8 @@ func parseConfig() error {
 		return err
 	}
 
-	if err := protojson.Unmarshal(policyByte, &cfg); err != nil {
+	// Attestation policy files also hold the vTPM PCR values, which are not part of check.Config.
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(policyByte, &cfg); err != nil {
 		return err
 	}
 	// Populate fields that should not be nil
@@ -412,6 +412,17 @@ func TestParseConfig(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "attestation policy file with PCR values",
+			setupConfig: func() string {
+				configFile := filepath.Join(tempDir, "attestation_policy.json")
+				if err := os.WriteFile(configFile, []byte(`{"policy":{"minimumBuild":8},"pcr_values":{"sha256":{"16":"00"}}}`), 0o644); err != nil {
+					t.Errorf("failed to write config file: %v", err)
+				}
+				return configFile
+			},
+			expectErr: false,
+		},
 		{
 			name: "nonexistent config file",
 			setupConfig: func() string {
@@ -83,6 +83,13 @@ func TestNewGetAttestationCmd(t *testing.T) {
 			mockError:    nil,
 			expectedOut:  "Attestation retrieved and saved successfully!",
 		},
+		{
+			name:         "successful refreshed SNP attestation retrieval",
+			args:         []string{"snp", "--tee", teeNonce, "--refresh"},
+			mockResponse: []byte("mock attestation"),
+			mockError:    nil,
+			expectedOut:  "Attestation retrieved and saved successfully!",
+		},
 		{
 			name:         "missing vTPM nonce",
 			args:         []string{"snp-vtpm", "--tee", teeNonce},
@@ -253,14 +260,14 @@ func TestNewValidateAttestationValidationCmd(t *testing.T) {
 		cmd.SetArgs([]string{vtpmFilePath, "--mode=vtpm"})
 		err := cmd.Execute()
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "required flag(s) \"format\", \"nonce\", \"output\", \"product\", \"report_data\" not set")
+		assert.Contains(t, err.Error(), "required flag(s) \"format\", \"nonce\", \"out-file\", \"product\", \"report_data\" not set")
 	})
 
 	t.Run("snp-vtpm mode with missing flags", func(t *testing.T) {
 		cmd.SetArgs([]string{vtpmFilePath, "--mode=snp-vtpm"})
 		err := cmd.Execute()
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "required flag(s) \"format\", \"nonce\", \"output\", \"product\", \"report_data\" not set")
+		assert.Contains(t, err.Error(), "required flag(s) \"format\", \"nonce\", \"out-file\", \"product\", \"report_data\" not set")
 	})
 
 	t.Run("valid snp mode execution", func(t *testing.T) {
@@ -293,7 +300,7 @@ func TestNewValidateAttestationValidationCmd(t *testing.T) {
 			return nil
 		}
 
-		cmd.SetArgs([]string{vtpmFilePath, "--mode=vtpm", "--nonce=123abc", "--format=binarypb", "--output=some_output"})
+		cmd.SetArgs([]string{vtpmFilePath, "--mode=vtpm", "--nonce=123abc", "--format=binarypb", "--out-file=some_output"})
 
 		err := cmd.PreRunE(cmd, []string{"../quote.dat"})
 		assert.NoError(t, err)
@@ -308,7 +315,7 @@ func TestNewValidateAttestationValidationCmd(t *testing.T) {
 			return nil
 		}
 
-		cmd.SetArgs([]string{vtpmFilePath, "--mode=snp-vtpm", "--nonce=123abc", "--format=textproto", "--output=some_output"})
+		cmd.SetArgs([]string{vtpmFilePath, "--mode=snp-vtpm", "--nonce=123abc", "--format=textproto", "--out-file=some_output"})
 		err := cmd.PreRunE(cmd, []string{"../quote.dat"})
 		assert.NoError(t, err)
 	})
@@ -0,0 +1,125 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/audit"
+)
+
+const auditLogFilePermission = 0o600
+
+func (c *CLI) NewAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit [command]",
+		Short: "Export and verify the audit log of the manager control-plane operations",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Printf("Export and verify the audit log of the manager control-plane operations\n\n")
+			cmd.Printf("Usage:\n  %s [command]\n\n", cmd.CommandPath())
+			cmd.Printf("Available Commands:\n")
+
+			for _, subCmd := range cmd.Commands() {
+				cmd.Printf("  %-15s%s\n", subCmd.Name(), subCmd.Short)
+			}
+
+			cmd.Printf("\nUse \"%s [command] --help\" for more information about a command.\n", cmd.CommandPath())
+		},
+	}
+
+	cmd.AddCommand(c.newAuditExportCmd(), newAuditVerifyCmd())
+
+	return cmd
+}
+
+func (c *CLI) newAuditExportCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:     "export",
+		Short:   "Download the audit log of the manager and verify its chain",
+		Example: "audit export -f audit.log",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			res, err := c.managerClient.AuditLog(cmd.Context(), &manager.AuditLogReq{})
+			if err != nil {
+				printError(cmd, "Error exporting audit log: %v ❌ ", err)
+				return
+			}
+
+			if err := os.WriteFile(file, res.Records, auditLogFilePermission); err != nil {
+				printError(cmd, "Error saving audit log: %v ❌ ", err)
+				return
+			}
+
+			// The records end with the head the manager reports unless the log was cut in transit.
+			count, head, err := audit.Verify(bytes.NewReader(res.Records))
+			if err == nil && (count != res.Count || head != res.Head) {
+				err = fmt.Errorf("%w: %d records ending with %s, the manager reported %d ending with %s", audit.ErrBrokenChain, count, head, res.Count, res.Head)
+			}
+			if err != nil {
+				printError(cmd, "Exported audit log does not verify: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("✅ Exported %d audit records to %s, head %s", res.Count, file, res.Head))
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "audit.log", "File the audit log is saved to")
+
+	return cmd
+}
+
+func newAuditVerifyCmd() *cobra.Command {
+	var head string
+
+	cmd := &cobra.Command{
+		Use:   "verify <file>",
+		Short: "Verify the hash chain of an exported audit log",
+		Long: `verify checks that the records of an exported audit log are numbered in order
+and chained to each other. A log cut after a record still verifies, pass the
+head of an earlier export with --head to check that the log still contains it.`,
+		Example: "audit verify audit.log --head <hash>",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading audit log: %v ❌ ", err)
+				return
+			}
+
+			count, last, err := audit.Verify(bytes.NewReader(data))
+			if err == nil && head != "" && !bytes.Contains(data, []byte(fmt.Sprintf(`"hash":%q`, head))) {
+				err = fmt.Errorf("%w: no record with hash %s", audit.ErrBrokenChain, head)
+			}
+			if err != nil {
+				printError(cmd, "Audit log does not verify: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("✅ Audit log of %d records verified, head %s", count, last))
+		},
+	}
+
+	cmd.Flags().StringVar(&head, "head", "", "Hash of a record the log must contain, e.g. the head of an earlier export")
+
+	return cmd
+}
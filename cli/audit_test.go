@@ -0,0 +1,171 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/audit"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+)
+
+// auditRecords returns an audit log of the operations, its size and head.
+func auditRecords(t *testing.T, operations ...string) ([]byte, uint64, string) {
+	t.Helper()
+
+	l, err := audit.Open(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer l.Close()
+
+	for _, op := range operations {
+		require.NoError(t, l.Append(audit.Record{Operation: op, Caller: audit.AnonymousCaller, Outcome: audit.OutcomeOK, StartedAt: time.Now(), EndedAt: time.Now()}))
+	}
+
+	data, count, head, err := l.Export()
+	require.NoError(t, err)
+
+	return data, count, head
+}
+
+func TestCLI_NewAuditExportCmd(t *testing.T) {
+	records, count, head := auditRecords(t, "CreateVM", "RemoveVM")
+	firstRecord := strings.SplitAfter(string(records), "\n")[0]
+
+	tests := []struct {
+		name           string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		expectedOutput string
+		saved          bool
+	}{
+		{
+			name: "export audit log",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("AuditLog", mock.Anything, mock.Anything).Return(&manager.AuditLogRes{Records: records, Count: count, Head: head}, nil)
+			},
+			expectedOutput: "Exported 2 audit records",
+			saved:          true,
+		},
+		{
+			name: "cut audit log",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("AuditLog", mock.Anything, mock.Anything).Return(&manager.AuditLogRes{Records: []byte(firstRecord), Count: count, Head: head}, nil)
+			},
+			expectedOutput: "Exported audit log does not verify",
+			saved:          true,
+		},
+		{
+			name: "manager error",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("AuditLog", mock.Anything, mock.Anything).Return(nil, errors.New("audit log is disabled"))
+			},
+			expectedOutput: "Error exporting audit log: audit log is disabled ❌",
+		},
+		{
+			name:      "manager connection failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			expectedOutput: "Failed to connect to manager: connection failed ❌",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{managerClient: mockClient}
+			if tt.setupCLI != nil {
+				tt.setupCLI(mockCLI)
+			}
+
+			file := filepath.Join(t.TempDir(), "audit.log")
+			cmd := mockCLI.NewAuditCmd()
+			cmd.SetArgs([]string{"export", "-f", file})
+
+			var output bytes.Buffer
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Contains(t, output.String(), tt.expectedOutput)
+			if tt.saved {
+				assert.FileExists(t, file)
+			} else {
+				assert.NoFileExists(t, file)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCLI_NewAuditVerifyCmd(t *testing.T) {
+	records, _, head := auditRecords(t, "CreateVM", "StopVM", "RemoveVM")
+	lines := strings.SplitAfter(string(records), "\n")
+
+	tests := []struct {
+		name           string
+		log            string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "intact log",
+			log:            string(records),
+			expectedOutput: "Audit log of 3 records verified, head " + head,
+		},
+		{
+			name:           "log containing an earlier head",
+			log:            string(records),
+			args:           []string{"--head", head},
+			expectedOutput: "Audit log of 3 records verified",
+		},
+		{
+			name:           "log cut before the earlier head",
+			log:            lines[0] + lines[1],
+			args:           []string{"--head", head},
+			expectedOutput: "Audit log does not verify",
+		},
+		{
+			name:           "tampered log",
+			log:            lines[0] + strings.Replace(lines[1], "StopVM", "GetImages", 1) + lines[2],
+			expectedOutput: "Audit log does not verify",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "audit.log")
+			require.NoError(t, os.WriteFile(file, []byte(tt.log), 0o600))
+
+			cmd := (&CLI{}).NewAuditCmd()
+			cmd.SetArgs(append([]string{"verify", file}, tt.args...))
+
+			var output bytes.Buffer
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Contains(t, output.String(), tt.expectedOutput)
+		})
+	}
+
+	cmd := (&CLI{}).NewAuditCmd()
+	cmd.SetArgs([]string{"verify", filepath.Join(t.TempDir(), "missing.log")})
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	assert.NoError(t, cmd.Execute())
+	assert.Contains(t, output.String(), "Error reading audit log")
+}
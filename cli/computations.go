@@ -0,0 +1,457 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"context"
+	"crypto"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/manager"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	reportCSVExt     = ".csv"
+	statusLaunched   = "launched"
+	statusCompleted  = "completed"
+	statusFailed     = "failed"
+	manifestFileGlob = "*.json"
+)
+
+var (
+	errParallelism   = errors.New("parallelism must be at least 1")
+	errNoManifests   = errors.New("no manifests found in directory")
+	errMissingServer = errors.New("server_url is required")
+	errRunFailed     = errors.New("computation run failed")
+	errTTLExpired    = errors.New("virtual machine TTL expired before the computation finished")
+)
+
+// batchManifest describes the virtual machine of a single computation, it
+// mirrors the create-vm flags with certificate paths relative to the manifest
+// file. The computation server at ServerURL sends the computation manifest to
+// the agent of the virtual machine.
+type batchManifest struct {
+	Name       string `json:"name,omitempty"`
+	ServerURL  string `json:"server_url"`
+	ServerCA   string `json:"server_ca,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	ClientCert string `json:"client_crt,omitempty"`
+	CAURL      string `json:"ca_url,omitempty"`
+	LogLevel   string `json:"log_level,omitempty"`
+	TTL        string `json:"ttl,omitempty"`
+	// Profile is the machine profile of the CVM, see the create-vm flag.
+	Profile string `json:"machine_profile,omitempty"`
+	// Tenant is the tenant whose quota the CVM counts against.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+type submissionResult struct {
+	Manifest string        `json:"manifest"`
+	Name     string        `json:"name"`
+	CvmID    string        `json:"cvm_id,omitempty"`
+	Port     string        `json:"port,omitempty"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (c *CLI) NewComputationCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "computation [command]",
+		Short: "Manage computations",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Printf("Manage computations\n\n")
+			cmd.Printf("Usage:\n  %s [command]\n\n", cmd.CommandPath())
+			cmd.Printf("Available Commands:\n")
+
+			for _, subCmd := range cmd.Commands() {
+				cmd.Printf("  %-15s%s\n", subCmd.Name(), subCmd.Short)
+			}
+
+			cmd.Printf("\nUse \"%s [command] --help\" for more information about a command.\n", cmd.CommandPath())
+		},
+	}
+}
+
+func (c *CLI) NewLaunchComputationsCmd() *cobra.Command {
+	var (
+		dir      string
+		parallel int
+		report   string
+		wait     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "launch",
+		Short:   "Launch a virtual machine for every manifest in a directory and wait for their computations to finish",
+		Example: "launch --dir ./manifests --parallel 5 --report report.csv",
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if parallel < 1 {
+				printError(cmd, "Invalid flags: %v ❌ ", errParallelism)
+				return
+			}
+
+			manifests, err := filepath.Glob(filepath.Join(dir, manifestFileGlob))
+			if err != nil {
+				printError(cmd, "Error listing manifests: %v ❌ ", err)
+				return
+			}
+			if len(manifests) == 0 {
+				printError(cmd, "Error listing manifests: %v ❌ ", errNoManifests)
+				return
+			}
+			sort.Strings(manifests)
+
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			cmd.Printf("🔗 Launching %d computations with parallelism %d\n", len(manifests), parallel)
+
+			results := c.launchManifests(cmd.Context(), manifests, parallel, wait, func(res submissionResult) {
+				switch res.Status {
+				case statusFailed:
+					cmd.Println(color.New(color.FgRed).Sprintf("❌ %s: %s", res.Name, res.Error))
+				case statusCompleted:
+					cmd.Println(color.New(color.FgGreen).Sprintf("✅ %s: computation on virtual machine %s finished", res.Name, res.CvmID))
+				default:
+					cmd.Println(color.New(color.FgGreen).Sprintf("✅ %s: virtual machine %s on port %s", res.Name, res.CvmID, res.Port))
+				}
+			})
+
+			out, csvReport := cmd.OutOrStdout(), false
+			if report != "" {
+				f, err := os.Create(report)
+				if err != nil {
+					printError(cmd, "Error creating report file: %v ❌ ", err)
+					return
+				}
+				defer f.Close()
+				out, csvReport = f, strings.EqualFold(filepath.Ext(report), reportCSVExt)
+			}
+
+			if err := writeReport(out, csvReport, results); err != nil {
+				printError(cmd, "Error writing report: %v ❌ ", err)
+				return
+			}
+
+			var failed int
+			for _, res := range results {
+				if res.Status == statusFailed {
+					failed++
+				}
+			}
+
+			done := statusLaunched
+			if wait {
+				done = statusCompleted
+			}
+
+			summary := fmt.Sprintf("%d %s, %d failed", len(results)-failed, done, failed)
+			if failed > 0 {
+				cmd.Println(color.New(color.FgYellow).Sprintf("⚠️  %s", summary))
+				return
+			}
+			cmd.Println(color.New(color.FgGreen).Sprintf("✅ %s", summary))
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", ".", "Directory containing computation manifests")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Number of computations launched concurrently")
+	cmd.Flags().StringVar(&report, "report", "", "File the summary report is written to in the output format, or as CSV if it ends with .csv, defaults to stdout")
+	cmd.Flags().BoolVar(&wait, "wait", true, "Wait for every computation to finish before writing the report")
+
+	return cmd
+}
+
+func (c *CLI) NewSignManifestCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:     "sign <computation_manifest_file_path> <private_key_file_path>",
+		Short:   "Sign a computation manifest with an Ed25519 or ECDSA key",
+		Example: "sign manifest.json private.pem --out-file signed_manifest.json",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifestFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading manifest file: %v ❌ ", err)
+				return
+			}
+
+			var cmp agent.Computation
+			if err := json.Unmarshal(manifestFile, &cmp); err != nil {
+				printError(cmd, "Error decoding manifest: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			signer, ok := privKey.(crypto.Signer)
+			if !ok {
+				printError(cmd, "Error signing manifest: %v ❌ ", agent.ErrUnsupportedKey)
+				return
+			}
+
+			cmp.Signature, err = agent.SignComputation(cmp, signer)
+			if err != nil {
+				printError(cmd, "Error signing manifest: %v ❌ ", err)
+				return
+			}
+
+			signed, err := json.MarshalIndent(cmp, "", "  ")
+			if err != nil {
+				printError(cmd, "Error encoding manifest: %v ❌ ", err)
+				return
+			}
+
+			if output == "" {
+				output = args[0]
+			}
+
+			if err := os.WriteFile(output, signed, 0o644); err != nil {
+				printError(cmd, "Error writing signed manifest: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Manifest signed successfully and saved to %s ✔", output))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "out-file", "o", "", "Path of the signed manifest, the input manifest is overwritten if empty")
+
+	return cmd
+}
+
+// launchManifests launches the virtual machine of every manifest with bounded
+// parallelism, waiting for their computations to finish if wait is set, and
+// returns the results in manifest order once all of them are done.
+func (c *CLI) launchManifests(ctx context.Context, manifests []string, parallel int, wait bool, onResult func(submissionResult)) []submissionResult {
+	results := make([]submissionResult, len(manifests))
+
+	var mu sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+
+	for i, path := range manifests {
+		g.Go(func() error {
+			res := c.launchManifest(ctx, path, wait)
+			results[i] = res
+
+			mu.Lock()
+			onResult(res)
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	return results
+}
+
+func (c *CLI) launchManifest(ctx context.Context, path string, wait bool) submissionResult {
+	start := time.Now()
+	res := submissionResult{
+		Manifest: path,
+		Name:     filepath.Base(path),
+		Status:   statusFailed,
+	}
+
+	fail := func(err error) submissionResult {
+		res.Error = err.Error()
+		res.Duration = time.Since(start)
+		return res
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	var m batchManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fail(err)
+	}
+
+	if m.Name != "" {
+		res.Name = m.Name
+	}
+
+	if m.ServerURL == "" {
+		return fail(errMissingServer)
+	}
+
+	req, err := m.createReq(filepath.Dir(path))
+	if err != nil {
+		return fail(err)
+	}
+
+	createRes, err := c.managerClient.CreateVm(ctx, req)
+	if err != nil {
+		return fail(err)
+	}
+
+	res.CvmID = createRes.CvmId
+	res.Port = createRes.ForwardedPort
+
+	if wait {
+		if err := c.waitComputation(ctx, createRes.CvmId); err != nil {
+			return fail(err)
+		}
+	}
+
+	res.Status = statusLaunched
+	if wait {
+		res.Status = statusCompleted
+	}
+	res.Duration = time.Since(start)
+
+	return res
+}
+
+// waitComputation watches the virtual machine until its computation is done,
+// which the manager sees as the virtual machine being stopped or removed, e.g.
+// by the computation server once the results were consumed. The run failed if
+// the agent sent a diagnostic snapshot, which it only does for failed runs, or
+// if the TTL of the virtual machine expired first.
+func (c *CLI) waitComputation(ctx context.Context, cvmID string) error {
+	stream, err := c.managerClient.WatchComputation(ctx, &manager.WatchComputationReq{CvmId: cvmID})
+	if err != nil {
+		return fmt.Errorf("error watching virtual machine: %w", err)
+	}
+
+	var failed bool
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error watching virtual machine: %w", err)
+		}
+
+		if event.EventType == manager.EventTTLExpired {
+			return errTTLExpired
+		}
+		if event.EventType == manager.EventDiagnosticsReceived {
+			failed = true
+		}
+		if event.EventType == manager.EventVMStopped || event.EventType == manager.EventVMRemoved {
+			break
+		}
+	}
+
+	if failed {
+		return errRunFailed
+	}
+
+	return nil
+}
+
+func (m batchManifest) createReq(baseDir string) (*manager.CreateReq, error) {
+	readRelative := func(p string) ([]byte, error) {
+		if p != "" && !filepath.IsAbs(p) {
+			p = filepath.Join(baseDir, p)
+		}
+		return fileReader(p)
+	}
+
+	serverCA, err := readRelative(m.ServerCA)
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey, err := readRelative(m.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCrt, err := readRelative(m.ClientCert)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &manager.CreateReq{
+		AgentCvmServerUrl:    m.ServerURL,
+		AgentCvmServerCaCert: serverCA,
+		AgentCvmClientKey:    clientKey,
+		AgentCvmClientCert:   clientCrt,
+		AgentCvmCaUrl:        m.CAURL,
+		AgentLogLevel:        m.LogLevel,
+		MachineProfile:       m.Profile,
+		Tenant:               m.Tenant,
+	}
+
+	if m.TTL != "" {
+		ttl, err := time.ParseDuration(m.TTL)
+		if err != nil {
+			return nil, err
+		}
+		req.Ttl = ttl.String()
+	}
+
+	return req, nil
+}
+
+// writeReport writes the results as CSV, or in the output format.
+func writeReport(w io.Writer, csvReport bool, results []submissionResult) error {
+	if csvReport {
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"manifest", "name", "cvm_id", "port", "status", "error", "duration"}); err != nil {
+			return err
+		}
+		for _, res := range results {
+			if err := cw.Write([]string{res.Manifest, res.Name, res.CvmID, res.Port, res.Status, res.Error, strconv.FormatFloat(res.Duration.Seconds(), 'f', 3, 64)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	return writeOutput(w, results, func(tw io.Writer) {
+		fmt.Fprintln(tw, "MANIFEST\tNAME\tCVM_ID\tPORT\tSTATUS\tDURATION\tERROR")
+		for _, res := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", res.Manifest, res.Name, res.CvmID, res.Port, res.Status, res.Duration.Round(time.Millisecond), res.Error)
+		}
+	})
+}
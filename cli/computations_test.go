@@ -0,0 +1,334 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc"
+)
+
+type watchClientStream struct {
+	grpc.ClientStream
+	events []*manager.ComputationEvent
+}
+
+func (s *watchClientStream) Recv() (*manager.ComputationEvent, error) {
+	if len(s.events) == 0 {
+		return nil, io.EOF
+	}
+
+	event := s.events[0]
+	s.events = s.events[1:]
+
+	return event, nil
+}
+
+// watchEvents returns a stream of the events of the virtual machine.
+func watchEvents(cvmID string, eventTypes ...string) *watchClientStream {
+	stream := &watchClientStream{}
+	for _, eventType := range eventTypes {
+		stream.events = append(stream.events, &manager.ComputationEvent{CvmId: cvmID, EventType: eventType})
+	}
+
+	return stream
+}
+
+func watchReq(cvmID string) any {
+	return mock.MatchedBy(func(req *manager.WatchComputationReq) bool { return req.CvmId == cvmID })
+}
+
+func TestCLI_NewLaunchComputationsCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		manifests      map[string]string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		flags          map[string]string
+		format         string
+		expectedOutput string
+		expectedError  string
+		validate       func(*testing.T, string)
+	}{
+		{
+			name: "launch all manifests and wait for them with json report",
+			manifests: map[string]string{
+				"a.json": `{"name":"sweep-a","server_url":"localhost:7001","ttl":"1h","server_ca":"ca.pem","machine_profile":"microvm","tenant":"acme"}`,
+				"b.json": `{"server_url":"localhost:7001"}`,
+				"ca.pem": "ca-cert-content",
+			},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("CreateVm", mock.Anything, mock.MatchedBy(func(req *manager.CreateReq) bool {
+					return req.Ttl == "1h0m0s" && string(req.AgentCvmServerCaCert) == "ca-cert-content" && req.MachineProfile == "microvm" && req.Tenant == "acme"
+				})).Return(&manager.CreateRes{CvmId: "vm-a", ForwardedPort: "6100"}, nil).Once()
+				m.On("CreateVm", mock.Anything, mock.MatchedBy(func(req *manager.CreateReq) bool {
+					return req.Ttl == ""
+				})).Return(&manager.CreateRes{CvmId: "vm-b", ForwardedPort: "6101"}, nil).Once()
+				m.On("WatchComputation", mock.Anything, watchReq("vm-a")).Return(watchEvents("vm-a", manager.EventState, manager.EventVMRunning, manager.EventVMRemoved), nil).Once()
+				m.On("WatchComputation", mock.Anything, watchReq("vm-b")).Return(watchEvents("vm-b", manager.EventState, manager.EventVMStopped, manager.EventVMRemoved), nil).Once()
+			},
+			setupCLI: func(cli *CLI) {},
+			flags: map[string]string{
+				"parallel": "2",
+				"report":   "report.json",
+			},
+			format:         OutputJSON,
+			expectedOutput: "✅ 2 completed, 0 failed",
+			validate: func(t *testing.T, dir string) {
+				data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+				require.NoError(t, err)
+
+				var results []submissionResult
+				require.NoError(t, json.Unmarshal(data, &results))
+				require.Len(t, results, 2)
+				assert.Equal(t, "sweep-a", results[0].Name)
+				assert.Equal(t, "vm-a", results[0].CvmID)
+				assert.Equal(t, statusCompleted, results[0].Status)
+				assert.Equal(t, "b.json", results[1].Name)
+				assert.Equal(t, "vm-b", results[1].CvmID)
+			},
+		},
+		{
+			name:      "launch without waiting",
+			manifests: map[string]string{"a.json": `{"server_url":"localhost:7001"}`},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(&manager.CreateRes{CvmId: "vm-a", ForwardedPort: "6100"}, nil).Once()
+			},
+			setupCLI:       func(cli *CLI) {},
+			flags:          map[string]string{"wait": "false"},
+			expectedOutput: "✅ 1 launched, 0 failed",
+		},
+		{
+			name: "failed and expired computations",
+			manifests: map[string]string{
+				"a.json": `{"name":"failed","server_url":"localhost:7001"}`,
+				"b.json": `{"name":"expired","server_url":"localhost:7001"}`,
+				"c.json": `{"name":"unwatched","server_url":"localhost:7001"}`,
+			},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(&manager.CreateRes{CvmId: "vm-a"}, nil).Once()
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(&manager.CreateRes{CvmId: "vm-b"}, nil).Once()
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(&manager.CreateRes{CvmId: "vm-c"}, nil).Once()
+				m.On("WatchComputation", mock.Anything, watchReq("vm-a")).Return(watchEvents("vm-a", manager.EventState, manager.EventDiagnosticsReceived, manager.EventVMRemoved), nil).Once()
+				m.On("WatchComputation", mock.Anything, watchReq("vm-b")).Return(watchEvents("vm-b", manager.EventState, manager.EventTTLExpired, manager.EventVMRemoved), nil).Once()
+				m.On("WatchComputation", mock.Anything, watchReq("vm-c")).Return(nil, errors.New("entity not found")).Once()
+			},
+			setupCLI:       func(cli *CLI) {},
+			flags:          map[string]string{"report": "report.json"},
+			format:         OutputJSON,
+			expectedOutput: "0 completed, 3 failed",
+			validate: func(t *testing.T, dir string) {
+				data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+				require.NoError(t, err)
+
+				var results []submissionResult
+				require.NoError(t, json.Unmarshal(data, &results))
+				require.Len(t, results, 3)
+				assert.Equal(t, errRunFailed.Error(), results[0].Error)
+				assert.Equal(t, "vm-a", results[0].CvmID)
+				assert.Equal(t, errTTLExpired.Error(), results[1].Error)
+				assert.Contains(t, results[2].Error, "entity not found")
+			},
+		},
+		{
+			name: "failed submissions in csv report",
+			manifests: map[string]string{
+				"a.json": `{"server_url":"localhost:7001"}`,
+				"b.json": `{"name":"no-server"}`,
+				"c.json": `not json`,
+			},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(nil, errors.New("no capacity")).Once()
+			},
+			setupCLI:       func(cli *CLI) {},
+			flags:          map[string]string{"report": "report.csv"},
+			format:         OutputJSON,
+			expectedOutput: "0 completed, 3 failed",
+			validate: func(t *testing.T, dir string) {
+				f, err := os.Open(filepath.Join(dir, "report.csv"))
+				require.NoError(t, err)
+				defer f.Close()
+
+				records, err := csv.NewReader(f).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 4)
+				assert.Equal(t, "no capacity", records[1][5])
+				assert.Equal(t, errMissingServer.Error(), records[2][5])
+				assert.Equal(t, statusFailed, records[3][4])
+			},
+		},
+		{
+			name:      "table report",
+			manifests: map[string]string{"a.json": `{"name":"sweep-a","server_url":"localhost:7001"}`},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(&manager.CreateRes{CvmId: "vm-a", ForwardedPort: "6100"}, nil).Once()
+			},
+			setupCLI:       func(cli *CLI) {},
+			flags:          map[string]string{"wait": "false"},
+			expectedOutput: "a.json  sweep-a  vm-a    6100  launched",
+		},
+		{
+			name:          "invalid parallelism",
+			manifests:     map[string]string{"a.json": `{"server_url":"localhost:7001"}`},
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			setupCLI:      func(cli *CLI) {},
+			flags:         map[string]string{"parallel": "0"},
+			expectedError: errParallelism.Error(),
+		},
+		{
+			name:          "empty directory",
+			manifests:     map[string]string{},
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			setupCLI:      func(cli *CLI) {},
+			expectedError: errNoManifests.Error(),
+		},
+		{
+			name:      "manager client initialization failure",
+			manifests: map[string]string{"a.json": `{"server_url":"localhost:7001"}`},
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			expectedError: "Failed to connect to manager: connection failed ❌",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.manifests {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+			tt.setupCLI(mockCLI)
+
+			if tt.format != "" {
+				OutputFormat = tt.format
+				defer func() { OutputFormat = OutputTable }()
+			}
+
+			cmd := mockCLI.NewLaunchComputationsCmd()
+			require.NoError(t, cmd.Flags().Set("dir", dir))
+			for flag, value := range tt.flags {
+				if flag == "report" {
+					value = filepath.Join(dir, value)
+				}
+				require.NoError(t, cmd.Flags().Set(flag, value))
+			}
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+			cmd.SetArgs([]string{})
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+
+			if tt.expectedError != "" {
+				assert.Contains(t, buf.String(), tt.expectedError)
+			}
+			if tt.expectedOutput != "" {
+				assert.Contains(t, buf.String(), tt.expectedOutput)
+			}
+			if tt.validate != nil {
+				tt.validate(t, dir)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCLI_NewSignManifestCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	edKeyPath := filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(edKeyPath, pem.EncodeToMemory(&pem.Block{Type: ed25519KeyType, Bytes: der}), 0o600))
+
+	rsaKeyPath := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(rsaKeyPath))
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"id":"1","name":"sample computation","result_consumers":[{"user_key":"a2V5"}]}`), 0o644))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+		signed         bool
+	}{
+		{
+			name:           "sign with ed25519 key",
+			args:           []string{manifestPath, edKeyPath},
+			expectedOutput: "Manifest signed successfully",
+			signed:         true,
+		},
+		{
+			name:           "sign with unsupported key",
+			args:           []string{manifestPath, rsaKeyPath},
+			expectedOutput: "Error signing manifest",
+		},
+		{
+			name:           "missing manifest",
+			args:           []string{filepath.Join(dir, "missing.json"), edKeyPath},
+			expectedOutput: "Error reading manifest file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "signed.json")
+
+			cmd := (&CLI{}).NewSignManifestCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(append(tt.args, "--out-file", output))
+			require.NoError(t, cmd.Execute())
+
+			assert.Contains(t, buf.String(), tt.expectedOutput)
+
+			if !tt.signed {
+				assert.NoFileExists(t, output)
+				return
+			}
+
+			data, err := os.ReadFile(output)
+			require.NoError(t, err)
+
+			var cmp agent.Computation
+			require.NoError(t, json.Unmarshal(data, &cmp))
+			assert.Equal(t, "sample computation", cmp.Name)
+
+			signingBytes, err := cmp.SigningBytes()
+			require.NoError(t, err)
+			assert.True(t, ed25519.Verify(pub, signingBytes, cmp.Signature))
+		})
+	}
+}
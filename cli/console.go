@@ -0,0 +1,79 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"errors"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/protobuf/proto"
+)
+
+func (c *CLI) NewConsoleCmd() *cobra.Command {
+	var (
+		follow bool
+		tail   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "console <cvm_id>",
+		Short: "Print the serial console output of a virtual machine",
+		Long: `console prints the serial console output the manager captured for the virtual
+machine, including the output of CVMs that failed to launch, to debug guests
+that never bring up the agent.`,
+		Example: "console <cvm_id> -f --tail 100",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req := &manager.ConsoleReq{CvmId: args[0], Follow: follow}
+			if tail >= 0 {
+				req.Tail = proto.Uint32(uint32(tail))
+			}
+
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			if err := c.receiveConsole(cmd, req); err != nil {
+				printError(cmd, "Error streaming console: %v ❌ ", err)
+				return
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep streaming new output while the virtual machine runs")
+	cmd.Flags().IntVar(&tail, "tail", -1, "Number of captured lines to show, all of them when negative")
+
+	return cmd
+}
+
+// receiveConsole writes the console output the manager streams for the request to stdout until the stream ends.
+func (c *CLI) receiveConsole(cmd *cobra.Command, req *manager.ConsoleReq) error {
+	stream, err := c.managerClient.Console(cmd.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := cmd.OutOrStdout().Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+}
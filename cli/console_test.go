@@ -0,0 +1,125 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc"
+)
+
+type consoleClientStream struct {
+	grpc.ClientStream
+	chunks []*manager.ConsoleChunk
+	err    error
+}
+
+func (s *consoleClientStream) Recv() (*manager.ConsoleChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+
+	return chunk, nil
+}
+
+func TestCLI_NewConsoleCmd(t *testing.T) {
+	chunks := func() []*manager.ConsoleChunk {
+		return []*manager.ConsoleChunk{
+			{CvmId: "vm-123", Data: []byte("Booting Linux\nLoading ")},
+			{CvmId: "vm-123", Data: []byte("initrd\n")},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		args           []string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		expectedStdout string
+		expectedOutput string
+	}{
+		{
+			name: "print captured console",
+			args: []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Console", mock.Anything, mock.MatchedBy(func(req *manager.ConsoleReq) bool {
+					return req.CvmId == "vm-123" && !req.Follow && req.Tail == nil
+				})).Return(&consoleClientStream{chunks: chunks()}, nil)
+			},
+			expectedStdout: "Booting Linux\nLoading initrd\n",
+		},
+		{
+			name: "follow with tail",
+			args: []string{"vm-123", "-f", "--tail", "100"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Console", mock.Anything, mock.MatchedBy(func(req *manager.ConsoleReq) bool {
+					return req.Follow && req.Tail != nil && req.GetTail() == 100
+				})).Return(&consoleClientStream{chunks: chunks()}, nil)
+			},
+			expectedStdout: "Booting Linux\nLoading initrd\n",
+		},
+		{
+			name: "stream failure",
+			args: []string{"vm-123", "-f"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Console", mock.Anything, mock.Anything).Return(&consoleClientStream{chunks: chunks()[:1], err: errors.New("connection reset")}, nil)
+			},
+			expectedStdout: "Booting Linux\nLoading ",
+			expectedOutput: "Error streaming console: connection reset ❌",
+		},
+		{
+			name: "manager error",
+			args: []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Console", mock.Anything, mock.Anything).Return(nil, errors.New("console capture is disabled"))
+			},
+			expectedOutput: "Error streaming console: console capture is disabled ❌",
+		},
+		{
+			name:      "manager connection failure",
+			args:      []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			expectedOutput: "Failed to connect to manager: connection failed ❌",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{managerClient: mockClient}
+			if tt.setupCLI != nil {
+				tt.setupCLI(mockCLI)
+			}
+
+			cmd := mockCLI.NewConsoleCmd()
+			cmd.SetArgs(tt.args)
+
+			var stdout, output bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&output)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Contains(t, stdout.String(), tt.expectedStdout)
+			assert.Contains(t, stdout.String()+output.String(), tt.expectedOutput)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
@@ -3,10 +3,13 @@
 package cli
 
 import (
+	"fmt"
+
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/ultravioletrs/cocos/agent/auth"
+	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/pkg/clients/grpc/agent"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -47,3 +50,18 @@ func printError(cmd *cobra.Command, message string, err error) {
 	msg := color.New(color.FgRed).Sprintf(message, err)
 	cmd.Println(msg)
 }
+
+// printManagerError prints an error of the manager, with the stage a CVM
+// launch failed at and how to fix the host when the manager reports them.
+func printManagerError(cmd *cobra.Command, message string, err error) {
+	details, ok := manager.ManagerErrorDetails(err)
+	if !ok {
+		printError(cmd, message, err)
+		return
+	}
+
+	cmd.Println(color.New(color.FgRed).Sprintf(message, fmt.Sprintf("%s (%s)", details.Message, details.Stage)))
+	if details.Remediation != "" {
+		cmd.Println(color.New(color.FgYellow).Sprintf("💡 %s", details.Remediation))
+	}
+}
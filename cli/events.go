@@ -0,0 +1,77 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+)
+
+func (cli *CLI) NewEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events [command]",
+		Short: "Inspect agent events",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Printf("Inspect agent events\n\n")
+			cmd.Printf("Usage:\n  %s [command]\n\n", cmd.CommandPath())
+			cmd.Printf("Available Commands:\n")
+
+			for _, subCmd := range cmd.Commands() {
+				cmd.Printf("  %-15s%s\n", subCmd.Name(), subCmd.Short)
+			}
+
+			cmd.Printf("\nUse \"%s [command] --help\" for more information about a command.\n", cmd.CommandPath())
+		},
+	}
+
+	cmd.AddCommand(cli.newDecryptEventCmd())
+
+	return cmd
+}
+
+func (cli *CLI) newDecryptEventCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "decrypt <event_details_file> <x25519_private_key_file_path>",
+		Short:   "Decrypt the event detail fields encrypted for the computation owner",
+		Example: "events decrypt details.json private.pem",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			details, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading event details file: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			privKey, err := encryption.ParsePrivateKey(privKeyFile)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			decrypted, err := events.DecryptDetails(privKey, details)
+			if err != nil {
+				printError(cmd, "Error decrypting event details: %v ❌ ", err)
+				return
+			}
+
+			var out bytes.Buffer
+			if err := json.Indent(&out, decrypted, "", "  "); err != nil {
+				printError(cmd, "Error formatting event details: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(out.String())
+		},
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/events"
+)
+
+func TestDecryptEventCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	privPath := filepath.Join(dir, "x25519.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: x25519KeyType, Bytes: privDer}), 0o600))
+
+	details, err := events.EncryptDetails(priv.PublicKey(), []string{"output"}, json.RawMessage(`{"output":"algorithm stderr"}`))
+	require.NoError(t, err)
+	detailsPath := filepath.Join(dir, "details.json")
+	require.NoError(t, os.WriteFile(detailsPath, details, 0o600))
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherDetails, err := events.EncryptDetails(other.PublicKey(), nil, json.RawMessage(`{"output":"algorithm stderr"}`))
+	require.NoError(t, err)
+	otherPath := filepath.Join(dir, "other.json")
+	require.NoError(t, os.WriteFile(otherPath, otherDetails, 0o600))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "successful decryption",
+			args:           []string{detailsPath, privPath},
+			expectedOutput: `"output": "algorithm stderr"`,
+		},
+		{
+			name:           "missing details file",
+			args:           []string{filepath.Join(dir, "missing.json"), privPath},
+			expectedOutput: "Error reading event details file",
+		},
+		{
+			name:           "invalid private key",
+			args:           []string{detailsPath, detailsPath},
+			expectedOutput: "Error decoding private key",
+		},
+		{
+			name:           "details encrypted for another key",
+			args:           []string{otherPath, privPath},
+			expectedOutput: "Error decrypting event details",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := (&CLI{}).NewEventsCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(append([]string{"decrypt"}, tt.args...))
+			require.NoError(t, cmd.Execute())
+
+			require.Contains(t, buf.String(), tt.expectedOutput)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/pkg/igvm"
+	"github.com/virtee/sev-snp-measure-go/cpuid"
+)
+
+// sevSNPGuestFeatures are the SEV features QEMU enables in the VMSA of SEV-SNP guests.
+const sevSNPGuestFeatures = 0x1
+
+func (cli *CLI) NewIGVMBuildCmd() *cobra.Command {
+	var (
+		cmdline string
+		vcpus   int
+		cpu     string
+		policy  uint64
+		output  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "igvmbuild <ovmf_file> <kernel_file> <initrd_file>",
+		Short: "Build a SEV-SNP IGVM file from OVMF, a kernel and an initrd",
+		Long: `igvmbuild merges an OVMF firmware built for SEV-SNP with the hashes of the kernel,
+initrd and command line and the initial state of every vCPU into an IGVM file, and
+prints its launch measurement. The CVM must boot the same kernel, initrd and command
+line with the same number of vCPUs.`,
+		Example: "igvmbuild OVMF.amdsev.fd bzImage rootfs.cpio.gz --vcpus 4 --cpu EPYC-v4 --out-file cocos.igvm",
+		Args:    cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			vcpuSig, ok := cpuid.CpuSigs[cpu]
+			if !ok {
+				printError(cmd, "Error building IGVM file: %v ❌ ", fmt.Errorf("unknown vCPU type %s", cpu))
+				return
+			}
+
+			file, err := igvm.BuildSNP(igvm.SNPConfig{
+				OVMF:          args[0],
+				Kernel:        args[1],
+				Initrd:        args[2],
+				Append:        cmdline,
+				VCPUs:         vcpus,
+				VCPUSig:       uint64(vcpuSig),
+				GuestFeatures: sevSNPGuestFeatures,
+				Policy:        policy,
+			})
+			if err != nil {
+				printError(cmd, "Error building IGVM file: %v ❌ ", err)
+				return
+			}
+
+			measurement, err := file.SNPLaunchDigest()
+			if err != nil {
+				printError(cmd, "Error measuring IGVM file: %v ❌ ", err)
+				return
+			}
+
+			data, err := file.Marshal()
+			if err != nil {
+				printError(cmd, "Error encoding IGVM file: %v ❌ ", err)
+				return
+			}
+
+			if err := os.WriteFile(output, data, filePermission); err != nil {
+				printError(cmd, "Error writing IGVM file: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("IGVM file written to %s ✔", output))
+			cmd.Printf("Launch measurement: %s\n", hex.EncodeToString(measurement))
+		},
+	}
+
+	cmd.Flags().StringVar(&cmdline, "append", qemu.KernelCommandLine, "Kernel command line the CVM boots with")
+	cmd.Flags().IntVar(&vcpus, "vcpus", 1, "Number of vCPUs the CVM is launched with")
+	cmd.Flags().StringVar(&cpu, "cpu", "EPYC-v4", "vCPU type the CVM is launched with")
+	cmd.Flags().Uint64Var(&policy, "policy", igvm.DefaultSNPPolicy, "SEV-SNP guest policy")
+	cmd.Flags().StringVarP(&output, "out-file", "o", "cocos.igvm", "Path of the IGVM file")
+
+	return cmd
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIGVMBuildCmd(t *testing.T) {
+	dir := t.TempDir()
+	ovmf := filepath.Join(dir, "OVMF.fd")
+	kernel := filepath.Join(dir, "bzImage")
+	initrd := filepath.Join(dir, "rootfs.cpio.gz")
+	for _, f := range []string{ovmf, kernel, initrd} {
+		require.NoError(t, os.WriteFile(f, make([]byte, 4096), 0o644))
+	}
+	output := filepath.Join(dir, "cocos.igvm")
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "unknown vCPU type",
+			args:           []string{ovmf, kernel, initrd, "--cpu", "unknown", "--out-file", output},
+			expectedOutput: "Error building IGVM file: unknown vCPU type unknown",
+		},
+		{
+			name:           "missing OVMF file",
+			args:           []string{filepath.Join(dir, "missing.fd"), kernel, initrd, "--out-file", output},
+			expectedOutput: "Error building IGVM file",
+		},
+		{
+			name:           "invalid OVMF file",
+			args:           []string{ovmf, kernel, initrd, "--out-file", output},
+			expectedOutput: "Error building IGVM file: parsing OVMF",
+		},
+		{
+			name:           "no vCPUs",
+			args:           []string{ovmf, kernel, initrd, "--vcpus", "0", "--out-file", output},
+			expectedOutput: "Error building IGVM file: at least one vCPU is required",
+		},
+		{
+			name:           "missing arguments",
+			args:           []string{ovmf},
+			expectedOutput: "accepts 3 arg(s), received 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := &CLI{}
+			cmd := cli.NewIGVMBuildCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			_ = cmd.Execute()
+
+			assert.Contains(t, buf.String(), tt.expectedOutput)
+			assert.NoFileExists(t, output)
+		})
+	}
+}
@@ -43,7 +43,14 @@ func (cli *CLI) NewIMAMeasurementsCmd() *cobra.Command {
 			}
 			defer imaMeasurementsFile.Close()
 
-			pcr10, err := cli.agentSDK.IMAMeasurements(cmd.Context(), imaMeasurementsFile)
+			var pcr10 []byte
+			err = withRetry(cmd, func() error {
+				if err := resetFile(imaMeasurementsFile); err != nil {
+					return err
+				}
+				pcr10, err = cli.agentSDK.IMAMeasurements(cmd.Context(), imaMeasurementsFile)
+				return err
+			})
 			if err != nil {
 				printError(cmd, "Error retrieving Linux IMA measurements file: %v ❌ ", err)
 				return
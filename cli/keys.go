@@ -3,101 +3,273 @@
 package cli
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
 )
 
 const (
-	keyBitSize     = 4096
-	rsaKeyType     = "PRIVATE KEY"
-	ecdsaKeyType   = "EC PRIVATE KEY"
-	ed25519KeyType = "PRIVATE KEY"
-	publicKeyType  = "PUBLIC KEY"
-	publicKeyFile  = "public.pem"
-	privateKeyFile = "private.pem"
-	ECDSA          = "ecdsa"
-	ED25519        = "ed25519"
+	keyBitSize       = 4096
+	rsaKeyType       = "PRIVATE KEY"
+	ecdsaKeyType     = "EC PRIVATE KEY"
+	ed25519KeyType   = "PRIVATE KEY"
+	x25519KeyType    = "PRIVATE KEY"
+	pkcs8KeyType     = "PRIVATE KEY"
+	pkcs1KeyType     = "RSA PRIVATE KEY"
+	publicKeyType    = "PUBLIC KEY"
+	pkcs1PubKeyType  = "RSA PUBLIC KEY"
+	publicKeyFile    = "public.pem"
+	privateKeyFile   = "private.pem"
+	ECDSA            = "ecdsa"
+	ED25519          = "ed25519"
+	X25519           = "x25519"
+	formatPKCS8      = "pkcs8"
+	formatPublic     = "public"
+	formatManifest   = "manifest"
+	manifestKeyField = "user_key"
 )
 
-var KeyType string
+var (
+	KeyType string
+
+	errUnsupportedKey    = errors.New("unsupported key")
+	errUnsupportedFormat = errors.New("format must be pkcs8, public or manifest")
+)
+
+// keyInfo describes a key in the JSON and YAML output formats.
+type keyInfo struct {
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	UserKey     string `json:"user_key"`
+	PublicKey   string `json:"public_key"`
+}
 
 func (cli *CLI) NewKeysCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "keys",
 		Short: "Generate a new public/private key pair",
 		Long: "Generates a new public/private key pair using an algorithm of the users choice.\n" +
-			"Supported algorithms are RSA, ecdsa, and ed25519.",
+			"Supported algorithms are RSA, ecdsa, ed25519 and x25519.\n" +
+			"x25519 keys are used for result encryption, not for signing.",
 		Example: "./build/cocos-cli keys -k rsa",
 		Args:    cobra.ExactArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
-			switch KeyType {
-			case ECDSA:
-				privEcdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-				if err != nil {
-					printError(cmd, "Error generating keys: %v ❌ ", err)
-					return
-				}
+			if err := writeKeyPair(".", KeyType); err != nil {
+				printError(cmd, "Error generating keys: %v ❌ ", err)
+				return
+			}
 
-				pubKeyBytes, err := x509.MarshalPKIXPublicKey(&privEcdsaKey.PublicKey)
-				if err != nil {
-					printError(cmd, "Error marshalling public key: %v ❌ ", err)
-					return
-				}
+			cmd.Printf("Successfully generated public/private key pair of type: %s", KeyType)
+		},
+	}
 
-				if err := generateAndWriteKeys(privEcdsaKey, pubKeyBytes, ecdsaKeyType); err != nil {
-					printError(cmd, "Error generating and writing keys: %v ❌ ", err)
-					return
-				}
+	cmd.AddCommand(cli.newKeysGenerateCmd())
+	cmd.AddCommand(cli.newKeysShowCmd())
+	cmd.AddCommand(cli.newKeysConvertCmd())
 
-			case ED25519:
-				pubEd25519Key, privEd25519Key, err := ed25519.GenerateKey(rand.Reader)
-				if err != nil {
-					printError(cmd, "Error generating keys: %v ❌ ", err)
-					return
-				}
-				pubKey, err := x509.MarshalPKIXPublicKey(pubEd25519Key)
-				if err != nil {
-					printError(cmd, "Error marshalling public key: %v ❌ ", err)
-					return
-				}
-				if err := generateAndWriteKeys(privEd25519Key, pubKey, ed25519KeyType); err != nil {
-					printError(cmd, "Error generating and writing keys: %v ❌ ", err)
-					return
-				}
+	return cmd
+}
 
-			default:
-				privKey, err := rsa.GenerateKey(rand.Reader, keyBitSize)
-				if err != nil {
-					printError(cmd, "Error generating keys: %v ❌ ", err)
-					return
-				}
+func (cli *CLI) newKeysGenerateCmd() *cobra.Command {
+	var (
+		outDir   string
+		manifest bool
+	)
 
-				pubKeyBytes, err := x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a key pair for an algorithm provider, data provider or result consumer",
+		Long: `generate writes private.pem and public.pem to the output directory. RSA keys are
+4096 bits and ECDSA keys use the P-384 curve. With --manifest the public key is also
+printed base64 encoded, as the user_key of the computation manifest expects it.`,
+		Example: "keys generate -k ed25519 --out-dir ./algo-provider --manifest",
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := os.MkdirAll(outDir, 0o755); err != nil {
+				printError(cmd, "Error creating output directory: %v ❌ ", err)
+				return
+			}
+
+			if err := writeKeyPair(outDir, KeyType); err != nil {
+				printError(cmd, "Error generating keys: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Generated %s key pair in %s ✔ ", KeyType, outDir))
+
+			if manifest {
+				pub, err := readPublicKey(filepath.Join(outDir, publicKeyFile))
 				if err != nil {
-					printError(cmd, "Error marshalling public key: %v ❌ ", err)
-					return
-				}
-				if err := generateAndWriteKeys(privKey, pubKeyBytes, rsaKeyType); err != nil {
-					printError(cmd, "Error generating and writing keys: %v ❌ ", err)
+					printError(cmd, "Error reading public key: %v ❌ ", err)
 					return
 				}
+				cmd.Printf("%s: %s\n", manifestKeyField, pub)
 			}
+		},
+	}
 
-			cmd.Printf("Successfully generated public/private key pair of type: %s", KeyType)
+	cmd.Flags().StringVarP(&outDir, "out-dir", "o", ".", "Directory the key pair is written to")
+	cmd.Flags().BoolVar(&manifest, "manifest", false, "Print the public key in the format of the computation manifest")
+
+	return cmd
+}
+
+func (cli *CLI) newKeysShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <key_file>",
+		Short: "Print the type, fingerprint and public key of a key",
+		Long: `show accepts a PEM encoded private or public key, or the base64 encoded user_key
+of a computation manifest. The fingerprint is the one the agent reports for the
+providers in the computation lineage.`,
+		Example: "keys show private.pem",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading key file: %v ❌ ", err)
+				return
+			}
+
+			_, pub, err := parseKey(data)
+			if err != nil {
+				printError(cmd, "Error parsing key: %v ❌ ", err)
+				return
+			}
+
+			der, err := x509.MarshalPKIXPublicKey(pub)
+			if err != nil {
+				printError(cmd, "Error marshalling public key: %v ❌ ", err)
+				return
+			}
+
+			info := keyInfo{
+				Type:        describeKey(pub),
+				Fingerprint: agent.KeyFingerprint(der),
+				UserKey:     base64.StdEncoding.EncodeToString(der),
+				PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: der})),
+			}
+
+			if err := printOutput(cmd, info, func(w io.Writer) {
+				cmd.Printf("Type: %s\n", info.Type)
+				cmd.Printf("Fingerprint: %s\n", info.Fingerprint)
+				cmd.Printf("Manifest %s: %s\n", manifestKeyField, info.UserKey)
+				cmd.Print(info.PublicKey)
+			}); err != nil {
+				printError(cmd, "Error printing key: %v ❌ ", err)
+			}
 		},
 	}
 }
 
-func generateAndWriteKeys(privKey any, pubKeyBytes []byte, keyType string) error {
-	privFile, err := os.Create(privateKeyFile)
+func (cli *CLI) newKeysConvertCmd() *cobra.Command {
+	var (
+		format string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "convert <key_file>",
+		Short: "Convert a key to PKCS#8, a PEM public key or the manifest format",
+		Long: `convert reads a PEM encoded private or public key, or the base64 encoded user_key
+of a computation manifest, and writes it as:
+  pkcs8     the private key in a PKCS#8 "PRIVATE KEY" block
+  public    the public key in a PKIX "PUBLIC KEY" block
+  manifest  the base64 encoded public key, as the user_key of the computation manifest`,
+		Example: "keys convert private.pem --format manifest",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading key file: %v ❌ ", err)
+				return
+			}
+
+			converted, err := convertKey(data, format)
+			if err != nil {
+				printError(cmd, "Error converting key: %v ❌ ", err)
+				return
+			}
+
+			if output == "" {
+				cmd.Print(string(converted))
+				return
+			}
+
+			// The converted key may be private, so it is only readable by the owner.
+			if err := os.WriteFile(output, converted, 0o600); err != nil {
+				printError(cmd, "Error writing key: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Converted key written to %s ✔ ", output))
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", formatManifest, "Output format: pkcs8, public or manifest")
+	cmd.Flags().StringVarP(&output, "out-file", "o", "", "Path of the converted key, printed if empty")
+
+	return cmd
+}
+
+// generateKey returns a new private key of the key type, RSA if the type is unknown.
+func generateKey(keyType string) (any, error) {
+	switch keyType {
+	case ECDSA:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case ED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case X25519:
+		return ecdh.X25519().GenerateKey(rand.Reader)
+	default:
+		return rsa.GenerateKey(rand.Reader, keyBitSize)
+	}
+}
+
+// writeKeyPair generates a key pair of the key type and writes it to the directory.
+func writeKeyPair(dir, keyType string) error {
+	privKey, err := generateKey(keyType)
+	if err != nil {
+		return err
+	}
+
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey(privKey))
+	if err != nil {
+		return err
+	}
+
+	pemType := rsaKeyType
+	switch keyType {
+	case ECDSA:
+		pemType = ecdsaKeyType
+	case ED25519:
+		pemType = ed25519KeyType
+	case X25519:
+		pemType = x25519KeyType
+	}
+
+	return generateAndWriteKeys(dir, privKey, pubKeyBytes, pemType)
+}
+
+func generateAndWriteKeys(dir string, privKey any, pubKeyBytes []byte, keyType string) error {
+	privFile, err := os.OpenFile(filepath.Join(dir, privateKeyFile), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
@@ -111,6 +283,8 @@ func generateAndWriteKeys(privKey any, pubKeyBytes []byte, keyType string) error
 		b, err = x509.MarshalECPrivateKey(privKey)
 	case ed25519.PrivateKey:
 		b, err = x509.MarshalPKCS8PrivateKey(privKey)
+	case *ecdh.PrivateKey:
+		b, err = x509.MarshalPKCS8PrivateKey(privKey)
 	}
 	if err != nil {
 		return err
@@ -123,7 +297,7 @@ func generateAndWriteKeys(privKey any, pubKeyBytes []byte, keyType string) error
 		return err
 	}
 
-	pubFile, err := os.Create(publicKeyFile)
+	pubFile, err := os.Create(filepath.Join(dir, publicKeyFile))
 	if err != nil {
 		return err
 	}
@@ -138,3 +312,120 @@ func generateAndWriteKeys(privKey any, pubKeyBytes []byte, keyType string) error
 
 	return nil
 }
+
+// readPublicKey returns the key of the file in the format of the computation manifest.
+func readPublicKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	converted, err := convertKey(data, formatManifest)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(converted)), nil
+}
+
+// parseKey parses a PEM encoded private or public key, or a base64 encoded
+// PKIX public key as held by the user_key of a computation manifest. The
+// private key is nil for public keys.
+func parseKey(data []byte) (any, crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		der, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: neither PEM nor base64 encoded", errUnsupportedKey)
+		}
+		pub, err := x509.ParsePKIXPublicKey(der)
+		return nil, pub, err
+	}
+
+	var (
+		priv any
+		err  error
+	)
+	switch block.Type {
+	case publicKeyType:
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		return nil, pub, err
+	case pkcs1PubKeyType:
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		return nil, pub, err
+	case pkcs8KeyType:
+		// RSA keys generated by the keys command are PKCS#1 encoded in a "PRIVATE KEY" block.
+		if priv, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		}
+	case pkcs1KeyType:
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case ecdsaKeyType:
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("%w: PEM block %q", errUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return priv, publicKey(priv), nil
+}
+
+func publicKey(priv any) crypto.PublicKey {
+	if k, ok := priv.(interface{ Public() crypto.PublicKey }); ok {
+		return k.Public()
+	}
+
+	return nil
+}
+
+// convertKey returns the key in the format.
+func convertKey(data []byte, format string) ([]byte, error) {
+	priv, pub, err := parseKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatPKCS8:
+		if priv == nil {
+			return nil, fmt.Errorf("%w: pkcs8 requires a private key", errUnsupportedKey)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pkcs8KeyType, Bytes: der}), nil
+	case formatPublic, formatManifest:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		if format == formatManifest {
+			return []byte(base64.StdEncoding.EncodeToString(der) + "\n"), nil
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: der}), nil
+	default:
+		return nil, errUnsupportedFormat
+	}
+}
+
+// describeKey returns the algorithm and size or curve of the public key.
+func describeKey(pub crypto.PublicKey) string {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", pub.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	case *ecdh.PublicKey:
+		if pub.Curve() == ecdh.X25519() {
+			return "X25519"
+		}
+		return "ECDH"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
@@ -3,13 +3,24 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
 )
 
 func TestNewKeysCmd(t *testing.T) {
@@ -33,6 +44,7 @@ func TestGenerateAndWriteKeys(t *testing.T) {
 		{"RSA", "rsa"},
 		{"ECDSA", "ecdsa"},
 		{"ED25519", "ed25519"},
+		{"X25519", "x25519"},
 	}
 
 	for _, tt := range tests {
@@ -63,7 +75,7 @@ func TestGenerateAndWriteKeys(t *testing.T) {
 				privKey, err = x509.ParsePKCS1PrivateKey(privPem.Bytes)
 			case "ecdsa":
 				privKey, err = x509.ParseECPrivateKey(privPem.Bytes)
-			case "ed25519":
+			case "ed25519", "x25519":
 				privKey, err = x509.ParsePKCS8PrivateKey(privPem.Bytes)
 			}
 			if err != nil {
@@ -83,6 +95,10 @@ func TestGenerateAndWriteKeys(t *testing.T) {
 				if _, ok := privKey.(ed25519.PrivateKey); !ok {
 					t.Errorf("Expected ED25519 private key, got %T", privKey)
 				}
+			case "x25519":
+				if _, ok := privKey.(*ecdh.PrivateKey); !ok {
+					t.Errorf("Expected X25519 private key, got %T", privKey)
+				}
 			}
 
 			os.Remove(privateKeyFile)
@@ -90,3 +106,147 @@ func TestGenerateAndWriteKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestKeysGenerateCmd(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data-provider")
+	KeyType = ECDSA
+	t.Cleanup(func() { KeyType = "" })
+
+	var out bytes.Buffer
+	cmd := (&CLI{}).NewKeysCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"generate", "--out-dir", dir, "--manifest"})
+	require.NoError(t, cmd.Execute())
+
+	privData, err := os.ReadFile(filepath.Join(dir, privateKeyFile))
+	require.NoError(t, err)
+	block, _ := pem.Decode(privData)
+	require.NotNil(t, block)
+	privKey, err := decodeKey(block)
+	require.NoError(t, err, "the generated key is usable by the other commands")
+	ecKey, ok := privKey.(*ecdsa.PrivateKey)
+	require.True(t, ok, "expected ECDSA private key, got %T", privKey)
+	assert.Equal(t, elliptic.P384(), ecKey.Curve)
+
+	info, err := os.Stat(filepath.Join(dir, privateKeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), manifestKeyField+": "+base64.StdEncoding.EncodeToString(der))
+}
+
+func TestKeysShowCmd(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: ed25519KeyType, Bytes: privDer}), 0o600))
+
+	var out bytes.Buffer
+	cmd := (&CLI{}).NewKeysCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"show", keyFile})
+	require.NoError(t, cmd.Execute())
+
+	assert.Contains(t, out.String(), "Type: Ed25519")
+	assert.Contains(t, out.String(), "Fingerprint: "+agent.KeyFingerprint(pubDer))
+	assert.Contains(t, out.String(), string(pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: pubDer})))
+}
+
+func TestConvertKey(t *testing.T) {
+	rsaKey, err := generateKey("rsa")
+	require.NoError(t, err)
+	rsaPriv := pem.EncodeToMemory(&pem.Block{Type: rsaKeyType, Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))})
+	rsaPubDer, err := x509.MarshalPKIXPublicKey(&rsaKey.(*rsa.PrivateKey).PublicKey)
+	require.NoError(t, err)
+	rsaPub := pem.EncodeToMemory(&pem.Block{Type: publicKeyType, Bytes: rsaPubDer})
+	rsaManifest := base64.StdEncoding.EncodeToString(rsaPubDer)
+
+	cases := []struct {
+		desc   string
+		key    []byte
+		format string
+		check  func(t *testing.T, out []byte)
+		err    error
+	}{
+		{
+			desc:   "PKCS#1 private key to PKCS#8",
+			key:    rsaPriv,
+			format: formatPKCS8,
+			check: func(t *testing.T, out []byte) {
+				block, _ := pem.Decode(out)
+				require.NotNil(t, block)
+				assert.Equal(t, pkcs8KeyType, block.Type)
+				key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+				require.NoError(t, err)
+				assert.True(t, key.(*rsa.PrivateKey).Equal(rsaKey))
+			},
+		},
+		{
+			desc:   "private key to public key",
+			key:    rsaPriv,
+			format: formatPublic,
+			check: func(t *testing.T, out []byte) {
+				assert.Equal(t, rsaPub, out)
+			},
+		},
+		{
+			desc:   "public key to manifest",
+			key:    rsaPub,
+			format: formatManifest,
+			check: func(t *testing.T, out []byte) {
+				assert.Equal(t, rsaManifest, strings.TrimSpace(string(out)))
+			},
+		},
+		{
+			desc:   "manifest to public key",
+			key:    []byte(rsaManifest + "\n"),
+			format: formatPublic,
+			check: func(t *testing.T, out []byte) {
+				assert.Equal(t, rsaPub, out)
+			},
+		},
+		{
+			desc:   "public key to PKCS#8",
+			key:    rsaPub,
+			format: formatPKCS8,
+			err:    errUnsupportedKey,
+		},
+		{
+			desc:   "unknown format",
+			key:    rsaPub,
+			format: "der",
+			err:    errUnsupportedFormat,
+		},
+		{
+			desc:   "unknown PEM block",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}),
+			format: formatPublic,
+			err:    errUnsupportedKey,
+		},
+		{
+			desc:   "not a key",
+			key:    []byte("not a key"),
+			format: formatPublic,
+			err:    errUnsupportedKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			out, err := convertKey(tc.key, tc.format)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, out)
+		})
+	}
+}
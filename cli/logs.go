@@ -0,0 +1,420 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const stderrStream = "stderr"
+
+var (
+	errInvalidSince    = errors.New("since must be a duration or an RFC 3339 timestamp")
+	errInvalidLogLevel = errors.New("level must be debug, info, warn or error")
+	errDecryptOutput   = errors.New("failed to decrypt the algorithm output")
+)
+
+// logLevelPattern matches the level names algorithms commonly prefix their log lines with.
+var logLevelPattern = regexp.MustCompile(`(?i)\b(debug|info|warn|warning|error|fatal|critical)\b`)
+
+type outputLevel int
+
+const (
+	outputDebug outputLevel = iota
+	outputInfo
+	outputWarn
+	outputError
+)
+
+func (c *CLI) NewLogsCmd() *cobra.Command {
+	var (
+		follow bool
+		since  string
+		tail   int
+		level  string
+		key    string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "logs <cvm_id>",
+		Short:   "Print the algorithm output of a computation",
+		Example: "logs <cvm_id> -f --since 10m --tail 100 --level warn",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req := &manager.LogsReq{CvmId: args[0], Follow: follow}
+
+			if since != "" {
+				t, err := parseSince(since, time.Now())
+				if err != nil {
+					printError(cmd, "Error parsing since: %v ❌ ", err)
+					return
+				}
+				req.Since = timestamppb.New(t)
+			}
+
+			if tail >= 0 {
+				req.Tail = proto.Uint32(uint32(tail))
+			}
+
+			minLevel, err := parseOutputLevel(level)
+			if err != nil {
+				printError(cmd, "Error parsing level: %v ❌ ", err)
+				return
+			}
+
+			var privKey *ecdh.PrivateKey
+			if key != "" {
+				privKeyFile, err := os.ReadFile(key)
+				if err != nil {
+					printError(cmd, "Error reading private key file: %v ❌ ", err)
+					return
+				}
+				if privKey, err = encryption.ParsePrivateKey(privKeyFile); err != nil {
+					printError(cmd, "Error decoding private key: %v ❌ ", err)
+					return
+				}
+			}
+
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			w := newOutputWriter(cmd.OutOrStdout(), cmd.ErrOrStderr(), minLevel)
+			w.key = privKey
+			err = c.streamLogs(cmd, req, w)
+			w.flush()
+			if err != nil {
+				printError(cmd, "Error streaming logs: %v ❌ ", err)
+				return
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep streaming new output, reconnecting if the stream drops")
+	cmd.Flags().StringVar(&since, "since", "", "Only show output captured after a duration ago (e.g. 10m) or an RFC 3339 timestamp")
+	cmd.Flags().IntVar(&tail, "tail", -1, "Number of buffered lines to show, all of them when negative")
+	cmd.Flags().StringVar(&level, "level", "", "Only show lines at or above the level: debug, info, warn or error")
+	cmd.Flags().StringVar(&key, "key", "", "X25519 private key file of the computation owner the agent encrypted the output for")
+
+	cmd.AddCommand(c.newLogsDownloadCmd())
+
+	return cmd
+}
+
+func (c *CLI) newLogsDownloadCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "download <cvm_id>",
+		Short: "Download the logs, console output and events of a computation in a zip archive",
+		Long: `download saves the manager log, QEMU console output, algorithm output, lifecycle
+events and diagnostic snapshot the manager kept for the computation in a single zip
+archive, e.g. to attach to a support ticket.`,
+		Example: "logs download <cvm_id> --out-file logs.zip",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			var res *manager.DownloadLogsRes
+			err := withRetry(cmd, func() (err error) {
+				res, err = c.managerClient.DownloadLogs(cmd.Context(), &manager.DownloadLogsReq{CvmId: args[0]})
+				return err
+			})
+			if err != nil {
+				printError(cmd, "Error downloading logs: %v ❌ ", err)
+				return
+			}
+
+			path := output
+			if path == "" {
+				path = fmt.Sprintf("logs-%s.zip", args[0])
+			}
+			if err := os.WriteFile(path, res.GetBundle(), filePermission); err != nil {
+				printError(cmd, "Error saving logs: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Logs saved to %s ✔", path))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "out-file", "o", "", "Path of the zip archive, logs-<cvm_id>.zip by default")
+
+	return cmd
+}
+
+// streamLogs writes the output the manager streams for the request to w. A
+// dropped stream is reopened after a backoff, asking only for the output
+// captured after the last received chunk, until MaxRetries consecutive
+// attempts fail.
+func (c *CLI) streamLogs(cmd *cobra.Command, req *manager.LogsReq, w *outputWriter) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 0; ; attempt++ {
+		received, err := c.receiveLogs(ctx, req, w)
+		if err == nil {
+			return nil
+		}
+		if received {
+			attempt = 0
+		}
+		if !retryableError(err) || attempt >= MaxRetries {
+			return err
+		}
+
+		delay := backoff(attempt)
+		if Verbose {
+			cmd.Printf("Log stream failed: %v\n", err)
+		}
+		cmd.Printf("Log stream interrupted, reconnecting in %s (%d/%d)\n", delay.Round(time.Millisecond), attempt+1, MaxRetries)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// receiveLogs writes the chunks of a single log stream to w until it ends, and
+// reports whether any chunk was received. The request is updated to resume
+// after the last received chunk.
+func (c *CLI) receiveLogs(ctx context.Context, req *manager.LogsReq, w *outputWriter) (bool, error) {
+	stream, err := c.managerClient.Logs(ctx, req)
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return received, nil
+		}
+		if err != nil {
+			return received, err
+		}
+
+		received = true
+		if err := w.write(chunk); err != nil {
+			return received, err
+		}
+
+		req.Tail = nil
+		if chunk.Timestamp != nil {
+			req.Since = timestamppb.New(chunk.Timestamp.AsTime().Add(time.Nanosecond))
+		}
+	}
+}
+
+// parseSince parses a duration before now or an RFC 3339 timestamp.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, errInvalidSince
+	}
+
+	return t, nil
+}
+
+func parseOutputLevel(level string) (outputLevel, error) {
+	switch strings.ToLower(level) {
+	case "", "debug":
+		return outputDebug, nil
+	case "info":
+		return outputInfo, nil
+	case "warn", "warning":
+		return outputWarn, nil
+	case "error":
+		return outputError, nil
+	default:
+		return 0, errInvalidLogLevel
+	}
+}
+
+// lineLevel returns the level of the first level name found in the line,
+// lines without one are considered info.
+func lineLevel(line []byte) outputLevel {
+	match := logLevelPattern.Find(line)
+	if match == nil {
+		return outputInfo
+	}
+
+	switch strings.ToLower(string(match)) {
+	case "debug":
+		return outputDebug
+	case "warn", "warning":
+		return outputWarn
+	case "error", "fatal", "critical":
+		return outputError
+	default:
+		return outputInfo
+	}
+}
+
+// logRecord is a line of the algorithm output in the JSON and YAML output formats.
+type logRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Stream    string    `json:"stream"`
+	Line      string    `json:"line"`
+}
+
+// outputWriter writes the algorithm stdout and stderr to the matching writers.
+// When a minimum level is set, the output is split into lines and the lines
+// below the level are dropped. In the JSON and YAML output formats every line
+// is written to stdout as a record. With a key, the output is decrypted first.
+type outputWriter struct {
+	stdout    io.Writer
+	stderr    io.Writer
+	minLevel  outputLevel
+	key       *ecdh.PrivateKey
+	partial   map[string][]byte
+	encrypted map[string][]byte
+	received  map[string]time.Time
+}
+
+func newOutputWriter(stdout, stderr io.Writer, minLevel outputLevel) *outputWriter {
+	return &outputWriter{
+		stdout:    stdout,
+		stderr:    stderr,
+		minLevel:  minLevel,
+		partial:   make(map[string][]byte),
+		encrypted: make(map[string][]byte),
+		received:  make(map[string]time.Time),
+	}
+}
+
+func (w *outputWriter) write(chunk *manager.LogChunk) error {
+	out := w.stdout
+	if chunk.Stream == stderrStream {
+		out = w.stderr
+	}
+
+	plaintext := chunk.Data
+	if w.key != nil {
+		var err error
+		if plaintext, err = w.decrypt(chunk.Stream, chunk.Data); err != nil {
+			return err
+		}
+	}
+
+	if w.minLevel == outputDebug && OutputFormat == OutputTable {
+		_, err := out.Write(plaintext)
+		return err
+	}
+
+	w.received[chunk.Stream] = chunk.GetTimestamp().AsTime()
+	data := append(w.partial[chunk.Stream], plaintext...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := w.writeLine(chunk.Stream, out, data[:i+1]); err != nil {
+			return err
+		}
+		data = data[i+1:]
+	}
+	w.partial[chunk.Stream] = bytes.Clone(data)
+
+	return nil
+}
+
+// decrypt returns the output of the complete lines of ciphertext the agent
+// encrypted, buffering the partial last line of the stream.
+func (w *outputWriter) decrypt(stream string, data []byte) ([]byte, error) {
+	data = append(w.encrypted[stream], data...)
+
+	var plaintext []byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+
+		output, err := logging.DecryptLine(w.key, data[:i])
+		if err != nil {
+			return nil, errors.Join(errDecryptOutput, err)
+		}
+		plaintext = append(plaintext, output...)
+		data = data[i+1:]
+	}
+	w.encrypted[stream] = bytes.Clone(data)
+
+	return plaintext, nil
+}
+
+// flush writes the buffered lines that did not end with a newline.
+func (w *outputWriter) flush() {
+	for stream, data := range w.partial {
+		out := w.stdout
+		if stream == stderrStream {
+			out = w.stderr
+		}
+		if len(data) > 0 {
+			_ = w.writeLine(stream, out, data)
+		}
+		delete(w.partial, stream)
+	}
+}
+
+func (w *outputWriter) writeLine(stream string, out io.Writer, line []byte) error {
+	if lineLevel(line) < w.minLevel {
+		return nil
+	}
+
+	if OutputFormat != OutputTable {
+		return writeRecord(w.stdout, logRecord{
+			Timestamp: w.received[stream],
+			Stream:    stream,
+			Line:      strings.TrimSuffix(string(line), "\n"),
+		})
+	}
+
+	_, err := out.Write(line)
+
+	return err
+}
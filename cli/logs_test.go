@@ -0,0 +1,432 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type logsClientStream struct {
+	grpc.ClientStream
+	chunks []*manager.LogChunk
+	err    error
+}
+
+func (s *logsClientStream) Recv() (*manager.LogChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+
+	return chunk, nil
+}
+
+func TestCLI_NewLogsCmd(t *testing.T) {
+	defer func(base time.Duration) { retryBackoff, MaxRetries = base, 0 }(retryBackoff)
+	retryBackoff = 0
+	MaxRetries = 2
+
+	at := time.Unix(1700000000, 0)
+	chunk := func(stream, data string, offset time.Duration) *manager.LogChunk {
+		return &manager.LogChunk{CvmId: "vm-123", Stream: stream, Data: []byte(data), Timestamp: timestamppb.New(at.Add(offset))}
+	}
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+
+	tests := []struct {
+		name           string
+		args           []string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		expectedStdout string
+		expectedStderr string
+		expectedOutput string
+		hidden         string
+	}{
+		{
+			name: "print buffered output",
+			args: []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.MatchedBy(func(req *manager.LogsReq) bool {
+					return req.CvmId == "vm-123" && !req.Follow && req.Tail == nil && req.Since == nil
+				})).Return(&logsClientStream{chunks: []*manager.LogChunk{
+					chunk("stdout", "epoch 1\n", 0),
+					chunk("stderr", "warning: slow\n", time.Second),
+				}}, nil)
+			},
+			expectedStdout: "epoch 1\n",
+			expectedStderr: "warning: slow\n",
+		},
+		{
+			name: "follow with tail and since",
+			args: []string{"vm-123", "-f", "--tail", "5", "--since", "2023-11-14T22:13:20Z"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.MatchedBy(func(req *manager.LogsReq) bool {
+					return req.Follow && req.GetTail() == 5 && req.Tail != nil && req.Since.AsTime().Equal(at)
+				})).Return(&logsClientStream{chunks: []*manager.LogChunk{chunk("stdout", "epoch 1\n", 0)}}, nil)
+			},
+			expectedStdout: "epoch 1\n",
+		},
+		{
+			name: "filter lines by level",
+			args: []string{"vm-123", "--level", "warn"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.Anything).Return(&logsClientStream{chunks: []*manager.LogChunk{
+					chunk("stdout", "INFO epoch 1\nWARNING: lr too ", 0),
+					chunk("stdout", "high\nepoch 2\n", 0),
+					chunk("stderr", "[ERROR] out of memory", 0),
+				}}, nil)
+			},
+			expectedStdout: "WARNING: lr too high\n",
+			expectedStderr: "[ERROR] out of memory",
+			hidden:         "epoch",
+		},
+		{
+			name: "reconnect after the stream drops",
+			args: []string{"vm-123", "-f", "--tail", "1"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.MatchedBy(func(req *manager.LogsReq) bool {
+					return req.Tail != nil
+				})).Return(&logsClientStream{chunks: []*manager.LogChunk{chunk("stdout", "epoch 1\n", 0)}, err: unavailable}, nil).Once()
+				m.On("Logs", mock.Anything, mock.MatchedBy(func(req *manager.LogsReq) bool {
+					return req.Tail == nil && req.Since.AsTime().Equal(at.Add(time.Nanosecond))
+				})).Return(&logsClientStream{err: unavailable}, nil).Once()
+				m.On("Logs", mock.Anything, mock.Anything).Return(&logsClientStream{chunks: []*manager.LogChunk{chunk("stdout", "epoch 2\n", time.Second)}}, nil).Once()
+			},
+			expectedStdout: "epoch 1\nLog stream interrupted, reconnecting in 0s (1/2)\n",
+			expectedOutput: "(2/2)\nepoch 2\n",
+		},
+		{
+			name: "give up after retries",
+			args: []string{"vm-123", "-f"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.Anything).Return(nil, unavailable).Times(3)
+			},
+			expectedOutput: "Error streaming logs:",
+		},
+		{
+			name: "manager error",
+			args: []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Logs", mock.Anything, mock.Anything).Return(nil, errors.New("agent log collection is disabled")).Once()
+			},
+			expectedOutput: "Error streaming logs: agent log collection is disabled ❌",
+		},
+		{
+			name:           "invalid since",
+			args:           []string{"vm-123", "--since", "yesterday"},
+			setupMock:      func(m *mocks.ManagerServiceClient) {},
+			expectedOutput: "Error parsing since: " + errInvalidSince.Error(),
+		},
+		{
+			name:           "invalid level",
+			args:           []string{"vm-123", "--level", "trace"},
+			setupMock:      func(m *mocks.ManagerServiceClient) {},
+			expectedOutput: "Error parsing level: " + errInvalidLogLevel.Error(),
+		},
+		{
+			name:      "manager connection failure",
+			args:      []string{"vm-123"},
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			expectedOutput: "Failed to connect to manager: connection failed ❌",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{managerClient: mockClient}
+			if tt.setupCLI != nil {
+				tt.setupCLI(mockCLI)
+			}
+
+			cmd := mockCLI.NewLogsCmd()
+			cmd.SetArgs(tt.args)
+
+			var stdout, output bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&output)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Contains(t, stdout.String(), tt.expectedStdout)
+			assert.Equal(t, tt.expectedStderr, output.String())
+			assert.Contains(t, stdout.String()+output.String(), tt.expectedOutput)
+			if tt.hidden != "" {
+				assert.NotContains(t, stdout.String()+output.String(), tt.hidden)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCLI_NewLogsCmdDecrypt(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "owner.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: x25519KeyType, Bytes: privDer}), 0o600))
+
+	var streamed bytes.Buffer
+	stdout := logging.Encrypt(&encryptedOutput{stdout: &streamed}, priv.PublicKey()).Stdout()
+	_, err = stdout.Write([]byte("INFO epoch 1\nWARNING: lr too high\n"))
+	require.NoError(t, err)
+	ciphertext := streamed.Bytes()
+
+	// The line of ciphertext is split across chunks as the manager may split it.
+	half := len(ciphertext) / 2
+	chunks := []*manager.LogChunk{
+		{CvmId: "vm-123", Stream: "stdout", Data: ciphertext[:half], Timestamp: timestamppb.Now()},
+		{CvmId: "vm-123", Stream: "stdout", Data: ciphertext[half:], Timestamp: timestamppb.Now()},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "decrypt output",
+			args:     []string{"vm-123", "--key", keyPath},
+			expected: "INFO epoch 1\nWARNING: lr too high\n",
+		},
+		{
+			name:     "decrypt and filter output",
+			args:     []string{"vm-123", "--key", keyPath, "--level", "warn"},
+			expected: "WARNING: lr too high\n",
+		},
+		{
+			name:     "missing key file",
+			args:     []string{"vm-123", "--key", filepath.Join(t.TempDir(), "missing.pem")},
+			expected: "Error reading private key file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			mockClient.On("Logs", mock.Anything, mock.Anything).Return(&logsClientStream{chunks: slices.Clone(chunks)}, nil).Maybe()
+
+			cmd := (&CLI{managerClient: mockClient}).NewLogsCmd()
+			cmd.SetArgs(tt.args)
+
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Contains(t, out.String(), tt.expected)
+			assert.NotContains(t, out.String(), string(ciphertext[:16]), "the ciphertext is not printed")
+		})
+	}
+}
+
+// encryptedOutput holds the output the agent streams encrypted.
+type encryptedOutput struct {
+	stdout io.Writer
+}
+
+func (o *encryptedOutput) Stdout() io.Writer { return o.stdout }
+
+func (o *encryptedOutput) Stderr() io.Writer { return io.Discard }
+
+func TestCLI_NewLogsCmdRecords(t *testing.T) {
+	defer func() { OutputFormat = OutputTable }()
+
+	at := time.Unix(1700000000, 0).UTC()
+	chunks := func() []*manager.LogChunk {
+		return []*manager.LogChunk{
+			{Stream: "stdout", Data: []byte("epoch 1\nepoch "), Timestamp: timestamppb.New(at)},
+			{Stream: "stdout", Data: []byte("2\n"), Timestamp: timestamppb.New(at.Add(time.Second))},
+			{Stream: "stderr", Data: []byte("warning: slow"), Timestamp: timestamppb.New(at)},
+		}
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: OutputJSON,
+			expected: `{"timestamp":"2023-11-14T22:13:20Z","stream":"stdout","line":"epoch 1"}
+{"timestamp":"2023-11-14T22:13:21Z","stream":"stdout","line":"epoch 2"}
+{"timestamp":"2023-11-14T22:13:20Z","stream":"stderr","line":"warning: slow"}
+`,
+		},
+		{
+			format: OutputYAML,
+			expected: `---
+line: epoch 1
+stream: stdout
+timestamp: "2023-11-14T22:13:20Z"
+---
+line: epoch 2
+stream: stdout
+timestamp: "2023-11-14T22:13:21Z"
+---
+line: 'warning: slow'
+stream: stderr
+timestamp: "2023-11-14T22:13:20Z"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			OutputFormat = tt.format
+
+			mockClient := new(mocks.ManagerServiceClient)
+			mockClient.On("Logs", mock.Anything, mock.Anything).Return(&logsClientStream{chunks: chunks()}, nil)
+
+			cmd := (&CLI{managerClient: mockClient}).NewLogsCmd()
+			cmd.SetArgs([]string{"vm-123"})
+
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			assert.NoError(t, cmd.Execute())
+			assert.Equal(t, tt.expected, stdout.String())
+			assert.Empty(t, stderr.String())
+		})
+	}
+}
+
+func TestCLI_NewLogsDownloadCmd(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "logs.zip")
+
+	tests := []struct {
+		name           string
+		args           []string
+		setupMock      func(*mocks.ManagerServiceClient)
+		expectedOutput string
+		expectedFile   string
+	}{
+		{
+			name: "download logs",
+			args: []string{"download", "vm-123", "--out-file", output},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("DownloadLogs", mock.Anything, &manager.DownloadLogsReq{CvmId: "vm-123"}).Return(&manager.DownloadLogsRes{Bundle: []byte("bundle")}, nil)
+			},
+			expectedOutput: "Logs saved to " + output,
+			expectedFile:   output,
+		},
+		{
+			name: "CVM not found",
+			args: []string{"download", "vm-456", "--out-file", filepath.Join(dir, "missing.zip")},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("DownloadLogs", mock.Anything, &manager.DownloadLogsReq{CvmId: "vm-456"}).Return(nil, errors.New("not found"))
+			},
+			expectedOutput: "Error downloading logs: not found ❌",
+		},
+		{
+			name:           "missing CVM argument",
+			args:           []string{"download"},
+			setupMock:      func(m *mocks.ManagerServiceClient) {},
+			expectedOutput: "accepts 1 arg(s), received 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{managerClient: mockClient}
+
+			cmd := mockCLI.NewLogsCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			_ = cmd.Execute()
+			assert.Contains(t, buf.String(), tt.expectedOutput)
+			if tt.expectedFile != "" {
+				data, err := os.ReadFile(tt.expectedFile)
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("bundle"), data)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	cases := []struct {
+		desc  string
+		since string
+		want  time.Time
+		err   error
+	}{
+		{desc: "duration", since: "10m", want: now.Add(-10 * time.Minute)},
+		{desc: "timestamp", since: "2023-11-14T22:00:00Z", want: time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)},
+		{desc: "negative duration", since: "-10m", err: errInvalidSince},
+		{desc: "invalid", since: "yesterday", err: errInvalidSince},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseSince(tc.since, now)
+			assert.ErrorIs(t, err, tc.err)
+			assert.True(t, tc.want.Equal(got))
+		})
+	}
+}
+
+func TestLineLevel(t *testing.T) {
+	cases := []struct {
+		line string
+		want outputLevel
+	}{
+		{line: "DEBUG:root:loading data", want: outputDebug},
+		{line: "time=... level=INFO msg=epoch", want: outputInfo},
+		{line: "WARNING: learning rate too high", want: outputWarn},
+		{line: "[warn] retrying", want: outputWarn},
+		{line: "Fatal: out of memory", want: outputError},
+		{line: "CRITICAL failure", want: outputError},
+		{line: "epoch 1 loss 0.3", want: outputInfo},
+		{line: "informative output", want: outputInfo},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, tc.want, lineLevel([]byte(tc.line)))
+		})
+	}
+}
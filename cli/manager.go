@@ -3,6 +3,10 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -12,13 +16,19 @@ import (
 )
 
 const (
-	serverURL = "server-url"
-	serverCA  = "server-ca"
-	clientKey = "client-key"
-	clientCrt = "client-crt"
-	caUrl     = "ca-url"
-	logLevel  = "log-level"
-	ttlFlag   = "ttl"
+	serverURL  = "server-url"
+	serverCA   = "server-ca"
+	clientKey  = "client-key"
+	clientCrt  = "client-crt"
+	caUrl      = "ca-url"
+	logLevel   = "log-level"
+	ttlFlag    = "ttl"
+	profile    = "machine-profile"
+	tenantFlag = "tenant"
+	hostCPUs   = "host-cpus"
+	numaNode   = "numa-node"
+	gpuFlag    = "gpu"
+	gpuCCFlag  = "gpu-nvidia-cc"
 )
 
 var (
@@ -29,6 +39,12 @@ var (
 	agentCVMCaUrl     string
 	agentLogLevel     string
 	ttl               time.Duration
+	machineProfile    string
+	tenant            string
+	vmHostCPUs        string
+	vmNUMANode        uint32
+	vmGPUs            []string
+	vmGPUNvidiaCC     bool
 )
 
 func (c *CLI) NewCreateVMCmd() *cobra.Command {
@@ -59,6 +75,16 @@ func (c *CLI) NewCreateVMCmd() *cobra.Command {
 			createReq.AgentCvmServerUrl = agentCVMServerUrl
 			createReq.AgentLogLevel = agentLogLevel
 			createReq.AgentCvmCaUrl = agentCVMCaUrl
+			createReq.MachineProfile = machineProfile
+			createReq.Tenant = tenant
+			createReq.HostCpus = vmHostCPUs
+			if cmd.Flags().Changed(numaNode) {
+				createReq.NumaNode = &vmNUMANode
+			}
+			createReq.GpuDevices = vmGPUs
+			if cmd.Flags().Changed(gpuCCFlag) {
+				createReq.GpuNvidiaCc = &vmGPUNvidiaCC
+			}
 
 			if ttl > 0 {
 				createReq.Ttl = ttl.String()
@@ -68,11 +94,15 @@ func (c *CLI) NewCreateVMCmd() *cobra.Command {
 
 			res, err := c.managerClient.CreateVm(cmd.Context(), createReq)
 			if err != nil {
-				printError(cmd, "Error creating virtual machine: %v ❌ ", err)
+				printManagerError(cmd, "Error creating virtual machine: %v ❌ ", err)
 				return
 			}
 
-			cmd.Println(color.New(color.FgGreen).Sprintf("✅ Virtual machine created successfully with id %s and port %s", res.CvmId, res.ForwardedPort))
+			if err := printOutput(cmd, res, func(w io.Writer) {
+				cmd.Println(color.New(color.FgGreen).Sprintf("✅ Virtual machine created successfully with id %s and port %s", res.CvmId, res.ForwardedPort))
+			}); err != nil {
+				printError(cmd, "Error printing virtual machine: %v ❌ ", err)
+			}
 		},
 	}
 
@@ -83,6 +113,12 @@ func (c *CLI) NewCreateVMCmd() *cobra.Command {
 	cmd.Flags().StringVar(&agentCVMCaUrl, caUrl, "", "CVM CA service URL")
 	cmd.Flags().StringVar(&agentLogLevel, logLevel, "", "Agent Log level")
 	cmd.Flags().DurationVar(&ttl, ttlFlag, 0, "TTL for the VM")
+	cmd.Flags().StringVar(&machineProfile, profile, "", "Machine profile of the VM, default or microvm, the manager profile if empty")
+	cmd.Flags().StringVar(&tenant, tenantFlag, "", "Tenant whose quota the VM counts against, which must be the subject of the client certificate")
+	cmd.Flags().StringVar(&vmHostCPUs, hostCPUs, "", "Host CPUs to pin the vCPUs to, e.g. 0-3,8, the manager HOST_CPUS if empty")
+	cmd.Flags().Uint32Var(&vmNUMANode, numaNode, 0, "Host NUMA node to allocate the VM memory from, the manager NUMA_NODE if unset")
+	cmd.Flags().StringSliceVar(&vmGPUs, gpuFlag, nil, "PCI addresses of the host GPUs to pass through to the VM, e.g. 0000:41:00.0")
+	cmd.Flags().BoolVar(&vmGPUNvidiaCC, gpuCCFlag, false, "Run the GPUs in NVIDIA confidential computing mode, the manager GPU_NVIDIA_CC if unset")
 	if err := cmd.MarkFlagRequired(serverURL); err != nil {
 		printError(cmd, "Error marking flag as required: %v ❌ ", err)
 		return cmd
@@ -123,6 +159,179 @@ func (c *CLI) NewRemoveVMCmd() *cobra.Command {
 	}
 }
 
+func (c *CLI) NewAttachDatasetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "attach-dataset",
+		Short:   "Hot-add a dataset disk image to a running virtual machine",
+		Example: `attach-dataset <cvm_id> <disk_image_path>`,
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			cmd.Println("🔗 Attaching dataset disk")
+
+			_, err := c.managerClient.AttachDataset(cmd.Context(), &manager.AttachDatasetReq{CvmId: args[0], DiskPath: args[1]})
+			if err != nil {
+				printError(cmd, "Error attaching dataset disk: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("✅ Dataset disk attached successfully"))
+		},
+	}
+}
+
+func (c *CLI) NewGetImagesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "images",
+		Short:   "List the guest images the manager boots virtual machines with",
+		Example: `images`,
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			var res *manager.GetImagesRes
+			err := withRetry(cmd, func() (err error) {
+				res, err = c.managerClient.GetImages(cmd.Context(), &manager.GetImagesReq{})
+				return err
+			})
+			if err != nil {
+				printError(cmd, "Error fetching images: %v ❌ ", err)
+				return
+			}
+
+			if err := printOutput(cmd, res, func(w io.Writer) {
+				fmt.Fprintln(w, "NAME\tVERSION\tDIGEST\tPATH")
+				for _, img := range res.Images {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", img.Name, img.Version, img.Digest, img.Path)
+				}
+			}); err != nil {
+				printError(cmd, "Error printing images: %v ❌ ", err)
+			}
+		},
+	}
+}
+
+func (c *CLI) NewTenantQuotaCmd() *cobra.Command {
+	var quota manager.TenantQuota
+
+	cmd := &cobra.Command{
+		Use:     "tenant-quota",
+		Short:   "Set the quota of the virtual machines a tenant runs concurrently, 0 leaves a resource unlimited",
+		Example: `tenant-quota <tenant> --max-vms 2 --max-vcpus 8 --max-memory-mb 16384`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			res, err := c.managerClient.SetTenantQuota(cmd.Context(), &manager.SetTenantQuotaReq{Tenant: args[0], Quota: &quota})
+			if err != nil {
+				printError(cmd, "Error setting tenant quota: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("✅ Quota of tenant %s set successfully", res.Tenant))
+
+			if err := printOutput(cmd, res, func(w io.Writer) {
+				fmt.Fprintln(w, "RESOURCE\tUSED\tQUOTA")
+				fmt.Fprintf(w, "vms\t%d\t%s\n", res.Usage.GetVms(), quotaLimit(uint64(res.Quota.GetMaxVms())))
+				fmt.Fprintf(w, "vcpus\t%d\t%s\n", res.Usage.GetVcpus(), quotaLimit(uint64(res.Quota.GetMaxVcpus())))
+				fmt.Fprintf(w, "memory_mb\t%d\t%s\n", res.Usage.GetMemoryMb(), quotaLimit(res.Quota.GetMaxMemoryMb()))
+			}); err != nil {
+				printError(cmd, "Error printing tenant quota: %v ❌ ", err)
+			}
+		},
+	}
+
+	cmd.Flags().Uint32Var(&quota.MaxVms, "max-vms", 0, "Maximum number of virtual machines the tenant runs concurrently")
+	cmd.Flags().Uint32Var(&quota.MaxVcpus, "max-vcpus", 0, "Maximum number of vCPUs of the virtual machines of the tenant")
+	cmd.Flags().Uint64Var(&quota.MaxMemoryMb, "max-memory-mb", 0, "Maximum memory in MiB of the virtual machines of the tenant")
+
+	return cmd
+}
+
+// quotaLimit prints a quota limit, 0 being unlimited.
+func quotaLimit(limit uint64) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+
+	return fmt.Sprint(limit)
+}
+
+func (c *CLI) NewDiagnosticsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "diagnostics",
+		Short:   "Print the diagnostic snapshot the agent of a virtual machine sent when its computation run failed",
+		Example: `diagnostics <cvm_id>`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			var res *manager.DiagnosticsRes
+			err := withRetry(cmd, func() (err error) {
+				res, err = c.managerClient.Diagnostics(cmd.Context(), &manager.DiagnosticsReq{CvmId: args[0]})
+				return err
+			})
+			if err != nil {
+				printError(cmd, "Error fetching diagnostics: %v ❌ ", err)
+				return
+			}
+
+			var snapshot bytes.Buffer
+			if err := json.Indent(&snapshot, res.Diagnostics.GetSnapshot(), "", "  "); err != nil {
+				printError(cmd, "Error decoding diagnostics: %v ❌ ", err)
+				return
+			}
+
+			if err := printOutput(cmd, json.RawMessage(snapshot.Bytes()), func(w io.Writer) {
+				cmd.Println(snapshot.String())
+			}); err != nil {
+				printError(cmd, "Error printing diagnostics: %v ❌ ", err)
+			}
+		},
+	}
+}
+
 func fileReader(path string) ([]byte, error) {
 	if path == "" {
 		return nil, nil
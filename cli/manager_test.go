@@ -15,6 +15,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -39,7 +41,12 @@ func TestCLI_NewCreateVMCmd(t *testing.T) {
 						req.Ttl == "1h0m0s" &&
 						string(req.AgentCvmServerCaCert) == "ca-cert-content" &&
 						string(req.AgentCvmClientKey) == "client-key-content" &&
-						string(req.AgentCvmClientCert) == "client-cert-content"
+						string(req.AgentCvmClientCert) == "client-cert-content" &&
+						req.MachineProfile == "microvm" &&
+						req.HostCpus == "8-11" &&
+						req.NumaNode != nil && *req.NumaNode == 1 &&
+						len(req.GpuDevices) == 2 && req.GpuDevices[1] == "0000:c1:00.0" &&
+						req.GpuNvidiaCc != nil && *req.GpuNvidiaCc
 				})).Return(&manager.CreateRes{
 					CvmId:         "vm-123",
 					ForwardedPort: "8080",
@@ -61,13 +68,18 @@ func TestCLI_NewCreateVMCmd(t *testing.T) {
 				return nil
 			},
 			flags: map[string]string{
-				"server-url": "https://server.com",
-				"server-ca":  "server-ca.pem",
-				"client-key": "client-key.pem",
-				"client-crt": "client-crt.pem",
-				"ca-url":     "https://ca.com",
-				"log-level":  "debug",
-				"ttl":        "1h",
+				"server-url":      "https://server.com",
+				"server-ca":       "server-ca.pem",
+				"client-key":      "client-key.pem",
+				"client-crt":      "client-crt.pem",
+				"ca-url":          "https://ca.com",
+				"log-level":       "debug",
+				"ttl":             "1h",
+				"machine-profile": "microvm",
+				"host-cpus":       "8-11",
+				"numa-node":       "1",
+				"gpu":             "0000:41:00.0,0000:c1:00.0",
+				"gpu-nvidia-cc":   "true",
 			},
 			expectedOutput: "✅ Virtual machine created successfully with id vm-123 and port 8080",
 			expectError:    false,
@@ -80,6 +92,11 @@ func TestCLI_NewCreateVMCmd(t *testing.T) {
 						req.AgentLogLevel == "" &&
 						req.AgentCvmCaUrl == "" &&
 						req.Ttl == "" &&
+						req.MachineProfile == "" &&
+						req.HostCpus == "" &&
+						req.NumaNode == nil &&
+						len(req.GpuDevices) == 0 &&
+						req.GpuNvidiaCc == nil &&
 						len(req.AgentCvmServerCaCert) == 0 &&
 						len(req.AgentCvmClientKey) == 0 &&
 						len(req.AgentCvmClientCert) == 0
@@ -149,6 +166,28 @@ func TestCLI_NewCreateVMCmd(t *testing.T) {
 			expectedError: "Error creating virtual machine: API error ❌",
 			expectError:   true,
 		},
+		{
+			name: "CreateVm launch failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				st, err := status.New(codes.FailedPrecondition, "qemu exited").WithDetails(&manager.ManagerError{
+					Stage:       manager.StageVsockFailed,
+					Message:     "qemu exited",
+					Remediation: "/dev/vhost-vsock is missing, load the vhost_vsock module",
+				})
+				require.NoError(t, err)
+				m.On("CreateVm", mock.Anything, mock.Anything).Return(nil, st.Err())
+			},
+			setupCLI: func(cli *CLI) {
+			},
+			setupFiles: func(tmpDir string) error {
+				return nil
+			},
+			flags: map[string]string{
+				"server-url": "https://server.com",
+			},
+			expectedError: "Error creating virtual machine: qemu exited (vsock_failed) ❌ \n💡 /dev/vhost-vsock is missing, load the vhost_vsock module",
+			expectError:   true,
+		},
 		{
 			name: "missing required server-url flag",
 			setupMock: func(m *mocks.ManagerServiceClient) {
@@ -326,6 +365,288 @@ func TestCLI_NewRemoveVMCmd(t *testing.T) {
 	}
 }
 
+func TestCLI_NewAttachDatasetCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		args           []string
+		expectedOutput string
+		expectedError  string
+		expectError    bool
+	}{
+		{
+			name: "successful dataset attach",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("AttachDataset", mock.Anything, &manager.AttachDatasetReq{
+					CvmId:    "vm-123",
+					DiskPath: "/data/dataset.img",
+				}).Return(&emptypb.Empty{}, nil)
+			},
+			setupCLI:       func(cli *CLI) {},
+			args:           []string{"vm-123", "/data/dataset.img"},
+			expectedOutput: "✅ Dataset disk attached successfully",
+		},
+		{
+			name:      "manager client initialization failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			args:          []string{"vm-123", "/data/dataset.img"},
+			expectedError: "Failed to connect to manager: connection failed ❌",
+			expectError:   true,
+		},
+		{
+			name: "AttachDataset API call failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("AttachDataset", mock.Anything, &manager.AttachDatasetReq{
+					CvmId:    "vm-456",
+					DiskPath: "/data/dataset.img",
+				}).Return(nil, errors.New("no free dataset disk slots"))
+			},
+			setupCLI:      func(cli *CLI) {},
+			args:          []string{"vm-456", "/data/dataset.img"},
+			expectedError: "Error attaching dataset disk: no free dataset disk slots ❌",
+			expectError:   true,
+		},
+		{
+			name:          "missing disk argument",
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			setupCLI:      func(cli *CLI) {},
+			args:          []string{"vm-123"},
+			expectError:   true,
+			expectedError: "accepts 2 arg(s), received 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+			tt.setupCLI(mockCLI)
+
+			cmd := mockCLI.NewAttachDatasetCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			err := cmd.Execute()
+
+			if tt.expectError {
+				assert.Contains(t, buf.String(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, buf.String(), tt.expectedOutput)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCLI_NewGetImagesCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		outputFormat   string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		expectedOutput string
+		expectedError  string
+		expectError    bool
+	}{
+		{
+			name: "successful images retrieval",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("GetImages", mock.Anything, &manager.GetImagesReq{}).Return(&manager.GetImagesRes{
+					Images: []*manager.Image{
+						{Name: "kernel", Path: "img/bzImage", Version: "v0.1.0", Digest: "abcd"},
+					},
+				}, nil)
+			},
+			setupCLI: func(cli *CLI) {
+			},
+			expectedOutput: "kernel  v0.1.0   abcd    img/bzImage",
+			expectError:    false,
+		},
+		{
+			name:         "successful images retrieval as JSON",
+			outputFormat: OutputJSON,
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("GetImages", mock.Anything, &manager.GetImagesReq{}).Return(&manager.GetImagesRes{
+					Images: []*manager.Image{
+						{Name: "kernel", Path: "img/bzImage", Version: "v0.1.0", Digest: "abcd"},
+					},
+				}, nil)
+			},
+			setupCLI: func(cli *CLI) {
+			},
+			expectedOutput: `"images": [
+    {
+      "name": "kernel",`,
+			expectError: false,
+		},
+		{
+			name: "manager client initialization failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				// No expectations set as initialization fails before calling any methods
+			},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			expectedError: "Failed to connect to manager: connection failed ❌",
+			expectError:   true,
+		},
+		{
+			name: "GetImages API call failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("GetImages", mock.Anything, &manager.GetImagesReq{}).Return(nil, errors.New("read failed"))
+			},
+			setupCLI: func(cli *CLI) {
+			},
+			expectedError: "Error fetching images: read failed ❌",
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.outputFormat != "" {
+				OutputFormat = tt.outputFormat
+				defer func() { OutputFormat = OutputTable }()
+			}
+
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+			tt.setupCLI(mockCLI)
+
+			cmd := mockCLI.NewGetImagesCmd()
+			cmd.SetArgs([]string{})
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			err := cmd.Execute()
+
+			if tt.expectError {
+				if tt.expectedError != "" {
+					assert.Contains(t, buf.String(), tt.expectedError)
+				}
+			} else {
+				assert.NoError(t, err)
+				if tt.expectedOutput != "" {
+					assert.Contains(t, buf.String(), tt.expectedOutput)
+				}
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCLI_NewTenantQuotaCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*mocks.ManagerServiceClient)
+		setupCLI       func(*CLI)
+		args           []string
+		expectedOutput []string
+		expectedError  string
+		expectError    bool
+	}{
+		{
+			name: "successful quota update",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				quota := &manager.TenantQuota{MaxVms: 2, MaxMemoryMb: 16384}
+				m.On("SetTenantQuota", mock.Anything, &manager.SetTenantQuotaReq{Tenant: "acme", Quota: quota}).Return(&manager.SetTenantQuotaRes{
+					Tenant: "acme",
+					Quota:  quota,
+					Usage:  &manager.TenantUsage{Vms: 1, Vcpus: 4, MemoryMb: 8192},
+				}, nil)
+			},
+			setupCLI: func(cli *CLI) {},
+			args:     []string{"acme", "--max-vms", "2", "--max-memory-mb", "16384"},
+			expectedOutput: []string{
+				"✅ Quota of tenant acme set successfully",
+				"vms        1     2",
+				"vcpus      4     unlimited",
+				"memory_mb  8192  16384",
+			},
+		},
+		{
+			name:      "manager client initialization failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {},
+			setupCLI: func(cli *CLI) {
+				cli.connectErr = errors.New("connection failed")
+			},
+			args:          []string{"acme"},
+			expectedError: "Failed to connect to manager: connection failed ❌",
+			expectError:   true,
+		},
+		{
+			name: "SetTenantQuota API call failure",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("SetTenantQuota", mock.Anything, mock.Anything).Return(nil, errors.New("permission denied"))
+			},
+			setupCLI:      func(cli *CLI) {},
+			args:          []string{"acme", "--max-vms", "1"},
+			expectedError: "Error setting tenant quota: permission denied ❌",
+			expectError:   true,
+		},
+		{
+			name:          "missing tenant argument",
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			setupCLI:      func(cli *CLI) {},
+			args:          []string{},
+			expectError:   true,
+			expectedError: "accepts 1 arg(s), received 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+			tt.setupCLI(mockCLI)
+
+			cmd := mockCLI.NewTenantQuotaCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			err := cmd.Execute()
+
+			if tt.expectError {
+				assert.Contains(t, buf.String(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				for _, output := range tt.expectedOutput {
+					assert.Contains(t, buf.String(), output)
+				}
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
 func TestFileReader(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -598,3 +919,69 @@ func TestTTLHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestCLI_NewDiagnosticsCmd(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(*mocks.ManagerServiceClient)
+		args           []string
+		expectedOutput string
+		expectedError  string
+		expectError    bool
+	}{
+		{
+			name: "successful diagnostics retrieval",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Diagnostics", mock.Anything, &manager.DiagnosticsReq{CvmId: "vm-123"}).Return(&manager.DiagnosticsRes{
+					Diagnostics: &manager.Diagnostics{CvmId: "vm-123", Snapshot: []byte(`{"state":"Running"}`)},
+				}, nil)
+			},
+			args:           []string{"vm-123"},
+			expectedOutput: "{\n  \"state\": \"Running\"\n}",
+		},
+		{
+			name: "no diagnostic snapshot",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Diagnostics", mock.Anything, &manager.DiagnosticsReq{CvmId: "vm-456"}).Return(nil, errors.New("no diagnostic snapshot for the CVM"))
+			},
+			args:          []string{"vm-456"},
+			expectedError: "Error fetching diagnostics: no diagnostic snapshot for the CVM ❌",
+			expectError:   true,
+		},
+		{
+			name:          "missing CVM argument",
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			expectedError: "accepts 1 arg(s), received 0",
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+
+			cmd := mockCLI.NewDiagnosticsCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			err := cmd.Execute()
+
+			if tt.expectError {
+				assert.Contains(t, buf.String(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, buf.String(), tt.expectedOutput)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// OutputFormat is the format commands print their results in, set by the
+// global --output flag.
+var OutputFormat = OutputTable
+
+var errInvalidOutputFormat = errors.New("output must be json, table or yaml")
+
+// ValidateOutputFormat checks that the format is one the commands print in.
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputTable, OutputJSON, OutputYAML:
+		return nil
+	default:
+		return errInvalidOutputFormat
+	}
+}
+
+// printOutput writes v to the standard output of the command in the JSON or
+// YAML output format, or renders it with table in the table format. Protobuf
+// messages are marshalled with their proto field names.
+func printOutput(cmd *cobra.Command, v any, table func(w io.Writer)) error {
+	return writeOutput(cmd.OutOrStdout(), v, table)
+}
+
+// writeOutput writes v to out as printOutput does.
+func writeOutput(out io.Writer, v any, table func(w io.Writer)) error {
+	if err := ValidateOutputFormat(OutputFormat); err != nil {
+		return err
+	}
+
+	if OutputFormat == OutputTable {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+
+	data, err := marshalOutput(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	return err
+}
+
+// writeRecord writes v as a record of a stream of results, a line of JSON in
+// the JSON output format or a YAML document in the YAML one.
+func writeRecord(w io.Writer, v any) error {
+	var data []byte
+	var err error
+	if OutputFormat == OutputYAML {
+		data, err = marshalOutput(v)
+		data = append([]byte("---\n"), data...)
+	} else {
+		data, err = json.Marshal(v)
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// marshalOutput returns v in the JSON or YAML output format, ending with a newline.
+func marshalOutput(v any) ([]byte, error) {
+	var data []byte
+	var err error
+	if msg, ok := v.(proto.Message); ok {
+		data, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if OutputFormat == OutputYAML {
+		// The value goes through JSON so the YAML output has the same field names.
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(yamlNumbers(value))
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+
+	return out.Bytes(), nil
+}
+
+// yamlNumbers replaces the JSON numbers of a decoded value with integers or
+// floats, which YAML would otherwise print as quoted strings.
+func yamlNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = yamlNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = yamlNumbers(e)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/manager"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, format := range []string{OutputTable, OutputJSON, OutputYAML} {
+		assert.NoError(t, ValidateOutputFormat(format))
+	}
+	assert.ErrorIs(t, ValidateOutputFormat("xml"), errInvalidOutputFormat)
+}
+
+func TestPrintOutput(t *testing.T) {
+	defer func() { OutputFormat = OutputTable }()
+
+	quota := &manager.SetTenantQuotaRes{
+		Tenant: "acme",
+		Quota:  &manager.TenantQuota{MaxVms: 2, MaxMemoryMb: 16384},
+	}
+	table := func(w io.Writer) {
+		fmt.Fprintln(w, "TENANT\tVMS")
+		fmt.Fprintf(w, "%s\t%d\n", quota.Tenant, quota.Quota.MaxVms)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		value    any
+		expected string
+		err      error
+	}{
+		{
+			name:     "table",
+			format:   OutputTable,
+			value:    quota,
+			expected: "TENANT  VMS\nacme    2\n",
+		},
+		{
+			name:     "protobuf message as JSON",
+			format:   OutputJSON,
+			value:    quota,
+			expected: "{\n  \"tenant\": \"acme\",\n  \"quota\": {\n    \"max_vms\": 2,\n    \"max_memory_mb\": \"16384\"\n  }\n}\n",
+		},
+		{
+			name:     "protobuf message as YAML",
+			format:   OutputYAML,
+			value:    quota,
+			expected: "quota:\n    max_memory_mb: \"16384\"\n    max_vms: 2\ntenant: acme\n",
+		},
+		{
+			name:     "struct as JSON",
+			format:   OutputJSON,
+			value:    []fileVerification{{Path: "result.csv", Status: fileVerified}},
+			expected: "[\n  {\n    \"path\": \"result.csv\",\n    \"status\": \"verified\"\n  }\n]\n",
+		},
+		{
+			name:     "struct as YAML",
+			format:   OutputYAML,
+			value:    resultVerification{ComputationID: "c1", Signature: fileVerified, Files: []fileVerification{}, Verified: true},
+			expected: "computation_id: c1\nfiles: []\nsignature: verified\nverified: true\n",
+		},
+		{
+			name:     "floats as YAML",
+			format:   OutputYAML,
+			value:    map[string]float64{"accuracy": 0.93, "epochs": 10},
+			expected: "accuracy: 0.93\nepochs: 10\n",
+		},
+		{
+			name:   "invalid format",
+			format: "xml",
+			value:  quota,
+			err:    errInvalidOutputFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			OutputFormat = tt.format
+
+			var buf bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&buf)
+
+			err := printOutput(cmd, tt.value, table)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"encoding/pem"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func (cli *CLI) NewRestoreCmd() *cobra.Command {
+	var sequence uint64
+
+	cmd := &cobra.Command{
+		Use:     "restore <private_key_file_path>",
+		Short:   "Resume the computation from its last checkpoint as its algorithm provider",
+		Example: "restore <private_key_file_path> --sequence 12",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.connectErr != nil {
+				printError(cmd, "Failed to connect to agent: %v ❌ ", cli.connectErr)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			if err := cli.agentSDK.Restore(cmd.Context(), sequence, privKey); err != nil {
+				printError(cmd, "Error restoring computation: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Computation restored successfully! ✔"))
+		},
+	}
+
+	cmd.Flags().Uint64Var(&sequence, "sequence", 0, "Sequence of the last CheckpointSaved event, older checkpoints are refused")
+
+	return cmd
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+)
+
+func TestRestoreCmd(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(keyFile))
+
+	cases := []struct {
+		desc       string
+		args       []string
+		sequence   uint64
+		restoreErr error
+		connectErr error
+		output     string
+	}{
+		{
+			desc:   "restore computation",
+			args:   []string{keyFile},
+			output: "Computation restored successfully",
+		},
+		{
+			desc:     "restore computation from a sequence",
+			args:     []string{keyFile, "--sequence", "12"},
+			sequence: 12,
+			output:   "Computation restored successfully",
+		},
+		{
+			desc:   "missing private key file",
+			args:   []string{filepath.Join(dir, "missing.pem")},
+			output: "Error reading private key file",
+		},
+		{
+			desc:       "restore failure",
+			args:       []string{keyFile},
+			restoreErr: errors.New("computation checkpoint is older than the requested sequence"),
+			output:     "computation checkpoint is older than the requested sequence",
+		},
+		{
+			desc:       "connection error",
+			args:       []string{keyFile},
+			connectErr: errors.New("failed to connect to agent"),
+			output:     "Failed to connect to agent",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockSDK := new(mocks.SDK)
+			mockSDK.On("Restore", mock.Anything, tc.sequence, mock.Anything).Return(tc.restoreErr)
+
+			testCLI := CLI{agentSDK: mockSDK, connectErr: tc.connectErr}
+
+			cmd := testCLI.NewRestoreCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(tc.args)
+			require.NoError(t, cmd.Execute())
+
+			assert.Contains(t, buf.String(), tc.output)
+		})
+	}
+}
@@ -3,15 +3,70 @@
 package cli
 
 import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/atls"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
 )
 
-const resultFilename = "results.zip"
+const (
+	resultFilename          = "results.zip"
+	decryptedResultFilename = "results_decrypted.zip"
+
+	fileVerified   = "verified"
+	fileModified   = "modified"
+	fileMissing    = "missing"
+	fileUnexpected = "unexpected"
+)
+
+var (
+	errInvalidAgentCert   = errors.New("agent certificate file does not contain a PEM encoded certificate")
+	errNoResultSigningKey = errors.New("result manifest has no signing key, set the agent certificate it is signed with")
+	errResultNotAttested  = errors.New("result manifest has no attestation report")
+)
+
+// resultVerifier returns the verifier of attestation reports of result manifests.
+var resultVerifier = atls.PlatformVerifier
+
+// fileVerification is the verification status of a single result file.
+type fileVerification struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// resultVerification is the report of the result verification in the JSON
+// and YAML output formats. Attestation is empty when the manifest is signed
+// with the attested agent certificate key.
+type resultVerification struct {
+	ComputationID string             `json:"computation_id"`
+	Attestation   string             `json:"attestation,omitempty"`
+	Signature     string             `json:"signature"`
+	Files         []fileVerification `json:"files"`
+	Verified      bool               `json:"verified"`
+}
+
+// verificationStatus returns the status of a verification that failed with err.
+func verificationStatus(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return fileVerified
+}
 
 func (cli *CLI) NewResultsCmd() *cobra.Command {
 	var outputDir string
@@ -80,5 +135,263 @@ func (cli *CLI) NewResultsCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Directory where the result file will be saved")
 	cmd.Flags().StringVarP(&filename, "filename", "f", resultFilename, "Name of the result file")
 
+	cmd.AddCommand(cli.newDecryptResultCmd())
+	cmd.AddCommand(cli.newVerifyResultCmd())
+
 	return cmd
 }
+
+func (cli *CLI) newDecryptResultCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:     "decrypt <encrypted_result_file> <x25519_private_key_file_path>",
+		Short:   "Decrypt a computation result encrypted for a result consumer",
+		Example: "result decrypt results.zip private.pem --out-file results_decrypted.zip",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ciphertext, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading result file: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			privKey, err := encryption.ParsePrivateKey(privKeyFile)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			plaintext, err := encryption.Decrypt(privKey, ciphertext)
+			if err != nil {
+				printError(cmd, "Error decrypting result: %v ❌ ", err)
+				return
+			}
+
+			if err := os.WriteFile(output, plaintext, 0o644); err != nil {
+				printError(cmd, "Error writing decrypted result: %v ❌ ", err)
+				return
+			}
+
+			absPath, err := filepath.Abs(output)
+			if err != nil {
+				absPath = output
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Computation result decrypted successfully! ✔"))
+			cmd.Println(color.New(color.FgCyan).Sprintf("📁 Location: %s", absPath))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "out-file", "o", decryptedResultFilename, "Path of the decrypted result file")
+
+	return cmd
+}
+
+func (cli *CLI) newVerifyResultCmd() *cobra.Command {
+	var manifestPath string
+	var agentCertPath string
+	var policyPath string
+
+	cmd := &cobra.Command{
+		Use:     "verify <result_archive>",
+		Short:   "Verify a downloaded computation result against its signed result manifest",
+		Example: "result verify results.zip --attestation-policy attestation_policy.json",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			archive, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading result archive: %v ❌ ", err)
+				return
+			}
+
+			manifest, err := readResultManifest(manifestPath, archive)
+			if err != nil {
+				printError(cmd, "Error reading result manifest: %v ❌ ", err)
+				return
+			}
+
+			files, err := agent.HashResultArchive(archive)
+			if err != nil {
+				printError(cmd, "Error hashing result archive: %v ❌ ", err)
+				return
+			}
+
+			// Manifests the agent embeds are signed with a key bound to the CVM by
+			// their attestation report, others with the attested agent certificate key.
+			var key crypto.PublicKey
+			var attErr error
+			switch {
+			case agentCertPath != "":
+				certFile, err := os.ReadFile(agentCertPath)
+				if err != nil {
+					printError(cmd, "Error reading agent certificate: %v ❌ ", err)
+					return
+				}
+
+				cert, err := parseCertificate(certFile)
+				if err != nil {
+					printError(cmd, "Error decoding agent certificate: %v ❌ ", err)
+					return
+				}
+				key = cert.PublicKey
+			case manifest.SigningKey != nil:
+				key, err = x509.ParsePKIXPublicKey(manifest.SigningKey)
+				if err != nil {
+					printError(cmd, "Error decoding result signing key: %v ❌ ", err)
+					return
+				}
+				if policyPath != "" {
+					attestation.AttestationPolicyPath = policyPath
+				}
+				attErr = verifyResultAttestation(manifest)
+			default:
+				printError(cmd, "Error: %v ❌ ", errNoResultSigningKey)
+				return
+			}
+
+			sigErr := agent.VerifyResultManifest(manifest, key)
+			report := verifyResultFiles(manifest, files)
+
+			failed := 0
+			for _, f := range report {
+				if f.Status != fileVerified {
+					failed++
+				}
+			}
+
+			verification := resultVerification{
+				ComputationID: manifest.ComputationID,
+				Signature:     verificationStatus(sigErr),
+				Files:         report,
+				Verified:      attErr == nil && sigErr == nil && failed == 0,
+			}
+			if agentCertPath == "" {
+				verification.Attestation = verificationStatus(attErr)
+			}
+
+			err = printOutput(cmd, verification, func(w io.Writer) {
+				cmd.Printf("Computation: %s\n", manifest.ComputationID)
+				if agentCertPath == "" {
+					if attErr != nil {
+						cmd.Println(color.New(color.FgRed).Sprintf("Attestation: %v ❌", attErr))
+					} else {
+						cmd.Println(color.New(color.FgGreen).Sprint("Attestation: valid ✔"))
+					}
+				}
+				if sigErr != nil {
+					cmd.Println(color.New(color.FgRed).Sprintf("Signature: %v ❌", sigErr))
+				} else {
+					cmd.Println(color.New(color.FgGreen).Sprint("Signature: valid ✔"))
+				}
+
+				fmt.Fprintln(w, "FILE\tSTATUS")
+				for _, f := range report {
+					fmt.Fprintf(w, "%s\t%s\n", f.Path, f.Status)
+				}
+			})
+			if err != nil {
+				printError(cmd, "Error printing verification report: %v ❌ ", err)
+				return
+			}
+
+			if attErr != nil || sigErr != nil || failed > 0 {
+				cmd.Println(color.New(color.FgRed).Sprintf("Result verification failed, %d of %d files do not match the manifest ❌", failed, len(report)))
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Result verified successfully! ✔"))
+		},
+	}
+
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Path of the signed result manifest, read from the archive if not set")
+	cmd.Flags().StringVar(&agentCertPath, "agent-cert", "", "Path of the PEM encoded attested agent certificate the manifest is signed with")
+	cmd.Flags().StringVar(&policyPath, "attestation-policy", "", "Path of the attestation policy the report of the signing key is verified with")
+
+	return cmd
+}
+
+// readResultManifest reads the result manifest at path or, if path is empty,
+// the manifest the agent added to the archive.
+func readResultManifest(path string, archive []byte) (agent.ResultManifest, error) {
+	if path == "" {
+		return agent.ReadResultManifest(archive)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return agent.ResultManifest{}, err
+	}
+
+	var manifest agent.ResultManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return agent.ResultManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// verifyResultAttestation verifies that the attestation report of the manifest
+// binds its signing key to the computation.
+func verifyResultAttestation(manifest agent.ResultManifest) error {
+	if manifest.Attestation == nil {
+		return errResultNotAttested
+	}
+
+	verifier, err := resultVerifier(manifest.Attestation.Platform)
+	if err != nil {
+		return err
+	}
+
+	reportData := agent.ResultReportData(manifest.SigningKey, manifest.ComputationID)
+
+	return verifier.VerifyAttestation(manifest.Attestation.Report, reportData[:], reportData[:vtpm.Nonce])
+}
+
+// verifyResultFiles compares the hashes of the archive files with the manifest,
+// reporting modified and missing manifest files and files the manifest does not list.
+func verifyResultFiles(manifest agent.ResultManifest, files map[string]string) []fileVerification {
+	report := make([]fileVerification, 0, len(manifest.Files))
+	listed := make(map[string]bool, len(manifest.Files))
+
+	for _, f := range manifest.Files {
+		listed[f.Path] = true
+
+		hash, ok := files[f.Path]
+		switch {
+		case !ok:
+			report = append(report, fileVerification{Path: f.Path, Status: fileMissing})
+		case hash != f.Hash:
+			report = append(report, fileVerification{Path: f.Path, Status: fileModified})
+		default:
+			report = append(report, fileVerification{Path: f.Path, Status: fileVerified})
+		}
+	}
+
+	for path := range files {
+		if !listed[path] {
+			report = append(report, fileVerification{Path: path, Status: fileUnexpected})
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Path < report[j].Path
+	})
+
+	return report
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errInvalidAgentCert
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
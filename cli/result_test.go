@@ -4,14 +4,31 @@
 package cli
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"math/big"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/atls"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	attestationmocks "github.com/ultravioletrs/cocos/pkg/attestation/mocks"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/encryption"
 	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
 )
 
@@ -175,3 +192,278 @@ func TestResultsCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestDecryptResultCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	privPath := filepath.Join(dir, "x25519.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: x25519KeyType, Bytes: privDer}), 0o600))
+
+	ciphertext, err := encryption.Encrypt(priv.PublicKey(), []byte(compResult))
+	require.NoError(t, err)
+	encPath := filepath.Join(dir, "results.zip")
+	require.NoError(t, os.WriteFile(encPath, ciphertext, 0o600))
+
+	rsaPath := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(rsaPath))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{
+			name:           "successful decryption",
+			args:           []string{encPath, privPath},
+			expectedOutput: "Computation result decrypted successfully",
+		},
+		{
+			name:           "missing result file",
+			args:           []string{filepath.Join(dir, "missing.zip"), privPath},
+			expectedOutput: "Error reading result file",
+		},
+		{
+			name:           "non x25519 private key",
+			args:           []string{encPath, rsaPath},
+			expectedOutput: "Error decoding private key",
+		},
+		{
+			name:           "result not encrypted",
+			args:           []string{privPath, privPath},
+			expectedOutput: "Error decrypting result",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), decryptedResultFilename)
+
+			cmd := (&CLI{}).NewResultsCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(append([]string{"decrypt", "--out-file", output}, tt.args...))
+			require.NoError(t, cmd.Execute())
+
+			require.Contains(t, buf.String(), tt.expectedOutput)
+		})
+	}
+
+	output := filepath.Join(dir, decryptedResultFilename)
+	cmd := (&CLI{}).NewResultsCmd()
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetArgs([]string{"decrypt", encPath, privPath, "-o", output})
+	require.NoError(t, cmd.Execute())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, compResult, string(data))
+}
+
+func writeResultArchive(t *testing.T, path string, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	return buf.Bytes()
+}
+
+func writeAgentCert(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+}
+
+func TestVerifyResultCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	agentKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, "agent.pem")
+	writeAgentCert(t, certPath, agentKey)
+	otherCertPath := filepath.Join(dir, "other.pem")
+	writeAgentCert(t, otherCertPath, otherKey)
+
+	files := map[string]string{"results/model.bin": "model", "results/metrics.csv": "accuracy,0.97"}
+	archivePath := filepath.Join(dir, "results.zip")
+	archive := writeResultArchive(t, archivePath, files)
+
+	manifest, err := agent.NewResultManifest("cmp1", archive)
+	require.NoError(t, err)
+	manifest.Signature, err = agent.SignResultManifest(manifest, agentKey)
+	require.NoError(t, err)
+	manifestData, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	manifestPath := filepath.Join(dir, "result_manifest.json")
+	require.NoError(t, os.WriteFile(manifestPath, manifestData, 0o600))
+
+	modifiedPath := filepath.Join(dir, "modified.zip")
+	writeResultArchive(t, modifiedPath, map[string]string{"results/model.bin": "tampered", "results/metrics.csv": "accuracy,0.97"})
+
+	extraPath := filepath.Join(dir, "extra.zip")
+	writeResultArchive(t, extraPath, map[string]string{"results/model.bin": "model", "results/extra.txt": "extra"})
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput []string
+	}{
+		{
+			name:           "verified result",
+			args:           []string{archivePath, "--manifest", manifestPath, "--agent-cert", certPath},
+			expectedOutput: []string{"Signature: valid", "results/model.bin", "Result verified successfully"},
+		},
+		{
+			name:           "modified file",
+			args:           []string{modifiedPath, "--manifest", manifestPath, "--agent-cert", certPath},
+			expectedOutput: []string{fileModified, "Result verification failed, 1 of 2 files"},
+		},
+		{
+			name:           "missing and unexpected files",
+			args:           []string{extraPath, "--manifest", manifestPath, "--agent-cert", certPath},
+			expectedOutput: []string{fileMissing, fileUnexpected, "Result verification failed, 2 of 3 files"},
+		},
+		{
+			name:           "signed by another key",
+			args:           []string{archivePath, "--manifest", manifestPath, "--agent-cert", otherCertPath},
+			expectedOutput: []string{agent.ErrResultManifestSignature.Error(), "Result verification failed"},
+		},
+		{
+			name:           "invalid agent certificate",
+			args:           []string{archivePath, "--manifest", manifestPath, "--agent-cert", manifestPath},
+			expectedOutput: []string{"Error decoding agent certificate"},
+		},
+		{
+			name:           "archive is not a zip",
+			args:           []string{manifestPath, "--manifest", manifestPath, "--agent-cert", certPath},
+			expectedOutput: []string{"Error hashing result archive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := (&CLI{}).NewResultsCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(append([]string{"verify"}, tt.args...))
+			require.NoError(t, cmd.Execute())
+
+			for _, expected := range tt.expectedOutput {
+				require.Contains(t, buf.String(), expected)
+			}
+		})
+	}
+}
+
+func TestVerifyEmbeddedResultManifest(t *testing.T) {
+	t.Cleanup(func() { resultVerifier = atls.PlatformVerifier })
+
+	dir := t.TempDir()
+	files := map[string]string{"results/model.bin": "model"}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signingKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	// signedArchive writes an archive with a manifest embedded the way the agent does.
+	signedArchive := func(name string, edit func(*agent.ResultManifest)) string {
+		manifest, err := agent.NewResultManifest("cmp1", writeResultArchive(t, filepath.Join(dir, name), files))
+		require.NoError(t, err)
+		manifest.SigningKey = signingKey
+		manifest.Attestation = &agent.ResultAttestation{Platform: attestation.SNPvTPM, Report: []byte("report")}
+		edit(&manifest)
+		manifest.Signature, err = agent.SignResultManifest(manifest, key)
+		require.NoError(t, err)
+		data, err := json.Marshal(manifest)
+		require.NoError(t, err)
+
+		path := filepath.Join(dir, name)
+		writeResultArchive(t, path, map[string]string{"results/model.bin": "model", agent.ResultManifestFile: string(data)})
+
+		return path
+	}
+
+	attested := signedArchive("attested.zip", func(m *agent.ResultManifest) {})
+	unattested := signedArchive("unattested.zip", func(m *agent.ResultManifest) { m.Attestation = nil })
+	unsigned := signedArchive("unsigned.zip", func(m *agent.ResultManifest) { m.SigningKey = nil })
+	noManifest := filepath.Join(dir, "plain.zip")
+	writeResultArchive(t, noManifest, files)
+
+	reportData := agent.ResultReportData(signingKey, "cmp1")
+	errReport := errors.New("report does not match the policy")
+
+	tests := []struct {
+		name           string
+		archive        string
+		verifyErr      error
+		expectedOutput []string
+	}{
+		{
+			name:           "attested result",
+			archive:        attested,
+			expectedOutput: []string{"Attestation: valid", "Signature: valid", "Result verified successfully"},
+		},
+		{
+			name:           "report rejected",
+			archive:        attested,
+			verifyErr:      errReport,
+			expectedOutput: []string{errReport.Error(), "Signature: valid", "Result verification failed"},
+		},
+		{
+			name:           "manifest without attestation",
+			archive:        unattested,
+			expectedOutput: []string{errResultNotAttested.Error(), "Result verification failed"},
+		},
+		{
+			name:           "manifest without signing key",
+			archive:        unsigned,
+			expectedOutput: []string{errNoResultSigningKey.Error()},
+		},
+		{
+			name:           "archive without manifest",
+			archive:        noManifest,
+			expectedOutput: []string{"Error reading result manifest", agent.ErrResultManifestNotFound.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := new(attestationmocks.Verifier)
+			verifier.On("VerifyAttestation", []byte("report"), reportData[:], reportData[:vtpm.Nonce]).Return(tt.verifyErr)
+			resultVerifier = func(platform attestation.PlatformType) (attestation.Verifier, error) {
+				require.Equal(t, attestation.SNPvTPM, platform)
+				return verifier, nil
+			}
+
+			cmd := (&CLI{}).NewResultsCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs([]string{"verify", tt.archive})
+			require.NoError(t, cmd.Execute())
+
+			for _, expected := range tt.expectedOutput {
+				require.Contains(t, buf.String(), expected)
+			}
+		})
+	}
+}
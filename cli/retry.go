@@ -0,0 +1,87 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxBackoff = 10 * time.Second
+
+// MaxRetries is the number of times a small agent or manager RPC is retried after a transient failure.
+var MaxRetries int
+
+// retryBackoff is the base delay between attempts, it is a variable so tests can shorten it.
+var retryBackoff = 500 * time.Millisecond
+
+// withRetry calls fn until it succeeds, fails with an error that is not transient,
+// or MaxRetries retries are exhausted. Retries wait for an exponential backoff with jitter.
+func withRetry(cmd *cobra.Command, fn func() error) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !retryableError(err) || attempt >= MaxRetries {
+			return err
+		}
+
+		delay := backoff(attempt)
+		if Verbose {
+			cmd.Printf("Request failed: %v\n", err)
+		}
+		cmd.Printf("Connection interrupted, retrying in %s (%d/%d)\n", delay.Round(time.Millisecond), attempt+1, MaxRetries)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay before the given retry attempt. The delay doubles
+// with every attempt up to maxBackoff, and a random half of it is jitter so
+// that clients dropped together do not reconnect together.
+func backoff(attempt int) time.Duration {
+	if retryBackoff <= 0 {
+		return 0
+	}
+
+	delay := maxBackoff
+	if attempt < 16 {
+		delay = min(retryBackoff<<attempt, maxBackoff)
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryableError reports whether err is a transient connection failure.
+func retryableError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// resetFile truncates a partially written output file so a retried request starts from scratch.
+func resetFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := f.Seek(0, 0)
+
+	return err
+}
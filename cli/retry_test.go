@@ -0,0 +1,119 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithRetry(t *testing.T) {
+	defer func(base time.Duration) { retryBackoff, MaxRetries = base, 0 }(retryBackoff)
+	retryBackoff = 0
+
+	unavailable := status.Error(codes.Unavailable, "connection reset by peer")
+	permanent := status.Error(codes.PermissionDenied, "denied")
+
+	cases := []struct {
+		desc       string
+		maxRetries int
+		errs       []error
+		calls      int
+		err        error
+	}{
+		{
+			desc:       "success on first attempt",
+			maxRetries: 3,
+			errs:       []error{nil},
+			calls:      1,
+		},
+		{
+			desc:       "success after transient failures",
+			maxRetries: 3,
+			errs:       []error{unavailable, status.Error(codes.DeadlineExceeded, "timeout"), nil},
+			calls:      3,
+		},
+		{
+			desc:       "retries exhausted",
+			maxRetries: 2,
+			errs:       []error{unavailable, unavailable, unavailable, nil},
+			calls:      3,
+			err:        unavailable,
+		},
+		{
+			desc:       "permanent failure is not retried",
+			maxRetries: 3,
+			errs:       []error{permanent, nil},
+			calls:      1,
+			err:        permanent,
+		},
+		{
+			desc:       "retries disabled",
+			maxRetries: 0,
+			errs:       []error{unavailable, nil},
+			calls:      1,
+			err:        unavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			MaxRetries = tc.maxRetries
+			cmd := &cobra.Command{}
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+
+			calls := 0
+			err := withRetry(cmd, func() error {
+				err := tc.errs[calls]
+				calls++
+				return err
+			})
+
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.calls, calls)
+			assert.Equal(t, tc.calls-1, bytes.Count(buf.Bytes(), []byte("retrying")))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer func(base time.Duration) { retryBackoff = base }(retryBackoff)
+	retryBackoff = 100 * time.Millisecond
+
+	for attempt := 0; attempt < 20; attempt++ {
+		delay := min(retryBackoff<<min(attempt, 16), maxBackoff)
+		got := backoff(attempt)
+		assert.GreaterOrEqual(t, got, delay/2)
+		assert.LessOrEqual(t, got, delay)
+	}
+
+	retryBackoff = 0
+	assert.Zero(t, backoff(3))
+}
+
+func TestResetFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "attestation.bin"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString("partial report")
+	require.NoError(t, err)
+
+	require.NoError(t, resetFile(f))
+	_, err = f.WriteString("report")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "report", string(data))
+}
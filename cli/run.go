@@ -0,0 +1,473 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	cvmsgrpc "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
+	"github.com/ultravioletrs/cocos/internal"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/pkg/sdk"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+var errRemoveVM = errors.New("error removing virtual machine")
+
+// computationRun runs the computation of a manifest on a new virtual machine
+// of the manager, serving the manifest to the agent as the computation
+// management server, and retrieves its result.
+type computationRun struct {
+	manager   manager.ManagerServiceClient
+	connect   agentConnector
+	serverURL string
+	agentHost string
+	timeout   time.Duration
+	keepVM    bool
+
+	computation  *cvms.ComputationRunReq
+	privKey      any
+	algorithm    string
+	requirements string
+	spec         *agent.AlgorithmSpec
+	datasets     []string
+	resultPath   string
+
+	booted   chan error
+	finished chan error
+}
+
+func (c *CLI) NewRunCmd() *cobra.Command {
+	var (
+		managerURL string
+		serverAddr string
+		listenAddr string
+		agentHost  string
+		timeout    time.Duration
+		keepVM     bool
+		outputDir  string
+		filename   string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "run <computation_manifest_file_path> <private_key_file_path> <algorithm_file_path> [dataset_path...]",
+		Short:   "Run a computation on a new virtual machine, from uploading its algorithm and datasets to retrieving its result",
+		Example: "run manifest.json private.pem algo.py data.csv --manager localhost:7001 --server-url 10.0.2.2:7005 -a python",
+		Args:    cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifestFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading manifest file: %v ❌ ", err)
+				return
+			}
+
+			var cmp agent.Computation
+			if err := json.Unmarshal(manifestFile, &cmp); err != nil {
+				printError(cmd, "Error decoding manifest: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			spec, err := algorithmSpec()
+			if err != nil {
+				printError(cmd, "Error in algorithm spec: %v ❌ ", err)
+				return
+			}
+
+			resultPath := filename
+			if outputDir != "" {
+				if err := os.MkdirAll(outputDir, 0o755); err != nil {
+					printError(cmd, "Error creating output directory: %v ❌ ", err)
+					return
+				}
+				resultPath = filepath.Join(outputDir, filename)
+			}
+
+			c.managerConfig.URL = managerURL
+			if err := c.InitializeManagerClient(cmd); err != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+				return
+			}
+			defer c.Close()
+
+			if listenAddr == "" {
+				_, port, err := net.SplitHostPort(serverAddr)
+				if err != nil {
+					printError(cmd, "Invalid computation server URL: %v ❌ ", err)
+					return
+				}
+				listenAddr = net.JoinHostPort("", port)
+			}
+
+			if agentHost == "" {
+				host, _, err := net.SplitHostPort(managerURL)
+				if err != nil {
+					printError(cmd, "Invalid manager URL: %v ❌ ", err)
+					return
+				}
+				agentHost = host
+			}
+
+			lis, err := net.Listen("tcp", listenAddr)
+			if err != nil {
+				printError(cmd, "Error starting computation server: %v ❌ ", err)
+				return
+			}
+
+			r := &computationRun{
+				manager:      c.managerClient,
+				connect:      c.connectAttestedAgent,
+				serverURL:    serverAddr,
+				agentHost:    agentHost,
+				timeout:      timeout,
+				keepVM:       keepVM,
+				computation:  computationRunReq(cmp),
+				privKey:      privKey,
+				algorithm:    args[2],
+				requirements: requirementsFile,
+				spec:         spec,
+				datasets:     args[3:],
+				resultPath:   resultPath,
+				booted:       make(chan error, 1),
+				finished:     make(chan error, 1),
+			}
+
+			cmd.Printf("🔗 Running computation %s\n", cmp.ID)
+
+			if err := r.run(cmd, lis); err != nil {
+				printError(cmd, "Computation run failed: %v ❌ ", err)
+				return
+			}
+
+			absPath, err := filepath.Abs(resultPath)
+			if err != nil {
+				absPath = resultPath
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Computation result retrieved and saved successfully! ✔"))
+			cmd.Println(color.New(color.FgCyan).Sprintf("📁 Location: %s", absPath))
+		},
+	}
+
+	cmd.Flags().StringVar(&managerURL, "manager", "", "Address of the manager the virtual machine is created on")
+	cmd.Flags().StringVar(&serverAddr, serverURL, "", "Address the agent reaches the computation server at")
+	cmd.Flags().StringVar(&listenAddr, "listen", "", "Address the computation server listens on, the port of the server URL if empty")
+	cmd.Flags().StringVar(&agentHost, "agent-host", "", "Host the forwarded agent port is reached at, the manager host if empty")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "Time the whole run may take")
+	cmd.Flags().BoolVar(&keepVM, "keep-vm", false, "Keep the virtual machine once the run is done")
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Directory where the result file will be saved")
+	cmd.Flags().StringVarP(&filename, "filename", "f", resultFilename, "Name of the result file")
+	cmd.Flags().StringVarP(&algoType, "algorithm", "a", string(algorithm.AlgoTypeBin), "Algorithm type to run")
+	cmd.Flags().StringVar(&pythonRuntime, "python-runtime", "", "Python runtime to use, python3 if not set")
+	cmd.Flags().StringVar(&minRuntime, "min-runtime-version", "", "Lowest python runtime version the algorithm supports")
+	cmd.Flags().StringVar(&algoEntrypoint, "entrypoint", "", "Exported function of a wasm module or command of a docker image to run")
+	cmd.Flags().StringVarP(&requirementsFile, "requirements", "r", "", "Python requirements file")
+	cmd.Flags().StringArrayVar(&algoArgs, "args", []string{}, "Arguments to pass to the algorithm")
+	cmd.Flags().BoolVarP(&decompressDataset, "decompress", "d", false, "Decompress the datasets on agent")
+	_ = cmd.MarkFlagRequired("manager")
+	_ = cmd.MarkFlagRequired(serverURL)
+
+	return cmd
+}
+
+// run creates the virtual machine, uploads the algorithm and datasets to its
+// agent once the attestation is verified, waits for the computation to finish
+// and retrieves its result. The virtual machine is removed unless it is kept.
+func (r *computationRun) run(cmd *cobra.Command, lis net.Listener) (err error) {
+	ctx, cancel := context.WithTimeout(cmd.Context(), r.timeout)
+	defer cancel()
+
+	// The agent blocks until its messages are read, they are read until the CLI exits.
+	incoming := make(chan *cvms.ClientStreamMessage)
+	go watchAgent(incoming, r.booted, r.finished)
+
+	srv := googlegrpc.NewServer()
+	cvms.RegisterServiceServer(srv, cvmsgrpc.NewServer(incoming, r))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	cmd.Println("⏳ Creating virtual machine")
+
+	res, err := r.manager.CreateVm(ctx, &manager.CreateReq{AgentCvmServerUrl: r.serverURL, Ttl: r.timeout.String()})
+	if err != nil {
+		return fmt.Errorf("error creating virtual machine: %w", err)
+	}
+
+	if r.keepVM {
+		defer cmd.Printf("🖥️ Virtual machine %s is kept\n", res.CvmId)
+	} else {
+		defer func() {
+			// The virtual machine is removed even when the run timed out.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), vmRemoveTime)
+			defer cancel()
+
+			cmd.Printf("⏳ Removing virtual machine %s\n", res.CvmId)
+			if _, rerr := r.manager.RemoveVm(ctx, &manager.RemoveReq{CvmId: res.CvmId}); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("%w %s: %w", errRemoveVM, res.CvmId, rerr))
+			}
+		}()
+	}
+
+	cmd.Printf("⏳ Waiting for virtual machine %s to receive the computation\n", res.CvmId)
+
+	if err := wait(ctx, r.booted); err != nil {
+		return fmt.Errorf("virtual machine %s: %w", res.CvmId, err)
+	}
+
+	url := net.JoinHostPort(r.agentHost, res.ForwardedPort)
+	cmd.Printf("⏳ Verifying attestation of agent at %s\n", url)
+
+	agentSDK, conn, err := r.connect(cmd, url)
+	if err != nil {
+		return fmt.Errorf("error connecting to agent: %w", err)
+	}
+	if conn != nil {
+		defer conn.Close()
+	}
+
+	if err := r.upload(cmd, ctx, agentSDK); err != nil {
+		return err
+	}
+
+	cmd.Println("⏳ Waiting for the computation to finish")
+
+	if err := wait(ctx, r.finished); err != nil {
+		return err
+	}
+
+	cmd.Println("⏳ Retrieving computation result file")
+
+	resultFile, err := os.Create(r.resultPath)
+	if err != nil {
+		return fmt.Errorf("error creating result file: %w", err)
+	}
+	defer resultFile.Close()
+
+	if err := agentSDK.Result(ctx, r.privKey, resultFile); err != nil {
+		return fmt.Errorf("error retrieving computation result: %w", err)
+	}
+
+	return nil
+}
+
+// Run serves the computation to the agent connecting to the server.
+func (r *computationRun) Run(ctx context.Context, ipAddress string, sendMessage cvmsgrpc.SendFunc, authInfo credentials.AuthInfo) {
+	if err := sendMessage(&cvms.ServerStreamMessage{
+		Message: &cvms.ServerStreamMessage_RunReq{RunReq: r.computation},
+	}); err != nil {
+		notify(r.booted, fmt.Errorf("error sending computation to agent at %s: %w", ipAddress, err))
+	}
+}
+
+func (r *computationRun) upload(cmd *cobra.Command, ctx context.Context, agentSDK sdk.SDK) error {
+	cmd.Println("Uploading algorithm file:", r.algorithm)
+
+	algo, err := os.Open(r.algorithm)
+	if err != nil {
+		return fmt.Errorf("error reading algorithm file: %w", err)
+	}
+	defer algo.Close()
+
+	var req *os.File
+	if r.requirements != "" {
+		if req, err = os.Open(r.requirements); err != nil {
+			return fmt.Errorf("error reading requirements file: %w", err)
+		}
+		defer req.Close()
+	}
+
+	if err := agentSDK.Algo(ctx, algo, req, r.spec, r.privKey); err != nil {
+		return fmt.Errorf("error uploading algorithm: %w", err)
+	}
+
+	for _, datasetPath := range r.datasets {
+		cmd.Println("Uploading dataset:", datasetPath)
+
+		if err := uploadDataset(ctx, agentSDK, datasetPath, r.privKey); err != nil {
+			return fmt.Errorf("error uploading dataset %s: %w", datasetPath, err)
+		}
+	}
+
+	return nil
+}
+
+// uploadDataset uploads the dataset file, directories are uploaded zipped.
+func uploadDataset(ctx context.Context, agentSDK sdk.SDK, datasetPath string, privKey any) error {
+	f, err := os.Stat(datasetPath)
+	if err != nil {
+		return err
+	}
+
+	var dataset *os.File
+	if f.IsDir() {
+		if dataset, err = internal.ZipDirectoryToTempFile(datasetPath); err != nil {
+			return err
+		}
+		defer os.Remove(dataset.Name())
+	} else if dataset, err = os.Open(datasetPath); err != nil {
+		return err
+	}
+	defer dataset.Close()
+
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(make(map[string]string)))
+
+	return agentSDK.Data(addDatasetMetadata(ctx), dataset, path.Base(datasetPath), privKey)
+}
+
+// computationRunReq returns the manifest the computation management server
+// sends for the computation, the agent is reached over attested TLS.
+func computationRunReq(cmp agent.Computation) *cvms.ComputationRunReq {
+	req := &cvms.ComputationRunReq{
+		Id:            cmp.ID,
+		Name:          cmp.Name,
+		Description:   cmp.Description,
+		Signature:     cmp.Signature,
+		ResultCodec:   cmp.ResultCodec,
+		Version:       cmp.Version,
+		Ttl:           cmp.TTL,
+		MaxRuntime:    cmp.MaxRuntime,
+		DatasetNaming: cmp.DatasetNaming,
+		AgentConfig:   &cvms.AgentConfig{Port: forwardedAgentPort, AttestedTls: true},
+	}
+
+	if enc := cmp.EventEncryption; enc != nil {
+		req.EventEncryption = &cvms.EventEncryption{
+			Key:          enc.Key,
+			Fields:       enc.Fields,
+			StreamOutput: enc.StreamOutput,
+		}
+	}
+
+	if approval := cmp.AttestationApproval; approval != nil {
+		req.AttestationApproval = &cvms.AttestationApproval{Key: approval.Key}
+	}
+
+	if st := cmp.Storage; st != nil {
+		req.Storage = &cvms.Storage{
+			Type:   st.Type,
+			SizeMb: st.SizeMB,
+		}
+	}
+
+	if rs := cmp.ResultSink; rs != nil {
+		req.ResultSink = &cvms.ResultSink{
+			Type:            rs.Type,
+			Endpoint:        rs.Endpoint,
+			Region:          rs.Region,
+			Bucket:          rs.Bucket,
+			Key:             rs.Key,
+			EncryptionKey:   rs.EncryptionKey,
+			AccessKeySecret: rs.AccessKeySecret,
+			SecretKeySecret: rs.SecretKeySecret,
+		}
+	}
+
+	if cp := cmp.Checkpoint; cp != nil {
+		req.Checkpoint = &cvms.Checkpoint{
+			Interval:  cp.Interval,
+			KeySecret: cp.KeySecret,
+		}
+	}
+
+	algo := cmp.Algorithm
+	req.Algorithm = &cvms.Algorithm{
+		Hash:    algo.Hash[:],
+		UserKey: algo.UserKey,
+		Args:    algo.Args,
+		Env:     algo.Env,
+		Type:    string(algo.Type),
+	}
+
+	for _, step := range algo.Steps {
+		req.Algorithm.Steps = append(req.Algorithm.Steps, &cvms.Step{
+			Name:     step.Name,
+			Args:     step.Args,
+			Datasets: step.Datasets,
+		})
+	}
+
+	if limits := algo.WasmLimits; limits != nil {
+		req.Algorithm.WasmLimits = &cvms.WasmLimits{
+			MaxMemoryMb:    limits.MaxMemoryMB,
+			TimeoutSeconds: limits.TimeoutSeconds,
+		}
+	}
+
+	if wd := algo.Watchdog; wd != nil {
+		req.Algorithm.Watchdog = &cvms.Watchdog{
+			IdleSeconds: wd.IdleSeconds,
+			Kill:        wd.Kill,
+		}
+	}
+
+	if res := algo.Resources; res != nil {
+		req.Algorithm.Resources = &cvms.Resources{
+			Cpus:     res.CPUs,
+			MemoryMb: res.MemoryMB,
+			DiskMb:   res.DiskMB,
+		}
+	}
+
+	if bundle := algo.Bundle; bundle != nil {
+		req.Algorithm.Bundle = &cvms.AlgorithmBundle{
+			Entrypoint: bundle.Entrypoint,
+			MaxSizeMb:  bundle.MaxSizeMB,
+			MaxFiles:   bundle.MaxFiles,
+		}
+	}
+
+	for _, ds := range cmp.Datasets {
+		dataset := &cvms.Dataset{
+			Hash:     ds.Hash[:],
+			UserKey:  ds.UserKey,
+			Filename: ds.Filename,
+		}
+		if archive := ds.Archive; archive != nil {
+			dataset.Archive = &cvms.DatasetArchive{
+				MaxSizeMb: archive.MaxSizeMB,
+				MaxFiles:  archive.MaxFiles,
+			}
+		}
+		req.Datasets = append(req.Datasets, dataset)
+	}
+
+	for _, rc := range cmp.ResultConsumers {
+		req.ResultConsumers = append(req.ResultConsumers, &cvms.ResultConsumer{
+			UserKey:       rc.UserKey,
+			EncryptionKey: rc.EncryptionKey,
+		})
+	}
+
+	return req
+}
@@ -0,0 +1,183 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"github.com/ultravioletrs/cocos/pkg/clients/grpc"
+	"github.com/ultravioletrs/cocos/pkg/sdk"
+	sdkmocks "github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestComputationRun(t *testing.T) {
+	connectErr := errors.New("attestation verification failed")
+
+	cases := []struct {
+		name       string
+		keepVM     bool
+		connectErr error
+		runEvent   *cvms.ClientStreamMessage
+		removed    bool
+		err        error
+	}{
+		{
+			name:     "computation runs",
+			runEvent: agentEvent(events.RunFinished, agent.Ready.String(), ""),
+			removed:  true,
+		},
+		{
+			name:     "virtual machine is kept",
+			keepVM:   true,
+			runEvent: agentEvent(events.RunFinished, agent.Ready.String(), ""),
+		},
+		{
+			name:       "attestation fails",
+			connectErr: connectErr,
+			removed:    true,
+			err:        connectErr,
+		},
+		{
+			name:     "computation fails",
+			runEvent: agentEvent(events.Error, agent.Failed.String(), `{"error":"exit status 1"}`),
+			removed:  true,
+			err:      errComputationFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			algoPath := filepath.Join(dir, "algo.sh")
+			require.NoError(t, os.WriteFile(algoPath, selftestAlgorithm, 0o600))
+			dataPath := filepath.Join(dir, "data.csv")
+			require.NoError(t, os.WriteFile(dataPath, selftestDataset, 0o600))
+			resultPath := filepath.Join(dir, resultFilename)
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			var send func(*cvms.ClientStreamMessage)
+
+			mc := new(mocks.ManagerServiceClient)
+			mc.On("CreateVm", mock.Anything, mock.MatchedBy(func(req *manager.CreateReq) bool {
+				return req.AgentCvmServerUrl == lis.Addr().String()
+			})).Run(func(args mock.Arguments) {
+				send = fakeAgent(t, lis.Addr().String())
+			}).Return(&manager.CreateRes{CvmId: "vm-1", ForwardedPort: "7020"}, nil)
+			if tc.removed {
+				mc.On("RemoveVm", mock.Anything, &manager.RemoveReq{CvmId: "vm-1"}).Return(&emptypb.Empty{}, nil)
+			}
+
+			agentSDK := new(sdkmocks.SDK)
+			agentSDK.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			agentSDK.On("Data", mock.Anything, mock.Anything, "data.csv", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				send(tc.runEvent)
+			})
+			agentSDK.On("Result", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				_, err := args.Get(2).(*os.File).Write(resultArchive(t, selftestResult))
+				require.NoError(t, err)
+			})
+
+			connect := func(cmd *cobra.Command, url string) (sdk.SDK, grpc.Client, error) {
+				assert.Equal(t, "agent.host:7020", url)
+				return agentSDK, nil, tc.connectErr
+			}
+
+			r := &computationRun{
+				manager:     mc,
+				connect:     connect,
+				serverURL:   lis.Addr().String(),
+				agentHost:   "agent.host",
+				timeout:     time.Minute,
+				keepVM:      tc.keepVM,
+				computation: &cvms.ComputationRunReq{Id: "computation-1"},
+				algorithm:   algoPath,
+				datasets:    []string{dataPath},
+				resultPath:  resultPath,
+				booted:      make(chan error, 1),
+				finished:    make(chan error, 1),
+			}
+
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(new(bytes.Buffer))
+
+			err = r.run(cmd, lis)
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+
+			if tc.err == nil {
+				result, err := os.ReadFile(resultPath)
+				require.NoError(t, err)
+				assert.Equal(t, resultArchive(t, selftestResult), result)
+			}
+			mc.AssertExpectations(t)
+			if !tc.removed {
+				mc.AssertNotCalled(t, "RemoveVm", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestComputationRunReq(t *testing.T) {
+	cmp := agent.Computation{
+		ID:          "1",
+		Name:        "sum",
+		Description: "sum of datasets",
+		Version:     agent.ManifestVersion,
+		TTL:         "2h",
+		Algorithm: agent.Algorithm{
+			Hash:     [32]byte{1},
+			UserKey:  []byte("algo key"),
+			Args:     []string{"--epochs", "2"},
+			Env:      map[string]string{"MODEL": "resnet-50"},
+			Steps:    []agent.Step{{Name: "sum", Args: []string{"--all"}, Datasets: []string{"data.csv"}}},
+			Watchdog: &agent.Watchdog{IdleSeconds: 60, Kill: true},
+		},
+		Datasets: agent.Datasets{{
+			Hash:     [32]byte{2},
+			UserKey:  []byte("data key"),
+			Filename: "data.csv",
+			Archive:  &agent.DatasetArchive{MaxSizeMB: 10, MaxFiles: 100},
+		}},
+		ResultConsumers:     []agent.ResultConsumer{{UserKey: []byte("result key")}},
+		AttestationApproval: &agent.AttestationApproval{Key: []byte("owner key")},
+		Storage:             &agent.Storage{Type: "tmpfs", SizeMB: 512},
+	}
+
+	req := computationRunReq(cmp)
+	assert.Equal(t, cmp.ID, req.Id)
+	assert.Equal(t, cmp.TTL, req.Ttl)
+	assert.Equal(t, cmp.Version, req.Version)
+	assert.Equal(t, cmp.Algorithm.Hash[:], req.Algorithm.Hash)
+	assert.Equal(t, cmp.Algorithm.Args, req.Algorithm.Args)
+	assert.Equal(t, cmp.Algorithm.Env, req.Algorithm.Env)
+	assert.Equal(t, cmp.Algorithm.Steps[0].Datasets, req.Algorithm.Steps[0].Datasets)
+	assert.True(t, req.Algorithm.Watchdog.Kill)
+	assert.Equal(t, cmp.Datasets[0].Hash[:], req.Datasets[0].Hash)
+	assert.Equal(t, cmp.Datasets[0].Filename, req.Datasets[0].Filename)
+	assert.Equal(t, uint64(100), req.Datasets[0].Archive.MaxFiles)
+	assert.Equal(t, cmp.ResultConsumers[0].UserKey, req.ResultConsumers[0].UserKey)
+	assert.Equal(t, cmp.AttestationApproval.Key, req.AttestationApproval.Key)
+	assert.Equal(t, uint64(512), req.Storage.SizeMb)
+	assert.Nil(t, req.ResultSink)
+	assert.Equal(t, forwardedAgentPort, req.AgentConfig.Port)
+	assert.True(t, req.AgentConfig.AttestedTls, "the agent is attested")
+}
@@ -44,7 +44,7 @@ func (c *CLI) InitializeAgentSDK(cmd *cobra.Command) error {
 	cmd.Println("🔗 Connected to agent ", agentGRPCClient.Secure())
 	c.client = agentGRPCClient
 
-	c.agentSDK = sdk.NewAgentSDK(agentClient)
+	c.agentSDK = sdk.NewAgentSDK(agentClient, sdk.WithMaxSendMsgSize(c.agentConfig.MaxSendMsgSize))
 	return nil
 }
 
@@ -0,0 +1,131 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+func (cli *CLI) NewSecretsCmd() *cobra.Command {
+	var fileSecrets, envSecrets []string
+
+	cmd := &cobra.Command{
+		Use:     "secrets <computation_manifest_file_path> <private_key_file_path>",
+		Short:   "Provision runtime secrets to the agent, signed by the computation owner",
+		Example: "secrets manifest.json private.pem --file api_token=token.txt --env MODEL_KEY=model.key",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.connectErr != nil {
+				printError(cmd, "Failed to connect to agent: %v ❌ ", cli.connectErr)
+				return
+			}
+
+			secrets, err := readSecrets(fileSecrets, envSecrets)
+			if err != nil {
+				printError(cmd, "Error reading secrets: %v ❌ ", err)
+				return
+			}
+
+			manifestFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading manifest file: %v ❌ ", err)
+				return
+			}
+
+			var cmp agent.Computation
+			if err := json.Unmarshal(manifestFile, &cmp); err != nil {
+				printError(cmd, "Error decoding manifest: %v ❌ ", err)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[1])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			signer, ok := privKey.(crypto.Signer)
+			if !ok {
+				printError(cmd, "Error signing secrets: %v ❌ ", agent.ErrUnsupportedKey)
+				return
+			}
+
+			signature, err := agent.SignSecrets(cmp, secrets, signer)
+			if err != nil {
+				printError(cmd, "Error signing secrets: %v ❌ ", err)
+				return
+			}
+
+			if err := cli.agentSDK.Secrets(cmd.Context(), secrets, signature); err != nil {
+				printError(cmd, "Error provisioning secrets: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Secrets provisioned successfully! ✔"))
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&fileSecrets, "file", []string{}, "Secret exposed to the algorithm as a file, as NAME=path of the file holding its value")
+	cmd.Flags().StringArrayVar(&envSecrets, "env", []string{}, "Secret exposed to the algorithm as an environment variable, as NAME=path of the file holding its value")
+
+	return cmd
+}
+
+// readSecrets reads the values of the NAME=path secrets from their files, so
+// that values are not passed on the command line.
+func readSecrets(fileSecrets, envSecrets []string) ([]agent.Secret, error) {
+	secrets, err := appendSecrets(nil, fileSecrets, false)
+	if err != nil {
+		return nil, err
+	}
+	if secrets, err = appendSecrets(secrets, envSecrets, true); err != nil {
+		return nil, err
+	}
+
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secrets, set them with --file or --env")
+	}
+
+	return secrets, nil
+}
+
+// appendSecrets appends the secrets of specs, dropping the trailing newline
+// of environment variable values.
+func appendSecrets(secrets []agent.Secret, specs []string, env bool) ([]agent.Secret, error) {
+	for _, spec := range specs {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("secret %q is not in the NAME=path form", spec)
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if env {
+			value = bytes.TrimSuffix(value, []byte("\n"))
+		}
+
+		secrets = append(secrets, agent.Secret{Name: name, Value: value, Env: env})
+	}
+
+	return secrets, nil
+}
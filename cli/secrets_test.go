@@ -0,0 +1,117 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+)
+
+func TestSecretsCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: ed25519KeyType, Bytes: der}), 0o600))
+
+	rsaKeyFile := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(rsaKeyFile))
+
+	manifestFile := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(manifestFile, []byte(`{"id":"1","name":"sample computation"}`), 0o644))
+
+	tokenFile := filepath.Join(dir, "token.txt")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token\n"), 0o600))
+
+	secrets := []agent.Secret{{Name: "api_token", Value: []byte("token\n")}, {Name: "API_TOKEN", Value: []byte("token"), Env: true}}
+	signingBytes, err := agent.SecretsSigningBytes(agent.Computation{ID: "1", Name: "sample computation"}, secrets)
+	require.NoError(t, err)
+
+	secretFlags := []string{"--file", "api_token=" + tokenFile, "--env", "API_TOKEN=" + tokenFile}
+
+	cases := []struct {
+		desc       string
+		args       []string
+		secretsErr error
+		connectErr error
+		output     string
+	}{
+		{
+			desc:   "provision secrets",
+			args:   append([]string{manifestFile, keyFile}, secretFlags...),
+			output: "Secrets provisioned successfully",
+		},
+		{
+			desc:   "no secrets",
+			args:   []string{manifestFile, keyFile},
+			output: "Error reading secrets",
+		},
+		{
+			desc:   "malformed secret",
+			args:   []string{manifestFile, keyFile, "--file", "api_token"},
+			output: "is not in the NAME=path form",
+		},
+		{
+			desc:   "missing secret file",
+			args:   []string{manifestFile, keyFile, "--file", "api_token=" + filepath.Join(dir, "missing.txt")},
+			output: "Error reading secrets",
+		},
+		{
+			desc:   "missing manifest file",
+			args:   append([]string{filepath.Join(dir, "missing.json"), keyFile}, secretFlags...),
+			output: "Error reading manifest file",
+		},
+		{
+			desc:   "unsupported private key",
+			args:   append([]string{manifestFile, rsaKeyFile}, secretFlags...),
+			output: "Error signing secrets",
+		},
+		{
+			desc:       "provisioning failure",
+			args:       append([]string{manifestFile, keyFile}, secretFlags...),
+			secretsErr: errors.New("secrets are not signed by the computation owner"),
+			output:     "secrets are not signed by the computation owner",
+		},
+		{
+			desc:       "connection error",
+			args:       append([]string{manifestFile, keyFile}, secretFlags...),
+			connectErr: errors.New("failed to connect to agent"),
+			output:     "Failed to connect to agent",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockSDK := new(mocks.SDK)
+			mockSDK.On("Secrets", mock.Anything, secrets, mock.MatchedBy(func(signature []byte) bool {
+				return ed25519.Verify(pub, signingBytes, signature)
+			})).Return(tc.secretsErr)
+
+			testCLI := CLI{agentSDK: mockSDK, connectErr: tc.connectErr}
+
+			cmd := testCLI.NewSecretsCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(tc.args)
+			require.NoError(t, cmd.Execute())
+
+			assert.Contains(t, buf.String(), tc.output)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cliArtifactName    = "cocos-cli"
+	checksumsSignature = ".sig"
+)
+
+var (
+	errInvalidReleaseKey      = errors.New("release public key is not a PEM encoded Ed25519 public key")
+	errReleaseSignature       = errors.New("checksums file signature verification failed")
+	errInvalidChecksumsLine   = errors.New("invalid checksums file line")
+	errDuplicateChecksumEntry = errors.New("duplicate checksums file entry")
+	errReleaseVerification    = errors.New("release verification failed")
+)
+
+// releasePublicKey is the public key of the Ed25519 RELEASE_SIGNING_KEY secret
+// the release workflow signs the checksums files with, which refuses to sign
+// with a key that does not match it.
+//
+//go:embed release.pub
+var releasePublicKey []byte
+
+func (cli *CLI) NewSelfCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self [command]",
+		Short: "Manage the CLI itself",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Printf("Manage the CLI itself\n\n")
+			cmd.Printf("Usage:\n  %s [command]\n\n", cmd.CommandPath())
+			cmd.Printf("Available Commands:\n")
+
+			for _, subCmd := range cmd.Commands() {
+				cmd.Printf("  %-15s%s\n", subCmd.Name(), subCmd.Short)
+			}
+
+			cmd.Printf("\nUse \"%s [command] --help\" for more information about a command.\n", cmd.CommandPath())
+		},
+	}
+
+	cmd.AddCommand(cli.newSelfVerifyCmd())
+
+	return cmd
+}
+
+func (cli *CLI) newSelfVerifyCmd() *cobra.Command {
+	var signaturePath string
+	var publicKeyPath string
+	var binaryName string
+
+	cmd := &cobra.Command{
+		Use:     "verify <checksums_file> [artifact...]",
+		Short:   "Verify the CLI binary and downloaded release artifacts against the signed release checksums",
+		Example: "self verify checksums.txt bzImage rootfs.cpio.gz",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checksumsFile, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read checksums file: %w", err)
+			}
+
+			if signaturePath == "" {
+				signaturePath = args[0] + checksumsSignature
+			}
+
+			signature, err := os.ReadFile(signaturePath)
+			if err != nil {
+				return fmt.Errorf("failed to read checksums signature: %w", err)
+			}
+
+			keyFile := releasePublicKey
+			if publicKeyPath != "" {
+				if keyFile, err = os.ReadFile(publicKeyPath); err != nil {
+					return fmt.Errorf("failed to read release public key: %w", err)
+				}
+			}
+
+			publicKey, err := parseReleaseKey(keyFile)
+			if err != nil {
+				return fmt.Errorf("failed to decode release public key: %w", err)
+			}
+
+			if !ed25519.Verify(publicKey, checksumsFile, signature) {
+				return errReleaseSignature
+			}
+			cmd.Println(color.New(color.FgGreen).Sprint("Checksums signature: valid ✔"))
+
+			checksums, err := parseChecksums(checksumsFile)
+			if err != nil {
+				return fmt.Errorf("failed to decode checksums file: %w", err)
+			}
+
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the CLI binary: %w", err)
+			}
+
+			artifacts := map[string]string{executable: binaryName}
+			for _, path := range args[1:] {
+				artifacts[path] = filepath.Base(path)
+			}
+
+			report, err := verifyArtifacts(checksums, artifacts)
+			if err != nil {
+				return fmt.Errorf("failed to hash release artifact: %w", err)
+			}
+
+			err = printOutput(cmd, report, func(w io.Writer) {
+				fmt.Fprintln(w, "ARTIFACT\tSTATUS")
+				for _, f := range report {
+					fmt.Fprintf(w, "%s\t%s\n", f.Path, f.Status)
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("failed to print verification report: %w", err)
+			}
+
+			failed := 0
+			for _, f := range report {
+				if f.Status != fileVerified {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%w, %d of %d artifacts do not match the checksums", errReleaseVerification, failed, len(report))
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Release artifacts verified successfully! ✔"))
+
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().StringVarP(&signaturePath, "signature", "s", "", "Path of the checksums file signature (default <checksums_file>.sig)")
+	cmd.Flags().StringVar(&publicKeyPath, "public-key", "", "Path of a PEM encoded Ed25519 public key used instead of the embedded release key")
+	cmd.Flags().StringVar(&binaryName, "binary-name", cliArtifactName, "Name of the CLI binary in the checksums file")
+
+	return cmd
+}
+
+func parseReleaseKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errInvalidReleaseKey
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Join(errInvalidReleaseKey, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errInvalidReleaseKey
+	}
+
+	return publicKey, nil
+}
+
+// parseChecksums decodes a checksums file in the sha256sum format, mapping
+// artifact names to their hex encoded SHA-256 hashes.
+func parseChecksums(data []byte) (map[string]string, error) {
+	checksums := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if _, err := hex.DecodeString(hash); !ok || err != nil || len(hash) != 2*sha256.Size || name == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidChecksumsLine, line)
+		}
+
+		name = filepath.Base(name)
+		if _, ok := checksums[name]; ok {
+			return nil, fmt.Errorf("%w: %s", errDuplicateChecksumEntry, name)
+		}
+		checksums[name] = strings.ToLower(hash)
+	}
+
+	return checksums, scanner.Err()
+}
+
+// verifyArtifacts hashes the artifacts, keyed by path, and compares them with
+// the checksums of their artifact names, reporting artifacts the checksums do not list as missing.
+func verifyArtifacts(checksums map[string]string, artifacts map[string]string) ([]fileVerification, error) {
+	report := make([]fileVerification, 0, len(artifacts))
+
+	for path, name := range artifacts {
+		expected, ok := checksums[name]
+		if !ok {
+			report = append(report, fileVerification{Path: path, Status: fileMissing})
+			continue
+		}
+
+		hash, err := sha256File(path)
+		if err != nil {
+			return nil, err
+		}
+
+		status := fileVerified
+		if hash != expected {
+			status = fileModified
+		}
+		report = append(report, fileVerification{Path: path, Status: status})
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Path < report[j].Path
+	})
+
+	return report, nil
+}
+
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,142 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeReleaseKey(t *testing.T, path string, key ed25519.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+}
+
+func TestEmbeddedReleaseKey(t *testing.T) {
+	_, err := parseReleaseKey(releasePublicKey)
+	require.NoError(t, err)
+}
+
+func TestSelfVerifyCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyPath := filepath.Join(dir, "release.pub")
+	writeReleaseKey(t, keyPath, publicKey)
+	otherKeyPath := filepath.Join(dir, "other.pub")
+	writeReleaseKey(t, otherKeyPath, otherKey)
+
+	imagePath := filepath.Join(dir, "bzImage")
+	require.NoError(t, os.WriteFile(imagePath, []byte("kernel"), 0o600))
+	tamperedPath := filepath.Join(dir, "rootfs.cpio.gz")
+	require.NoError(t, os.WriteFile(tamperedPath, []byte("tampered"), 0o600))
+	unlistedPath := filepath.Join(dir, "unlisted")
+	require.NoError(t, os.WriteFile(unlistedPath, []byte("unlisted"), 0o600))
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	cliHash, err := sha256File(executable)
+	require.NoError(t, err)
+	imageHash, err := sha256File(imagePath)
+	require.NoError(t, err)
+
+	checksums := fmt.Sprintf("%s  cocos-cli\n%s  bzImage\n%s *rootfs.cpio.gz\n", cliHash, imageHash, imageHash)
+	checksumsPath := filepath.Join(dir, "checksums.txt")
+	require.NoError(t, os.WriteFile(checksumsPath, []byte(checksums), 0o600))
+	require.NoError(t, os.WriteFile(checksumsPath+checksumsSignature, ed25519.Sign(privateKey, []byte(checksums)), 0o600))
+
+	invalidPath := filepath.Join(dir, "invalid.txt")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("not-a-hash cocos-cli\n"), 0o600))
+	invalidSigPath := filepath.Join(dir, "invalid.sig")
+	require.NoError(t, os.WriteFile(invalidSigPath, ed25519.Sign(privateKey, []byte("not-a-hash cocos-cli\n")), 0o600))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedOutput []string
+		err            error
+	}{
+		{
+			name:           "verified binary and artifact",
+			args:           []string{checksumsPath, imagePath, "--public-key", keyPath},
+			expectedOutput: []string{"Checksums signature: valid", "bzImage", "Release artifacts verified successfully"},
+		},
+		{
+			name:           "tampered artifact",
+			args:           []string{checksumsPath, tamperedPath, "--public-key", keyPath},
+			expectedOutput: []string{fileModified, "1 of 2 artifacts do not match"},
+			err:            errReleaseVerification,
+		},
+		{
+			name:           "artifact missing from checksums",
+			args:           []string{checksumsPath, unlistedPath, "--public-key", keyPath},
+			expectedOutput: []string{fileMissing, "1 of 2 artifacts do not match"},
+			err:            errReleaseVerification,
+		},
+		{
+			name:           "binary missing from checksums",
+			args:           []string{checksumsPath, "--binary-name", "cocos-agent", "--public-key", keyPath},
+			expectedOutput: []string{fileMissing},
+			err:            errReleaseVerification,
+		},
+		{
+			name: "signed by another key",
+			args: []string{checksumsPath, "--public-key", otherKeyPath},
+			err:  errReleaseSignature,
+		},
+		{
+			name: "signed by the embedded key",
+			args: []string{checksumsPath},
+			err:  errReleaseSignature,
+		},
+		{
+			name: "invalid public key",
+			args: []string{checksumsPath, "--public-key", checksumsPath},
+			err:  errInvalidReleaseKey,
+		},
+		{
+			name:           "missing signature",
+			args:           []string{checksumsPath, "--signature", filepath.Join(dir, "missing.sig"), "--public-key", keyPath},
+			expectedOutput: []string{"failed to read checksums signature"},
+			err:            os.ErrNotExist,
+		},
+		{
+			name: "invalid checksums file",
+			args: []string{invalidPath, "--signature", invalidSigPath, "--public-key", keyPath},
+			err:  errInvalidChecksumsLine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := (&CLI{}).NewSelfCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetArgs(append([]string{"verify"}, tt.args...))
+			err := cmd.Execute()
+			require.ErrorIs(t, err, tt.err)
+
+			output := buf.String()
+			if err != nil {
+				output += err.Error()
+			}
+			for _, expected := range tt.expectedOutput {
+				require.Contains(t, output, expected)
+			}
+		})
+	}
+}
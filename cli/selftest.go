@@ -0,0 +1,530 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	cvmsgrpc "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/pkg/clients/grpc"
+	agentgrpc "github.com/ultravioletrs/cocos/pkg/clients/grpc/agent"
+	"github.com/ultravioletrs/cocos/pkg/sdk"
+	"golang.org/x/crypto/sha3"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	stageBoot        = "vm boot"
+	stageAttestation = "attestation"
+	stageUpload      = "upload"
+	stageExecution   = "execution"
+	stageResult      = "result"
+	stageCleanup     = "cleanup"
+
+	stagePassed  = "passed"
+	stageFailed  = "failed"
+	stageSkipped = "skipped"
+
+	selftestDatasetFile = "selftest.csv"
+	selftestResultFile  = "sum.txt"
+	selftestResult      = "55"
+	// forwardedAgentPort is the guest port the manager forwards to the agent.
+	forwardedAgentPort = "7002"
+	vmRemoveTime       = 30 * time.Second
+)
+
+var (
+	errSelftestFailed    = errors.New("self-test failed")
+	errSelftestResult    = errors.New("unexpected self-test result")
+	errSelftestNoResult  = errors.New("self-test result file is missing from the result archive")
+	errComputationFailed = errors.New("computation failed")
+)
+
+// selftestAlgorithm and selftestDataset are the computation the self-test runs,
+// the algorithm writes the sum of the dataset numbers to its results.
+var (
+	//go:embed selftest.sh
+	selftestAlgorithm []byte
+	//go:embed selftest.csv
+	selftestDataset []byte
+)
+
+// selftestStage is the outcome of a stage of the self-test.
+type selftestStage struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Details  string        `json:"details,omitempty"`
+	err      error
+}
+
+// agentConnector connects to the agent of a virtual machine of the manager
+// and completes the attested TLS handshake with it.
+type agentConnector func(cmd *cobra.Command, url string) (sdk.SDK, grpc.Client, error)
+
+// selftest runs the built-in computation on a virtual machine of the manager,
+// serving its manifest to the agent as the computation management server.
+type selftest struct {
+	manager   manager.ManagerServiceClient
+	connect   agentConnector
+	serverURL string
+	agentHost string
+	timeout   time.Duration
+
+	computation *cvms.ComputationRunReq
+	privKey     any
+
+	booted   chan error
+	finished chan error
+	stages   []selftestStage
+}
+
+func (c *CLI) NewSelftestCmd() *cobra.Command {
+	var (
+		managerURL string
+		serverAddr string
+		listenAddr string
+		agentHost  string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:     "selftest",
+		Short:   "Run a built-in computation through a new virtual machine and report the outcome of every stage",
+		Example: "selftest --manager localhost:7001 --server-url 10.0.2.2:7005",
+		Args:    cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			c.managerConfig.URL = managerURL
+			if err := c.InitializeManagerClient(cmd); err != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+				return
+			}
+			defer c.Close()
+
+			if listenAddr == "" {
+				_, port, err := net.SplitHostPort(serverAddr)
+				if err != nil {
+					printError(cmd, "Invalid computation server URL: %v ❌ ", err)
+					return
+				}
+				listenAddr = net.JoinHostPort("", port)
+			}
+
+			if agentHost == "" {
+				host, _, err := net.SplitHostPort(managerURL)
+				if err != nil {
+					printError(cmd, "Invalid manager URL: %v ❌ ", err)
+					return
+				}
+				agentHost = host
+			}
+
+			lis, err := net.Listen("tcp", listenAddr)
+			if err != nil {
+				printError(cmd, "Error starting computation server: %v ❌ ", err)
+				return
+			}
+
+			st, err := newSelftest(c.managerClient, c.connectAttestedAgent, serverAddr, agentHost, timeout)
+			if err != nil {
+				printError(cmd, "Error preparing self-test computation: %v ❌ ", err)
+				return
+			}
+
+			cmd.Printf("🔗 Running self-test computation %s\n", st.computation.Id)
+
+			err = st.run(cmd, lis)
+			if werr := st.writeReport(cmd.OutOrStdout()); werr != nil {
+				printError(cmd, "Error printing self-test report: %v ❌ ", werr)
+				return
+			}
+			if err != nil {
+				printError(cmd, "Self-test failed: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprint("✅ Self-test passed"))
+		},
+	}
+
+	cmd.Flags().StringVar(&managerURL, "manager", "", "Address of the manager the virtual machine is created on")
+	cmd.Flags().StringVar(&serverAddr, serverURL, "", "Address the agent reaches the self-test computation server at")
+	cmd.Flags().StringVar(&listenAddr, "listen", "", "Address the self-test computation server listens on, the port of the server URL if empty")
+	cmd.Flags().StringVar(&agentHost, "agent-host", "", "Host the forwarded agent port is reached at, the manager host if empty")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "Time the whole self-test may take")
+	_ = cmd.MarkFlagRequired("manager")
+	_ = cmd.MarkFlagRequired(serverURL)
+
+	return cmd
+}
+
+// newSelftest returns a self-test with the manifest of a new computation,
+// whose algorithm, dataset and result are provided by a new key.
+func newSelftest(mc manager.ManagerServiceClient, connect agentConnector, serverURL, agentHost string, timeout time.Duration) (*selftest, error) {
+	privKey, err := generateKey(ECDSA)
+	if err != nil {
+		return nil, err
+	}
+
+	userKey, err := x509.MarshalPKIXPublicKey(publicKey(privKey))
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	algoHash := sha3.Sum256(selftestAlgorithm)
+	dataHash := sha3.Sum256(selftestDataset)
+
+	return &selftest{
+		manager:   mc,
+		connect:   connect,
+		serverURL: serverURL,
+		agentHost: agentHost,
+		timeout:   timeout,
+		computation: &cvms.ComputationRunReq{
+			Id:              "selftest-" + hex.EncodeToString(id),
+			Name:            "cocos-cli self-test",
+			Description:     "Built-in computation of the cocos-cli self-test",
+			Datasets:        []*cvms.Dataset{{Hash: dataHash[:], UserKey: userKey, Filename: selftestDatasetFile}},
+			Algorithm:       &cvms.Algorithm{Hash: algoHash[:], UserKey: userKey},
+			ResultConsumers: []*cvms.ResultConsumer{{UserKey: userKey}},
+			Version:         agent.ManifestVersion,
+			Ttl:             timeout.String(),
+			AgentConfig:     &cvms.AgentConfig{Port: forwardedAgentPort, AttestedTls: true},
+		},
+		privKey:  privKey,
+		booted:   make(chan error, 1),
+		finished: make(chan error, 1),
+	}, nil
+}
+
+// run runs every stage of the self-test, stages following a failed one are
+// skipped. The virtual machine is removed once the stages are done.
+func (st *selftest) run(cmd *cobra.Command, lis net.Listener) error {
+	ctx, cancel := context.WithTimeout(cmd.Context(), st.timeout)
+	defer cancel()
+
+	// The agent blocks until its messages are read, they are read until the CLI exits.
+	incoming := make(chan *cvms.ClientStreamMessage)
+	go watchAgent(incoming, st.booted, st.finished)
+
+	srv := googlegrpc.NewServer()
+	cvms.RegisterServiceServer(srv, cvmsgrpc.NewServer(incoming, st))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	var (
+		cvmID     string
+		agentPort string
+		agentSDK  sdk.SDK
+		conn      grpc.Client
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	st.stage(cmd, stageBoot, func() (string, error) {
+		res, err := st.manager.CreateVm(ctx, &manager.CreateReq{AgentCvmServerUrl: st.serverURL, Ttl: st.timeout.String()})
+		if err != nil {
+			return "", err
+		}
+		cvmID, agentPort = res.CvmId, res.ForwardedPort
+
+		if err := wait(ctx, st.booted); err != nil {
+			return "", fmt.Errorf("virtual machine %s: %w", cvmID, err)
+		}
+
+		return fmt.Sprintf("virtual machine %s, agent on port %s", cvmID, agentPort), nil
+	})
+
+	st.stage(cmd, stageAttestation, func() (string, error) {
+		url := net.JoinHostPort(st.agentHost, agentPort)
+
+		var err error
+		if agentSDK, conn, err = st.connect(cmd, url); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("attested TLS with agent at %s", url), nil
+	})
+
+	st.stage(cmd, stageUpload, func() (string, error) {
+		return "algorithm and dataset", st.upload(ctx, agentSDK)
+	})
+
+	st.stage(cmd, stageExecution, func() (string, error) {
+		return "", wait(ctx, st.finished)
+	})
+
+	st.stage(cmd, stageResult, func() (string, error) {
+		return fmt.Sprintf("%s holds %s", selftestResultFile, selftestResult), st.result(ctx, agentSDK)
+	})
+
+	if cvmID != "" {
+		st.stage(cmd, stageCleanup, func() (string, error) {
+			// The virtual machine is removed even when the self-test timed out.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), vmRemoveTime)
+			defer cancel()
+
+			if _, err := st.manager.RemoveVm(ctx, &manager.RemoveReq{CvmId: cvmID}); err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("virtual machine %s removed", cvmID), nil
+		})
+	}
+
+	return st.err()
+}
+
+// Run serves the self-test computation to the agent connecting to the server.
+func (st *selftest) Run(ctx context.Context, ipAddress string, sendMessage cvmsgrpc.SendFunc, authInfo credentials.AuthInfo) {
+	if err := sendMessage(&cvms.ServerStreamMessage{
+		Message: &cvms.ServerStreamMessage_RunReq{RunReq: st.computation},
+	}); err != nil {
+		notify(st.booted, fmt.Errorf("error sending computation to agent at %s: %w", ipAddress, err))
+	}
+}
+
+// watchAgent follows the messages of the agent, notifying booted once the
+// agent received the computation and finished once the computation is done.
+// The agent is blocked until its messages are read.
+func watchAgent(incoming <-chan *cvms.ClientStreamMessage, booted, finished chan<- error) {
+	for msg := range incoming {
+		switch m := msg.Message.(type) {
+		case *cvms.ClientStreamMessage_RunRes:
+			if m.RunRes.Error != "" {
+				notify(booted, errors.New(m.RunRes.Error))
+				continue
+			}
+			notify(booted, nil)
+		case *cvms.ClientStreamMessage_AgentEvent:
+			switch {
+			case m.AgentEvent.EventType == events.RunFinished:
+				notify(finished, nil)
+			case m.AgentEvent.Status == agent.Failed.String():
+				err := fmt.Errorf("%w: %s", errComputationFailed, eventError(m.AgentEvent))
+				// A computation failing before it is running fails the boot.
+				notify(booted, err)
+				notify(finished, err)
+			}
+		}
+	}
+}
+
+func (st *selftest) upload(ctx context.Context, agentSDK sdk.SDK) error {
+	dir, err := os.MkdirTemp("", "cocos-selftest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	algo, err := writeSelftestFile(dir, "algorithm", selftestAlgorithm)
+	if err != nil {
+		return err
+	}
+	defer algo.Close()
+
+	spec, err := agent.NewAlgorithmSpec(algorithm.Spec{Type: algorithm.AlgoTypeBin})
+	if err != nil {
+		return err
+	}
+
+	if err := agentSDK.Algo(ctx, algo, nil, spec, st.privKey); err != nil {
+		return fmt.Errorf("error uploading algorithm: %w", err)
+	}
+
+	dataset, err := writeSelftestFile(dir, selftestDatasetFile, selftestDataset)
+	if err != nil {
+		return err
+	}
+	defer dataset.Close()
+
+	if err := agentSDK.Data(ctx, dataset, selftestDatasetFile, st.privKey); err != nil {
+		return fmt.Errorf("error uploading dataset: %w", err)
+	}
+
+	return nil
+}
+
+func (st *selftest) result(ctx context.Context, agentSDK sdk.SDK) error {
+	f, err := os.CreateTemp("", "cocos-selftest-result")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := agentSDK.Result(ctx, st.privKey, f); err != nil {
+		return err
+	}
+
+	archive, err := os.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+
+	rc, err := r.Open(selftestResultFile)
+	if err != nil {
+		return errSelftestNoResult
+	}
+	defer rc.Close()
+
+	sum, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	if got := strings.TrimSpace(string(sum)); got != selftestResult {
+		return fmt.Errorf("%w: %s holds %q instead of %q", errSelftestResult, selftestResultFile, got, selftestResult)
+	}
+
+	return nil
+}
+
+// stage runs the stage unless a previous stage failed, and records its outcome.
+func (st *selftest) stage(cmd *cobra.Command, name string, fn func() (string, error)) {
+	if name != stageCleanup && st.err() != nil {
+		st.stages = append(st.stages, selftestStage{Name: name, Status: stageSkipped})
+		return
+	}
+
+	cmd.Printf("⏳ %s\n", name)
+
+	start := time.Now()
+	details, err := fn()
+	stage := selftestStage{Name: name, Status: stagePassed, Duration: time.Since(start), Details: details}
+	if err != nil {
+		stage.Status = stageFailed
+		stage.Details = err.Error()
+		stage.err = err
+	}
+
+	st.stages = append(st.stages, stage)
+}
+
+// err returns an error when a stage failed.
+func (st *selftest) err() error {
+	for _, stage := range st.stages {
+		if stage.Status == stageFailed {
+			return fmt.Errorf("%w at stage %s: %w", errSelftestFailed, stage.Name, stage.err)
+		}
+	}
+
+	return nil
+}
+
+// writeReport writes the stages in the output format.
+func (st *selftest) writeReport(w io.Writer) error {
+	return writeOutput(w, st.stages, func(tw io.Writer) {
+		fmt.Fprintln(tw, "STAGE\tSTATUS\tDURATION\tDETAILS")
+		for _, stage := range st.stages {
+			duration := "-"
+			if stage.Status != stageSkipped {
+				duration = stage.Duration.Round(time.Millisecond).String()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", stage.Name, stage.Status, duration, stage.Details)
+		}
+	})
+}
+
+// connectAttestedAgent connects to the agent over attested TLS, the first
+// request completes the handshake, which verifies the attestation report of
+// the virtual machine against the attestation policy of the CLI.
+func (c *CLI) connectAttestedAgent(cmd *cobra.Command, url string) (sdk.SDK, grpc.Client, error) {
+	cfg := c.agentConfig
+	cfg.URL = url
+	cfg.AttestedTLS = true
+
+	client, agentClient, err := agentgrpc.NewAgentClient(cmd.Context(), cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The agent server may still be starting once the computation was received.
+	err = withRetry(cmd, func() error {
+		_, err := agentClient.Capabilities(cmd.Context(), &agent.CapabilitiesRequest{})
+		return err
+	})
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+
+	return sdk.NewAgentSDK(agentClient, sdk.WithMaxSendMsgSize(cfg.MaxSendMsgSize)), client, nil
+}
+
+func writeSelftestFile(dir, name string, data []byte) (*os.File, error) {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// eventError returns the error the details of a failure event hold.
+func eventError(event *cvms.AgentEvent) string {
+	var details struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(event.Details, &details); err != nil || details.Error == "" {
+		return event.EventType
+	}
+
+	return details.Error
+}
+
+// wait waits for the outcome sent to ch.
+func wait(ctx context.Context, ch <-chan error) error {
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify sends the outcome to ch unless an outcome is pending already.
+func notify(ch chan<- error, err error) {
+	select {
+	case ch <- err:
+	default:
+	}
+}
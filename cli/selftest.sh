@@ -0,0 +1,11 @@
+#!/bin/sh
+# Self-test algorithm of cocos-cli, it writes the sum of the numbers of its
+# dataset to the results directory.
+set -eu
+
+sum=0
+while read -r n; do
+	sum=$((sum + n))
+done < "$COCOS_DATASETS_DIR/selftest.csv"
+
+echo "$sum" > "$COCOS_RESULTS_DIR/sum.txt"
@@ -0,0 +1,249 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"github.com/ultravioletrs/cocos/pkg/clients/grpc"
+	"github.com/ultravioletrs/cocos/pkg/sdk"
+	sdkmocks "github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// fakeAgent receives the computation of the self-test server as the agent of
+// the virtual machine does, and returns the function it reports messages with.
+func fakeAgent(t *testing.T, addr string) func(*cvms.ClientStreamMessage) {
+	conn, err := googlegrpc.NewClient(addr, googlegrpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	stream, err := cvms.NewServiceClient(conn).Process(ctx)
+	require.NoError(t, err)
+
+	msgs := make(chan *cvms.ClientStreamMessage, 10)
+	go func() {
+		for msg := range msgs {
+			if err := stream.Send(msg); err != nil {
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(msgs) })
+
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			if chunk := msg.GetRunReqChunks(); chunk != nil && chunk.IsLast {
+				msgs <- &cvms.ClientStreamMessage{Message: &cvms.ClientStreamMessage_RunRes{RunRes: &cvms.RunResponse{ComputationId: chunk.Id}}}
+			}
+		}
+	}()
+
+	return func(msg *cvms.ClientStreamMessage) { msgs <- msg }
+}
+
+func agentEvent(eventType, status, details string) *cvms.ClientStreamMessage {
+	return &cvms.ClientStreamMessage{Message: &cvms.ClientStreamMessage_AgentEvent{
+		AgentEvent: &cvms.AgentEvent{EventType: eventType, Status: status, Details: []byte(details)},
+	}}
+}
+
+func resultArchive(t *testing.T, sum string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(selftestResultFile)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(sum + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestSelftest(t *testing.T) {
+	connectErr := errors.New("attestation verification failed")
+
+	cases := []struct {
+		name       string
+		connectErr error
+		runEvent   *cvms.ClientStreamMessage
+		result     string
+		statuses   map[string]string
+		err        error
+	}{
+		{
+			name:     "every stage passes",
+			runEvent: agentEvent(events.RunFinished, agent.Ready.String(), ""),
+			result:   selftestResult,
+			statuses: map[string]string{
+				stageBoot:        stagePassed,
+				stageAttestation: stagePassed,
+				stageUpload:      stagePassed,
+				stageExecution:   stagePassed,
+				stageResult:      stagePassed,
+				stageCleanup:     stagePassed,
+			},
+		},
+		{
+			name:       "attestation fails",
+			connectErr: connectErr,
+			statuses: map[string]string{
+				stageBoot:        stagePassed,
+				stageAttestation: stageFailed,
+				stageUpload:      stageSkipped,
+				stageExecution:   stageSkipped,
+				stageResult:      stageSkipped,
+				stageCleanup:     stagePassed,
+			},
+			err: errSelftestFailed,
+		},
+		{
+			name:     "computation fails",
+			runEvent: agentEvent(events.Error, agent.Failed.String(), `{"error":"exit status 1"}`),
+			statuses: map[string]string{
+				stageBoot:        stagePassed,
+				stageAttestation: stagePassed,
+				stageUpload:      stagePassed,
+				stageExecution:   stageFailed,
+				stageResult:      stageSkipped,
+				stageCleanup:     stagePassed,
+			},
+			err: errComputationFailed,
+		},
+		{
+			name:     "unexpected result",
+			runEvent: agentEvent(events.RunFinished, agent.Ready.String(), ""),
+			result:   "42",
+			statuses: map[string]string{
+				stageBoot:        stagePassed,
+				stageAttestation: stagePassed,
+				stageUpload:      stagePassed,
+				stageExecution:   stagePassed,
+				stageResult:      stageFailed,
+				stageCleanup:     stagePassed,
+			},
+			err: errSelftestResult,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			var send func(*cvms.ClientStreamMessage)
+
+			mc := new(mocks.ManagerServiceClient)
+			mc.On("CreateVm", mock.Anything, mock.MatchedBy(func(req *manager.CreateReq) bool {
+				return req.AgentCvmServerUrl == lis.Addr().String()
+			})).Run(func(args mock.Arguments) {
+				send = fakeAgent(t, lis.Addr().String())
+			}).Return(&manager.CreateRes{CvmId: "vm-1", ForwardedPort: "7020"}, nil)
+			mc.On("RemoveVm", mock.Anything, &manager.RemoveReq{CvmId: "vm-1"}).Return(&emptypb.Empty{}, nil)
+
+			agentSDK := new(sdkmocks.SDK)
+			agentSDK.On("Algo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			agentSDK.On("Data", mock.Anything, mock.Anything, selftestDatasetFile, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				send(tc.runEvent)
+			})
+			agentSDK.On("Result", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				_, err := args.Get(2).(*os.File).Write(resultArchive(t, tc.result))
+				require.NoError(t, err)
+			})
+
+			connect := func(cmd *cobra.Command, url string) (sdk.SDK, grpc.Client, error) {
+				assert.Equal(t, "agent.host:7020", url)
+				return agentSDK, nil, tc.connectErr
+			}
+
+			st, err := newSelftest(mc, connect, lis.Addr().String(), "agent.host", time.Minute)
+			require.NoError(t, err)
+
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			cmd.SetOut(new(bytes.Buffer))
+
+			err = st.run(cmd, lis)
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+
+			statuses := make(map[string]string)
+			for _, stage := range st.stages {
+				statuses[stage.Name] = stage.Status
+			}
+			assert.Equal(t, tc.statuses, statuses)
+
+			var report bytes.Buffer
+			require.NoError(t, st.writeReport(&report))
+			assert.Contains(t, report.String(), "STAGE")
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSelftestReportOutput(t *testing.T) {
+	defer func() { OutputFormat = OutputTable }()
+	st := &selftest{stages: []selftestStage{
+		{Name: "create", Status: stagePassed, Duration: time.Second},
+		{Name: "attest", Status: stageFailed, Details: "report rejected"},
+		{Name: "upload", Status: stageSkipped},
+	}}
+
+	OutputFormat = OutputJSON
+	var report bytes.Buffer
+	require.NoError(t, st.writeReport(&report))
+
+	var stages []selftestStage
+	require.NoError(t, json.Unmarshal(report.Bytes(), &stages))
+	require.Len(t, stages, 3)
+	assert.Equal(t, "attest", stages[1].Name)
+	assert.Equal(t, stageFailed, stages[1].Status)
+	assert.Equal(t, "report rejected", stages[1].Details)
+	assert.Equal(t, time.Second, stages[0].Duration)
+
+	OutputFormat = OutputYAML
+	report.Reset()
+	require.NoError(t, st.writeReport(&report))
+	assert.Contains(t, report.String(), "status: skipped")
+}
+
+func TestSelftestComputation(t *testing.T) {
+	st, err := newSelftest(nil, nil, "10.0.2.2:7005", "localhost", time.Minute)
+	require.NoError(t, err)
+
+	cmp := st.computation
+	assert.Equal(t, forwardedAgentPort, cmp.AgentConfig.Port)
+	assert.True(t, cmp.AgentConfig.AttestedTls, "the agent is attested")
+	assert.Equal(t, cmp.Algorithm.UserKey, cmp.Datasets[0].UserKey)
+	assert.Equal(t, cmp.Algorithm.UserKey, cmp.ResultConsumers[0].UserKey)
+	assert.Equal(t, selftestDatasetFile, cmp.Datasets[0].Filename)
+
+	other, err := newSelftest(nil, nil, "10.0.2.2:7005", "localhost", time.Minute)
+	require.NoError(t, err)
+	assert.NotEqual(t, cmp.Id, other.computation.Id, "self-tests run computations of their own")
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/manager"
+)
+
+const vcekName = "vcek.pem"
+
+func (c *CLI) NewSNPCertsCmd(fileSavePath string) *cobra.Command {
+	var (
+		product   string
+		outputDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snp-certs <chip_id> <reported_tcb>",
+		Short: "Fetch the SEV-SNP certificate chain of a chip from the manager",
+		Long: `snp-certs fetches the ARK, ASK and VCEK attestation reports of the chip at the
+reported TCB version are verified with from the certificate cache of the manager,
+instead of the AMD KDS. The chip ID is hex encoded, the reported TCB version is a
+decimal or 0x prefixed hexadecimal number, as in the attestation report.
+
+The ASK and ARK are saved to ask_ark.pem, as ca-bundle does, and the VCEK to vcek.pem.`,
+		Example: "snp-certs <chip_id> 0x7300000000000003 --product Milan",
+		Args:    cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			chipID, err := hex.DecodeString(args[0])
+			if err != nil {
+				printError(cmd, "Error decoding chip ID: %v ❌ ", err)
+				return
+			}
+
+			reportedTCB, err := strconv.ParseUint(args[1], 0, 64)
+			if err != nil {
+				printError(cmd, "Error parsing reported TCB version: %v ❌ ", err)
+				return
+			}
+
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			var res *manager.SNPCertChainRes
+			err = withRetry(cmd, func() (err error) {
+				res, err = c.managerClient.SNPCertChain(cmd.Context(), &manager.SNPCertChainReq{Product: product, ChipId: chipID, ReportedTcb: reportedTCB})
+				return err
+			})
+			if err != nil {
+				printError(cmd, "Error fetching certificate chain: %v ❌ ", err)
+				return
+			}
+
+			dir := outputDir
+			if dir == "" {
+				dir = path.Join(fileSavePath, product)
+			}
+			if err := os.MkdirAll(dir, filePermisionKeys); err != nil {
+				printError(cmd, "Error while creating directory for certificates: %v ❌ ", err)
+				return
+			}
+
+			chain := res.GetChain()
+			bundle := append(encodeCertificate(chain.GetAsk()), encodeCertificate(chain.GetArk())...)
+			if err := saveToFile(path.Join(dir, caBundleName), bundle); err != nil {
+				printError(cmd, "Error while saving ARK-ASK to file: %v ❌ ", err)
+				return
+			}
+			if err := saveToFile(path.Join(dir, vcekName), encodeCertificate(chain.GetVcek())); err != nil {
+				printError(cmd, "Error while saving VCEK to file: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Certificate chain saved to %s ✔", dir))
+		},
+	}
+
+	cmd.Flags().StringVar(&product, "product", "Milan", "Product line of the chip: Milan, Genoa or Turin")
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", fmt.Sprintf("Directory the certificates are saved to, %s/<product> by default", fileSavePath))
+
+	return cmd
+}
+
+func encodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+)
+
+func TestCLI_NewSNPCertsCmd(t *testing.T) {
+	chipID := bytes.Repeat([]byte{0xab}, 64)
+	chain := &manager.SNPCertChain{Ark: []byte("ark"), Ask: []byte("ask"), Vcek: []byte("vcek")}
+
+	tests := []struct {
+		name           string
+		args           []string
+		setupMock      func(*mocks.ManagerServiceClient)
+		expectedOutput string
+		expectedDir    string
+	}{
+		{
+			name: "fetch certificate chain",
+			args: []string{hex.EncodeToString(chipID), "0x7300000000000003"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("SNPCertChain", mock.Anything, &manager.SNPCertChainReq{Product: "Milan", ChipId: chipID, ReportedTcb: 0x7300000000000003}).Return(&manager.SNPCertChainRes{Chain: chain}, nil)
+			},
+			expectedOutput: "Certificate chain saved to",
+			expectedDir:    "Milan",
+		},
+		{
+			name: "fetch certificate chain of another product",
+			args: []string{hex.EncodeToString(chipID), "3", "--product", "Genoa"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("SNPCertChain", mock.Anything, &manager.SNPCertChainReq{Product: "Genoa", ChipId: chipID, ReportedTcb: 3}).Return(&manager.SNPCertChainRes{Chain: chain}, nil)
+			},
+			expectedOutput: "Certificate chain saved to",
+			expectedDir:    "Genoa",
+		},
+		{
+			name: "certificate cache disabled",
+			args: []string{hex.EncodeToString(chipID), "3"},
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("SNPCertChain", mock.Anything, mock.Anything).Return(nil, errors.New("SEV-SNP certificate cache is disabled"))
+			},
+			expectedOutput: "Error fetching certificate chain: SEV-SNP certificate cache is disabled ❌",
+		},
+		{
+			name:           "invalid chip ID",
+			args:           []string{"chip", "3"},
+			setupMock:      func(m *mocks.ManagerServiceClient) {},
+			expectedOutput: "Error decoding chip ID",
+		},
+		{
+			name:           "invalid reported TCB",
+			args:           []string{hex.EncodeToString(chipID), "latest"},
+			setupMock:      func(m *mocks.ManagerServiceClient) {},
+			expectedOutput: "Error parsing reported TCB version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			mockCLI := &CLI{managerClient: mockClient}
+
+			cmd := mockCLI.NewSNPCertsCmd(dir)
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			_ = cmd.Execute()
+			assert.Contains(t, buf.String(), tt.expectedOutput)
+			mockClient.AssertExpectations(t)
+
+			if tt.expectedDir == "" {
+				return
+			}
+
+			bundle, err := os.ReadFile(filepath.Join(dir, tt.expectedDir, caBundleName))
+			require.NoError(t, err)
+			ask, rest := pem.Decode(bundle)
+			require.NotNil(t, ask)
+			ark, _ := pem.Decode(rest)
+			require.NotNil(t, ark)
+			assert.Equal(t, chain.Ask, ask.Bytes, "the ASK comes first, as in the bundles of the KDS")
+			assert.Equal(t, chain.Ark, ark.Bytes)
+
+			vcek, err := os.ReadFile(filepath.Join(dir, tt.expectedDir, vcekName))
+			require.NoError(t, err)
+			block, _ := pem.Decode(vcek)
+			require.NotNil(t, block)
+			assert.Equal(t, chain.Vcek, block.Bytes)
+		})
+	}
+}
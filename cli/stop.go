@@ -0,0 +1,63 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"encoding/pem"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var shutdownAgent bool
+
+func (cli *CLI) NewStopCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "stop <private_key_file_path>",
+		Short:   "Cancel the running computation as its algorithm provider",
+		Example: "stop <private_key_file_path>",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.connectErr != nil {
+				printError(cmd, "Failed to connect to agent: %v ❌ ", cli.connectErr)
+				return
+			}
+
+			privKeyFile, err := os.ReadFile(args[0])
+			if err != nil {
+				printError(cmd, "Error reading private key file: %v ❌ ", err)
+				return
+			}
+
+			pemBlock, _ := pem.Decode(privKeyFile)
+
+			privKey, err := decodeKey(pemBlock)
+			if err != nil {
+				printError(cmd, "Error decoding private key: %v ❌ ", err)
+				return
+			}
+
+			if shutdownAgent {
+				if err := cli.agentSDK.Shutdown(cmd.Context(), privKey); err != nil {
+					printError(cmd, "Error shutting down agent: %v ❌ ", err)
+					return
+				}
+
+				cmd.Println(color.New(color.FgGreen).Sprintf("Agent shut down successfully! ✔"))
+				return
+			}
+
+			if err := cli.agentSDK.Stop(cmd.Context(), privKey); err != nil {
+				printError(cmd, "Error stopping computation: %v ❌ ", err)
+				return
+			}
+
+			cmd.Println(color.New(color.FgGreen).Sprintf("Computation stopped successfully! ✔"))
+		},
+	}
+
+	cmd.Flags().BoolVar(&shutdownAgent, "shutdown", false, "Drain the agent and exit it instead of waiting for a new manifest")
+
+	return cmd
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/pkg/sdk/mocks"
+)
+
+func TestStopCmd(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, generateRSAPrivateKeyFile(keyFile))
+
+	cases := []struct {
+		desc       string
+		keyFile    string
+		shutdown   bool
+		stopErr    error
+		connectErr error
+		output     string
+	}{
+		{
+			desc:    "stop computation",
+			keyFile: keyFile,
+			output:  "Computation stopped successfully",
+		},
+		{
+			desc:    "missing private key file",
+			keyFile: filepath.Join(t.TempDir(), "missing.pem"),
+			output:  "Error reading private key file",
+		},
+		{
+			desc:    "stop failure",
+			keyFile: keyFile,
+			stopErr: errors.New("agent not expecting this operation"),
+			output:  "agent not expecting this operation",
+		},
+		{
+			desc:     "shut down agent",
+			keyFile:  keyFile,
+			shutdown: true,
+			output:   "Agent shut down successfully",
+		},
+		{
+			desc:     "shutdown failure",
+			keyFile:  keyFile,
+			shutdown: true,
+			stopErr:  errors.New("agent is shutting down"),
+			output:   "Error shutting down agent",
+		},
+		{
+			desc:       "connection error",
+			keyFile:    keyFile,
+			connectErr: errors.New("failed to connect to agent"),
+			output:     "Failed to connect to agent",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockSDK := new(mocks.SDK)
+			mockSDK.On("Stop", mock.Anything, mock.Anything).Return(tc.stopErr)
+			mockSDK.On("Shutdown", mock.Anything, mock.Anything).Return(tc.stopErr)
+
+			testCLI := CLI{agentSDK: mockSDK, connectErr: tc.connectErr}
+
+			cmd := testCLI.NewStopCmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			args := []string{tc.keyFile}
+			if tc.shutdown {
+				args = append(args, "--shutdown")
+			}
+			cmd.SetArgs(args)
+			require.NoError(t, cmd.Execute())
+
+			assert.Contains(t, buf.String(), tc.output)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/manager"
+)
+
+// mermaidTimeLayout matches the dateFormat of the rendered Mermaid charts.
+const mermaidTimeLayout = "2006-01-02T15:04:05.000-07:00"
+
+func (c *CLI) NewTimelineCmd() *cobra.Command {
+	var mermaid bool
+
+	cmd := &cobra.Command{
+		Use:     "timeline <cvm_id>",
+		Short:   "Print the phases a computation went through and the time spent in each",
+		Example: "timeline <cvm_id> --mermaid",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if c.connectErr != nil {
+				printError(cmd, "Failed to connect to manager: %v ❌ ", c.connectErr)
+				return
+			}
+			if c.managerClient == nil {
+				if err := c.InitializeManagerClient(cmd); err != nil {
+					printError(cmd, "Failed to connect to manager: %v ❌ ", err)
+					return
+				}
+			}
+			defer c.Close()
+
+			var res *manager.TimelineRes
+			err := withRetry(cmd, func() (err error) {
+				res, err = c.managerClient.Timeline(cmd.Context(), &manager.TimelineReq{CvmId: args[0]})
+				return err
+			})
+			if err != nil {
+				printError(cmd, "Error fetching timeline: %v ❌ ", err)
+				return
+			}
+
+			timeline := res.GetTimeline()
+			if mermaid {
+				cmd.Print(mermaidTimeline(timeline))
+				return
+			}
+
+			if err := printOutput(cmd, timeline, func(w io.Writer) {
+				writeTimelineTable(w, timeline)
+			}); err != nil {
+				printError(cmd, "Error printing timeline: %v ❌ ", err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&mermaid, "mermaid", false, "Print the timeline as a Mermaid Gantt chart instead of the output format")
+
+	return cmd
+}
+
+// writeTimelineTable writes the phases of the timeline, then its milestones.
+// Ongoing phases last until the timeline was generated.
+func writeTimelineTable(w io.Writer, timeline *manager.Timeline) {
+	fmt.Fprintln(w, "PHASE\tSTART\tEND\tDURATION\tDETAILS")
+	for _, phase := range timeline.GetPhases() {
+		start := phase.GetStart().AsTime()
+		end, endText := timeline.GetGeneratedAt().AsTime(), "ongoing"
+		if phase.GetEnd() != nil {
+			end = phase.GetEnd().AsTime()
+			endText = end.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", phase.GetName(), start.UTC().Format(time.RFC3339), endText, end.Sub(start).Round(time.Millisecond), phase.GetDetails())
+	}
+
+	if len(timeline.GetMilestones()) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "EVENT\tTIME\tDETAILS")
+	for _, milestone := range timeline.GetMilestones() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", milestone.GetEventType(), milestone.GetTimestamp().AsTime().UTC().Format(time.RFC3339), milestone.GetDetails())
+	}
+}
+
+// mermaidTimeline renders the timeline as a Mermaid Gantt chart, with the
+// phases in one section and the milestones in another. Ongoing phases end
+// when the timeline was generated.
+func mermaidTimeline(timeline *manager.Timeline) string {
+	var b strings.Builder
+
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title Computation %s\n", timeline.GetCvmId())
+	b.WriteString("    dateFormat YYYY-MM-DDTHH:mm:ss.SSSZ\n")
+	b.WriteString("    axisFormat %H:%M:%S\n")
+
+	if len(timeline.GetPhases()) > 0 {
+		b.WriteString("    section Phases\n")
+	}
+	for i, phase := range timeline.GetPhases() {
+		start := phase.GetStart().AsTime()
+		end, label := phase.GetEnd().AsTime(), ""
+		if phase.GetEnd() == nil {
+			end, label = timeline.GetGeneratedAt().AsTime(), ", ongoing"
+		}
+
+		fmt.Fprintf(&b, "    %s (%s%s) :phase%d, %s, %s\n", mermaidName(phase.GetName()), end.Sub(start).Round(time.Millisecond), label, i, mermaidTime(start), mermaidTime(end))
+	}
+
+	if len(timeline.GetMilestones()) > 0 {
+		b.WriteString("    section Events\n")
+	}
+	for i, milestone := range timeline.GetMilestones() {
+		fmt.Fprintf(&b, "    %s :milestone, event%d, %s, 0s\n", mermaidName(milestone.GetEventType()), i, mermaidTime(milestone.GetTimestamp().AsTime()))
+	}
+
+	return b.String()
+}
+
+func mermaidTime(t time.Time) string {
+	return t.UTC().Format(mermaidTimeLayout)
+}
+
+// mermaidName strips the characters that delimit Mermaid task fields.
+func mermaidName(name string) string {
+	return strings.NewReplacer(":", " ", "#", " ", ";", " ").Replace(name)
+}
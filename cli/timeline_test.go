@@ -0,0 +1,132 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCLI_NewTimelineCmd(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ts := func(offset time.Duration) *timestamppb.Timestamp {
+		return timestamppb.New(at.Add(offset))
+	}
+	timeline := &manager.Timeline{
+		CvmId:       "vm-123",
+		GeneratedAt: ts(5 * time.Minute),
+		Phases: []*manager.TimelinePhase{
+			{Name: "provisioning", Start: ts(0), End: ts(30 * time.Second)},
+			{Name: "running", Start: ts(30 * time.Second)},
+		},
+		Milestones: []*manager.TimelineMilestone{
+			{EventType: "dataset-attached", Timestamp: ts(time.Minute), Details: "/tmp/dataset.img"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		setupMock      func(*mocks.ManagerServiceClient)
+		args           []string
+		format         string
+		expectedOutput string
+		expectedError  string
+		expectError    bool
+	}{
+		{
+			name: "table timeline",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Timeline", mock.Anything, &manager.TimelineReq{CvmId: "vm-123"}).Return(&manager.TimelineRes{Timeline: timeline}, nil)
+			},
+			args: []string{"vm-123"},
+			expectedOutput: "PHASE         START                 END                   DURATION  DETAILS\n" +
+				"provisioning  2026-01-02T03:04:05Z  2026-01-02T03:04:35Z  30s       \n" +
+				"running       2026-01-02T03:04:35Z  ongoing               4m30s     \n" +
+				"\n" +
+				"EVENT             TIME                  DETAILS\n" +
+				"dataset-attached  2026-01-02T03:05:05Z  /tmp/dataset.img\n",
+		},
+		{
+			name: "json timeline",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Timeline", mock.Anything, &manager.TimelineReq{CvmId: "vm-123"}).Return(&manager.TimelineRes{Timeline: timeline}, nil)
+			},
+			args:           []string{"vm-123"},
+			format:         OutputJSON,
+			expectedOutput: "\"phases\": [\n    {\n      \"name\": \"provisioning\",\n      \"start\": \"2026-01-02T03:04:05Z\",\n      \"end\": \"2026-01-02T03:04:35Z\"\n    },",
+		},
+		{
+			name: "mermaid timeline",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Timeline", mock.Anything, &manager.TimelineReq{CvmId: "vm-123"}).Return(&manager.TimelineRes{Timeline: timeline}, nil)
+			},
+			args: []string{"vm-123", "--mermaid"},
+			expectedOutput: "gantt\n" +
+				"    title Computation vm-123\n" +
+				"    dateFormat YYYY-MM-DDTHH:mm:ss.SSSZ\n" +
+				"    axisFormat %H:%M:%S\n" +
+				"    section Phases\n" +
+				"    provisioning (30s) :phase0, 2026-01-02T03:04:05.000+00:00, 2026-01-02T03:04:35.000+00:00\n" +
+				"    running (4m30s, ongoing) :phase1, 2026-01-02T03:04:35.000+00:00, 2026-01-02T03:09:05.000+00:00\n" +
+				"    section Events\n" +
+				"    dataset-attached :milestone, event0, 2026-01-02T03:05:05.000+00:00, 0s\n",
+		},
+		{
+			name: "CVM not found",
+			setupMock: func(m *mocks.ManagerServiceClient) {
+				m.On("Timeline", mock.Anything, &manager.TimelineReq{CvmId: "vm-456"}).Return(nil, errors.New("not found"))
+			},
+			args:          []string{"vm-456"},
+			expectedError: "Error fetching timeline: not found ❌",
+			expectError:   true,
+		},
+		{
+			name:          "missing CVM argument",
+			setupMock:     func(m *mocks.ManagerServiceClient) {},
+			expectedError: "accepts 1 arg(s), received 0",
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.ManagerServiceClient)
+			tt.setupMock(mockClient)
+
+			if tt.format != "" {
+				OutputFormat = tt.format
+				defer func() { OutputFormat = OutputTable }()
+			}
+
+			mockCLI := &CLI{
+				managerClient: mockClient,
+			}
+
+			cmd := mockCLI.NewTimelineCmd()
+			cmd.SetArgs(tt.args)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(&buf)
+
+			err := cmd.Execute()
+
+			if tt.expectError {
+				assert.Contains(t, buf.String(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, buf.String(), tt.expectedOutput)
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/internal"
+)
+
+const (
+	uploadStatePermission    = 0o600
+	uploadStateDirPermission = 0o755
+)
+
+// uploadState records the progress of a resumable upload, keyed by the file hash.
+type uploadState struct {
+	File    string    `json:"file"`
+	Offset  int64     `json:"offset"`
+	Updated time.Time `json:"updated"`
+}
+
+func loadUploadState(path string) (uploadState, error) {
+	var state uploadState
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(data, &state)
+
+	return state, err
+}
+
+func saveUploadState(path string, state uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, uploadStatePermission)
+}
+
+// uploadResumable uploads the algorithm in acknowledged chunks, retrying dropped
+// connections from the last offset acknowledged by the agent.
+func (cli *CLI) uploadResumable(cmd *cobra.Command, ctx context.Context, algo, req *os.File, spec *agent.AlgorithmSpec, privKey any, stateDir string) error {
+	uploadID, err := internal.ChecksumHex(algo.Name())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(stateDir, uploadStateDirPermission); err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(stateDir, uploadID+".json")
+	if state, err := loadUploadState(statePath); err == nil && state.Offset > 0 {
+		cmd.Printf("Resuming upload of %s from byte %d\n", state.File, state.Offset)
+	}
+
+	onAck := func(offset int64) {
+		if err := saveUploadState(statePath, uploadState{File: algo.Name(), Offset: offset, Updated: time.Now()}); err != nil && Verbose {
+			cmd.Printf("Failed to save upload state: %v\n", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = cli.agentSDK.ResumableAlgo(ctx, algo, req, spec, privKey, uploadID, onAck)
+		if err == nil || !retryableError(err) {
+			os.Remove(statePath)
+			return err
+		}
+
+		if attempt >= uploadRetries {
+			cmd.Printf("Upload interrupted, rerun with --resume to continue from the last acknowledged chunk\n")
+			return err
+		}
+
+		cmd.Printf("Upload interrupted, retrying (%d/%d)\n", attempt+1, uploadRetries)
+		time.Sleep(backoff(attempt))
+	}
+}
@@ -26,6 +26,7 @@ const (
 	completion           = "completion"
 	filePermision        = 0o755
 	cocosDirectory       = ".cocos"
+	uploadsDirectory     = "uploads"
 )
 
 type config struct {
@@ -34,6 +35,14 @@ type config struct {
 }
 
 func main() {
+	// A failed command exits with a non-zero status once the deferred cleanup ran.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	rootCmd := &cobra.Command{
 		Use:   "cocos-cli [command]",
 		Short: "CLI application for CoCos Service API",
@@ -123,23 +132,51 @@ func main() {
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&cli.Verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().IntVar(&cli.MaxRetries, "max-retries", 3, "Number of times attestation and other small requests are retried after a transient connection failure")
+	rootCmd.PersistentFlags().StringVar(&cli.OutputFormat, "output", cli.OutputTable, "Format command results are printed in: json, table or yaml")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return cli.ValidateOutputFormat(cli.OutputFormat)
+	}
 
 	keysCmd := cliSVC.NewKeysCmd()
 	attestationCmd := cliSVC.NewAttestationCmd()
 	attestationPolicyCmd := cliSVC.NewAttestationPolicyCmd()
+	computationCmd := cliSVC.NewComputationCmd()
 
 	// Agent Commands
-	rootCmd.AddCommand(cliSVC.NewAlgorithmCmd())
+	rootCmd.AddCommand(cliSVC.NewAlgorithmCmd(path.Join(directoryCachePath, uploadsDirectory)))
 	rootCmd.AddCommand(cliSVC.NewDatasetsCmd())
 	rootCmd.AddCommand(cliSVC.NewResultsCmd())
+	rootCmd.AddCommand(cliSVC.NewStopCmd())
+	rootCmd.AddCommand(cliSVC.NewRestoreCmd())
+	rootCmd.AddCommand(cliSVC.NewApproveAttestationCmd())
+	rootCmd.AddCommand(cliSVC.NewSecretsCmd())
 	rootCmd.AddCommand(attestationCmd)
 	rootCmd.AddCommand(cliSVC.NewFileHashCmd())
 	rootCmd.AddCommand(attestationPolicyCmd)
 	rootCmd.AddCommand(keysCmd)
 	rootCmd.AddCommand(cliSVC.NewCABundleCmd(directoryCachePath))
+	rootCmd.AddCommand(cliSVC.NewSNPCertsCmd(directoryCachePath))
 	rootCmd.AddCommand(cliSVC.NewCreateVMCmd())
 	rootCmd.AddCommand(cliSVC.NewRemoveVMCmd())
+	rootCmd.AddCommand(cliSVC.NewAttachDatasetCmd())
+	rootCmd.AddCommand(cliSVC.NewGetImagesCmd())
+	rootCmd.AddCommand(cliSVC.NewTenantQuotaCmd())
+	rootCmd.AddCommand(cliSVC.NewLogsCmd())
+	rootCmd.AddCommand(cliSVC.NewConsoleCmd())
+	rootCmd.AddCommand(cliSVC.NewAuditCmd())
+	rootCmd.AddCommand(cliSVC.NewDiagnosticsCmd())
+	rootCmd.AddCommand(cliSVC.NewTimelineCmd())
+	rootCmd.AddCommand(computationCmd)
 	rootCmd.AddCommand(cliSVC.NewIMAMeasurementsCmd())
+	rootCmd.AddCommand(cliSVC.NewSelfCmd())
+	rootCmd.AddCommand(cliSVC.NewEventsCmd())
+	rootCmd.AddCommand(cliSVC.NewSelftestCmd())
+	rootCmd.AddCommand(cliSVC.NewRunCmd())
+
+	// Computation commands
+	computationCmd.AddCommand(cliSVC.NewLaunchComputationsCmd())
+	computationCmd.AddCommand(cliSVC.NewSignManifestCmd())
 
 	// Attestation commands
 	attestationCmd.AddCommand(cliSVC.NewGetAttestationCmd())
@@ -148,6 +185,7 @@ func main() {
 	// measure.
 	rootCmd.AddCommand(cmd.NewRootCmd())
 	rootCmd.AddCommand(cliSVC.NewMeasureCmd(cfg.IgvmBinaryPath))
+	rootCmd.AddCommand(cliSVC.NewIGVMBuildCmd())
 
 	// Flags
 	keysCmd.PersistentFlags().StringVarP(
@@ -166,10 +204,11 @@ func main() {
 	attestationPolicyCmd.AddCommand(cliSVC.NewAzureAttestationPolicy())
 	attestationPolicyCmd.AddCommand(cliSVC.NewTDXAttestationPolicy())
 	attestationPolicyCmd.AddCommand(cliSVC.NewExtendWithManifestCmd())
+	attestationPolicyCmd.AddCommand(cliSVC.NewCreatePolicyCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		logErrorCmd(*rootCmd, err)
-		return
+		exitCode = 1
 	}
 }
 
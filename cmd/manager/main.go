@@ -5,12 +5,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	mglog "github.com/absmach/supermq/logger"
 	"github.com/absmach/supermq/pkg/jaeger"
@@ -20,14 +24,23 @@ import (
 	"github.com/absmach/supermq/pkg/uuid"
 	"github.com/caarlos0/env/v11"
 	"github.com/go-chi/chi/v5"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/manager/api"
 	managergrpc "github.com/ultravioletrs/cocos/manager/api/grpc"
 	"github.com/ultravioletrs/cocos/manager/api/http"
+	"github.com/ultravioletrs/cocos/manager/artifacts"
+	"github.com/ultravioletrs/cocos/manager/audit"
+	"github.com/ultravioletrs/cocos/manager/broker"
 	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/manager/snpcerts"
 	"github.com/ultravioletrs/cocos/manager/tracing"
+	"github.com/ultravioletrs/cocos/manager/webhook"
 	"github.com/ultravioletrs/cocos/pkg/server"
 	grpcserver "github.com/ultravioletrs/cocos/pkg/server/grpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
@@ -35,11 +48,12 @@ import (
 )
 
 const (
-	svcName        = "manager"
-	envPrefixGRPC  = "MANAGER_GRPC_"
-	envPrefixHTTP  = "MANAGER_HTTP_"
-	envPrefixQemu  = "MANAGER_QEMU_"
-	defSvcHTTPPort = "7003"
+	svcName          = "manager"
+	envPrefixGRPC    = "MANAGER_GRPC_"
+	envPrefixHTTP    = "MANAGER_HTTP_"
+	envPrefixQemu    = "MANAGER_QEMU_"
+	envPrefixMetrics = "MANAGER_METRICS_"
+	defSvcHTTPPort   = "7003"
 )
 
 type config struct {
@@ -48,13 +62,27 @@ type config struct {
 	TraceRatio              float64 `env:"COCOS_JAEGER_TRACE_RATIO"           envDefault:"1.0"`
 	InstanceID              string  `env:"MANAGER_INSTANCE_ID"                envDefault:""`
 	AttestationPolicyBinary string  `env:"MANAGER_ATTESTATION_POLICY_BINARY"  envDefault:"../../build/attestation_policy"`
-	IgvmMeasureBinary       string  `env:"MANAGER_IGVMMEASURE_BINARY"         envDefault:"../../build/igvmmeasure"`
+	IgvmMeasureBinary       string  `env:"MANAGER_IGVMMEASURE_BINARY"         envDefault:""`
 	PcrValues               string  `env:"MANAGER_PCR_VALUES"                 envDefault:""`
 	EosVersion              string  `env:"MANAGER_EOS_VERSION"                envDefault:""`
 	MaxVMs                  int     `env:"MANAGER_MAX_VMS"                    envDefault:"10"`
+	Quota                   manager.QuotaConfig
+	Pool                    manager.PoolConfig
+	Heartbeat               manager.HeartbeatConfig
+	Logs                    manager.LogsConfig
+	Console                 manager.ConsoleConfig
+	Events                  broker.Config
+	Webhook                 webhook.Config
+	Artifacts               artifacts.Config
+	SNPCerts                snpcerts.Config
+	Audit                   audit.Config
 }
 
 func main() {
+	checkConfig := flag.Bool("check-config", false, "Validate the configuration and the host, print a report and exit")
+	backendInfo := flag.Bool("backend-info", false, "Print the backend info JSON attestation policies are generated from and exit")
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -63,7 +91,13 @@ func main() {
 		log.Fatalf("failed to load %s configuration : %s", svcName, err)
 	}
 
-	logger, err := mglog.New(os.Stdout, cfg.LogLevel)
+	// The backend info is printed to stdout, so it can be redirected to a file.
+	logOutput := os.Stdout
+	if *backendInfo {
+		logOutput = os.Stderr
+	}
+
+	logger, err := mglog.New(logOutput, cfg.LogLevel)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -90,6 +124,10 @@ func main() {
 	}()
 	tracer := tp.Tracer(svcName)
 
+	if cfg.Heartbeat.Spans, err = agentSpansClient(cfg.JaegerURL); err != nil {
+		logger.Warn(fmt.Sprintf("Agent spans are not exported: %s", err))
+	}
+
 	qemuCfg, err := qemu.NewConfig()
 	if err != nil {
 		logger.Error(fmt.Sprintf("failed to create config: %v", err))
@@ -110,6 +148,34 @@ func main() {
 		exitCode = 1
 		return
 	}
+
+	if err := artifacts.New(cfg.Artifacts, nil).Resolve(ctx, qemuCfg); err != nil {
+		logger.Error(fmt.Sprintf("failed to fetch images: %s", err))
+		exitCode = 1
+		return
+	}
+
+	if *checkConfig {
+		if err := manager.WriteCheckReport(os.Stdout, manager.CheckConfig(*qemuCfg, cfg.AttestationPolicyBinary)); err != nil {
+			logger.Error(err.Error())
+			exitCode = 1
+		}
+		return
+	}
+
+	if *backendInfo {
+		if err := printBackendInfo(*qemuCfg, cfg.AttestationPolicyBinary, cfg.IgvmMeasureBinary); err != nil {
+			logger.Error(err.Error())
+			exitCode = 1
+		}
+		return
+	}
+
+	if err := qemuCfg.Validate(); err != nil {
+		logger.Error(err.Error())
+		exitCode = 1
+		return
+	}
 	args := qemuCfg.ConstructQemuArgs()
 	logger.Info(strings.Join(args, " "))
 
@@ -125,7 +191,74 @@ func main() {
 		logger.Error(fmt.Sprintf("failed to load %s gRPC server configuration : %s", svcName, err))
 	}
 
-	svc, err := newService(logger, tracer, *qemuCfg, cfg.AttestationPolicyBinary, cfg.IgvmMeasureBinary, cfg.PcrValues, cfg.EosVersion, cfg.MaxVMs)
+	// The metrics are also served on a dedicated listener when its port is set,
+	// so that they can be scraped without exposing the manager HTTP API.
+	metricsServerConfig := smqserver.Config{}
+	if err := env.ParseWithOptions(&metricsServerConfig, env.Options{Prefix: envPrefixMetrics}); err != nil {
+		logger.Error(fmt.Sprintf("failed to load %s metrics server configuration : %s", svcName, err))
+	}
+
+	if cfg.Heartbeat.Port != 0 && qemuCfg.VSockConfig.GuestCID != 0 {
+		if cfg.Heartbeat.Listener, err = manager.HeartbeatListener(cfg.Heartbeat.Port); err != nil {
+			logger.Error(fmt.Sprintf("Failed to listen for agent heartbeats: %s", err))
+		}
+	}
+
+	// The upgrade runs once the service was shut down, so it is deferred first.
+	var (
+		upgrading bool
+		handoff   *os.File
+	)
+	defer func() {
+		if !upgrading {
+			return
+		}
+
+		if err := upgradeManager(logger, handoff); err != nil {
+			logger.Error(fmt.Sprintf("Failed to upgrade %s service: %s", svcName, err))
+			exitCode = 1
+		}
+	}()
+
+	publisher, err := broker.New(cfg.Events, svcName+"-"+cfg.InstanceID, logger)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to connect to events broker: %s", err))
+		exitCode = 1
+		return
+	}
+
+	hook, err := webhook.New(cfg.Webhook, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to configure events webhook: %s", err))
+		exitCode = 1
+		return
+	}
+
+	var snpCerts manager.SNPCertificates
+	if cfg.SNPCerts.Dir != "" {
+		cache := snpcerts.New(cfg.SNPCerts, nil)
+		if cfg.SNPCerts.CertTable != "" {
+			product, err := cache.Preload(cfg.SNPCerts.CertTable)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Failed to preload SEV-SNP certificates from %s: %s", cfg.SNPCerts.CertTable, err))
+			} else {
+				logger.Info(fmt.Sprintf("Preloaded %s SEV-SNP certificates from %s", product, cfg.SNPCerts.CertTable))
+			}
+		}
+		snpCerts = cache
+	}
+
+	auditLog, err := audit.Open(cfg.Audit)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to open audit log: %s", err))
+		exitCode = 1
+		return
+	}
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+
+	svc, err := newService(logger, tracer, *qemuCfg, cfg.AttestationPolicyBinary, cfg.IgvmMeasureBinary, cfg.PcrValues, cfg.EosVersion, cfg.MaxVMs, cfg.Quota, cfg.Pool, cfg.Heartbeat, cfg.Logs, cfg.Console, []manager.EventPublisher{publisher, hook}, snpCerts, auditLog)
 	if err != nil {
 		logger.Error(err.Error())
 		exitCode = 1
@@ -145,7 +278,11 @@ func main() {
 		manager.RegisterManagerServiceServer(srv, managergrpc.NewServer(svc))
 	}
 
-	gs := grpcserver.New(ctx, cancel, svcName, managerGRPCConfig, registerManagerServiceServer, logger, nil, nil)
+	gs := grpcserver.New(ctx, cancel, svcName, managerGRPCConfig, registerManagerServiceServer, logger, nil, nil,
+		grpcserver.WithHealthCheck(manager.ManagerService_ServiceDesc.ServiceName, func() error {
+			return manager.CheckQemu(*qemuCfg)
+		}),
+	)
 
 	hs := httpserver.NewServer(ctx, cancel, svcName, httpServerConfig, http.MakeHandler(chi.NewMux(), svcName, cfg.InstanceID), logger)
 
@@ -157,8 +294,44 @@ func main() {
 		return hs.Start()
 	})
 
+	servers := []server.Server{gs, hs}
+	if metricsServerConfig.Port != "" {
+		ms := httpserver.NewServer(ctx, cancel, svcName+"-metrics", metricsServerConfig, http.MakeHandler(chi.NewMux(), svcName, cfg.InstanceID), logger)
+		servers = append(servers, ms)
+
+		g.Go(func() error {
+			return ms.Start()
+		})
+	}
+
 	g.Go(func() error {
-		return server.StopHandler(ctx, cancel, logger, svcName, gs, hs)
+		return server.StopHandler(ctx, cancel, logger, svcName, servers...)
+	})
+
+	g.Go(func() error {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGUSR2)
+		defer signal.Stop(c)
+
+		select {
+		case <-c:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if cfg.Heartbeat.Listener != nil {
+			f, err := manager.HandoffFile(cfg.Heartbeat.Listener)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Agent heartbeats are not handed off: %s", err))
+			}
+			handoff = f
+		}
+
+		logger.Info(fmt.Sprintf("%s service draining for an upgrade", svcName))
+		upgrading = true
+		cancel()
+
+		return nil
 	})
 
 	if err := g.Wait(); err != nil {
@@ -166,15 +339,84 @@ func main() {
 	}
 }
 
-func newService(logger *slog.Logger, tracer trace.Tracer, qemuCfg qemu.Config, attestationPolicyPath string, igvmMeasurementBinaryPath string, pcrValuesFilePath string, eosVersion string, maxVMs int) (manager.Service, error) {
-	svc, err := manager.New(qemuCfg, attestationPolicyPath, igvmMeasurementBinaryPath, pcrValuesFilePath, logger, qemu.NewVM, eosVersion, maxVMs)
+// upgradeManager replaces the drained manager with the binary it was started
+// from, which an upgrade installed a new version of.
+func upgradeManager(logger *slog.Logger, handoff *os.File) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Upgrading %s service to %s", svcName, path))
+
+	return manager.Upgrade(path, handoff)
+}
+
+// printBackendInfo prints the backend info of the CVMs launched with qemuCfg as JSON.
+func printBackendInfo(qemuCfg qemu.Config, attestationPolicyBinary, igvmMeasureBinary string) error {
+	info, err := manager.GetBackendInfo(qemuCfg, attestationPolicyBinary, igvmMeasureBinary)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(info)
+}
+
+// agentSpansClient returns the client forwarding the spans the agents export over vsock to the trace collector.
+func agentSpansClient(jaegerURL url.URL) (otlptrace.Client, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(jaegerURL.Host), otlptracehttp.WithURLPath(jaegerURL.Path)}
+
+	switch jaegerURL.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("unsupported tracing URL scheme %q", jaegerURL.Scheme)
+	}
+
+	return otlptracehttp.NewClient(opts...), nil
+}
+
+func newService(logger *slog.Logger, tracer trace.Tracer, qemuCfg qemu.Config, attestationPolicyPath string, igvmMeasurementBinaryPath string, pcrValuesFilePath string, eosVersion string, maxVMs int, quotaCfg manager.QuotaConfig, poolCfg manager.PoolConfig, heartbeatCfg manager.HeartbeatConfig, logsCfg manager.LogsConfig, consoleCfg manager.ConsoleConfig, publishers []manager.EventPublisher, snpCerts manager.SNPCertificates, auditLog *audit.Log) (manager.Service, error) {
+	svc, err := manager.New(qemuCfg, attestationPolicyPath, igvmMeasurementBinaryPath, pcrValuesFilePath, logger, qemu.NewVM, eosVersion, maxVMs, quotaCfg, poolCfg, heartbeatCfg, logsCfg, consoleCfg, publishers, snpCerts)
 	if err != nil {
 		return nil, err
 	}
 	svc = api.LoggingMiddleware(svc, logger)
 	counter, latency := prometheus.MakeMetrics(svcName, "api")
-	svc = api.MetricsMiddleware(svc, counter, latency)
+	svc = api.MetricsMiddleware(svc, counter, latency, makeVMMetrics())
 	svc = tracing.New(svc, tracer)
+	if auditLog != nil {
+		svc = api.AuditMiddleware(svc, auditLog, manager.NewAdmins(quotaCfg.Admins), logger)
+	}
 
 	return svc, nil
 }
+
+// makeVMMetrics registers the metrics of the CVMs the manager runs.
+func makeVMMetrics() api.VMMetrics {
+	return api.VMMetrics{
+		Active: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: svcName,
+			Subsystem: "vms",
+			Name:      "active",
+			Help:      "Number of CVMs created and not removed yet.",
+		}, nil),
+		BootTime: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: svcName,
+			Subsystem: "vms",
+			Name:      "boot_time_seconds",
+			Help:      "Time it takes to create and boot a CVM.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.5, 2, 10),
+		}, nil),
+		BrokenConnections: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "vms",
+			Name:      "broken_connections_total",
+			Help:      "Number of times a CVM agent stopped sending heartbeats.",
+		}, nil),
+	}
+}
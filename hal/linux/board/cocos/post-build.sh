@@ -14,6 +14,7 @@ fi
 # Create the mount points
 mkdir -p ${TARGET_DIR}/etc/certs
 mkdir -p ${TARGET_DIR}/etc/cocos
+mkdir -p ${TARGET_DIR}/var/lib/cocos/checkpoints
 
 # Ensure /etc/fstab exists
 if [ ! -f "${TARGET_DIR}/etc/fstab" ]; then
@@ -26,3 +27,7 @@ echo "certs_share /etc/certs 9p trans=virtio,version=9p2000.L,cache=mmap 0 0" >>
 
 grep -q "env_share /etc/cocos" ${TARGET_DIR}/etc/fstab || \
 echo "env_share /etc/cocos 9p trans=virtio,version=9p2000.L,cache=mmap 0 0" >> "${TARGET_DIR}/etc/fstab"
+
+# The checkpoint share is optional, the manager only adds it with a checkpoint mount.
+grep -q "checkpoint_share /var/lib/cocos/checkpoints" ${TARGET_DIR}/etc/fstab || \
+echo "checkpoint_share /var/lib/cocos/checkpoints 9p trans=virtio,version=9p2000.L,cache=mmap,nofail 0 0" >> "${TARGET_DIR}/etc/fstab"
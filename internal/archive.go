@@ -0,0 +1,321 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/sha3"
+)
+
+// Archive formats ExtractArchive detects from the content of an archive.
+const (
+	ArchiveZip     = "zip"
+	ArchiveTar     = "tar"
+	ArchiveTarGzip = "tar.gz"
+	ArchiveTarZstd = "tar.zst"
+)
+
+var (
+	// ErrUnsupportedArchive indicates data that is not a zip, tar, tar.gz or tar.zst archive.
+	ErrUnsupportedArchive = errors.New("unsupported archive, expected zip, tar, tar.gz or tar.zst")
+	// ErrUnsafeArchive indicates an archive with entries outside of the
+	// extraction directory, links, special files or duplicate entries.
+	ErrUnsafeArchive = errors.New("unsafe archive entry")
+	// ErrArchiveLimit indicates an archive that expands past its size or file limits.
+	ErrArchiveLimit = errors.New("archive exceeds its extraction limits")
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	tarMagic      = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// ArchiveLimits bound what an archive expands to, zero values leave a limit unbounded.
+type ArchiveLimits struct {
+	// MaxSize is the total size in bytes of the extracted files.
+	MaxSize uint64
+	// MaxFiles is the number of extracted files.
+	MaxFiles uint64
+}
+
+// ArchiveInfo describes an extracted archive.
+type ArchiveInfo struct {
+	Format string
+	Files  uint64
+	// Size is the total size in bytes of the extracted files.
+	Size uint64
+	// ContentHash is the SHA3-256 hash of the sorted "<sha3-256 hex>  <path>\n"
+	// lines of the extracted files, which does not depend on the archive format,
+	// compression or entry order.
+	ContentHash [32]byte
+}
+
+// DetectArchive returns the format of the archive, or an empty string when
+// the data is not an archive ExtractArchive supports.
+func DetectArchive(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, zipMagic), bytes.HasPrefix(data, emptyZipMagic):
+		return ArchiveZip
+	case bytes.HasPrefix(data, gzipMagic):
+		return ArchiveTarGzip
+	case bytes.HasPrefix(data, zstdMagic):
+		return ArchiveTarZstd
+	case isTar(data):
+		return ArchiveTar
+	default:
+		return ""
+	}
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= tarMagicOffset+len(tarMagic) && bytes.Equal(data[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// ArchiveName returns the name of the archive without its archive extension,
+// e.g. data for data.tar.gz.
+func ArchiveName(name string) string {
+	for _, ext := range []string{".tar.gz", ".tar.zst", ".tgz", ".tzst"} {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// ExtractArchive extracts the archive into dir, detecting its format from its
+// content. Entries must be local paths of regular files or directories, and the
+// extracted files must fit in the limits, which are checked against the bytes
+// actually decompressed rather than the sizes the archive claims. An empty dir
+// only verifies and hashes the archive.
+func ExtractArchive(data []byte, dir string, limits ArchiveLimits) (ArchiveInfo, error) {
+	info := ArchiveInfo{Format: DetectArchive(data)}
+	x := &extractor{dir: dir, limits: limits, files: make(map[string]string)}
+
+	var err error
+	switch info.Format {
+	case ArchiveZip:
+		err = x.zip(data)
+	case ArchiveTar:
+		err = x.tar(bytes.NewReader(data))
+	case ArchiveTarGzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			defer r.Close()
+			err = x.tar(r)
+		}
+	case ArchiveTarZstd:
+		var r *zstd.Decoder
+		if r, err = zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1)); err == nil {
+			defer r.Close()
+			err = x.tar(r)
+		}
+	default:
+		err = ErrUnsupportedArchive
+	}
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	info.Files = uint64(len(x.files))
+	info.Size = x.size
+	info.ContentHash = x.contentHash()
+
+	return info, nil
+}
+
+type extractor struct {
+	dir    string
+	limits ArchiveLimits
+	size   uint64
+	// files are the hex encoded hashes of the extracted files, by path.
+	files map[string]string
+	dirs  []string
+}
+
+func (x *extractor) zip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := x.mkdir(f.Name); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = x.file(f.Name, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: %s is a %s", ErrUnsafeArchive, f.Name, fileType(mode))
+		}
+	}
+
+	return nil
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if len(x.files) == 0 && len(x.dirs) == 0 {
+				return fmt.Errorf("%w: %v", ErrUnsupportedArchive, err)
+			}
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := x.mkdir(hdr.Name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := x.file(hdr.Name, tr); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+		default:
+			return fmt.Errorf("%w: %s is a %s", ErrUnsafeArchive, hdr.Name, fileType(hdr.FileInfo().Mode()))
+		}
+	}
+}
+
+// entryPath returns the slash separated path of the entry relative to the
+// extraction directory, or an error if it escapes it.
+func entryPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if strings.ContainsRune(name, '\\') || !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", fmt.Errorf("%w: %s is outside of the extraction directory", ErrUnsafeArchive, name)
+	}
+
+	return clean, nil
+}
+
+func (x *extractor) mkdir(name string) error {
+	p, err := entryPath(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := x.files[p]; ok {
+		return fmt.Errorf("%w: %s is both a file and a directory", ErrUnsafeArchive, name)
+	}
+	x.dirs = append(x.dirs, p)
+
+	if x.dir == "" {
+		return nil
+	}
+
+	return os.MkdirAll(filepath.Join(x.dir, filepath.FromSlash(p)), 0o755)
+}
+
+func (x *extractor) file(name string, r io.Reader) error {
+	p, err := entryPath(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := x.files[p]; ok {
+		return fmt.Errorf("%w: duplicate entry %s", ErrUnsafeArchive, name)
+	}
+	if x.limits.MaxFiles > 0 && uint64(len(x.files))+1 > x.limits.MaxFiles {
+		return fmt.Errorf("%w: more than %d files", ErrArchiveLimit, x.limits.MaxFiles)
+	}
+
+	hash := sha3.New256()
+	w := io.Writer(hash)
+	if x.dir != "" {
+		dst := filepath.Join(x.dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		// O_EXCL never follows a file already in the directory.
+		f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = io.MultiWriter(f, hash)
+	}
+
+	// Reading one byte past the remaining budget detects archives that expand past it.
+	limit := int64(-1)
+	if x.limits.MaxSize > 0 {
+		limit = int64(x.limits.MaxSize-x.size) + 1
+		r = io.LimitReader(r, limit)
+	}
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if limit >= 0 && n == limit {
+		return fmt.Errorf("%w: more than %d bytes", ErrArchiveLimit, x.limits.MaxSize)
+	}
+
+	x.size += uint64(n)
+	x.files[p] = hex.EncodeToString(hash.Sum(nil))
+
+	return nil
+}
+
+func (x *extractor) contentHash() [32]byte {
+	paths := make([]string, 0, len(x.files))
+	for p := range x.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	hash := sha3.New256()
+	for _, p := range paths {
+		fmt.Fprintf(hash, "%s  %s\n", x.files[p], p)
+	}
+
+	var sum [32]byte
+	copy(sum[:], hash.Sum(nil))
+
+	return sum
+}
+
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return "symbolic link"
+	case mode.Type() == 0:
+		return "hard link"
+	default:
+		return "special file"
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type archiveEntry struct {
+	name    string
+	content string
+	link    bool
+}
+
+var archiveFiles = []archiveEntry{
+	{name: "b.csv", content: "second"},
+	{name: "dir/a.csv", content: "first"},
+}
+
+func zipArchive(t *testing.T, entries []archiveEntry) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.link {
+			hdr.SetMode(os.ModeSymlink | 0o777)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip archive: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func tarArchive(t *testing.T, entries []archiveEntry) []byte {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.link {
+			hdr = &tar.Header{Name: e.name, Linkname: e.content, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if !e.link {
+			if _, err := tw.Write([]byte(e.content)); err != nil {
+				t.Fatalf("Failed to write tar entry: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar archive: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatalf("Failed to gzip data: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, data []byte) []byte {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("Failed to create zstd encoder: %v", err)
+	}
+	defer enc.Close()
+
+	return enc.EncodeAll(data, nil)
+}
+
+func TestExtractArchive(t *testing.T) {
+	tarData := tarArchive(t, archiveFiles)
+
+	cases := []struct {
+		desc   string
+		data   []byte
+		format string
+	}{
+		{desc: "zip", data: zipArchive(t, archiveFiles), format: ArchiveZip},
+		{desc: "tar", data: tarData, format: ArchiveTar},
+		{desc: "tar.gz", data: gzipData(t, tarData), format: ArchiveTarGzip},
+		{desc: "tar.zst", data: zstdData(t, tarData), format: ArchiveTarZstd},
+	}
+
+	var contentHash [32]byte
+	for i, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			info, err := ExtractArchive(tc.data, dir, ArchiveLimits{MaxSize: 11, MaxFiles: 2})
+			if err != nil {
+				t.Fatalf("ExtractArchive failed: %v", err)
+			}
+
+			if info.Format != tc.format || info.Files != 2 || info.Size != 11 {
+				t.Errorf("ExtractArchive() = %+v, want format %s with 2 files of 11 bytes", info, tc.format)
+			}
+			if i > 0 && info.ContentHash != contentHash {
+				t.Errorf("Content hash of %s differs from the zip archive", tc.desc)
+			}
+			contentHash = info.ContentHash
+
+			for _, e := range archiveFiles {
+				content, err := os.ReadFile(filepath.Join(dir, e.name))
+				if err != nil {
+					t.Fatalf("Failed to read extracted file: %v", err)
+				}
+				if string(content) != e.content {
+					t.Errorf("Extracted %s = %q, want %q", e.name, content, e.content)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArchiveVerifyOnly(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	info, err := ExtractArchive(zipArchive(t, archiveFiles), "", ArchiveLimits{})
+	if err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	if info.Files != 2 {
+		t.Errorf("Files = %d, want 2", info.Files)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Verifying the archive wrote %d entries", len(entries))
+	}
+}
+
+func TestExtractArchiveErrors(t *testing.T) {
+	cases := []struct {
+		desc   string
+		data   []byte
+		limits ArchiveLimits
+		err    error
+	}{
+		{
+			desc: "not an archive",
+			data: []byte("plain text"),
+			err:  ErrUnsupportedArchive,
+		},
+		{
+			desc: "gzip without tar",
+			data: gzipData(t, []byte("plain text")),
+			err:  ErrUnsupportedArchive,
+		},
+		{
+			desc: "zip slip",
+			data: zipArchive(t, []archiveEntry{{name: "../escape.csv", content: "x"}}),
+			err:  ErrUnsafeArchive,
+		},
+		{
+			desc: "absolute path",
+			data: tarArchive(t, []archiveEntry{{name: "/etc/escape.csv", content: "x"}}),
+			err:  ErrUnsafeArchive,
+		},
+		{
+			desc: "symbolic link",
+			data: tarArchive(t, []archiveEntry{{name: "link", content: "/etc/passwd", link: true}}),
+			err:  ErrUnsafeArchive,
+		},
+		{
+			desc: "zip symbolic link",
+			data: zipArchive(t, []archiveEntry{{name: "link", content: "/etc/passwd", link: true}}),
+			err:  ErrUnsafeArchive,
+		},
+		{
+			desc: "duplicate entry",
+			data: tarArchive(t, []archiveEntry{{name: "a.csv", content: "x"}, {name: "./a.csv", content: "y"}}),
+			err:  ErrUnsafeArchive,
+		},
+		{
+			desc:   "too large",
+			data:   zipArchive(t, archiveFiles),
+			limits: ArchiveLimits{MaxSize: 10},
+			err:    ErrArchiveLimit,
+		},
+		{
+			desc:   "too many files",
+			data:   zipArchive(t, archiveFiles),
+			limits: ArchiveLimits{MaxFiles: 1},
+			err:    ErrArchiveLimit,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := ExtractArchive(tc.data, dir, tc.limits); !errors.Is(err, tc.err) {
+				t.Errorf("ExtractArchive() error = %v, want %v", err, tc.err)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.csv")); err == nil {
+				t.Errorf("Archive entry was extracted outside of the directory")
+			}
+		})
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	cases := map[string]string{
+		"data.zip":     "data",
+		"data.tar.gz":  "data",
+		"data.tgz":     "data",
+		"data.tar.zst": "data",
+		"data.tar":     "data",
+		".tar.gz":      ".tar",
+	}
+
+	for name, want := range cases {
+		if got := ArchiveName(name); got != want {
+			t.Errorf("ArchiveName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cmdline maps agent environment variables to and from kernel command
+// line parameters. Configuration passed on the kernel command line is part of
+// the launch measurement, so it can be verified through attestation.
+package cmdline
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Prefix marks the kernel parameters that carry agent configuration.
+	Prefix = "cocos."
+	// ProcCmdline is the kernel command line of the running system.
+	ProcCmdline = "/proc/cmdline"
+)
+
+var ErrInvalidParam = errors.New("kernel parameter name or value contains an unsupported character")
+
+// Format renders environment variables as kernel parameters, e.g. AGENT_LOG_LEVEL=info
+// becomes cocos.agent_log_level=info. Parameters are sorted so that the command line,
+// and with it the launch measurement, does not depend on map ordering.
+func Format(env map[string]string) (string, error) {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := env[k]
+		if k == "" || strings.ContainsAny(k, " =\"\n") || strings.ContainsAny(v, "\"\n") {
+			return "", ErrInvalidParam
+		}
+
+		if strings.Contains(v, " ") {
+			v = `"` + v + `"`
+		}
+
+		params = append(params, Prefix+strings.ToLower(k)+"="+v)
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// Parse returns the cocos parameters of a kernel command line as environment variables.
+// The manager passes the command line to QEMU quoted as a whole, it is unquoted first.
+func Parse(cmdline string) map[string]string {
+	env := make(map[string]string)
+
+	if unquoted, err := strconv.Unquote(strings.TrimSpace(cmdline)); err == nil {
+		cmdline = unquoted
+	}
+
+	for _, param := range split(cmdline) {
+		if !strings.HasPrefix(param, Prefix) {
+			continue
+		}
+
+		key, value, _ := strings.Cut(strings.TrimPrefix(param, Prefix), "=")
+		if key == "" {
+			continue
+		}
+
+		env[strings.ToUpper(key)] = value
+	}
+
+	return env
+}
+
+// Load returns the cocos parameters of the kernel command line at path as
+// environment variables. A missing command line file has no parameters.
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	return Parse(string(data)), nil
+}
+
+// LoadEnv exports the cocos parameters of the kernel command line at path as
+// environment variables. Measured parameters take precedence over variables that
+// are already set. A missing command line file is not an error.
+func LoadEnv(path string) error {
+	env, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// split breaks the command line on spaces outside of double quotes and removes the quotes.
+func split(cmdline string) []string {
+	var params []string
+	var cur strings.Builder
+	inQuote := false
+
+	for _, r := range strings.TrimSpace(cmdline) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuote:
+			if cur.Len() > 0 {
+				params = append(params, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+
+	if cur.Len() > 0 {
+		params = append(params, cur.String())
+	}
+
+	return params
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package cmdline
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		desc     string
+		env      map[string]string
+		expected string
+		err      error
+	}{
+		{
+			desc:     "sorted parameters",
+			env:      map[string]string{"AGENT_LOG_LEVEL": "info", "AGENT_CVM_GRPC_URL": "10.0.2.2:7001"},
+			expected: "cocos.agent_cvm_grpc_url=10.0.2.2:7001 cocos.agent_log_level=info",
+		},
+		{
+			desc:     "value with spaces",
+			env:      map[string]string{"AGENT_OS_BUILD": "a b"},
+			expected: `cocos.agent_os_build="a b"`,
+		},
+		{
+			desc:     "empty value",
+			env:      map[string]string{"AGENT_CVM_CA_URL": ""},
+			expected: "cocos.agent_cvm_ca_url=",
+		},
+		{
+			desc: "value with quote",
+			env:  map[string]string{"AGENT_LOG_LEVEL": `in"fo`},
+			err:  ErrInvalidParam,
+		},
+		{
+			desc: "key with space",
+			env:  map[string]string{"AGENT LOG": "info"},
+			err:  ErrInvalidParam,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmdline, err := Format(tc.env)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, cmdline)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cmdline := `quiet console=null cocos.agent_log_level=info cocos.agent_os_build="a b" cocos.=x cocos.agent_feature` + "\n"
+
+	env := Parse(cmdline)
+
+	assert.Equal(t, map[string]string{
+		"AGENT_LOG_LEVEL": "info",
+		"AGENT_OS_BUILD":  "a b",
+		"AGENT_FEATURE":   "",
+	}, env)
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	env := map[string]string{
+		"AGENT_LOG_LEVEL":    "debug",
+		"AGENT_CVM_GRPC_URL": "192.168.100.1:7001",
+		"AGENT_OS_BUILD":     "UVC build 1",
+	}
+
+	cmdline, err := Format(env)
+	require.NoError(t, err)
+
+	assert.Equal(t, env, Parse("quiet console=null "+cmdline))
+	assert.Equal(t, env, Parse(strconv.Quote("quiet console=null "+cmdline)+"\n"), "command line quoted as a whole")
+}
+
+func TestLoadEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmdline")
+	require.NoError(t, os.WriteFile(path, []byte("quiet cocos.agent_log_level=warn\n"), 0o644))
+
+	t.Setenv("AGENT_LOG_LEVEL", "debug")
+
+	require.NoError(t, LoadEnv(path))
+	assert.Equal(t, "warn", os.Getenv("AGENT_LOG_LEVEL"))
+
+	assert.NoError(t, LoadEnv(filepath.Join(t.TempDir(), "missing")))
+}
@@ -0,0 +1,201 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// CodecDeflate compresses archive entries with DEFLATE, readable by any zip tool.
+	CodecDeflate = "deflate"
+	// CodecZstd compresses archive entries with Zstandard, which is faster for large results.
+	CodecZstd = "zstd"
+
+	// ZstdMethod is the zip compression method of Zstandard entries.
+	ZstdMethod uint16 = 93
+)
+
+// ErrUnsupportedCodec indicates a compression codec other than deflate or zstd.
+var ErrUnsupportedCodec = errors.New("unsupported compression codec, expected deflate or zstd")
+
+func init() {
+	zip.RegisterDecompressor(ZstdMethod, func(r io.Reader) io.ReadCloser {
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return io.NopCloser(errReader{err})
+		}
+		return dec.IOReadCloser()
+	})
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// ValidateCodec checks that codec is supported, an empty codec selects deflate.
+func ValidateCodec(codec string) error {
+	switch codec {
+	case "", CodecDeflate, CodecZstd:
+		return nil
+	default:
+		return ErrUnsupportedCodec
+	}
+}
+
+// CodecForMethod returns the codec of a zip compression method, or an empty string for stored entries.
+func CodecForMethod(method uint16) string {
+	switch method {
+	case zip.Deflate:
+		return CodecDeflate
+	case ZstdMethod:
+		return CodecZstd
+	default:
+		return ""
+	}
+}
+
+type compressedEntry struct {
+	header *zip.FileHeader
+	data   []byte
+}
+
+// ZipDirectoryParallel zips the directory compressing its files concurrently with
+// the given codec. The number of workers is capped by the number of CPUs, zero or
+// a negative value uses all of them.
+func ZipDirectoryParallel(sourceDir, codec string, workers int) ([]byte, error) {
+	if codec == "" {
+		codec = CodecDeflate
+	}
+	if err := ValidateCodec(codec); err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 || workers > runtime.NumCPU() {
+		workers = runtime.NumCPU()
+	}
+
+	var paths []string
+	var entries []*compressedEntry
+
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+
+		paths = append(paths, path)
+		entries = append(entries, &compressedEntry{header: header})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var g errgroup.Group
+	g.SetLimit(workers)
+
+	for i := range entries {
+		g.Go(func() error {
+			return compressFile(paths[i], codec, entries[i])
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	for _, entry := range entries {
+		w, err := zipWriter.CreateRaw(entry.header)
+		if err != nil {
+			zipWriter.Close()
+			return nil, err
+		}
+
+		if _, err := w.Write(entry.data); err != nil {
+			zipWriter.Close()
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// compressFile compresses the file into entry and fills in the sizes and checksum of its header.
+func compressFile(path, codec string, entry *compressedEntry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := new(bytes.Buffer)
+
+	var w io.WriteCloser
+	switch codec {
+	case CodecZstd:
+		entry.header.Method = ZstdMethod
+		w, err = zstd.NewWriter(buf, zstd.WithEncoderConcurrency(1))
+	default:
+		entry.header.Method = zip.Deflate
+		w, err = flate.NewWriter(buf, flate.DefaultCompression)
+	}
+	if err != nil {
+		return err
+	}
+
+	checksum := crc32.NewIEEE()
+	size, err := io.Copy(io.MultiWriter(w, checksum), f)
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	entry.data = buf.Bytes()
+	entry.header.CRC32 = checksum.Sum32()
+	entry.header.UncompressedSize64 = uint64(size)
+	entry.header.CompressedSize64 = uint64(len(entry.data))
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZipDirectoryParallel(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"file1.txt":        strings.Repeat("Content of file 1\n", 1000),
+		"file2.txt":        "Content of file 2",
+		"subdir/file3.txt": "Content of file 3 in subdirectory",
+		"empty.txt":        "",
+	}
+
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		codec   string
+		workers int
+		method  uint16
+	}{
+		{name: "default codec", codec: "", workers: 0, method: zip.Deflate},
+		{name: "deflate single worker", codec: CodecDeflate, workers: 1, method: zip.Deflate},
+		{name: "zstd", codec: CodecZstd, workers: 4, method: ZstdMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zipData, err := ZipDirectoryParallel(tempDir, tt.codec, tt.workers)
+			if err != nil {
+				t.Fatalf("ZipDirectoryParallel failed: %v", err)
+			}
+
+			reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+			if err != nil {
+				t.Fatalf("Failed to read zip: %v", err)
+			}
+			if len(reader.File) != len(testFiles) {
+				t.Fatalf("Expected %d files, got %d", len(testFiles), len(reader.File))
+			}
+			for _, f := range reader.File {
+				if f.Method != tt.method {
+					t.Errorf("File %s compressed with method %d, expected %d", f.Name, f.Method, tt.method)
+				}
+			}
+
+			unzipDir := t.TempDir()
+			if err := UnzipFromMemory(zipData, unzipDir); err != nil {
+				t.Fatalf("UnzipFromMemory failed: %v", err)
+			}
+
+			for path, expectedContent := range testFiles {
+				content, err := os.ReadFile(filepath.Join(unzipDir, path))
+				if err != nil {
+					t.Errorf("Failed to read unzipped file %s: %v", path, err)
+					continue
+				}
+				if string(content) != expectedContent {
+					t.Errorf("Content mismatch for file %s", path)
+				}
+			}
+		})
+	}
+}
+
+func TestZipDirectoryParallel_InvalidInput(t *testing.T) {
+	if _, err := ZipDirectoryParallel(t.TempDir(), "lz4", 0); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("Expected ErrUnsupportedCodec, got %v", err)
+	}
+
+	if _, err := ZipDirectoryParallel("/non/existent/directory", CodecDeflate, 0); err == nil {
+		t.Error("Expected error for non-existent directory, got nil")
+	}
+}
+
+func TestCodecForMethod(t *testing.T) {
+	tests := map[uint16]string{
+		zip.Store:   "",
+		zip.Deflate: CodecDeflate,
+		ZstdMethod:  CodecZstd,
+	}
+
+	for method, expected := range tests {
+		if codec := CodecForMethod(method); codec != expected {
+			t.Errorf("CodecForMethod(%d) = %q, expected %q", method, codec, expected)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// FetchConfig requests the agent configuration from the manager on a
+// connection returned by dial and returns it as environment variables.
+func FetchConfig(ctx context.Context, dial func() (net.Conn, error)) (map[string]string, error) {
+	payload, err := deliver(ctx, dial, msgConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg map[string]string
+	if err := json.Unmarshal(payload, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedMessage, err)
+	}
+
+	return cfg, nil
+}
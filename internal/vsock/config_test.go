@@ -0,0 +1,67 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchConfig(t *testing.T) {
+	cases := []struct {
+		desc   string
+		opts   []MonitorOption
+		config map[string]string
+		err    bool
+	}{
+		{
+			desc: "monitor serving configuration",
+			opts: []MonitorOption{WithConfig(func(id uint32) map[string]string {
+				assert.Equal(t, uint32(testCID), id)
+				return map[string]string{"AGENT_LOG_LEVEL": "info"}
+			})},
+			config: map[string]string{"AGENT_LOG_LEVEL": "info"},
+		},
+		{
+			desc:   "monitor serving empty configuration",
+			opts:   []MonitorOption{WithConfig(func(uint32) map[string]string { return map[string]string{} })},
+			config: map[string]string{},
+		},
+		{
+			desc: "monitor without configuration for the VM",
+			opts: []MonitorOption{WithConfig(func(uint32) map[string]string { return nil })},
+			err:  true,
+		},
+		{
+			desc: "monitor not serving configuration",
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			l := listen(t)
+			monitor := NewMonitor(testInterval, 3, slog.Default(), tc.opts...)
+			go func() {
+				_ = monitor.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+			}()
+
+			dial := func() (net.Conn, error) { return net.Dial("tcp", l.Addr().String()) }
+			cfg, err := FetchConfig(context.Background(), dial)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.config, cfg)
+			assert.Empty(t, monitor.Unhealthy(time.Now().Add(time.Hour)), "configuration requests are not heartbeats")
+		})
+	}
+}
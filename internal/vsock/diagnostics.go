@@ -0,0 +1,17 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"net"
+)
+
+// SendDiagnostics sends the diagnostic snapshot of the agent to the manager on
+// a connection returned by dial and waits for the manager to acknowledge it.
+// The snapshot carries the trace context of ctx, the one of the failed run.
+func SendDiagnostics(ctx context.Context, dial func() (net.Conn, error), snapshot []byte) error {
+	_, err := deliver(ctx, dial, msgDiagnostics, snapshot)
+
+	return err
+}
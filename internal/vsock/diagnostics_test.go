@@ -0,0 +1,67 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSendDiagnostics(t *testing.T) {
+	cases := []struct {
+		desc   string
+		accept bool
+		err    bool
+	}{
+		{
+			desc:   "monitor accepting diagnostics",
+			accept: true,
+		},
+		{
+			desc: "monitor not accepting diagnostics",
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			traced := make(chan trace.SpanContext, 1)
+
+			var opts []MonitorOption
+			if tc.accept {
+				opts = append(opts, WithDiagnostics(func(ctx context.Context, id uint32, snapshot []byte) {
+					assert.Equal(t, uint32(testCID), id)
+					traced <- trace.SpanContextFromContext(ctx)
+					received <- snapshot
+				}))
+			}
+
+			l := listen(t)
+			monitor := NewMonitor(testInterval, 3, slog.Default(), opts...)
+			go func() {
+				_ = monitor.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+			}()
+
+			dial := func() (net.Conn, error) { return net.Dial("tcp", l.Addr().String()) }
+			ctx := trace.ContextWithRemoteSpanContext(context.Background(), testSpanContext(t))
+			err := SendDiagnostics(ctx, dial, []byte(`{"state":"Running"}`))
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `{"state":"Running"}`, string(<-received))
+			assert.Equal(t, testSpanContext(t), <-traced, "snapshots carry the trace of the failed run")
+			assert.Empty(t, monitor.Unhealthy(time.Now().Add(time.Hour)), "diagnostics are not heartbeats")
+		})
+	}
+}
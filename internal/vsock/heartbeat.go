@@ -0,0 +1,427 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	msgHeartbeat messageType = iota + 1
+	msgAck
+	msgSpans
+	msgDiagnostics
+	msgConfig
+
+	// traceContextSize is the size of the encoded trace context: trace ID,
+	// span ID and trace flags, as in the W3C traceparent.
+	traceContextSize = 16 + 8 + 1
+	// headerSize is the size of the encoded message header: type, sequence
+	// number, send time, payload length and trace context.
+	headerSize = 1 + 8 + 8 + 4 + traceContextSize
+	// maxPayloadSize bounds the payload a peer can make the other side allocate.
+	maxPayloadSize = 4 << 20
+)
+
+var (
+	// ErrUnexpectedMessage indicates a message that does not follow the heartbeat protocol.
+	ErrUnexpectedMessage = errors.New("unexpected heartbeat message")
+
+	errInvalidInterval = errors.New("heartbeat interval must be positive")
+	errPayloadTooLarge = errors.New("message payload too large")
+	errNoConfig        = errors.New("no configuration for the VM")
+)
+
+type messageType uint8
+
+// message is a frame of the protocol. The header of every message carries a
+// trace context: the one of the computation the manager runs on the VM in an
+// acknowledgement, the one of the failed run in a diagnostics message. The
+// payload of a spans message is an OTLP trace export request, the payload of a
+// diagnostics message the snapshot an agent captured on a fatal error and the
+// payload of the acknowledgement of a config message the agent configuration.
+type message struct {
+	typ     messageType
+	seq     uint64
+	sentAt  int64
+	trace   trace.SpanContext
+	payload []byte
+}
+
+func writeMessage(w io.Writer, m message) error {
+	if len(m.payload) > maxPayloadSize {
+		return errPayloadTooLarge
+	}
+
+	buf := make([]byte, headerSize+len(m.payload))
+	buf[0] = byte(m.typ)
+	binary.BigEndian.PutUint64(buf[1:], m.seq)
+	binary.BigEndian.PutUint64(buf[9:], uint64(m.sentAt))
+	binary.BigEndian.PutUint32(buf[17:], uint32(len(m.payload)))
+	if m.trace.IsValid() {
+		traceID, spanID := m.trace.TraceID(), m.trace.SpanID()
+		copy(buf[21:], traceID[:])
+		copy(buf[37:], spanID[:])
+		buf[45] = byte(m.trace.TraceFlags())
+	}
+	copy(buf[headerSize:], m.payload)
+
+	_, err := w.Write(buf)
+
+	return err
+}
+
+func readMessage(r io.Reader) (message, error) {
+	var buf [headerSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return message{}, err
+	}
+
+	m := message{
+		typ:    messageType(buf[0]),
+		seq:    binary.BigEndian.Uint64(buf[1:]),
+		sentAt: int64(binary.BigEndian.Uint64(buf[9:])),
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(buf[21:37]),
+		SpanID:     trace.SpanID(buf[37:45]),
+		TraceFlags: trace.TraceFlags(buf[45]),
+		Remote:     true,
+	})
+	if sc.IsValid() {
+		m.trace = sc
+	}
+
+	size := binary.BigEndian.Uint32(buf[17:])
+	if size > maxPayloadSize {
+		return message{}, errPayloadTooLarge
+	}
+	if size > 0 {
+		m.payload = make([]byte, size)
+		if _, err := io.ReadFull(r, m.payload); err != nil {
+			return message{}, err
+		}
+	}
+
+	return m, nil
+}
+
+// Heartbeater sends heartbeats to the manager and measures their round trip time.
+type Heartbeater struct {
+	dial     func() (net.Conn, error)
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	seq     uint64
+	rtt     time.Duration
+	spanCtx trace.SpanContext
+}
+
+// NewHeartbeater returns a heartbeater sending a heartbeat every interval on the connections returned by dial.
+func NewHeartbeater(dial func() (net.Conn, error), interval time.Duration, logger *slog.Logger) *Heartbeater {
+	return &Heartbeater{
+		dial:     dial,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run sends heartbeats until ctx is done, reconnecting after failures.
+// Sequence numbers keep increasing across connections, so the manager can
+// tell how many heartbeats were lost.
+func (h *Heartbeater) Run(ctx context.Context) error {
+	if h.interval <= 0 {
+		return errInvalidInterval
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		if conn == nil {
+			c, err := h.dial()
+			if err != nil {
+				h.logger.Debug("failed to connect to the heartbeat monitor", "error", err)
+			}
+			conn = c
+		}
+
+		if conn != nil {
+			if err := h.beat(conn); err != nil {
+				h.logger.Warn("failed to send heartbeat", "error", err)
+				conn.Close()
+				conn = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RTT returns the round trip time of the last acknowledged heartbeat.
+func (h *Heartbeater) RTT() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.rtt
+}
+
+// SpanContext returns the span context of the computation the manager last
+// reported in its acknowledgements, it is invalid while there is none.
+func (h *Heartbeater) SpanContext() trace.SpanContext {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.spanCtx
+}
+
+func (h *Heartbeater) beat(conn net.Conn) error {
+	h.mu.Lock()
+	h.seq++
+	seq := h.seq
+	h.mu.Unlock()
+
+	sent := time.Now()
+	if err := conn.SetDeadline(sent.Add(h.interval)); err != nil {
+		return err
+	}
+
+	if err := writeMessage(conn, message{typ: msgHeartbeat, seq: seq, sentAt: sent.UnixNano()}); err != nil {
+		return err
+	}
+
+	ack, err := readMessage(conn)
+	if err != nil {
+		return err
+	}
+	if ack.typ != msgAck || ack.seq != seq {
+		return fmt.Errorf("%w: type %d, sequence %d", ErrUnexpectedMessage, ack.typ, ack.seq)
+	}
+
+	rtt := time.Since(sent)
+
+	h.mu.Lock()
+	h.rtt = rtt
+	h.spanCtx = ack.trace
+	h.mu.Unlock()
+
+	h.logger.Debug("heartbeat acknowledged", "seq", seq, "rtt", rtt)
+
+	return nil
+}
+
+type peer struct {
+	lastSeen  time.Time
+	seq       uint64
+	unhealthy bool
+}
+
+// Monitor acknowledges the heartbeats of the VMs and tracks which of them stopped sending heartbeats.
+type Monitor struct {
+	interval    time.Duration
+	missed      int
+	logger      *slog.Logger
+	traceParent func(id uint32) string
+	spans       func(id uint32, spans *coltracepb.ExportTraceServiceRequest)
+	diagnostics func(ctx context.Context, id uint32, snapshot []byte)
+	config      func(id uint32) map[string]string
+
+	mu    sync.Mutex
+	peers map[uint32]*peer
+}
+
+// MonitorOption configures optional behavior of a Monitor.
+type MonitorOption func(*Monitor)
+
+// WithTraceParent makes the monitor acknowledge heartbeats with the W3C
+// traceparent fn returns for the VM, so the spans of the agent join the trace.
+func WithTraceParent(fn func(id uint32) string) MonitorOption {
+	return func(m *Monitor) {
+		m.traceParent = fn
+	}
+}
+
+// WithSpans makes the monitor accept the spans exported by the agents and hand them to fn.
+func WithSpans(fn func(id uint32, spans *coltracepb.ExportTraceServiceRequest)) MonitorOption {
+	return func(m *Monitor) {
+		m.spans = fn
+	}
+}
+
+// WithDiagnostics makes the monitor accept the diagnostic snapshots sent by the
+// agents and hand them to fn, with a context carrying the trace of the failed run.
+func WithDiagnostics(fn func(ctx context.Context, id uint32, snapshot []byte)) MonitorOption {
+	return func(m *Monitor) {
+		m.diagnostics = fn
+	}
+}
+
+// WithConfig makes the monitor answer the configuration requests of the agents
+// with the environment variables fn returns for the VM, requests are rejected
+// when it returns nil.
+func WithConfig(fn func(id uint32) map[string]string) MonitorOption {
+	return func(m *Monitor) {
+		m.config = fn
+	}
+}
+
+// NewMonitor returns a monitor that considers a VM unhealthy once it missed
+// the given number of heartbeats expected every interval.
+func NewMonitor(interval time.Duration, missed int, logger *slog.Logger, opts ...MonitorOption) *Monitor {
+	m := &Monitor{
+		interval: interval,
+		missed:   max(missed, 1),
+		logger:   logger,
+		peers:    make(map[uint32]*peer),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Serve acknowledges the heartbeats received on the connections accepted from l
+// until it is closed, identifying each VM with the ID peerID returns for its address.
+func (m *Monitor) Serve(l net.Listener, peerID func(net.Addr) (uint32, error)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		id, err := peerID(conn.RemoteAddr())
+		if err != nil {
+			m.logger.Warn("rejected heartbeat connection", "error", err)
+			conn.Close()
+			continue
+		}
+
+		go m.handle(id, conn)
+	}
+}
+
+func (m *Monitor) handle(id uint32, conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+
+		ack := message{typ: msgAck, seq: msg.seq, sentAt: msg.sentAt}
+
+		switch {
+		case msg.typ == msgHeartbeat:
+			m.beat(id, msg.seq)
+			if m.traceParent != nil {
+				carrier := propagation.MapCarrier{traceParentKey: m.traceParent(id)}
+				ack.trace = trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+			}
+		case msg.typ == msgSpans && m.spans != nil:
+			var req coltracepb.ExportTraceServiceRequest
+			if err := proto.Unmarshal(msg.payload, &req); err != nil {
+				m.logger.Warn("closing heartbeat connection", "cid", id, "error", err)
+				return
+			}
+			m.spans(id, &req)
+		case msg.typ == msgDiagnostics && m.diagnostics != nil:
+			m.diagnostics(trace.ContextWithRemoteSpanContext(context.Background(), msg.trace), id, msg.payload)
+		case msg.typ == msgConfig && m.config != nil:
+			cfg := m.config(id)
+			if cfg == nil {
+				m.logger.Warn("closing heartbeat connection", "cid", id, "error", errNoConfig)
+				return
+			}
+			if ack.payload, err = json.Marshal(cfg); err != nil {
+				m.logger.Warn("closing heartbeat connection", "cid", id, "error", err)
+				return
+			}
+		default:
+			m.logger.Warn("closing heartbeat connection", "cid", id, "error", ErrUnexpectedMessage)
+			return
+		}
+
+		if err := writeMessage(conn, ack); err != nil {
+			return
+		}
+	}
+}
+
+func (m *Monitor) beat(id uint32, seq uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[id]
+	if !ok {
+		p = &peer{}
+		m.peers[id] = p
+	}
+
+	if p.seq != 0 && seq > p.seq+1 {
+		m.logger.Warn("lost heartbeats", "cid", id, "count", seq-p.seq-1)
+	}
+	if p.unhealthy {
+		m.logger.Info("VM is sending heartbeats again", "cid", id)
+	}
+
+	p.lastSeen = time.Now()
+	p.seq = seq
+	p.unhealthy = false
+}
+
+// Unhealthy returns the IDs of the VMs that sent heartbeats before but missed
+// the configured number of them by now. Each VM is reported once until it
+// sends heartbeats again.
+func (m *Monitor) Unhealthy(now time.Time) []uint32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deadline := time.Duration(m.missed) * m.interval
+
+	var ids []uint32
+	for id, p := range m.peers {
+		if !p.unhealthy && now.Sub(p.lastSeen) > deadline {
+			p.unhealthy = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Forget stops tracking the VM, e.g. once it was removed.
+func (m *Monitor) Forget(id uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.peers, id)
+}
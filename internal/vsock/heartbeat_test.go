@@ -0,0 +1,186 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/vsock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testCID         = 3
+	testInterval    = 10 * time.Millisecond
+	testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+)
+
+func TestMessage(t *testing.T) {
+	cases := []struct {
+		desc string
+		msg  message
+	}{
+		{
+			desc: "heartbeat",
+			msg:  message{typ: msgHeartbeat, seq: 42, sentAt: time.Now().UnixNano()},
+		},
+		{
+			desc: "acknowledgement with trace context",
+			msg:  message{typ: msgAck, seq: 42, sentAt: time.Now().UnixNano(), trace: testSpanContext(t)},
+		},
+		{
+			desc: "diagnostics with trace context",
+			msg:  message{typ: msgDiagnostics, sentAt: time.Now().UnixNano(), trace: testSpanContext(t), payload: []byte(`{"state":"Failed"}`)},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writeMessage(&buf, tc.msg))
+			assert.Equal(t, headerSize+len(tc.msg.payload), buf.Len())
+
+			got, err := readMessage(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.msg, got)
+		})
+	}
+
+	_, err := readMessage(bytes.NewReader([]byte{byte(msgAck), 0}))
+	assert.Error(t, err)
+
+	assert.ErrorIs(t, writeMessage(io.Discard, message{typ: msgSpans, payload: make([]byte, maxPayloadSize+1)}), errPayloadTooLarge)
+
+	header := make([]byte, headerSize)
+	header[0] = byte(msgSpans)
+	binary.BigEndian.PutUint32(header[17:], maxPayloadSize+1)
+	_, err = readMessage(bytes.NewReader(header))
+	assert.ErrorIs(t, err, errPayloadTooLarge)
+}
+
+func TestContextID(t *testing.T) {
+	cid, err := ContextID(&vsock.Addr{ContextID: testCID, Port: 1024})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(testCID), cid)
+
+	_, err = ContextID(&net.TCPAddr{})
+	assert.ErrorIs(t, err, errNotVsock)
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	return l
+}
+
+func TestHeartbeat(t *testing.T) {
+	l := listen(t)
+	monitor := NewMonitor(testInterval, 3, slog.Default())
+	go func() {
+		_ = monitor.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+	}()
+
+	hb := NewHeartbeater(func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}, testInterval, slog.Default())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- hb.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return hb.RTT() > 0 }, time.Second, testInterval)
+	assert.Empty(t, monitor.Unhealthy(time.Now()))
+
+	cancel()
+	require.NoError(t, <-done)
+
+	later := time.Now().Add(10 * testInterval)
+	assert.Equal(t, []uint32{testCID}, monitor.Unhealthy(later))
+	assert.Empty(t, monitor.Unhealthy(later), "unhealthy VMs are reported once")
+
+	monitor.beat(testCID, hb.seq+1)
+	assert.Empty(t, monitor.Unhealthy(time.Now()))
+
+	monitor.Forget(testCID)
+	assert.Empty(t, monitor.Unhealthy(later))
+}
+
+func TestHeartbeaterUnexpectedAck(t *testing.T) {
+	l := listen(t)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		msg, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		_ = writeMessage(conn, message{typ: msgAck, seq: msg.seq + 1})
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	hb := NewHeartbeater(nil, time.Second, slog.Default())
+	assert.ErrorIs(t, hb.beat(conn), ErrUnexpectedMessage)
+	assert.Zero(t, hb.RTT())
+}
+
+func TestHeartbeaterInvalidInterval(t *testing.T) {
+	hb := NewHeartbeater(nil, 0, slog.Default())
+	assert.ErrorIs(t, hb.Run(context.Background()), errInvalidInterval)
+}
+
+func TestTraceContext(t *testing.T) {
+	l := listen(t)
+	monitor := NewMonitor(testInterval, 3, slog.Default(), WithTraceParent(func(id uint32) string {
+		if id != testCID {
+			return ""
+		}
+
+		return testTraceParent
+	}))
+	go func() {
+		_ = monitor.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	hb := NewHeartbeater(nil, time.Second, slog.Default())
+	assert.False(t, hb.SpanContext().IsValid())
+
+	require.NoError(t, hb.beat(conn))
+
+	sc := hb.SpanContext()
+	require.True(t, sc.IsValid())
+	assert.True(t, sc.IsRemote())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+}
+
+func testSpanContext(t *testing.T) trace.SpanContext {
+	carrier := propagation.MapCarrier{traceParentKey: testTraceParent}
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	require.True(t, sc.IsValid())
+
+	return sc
+}
@@ -0,0 +1,609 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// LogStdout is the standard output of the algorithm.
+	LogStdout LogStream = iota + 1
+	// LogStderr is the standard error of the algorithm.
+	LogStderr
+
+	logAckCumulative logAckType = iota + 1
+	logAckMissing
+
+	// logHeaderSize is the size of the encoded log record header: stream,
+	// sequence number, capture time and data length.
+	logHeaderSize = 1 + 8 + 8 + 4
+	// logAckSize is the size of an encoded acknowledgement: type and the
+	// first and last sequence numbers it covers.
+	logAckSize = 1 + 8 + 8
+	// logHelloSize is the size of the shipper ID that starts a logs channel.
+	logHelloSize = 8
+	// maxLogRecordSize bounds the data of a log record, larger writes are split.
+	maxLogRecordSize = 64 << 10
+	// logQueueSize is the number of log records the agent queues while the
+	// manager is unreachable or slower than the algorithm, further records are dropped.
+	logQueueSize = 1024
+	// logRetryInterval is the time the agent waits before reconnecting to the manager.
+	logRetryInterval = time.Second
+	// DefaultLogWindow is the number of log records the agent sends before it
+	// waits for the manager to acknowledge them.
+	DefaultLogWindow = 256
+	// logAckBatch is the number of records the manager receives before it
+	// acknowledges them without waiting for logAckDelay.
+	logAckBatch = 32
+	// logAckDelay bounds the time the manager holds back an acknowledgement.
+	logAckDelay = 50 * time.Millisecond
+	// logAckTimeout is the time the agent waits for an acknowledgement of the
+	// records in flight before it reconnects and sends them again.
+	logAckTimeout = 10 * time.Second
+	// logReorderLimit bounds the records the manager holds while it waits for
+	// the missing records before them.
+	logReorderLimit = 4 * DefaultLogWindow
+)
+
+var (
+	errInvalidLogStream = errors.New("invalid log stream")
+	errInvalidLogAck    = errors.New("invalid log acknowledgement")
+	errLogAckTimeout    = errors.New("log records were not acknowledged in time")
+)
+
+// logAckType tells a cumulative acknowledgement from a request to send missing records again.
+type logAckType uint8
+
+// logAck acknowledges the records up to to when it is cumulative, and asks
+// for the records from from to to when they are missing.
+type logAck struct {
+	typ      logAckType
+	from, to uint64
+}
+
+// LogStream identifies the output stream of the algorithm a log record was captured from.
+type LogStream uint8
+
+func (s LogStream) String() string {
+	switch s {
+	case LogStdout:
+		return "stdout"
+	case LogStderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// LogRecord is a chunk of the algorithm output captured by the agent.
+type LogRecord struct {
+	Stream LogStream
+	Time   time.Time
+	Data   []byte
+}
+
+func writeLogRecord(w io.Writer, seq uint64, r LogRecord) error {
+	if len(r.Data) > maxLogRecordSize {
+		return errPayloadTooLarge
+	}
+
+	buf := make([]byte, logHeaderSize+len(r.Data))
+	buf[0] = byte(r.Stream)
+	binary.BigEndian.PutUint64(buf[1:], seq)
+	binary.BigEndian.PutUint64(buf[9:], uint64(r.Time.UnixNano()))
+	binary.BigEndian.PutUint32(buf[17:], uint32(len(r.Data)))
+	copy(buf[logHeaderSize:], r.Data)
+
+	_, err := w.Write(buf)
+
+	return err
+}
+
+func readLogRecord(r io.Reader) (uint64, LogRecord, error) {
+	var buf [logHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, LogRecord{}, err
+	}
+
+	rec := LogRecord{
+		Stream: LogStream(buf[0]),
+		Time:   time.Unix(0, int64(binary.BigEndian.Uint64(buf[9:]))),
+	}
+	if rec.Stream != LogStdout && rec.Stream != LogStderr {
+		return 0, LogRecord{}, errInvalidLogStream
+	}
+
+	size := binary.BigEndian.Uint32(buf[17:])
+	if size > maxLogRecordSize {
+		return 0, LogRecord{}, errPayloadTooLarge
+	}
+	rec.Data = make([]byte, size)
+	if _, err := io.ReadFull(r, rec.Data); err != nil {
+		return 0, LogRecord{}, err
+	}
+
+	return binary.BigEndian.Uint64(buf[1:]), rec, nil
+}
+
+func writeLogAck(w io.Writer, a logAck) error {
+	var buf [logAckSize]byte
+	buf[0] = byte(a.typ)
+	binary.BigEndian.PutUint64(buf[1:], a.from)
+	binary.BigEndian.PutUint64(buf[9:], a.to)
+
+	_, err := w.Write(buf[:])
+
+	return err
+}
+
+func readLogAck(r io.Reader) (logAck, error) {
+	var buf [logAckSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return logAck{}, err
+	}
+
+	a := logAck{
+		typ:  logAckType(buf[0]),
+		from: binary.BigEndian.Uint64(buf[1:]),
+		to:   binary.BigEndian.Uint64(buf[9:]),
+	}
+	if (a.typ != logAckCumulative && a.typ != logAckMissing) || a.from > a.to {
+		return logAck{}, fmt.Errorf("%w: type %d, from %d to %d", errInvalidLogAck, a.typ, a.from, a.to)
+	}
+
+	return a, nil
+}
+
+// LogShipper streams the output of the algorithm to the manager on the logs
+// channel of a session. Writes never block the algorithm, records are queued
+// and dropped once the queue is full.
+//
+// Records are numbered, and up to a window of them are in flight until the
+// manager acknowledges them. The manager acknowledges the records it received
+// in batches, and asks for the missing ones when it detects a gap. Records in
+// flight when the connection fails are sent again once the shipper reconnects,
+// the manager discards those it already received.
+type LogShipper struct {
+	dial    func() (net.Conn, error)
+	logger  *slog.Logger
+	window  int
+	queue   chan LogRecord
+	dropped atomic.Uint64
+
+	// id tells the manager the records of this shipper from those of a
+	// previous agent of the VM, whose sequence numbers it would mistake for
+	// records it already received.
+	id uint64
+	// seq is the sequence number of the last record sent.
+	seq uint64
+	// inflight holds the records sent and not acknowledged yet, in order.
+	inflight []sequencedLogRecord
+}
+
+type sequencedLogRecord struct {
+	seq uint64
+	rec LogRecord
+}
+
+// LogShipperOption configures optional behavior of a LogShipper.
+type LogShipperOption func(*LogShipper)
+
+// WithLogWindow sets the number of records sent before the shipper waits for
+// the manager to acknowledge them, DefaultLogWindow by default or when n is
+// not positive.
+func WithLogWindow(n int) LogShipperOption {
+	return func(s *LogShipper) {
+		if n > 0 {
+			s.window = n
+		}
+	}
+}
+
+// NewLogShipper returns a log shipper sending the output on the connections returned by dial.
+func NewLogShipper(dial func() (net.Conn, error), logger *slog.Logger, opts ...LogShipperOption) *LogShipper {
+	s := &LogShipper{
+		dial:   dial,
+		logger: logger,
+		window: DefaultLogWindow,
+		queue:  make(chan LogRecord, logQueueSize),
+		id:     rand.Uint64(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Stdout returns the writer shipping the standard output of the algorithm.
+func (s *LogShipper) Stdout() io.Writer {
+	return &logWriter{shipper: s, stream: LogStdout}
+}
+
+// Stderr returns the writer shipping the standard error of the algorithm.
+func (s *LogShipper) Stderr() io.Writer {
+	return &logWriter{shipper: s, stream: LogStderr}
+}
+
+// Run ships the queued output until ctx is done, reconnecting after failures.
+func (s *LogShipper) Run(ctx context.Context) error {
+	for {
+		if err := s.ship(ctx); err != nil {
+			s.logger.Debug("failed to ship algorithm logs", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logRetryInterval):
+		}
+	}
+}
+
+func (s *LogShipper) ship(ctx context.Context) error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+
+	session, err := NewSession(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer session.Close()
+
+	ch, err := session.OpenChannel(ChannelLogs)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if _, err := ch.Write(binary.BigEndian.AppendUint64(nil, s.id)); err != nil {
+		return err
+	}
+
+	acks := make(chan logAck)
+	ackErr := make(chan error, 1)
+	go func() {
+		for {
+			a, err := readLogAck(ch)
+			if err != nil {
+				ackErr <- err
+				return
+			}
+			select {
+			case acks <- a:
+			case <-session.Done():
+				return
+			}
+		}
+	}()
+
+	// The records the previous connection left unacknowledged are sent first.
+	for _, r := range s.inflight {
+		if err := writeLogRecord(ch, r.seq, r.rec); err != nil {
+			return err
+		}
+	}
+
+	ackTimer := time.NewTimer(logAckTimeout)
+	defer ackTimer.Stop()
+
+	for {
+		// Records are only taken from the queue while the window has room.
+		var queue <-chan LogRecord
+		if len(s.inflight) < s.window {
+			queue = s.queue
+		}
+		var ackTimeout <-chan time.Time
+		if len(s.inflight) > 0 {
+			ackTimeout = ackTimer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-session.Done():
+			return ErrSessionClosed
+		case err := <-ackErr:
+			return err
+		case <-ackTimeout:
+			return errLogAckTimeout
+		case a := <-acks:
+			if err := s.acknowledge(ch, a); err != nil {
+				return err
+			}
+			ackTimer.Reset(logAckTimeout)
+		case rec := <-queue:
+			if dropped := s.dropped.Swap(0); dropped > 0 {
+				s.logger.Warn("dropped algorithm log records", "count", dropped)
+			}
+			if len(s.inflight) == 0 {
+				ackTimer.Reset(logAckTimeout)
+			}
+			s.seq++
+			s.inflight = append(s.inflight, sequencedLogRecord{seq: s.seq, rec: rec})
+			if err := writeLogRecord(ch, s.seq, rec); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// acknowledge releases the records the manager received, or sends again those it misses.
+func (s *LogShipper) acknowledge(w io.Writer, a logAck) error {
+	if a.typ == logAckCumulative {
+		i := 0
+		for i < len(s.inflight) && s.inflight[i].seq <= a.to {
+			i++
+		}
+		clear(s.inflight[:i])
+		s.inflight = s.inflight[i:]
+
+		return nil
+	}
+
+	for _, r := range s.inflight {
+		if r.seq > a.to {
+			break
+		}
+		if r.seq < a.from {
+			continue
+		}
+		if err := writeLogRecord(w, r.seq, r.rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *LogShipper) enqueue(rec LogRecord) {
+	select {
+	case s.queue <- rec:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+type logWriter struct {
+	shipper *LogShipper
+	stream  LogStream
+}
+
+// Write implements io.Writer.
+func (w *logWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	for data := p; len(data) > 0; {
+		n := min(len(data), maxLogRecordSize)
+		w.shipper.enqueue(LogRecord{Stream: w.stream, Time: now, Data: append([]byte(nil), data[:n]...)})
+		data = data[n:]
+	}
+
+	return len(p), nil
+}
+
+// LogCollector receives the output of the algorithms the agents ship over vsock.
+type LogCollector struct {
+	logger *slog.Logger
+	fn     func(id uint32, rec LogRecord)
+
+	mu      sync.Mutex
+	streams map[uint32]*logSequence
+}
+
+// logSequence orders the records a shipper sent, across its connections.
+type logSequence struct {
+	mu      sync.Mutex
+	shipper uint64
+	// next is the sequence number of the next record to hand over, 0 until
+	// the first record is received.
+	next uint64
+	// seen is the highest sequence number received or asked for again.
+	seen uint64
+	// held are the records received ahead of missing ones.
+	held map[uint64]LogRecord
+}
+
+// NewLogCollector returns a collector handing the received log records of each VM to fn.
+func NewLogCollector(logger *slog.Logger, fn func(id uint32, rec LogRecord)) *LogCollector {
+	return &LogCollector{
+		logger:  logger,
+		fn:      fn,
+		streams: make(map[uint32]*logSequence),
+	}
+}
+
+// Serve receives the logs of the sessions accepted from l until it is closed,
+// identifying each VM with the ID peerID returns for its address.
+func (c *LogCollector) Serve(l net.Listener, peerID func(net.Addr) (uint32, error)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		id, err := peerID(conn.RemoteAddr())
+		if err != nil {
+			c.logger.Warn("rejected logs connection", "error", err)
+			conn.Close()
+			continue
+		}
+
+		go c.handle(id, conn)
+	}
+}
+
+func (c *LogCollector) handle(id uint32, conn net.Conn) {
+	session, err := NewSession(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer session.Close()
+
+	for {
+		ch, err := session.Accept()
+		if err != nil {
+			return
+		}
+
+		if ch.ID() != ChannelLogs {
+			ch.Close()
+			continue
+		}
+
+		go c.receive(id, ch)
+	}
+}
+
+// sequence returns the ordering of the records of the shipper running on the
+// VM, starting over when the VM runs another shipper.
+func (c *LogCollector) sequence(id uint32, shipper uint64) *logSequence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq, ok := c.streams[id]
+	if !ok || seq.shipper != shipper {
+		seq = &logSequence{shipper: shipper, held: make(map[uint64]LogRecord)}
+		c.streams[id] = seq
+	}
+
+	return seq
+}
+
+func (c *LogCollector) receive(id uint32, ch *Channel) {
+	defer ch.Close()
+
+	var hello [logHelloSize]byte
+	if _, err := io.ReadFull(ch, hello[:]); err != nil {
+		return
+	}
+	seq := c.sequence(id, binary.BigEndian.Uint64(hello[:]))
+
+	var writeMu sync.Mutex
+	send := func(a logAck) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+
+		_ = writeLogAck(ch, a)
+	}
+
+	// Received records are acknowledged once a batch of them is received, or
+	// once logAckDelay passed, whichever comes first.
+	flush := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(logAckDelay)
+		defer ticker.Stop()
+
+		var acked uint64
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			case <-flush:
+			}
+
+			if last := seq.last(); last > acked {
+				send(logAck{typ: logAckCumulative, to: last})
+				acked = last
+			}
+		}
+	}()
+
+	for received := 1; ; received++ {
+		n, rec, err := readLogRecord(ch)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, ErrSessionClosed) {
+				c.logger.Warn("closing logs channel", "cid", id, "error", err)
+			}
+			return
+		}
+
+		ready, missing := seq.add(n, rec)
+		if missing != nil {
+			send(*missing)
+		}
+		for _, rec := range ready {
+			c.fn(id, rec)
+		}
+
+		if received%logAckBatch == 0 {
+			signal(flush)
+		}
+	}
+}
+
+// add records the received record and returns the records ready to be
+// handed over in order, and the records to ask for when it reveals a gap.
+func (s *logSequence) add(n uint64, rec LogRecord) ([]LogRecord, *logAck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == 0 {
+		s.next = n
+	}
+
+	switch {
+	case n < s.next:
+		// Sent again after a reconnection, it was already handed over.
+		return nil, nil
+	case n > s.next:
+		// Records beyond the limit are dropped as if they were missing, so
+		// they are asked for again with the gap a later record reveals.
+		if len(s.held) >= logReorderLimit {
+			return nil, nil
+		}
+		s.held[n] = rec
+
+		var missing *logAck
+		if from := max(s.next, s.seen+1); from < n {
+			missing = &logAck{typ: logAckMissing, from: from, to: n - 1}
+		}
+		s.seen = max(s.seen, n)
+
+		return nil, missing
+	}
+
+	ready := []LogRecord{rec}
+	s.next++
+	for {
+		rec, ok := s.held[s.next]
+		if !ok {
+			break
+		}
+		delete(s.held, s.next)
+		ready = append(ready, rec)
+		s.next++
+	}
+	s.seen = max(s.seen, s.next-1)
+
+	return ready, nil
+}
+
+// last returns the sequence number of the last record handed over in order.
+func (s *logSequence) last() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == 0 {
+		return 0
+	}
+
+	return s.next - 1
+}
@@ -0,0 +1,296 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRecord(t *testing.T) {
+	rec := LogRecord{Stream: LogStderr, Time: time.Unix(0, time.Now().UnixNano()), Data: []byte("training epoch 1\n")}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeLogRecord(&buf, 42, rec))
+	assert.Equal(t, logHeaderSize+len(rec.Data), buf.Len())
+
+	seq, got, err := readLogRecord(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), seq)
+	assert.Equal(t, rec.Stream, got.Stream)
+	assert.True(t, rec.Time.Equal(got.Time))
+	assert.Equal(t, rec.Data, got.Data)
+
+	assert.ErrorIs(t, writeLogRecord(&buf, 1, LogRecord{Stream: LogStdout, Data: make([]byte, maxLogRecordSize+1)}), errPayloadTooLarge)
+
+	header := make([]byte, logHeaderSize)
+	_, _, err = readLogRecord(bytes.NewReader(header))
+	assert.ErrorIs(t, err, errInvalidLogStream)
+
+	header[0] = byte(LogStdout)
+	binary.BigEndian.PutUint32(header[17:], maxLogRecordSize+1)
+	_, _, err = readLogRecord(bytes.NewReader(header))
+	assert.ErrorIs(t, err, errPayloadTooLarge)
+}
+
+func TestLogAck(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeLogAck(&buf, logAck{typ: logAckMissing, from: 3, to: 7}))
+	assert.Equal(t, logAckSize, buf.Len())
+
+	a, err := readLogAck(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, logAck{typ: logAckMissing, from: 3, to: 7}, a)
+
+	require.NoError(t, writeLogAck(&buf, logAck{typ: logAckMissing, from: 7, to: 3}))
+	_, err = readLogAck(&buf)
+	assert.ErrorIs(t, err, errInvalidLogAck)
+
+	require.NoError(t, writeLogAck(&buf, logAck{typ: 9, to: 3}))
+	_, err = readLogAck(&buf)
+	assert.ErrorIs(t, err, errInvalidLogAck)
+}
+
+func TestLogSequence(t *testing.T) {
+	rec := func(data string) LogRecord { return LogRecord{Stream: LogStdout, Data: []byte(data)} }
+	s := &logSequence{held: make(map[uint64]LogRecord)}
+
+	ready, missing := s.add(5, rec("5"))
+	assert.Equal(t, []LogRecord{rec("5")}, ready, "the first record starts the sequence")
+	assert.Nil(t, missing)
+
+	ready, missing = s.add(8, rec("8"))
+	assert.Empty(t, ready)
+	assert.Equal(t, &logAck{typ: logAckMissing, from: 6, to: 7}, missing)
+
+	ready, missing = s.add(9, rec("9"))
+	assert.Empty(t, ready)
+	assert.Nil(t, missing, "the gap was already reported")
+
+	ready, missing = s.add(11, rec("11"))
+	assert.Empty(t, ready)
+	assert.Equal(t, &logAck{typ: logAckMissing, from: 10, to: 10}, missing, "only the new gap is reported")
+
+	ready, _ = s.add(6, rec("6"))
+	assert.Equal(t, []LogRecord{rec("6")}, ready)
+	ready, _ = s.add(7, rec("7"))
+	assert.Equal(t, []LogRecord{rec("7"), rec("8"), rec("9")}, ready)
+	assert.Equal(t, uint64(9), s.last())
+
+	ready, missing = s.add(7, rec("7"))
+	assert.Empty(t, ready, "records received again are discarded")
+	assert.Nil(t, missing)
+
+	ready, _ = s.add(10, rec("10"))
+	assert.Equal(t, []LogRecord{rec("10"), rec("11")}, ready)
+	assert.Equal(t, uint64(11), s.last())
+	assert.Empty(t, s.held)
+}
+
+func TestLogShipper(t *testing.T) {
+	l := listen(t)
+
+	var (
+		mu      sync.Mutex
+		records []LogRecord
+	)
+	collector := NewLogCollector(slog.Default(), func(id uint32, rec LogRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, uint32(testCID), id)
+		records = append(records, rec)
+	})
+	go func() {
+		_ = collector.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+	}()
+
+	shipper := NewLogShipper(func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}, slog.Default())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- shipper.Run(ctx) }()
+
+	_, err := shipper.Stdout().Write([]byte("epoch 1\n"))
+	require.NoError(t, err)
+	n, err := shipper.Stderr().Write(make([]byte, maxLogRecordSize+1))
+	require.NoError(t, err)
+	assert.Equal(t, maxLogRecordSize+1, n)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(records) == 3
+	}, time.Second, testInterval)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, LogStdout, records[0].Stream)
+	assert.Equal(t, "epoch 1\n", string(records[0].Data))
+	assert.Equal(t, LogStderr, records[1].Stream)
+	assert.Len(t, records[1].Data, maxLogRecordSize)
+	assert.Len(t, records[2].Data, 1)
+}
+
+// fakeLogCollector accepts one logs channel from l and hands it to fn after reading the shipper ID.
+func fakeLogCollector(t *testing.T, l net.Listener, fn func(ch *Channel)) {
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		session, err := NewSession(conn)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer session.Close()
+
+		ch, err := session.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		var hello [logHelloSize]byte
+		if _, err := io.ReadFull(ch, hello[:]); !assert.NoError(t, err) {
+			return
+		}
+
+		fn(ch)
+	}()
+}
+
+func TestLogShipperWindow(t *testing.T) {
+	l := listen(t)
+
+	received := make(chan uint64, 10)
+	acks := make(chan logAck)
+	fakeLogCollector(t, l, func(ch *Channel) {
+		go func() {
+			for a := range acks {
+				_ = writeLogAck(ch, a)
+			}
+		}()
+		for {
+			seq, _, err := readLogRecord(ch)
+			if err != nil {
+				return
+			}
+			received <- seq
+		}
+	})
+
+	shipper := NewLogShipper(func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}, slog.Default(), WithLogWindow(2))
+
+	for range 3 {
+		_, err := shipper.Stdout().Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = shipper.Run(ctx) }()
+
+	assert.Equal(t, uint64(1), <-received)
+	assert.Equal(t, uint64(2), <-received)
+	assert.Never(t, func() bool { return len(received) > 0 }, 5*testInterval, testInterval, "the window is full")
+
+	acks <- logAck{typ: logAckCumulative, to: 1}
+	assert.Equal(t, uint64(3), <-received)
+
+	// Asking for missing records sends them again.
+	acks <- logAck{typ: logAckMissing, from: 2, to: 3}
+	assert.Equal(t, uint64(2), <-received)
+	assert.Equal(t, uint64(3), <-received)
+	close(acks)
+}
+
+func TestLogShipperResendsAfterReconnecting(t *testing.T) {
+	l := listen(t)
+
+	// The first manager receives the records without acknowledging them and drops the connection.
+	first := make(chan struct{})
+	fakeLogCollector(t, l, func(ch *Channel) {
+		defer close(first)
+		for range 2 {
+			_, _, err := readLogRecord(ch)
+			assert.NoError(t, err)
+		}
+	})
+
+	shipper := NewLogShipper(func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}, slog.Default())
+
+	for _, line := range []string{"epoch 1\n", "epoch 2\n"} {
+		_, err := shipper.Stdout().Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = shipper.Run(ctx) }()
+	<-first
+
+	var (
+		mu      sync.Mutex
+		records []string
+	)
+	collector := NewLogCollector(slog.Default(), func(id uint32, rec LogRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		records = append(records, string(rec.Data))
+	})
+	go func() {
+		_ = collector.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(records) == 2
+	}, 3*logRetryInterval, testInterval)
+
+	_, err := shipper.Stdout().Write([]byte("epoch 3\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(records) == 3
+	}, time.Second, testInterval)
+	assert.Equal(t, []string{"epoch 1\n", "epoch 2\n", "epoch 3\n"}, records)
+}
+
+func TestLogShipperDropsWhenFull(t *testing.T) {
+	shipper := NewLogShipper(nil, slog.Default())
+
+	for range logQueueSize + 2 {
+		n, err := shipper.Stdout().Write([]byte("line\n"))
+		require.NoError(t, err)
+		assert.Equal(t, len("line\n"), n)
+	}
+
+	assert.Len(t, shipper.queue, logQueueSize)
+	assert.Equal(t, uint64(2), shipper.dropped.Load())
+}
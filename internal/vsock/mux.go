@@ -0,0 +1,574 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChannelLogs is the channel the agent ships the algorithm logs on. The ID
+// stays 3 so agents and managers of earlier releases still agree on it.
+const ChannelLogs uint32 = 3
+
+const (
+	frameOpen frameType = iota + 1
+	frameData
+	frameWindow
+	frameClose
+	frameReset
+
+	// frameHeaderSize is the size of the encoded frame header: type, channel
+	// ID and payload length.
+	frameHeaderSize = 1 + 4 + 4
+	// maxFramePayload bounds the data frames, so a large write on one channel
+	// does not hold the connection while other channels have data to send.
+	maxFramePayload = 16 << 10
+	// DefaultWindow is the data a channel buffers before its reader consumes it.
+	DefaultWindow = 256 << 10
+	// acceptBacklog is the number of opened channels waiting to be accepted.
+	acceptBacklog = 16
+)
+
+var (
+	// ErrSessionClosed indicates the session or its connection was closed.
+	ErrSessionClosed = errors.New("vsock session closed")
+	// ErrChannelInUse indicates a channel ID that is already open on the session.
+	ErrChannelInUse = errors.New("vsock channel already open")
+	// ErrChannelReset indicates the peer rejected or aborted the channel.
+	ErrChannelReset = errors.New("vsock channel reset by peer")
+	// ErrChannelClosed indicates the use of a channel that was closed, or a
+	// write on a channel the peer closed.
+	ErrChannelClosed = errors.New("vsock channel closed")
+
+	errInvalidWindow  = errors.New("channel window must be at least the frame size")
+	errWindowExceeded = errors.New("peer exceeded the channel window")
+	errUnknownFrame   = errors.New("unknown frame type")
+)
+
+type frameType uint8
+
+type frame struct {
+	typ     frameType
+	channel uint32
+	payload []byte
+}
+
+func writeFrame(w io.Writer, f frame) error {
+	buf := make([]byte, frameHeaderSize+len(f.payload))
+	buf[0] = byte(f.typ)
+	binary.BigEndian.PutUint32(buf[1:], f.channel)
+	binary.BigEndian.PutUint32(buf[5:], uint32(len(f.payload)))
+	copy(buf[frameHeaderSize:], f.payload)
+
+	_, err := w.Write(buf)
+
+	return err
+}
+
+func readFrame(r io.Reader) (frame, error) {
+	var buf [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return frame{}, err
+	}
+
+	f := frame{
+		typ:     frameType(buf[0]),
+		channel: binary.BigEndian.Uint32(buf[1:]),
+	}
+
+	size := binary.BigEndian.Uint32(buf[5:])
+	if size > maxFramePayload {
+		return frame{}, errPayloadTooLarge
+	}
+	if size > 0 {
+		f.payload = make([]byte, size)
+		if _, err := io.ReadFull(r, f.payload); err != nil {
+			return frame{}, err
+		}
+	}
+
+	return f, nil
+}
+
+func windowPayload(n uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, n)
+}
+
+// Session multiplexes logical channels over a single connection, e.g. the
+// logs connection between the agent and the manager. Every channel has its
+// own flow control window, so a channel whose reader falls behind does not
+// block the others.
+type Session struct {
+	conn   net.Conn
+	window uint32
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	channels map[uint32]*Channel
+	err      error
+
+	accept chan *Channel
+	done   chan struct{}
+}
+
+// SessionOption configures optional behavior of a Session.
+type SessionOption func(*Session)
+
+// WithWindow sets the data each channel of the session buffers before its
+// reader consumes it, DefaultWindow by default.
+func WithWindow(size uint32) SessionOption {
+	return func(s *Session) {
+		s.window = size
+	}
+}
+
+// NewSession starts multiplexing channels over conn. The session owns conn
+// and closes it once it is closed.
+func NewSession(conn net.Conn, opts ...SessionOption) (*Session, error) {
+	s := &Session{
+		conn:     conn,
+		window:   DefaultWindow,
+		channels: make(map[uint32]*Channel),
+		accept:   make(chan *Channel, acceptBacklog),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.window < maxFramePayload {
+		return nil, errInvalidWindow
+	}
+
+	go s.readLoop()
+
+	return s, nil
+}
+
+// OpenChannel opens the channel id to the peer. Channel IDs are chosen by the
+// side opening them, e.g. ChannelLogs is opened by the agent.
+func (s *Session) OpenChannel(id uint32) (*Channel, error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	if _, ok := s.channels[id]; ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d", ErrChannelInUse, id)
+	}
+	ch := newChannel(s, id, 0)
+	s.channels[id] = ch
+	s.mu.Unlock()
+
+	// The peer grants its window once it registered the channel.
+	if err := s.writeFrame(frame{typ: frameOpen, channel: id, payload: windowPayload(s.window)}); err != nil {
+		s.remove(id)
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// Accept waits for the next channel opened by the peer.
+func (s *Session) Accept() (*Channel, error) {
+	select {
+	case ch := <-s.accept:
+		return ch, nil
+	case <-s.done:
+		return nil, s.closeErr()
+	}
+}
+
+// Close closes every channel and the connection.
+func (s *Session) Close() error {
+	s.shutdown(ErrSessionClosed)
+	return nil
+}
+
+// Done is closed once the session is closed.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) readLoop() {
+	for {
+		f, err := readFrame(s.conn)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				err = ErrSessionClosed
+			}
+			s.shutdown(err)
+			return
+		}
+
+		if err := s.handle(f); err != nil {
+			s.shutdown(err)
+			return
+		}
+	}
+}
+
+func (s *Session) handle(f frame) error {
+	s.mu.Lock()
+	ch, ok := s.channels[f.channel]
+	s.mu.Unlock()
+
+	switch f.typ {
+	case frameOpen:
+		if ok || len(f.payload) != 4 {
+			return s.writeFrame(frame{typ: frameReset, channel: f.channel})
+		}
+
+		ch := newChannel(s, f.channel, binary.BigEndian.Uint32(f.payload))
+		s.mu.Lock()
+		s.channels[f.channel] = ch
+		s.mu.Unlock()
+
+		select {
+		case s.accept <- ch:
+		default:
+			s.remove(f.channel)
+			return s.writeFrame(frame{typ: frameReset, channel: f.channel})
+		}
+
+		return s.writeFrame(frame{typ: frameWindow, channel: f.channel, payload: windowPayload(s.window)})
+	case frameData:
+		if !ok {
+			return nil
+		}
+		return ch.receive(f.payload)
+	case frameWindow:
+		if ok && len(f.payload) == 4 {
+			ch.grant(binary.BigEndian.Uint32(f.payload))
+		}
+		return nil
+	case frameClose:
+		if ok {
+			ch.remoteClose()
+		}
+		return nil
+	case frameReset:
+		if ok {
+			ch.reset(ErrChannelReset)
+			s.remove(f.channel)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %d", errUnknownFrame, f.typ)
+	}
+}
+
+func (s *Session) writeFrame(f frame) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return s.closeErr()
+	default:
+	}
+
+	return writeFrame(s.conn, f)
+}
+
+func (s *Session) remove(id uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels, id)
+}
+
+func (s *Session) shutdown(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	channels := s.channels
+	s.channels = make(map[uint32]*Channel)
+	close(s.done)
+	s.mu.Unlock()
+
+	s.conn.Close()
+	for _, ch := range channels {
+		ch.reset(err)
+	}
+}
+
+func (s *Session) closeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Channel is a logical connection of a Session.
+type Channel struct {
+	session *Session
+	id      uint32
+
+	mu            sync.Mutex
+	buf           []byte
+	consumed      uint32
+	sendWindow    uint32
+	localClosed   bool
+	remoteClosed  bool
+	err           error
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	readReady  chan struct{}
+	writeReady chan struct{}
+}
+
+var _ net.Conn = (*Channel)(nil)
+
+func newChannel(s *Session, id, sendWindow uint32) *Channel {
+	return &Channel{
+		session:    s,
+		id:         id,
+		sendWindow: sendWindow,
+		readReady:  make(chan struct{}, 1),
+		writeReady: make(chan struct{}, 1),
+	}
+}
+
+// ID returns the channel ID.
+func (c *Channel) ID() uint32 {
+	return c.id
+}
+
+// Read reads the data the peer sent on the channel, io.EOF once the peer
+// closed it and its data was read.
+func (c *Channel) Read(p []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.buf) > 0 {
+			n := copy(p, c.buf)
+			c.buf = c.buf[n:]
+			c.consumed += uint32(n)
+
+			// Grant the consumed data back once half of the window was read.
+			var update uint32
+			if c.consumed >= c.session.window/2 && !c.remoteClosed {
+				update, c.consumed = c.consumed, 0
+			}
+			c.mu.Unlock()
+
+			if update > 0 {
+				_ = c.session.writeFrame(frame{typ: frameWindow, channel: c.id, payload: windowPayload(update)})
+			}
+
+			return n, nil
+		}
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return 0, err
+		}
+		if c.localClosed {
+			c.mu.Unlock()
+			return 0, ErrChannelClosed
+		}
+		if c.remoteClosed {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		if err := c.wait(c.readReady, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write sends p to the peer, blocking while the peer window is exhausted.
+func (c *Channel) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		c.mu.Lock()
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return written, err
+		}
+		if c.localClosed || c.remoteClosed {
+			c.mu.Unlock()
+			return written, ErrChannelClosed
+		}
+		if c.sendWindow == 0 {
+			deadline := c.writeDeadline
+			c.mu.Unlock()
+
+			if err := c.wait(c.writeReady, deadline); err != nil {
+				return written, err
+			}
+			continue
+		}
+
+		n := min(len(p)-written, int(c.sendWindow), maxFramePayload)
+		c.sendWindow -= uint32(n)
+		c.mu.Unlock()
+
+		if err := c.session.writeFrame(frame{typ: frameData, channel: c.id, payload: p[written : written+n]}); err != nil {
+			return written, err
+		}
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close closes the channel. The peer reads io.EOF once it read the data sent
+// before, and its writes fail.
+func (c *Channel) Close() error {
+	c.mu.Lock()
+	if c.localClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.localClosed = true
+	remoteClosed := c.remoteClosed
+	c.mu.Unlock()
+	c.notify()
+
+	if remoteClosed {
+		c.session.remove(c.id)
+	}
+
+	if err := c.session.writeFrame(frame{typ: frameClose, channel: c.id}); err != nil && !errors.Is(err, ErrSessionClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// LocalAddr returns the local address of the session connection.
+func (c *Channel) LocalAddr() net.Addr {
+	return c.session.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote address of the session connection.
+func (c *Channel) RemoteAddr() net.Addr {
+	return c.session.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the channel.
+func (c *Channel) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.mu.Unlock()
+	c.notify()
+
+	return nil
+}
+
+// SetReadDeadline sets the deadline of pending and future reads.
+func (c *Channel) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	c.notify()
+
+	return nil
+}
+
+// SetWriteDeadline sets the deadline of pending and future writes.
+func (c *Channel) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+	c.notify()
+
+	return nil
+}
+
+func (c *Channel) receive(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.localClosed || c.err != nil {
+		return nil
+	}
+	if uint32(len(c.buf))+c.consumed+uint32(len(data)) > c.session.window {
+		return fmt.Errorf("%w: channel %d", errWindowExceeded, c.id)
+	}
+	c.buf = append(c.buf, data...)
+	signal(c.readReady)
+
+	return nil
+}
+
+func (c *Channel) grant(n uint32) {
+	c.mu.Lock()
+	c.sendWindow += n
+	c.mu.Unlock()
+	signal(c.writeReady)
+}
+
+func (c *Channel) remoteClose() {
+	c.mu.Lock()
+	c.remoteClosed = true
+	localClosed := c.localClosed
+	c.mu.Unlock()
+	c.notify()
+
+	if localClosed {
+		c.session.remove(c.id)
+	}
+}
+
+func (c *Channel) reset(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+func (c *Channel) notify() {
+	signal(c.readReady)
+	signal(c.writeReady)
+}
+
+// wait blocks until ready is signaled, the deadline passes or the session is closed.
+func (c *Channel) wait(ready chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	case <-c.session.done:
+		return c.session.closeErr()
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Channel IDs the tests open besides ChannelLogs.
+const (
+	channelA uint32 = iota + 1
+	channelB
+)
+
+// connPair returns both ends of a loopback connection, which buffers writes
+// like a vsock connection does.
+func connPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	server, ok := <-accepted
+	require.True(t, ok)
+
+	return client, server
+}
+
+func sessionPair(t *testing.T, opts ...SessionOption) (*Session, *Session) {
+	t.Helper()
+
+	clientConn, serverConn := connPair(t)
+	client, err := NewSession(clientConn, opts...)
+	require.NoError(t, err)
+	server, err := NewSession(serverConn, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	return client, server
+}
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+	f := frame{typ: frameData, channel: ChannelLogs, payload: []byte("log line")}
+	require.NoError(t, writeFrame(&buf, f))
+	assert.Equal(t, frameHeaderSize+len(f.payload), buf.Len())
+
+	got, err := readFrame(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+
+	buf.Reset()
+	require.NoError(t, writeFrame(&buf, frame{typ: frameData, channel: ChannelLogs, payload: make([]byte, maxFramePayload+1)}))
+	_, err = readFrame(&buf)
+	assert.ErrorIs(t, err, errPayloadTooLarge)
+}
+
+func TestNewSessionInvalidWindow(t *testing.T) {
+	conn, _ := net.Pipe()
+	_, err := NewSession(conn, WithWindow(maxFramePayload-1))
+	assert.ErrorIs(t, err, errInvalidWindow)
+}
+
+func TestSessionChannels(t *testing.T) {
+	client, server := sessionPair(t)
+
+	for _, id := range []uint32{channelA, channelB, ChannelLogs} {
+		ch, err := client.OpenChannel(id)
+		require.NoError(t, err)
+
+		peer, err := server.Accept()
+		require.NoError(t, err)
+		assert.Equal(t, id, peer.ID())
+
+		_, err = ch.Write([]byte("request"))
+		require.NoError(t, err)
+		buf := make([]byte, len("request"))
+		_, err = io.ReadFull(peer, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "request", string(buf))
+
+		_, err = peer.Write([]byte("response"))
+		require.NoError(t, err)
+		buf = make([]byte, len("response"))
+		_, err = io.ReadFull(ch, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "response", string(buf))
+	}
+
+	_, err := client.OpenChannel(ChannelLogs)
+	assert.ErrorIs(t, err, ErrChannelInUse)
+}
+
+func TestChannelLargeTransfer(t *testing.T) {
+	client, server := sessionPair(t, WithWindow(maxFramePayload))
+
+	ch, err := client.OpenChannel(ChannelLogs)
+	require.NoError(t, err)
+	peer, err := server.Accept()
+	require.NoError(t, err)
+
+	data := make([]byte, 1<<20)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ch.Write(data)
+		if err == nil {
+			err = ch.Close()
+		}
+		errs <- err
+	}()
+
+	got, err := io.ReadAll(peer)
+	require.NoError(t, err)
+	require.NoError(t, <-errs)
+	assert.Equal(t, data, got)
+}
+
+func TestChannelHeadOfLineBlocking(t *testing.T) {
+	client, server := sessionPair(t, WithWindow(maxFramePayload))
+
+	logs, err := client.OpenChannel(ChannelLogs)
+	require.NoError(t, err)
+	_, err = server.Accept()
+	require.NoError(t, err)
+	events, err := client.OpenChannel(channelB)
+	require.NoError(t, err)
+	peerEvents, err := server.Accept()
+	require.NoError(t, err)
+
+	// Nobody reads the logs channel, so its writer stalls once the window is used.
+	require.NoError(t, logs.SetWriteDeadline(time.Now().Add(100*time.Millisecond)))
+	n, err := logs.Write(make([]byte, 2*maxFramePayload))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Equal(t, maxFramePayload, n)
+
+	_, err = events.Write([]byte("event"))
+	require.NoError(t, err)
+	require.NoError(t, peerEvents.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, len("event"))
+	_, err = io.ReadFull(peerEvents, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "event", string(buf))
+}
+
+func TestChannelClose(t *testing.T) {
+	client, server := sessionPair(t)
+
+	ch, err := client.OpenChannel(channelA)
+	require.NoError(t, err)
+	peer, err := server.Accept()
+	require.NoError(t, err)
+
+	_, err = ch.Write([]byte("bye"))
+	require.NoError(t, err)
+	require.NoError(t, ch.Close())
+
+	got, err := io.ReadAll(peer)
+	require.NoError(t, err)
+	assert.Equal(t, "bye", string(got))
+
+	_, err = peer.Write([]byte("late"))
+	assert.ErrorIs(t, err, ErrChannelClosed)
+	_, err = ch.Write([]byte("late"))
+	assert.ErrorIs(t, err, ErrChannelClosed)
+	_, err = ch.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrChannelClosed)
+
+	// The ID can be reused once both sides closed the channel.
+	require.NoError(t, peer.Close())
+	require.Eventually(t, func() bool {
+		_, err := client.OpenChannel(channelA)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestChannelReadDeadline(t *testing.T) {
+	client, server := sessionPair(t)
+
+	ch, err := client.OpenChannel(channelA)
+	require.NoError(t, err)
+	_, err = server.Accept()
+	require.NoError(t, err)
+
+	require.NoError(t, ch.SetReadDeadline(time.Now().Add(20*time.Millisecond)))
+	_, err = ch.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestSessionClose(t *testing.T) {
+	client, server := sessionPair(t)
+
+	ch, err := client.OpenChannel(channelB)
+	require.NoError(t, err)
+	_, err = server.Accept()
+	require.NoError(t, err)
+
+	reads := make(chan error, 1)
+	go func() {
+		_, err := ch.Read(make([]byte, 1))
+		reads <- err
+	}()
+
+	require.NoError(t, server.Close())
+
+	select {
+	case err := <-reads:
+		assert.ErrorIs(t, err, ErrSessionClosed)
+	case <-time.After(time.Second):
+		t.Fatal("read not interrupted by the session close")
+	}
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("session not closed after the peer closed the connection")
+	}
+
+	_, err = server.Accept()
+	assert.ErrorIs(t, err, ErrSessionClosed)
+	_, err = client.OpenChannel(ChannelLogs)
+	assert.ErrorIs(t, err, ErrSessionClosed)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func listenFile(cid, port uint32) (*os.File, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	if err := unix.Listen(fd, unix.SOMAXCONN); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("listen", err)
+	}
+
+	return os.NewFile(uintptr(fd), "vsock-listener"), nil
+}
+
+// closeOnExec keeps an inherited socket from leaking to the QEMU processes.
+func closeOnExec(f *os.File) error {
+	return control(f, func(fd int) error {
+		unix.CloseOnExec(fd)
+		return nil
+	})
+}
+
+func inheritableDup(f *os.File) (*os.File, error) {
+	var dup int
+	err := control(f, func(fd int) (err error) {
+		dup, err = unix.Dup(fd)
+		return os.NewSyscallError("dup", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(dup), f.Name()), nil
+}
+
+// control runs fn with the descriptor of f without switching it to blocking
+// mode, which File.Fd does and which would also affect the listener sharing it.
+func control(f *os.File, fn func(fd int) error) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	if err := rc.Control(func(fd uintptr) {
+		fnErr = fn(int(fd))
+	}); err != nil {
+		return err
+	}
+
+	return fnErr
+}
@@ -0,0 +1,19 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package vsock
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("vsock is only supported on Linux")
+
+func listenFile(_, _ uint32) (*os.File, error) { return nil, errUnsupported }
+
+func closeOnExec(_ *os.File) error { return errUnsupported }
+
+func inheritableDup(_ *os.File) (*os.File, error) { return nil, errUnsupported }
@@ -0,0 +1,89 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// traceParentKey is the W3C trace context header carrying the parent span.
+	traceParentKey = "traceparent"
+	// uploadTimeout bounds span and diagnostics uploads when their context has no deadline.
+	uploadTimeout = 10 * time.Second
+)
+
+var _ otlptrace.Client = (*spanClient)(nil)
+
+// spanClient exports spans to the manager, which forwards them to its
+// trace collector, because the CVM has no network route to the collector.
+type spanClient struct {
+	dial func() (net.Conn, error)
+}
+
+// NewSpanClient returns an OTLP trace client sending spans on the connections returned by dial.
+func NewSpanClient(dial func() (net.Conn, error)) otlptrace.Client {
+	return &spanClient{dial: dial}
+}
+
+func (c *spanClient) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *spanClient) Stop(ctx context.Context) error {
+	return nil
+}
+
+// UploadTraces sends the spans in a single message and waits for the manager to acknowledge them.
+func (c *spanClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	payload, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+
+	_, err = deliver(ctx, c.dial, msgSpans, payload)
+
+	return err
+}
+
+// deliver sends the payload in a single message on a new connection returned
+// by dial, waits for the manager to acknowledge it and returns the payload of
+// the acknowledgement. The message carries the trace context of ctx.
+func deliver(ctx context.Context, dial func() (net.Conn, error), typ messageType, payload []byte) ([]byte, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(uploadTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := writeMessage(conn, message{typ: typ, sentAt: time.Now().UnixNano(), trace: trace.SpanContextFromContext(ctx), payload: payload}); err != nil {
+		return nil, err
+	}
+
+	ack, err := readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if ack.typ != msgAck {
+		return nil, fmt.Errorf("%w: type %d", ErrUnexpectedMessage, ack.typ)
+	}
+
+	return ack.payload, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package vsock
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestUploadTraces(t *testing.T) {
+	spans := []*tracepb.ResourceSpans{
+		{
+			ScopeSpans: []*tracepb.ScopeSpans{
+				{Spans: []*tracepb.Span{{Name: "run_computation"}}},
+			},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		accept   bool
+		err      bool
+		received int
+	}{
+		{
+			desc:     "monitor accepting spans",
+			accept:   true,
+			received: 1,
+		},
+		{
+			desc: "monitor not accepting spans",
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			received := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+
+			var opts []MonitorOption
+			if tc.accept {
+				opts = append(opts, WithSpans(func(id uint32, req *coltracepb.ExportTraceServiceRequest) {
+					assert.Equal(t, uint32(testCID), id)
+					received <- req
+				}))
+			}
+
+			l := listen(t)
+			monitor := NewMonitor(testInterval, 3, slog.Default(), opts...)
+			go func() {
+				_ = monitor.Serve(l, func(net.Addr) (uint32, error) { return testCID, nil })
+			}()
+
+			client := NewSpanClient(func() (net.Conn, error) {
+				return net.Dial("tcp", l.Addr().String())
+			})
+			require.NoError(t, client.Start(context.Background()))
+			defer client.Stop(context.Background())
+
+			err := client.UploadTraces(context.Background(), spans)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			req := <-received
+			require.Len(t, req.ResourceSpans, tc.received)
+			assert.Equal(t, "run_computation", req.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+			assert.Empty(t, monitor.Unhealthy(time.Now().Add(time.Hour)), "spans are not heartbeats")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vsock implements the heartbeat protocol the agent uses to report its
+// liveness to the manager over virtio vsock. The agent sends sequenced
+// heartbeats to the host, the manager acknowledges each of them so the agent
+// can measure the round trip time, and marks CVMs that stop sending them as
+// unhealthy. Acknowledgements carry the trace context of the computation, and
+// the agent exports its spans over the same protocol so they join the trace
+// of the manager.
+//
+// A Session multiplexes logical channels, e.g. agent logs, agent events and
+// control messages, over one vsock connection. Frames carry the channel ID,
+// and every channel has its own flow control window, so a channel whose reader
+// falls behind does not block the others.
+package vsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/mdlayher/vsock"
+)
+
+var errNotVsock = errors.New("not a vsock address")
+
+// Listener is a vsock listener whose socket can be handed off to another
+// process, e.g. the manager replacing this one during an upgrade.
+type Listener struct {
+	*vsock.Listener
+	file *os.File
+}
+
+// Listen listens for guest connections on the host vsock port.
+func Listen(port uint32) (*Listener, error) {
+	f, err := listenFile(vsock.Host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	return FileListener(f)
+}
+
+// FileListener serves the listening vsock socket f, e.g. one inherited from
+// the process that handed it off. The listener takes ownership of f.
+func FileListener(f *os.File) (*Listener, error) {
+	if err := closeOnExec(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l, err := vsock.FileListener(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Listener{Listener: l, file: f}, nil
+}
+
+// File returns a copy of the listening socket that is inherited by executed
+// programs. Connections keep queuing on the socket until the process that
+// receives it serves it with FileListener, even once l is closed.
+func (l *Listener) File() (*os.File, error) {
+	return inheritableDup(l.file)
+}
+
+// Close stops accepting connections and closes the socket unless it was handed off.
+func (l *Listener) Close() error {
+	err := l.Listener.Close()
+	l.file.Close()
+
+	return err
+}
+
+// DialHost connects from the guest to the host vsock port.
+func DialHost(port uint32) (net.Conn, error) {
+	return vsock.Dial(vsock.Host, port, nil)
+}
+
+// ContextID returns the context ID of the VM at the vsock address.
+func ContextID(addr net.Addr) (uint32, error) {
+	a, ok := addr.(*vsock.Addr)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", errNotVsock, addr)
+	}
+
+	return a.ContextID, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/audit"
+)
+
+var _ manager.Service = (*auditMiddleware)(nil)
+
+// auditMiddleware records the control-plane operations in the audit log, the
+// other methods are passed through to the embedded service.
+type auditMiddleware struct {
+	manager.Service
+	log    *audit.Log
+	admins manager.Admins
+	logger *slog.Logger
+}
+
+// AuditMiddleware records the CVM lifecycle, backend info, capabilities, quota
+// and audit log export calls to the service in log, and serves the log export
+// to the admins.
+func AuditMiddleware(svc manager.Service, log *audit.Log, admins manager.Admins, logger *slog.Logger) manager.Service {
+	return &auditMiddleware{svc, log, admins, logger}
+}
+
+// record appends the call to the audit log. The call already happened, so a
+// failure to record it is logged rather than returned.
+func (am *auditMiddleware) record(ctx context.Context, operation string, begin time.Time, err error, params ...any) {
+	caller, addr := audit.Caller(ctx)
+	rec := audit.Record{
+		Operation:    operation,
+		Caller:       caller,
+		Address:      addr,
+		ParamsDigest: audit.Digest(params...),
+		Outcome:      audit.OutcomeOK,
+		StartedAt:    begin,
+		EndedAt:      time.Now(),
+	}
+	if err != nil {
+		rec.Outcome = audit.OutcomeError
+		rec.Error = err.Error()
+	}
+
+	if err := am.log.Append(rec); err != nil {
+		am.logger.Error(fmt.Sprintf("Failed to record %s call in the audit log: %s", operation, err))
+	}
+}
+
+func (am *auditMiddleware) CreateVM(ctx context.Context, req *manager.CreateReq) (agentAddr string, id string, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "CreateVM", begin, err, req)
+	}(time.Now())
+
+	return am.Service.CreateVM(ctx, req)
+}
+
+func (am *auditMiddleware) RemoveVM(ctx context.Context, computationID string) (err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "RemoveVM", begin, err, computationID)
+	}(time.Now())
+
+	return am.Service.RemoveVM(ctx, computationID)
+}
+
+func (am *auditMiddleware) StopVM(ctx context.Context, computationID string) (state string, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "StopVM", begin, err, computationID)
+	}(time.Now())
+
+	return am.Service.StopVM(ctx, computationID)
+}
+
+func (am *auditMiddleware) AttachDataset(ctx context.Context, computationID, diskPath string) (err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "AttachDataset", begin, err, computationID, diskPath)
+	}(time.Now())
+
+	return am.Service.AttachDataset(ctx, computationID, diskPath)
+}
+
+func (am *auditMiddleware) FetchAttestationPolicy(ctx context.Context, computationID string) (policy []byte, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "FetchAttestationPolicy", begin, err, computationID)
+	}(time.Now())
+
+	return am.Service.FetchAttestationPolicy(ctx, computationID)
+}
+
+func (am *auditMiddleware) HostCapabilities(ctx context.Context) (caps *manager.HostCapabilities, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "HostCapabilities", begin, err)
+	}(time.Now())
+
+	return am.Service.HostCapabilities(ctx)
+}
+
+func (am *auditMiddleware) SetTenantQuota(ctx context.Context, tenant string, quota *manager.TenantQuota) (usage *manager.TenantUsage, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "SetTenantQuota", begin, err, tenant, quota)
+	}(time.Now())
+
+	return am.Service.SetTenantQuota(ctx, tenant, quota)
+}
+
+// AuditLog exports the audit log to the admins, as it holds the callers and
+// parameter digests of every tenant. The export is recorded once it is read,
+// so it is part of the next export.
+func (am *auditMiddleware) AuditLog(ctx context.Context) (res *manager.AuditLogRes, err error) {
+	defer func(begin time.Time) {
+		am.record(ctx, "AuditLog", begin, err)
+	}(time.Now())
+
+	if err := am.admins.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	records, count, head, err := am.log.Export()
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.AuditLogRes{Records: records, Count: count, Head: head}, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager"
+	"github.com/ultravioletrs/cocos/manager/audit"
+	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// clientContext returns the context of a call from addr by the client whose
+// verified certificate has the common name.
+func clientContext(addr net.Addr, commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     addr,
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	log, err := audit.Open(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer log.Close()
+
+	errStop := errors.New("stop failed")
+	req := &manager.CreateReq{Tenant: "acme"}
+
+	svc := new(mocks.Service)
+	svc.On("CreateVM", mock.Anything, req).Return("6100", "vm1", nil)
+	svc.On("StopVM", mock.Anything, "vm1").Return("", errStop)
+	svc.On("GetImages", mock.Anything).Return([]*manager.Image{}, nil)
+	svc.On("HostCapabilities", mock.Anything).Return(&manager.HostCapabilities{}, nil)
+
+	am := AuditMiddleware(svc, log, manager.NewAdmins([]string{"CN=admin"}), slog.Default())
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+	_, _, err = am.CreateVM(ctx, req)
+	require.NoError(t, err)
+	_, err = am.StopVM(ctx, "vm1")
+	assert.ErrorIs(t, err, errStop)
+	_, err = am.GetImages(ctx)
+	require.NoError(t, err)
+	_, err = am.HostCapabilities(ctx)
+	require.NoError(t, err)
+
+	res, err := am.AuditLog(clientContext(addr, "admin"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), res.Count, "only control-plane operations are recorded")
+
+	count, head, err := audit.Verify(bytes.NewReader(res.Records))
+	require.NoError(t, err)
+	assert.Equal(t, res.Count, count)
+	assert.Equal(t, res.Head, head)
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(bytes.NewReader(res.Records))
+	for scanner.Scan() {
+		var rec audit.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "CreateVM", records[0].Operation)
+	assert.Equal(t, audit.AnonymousCaller, records[0].Caller)
+	assert.Equal(t, "10.0.0.1:4321", records[0].Address)
+	assert.Equal(t, audit.Digest(req), records[0].ParamsDigest)
+	assert.Equal(t, audit.OutcomeOK, records[0].Outcome)
+	assert.False(t, records[0].EndedAt.Before(records[0].StartedAt))
+
+	assert.Equal(t, "StopVM", records[1].Operation)
+	assert.Equal(t, audit.OutcomeError, records[1].Outcome)
+	assert.Equal(t, errStop.Error(), records[1].Error)
+
+	assert.Equal(t, "HostCapabilities", records[2].Operation)
+
+	res, err = am.AuditLog(clientContext(addr, "admin"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), res.Count, "exports are recorded")
+
+	svc.AssertExpectations(t)
+}
+
+func TestAuditMiddlewareExportAdmins(t *testing.T) {
+	log, err := audit.Open(audit.Config{File: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer log.Close()
+
+	am := AuditMiddleware(new(mocks.Service), log, manager.NewAdmins([]string{"CN=admin"}), slog.Default())
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}
+
+	for _, ctx := range []context.Context{
+		peer.NewContext(context.Background(), &peer.Peer{Addr: addr}),
+		clientContext(addr, "acme"),
+	} {
+		res, err := am.AuditLog(ctx)
+		assert.ErrorIs(t, err, manager.ErrUnauthorizedAccess)
+		assert.Nil(t, res)
+	}
+
+	res, err := am.AuditLog(clientContext(addr, "admin"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), res.Count, "refused exports are recorded")
+}
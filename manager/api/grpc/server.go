@@ -7,6 +7,9 @@ import (
 	"errors"
 
 	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -30,7 +33,7 @@ func NewServer(svc manager.Service) manager.ManagerServiceServer {
 func (s *grpcServer) CreateVm(ctx context.Context, req *manager.CreateReq) (*manager.CreateRes, error) {
 	port, id, err := s.svc.CreateVM(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, launchStatus(err)
 	}
 
 	return &manager.CreateRes{
@@ -47,6 +50,26 @@ func (s *grpcServer) RemoveVm(ctx context.Context, req *manager.RemoveReq) (*emp
 	return &emptypb.Empty{}, nil
 }
 
+func (s *grpcServer) StopVm(ctx context.Context, req *manager.StopReq) (*manager.StopRes, error) {
+	state, err := s.svc.StopVM(ctx, req.CvmId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.StopRes{
+		CvmId: req.CvmId,
+		State: state,
+	}, nil
+}
+
+func (s *grpcServer) AttachDataset(ctx context.Context, req *manager.AttachDatasetReq) (*emptypb.Empty, error) {
+	if err := s.svc.AttachDataset(ctx, req.CvmId, req.DiskPath); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
 func (s *grpcServer) CVMInfo(ctx context.Context, req *manager.CVMInfoReq) (*manager.CVMInfoRes, error) {
 	ovmf, cpunum, cputype, eosversion := s.svc.ReturnCVMInfo(ctx)
 
@@ -70,3 +93,175 @@ func (s *grpcServer) AttestationPolicy(ctx context.Context, req *manager.Attesta
 		Id:   req.Id,
 	}, nil
 }
+
+func (s *grpcServer) GetImages(ctx context.Context, req *manager.GetImagesReq) (*manager.GetImagesRes, error) {
+	images, err := s.svc.GetImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.GetImagesRes{Images: images}, nil
+}
+
+func (s *grpcServer) HostCapabilities(ctx context.Context, req *manager.HostCapabilitiesReq) (*manager.HostCapabilitiesRes, error) {
+	caps, err := s.svc.HostCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.HostCapabilitiesRes{Capabilities: caps}, nil
+}
+
+func (s *grpcServer) Diagnostics(ctx context.Context, req *manager.DiagnosticsReq) (*manager.DiagnosticsRes, error) {
+	diagnostics, err := s.svc.Diagnostics(ctx, req.CvmId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.DiagnosticsRes{Diagnostics: diagnostics}, nil
+}
+
+func (s *grpcServer) Timeline(ctx context.Context, req *manager.TimelineReq) (*manager.TimelineRes, error) {
+	timeline, err := s.svc.Timeline(ctx, req.CvmId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.TimelineRes{Timeline: timeline}, nil
+}
+
+func (s *grpcServer) DownloadLogs(ctx context.Context, req *manager.DownloadLogsReq) (*manager.DownloadLogsRes, error) {
+	bundle, err := s.svc.DownloadLogs(ctx, req.CvmId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.DownloadLogsRes{Bundle: bundle}, nil
+}
+
+func (s *grpcServer) SNPCertChain(ctx context.Context, req *manager.SNPCertChainReq) (*manager.SNPCertChainRes, error) {
+	chain, err := s.svc.SNPCertChain(ctx, req.Product, req.ChipId, req.ReportedTcb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.SNPCertChainRes{Chain: chain}, nil
+}
+
+func (s *grpcServer) SetTenantQuota(ctx context.Context, req *manager.SetTenantQuotaReq) (*manager.SetTenantQuotaRes, error) {
+	usage, err := s.svc.SetTenantQuota(ctx, req.Tenant, req.Quota)
+	if errors.Is(err, manager.ErrUnauthorizedAccess) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := req.Tenant
+	if tenant == "" {
+		tenant = manager.DefaultTenant
+	}
+
+	return &manager.SetTenantQuotaRes{Tenant: tenant, Quota: req.Quota, Usage: usage}, nil
+}
+
+func (s *grpcServer) AuditLog(ctx context.Context, req *manager.AuditLogReq) (*manager.AuditLogRes, error) {
+	res, err := s.svc.AuditLog(ctx)
+	if errors.Is(err, manager.ErrUnauthorizedAccess) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return res, err
+}
+
+func (s *grpcServer) WatchComputation(req *manager.WatchComputationReq, stream grpc.ServerStreamingServer[manager.ComputationEvent]) error {
+	events, err := s.svc.WatchComputation(stream.Context(), req.CvmId)
+	if err != nil {
+		return err
+	}
+
+	for event := range events {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *grpcServer) Logs(req *manager.LogsReq, stream grpc.ServerStreamingServer[manager.LogChunk]) error {
+	filter := manager.LogsFilter{Tail: -1, Follow: req.Follow}
+	if req.Since != nil {
+		filter.Since = req.Since.AsTime()
+	}
+	if req.Tail != nil {
+		filter.Tail = int(req.GetTail())
+	}
+
+	chunks, err := s.svc.Logs(stream.Context(), req.CvmId, filter)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range chunks {
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *grpcServer) Console(req *manager.ConsoleReq, stream grpc.ServerStreamingServer[manager.ConsoleChunk]) error {
+	filter := manager.ConsoleFilter{Tail: -1, Follow: req.Follow}
+	if req.Tail != nil {
+		filter.Tail = int(req.GetTail())
+	}
+
+	chunks, err := s.svc.Console(stream.Context(), req.CvmId, filter)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range chunks {
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// launchStatus returns the status of a failed CVM launch with its ManagerError
+// details, so callers can tell the failure modes apart. Launches for another
+// tenant than the caller's fail with PermissionDenied, launches exceeding the
+// quota of their tenant with ResourceExhausted, launches the host CPUs,
+// NUMA nodes or GPUs cannot satisfy with InvalidArgument, launches requesting
+// GPUs another CVM uses with FailedPrecondition, other errors are returned as
+// they are.
+func launchStatus(err error) error {
+	if errors.Is(err, manager.ErrUnauthorizedAccess) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, manager.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, manager.ErrInvalidPlacement) || errors.Is(err, manager.ErrInvalidGPU) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, manager.ErrGPUInUse) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	var le *manager.LaunchError
+	if !errors.As(err, &le) {
+		return err
+	}
+
+	st, detailsErr := status.New(codes.FailedPrecondition, le.Error()).WithDetails(le.Details())
+	if detailsErr != nil {
+		return err
+	}
+
+	return st.Err()
+}
@@ -8,13 +8,20 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/ultravioletrs/cocos/manager"
 	"github.com/ultravioletrs/cocos/manager/mocks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestNewServer(t *testing.T) {
@@ -108,6 +115,94 @@ func TestCreateVm(t *testing.T) {
 	}
 }
 
+func TestCreateVmLaunchError(t *testing.T) {
+	mockSvc := new(mocks.Service)
+	server := NewServer(mockSvc)
+
+	launchErr := &manager.LaunchError{Stage: manager.StageVsockFailed, Remediation: "load the vhost_vsock module", Err: errors.New("qemu exited")}
+	mockSvc.On("CreateVM", mock.Anything, mock.Anything).Return("", "vm-123", launchErr)
+
+	_, err := server.CreateVm(context.Background(), &manager.CreateReq{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	details, ok := manager.ManagerErrorDetails(err)
+	assert.True(t, ok)
+	assert.True(t, proto.Equal(&manager.ManagerError{
+		Stage:       manager.StageVsockFailed,
+		Message:     "qemu exited",
+		Remediation: "load the vhost_vsock module",
+	}, details))
+}
+
+func TestCreateVmQuotaExceeded(t *testing.T) {
+	mockSvc := new(mocks.Service)
+	server := NewServer(mockSvc)
+
+	quotaErr := fmt.Errorf("%w: tenant acme already runs 1 of 1 CVMs", manager.ErrQuotaExceeded)
+	mockSvc.On("CreateVM", mock.Anything, mock.Anything).Return("", "vm-123", quotaErr)
+
+	_, err := server.CreateVm(context.Background(), &manager.CreateReq{Tenant: "acme"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "tenant acme already runs 1 of 1 CVMs")
+}
+
+func TestCreateVmOtherTenant(t *testing.T) {
+	mockSvc := new(mocks.Service)
+	server := NewServer(mockSvc)
+
+	tenantErr := fmt.Errorf("%w: caller of tenant default cannot create CVMs for tenant acme", manager.ErrUnauthorizedAccess)
+	mockSvc.On("CreateVM", mock.Anything, mock.Anything).Return("", "vm-123", tenantErr)
+
+	_, err := server.CreateVm(context.Background(), &manager.CreateReq{Tenant: "acme"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Contains(t, err.Error(), "cannot create CVMs for tenant acme")
+}
+
+func TestCreateVmInvalidPlacement(t *testing.T) {
+	mockSvc := new(mocks.Service)
+	server := NewServer(mockSvc)
+
+	placementErr := fmt.Errorf("%w: host has no NUMA node 2", manager.ErrInvalidPlacement)
+	mockSvc.On("CreateVM", mock.Anything, mock.Anything).Return("", "vm-123", placementErr)
+
+	node := uint32(2)
+	_, err := server.CreateVm(context.Background(), &manager.CreateReq{NumaNode: &node})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "host has no NUMA node 2")
+}
+
+func TestCreateVmGPUs(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+		code codes.Code
+	}{
+		{
+			desc: "GPU bound to its host driver",
+			err:  fmt.Errorf("%w: device 0000:41:00.0 of IOMMU group 12 is bound to nvidia, bind it to vfio-pci", manager.ErrInvalidGPU),
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "GPU passed through to another CVM",
+			err:  fmt.Errorf("%w: the IOMMU group of GPU 0000:41:00.0 is passed through to CVM vm-1", manager.ErrGPUInUse),
+			code: codes.FailedPrecondition,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("CreateVM", mock.Anything, mock.Anything).Return("", "vm-123", tc.err)
+
+			_, err := server.CreateVm(context.Background(), &manager.CreateReq{GpuDevices: []string{"0000:41:00.0"}})
+			assert.Equal(t, tc.code, status.Code(err))
+			assert.Contains(t, err.Error(), "0000:41:00.0")
+		})
+	}
+}
+
 func TestRemoveVm(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -172,6 +267,97 @@ func TestRemoveVm(t *testing.T) {
 	}
 }
 
+func TestStopVm(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *manager.StopReq
+		mockState   string
+		mockErr     error
+		expectedRes *manager.StopRes
+		expectedErr error
+	}{
+		{
+			name:      "successful VM stop",
+			req:       &manager.StopReq{CvmId: "cvm-123"},
+			mockState: "StopComputationRun",
+			expectedRes: &manager.StopRes{
+				CvmId: "cvm-123",
+				State: "StopComputationRun",
+			},
+		},
+		{
+			name:        "VM stop failure",
+			req:         &manager.StopReq{CvmId: "cvm-456"},
+			mockErr:     errors.New("failed to stop VM"),
+			expectedErr: errors.New("failed to stop VM"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("StopVM", mock.Anything, tt.req.CvmId).Return(tt.mockState, tt.mockErr)
+
+			res, err := server.StopVm(context.Background(), tt.req)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAttachDataset(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *manager.AttachDatasetReq
+		mockErr     error
+		expectedErr error
+	}{
+		{
+			name: "successful dataset attach",
+			req:  &manager.AttachDatasetReq{CvmId: "cvm-123", DiskPath: "/data/dataset.img"},
+		},
+		{
+			name:        "dataset attach failure",
+			req:         &manager.AttachDatasetReq{CvmId: "cvm-456", DiskPath: "/data/dataset.img"},
+			mockErr:     errors.New("failed to attach dataset disk"),
+			expectedErr: errors.New("failed to attach dataset disk"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("AttachDataset", mock.Anything, tt.req.CvmId, tt.req.DiskPath).Return(tt.mockErr)
+
+			res, err := server.AttachDataset(context.Background(), tt.req)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, &emptypb.Empty{}, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestCVMInfo(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -363,6 +549,390 @@ func TestAttestationPolicy(t *testing.T) {
 	}
 }
 
+func TestGetImages(t *testing.T) {
+	images := []*manager.Image{
+		{Name: "kernel", Path: "img/bzImage", Version: "v0.1.0", Digest: "abcd"},
+		{Name: "rootfs", Path: "img/rootfs.cpio.gz", Version: "v0.1.0", Digest: "ef01"},
+	}
+
+	tests := []struct {
+		name        string
+		mockImages  []*manager.Image
+		mockErr     error
+		expectedRes *manager.GetImagesRes
+		expectedErr error
+	}{
+		{
+			name:        "successful images retrieval",
+			mockImages:  images,
+			expectedRes: &manager.GetImagesRes{Images: images},
+		},
+		{
+			name:        "images retrieval failure",
+			mockErr:     errors.New("failed to read image"),
+			expectedErr: errors.New("failed to read image"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("GetImages", mock.Anything).Return(tt.mockImages, tt.mockErr)
+
+			res, err := server.GetImages(context.Background(), &manager.GetImagesReq{})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHostCapabilities(t *testing.T) {
+	caps := &manager.HostCapabilities{KernelVersion: "6.11.0-snp-host", SevSnp: true, Kvm: true, Vsock: true}
+
+	tests := []struct {
+		name        string
+		mockCaps    *manager.HostCapabilities
+		mockErr     error
+		expectedRes *manager.HostCapabilitiesRes
+		expectedErr error
+	}{
+		{
+			name:        "successful capabilities retrieval",
+			mockCaps:    caps,
+			expectedRes: &manager.HostCapabilitiesRes{Capabilities: caps},
+		},
+		{
+			name:        "capabilities retrieval failure",
+			mockErr:     errors.New("failed to detect capabilities"),
+			expectedErr: errors.New("failed to detect capabilities"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("HostCapabilities", mock.Anything).Return(tt.mockCaps, tt.mockErr)
+
+			res, err := server.HostCapabilities(context.Background(), &manager.HostCapabilitiesReq{})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	diagnostics := &manager.Diagnostics{CvmId: "vm1", Snapshot: []byte(`{"state":"Running"}`)}
+
+	tests := []struct {
+		name            string
+		mockDiagnostics *manager.Diagnostics
+		mockErr         error
+		expectedRes     *manager.DiagnosticsRes
+		expectedErr     error
+	}{
+		{
+			name:            "successful diagnostics retrieval",
+			mockDiagnostics: diagnostics,
+			expectedRes:     &manager.DiagnosticsRes{Diagnostics: diagnostics},
+		},
+		{
+			name:        "no diagnostic snapshot",
+			mockErr:     manager.ErrDiagnosticsNotFound,
+			expectedErr: manager.ErrDiagnosticsNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("Diagnostics", mock.Anything, "vm1").Return(tt.mockDiagnostics, tt.mockErr)
+
+			res, err := server.Diagnostics(context.Background(), &manager.DiagnosticsReq{CvmId: "vm1"})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	timeline := &manager.Timeline{
+		CvmId:  "vm1",
+		Phases: []*manager.TimelinePhase{{Name: "running", Start: timestamppb.Now()}},
+	}
+
+	tests := []struct {
+		name         string
+		mockTimeline *manager.Timeline
+		mockErr      error
+		expectedRes  *manager.TimelineRes
+		expectedErr  error
+	}{
+		{
+			name:         "successful timeline retrieval",
+			mockTimeline: timeline,
+			expectedRes:  &manager.TimelineRes{Timeline: timeline},
+		},
+		{
+			name:        "CVM not found",
+			mockErr:     manager.ErrNotFound,
+			expectedErr: manager.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("Timeline", mock.Anything, "vm1").Return(tt.mockTimeline, tt.mockErr)
+
+			res, err := server.Timeline(context.Background(), &manager.TimelineReq{CvmId: "vm1"})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDownloadLogs(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockBundle  []byte
+		mockErr     error
+		expectedRes *manager.DownloadLogsRes
+		expectedErr error
+	}{
+		{
+			name:        "successful logs download",
+			mockBundle:  []byte("bundle"),
+			expectedRes: &manager.DownloadLogsRes{Bundle: []byte("bundle")},
+		},
+		{
+			name:        "CVM not found",
+			mockErr:     manager.ErrNotFound,
+			expectedErr: manager.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("DownloadLogs", mock.Anything, "vm1").Return(tt.mockBundle, tt.mockErr)
+
+			res, err := server.DownloadLogs(context.Background(), &manager.DownloadLogsReq{CvmId: "vm1"})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSNPCertChain(t *testing.T) {
+	chain := &manager.SNPCertChain{Ark: []byte("ark"), Ask: []byte("ask"), Vcek: []byte("vcek")}
+
+	tests := []struct {
+		name        string
+		mockChain   *manager.SNPCertChain
+		mockErr     error
+		expectedRes *manager.SNPCertChainRes
+		expectedErr error
+	}{
+		{
+			name:        "successful certificate chain retrieval",
+			mockChain:   chain,
+			expectedRes: &manager.SNPCertChainRes{Chain: chain},
+		},
+		{
+			name:        "certificate cache disabled",
+			mockErr:     manager.ErrSNPCertsDisabled,
+			expectedErr: manager.ErrSNPCertsDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("SNPCertChain", mock.Anything, "Milan", []byte("chip"), uint64(0x7300000000000003)).Return(tt.mockChain, tt.mockErr)
+
+			res, err := server.SNPCertChain(context.Background(), &manager.SNPCertChainReq{Product: "Milan", ChipId: []byte("chip"), ReportedTcb: 0x7300000000000003})
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetTenantQuota(t *testing.T) {
+	quota := &manager.TenantQuota{MaxVms: 2, MaxVcpus: 8, MaxMemoryMb: 16384}
+	usage := &manager.TenantUsage{Vms: 1, Vcpus: 4, MemoryMb: 8192}
+	adminErr := fmt.Errorf("%w: anonymous is not an admin", manager.ErrUnauthorizedAccess)
+
+	tests := []struct {
+		name        string
+		req         *manager.SetTenantQuotaReq
+		tenant      string
+		mockUsage   *manager.TenantUsage
+		mockErr     error
+		expectedRes *manager.SetTenantQuotaRes
+		expectedErr error
+	}{
+		{
+			name:        "successful quota update",
+			req:         &manager.SetTenantQuotaReq{Tenant: "acme", Quota: quota},
+			tenant:      "acme",
+			mockUsage:   usage,
+			expectedRes: &manager.SetTenantQuotaRes{Tenant: "acme", Quota: quota, Usage: usage},
+		},
+		{
+			name:        "default tenant",
+			req:         &manager.SetTenantQuotaReq{Quota: quota},
+			mockUsage:   &manager.TenantUsage{},
+			expectedRes: &manager.SetTenantQuotaRes{Tenant: manager.DefaultTenant, Quota: quota, Usage: &manager.TenantUsage{}},
+		},
+		{
+			name:        "missing quota",
+			req:         &manager.SetTenantQuotaReq{Tenant: "acme"},
+			tenant:      "acme",
+			mockErr:     manager.ErrMalformedEntity,
+			expectedErr: manager.ErrMalformedEntity,
+		},
+		{
+			name:        "caller is not an admin",
+			req:         &manager.SetTenantQuotaReq{Tenant: "acme", Quota: quota},
+			tenant:      "acme",
+			mockErr:     adminErr,
+			expectedErr: status.Error(codes.PermissionDenied, adminErr.Error()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("SetTenantQuota", mock.Anything, tt.tenant, tt.req.Quota).Return(tt.mockUsage, tt.mockErr)
+
+			res, err := server.SetTenantQuota(context.Background(), tt.req)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedErr.Error(), err.Error())
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockRes     *manager.AuditLogRes
+		mockErr     error
+		expectedErr error
+		code        codes.Code
+	}{
+		{
+			name:    "successful export",
+			mockRes: &manager.AuditLogRes{Records: []byte("{\"seq\":1}\n"), Count: 1, Head: "abc"},
+		},
+		{
+			name:        "audit log disabled",
+			mockErr:     manager.ErrAuditDisabled,
+			expectedErr: manager.ErrAuditDisabled,
+		},
+		{
+			name:    "caller is not an admin",
+			mockErr: fmt.Errorf("%w: anonymous is not an admin", manager.ErrUnauthorizedAccess),
+			code:    codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			mockSvc.On("AuditLog", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			res, err := server.AuditLog(context.Background(), &manager.AuditLogReq{})
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+			} else {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			assert.Equal(t, tt.mockRes, res)
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestContextCancellation(t *testing.T) {
 	t.Run("CreateVm with cancelled context", func(t *testing.T) {
 		mockSvc := new(mocks.Service)
@@ -423,3 +993,262 @@ func TestErrorHandling(t *testing.T) {
 		mockSvc.AssertExpectations(t)
 	})
 }
+
+type watchStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sent    []*manager.ComputationEvent
+	sendErr error
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(event *manager.ComputationEvent) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, event)
+	return nil
+}
+
+func TestWatchComputation(t *testing.T) {
+	events := []*manager.ComputationEvent{
+		{CvmId: "vm-123", EventType: manager.EventState, State: "VmRunning"},
+		{CvmId: "vm-123", EventType: manager.EventVMRemoved, State: "StopComputationRun"},
+	}
+
+	tests := []struct {
+		name        string
+		mockErr     error
+		sendErr     error
+		expectedErr error
+		expectedLen int
+	}{
+		{
+			name:        "stream events until closed",
+			expectedLen: len(events),
+		},
+		{
+			name:        "computation not found",
+			mockErr:     manager.ErrNotFound,
+			expectedErr: manager.ErrNotFound,
+		},
+		{
+			name:        "send failure",
+			sendErr:     errors.New("stream closed"),
+			expectedErr: errors.New("stream closed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			var ch chan *manager.ComputationEvent
+			if tt.mockErr == nil {
+				ch = make(chan *manager.ComputationEvent, len(events))
+				for _, e := range events {
+					ch <- e
+				}
+				close(ch)
+			}
+
+			mockSvc.On("WatchComputation", mock.Anything, "vm-123").Return((<-chan *manager.ComputationEvent)(ch), tt.mockErr)
+
+			stream := &watchStream{ctx: context.Background(), sendErr: tt.sendErr}
+			err := server.WatchComputation(&manager.WatchComputationReq{CvmId: "vm-123"}, stream)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Len(t, stream.sent, tt.expectedLen)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+type logsStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sent    []*manager.LogChunk
+	sendErr error
+}
+
+func (s *logsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *logsStream) Send(chunk *manager.LogChunk) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, chunk)
+	return nil
+}
+
+func TestLogs(t *testing.T) {
+	chunks := []*manager.LogChunk{
+		{CvmId: "vm-123", Stream: "stdout", Data: []byte("epoch 1\n")},
+		{CvmId: "vm-123", Stream: "stderr", Data: []byte("warning\n")},
+	}
+
+	since := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name        string
+		req         *manager.LogsReq
+		filter      manager.LogsFilter
+		mockErr     error
+		sendErr     error
+		expectedErr error
+		expectedLen int
+	}{
+		{
+			name:        "stream logs until closed",
+			req:         &manager.LogsReq{CvmId: "vm-123"},
+			filter:      manager.LogsFilter{Tail: -1},
+			expectedLen: len(chunks),
+		},
+		{
+			name: "follow logs with filters",
+			req: &manager.LogsReq{
+				CvmId:  "vm-123",
+				Since:  timestamppb.New(since),
+				Tail:   proto.Uint32(10),
+				Follow: true,
+			},
+			filter:      manager.LogsFilter{Since: since, Tail: 10, Follow: true},
+			expectedLen: len(chunks),
+		},
+		{
+			name:        "log collection disabled",
+			req:         &manager.LogsReq{CvmId: "vm-123"},
+			filter:      manager.LogsFilter{Tail: -1},
+			mockErr:     manager.ErrLogsDisabled,
+			expectedErr: manager.ErrLogsDisabled,
+		},
+		{
+			name:        "send failure",
+			req:         &manager.LogsReq{CvmId: "vm-123"},
+			filter:      manager.LogsFilter{Tail: -1},
+			sendErr:     errors.New("stream closed"),
+			expectedErr: errors.New("stream closed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			var ch chan *manager.LogChunk
+			if tt.mockErr == nil {
+				ch = make(chan *manager.LogChunk, len(chunks))
+				for _, c := range chunks {
+					ch <- c
+				}
+				close(ch)
+			}
+
+			mockSvc.On("Logs", mock.Anything, "vm-123", mock.MatchedBy(func(filter manager.LogsFilter) bool {
+				return filter.Since.Equal(tt.filter.Since) && filter.Tail == tt.filter.Tail && filter.Follow == tt.filter.Follow
+			})).Return((<-chan *manager.LogChunk)(ch), tt.mockErr)
+
+			stream := &logsStream{ctx: context.Background(), sendErr: tt.sendErr}
+			err := server.Logs(tt.req, stream)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Len(t, stream.sent, tt.expectedLen)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+type consoleStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sent    []*manager.ConsoleChunk
+	sendErr error
+}
+
+func (s *consoleStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *consoleStream) Send(chunk *manager.ConsoleChunk) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, chunk)
+	return nil
+}
+
+func TestConsole(t *testing.T) {
+	chunks := []*manager.ConsoleChunk{
+		{CvmId: "vm-123", Data: []byte("Booting Linux\n")},
+		{CvmId: "vm-123", Data: []byte("Kernel panic - not syncing\n")},
+	}
+
+	tests := []struct {
+		name        string
+		req         *manager.ConsoleReq
+		filter      manager.ConsoleFilter
+		mockErr     error
+		sendErr     error
+		expectedErr error
+		expectedLen int
+	}{
+		{
+			name:        "stream console until closed",
+			req:         &manager.ConsoleReq{CvmId: "vm-123"},
+			filter:      manager.ConsoleFilter{Tail: -1},
+			expectedLen: len(chunks),
+		},
+		{
+			name:        "follow console tail",
+			req:         &manager.ConsoleReq{CvmId: "vm-123", Tail: proto.Uint32(100), Follow: true},
+			filter:      manager.ConsoleFilter{Tail: 100, Follow: true},
+			expectedLen: len(chunks),
+		},
+		{
+			name:        "console capture disabled",
+			req:         &manager.ConsoleReq{CvmId: "vm-123"},
+			filter:      manager.ConsoleFilter{Tail: -1},
+			mockErr:     manager.ErrConsoleDisabled,
+			expectedErr: manager.ErrConsoleDisabled,
+		},
+		{
+			name:        "send failure",
+			req:         &manager.ConsoleReq{CvmId: "vm-123"},
+			filter:      manager.ConsoleFilter{Tail: -1},
+			sendErr:     errors.New("stream closed"),
+			expectedErr: errors.New("stream closed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(mocks.Service)
+			server := NewServer(mockSvc)
+
+			var ch chan *manager.ConsoleChunk
+			if tt.mockErr == nil {
+				ch = make(chan *manager.ConsoleChunk, len(chunks))
+				for _, c := range chunks {
+					ch <- c
+				}
+				close(ch)
+			}
+
+			mockSvc.On("Console", mock.Anything, "vm-123", tt.filter).Return((<-chan *manager.ConsoleChunk)(ch), tt.mockErr)
+
+			stream := &consoleStream{ctx: context.Background(), sendErr: tt.sendErr}
+			err := server.Console(tt.req, stream)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Len(t, stream.sent, tt.expectedLen)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -52,6 +52,32 @@ func (lm *loggingMiddleware) RemoveVM(ctx context.Context, id string) (err error
 	return lm.svc.RemoveVM(ctx, id)
 }
 
+func (lm *loggingMiddleware) StopVM(ctx context.Context, id string) (state string, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method StopVM for vm %s ended in state %s and took %s to complete", id, state, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.StopVM(ctx, id)
+}
+
+func (lm *loggingMiddleware) AttachDataset(ctx context.Context, id, diskPath string) (err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method AttachDataset for vm %s and disk %s took %s to complete", id, diskPath, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.AttachDataset(ctx, id, diskPath)
+}
+
 func (lm *loggingMiddleware) FetchAttestationPolicy(ctx context.Context, cmpId string) (body []byte, err error) {
 	defer func(begin time.Time) {
 		message := fmt.Sprintf("Method FetchAttestation  for computation %s took %s to complete", cmpId, time.Since(begin))
@@ -75,6 +101,149 @@ func (lm *loggingMiddleware) ReturnCVMInfo(ctx context.Context) (string, int, st
 	return lm.svc.ReturnCVMInfo(ctx)
 }
 
+func (lm *loggingMiddleware) GetImages(ctx context.Context) (images []*manager.Image, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method GetImages returned %d images and took %s to complete", len(images), time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.GetImages(ctx)
+}
+
+func (lm *loggingMiddleware) HostCapabilities(ctx context.Context) (caps *manager.HostCapabilities, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method HostCapabilities took %s to complete", time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.HostCapabilities(ctx)
+}
+
+func (lm *loggingMiddleware) Diagnostics(ctx context.Context, computationID string) (diagnostics *manager.Diagnostics, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Diagnostics for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.Diagnostics(ctx, computationID)
+}
+
+func (lm *loggingMiddleware) Timeline(ctx context.Context, computationID string) (timeline *manager.Timeline, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Timeline for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.Timeline(ctx, computationID)
+}
+
+func (lm *loggingMiddleware) DownloadLogs(ctx context.Context, computationID string) (bundle []byte, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method DownloadLogs for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.DownloadLogs(ctx, computationID)
+}
+
+func (lm *loggingMiddleware) SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (chain *manager.SNPCertChain, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method SNPCertChain for %s chip %x at TCB %#x took %s to complete", product, chipID, reportedTCB, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.SNPCertChain(ctx, product, chipID, reportedTCB)
+}
+
+func (lm *loggingMiddleware) SetTenantQuota(ctx context.Context, tenant string, quota *manager.TenantQuota) (usage *manager.TenantUsage, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method SetTenantQuota for tenant %s took %s to complete", tenant, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.SetTenantQuota(ctx, tenant, quota)
+}
+
+func (lm *loggingMiddleware) WatchComputation(ctx context.Context, computationID string) (events <-chan *manager.ComputationEvent, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method WatchComputation for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.WatchComputation(ctx, computationID)
+}
+
+func (lm *loggingMiddleware) Logs(ctx context.Context, computationID string, filter manager.LogsFilter) (chunks <-chan *manager.LogChunk, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Logs for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.Logs(ctx, computationID, filter)
+}
+
+func (lm *loggingMiddleware) AuditLog(ctx context.Context) (res *manager.AuditLogRes, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method AuditLog took %s to complete", time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.AuditLog(ctx)
+}
+
+func (lm *loggingMiddleware) Console(ctx context.Context, computationID string, filter manager.ConsoleFilter) (chunks <-chan *manager.ConsoleChunk, err error) {
+	defer func(begin time.Time) {
+		message := fmt.Sprintf("Method Console for vm %s took %s to complete", computationID, time.Since(begin))
+		if err != nil {
+			lm.logger.Warn(fmt.Sprintf("%s with error: %s.", message, err))
+			return
+		}
+		lm.logger.Info(message)
+	}(time.Now())
+
+	return lm.svc.Console(ctx, computationID, filter)
+}
+
 func (lm *loggingMiddleware) Shutdown() (err error) {
 	defer func(begin time.Time) {
 		if err != nil {
@@ -15,29 +15,70 @@ import (
 
 var _ manager.Service = (*metricsMiddleware)(nil)
 
+// VMMetrics track the CVMs created through the service.
+type VMMetrics struct {
+	// Active is the number of CVMs created and not removed yet.
+	Active metrics.Gauge
+	// BootTime is the time it takes to create and boot a CVM.
+	BootTime metrics.Histogram
+	// BrokenConnections counts the CVMs whose agent stopped sending heartbeats.
+	BrokenConnections metrics.Counter
+}
+
 type metricsMiddleware struct {
 	counter metrics.Counter
 	latency metrics.Histogram
+	vms     VMMetrics
 	svc     manager.Service
 }
 
 // MetricsMiddleware instruments core service by tracking request count and
-// latency.
-func MetricsMiddleware(svc manager.Service, counter metrics.Counter, latency metrics.Histogram) manager.Service {
+// latency, and the number, boot time and broken agent connections of the CVMs.
+func MetricsMiddleware(svc manager.Service, counter metrics.Counter, latency metrics.Histogram, vms VMMetrics) manager.Service {
 	return &metricsMiddleware{
 		counter: counter,
 		latency: latency,
+		vms:     vms,
 		svc:     svc,
 	}
 }
 
 func (ms *metricsMiddleware) CreateVM(ctx context.Context, req *manager.CreateReq) (string, string, error) {
-	defer func(begin time.Time) {
+	begin := time.Now()
+	defer func() {
 		ms.counter.With("method", "Run").Add(1)
 		ms.latency.With("method", "Run").Observe(time.Since(begin).Seconds())
-	}(time.Now())
+	}()
+
+	port, id, err := ms.svc.CreateVM(ctx, req)
+	if err != nil {
+		return port, id, err
+	}
+
+	ms.vms.BootTime.Observe(time.Since(begin).Seconds())
+	ms.watchVM(id)
+
+	return port, id, nil
+}
+
+// watchVM counts the CVM as active until it is removed, which closes its
+// events, and counts the times its agent connection breaks.
+func (ms *metricsMiddleware) watchVM(id string) {
+	events, err := ms.svc.WatchComputation(context.Background(), id)
+	if err != nil {
+		return
+	}
+
+	ms.vms.Active.Add(1)
+	go func() {
+		defer ms.vms.Active.Add(-1)
 
-	return ms.svc.CreateVM(ctx, req)
+		for event := range events {
+			if event.EventType == manager.EventVMUnhealthy {
+				ms.vms.BrokenConnections.Add(1)
+			}
+		}
+	}()
 }
 
 func (ms *metricsMiddleware) RemoveVM(ctx context.Context, computationID string) error {
@@ -49,6 +90,24 @@ func (ms *metricsMiddleware) RemoveVM(ctx context.Context, computationID string)
 	return ms.svc.RemoveVM(ctx, computationID)
 }
 
+func (ms *metricsMiddleware) StopVM(ctx context.Context, computationID string) (string, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "StopVM").Add(1)
+		ms.latency.With("method", "StopVM").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.StopVM(ctx, computationID)
+}
+
+func (ms *metricsMiddleware) AttachDataset(ctx context.Context, computationID, diskPath string) error {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "AttachDataset").Add(1)
+		ms.latency.With("method", "AttachDataset").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.AttachDataset(ctx, computationID, diskPath)
+}
+
 func (ms *metricsMiddleware) FetchAttestationPolicy(ctx context.Context, cmpId string) ([]byte, error) {
 	defer func(begin time.Time) {
 		ms.counter.With("method", "FetchAttestationPolicy").Add(1)
@@ -67,6 +126,105 @@ func (ms *metricsMiddleware) ReturnCVMInfo(ctx context.Context) (string, int, st
 	return ms.svc.ReturnCVMInfo(ctx)
 }
 
+func (ms *metricsMiddleware) GetImages(ctx context.Context) ([]*manager.Image, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "GetImages").Add(1)
+		ms.latency.With("method", "GetImages").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.GetImages(ctx)
+}
+
+func (ms *metricsMiddleware) HostCapabilities(ctx context.Context) (*manager.HostCapabilities, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "HostCapabilities").Add(1)
+		ms.latency.With("method", "HostCapabilities").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.HostCapabilities(ctx)
+}
+
+func (ms *metricsMiddleware) Diagnostics(ctx context.Context, computationID string) (*manager.Diagnostics, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "Diagnostics").Add(1)
+		ms.latency.With("method", "Diagnostics").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Diagnostics(ctx, computationID)
+}
+
+func (ms *metricsMiddleware) Timeline(ctx context.Context, computationID string) (*manager.Timeline, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "Timeline").Add(1)
+		ms.latency.With("method", "Timeline").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Timeline(ctx, computationID)
+}
+
+func (ms *metricsMiddleware) DownloadLogs(ctx context.Context, computationID string) ([]byte, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "DownloadLogs").Add(1)
+		ms.latency.With("method", "DownloadLogs").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.DownloadLogs(ctx, computationID)
+}
+
+func (ms *metricsMiddleware) SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*manager.SNPCertChain, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "SNPCertChain").Add(1)
+		ms.latency.With("method", "SNPCertChain").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.SNPCertChain(ctx, product, chipID, reportedTCB)
+}
+
+func (ms *metricsMiddleware) SetTenantQuota(ctx context.Context, tenant string, quota *manager.TenantQuota) (*manager.TenantUsage, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "SetTenantQuota").Add(1)
+		ms.latency.With("method", "SetTenantQuota").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.SetTenantQuota(ctx, tenant, quota)
+}
+
+func (ms *metricsMiddleware) WatchComputation(ctx context.Context, computationID string) (<-chan *manager.ComputationEvent, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "WatchComputation").Add(1)
+		ms.latency.With("method", "WatchComputation").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.WatchComputation(ctx, computationID)
+}
+
+func (ms *metricsMiddleware) Logs(ctx context.Context, computationID string, filter manager.LogsFilter) (<-chan *manager.LogChunk, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "Logs").Add(1)
+		ms.latency.With("method", "Logs").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Logs(ctx, computationID, filter)
+}
+
+func (ms *metricsMiddleware) AuditLog(ctx context.Context) (*manager.AuditLogRes, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "AuditLog").Add(1)
+		ms.latency.With("method", "AuditLog").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.AuditLog(ctx)
+}
+
+func (ms *metricsMiddleware) Console(ctx context.Context, computationID string, filter manager.ConsoleFilter) (<-chan *manager.ConsoleChunk, error) {
+	defer func(begin time.Time) {
+		ms.counter.With("method", "Console").Add(1)
+		ms.latency.With("method", "Console").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return ms.svc.Console(ctx, computationID, filter)
+}
+
 func (ms *metricsMiddleware) Shutdown() error {
 	defer func(begin time.Time) {
 		ms.counter.With("method", "Shutdown").Add(1)
@@ -0,0 +1,229 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package artifacts keeps the kernel, root file system and firmware images the
+// manager boots CVMs with in a content-addressed cache. Images referenced by
+// digest are downloaded once, verified against their SHA-256 digest and reused
+// from the cache afterwards.
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+const (
+	// digestPrefix starts a reference to an image of the registry by digest, e.g. sha256:<hex>.
+	digestPrefix = "sha256:"
+	// digestFragment is the URL fragment holding the digest of a downloaded image, e.g. https://host/bzImage#sha256=<hex>.
+	digestFragment = "sha256="
+)
+
+var (
+	// ErrInvalidReference indicates an image reference without a valid SHA-256 digest.
+	ErrInvalidReference = errors.New("invalid image reference")
+	// ErrNoRegistry indicates an image referenced by digest while no registry is configured.
+	ErrNoRegistry = errors.New("no image registry configured")
+	// ErrDownload indicates an image that could not be downloaded.
+	ErrDownload = errors.New("failed to download image")
+	// ErrDigestMismatch indicates a downloaded image whose content does not match its digest.
+	ErrDigestMismatch = errors.New("image digest mismatch")
+)
+
+// Config is where the manager keeps and downloads images from.
+type Config struct {
+	// Dir holds the cached images, named after their digest.
+	Dir string `env:"MANAGER_ARTIFACTS_DIR"          envDefault:"/var/cache/cocos/artifacts"`
+	// RegistryURL serves images referenced by digest under <url>/sha256/<hex>.
+	RegistryURL string `env:"MANAGER_ARTIFACTS_REGISTRY_URL" envDefault:""`
+}
+
+// Cache downloads images and keeps them under their digest.
+type Cache struct {
+	dir      string
+	registry string
+	client   *http.Client
+}
+
+// New returns the cache of the configuration, images are downloaded with
+// http.DefaultClient if client is nil. The cache directory is created on the
+// first download.
+func New(cfg Config, client *http.Client) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Cache{dir: cfg.Dir, registry: strings.TrimSuffix(cfg.RegistryURL, "/"), client: client}
+}
+
+// IsReference reports whether the image is referenced by digest, either in the
+// registry, sha256:<hex>, or at a URL, http(s)://...#sha256=<hex>, rather
+// than by a path on the host.
+func IsReference(image string) bool {
+	return strings.HasPrefix(image, digestPrefix) || strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// Fetch returns the path of the image in the cache, downloading it on first
+// use. An image that is not a reference is a path on the host and returned as is.
+func (c *Cache) Fetch(ctx context.Context, image string) (string, error) {
+	if !IsReference(image) {
+		return image, nil
+	}
+
+	source, digest, err := c.parse(image)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(c.dir, "sha256", digest)
+	// Cached images are verified again, a corrupted copy is downloaded anew.
+	if sum, err := fileDigest(path); err == nil && sum == digest {
+		return path, nil
+	}
+
+	if err := c.download(ctx, source, digest, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// parse returns the URL the image is downloaded from and its hex encoded digest.
+func (c *Cache) parse(image string) (string, string, error) {
+	if digest, ok := strings.CutPrefix(image, digestPrefix); ok {
+		if err := validDigest(digest); err != nil {
+			return "", "", err
+		}
+		if c.registry == "" {
+			return "", "", fmt.Errorf("%w for %s", ErrNoRegistry, image)
+		}
+
+		return c.registry + "/sha256/" + digest, digest, nil
+	}
+
+	u, err := url.Parse(image)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidReference, err)
+	}
+
+	digest, ok := strings.CutPrefix(u.Fragment, digestFragment)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s has no #%s<hex> digest", ErrInvalidReference, image, digestFragment)
+	}
+	if err := validDigest(digest); err != nil {
+		return "", "", err
+	}
+	u.Fragment = ""
+
+	return u.String(), digest, nil
+}
+
+// download writes the image to path once its content matches the digest.
+func (c *Cache) download(ctx context.Context, source, digest, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return fmt.Errorf("%w from %s: %w", ErrDownload, source, err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w from %s: %w", ErrDownload, source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w from %s: %s", ErrDownload, source, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// The image is downloaded next to its final path, so it is renamed into place atomically.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%w from %s: %w", ErrDownload, source, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != digest {
+		return fmt.Errorf("%w: %s is sha256:%s, expected sha256:%s", ErrDigestMismatch, source, sum, digest)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Resolve replaces the images of the QEMU configuration referenced by digest
+// with their path in the cache. Only the firmware of the enabled TEE backend
+// is fetched.
+func (c *Cache) Resolve(ctx context.Context, cfg *qemu.Config) error {
+	images := []*string{&cfg.DiskImgConfig.KernelFile, &cfg.DiskImgConfig.RootFsFile}
+
+	switch {
+	case cfg.EnableSEVSNP && cfg.SEVSNPDirectBoot():
+		images = append(images, &cfg.SEVSNPConfig.OVMF)
+	case cfg.EnableSEVSNP:
+		images = append(images, &cfg.IGVMConfig.File)
+	case cfg.EnableTDX:
+		images = append(images, &cfg.TDXConfig.OVMF)
+	default:
+		images = append(images, &cfg.OVMFCodeConfig.File)
+	}
+
+	for _, image := range images {
+		path, err := c.Fetch(ctx, *image)
+		if err != nil {
+			return err
+		}
+		*image = path
+	}
+
+	return nil
+}
+
+func validDigest(digest string) error {
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size || strings.ToLower(digest) != digest {
+		return fmt.Errorf("%w: %q is not a lower case hex encoded SHA-256 digest", ErrInvalidReference, digest)
+	}
+
+	return nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// serve serves the images by path and counts the requests.
+func serve(t *testing.T, images map[string][]byte) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		data, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestFetch(t *testing.T) {
+	kernel := []byte("kernel image")
+	rootfs := []byte("root file system")
+
+	srv, requests := serve(t, map[string][]byte{
+		"/bzImage":                           kernel,
+		"/sha256/" + digestOf(rootfs):        rootfs,
+		"/tampered":                          []byte("tampered image"),
+		"/sha256/" + digestOf([]byte("any")): []byte("other image"),
+	})
+
+	cases := []struct {
+		desc  string
+		image string
+		data  []byte
+		err   error
+	}{
+		{
+			desc:  "host path",
+			image: "/usr/share/OVMF/OVMF_CODE.fd",
+		},
+		{
+			desc:  "URL with digest",
+			image: srv.URL + "/bzImage#sha256=" + digestOf(kernel),
+			data:  kernel,
+		},
+		{
+			desc:  "registry digest",
+			image: "sha256:" + digestOf(rootfs),
+			data:  rootfs,
+		},
+		{
+			desc:  "URL without digest",
+			image: srv.URL + "/bzImage",
+			err:   ErrInvalidReference,
+		},
+		{
+			desc:  "malformed digest",
+			image: "sha256:" + digestOf(rootfs)[:10],
+			err:   ErrInvalidReference,
+		},
+		{
+			desc:  "content not matching the digest",
+			image: srv.URL + "/tampered#sha256=" + digestOf(kernel),
+			err:   ErrDigestMismatch,
+		},
+		{
+			desc:  "registry content not matching the digest",
+			image: "sha256:" + digestOf([]byte("any")),
+			err:   ErrDigestMismatch,
+		},
+		{
+			desc:  "missing image",
+			image: srv.URL + "/missing#sha256=" + digestOf(kernel),
+			err:   ErrDownload,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			cache := New(Config{Dir: dir, RegistryURL: srv.URL + "/"}, srv.Client())
+
+			path, err := cache.Fetch(context.Background(), tc.image)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+				entries, _ := os.ReadDir(filepath.Join(dir, "sha256"))
+				assert.Empty(t, entries, "rejected images are not cached")
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.data == nil {
+				assert.Equal(t, tc.image, path)
+				return
+			}
+
+			assert.Equal(t, filepath.Join(dir, "sha256", digestOf(tc.data)), path)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.data, data)
+		})
+	}
+
+	t.Run("cached image reused", func(t *testing.T) {
+		cache := New(Config{Dir: t.TempDir()}, srv.Client())
+		image := srv.URL + "/bzImage#sha256=" + digestOf(kernel)
+
+		requests.Store(0)
+		first, err := cache.Fetch(context.Background(), image)
+		require.NoError(t, err)
+		second, err := cache.Fetch(context.Background(), image)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, int32(1), requests.Load())
+
+		require.NoError(t, os.WriteFile(first, []byte("corrupted"), 0o644))
+		_, err = cache.Fetch(context.Background(), image)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), requests.Load(), "a corrupted copy is downloaded again")
+		data, err := os.ReadFile(first)
+		require.NoError(t, err)
+		assert.Equal(t, kernel, data)
+	})
+
+	t.Run("registry not configured", func(t *testing.T) {
+		_, err := New(Config{Dir: t.TempDir()}, nil).Fetch(context.Background(), "sha256:"+digestOf(rootfs))
+		assert.True(t, errors.Is(err, ErrNoRegistry), "expected %v, got %v", ErrNoRegistry, err)
+	})
+}
+
+func TestResolve(t *testing.T) {
+	kernel := []byte("kernel image")
+	igvm := []byte("igvm file")
+	srv, _ := serve(t, map[string][]byte{"/sha256/" + digestOf(kernel): kernel, "/sha256/" + digestOf(igvm): igvm})
+
+	dir := t.TempDir()
+	cfg := qemu.Config{EnableSEVSNP: true}
+	cfg.DiskImgConfig.KernelFile = "sha256:" + digestOf(kernel)
+	cfg.DiskImgConfig.RootFsFile = "img/rootfs.cpio.gz"
+	cfg.IGVMConfig.File = "sha256:" + digestOf(igvm)
+	// The OVMF code file is not used by SEV-SNP CVMs and not fetched.
+	cfg.OVMFCodeConfig.File = "sha256:" + digestOf([]byte("unused"))
+
+	require.NoError(t, New(Config{Dir: dir, RegistryURL: srv.URL}, srv.Client()).Resolve(context.Background(), &cfg))
+	assert.Equal(t, filepath.Join(dir, "sha256", digestOf(kernel)), cfg.DiskImgConfig.KernelFile)
+	assert.Equal(t, "img/rootfs.cpio.gz", cfg.DiskImgConfig.RootFsFile)
+	assert.Equal(t, filepath.Join(dir, "sha256", digestOf(igvm)), cfg.IGVMConfig.File)
+	assert.Equal(t, "sha256:"+digestOf([]byte("unused")), cfg.OVMFCodeConfig.File)
+}
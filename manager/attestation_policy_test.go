@@ -64,7 +64,7 @@ func TestFetchAttestationPolicy(t *testing.T) {
 				},
 				LaunchTCB: 0,
 			},
-			expectedError: "pathToBinary cannot be empty",
+			expectedError: "failed to compute the launch measurement",
 		},
 		{
 			name:           "Invalid computation ID",
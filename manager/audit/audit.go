@@ -0,0 +1,246 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit keeps an append-only log of the control-plane operations of
+// the manager. Every record carries the hash of the previous one, so removing,
+// reordering or altering a record breaks the chain, which Verify detects.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// OutcomeOK is the outcome of an operation that succeeded.
+	OutcomeOK = "ok"
+	// OutcomeError is the outcome of an operation that failed.
+	OutcomeError = "error"
+	// AnonymousCaller identifies callers that presented no verified client certificate.
+	AnonymousCaller = "anonymous"
+
+	// maxRecordSize bounds the size of a record line read back from the log.
+	maxRecordSize = 1 << 20
+)
+
+// ErrBrokenChain indicates an audit log whose records do not follow each other.
+var ErrBrokenChain = errors.New("audit log chain is broken")
+
+// Config configures the audit log of the manager.
+type Config struct {
+	// File is the file records are appended to, auditing is disabled when it is empty.
+	File string `env:"MANAGER_AUDIT_FILE" envDefault:""`
+}
+
+// Record is an operation of the audit log, written as a line of JSON.
+type Record struct {
+	// Seq numbers the records from 1.
+	Seq uint64 `json:"seq"`
+	// Operation is the service method called.
+	Operation string `json:"operation"`
+	// Caller is the subject of the verified client certificate, AnonymousCaller without one.
+	Caller string `json:"caller"`
+	// Address is the network address the call came from.
+	Address string `json:"address,omitempty"`
+	// ParamsDigest is the SHA-256 digest of the parameters, which may hold secrets.
+	ParamsDigest string `json:"params_digest"`
+	// Outcome is OutcomeOK or OutcomeError.
+	Outcome string `json:"outcome"`
+	// Error is the error the operation failed with.
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// PrevHash is the hash of the previous record, empty for the first one.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the SHA-256 digest of the record with an empty Hash.
+	Hash string `json:"hash"`
+}
+
+// digest returns the hash of the record.
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends records to the audit log file.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	seq  uint64
+	head string
+}
+
+// Open opens the audit log file of the configuration, creating it if needed,
+// and resumes its chain. It returns nil without an error when auditing is
+// disabled, and an error wrapping ErrBrokenChain if the file does not verify.
+func Open(cfg Config) (*Log, error) {
+	if cfg.File == "" {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	seq, head, err := Verify(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Log{f: f, seq: seq, head: head}, nil
+}
+
+// Append chains the record to the log and writes it to the file, which is
+// synced before Append returns.
+func (l *Log) Append(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.Seq = l.seq + 1
+	r.PrevHash = l.head
+	r.StartedAt = r.StartedAt.UTC()
+	r.EndedAt = r.EndedAt.UTC()
+
+	hash, err := r.digest()
+	if err != nil {
+		return err
+	}
+	r.Hash = hash
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.seq, l.head = r.Seq, r.Hash
+
+	return nil
+}
+
+// Export returns the content of the log, the number of its records and the
+// hash of the last one, which anchors the exported chain.
+func (l *Log) Export() ([]byte, uint64, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.f.Name())
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return data, l.seq, l.head, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.f.Close()
+}
+
+// Verify checks that the records read from r are numbered in order and chained
+// to each other, and returns the number of records and the hash of the last
+// one. A log cut after a record still verifies, the returned head has to be
+// compared with the one of a previous export to detect it.
+func Verify(r io.Reader) (uint64, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+
+	var seq uint64
+	var head string
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var rec Record
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			return 0, "", fmt.Errorf("%w: record %d: %w", ErrBrokenChain, seq+1, err)
+		}
+
+		hash, err := rec.digest()
+		if err != nil {
+			return 0, "", err
+		}
+
+		switch {
+		case rec.Seq != seq+1:
+			return 0, "", fmt.Errorf("%w: record %d follows record %d", ErrBrokenChain, rec.Seq, seq)
+		case rec.PrevHash != head:
+			return 0, "", fmt.Errorf("%w: record %d is not chained to the previous record", ErrBrokenChain, rec.Seq)
+		case rec.Hash != hash:
+			return 0, "", fmt.Errorf("%w: record %d does not match its hash", ErrBrokenChain, rec.Seq)
+		}
+
+		seq, head = rec.Seq, rec.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", fmt.Errorf("%w: %w", ErrBrokenChain, err)
+	}
+
+	return seq, head, nil
+}
+
+// Digest returns the SHA-256 digest of the JSON encoding of the parameters.
+func Digest(params ...any) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		data = []byte(fmt.Sprint(params...))
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Caller returns the identity of the gRPC caller of ctx: the subject of its
+// verified client certificate, or AnonymousCaller, and its address.
+func Caller(ctx context.Context) (string, string) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return AnonymousCaller, ""
+	}
+
+	var addr string
+	if p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+		return info.State.VerifiedChains[0][0].Subject.String(), addr
+	}
+
+	return AnonymousCaller, addr
+}
@@ -0,0 +1,193 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func appendRecords(t *testing.T, l *Log, operations ...string) {
+	t.Helper()
+
+	for _, op := range operations {
+		begin := time.Now()
+		require.NoError(t, l.Append(Record{
+			Operation:    op,
+			Caller:       AnonymousCaller,
+			ParamsDigest: Digest(op),
+			Outcome:      OutcomeOK,
+			StartedAt:    begin,
+			EndedAt:      begin.Add(time.Millisecond),
+		}))
+	}
+}
+
+func TestOpenDisabled(t *testing.T) {
+	l, err := Open(Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+}
+
+func TestLog(t *testing.T) {
+	cfg := Config{File: filepath.Join(t.TempDir(), "audit.log")}
+
+	l, err := Open(cfg)
+	require.NoError(t, err)
+	appendRecords(t, l, "CreateVM", "StopVM")
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(cfg.File)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	l, err = Open(cfg)
+	require.NoError(t, err, "reopening the log resumes its chain")
+	defer l.Close()
+	appendRecords(t, l, "RemoveVM")
+
+	data, count, head, err := l.Export()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	seq, verified, err := Verify(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), seq)
+	assert.Equal(t, head, verified)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `"operation":"RemoveVM"`)
+}
+
+func TestVerify(t *testing.T) {
+	cfg := Config{File: filepath.Join(t.TempDir(), "audit.log")}
+	l, err := Open(cfg)
+	require.NoError(t, err)
+	appendRecords(t, l, "CreateVM", "AttachDataset", "RemoveVM")
+	data, _, _, err := l.Export()
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	lines := strings.SplitAfter(string(data), "\n")[:3]
+
+	cases := []struct {
+		desc string
+		log  string
+		seq  uint64
+		err  error
+	}{
+		{
+			desc: "intact log",
+			log:  string(data),
+			seq:  3,
+		},
+		{
+			desc: "empty log",
+			log:  "",
+		},
+		{
+			desc: "cut log",
+			log:  lines[0] + lines[1],
+			seq:  2,
+		},
+		{
+			desc: "removed record",
+			log:  lines[0] + lines[2],
+			err:  ErrBrokenChain,
+		},
+		{
+			desc: "reordered records",
+			log:  lines[1] + lines[0] + lines[2],
+			err:  ErrBrokenChain,
+		},
+		{
+			desc: "altered record",
+			log:  lines[0] + strings.Replace(lines[1], "AttachDataset", "GetImages", 1) + lines[2],
+			err:  ErrBrokenChain,
+		},
+		{
+			desc: "altered outcome",
+			log:  lines[0] + strings.Replace(lines[1], `"outcome":"ok"`, `"outcome":"error"`, 1) + lines[2],
+			err:  ErrBrokenChain,
+		},
+		{
+			desc: "malformed record",
+			log:  lines[0] + "{\"seq\":2,\n",
+			err:  ErrBrokenChain,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			seq, _, err := Verify(strings.NewReader(tc.log))
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.seq, seq)
+		})
+	}
+
+	require.NoError(t, os.WriteFile(cfg.File, []byte(lines[1]+lines[2]), 0o600))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBrokenChain, "a tampered log is not appended to")
+}
+
+func TestDigest(t *testing.T) {
+	assert.Equal(t, Digest("vm1", "/disk.img"), Digest("vm1", "/disk.img"))
+	assert.NotEqual(t, Digest("vm1", "/disk.img"), Digest("vm1/", "disk.img"))
+	assert.Len(t, Digest(), 64)
+}
+
+func TestCaller(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "operator", Organization: []string{"Ultraviolet"}}}
+
+	cases := []struct {
+		desc   string
+		ctx    context.Context
+		caller string
+		addr   string
+	}{
+		{
+			desc:   "no peer",
+			ctx:    context.Background(),
+			caller: AnonymousCaller,
+		},
+		{
+			desc:   "peer without TLS",
+			ctx:    peer.NewContext(context.Background(), &peer.Peer{Addr: addr}),
+			caller: AnonymousCaller,
+			addr:   "10.0.0.1:4321",
+		},
+		{
+			desc: "peer with a verified client certificate",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				Addr:     addr,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+			}),
+			caller: "CN=operator,O=Ultraviolet",
+			addr:   "10.0.0.1:4321",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			caller, addr := Caller(tc.ctx)
+			assert.Equal(t, tc.caller, caller)
+			assert.Equal(t, tc.addr, addr)
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/google/go-sev-guest/proto/check"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/cmdconfig"
+	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
+	"github.com/ultravioletrs/cocos/pkg/igvm"
+	"github.com/virtee/sev-snp-measure-go/cpuid"
+	"github.com/virtee/sev-snp-measure-go/guest"
+	"github.com/virtee/sev-snp-measure-go/vmmtypes"
+)
+
+// sevSNPGuestFeatures are the SEV features enabled in the VMSA of QEMU SEV-SNP guests.
+const sevSNPGuestFeatures = 0x1
+
+var (
+	// ErrUnsupportedPlatform indicates an operation the TEE backend of the CVM does not support.
+	ErrUnsupportedPlatform = errors.New("operation not supported by the CVM TEE backend")
+
+	// ErrFailedToMeasure indicates that the expected launch measurement of the CVM could not be computed.
+	ErrFailedToMeasure = errors.New("failed to compute the launch measurement")
+)
+
+// TEEBackend abstracts the trusted execution environment CVMs are launched on,
+// so that launch, measurement and attestation policy handling dispatch on the
+// platform type instead of checking SEV-SNP and TDX flags at every call site.
+type TEEBackend interface {
+	// Platform returns the attestation platform type of the backend.
+	Platform() attestation.PlatformType
+	// Firmware returns the firmware image CVMs boot with.
+	Firmware() *Image
+	// LaunchTCB returns the TCB version that is present when a CVM is launched.
+	LaunchTCB() (uint64, error)
+	// Measurement computes the expected launch measurement of a CVM launched with cfg.
+	Measurement(cfg qemu.Config) ([]byte, error)
+	// AttestationPolicy returns the attestation policy CVM attestations are verified with.
+	AttestationPolicy(vmi qemu.VMInfo) ([]byte, error)
+}
+
+// backend returns the TEE backend of CVMs launched with cfg.
+func (ms *managerService) backend(cfg qemu.Config) TEEBackend {
+	switch {
+	case cfg.EnableSEVSNP:
+		return &sevSNPBackend{ms: ms}
+	case cfg.EnableTDX:
+		return &tdxBackend{ms: ms}
+	default:
+		return &noTEEBackend{ms: ms}
+	}
+}
+
+type sevSNPBackend struct {
+	ms *managerService
+}
+
+var _ TEEBackend = (*sevSNPBackend)(nil)
+
+func (b *sevSNPBackend) Platform() attestation.PlatformType {
+	return attestation.SNPvTPM
+}
+
+func (b *sevSNPBackend) Firmware() *Image {
+	if b.ms.qemuCfg.SEVSNPDirectBoot() {
+		return &Image{Name: "ovmf", Path: b.ms.qemuCfg.SEVSNPConfig.OVMF, Version: b.ms.qemuCfg.OVMFCodeConfig.Version}
+	}
+
+	return &Image{Name: "igvm", Path: b.ms.qemuCfg.IGVMConfig.File}
+}
+
+func (b *sevSNPBackend) LaunchTCB() (uint64, error) {
+	policy, err := b.policy()
+	if err != nil {
+		return 0, err
+	}
+
+	return policy.Config.Policy.MinimumLaunchTcb, nil
+}
+
+// Measurement derives the launch digest from the contents of the IGVM file, or
+// for direct boot computes the launch digest of the OVMF firmware with the
+// hashes of the kernel, initrd and command line the CVM boots, so the policy
+// matches what is actually launched. IGVM files are measured with the
+// igvmmeasure binary instead when one is configured.
+func (b *sevSNPBackend) Measurement(cfg qemu.Config) ([]byte, error) {
+	if cfg.SEVSNPDirectBoot() {
+		return b.directBootMeasurement(cfg)
+	}
+
+	if b.ms.igvmMeasurementBinaryPath == "" {
+		return b.igvmMeasurement(cfg)
+	}
+
+	var stderrBuffer bytes.Buffer
+	stderr := bufio.NewWriter(&stderrBuffer)
+
+	igvmMeasurement, err := cmdconfig.NewCmdConfig(b.ms.igvmMeasurementBinaryPath, cmdconfig.IgvmMeasureOptions, stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	outputByte, err := igvmMeasurement.Run(b.ms.qemuCfg.IGVMConfig.File)
+	if err != nil {
+		return nil, err
+	}
+
+	outputString := string(outputByte)
+	lines := strings.Split(strings.TrimSpace(outputString), "\n")
+
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("error: %s", outputString)
+	}
+
+	return hex.DecodeString(strings.ToLower(strings.TrimSpace(outputString)))
+}
+
+func (b *sevSNPBackend) igvmMeasurement(cfg qemu.Config) ([]byte, error) {
+	file, err := igvm.ReadFile(cfg.IGVMConfig.File)
+	if err != nil {
+		return nil, errors.Wrap(ErrFailedToMeasure, err)
+	}
+
+	measurement, err := file.SNPLaunchDigest()
+	if err != nil {
+		return nil, errors.Wrap(ErrFailedToMeasure, err)
+	}
+
+	return measurement, nil
+}
+
+func (b *sevSNPBackend) directBootMeasurement(cfg qemu.Config) ([]byte, error) {
+	vcpuSig, ok := cpuid.CpuSigs[cfg.CPU]
+	if !ok {
+		return nil, errors.Wrap(ErrFailedToMeasure, fmt.Errorf("unknown vCPU type %s", cfg.CPU))
+	}
+
+	if _, err := cfg.KernelCmdline(); err != nil {
+		return nil, errors.Wrap(ErrInvalidKernelParams, err)
+	}
+	// The guest measures the command line as QEMU receives it.
+	cmdline := cfg.KernelAppend()
+
+	measurement, err := guest.CalcLaunchDigest(guest.SEV_SNP, cfg.SMPCount, uint64(vcpuSig), cfg.SEVSNPConfig.OVMF,
+		cfg.DiskImgConfig.KernelFile, cfg.DiskImgConfig.RootFsFile, cmdline, sevSNPGuestFeatures, "", vmmtypes.QEMU, false, "", 0)
+	if err != nil {
+		return nil, errors.Wrap(ErrFailedToMeasure, err)
+	}
+
+	return measurement, nil
+}
+
+func (b *sevSNPBackend) AttestationPolicy(vmi qemu.VMInfo) ([]byte, error) {
+	attestationPolicy, err := b.policy()
+	if err != nil {
+		return nil, err
+	}
+
+	measurement, err := b.Measurement(vmi.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if measurement != nil {
+		attestationPolicy.Config.Policy.Measurement = measurement
+	}
+
+	if vmi.Config.SEVSNPConfig.EnableHostData {
+		hostData, err := base64.StdEncoding.DecodeString(vmi.Config.SEVSNPConfig.HostData)
+		if err != nil {
+			return nil, err
+		}
+		attestationPolicy.Config.Policy.HostData = hostData
+	}
+
+	attestationPolicy.Config.Policy.MinimumLaunchTcb = vmi.LaunchTCB
+
+	return vtpm.ConvertPolicyToJSON(&attestationPolicy)
+}
+
+// policy runs the attestation policy binary and decodes the base SEV-SNP policy.
+func (b *sevSNPBackend) policy() (attestation.Config, error) {
+	attestationPolicy := attestation.Config{Config: &check.Config{RootOfTrust: &check.RootOfTrust{}, Policy: &check.Policy{}}, PcrConfig: &attestation.PcrConfig{}}
+
+	options := []string{"--policy", "196608"}
+	if b.ms.pcrValuesFilePath != "" {
+		options = append(options, "--pcr", b.ms.pcrValuesFilePath)
+	}
+
+	var stderrBuffer bytes.Buffer
+	attestPolicyCmd, err := cmdconfig.NewCmdConfig("sudo", options, bufio.NewWriter(&stderrBuffer))
+	if err != nil {
+		return attestationPolicy, err
+	}
+
+	b.ms.ap.Lock()
+	stdOutByte, err := attestPolicyCmd.Run(b.ms.attestationPolicyBinaryPath)
+	b.ms.ap.Unlock()
+	if err != nil {
+		return attestationPolicy, errors.Wrap(ErrFailedToCreateAttestationPolicy, err)
+	}
+
+	if err := vtpm.ReadPolicyFromByte(stdOutByte, &attestationPolicy); err != nil {
+		return attestationPolicy, errors.Wrap(ErrUnmarshalFailed, err)
+	}
+
+	return attestationPolicy, nil
+}
+
+type tdxBackend struct {
+	ms *managerService
+}
+
+var _ TEEBackend = (*tdxBackend)(nil)
+
+func (b *tdxBackend) Platform() attestation.PlatformType {
+	return attestation.TDX
+}
+
+func (b *tdxBackend) Firmware() *Image {
+	return &Image{Name: "ovmf", Path: b.ms.qemuCfg.TDXConfig.OVMF, Version: b.ms.qemuCfg.OVMFCodeConfig.Version}
+}
+
+func (b *tdxBackend) LaunchTCB() (uint64, error) {
+	return 0, nil
+}
+
+// Measurement is not computed by the manager for TDX, the expected MRTD is
+// part of the policy produced by the attestation policy binary.
+func (b *tdxBackend) Measurement(_ qemu.Config) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (b *tdxBackend) AttestationPolicy(_ qemu.VMInfo) ([]byte, error) {
+	var stderrBuffer bytes.Buffer
+
+	attestPolicyCmd, err := cmdconfig.NewCmdConfig(b.ms.attestationPolicyBinaryPath, nil, bufio.NewWriter(&stderrBuffer))
+	if err != nil {
+		return nil, err
+	}
+
+	b.ms.ap.Lock()
+	defer b.ms.ap.Unlock()
+
+	return attestPolicyCmd.Run("")
+}
+
+// noTEEBackend launches regular VMs, used for development on hosts without SEV-SNP or TDX.
+type noTEEBackend struct {
+	ms *managerService
+}
+
+var _ TEEBackend = (*noTEEBackend)(nil)
+
+func (b *noTEEBackend) Platform() attestation.PlatformType {
+	return attestation.NoCC
+}
+
+func (b *noTEEBackend) Firmware() *Image {
+	return &Image{Name: "ovmf", Path: b.ms.qemuCfg.OVMFCodeConfig.File, Version: b.ms.qemuCfg.OVMFCodeConfig.Version}
+}
+
+func (b *noTEEBackend) LaunchTCB() (uint64, error) {
+	return 0, nil
+}
+
+func (b *noTEEBackend) Measurement(_ qemu.Config) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+func (b *noTEEBackend) AttestationPolicy(_ qemu.VMInfo) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"encoding/base64"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+// Platforms of the backend info.
+const (
+	BackendSEVSNP = "sev-snp"
+	BackendTDX    = "tdx"
+	BackendNone   = "none"
+)
+
+// ErrBackendInfo indicates that the backend info could not be collected.
+var ErrBackendInfo = errors.New("failed to collect the backend info")
+
+// BackendInfo describes what the attestations of the CVMs the manager launches
+// are expected to report, so verifiers can generate their attestation policy
+// with cocos-cli policy create instead of editing it by hand. Only SEV-SNP
+// backends report the expected values, the manager does not compute the TDX
+// measurement. The GPUs CVMs may be passed and their NVIDIA confidential
+// computing mode tell verifiers to expect GPU attestations.
+type BackendInfo struct {
+	Platform         string `json:"platform"`
+	Measurement      []byte `json:"measurement,omitempty"`
+	GuestPolicy      uint64 `json:"guest_policy,omitempty"`
+	MinimumTCB       uint64 `json:"minimum_tcb,omitempty"`
+	MinimumLaunchTCB uint64 `json:"minimum_launch_tcb,omitempty"`
+	MinimumBuild     uint32 `json:"minimum_build,omitempty"`
+	MinimumVersion   string `json:"minimum_version,omitempty"`
+	Product          string `json:"product,omitempty"`
+	HostData         []byte `json:"host_data,omitempty"`
+
+	// GPUs are the PCI addresses of the host GPUs CVMs may be passed.
+	GPUs        []string `json:"gpus,omitempty"`
+	GPUNvidiaCC bool     `json:"gpu_nvidia_cc,omitempty"`
+}
+
+// GetBackendInfo returns the backend info of CVMs launched with cfg. SEV-SNP
+// backends run the attestation policy binary for the host values and measure
+// the firmware CVMs boot, with the igvmmeasure binary when one is set.
+func GetBackendInfo(cfg qemu.Config, attestationPolicyBinary, igvmMeasureBinary string) (*BackendInfo, error) {
+	ms := &managerService{
+		qemuCfg:                     cfg,
+		attestationPolicyBinaryPath: attestationPolicyBinary,
+		igvmMeasurementBinaryPath:   igvmMeasureBinary,
+	}
+
+	var info *BackendInfo
+	switch {
+	case cfg.EnableSEVSNP:
+		var err error
+		if info, err = ms.sevSNPBackendInfo(); err != nil {
+			return nil, err
+		}
+	case cfg.EnableTDX:
+		info = &BackendInfo{Platform: BackendTDX}
+	default:
+		info = &BackendInfo{Platform: BackendNone}
+	}
+
+	info.GPUs = cfg.GPUConfig.Allowed
+	info.GPUNvidiaCC = len(cfg.GPUConfig.Allowed) > 0 && cfg.GPUConfig.NvidiaCC
+
+	return info, nil
+}
+
+func (ms *managerService) sevSNPBackendInfo() (*BackendInfo, error) {
+	backend := &sevSNPBackend{ms: ms}
+
+	policy, err := backend.policy()
+	if err != nil {
+		return nil, errors.Wrap(ErrBackendInfo, err)
+	}
+
+	measurement, err := backend.Measurement(ms.qemuCfg)
+	if err != nil {
+		return nil, errors.Wrap(ErrBackendInfo, err)
+	}
+
+	info := &BackendInfo{
+		Platform:         BackendSEVSNP,
+		Measurement:      measurement,
+		GuestPolicy:      policy.Config.Policy.Policy,
+		MinimumTCB:       policy.Config.Policy.MinimumTcb,
+		MinimumLaunchTCB: policy.Config.Policy.MinimumLaunchTcb,
+		MinimumBuild:     policy.Config.Policy.MinimumBuild,
+		MinimumVersion:   policy.Config.Policy.MinimumVersion,
+		Product:          policy.Config.RootOfTrust.ProductLine,
+	}
+
+	if ms.qemuCfg.SEVSNPConfig.EnableHostData {
+		if info.HostData, err = base64.StdEncoding.DecodeString(ms.qemuCfg.SEVSNPConfig.HostData); err != nil {
+			return nil, errors.Wrap(ErrBackendInfo, err)
+		}
+	}
+
+	return info, nil
+}
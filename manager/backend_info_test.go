@@ -0,0 +1,110 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+func TestGetBackendInfo(t *testing.T) {
+	dir := t.TempDir()
+
+	// sudo runs the attestation policy binary as the user running the tests.
+	writeCheckFile(t, dir, "sudo", "#!/bin/sh\nexec \"$@\"\n", 0o755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	policyBin := writeCheckFile(t, dir, "attestation_policy", `#!/bin/sh
+echo '{"policy": {"policy": "196608", "minimumTcb": "15352208179752599555", "minimumLaunchTcb": "15352208179752599555", "minimumBuild": 8, "minimumVersion": "1.55"}, "rootOfTrust": {"product": "Milan", "productLine": "Milan"}}'
+`, 0o755)
+	failingBin := writeCheckFile(t, dir, "failing_policy", "#!/bin/sh\nexit 1\n", 0o755)
+	measureBin := writeCheckFile(t, dir, "igvmmeasure", "#!/bin/sh\necho "+string(bytes.Repeat([]byte("AB"), 48))+"\n", 0o755)
+
+	snp := qemu.Config{EnableSEVSNP: true, IGVMConfig: qemu.IGVMConfig{File: "coconut-qemu.igvm"}}
+	withHostData := snp
+	withHostData.SEVSNPConfig = qemu.SEVSNPConfig{EnableHostData: true, HostData: "AQID"}
+
+	cases := []struct {
+		desc      string
+		cfg       qemu.Config
+		policyBin string
+		info      *BackendInfo
+		err       error
+	}{
+		{
+			desc:      "SEV-SNP backend",
+			cfg:       snp,
+			policyBin: policyBin,
+			info: &BackendInfo{
+				Platform:         BackendSEVSNP,
+				Measurement:      bytes.Repeat([]byte{0xab}, 48),
+				GuestPolicy:      196608,
+				MinimumTCB:       15352208179752599555,
+				MinimumLaunchTCB: 15352208179752599555,
+				MinimumBuild:     8,
+				MinimumVersion:   "1.55",
+				Product:          "Milan",
+			},
+		},
+		{
+			desc:      "SEV-SNP backend with host data",
+			cfg:       withHostData,
+			policyBin: policyBin,
+			info: &BackendInfo{
+				Platform:         BackendSEVSNP,
+				Measurement:      bytes.Repeat([]byte{0xab}, 48),
+				GuestPolicy:      196608,
+				MinimumTCB:       15352208179752599555,
+				MinimumLaunchTCB: 15352208179752599555,
+				MinimumBuild:     8,
+				MinimumVersion:   "1.55",
+				Product:          "Milan",
+				HostData:         []byte{1, 2, 3},
+			},
+		},
+		{
+			desc:      "attestation policy binary fails",
+			cfg:       snp,
+			policyBin: failingBin,
+			err:       ErrBackendInfo,
+		},
+		{
+			desc: "TDX backend",
+			cfg:  qemu.Config{EnableTDX: true},
+			info: &BackendInfo{Platform: BackendTDX},
+		},
+		{
+			desc: "TDX backend with GPUs in NVIDIA confidential computing mode",
+			cfg:  qemu.Config{EnableTDX: true, GPUConfig: qemu.GPUConfig{Allowed: []string{"0000:41:00.0"}, NvidiaCC: true}},
+			info: &BackendInfo{Platform: BackendTDX, GPUs: []string{"0000:41:00.0"}, GPUNvidiaCC: true},
+		},
+		{
+			desc: "no TEE backend without GPUs",
+			cfg:  qemu.Config{GPUConfig: qemu.GPUConfig{NvidiaCC: true}},
+			info: &BackendInfo{Platform: BackendNone},
+		},
+		{
+			desc: "no TEE backend",
+			cfg:  qemu.Config{},
+			info: &BackendInfo{Platform: BackendNone},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			info, err := GetBackendInfo(tc.cfg, tc.policyBin, measureBin)
+			if tc.err != nil {
+				assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.info, info)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/igvm"
+)
+
+func TestBackend(t *testing.T) {
+	ms := &managerService{
+		qemuCfg: qemu.Config{
+			IGVMConfig:     qemu.IGVMConfig{File: "coconut-qemu.igvm"},
+			TDXConfig:      qemu.TDXConfig{OVMF: "OVMF.tdx.fd"},
+			OVMFCodeConfig: qemu.OVMFCodeConfig{File: "OVMF_CODE.fd", Version: "edk2-stable202408"},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		cfg      qemu.Config
+		platform attestation.PlatformType
+		firmware *Image
+		tcb      bool
+		err      error
+	}{
+		{
+			desc:     "SEV-SNP backend",
+			cfg:      qemu.Config{EnableSEVSNP: true},
+			platform: attestation.SNPvTPM,
+			firmware: &Image{Name: "igvm", Path: "coconut-qemu.igvm"},
+		},
+
+		{
+			desc:     "TDX backend",
+			cfg:      qemu.Config{EnableTDX: true},
+			platform: attestation.TDX,
+			firmware: &Image{Name: "ovmf", Path: "OVMF.tdx.fd", Version: "edk2-stable202408"},
+			tcb:      true,
+		},
+		{
+			desc:     "no TEE backend",
+			cfg:      qemu.Config{},
+			platform: attestation.NoCC,
+			firmware: &Image{Name: "ovmf", Path: "OVMF_CODE.fd", Version: "edk2-stable202408"},
+			tcb:      true,
+			err:      ErrUnsupportedPlatform,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			backend := ms.backend(tc.cfg)
+			assert.Equal(t, tc.platform, backend.Platform())
+			assert.Equal(t, tc.firmware, backend.Firmware())
+
+			if tc.tcb {
+				launchTCB, err := backend.LaunchTCB()
+				assert.NoError(t, err)
+				assert.Zero(t, launchTCB)
+			}
+
+			if tc.err != nil {
+				_, err := backend.AttestationPolicy(qemu.VMInfo{Config: tc.cfg})
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSEVSNPDirectBootMeasurement(t *testing.T) {
+	dir := t.TempDir()
+	ovmf := filepath.Join(dir, "OVMF.amdsev.fd")
+	require.NoError(t, os.WriteFile(ovmf, make([]byte, 4096), 0o644))
+
+	cfg := qemu.Config{
+		EnableSEVSNP:  true,
+		CPU:           "EPYC-v4",
+		SMPCount:      2,
+		SEVSNPConfig:  qemu.SEVSNPConfig{OVMF: ovmf},
+		DiskImgConfig: qemu.DiskImgConfig{KernelFile: filepath.Join(dir, "bzImage"), RootFsFile: filepath.Join(dir, "rootfs.cpio.gz")},
+	}
+
+	cases := []struct {
+		desc   string
+		modify func(*qemu.Config)
+		err    error
+	}{
+		{
+			desc:   "unknown vCPU type",
+			modify: func(c *qemu.Config) { c.CPU = "unknown" },
+			err:    ErrFailedToMeasure,
+		},
+		{
+			desc:   "missing OVMF file",
+			modify: func(c *qemu.Config) { c.CPU = "EPYC"; c.SEVSNPConfig.OVMF = filepath.Join(dir, "missing.fd") },
+			err:    ErrFailedToMeasure,
+		},
+	}
+
+	backend := &sevSNPBackend{ms: &managerService{qemuCfg: cfg}}
+	assert.Equal(t, &Image{Name: "ovmf", Path: ovmf}, backend.Firmware())
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := cfg
+			tc.modify(&c)
+			_, err := backend.Measurement(c)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestSEVSNPIGVMMeasurement(t *testing.T) {
+	dir := t.TempDir()
+	file := &igvm.File{
+		Headers: []igvm.Header{
+			&igvm.SupportedPlatform{CompatibilityMask: 1, PlatformType: igvm.PlatformSEVSNP, PlatformVersion: 1},
+			&igvm.PageData{GPA: 0xFFFFF000, CompatibilityMask: 1, Data: []byte("firmware")},
+			&igvm.VPContext{GPA: 0xFFFFFFFFF000, CompatibilityMask: 1, Data: make([]byte, igvm.PageSize)},
+		},
+	}
+	data, err := file.Marshal()
+	require.NoError(t, err)
+	igvmFile := filepath.Join(dir, "coconut-qemu.igvm")
+	require.NoError(t, os.WriteFile(igvmFile, data, 0o644))
+
+	expected, err := file.SNPLaunchDigest()
+	require.NoError(t, err)
+
+	cfg := qemu.Config{EnableSEVSNP: true, IGVMConfig: qemu.IGVMConfig{File: igvmFile}}
+	backend := &sevSNPBackend{ms: &managerService{qemuCfg: cfg}}
+
+	measurement, err := backend.Measurement(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, expected, measurement)
+
+	corrupt := filepath.Join(dir, "corrupt.igvm")
+	require.NoError(t, os.WriteFile(corrupt, data[:len(data)-1], 0o644))
+	missing := filepath.Join(dir, "missing.igvm")
+
+	for _, f := range []string{corrupt, missing} {
+		c := cfg
+		c.IGVMConfig.File = f
+		_, err := backend.Measurement(c)
+		assert.True(t, errors.Contains(err, ErrFailedToMeasure), "expected %v, got %v", ErrFailedToMeasure, err)
+	}
+}
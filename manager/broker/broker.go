@@ -0,0 +1,65 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package broker forwards the computation events of the manager to a NATS or
+// MQTT message broker, so that external orchestration can follow the
+// lifecycle of the computations it created.
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ultravioletrs/cocos/manager"
+)
+
+var (
+	// ErrUnsupportedScheme indicates the broker URL selects neither NATS nor MQTT.
+	ErrUnsupportedScheme = errors.New("unsupported message broker URL scheme")
+
+	// ErrInvalidURL indicates the broker URL could not be parsed.
+	ErrInvalidURL = errors.New("invalid message broker URL")
+)
+
+// Config is the message broker the manager forwards computation events to.
+type Config struct {
+	// URL of the broker, forwarding is disabled when it is empty.
+	URL string `env:"MANAGER_EVENTS_BROKER_URL"     envDefault:""`
+	// Topic the events are published under, followed by the CVM ID and the event type.
+	Topic string `env:"MANAGER_EVENTS_TOPIC"          envDefault:"cocos.manager.events"`
+	// ReconnectWait is the delay between attempts to (re)connect to the broker.
+	ReconnectWait time.Duration `env:"MANAGER_EVENTS_RECONNECT_WAIT" envDefault:"2s"`
+}
+
+// New connects to the broker of the URL scheme, nats:// or tls:// select NATS and
+// mqtt://, mqtts://, tcp://, ssl://, ws:// or wss:// select MQTT. The
+// connection is retried in the background until the broker is reachable.
+// It returns nil without an error when no broker is configured.
+func New(cfg Config, clientID string, logger *slog.Logger) (manager.EventPublisher, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "nats", "tls":
+		p, err := newNATS(cfg, clientID, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	case "mqtt", "mqtts", "tcp", "ssl", "ws", "wss":
+		return newMQTT(cfg, clientID, logger), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package broker
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/manager"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		desc      string
+		url       string
+		publisher bool
+		err       error
+	}{
+		{
+			desc: "no broker configured",
+			url:  "",
+		},
+		{
+			desc:      "MQTT broker",
+			url:       "mqtt://127.0.0.1:1",
+			publisher: true,
+		},
+		{
+			desc: "unsupported scheme",
+			url:  "amqp://127.0.0.1:5672",
+			err:  ErrUnsupportedScheme,
+		},
+		{
+			desc: "invalid URL",
+			url:  "nats://[::1",
+			err:  ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p, err := New(Config{URL: tc.url, Topic: "cocos.manager.events"}, "manager-test", slog.Default())
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.publisher, p != nil)
+			if p != nil {
+				assert.NoError(t, p.Close())
+			}
+		})
+	}
+}
+
+func TestTopics(t *testing.T) {
+	event := &manager.ComputationEvent{CvmId: "vm1", EventType: manager.EventVMRunning}
+
+	assert.Equal(t, "cocos.manager.events.vm1.vm-running", natsSubject("cocos.manager.events", event))
+	assert.Equal(t, "cocos/manager/events", mqttTopic("cocos.manager.events"))
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// mqttQoS delivers every event at least once.
+	mqttQoS = 1
+	// mqttDisconnectQuiesce is the time in milliseconds to finish sending pending events on close.
+	mqttDisconnectQuiesce = 250
+)
+
+type mqttPublisher struct {
+	client mqtt.Client
+	topic  string
+}
+
+func newMQTT(cfg Config, clientID string, logger *slog.Logger) *mqttPublisher {
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.URL).
+		SetClientID(clientID).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(cfg.ReconnectWait).
+		SetMaxReconnectInterval(cfg.ReconnectWait).
+		SetOnConnectHandler(func(mqtt.Client) {
+			logger.Info("Connected to events broker", "url", cfg.URL)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			logger.Warn("Disconnected from events broker", "error", err)
+		})
+
+	client := mqtt.NewClient(opts)
+	// With connect retry the token only completes once connected, events
+	// published meanwhile are queued by the client.
+	client.Connect()
+
+	return &mqttPublisher{client: client, topic: mqttTopic(cfg.Topic)}
+}
+
+// Publish sends the event to the <topic>/<cvm_id>/<event_type> topic, where
+// the dots of the configured topic are replaced by MQTT level separators.
+func (p *mqttPublisher) Publish(ctx context.Context, event *manager.ComputationEvent) error {
+	data, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	token := p.client.Publish(p.topic+"/"+event.CvmId+"/"+event.EventType, mqttQoS, false, data)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *mqttPublisher) Close() error {
+	p.client.Disconnect(mqttDisconnectQuiesce)
+
+	return nil
+}
+
+func mqttTopic(topic string) string {
+	return strings.ReplaceAll(topic, ".", "/")
+}
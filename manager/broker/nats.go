@@ -0,0 +1,58 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package broker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type natsPublisher struct {
+	conn  *nats.Conn
+	topic string
+}
+
+func newNATS(cfg Config, clientID string, logger *slog.Logger) (*natsPublisher, error) {
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name(clientID),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("Disconnected from events broker", "error", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info("Connected to events broker", "url", c.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsPublisher{conn: conn, topic: cfg.Topic}, nil
+}
+
+// Publish sends the event to the <topic>.<cvm_id>.<event_type> subject, events
+// published while reconnecting are buffered by the client.
+func (p *natsPublisher) Publish(_ context.Context, event *manager.ComputationEvent) error {
+	data, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.conn.Publish(natsSubject(p.topic, event), data)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+func natsSubject(topic string, event *manager.ComputationEvent) string {
+	return topic + "." + event.CvmId + "." + event.EventType
+}
@@ -0,0 +1,28 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+
+	"github.com/ultravioletrs/cocos/manager/snpcerts"
+)
+
+// SNPCertificates returns the certificate chains SEV-SNP attestation reports are verified with.
+type SNPCertificates interface {
+	// Chain returns the ARK, ASK and VCEK of the chip at the reported TCB version.
+	Chain(ctx context.Context, productLine string, chipID []byte, reportedTCB uint64) (snpcerts.Chain, error)
+}
+
+func (ms *managerService) SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*SNPCertChain, error) {
+	if ms.snpCerts == nil {
+		return nil, ErrSNPCertsDisabled
+	}
+
+	chain, err := ms.snpCerts.Chain(ctx, product, chipID, reportedTCB)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SNPCertChain{Ark: chain.ARK, Ask: chain.ASK, Vcek: chain.VCEK}, nil
+}
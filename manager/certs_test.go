@@ -0,0 +1,43 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/snpcerts"
+)
+
+type certsFunc func(ctx context.Context, productLine string, chipID []byte, reportedTCB uint64) (snpcerts.Chain, error)
+
+func (f certsFunc) Chain(ctx context.Context, productLine string, chipID []byte, reportedTCB uint64) (snpcerts.Chain, error) {
+	return f(ctx, productLine, chipID, reportedTCB)
+}
+
+func TestSNPCertChain(t *testing.T) {
+	ms := &managerService{}
+	_, err := ms.SNPCertChain(context.Background(), "Milan", []byte("chip"), 1)
+	assert.ErrorIs(t, err, ErrSNPCertsDisabled)
+
+	fetchErr := errors.New("KDS unavailable")
+	ms.snpCerts = certsFunc(func(ctx context.Context, productLine string, chipID []byte, reportedTCB uint64) (snpcerts.Chain, error) {
+		if productLine != "Milan" {
+			return snpcerts.Chain{}, fetchErr
+		}
+		assert.Equal(t, []byte("chip"), chipID)
+		assert.Equal(t, uint64(1), reportedTCB)
+
+		return snpcerts.Chain{ARK: []byte("ark"), ASK: []byte("ask"), VCEK: []byte("vcek")}, nil
+	})
+
+	chain, err := ms.SNPCertChain(context.Background(), "Milan", []byte("chip"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &SNPCertChain{Ark: []byte("ark"), Ask: []byte("ask"), Vcek: []byte("vcek")}, chain)
+
+	_, err = ms.SNPCertChain(context.Background(), "Genoa", []byte("chip"), 1)
+	assert.ErrorIs(t, err, fetchErr)
+}
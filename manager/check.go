@@ -0,0 +1,258 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+const (
+	qemuVersionTimeout = 5 * time.Second
+	kernelParamEnabled = "Y"
+	checkStatusOK      = "ok"
+	checkStatusFailed  = "FAILED"
+	checkNoDetail      = "-"
+)
+
+// Host paths probed by CheckConfig, variables so tests can point them at fixtures.
+var (
+	devKVM        = "/dev/kvm"
+	devSEV        = "/dev/sev"
+	devVhostVsock = "/dev/vhost-vsock"
+	appArmorFS    = "/sys/kernel/security/apparmor"
+	sevSNPParam   = "/sys/module/kvm_amd/parameters/sev_snp"
+	tdxParam      = "/sys/module/kvm_intel/parameters/tdx"
+)
+
+// ErrCheckFailed indicates that at least one configuration check failed.
+var ErrCheckFailed = errors.New("manager configuration check failed")
+
+// CheckResult is the outcome of a single deployment check.
+type CheckResult struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+// CheckConfig verifies that the host can launch CVMs with the QEMU configuration,
+// so misconfigurations are caught at deploy time instead of on the first run.
+// Every check is run and reported, a failed check does not stop the others.
+func CheckConfig(cfg qemu.Config, attestationPolicyBinary string) []CheckResult {
+	results := []CheckResult{
+		{Name: "configuration", Err: cfg.Validate()},
+		checkQemuBinary(cfg.QemuBinPath),
+		checkFile("kernel", cfg.DiskImgConfig.KernelFile),
+		checkFile("rootfs", cfg.DiskImgConfig.RootFsFile),
+	}
+
+	ms := &managerService{qemuCfg: cfg}
+	firmware := ms.backend(cfg).Firmware()
+	results = append(results, checkFile(firmware.Name, firmware.Path))
+
+	switch {
+	case cfg.EnableSEVSNP:
+		results = append(results, checkFile("sev device", devSEV), checkKernelParam("sev-snp support", sevSNPParam))
+	case cfg.EnableTDX:
+		results = append(results, checkKernelParam("tdx support", tdxParam))
+	default:
+		results = append(results, checkFile("ovmf vars", cfg.OVMFVarsConfig.File))
+	}
+
+	if cfg.EnableKVM {
+		results = append(results, checkFile("kvm device", devKVM))
+	}
+
+	if cfg.VSockConfig.GuestCID != 0 {
+		results = append(results, checkFile("vsock module", devVhostVsock))
+	}
+
+	if cfg.SandboxConfig.User != "" {
+		results = append(results, checkUser("sandbox user", cfg.SandboxConfig.User), checkCommand("setpriv"))
+		if cfg.DatasetDiskConfig.DiskSlots > 0 {
+			results = append(results, checkCommand("setfacl"))
+		}
+	}
+
+	if cfg.SandboxConfig.AppArmor {
+		results = append(results, checkFile("apparmor", appArmorFS), checkCommand("aa-exec"), checkCommand("apparmor_parser"))
+	}
+
+	results = append(results, checkPortRange(cfg.HostFwdRange))
+
+	if cfg.EnableSEVSNP || cfg.EnableTDX {
+		results = append(results, checkExecutable("attestation policy binary", attestationPolicyBinary))
+	}
+
+	return results
+}
+
+// WriteCheckReport writes a table of the check results and returns ErrCheckFailed if any of them failed.
+func WriteCheckReport(w io.Writer, results []CheckResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+
+	var failed bool
+	for _, r := range results {
+		status, detail := checkStatusOK, r.Detail
+		if r.Err != nil {
+			failed = true
+			status, detail = checkStatusFailed, r.Err.Error()
+		}
+		if detail == "" {
+			detail = checkNoDetail
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, status, detail)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed {
+		return ErrCheckFailed
+	}
+
+	return nil
+}
+
+// CheckQemu returns why the host cannot launch CVMs, it returns nil while the
+// QEMU binary and, with KVM enabled, the kvm device are available. Unlike
+// CheckConfig it does not run QEMU, so it is cheap enough for health checks.
+func CheckQemu(cfg qemu.Config) error {
+	if _, err := exec.LookPath(cfg.QemuBinPath); err != nil {
+		return err
+	}
+
+	if cfg.EnableKVM && !hostFileExists(devKVM) {
+		return fmt.Errorf("%s is missing", devKVM)
+	}
+
+	return nil
+}
+
+func checkQemuBinary(bin string) CheckResult {
+	result := CheckResult{Name: "qemu binary"}
+
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), qemuVersionTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		result.Err = fmt.Errorf("failed to get %s version: %w", path, err)
+		return result
+	}
+
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	result.Detail = version
+
+	return result
+}
+
+func checkFile(name, path string) CheckResult {
+	result := CheckResult{Name: name, Detail: path}
+
+	if path == "" {
+		result.Err = errors.New("path is empty")
+		return result
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		result.Err = err
+	}
+
+	return result
+}
+
+func checkExecutable(name, path string) CheckResult {
+	result := CheckResult{Name: name, Detail: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		result.Err = fmt.Errorf("%s is not executable", path)
+	}
+
+	return result
+}
+
+// checkCommand checks that a command the manager runs is in the PATH.
+func checkCommand(name string) CheckResult {
+	result := CheckResult{Name: name}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Detail = path
+
+	return result
+}
+
+func checkUser(name, username string) CheckResult {
+	result := CheckResult{Name: name, Detail: username}
+
+	if _, err := user.Lookup(username); err != nil {
+		result.Err = err
+	}
+
+	return result
+}
+
+// checkKernelParam checks that a KVM module parameter enabling a TEE is set.
+func checkKernelParam(name, path string) CheckResult {
+	result := CheckResult{Name: name, Detail: path}
+
+	value, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if v := string(bytes.TrimSpace(value)); v != kernelParamEnabled {
+		result.Err = fmt.Errorf("%s is %q, the KVM module must be loaded with it enabled", path, v)
+	}
+
+	return result
+}
+
+func checkPortRange(input string) CheckResult {
+	result := CheckResult{Name: "host port range", Detail: input}
+
+	ports, err := qemu.NewPortAllocator(input)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	free := ports.Free()
+	if free == 0 {
+		result.Err = qemu.ErrNoFreePort
+		return result
+	}
+	result.Detail = fmt.Sprintf("%s, %d free", input, free)
+
+	return result
+}
@@ -0,0 +1,246 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+func writeCheckFile(t *testing.T, dir, name, content string, perm os.FileMode) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), perm))
+	return path
+}
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	qemuBin := writeCheckFile(t, dir, "qemu-system-x86_64", "#!/bin/sh\necho 'QEMU emulator version 9.1.0'\necho 'Copyright (c) 2003-2024'\n", 0o755)
+	policyBin := writeCheckFile(t, dir, "attestation_policy", "#!/bin/sh\n", 0o755)
+	notExecutable := writeCheckFile(t, dir, "policy.json", "{}", 0o644)
+	kernel := writeCheckFile(t, dir, "bzImage", "", 0o644)
+	rootfs := writeCheckFile(t, dir, "rootfs.cpio.gz", "", 0o644)
+	igvm := writeCheckFile(t, dir, "coconut-qemu.igvm", "", 0o644)
+	tdvf := writeCheckFile(t, dir, "OVMF.tdx.fd", "", 0o644)
+
+	devSEV = writeCheckFile(t, dir, "sev", "", 0o644)
+	devKVM = writeCheckFile(t, dir, "kvm", "", 0o644)
+	devVhostVsock = filepath.Join(dir, "vhost-vsock")
+	sevSNPParam = writeCheckFile(t, dir, "sev_snp", "Y\n", 0o644)
+	tdxParam = writeCheckFile(t, dir, "tdx", "N\n", 0o644)
+	appArmorFS = dir
+
+	binDir := t.TempDir()
+	writeCheckFile(t, binDir, "setpriv", "#!/bin/sh\n", 0o755)
+	writeCheckFile(t, binDir, "apparmor_parser", "#!/bin/sh\n", 0o755)
+	t.Setenv("PATH", binDir)
+
+	baseConfig := func() qemu.Config {
+		cfg := qemu.Config{
+			QemuBinPath:    qemuBin,
+			EnableKVM:      true,
+			SMPCount:       2,
+			MaxCPUs:        4,
+			MemoryConfig:   qemu.MemoryConfig{Size: "2048M", Max: "30G", Slots: 5},
+			DiskImgConfig:  qemu.DiskImgConfig{KernelFile: kernel, RootFsFile: rootfs},
+			IGVMConfig:     qemu.IGVMConfig{File: igvm},
+			TDXConfig:      qemu.TDXConfig{OVMF: tdvf, QuoteGenerationPort: 4050},
+			SEVSNPConfig:   qemu.SEVSNPConfig{CBitPos: 51, ReducedPhysBits: 1},
+			EnableSEVSNP:   true,
+			NetDevConfig:   qemu.NetDevConfig{ID: "vmnic", Mode: qemu.NetModeUser, HostFwdAgent: 7020, GuestFwdAgent: 7002},
+			VSockConfig:    qemu.VSockConfig{ID: "vhost-vsock-pci0"},
+			OVMFCodeConfig: qemu.OVMFCodeConfig{File: filepath.Join(dir, "OVMF_CODE.fd")},
+			HostFwdRange:   "6100-6200",
+		}
+		return cfg
+	}
+
+	cases := []struct {
+		desc   string
+		config func() qemu.Config
+		policy string
+		failed []string
+	}{
+		{
+			desc:   "valid SEV-SNP host",
+			config: baseConfig,
+			policy: policyBin,
+		},
+		{
+			desc: "missing TDX support",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.EnableSEVSNP = false
+				cfg.EnableTDX = true
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"tdx support"},
+		},
+		{
+			desc: "missing QEMU binary and images",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.QemuBinPath = filepath.Join(dir, "missing-qemu")
+				cfg.DiskImgConfig.KernelFile = ""
+				cfg.IGVMConfig.File = filepath.Join(dir, "missing.igvm")
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"configuration", "qemu binary", "kernel", "igvm"},
+		},
+		{
+			desc: "vsock module not loaded",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.VSockConfig.GuestCID = 3
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"vsock module"},
+		},
+		{
+			desc: "invalid port range",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.HostFwdRange = "6200-70000"
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"host port range"},
+		},
+		{
+			desc: "sandbox user",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.SandboxConfig.User = "root"
+				return cfg
+			},
+			policy: policyBin,
+		},
+		{
+			desc: "missing sandbox user",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.SandboxConfig.User = "cocos-missing-user"
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"sandbox user"},
+		},
+		{
+			desc: "sandbox user with dataset disks without setfacl",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.SandboxConfig.User = "root"
+				cfg.DatasetDiskConfig.DiskSlots = 2
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"setfacl"},
+		},
+		{
+			desc: "AppArmor without aa-exec",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.SandboxConfig.AppArmor = true
+				return cfg
+			},
+			policy: policyBin,
+			failed: []string{"aa-exec"},
+		},
+		{
+			desc:   "attestation policy binary not executable",
+			config: baseConfig,
+			policy: notExecutable,
+			failed: []string{"attestation policy binary"},
+		},
+		{
+			desc: "without TEE",
+			config: func() qemu.Config {
+				cfg := baseConfig()
+				cfg.EnableSEVSNP = false
+				cfg.OVMFCodeConfig.File = writeCheckFile(t, dir, "OVMF_CODE.fd", "", 0o644)
+				cfg.OVMFVarsConfig.File = writeCheckFile(t, dir, "OVMF_VARS.fd", "", 0o644)
+				return cfg
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			results := CheckConfig(tc.config(), tc.policy)
+
+			var failed []string
+			for _, r := range results {
+				if r.Err != nil {
+					failed = append(failed, r.Name)
+				}
+			}
+			assert.Equal(t, tc.failed, failed)
+		})
+	}
+
+	results := CheckConfig(baseConfig(), policyBin)
+	assert.Equal(t, "QEMU emulator version 9.1.0", results[1].Detail)
+}
+
+func TestCheckQemu(t *testing.T) {
+	dir := t.TempDir()
+	qemuBin := writeCheckFile(t, dir, "qemu-system-x86_64", "#!/bin/sh\n", 0o755)
+
+	kvm := writeCheckFile(t, dir, "kvm", "", 0o644)
+
+	orig := devKVM
+	t.Cleanup(func() { devKVM = orig })
+
+	cases := []struct {
+		desc  string
+		cfg   qemu.Config
+		noKVM bool
+		err   bool
+	}{
+		{desc: "qemu available", cfg: qemu.Config{QemuBinPath: qemuBin, EnableKVM: true}},
+		{desc: "qemu binary missing", cfg: qemu.Config{QemuBinPath: filepath.Join(dir, "missing-qemu")}, err: true},
+		{desc: "kvm device missing", cfg: qemu.Config{QemuBinPath: qemuBin, EnableKVM: true}, noKVM: true, err: true},
+		{desc: "kvm device not required", cfg: qemu.Config{QemuBinPath: qemuBin}, noKVM: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			devKVM = kvm
+			if tc.noKVM {
+				devKVM = filepath.Join(dir, "missing-kvm")
+			}
+
+			err := CheckQemu(tc.cfg)
+			assert.Equal(t, tc.err, err != nil, "unexpected error %v", err)
+		})
+	}
+}
+
+func TestWriteCheckReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCheckReport(&buf, []CheckResult{
+		{Name: "configuration"},
+		{Name: "kvm device", Detail: "/dev/kvm"},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "kvm device     ok      /dev/kvm")
+
+	buf.Reset()
+	err = WriteCheckReport(&buf, []CheckResult{
+		{Name: "configuration"},
+		{Name: "sev device", Detail: "/dev/sev", Err: os.ErrNotExist},
+	})
+	assert.ErrorIs(t, err, ErrCheckFailed)
+	assert.Contains(t, buf.String(), "FAILED")
+	assert.Contains(t, buf.String(), os.ErrNotExist.Error())
+}
@@ -0,0 +1,317 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	consoleFileName = "console.log"
+	// consoleChunkSize bounds the captured output sent in a single chunk.
+	consoleChunkSize = 64 << 10
+	// consoleWatchBufferSize is the number of chunks buffered per subscriber on
+	// top of the captured output, chunks are dropped for subscribers that fall further behind.
+	consoleWatchBufferSize = 256
+)
+
+// ConsoleConfig configures the capture of the QEMU serial console of the CVMs.
+type ConsoleConfig struct {
+	// Dir is the directory the console output of each CVM is written to, console capture is disabled when it is empty.
+	Dir string `env:"MANAGER_CONSOLE_DIR"       envDefault:"/tmp/cocos/console"`
+	// MaxSize is the number of bytes a console file grows to before it is rotated.
+	MaxSize int64 `env:"MANAGER_CONSOLE_MAX_SIZE"  envDefault:"10485760"`
+	// MaxFiles is the number of rotated console files kept per CVM besides the current one.
+	MaxFiles int `env:"MANAGER_CONSOLE_MAX_FILES" envDefault:"3"`
+}
+
+// ConsoleFilter selects the captured console output sent to a console subscriber.
+type ConsoleFilter struct {
+	// Tail limits the captured output to its last lines, all of it is sent when Tail is negative.
+	Tail int
+	// Follow keeps streaming new output while the CVM runs, the stream ends after the captured output otherwise.
+	Follow bool
+}
+
+// consoles writes the console output of each CVM to a file in a directory of
+// its own, rotated once it exceeds the configured size, and fans the new
+// output out to the subscribers.
+type consoles struct {
+	cfg ConsoleConfig
+
+	mu    sync.Mutex
+	files map[string]*consoleFile
+	subs  map[string]map[chan *ConsoleChunk]struct{}
+}
+
+type consoleFile struct {
+	f    *os.File
+	size int64
+}
+
+// newConsoles returns the console capture of the configuration, nil when it is disabled.
+func newConsoles(cfg ConsoleConfig) *consoles {
+	if cfg.Dir == "" {
+		return nil
+	}
+
+	return &consoles{
+		cfg:   cfg,
+		files: make(map[string]*consoleFile),
+		subs:  make(map[string]map[chan *ConsoleChunk]struct{}),
+	}
+}
+
+// path returns the path of the console file of the CVM, or of its i-th rotated file.
+func (c *consoles) path(id string, i int) string {
+	path := filepath.Join(c.cfg.Dir, id, consoleFileName)
+	if i > 0 {
+		path = fmt.Sprintf("%s.%d", path, i)
+	}
+
+	return path
+}
+
+// write appends the output to the console file of the CVM, failures are
+// dropped since they cannot be logged without being captured again.
+func (c *consoles) write(id string, output []byte) {
+	if c == nil || !validConsoleID(id) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, ok := c.files[id]
+	if ok && c.cfg.MaxSize > 0 && file.size > 0 && file.size+int64(len(output)) > c.cfg.MaxSize {
+		file.f.Close()
+		delete(c.files, id)
+		c.rotate(id)
+		ok = false
+	}
+	if !ok {
+		var err error
+		if file, err = c.open(id); err != nil {
+			return
+		}
+		c.files[id] = file
+	}
+
+	n, _ := file.f.Write(output)
+	file.size += int64(n)
+
+	for ch := range c.subs[id] {
+		select {
+		case ch <- &ConsoleChunk{CvmId: id, Data: bytes.Clone(output)}:
+		default:
+		}
+	}
+}
+
+func (c *consoles) open(id string) (*consoleFile, error) {
+	if err := os.MkdirAll(filepath.Join(c.cfg.Dir, id), 0o750); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(c.path(id, 0), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &consoleFile{f: f, size: info.Size()}, nil
+}
+
+// rotate shifts the console files of the CVM by one, dropping the oldest.
+func (c *consoles) rotate(id string) {
+	if c.cfg.MaxFiles <= 0 {
+		os.Remove(c.path(id, 0))
+		return
+	}
+
+	for i := c.cfg.MaxFiles; i > 0; i-- {
+		os.Rename(c.path(id, i-1), c.path(id, i))
+	}
+}
+
+// captured returns the console output of the CVM kept in its files, oldest first.
+func (c *consoles) captured(id string) ([]byte, error) {
+	var out []byte
+	found := false
+	for i := max(c.cfg.MaxFiles, 0); i >= 0; i-- {
+		data, err := os.ReadFile(c.path(id, i))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		out = append(out, data...)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return out, nil
+}
+
+// subscribe queues the captured output selected by the filter and registers
+// a subscriber for the CVM, the channel is closed right away unless it follows
+// the output. CVMs without captured output are not found unless they run.
+func (c *consoles) subscribe(id string, filter ConsoleFilter, running bool) (chan *ConsoleChunk, error) {
+	if !validConsoleID(id) {
+		return nil, ErrNotFound
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.captured(id)
+	if err != nil && !(errors.Is(err, ErrNotFound) && running) {
+		return nil, err
+	}
+	if filter.Tail >= 0 {
+		data = tailLines(data, filter.Tail)
+	}
+
+	var chunks []*ConsoleChunk
+	for len(data) > 0 {
+		n := min(len(data), consoleChunkSize)
+		chunks = append(chunks, &ConsoleChunk{CvmId: id, Data: data[:n]})
+		data = data[n:]
+	}
+
+	ch := make(chan *ConsoleChunk, len(chunks)+consoleWatchBufferSize)
+	for _, chunk := range chunks {
+		ch <- chunk
+	}
+
+	if !filter.Follow {
+		close(ch)
+		return ch, nil
+	}
+
+	if c.subs[id] == nil {
+		c.subs[id] = make(map[chan *ConsoleChunk]struct{})
+	}
+	c.subs[id][ch] = struct{}{}
+
+	return ch, nil
+}
+
+// unsubscribe removes and closes the subscriber channel if it is still registered.
+func (c *consoles) unsubscribe(id string, ch chan *ConsoleChunk) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subs[id][ch]; !ok {
+		return
+	}
+
+	delete(c.subs[id], ch)
+	if len(c.subs[id]) == 0 {
+		delete(c.subs, id)
+	}
+	close(ch)
+}
+
+// close closes the console file of the CVM and ends its subscriptions, the
+// files are kept so the console of a CVM that failed to launch can be read.
+func (c *consoles) close(id string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if file, ok := c.files[id]; ok {
+		file.f.Close()
+		delete(c.files, id)
+	}
+	for ch := range c.subs[id] {
+		close(ch)
+	}
+	delete(c.subs, id)
+}
+
+// remove closes the console file of the CVM and deletes its files.
+func (c *consoles) remove(id string) {
+	if c == nil || !validConsoleID(id) {
+		return
+	}
+
+	c.close(id)
+	os.RemoveAll(filepath.Join(c.cfg.Dir, id))
+}
+
+// validConsoleID reports whether the CVM ID names a directory of the console
+// directory, rather than a path leading out of it.
+func validConsoleID(id string) bool {
+	return id != "" && id != "." && id != ".." && filepath.Base(id) == id
+}
+
+// tailLines returns the part of the output holding its last n lines.
+func tailLines(data []byte, n int) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	// The newline ending the output does not start another line.
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if data[i] != '\n' {
+			continue
+		}
+		if n--; n == 0 {
+			return data[i+1:]
+		}
+	}
+
+	return data
+}
+
+func (ms *managerService) Console(ctx context.Context, computationID string, filter ConsoleFilter) (<-chan *ConsoleChunk, error) {
+	if ms.consoles == nil {
+		return nil, ErrConsoleDisabled
+	}
+
+	// The CVM lock orders the subscription with the removal of the CVM, which
+	// ends it. Only the console of a running CVM is followed, the consoles of
+	// CVMs that failed to launch are read from their files.
+	ms.mu.Lock()
+	_, running := ms.vms[computationID]
+	filter.Follow = filter.Follow && running
+	ch, err := ms.consoles.subscribe(computationID, filter, running)
+	ms.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !filter.Follow {
+		return ch, nil
+	}
+
+	go func() {
+		<-ctx.Done()
+		ms.consoles.unsubscribe(computationID, ch)
+	}()
+
+	return ch, nil
+}
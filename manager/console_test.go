@@ -0,0 +1,187 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+// readConsole returns the output of a console stream, up to the first chunk
+// when it follows the console.
+func readConsole(t *testing.T, chunks <-chan *ConsoleChunk, follow bool) string {
+	t.Helper()
+
+	var out []byte
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return string(out)
+			}
+			out = append(out, chunk.Data...)
+			if follow {
+				return string(out)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a console chunk")
+			return ""
+		}
+	}
+}
+
+func TestConsole(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+	cvm.On("Stop").Return(nil)
+
+	dir := t.TempDir()
+	ms.consoles = newConsoles(ConsoleConfig{Dir: dir, MaxSize: 1 << 20, MaxFiles: 1})
+	ms.vmLogs = newVMLogs(ms.consoles)
+	ms.logger = slog.New(newVMLogHandler(slog.NewTextHandler(io.Discard, nil), ms.vmLogs))
+
+	ms.mu.Lock()
+	ms.publishEvent("vm1", EventVMProvisioning, cvm, "")
+	ms.mu.Unlock()
+
+	ch, err := ms.Console(context.Background(), "vm1", ConsoleFilter{Tail: -1})
+	require.NoError(t, err)
+	assert.Empty(t, readConsole(t, ch, false), "a running CVM may have no console output yet")
+
+	logger := ms.logger.With(slog.String("cvm", "vm1"))
+	_, err = (&vm.Stdout{StateMachine: cvm, Logger: logger}).Write([]byte("Booting Linux\nLoading initrd\n"))
+	require.NoError(t, err)
+	_, err = (&vm.Stderr{StateMachine: cvm, Logger: logger}).Write([]byte("qemu: warning: host lacks a feature\n"))
+	require.NoError(t, err)
+	ms.logger.Info("Not console output", "cvm", "vm1")
+
+	data, err := os.ReadFile(filepath.Join(dir, "vm1", consoleFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "Booting Linux\nLoading initrd\nqemu: warning: host lacks a feature\n", string(data))
+
+	ch, err = ms.Console(context.Background(), "vm1", ConsoleFilter{Tail: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "Loading initrd\nqemu: warning: host lacks a feature\n", readConsole(t, ch, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err = ms.Console(ctx, "vm1", ConsoleFilter{Tail: 0, Follow: true})
+	require.NoError(t, err)
+	_, err = (&vm.Stdout{StateMachine: cvm, Logger: logger}).Write([]byte("cocos-agent started\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "cocos-agent started\n", readConsole(t, ch, true))
+
+	require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+	_, ok := <-ch
+	assert.False(t, ok, "removing the CVM ends the stream")
+	assert.NoDirExists(t, filepath.Join(dir, "vm1"), "the console files of a removed CVM are deleted")
+
+	_, err = ms.Console(context.Background(), "vm1", ConsoleFilter{Tail: -1})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestConsoleFailedLaunch(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+	ms.consoles = newConsoles(ConsoleConfig{Dir: t.TempDir()})
+
+	ms.consoles.write("vm2", []byte("Kernel panic - not syncing\n"))
+	ms.consoles.close("vm2")
+
+	ch, err := ms.Console(context.Background(), "vm2", ConsoleFilter{Tail: -1, Follow: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Kernel panic - not syncing\n", readConsole(t, ch, false), "the stream ends after the captured output")
+}
+
+func TestConsoleRotation(t *testing.T) {
+	cases := []struct {
+		desc     string
+		maxFiles int
+		captured string
+	}{
+		{
+			desc:     "rotated files kept",
+			maxFiles: 1,
+			captured: "abcdefghij\nklm\n",
+		},
+		{
+			desc:     "no rotated files kept",
+			maxFiles: 0,
+			captured: "klm\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := newConsoles(ConsoleConfig{Dir: t.TempDir(), MaxSize: 12, MaxFiles: tc.maxFiles})
+			for _, output := range []string{"0123456789\n", "abcdefghij\n", "klm\n"} {
+				c.write("vm1", []byte(output))
+			}
+
+			ch, err := c.subscribe("vm1", ConsoleFilter{Tail: -1}, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.captured, readConsole(t, ch, false))
+			assert.NoFileExists(t, c.path("vm1", tc.maxFiles+1))
+		})
+	}
+}
+
+func TestConsoleChunks(t *testing.T) {
+	c := newConsoles(ConsoleConfig{Dir: t.TempDir()})
+	c.write("vm1", make([]byte, consoleChunkSize+1))
+
+	ch, err := c.subscribe("vm1", ConsoleFilter{Tail: -1}, false)
+	require.NoError(t, err)
+	assert.Len(t, ch, 2, "captured output is sent in chunks of a bounded size")
+}
+
+func TestConsoleNotFound(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+	dir := t.TempDir()
+	ms.consoles = newConsoles(ConsoleConfig{Dir: filepath.Join(dir, "console")})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, consoleFileName), []byte("host file"), 0o644))
+
+	for _, id := range []string{"unknown", "..", "../console", ""} {
+		_, err := ms.Console(context.Background(), id, ConsoleFilter{Tail: -1})
+		assert.ErrorIs(t, err, ErrNotFound, id)
+	}
+}
+
+func TestConsoleDisabled(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+	ms.consoles = newConsoles(ConsoleConfig{})
+	assert.Nil(t, ms.consoles)
+
+	_, err := ms.Console(context.Background(), "vm1", ConsoleFilter{})
+	assert.ErrorIs(t, err, ErrConsoleDisabled)
+
+	ms.consoles.write("vm1", []byte("dropped"))
+	ms.consoles.remove("vm1")
+}
+
+func TestTailLines(t *testing.T) {
+	cases := []struct {
+		data string
+		n    int
+		want string
+	}{
+		{data: "a\nb\nc\n", n: 2, want: "b\nc\n"},
+		{data: "a\nb\nc", n: 2, want: "b\nc"},
+		{data: "a\nb\n", n: 5, want: "a\nb\n"},
+		{data: "a\nb\n", n: 0, want: ""},
+		{data: "", n: 1, want: ""},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, string(tailLines([]byte(tc.data), tc.n)), "%q tail %d", tc.data, tc.n)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// collectDiagnostics keeps the diagnostic snapshot the agent of the CVM with
+// the vsock CID sent, replacing the one of an earlier failed run. The trace of
+// the failed run ctx carries is logged so operators can find its spans.
+func (ms *managerService) collectDiagnostics(ctx context.Context, cid uint32, snapshot []byte) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	id, cvm, ok := ms.vmByGuestCID(cid)
+	if !ok {
+		return
+	}
+
+	ms.heartbeats.mu.Lock()
+	ms.heartbeats.diagnostics[id] = &Diagnostics{
+		CvmId:      id,
+		Snapshot:   snapshot,
+		ReceivedAt: timestamppb.Now(),
+	}
+	ms.heartbeats.mu.Unlock()
+
+	args := []any{"cvm", id, "size", len(snapshot)}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		args = append(args, "trace_id", sc.TraceID().String())
+	}
+	ms.logger.Warn("CVM agent sent a diagnostic snapshot", args...)
+	ms.publishEvent(id, EventDiagnosticsReceived, cvm, "")
+}
+
+func (ms *managerService) Diagnostics(ctx context.Context, computationID string) (*Diagnostics, error) {
+	ms.mu.Lock()
+	_, ok := ms.vms[computationID]
+	ms.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// Snapshots are sent over the heartbeat connection.
+	if ms.heartbeats == nil {
+		return nil, ErrDiagnosticsNotFound
+	}
+
+	ms.heartbeats.mu.Lock()
+	defer ms.heartbeats.mu.Unlock()
+
+	diagnostics, ok := ms.heartbeats.diagnostics[computationID]
+	if !ok {
+		return nil, ErrDiagnosticsNotFound
+	}
+
+	return diagnostics, nil
+}
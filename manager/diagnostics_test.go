@@ -0,0 +1,72 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+func TestDiagnostics(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+	vmi := qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: testGuestCID}}}
+	cvm.On("GetConfig").Return(vmi)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := HeartbeatConfig{Port: 7004, Interval: time.Minute, MissedLimit: 2}
+	ms.serveHeartbeats(l, func(net.Addr) (uint32, error) { return testGuestCID, nil }, cfg)
+	defer ms.stopHeartbeats()
+
+	_, err = ms.Diagnostics(context.Background(), "vm2")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = ms.Diagnostics(context.Background(), "vm1")
+	assert.ErrorIs(t, err, ErrDiagnosticsNotFound)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := ms.WatchComputation(ctx, "vm1")
+	require.NoError(t, err)
+	<-events
+
+	dial := func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}
+	for _, snapshot := range []string{`{"error":"first run"}`, `{"error":"second run"}`} {
+		require.NoError(t, vsock.SendDiagnostics(context.Background(), dial, []byte(snapshot)))
+
+		select {
+		case event := <-events:
+			assert.Equal(t, EventDiagnosticsReceived, event.EventType)
+		case <-time.After(time.Second):
+			t.Fatalf("missing %s event", EventDiagnosticsReceived)
+		}
+	}
+
+	diagnostics, err := ms.Diagnostics(context.Background(), "vm1")
+	require.NoError(t, err)
+	assert.Equal(t, "vm1", diagnostics.CvmId)
+	assert.JSONEq(t, `{"error":"second run"}`, string(diagnostics.Snapshot))
+	assert.NotNil(t, diagnostics.ReceivedAt)
+
+	ms.forgetHeartbeats("vm1", cvm)
+	_, err = ms.Diagnostics(context.Background(), "vm1")
+	assert.ErrorIs(t, err, ErrDiagnosticsNotFound)
+}
+
+func TestDiagnosticsHeartbeatsDisabled(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+
+	_, err := ms.Diagnostics(context.Background(), "vm1")
+	assert.ErrorIs(t, err, ErrDiagnosticsNotFound)
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// EventState is sent first to every new subscriber with the current CVM state.
+	EventState = "state"
+	// EventVMProvisioning is sent when the CVM was created, before it is started.
+	EventVMProvisioning = "vm-provisioning"
+	EventVMRunning      = "vm-running"
+	EventVMStopped      = "vm-stopped"
+	EventVMRemoved      = "vm-removed"
+	// EventVMAdopted is sent when a restarted manager took over a CVM that kept running.
+	EventVMAdopted  = "vm-adopted"
+	EventTTLExpired = "ttl-expired"
+	// EventDatasetAttached carries the path of the disk image hot-added to the CVM.
+	EventDatasetAttached = "dataset-attached"
+	// EventVMUnhealthy is sent when the CVM agent stopped sending heartbeats.
+	EventVMUnhealthy = "vm-unhealthy"
+	// EventVMRestarted is sent when an unhealthy CVM was reset.
+	EventVMRestarted = "vm-restarted"
+	// EventDiagnosticsReceived is sent when the CVM agent sent a diagnostic snapshot of a failed run.
+	EventDiagnosticsReceived = "diagnostics-received"
+	// EventGuestPanicked is relayed from the hypervisor when the CVM guest kernel panics.
+	EventGuestPanicked = vm.EventGuestPanicked
+
+	// watchBufferSize is the number of events buffered per subscriber,
+	// events are dropped for subscribers that fall further behind.
+	watchBufferSize = 32
+)
+
+// watchers fans out computation events to the subscribers of each CVM.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[string]map[chan *ComputationEvent]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{subs: make(map[string]map[chan *ComputationEvent]struct{})}
+}
+
+func newComputationEvent(id, eventType, state, details string) *ComputationEvent {
+	return &ComputationEvent{
+		CvmId:     id,
+		EventType: eventType,
+		State:     state,
+		Details:   details,
+		Timestamp: timestamppb.Now(),
+	}
+}
+
+// subscribe registers a subscriber for the CVM and queues the initial event.
+func (w *watchers) subscribe(id string, initial *ComputationEvent) chan *ComputationEvent {
+	ch := make(chan *ComputationEvent, watchBufferSize)
+	ch <- initial
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subs[id] == nil {
+		w.subs[id] = make(map[chan *ComputationEvent]struct{})
+	}
+	w.subs[id][ch] = struct{}{}
+
+	return ch
+}
+
+// unsubscribe removes and closes the subscriber channel if it is still registered.
+func (w *watchers) unsubscribe(id string, ch chan *ComputationEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.subs[id][ch]; !ok {
+		return
+	}
+
+	delete(w.subs[id], ch)
+	if len(w.subs[id]) == 0 {
+		delete(w.subs, id)
+	}
+	close(ch)
+}
+
+// watching reports whether the CVM has any subscribers.
+func (w *watchers) watching(id string) bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.subs[id]) > 0
+}
+
+// publish delivers the event to the CVM subscribers without blocking the caller.
+func (w *watchers) publish(event *ComputationEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.subs[event.CvmId] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// close ends all subscriptions of the CVM.
+func (w *watchers) close(id string) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.subs[id] {
+		close(ch)
+	}
+	delete(w.subs, id)
+}
+
+func (ms *managerService) WatchComputation(ctx context.Context, computationID string) (<-chan *ComputationEvent, error) {
+	ms.mu.Lock()
+	cvm, ok := ms.vms[computationID]
+	if !ok {
+		ms.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	ch := ms.watchers.subscribe(computationID, newComputationEvent(computationID, EventState, cvm.State(), ""))
+	ms.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		ms.watchers.unsubscribe(computationID, ch)
+	}()
+
+	return ch, nil
+}
+
+// publishEvent records the event in the CVM timeline, notifies the CVM
+// subscribers and forwards the event to the event publishers, callers hold
+// ms.mu so that events are ordered with the state snapshot sent to new
+// subscribers.
+func (ms *managerService) publishEvent(id, eventType string, cvm vm.VM, details string) {
+	event := newComputationEvent(id, eventType, cvm.State(), details)
+	ms.recordEvent(event)
+
+	if ms.watchers.watching(id) {
+		ms.watchers.publish(event)
+	}
+	for _, f := range ms.forwarders {
+		f.forward(event)
+	}
+}
+
+// relayVMEvents publishes the hypervisor events of the CVM until the VM exits.
+func (ms *managerService) relayVMEvents(id string, cvm vm.VM) {
+	src, ok := cvm.(vm.EventSource)
+	if !ok {
+		return
+	}
+
+	go func() {
+		for event := range src.Events() {
+			ms.logger.Warn("CVM hypervisor event", "cvm", id, "event", event.EventType, "details", string(event.Details))
+
+			ms.mu.Lock()
+			if cvm, ok := ms.vms[id]; ok {
+				ms.publishEvent(id, event.EventType, cvm, string(event.Details))
+			}
+			ms.mu.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	persistenceMocks "github.com/ultravioletrs/cocos/manager/qemu/mocks"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+func newWatchService(t *testing.T, id string) (*managerService, *mocks.VM) {
+	persistence := new(persistenceMocks.Persistence)
+	persistence.On("DeleteVM", mock.Anything).Return(nil)
+
+	cvm := mocks.NewVM(t)
+
+	return &managerService{
+		logger:      slog.Default(),
+		vms:         map[string]vm.VM{id: cvm},
+		persistence: persistence,
+		ttlManager:  NewTTLManager(),
+		watchers:    newWatchers(),
+		ports:       newTestPorts(),
+	}, cvm
+}
+
+func collectEvents(t *testing.T, events <-chan *ComputationEvent) []*ComputationEvent {
+	var received []*ComputationEvent
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received
+			}
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the event stream to close")
+		}
+	}
+}
+
+func TestWatchComputation(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String()).Times(2)
+	cvm.On("State").Return(pkgmanager.StopComputationRun.String())
+	cvm.On("Stop").Return(nil).Once()
+
+	events, err := ms.WatchComputation(context.Background(), "vm1")
+	require.NoError(t, err)
+
+	_, err = ms.StopVM(context.Background(), "vm1")
+	require.NoError(t, err)
+	require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+
+	received := collectEvents(t, events)
+	require.Len(t, received, 3)
+
+	expected := []struct {
+		eventType string
+		state     string
+	}{
+		{EventState, pkgmanager.VmRunning.String()},
+		{EventVMStopped, pkgmanager.StopComputationRun.String()},
+		{EventVMRemoved, pkgmanager.StopComputationRun.String()},
+	}
+	for i, e := range expected {
+		assert.Equal(t, "vm1", received[i].CvmId)
+		assert.Equal(t, e.eventType, received[i].EventType)
+		assert.Equal(t, e.state, received[i].State)
+		assert.NotNil(t, received[i].Timestamp)
+	}
+}
+
+func TestWatchComputationNotFound(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+
+	_, err := ms.WatchComputation(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestWatchComputationCancel(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := ms.WatchComputation(ctx, "vm1")
+	require.NoError(t, err)
+
+	cancel()
+
+	received := collectEvents(t, events)
+	require.Len(t, received, 1)
+	assert.Equal(t, EventState, received[0].EventType)
+
+	ms.watchers.mu.Lock()
+	assert.Empty(t, ms.watchers.subs)
+	ms.watchers.mu.Unlock()
+}
+
+func TestWatchersPublishDoesNotBlock(t *testing.T) {
+	w := newWatchers()
+	ch := w.subscribe("vm1", newComputationEvent("vm1", EventState, "", ""))
+
+	for i := 0; i < watchBufferSize*2; i++ {
+		w.publish(newComputationEvent("vm1", EventVMRunning, "", ""))
+	}
+	w.close("vm1")
+
+	count := 0
+	for range ch {
+		count++
+	}
+	assert.Equal(t, watchBufferSize, count)
+}
+
+type eventSourceVM struct {
+	*mocks.VM
+	events chan vm.Event
+}
+
+func (v *eventSourceVM) Events() <-chan vm.Event {
+	return v.events
+}
+
+func TestRelayVMEvents(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+
+	src := &eventSourceVM{VM: cvm, events: make(chan vm.Event, 1)}
+	ms.vms["vm1"] = src
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := ms.WatchComputation(ctx, "vm1")
+	require.NoError(t, err)
+
+	ms.relayVMEvents("vm1", src)
+	src.events <- vm.Event{EventType: vm.EventGuestPanicked, Details: []byte(`{"action":"pause"}`)}
+	close(src.events)
+
+	<-events
+	select {
+	case event := <-events:
+		assert.Equal(t, EventGuestPanicked, event.EventType)
+		assert.Equal(t, `{"action":"pause"}`, event.Details)
+		assert.Equal(t, pkgmanager.VmRunning.String(), event.State)
+	case <-time.After(time.Second):
+		t.Fatal("guest panic was not relayed")
+	}
+
+	cancel()
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// forwardBufferSize is the number of events queued for each publisher,
+	// events are dropped while the publisher falls further behind.
+	forwardBufferSize = 256
+	forwardTimeout    = 10 * time.Second
+)
+
+// EventPublisher publishes computation events to an external message broker or
+// webhook, e.g. for the orchestration that created the computations.
+type EventPublisher interface {
+	// Publish sends the event to the broker or webhook.
+	Publish(ctx context.Context, event *ComputationEvent) error
+
+	// Close flushes the pending events and disconnects from the broker.
+	Close() error
+}
+
+// forwarder publishes computation events in the background, so that a slow or
+// unreachable publisher never blocks the manager.
+type forwarder struct {
+	publisher EventPublisher
+	logger    *slog.Logger
+	events    chan *ComputationEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newForwarder(publisher EventPublisher, logger *slog.Logger) *forwarder {
+	if publisher == nil {
+		return nil
+	}
+
+	f := &forwarder{
+		publisher: publisher,
+		logger:    logger,
+		events:    make(chan *ComputationEvent, forwardBufferSize),
+		done:      make(chan struct{}),
+	}
+	go f.run()
+
+	return f
+}
+
+func (f *forwarder) run() {
+	defer close(f.done)
+
+	for event := range f.events {
+		ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+		if err := f.publisher.Publish(ctx, event); err != nil {
+			f.logger.Warn("Failed to forward computation event", "cvm", event.CvmId, "event", event.EventType, "error", err)
+		}
+		cancel()
+	}
+}
+
+// newForwarders returns a forwarder for each configured publisher, so that a
+// slow publisher never holds up the others.
+func newForwarders(publishers []EventPublisher, logger *slog.Logger) []*forwarder {
+	var forwarders []*forwarder
+	for _, publisher := range publishers {
+		if f := newForwarder(publisher, logger); f != nil {
+			forwarders = append(forwarders, f)
+		}
+	}
+
+	return forwarders
+}
+
+// forward queues the event for the publisher without blocking the caller.
+func (f *forwarder) forward(event *ComputationEvent) {
+	if f == nil {
+		return
+	}
+
+	select {
+	case f.events <- event:
+	default:
+		f.logger.Warn("Dropping computation event, publisher queue is full", "cvm", event.CvmId, "event", event.EventType)
+	}
+}
+
+// close publishes the queued events and closes the publisher.
+func (f *forwarder) close() {
+	if f == nil {
+		return
+	}
+
+	f.closeOnce.Do(func() {
+		close(f.events)
+		<-f.done
+
+		if err := f.publisher.Close(); err != nil {
+			f.logger.Warn("Failed to close event publisher", "error", err)
+		}
+	})
+}
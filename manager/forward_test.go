@@ -0,0 +1,139 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+type fakePublisher struct {
+	mu     sync.Mutex
+	events []*ComputationEvent
+	err    error
+	closed bool
+	// busy receives each event before Publish waits for block.
+	busy  chan struct{}
+	block chan struct{}
+}
+
+func (p *fakePublisher) Publish(_ context.Context, event *ComputationEvent) error {
+	if p.block != nil {
+		p.busy <- struct{}{}
+		<-p.block
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+
+	return p.err
+}
+
+func (p *fakePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	return nil
+}
+
+func TestForwardEvents(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+	}{
+		{
+			desc: "forward events",
+		},
+		{
+			desc: "forward events with failing publisher",
+			err:  errors.New("broker unavailable"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			publisher := &fakePublisher{err: tc.err}
+			ms, cvm := newWatchService(t, "vm1")
+			ms.forwarders = newForwarders([]EventPublisher{publisher}, slog.Default())
+			cvm.On("State").Return(pkgmanager.VmRunning.String()).Once()
+			cvm.On("State").Return(pkgmanager.StopComputationRun.String())
+			cvm.On("Stop").Return(nil).Once()
+
+			_, err := ms.StopVM(context.Background(), "vm1")
+			require.NoError(t, err)
+			require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+
+			require.NoError(t, ms.Shutdown())
+
+			publisher.mu.Lock()
+			defer publisher.mu.Unlock()
+
+			require.Len(t, publisher.events, 2)
+			assert.Equal(t, EventVMStopped, publisher.events[0].EventType)
+			assert.Equal(t, EventVMRemoved, publisher.events[1].EventType)
+			for _, event := range publisher.events {
+				assert.Equal(t, "vm1", event.CvmId)
+				assert.Equal(t, pkgmanager.StopComputationRun.String(), event.State)
+			}
+			assert.True(t, publisher.closed)
+		})
+	}
+}
+
+func TestForwarderDoesNotBlock(t *testing.T) {
+	publisher := &fakePublisher{busy: make(chan struct{}, forwardBufferSize+2), block: make(chan struct{})}
+	f := newForwarder(publisher, slog.Default())
+
+	// The publisher holds the first event while the queue fills up.
+	f.forward(newComputationEvent("vm1", EventVMRunning, "", ""))
+	<-publisher.busy
+	for range forwardBufferSize + 1 {
+		f.forward(newComputationEvent("vm1", EventVMRunning, "", ""))
+	}
+
+	close(publisher.block)
+	f.close()
+	f.close()
+
+	assert.Len(t, publisher.events, forwardBufferSize+1)
+	assert.True(t, publisher.closed)
+}
+
+func TestNilForwarder(t *testing.T) {
+	f := newForwarder(nil, slog.Default())
+	assert.Nil(t, f)
+
+	f.forward(newComputationEvent("vm1", EventVMRunning, "", ""))
+	f.close()
+}
+
+func TestForwardersAreIndependent(t *testing.T) {
+	slow := &fakePublisher{busy: make(chan struct{}, 1), block: make(chan struct{})}
+	fast := &fakePublisher{}
+	forwarders := newForwarders([]EventPublisher{slow, nil, fast}, slog.Default())
+	require.Len(t, forwarders, 2)
+
+	for _, f := range forwarders {
+		f.forward(newComputationEvent("vm1", EventVMRunning, "", ""))
+	}
+
+	// The fast publisher receives the event while the slow one holds it.
+	<-slow.busy
+	forwarders[1].close()
+	assert.Len(t, fast.events, 1)
+
+	close(slow.block)
+	forwarders[0].close()
+	assert.Len(t, slow.events, 1)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+const (
+	vfioDriver         = "vfio-pci"
+	nvidiaVendorID     = "0x10de"
+	pciClassDisplay    = "0x03"
+	pciClassBridge     = "0x0604"
+	pciIOMMUGroupLink  = "iommu_group"
+	pciDriverLink      = "driver"
+	iommuGroupDevsPath = "devices"
+)
+
+// Host paths of the PCI devices and IOMMU groups, variables so tests can
+// point them at fixtures.
+var (
+	pciDevicesDir  = "/sys/bus/pci/devices"
+	iommuGroupsDir = "/sys/kernel/iommu_groups"
+)
+
+// gpusRequested reports whether the request passes GPUs through or sets their
+// NVIDIA confidential computing mode, which pooled VMs launched without GPUs
+// cannot satisfy.
+func gpusRequested(req *CreateReq) bool {
+	return len(req.GpuDevices) > 0 || req.GpuNvidiaCc != nil
+}
+
+// checkGPUs verifies that the GPUs of the VM can be passed through to it: each
+// must sit in an IOMMU group whose devices are all bound to vfio-pci, or to no
+// driver, and no other VM may use the group. GPUs in NVIDIA confidential
+// computing mode must be NVIDIA GPUs.
+func (ms *managerService) checkGPUs(cfg qemu.Config) error {
+	groups := make(map[string]string, len(cfg.GPUConfig.Devices))
+	for _, addr := range cfg.GPUConfig.Devices {
+		gpu, err := readGPU(addr)
+		if err != nil {
+			return err
+		}
+		if gpu.IommuGroup == "" {
+			return fmt.Errorf("%w: GPU %s is in no IOMMU group, enable the IOMMU", ErrInvalidGPU, addr)
+		}
+		if cfg.GPUConfig.NvidiaCC && gpu.VendorId != nvidiaVendorID {
+			return fmt.Errorf("%w: GPU %s of vendor %s has no NVIDIA confidential computing mode", ErrInvalidGPU, addr, gpu.VendorId)
+		}
+		if err := checkIOMMUGroup(gpu.IommuGroup); err != nil {
+			return err
+		}
+		groups[gpu.IommuGroup] = addr
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for id, cvm := range ms.vms {
+		vmi, ok := cvm.GetConfig().(qemu.VMInfo)
+		if !ok {
+			continue
+		}
+		for _, addr := range vmi.Config.GPUConfig.Devices {
+			if addr, ok := groups[linkTarget(filepath.Join(pciDevicesDir, addr, pciIOMMUGroupLink))]; ok {
+				return fmt.Errorf("%w: the IOMMU group of GPU %s is passed through to CVM %s", ErrGPUInUse, addr, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkIOMMUGroup verifies that VFIO can take the IOMMU group over, PCI
+// bridges of the group may keep their driver.
+func checkIOMMUGroup(group string) error {
+	entries, err := os.ReadDir(filepath.Join(iommuGroupsDir, group, iommuGroupDevsPath))
+	if err != nil {
+		return fmt.Errorf("%w: failed to list the devices of IOMMU group %s: %v", ErrInvalidGPU, group, err)
+	}
+
+	for _, entry := range entries {
+		dir := filepath.Join(pciDevicesDir, entry.Name())
+		if strings.HasPrefix(readHostFile(filepath.Join(dir, "class")), pciClassBridge) {
+			continue
+		}
+		if driver := linkTarget(filepath.Join(dir, pciDriverLink)); driver != "" && driver != vfioDriver {
+			return fmt.Errorf("%w: device %s of IOMMU group %s is bound to %s, bind it to %s", ErrInvalidGPU, entry.Name(), group, driver, vfioDriver)
+		}
+	}
+
+	return nil
+}
+
+// detectGPUs returns the display controllers of the host ordered by PCI address.
+func detectGPUs() []*Gpu {
+	entries, err := os.ReadDir(pciDevicesDir)
+	if err != nil {
+		return nil
+	}
+
+	var gpus []*Gpu
+	for _, entry := range entries {
+		if !strings.HasPrefix(readHostFile(filepath.Join(pciDevicesDir, entry.Name(), "class")), pciClassDisplay) {
+			continue
+		}
+		if gpu, err := readGPU(entry.Name()); err == nil {
+			gpus = append(gpus, gpu)
+		}
+	}
+
+	return gpus
+}
+
+// readGPU returns the IDs, IOMMU group and driver of the PCI device at addr.
+func readGPU(addr string) (*Gpu, error) {
+	dir := filepath.Join(pciDevicesDir, addr)
+	if !hostFileExists(dir) {
+		return nil, fmt.Errorf("%w: host has no PCI device %s", ErrInvalidGPU, addr)
+	}
+
+	return &Gpu{
+		Address:    addr,
+		VendorId:   readHostFile(filepath.Join(dir, "vendor")),
+		DeviceId:   readHostFile(filepath.Join(dir, "device")),
+		IommuGroup: linkTarget(filepath.Join(dir, pciIOMMUGroupLink)),
+		Driver:     linkTarget(filepath.Join(dir, pciDriverLink)),
+	}, nil
+}
+
+// linkTarget returns the name of the file a sysfs link points to, or an empty
+// string when there is no link.
+func linkTarget(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Base(target)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	"google.golang.org/protobuf/proto"
+)
+
+// pciDevice describes a PCI device of the sysfs fixture, it is in no IOMMU
+// group or bound to no driver when they are empty.
+type pciDevice struct {
+	addr   string
+	class  string
+	vendor string
+	group  string
+	driver string
+}
+
+// setupPCI points the PCI device and IOMMU group paths at a sysfs fixture with the devices.
+func setupPCI(t *testing.T, devices ...pciDevice) {
+	origDevices, origGroups := pciDevicesDir, iommuGroupsDir
+	t.Cleanup(func() { pciDevicesDir, iommuGroupsDir = origDevices, origGroups })
+
+	dir := t.TempDir()
+	pciDevicesDir = filepath.Join(dir, "bus", "pci", "devices")
+	iommuGroupsDir = filepath.Join(dir, "kernel", "iommu_groups")
+
+	for _, dev := range devices {
+		devDir := filepath.Join(pciDevicesDir, dev.addr)
+		require.NoError(t, os.MkdirAll(devDir, 0o755))
+		for name, content := range map[string]string{"class": dev.class, "vendor": dev.vendor, "device": "0x2330"} {
+			require.NoError(t, os.WriteFile(filepath.Join(devDir, name), []byte(content+"\n"), 0o644))
+		}
+
+		if dev.group != "" {
+			groupDevs := filepath.Join(iommuGroupsDir, dev.group, iommuGroupDevsPath)
+			require.NoError(t, os.MkdirAll(groupDevs, 0o755))
+			require.NoError(t, os.Symlink(devDir, filepath.Join(groupDevs, dev.addr)))
+			require.NoError(t, os.Symlink(filepath.Join(iommuGroupsDir, dev.group), filepath.Join(devDir, pciIOMMUGroupLink)))
+		}
+		if dev.driver != "" {
+			require.NoError(t, os.Symlink(filepath.Join(dir, "bus", "pci", "drivers", dev.driver), filepath.Join(devDir, pciDriverLink)))
+		}
+	}
+}
+
+func TestCheckGPUs(t *testing.T) {
+	setupPCI(t,
+		pciDevice{addr: "0000:41:00.0", class: "0x030200", vendor: nvidiaVendorID, group: "12", driver: vfioDriver},
+		pciDevice{addr: "0000:41:00.1", class: "0x040300", vendor: nvidiaVendorID, group: "12"},
+		pciDevice{addr: "0000:40:01.1", class: "0x060400", vendor: "0x1022", group: "12", driver: "pcieport"},
+		pciDevice{addr: "0000:81:00.0", class: "0x030200", vendor: nvidiaVendorID, group: "30", driver: "nvidia"},
+		pciDevice{addr: "0000:c1:00.0", class: "0x038000", vendor: "0x1002", group: "45", driver: vfioDriver},
+		pciDevice{addr: "0000:e1:00.0", class: "0x030200", vendor: nvidiaVendorID},
+		pciDevice{addr: "0000:e2:00.0", class: "0x030200", vendor: nvidiaVendorID, group: "51", driver: vfioDriver},
+	)
+
+	inUse := new(mocks.VM)
+	inUse.On("GetConfig").Return(qemu.VMInfo{Config: qemu.Config{GPUConfig: qemu.GPUConfig{Devices: []string{"0000:e2:00.0"}}}})
+
+	cases := []struct {
+		desc string
+		gpus qemu.GPUConfig
+		err  error
+	}{
+		{
+			desc: "no GPUs",
+		},
+		{
+			desc: "GPU sharing its group with a bridge and a driverless function",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:41:00.0"}, NvidiaCC: true},
+		},
+		{
+			desc: "GPU of another vendor",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:c1:00.0"}},
+		},
+		{
+			desc: "GPU of another vendor in NVIDIA confidential computing mode",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:c1:00.0"}, NvidiaCC: true},
+			err:  ErrInvalidGPU,
+		},
+		{
+			desc: "GPU bound to its host driver",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:81:00.0"}},
+			err:  ErrInvalidGPU,
+		},
+		{
+			desc: "GPU in no IOMMU group",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:e1:00.0"}},
+			err:  ErrInvalidGPU,
+		},
+		{
+			desc: "missing GPU",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:f1:00.0"}},
+			err:  ErrInvalidGPU,
+		},
+		{
+			desc: "GPU passed through to another CVM",
+			gpus: qemu.GPUConfig{Devices: []string{"0000:41:00.0", "0000:e2:00.0"}},
+			err:  ErrGPUInUse,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ms := &managerService{vms: map[string]vm.VM{"vm1": inUse}}
+			err := ms.checkGPUs(qemu.Config{GPUConfig: tc.gpus})
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestDetectGPUs(t *testing.T) {
+	setupPCI(t,
+		pciDevice{addr: "0000:c1:00.0", class: "0x038000", vendor: "0x1002", group: "45"},
+		pciDevice{addr: "0000:41:00.1", class: "0x040300", vendor: nvidiaVendorID, group: "12"},
+		pciDevice{addr: "0000:41:00.0", class: "0x030200", vendor: nvidiaVendorID, group: "12", driver: vfioDriver},
+	)
+
+	got := detectGPUs()
+	require.Len(t, got, 2)
+	for i, want := range []*Gpu{
+		{Address: "0000:41:00.0", VendorId: nvidiaVendorID, DeviceId: "0x2330", IommuGroup: "12", Driver: vfioDriver},
+		{Address: "0000:c1:00.0", VendorId: "0x1002", DeviceId: "0x2330", IommuGroup: "45"},
+	} {
+		assert.True(t, proto.Equal(want, got[i]), "GPU %d: %v", i, got[i])
+	}
+}
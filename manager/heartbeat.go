@@ -0,0 +1,282 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+)
+
+const (
+	agentHeartbeatPortKey     = "AGENT_HEARTBEAT_PORT"
+	agentHeartbeatIntervalKey = "AGENT_HEARTBEAT_INTERVAL"
+	agentConfigPortKey        = "AGENT_CONFIG_PORT"
+	traceParentKey            = "traceparent"
+	spansTimeout              = 10 * time.Second
+)
+
+// HeartbeatConfig configures the liveness checks of the CVM agents, which send heartbeats over vsock.
+type HeartbeatConfig struct {
+	// Port is the host vsock port agents send heartbeats to, heartbeats are disabled when it is 0.
+	Port        uint32        `env:"MANAGER_HEARTBEAT_PORT"         envDefault:"0"`
+	Interval    time.Duration `env:"MANAGER_HEARTBEAT_INTERVAL"     envDefault:"5s"`
+	MissedLimit int           `env:"MANAGER_HEARTBEAT_MISSED_LIMIT" envDefault:"3"`
+	// Restart resets CVMs whose agent stopped sending heartbeats.
+	Restart bool `env:"MANAGER_HEARTBEAT_RESTART" envDefault:"false"`
+	// AgentConfig holds agent environment variables the agents fetch over the
+	// heartbeat port at startup, overriding their environment file but not their
+	// kernel command line. No configuration is served when it is empty.
+	AgentConfig map[string]string `env:"MANAGER_AGENT_CONFIG" envKeyValSeparator:"="`
+	// Spans receives the spans the agents export over vsock, they are dropped when it is nil.
+	Spans otlptrace.Client
+	// Listener receives the heartbeats, a vsock listener on Port is opened when it is nil.
+	Listener net.Listener
+}
+
+type heartbeats struct {
+	cfg      HeartbeatConfig
+	monitor  *vsock.Monitor
+	listener net.Listener
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mu           sync.Mutex
+	traceParents map[string]string
+	diagnostics  map[string]*Diagnostics
+}
+
+func (ms *managerService) startHeartbeats(cfg HeartbeatConfig) {
+	if cfg.Port == 0 {
+		return
+	}
+
+	if ms.qemuCfg.VSockConfig.GuestCID == 0 {
+		ms.logger.Warn("Agent heartbeats are disabled because CVMs have no vsock device")
+		if cfg.Listener != nil {
+			cfg.Listener.Close()
+		}
+		return
+	}
+
+	l := cfg.Listener
+	if l == nil {
+		var err error
+		if l, err = vsock.Listen(cfg.Port); err != nil {
+			ms.logger.Error("Failed to listen for agent heartbeats", "port", cfg.Port, "error", err)
+			return
+		}
+	}
+
+	ms.serveHeartbeats(l, vsock.ContextID, cfg)
+}
+
+// serveHeartbeats acknowledges the agent heartbeats received on l and checks
+// every interval for CVMs that missed too many of them.
+func (ms *managerService) serveHeartbeats(l net.Listener, peerID func(net.Addr) (uint32, error), cfg HeartbeatConfig) {
+	opts := []vsock.MonitorOption{vsock.WithTraceParent(ms.traceParent), vsock.WithDiagnostics(ms.collectDiagnostics)}
+	if len(cfg.AgentConfig) > 0 {
+		opts = append(opts, vsock.WithConfig(ms.agentConfig))
+	}
+	if cfg.Spans != nil {
+		if err := cfg.Spans.Start(context.Background()); err != nil {
+			ms.logger.Error("Failed to start the agent spans exporter", "error", err)
+		} else {
+			opts = append(opts, vsock.WithSpans(ms.forwardSpans))
+		}
+	}
+
+	ms.heartbeats = &heartbeats{
+		cfg:          cfg,
+		monitor:      vsock.NewMonitor(cfg.Interval, cfg.MissedLimit, ms.logger, opts...),
+		listener:     l,
+		done:         make(chan struct{}),
+		traceParents: make(map[string]string),
+		diagnostics:  make(map[string]*Diagnostics),
+	}
+
+	go func() {
+		_ = ms.heartbeats.monitor.Serve(l, peerID)
+	}()
+
+	ms.heartbeats.wg.Add(1)
+	go func() {
+		defer ms.heartbeats.wg.Done()
+
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ms.heartbeats.done:
+				return
+			case <-ticker.C:
+				ms.checkHeartbeats()
+			}
+		}
+	}()
+}
+
+func (ms *managerService) stopHeartbeats() {
+	if ms.heartbeats == nil {
+		return
+	}
+
+	close(ms.heartbeats.done)
+	ms.heartbeats.listener.Close()
+	ms.heartbeats.wg.Wait()
+
+	if ms.heartbeats.cfg.Spans != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), spansTimeout)
+		defer cancel()
+
+		if err := ms.heartbeats.cfg.Spans.Stop(ctx); err != nil {
+			ms.logger.Error("Failed to stop the agent spans exporter", "error", err)
+		}
+	}
+}
+
+// checkHeartbeats reports the CVMs whose agent stopped sending heartbeats and
+// resets them when configured to.
+func (ms *managerService) checkHeartbeats() {
+	for _, cid := range ms.heartbeats.monitor.Unhealthy(time.Now()) {
+		ms.mu.Lock()
+		id, cvm, ok := ms.vmByGuestCID(cid)
+		if ok {
+			ms.publishEvent(id, EventVMUnhealthy, cvm, fmt.Sprintf("missed %d heartbeats", ms.heartbeats.cfg.MissedLimit))
+		}
+		ms.mu.Unlock()
+
+		if !ok {
+			ms.heartbeats.monitor.Forget(cid)
+			continue
+		}
+
+		ms.logger.Warn("CVM agent stopped sending heartbeats", "cvm", id, "cid", cid)
+
+		if !ms.heartbeats.cfg.Restart {
+			continue
+		}
+
+		if err := cvm.Restart(); err != nil {
+			if errors.Is(err, qemu.ErrResetUnsupported) {
+				ms.logger.Warn("Unhealthy confidential CVM is not reset, stop it or remove it instead", "cvm", id)
+				continue
+			}
+			ms.logger.Error("Failed to restart unhealthy CVM", "cvm", id, "error", err)
+			continue
+		}
+
+		ms.mu.Lock()
+		ms.publishEvent(id, EventVMRestarted, cvm, "")
+		ms.mu.Unlock()
+	}
+}
+
+// forgetHeartbeats stops tracking the heartbeats of a removed CVM, its vsock CID may be reused.
+func (ms *managerService) forgetHeartbeats(id string, cvm vm.VM) {
+	if ms.heartbeats == nil {
+		return
+	}
+
+	ms.heartbeats.mu.Lock()
+	delete(ms.heartbeats.traceParents, id)
+	delete(ms.heartbeats.diagnostics, id)
+	ms.heartbeats.mu.Unlock()
+
+	if vmi, ok := cvm.GetConfig().(qemu.VMInfo); ok {
+		ms.heartbeats.monitor.Forget(uint32(vmi.Config.VSockConfig.GuestCID))
+	}
+}
+
+// heartbeatEnvironment configures the agent to send heartbeats when they are
+// enabled, and to fetch its configuration from the heartbeat port when there is one.
+func (ms *managerService) heartbeatEnvironment(envMap map[string]string) {
+	if ms.heartbeats == nil {
+		return
+	}
+
+	port := strconv.FormatUint(uint64(ms.heartbeats.cfg.Port), 10)
+	envMap[agentHeartbeatPortKey] = port
+	envMap[agentHeartbeatIntervalKey] = ms.heartbeats.cfg.Interval.String()
+	if len(ms.heartbeats.cfg.AgentConfig) > 0 {
+		envMap[agentConfigPortKey] = port
+	}
+}
+
+// agentConfig returns the configuration served to the agent of the CVM with the vsock CID, nil for unknown CVMs.
+func (ms *managerService) agentConfig(cid uint32) map[string]string {
+	ms.mu.Lock()
+	_, _, ok := ms.vmByGuestCID(cid)
+	ms.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	return maps.Clone(ms.heartbeats.cfg.AgentConfig)
+}
+
+// traceComputation records the trace context of the request creating the CVM,
+// which the agent receives with the heartbeat acknowledgements and continues.
+func (ms *managerService) traceComputation(ctx context.Context, id string) {
+	if ms.heartbeats == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	ms.heartbeats.mu.Lock()
+	defer ms.heartbeats.mu.Unlock()
+
+	ms.heartbeats.traceParents[id] = carrier.Get(traceParentKey)
+}
+
+// traceParent returns the trace context of the computation running on the CVM with the vsock CID.
+func (ms *managerService) traceParent(cid uint32) string {
+	ms.mu.Lock()
+	id, _, ok := ms.vmByGuestCID(cid)
+	ms.mu.Unlock()
+	if !ok {
+		return ""
+	}
+
+	ms.heartbeats.mu.Lock()
+	defer ms.heartbeats.mu.Unlock()
+
+	return ms.heartbeats.traceParents[id]
+}
+
+// forwardSpans exports the spans of an agent to the trace collector of the manager.
+func (ms *managerService) forwardSpans(cid uint32, req *coltracepb.ExportTraceServiceRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), spansTimeout)
+	defer cancel()
+
+	if err := ms.heartbeats.cfg.Spans.UploadTraces(ctx, req.GetResourceSpans()); err != nil {
+		ms.logger.Warn("Failed to export agent spans", "cid", cid, "error", err)
+	}
+}
+
+// vmByGuestCID returns the CVM with the vsock CID, callers hold ms.mu.
+func (ms *managerService) vmByGuestCID(cid uint32) (string, vm.VM, bool) {
+	for id, cvm := range ms.vms {
+		if vmi, ok := cvm.GetConfig().(qemu.VMInfo); ok && uint32(vmi.Config.VSockConfig.GuestCID) == cid {
+			return id, cvm, true
+		}
+	}
+
+	return "", nil, false
+}
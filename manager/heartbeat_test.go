@@ -0,0 +1,209 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+const testGuestCID = 5
+
+func TestHeartbeats(t *testing.T) {
+	cases := []struct {
+		desc       string
+		restart    bool
+		restartErr error
+		events     []string
+	}{
+		{
+			desc:   "unhealthy CVM is reported",
+			events: []string{EventVMUnhealthy},
+		},
+		{
+			desc:    "unhealthy CVM is restarted",
+			restart: true,
+			events:  []string{EventVMUnhealthy, EventVMRestarted},
+		},
+		{
+			desc:       "unhealthy confidential CVM is not reset",
+			restart:    true,
+			restartErr: qemu.ErrResetUnsupported,
+			events:     []string{EventVMUnhealthy},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ms, cvm := newWatchService(t, "vm1")
+			cvm.On("State").Return(pkgmanager.VmRunning.String())
+			vmi := qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: testGuestCID}}}
+			cvm.On("GetConfig").Return(vmi)
+			restarted := make(chan struct{})
+			if tc.restart {
+				cvm.On("Restart").Run(func(mock.Arguments) { close(restarted) }).Return(tc.restartErr).Once()
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			cfg := HeartbeatConfig{Port: 7004, Interval: 10 * time.Millisecond, MissedLimit: 2, Restart: tc.restart}
+			ms.serveHeartbeats(l, func(net.Addr) (uint32, error) { return testGuestCID, nil }, cfg)
+			defer ms.stopHeartbeats()
+
+			envMap := map[string]string{}
+			ms.heartbeatEnvironment(envMap)
+			assert.Equal(t, map[string]string{agentHeartbeatPortKey: "7004", agentHeartbeatIntervalKey: "10ms"}, envMap)
+
+			watchCtx, stopWatch := context.WithCancel(context.Background())
+			defer stopWatch()
+			events, err := ms.WatchComputation(watchCtx, "vm1")
+			require.NoError(t, err)
+			<-events
+
+			hb := vsock.NewHeartbeater(func() (net.Conn, error) {
+				return net.Dial("tcp", l.Addr().String())
+			}, cfg.Interval, ms.logger)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- hb.Run(ctx) }()
+
+			require.Eventually(t, func() bool { return hb.RTT() > 0 }, time.Second, cfg.Interval)
+			cancel()
+			require.NoError(t, <-done)
+
+			for _, want := range tc.events {
+				select {
+				case event := <-events:
+					assert.Equal(t, want, event.EventType)
+				case <-time.After(time.Second):
+					t.Fatalf("missing %s event", want)
+				}
+			}
+			if tc.restart {
+				select {
+				case <-restarted:
+				case <-time.After(time.Second):
+					t.Fatal("CVM restart not attempted")
+				}
+			}
+			select {
+			case event := <-events:
+				t.Fatalf("unexpected %s event", event.EventType)
+			case <-time.After(5 * cfg.Interval):
+			}
+		})
+	}
+}
+
+func TestHeartbeatsDisabled(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	ms.qemuCfg.VSockConfig.GuestCID = 3
+
+	ms.startHeartbeats(HeartbeatConfig{})
+	assert.Nil(t, ms.heartbeats)
+
+	envMap := map[string]string{}
+	ms.heartbeatEnvironment(envMap)
+	assert.Empty(t, envMap)
+
+	ms.forgetHeartbeats("vm1", cvm)
+	ms.stopHeartbeats()
+}
+
+type fakeSpansClient struct {
+	spans chan []*tracepb.ResourceSpans
+}
+
+func (c *fakeSpansClient) Start(ctx context.Context) error { return nil }
+
+func (c *fakeSpansClient) Stop(ctx context.Context) error { return nil }
+
+func (c *fakeSpansClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	c.spans <- spans
+	return nil
+}
+
+func TestHeartbeatsTraceContext(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	vmi := qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: testGuestCID}}}
+	cvm.On("GetConfig").Return(vmi)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	spans := &fakeSpansClient{spans: make(chan []*tracepb.ResourceSpans, 1)}
+	cfg := HeartbeatConfig{Port: 7004, Interval: 10 * time.Millisecond, MissedLimit: 2, Spans: spans}
+	ms.serveHeartbeats(l, func(net.Addr) (uint32, error) { return testGuestCID, nil }, cfg)
+	defer ms.stopHeartbeats()
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ms.traceComputation(trace.ContextWithSpanContext(context.Background(), sc), "vm1")
+
+	dial := func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}
+	hb := vsock.NewHeartbeater(dial, cfg.Interval, ms.logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- hb.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return hb.SpanContext().IsValid() }, time.Second, cfg.Interval)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, sc.TraceID(), hb.SpanContext().TraceID())
+	assert.Equal(t, sc.SpanID(), hb.SpanContext().SpanID())
+
+	want := []*tracepb.ResourceSpans{{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{Name: "run_computation"}}}}}}
+	require.NoError(t, vsock.NewSpanClient(dial).UploadTraces(context.Background(), want))
+	got := <-spans.spans
+	require.Len(t, got, 1)
+	assert.Equal(t, "run_computation", got[0].ScopeSpans[0].Spans[0].Name)
+
+	ms.forgetHeartbeats("vm1", cvm)
+	assert.Empty(t, ms.traceParent(testGuestCID))
+}
+
+func TestHeartbeatsAgentConfig(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("GetConfig").Return(qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: testGuestCID}}})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	agentCfg := map[string]string{"AGENT_LOG_LEVEL": "info", "AGENT_LOGS_WINDOW": "64"}
+	cfg := HeartbeatConfig{Port: 7004, Interval: 10 * time.Millisecond, MissedLimit: 2, AgentConfig: agentCfg}
+	var cid atomic.Uint32
+	cid.Store(testGuestCID)
+	ms.serveHeartbeats(l, func(net.Addr) (uint32, error) { return cid.Load(), nil }, cfg)
+	defer ms.stopHeartbeats()
+
+	envMap := map[string]string{}
+	ms.heartbeatEnvironment(envMap)
+	assert.Equal(t, "7004", envMap[agentConfigPortKey], "agents fetch their configuration from the heartbeat port")
+
+	dial := func() (net.Conn, error) { return net.Dial("tcp", l.Addr().String()) }
+	got, err := vsock.FetchConfig(context.Background(), dial)
+	require.NoError(t, err)
+	assert.Equal(t, agentCfg, got)
+
+	cid.Store(testGuestCID + 1)
+	_, err = vsock.FetchConfig(context.Background(), dial)
+	assert.Error(t, err, "unknown CVMs are not served a configuration")
+}
@@ -0,0 +1,285 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ultravioletrs/cocos/internal/cmdline"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	cpuProcessor   = "processor"
+	cpuVendorID    = "vendor_id"
+	cpuModelName   = "model name"
+	cpuFlags       = "flags"
+	cpuFlagSME     = "sme"
+	cpuFlagSEVSNP  = "sev_snp"
+	cpuFlagTDXHost = "tdx_host_platform"
+	smeEnabled     = "mem_encrypt=on"
+	memTotal       = "MemTotal"
+	memAvailable   = "MemAvailable"
+	numaNodePrefix = "node"
+)
+
+// Host paths probed by DetectHostCapabilities on top of the CheckConfig ones,
+// variables so tests can point them at fixtures.
+var (
+	cpuInfoFile   = "/proc/cpuinfo"
+	memInfoFile   = "/proc/meminfo"
+	osReleaseFile = "/proc/sys/kernel/osrelease"
+	cmdlineFile   = cmdline.ProcCmdline
+	iommuClassDir = "/sys/class/iommu"
+	sevParam      = "/sys/module/kvm_amd/parameters/sev"
+	sevESParam    = "/sys/module/kvm_amd/parameters/sev_es"
+	numaNodeDir   = "/sys/devices/system/node"
+)
+
+// DetectHostCapabilities reports the TEE and virtualization features of the host,
+// along with hints on how to enable the ones it lacks. Files that cannot be read
+// are reported as missing capabilities.
+func DetectHostCapabilities() *HostCapabilities {
+	cpuinfo := readHostFile(cpuInfoFile)
+	kernelCmdline := readHostFile(cmdlineFile)
+	cpu := parseCPUInfo(cpuinfo)
+	flags := cpu.flags
+	memory, available := parseMemInfo(readHostFile(memInfoFile))
+
+	caps := &HostCapabilities{
+		KernelVersion:   readHostFile(osReleaseFile),
+		KernelCmdline:   kernelCmdline,
+		CpuModel:        cpu.model,
+		CpuVendor:       cpu.vendor,
+		Vcpus:           cpu.processors,
+		MemoryTotal:     memory,
+		MemoryAvailable: available,
+		Kvm:             hostFileExists(devKVM),
+		Sev:             readHostFile(sevParam) == kernelParamEnabled,
+		SevEs:           readHostFile(sevESParam) == kernelParamEnabled,
+		SevSnp:          qemu.SEVSNPEnabled(cpuinfo, readHostFile(sevSNPParam)),
+		Sme:             flags[cpuFlagSME] && slices.Contains(strings.Fields(kernelCmdline), smeEnabled),
+		Tdx:             qemu.TDXEnabled(cpuinfo, readHostFile(tdxParam)),
+		Iommu:           hostDirNotEmpty(iommuClassDir),
+		Vsock:           hostFileExists(devVhostVsock),
+		NumaNodes:       detectNUMANodes(),
+		Gpus:            detectGPUs(),
+	}
+
+	if !caps.Kvm {
+		caps.Issues = append(caps.Issues, devKVM+" is missing, enable virtualization in the BIOS and load the kvm module")
+	}
+	if flags[cpuFlagSEVSNP] && !caps.SevSnp {
+		caps.Issues = append(caps.Issues, "the CPU supports SEV-SNP but it is disabled, load kvm_amd with sev_snp=1")
+	}
+	if flags[cpuFlagSEVSNP] && !hostFileExists(devSEV) {
+		caps.Issues = append(caps.Issues, devSEV+" is missing, load the ccp module")
+	}
+	if flags[cpuFlagSME] && !caps.Sme {
+		caps.Issues = append(caps.Issues, "the CPU supports SME but it is disabled, add "+smeEnabled+" to the kernel command line")
+	}
+	if flags[cpuFlagTDXHost] && !caps.Tdx {
+		caps.Issues = append(caps.Issues, "the CPU supports TDX but it is disabled, load kvm_intel with tdx=1")
+	}
+	if !caps.SevSnp && !caps.Tdx {
+		caps.Issues = append(caps.Issues, "neither SEV-SNP nor TDX is enabled, CVMs run without confidential computing support")
+	}
+	if !caps.Iommu {
+		caps.Issues = append(caps.Issues, "no IOMMU is enabled, enable it in the BIOS and add amd_iommu=on or intel_iommu=on to the kernel command line")
+	}
+	if !caps.Vsock {
+		caps.Issues = append(caps.Issues, devVhostVsock+" is missing, load the vhost_vsock module")
+	}
+
+	return caps
+}
+
+func (ms *managerService) HostCapabilities(ctx context.Context) (*HostCapabilities, error) {
+	if ms.hostCapabilities == nil {
+		return nil, nil
+	}
+
+	// Features do not change while the manager runs, the available memory and
+	// the drivers GPUs are bound to do.
+	caps := proto.Clone(ms.hostCapabilities).(*HostCapabilities)
+	if _, available := parseMemInfo(readHostFile(memInfoFile)); available > 0 {
+		caps.MemoryAvailable = available
+	}
+	caps.Gpus = detectGPUs()
+
+	return caps, nil
+}
+
+// logHostCapabilities logs the capabilities detected at startup and a warning for each issue.
+func (ms *managerService) logHostCapabilities() {
+	caps := ms.hostCapabilities
+	ms.logger.Info("Detected host capabilities",
+		"kernel", caps.KernelVersion,
+		"cpu", caps.CpuModel,
+		"vendor", caps.CpuVendor,
+		"vcpus", caps.Vcpus,
+		"memory", caps.MemoryTotal,
+		"kvm", caps.Kvm,
+		"sev", caps.Sev,
+		"sev_es", caps.SevEs,
+		"sev_snp", caps.SevSnp,
+		"sme", caps.Sme,
+		"tdx", caps.Tdx,
+		"iommu", caps.Iommu,
+		"vsock", caps.Vsock,
+		"numa_nodes", len(caps.NumaNodes),
+		"gpus", len(caps.Gpus),
+	)
+
+	for _, issue := range caps.Issues {
+		ms.logger.Warn("Host capability issue: " + issue)
+	}
+}
+
+// cpuInfo is the description of the host CPU in /proc/cpuinfo.
+type cpuInfo struct {
+	vendor     string
+	model      string
+	flags      map[string]bool
+	processors uint32
+}
+
+// parseCPUInfo returns the vendor, model name and flags of the first processor
+// in /proc/cpuinfo and the number of processors it lists.
+func parseCPUInfo(cpuinfo string) cpuInfo {
+	info := cpuInfo{flags: make(map[string]bool)}
+
+	scanner := bufio.NewScanner(strings.NewReader(cpuinfo))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case cpuProcessor:
+			info.processors++
+		case cpuVendorID:
+			if info.vendor == "" {
+				info.vendor = strings.TrimSpace(value)
+			}
+		case cpuModelName:
+			if info.model == "" {
+				info.model = strings.TrimSpace(value)
+			}
+		case cpuFlags:
+			if len(info.flags) == 0 {
+				for _, flag := range strings.Fields(value) {
+					info.flags[flag] = true
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// parseMemInfo returns the total and available memory in /proc/meminfo, in bytes.
+func parseMemInfo(meminfo string) (uint64, uint64) {
+	var total, available uint64
+
+	scanner := bufio.NewScanner(strings.NewReader(meminfo))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		// Sizes are in KiB, e.g. "MemTotal:       65536000 kB".
+		kib, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch key {
+		case memTotal:
+			total = kib * 1024
+		case memAvailable:
+			available = kib * 1024
+		}
+	}
+
+	return total, available
+}
+
+// detectNUMANodes returns the NUMA nodes of the host ordered by ID, with
+// their CPUs and memory as listed in sysfs.
+func detectNUMANodes() []*NumaNode {
+	entries, err := os.ReadDir(numaNodeDir)
+	if err != nil {
+		return nil
+	}
+
+	var nodes []*NumaNode
+	for _, entry := range entries {
+		// Other entries are attributes of the topology, e.g. online or possible.
+		name, ok := strings.CutPrefix(entry.Name(), numaNodePrefix)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		dir := filepath.Join(numaNodeDir, entry.Name())
+		memory, _ := parseMemInfo(nodeMemInfo(readHostFile(filepath.Join(dir, "meminfo"))))
+		nodes = append(nodes, &NumaNode{
+			Id:          uint32(id),
+			Cpus:        readHostFile(filepath.Join(dir, "cpulist")),
+			MemoryTotal: memory,
+		})
+	}
+
+	slices.SortFunc(nodes, func(a, b *NumaNode) int {
+		return int(a.Id) - int(b.Id)
+	})
+
+	return nodes
+}
+
+// nodeMemInfo strips the node prefix from the lines of a node meminfo, e.g.
+// "Node 0 MemTotal:       65536000 kB", so it parses as /proc/meminfo.
+func nodeMemInfo(meminfo string) string {
+	lines := strings.Split(meminfo, "\n")
+	for i, line := range lines {
+		if fields := strings.SplitN(line, " ", 3); len(fields) == 3 && fields[0] == "Node" {
+			lines[i] = fields[2]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// readHostFile returns the trimmed content of a host file, or an empty string if it cannot be read.
+func readHostFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return string(bytes.TrimSpace(data))
+}
+
+func hostFileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func hostDirNotEmpty(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) > 0
+}
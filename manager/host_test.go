@@ -0,0 +1,270 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	amdCPUInfo = `processor	: 0
+vendor_id	: AuthenticAMD
+model name	: AMD EPYC 9124 16-Core Processor
+flags		: fpu vme sme sev sev_es sev_snp
+
+processor	: 1
+model name	: AMD EPYC 9124 16-Core Processor
+flags		: fpu vme sme sev sev_es sev_snp
+`
+	memInfo = `MemTotal:       65536000 kB
+MemFree:        30000000 kB
+MemAvailable:   48000000 kB
+`
+	intelCPUInfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Platinum 8570
+flags		: fpu vme vmx tdx_host_platform
+`
+)
+
+// hostFixture describes the host files DetectHostCapabilities probes, missing files are not created.
+type hostFixture struct {
+	cpuinfo  string
+	meminfo  string
+	cmdline  string
+	sev      string
+	sevES    string
+	sevSNP   string
+	tdx      string
+	devices  bool
+	iommuDev bool
+}
+
+func setupHost(t *testing.T, host hostFixture) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if content != "" {
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+		return path
+	}
+
+	cpuInfoFile = write("cpuinfo", host.cpuinfo)
+	cmdlineFile = write("cmdline", host.cmdline)
+	memInfoFile = write("meminfo", host.meminfo)
+	osReleaseFile = write("osrelease", "6.11.0-snp-host\n")
+	sevParam = write("sev", host.sev)
+	sevESParam = write("sev_es", host.sevES)
+	sevSNPParam = write("sev_snp", host.sevSNP)
+	tdxParam = write("tdx", host.tdx)
+
+	devKVM = filepath.Join(dir, "kvm")
+	devSEV = filepath.Join(dir, "sev-device")
+	devVhostVsock = filepath.Join(dir, "vhost-vsock")
+	if host.devices {
+		for _, dev := range []string{devKVM, devSEV, devVhostVsock} {
+			require.NoError(t, os.WriteFile(dev, nil, 0o644))
+		}
+	}
+
+	numaNodeDir = filepath.Join(dir, "node")
+	pciDevicesDir = filepath.Join(dir, "pci")
+	iommuGroupsDir = filepath.Join(dir, "iommu_groups")
+
+	iommuClassDir = filepath.Join(dir, "iommu")
+	require.NoError(t, os.Mkdir(iommuClassDir, 0o755))
+	if host.iommuDev {
+		require.NoError(t, os.Mkdir(filepath.Join(iommuClassDir, "ivhd0"), 0o755))
+	}
+}
+
+func TestDetectHostCapabilities(t *testing.T) {
+	cases := []struct {
+		desc   string
+		host   hostFixture
+		caps   *HostCapabilities
+		issues []string
+	}{
+		{
+			desc: "SEV-SNP host",
+			host: hostFixture{
+				cpuinfo:  amdCPUInfo,
+				meminfo:  memInfo,
+				cmdline:  "BOOT_IMAGE=/vmlinuz mem_encrypt=on kvm_amd.sev=1 amd_iommu=on\n",
+				sev:      "Y\n",
+				sevES:    "Y\n",
+				sevSNP:   "Y\n",
+				devices:  true,
+				iommuDev: true,
+			},
+			caps: &HostCapabilities{
+				CpuModel:        "AMD EPYC 9124 16-Core Processor",
+				CpuVendor:       "AuthenticAMD",
+				Vcpus:           2,
+				MemoryTotal:     65536000 * 1024,
+				MemoryAvailable: 48000000 * 1024,
+				Kvm:             true,
+				Sev:             true,
+				SevEs:           true,
+				SevSnp:          true,
+				Sme:             true,
+				Iommu:           true,
+				Vsock:           true,
+			},
+		},
+		{
+			desc: "SEV-SNP disabled",
+			host: hostFixture{
+				cpuinfo: amdCPUInfo,
+				cmdline: "BOOT_IMAGE=/vmlinuz\n",
+				sev:     "Y\n",
+				sevES:   "N\n",
+				sevSNP:  "N\n",
+			},
+			caps: &HostCapabilities{
+				CpuModel:  "AMD EPYC 9124 16-Core Processor",
+				CpuVendor: "AuthenticAMD",
+				Vcpus:     2,
+				Sev:       true,
+			},
+			issues: []string{
+				"kvm is missing",
+				"the CPU supports SEV-SNP but it is disabled",
+				"sev-device is missing",
+				"the CPU supports SME but it is disabled",
+				"neither SEV-SNP nor TDX is enabled",
+				"no IOMMU is enabled",
+				"vhost-vsock is missing",
+			},
+		},
+		{
+			desc: "TDX host",
+			host: hostFixture{
+				cpuinfo:  intelCPUInfo,
+				cmdline:  "BOOT_IMAGE=/vmlinuz intel_iommu=on\n",
+				tdx:      "Y\n",
+				devices:  true,
+				iommuDev: true,
+			},
+			caps: &HostCapabilities{
+				CpuModel:  "Intel(R) Xeon(R) Platinum 8570",
+				CpuVendor: "GenuineIntel",
+				Vcpus:     1,
+				Kvm:       true,
+				Tdx:       true,
+				Iommu:     true,
+				Vsock:     true,
+			},
+		},
+		{
+			desc: "TDX disabled",
+			host: hostFixture{
+				cpuinfo:  intelCPUInfo,
+				tdx:      "N\n",
+				devices:  true,
+				iommuDev: true,
+			},
+			caps: &HostCapabilities{
+				CpuModel:  "Intel(R) Xeon(R) Platinum 8570",
+				CpuVendor: "GenuineIntel",
+				Vcpus:     1,
+				Kvm:       true,
+				Iommu:     true,
+				Vsock:     true,
+			},
+			issues: []string{
+				"the CPU supports TDX but it is disabled",
+				"neither SEV-SNP nor TDX is enabled",
+			},
+		},
+		{
+			desc: "unreadable host files",
+			host: hostFixture{},
+			caps: &HostCapabilities{},
+			issues: []string{
+				"kvm is missing",
+				"neither SEV-SNP nor TDX is enabled",
+				"no IOMMU is enabled",
+				"vhost-vsock is missing",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			setupHost(t, tc.host)
+
+			caps := DetectHostCapabilities()
+
+			assert.Equal(t, "6.11.0-snp-host", caps.KernelVersion)
+			assert.Equal(t, readHostFile(cmdlineFile), caps.KernelCmdline)
+			assert.Equal(t, tc.caps.CpuModel, caps.CpuModel)
+			assert.Equal(t, tc.caps.CpuVendor, caps.CpuVendor)
+			assert.Equal(t, tc.caps.Vcpus, caps.Vcpus, "vcpus")
+			assert.Equal(t, tc.caps.MemoryTotal, caps.MemoryTotal, "memory total")
+			assert.Equal(t, tc.caps.MemoryAvailable, caps.MemoryAvailable, "memory available")
+			assert.Equal(t, tc.caps.Kvm, caps.Kvm, "kvm")
+			assert.Equal(t, tc.caps.Sev, caps.Sev, "sev")
+			assert.Equal(t, tc.caps.SevEs, caps.SevEs, "sev_es")
+			assert.Equal(t, tc.caps.SevSnp, caps.SevSnp, "sev_snp")
+			assert.Equal(t, tc.caps.Sme, caps.Sme, "sme")
+			assert.Equal(t, tc.caps.Tdx, caps.Tdx, "tdx")
+			assert.Equal(t, tc.caps.Iommu, caps.Iommu, "iommu")
+			assert.Equal(t, tc.caps.Vsock, caps.Vsock, "vsock")
+
+			require.Len(t, caps.Issues, len(tc.issues), "%v", caps.Issues)
+			for i, issue := range tc.issues {
+				assert.Contains(t, caps.Issues[i], issue)
+			}
+		})
+	}
+}
+
+func TestHostCapabilitiesMemory(t *testing.T) {
+	setupHost(t, hostFixture{cpuinfo: amdCPUInfo, meminfo: memInfo})
+	ms := &managerService{hostCapabilities: DetectHostCapabilities()}
+
+	require.NoError(t, os.WriteFile(memInfoFile, []byte("MemTotal:       65536000 kB\nMemAvailable:   1000 kB\n"), 0o644))
+
+	caps, err := ms.HostCapabilities(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000*1024), caps.MemoryAvailable, "the available memory is read on every request")
+	assert.Equal(t, uint64(65536000*1024), caps.MemoryTotal)
+	assert.Equal(t, uint64(48000000*1024), ms.hostCapabilities.MemoryAvailable, "the capabilities detected at startup are kept")
+}
+
+func TestDetectNUMANodes(t *testing.T) {
+	setupHost(t, hostFixture{cpuinfo: amdCPUInfo})
+	assert.Empty(t, DetectHostCapabilities().NumaNodes, "hosts without a NUMA topology report no nodes")
+
+	nodes := map[string]string{
+		"node1": "8-15,24-31",
+		"node0": "0-7,16-23",
+	}
+	for name, cpus := range nodes {
+		dir := filepath.Join(numaNodeDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cpulist"), []byte(cpus+"\n"), 0o644))
+		meminfo := "Node " + name[len("node"):] + " MemTotal:       32768000 kB\nNode " + name[len("node"):] + " MemFree:        1000 kB\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "meminfo"), []byte(meminfo), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(numaNodeDir, "online"), []byte("0-1\n"), 0o644))
+
+	got := DetectHostCapabilities().NumaNodes
+	require.Len(t, got, 2)
+	for i, want := range []*NumaNode{
+		{Id: 0, Cpus: "0-7,16-23", MemoryTotal: 32768000 * 1024},
+		{Id: 1, Cpus: "8-15,24-31", MemoryTotal: 32768000 * 1024},
+	} {
+		assert.True(t, proto.Equal(want, got[i]), "node %d: %v", i, got[i])
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"google.golang.org/grpc/status"
+)
+
+// Stages of a failed CVM launch, reported in the ManagerError of CreateVm.
+const (
+	StageQemuLaunch      = "qemu_launch"
+	StageOVMFMissing     = "ovmf_missing"
+	StageSEVNotSupported = "sev_not_supported"
+	StageVsockFailed     = "vsock_failed"
+)
+
+// LaunchError is a failure to start a CVM with the stage it failed at and a
+// hint on how to fix the host.
+type LaunchError struct {
+	Stage       string
+	Remediation string
+	Err         error
+}
+
+func (e *LaunchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LaunchError) Unwrap() error {
+	return e.Err
+}
+
+// Details returns the ManagerError the error is reported to callers with.
+func (e *LaunchError) Details() *ManagerError {
+	return &ManagerError{Stage: e.Stage, Message: e.Err.Error(), Remediation: e.Remediation}
+}
+
+// ManagerErrorDetails returns the ManagerError attached to an error returned by
+// the manager gRPC API, if any.
+func ManagerErrorDetails(err error) (*ManagerError, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+
+	for _, detail := range st.Details() {
+		if me, ok := detail.(*ManagerError); ok {
+			return me, true
+		}
+	}
+
+	return nil, false
+}
+
+// launchError probes the host for the cause of a failure to start a CVM with
+// cfg, as QEMU only reports it in its output. Errors are reported at the
+// qemu_launch stage when no host requirement is missing.
+func (ms *managerService) launchError(cfg qemu.Config, err error) error {
+	// microvm VMs boot the kernel directly, without firmware.
+	firmware := ms.backend(cfg).Firmware()
+	switch {
+	case !cfg.MicroVM() && !hostFileExists(firmware.Path):
+		return &LaunchError{
+			Stage:       StageOVMFMissing,
+			Remediation: "the " + firmware.Name + " firmware " + firmware.Path + " is missing, install it or point the manager at it",
+			Err:         err,
+		}
+	case !cfg.MicroVM() && !cfg.EnableSEVSNP && !cfg.EnableTDX && !hostFileExists(cfg.OVMFVarsConfig.File):
+		return &LaunchError{
+			Stage:       StageOVMFMissing,
+			Remediation: "the ovmf vars file " + cfg.OVMFVarsConfig.File + " is missing, install it or set MANAGER_QEMU_OVMF_VARS_FILE",
+			Err:         err,
+		}
+	case cfg.EnableSEVSNP && !hostFileExists(devSEV):
+		return &LaunchError{
+			Stage:       StageSEVNotSupported,
+			Remediation: devSEV + " is missing, load the ccp module",
+			Err:         err,
+		}
+	case cfg.EnableSEVSNP && readHostFile(sevSNPParam) != kernelParamEnabled:
+		return &LaunchError{
+			Stage:       StageSEVNotSupported,
+			Remediation: "SEV-SNP is disabled, enable it in the BIOS and load kvm_amd with sev_snp=1",
+			Err:         err,
+		}
+	case cfg.VSockConfig.GuestCID != 0 && !hostFileExists(devVhostVsock):
+		return &LaunchError{
+			Stage:       StageVsockFailed,
+			Remediation: devVhostVsock + " is missing, load the vhost_vsock module",
+			Err:         err,
+		}
+	default:
+		return &LaunchError{
+			Stage:       StageQemuLaunch,
+			Remediation: "QEMU failed to start the CVM, check the manager log for its output and validate the host with cocos-manager --check-config",
+			Err:         err,
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+func TestLaunchError(t *testing.T) {
+	dir := t.TempDir()
+	ovmfCode := writeCheckFile(t, dir, "OVMF_CODE.fd", "", 0o644)
+	ovmfVars := writeCheckFile(t, dir, "OVMF_VARS.fd", "", 0o644)
+	igvm := writeCheckFile(t, dir, "coconut-qemu.igvm", "", 0o644)
+	missing := filepath.Join(dir, "missing")
+
+	devSEV = writeCheckFile(t, dir, "sev", "", 0o644)
+	devVhostVsock = writeCheckFile(t, dir, "vhost-vsock", "", 0o644)
+	sevSNPParam = writeCheckFile(t, dir, "sev_snp", "Y\n", 0o644)
+	sevDisabled := writeCheckFile(t, dir, "sev_snp_disabled", "N\n", 0o644)
+
+	noTEE := func() qemu.Config {
+		cfg := qemu.Config{}
+		cfg.OVMFCodeConfig.File = ovmfCode
+		cfg.OVMFVarsConfig.File = ovmfVars
+		return cfg
+	}
+	sevSNP := func() qemu.Config {
+		cfg := qemu.Config{EnableSEVSNP: true}
+		cfg.IGVMConfig.File = igvm
+		return cfg
+	}
+
+	cases := []struct {
+		desc  string
+		cfg   func() qemu.Config
+		host  func()
+		stage string
+	}{
+		{
+			desc:  "qemu failure",
+			cfg:   noTEE,
+			stage: StageQemuLaunch,
+		},
+		{
+			desc: "missing ovmf code",
+			cfg: func() qemu.Config {
+				cfg := noTEE()
+				cfg.OVMFCodeConfig.File = missing
+				return cfg
+			},
+			stage: StageOVMFMissing,
+		},
+		{
+			desc: "missing ovmf vars",
+			cfg: func() qemu.Config {
+				cfg := noTEE()
+				cfg.OVMFVarsConfig.File = missing
+				return cfg
+			},
+			stage: StageOVMFMissing,
+		},
+		{
+			desc: "missing igvm file",
+			cfg: func() qemu.Config {
+				cfg := sevSNP()
+				cfg.IGVMConfig.File = missing
+				return cfg
+			},
+			stage: StageOVMFMissing,
+		},
+		{
+			desc:  "missing sev device",
+			cfg:   sevSNP,
+			host:  func() { devSEV = missing },
+			stage: StageSEVNotSupported,
+		},
+		{
+			desc:  "sev-snp disabled",
+			cfg:   sevSNP,
+			host:  func() { sevSNPParam = sevDisabled },
+			stage: StageSEVNotSupported,
+		},
+		{
+			desc: "missing vsock device",
+			cfg: func() qemu.Config {
+				cfg := noTEE()
+				cfg.VSockConfig.GuestCID = 3
+				return cfg
+			},
+			host:  func() { devVhostVsock = missing },
+			stage: StageVsockFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sev, vsock, param := devSEV, devVhostVsock, sevSNPParam
+			t.Cleanup(func() { devSEV, devVhostVsock, sevSNPParam = sev, vsock, param })
+			if tc.host != nil {
+				tc.host()
+			}
+
+			cfg := tc.cfg()
+			ms := &managerService{qemuCfg: cfg}
+			startErr := errors.New("qemu exited")
+
+			err := ms.launchError(cfg, startErr)
+
+			var le *LaunchError
+			assert.True(t, errors.As(err, &le))
+			assert.Equal(t, tc.stage, le.Stage)
+			assert.NotEmpty(t, le.Remediation)
+			assert.ErrorIs(t, err, startErr)
+			assert.Equal(t, startErr.Error(), le.Details().Message)
+		})
+	}
+}
+
+func TestManagerErrorDetails(t *testing.T) {
+	_, ok := ManagerErrorDetails(errors.New("not a status"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// vmLogSize is the number of bytes of manager log and of console output kept
+// per CVM for its log bundle, the oldest lines are dropped first.
+const vmLogSize = 1 << 20
+
+// Files of the log bundle.
+const (
+	bundleManagerLog  = "manager.log"
+	bundleConsoleLog  = "console.log"
+	bundleAlgorithm   = "algorithm.log"
+	bundleEvents      = "events.jsonl"
+	bundleDiagnostics = "diagnostics.json"
+)
+
+// cvmLogKeys are the log attributes the manager names the CVM of a record with.
+var cvmLogKeys = []string{"cvm", "vmID", "computation", "computationId"}
+
+// vmLogs keeps the recent manager log records and console output of each CVM,
+// and writes the console output to the console files.
+type vmLogs struct {
+	mu       sync.Mutex
+	logs     map[string]*vmLog
+	consoles *consoles
+}
+
+type vmLog struct {
+	manager lineBuffer
+	console lineBuffer
+}
+
+// lineBuffer holds the latest lines, up to vmLogSize bytes.
+type lineBuffer struct {
+	lines [][]byte
+	size  int
+}
+
+func (b *lineBuffer) add(line []byte) {
+	b.lines = append(b.lines, line)
+	b.size += len(line)
+	for b.size > vmLogSize && len(b.lines) > 0 {
+		b.size -= len(b.lines[0])
+		b.lines[0] = nil
+		b.lines = b.lines[1:]
+	}
+}
+
+func (b *lineBuffer) bytes() []byte {
+	return bytes.Join(b.lines, nil)
+}
+
+func newVMLogs(consoles *consoles) *vmLogs {
+	return &vmLogs{logs: make(map[string]*vmLog), consoles: consoles}
+}
+
+// open starts keeping the records of the CVM, records of other CVMs are dropped.
+func (l *vmLogs) open(id string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.logs[id]; !ok {
+		l.logs[id] = &vmLog{}
+	}
+}
+
+// drop discards the records of the CVM and closes its console file.
+func (l *vmLogs) drop(id string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.logs, id)
+	l.consoles.close(id)
+}
+
+func (l *vmLogs) add(id string, console bool, line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.logs[id]
+	if !ok {
+		return
+	}
+
+	if console {
+		log.console.add(line)
+		return
+	}
+	log.manager.add(line)
+}
+
+// addConsole writes the raw console output of the CVM to its console file.
+func (l *vmLogs) addConsole(id string, output []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.logs[id]; ok {
+		l.consoles.write(id, output)
+	}
+}
+
+// snapshot returns the kept manager log and console output of the CVM.
+func (l *vmLogs) snapshot(id string) ([]byte, []byte) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log, ok := l.logs[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return log.manager.bytes(), log.console.bytes()
+}
+
+// vmLogHandler passes records on to the manager log handler and keeps a
+// copy of those naming a CVM for its log bundle. QEMU output, which carries
+// the vm.StreamKey attribute, is kept as the console output of the CVM and
+// written to its console file.
+type vmLogHandler struct {
+	slog.Handler
+	logs  *vmLogs
+	attrs []slog.Attr
+}
+
+func newVMLogHandler(h slog.Handler, logs *vmLogs) *vmLogHandler {
+	return &vmLogHandler{Handler: h, logs: logs}
+}
+
+func (h *vmLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := slices.Clone(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	var id string
+	var console bool
+	for _, a := range attrs {
+		if slices.Contains(cvmLogKeys, a.Key) && id == "" {
+			id = a.Value.String()
+		}
+		if a.Key == vm.StreamKey {
+			console = true
+		}
+	}
+	if id != "" {
+		h.logs.add(id, console, formatRecord(r, attrs))
+		if console {
+			h.logs.addConsole(id, []byte(r.Message))
+		}
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *vmLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &vmLogHandler{Handler: h.Handler.WithAttrs(attrs), logs: h.logs, attrs: append(slices.Clone(h.attrs), attrs...)}
+}
+
+func (h *vmLogHandler) WithGroup(name string) slog.Handler {
+	return &vmLogHandler{Handler: h.Handler.WithGroup(name), logs: h.logs, attrs: h.attrs}
+}
+
+// formatRecord formats the record as a line of its time, level, message and attributes.
+func formatRecord(r slog.Record, attrs []slog.Attr) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", r.Time.UTC().Format(time.RFC3339Nano), r.Level, strings.TrimRight(r.Message, "\n"))
+	for _, a := range attrs {
+		fmt.Fprintf(&b, " %s=%q", a.Key, a.Value.String())
+	}
+	b.WriteByte('\n')
+
+	return []byte(b.String())
+}
+
+func (ms *managerService) DownloadLogs(ctx context.Context, computationID string) ([]byte, error) {
+	ms.mu.Lock()
+	if _, ok := ms.vms[computationID]; !ok {
+		ms.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	events := slices.Clone(ms.history[computationID])
+	ms.mu.Unlock()
+
+	files := map[string][]byte{}
+	files[bundleManagerLog], files[bundleConsoleLog] = ms.vmLogs.snapshot(computationID)
+
+	var eventLines bytes.Buffer
+	for _, event := range events {
+		data, err := protojson.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		eventLines.Write(data)
+		eventLines.WriteByte('\n')
+	}
+	files[bundleEvents] = eventLines.Bytes()
+
+	if ms.logs != nil {
+		files[bundleAlgorithm] = ms.logs.buffered(computationID)
+	}
+
+	if diagnostics, err := ms.Diagnostics(ctx, computationID); err == nil {
+		files[bundleDiagnostics] = diagnostics.Snapshot
+	}
+
+	return zipBundle(files)
+}
+
+// zipBundle writes the files to a zip archive in name order.
+func zipBundle(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func readBundle(t *testing.T, bundle []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	return files
+}
+
+func TestDownloadLogs(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+	cvm.On("Stop").Return(nil)
+
+	var managerLog bytes.Buffer
+	ms.vmLogs = newVMLogs(nil)
+	ms.logger = slog.New(newVMLogHandler(slog.NewTextHandler(&managerLog, nil), ms.vmLogs))
+	ms.logs = &logs{
+		cfg:     LogsConfig{BufferSize: 1024},
+		backlog: make(map[string]*logBacklog),
+		subs:    make(map[string]map[chan *LogChunk]struct{}),
+	}
+
+	_, err := ms.DownloadLogs(context.Background(), "vm2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ms.logger.Info("Not kept before the first event of the CVM", "cvm", "vm1")
+
+	ms.mu.Lock()
+	ms.publishEvent("vm1", EventVMProvisioning, cvm, "")
+	ms.publishEvent("vm1", EventVMRunning, cvm, "")
+	ms.mu.Unlock()
+
+	ms.logger.Warn("CVM agent missed a heartbeat", "cvm", "vm1", "missed", 1)
+	ms.logger.With("vmID", "vm1").Error("Failed to reset VM")
+	ms.logger.Info("Other CVM", "cvm", "vm2")
+	ms.logger.Info("Manager started")
+
+	stdout := &vm.Stdout{StateMachine: cvm, Logger: ms.logger.With(slog.String("cvm", "vm1"))}
+	_, err = stdout.Write([]byte("SeaBIOS booting"))
+	require.NoError(t, err)
+
+	ms.logs.publish(&LogChunk{CvmId: "vm1", Stream: "stdout", Data: []byte("epoch 1\n"), Timestamp: timestamppb.Now()})
+
+	bundle, err := ms.DownloadLogs(context.Background(), "vm1")
+	require.NoError(t, err)
+	files := readBundle(t, bundle)
+
+	managerLines := strings.Split(strings.TrimSpace(files[bundleManagerLog]), "\n")
+	require.Len(t, managerLines, 2)
+	assert.Contains(t, managerLines[0], `WARN CVM agent missed a heartbeat cvm="vm1" missed="1"`)
+	assert.Contains(t, managerLines[1], `ERROR Failed to reset VM vmID="vm1"`)
+	assert.Contains(t, files[bundleConsoleLog], `INFO SeaBIOS booting cvm="vm1" state="VmRunning" stream="stdout"`)
+	assert.NotContains(t, files[bundleConsoleLog], "heartbeat")
+	assert.Equal(t, "epoch 1\n", files[bundleAlgorithm])
+	events := strings.Split(strings.TrimSpace(files[bundleEvents]), "\n")
+	require.Len(t, events, 2)
+	assert.Contains(t, events[0], EventVMProvisioning)
+	assert.Contains(t, events[1], EventVMRunning)
+	assert.NotContains(t, files, bundleDiagnostics)
+
+	assert.Contains(t, managerLog.String(), "Manager started", "records are still passed on to the manager log")
+
+	require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+	kept, _ := ms.vmLogs.snapshot("vm1")
+	assert.Empty(t, kept, "the logs of a removed CVM are dropped")
+	_, err = ms.DownloadLogs(context.Background(), "vm1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,307 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	agentLogsPortKey = "AGENT_LOGS_PORT"
+	// logsWatchBufferSize is the number of chunks buffered per subscriber on
+	// top of the backlog, chunks are dropped for subscribers that fall further behind.
+	logsWatchBufferSize = 256
+)
+
+// LogsConfig configures the collection of the algorithm output the CVM agents stream over vsock.
+type LogsConfig struct {
+	// Port is the host vsock port agents stream the algorithm output to, log collection is disabled when it is 0.
+	Port uint32 `env:"MANAGER_LOGS_PORT"        envDefault:"0"`
+	// BufferSize is the number of bytes of output kept per CVM for new subscribers.
+	BufferSize int `env:"MANAGER_LOGS_BUFFER_SIZE" envDefault:"1048576"`
+	// Listener receives the agent connections, a vsock listener on Port is opened when it is nil.
+	Listener net.Listener
+}
+
+// LogsFilter selects the buffered output sent to a logs subscriber.
+type LogsFilter struct {
+	// Since skips the output captured before it when it is not zero.
+	Since time.Time
+	// Tail limits the buffered output to its last lines, all of it is sent when Tail is negative.
+	Tail int
+	// Follow keeps streaming new output, the stream ends after the buffered output otherwise.
+	Follow bool
+}
+
+// logs buffers the algorithm output of each CVM and fans it out to the subscribers.
+type logs struct {
+	cfg      LogsConfig
+	listener net.Listener
+
+	mu      sync.Mutex
+	backlog map[string]*logBacklog
+	subs    map[string]map[chan *LogChunk]struct{}
+}
+
+// logBacklog holds the latest output of a CVM, up to the configured buffer size.
+type logBacklog struct {
+	chunks []*LogChunk
+	size   int
+}
+
+func (ms *managerService) startLogs(cfg LogsConfig) {
+	if cfg.Port == 0 {
+		return
+	}
+
+	if ms.qemuCfg.VSockConfig.GuestCID == 0 {
+		ms.logger.Warn("Agent log collection is disabled because CVMs have no vsock device")
+		if cfg.Listener != nil {
+			cfg.Listener.Close()
+		}
+		return
+	}
+
+	l := cfg.Listener
+	if l == nil {
+		var err error
+		if l, err = vsock.Listen(cfg.Port); err != nil {
+			ms.logger.Error("Failed to listen for agent logs", "port", cfg.Port, "error", err)
+			return
+		}
+	}
+
+	ms.serveLogs(l, vsock.ContextID, cfg)
+}
+
+// serveLogs collects the algorithm output the agents stream on the connections accepted from l.
+func (ms *managerService) serveLogs(l net.Listener, peerID func(net.Addr) (uint32, error), cfg LogsConfig) {
+	ms.logs = &logs{
+		cfg:      cfg,
+		listener: l,
+		backlog:  make(map[string]*logBacklog),
+		subs:     make(map[string]map[chan *LogChunk]struct{}),
+	}
+
+	collector := vsock.NewLogCollector(ms.logger, ms.collectLog)
+	go func() {
+		_ = collector.Serve(l, peerID)
+	}()
+}
+
+func (ms *managerService) stopLogs() {
+	if ms.logs == nil {
+		return
+	}
+
+	ms.logs.listener.Close()
+}
+
+// logsEnvironment configures the agent to stream the algorithm output when log collection is enabled.
+func (ms *managerService) logsEnvironment(envMap map[string]string) {
+	if ms.logs == nil {
+		return
+	}
+
+	envMap[agentLogsPortKey] = strconv.FormatUint(uint64(ms.logs.cfg.Port), 10)
+}
+
+// collectLog records the output of the algorithm running on the CVM with the vsock CID.
+func (ms *managerService) collectLog(cid uint32, rec vsock.LogRecord) {
+	// The CVM lock orders the output with the removal of the CVM, which drops its backlog.
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	id, _, ok := ms.vmByGuestCID(cid)
+	if !ok {
+		return
+	}
+
+	ms.logs.publish(&LogChunk{
+		CvmId:     id,
+		Stream:    rec.Stream.String(),
+		Data:      rec.Data,
+		Timestamp: timestamppb.New(rec.Time),
+	})
+}
+
+func (ms *managerService) Logs(ctx context.Context, computationID string, filter LogsFilter) (<-chan *LogChunk, error) {
+	if ms.logs == nil {
+		return nil, ErrLogsDisabled
+	}
+
+	ms.mu.Lock()
+	if _, ok := ms.vms[computationID]; !ok {
+		ms.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	ch := ms.logs.subscribe(computationID, filter)
+	ms.mu.Unlock()
+
+	if !filter.Follow {
+		return ch, nil
+	}
+
+	go func() {
+		<-ctx.Done()
+		ms.logs.unsubscribe(computationID, ch)
+	}()
+
+	return ch, nil
+}
+
+// subscribe queues the buffered output selected by the filter and registers
+// a subscriber for the CVM, the channel is closed right away unless it follows the output.
+func (l *logs) subscribe(id string, filter LogsFilter) chan *LogChunk {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var chunks []*LogChunk
+	if b, ok := l.backlog[id]; ok {
+		for _, chunk := range b.chunks {
+			if filter.Since.IsZero() || !chunk.Timestamp.AsTime().Before(filter.Since) {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	if filter.Tail >= 0 {
+		chunks = tailChunks(chunks, filter.Tail)
+	}
+
+	ch := make(chan *LogChunk, len(chunks)+logsWatchBufferSize)
+	for _, chunk := range chunks {
+		ch <- chunk
+	}
+
+	if !filter.Follow {
+		close(ch)
+		return ch
+	}
+
+	if l.subs[id] == nil {
+		l.subs[id] = make(map[chan *LogChunk]struct{})
+	}
+	l.subs[id][ch] = struct{}{}
+
+	return ch
+}
+
+// tailChunks returns the part of the chunks holding their last n lines.
+func tailChunks(chunks []*LogChunk, n int) []*LogChunk {
+	if n == 0 {
+		return nil
+	}
+
+	lines := 0
+	last := true
+	for i := len(chunks) - 1; i >= 0; i-- {
+		data := chunks[i].Data
+		for j := len(data) - 1; j >= 0; j-- {
+			// The newline ending the output does not start another line.
+			if data[j] != '\n' || last {
+				last = false
+				continue
+			}
+
+			if lines++; lines < n {
+				continue
+			}
+
+			tail := chunks[i+1:]
+			if j+1 < len(data) {
+				chunk := &LogChunk{
+					CvmId:     chunks[i].CvmId,
+					Stream:    chunks[i].Stream,
+					Data:      bytes.Clone(data[j+1:]),
+					Timestamp: chunks[i].Timestamp,
+				}
+				tail = append([]*LogChunk{chunk}, tail...)
+			}
+
+			return tail
+		}
+	}
+
+	return chunks
+}
+
+// buffered returns the buffered output of the CVM.
+func (l *logs) buffered(id string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buf bytes.Buffer
+	if b, ok := l.backlog[id]; ok {
+		for _, chunk := range b.chunks {
+			buf.Write(chunk.Data)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// unsubscribe removes and closes the subscriber channel if it is still registered.
+func (l *logs) unsubscribe(id string, ch chan *LogChunk) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.subs[id][ch]; !ok {
+		return
+	}
+
+	delete(l.subs[id], ch)
+	if len(l.subs[id]) == 0 {
+		delete(l.subs, id)
+	}
+	close(ch)
+}
+
+// publish buffers the chunk and delivers it to the CVM subscribers without blocking the caller.
+func (l *logs) publish(chunk *LogChunk) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.backlog[chunk.CvmId]
+	if !ok {
+		b = &logBacklog{}
+		l.backlog[chunk.CvmId] = b
+	}
+	b.chunks = append(b.chunks, chunk)
+	b.size += len(chunk.Data)
+	for b.size > l.cfg.BufferSize && len(b.chunks) > 0 {
+		b.size -= len(b.chunks[0].Data)
+		b.chunks[0] = nil
+		b.chunks = b.chunks[1:]
+	}
+
+	for ch := range l.subs[chunk.CvmId] {
+		select {
+		case ch <- chunk:
+		default:
+		}
+	}
+}
+
+// close ends all subscriptions of the CVM and drops its buffered output.
+func (l *logs) close(id string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subs[id] {
+		close(ch)
+	}
+	delete(l.subs, id)
+	delete(l.backlog, id)
+}
@@ -0,0 +1,237 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func receiveChunk(t *testing.T, chunks <-chan *LogChunk) *LogChunk {
+	t.Helper()
+
+	select {
+	case chunk, ok := <-chunks:
+		require.True(t, ok, "log stream closed")
+		return chunk
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a log chunk")
+		return nil
+	}
+}
+
+func TestLogs(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	vmi := qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: testGuestCID}}}
+	cvm.On("GetConfig").Return(vmi)
+	cvm.On("State").Return(pkgmanager.StopComputationRun.String())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := LogsConfig{Port: 7005, BufferSize: 1 << 20}
+	ms.serveLogs(l, func(net.Addr) (uint32, error) { return testGuestCID, nil }, cfg)
+	defer ms.stopLogs()
+
+	envMap := map[string]string{}
+	ms.logsEnvironment(envMap)
+	assert.Equal(t, map[string]string{agentLogsPortKey: "7005"}, envMap)
+
+	shipper := vsock.NewLogShipper(func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}, ms.logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- shipper.Run(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	_, err = shipper.Stdout().Write([]byte("epoch 1\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		ms.logs.mu.Lock()
+		defer ms.logs.mu.Unlock()
+
+		return ms.logs.backlog["vm1"] != nil
+	}, time.Second, 10*time.Millisecond)
+
+	chunks, err := ms.Logs(context.Background(), "vm1", LogsFilter{Tail: -1, Follow: true})
+	require.NoError(t, err)
+
+	chunk := receiveChunk(t, chunks)
+	assert.Equal(t, "vm1", chunk.CvmId)
+	assert.Equal(t, "stdout", chunk.Stream)
+	assert.Equal(t, "epoch 1\n", string(chunk.Data))
+	assert.NotNil(t, chunk.Timestamp)
+
+	_, err = shipper.Stderr().Write([]byte("warning\n"))
+	require.NoError(t, err)
+
+	chunk = receiveChunk(t, chunks)
+	assert.Equal(t, "stderr", chunk.Stream)
+	assert.Equal(t, "warning\n", string(chunk.Data))
+
+	require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+	_, ok := <-chunks
+	assert.False(t, ok, "log stream is closed once the CVM is removed")
+
+	ms.logs.mu.Lock()
+	assert.Empty(t, ms.logs.backlog)
+	ms.logs.mu.Unlock()
+}
+
+func TestLogsBacklog(t *testing.T) {
+	l := &logs{
+		cfg:     LogsConfig{BufferSize: 10},
+		backlog: make(map[string]*logBacklog),
+		subs:    make(map[string]map[chan *LogChunk]struct{}),
+	}
+
+	for _, data := range []string{"first\n", "second\n", "third\n"} {
+		l.publish(&LogChunk{CvmId: "vm1", Data: []byte(data)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := l.subscribe("vm1", LogsFilter{Tail: -1, Follow: true})
+	go func() {
+		<-ctx.Done()
+		l.unsubscribe("vm1", ch)
+	}()
+
+	assert.Equal(t, "third\n", string(receiveChunk(t, ch).Data))
+	assert.Empty(t, ch, "older output is evicted beyond the buffer size")
+
+	cancel()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		return len(l.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogsFilter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	l := &logs{
+		cfg:     LogsConfig{BufferSize: 1 << 20},
+		backlog: make(map[string]*logBacklog),
+		subs:    make(map[string]map[chan *LogChunk]struct{}),
+	}
+	for i, data := range []string{"epoch 1\nepoch 2\n", "epoch 3\nepo", "ch 4\n"} {
+		l.publish(&LogChunk{CvmId: "vm1", Stream: "stdout", Data: []byte(data), Timestamp: timestamppb.New(start.Add(time.Duration(i) * time.Second))})
+	}
+
+	cases := []struct {
+		desc   string
+		filter LogsFilter
+		output string
+	}{
+		{
+			desc:   "all buffered output",
+			filter: LogsFilter{Tail: -1},
+			output: "epoch 1\nepoch 2\nepoch 3\nepoch 4\n",
+		},
+		{
+			desc:   "no buffered output",
+			filter: LogsFilter{Tail: 0},
+		},
+		{
+			desc:   "last line split across chunks",
+			filter: LogsFilter{Tail: 1},
+			output: "epoch 4\n",
+		},
+		{
+			desc:   "last lines within a chunk",
+			filter: LogsFilter{Tail: 3},
+			output: "epoch 2\nepoch 3\nepoch 4\n",
+		},
+		{
+			desc:   "more lines than buffered",
+			filter: LogsFilter{Tail: 10},
+			output: "epoch 1\nepoch 2\nepoch 3\nepoch 4\n",
+		},
+		{
+			desc:   "output since a time",
+			filter: LogsFilter{Since: start.Add(time.Second), Tail: -1},
+			output: "epoch 3\nepoch 4\n",
+		},
+		{
+			desc:   "output since a time and last line",
+			filter: LogsFilter{Since: start.Add(2 * time.Second), Tail: 1},
+			output: "ch 4\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var output string
+			for chunk := range l.subscribe("vm1", tc.filter) {
+				output += string(chunk.Data)
+			}
+			assert.Equal(t, tc.output, output)
+		})
+	}
+
+	assert.Empty(t, l.subs, "subscribers that do not follow the output are not registered")
+	assert.Equal(t, "epoch 3\nepo", string(l.backlog["vm1"].chunks[1].Data), "the buffered output is not modified")
+}
+
+func TestLogsPublishDoesNotBlock(t *testing.T) {
+	l := &logs{
+		cfg:     LogsConfig{BufferSize: 1 << 20},
+		backlog: make(map[string]*logBacklog),
+		subs:    make(map[string]map[chan *LogChunk]struct{}),
+	}
+	ch := l.subscribe("vm1", LogsFilter{Tail: -1, Follow: true})
+
+	for range logsWatchBufferSize * 2 {
+		l.publish(&LogChunk{CvmId: "vm1", Data: []byte("line\n")})
+	}
+	l.close("vm1")
+
+	count := 0
+	for range ch {
+		count++
+	}
+	assert.Equal(t, logsWatchBufferSize, count)
+}
+
+func TestLogsDisabled(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+	ms.qemuCfg.VSockConfig.GuestCID = 3
+
+	ms.startLogs(LogsConfig{})
+	assert.Nil(t, ms.logs)
+
+	envMap := map[string]string{}
+	ms.logsEnvironment(envMap)
+	assert.Empty(t, envMap)
+
+	_, err := ms.Logs(context.Background(), "vm1", LogsFilter{})
+	assert.ErrorIs(t, err, ErrLogsDisabled)
+
+	ms.logs.close("vm1")
+	ms.stopLogs()
+}
+
+func TestLogsNotFound(t *testing.T) {
+	ms, _ := newWatchService(t, "vm1")
+	ms.logs = &logs{
+		backlog: make(map[string]*logBacklog),
+		subs:    make(map[string]map[chan *LogChunk]struct{}),
+	}
+
+	_, err := ms.Logs(context.Background(), "unknown", LogsFilter{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -13,6 +13,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -35,8 +36,27 @@ type CreateReq struct {
 	AgentCvmCaUrl        string                 `protobuf:"bytes,6,opt,name=agent_cvm_ca_url,json=agentCvmCaUrl,proto3" json:"agent_cvm_ca_url,omitempty"`
 	Ttl                  string                 `protobuf:"bytes,7,opt,name=ttl,proto3" json:"ttl,omitempty"`
 	AgentCertsToken      string                 `protobuf:"bytes,8,opt,name=agent_certs_token,json=agentCertsToken,proto3" json:"agent_certs_token,omitempty"`
-	unknownFields        protoimpl.UnknownFields
-	sizeCache            protoimpl.SizeCache
+	// machine_profile selects the machine the CVM is launched with, default or
+	// microvm, the manager MACHINE_PROFILE when empty.
+	MachineProfile string `protobuf:"bytes,9,opt,name=machine_profile,json=machineProfile,proto3" json:"machine_profile,omitempty"`
+	// tenant the CVM counts against the quotas of. The tenant is the subject of
+	// the verified client certificate of the caller, or the default tenant
+	// without mutual TLS, and requesting another tenant is refused.
+	Tenant string `protobuf:"bytes,10,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// host_cpus pins the vCPUs to host CPUs, in the cpulist format, e.g. 0-3,8,
+	// the manager HOST_CPUS when empty.
+	HostCpus string `protobuf:"bytes,11,opt,name=host_cpus,json=hostCpus,proto3" json:"host_cpus,omitempty"`
+	// numa_node is the host NUMA node the guest memory is allocated from, the
+	// manager NUMA_NODE when unset.
+	NumaNode *uint32 `protobuf:"varint,12,opt,name=numa_node,json=numaNode,proto3,oneof" json:"numa_node,omitempty"`
+	// gpu_devices are the PCI addresses of the host GPUs passed through to the
+	// CVM, e.g. 0000:41:00.0, among the manager GPU_DEVICES.
+	GpuDevices []string `protobuf:"bytes,13,rep,name=gpu_devices,json=gpuDevices,proto3" json:"gpu_devices,omitempty"`
+	// gpu_nvidia_cc runs the GPUs in NVIDIA confidential computing mode, the
+	// manager GPU_NVIDIA_CC when unset.
+	GpuNvidiaCc   *bool `protobuf:"varint,14,opt,name=gpu_nvidia_cc,json=gpuNvidiaCc,proto3,oneof" json:"gpu_nvidia_cc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateReq) Reset() {
@@ -125,6 +145,48 @@ func (x *CreateReq) GetAgentCertsToken() string {
 	return ""
 }
 
+func (x *CreateReq) GetMachineProfile() string {
+	if x != nil {
+		return x.MachineProfile
+	}
+	return ""
+}
+
+func (x *CreateReq) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *CreateReq) GetHostCpus() string {
+	if x != nil {
+		return x.HostCpus
+	}
+	return ""
+}
+
+func (x *CreateReq) GetNumaNode() uint32 {
+	if x != nil && x.NumaNode != nil {
+		return *x.NumaNode
+	}
+	return 0
+}
+
+func (x *CreateReq) GetGpuDevices() []string {
+	if x != nil {
+		return x.GpuDevices
+	}
+	return nil
+}
+
+func (x *CreateReq) GetGpuNvidiaCc() bool {
+	if x != nil && x.GpuNvidiaCc != nil {
+		return *x.GpuNvidiaCc
+	}
+	return false
+}
+
 type CreateRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ForwardedPort string                 `protobuf:"bytes,1,opt,name=forwarded_port,json=forwardedPort,proto3" json:"forwarded_port,omitempty"`
@@ -221,6 +283,154 @@ func (x *RemoveReq) GetCvmId() string {
 	return ""
 }
 
+type StopReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopReq) Reset() {
+	*x = StopReq{}
+	mi := &file_manager_manager_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopReq) ProtoMessage() {}
+
+func (x *StopReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopReq.ProtoReflect.Descriptor instead.
+func (*StopReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StopReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+type StopRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopRes) Reset() {
+	*x = StopRes{}
+	mi := &file_manager_manager_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRes) ProtoMessage() {}
+
+func (x *StopRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRes.ProtoReflect.Descriptor instead.
+func (*StopRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StopRes) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *StopRes) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+type AttachDatasetReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	DiskPath      string                 `protobuf:"bytes,2,opt,name=disk_path,json=diskPath,proto3" json:"disk_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachDatasetReq) Reset() {
+	*x = AttachDatasetReq{}
+	mi := &file_manager_manager_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttachDatasetReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttachDatasetReq) ProtoMessage() {}
+
+func (x *AttachDatasetReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttachDatasetReq.ProtoReflect.Descriptor instead.
+func (*AttachDatasetReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AttachDatasetReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *AttachDatasetReq) GetDiskPath() string {
+	if x != nil {
+		return x.DiskPath
+	}
+	return ""
+}
+
 type AttestationPolicyRes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Info          []byte                 `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
@@ -231,7 +441,7 @@ type AttestationPolicyRes struct {
 
 func (x *AttestationPolicyRes) Reset() {
 	*x = AttestationPolicyRes{}
-	mi := &file_manager_manager_proto_msgTypes[3]
+	mi := &file_manager_manager_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -243,7 +453,7 @@ func (x *AttestationPolicyRes) String() string {
 func (*AttestationPolicyRes) ProtoMessage() {}
 
 func (x *AttestationPolicyRes) ProtoReflect() protoreflect.Message {
-	mi := &file_manager_manager_proto_msgTypes[3]
+	mi := &file_manager_manager_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -256,7 +466,7 @@ func (x *AttestationPolicyRes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationPolicyRes.ProtoReflect.Descriptor instead.
 func (*AttestationPolicyRes) Descriptor() ([]byte, []int) {
-	return file_manager_manager_proto_rawDescGZIP(), []int{3}
+	return file_manager_manager_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *AttestationPolicyRes) GetInfo() []byte {
@@ -287,7 +497,7 @@ type CVMInfoRes struct {
 
 func (x *CVMInfoRes) Reset() {
 	*x = CVMInfoRes{}
-	mi := &file_manager_manager_proto_msgTypes[4]
+	mi := &file_manager_manager_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -299,7 +509,7 @@ func (x *CVMInfoRes) String() string {
 func (*CVMInfoRes) ProtoMessage() {}
 
 func (x *CVMInfoRes) ProtoReflect() protoreflect.Message {
-	mi := &file_manager_manager_proto_msgTypes[4]
+	mi := &file_manager_manager_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -312,7 +522,7 @@ func (x *CVMInfoRes) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CVMInfoRes.ProtoReflect.Descriptor instead.
 func (*CVMInfoRes) Descriptor() ([]byte, []int) {
-	return file_manager_manager_proto_rawDescGZIP(), []int{4}
+	return file_manager_manager_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *CVMInfoRes) GetId() string {
@@ -366,7 +576,7 @@ type AttestationPolicyReq struct {
 
 func (x *AttestationPolicyReq) Reset() {
 	*x = AttestationPolicyReq{}
-	mi := &file_manager_manager_proto_msgTypes[5]
+	mi := &file_manager_manager_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -378,7 +588,7 @@ func (x *AttestationPolicyReq) String() string {
 func (*AttestationPolicyReq) ProtoMessage() {}
 
 func (x *AttestationPolicyReq) ProtoReflect() protoreflect.Message {
-	mi := &file_manager_manager_proto_msgTypes[5]
+	mi := &file_manager_manager_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -391,7 +601,7 @@ func (x *AttestationPolicyReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AttestationPolicyReq.ProtoReflect.Descriptor instead.
 func (*AttestationPolicyReq) Descriptor() ([]byte, []int) {
-	return file_manager_manager_proto_rawDescGZIP(), []int{5}
+	return file_manager_manager_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *AttestationPolicyReq) GetId() string {
@@ -410,7 +620,7 @@ type CVMInfoReq struct {
 
 func (x *CVMInfoReq) Reset() {
 	*x = CVMInfoReq{}
-	mi := &file_manager_manager_proto_msgTypes[6]
+	mi := &file_manager_manager_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -422,7 +632,7 @@ func (x *CVMInfoReq) String() string {
 func (*CVMInfoReq) ProtoMessage() {}
 
 func (x *CVMInfoReq) ProtoReflect() protoreflect.Message {
-	mi := &file_manager_manager_proto_msgTypes[6]
+	mi := &file_manager_manager_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -435,7 +645,7 @@ func (x *CVMInfoReq) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CVMInfoReq.ProtoReflect.Descriptor instead.
 func (*CVMInfoReq) Descriptor() ([]byte, []int) {
-	return file_manager_manager_proto_rawDescGZIP(), []int{6}
+	return file_manager_manager_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *CVMInfoReq) GetId() string {
@@ -445,48 +655,2206 @@ func (x *CVMInfoReq) GetId() string {
 	return ""
 }
 
-var File_manager_manager_proto protoreflect.FileDescriptor
+type GetImagesReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_manager_manager_proto_rawDesc = "" +
-	"\n" +
-	"\x15manager/manager.proto\x12\amanager\x1a\x1bgoogle/protobuf/empty.proto\"\xe7\x02\n" +
-	"\tCreateReq\x12&\n" +
-	"\x0fagent_log_level\x18\x01 \x01(\tR\ragentLogLevel\x126\n" +
-	"\x18agent_cvm_server_ca_cert\x18\x02 \x01(\fR\x14agentCvmServerCaCert\x12/\n" +
-	"\x14agent_cvm_client_key\x18\x03 \x01(\fR\x11agentCvmClientKey\x121\n" +
-	"\x15agent_cvm_client_cert\x18\x04 \x01(\fR\x12agentCvmClientCert\x12/\n" +
-	"\x14agent_cvm_server_url\x18\x05 \x01(\tR\x11agentCvmServerUrl\x12'\n" +
-	"\x10agent_cvm_ca_url\x18\x06 \x01(\tR\ragentCvmCaUrl\x12\x10\n" +
-	"\x03ttl\x18\a \x01(\tR\x03ttl\x12*\n" +
-	"\x11agent_certs_token\x18\b \x01(\tR\x0fagentCertsToken\"I\n" +
-	"\tCreateRes\x12%\n" +
-	"\x0eforwarded_port\x18\x01 \x01(\tR\rforwardedPort\x12\x15\n" +
-	"\x06cvm_id\x18\x02 \x01(\tR\x05cvmId\"\"\n" +
-	"\tRemoveReq\x12\x15\n" +
-	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\":\n" +
-	"\x14AttestationPolicyRes\x12\x12\n" +
-	"\x04info\x18\x01 \x01(\fR\x04info\x12\x0e\n" +
-	"\x02id\x18\x02 \x01(\tR\x02id\"\xb3\x01\n" +
-	"\n" +
-	"CVMInfoRes\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
-	"\fovmf_version\x18\x02 \x01(\tR\vovmfVersion\x12\x17\n" +
-	"\acpu_num\x18\x03 \x01(\x05R\x06cpuNum\x12\x19\n" +
-	"\bcpu_type\x18\x04 \x01(\tR\acpuType\x12\x1d\n" +
-	"\n" +
-	"kernel_cmd\x18\x05 \x01(\tR\tkernelCmd\x12\x1f\n" +
-	"\veos_version\x18\x06 \x01(\tR\n" +
-	"eosVersion\"&\n" +
-	"\x14AttestationPolicyReq\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\x1c\n" +
-	"\n" +
-	"CVMInfoReq\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id2\x8c\x02\n" +
+func (x *GetImagesReq) Reset() {
+	*x = GetImagesReq{}
+	mi := &file_manager_manager_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetImagesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetImagesReq) ProtoMessage() {}
+
+func (x *GetImagesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetImagesReq.ProtoReflect.Descriptor instead.
+func (*GetImagesReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{10}
+}
+
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Digest        string                 `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"` // sha3.Sum256 of the image file, hex encoded.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_manager_manager_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Image) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Image) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Image) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Image) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+type GetImagesRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Images        []*Image               `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetImagesRes) Reset() {
+	*x = GetImagesRes{}
+	mi := &file_manager_manager_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetImagesRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetImagesRes) ProtoMessage() {}
+
+func (x *GetImagesRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetImagesRes.ProtoReflect.Descriptor instead.
+func (*GetImagesRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetImagesRes) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+type WatchComputationReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchComputationReq) Reset() {
+	*x = WatchComputationReq{}
+	mi := &file_manager_manager_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchComputationReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchComputationReq) ProtoMessage() {}
+
+func (x *WatchComputationReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchComputationReq.ProtoReflect.Descriptor instead.
+func (*WatchComputationReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchComputationReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+type ComputationEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Details       string                 `protobuf:"bytes,4,opt,name=details,proto3" json:"details,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComputationEvent) Reset() {
+	*x = ComputationEvent{}
+	mi := &file_manager_manager_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComputationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputationEvent) ProtoMessage() {}
+
+func (x *ComputationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputationEvent.ProtoReflect.Descriptor instead.
+func (*ComputationEvent) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ComputationEvent) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *ComputationEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *ComputationEvent) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *ComputationEvent) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+func (x *ComputationEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type LogsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`      // skips the buffered output captured before it.
+	Tail          *uint32                `protobuf:"varint,3,opt,name=tail,proto3,oneof" json:"tail,omitempty"` // number of buffered lines sent, all of them when unset.
+	Follow        bool                   `protobuf:"varint,4,opt,name=follow,proto3" json:"follow,omitempty"`   // keeps streaming new output after the buffered output.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogsReq) Reset() {
+	*x = LogsReq{}
+	mi := &file_manager_manager_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogsReq) ProtoMessage() {}
+
+func (x *LogsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogsReq.ProtoReflect.Descriptor instead.
+func (*LogsReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LogsReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *LogsReq) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *LogsReq) GetTail() uint32 {
+	if x != nil && x.Tail != nil {
+		return *x.Tail
+	}
+	return 0
+}
+
+func (x *LogsReq) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type LogChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	Stream        string                 `protobuf:"bytes,2,opt,name=stream,proto3" json:"stream,omitempty"` // stdout or stderr of the algorithm.
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogChunk) Reset() {
+	*x = LogChunk{}
+	mi := &file_manager_manager_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogChunk) ProtoMessage() {}
+
+func (x *LogChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogChunk.ProtoReflect.Descriptor instead.
+func (*LogChunk) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LogChunk) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *LogChunk) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *LogChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *LogChunk) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type HostCapabilitiesReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostCapabilitiesReq) Reset() {
+	*x = HostCapabilitiesReq{}
+	mi := &file_manager_manager_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostCapabilitiesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostCapabilitiesReq) ProtoMessage() {}
+
+func (x *HostCapabilitiesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostCapabilitiesReq.ProtoReflect.Descriptor instead.
+func (*HostCapabilitiesReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{17}
+}
+
+type HostCapabilities struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	KernelVersion   string                 `protobuf:"bytes,1,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"`
+	KernelCmdline   string                 `protobuf:"bytes,2,opt,name=kernel_cmdline,json=kernelCmdline,proto3" json:"kernel_cmdline,omitempty"`
+	CpuModel        string                 `protobuf:"bytes,3,opt,name=cpu_model,json=cpuModel,proto3" json:"cpu_model,omitempty"`
+	Kvm             bool                   `protobuf:"varint,4,opt,name=kvm,proto3" json:"kvm,omitempty"`                                                 // /dev/kvm is available.
+	Sev             bool                   `protobuf:"varint,5,opt,name=sev,proto3" json:"sev,omitempty"`                                                 // kvm_amd is loaded with SEV enabled.
+	SevEs           bool                   `protobuf:"varint,6,opt,name=sev_es,json=sevEs,proto3" json:"sev_es,omitempty"`                                // kvm_amd is loaded with SEV-ES enabled.
+	SevSnp          bool                   `protobuf:"varint,7,opt,name=sev_snp,json=sevSnp,proto3" json:"sev_snp,omitempty"`                             // the CPU supports SEV-SNP and kvm_amd is loaded with it enabled.
+	Sme             bool                   `protobuf:"varint,8,opt,name=sme,proto3" json:"sme,omitempty"`                                                 // the CPU supports SME and the kernel enables it with mem_encrypt=on.
+	Tdx             bool                   `protobuf:"varint,9,opt,name=tdx,proto3" json:"tdx,omitempty"`                                                 // the CPU supports TDX and kvm_intel is loaded with it enabled.
+	Iommu           bool                   `protobuf:"varint,10,opt,name=iommu,proto3" json:"iommu,omitempty"`                                            // an IOMMU is enabled.
+	Vsock           bool                   `protobuf:"varint,11,opt,name=vsock,proto3" json:"vsock,omitempty"`                                            // /dev/vhost-vsock is available.
+	Issues          []string               `protobuf:"bytes,12,rep,name=issues,proto3" json:"issues,omitempty"`                                           // hints on how to enable the missing capabilities.
+	CpuVendor       string                 `protobuf:"bytes,13,opt,name=cpu_vendor,json=cpuVendor,proto3" json:"cpu_vendor,omitempty"`                    // vendor ID of the CPU, e.g. AuthenticAMD or GenuineIntel.
+	Vcpus           uint32                 `protobuf:"varint,14,opt,name=vcpus,proto3" json:"vcpus,omitempty"`                                            // logical CPUs online on the host.
+	MemoryTotal     uint64                 `protobuf:"varint,15,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"`             // bytes of physical memory.
+	MemoryAvailable uint64                 `protobuf:"varint,16,opt,name=memory_available,json=memoryAvailable,proto3" json:"memory_available,omitempty"` // bytes of memory available to new CVMs when the capabilities were requested.
+	NumaNodes       []*NumaNode            `protobuf:"bytes,17,rep,name=numa_nodes,json=numaNodes,proto3" json:"numa_nodes,omitempty"`                    // NUMA topology of the host, empty when the kernel does not expose it.
+	Gpus            []*Gpu                 `protobuf:"bytes,18,rep,name=gpus,proto3" json:"gpus,omitempty"`                                               // display controllers of the host, the GPUs CVMs can be passed.
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *HostCapabilities) Reset() {
+	*x = HostCapabilities{}
+	mi := &file_manager_manager_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostCapabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostCapabilities) ProtoMessage() {}
+
+func (x *HostCapabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostCapabilities.ProtoReflect.Descriptor instead.
+func (*HostCapabilities) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *HostCapabilities) GetKernelVersion() string {
+	if x != nil {
+		return x.KernelVersion
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetKernelCmdline() string {
+	if x != nil {
+		return x.KernelCmdline
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetCpuModel() string {
+	if x != nil {
+		return x.CpuModel
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetKvm() bool {
+	if x != nil {
+		return x.Kvm
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetSev() bool {
+	if x != nil {
+		return x.Sev
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetSevEs() bool {
+	if x != nil {
+		return x.SevEs
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetSevSnp() bool {
+	if x != nil {
+		return x.SevSnp
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetSme() bool {
+	if x != nil {
+		return x.Sme
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetTdx() bool {
+	if x != nil {
+		return x.Tdx
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetIommu() bool {
+	if x != nil {
+		return x.Iommu
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetVsock() bool {
+	if x != nil {
+		return x.Vsock
+	}
+	return false
+}
+
+func (x *HostCapabilities) GetIssues() []string {
+	if x != nil {
+		return x.Issues
+	}
+	return nil
+}
+
+func (x *HostCapabilities) GetCpuVendor() string {
+	if x != nil {
+		return x.CpuVendor
+	}
+	return ""
+}
+
+func (x *HostCapabilities) GetVcpus() uint32 {
+	if x != nil {
+		return x.Vcpus
+	}
+	return 0
+}
+
+func (x *HostCapabilities) GetMemoryTotal() uint64 {
+	if x != nil {
+		return x.MemoryTotal
+	}
+	return 0
+}
+
+func (x *HostCapabilities) GetMemoryAvailable() uint64 {
+	if x != nil {
+		return x.MemoryAvailable
+	}
+	return 0
+}
+
+func (x *HostCapabilities) GetNumaNodes() []*NumaNode {
+	if x != nil {
+		return x.NumaNodes
+	}
+	return nil
+}
+
+func (x *HostCapabilities) GetGpus() []*Gpu {
+	if x != nil {
+		return x.Gpus
+	}
+	return nil
+}
+
+type NumaNode struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Cpus          string                 `protobuf:"bytes,2,opt,name=cpus,proto3" json:"cpus,omitempty"`                                   // CPUs of the node in the cpulist format, e.g. 0-7,16-23.
+	MemoryTotal   uint64                 `protobuf:"varint,3,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"` // bytes of memory of the node.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NumaNode) Reset() {
+	*x = NumaNode{}
+	mi := &file_manager_manager_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NumaNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NumaNode) ProtoMessage() {}
+
+func (x *NumaNode) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NumaNode.ProtoReflect.Descriptor instead.
+func (*NumaNode) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *NumaNode) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *NumaNode) GetCpus() string {
+	if x != nil {
+		return x.Cpus
+	}
+	return ""
+}
+
+func (x *NumaNode) GetMemoryTotal() uint64 {
+	if x != nil {
+		return x.MemoryTotal
+	}
+	return 0
+}
+
+type Gpu struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`                         // PCI address, e.g. 0000:41:00.0.
+	VendorId      string                 `protobuf:"bytes,2,opt,name=vendor_id,json=vendorId,proto3" json:"vendor_id,omitempty"`       // PCI vendor ID, e.g. 0x10de for NVIDIA.
+	DeviceId      string                 `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`       // PCI device ID.
+	IommuGroup    string                 `protobuf:"bytes,4,opt,name=iommu_group,json=iommuGroup,proto3" json:"iommu_group,omitempty"` // IOMMU group of the GPU, empty when the IOMMU is disabled.
+	Driver        string                 `protobuf:"bytes,5,opt,name=driver,proto3" json:"driver,omitempty"`                           // driver bound to the GPU, vfio-pci for GPUs ready for passthrough.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Gpu) Reset() {
+	*x = Gpu{}
+	mi := &file_manager_manager_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Gpu) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Gpu) ProtoMessage() {}
+
+func (x *Gpu) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Gpu.ProtoReflect.Descriptor instead.
+func (*Gpu) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Gpu) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Gpu) GetVendorId() string {
+	if x != nil {
+		return x.VendorId
+	}
+	return ""
+}
+
+func (x *Gpu) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *Gpu) GetIommuGroup() string {
+	if x != nil {
+		return x.IommuGroup
+	}
+	return ""
+}
+
+func (x *Gpu) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+type HostCapabilitiesRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capabilities  *HostCapabilities      `protobuf:"bytes,1,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostCapabilitiesRes) Reset() {
+	*x = HostCapabilitiesRes{}
+	mi := &file_manager_manager_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostCapabilitiesRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostCapabilitiesRes) ProtoMessage() {}
+
+func (x *HostCapabilitiesRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostCapabilitiesRes.ProtoReflect.Descriptor instead.
+func (*HostCapabilitiesRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *HostCapabilitiesRes) GetCapabilities() *HostCapabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type DiagnosticsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsReq) Reset() {
+	*x = DiagnosticsReq{}
+	mi := &file_manager_manager_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsReq) ProtoMessage() {}
+
+func (x *DiagnosticsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsReq.ProtoReflect.Descriptor instead.
+func (*DiagnosticsReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DiagnosticsReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+type Diagnostics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	Snapshot      []byte                 `protobuf:"bytes,2,opt,name=snapshot,proto3" json:"snapshot,omitempty"` // JSON snapshot the agent captured when the computation run failed.
+	ReceivedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Diagnostics) Reset() {
+	*x = Diagnostics{}
+	mi := &file_manager_manager_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Diagnostics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostics) ProtoMessage() {}
+
+func (x *Diagnostics) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostics.ProtoReflect.Descriptor instead.
+func (*Diagnostics) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Diagnostics) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *Diagnostics) GetSnapshot() []byte {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *Diagnostics) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+type DiagnosticsRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Diagnostics   *Diagnostics           `protobuf:"bytes,1,opt,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsRes) Reset() {
+	*x = DiagnosticsRes{}
+	mi := &file_manager_manager_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsRes) ProtoMessage() {}
+
+func (x *DiagnosticsRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsRes.ProtoReflect.Descriptor instead.
+func (*DiagnosticsRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *DiagnosticsRes) GetDiagnostics() *Diagnostics {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+type TimelineReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimelineReq) Reset() {
+	*x = TimelineReq{}
+	mi := &file_manager_manager_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimelineReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimelineReq) ProtoMessage() {}
+
+func (x *TimelineReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimelineReq.ProtoReflect.Descriptor instead.
+func (*TimelineReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TimelineReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+type TimelinePhase struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // provisioning, running, unhealthy, restarted or stopped.
+	Start         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"` // unset while the phase is ongoing.
+	Details       string                 `protobuf:"bytes,4,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimelinePhase) Reset() {
+	*x = TimelinePhase{}
+	mi := &file_manager_manager_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimelinePhase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimelinePhase) ProtoMessage() {}
+
+func (x *TimelinePhase) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimelinePhase.ProtoReflect.Descriptor instead.
+func (*TimelinePhase) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TimelinePhase) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TimelinePhase) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *TimelinePhase) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *TimelinePhase) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+type TimelineMilestone struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventType     string                 `protobuf:"bytes,1,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Details       string                 `protobuf:"bytes,3,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimelineMilestone) Reset() {
+	*x = TimelineMilestone{}
+	mi := &file_manager_manager_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimelineMilestone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimelineMilestone) ProtoMessage() {}
+
+func (x *TimelineMilestone) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimelineMilestone.ProtoReflect.Descriptor instead.
+func (*TimelineMilestone) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TimelineMilestone) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *TimelineMilestone) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *TimelineMilestone) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+type Timeline struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	GeneratedAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=generated_at,json=generatedAt,proto3" json:"generated_at,omitempty"` // ongoing phases last until then.
+	Phases        []*TimelinePhase       `protobuf:"bytes,3,rep,name=phases,proto3" json:"phases,omitempty"`
+	Milestones    []*TimelineMilestone   `protobuf:"bytes,4,rep,name=milestones,proto3" json:"milestones,omitempty"` // events that do not start a phase.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Timeline) Reset() {
+	*x = Timeline{}
+	mi := &file_manager_manager_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Timeline) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Timeline) ProtoMessage() {}
+
+func (x *Timeline) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Timeline.ProtoReflect.Descriptor instead.
+func (*Timeline) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Timeline) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *Timeline) GetGeneratedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.GeneratedAt
+	}
+	return nil
+}
+
+func (x *Timeline) GetPhases() []*TimelinePhase {
+	if x != nil {
+		return x.Phases
+	}
+	return nil
+}
+
+func (x *Timeline) GetMilestones() []*TimelineMilestone {
+	if x != nil {
+		return x.Milestones
+	}
+	return nil
+}
+
+type TimelineRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timeline      *Timeline              `protobuf:"bytes,1,opt,name=timeline,proto3" json:"timeline,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimelineRes) Reset() {
+	*x = TimelineRes{}
+	mi := &file_manager_manager_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimelineRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimelineRes) ProtoMessage() {}
+
+func (x *TimelineRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimelineRes.ProtoReflect.Descriptor instead.
+func (*TimelineRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *TimelineRes) GetTimeline() *Timeline {
+	if x != nil {
+		return x.Timeline
+	}
+	return nil
+}
+
+type DownloadLogsReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadLogsReq) Reset() {
+	*x = DownloadLogsReq{}
+	mi := &file_manager_manager_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadLogsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadLogsReq) ProtoMessage() {}
+
+func (x *DownloadLogsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadLogsReq.ProtoReflect.Descriptor instead.
+func (*DownloadLogsReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *DownloadLogsReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+type DownloadLogsRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bundle        []byte                 `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"` // zip archive of the manager log, console output, algorithm output, events and diagnostics of the CVM.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadLogsRes) Reset() {
+	*x = DownloadLogsRes{}
+	mi := &file_manager_manager_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadLogsRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadLogsRes) ProtoMessage() {}
+
+func (x *DownloadLogsRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadLogsRes.ProtoReflect.Descriptor instead.
+func (*DownloadLogsRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *DownloadLogsRes) GetBundle() []byte {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+type SNPCertChainReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Product       string                 `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`                             // product line of the chip, Milan, Genoa or Turin.
+	ChipId        []byte                 `protobuf:"bytes,2,opt,name=chip_id,json=chipId,proto3" json:"chip_id,omitempty"`                 // chip ID of the attestation report.
+	ReportedTcb   uint64                 `protobuf:"varint,3,opt,name=reported_tcb,json=reportedTcb,proto3" json:"reported_tcb,omitempty"` // reported TCB version of the attestation report.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SNPCertChainReq) Reset() {
+	*x = SNPCertChainReq{}
+	mi := &file_manager_manager_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SNPCertChainReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SNPCertChainReq) ProtoMessage() {}
+
+func (x *SNPCertChainReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SNPCertChainReq.ProtoReflect.Descriptor instead.
+func (*SNPCertChainReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *SNPCertChainReq) GetProduct() string {
+	if x != nil {
+		return x.Product
+	}
+	return ""
+}
+
+func (x *SNPCertChainReq) GetChipId() []byte {
+	if x != nil {
+		return x.ChipId
+	}
+	return nil
+}
+
+func (x *SNPCertChainReq) GetReportedTcb() uint64 {
+	if x != nil {
+		return x.ReportedTcb
+	}
+	return 0
+}
+
+type SNPCertChain struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ark           []byte                 `protobuf:"bytes,1,opt,name=ark,proto3" json:"ark,omitempty"`   // DER encoded AMD root key certificate.
+	Ask           []byte                 `protobuf:"bytes,2,opt,name=ask,proto3" json:"ask,omitempty"`   // DER encoded AMD SEV key certificate.
+	Vcek          []byte                 `protobuf:"bytes,3,opt,name=vcek,proto3" json:"vcek,omitempty"` // DER encoded versioned chip endorsement key certificate.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SNPCertChain) Reset() {
+	*x = SNPCertChain{}
+	mi := &file_manager_manager_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SNPCertChain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SNPCertChain) ProtoMessage() {}
+
+func (x *SNPCertChain) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SNPCertChain.ProtoReflect.Descriptor instead.
+func (*SNPCertChain) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *SNPCertChain) GetArk() []byte {
+	if x != nil {
+		return x.Ark
+	}
+	return nil
+}
+
+func (x *SNPCertChain) GetAsk() []byte {
+	if x != nil {
+		return x.Ask
+	}
+	return nil
+}
+
+func (x *SNPCertChain) GetVcek() []byte {
+	if x != nil {
+		return x.Vcek
+	}
+	return nil
+}
+
+type SNPCertChainRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chain         *SNPCertChain          `protobuf:"bytes,1,opt,name=chain,proto3" json:"chain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SNPCertChainRes) Reset() {
+	*x = SNPCertChainRes{}
+	mi := &file_manager_manager_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SNPCertChainRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SNPCertChainRes) ProtoMessage() {}
+
+func (x *SNPCertChainRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SNPCertChainRes.ProtoReflect.Descriptor instead.
+func (*SNPCertChainRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *SNPCertChainRes) GetChain() *SNPCertChain {
+	if x != nil {
+		return x.Chain
+	}
+	return nil
+}
+
+// ManagerError is attached to the status of a failed CreateVm as a detail.
+type ManagerError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stage         string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"` // qemu_launch, ovmf_missing, sev_not_supported or vsock_failed.
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Remediation   string                 `protobuf:"bytes,3,opt,name=remediation,proto3" json:"remediation,omitempty"` // hint on how to fix the host.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ManagerError) Reset() {
+	*x = ManagerError{}
+	mi := &file_manager_manager_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManagerError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManagerError) ProtoMessage() {}
+
+func (x *ManagerError) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManagerError.ProtoReflect.Descriptor instead.
+func (*ManagerError) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ManagerError) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ManagerError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ManagerError) GetRemediation() string {
+	if x != nil {
+		return x.Remediation
+	}
+	return ""
+}
+
+type TenantQuota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxVms        uint32                 `protobuf:"varint,1,opt,name=max_vms,json=maxVms,proto3" json:"max_vms,omitempty"`                  // concurrent CVMs, unlimited when 0.
+	MaxVcpus      uint32                 `protobuf:"varint,2,opt,name=max_vcpus,json=maxVcpus,proto3" json:"max_vcpus,omitempty"`            // vCPUs of all the CVMs, unlimited when 0.
+	MaxMemoryMb   uint64                 `protobuf:"varint,3,opt,name=max_memory_mb,json=maxMemoryMb,proto3" json:"max_memory_mb,omitempty"` // MiB of memory of all the CVMs, unlimited when 0.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TenantQuota) Reset() {
+	*x = TenantQuota{}
+	mi := &file_manager_manager_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TenantQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TenantQuota) ProtoMessage() {}
+
+func (x *TenantQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TenantQuota.ProtoReflect.Descriptor instead.
+func (*TenantQuota) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *TenantQuota) GetMaxVms() uint32 {
+	if x != nil {
+		return x.MaxVms
+	}
+	return 0
+}
+
+func (x *TenantQuota) GetMaxVcpus() uint32 {
+	if x != nil {
+		return x.MaxVcpus
+	}
+	return 0
+}
+
+func (x *TenantQuota) GetMaxMemoryMb() uint64 {
+	if x != nil {
+		return x.MaxMemoryMb
+	}
+	return 0
+}
+
+type TenantUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vms           uint32                 `protobuf:"varint,1,opt,name=vms,proto3" json:"vms,omitempty"`
+	Vcpus         uint32                 `protobuf:"varint,2,opt,name=vcpus,proto3" json:"vcpus,omitempty"`
+	MemoryMb      uint64                 `protobuf:"varint,3,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TenantUsage) Reset() {
+	*x = TenantUsage{}
+	mi := &file_manager_manager_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TenantUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TenantUsage) ProtoMessage() {}
+
+func (x *TenantUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TenantUsage.ProtoReflect.Descriptor instead.
+func (*TenantUsage) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *TenantUsage) GetVms() uint32 {
+	if x != nil {
+		return x.Vms
+	}
+	return 0
+}
+
+func (x *TenantUsage) GetVcpus() uint32 {
+	if x != nil {
+		return x.Vcpus
+	}
+	return 0
+}
+
+func (x *TenantUsage) GetMemoryMb() uint64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+type SetTenantQuotaReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Quota         *TenantQuota           `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetTenantQuotaReq) Reset() {
+	*x = SetTenantQuotaReq{}
+	mi := &file_manager_manager_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetTenantQuotaReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetTenantQuotaReq) ProtoMessage() {}
+
+func (x *SetTenantQuotaReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetTenantQuotaReq.ProtoReflect.Descriptor instead.
+func (*SetTenantQuotaReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *SetTenantQuotaReq) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *SetTenantQuotaReq) GetQuota() *TenantQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type SetTenantQuotaRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Quota         *TenantQuota           `protobuf:"bytes,2,opt,name=quota,proto3" json:"quota,omitempty"`
+	Usage         *TenantUsage           `protobuf:"bytes,3,opt,name=usage,proto3" json:"usage,omitempty"` // resources the CVMs of the tenant use, which may exceed a lowered quota.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetTenantQuotaRes) Reset() {
+	*x = SetTenantQuotaRes{}
+	mi := &file_manager_manager_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetTenantQuotaRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetTenantQuotaRes) ProtoMessage() {}
+
+func (x *SetTenantQuotaRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetTenantQuotaRes.ProtoReflect.Descriptor instead.
+func (*SetTenantQuotaRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SetTenantQuotaRes) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *SetTenantQuotaRes) GetQuota() *TenantQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+func (x *SetTenantQuotaRes) GetUsage() *TenantUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type ConsoleReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	Tail          *uint32                `protobuf:"varint,2,opt,name=tail,proto3,oneof" json:"tail,omitempty"` // number of captured lines sent, all of them when unset.
+	Follow        bool                   `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`   // keeps streaming new output after the captured output.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsoleReq) Reset() {
+	*x = ConsoleReq{}
+	mi := &file_manager_manager_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsoleReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsoleReq) ProtoMessage() {}
+
+func (x *ConsoleReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsoleReq.ProtoReflect.Descriptor instead.
+func (*ConsoleReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ConsoleReq) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *ConsoleReq) GetTail() uint32 {
+	if x != nil && x.Tail != nil {
+		return *x.Tail
+	}
+	return 0
+}
+
+func (x *ConsoleReq) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type ConsoleChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CvmId         string                 `protobuf:"bytes,1,opt,name=cvm_id,json=cvmId,proto3" json:"cvm_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"` // serial console output of the CVM and QEMU errors.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsoleChunk) Reset() {
+	*x = ConsoleChunk{}
+	mi := &file_manager_manager_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsoleChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsoleChunk) ProtoMessage() {}
+
+func (x *ConsoleChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsoleChunk.ProtoReflect.Descriptor instead.
+func (*ConsoleChunk) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ConsoleChunk) GetCvmId() string {
+	if x != nil {
+		return x.CvmId
+	}
+	return ""
+}
+
+func (x *ConsoleChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type AuditLogReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditLogReq) Reset() {
+	*x = AuditLogReq{}
+	mi := &file_manager_manager_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditLogReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditLogReq) ProtoMessage() {}
+
+func (x *AuditLogReq) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditLogReq.ProtoReflect.Descriptor instead.
+func (*AuditLogReq) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{42}
+}
+
+type AuditLogRes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []byte                 `protobuf:"bytes,1,opt,name=records,proto3" json:"records,omitempty"` // hash-chained audit records, a line of JSON each.
+	Count         uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`    // number of records.
+	Head          string                 `protobuf:"bytes,3,opt,name=head,proto3" json:"head,omitempty"`       // hash of the last record, anchoring the chain.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditLogRes) Reset() {
+	*x = AuditLogRes{}
+	mi := &file_manager_manager_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditLogRes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditLogRes) ProtoMessage() {}
+
+func (x *AuditLogRes) ProtoReflect() protoreflect.Message {
+	mi := &file_manager_manager_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditLogRes.ProtoReflect.Descriptor instead.
+func (*AuditLogRes) Descriptor() ([]byte, []int) {
+	return file_manager_manager_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *AuditLogRes) GetRecords() []byte {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *AuditLogRes) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *AuditLogRes) GetHead() string {
+	if x != nil {
+		return x.Head
+	}
+	return ""
+}
+
+var File_manager_manager_proto protoreflect.FileDescriptor
+
+const file_manager_manager_proto_rawDesc = "" +
+	"\n" +
+	"\x15manager/manager.proto\x12\amanager\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd1\x04\n" +
+	"\tCreateReq\x12&\n" +
+	"\x0fagent_log_level\x18\x01 \x01(\tR\ragentLogLevel\x126\n" +
+	"\x18agent_cvm_server_ca_cert\x18\x02 \x01(\fR\x14agentCvmServerCaCert\x12/\n" +
+	"\x14agent_cvm_client_key\x18\x03 \x01(\fR\x11agentCvmClientKey\x121\n" +
+	"\x15agent_cvm_client_cert\x18\x04 \x01(\fR\x12agentCvmClientCert\x12/\n" +
+	"\x14agent_cvm_server_url\x18\x05 \x01(\tR\x11agentCvmServerUrl\x12'\n" +
+	"\x10agent_cvm_ca_url\x18\x06 \x01(\tR\ragentCvmCaUrl\x12\x10\n" +
+	"\x03ttl\x18\a \x01(\tR\x03ttl\x12*\n" +
+	"\x11agent_certs_token\x18\b \x01(\tR\x0fagentCertsToken\x12'\n" +
+	"\x0fmachine_profile\x18\t \x01(\tR\x0emachineProfile\x12\x16\n" +
+	"\x06tenant\x18\n" +
+	" \x01(\tR\x06tenant\x12\x1b\n" +
+	"\thost_cpus\x18\v \x01(\tR\bhostCpus\x12 \n" +
+	"\tnuma_node\x18\f \x01(\rH\x00R\bnumaNode\x88\x01\x01\x12\x1f\n" +
+	"\vgpu_devices\x18\r \x03(\tR\n" +
+	"gpuDevices\x12'\n" +
+	"\rgpu_nvidia_cc\x18\x0e \x01(\bH\x01R\vgpuNvidiaCc\x88\x01\x01B\f\n" +
+	"\n" +
+	"_numa_nodeB\x10\n" +
+	"\x0e_gpu_nvidia_cc\"I\n" +
+	"\tCreateRes\x12%\n" +
+	"\x0eforwarded_port\x18\x01 \x01(\tR\rforwardedPort\x12\x15\n" +
+	"\x06cvm_id\x18\x02 \x01(\tR\x05cvmId\"\"\n" +
+	"\tRemoveReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\" \n" +
+	"\aStopReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\"6\n" +
+	"\aStopRes\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\"F\n" +
+	"\x10AttachDatasetReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x1b\n" +
+	"\tdisk_path\x18\x02 \x01(\tR\bdiskPath\":\n" +
+	"\x14AttestationPolicyRes\x12\x12\n" +
+	"\x04info\x18\x01 \x01(\fR\x04info\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"\xb3\x01\n" +
+	"\n" +
+	"CVMInfoRes\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\fovmf_version\x18\x02 \x01(\tR\vovmfVersion\x12\x17\n" +
+	"\acpu_num\x18\x03 \x01(\x05R\x06cpuNum\x12\x19\n" +
+	"\bcpu_type\x18\x04 \x01(\tR\acpuType\x12\x1d\n" +
+	"\n" +
+	"kernel_cmd\x18\x05 \x01(\tR\tkernelCmd\x12\x1f\n" +
+	"\veos_version\x18\x06 \x01(\tR\n" +
+	"eosVersion\"&\n" +
+	"\x14AttestationPolicyReq\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1c\n" +
+	"\n" +
+	"CVMInfoReq\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x0e\n" +
+	"\fGetImagesReq\"a\n" +
+	"\x05Image\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x16\n" +
+	"\x06digest\x18\x04 \x01(\tR\x06digest\"6\n" +
+	"\fGetImagesRes\x12&\n" +
+	"\x06images\x18\x01 \x03(\v2\x0e.manager.ImageR\x06images\",\n" +
+	"\x13WatchComputationReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\"\xb2\x01\n" +
+	"\x10ComputationEvent\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x18\n" +
+	"\adetails\x18\x04 \x01(\tR\adetails\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\x8c\x01\n" +
+	"\aLogsReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12\x17\n" +
+	"\x04tail\x18\x03 \x01(\rH\x00R\x04tail\x88\x01\x01\x12\x16\n" +
+	"\x06follow\x18\x04 \x01(\bR\x06followB\a\n" +
+	"\x05_tail\"\x87\x01\n" +
+	"\bLogChunk\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x16\n" +
+	"\x06stream\x18\x02 \x01(\tR\x06stream\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\x15\n" +
+	"\x13HostCapabilitiesReq\"\x90\x04\n" +
+	"\x10HostCapabilities\x12%\n" +
+	"\x0ekernel_version\x18\x01 \x01(\tR\rkernelVersion\x12%\n" +
+	"\x0ekernel_cmdline\x18\x02 \x01(\tR\rkernelCmdline\x12\x1b\n" +
+	"\tcpu_model\x18\x03 \x01(\tR\bcpuModel\x12\x10\n" +
+	"\x03kvm\x18\x04 \x01(\bR\x03kvm\x12\x10\n" +
+	"\x03sev\x18\x05 \x01(\bR\x03sev\x12\x15\n" +
+	"\x06sev_es\x18\x06 \x01(\bR\x05sevEs\x12\x17\n" +
+	"\asev_snp\x18\a \x01(\bR\x06sevSnp\x12\x10\n" +
+	"\x03sme\x18\b \x01(\bR\x03sme\x12\x10\n" +
+	"\x03tdx\x18\t \x01(\bR\x03tdx\x12\x14\n" +
+	"\x05iommu\x18\n" +
+	" \x01(\bR\x05iommu\x12\x14\n" +
+	"\x05vsock\x18\v \x01(\bR\x05vsock\x12\x16\n" +
+	"\x06issues\x18\f \x03(\tR\x06issues\x12\x1d\n" +
+	"\n" +
+	"cpu_vendor\x18\r \x01(\tR\tcpuVendor\x12\x14\n" +
+	"\x05vcpus\x18\x0e \x01(\rR\x05vcpus\x12!\n" +
+	"\fmemory_total\x18\x0f \x01(\x04R\vmemoryTotal\x12)\n" +
+	"\x10memory_available\x18\x10 \x01(\x04R\x0fmemoryAvailable\x120\n" +
+	"\n" +
+	"numa_nodes\x18\x11 \x03(\v2\x11.manager.NumaNodeR\tnumaNodes\x12 \n" +
+	"\x04gpus\x18\x12 \x03(\v2\f.manager.GpuR\x04gpus\"Q\n" +
+	"\bNumaNode\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04cpus\x18\x02 \x01(\tR\x04cpus\x12!\n" +
+	"\fmemory_total\x18\x03 \x01(\x04R\vmemoryTotal\"\x92\x01\n" +
+	"\x03Gpu\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x1b\n" +
+	"\tvendor_id\x18\x02 \x01(\tR\bvendorId\x12\x1b\n" +
+	"\tdevice_id\x18\x03 \x01(\tR\bdeviceId\x12\x1f\n" +
+	"\viommu_group\x18\x04 \x01(\tR\n" +
+	"iommuGroup\x12\x16\n" +
+	"\x06driver\x18\x05 \x01(\tR\x06driver\"T\n" +
+	"\x13HostCapabilitiesRes\x12=\n" +
+	"\fcapabilities\x18\x01 \x01(\v2\x19.manager.HostCapabilitiesR\fcapabilities\"'\n" +
+	"\x0eDiagnosticsReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\"}\n" +
+	"\vDiagnostics\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x1a\n" +
+	"\bsnapshot\x18\x02 \x01(\fR\bsnapshot\x12;\n" +
+	"\vreceived_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\"H\n" +
+	"\x0eDiagnosticsRes\x126\n" +
+	"\vdiagnostics\x18\x01 \x01(\v2\x14.manager.DiagnosticsR\vdiagnostics\"$\n" +
+	"\vTimelineReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\"\x9d\x01\n" +
+	"\rTimelinePhase\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x120\n" +
+	"\x05start\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x18\n" +
+	"\adetails\x18\x04 \x01(\tR\adetails\"\x86\x01\n" +
+	"\x11TimelineMilestone\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x01 \x01(\tR\teventType\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x18\n" +
+	"\adetails\x18\x03 \x01(\tR\adetails\"\xcc\x01\n" +
+	"\bTimeline\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12=\n" +
+	"\fgenerated_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vgeneratedAt\x12.\n" +
+	"\x06phases\x18\x03 \x03(\v2\x16.manager.TimelinePhaseR\x06phases\x12:\n" +
+	"\n" +
+	"milestones\x18\x04 \x03(\v2\x1a.manager.TimelineMilestoneR\n" +
+	"milestones\"<\n" +
+	"\vTimelineRes\x12-\n" +
+	"\btimeline\x18\x01 \x01(\v2\x11.manager.TimelineR\btimeline\"(\n" +
+	"\x0fDownloadLogsReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\")\n" +
+	"\x0fDownloadLogsRes\x12\x16\n" +
+	"\x06bundle\x18\x01 \x01(\fR\x06bundle\"g\n" +
+	"\x0fSNPCertChainReq\x12\x18\n" +
+	"\aproduct\x18\x01 \x01(\tR\aproduct\x12\x17\n" +
+	"\achip_id\x18\x02 \x01(\fR\x06chipId\x12!\n" +
+	"\freported_tcb\x18\x03 \x01(\x04R\vreportedTcb\"F\n" +
+	"\fSNPCertChain\x12\x10\n" +
+	"\x03ark\x18\x01 \x01(\fR\x03ark\x12\x10\n" +
+	"\x03ask\x18\x02 \x01(\fR\x03ask\x12\x12\n" +
+	"\x04vcek\x18\x03 \x01(\fR\x04vcek\">\n" +
+	"\x0fSNPCertChainRes\x12+\n" +
+	"\x05chain\x18\x01 \x01(\v2\x15.manager.SNPCertChainR\x05chain\"`\n" +
+	"\fManagerError\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12 \n" +
+	"\vremediation\x18\x03 \x01(\tR\vremediation\"g\n" +
+	"\vTenantQuota\x12\x17\n" +
+	"\amax_vms\x18\x01 \x01(\rR\x06maxVms\x12\x1b\n" +
+	"\tmax_vcpus\x18\x02 \x01(\rR\bmaxVcpus\x12\"\n" +
+	"\rmax_memory_mb\x18\x03 \x01(\x04R\vmaxMemoryMb\"R\n" +
+	"\vTenantUsage\x12\x10\n" +
+	"\x03vms\x18\x01 \x01(\rR\x03vms\x12\x14\n" +
+	"\x05vcpus\x18\x02 \x01(\rR\x05vcpus\x12\x1b\n" +
+	"\tmemory_mb\x18\x03 \x01(\x04R\bmemoryMb\"W\n" +
+	"\x11SetTenantQuotaReq\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12*\n" +
+	"\x05quota\x18\x02 \x01(\v2\x14.manager.TenantQuotaR\x05quota\"\x83\x01\n" +
+	"\x11SetTenantQuotaRes\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12*\n" +
+	"\x05quota\x18\x02 \x01(\v2\x14.manager.TenantQuotaR\x05quota\x12*\n" +
+	"\x05usage\x18\x03 \x01(\v2\x14.manager.TenantUsageR\x05usage\"]\n" +
+	"\n" +
+	"ConsoleReq\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x17\n" +
+	"\x04tail\x18\x02 \x01(\rH\x00R\x04tail\x88\x01\x01\x12\x16\n" +
+	"\x06follow\x18\x03 \x01(\bR\x06followB\a\n" +
+	"\x05_tail\"9\n" +
+	"\fConsoleChunk\x12\x15\n" +
+	"\x06cvm_id\x18\x01 \x01(\tR\x05cvmId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\r\n" +
+	"\vAuditLogReq\"Q\n" +
+	"\vAuditLogRes\x12\x18\n" +
+	"\arecords\x18\x01 \x01(\fR\arecords\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\x12\x12\n" +
+	"\x04head\x18\x03 \x01(\tR\x04head2\xdd\b\n" +
 	"\x0eManagerService\x124\n" +
 	"\bCreateVm\x12\x12.manager.CreateReq\x1a\x12.manager.CreateRes\"\x00\x128\n" +
-	"\bRemoveVm\x12\x12.manager.RemoveReq\x1a\x16.google.protobuf.Empty\"\x00\x125\n" +
+	"\bRemoveVm\x12\x12.manager.RemoveReq\x1a\x16.google.protobuf.Empty\"\x00\x12.\n" +
+	"\x06StopVm\x12\x10.manager.StopReq\x1a\x10.manager.StopRes\"\x00\x12D\n" +
+	"\rAttachDataset\x12\x19.manager.AttachDatasetReq\x1a\x16.google.protobuf.Empty\"\x00\x125\n" +
 	"\aCVMInfo\x12\x13.manager.CVMInfoReq\x1a\x13.manager.CVMInfoRes\"\x00\x12S\n" +
-	"\x11AttestationPolicy\x12\x1d.manager.AttestationPolicyReq\x1a\x1d.manager.AttestationPolicyRes\"\x00B\vZ\t./managerb\x06proto3"
+	"\x11AttestationPolicy\x12\x1d.manager.AttestationPolicyReq\x1a\x1d.manager.AttestationPolicyRes\"\x00\x12;\n" +
+	"\tGetImages\x12\x15.manager.GetImagesReq\x1a\x15.manager.GetImagesRes\"\x00\x12O\n" +
+	"\x10WatchComputation\x12\x1c.manager.WatchComputationReq\x1a\x19.manager.ComputationEvent\"\x000\x01\x12/\n" +
+	"\x04Logs\x12\x10.manager.LogsReq\x1a\x11.manager.LogChunk\"\x000\x01\x12P\n" +
+	"\x10HostCapabilities\x12\x1c.manager.HostCapabilitiesReq\x1a\x1c.manager.HostCapabilitiesRes\"\x00\x12A\n" +
+	"\vDiagnostics\x12\x17.manager.DiagnosticsReq\x1a\x17.manager.DiagnosticsRes\"\x00\x128\n" +
+	"\bTimeline\x12\x14.manager.TimelineReq\x1a\x14.manager.TimelineRes\"\x00\x12D\n" +
+	"\fDownloadLogs\x12\x18.manager.DownloadLogsReq\x1a\x18.manager.DownloadLogsRes\"\x00\x12D\n" +
+	"\fSNPCertChain\x12\x18.manager.SNPCertChainReq\x1a\x18.manager.SNPCertChainRes\"\x00\x12J\n" +
+	"\x0eSetTenantQuota\x12\x1a.manager.SetTenantQuotaReq\x1a\x1a.manager.SetTenantQuotaRes\"\x00\x129\n" +
+	"\aConsole\x12\x13.manager.ConsoleReq\x1a\x15.manager.ConsoleChunk\"\x000\x01\x128\n" +
+	"\bAuditLog\x12\x14.manager.AuditLogReq\x1a\x14.manager.AuditLogRes\"\x00B\vZ\t./managerb\x06proto3"
 
 var (
 	file_manager_manager_proto_rawDescOnce sync.Once
@@ -500,31 +2868,115 @@ func file_manager_manager_proto_rawDescGZIP() []byte {
 	return file_manager_manager_proto_rawDescData
 }
 
-var file_manager_manager_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_manager_manager_proto_msgTypes = make([]protoimpl.MessageInfo, 44)
 var file_manager_manager_proto_goTypes = []any{
-	(*CreateReq)(nil),            // 0: manager.CreateReq
-	(*CreateRes)(nil),            // 1: manager.CreateRes
-	(*RemoveReq)(nil),            // 2: manager.RemoveReq
-	(*AttestationPolicyRes)(nil), // 3: manager.AttestationPolicyRes
-	(*CVMInfoRes)(nil),           // 4: manager.CVMInfoRes
-	(*AttestationPolicyReq)(nil), // 5: manager.AttestationPolicyReq
-	(*CVMInfoReq)(nil),           // 6: manager.CVMInfoReq
-	(*emptypb.Empty)(nil),        // 7: google.protobuf.Empty
+	(*CreateReq)(nil),             // 0: manager.CreateReq
+	(*CreateRes)(nil),             // 1: manager.CreateRes
+	(*RemoveReq)(nil),             // 2: manager.RemoveReq
+	(*StopReq)(nil),               // 3: manager.StopReq
+	(*StopRes)(nil),               // 4: manager.StopRes
+	(*AttachDatasetReq)(nil),      // 5: manager.AttachDatasetReq
+	(*AttestationPolicyRes)(nil),  // 6: manager.AttestationPolicyRes
+	(*CVMInfoRes)(nil),            // 7: manager.CVMInfoRes
+	(*AttestationPolicyReq)(nil),  // 8: manager.AttestationPolicyReq
+	(*CVMInfoReq)(nil),            // 9: manager.CVMInfoReq
+	(*GetImagesReq)(nil),          // 10: manager.GetImagesReq
+	(*Image)(nil),                 // 11: manager.Image
+	(*GetImagesRes)(nil),          // 12: manager.GetImagesRes
+	(*WatchComputationReq)(nil),   // 13: manager.WatchComputationReq
+	(*ComputationEvent)(nil),      // 14: manager.ComputationEvent
+	(*LogsReq)(nil),               // 15: manager.LogsReq
+	(*LogChunk)(nil),              // 16: manager.LogChunk
+	(*HostCapabilitiesReq)(nil),   // 17: manager.HostCapabilitiesReq
+	(*HostCapabilities)(nil),      // 18: manager.HostCapabilities
+	(*NumaNode)(nil),              // 19: manager.NumaNode
+	(*Gpu)(nil),                   // 20: manager.Gpu
+	(*HostCapabilitiesRes)(nil),   // 21: manager.HostCapabilitiesRes
+	(*DiagnosticsReq)(nil),        // 22: manager.DiagnosticsReq
+	(*Diagnostics)(nil),           // 23: manager.Diagnostics
+	(*DiagnosticsRes)(nil),        // 24: manager.DiagnosticsRes
+	(*TimelineReq)(nil),           // 25: manager.TimelineReq
+	(*TimelinePhase)(nil),         // 26: manager.TimelinePhase
+	(*TimelineMilestone)(nil),     // 27: manager.TimelineMilestone
+	(*Timeline)(nil),              // 28: manager.Timeline
+	(*TimelineRes)(nil),           // 29: manager.TimelineRes
+	(*DownloadLogsReq)(nil),       // 30: manager.DownloadLogsReq
+	(*DownloadLogsRes)(nil),       // 31: manager.DownloadLogsRes
+	(*SNPCertChainReq)(nil),       // 32: manager.SNPCertChainReq
+	(*SNPCertChain)(nil),          // 33: manager.SNPCertChain
+	(*SNPCertChainRes)(nil),       // 34: manager.SNPCertChainRes
+	(*ManagerError)(nil),          // 35: manager.ManagerError
+	(*TenantQuota)(nil),           // 36: manager.TenantQuota
+	(*TenantUsage)(nil),           // 37: manager.TenantUsage
+	(*SetTenantQuotaReq)(nil),     // 38: manager.SetTenantQuotaReq
+	(*SetTenantQuotaRes)(nil),     // 39: manager.SetTenantQuotaRes
+	(*ConsoleReq)(nil),            // 40: manager.ConsoleReq
+	(*ConsoleChunk)(nil),          // 41: manager.ConsoleChunk
+	(*AuditLogReq)(nil),           // 42: manager.AuditLogReq
+	(*AuditLogRes)(nil),           // 43: manager.AuditLogRes
+	(*timestamppb.Timestamp)(nil), // 44: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 45: google.protobuf.Empty
 }
 var file_manager_manager_proto_depIdxs = []int32{
-	0, // 0: manager.ManagerService.CreateVm:input_type -> manager.CreateReq
-	2, // 1: manager.ManagerService.RemoveVm:input_type -> manager.RemoveReq
-	6, // 2: manager.ManagerService.CVMInfo:input_type -> manager.CVMInfoReq
-	5, // 3: manager.ManagerService.AttestationPolicy:input_type -> manager.AttestationPolicyReq
-	1, // 4: manager.ManagerService.CreateVm:output_type -> manager.CreateRes
-	7, // 5: manager.ManagerService.RemoveVm:output_type -> google.protobuf.Empty
-	4, // 6: manager.ManagerService.CVMInfo:output_type -> manager.CVMInfoRes
-	3, // 7: manager.ManagerService.AttestationPolicy:output_type -> manager.AttestationPolicyRes
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	11, // 0: manager.GetImagesRes.images:type_name -> manager.Image
+	44, // 1: manager.ComputationEvent.timestamp:type_name -> google.protobuf.Timestamp
+	44, // 2: manager.LogsReq.since:type_name -> google.protobuf.Timestamp
+	44, // 3: manager.LogChunk.timestamp:type_name -> google.protobuf.Timestamp
+	19, // 4: manager.HostCapabilities.numa_nodes:type_name -> manager.NumaNode
+	20, // 5: manager.HostCapabilities.gpus:type_name -> manager.Gpu
+	18, // 6: manager.HostCapabilitiesRes.capabilities:type_name -> manager.HostCapabilities
+	44, // 7: manager.Diagnostics.received_at:type_name -> google.protobuf.Timestamp
+	23, // 8: manager.DiagnosticsRes.diagnostics:type_name -> manager.Diagnostics
+	44, // 9: manager.TimelinePhase.start:type_name -> google.protobuf.Timestamp
+	44, // 10: manager.TimelinePhase.end:type_name -> google.protobuf.Timestamp
+	44, // 11: manager.TimelineMilestone.timestamp:type_name -> google.protobuf.Timestamp
+	44, // 12: manager.Timeline.generated_at:type_name -> google.protobuf.Timestamp
+	26, // 13: manager.Timeline.phases:type_name -> manager.TimelinePhase
+	27, // 14: manager.Timeline.milestones:type_name -> manager.TimelineMilestone
+	28, // 15: manager.TimelineRes.timeline:type_name -> manager.Timeline
+	33, // 16: manager.SNPCertChainRes.chain:type_name -> manager.SNPCertChain
+	36, // 17: manager.SetTenantQuotaReq.quota:type_name -> manager.TenantQuota
+	36, // 18: manager.SetTenantQuotaRes.quota:type_name -> manager.TenantQuota
+	37, // 19: manager.SetTenantQuotaRes.usage:type_name -> manager.TenantUsage
+	0,  // 20: manager.ManagerService.CreateVm:input_type -> manager.CreateReq
+	2,  // 21: manager.ManagerService.RemoveVm:input_type -> manager.RemoveReq
+	3,  // 22: manager.ManagerService.StopVm:input_type -> manager.StopReq
+	5,  // 23: manager.ManagerService.AttachDataset:input_type -> manager.AttachDatasetReq
+	9,  // 24: manager.ManagerService.CVMInfo:input_type -> manager.CVMInfoReq
+	8,  // 25: manager.ManagerService.AttestationPolicy:input_type -> manager.AttestationPolicyReq
+	10, // 26: manager.ManagerService.GetImages:input_type -> manager.GetImagesReq
+	13, // 27: manager.ManagerService.WatchComputation:input_type -> manager.WatchComputationReq
+	15, // 28: manager.ManagerService.Logs:input_type -> manager.LogsReq
+	17, // 29: manager.ManagerService.HostCapabilities:input_type -> manager.HostCapabilitiesReq
+	22, // 30: manager.ManagerService.Diagnostics:input_type -> manager.DiagnosticsReq
+	25, // 31: manager.ManagerService.Timeline:input_type -> manager.TimelineReq
+	30, // 32: manager.ManagerService.DownloadLogs:input_type -> manager.DownloadLogsReq
+	32, // 33: manager.ManagerService.SNPCertChain:input_type -> manager.SNPCertChainReq
+	38, // 34: manager.ManagerService.SetTenantQuota:input_type -> manager.SetTenantQuotaReq
+	40, // 35: manager.ManagerService.Console:input_type -> manager.ConsoleReq
+	42, // 36: manager.ManagerService.AuditLog:input_type -> manager.AuditLogReq
+	1,  // 37: manager.ManagerService.CreateVm:output_type -> manager.CreateRes
+	45, // 38: manager.ManagerService.RemoveVm:output_type -> google.protobuf.Empty
+	4,  // 39: manager.ManagerService.StopVm:output_type -> manager.StopRes
+	45, // 40: manager.ManagerService.AttachDataset:output_type -> google.protobuf.Empty
+	7,  // 41: manager.ManagerService.CVMInfo:output_type -> manager.CVMInfoRes
+	6,  // 42: manager.ManagerService.AttestationPolicy:output_type -> manager.AttestationPolicyRes
+	12, // 43: manager.ManagerService.GetImages:output_type -> manager.GetImagesRes
+	14, // 44: manager.ManagerService.WatchComputation:output_type -> manager.ComputationEvent
+	16, // 45: manager.ManagerService.Logs:output_type -> manager.LogChunk
+	21, // 46: manager.ManagerService.HostCapabilities:output_type -> manager.HostCapabilitiesRes
+	24, // 47: manager.ManagerService.Diagnostics:output_type -> manager.DiagnosticsRes
+	29, // 48: manager.ManagerService.Timeline:output_type -> manager.TimelineRes
+	31, // 49: manager.ManagerService.DownloadLogs:output_type -> manager.DownloadLogsRes
+	34, // 50: manager.ManagerService.SNPCertChain:output_type -> manager.SNPCertChainRes
+	39, // 51: manager.ManagerService.SetTenantQuota:output_type -> manager.SetTenantQuotaRes
+	41, // 52: manager.ManagerService.Console:output_type -> manager.ConsoleChunk
+	43, // 53: manager.ManagerService.AuditLog:output_type -> manager.AuditLogRes
+	37, // [37:54] is the sub-list for method output_type
+	20, // [20:37] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_manager_manager_proto_init() }
@@ -532,13 +2984,16 @@ func file_manager_manager_proto_init() {
 	if File_manager_manager_proto != nil {
 		return
 	}
+	file_manager_manager_proto_msgTypes[0].OneofWrappers = []any{}
+	file_manager_manager_proto_msgTypes[15].OneofWrappers = []any{}
+	file_manager_manager_proto_msgTypes[40].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_manager_manager_proto_rawDesc), len(file_manager_manager_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   44,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
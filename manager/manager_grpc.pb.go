@@ -25,8 +25,21 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	ManagerService_CreateVm_FullMethodName          = "/manager.ManagerService/CreateVm"
 	ManagerService_RemoveVm_FullMethodName          = "/manager.ManagerService/RemoveVm"
+	ManagerService_StopVm_FullMethodName            = "/manager.ManagerService/StopVm"
+	ManagerService_AttachDataset_FullMethodName     = "/manager.ManagerService/AttachDataset"
 	ManagerService_CVMInfo_FullMethodName           = "/manager.ManagerService/CVMInfo"
 	ManagerService_AttestationPolicy_FullMethodName = "/manager.ManagerService/AttestationPolicy"
+	ManagerService_GetImages_FullMethodName         = "/manager.ManagerService/GetImages"
+	ManagerService_WatchComputation_FullMethodName  = "/manager.ManagerService/WatchComputation"
+	ManagerService_Logs_FullMethodName              = "/manager.ManagerService/Logs"
+	ManagerService_HostCapabilities_FullMethodName  = "/manager.ManagerService/HostCapabilities"
+	ManagerService_Diagnostics_FullMethodName       = "/manager.ManagerService/Diagnostics"
+	ManagerService_Timeline_FullMethodName          = "/manager.ManagerService/Timeline"
+	ManagerService_DownloadLogs_FullMethodName      = "/manager.ManagerService/DownloadLogs"
+	ManagerService_SNPCertChain_FullMethodName      = "/manager.ManagerService/SNPCertChain"
+	ManagerService_SetTenantQuota_FullMethodName    = "/manager.ManagerService/SetTenantQuota"
+	ManagerService_Console_FullMethodName           = "/manager.ManagerService/Console"
+	ManagerService_AuditLog_FullMethodName          = "/manager.ManagerService/AuditLog"
 )
 
 // ManagerServiceClient is the client API for ManagerService service.
@@ -35,8 +48,21 @@ const (
 type ManagerServiceClient interface {
 	CreateVm(ctx context.Context, in *CreateReq, opts ...grpc.CallOption) (*CreateRes, error)
 	RemoveVm(ctx context.Context, in *RemoveReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	StopVm(ctx context.Context, in *StopReq, opts ...grpc.CallOption) (*StopRes, error)
+	AttachDataset(ctx context.Context, in *AttachDatasetReq, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	CVMInfo(ctx context.Context, in *CVMInfoReq, opts ...grpc.CallOption) (*CVMInfoRes, error)
 	AttestationPolicy(ctx context.Context, in *AttestationPolicyReq, opts ...grpc.CallOption) (*AttestationPolicyRes, error)
+	GetImages(ctx context.Context, in *GetImagesReq, opts ...grpc.CallOption) (*GetImagesRes, error)
+	WatchComputation(ctx context.Context, in *WatchComputationReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ComputationEvent], error)
+	Logs(ctx context.Context, in *LogsReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error)
+	HostCapabilities(ctx context.Context, in *HostCapabilitiesReq, opts ...grpc.CallOption) (*HostCapabilitiesRes, error)
+	Diagnostics(ctx context.Context, in *DiagnosticsReq, opts ...grpc.CallOption) (*DiagnosticsRes, error)
+	Timeline(ctx context.Context, in *TimelineReq, opts ...grpc.CallOption) (*TimelineRes, error)
+	DownloadLogs(ctx context.Context, in *DownloadLogsReq, opts ...grpc.CallOption) (*DownloadLogsRes, error)
+	SNPCertChain(ctx context.Context, in *SNPCertChainReq, opts ...grpc.CallOption) (*SNPCertChainRes, error)
+	SetTenantQuota(ctx context.Context, in *SetTenantQuotaReq, opts ...grpc.CallOption) (*SetTenantQuotaRes, error)
+	Console(ctx context.Context, in *ConsoleReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleChunk], error)
+	AuditLog(ctx context.Context, in *AuditLogReq, opts ...grpc.CallOption) (*AuditLogRes, error)
 }
 
 type managerServiceClient struct {
@@ -67,6 +93,26 @@ func (c *managerServiceClient) RemoveVm(ctx context.Context, in *RemoveReq, opts
 	return out, nil
 }
 
+func (c *managerServiceClient) StopVm(ctx context.Context, in *StopReq, opts ...grpc.CallOption) (*StopRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopRes)
+	err := c.cc.Invoke(ctx, ManagerService_StopVm_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) AttachDataset(ctx context.Context, in *AttachDatasetReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ManagerService_AttachDataset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *managerServiceClient) CVMInfo(ctx context.Context, in *CVMInfoReq, opts ...grpc.CallOption) (*CVMInfoRes, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CVMInfoRes)
@@ -87,14 +133,164 @@ func (c *managerServiceClient) AttestationPolicy(ctx context.Context, in *Attest
 	return out, nil
 }
 
+func (c *managerServiceClient) GetImages(ctx context.Context, in *GetImagesReq, opts ...grpc.CallOption) (*GetImagesRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetImagesRes)
+	err := c.cc.Invoke(ctx, ManagerService_GetImages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) WatchComputation(ctx context.Context, in *WatchComputationReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ComputationEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ManagerService_ServiceDesc.Streams[0], ManagerService_WatchComputation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchComputationReq, ComputationEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_WatchComputationClient = grpc.ServerStreamingClient[ComputationEvent]
+
+func (c *managerServiceClient) Logs(ctx context.Context, in *LogsReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ManagerService_ServiceDesc.Streams[1], ManagerService_Logs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LogsReq, LogChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_LogsClient = grpc.ServerStreamingClient[LogChunk]
+
+func (c *managerServiceClient) HostCapabilities(ctx context.Context, in *HostCapabilitiesReq, opts ...grpc.CallOption) (*HostCapabilitiesRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostCapabilitiesRes)
+	err := c.cc.Invoke(ctx, ManagerService_HostCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) Diagnostics(ctx context.Context, in *DiagnosticsReq, opts ...grpc.CallOption) (*DiagnosticsRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiagnosticsRes)
+	err := c.cc.Invoke(ctx, ManagerService_Diagnostics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) Timeline(ctx context.Context, in *TimelineReq, opts ...grpc.CallOption) (*TimelineRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TimelineRes)
+	err := c.cc.Invoke(ctx, ManagerService_Timeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) DownloadLogs(ctx context.Context, in *DownloadLogsReq, opts ...grpc.CallOption) (*DownloadLogsRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DownloadLogsRes)
+	err := c.cc.Invoke(ctx, ManagerService_DownloadLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) SNPCertChain(ctx context.Context, in *SNPCertChainReq, opts ...grpc.CallOption) (*SNPCertChainRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SNPCertChainRes)
+	err := c.cc.Invoke(ctx, ManagerService_SNPCertChain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) SetTenantQuota(ctx context.Context, in *SetTenantQuotaReq, opts ...grpc.CallOption) (*SetTenantQuotaRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetTenantQuotaRes)
+	err := c.cc.Invoke(ctx, ManagerService_SetTenantQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerServiceClient) Console(ctx context.Context, in *ConsoleReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ConsoleChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ManagerService_ServiceDesc.Streams[2], ManagerService_Console_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ConsoleReq, ConsoleChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_ConsoleClient = grpc.ServerStreamingClient[ConsoleChunk]
+
+func (c *managerServiceClient) AuditLog(ctx context.Context, in *AuditLogReq, opts ...grpc.CallOption) (*AuditLogRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuditLogRes)
+	err := c.cc.Invoke(ctx, ManagerService_AuditLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ManagerServiceServer is the server API for ManagerService service.
 // All implementations must embed UnimplementedManagerServiceServer
 // for forward compatibility.
 type ManagerServiceServer interface {
 	CreateVm(context.Context, *CreateReq) (*CreateRes, error)
 	RemoveVm(context.Context, *RemoveReq) (*emptypb.Empty, error)
+	StopVm(context.Context, *StopReq) (*StopRes, error)
+	AttachDataset(context.Context, *AttachDatasetReq) (*emptypb.Empty, error)
 	CVMInfo(context.Context, *CVMInfoReq) (*CVMInfoRes, error)
 	AttestationPolicy(context.Context, *AttestationPolicyReq) (*AttestationPolicyRes, error)
+	GetImages(context.Context, *GetImagesReq) (*GetImagesRes, error)
+	WatchComputation(*WatchComputationReq, grpc.ServerStreamingServer[ComputationEvent]) error
+	Logs(*LogsReq, grpc.ServerStreamingServer[LogChunk]) error
+	HostCapabilities(context.Context, *HostCapabilitiesReq) (*HostCapabilitiesRes, error)
+	Diagnostics(context.Context, *DiagnosticsReq) (*DiagnosticsRes, error)
+	Timeline(context.Context, *TimelineReq) (*TimelineRes, error)
+	DownloadLogs(context.Context, *DownloadLogsReq) (*DownloadLogsRes, error)
+	SNPCertChain(context.Context, *SNPCertChainReq) (*SNPCertChainRes, error)
+	SetTenantQuota(context.Context, *SetTenantQuotaReq) (*SetTenantQuotaRes, error)
+	Console(*ConsoleReq, grpc.ServerStreamingServer[ConsoleChunk]) error
+	AuditLog(context.Context, *AuditLogReq) (*AuditLogRes, error)
 	mustEmbedUnimplementedManagerServiceServer()
 }
 
@@ -111,12 +307,51 @@ func (UnimplementedManagerServiceServer) CreateVm(context.Context, *CreateReq) (
 func (UnimplementedManagerServiceServer) RemoveVm(context.Context, *RemoveReq) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveVm not implemented")
 }
+func (UnimplementedManagerServiceServer) StopVm(context.Context, *StopReq) (*StopRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopVm not implemented")
+}
+func (UnimplementedManagerServiceServer) AttachDataset(context.Context, *AttachDatasetReq) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AttachDataset not implemented")
+}
 func (UnimplementedManagerServiceServer) CVMInfo(context.Context, *CVMInfoReq) (*CVMInfoRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CVMInfo not implemented")
 }
 func (UnimplementedManagerServiceServer) AttestationPolicy(context.Context, *AttestationPolicyReq) (*AttestationPolicyRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AttestationPolicy not implemented")
 }
+func (UnimplementedManagerServiceServer) GetImages(context.Context, *GetImagesReq) (*GetImagesRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetImages not implemented")
+}
+func (UnimplementedManagerServiceServer) WatchComputation(*WatchComputationReq, grpc.ServerStreamingServer[ComputationEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchComputation not implemented")
+}
+func (UnimplementedManagerServiceServer) Logs(*LogsReq, grpc.ServerStreamingServer[LogChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method Logs not implemented")
+}
+func (UnimplementedManagerServiceServer) HostCapabilities(context.Context, *HostCapabilitiesReq) (*HostCapabilitiesRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HostCapabilities not implemented")
+}
+func (UnimplementedManagerServiceServer) Diagnostics(context.Context, *DiagnosticsReq) (*DiagnosticsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Diagnostics not implemented")
+}
+func (UnimplementedManagerServiceServer) Timeline(context.Context, *TimelineReq) (*TimelineRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Timeline not implemented")
+}
+func (UnimplementedManagerServiceServer) DownloadLogs(context.Context, *DownloadLogsReq) (*DownloadLogsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DownloadLogs not implemented")
+}
+func (UnimplementedManagerServiceServer) SNPCertChain(context.Context, *SNPCertChainReq) (*SNPCertChainRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SNPCertChain not implemented")
+}
+func (UnimplementedManagerServiceServer) SetTenantQuota(context.Context, *SetTenantQuotaReq) (*SetTenantQuotaRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTenantQuota not implemented")
+}
+func (UnimplementedManagerServiceServer) Console(*ConsoleReq, grpc.ServerStreamingServer[ConsoleChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method Console not implemented")
+}
+func (UnimplementedManagerServiceServer) AuditLog(context.Context, *AuditLogReq) (*AuditLogRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuditLog not implemented")
+}
 func (UnimplementedManagerServiceServer) mustEmbedUnimplementedManagerServiceServer() {}
 func (UnimplementedManagerServiceServer) testEmbeddedByValue()                        {}
 
@@ -174,6 +409,42 @@ func _ManagerService_RemoveVm_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ManagerService_StopVm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).StopVm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_StopVm_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).StopVm(ctx, req.(*StopReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_AttachDataset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AttachDatasetReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).AttachDataset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_AttachDataset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).AttachDataset(ctx, req.(*AttachDatasetReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ManagerService_CVMInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CVMInfoReq)
 	if err := dec(in); err != nil {
@@ -210,6 +481,183 @@ func _ManagerService_AttestationPolicy_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ManagerService_GetImages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetImagesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).GetImages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_GetImages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).GetImages(ctx, req.(*GetImagesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_WatchComputation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchComputationReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServiceServer).WatchComputation(m, &grpc.GenericServerStream[WatchComputationReq, ComputationEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_WatchComputationServer = grpc.ServerStreamingServer[ComputationEvent]
+
+func _ManagerService_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServiceServer).Logs(m, &grpc.GenericServerStream[LogsReq, LogChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_LogsServer = grpc.ServerStreamingServer[LogChunk]
+
+func _ManagerService_HostCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostCapabilitiesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).HostCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_HostCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).HostCapabilities(ctx, req.(*HostCapabilitiesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_Diagnostics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiagnosticsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).Diagnostics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_Diagnostics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).Diagnostics(ctx, req.(*DiagnosticsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_Timeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TimelineReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).Timeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_Timeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).Timeline(ctx, req.(*TimelineReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_DownloadLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DownloadLogsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).DownloadLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_DownloadLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).DownloadLogs(ctx, req.(*DownloadLogsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_SNPCertChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SNPCertChainReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).SNPCertChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_SNPCertChain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).SNPCertChain(ctx, req.(*SNPCertChainReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_SetTenantQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTenantQuotaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).SetTenantQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_SetTenantQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).SetTenantQuota(ctx, req.(*SetTenantQuotaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ManagerService_Console_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ConsoleReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServiceServer).Console(m, &grpc.GenericServerStream[ConsoleReq, ConsoleChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ManagerService_ConsoleServer = grpc.ServerStreamingServer[ConsoleChunk]
+
+func _ManagerService_AuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditLogReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServiceServer).AuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ManagerService_AuditLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServiceServer).AuditLog(ctx, req.(*AuditLogReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ManagerService_ServiceDesc is the grpc.ServiceDesc for ManagerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -225,6 +673,14 @@ var ManagerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveVm",
 			Handler:    _ManagerService_RemoveVm_Handler,
 		},
+		{
+			MethodName: "StopVm",
+			Handler:    _ManagerService_StopVm_Handler,
+		},
+		{
+			MethodName: "AttachDataset",
+			Handler:    _ManagerService_AttachDataset_Handler,
+		},
 		{
 			MethodName: "CVMInfo",
 			Handler:    _ManagerService_CVMInfo_Handler,
@@ -233,7 +689,55 @@ var ManagerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AttestationPolicy",
 			Handler:    _ManagerService_AttestationPolicy_Handler,
 		},
+		{
+			MethodName: "GetImages",
+			Handler:    _ManagerService_GetImages_Handler,
+		},
+		{
+			MethodName: "HostCapabilities",
+			Handler:    _ManagerService_HostCapabilities_Handler,
+		},
+		{
+			MethodName: "Diagnostics",
+			Handler:    _ManagerService_Diagnostics_Handler,
+		},
+		{
+			MethodName: "Timeline",
+			Handler:    _ManagerService_Timeline_Handler,
+		},
+		{
+			MethodName: "DownloadLogs",
+			Handler:    _ManagerService_DownloadLogs_Handler,
+		},
+		{
+			MethodName: "SNPCertChain",
+			Handler:    _ManagerService_SNPCertChain_Handler,
+		},
+		{
+			MethodName: "SetTenantQuota",
+			Handler:    _ManagerService_SetTenantQuota_Handler,
+		},
+		{
+			MethodName: "AuditLog",
+			Handler:    _ManagerService_AuditLog_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchComputation",
+			Handler:       _ManagerService_WatchComputation_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Logs",
+			Handler:       _ManagerService_Logs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Console",
+			Handler:       _ManagerService_Console_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "manager/manager.proto",
 }
@@ -43,6 +43,93 @@ func (_m *ManagerServiceClient) EXPECT() *ManagerServiceClient_Expecter {
 	return &ManagerServiceClient_Expecter{mock: &_m.Mock}
 }
 
+// AttachDataset provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) AttachDataset(ctx context.Context, in *manager.AttachDatasetReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AttachDataset")
+	}
+
+	var r0 *emptypb.Empty
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.AttachDatasetReq, ...grpc.CallOption) (*emptypb.Empty, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.AttachDatasetReq, ...grpc.CallOption) *emptypb.Empty); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*emptypb.Empty)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.AttachDatasetReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_AttachDataset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AttachDataset'
+type ManagerServiceClient_AttachDataset_Call struct {
+	*mock.Call
+}
+
+// AttachDataset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.AttachDatasetReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) AttachDataset(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_AttachDataset_Call {
+	return &ManagerServiceClient_AttachDataset_Call{Call: _e.mock.On("AttachDataset",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_AttachDataset_Call) Run(run func(ctx context.Context, in *manager.AttachDatasetReq, opts ...grpc.CallOption)) *ManagerServiceClient_AttachDataset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.AttachDatasetReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.AttachDatasetReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_AttachDataset_Call) Return(empty *emptypb.Empty, err error) *ManagerServiceClient_AttachDataset_Call {
+	_c.Call.Return(empty, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_AttachDataset_Call) RunAndReturn(run func(ctx context.Context, in *manager.AttachDatasetReq, opts ...grpc.CallOption) (*emptypb.Empty, error)) *ManagerServiceClient_AttachDataset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // AttestationPolicy provides a mock function for the type ManagerServiceClient
 func (_mock *ManagerServiceClient) AttestationPolicy(ctx context.Context, in *manager.AttestationPolicyReq, opts ...grpc.CallOption) (*manager.AttestationPolicyRes, error) {
 	// grpc.CallOption
@@ -217,6 +304,180 @@ func (_c *ManagerServiceClient_CVMInfo_Call) RunAndReturn(run func(ctx context.C
 	return _c
 }
 
+// AuditLog provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) AuditLog(ctx context.Context, in *manager.AuditLogReq, opts ...grpc.CallOption) (*manager.AuditLogRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AuditLog")
+	}
+
+	var r0 *manager.AuditLogRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.AuditLogReq, ...grpc.CallOption) (*manager.AuditLogRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.AuditLogReq, ...grpc.CallOption) *manager.AuditLogRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.AuditLogRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.AuditLogReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_AuditLog_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AuditLog'
+type ManagerServiceClient_AuditLog_Call struct {
+	*mock.Call
+}
+
+// AuditLog is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.AuditLogReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) AuditLog(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_AuditLog_Call {
+	return &ManagerServiceClient_AuditLog_Call{Call: _e.mock.On("AuditLog",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_AuditLog_Call) Run(run func(ctx context.Context, in *manager.AuditLogReq, opts ...grpc.CallOption)) *ManagerServiceClient_AuditLog_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.AuditLogReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.AuditLogReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_AuditLog_Call) Return(auditLogRes *manager.AuditLogRes, err error) *ManagerServiceClient_AuditLog_Call {
+	_c.Call.Return(auditLogRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_AuditLog_Call) RunAndReturn(run func(ctx context.Context, in *manager.AuditLogReq, opts ...grpc.CallOption) (*manager.AuditLogRes, error)) *ManagerServiceClient_AuditLog_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Console provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) Console(ctx context.Context, in *manager.ConsoleReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ConsoleChunk], error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Console")
+	}
+
+	var r0 grpc.ServerStreamingClient[manager.ConsoleChunk]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.ConsoleReq, ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ConsoleChunk], error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.ConsoleReq, ...grpc.CallOption) grpc.ServerStreamingClient[manager.ConsoleChunk]); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(grpc.ServerStreamingClient[manager.ConsoleChunk])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.ConsoleReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_Console_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Console'
+type ManagerServiceClient_Console_Call struct {
+	*mock.Call
+}
+
+// Console is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.ConsoleReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) Console(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_Console_Call {
+	return &ManagerServiceClient_Console_Call{Call: _e.mock.On("Console",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_Console_Call) Run(run func(ctx context.Context, in *manager.ConsoleReq, opts ...grpc.CallOption)) *ManagerServiceClient_Console_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.ConsoleReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.ConsoleReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_Console_Call) Return(serverStreamingClient grpc.ServerStreamingClient[manager.ConsoleChunk], err error) *ManagerServiceClient_Console_Call {
+	_c.Call.Return(serverStreamingClient, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_Console_Call) RunAndReturn(run func(ctx context.Context, in *manager.ConsoleReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ConsoleChunk], error)) *ManagerServiceClient_Console_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CreateVm provides a mock function for the type ManagerServiceClient
 func (_mock *ManagerServiceClient) CreateVm(ctx context.Context, in *manager.CreateReq, opts ...grpc.CallOption) (*manager.CreateRes, error) {
 	// grpc.CallOption
@@ -304,8 +565,8 @@ func (_c *ManagerServiceClient_CreateVm_Call) RunAndReturn(run func(ctx context.
 	return _c
 }
 
-// RemoveVm provides a mock function for the type ManagerServiceClient
-func (_mock *ManagerServiceClient) RemoveVm(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+// Diagnostics provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) Diagnostics(ctx context.Context, in *manager.DiagnosticsReq, opts ...grpc.CallOption) (*manager.DiagnosticsRes, error) {
 	// grpc.CallOption
 	_va := make([]interface{}, len(opts))
 	for _i := range opts {
@@ -317,22 +578,22 @@ func (_mock *ManagerServiceClient) RemoveVm(ctx context.Context, in *manager.Rem
 	ret := _mock.Called(_ca...)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RemoveVm")
+		panic("no return value specified for Diagnostics")
 	}
 
-	var r0 *emptypb.Empty
+	var r0 *manager.DiagnosticsRes
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) (*emptypb.Empty, error)); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.DiagnosticsReq, ...grpc.CallOption) (*manager.DiagnosticsRes, error)); ok {
 		return returnFunc(ctx, in, opts...)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) *emptypb.Empty); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.DiagnosticsReq, ...grpc.CallOption) *manager.DiagnosticsRes); ok {
 		r0 = returnFunc(ctx, in, opts...)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*emptypb.Empty)
+			r0 = ret.Get(0).(*manager.DiagnosticsRes)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) error); ok {
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.DiagnosticsReq, ...grpc.CallOption) error); ok {
 		r1 = returnFunc(ctx, in, opts...)
 	} else {
 		r1 = ret.Error(1)
@@ -340,29 +601,29 @@ func (_mock *ManagerServiceClient) RemoveVm(ctx context.Context, in *manager.Rem
 	return r0, r1
 }
 
-// ManagerServiceClient_RemoveVm_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveVm'
-type ManagerServiceClient_RemoveVm_Call struct {
+// ManagerServiceClient_Diagnostics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Diagnostics'
+type ManagerServiceClient_Diagnostics_Call struct {
 	*mock.Call
 }
 
-// RemoveVm is a helper method to define mock.On call
+// Diagnostics is a helper method to define mock.On call
 //   - ctx context.Context
-//   - in *manager.RemoveReq
+//   - in *manager.DiagnosticsReq
 //   - opts ...grpc.CallOption
-func (_e *ManagerServiceClient_Expecter) RemoveVm(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_RemoveVm_Call {
-	return &ManagerServiceClient_RemoveVm_Call{Call: _e.mock.On("RemoveVm",
+func (_e *ManagerServiceClient_Expecter) Diagnostics(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_Diagnostics_Call {
+	return &ManagerServiceClient_Diagnostics_Call{Call: _e.mock.On("Diagnostics",
 		append([]interface{}{ctx, in}, opts...)...)}
 }
 
-func (_c *ManagerServiceClient_RemoveVm_Call) Run(run func(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption)) *ManagerServiceClient_RemoveVm_Call {
+func (_c *ManagerServiceClient_Diagnostics_Call) Run(run func(ctx context.Context, in *manager.DiagnosticsReq, opts ...grpc.CallOption)) *ManagerServiceClient_Diagnostics_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 *manager.RemoveReq
+		var arg1 *manager.DiagnosticsReq
 		if args[1] != nil {
-			arg1 = args[1].(*manager.RemoveReq)
+			arg1 = args[1].(*manager.DiagnosticsReq)
 		}
 		var arg2 []grpc.CallOption
 		variadicArgs := make([]grpc.CallOption, len(args)-2)
@@ -381,12 +642,882 @@ func (_c *ManagerServiceClient_RemoveVm_Call) Run(run func(ctx context.Context,
 	return _c
 }
 
-func (_c *ManagerServiceClient_RemoveVm_Call) Return(empty *emptypb.Empty, err error) *ManagerServiceClient_RemoveVm_Call {
-	_c.Call.Return(empty, err)
+func (_c *ManagerServiceClient_Diagnostics_Call) Return(diagnosticsRes *manager.DiagnosticsRes, err error) *ManagerServiceClient_Diagnostics_Call {
+	_c.Call.Return(diagnosticsRes, err)
 	return _c
 }
 
-func (_c *ManagerServiceClient_RemoveVm_Call) RunAndReturn(run func(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption) (*emptypb.Empty, error)) *ManagerServiceClient_RemoveVm_Call {
+func (_c *ManagerServiceClient_Diagnostics_Call) RunAndReturn(run func(ctx context.Context, in *manager.DiagnosticsReq, opts ...grpc.CallOption) (*manager.DiagnosticsRes, error)) *ManagerServiceClient_Diagnostics_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DownloadLogs provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) DownloadLogs(ctx context.Context, in *manager.DownloadLogsReq, opts ...grpc.CallOption) (*manager.DownloadLogsRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DownloadLogs")
+	}
+
+	var r0 *manager.DownloadLogsRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.DownloadLogsReq, ...grpc.CallOption) (*manager.DownloadLogsRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.DownloadLogsReq, ...grpc.CallOption) *manager.DownloadLogsRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.DownloadLogsRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.DownloadLogsReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_DownloadLogs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DownloadLogs'
+type ManagerServiceClient_DownloadLogs_Call struct {
+	*mock.Call
+}
+
+// DownloadLogs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.DownloadLogsReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) DownloadLogs(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_DownloadLogs_Call {
+	return &ManagerServiceClient_DownloadLogs_Call{Call: _e.mock.On("DownloadLogs",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_DownloadLogs_Call) Run(run func(ctx context.Context, in *manager.DownloadLogsReq, opts ...grpc.CallOption)) *ManagerServiceClient_DownloadLogs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.DownloadLogsReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.DownloadLogsReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_DownloadLogs_Call) Return(downloadLogsRes *manager.DownloadLogsRes, err error) *ManagerServiceClient_DownloadLogs_Call {
+	_c.Call.Return(downloadLogsRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_DownloadLogs_Call) RunAndReturn(run func(ctx context.Context, in *manager.DownloadLogsReq, opts ...grpc.CallOption) (*manager.DownloadLogsRes, error)) *ManagerServiceClient_DownloadLogs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetImages provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) GetImages(ctx context.Context, in *manager.GetImagesReq, opts ...grpc.CallOption) (*manager.GetImagesRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetImages")
+	}
+
+	var r0 *manager.GetImagesRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.GetImagesReq, ...grpc.CallOption) (*manager.GetImagesRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.GetImagesReq, ...grpc.CallOption) *manager.GetImagesRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.GetImagesRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.GetImagesReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_GetImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImages'
+type ManagerServiceClient_GetImages_Call struct {
+	*mock.Call
+}
+
+// GetImages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.GetImagesReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) GetImages(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_GetImages_Call {
+	return &ManagerServiceClient_GetImages_Call{Call: _e.mock.On("GetImages",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_GetImages_Call) Run(run func(ctx context.Context, in *manager.GetImagesReq, opts ...grpc.CallOption)) *ManagerServiceClient_GetImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.GetImagesReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.GetImagesReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_GetImages_Call) Return(getImagesRes *manager.GetImagesRes, err error) *ManagerServiceClient_GetImages_Call {
+	_c.Call.Return(getImagesRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_GetImages_Call) RunAndReturn(run func(ctx context.Context, in *manager.GetImagesReq, opts ...grpc.CallOption) (*manager.GetImagesRes, error)) *ManagerServiceClient_GetImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// HostCapabilities provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) HostCapabilities(ctx context.Context, in *manager.HostCapabilitiesReq, opts ...grpc.CallOption) (*manager.HostCapabilitiesRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HostCapabilities")
+	}
+
+	var r0 *manager.HostCapabilitiesRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.HostCapabilitiesReq, ...grpc.CallOption) (*manager.HostCapabilitiesRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.HostCapabilitiesReq, ...grpc.CallOption) *manager.HostCapabilitiesRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.HostCapabilitiesRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.HostCapabilitiesReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_HostCapabilities_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HostCapabilities'
+type ManagerServiceClient_HostCapabilities_Call struct {
+	*mock.Call
+}
+
+// HostCapabilities is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.HostCapabilitiesReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) HostCapabilities(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_HostCapabilities_Call {
+	return &ManagerServiceClient_HostCapabilities_Call{Call: _e.mock.On("HostCapabilities",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_HostCapabilities_Call) Run(run func(ctx context.Context, in *manager.HostCapabilitiesReq, opts ...grpc.CallOption)) *ManagerServiceClient_HostCapabilities_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.HostCapabilitiesReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.HostCapabilitiesReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_HostCapabilities_Call) Return(hostCapabilitiesRes *manager.HostCapabilitiesRes, err error) *ManagerServiceClient_HostCapabilities_Call {
+	_c.Call.Return(hostCapabilitiesRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_HostCapabilities_Call) RunAndReturn(run func(ctx context.Context, in *manager.HostCapabilitiesReq, opts ...grpc.CallOption) (*manager.HostCapabilitiesRes, error)) *ManagerServiceClient_HostCapabilities_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Logs provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) Logs(ctx context.Context, in *manager.LogsReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.LogChunk], error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logs")
+	}
+
+	var r0 grpc.ServerStreamingClient[manager.LogChunk]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.LogsReq, ...grpc.CallOption) (grpc.ServerStreamingClient[manager.LogChunk], error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.LogsReq, ...grpc.CallOption) grpc.ServerStreamingClient[manager.LogChunk]); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(grpc.ServerStreamingClient[manager.LogChunk])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.LogsReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_Logs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Logs'
+type ManagerServiceClient_Logs_Call struct {
+	*mock.Call
+}
+
+// Logs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.LogsReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) Logs(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_Logs_Call {
+	return &ManagerServiceClient_Logs_Call{Call: _e.mock.On("Logs",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_Logs_Call) Run(run func(ctx context.Context, in *manager.LogsReq, opts ...grpc.CallOption)) *ManagerServiceClient_Logs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.LogsReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.LogsReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_Logs_Call) Return(serverStreamingClient grpc.ServerStreamingClient[manager.LogChunk], err error) *ManagerServiceClient_Logs_Call {
+	_c.Call.Return(serverStreamingClient, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_Logs_Call) RunAndReturn(run func(ctx context.Context, in *manager.LogsReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.LogChunk], error)) *ManagerServiceClient_Logs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveVm provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) RemoveVm(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveVm")
+	}
+
+	var r0 *emptypb.Empty
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) (*emptypb.Empty, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) *emptypb.Empty); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*emptypb.Empty)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.RemoveReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_RemoveVm_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveVm'
+type ManagerServiceClient_RemoveVm_Call struct {
+	*mock.Call
+}
+
+// RemoveVm is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.RemoveReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) RemoveVm(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_RemoveVm_Call {
+	return &ManagerServiceClient_RemoveVm_Call{Call: _e.mock.On("RemoveVm",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_RemoveVm_Call) Run(run func(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption)) *ManagerServiceClient_RemoveVm_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.RemoveReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.RemoveReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_RemoveVm_Call) Return(empty *emptypb.Empty, err error) *ManagerServiceClient_RemoveVm_Call {
+	_c.Call.Return(empty, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_RemoveVm_Call) RunAndReturn(run func(ctx context.Context, in *manager.RemoveReq, opts ...grpc.CallOption) (*emptypb.Empty, error)) *ManagerServiceClient_RemoveVm_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SNPCertChain provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) SNPCertChain(ctx context.Context, in *manager.SNPCertChainReq, opts ...grpc.CallOption) (*manager.SNPCertChainRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SNPCertChain")
+	}
+
+	var r0 *manager.SNPCertChainRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.SNPCertChainReq, ...grpc.CallOption) (*manager.SNPCertChainRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.SNPCertChainReq, ...grpc.CallOption) *manager.SNPCertChainRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.SNPCertChainRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.SNPCertChainReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_SNPCertChain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SNPCertChain'
+type ManagerServiceClient_SNPCertChain_Call struct {
+	*mock.Call
+}
+
+// SNPCertChain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.SNPCertChainReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) SNPCertChain(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_SNPCertChain_Call {
+	return &ManagerServiceClient_SNPCertChain_Call{Call: _e.mock.On("SNPCertChain",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_SNPCertChain_Call) Run(run func(ctx context.Context, in *manager.SNPCertChainReq, opts ...grpc.CallOption)) *ManagerServiceClient_SNPCertChain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.SNPCertChainReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.SNPCertChainReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_SNPCertChain_Call) Return(downloadLogsRes *manager.SNPCertChainRes, err error) *ManagerServiceClient_SNPCertChain_Call {
+	_c.Call.Return(downloadLogsRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_SNPCertChain_Call) RunAndReturn(run func(ctx context.Context, in *manager.SNPCertChainReq, opts ...grpc.CallOption) (*manager.SNPCertChainRes, error)) *ManagerServiceClient_SNPCertChain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetTenantQuota provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) SetTenantQuota(ctx context.Context, in *manager.SetTenantQuotaReq, opts ...grpc.CallOption) (*manager.SetTenantQuotaRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTenantQuota")
+	}
+
+	var r0 *manager.SetTenantQuotaRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.SetTenantQuotaReq, ...grpc.CallOption) (*manager.SetTenantQuotaRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.SetTenantQuotaReq, ...grpc.CallOption) *manager.SetTenantQuotaRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.SetTenantQuotaRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.SetTenantQuotaReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_SetTenantQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetTenantQuota'
+type ManagerServiceClient_SetTenantQuota_Call struct {
+	*mock.Call
+}
+
+// SetTenantQuota is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.SetTenantQuotaReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) SetTenantQuota(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_SetTenantQuota_Call {
+	return &ManagerServiceClient_SetTenantQuota_Call{Call: _e.mock.On("SetTenantQuota",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_SetTenantQuota_Call) Run(run func(ctx context.Context, in *manager.SetTenantQuotaReq, opts ...grpc.CallOption)) *ManagerServiceClient_SetTenantQuota_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.SetTenantQuotaReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.SetTenantQuotaReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_SetTenantQuota_Call) Return(setTenantQuotaRes *manager.SetTenantQuotaRes, err error) *ManagerServiceClient_SetTenantQuota_Call {
+	_c.Call.Return(setTenantQuotaRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_SetTenantQuota_Call) RunAndReturn(run func(ctx context.Context, in *manager.SetTenantQuotaReq, opts ...grpc.CallOption) (*manager.SetTenantQuotaRes, error)) *ManagerServiceClient_SetTenantQuota_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StopVm provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) StopVm(ctx context.Context, in *manager.StopReq, opts ...grpc.CallOption) (*manager.StopRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StopVm")
+	}
+
+	var r0 *manager.StopRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.StopReq, ...grpc.CallOption) (*manager.StopRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.StopReq, ...grpc.CallOption) *manager.StopRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.StopRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.StopReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_StopVm_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StopVm'
+type ManagerServiceClient_StopVm_Call struct {
+	*mock.Call
+}
+
+// StopVm is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.StopReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) StopVm(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_StopVm_Call {
+	return &ManagerServiceClient_StopVm_Call{Call: _e.mock.On("StopVm",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_StopVm_Call) Run(run func(ctx context.Context, in *manager.StopReq, opts ...grpc.CallOption)) *ManagerServiceClient_StopVm_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.StopReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.StopReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_StopVm_Call) Return(stopRes *manager.StopRes, err error) *ManagerServiceClient_StopVm_Call {
+	_c.Call.Return(stopRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_StopVm_Call) RunAndReturn(run func(ctx context.Context, in *manager.StopReq, opts ...grpc.CallOption) (*manager.StopRes, error)) *ManagerServiceClient_StopVm_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Timeline provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) Timeline(ctx context.Context, in *manager.TimelineReq, opts ...grpc.CallOption) (*manager.TimelineRes, error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Timeline")
+	}
+
+	var r0 *manager.TimelineRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.TimelineReq, ...grpc.CallOption) (*manager.TimelineRes, error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.TimelineReq, ...grpc.CallOption) *manager.TimelineRes); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.TimelineRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.TimelineReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_Timeline_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Timeline'
+type ManagerServiceClient_Timeline_Call struct {
+	*mock.Call
+}
+
+// Timeline is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.TimelineReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) Timeline(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_Timeline_Call {
+	return &ManagerServiceClient_Timeline_Call{Call: _e.mock.On("Timeline",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_Timeline_Call) Run(run func(ctx context.Context, in *manager.TimelineReq, opts ...grpc.CallOption)) *ManagerServiceClient_Timeline_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.TimelineReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.TimelineReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_Timeline_Call) Return(timelineRes *manager.TimelineRes, err error) *ManagerServiceClient_Timeline_Call {
+	_c.Call.Return(timelineRes, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_Timeline_Call) RunAndReturn(run func(ctx context.Context, in *manager.TimelineReq, opts ...grpc.CallOption) (*manager.TimelineRes, error)) *ManagerServiceClient_Timeline_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WatchComputation provides a mock function for the type ManagerServiceClient
+func (_mock *ManagerServiceClient) WatchComputation(ctx context.Context, in *manager.WatchComputationReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ComputationEvent], error) {
+	// grpc.CallOption
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _mock.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchComputation")
+	}
+
+	var r0 grpc.ServerStreamingClient[manager.ComputationEvent]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.WatchComputationReq, ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ComputationEvent], error)); ok {
+		return returnFunc(ctx, in, opts...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *manager.WatchComputationReq, ...grpc.CallOption) grpc.ServerStreamingClient[manager.ComputationEvent]); ok {
+		r0 = returnFunc(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(grpc.ServerStreamingClient[manager.ComputationEvent])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *manager.WatchComputationReq, ...grpc.CallOption) error); ok {
+		r1 = returnFunc(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// ManagerServiceClient_WatchComputation_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchComputation'
+type ManagerServiceClient_WatchComputation_Call struct {
+	*mock.Call
+}
+
+// WatchComputation is a helper method to define mock.On call
+//   - ctx context.Context
+//   - in *manager.WatchComputationReq
+//   - opts ...grpc.CallOption
+func (_e *ManagerServiceClient_Expecter) WatchComputation(ctx interface{}, in interface{}, opts ...interface{}) *ManagerServiceClient_WatchComputation_Call {
+	return &ManagerServiceClient_WatchComputation_Call{Call: _e.mock.On("WatchComputation",
+		append([]interface{}{ctx, in}, opts...)...)}
+}
+
+func (_c *ManagerServiceClient_WatchComputation_Call) Run(run func(ctx context.Context, in *manager.WatchComputationReq, opts ...grpc.CallOption)) *ManagerServiceClient_WatchComputation_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *manager.WatchComputationReq
+		if args[1] != nil {
+			arg1 = args[1].(*manager.WatchComputationReq)
+		}
+		var arg2 []grpc.CallOption
+		variadicArgs := make([]grpc.CallOption, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(grpc.CallOption)
+			}
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *ManagerServiceClient_WatchComputation_Call) Return(serverStreamingClient grpc.ServerStreamingClient[manager.ComputationEvent], err error) *ManagerServiceClient_WatchComputation_Call {
+	_c.Call.Return(serverStreamingClient, err)
+	return _c
+}
+
+func (_c *ManagerServiceClient_WatchComputation_Call) RunAndReturn(run func(ctx context.Context, in *manager.WatchComputationReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[manager.ComputationEvent], error)) *ManagerServiceClient_WatchComputation_Call {
 	_c.Call.Return(run)
 	return _c
 }
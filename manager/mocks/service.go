@@ -41,6 +41,203 @@ func (_m *Service) EXPECT() *Service_Expecter {
 	return &Service_Expecter{mock: &_m.Mock}
 }
 
+// AttachDataset provides a mock function for the type Service
+func (_mock *Service) AttachDataset(ctx context.Context, computationID string, diskPath string) error {
+	ret := _mock.Called(ctx, computationID, diskPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AttachDataset")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, computationID, diskPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_AttachDataset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AttachDataset'
+type Service_AttachDataset_Call struct {
+	*mock.Call
+}
+
+// AttachDataset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+//   - diskPath string
+func (_e *Service_Expecter) AttachDataset(ctx interface{}, computationID interface{}, diskPath interface{}) *Service_AttachDataset_Call {
+	return &Service_AttachDataset_Call{Call: _e.mock.On("AttachDataset", ctx, computationID, diskPath)}
+}
+
+func (_c *Service_AttachDataset_Call) Run(run func(ctx context.Context, computationID string, diskPath string)) *Service_AttachDataset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_AttachDataset_Call) Return(err error) *Service_AttachDataset_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_AttachDataset_Call) RunAndReturn(run func(ctx context.Context, computationID string, diskPath string) error) *Service_AttachDataset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AuditLog provides a mock function for the type Service
+func (_mock *Service) AuditLog(ctx context.Context) (*manager.AuditLogRes, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AuditLog")
+	}
+
+	var r0 *manager.AuditLogRes
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*manager.AuditLogRes, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *manager.AuditLogRes); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.AuditLogRes)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_AuditLog_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AuditLog'
+type Service_AuditLog_Call struct {
+	*mock.Call
+}
+
+// AuditLog is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Service_Expecter) AuditLog(ctx interface{}) *Service_AuditLog_Call {
+	return &Service_AuditLog_Call{Call: _e.mock.On("AuditLog", ctx)}
+}
+
+func (_c *Service_AuditLog_Call) Run(run func(ctx context.Context)) *Service_AuditLog_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_AuditLog_Call) Return(auditLogRes *manager.AuditLogRes, err error) *Service_AuditLog_Call {
+	_c.Call.Return(auditLogRes, err)
+	return _c
+}
+
+func (_c *Service_AuditLog_Call) RunAndReturn(run func(ctx context.Context) (*manager.AuditLogRes, error)) *Service_AuditLog_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Console provides a mock function for the type Service
+func (_mock *Service) Console(ctx context.Context, computationID string, filter manager.ConsoleFilter) (<-chan *manager.ConsoleChunk, error) {
+	ret := _mock.Called(ctx, computationID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Console")
+	}
+
+	var r0 <-chan *manager.ConsoleChunk
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, manager.ConsoleFilter) (<-chan *manager.ConsoleChunk, error)); ok {
+		return returnFunc(ctx, computationID, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, manager.ConsoleFilter) <-chan *manager.ConsoleChunk); ok {
+		r0 = returnFunc(ctx, computationID, filter)
+	} else {
+		r0 = ret.Get(0).(<-chan *manager.ConsoleChunk)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, manager.ConsoleFilter) error); ok {
+		r1 = returnFunc(ctx, computationID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_Console_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Console'
+type Service_Console_Call struct {
+	*mock.Call
+}
+
+// Console is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+//   - filter manager.ConsoleFilter
+func (_e *Service_Expecter) Console(ctx interface{}, computationID interface{}, filter interface{}) *Service_Console_Call {
+	return &Service_Console_Call{Call: _e.mock.On("Console", ctx, computationID, filter)}
+}
+
+func (_c *Service_Console_Call) Run(run func(ctx context.Context, computationID string, filter manager.ConsoleFilter)) *Service_Console_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 manager.ConsoleFilter
+		if args[2] != nil {
+			arg2 = args[2].(manager.ConsoleFilter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Console_Call) Return(consoleChunk <-chan *manager.ConsoleChunk, err error) *Service_Console_Call {
+	_c.Call.Return(consoleChunk, err)
+	return _c
+}
+
+func (_c *Service_Console_Call) RunAndReturn(run func(ctx context.Context, computationID string, filter manager.ConsoleFilter) (<-chan *manager.ConsoleChunk, error)) *Service_Console_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CreateVM provides a mock function for the type Service
 func (_mock *Service) CreateVM(ctx context.Context, req *manager.CreateReq) (string, string, error) {
 	ret := _mock.Called(ctx, req)
@@ -113,24 +310,24 @@ func (_c *Service_CreateVM_Call) RunAndReturn(run func(ctx context.Context, req
 	return _c
 }
 
-// FetchAttestationPolicy provides a mock function for the type Service
-func (_mock *Service) FetchAttestationPolicy(ctx context.Context, computationID string) ([]byte, error) {
+// Diagnostics provides a mock function for the type Service
+func (_mock *Service) Diagnostics(ctx context.Context, computationID string) (*manager.Diagnostics, error) {
 	ret := _mock.Called(ctx, computationID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for FetchAttestationPolicy")
+		panic("no return value specified for Diagnostics")
 	}
 
-	var r0 []byte
+	var r0 *manager.Diagnostics
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*manager.Diagnostics, error)); ok {
 		return returnFunc(ctx, computationID)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *manager.Diagnostics); ok {
 		r0 = returnFunc(ctx, computationID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]byte)
+			r0 = ret.Get(0).(*manager.Diagnostics)
 		}
 	}
 	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
@@ -141,19 +338,19 @@ func (_mock *Service) FetchAttestationPolicy(ctx context.Context, computationID
 	return r0, r1
 }
 
-// Service_FetchAttestationPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FetchAttestationPolicy'
-type Service_FetchAttestationPolicy_Call struct {
+// Service_Diagnostics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Diagnostics'
+type Service_Diagnostics_Call struct {
 	*mock.Call
 }
 
-// FetchAttestationPolicy is a helper method to define mock.On call
+// Diagnostics is a helper method to define mock.On call
 //   - ctx context.Context
 //   - computationID string
-func (_e *Service_Expecter) FetchAttestationPolicy(ctx interface{}, computationID interface{}) *Service_FetchAttestationPolicy_Call {
-	return &Service_FetchAttestationPolicy_Call{Call: _e.mock.On("FetchAttestationPolicy", ctx, computationID)}
+func (_e *Service_Expecter) Diagnostics(ctx interface{}, computationID interface{}) *Service_Diagnostics_Call {
+	return &Service_Diagnostics_Call{Call: _e.mock.On("Diagnostics", ctx, computationID)}
 }
 
-func (_c *Service_FetchAttestationPolicy_Call) Run(run func(ctx context.Context, computationID string)) *Service_FetchAttestationPolicy_Call {
+func (_c *Service_Diagnostics_Call) Run(run func(ctx context.Context, computationID string)) *Service_Diagnostics_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -171,46 +368,57 @@ func (_c *Service_FetchAttestationPolicy_Call) Run(run func(ctx context.Context,
 	return _c
 }
 
-func (_c *Service_FetchAttestationPolicy_Call) Return(bytes []byte, err error) *Service_FetchAttestationPolicy_Call {
-	_c.Call.Return(bytes, err)
+func (_c *Service_Diagnostics_Call) Return(diagnostics *manager.Diagnostics, err error) *Service_Diagnostics_Call {
+	_c.Call.Return(diagnostics, err)
 	return _c
 }
 
-func (_c *Service_FetchAttestationPolicy_Call) RunAndReturn(run func(ctx context.Context, computationID string) ([]byte, error)) *Service_FetchAttestationPolicy_Call {
+func (_c *Service_Diagnostics_Call) RunAndReturn(run func(ctx context.Context, computationID string) (*manager.Diagnostics, error)) *Service_Diagnostics_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// RemoveVM provides a mock function for the type Service
-func (_mock *Service) RemoveVM(ctx context.Context, computationID string) error {
+// DownloadLogs provides a mock function for the type Service
+func (_mock *Service) DownloadLogs(ctx context.Context, computationID string) ([]byte, error) {
 	ret := _mock.Called(ctx, computationID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RemoveVM")
+		panic("no return value specified for DownloadLogs")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	var r0 []byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return returnFunc(ctx, computationID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
 		r0 = returnFunc(ctx, computationID)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, computationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// Service_RemoveVM_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveVM'
-type Service_RemoveVM_Call struct {
+// Service_DownloadLogs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DownloadLogs'
+type Service_DownloadLogs_Call struct {
 	*mock.Call
 }
 
-// RemoveVM is a helper method to define mock.On call
+// DownloadLogs is a helper method to define mock.On call
 //   - ctx context.Context
 //   - computationID string
-func (_e *Service_Expecter) RemoveVM(ctx interface{}, computationID interface{}) *Service_RemoveVM_Call {
-	return &Service_RemoveVM_Call{Call: _e.mock.On("RemoveVM", ctx, computationID)}
+func (_e *Service_Expecter) DownloadLogs(ctx interface{}, computationID interface{}) *Service_DownloadLogs_Call {
+	return &Service_DownloadLogs_Call{Call: _e.mock.On("DownloadLogs", ctx, computationID)}
 }
 
-func (_c *Service_RemoveVM_Call) Run(run func(ctx context.Context, computationID string)) *Service_RemoveVM_Call {
+func (_c *Service_DownloadLogs_Call) Run(run func(ctx context.Context, computationID string)) *Service_DownloadLogs_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -228,94 +436,569 @@ func (_c *Service_RemoveVM_Call) Run(run func(ctx context.Context, computationID
 	return _c
 }
 
-func (_c *Service_RemoveVM_Call) Return(err error) *Service_RemoveVM_Call {
-	_c.Call.Return(err)
+func (_c *Service_DownloadLogs_Call) Return(bytes []byte, err error) *Service_DownloadLogs_Call {
+	_c.Call.Return(bytes, err)
 	return _c
 }
 
-func (_c *Service_RemoveVM_Call) RunAndReturn(run func(ctx context.Context, computationID string) error) *Service_RemoveVM_Call {
+func (_c *Service_DownloadLogs_Call) RunAndReturn(run func(ctx context.Context, computationID string) ([]byte, error)) *Service_DownloadLogs_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ReturnCVMInfo provides a mock function for the type Service
-func (_mock *Service) ReturnCVMInfo(ctx context.Context) (string, int, string, string) {
-	ret := _mock.Called(ctx)
+// FetchAttestationPolicy provides a mock function for the type Service
+func (_mock *Service) FetchAttestationPolicy(ctx context.Context, computationID string) ([]byte, error) {
+	ret := _mock.Called(ctx, computationID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ReturnCVMInfo")
+		panic("no return value specified for FetchAttestationPolicy")
 	}
 
-	var r0 string
-	var r1 int
-	var r2 string
-	var r3 string
-	if returnFunc, ok := ret.Get(0).(func(context.Context) (string, int, string, string)); ok {
-		return returnFunc(ctx)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context) string); ok {
-		r0 = returnFunc(ctx)
-	} else {
-		r0 = ret.Get(0).(string)
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context) int); ok {
-		r1 = returnFunc(ctx)
-	} else {
-		r1 = ret.Get(1).(int)
+	var r0 []byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return returnFunc(ctx, computationID)
 	}
-	if returnFunc, ok := ret.Get(2).(func(context.Context) string); ok {
-		r2 = returnFunc(ctx)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = returnFunc(ctx, computationID)
 	} else {
-		r2 = ret.Get(2).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
 	}
-	if returnFunc, ok := ret.Get(3).(func(context.Context) string); ok {
-		r3 = returnFunc(ctx)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, computationID)
 	} else {
-		r3 = ret.Get(3).(string)
+		r1 = ret.Error(1)
 	}
-	return r0, r1, r2, r3
+	return r0, r1
 }
 
-// Service_ReturnCVMInfo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReturnCVMInfo'
-type Service_ReturnCVMInfo_Call struct {
+// Service_FetchAttestationPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FetchAttestationPolicy'
+type Service_FetchAttestationPolicy_Call struct {
 	*mock.Call
 }
 
-// ReturnCVMInfo is a helper method to define mock.On call
+// FetchAttestationPolicy is a helper method to define mock.On call
 //   - ctx context.Context
-func (_e *Service_Expecter) ReturnCVMInfo(ctx interface{}) *Service_ReturnCVMInfo_Call {
-	return &Service_ReturnCVMInfo_Call{Call: _e.mock.On("ReturnCVMInfo", ctx)}
+//   - computationID string
+func (_e *Service_Expecter) FetchAttestationPolicy(ctx interface{}, computationID interface{}) *Service_FetchAttestationPolicy_Call {
+	return &Service_FetchAttestationPolicy_Call{Call: _e.mock.On("FetchAttestationPolicy", ctx, computationID)}
 }
 
-func (_c *Service_ReturnCVMInfo_Call) Run(run func(ctx context.Context)) *Service_ReturnCVMInfo_Call {
+func (_c *Service_FetchAttestationPolicy_Call) Run(run func(ctx context.Context, computationID string)) *Service_FetchAttestationPolicy_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
 		run(
 			arg0,
+			arg1,
 		)
 	})
 	return _c
 }
 
-func (_c *Service_ReturnCVMInfo_Call) Return(s string, n int, s1 string, s2 string) *Service_ReturnCVMInfo_Call {
-	_c.Call.Return(s, n, s1, s2)
+func (_c *Service_FetchAttestationPolicy_Call) Return(bytes []byte, err error) *Service_FetchAttestationPolicy_Call {
+	_c.Call.Return(bytes, err)
 	return _c
 }
 
-func (_c *Service_ReturnCVMInfo_Call) RunAndReturn(run func(ctx context.Context) (string, int, string, string)) *Service_ReturnCVMInfo_Call {
+func (_c *Service_FetchAttestationPolicy_Call) RunAndReturn(run func(ctx context.Context, computationID string) ([]byte, error)) *Service_FetchAttestationPolicy_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Shutdown provides a mock function for the type Service
-func (_mock *Service) Shutdown() error {
-	ret := _mock.Called()
+// GetImages provides a mock function for the type Service
+func (_mock *Service) GetImages(ctx context.Context) ([]*manager.Image, error) {
+	ret := _mock.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Shutdown")
+		panic("no return value specified for GetImages")
+	}
+
+	var r0 []*manager.Image
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]*manager.Image, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []*manager.Image); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*manager.Image)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_GetImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImages'
+type Service_GetImages_Call struct {
+	*mock.Call
+}
+
+// GetImages is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Service_Expecter) GetImages(ctx interface{}) *Service_GetImages_Call {
+	return &Service_GetImages_Call{Call: _e.mock.On("GetImages", ctx)}
+}
+
+func (_c *Service_GetImages_Call) Run(run func(ctx context.Context)) *Service_GetImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_GetImages_Call) Return(images []*manager.Image, err error) *Service_GetImages_Call {
+	_c.Call.Return(images, err)
+	return _c
+}
+
+func (_c *Service_GetImages_Call) RunAndReturn(run func(ctx context.Context) ([]*manager.Image, error)) *Service_GetImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// HostCapabilities provides a mock function for the type Service
+func (_mock *Service) HostCapabilities(ctx context.Context) (*manager.HostCapabilities, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HostCapabilities")
+	}
+
+	var r0 *manager.HostCapabilities
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*manager.HostCapabilities, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *manager.HostCapabilities); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.HostCapabilities)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_HostCapabilities_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HostCapabilities'
+type Service_HostCapabilities_Call struct {
+	*mock.Call
+}
+
+// HostCapabilities is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Service_Expecter) HostCapabilities(ctx interface{}) *Service_HostCapabilities_Call {
+	return &Service_HostCapabilities_Call{Call: _e.mock.On("HostCapabilities", ctx)}
+}
+
+func (_c *Service_HostCapabilities_Call) Run(run func(ctx context.Context)) *Service_HostCapabilities_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_HostCapabilities_Call) Return(hostCapabilities *manager.HostCapabilities, err error) *Service_HostCapabilities_Call {
+	_c.Call.Return(hostCapabilities, err)
+	return _c
+}
+
+func (_c *Service_HostCapabilities_Call) RunAndReturn(run func(ctx context.Context) (*manager.HostCapabilities, error)) *Service_HostCapabilities_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Logs provides a mock function for the type Service
+func (_mock *Service) Logs(ctx context.Context, computationID string, filter manager.LogsFilter) (<-chan *manager.LogChunk, error) {
+	ret := _mock.Called(ctx, computationID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logs")
+	}
+
+	var r0 <-chan *manager.LogChunk
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, manager.LogsFilter) (<-chan *manager.LogChunk, error)); ok {
+		return returnFunc(ctx, computationID, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, manager.LogsFilter) <-chan *manager.LogChunk); ok {
+		r0 = returnFunc(ctx, computationID, filter)
+	} else {
+		r0 = ret.Get(0).(<-chan *manager.LogChunk)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, manager.LogsFilter) error); ok {
+		r1 = returnFunc(ctx, computationID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_Logs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Logs'
+type Service_Logs_Call struct {
+	*mock.Call
+}
+
+// Logs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+//   - filter manager.LogsFilter
+func (_e *Service_Expecter) Logs(ctx interface{}, computationID interface{}, filter interface{}) *Service_Logs_Call {
+	return &Service_Logs_Call{Call: _e.mock.On("Logs", ctx, computationID, filter)}
+}
+
+func (_c *Service_Logs_Call) Run(run func(ctx context.Context, computationID string, filter manager.LogsFilter)) *Service_Logs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 manager.LogsFilter
+		if args[2] != nil {
+			arg2 = args[2].(manager.LogsFilter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Logs_Call) Return(logChunk <-chan *manager.LogChunk, err error) *Service_Logs_Call {
+	_c.Call.Return(logChunk, err)
+	return _c
+}
+
+func (_c *Service_Logs_Call) RunAndReturn(run func(ctx context.Context, computationID string, filter manager.LogsFilter) (<-chan *manager.LogChunk, error)) *Service_Logs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveVM provides a mock function for the type Service
+func (_mock *Service) RemoveVM(ctx context.Context, computationID string) error {
+	ret := _mock.Called(ctx, computationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveVM")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, computationID)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Service_RemoveVM_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveVM'
+type Service_RemoveVM_Call struct {
+	*mock.Call
+}
+
+// RemoveVM is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+func (_e *Service_Expecter) RemoveVM(ctx interface{}, computationID interface{}) *Service_RemoveVM_Call {
+	return &Service_RemoveVM_Call{Call: _e.mock.On("RemoveVM", ctx, computationID)}
+}
+
+func (_c *Service_RemoveVM_Call) Run(run func(ctx context.Context, computationID string)) *Service_RemoveVM_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_RemoveVM_Call) Return(err error) *Service_RemoveVM_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Service_RemoveVM_Call) RunAndReturn(run func(ctx context.Context, computationID string) error) *Service_RemoveVM_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReturnCVMInfo provides a mock function for the type Service
+func (_mock *Service) ReturnCVMInfo(ctx context.Context) (string, int, string, string) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReturnCVMInfo")
+	}
+
+	var r0 string
+	var r1 int
+	var r2 string
+	var r3 string
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (string, int, string, string)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) int); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context) string); ok {
+		r2 = returnFunc(ctx)
+	} else {
+		r2 = ret.Get(2).(string)
+	}
+	if returnFunc, ok := ret.Get(3).(func(context.Context) string); ok {
+		r3 = returnFunc(ctx)
+	} else {
+		r3 = ret.Get(3).(string)
+	}
+	return r0, r1, r2, r3
+}
+
+// Service_ReturnCVMInfo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReturnCVMInfo'
+type Service_ReturnCVMInfo_Call struct {
+	*mock.Call
+}
+
+// ReturnCVMInfo is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Service_Expecter) ReturnCVMInfo(ctx interface{}) *Service_ReturnCVMInfo_Call {
+	return &Service_ReturnCVMInfo_Call{Call: _e.mock.On("ReturnCVMInfo", ctx)}
+}
+
+func (_c *Service_ReturnCVMInfo_Call) Run(run func(ctx context.Context)) *Service_ReturnCVMInfo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_ReturnCVMInfo_Call) Return(s string, n int, s1 string, s2 string) *Service_ReturnCVMInfo_Call {
+	_c.Call.Return(s, n, s1, s2)
+	return _c
+}
+
+func (_c *Service_ReturnCVMInfo_Call) RunAndReturn(run func(ctx context.Context) (string, int, string, string)) *Service_ReturnCVMInfo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SNPCertChain provides a mock function for the type Service
+func (_mock *Service) SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*manager.SNPCertChain, error) {
+	ret := _mock.Called(ctx, product, chipID, reportedTCB)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SNPCertChain")
+	}
+
+	var r0 *manager.SNPCertChain
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []byte, uint64) (*manager.SNPCertChain, error)); ok {
+		return returnFunc(ctx, product, chipID, reportedTCB)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []byte, uint64) *manager.SNPCertChain); ok {
+		r0 = returnFunc(ctx, product, chipID, reportedTCB)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.SNPCertChain)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, []byte, uint64) error); ok {
+		r1 = returnFunc(ctx, product, chipID, reportedTCB)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_SNPCertChain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SNPCertChain'
+type Service_SNPCertChain_Call struct {
+	*mock.Call
+}
+
+// SNPCertChain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - product string
+//   - chipID []byte
+//   - reportedTCB uint64
+func (_e *Service_Expecter) SNPCertChain(ctx interface{}, product interface{}, chipID interface{}, reportedTCB interface{}) *Service_SNPCertChain_Call {
+	return &Service_SNPCertChain_Call{Call: _e.mock.On("SNPCertChain", ctx, product, chipID, reportedTCB)}
+}
+
+func (_c *Service_SNPCertChain_Call) Run(run func(ctx context.Context, product string, chipID []byte, reportedTCB uint64)) *Service_SNPCertChain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		var arg3 uint64
+		if args[3] != nil {
+			arg3 = args[3].(uint64)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_SNPCertChain_Call) Return(sNPCertChain *manager.SNPCertChain, err error) *Service_SNPCertChain_Call {
+	_c.Call.Return(sNPCertChain, err)
+	return _c
+}
+
+func (_c *Service_SNPCertChain_Call) RunAndReturn(run func(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*manager.SNPCertChain, error)) *Service_SNPCertChain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetTenantQuota provides a mock function for the type Service
+func (_mock *Service) SetTenantQuota(ctx context.Context, tenant string, quota *manager.TenantQuota) (*manager.TenantUsage, error) {
+	ret := _mock.Called(ctx, tenant, quota)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTenantQuota")
+	}
+
+	var r0 *manager.TenantUsage
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, *manager.TenantQuota) (*manager.TenantUsage, error)); ok {
+		return returnFunc(ctx, tenant, quota)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, *manager.TenantQuota) *manager.TenantUsage); ok {
+		r0 = returnFunc(ctx, tenant, quota)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.TenantUsage)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, *manager.TenantQuota) error); ok {
+		r1 = returnFunc(ctx, tenant, quota)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_SetTenantQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetTenantQuota'
+type Service_SetTenantQuota_Call struct {
+	*mock.Call
+}
+
+// SetTenantQuota is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tenant string
+//   - quota *manager.TenantQuota
+func (_e *Service_Expecter) SetTenantQuota(ctx interface{}, tenant interface{}, quota interface{}) *Service_SetTenantQuota_Call {
+	return &Service_SetTenantQuota_Call{Call: _e.mock.On("SetTenantQuota", ctx, tenant, quota)}
+}
+
+func (_c *Service_SetTenantQuota_Call) Run(run func(ctx context.Context, tenant string, quota *manager.TenantQuota)) *Service_SetTenantQuota_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 *manager.TenantQuota
+		if args[2] != nil {
+			arg2 = args[2].(*manager.TenantQuota)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_SetTenantQuota_Call) Return(tenantUsage *manager.TenantUsage, err error) *Service_SetTenantQuota_Call {
+	_c.Call.Return(tenantUsage, err)
+	return _c
+}
+
+func (_c *Service_SetTenantQuota_Call) RunAndReturn(run func(ctx context.Context, tenant string, quota *manager.TenantQuota) (*manager.TenantUsage, error)) *Service_SetTenantQuota_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Shutdown provides a mock function for the type Service
+func (_mock *Service) Shutdown() error {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
 	}
 
 	var r0 error
@@ -353,3 +1036,205 @@ func (_c *Service_Shutdown_Call) RunAndReturn(run func() error) *Service_Shutdow
 	_c.Call.Return(run)
 	return _c
 }
+
+// StopVM provides a mock function for the type Service
+func (_mock *Service) StopVM(ctx context.Context, computationID string) (string, error) {
+	ret := _mock.Called(ctx, computationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StopVM")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return returnFunc(ctx, computationID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = returnFunc(ctx, computationID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, computationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_StopVM_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StopVM'
+type Service_StopVM_Call struct {
+	*mock.Call
+}
+
+// StopVM is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+func (_e *Service_Expecter) StopVM(ctx interface{}, computationID interface{}) *Service_StopVM_Call {
+	return &Service_StopVM_Call{Call: _e.mock.On("StopVM", ctx, computationID)}
+}
+
+func (_c *Service_StopVM_Call) Run(run func(ctx context.Context, computationID string)) *Service_StopVM_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_StopVM_Call) Return(s string, err error) *Service_StopVM_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *Service_StopVM_Call) RunAndReturn(run func(ctx context.Context, computationID string) (string, error)) *Service_StopVM_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Timeline provides a mock function for the type Service
+func (_mock *Service) Timeline(ctx context.Context, computationID string) (*manager.Timeline, error) {
+	ret := _mock.Called(ctx, computationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Timeline")
+	}
+
+	var r0 *manager.Timeline
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*manager.Timeline, error)); ok {
+		return returnFunc(ctx, computationID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *manager.Timeline); ok {
+		r0 = returnFunc(ctx, computationID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*manager.Timeline)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, computationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_Timeline_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Timeline'
+type Service_Timeline_Call struct {
+	*mock.Call
+}
+
+// Timeline is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+func (_e *Service_Expecter) Timeline(ctx interface{}, computationID interface{}) *Service_Timeline_Call {
+	return &Service_Timeline_Call{Call: _e.mock.On("Timeline", ctx, computationID)}
+}
+
+func (_c *Service_Timeline_Call) Run(run func(ctx context.Context, computationID string)) *Service_Timeline_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_Timeline_Call) Return(timeline *manager.Timeline, err error) *Service_Timeline_Call {
+	_c.Call.Return(timeline, err)
+	return _c
+}
+
+func (_c *Service_Timeline_Call) RunAndReturn(run func(ctx context.Context, computationID string) (*manager.Timeline, error)) *Service_Timeline_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WatchComputation provides a mock function for the type Service
+func (_mock *Service) WatchComputation(ctx context.Context, computationID string) (<-chan *manager.ComputationEvent, error) {
+	ret := _mock.Called(ctx, computationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchComputation")
+	}
+
+	var r0 <-chan *manager.ComputationEvent
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (<-chan *manager.ComputationEvent, error)); ok {
+		return returnFunc(ctx, computationID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) <-chan *manager.ComputationEvent); ok {
+		r0 = returnFunc(ctx, computationID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan *manager.ComputationEvent)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, computationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// Service_WatchComputation_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchComputation'
+type Service_WatchComputation_Call struct {
+	*mock.Call
+}
+
+// WatchComputation is a helper method to define mock.On call
+//   - ctx context.Context
+//   - computationID string
+func (_e *Service_Expecter) WatchComputation(ctx interface{}, computationID interface{}) *Service_WatchComputation_Call {
+	return &Service_WatchComputation_Call{Call: _e.mock.On("WatchComputation", ctx, computationID)}
+}
+
+func (_c *Service_WatchComputation_Call) Run(run func(ctx context.Context, computationID string)) *Service_WatchComputation_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *Service_WatchComputation_Call) Return(computationEvent <-chan *manager.ComputationEvent, err error) *Service_WatchComputation_Call {
+	_c.Call.Return(computationEvent, err)
+	return _c
+}
+
+func (_c *Service_WatchComputation_Call) RunAndReturn(run func(ctx context.Context, computationID string) (<-chan *manager.ComputationEvent, error)) *Service_WatchComputation_Call {
+	_c.Call.Return(run)
+	return _c
+}
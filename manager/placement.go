@@ -0,0 +1,72 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+// placementRequested reports whether the request pins the vCPUs or selects
+// a NUMA node, which pooled VMs launched with the configured placement cannot satisfy.
+func placementRequested(req *CreateReq) bool {
+	return req.HostCpus != "" || req.NumaNode != nil
+}
+
+// checkPlacement verifies that the host CPUs the vCPUs are pinned to are
+// online and that the NUMA node exists. Topologies the host does not expose
+// are not checked.
+func (ms *managerService) checkPlacement(cfg qemu.Config) error {
+	placement := cfg.PlacementConfig
+	cpus, err := qemu.ParseCPUList(placement.HostCPUs)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPlacement, err)
+	}
+	if len(cpus) > 0 && len(cpus) < cfg.SMPCount {
+		return fmt.Errorf("%w: %d host CPUs cannot pin %d vCPUs", ErrInvalidPlacement, len(cpus), cfg.SMPCount)
+	}
+
+	caps := ms.hostCapabilities
+	if caps == nil {
+		return nil
+	}
+
+	var online []int
+	for _, node := range caps.NumaNodes {
+		nodeCPUs, err := qemu.ParseCPUList(node.Cpus)
+		if err != nil {
+			return nil
+		}
+		online = append(online, nodeCPUs...)
+	}
+	if len(caps.NumaNodes) == 0 {
+		for cpu := range int(caps.Vcpus) {
+			online = append(online, cpu)
+		}
+	}
+	if len(online) > 0 {
+		for _, cpu := range cpus {
+			if !slices.Contains(online, cpu) {
+				return fmt.Errorf("%w: host CPU %d is not online", ErrInvalidPlacement, cpu)
+			}
+		}
+	}
+
+	if placement.NUMANode == "" || len(caps.NumaNodes) == 0 {
+		return nil
+	}
+	id, err := strconv.ParseUint(placement.NUMANode, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: NUMA node %q is not a node number", ErrInvalidPlacement, placement.NUMANode)
+	}
+	for _, node := range caps.NumaNodes {
+		if uint64(node.Id) == id {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host has no NUMA node %d", ErrInvalidPlacement, id)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+func TestCheckPlacement(t *testing.T) {
+	numa := &HostCapabilities{
+		Vcpus: 32,
+		NumaNodes: []*NumaNode{
+			{Id: 0, Cpus: "0-7,16-23"},
+			{Id: 1, Cpus: "8-15,24-31"},
+		},
+	}
+
+	cases := []struct {
+		desc      string
+		caps      *HostCapabilities
+		placement qemu.PlacementConfig
+		err       error
+	}{
+		{
+			desc: "no placement",
+			caps: numa,
+		},
+		{
+			desc:      "pinned to a NUMA node",
+			caps:      numa,
+			placement: qemu.PlacementConfig{HostCPUs: "8-11", NUMANode: "1"},
+		},
+		{
+			desc:      "pinned on a host without NUMA topology",
+			caps:      &HostCapabilities{Vcpus: 4},
+			placement: qemu.PlacementConfig{HostCPUs: "0-3", NUMANode: "0"},
+		},
+		{
+			desc:      "unknown topology",
+			placement: qemu.PlacementConfig{HostCPUs: "60-63", NUMANode: "3"},
+		},
+		{
+			desc:      "fewer host CPUs than vCPUs",
+			caps:      numa,
+			placement: qemu.PlacementConfig{HostCPUs: "0-1"},
+			err:       ErrInvalidPlacement,
+		},
+		{
+			desc:      "invalid CPU list",
+			caps:      numa,
+			placement: qemu.PlacementConfig{HostCPUs: "4-0"},
+			err:       ErrInvalidPlacement,
+		},
+		{
+			desc:      "offline host CPU",
+			caps:      numa,
+			placement: qemu.PlacementConfig{HostCPUs: "30-33"},
+			err:       ErrInvalidPlacement,
+		},
+		{
+			desc:      "offline host CPU without NUMA topology",
+			caps:      &HostCapabilities{Vcpus: 4},
+			placement: qemu.PlacementConfig{HostCPUs: "2-5"},
+			err:       ErrInvalidPlacement,
+		},
+		{
+			desc:      "unknown NUMA node",
+			caps:      numa,
+			placement: qemu.PlacementConfig{NUMANode: "2"},
+			err:       ErrInvalidPlacement,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ms := &managerService{hostCapabilities: tc.caps}
+			err := ms.checkPlacement(qemu.Config{SMPCount: 4, PlacementConfig: tc.placement})
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	"github.com/ultravioletrs/cocos/manager/vm"
+)
+
+// PoolConfig configures the pool of pre-booted VMs used to speed up VM creation.
+type PoolConfig struct {
+	Size           int           `env:"MANAGER_VM_POOL_SIZE"            envDefault:"0"`
+	HealthInterval time.Duration `env:"MANAGER_VM_POOL_HEALTH_INTERVAL" envDefault:"30s"`
+}
+
+type pooledVM struct {
+	id   string
+	cvm  vm.VM
+	info qemu.VMInfo
+	port int
+}
+
+// vmPool holds idle VMs that are booted ahead of time and handed out on creation.
+type vmPool struct {
+	mu      sync.Mutex
+	cfg     PoolConfig
+	idle    []pooledVM
+	booting int
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newVMPool(cfg PoolConfig) *vmPool {
+	return &vmPool{
+		cfg:  cfg,
+		done: make(chan struct{}),
+	}
+}
+
+// take removes the oldest idle VM launched with the machine profile from the
+// pool, whatever its profile when profile is empty.
+func (p *vmPool) take(profile string) (pooledVM, bool) {
+	if p == nil {
+		return pooledVM{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, pvm := range p.idle {
+		if profile == "" || pvm.info.Config.Profile == profile {
+			p.idle = slices.Delete(p.idle, i, i+1)
+			return pvm, true
+		}
+	}
+
+	return pooledVM{}, false
+}
+
+// fillPool boots VMs until the pool reaches its configured size, without
+// exceeding the maximum number of VMs the manager is allowed to run.
+func (ms *managerService) fillPool() {
+	if ms.pool == nil {
+		return
+	}
+
+	for {
+		ms.mu.Lock()
+		ms.pool.mu.Lock()
+		pending := len(ms.pool.idle) + ms.pool.booting
+		full := pending >= ms.pool.cfg.Size || (ms.maxVMs > 0 && len(ms.vms)+pending >= ms.maxVMs)
+		if !full {
+			ms.pool.booting++
+		}
+		ms.pool.mu.Unlock()
+		ms.mu.Unlock()
+
+		if full {
+			return
+		}
+
+		pvm, err := ms.bootPooledVM()
+
+		if err != nil {
+			ms.pool.mu.Lock()
+			ms.pool.booting--
+			ms.pool.mu.Unlock()
+			ms.logger.Error("Failed to boot pooled VM", "error", err)
+			return
+		}
+
+		ms.pool.mu.Lock()
+		ms.pool.booting--
+		stopped := ms.pool.cfg.Size == 0
+		if !stopped {
+			ms.pool.idle = append(ms.pool.idle, pvm)
+		}
+		ms.pool.mu.Unlock()
+
+		if stopped {
+			ms.discardPooledVM(pvm)
+			return
+		}
+
+		ms.logger.Info("Pooled VM is ready", "vmID", pvm.id)
+	}
+}
+
+func (ms *managerService) bootPooledVM() (pooledVM, error) {
+	id := uuid.New().String()
+
+	cfg, agentPort, err := ms.prepareVM(id, &CreateReq{})
+	if err != nil {
+		return pooledVM{}, err
+	}
+
+	cvm := ms.vmFactory(cfg, id, ms.logger)
+	if err := cvm.Start(); err != nil {
+		ms.ports.Release(id)
+		removeMounts(cfg)
+		return pooledVM{}, err
+	}
+
+	return pooledVM{id: id, cvm: cvm, info: cfg, port: agentPort}, nil
+}
+
+// assignPooledVM provisions a pooled VM with the request certificates and
+// environment and registers it as a regular VM.
+func (ms *managerService) assignPooledVM(pvm pooledVM, req *CreateReq) (string, string, error) {
+	defer func() {
+		go ms.fillPool()
+	}()
+
+	if err := writeCerts(pvm.info.Config.CertsMount, req); err != nil {
+		ms.discardPooledVM(pvm)
+		return "", pvm.id, err
+	}
+
+	envMap := agentEnvironment(pvm.id, req)
+	ms.heartbeatEnvironment(envMap)
+	ms.logsEnvironment(envMap)
+
+	if err := writeEnvironment(pvm.info.Config.EnvMount, envMap); err != nil {
+		ms.discardPooledVM(pvm)
+		return "", pvm.id, err
+	}
+
+	ms.mu.Lock()
+	if ms.maxVMs > 0 && len(ms.vms) >= ms.maxVMs {
+		ms.mu.Unlock()
+		ms.discardPooledVM(pvm)
+		return "", pvm.id, ErrMaxVMsExceeded
+	}
+	ms.vms[pvm.id] = pvm.cvm
+	ms.mu.Unlock()
+
+	if err := ms.activateVM(pvm.id, pvm.cvm, pvm.info, req.Ttl); err != nil {
+		ms.mu.Lock()
+		delete(ms.vms, pvm.id)
+		ms.mu.Unlock()
+		ms.discardPooledVM(pvm)
+		return "", pvm.id, err
+	}
+
+	return fmt.Sprint(pvm.port), pvm.id, nil
+}
+
+// checkPool stops idle VMs whose process is gone and boots replacements.
+func (ms *managerService) checkPool() {
+	if ms.pool == nil {
+		return
+	}
+
+	ms.pool.mu.Lock()
+	healthy := ms.pool.idle[:0]
+	var dead []pooledVM
+	for _, pvm := range ms.pool.idle {
+		if ms.processExists(pvm.cvm.GetProcess()) {
+			healthy = append(healthy, pvm)
+			continue
+		}
+		dead = append(dead, pvm)
+	}
+	ms.pool.idle = healthy
+	ms.pool.mu.Unlock()
+
+	for _, pvm := range dead {
+		ms.logger.Warn("Pooled VM is not running, replacing it", "vmID", pvm.id)
+		ms.discardPooledVM(pvm)
+	}
+
+	ms.fillPool()
+}
+
+func (ms *managerService) monitorPool() {
+	defer ms.pool.wg.Done()
+
+	ticker := time.NewTicker(ms.pool.cfg.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ms.pool.done:
+			return
+		case <-ticker.C:
+			ms.checkPool()
+		}
+	}
+}
+
+func (ms *managerService) startPool(cfg PoolConfig) {
+	if cfg.Size <= 0 {
+		return
+	}
+
+	// Pooled VMs boot before their configuration is known, so it cannot be measured.
+	if ms.qemuCfg.AgentCmdline {
+		ms.logger.Warn("VM pool is disabled because agent configuration is passed on the kernel command line")
+		return
+	}
+
+	ms.pool = newVMPool(cfg)
+
+	go ms.fillPool()
+
+	if cfg.HealthInterval > 0 {
+		ms.pool.wg.Add(1)
+		go ms.monitorPool()
+	}
+}
+
+// stopPool stops the health monitor and all idle VMs.
+func (ms *managerService) stopPool() {
+	if ms.pool == nil {
+		return
+	}
+
+	close(ms.pool.done)
+	ms.pool.wg.Wait()
+
+	ms.pool.mu.Lock()
+	idle := ms.pool.idle
+	ms.pool.idle = nil
+	ms.pool.cfg.Size = 0
+	ms.pool.mu.Unlock()
+
+	for _, pvm := range idle {
+		ms.discardPooledVM(pvm)
+	}
+}
+
+func (ms *managerService) discardPooledVM(pvm pooledVM) {
+	if err := pvm.cvm.Stop(); err != nil {
+		ms.logger.Warn("Failed to stop pooled VM", "vmID", pvm.id, "error", err)
+	}
+	ms.ports.Release(pvm.id)
+
+	removeMounts(pvm.info)
+}
+
+func removeMounts(info qemu.VMInfo) {
+	os.RemoveAll(info.Config.CertsMount)
+	os.RemoveAll(info.Config.EnvMount)
+}
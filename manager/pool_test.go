@@ -0,0 +1,273 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	persistenceMocks "github.com/ultravioletrs/cocos/manager/qemu/mocks"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+func newPoolService(vmf *mocks.Provider, size, maxVMs int) *managerService {
+	persistence := new(persistenceMocks.Persistence)
+	persistence.On("SaveVM", mock.Anything).Return(nil)
+	persistence.On("DeleteVM", mock.Anything).Return(nil)
+
+	return &managerService{
+		qemuCfg:     qemu.Config{NetDevConfig: qemu.NetDevConfig{Mode: qemu.NetModeBridge}},
+		logger:      slog.Default(),
+		vms:         make(map[string]vm.VM),
+		vmFactory:   vmf.Execute,
+		persistence: persistence,
+		ttlManager:  NewTTLManager(),
+		maxVMs:      maxVMs,
+		pool:        newVMPool(PoolConfig{Size: size}),
+		ports:       newTestPorts(),
+	}
+}
+
+func TestFillPool(t *testing.T) {
+	cases := []struct {
+		desc     string
+		size     int
+		maxVMs   int
+		running  int
+		startErr error
+		expected int
+	}{
+		{
+			desc:     "fill pool to configured size",
+			size:     3,
+			expected: 3,
+		},
+		{
+			desc:     "fill pool limited by max VMs",
+			size:     3,
+			maxVMs:   3,
+			running:  2,
+			expected: 1,
+		},
+		{
+			desc:     "fill pool with failing VM start",
+			size:     2,
+			startErr: assert.AnError,
+			expected: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			vmMock := new(mocks.VM)
+			vmMock.On("Start").Return(tc.startErr)
+			vmMock.On("Stop").Return(nil)
+
+			vmf := new(mocks.Provider)
+			vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+			ms := newPoolService(vmf, tc.size, tc.maxVMs)
+			for i := 0; i < tc.running; i++ {
+				ms.vms[string(rune('a'+i))] = vmMock
+			}
+
+			ms.fillPool()
+			assert.Len(t, ms.pool.idle, tc.expected)
+
+			ms.stopPool()
+			assert.Empty(t, ms.pool.idle)
+		})
+	}
+}
+
+func TestCreateVMFromPool(t *testing.T) {
+	vmMock := new(mocks.VM)
+	vmMock.On("Start").Return(nil)
+	vmMock.On("Stop").Return(nil)
+	vmMock.On("GetProcess").Return(os.Getpid())
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	pooled := ms.pool.idle[0]
+
+	req := &CreateReq{
+		AgentCvmServerUrl:  "localhost:7001",
+		AgentCvmClientCert: []byte("cert"),
+	}
+	_, id, err := ms.CreateVM(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, pooled.id, id)
+	assert.Contains(t, ms.vms, id)
+
+	cert, err := os.ReadFile(filepath.Join(pooled.info.Config.CertsMount, "cert.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, "cert", string(cert))
+
+	env, err := os.ReadFile(filepath.Join(pooled.info.Config.EnvMount, cvmEnvironmentFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(env), agentCvmId+"="+id)
+
+	ms.stopPool()
+	removeMounts(pooled.info)
+}
+
+func TestCreateVMFromPoolInvalidTTL(t *testing.T) {
+	vmMock := new(mocks.VM)
+	vmMock.On("Start").Return(nil)
+	vmMock.On("Stop").Return(nil)
+	vmMock.On("GetProcess").Return(os.Getpid())
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.quotas = newQuotas(QuotaConfig{MaxVMs: 1})
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	pooled := ms.pool.idle[0]
+
+	_, _, err := ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "localhost:7001", Ttl: "soon"})
+	assert.True(t, errors.Contains(err, ErrMalformedEntity), "expected %v, got %v", ErrMalformedEntity, err)
+	assert.Empty(t, ms.vms)
+	assert.Empty(t, ms.quotas.vms)
+	assert.Contains(t, ms.pool.idle, pooled, "the pooled VM is kept for the next request")
+
+	ms.stopPool()
+	removeMounts(pooled.info)
+}
+
+func TestCreateVMProfileBypassesPool(t *testing.T) {
+	vmMock := new(mocks.VM)
+	vmMock.On("Start").Return(nil)
+	vmMock.On("Stop").Return(nil)
+	vmMock.On("GetProcess").Return(os.Getpid())
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.qemuCfg.Profile = qemu.ProfileDefault
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	pooled := ms.pool.idle[0]
+
+	_, id, err := ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "localhost:7001", MachineProfile: qemu.ProfileMicroVM})
+	require.NoError(t, err)
+	assert.NotEqual(t, pooled.id, id, "pooled VMs run the configured profile")
+	assert.Len(t, ms.pool.idle, 1)
+
+	_, id, err = ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "localhost:7001", MachineProfile: qemu.ProfileDefault})
+	require.NoError(t, err)
+	assert.Equal(t, pooled.id, id)
+
+	ms.stopPool()
+	removeMounts(pooled.info)
+}
+
+func TestCreateVMPlacementBypassesPool(t *testing.T) {
+	vmMock := new(mocks.VM)
+	vmMock.On("Start").Return(nil)
+	vmMock.On("Stop").Return(nil)
+	vmMock.On("GetProcess").Return(os.Getpid())
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	pooled := ms.pool.idle[0]
+
+	node := uint32(0)
+	_, id, err := ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "localhost:7001", NumaNode: &node})
+	require.NoError(t, err)
+	assert.NotEqual(t, pooled.id, id, "pooled VMs run the configured placement")
+	assert.Len(t, ms.pool.idle, 1)
+
+	vmi := vmf.Calls[len(vmf.Calls)-1].Arguments.Get(0).(qemu.VMInfo)
+	assert.Equal(t, "0", vmi.Config.PlacementConfig.NUMANode)
+
+	ms.stopPool()
+	removeMounts(pooled.info)
+}
+
+func TestCreateVMGPUsBypassPool(t *testing.T) {
+	setupPCI(t, pciDevice{addr: "0000:41:00.0", class: "0x030200", vendor: nvidiaVendorID, group: "12", driver: vfioDriver})
+
+	vmMock := new(mocks.VM)
+	vmMock.On("Start").Return(nil)
+	vmMock.On("Stop").Return(nil)
+	vmMock.On("GetProcess").Return(os.Getpid())
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.qemuCfg.GPUConfig.Allowed = []string{"0000:41:00.0"}
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	pooled := ms.pool.idle[0]
+
+	_, id, err := ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "localhost:7001", GpuDevices: []string{"41:00.0"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, pooled.id, id, "pooled VMs run without GPUs")
+	assert.Len(t, ms.pool.idle, 1)
+
+	vmi := vmf.Calls[len(vmf.Calls)-1].Arguments.Get(0).(qemu.VMInfo)
+	assert.Equal(t, []string{"0000:41:00.0"}, vmi.Config.GPUConfig.Devices)
+
+	ms.stopPool()
+	removeMounts(pooled.info)
+}
+
+func TestCheckPool(t *testing.T) {
+	deadVM := new(mocks.VM)
+	deadVM.On("Start").Return(nil).Once()
+	deadVM.On("GetProcess").Return(99999)
+	deadVM.On("Stop").Return(nil).Once()
+
+	liveVM := new(mocks.VM)
+	liveVM.On("Start").Return(nil)
+	liveVM.On("GetProcess").Return(os.Getpid())
+	liveVM.On("Stop").Return(nil)
+
+	vmf := new(mocks.Provider)
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(deadVM).Once()
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(liveVM)
+
+	ms := newPoolService(vmf, 1, 0)
+	ms.fillPool()
+	require.Len(t, ms.pool.idle, 1)
+	assert.Equal(t, deadVM, ms.pool.idle[0].cvm)
+
+	ms.checkPool()
+	require.Len(t, ms.pool.idle, 1)
+	assert.Equal(t, liveVM, ms.pool.idle[0].cvm)
+	deadVM.AssertExpectations(t)
+
+	ms.stopPool()
+}
@@ -4,22 +4,69 @@ package qemu
 
 import (
 	"fmt"
+	"maps"
 	"strconv"
 
 	"github.com/caarlos0/env/v10"
+	"github.com/ultravioletrs/cocos/internal/cmdline"
 )
 
 const (
 	KernelCommandLine = "quiet console=null"
+	NetModeUser       = "user"
+	NetModeBridge     = "bridge"
 	TDXObject         = "{\"qom-type\":\"tdx-guest\",\"id\":\"%s\",\"quote-generation-socket\":{\"type\": \"vsock\", \"cid\":\"2\",\"port\":\"%d\"}}"
 )
 
+// Machine profiles a VM is launched with.
+const (
+	// ProfileDefault launches a q35 machine booted by OVMF, with the
+	// confidential computing features of the host.
+	ProfileDefault = "default"
+	// ProfileMicroVM launches a QEMU microvm machine, without PCI and with
+	// virtio-mmio devices, that boots the kernel directly. It boots faster
+	// and runs without confidential computing.
+	ProfileMicroVM = "microvm"
+)
+
 type MemoryConfig struct {
 	Size  string `env:"MEMORY_SIZE"  envDefault:"2048M"`
 	Slots int    `env:"MEMORY_SLOTS" envDefault:"5"`
 	Max   string `env:"MAX_MEMORY"   envDefault:"30G"`
 }
 
+// SizeMB returns the memory size in MiB, rounded up, 0 when it is empty. As
+// for QEMU, sizes without a suffix are in MiB.
+func (c MemoryConfig) SizeMB() (uint64, error) {
+	if c.Size == "" {
+		return 0, nil
+	}
+	if !memorySize.MatchString(c.Size) {
+		return 0, invalid("memory size %q is not a number with an optional K, M, G or T suffix", c.Size)
+	}
+
+	digits, unit := c.Size, c.Size[len(c.Size)-1]
+	if unit < '0' || unit > '9' {
+		digits = c.Size[:len(c.Size)-1]
+	}
+
+	size, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, invalid("memory size %q: %v", c.Size, err)
+	}
+
+	switch unit {
+	case 'K':
+		return (size + 1023) / 1024, nil
+	case 'G':
+		return size << 10, nil
+	case 'T':
+		return size << 20, nil
+	default:
+		return size, nil
+	}
+}
+
 type OVMFCodeConfig struct {
 	If       string `env:"OVMF_CODE_IF"       envDefault:"pflash"`
 	Format   string `env:"OVMF_CODE_FORMAT"   envDefault:"raw"`
@@ -40,6 +87,13 @@ type NetDevConfig struct {
 	ID            string `env:"NETDEV_ID"       envDefault:"vmnic"`
 	HostFwdAgent  int    `env:"HOST_FWD_AGENT"  envDefault:"7020"`
 	GuestFwdAgent int    `env:"GUEST_FWD_AGENT" envDefault:"7002"`
+	// Mode is either user (slirp with port forwarding) or bridge (tap device attached to a host bridge).
+	Mode   string `env:"NETDEV_MODE"   envDefault:"user"`
+	Bridge string `env:"NETDEV_BRIDGE" envDefault:"br0"`
+	// MAC is assigned to the guest NIC, a random one is generated per VM when empty.
+	MAC  string `env:"NETDEV_MAC"  envDefault:""`
+	VLAN int    `env:"NETDEV_VLAN" envDefault:"0"`
+	Tap  string
 }
 
 type VirtioNetPciConfig struct {
@@ -60,6 +114,14 @@ type SEVSNPConfig struct {
 	ReducedPhysBits int    `env:"SEV_SNP_REDUCED_PHYS_BITS" envDefault:"1"`
 	EnableHostData  bool   `env:"ENABLE_HOST_DATA"      envDefault:"false"`
 	HostData        string `env:"HOST_DATA"             envDefault:""`
+	// OVMF switches SEV-SNP CVMs from IGVM to direct boot with this OVMF firmware
+	// and measured kernel hashes, so the launch measurement is computed from the boot components.
+	OVMF string `env:"SEV_SNP_OVMF_FILE" envDefault:""`
+}
+
+// SEVSNPDirectBoot reports whether SEV-SNP CVMs boot the kernel directly from OVMF instead of an IGVM file.
+func (config Config) SEVSNPDirectBoot() bool {
+	return config.EnableSEVSNP && config.SEVSNPConfig.OVMF != ""
 }
 
 type TDXConfig struct {
@@ -73,6 +135,24 @@ type IGVMConfig struct {
 	File string `env:"IGVM_FILE"      envDefault:"/root/coconut-qemu.igvm"`
 }
 
+type VSockConfig struct {
+	ID string `env:"VSOCK_ID" envDefault:"vhost-vsock-pci0"`
+	// GuestCID is the vsock context ID of the first CVM, vsock is disabled when it is 0.
+	GuestCID int `env:"VSOCK_GUEST_CID" envDefault:"0"`
+}
+
+type MicroVMConfig struct {
+	// Machine is the QEMU machine of the microvm profile with its options.
+	Machine string `env:"MICROVM_MACHINE" envDefault:"microvm,x-option-roms=off,pit=off,pic=off,isa-serial=off,rtc=off"`
+}
+
+type DatasetDiskConfig struct {
+	// DiskSlots is the number of hotpluggable PCIe ports reserved for dataset disks, hot-adding is disabled when it is 0.
+	DiskSlots int `env:"DATASET_DISK_SLOTS" envDefault:"0"`
+	// Dir is the directory of the disk images that can be attached, images elsewhere on the host are refused.
+	Dir string `env:"DATASET_DISK_DIR"   envDefault:"/var/lib/cocos/datasets"`
+}
+
 type Config struct {
 	EnableSEVSNP bool
 	EnableTDX    bool
@@ -81,6 +161,10 @@ type Config struct {
 
 	EnableKVM bool `env:"ENABLE_KVM" envDefault:"true"`
 
+	// Profile is the machine profile VMs are launched with unless the request selects another one.
+	Profile string `env:"MACHINE_PROFILE" envDefault:"default"`
+	MicroVMConfig
+
 	// machine, CPU, RAM
 	Machine  string `env:"MACHINE"     envDefault:"q35"`
 	CPU      string `env:"CPU"         envDefault:"EPYC"`
@@ -89,6 +173,12 @@ type Config struct {
 	MemID    string `env:"MEM_ID"      envDefault:"ram1"`
 	MemoryConfig
 
+	// vCPU pinning and NUMA memory placement
+	PlacementConfig
+
+	// GPU passthrough
+	GPUConfig
+
 	// OVMF
 	OVMFCodeConfig
 	OVMFVarsConfig
@@ -96,9 +186,11 @@ type Config struct {
 	// network
 	NetDevConfig
 	VirtioNetPciConfig
+	VSockConfig
 
 	// disk
 	DiskImgConfig
+	DatasetDiskConfig
 
 	// SEV-SNP
 	SEVSNPConfig
@@ -112,6 +204,11 @@ type Config struct {
 	// display
 	NoGraphic bool   `env:"NO_GRAPHIC" envDefault:"true"`
 	Monitor   string `env:"MONITOR"    envDefault:"pty"`
+	// QMPSocket is the per-VM QMP socket the manager controls the VM through.
+	QMPSocket string
+
+	// host isolation
+	SandboxConfig
 
 	// ports
 	HostFwdRange string `env:"HOST_FWD_RANGE" envDefault:"6100-6200"`
@@ -119,9 +216,80 @@ type Config struct {
 	// mounts
 	CertsMount string `env:"CERTS_MOUNT" envDefault:""`
 	EnvMount   string `env:"ENV_MOUNT"   envDefault:""`
+	// CheckpointMount is the host directory the agents write computation checkpoints to, kept across VM restarts.
+	CheckpointMount string `env:"CHECKPOINT_MOUNT" envDefault:""`
+
+	// measured agent configuration
+	// KernelParams are agent environment variables passed to every CVM on the kernel command line.
+	KernelParams map[string]string `env:"KERNEL_PARAMS"`
+	// AgentCmdline moves the per-CVM agent configuration from the environment file to the kernel command line.
+	AgentCmdline bool `env:"AGENT_CMDLINE" envDefault:"false"`
+	AgentParams  map[string]string
+}
+
+// MicroVM reports whether VMs are launched with the microvm profile.
+func (config Config) MicroVM() bool {
+	return config.Profile == ProfileMicroVM
+}
+
+// WithProfile returns the configuration of a VM launched with the machine
+// profile, the configured one when profile is empty. The confidential
+// computing features of the host are disabled for microvm VMs.
+func (config Config) WithProfile(profile string) (Config, error) {
+	switch profile {
+	case "":
+		profile = config.Profile
+	case ProfileDefault, ProfileMicroVM:
+	default:
+		return config, invalid("unknown machine profile %q, expected %s or %s", profile, ProfileDefault, ProfileMicroVM)
+	}
+
+	config.Profile = profile
+	if config.MicroVM() {
+		config.EnableSEVSNP = false
+		config.EnableTDX = false
+	}
+
+	return config, nil
+}
+
+// KernelCmdline returns the kernel command line with the agent configuration
+// appended as cocos parameters, per-CVM parameters override the shared ones.
+func (config Config) KernelCmdline() (string, error) {
+	params := make(map[string]string, len(config.KernelParams)+len(config.AgentParams))
+	maps.Copy(params, config.KernelParams)
+	maps.Copy(params, config.AgentParams)
+
+	if len(params) == 0 {
+		return KernelCommandLine, nil
+	}
+
+	agentParams, err := cmdline.Format(params)
+	if err != nil {
+		return "", err
+	}
+
+	return KernelCommandLine + " " + agentParams, nil
+}
+
+// KernelAppend returns the -append argument of the CVM. The command line is
+// passed quoted, as it always was, so that the launch measurement of CVMs
+// without agent parameters is unchanged. Parameters are validated when the CVM
+// is created, it falls back to the bare command line otherwise.
+func (config Config) KernelAppend() string {
+	kernelCmdline, err := config.KernelCmdline()
+	if err != nil {
+		kernelCmdline = KernelCommandLine
+	}
+
+	return strconv.Quote(kernelCmdline)
 }
 
 func (config Config) ConstructQemuArgs() []string {
+	if config.MicroVM() {
+		return config.microVMArgs()
+	}
+
 	args := []string{}
 
 	// virtualization
@@ -145,6 +313,16 @@ func (config Config) ConstructQemuArgs() []string {
 		config.MemoryConfig.Slots,
 		config.MemoryConfig.Max))
 
+	// Confidential VMs bind their memfd backend to the NUMA node below.
+	if !config.EnableSEVSNP && !config.EnableTDX && config.PlacementConfig.NUMANode != "" {
+		args = append(args, "-object",
+			fmt.Sprintf("memory-backend-ram,id=%s,size=%s%s",
+				config.MemID,
+				config.MemoryConfig.Size,
+				config.PlacementConfig.memoryPolicy()))
+		args = append(args, "-machine", "memory-backend="+config.MemID)
+	}
+
 	if !config.EnableSEVSNP && !config.EnableTDX {
 		// OVMF
 		args = append(args, "-drive",
@@ -164,38 +342,71 @@ func (config Config) ConstructQemuArgs() []string {
 	}
 
 	// network
-	args = append(args, "-netdev",
-		fmt.Sprintf("user,id=%s,hostfwd=tcp::%d-:%d",
-			config.NetDevConfig.ID,
-			config.NetDevConfig.HostFwdAgent, config.NetDevConfig.GuestFwdAgent))
+	if config.NetDevConfig.Mode == NetModeBridge {
+		args = append(args, "-netdev",
+			fmt.Sprintf("tap,id=%s,ifname=%s,script=no,downscript=no",
+				config.NetDevConfig.ID,
+				config.NetDevConfig.Tap))
+	} else {
+		args = append(args, "-netdev",
+			fmt.Sprintf("user,id=%s,hostfwd=tcp::%d-:%d",
+				config.NetDevConfig.ID,
+				config.NetDevConfig.HostFwdAgent, config.NetDevConfig.GuestFwdAgent))
+	}
+
+	mac := ""
+	if config.NetDevConfig.MAC != "" {
+		mac = fmt.Sprintf(",mac=%s", config.NetDevConfig.MAC)
+	}
 
 	args = append(args, "-device",
-		fmt.Sprintf("virtio-net-pci,disable-legacy=%s,iommu_platform=%v,netdev=%s,addr=%s,romfile=%s",
+		fmt.Sprintf("virtio-net-pci,disable-legacy=%s,iommu_platform=%v,netdev=%s,addr=%s,romfile=%s%s",
 			config.VirtioNetPciConfig.DisableLegacy,
 			config.VirtioNetPciConfig.IOMMUPlatform,
 			config.NetDevConfig.ID,
 			config.VirtioNetPciConfig.Addr,
-			config.VirtioNetPciConfig.ROMFile))
+			config.VirtioNetPciConfig.ROMFile,
+			mac))
+
+	if config.VSockConfig.GuestCID != 0 {
+		args = append(args, "-device",
+			fmt.Sprintf("vhost-vsock-pci,id=%s,guest-cid=%d",
+				config.VSockConfig.ID,
+				config.VSockConfig.GuestCID))
+	}
 
 	// SEV-SNP
 	if config.EnableSEVSNP {
 		sevSnpType := "sev-snp-guest"
-		hostData := ""
-
-		args = append(args, "-machine",
-			fmt.Sprintf("confidential-guest-support=%s,memory-backend=%s,igvm-cfg=%s",
-				config.SEVSNPConfig.ID,
-				config.MemID,
-				config.IGVMConfig.ID))
+		options := ""
+
+		if config.SEVSNPDirectBoot() {
+			args = append(args, "-machine",
+				fmt.Sprintf("confidential-guest-support=%s,memory-backend=%s",
+					config.SEVSNPConfig.ID,
+					config.MemID))
+		} else {
+			args = append(args, "-machine",
+				fmt.Sprintf("confidential-guest-support=%s,memory-backend=%s,igvm-cfg=%s",
+					config.SEVSNPConfig.ID,
+					config.MemID,
+					config.IGVMConfig.ID))
+		}
 
 		if config.SEVSNPConfig.EnableHostData {
-			hostData = fmt.Sprintf(",host-data=%s", config.SEVSNPConfig.HostData)
+			options = fmt.Sprintf(",host-data=%s", config.SEVSNPConfig.HostData)
 		}
 
 		args = append(args, "-object",
-			fmt.Sprintf("memory-backend-memfd,id=%s,size=%s,share=true,prealloc=false",
+			fmt.Sprintf("memory-backend-memfd,id=%s,size=%s,share=true,prealloc=false%s",
 				config.MemID,
-				config.MemoryConfig.Size))
+				config.MemoryConfig.Size,
+				config.PlacementConfig.memoryPolicy()))
+
+		if config.SEVSNPDirectBoot() {
+			// The kernel, initrd and command line hashes are part of the launch measurement.
+			options += ",kernel-hashes=on"
+		}
 
 		args = append(args, "-object",
 			fmt.Sprintf("%s,id=%s,cbitpos=%d,reduced-phys-bits=%d%s",
@@ -203,12 +414,16 @@ func (config Config) ConstructQemuArgs() []string {
 				config.SEVSNPConfig.ID,
 				config.SEVSNPConfig.CBitPos,
 				config.SEVSNPConfig.ReducedPhysBits,
-				hostData))
-
-		args = append(args, "-object",
-			fmt.Sprintf("igvm-cfg,id=%s,file=%s",
-				config.IGVMConfig.ID,
-				config.IGVMConfig.File))
+				options))
+
+		if config.SEVSNPDirectBoot() {
+			args = append(args, "-bios", config.SEVSNPConfig.OVMF)
+		} else {
+			args = append(args, "-object",
+				fmt.Sprintf("igvm-cfg,id=%s,file=%s",
+					config.IGVMConfig.ID,
+					config.IGVMConfig.File))
+		}
 	}
 
 	if config.EnableTDX {
@@ -223,16 +438,17 @@ func (config Config) ConstructQemuArgs() []string {
 				config.MemID))
 
 		args = append(args, "-object",
-			fmt.Sprintf("memory-backend-memfd,id=%s,size=%s,share=true,prealloc=false",
+			fmt.Sprintf("memory-backend-memfd,id=%s,size=%s,share=true,prealloc=false%s",
 				config.MemID,
-				config.MemoryConfig.Size))
+				config.MemoryConfig.Size,
+				config.PlacementConfig.memoryPolicy()))
 
 		args = append(args, "-bios", config.TDXConfig.OVMF)
 		args = append(args, "-nodefaults")
 	}
 
 	args = append(args, "-kernel", config.DiskImgConfig.KernelFile)
-	args = append(args, "-append", strconv.Quote(KernelCommandLine))
+	args = append(args, "-append", config.KernelAppend())
 	args = append(args, "-initrd", config.DiskImgConfig.RootFsFile)
 
 	// display
@@ -242,6 +458,26 @@ func (config Config) ConstructQemuArgs() []string {
 
 	args = append(args, "-monitor", config.Monitor)
 
+	if config.QMPSocket != "" {
+		args = append(args, "-qmp", fmt.Sprintf("unix:%s,server=on,wait=off", config.QMPSocket))
+		// guest panics are reported as QMP events
+		args = append(args, "-device", "pvpanic")
+	}
+
+	// dataset disks are hot-added over QMP into the reserved root ports
+	if config.DatasetDiskConfig.DiskSlots > 0 && config.QMPSocket != "" {
+		for i := range config.DatasetDiskConfig.DiskSlots {
+			args = append(args, "-device", fmt.Sprintf("pcie-root-port,id=%s,chassis=%d", DatasetDiskPort(i), i+1))
+		}
+	}
+
+	// GPUs sit behind root ports of their own, after the dataset disk ones
+	args = append(args, config.GPUConfig.args(config.DatasetDiskConfig.DiskSlots+1)...)
+
+	if config.SandboxConfig.Seccomp {
+		args = append(args, "-sandbox", seccompSandbox)
+	}
+
 	if config.CertsMount != "" {
 		args = append(args, "-fsdev", fmt.Sprintf("local,id=cert_fs,path=%s,security_model=mapped", config.CertsMount))
 		args = append(args, "-device", "virtio-9p-pci,fsdev=cert_fs,mount_tag=certs_share")
@@ -252,6 +488,104 @@ func (config Config) ConstructQemuArgs() []string {
 		args = append(args, "-device", "virtio-9p-pci,fsdev=env_fs,mount_tag=env_share")
 	}
 
+	if config.CheckpointMount != "" {
+		args = append(args, "-fsdev", fmt.Sprintf("local,id=checkpoint_fs,path=%s,security_model=mapped", config.CheckpointMount))
+		args = append(args, "-device", "virtio-9p-pci,fsdev=checkpoint_fs,mount_tag=checkpoint_share")
+	}
+
+	return args
+}
+
+// microVMArgs returns the arguments of a microvm machine. Its devices sit on
+// virtio-mmio transports since the machine has no PCI bus, so dataset disks
+// cannot be hot-added, and the kernel is booted directly without OVMF.
+func (config Config) microVMArgs() []string {
+	args := []string{}
+
+	if config.EnableKVM {
+		args = append(args, "-enable-kvm")
+	}
+
+	args = append(args, "-machine", config.MicroVMConfig.Machine)
+
+	if config.CPU != "" {
+		args = append(args, "-cpu", config.CPU)
+	}
+
+	// microvm supports neither CPU nor memory hotplug.
+	args = append(args, "-smp", strconv.Itoa(config.SMPCount))
+	args = append(args, "-m", config.MemoryConfig.Size)
+	if config.PlacementConfig.NUMANode != "" {
+		args = append(args, "-object",
+			fmt.Sprintf("memory-backend-ram,id=%s,size=%s%s",
+				config.MemID,
+				config.MemoryConfig.Size,
+				config.PlacementConfig.memoryPolicy()))
+		args = append(args, "-machine", "memory-backend="+config.MemID)
+	}
+	args = append(args, "-nodefaults")
+
+	// network
+	if config.NetDevConfig.Mode == NetModeBridge {
+		args = append(args, "-netdev",
+			fmt.Sprintf("tap,id=%s,ifname=%s,script=no,downscript=no",
+				config.NetDevConfig.ID,
+				config.NetDevConfig.Tap))
+	} else {
+		args = append(args, "-netdev",
+			fmt.Sprintf("user,id=%s,hostfwd=tcp::%d-:%d",
+				config.NetDevConfig.ID,
+				config.NetDevConfig.HostFwdAgent, config.NetDevConfig.GuestFwdAgent))
+	}
+
+	mac := ""
+	if config.NetDevConfig.MAC != "" {
+		mac = fmt.Sprintf(",mac=%s", config.NetDevConfig.MAC)
+	}
+
+	args = append(args, "-device", fmt.Sprintf("virtio-net-device,netdev=%s%s", config.NetDevConfig.ID, mac))
+
+	if config.VSockConfig.GuestCID != 0 {
+		args = append(args, "-device",
+			fmt.Sprintf("vhost-vsock-device,id=%s,guest-cid=%d",
+				config.VSockConfig.ID,
+				config.VSockConfig.GuestCID))
+	}
+
+	args = append(args, "-kernel", config.DiskImgConfig.KernelFile)
+	args = append(args, "-append", config.KernelAppend())
+	args = append(args, "-initrd", config.DiskImgConfig.RootFsFile)
+
+	// display
+	if config.NoGraphic {
+		args = append(args, "-nographic")
+	}
+
+	args = append(args, "-monitor", config.Monitor)
+
+	if config.QMPSocket != "" {
+		args = append(args, "-qmp", fmt.Sprintf("unix:%s,server=on,wait=off", config.QMPSocket))
+	}
+
+	if config.SandboxConfig.Seccomp {
+		args = append(args, "-sandbox", seccompSandbox)
+	}
+
+	if config.CertsMount != "" {
+		args = append(args, "-fsdev", fmt.Sprintf("local,id=cert_fs,path=%s,security_model=mapped", config.CertsMount))
+		args = append(args, "-device", "virtio-9p-device,fsdev=cert_fs,mount_tag=certs_share")
+	}
+
+	if config.EnvMount != "" {
+		args = append(args, "-fsdev", fmt.Sprintf("local,id=env_fs,path=%s,security_model=mapped", config.EnvMount))
+		args = append(args, "-device", "virtio-9p-device,fsdev=env_fs,mount_tag=env_share")
+	}
+
+	if config.CheckpointMount != "" {
+		args = append(args, "-fsdev", fmt.Sprintf("local,id=checkpoint_fs,path=%s,security_model=mapped", config.CheckpointMount))
+		args = append(args, "-device", "virtio-9p-device,fsdev=checkpoint_fs,mount_tag=checkpoint_share")
+	}
+
 	return args
 }
 
@@ -265,5 +599,10 @@ func NewConfig() (*Config, error) {
 	cfg.EnableSEVSNP = SEVSNPEnabledOnHost()
 	cfg.EnableTDX = TDXEnabledOnHost()
 
+	cfg, err := cfg.WithProfile("")
+	if err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
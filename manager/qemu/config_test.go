@@ -4,6 +4,8 @@ package qemu
 
 import (
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -75,6 +77,76 @@ func TestConstructQemuArgs(t *testing.T) {
 				"-monitor", "pty",
 			},
 		},
+		{
+			name: "Dataset disk slots",
+			config: Config{
+				QemuBinPath: "qemu-system-x86_64",
+				EnableKVM:   true,
+				Machine:     "q35",
+				CPU:         "EPYC",
+				SMPCount:    4,
+				MaxCPUs:     64,
+				MemID:       "ram1",
+				MemoryConfig: MemoryConfig{
+					Size:  "2048M",
+					Slots: 5,
+					Max:   "30G",
+				},
+				OVMFCodeConfig: OVMFCodeConfig{
+					If:       "pflash",
+					Format:   "raw",
+					Unit:     0,
+					File:     "/usr/share/OVMF/OVMF_CODE.fd",
+					ReadOnly: "on",
+				},
+				OVMFVarsConfig: OVMFVarsConfig{
+					If:     "pflash",
+					Format: "raw",
+					Unit:   1,
+					File:   "/usr/share/OVMF/OVMF_VARS.fd",
+				},
+				NetDevConfig: NetDevConfig{
+					ID:            "vmnic",
+					HostFwdAgent:  7020,
+					GuestFwdAgent: 7002,
+				},
+				VirtioNetPciConfig: VirtioNetPciConfig{
+					DisableLegacy: "on",
+					IOMMUPlatform: true,
+					Addr:          "0x2",
+				},
+				DiskImgConfig: DiskImgConfig{
+					KernelFile: "img/bzImage",
+					RootFsFile: "img/rootfs.cpio.gz",
+				},
+				NoGraphic: true,
+				Monitor:   "pty",
+				QMPSocket: "/tmp/qmp-vm.sock",
+				DatasetDiskConfig: DatasetDiskConfig{
+					DiskSlots: 2,
+				},
+			},
+			expected: []string{
+				"-enable-kvm",
+				"-machine", "q35",
+				"-cpu", "EPYC",
+				"-smp", "4,maxcpus=64",
+				"-m", "2048M,slots=5,maxmem=30G",
+				"-drive", "if=pflash,format=raw,unit=0,file=/usr/share/OVMF/OVMF_CODE.fd,readonly=on",
+				"-drive", "if=pflash,format=raw,unit=1,file=/usr/share/OVMF/OVMF_VARS.fd",
+				"-netdev", "user,id=vmnic,hostfwd=tcp::7020-:7002",
+				"-device", "virtio-net-pci,disable-legacy=on,iommu_platform=true,netdev=vmnic,addr=0x2,romfile=",
+				"-kernel", "img/bzImage",
+				"-append", "\"quiet console=null\"",
+				"-initrd", "img/rootfs.cpio.gz",
+				"-nographic",
+				"-monitor", "pty",
+				"-qmp", "unix:/tmp/qmp-vm.sock,server=on,wait=off",
+				"-device", "pvpanic",
+				"-device", "pcie-root-port,id=dsport0,chassis=1",
+				"-device", "pcie-root-port,id=dsport1,chassis=2",
+			},
+		},
 		{
 			name: "SEV-SNP enabled configuration",
 			config: Config{
@@ -192,3 +264,253 @@ func TestConstructQemuArgs_HostData(t *testing.T) {
 		t.Errorf("ConstructQemuArgs() did not contain expected SEV-SNP configuration with host data")
 	}
 }
+
+func TestConstructQemuArgs_SEVSNPDirectBoot(t *testing.T) {
+	config := Config{
+		EnableSEVSNP: true,
+		MemID:        "ram1",
+		MemoryConfig: MemoryConfig{Size: "2048M"},
+		SEVSNPConfig: SEVSNPConfig{
+			ID:              "sev0",
+			CBitPos:         51,
+			ReducedPhysBits: 1,
+			OVMF:            "OVMF.amdsev.fd",
+		},
+		IGVMConfig: IGVMConfig{ID: "igvm0", File: "coconut-qemu.igvm"},
+	}
+
+	args := strings.Join(config.ConstructQemuArgs(), " ")
+
+	for _, want := range []string{
+		"-machine confidential-guest-support=sev0,memory-backend=ram1 ",
+		"-object sev-snp-guest,id=sev0,cbitpos=51,reduced-phys-bits=1,kernel-hashes=on",
+		"-bios OVMF.amdsev.fd",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("ConstructQemuArgs() = %s, want it to contain %s", args, want)
+		}
+	}
+
+	if strings.Contains(args, "igvm") {
+		t.Errorf("ConstructQemuArgs() = %s, want no IGVM arguments", args)
+	}
+}
+
+func TestConstructQemuArgs_BridgeNetwork(t *testing.T) {
+	config := Config{
+		NetDevConfig: NetDevConfig{
+			ID:     "vmnic",
+			Mode:   NetModeBridge,
+			Bridge: "br0",
+			MAC:    "52:54:00:12:34:56",
+			Tap:    "cctap12345678",
+		},
+		VirtioNetPciConfig: VirtioNetPciConfig{
+			DisableLegacy: "on",
+			IOMMUPlatform: true,
+			Addr:          "0x2",
+		},
+	}
+
+	result := config.ConstructQemuArgs()
+
+	expected := map[string]string{
+		"-netdev": "tap,id=vmnic,ifname=cctap12345678,script=no,downscript=no",
+		"-device": "virtio-net-pci,disable-legacy=on,iommu_platform=true,netdev=vmnic,addr=0x2,romfile=,mac=52:54:00:12:34:56",
+	}
+
+	for flag, value := range expected {
+		found := false
+		for i, arg := range result {
+			if arg == flag && i+1 < len(result) && result[i+1] == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("ConstructQemuArgs() did not contain %s %s", flag, value)
+		}
+	}
+}
+
+func TestConstructQemuArgs_VSock(t *testing.T) {
+	config := Config{
+		VSockConfig: VSockConfig{
+			ID:       "vhost-vsock-pci0",
+			GuestCID: 3,
+		},
+	}
+
+	result := config.ConstructQemuArgs()
+
+	found := false
+	for i, arg := range result {
+		if arg == "-device" && i+1 < len(result) && result[i+1] == "vhost-vsock-pci,id=vhost-vsock-pci0,guest-cid=3" {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		t.Errorf("ConstructQemuArgs() did not contain vsock device")
+	}
+
+	config.VSockConfig.GuestCID = 0
+	for _, arg := range config.ConstructQemuArgs() {
+		if strings.HasPrefix(arg, "vhost-vsock-pci") {
+			t.Errorf("ConstructQemuArgs() contains vsock device when it is disabled")
+		}
+	}
+}
+
+func TestConstructQemuArgs_CheckpointMount(t *testing.T) {
+	config := Config{CheckpointMount: "/var/lib/cocos/checkpoints"}
+
+	args := strings.Join(config.ConstructQemuArgs(), " ")
+	if !strings.Contains(args, "-fsdev local,id=checkpoint_fs,path=/var/lib/cocos/checkpoints,security_model=mapped") {
+		t.Errorf("ConstructQemuArgs() did not contain the checkpoint fsdev")
+	}
+	if !strings.Contains(args, "-device virtio-9p-pci,fsdev=checkpoint_fs,mount_tag=checkpoint_share") {
+		t.Errorf("ConstructQemuArgs() did not contain the checkpoint share")
+	}
+
+	config.CheckpointMount = ""
+	if strings.Contains(strings.Join(config.ConstructQemuArgs(), " "), "checkpoint_share") {
+		t.Errorf("ConstructQemuArgs() contains the checkpoint share when it is disabled")
+	}
+}
+
+func TestConstructQemuArgs_MicroVM(t *testing.T) {
+	config := Config{
+		Profile:       ProfileMicroVM,
+		MicroVMConfig: MicroVMConfig{Machine: "microvm,pit=off"},
+		EnableKVM:     true,
+		Machine:       "q35",
+		CPU:           "EPYC",
+		SMPCount:      2,
+		MaxCPUs:       64,
+		MemoryConfig:  MemoryConfig{Size: "512M", Slots: 5, Max: "30G"},
+		NetDevConfig: NetDevConfig{
+			ID:            "vmnic",
+			HostFwdAgent:  7020,
+			GuestFwdAgent: 7002,
+			MAC:           "52:54:00:12:34:56",
+		},
+		VSockConfig:       VSockConfig{ID: "vhost-vsock-pci0", GuestCID: 3},
+		DiskImgConfig:     DiskImgConfig{KernelFile: "img/bzImage", RootFsFile: "img/rootfs.cpio.gz"},
+		DatasetDiskConfig: DatasetDiskConfig{DiskSlots: 2},
+		Monitor:           "pty",
+		QMPSocket:         "/tmp/vm.qmp",
+		CertsMount:        "/tmp/certs",
+	}
+
+	expected := []string{
+		"-enable-kvm",
+		"-machine", "microvm,pit=off",
+		"-cpu", "EPYC",
+		"-smp", "2",
+		"-m", "512M",
+		"-nodefaults",
+		"-netdev", "user,id=vmnic,hostfwd=tcp::7020-:7002",
+		"-device", "virtio-net-device,netdev=vmnic,mac=52:54:00:12:34:56",
+		"-device", "vhost-vsock-device,id=vhost-vsock-pci0,guest-cid=3",
+		"-kernel", "img/bzImage",
+		"-append", strconv.Quote(KernelCommandLine),
+		"-initrd", "img/rootfs.cpio.gz",
+		"-monitor", "pty",
+		"-qmp", "unix:/tmp/vm.qmp,server=on,wait=off",
+		"-fsdev", "local,id=cert_fs,path=/tmp/certs,security_model=mapped",
+		"-device", "virtio-9p-device,fsdev=cert_fs,mount_tag=certs_share",
+	}
+
+	if result := config.ConstructQemuArgs(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("ConstructQemuArgs() = %v, want %v", result, expected)
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	config := Config{Profile: ProfileDefault, EnableSEVSNP: true}
+
+	got, err := config.WithProfile("")
+	if err != nil || got.Profile != ProfileDefault || !got.EnableSEVSNP {
+		t.Errorf("WithProfile(\"\") = %+v, %v, want the configured profile", got, err)
+	}
+
+	got, err = config.WithProfile(ProfileMicroVM)
+	if err != nil || !got.MicroVM() || got.EnableSEVSNP || got.EnableTDX {
+		t.Errorf("WithProfile(%q) = %+v, %v, want a microvm without confidential computing", ProfileMicroVM, got, err)
+	}
+	if !config.EnableSEVSNP {
+		t.Errorf("WithProfile() changed the configuration it was called on")
+	}
+
+	if _, err := config.WithProfile("firecracker"); err == nil {
+		t.Errorf("WithProfile() accepted an unknown profile")
+	}
+}
+
+func TestMemorySizeMB(t *testing.T) {
+	cases := []struct {
+		size    string
+		want    uint64
+		wantErr bool
+	}{
+		{size: "", want: 0},
+		{size: "2048", want: 2048},
+		{size: "2048M", want: 2048},
+		{size: "4G", want: 4096},
+		{size: "1T", want: 1 << 20},
+		{size: "1536K", want: 2},
+		{size: "2GB", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := MemoryConfig{Size: tc.size}.SizeMB()
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("SizeMB(%q) = %d, %v, want %d", tc.size, got, err, tc.want)
+		}
+	}
+}
+
+func TestKernelCmdline(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "no agent parameters",
+			config:   Config{},
+			expected: KernelCommandLine,
+		},
+		{
+			name: "shared and per CVM parameters",
+			config: Config{
+				KernelParams: map[string]string{"AGENT_LOG_LEVEL": "info", "AGENT_OS_BUILD": "UVC"},
+				AgentParams:  map[string]string{"AGENT_LOG_LEVEL": "debug", "AGENT_CVM_ID": "vm1"},
+			},
+			expected: KernelCommandLine + " cocos.agent_cvm_id=vm1 cocos.agent_log_level=debug cocos.agent_os_build=UVC",
+		},
+		{
+			name: "invalid parameter",
+			config: Config{
+				AgentParams: map[string]string{"AGENT_LOG_LEVEL": "\"debug\""},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.KernelCmdline()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KernelCmdline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("KernelCmdline() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const defaultPCIDomain = "0000:"
+
+var pciAddress = regexp.MustCompile(`^([0-9a-f]{4}:)?[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
+
+type GPUConfig struct {
+	// Allowed are the PCI addresses of the host GPUs VMs may request, e.g.
+	// 0000:41:00.0, GPU passthrough is disabled when it is empty.
+	Allowed []string `env:"GPU_DEVICES" envDefault:""`
+	// NvidiaCC is the NVIDIA confidential computing mode of VMs whose request
+	// does not set it, which requires a SEV-SNP or TDX VM.
+	NvidiaCC bool `env:"GPU_NVIDIA_CC" envDefault:"false"`
+	// MMIO64MB is the size of the 64-bit PCI MMIO window OVMF maps the GPU
+	// BARs into, the OVMF default when it is 0.
+	MMIO64MB uint64 `env:"GPU_MMIO64_MB" envDefault:"262144"`
+	// Devices are the PCI addresses of the host GPUs passed through to the VM.
+	Devices []string
+}
+
+// parsePCIAddress returns the PCI address in the domain:bus:device.function
+// format of sysfs, adding the 0000 domain when the address has none.
+func parsePCIAddress(addr string) (string, error) {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if !pciAddress.MatchString(addr) {
+		return "", fmt.Errorf("%q is not a PCI address, expected [domain:]bus:device.function", addr)
+	}
+	if len(addr) == len("00:00.0") {
+		addr = defaultPCIDomain + addr
+	}
+
+	return addr, nil
+}
+
+// GPUPort returns the ID of the PCIe root port the i-th GPU of a VM sits behind.
+func GPUPort(i int) string {
+	return fmt.Sprintf("gpuport%d", i)
+}
+
+// WithGPUs returns the configuration of a VM the host GPUs at the PCI
+// addresses are passed through to, in the NVIDIA confidential computing mode
+// of nvidiaCC, the configured one when it is nil. The GPUs must be among the
+// allowed ones, and only default profile VMs have the PCIe bus they sit on.
+func (config Config) WithGPUs(devices []string, nvidiaCC *bool) (Config, error) {
+	if nvidiaCC != nil {
+		config.GPUConfig.NvidiaCC = *nvidiaCC
+	}
+	if len(devices) == 0 {
+		return config, nil
+	}
+
+	if config.MicroVM() {
+		return config, invalid("the %s profile does not support GPU passthrough", ProfileMicroVM)
+	}
+	if config.GPUConfig.NvidiaCC && !config.EnableSEVSNP && !config.EnableTDX {
+		return config, invalid("NVIDIA confidential computing mode requires a SEV-SNP or TDX VM")
+	}
+
+	allowed, err := parsePCIAddresses(config.GPUConfig.Allowed)
+	if err != nil {
+		return config, err
+	}
+	requested, err := parsePCIAddresses(devices)
+	if err != nil {
+		return config, err
+	}
+	for _, dev := range requested {
+		if !slices.Contains(allowed, dev) {
+			return config, invalid("GPU %s is not allowed, set MANAGER_QEMU_GPU_DEVICES to pass it through", dev)
+		}
+	}
+
+	config.GPUConfig.Devices = requested
+
+	return config, nil
+}
+
+func (cfg GPUConfig) validate() error {
+	_, err := parsePCIAddresses(cfg.Allowed)
+	return err
+}
+
+// args returns the root ports the GPUs are plugged into, numbered from the
+// first free chassis, and the VFIO devices passing the GPUs through.
+func (cfg GPUConfig) args(chassis int) []string {
+	if len(cfg.Devices) == 0 {
+		return nil
+	}
+
+	var args []string
+	for i, dev := range cfg.Devices {
+		args = append(args, "-device", fmt.Sprintf("pcie-root-port,id=%s,chassis=%d", GPUPort(i), chassis+i))
+		args = append(args, "-device", fmt.Sprintf("vfio-pci,host=%s,bus=%s", dev, GPUPort(i)))
+	}
+
+	// The BARs of data center GPUs exceed the default 64-bit MMIO window of OVMF.
+	if cfg.MMIO64MB > 0 {
+		args = append(args, "-fw_cfg", fmt.Sprintf("name=opt/ovmf/X-PciMmio64Mb,string=%d", cfg.MMIO64MB))
+	}
+
+	return args
+}
+
+func parsePCIAddresses(addrs []string) ([]string, error) {
+	parsed := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		dev, err := parsePCIAddress(addr)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		if slices.Contains(parsed, dev) {
+			return nil, invalid("GPU %s is listed twice", dev)
+		}
+		parsed = append(parsed, dev)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithGPUs(t *testing.T) {
+	on, off := true, false
+	allowed := GPUConfig{Allowed: []string{"0000:41:00.0", "0000:C1:00.0"}, NvidiaCC: true}
+
+	cases := []struct {
+		desc     string
+		config   Config
+		devices  []string
+		nvidiaCC *bool
+		want     GPUConfig
+		err      error
+	}{
+		{
+			desc:   "no GPUs",
+			config: Config{GPUConfig: allowed},
+			want:   allowed,
+		},
+		{
+			desc:    "GPUs of a TDX VM",
+			config:  Config{EnableTDX: true, GPUConfig: allowed},
+			devices: []string{"41:00.0", "0000:c1:00.0"},
+			want:    GPUConfig{Allowed: allowed.Allowed, NvidiaCC: true, Devices: []string{"0000:41:00.0", "0000:c1:00.0"}},
+		},
+		{
+			desc:     "GPU outside of confidential computing mode",
+			config:   Config{GPUConfig: allowed},
+			devices:  []string{"41:00.0"},
+			nvidiaCC: &off,
+			want:     GPUConfig{Allowed: allowed.Allowed, Devices: []string{"0000:41:00.0"}},
+		},
+		{
+			desc:     "confidential computing mode without a TEE",
+			config:   Config{GPUConfig: allowed},
+			devices:  []string{"41:00.0"},
+			nvidiaCC: &on,
+			err:      ErrInvalidConfig,
+		},
+		{
+			desc:    "GPU not allowed",
+			config:  Config{EnableSEVSNP: true, GPUConfig: allowed},
+			devices: []string{"81:00.0"},
+			err:     ErrInvalidConfig,
+		},
+		{
+			desc:    "GPU requested twice",
+			config:  Config{EnableSEVSNP: true, GPUConfig: allowed},
+			devices: []string{"41:00.0", "0000:41:00.0"},
+			err:     ErrInvalidConfig,
+		},
+		{
+			desc:    "invalid GPU address",
+			config:  Config{EnableSEVSNP: true, GPUConfig: allowed},
+			devices: []string{"gpu0"},
+			err:     ErrInvalidConfig,
+		},
+		{
+			desc:     "microvm",
+			config:   Config{Profile: ProfileMicroVM, GPUConfig: allowed},
+			devices:  []string{"41:00.0"},
+			nvidiaCC: &off,
+			err:      ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.WithGPUs(tc.devices, tc.nvidiaCC)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err == nil {
+				assert.Equal(t, tc.want, got.GPUConfig)
+			}
+		})
+	}
+}
+
+func TestConstructQemuArgs_GPUs(t *testing.T) {
+	config := Config{
+		QMPSocket:         "/tmp/qmp.sock",
+		DatasetDiskConfig: DatasetDiskConfig{DiskSlots: 2},
+		GPUConfig:         GPUConfig{MMIO64MB: 262144},
+	}
+
+	args := strings.Join(config.ConstructQemuArgs(), " ")
+	assert.NotContains(t, args, "vfio-pci")
+	assert.NotContains(t, args, "X-PciMmio64Mb")
+
+	config.GPUConfig.Devices = []string{"0000:41:00.0", "0000:c1:00.0"}
+	args = strings.Join(config.ConstructQemuArgs(), " ")
+	for _, want := range []string{
+		"-device pcie-root-port,id=dsport1,chassis=2",
+		"-device pcie-root-port,id=gpuport0,chassis=3 -device vfio-pci,host=0000:41:00.0,bus=gpuport0",
+		"-device pcie-root-port,id=gpuport1,chassis=4 -device vfio-pci,host=0000:c1:00.0,bus=gpuport1",
+		"-fw_cfg name=opt/ovmf/X-PciMmio64Mb,string=262144",
+	} {
+		assert.Contains(t, args, want)
+	}
+
+	config.GPUConfig.MMIO64MB = 0
+	args = strings.Join(config.ConstructQemuArgs(), " ")
+	assert.NotContains(t, args, "X-PciMmio64Mb")
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	tapPrefix = "cctap"
+	// Linux limits interface names to 15 characters.
+	maxIfNameLen = 15
+)
+
+// runCommand executes a host command, it is a variable so tests can stub it.
+var runCommand = func(useSudo bool, name string, args ...string) error {
+	if useSudo {
+		args = append([]string{name}, args...)
+		name = "sudo"
+	}
+
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// TapName returns the tap interface name used for the VM with the given ID.
+func TapName(cvmID string) string {
+	name := tapPrefix + strings.ReplaceAll(cvmID, "-", "")
+	if len(name) > maxIfNameLen {
+		name = name[:maxIfNameLen]
+	}
+
+	return name
+}
+
+// GenerateMAC returns a random MAC address within the QEMU OUI.
+func GenerateMAC() (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", buf[0], buf[1], buf[2]), nil
+}
+
+// setupTap creates the tap device, attaches it to the bridge and tags it with
+// the configured VLAN.
+func setupTap(cfg NetDevConfig, useSudo bool) (err error) {
+	if err := runCommand(useSudo, "ip", "tuntap", "add", "dev", cfg.Tap, "mode", "tap"); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = teardownTap(cfg, useSudo)
+		}
+	}()
+
+	if err := runCommand(useSudo, "ip", "link", "set", "dev", cfg.Tap, "master", cfg.Bridge); err != nil {
+		return err
+	}
+
+	if cfg.VLAN > 0 {
+		if err := runCommand(useSudo, "bridge", "vlan", "add", "dev", cfg.Tap, "vid", strconv.Itoa(cfg.VLAN), "pvid", "untagged"); err != nil {
+			return err
+		}
+	}
+
+	return runCommand(useSudo, "ip", "link", "set", "dev", cfg.Tap, "up")
+}
+
+func teardownTap(cfg NetDevConfig, useSudo bool) error {
+	return runCommand(useSudo, "ip", "link", "del", "dev", cfg.Tap)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTapName(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{
+			name:     "uuid is truncated",
+			id:       "0f8fad5b-d9cb-469f-a165-70867728950e",
+			expected: "cctap0f8fad5bd9",
+		},
+		{
+			name:     "short id",
+			id:       "abc",
+			expected: "cctapabc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := TapName(tt.id)
+			assert.Equal(t, tt.expected, name)
+			assert.LessOrEqual(t, len(name), maxIfNameLen)
+		})
+	}
+}
+
+func TestGenerateMAC(t *testing.T) {
+	mac, err := GenerateMAC()
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^52:54:00(:[0-9a-f]{2}){3}$`), mac)
+}
+
+func TestSetupTap(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      NetDevConfig
+		failOn   string
+		expected []string
+		err      bool
+	}{
+		{
+			name: "without VLAN",
+			cfg:  NetDevConfig{Tap: "cctap1", Bridge: "br0"},
+			expected: []string{
+				"ip tuntap add dev cctap1 mode tap",
+				"ip link set dev cctap1 master br0",
+				"ip link set dev cctap1 up",
+			},
+		},
+		{
+			name: "with VLAN",
+			cfg:  NetDevConfig{Tap: "cctap1", Bridge: "br0", VLAN: 42},
+			expected: []string{
+				"ip tuntap add dev cctap1 mode tap",
+				"ip link set dev cctap1 master br0",
+				"bridge vlan add dev cctap1 vid 42 pvid untagged",
+				"ip link set dev cctap1 up",
+			},
+		},
+		{
+			name:   "attach to bridge fails",
+			cfg:    NetDevConfig{Tap: "cctap1", Bridge: "br0"},
+			failOn: "ip link set dev cctap1 master br0",
+			expected: []string{
+				"ip tuntap add dev cctap1 mode tap",
+				"ip link set dev cctap1 master br0",
+				"ip link del dev cctap1",
+			},
+			err: true,
+		},
+	}
+
+	orig := runCommand
+	defer func() { runCommand = orig }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			runCommand = func(_ bool, name string, args ...string) error {
+				call := strings.Join(append([]string{name}, args...), " ")
+				calls = append(calls, call)
+				if call == tt.failOn {
+					return errors.New("command failed")
+				}
+				return nil
+			}
+
+			err := setupTap(tt.cfg, false)
+			assert.Equal(t, tt.err, err != nil)
+			assert.Equal(t, tt.expected, calls)
+		})
+	}
+}
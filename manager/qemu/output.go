@@ -0,0 +1,201 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/ultravioletrs/cocos/manager/vm"
+)
+
+// OutputFDsEnv holds the read ends of the QEMU output pipes a manager inherits
+// from the manager it replaced during an upgrade, as comma separated
+// <computation id>:<stdout fd>:<stderr fd> entries.
+const OutputFDsEnv = "MANAGER_QEMU_OUTPUT_FDS"
+
+// outputPipes are the read ends of the standard output and error pipes of a QEMU process.
+type outputPipes [2]*os.File
+
+// outputs tracks the output pipes of the running QEMU processes by computation
+// ID, so that they survive the exec of an upgrade.
+var outputs = struct {
+	sync.Mutex
+	pipes     map[string]outputPipes
+	inherited map[string]outputPipes
+	once      sync.Once
+}{pipes: make(map[string]outputPipes)}
+
+// newOutputPipes creates the output pipes of a QEMU process, the write ends
+// are passed to QEMU and must be closed once it started.
+func newOutputPipes() (outputPipes, outputPipes, error) {
+	var r, w outputPipes
+	for i := range r {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			closeOutput(r)
+			closeOutput(w)
+			return outputPipes{}, outputPipes{}, err
+		}
+		r[i], w[i] = pr, pw
+	}
+
+	return r, w, nil
+}
+
+// relayOutput logs the output QEMU writes to the pipes until it exits. The
+// pipes are kept outside of exec.Cmd so that they can be handed off.
+func (v *qemuVM) relayOutput(pipes outputPipes) {
+	outputs.Lock()
+	outputs.pipes[v.cvmId] = pipes
+	outputs.Unlock()
+
+	logger := v.logger.With(slog.String("cvm", v.cvmId))
+	writers := [2]io.Writer{
+		&vm.Stdout{StateMachine: v.StateMachine, Logger: logger},
+		&vm.Stderr{StateMachine: v.StateMachine, Logger: logger},
+	}
+
+	var wg sync.WaitGroup
+	for i, r := range pipes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := io.Copy(writers[i], r); err != nil {
+				v.logger.Debug("QEMU output relay stopped", "cvm", v.cvmId, "error", err)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+
+		outputs.Lock()
+		if outputs.pipes[v.cvmId] == pipes {
+			delete(outputs.pipes, v.cvmId)
+		}
+		outputs.Unlock()
+
+		closeOutput(pipes)
+	}()
+}
+
+// HandoffOutputs returns the OutputFDsEnv value handing the output pipes of the
+// running QEMU processes off to the manager an upgrade execs. The pipes are
+// duplicated without the close-on-exec flag.
+func HandoffOutputs() (string, error) {
+	outputs.Lock()
+	defer outputs.Unlock()
+
+	var entries []string
+	for id, pipes := range outputs.pipes {
+		var fds [2]int
+		for i, f := range pipes {
+			fd, err := inheritableFD(f)
+			if err != nil {
+				return "", fmt.Errorf("failed to hand off the QEMU output of %s: %w", id, err)
+			}
+			fds[i] = fd
+		}
+		entries = append(entries, fmt.Sprintf("%s:%d:%d", id, fds[0], fds[1]))
+	}
+
+	return strings.Join(entries, ","), nil
+}
+
+// inheritedOutput returns the output pipes of the VM the previous manager
+// handed off, if any, and removes them from the inherited ones.
+func inheritedOutput(id string) (outputPipes, bool) {
+	outputs.once.Do(loadInheritedOutputs)
+
+	outputs.Lock()
+	defer outputs.Unlock()
+
+	pipes, ok := outputs.inherited[id]
+	delete(outputs.inherited, id)
+
+	return pipes, ok
+}
+
+// CloseInheritedOutputs closes the inherited output pipes of the VMs that were
+// not adopted, their QEMU processes would otherwise block once a pipe is full.
+func CloseInheritedOutputs() {
+	outputs.once.Do(loadInheritedOutputs)
+
+	outputs.Lock()
+	defer outputs.Unlock()
+
+	for id, pipes := range outputs.inherited {
+		closeOutput(pipes)
+		delete(outputs.inherited, id)
+	}
+}
+
+func loadInheritedOutputs() {
+	outputs.inherited = parseOutputFDs(os.Getenv(OutputFDsEnv))
+	os.Unsetenv(OutputFDsEnv)
+}
+
+// parseOutputFDs parses an OutputFDsEnv value, malformed entries are ignored.
+func parseOutputFDs(value string) map[string]outputPipes {
+	inherited := make(map[string]outputPipes)
+	if value == "" {
+		return inherited
+	}
+
+	for entry := range strings.SplitSeq(value, ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" {
+			continue
+		}
+
+		stdout, err := strconv.Atoi(parts[1])
+		if err != nil || stdout < 0 {
+			continue
+		}
+		stderr, err := strconv.Atoi(parts[2])
+		if err != nil || stderr < 0 {
+			continue
+		}
+
+		inherited[parts[0]] = outputPipes{
+			os.NewFile(uintptr(stdout), "qemu-stdout"),
+			os.NewFile(uintptr(stderr), "qemu-stderr"),
+		}
+	}
+
+	return inherited
+}
+
+// inheritableFD duplicates the descriptor of f without the close-on-exec flag,
+// without switching f to blocking mode as File.Fd does.
+func inheritableFD(f *os.File) (int, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var dup int
+	var dupErr error
+	if err := rc.Control(func(fd uintptr) {
+		dup, dupErr = syscall.Dup(int(fd))
+	}); err != nil {
+		return 0, err
+	}
+
+	return dup, os.NewSyscallError("dup", dupErr)
+}
+
+func closeOutput(pipes outputPipes) {
+	for _, f := range pipes {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
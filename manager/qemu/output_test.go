@@ -0,0 +1,92 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuffer collects the log records of the VMs of a test.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func relayed(id string) bool {
+	outputs.Lock()
+	defer outputs.Unlock()
+
+	_, ok := outputs.pipes[id]
+
+	return ok
+}
+
+func TestOutputHandoff(t *testing.T) {
+	var logs logBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	output, qemuOutput, err := newOutputPipes()
+	require.NoError(t, err)
+	defer closeOutput(qemuOutput)
+
+	previous := NewVM(VMInfo{}, "handoff-cvm", logger).(*qemuVM)
+	previous.relayOutput(output)
+
+	fmt.Fprintln(qemuOutput[0], "booting")
+	require.Eventually(t, func() bool { return strings.Contains(logs.String(), "booting") }, time.Second, 10*time.Millisecond)
+
+	value, err := HandoffOutputs()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, "handoff-cvm:"), value)
+
+	// The exec of the upgrade closes the pipes of the previous manager.
+	closeOutput(output)
+
+	t.Setenv(OutputFDsEnv, value)
+	outputs.once = sync.Once{}
+	defer func() { outputs.once = sync.Once{} }()
+
+	adopted := NewVM(VMInfo{}, "handoff-cvm", logger).(*qemuVM)
+	inherited, ok := inheritedOutput(adopted.cvmId)
+	require.True(t, ok)
+	adopted.relayOutput(inherited)
+
+	_, ok = inheritedOutput(adopted.cvmId)
+	assert.False(t, ok, "inherited output pipes are claimed once")
+
+	fmt.Fprintln(qemuOutput[1], "guest panicked")
+	require.Eventually(t, func() bool { return strings.Contains(logs.String(), "guest panicked") }, time.Second, 10*time.Millisecond)
+	assert.Contains(t, logs.String(), "stream=stderr")
+
+	closeOutput(qemuOutput)
+	require.Eventually(t, func() bool { return !relayed("handoff-cvm") }, time.Second, 10*time.Millisecond, "the output is no longer handed off once QEMU exits")
+}
+
+func TestParseOutputFDs(t *testing.T) {
+	for _, value := range []string{"", "cvm", "cvm:1", ":3:4", "cvm:x:4", "cvm:3:-1"} {
+		assert.Empty(t, parseOutputFDs(value), value)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 const jsonExt = ".json"
@@ -15,6 +16,12 @@ type VMState struct {
 	ID     string
 	VMinfo VMInfo
 	PID    int
+	// TTL is the time to live of the VM and Expiry when it elapses, so that
+	// the manager restoring the VM, e.g. after an upgrade, still enforces it.
+	TTL    string    `json:",omitempty"`
+	Expiry time.Time `json:",omitzero"`
+	// Tenant is the tenant whose quota the VM counts against.
+	Tenant string `json:",omitempty"`
 }
 
 type FilePersistence struct {
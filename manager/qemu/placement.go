@@ -0,0 +1,127 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const qmpQueryCPUsFastCmd = "query-cpus-fast"
+
+type PlacementConfig struct {
+	// HostCPUs is the list of host CPUs the vCPUs are pinned to, in the
+	// cpulist format of sysfs, e.g. 0-3,8. The i-th vCPU is pinned to the i-th
+	// CPU of the list, vCPUs float across all host CPUs when it is empty.
+	HostCPUs string `env:"HOST_CPUS" envDefault:""`
+	// NUMANode is the host NUMA node the guest memory is allocated from, it
+	// is allocated by the default policy of the host when empty.
+	NUMANode string `env:"NUMA_NODE" envDefault:""`
+}
+
+// vcpuThread is a vCPU of a running VM as reported by query-cpus-fast.
+type vcpuThread struct {
+	Index    int `json:"cpu-index"`
+	ThreadID int `json:"thread-id"`
+}
+
+// ParseCPUList returns the CPUs of a cpulist, e.g. 0-3,8, in the order they are listed.
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU %q in CPU list %q", first, list)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU range %q in CPU list %q", part, list)
+			}
+		}
+
+		for cpu := start; cpu <= end; cpu++ {
+			if slices.Contains(cpus, cpu) {
+				return nil, fmt.Errorf("CPU %d is listed twice in CPU list %q", cpu, list)
+			}
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
+
+// WithPlacement returns the configuration of a VM whose vCPUs are pinned to
+// hostCPUs and whose memory is allocated from numaNode, the configured ones
+// when hostCPUs is empty or numaNode is nil.
+func (config Config) WithPlacement(hostCPUs string, numaNode *uint32) Config {
+	if hostCPUs != "" {
+		config.PlacementConfig.HostCPUs = hostCPUs
+	}
+	if numaNode != nil {
+		config.PlacementConfig.NUMANode = strconv.FormatUint(uint64(*numaNode), 10)
+	}
+
+	return config
+}
+
+func (cfg PlacementConfig) validate(smpCount int) error {
+	cpus, err := ParseCPUList(cfg.HostCPUs)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if len(cpus) > 0 && len(cpus) < smpCount {
+		return invalid("%d host CPUs cannot pin %d vCPUs", len(cpus), smpCount)
+	}
+
+	if cfg.NUMANode != "" {
+		if _, err := strconv.ParseUint(cfg.NUMANode, 10, 32); err != nil {
+			return invalid("NUMA node %q is not a node number", cfg.NUMANode)
+		}
+	}
+
+	return nil
+}
+
+// memoryPolicy returns the options binding a memory backend to the NUMA node.
+func (cfg PlacementConfig) memoryPolicy() string {
+	if cfg.NUMANode == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(",host-nodes=%s,policy=bind", cfg.NUMANode)
+}
+
+// pinVCPUs pins the vCPU threads of the running VM to the configured host CPUs.
+func (v *qemuVM) pinVCPUs(qmp *qmpClient) error {
+	// The CPU list was validated when the VM was created.
+	cpus, err := ParseCPUList(v.vmi.Config.PlacementConfig.HostCPUs)
+	if err != nil || len(cpus) == 0 {
+		return err
+	}
+
+	var vcpus []vcpuThread
+	if err := qmp.execute(qmpQueryCPUsFastCmd, nil, &vcpus); err != nil {
+		return err
+	}
+
+	for _, vcpu := range vcpus {
+		cpu := cpus[vcpu.Index%len(cpus)]
+		if err := runCommand(v.vmi.Config.UseSudo, "taskset", "-pc", strconv.Itoa(cpu), strconv.Itoa(vcpu.ThreadID)); err != nil {
+			return fmt.Errorf("failed to pin vCPU %d: %w", vcpu.Index, err)
+		}
+	}
+
+	v.logger.Info("pinned vCPUs", "cvm", v.cvmId, "vcpus", len(vcpus), "host_cpus", v.vmi.Config.PlacementConfig.HostCPUs)
+
+	return nil
+}
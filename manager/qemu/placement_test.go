@@ -0,0 +1,123 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cases := []struct {
+		list string
+		cpus []int
+		err  bool
+	}{
+		{list: ""},
+		{list: "3", cpus: []int{3}},
+		{list: "0-3,8,10-11", cpus: []int{0, 1, 2, 3, 8, 10, 11}},
+		{list: "8-9, 2", cpus: []int{8, 9, 2}},
+		{list: "3-1", err: true},
+		{list: "0-3,2", err: true},
+		{list: "a", err: true},
+		{list: "-1", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.list, func(t *testing.T) {
+			cpus, err := ParseCPUList(tc.list)
+			assert.Equal(t, tc.err, err != nil, "unexpected error %v", err)
+			assert.Equal(t, tc.cpus, cpus)
+		})
+	}
+}
+
+func TestWithPlacement(t *testing.T) {
+	config := Config{PlacementConfig: PlacementConfig{HostCPUs: "0-3", NUMANode: "0"}}
+
+	assert.Equal(t, config, config.WithPlacement("", nil))
+
+	node := uint32(1)
+	got := config.WithPlacement("4-7", &node)
+	assert.Equal(t, PlacementConfig{HostCPUs: "4-7", NUMANode: "1"}, got.PlacementConfig)
+	assert.Equal(t, "0-3", config.PlacementConfig.HostCPUs)
+}
+
+func TestConstructQemuArgs_NUMANode(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config Config
+		want   []string
+	}{
+		{
+			desc:   "default",
+			config: Config{MemID: "ram1", MemoryConfig: MemoryConfig{Size: "2048M"}},
+			want: []string{
+				"-object memory-backend-ram,id=ram1,size=2048M,host-nodes=1,policy=bind",
+				"-machine memory-backend=ram1",
+			},
+		},
+		{
+			desc:   "SEV-SNP",
+			config: Config{EnableSEVSNP: true, MemID: "ram1", MemoryConfig: MemoryConfig{Size: "2048M"}, SEVSNPConfig: SEVSNPConfig{ID: "sev0"}},
+			want:   []string{"-object memory-backend-memfd,id=ram1,size=2048M,share=true,prealloc=false,host-nodes=1,policy=bind"},
+		},
+		{
+			desc:   "microvm",
+			config: Config{Profile: ProfileMicroVM, MemID: "ram1", MemoryConfig: MemoryConfig{Size: "512M"}},
+			want: []string{
+				"-object memory-backend-ram,id=ram1,size=512M,host-nodes=1,policy=bind",
+				"-machine memory-backend=ram1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			args := strings.Join(tc.config.ConstructQemuArgs(), " ")
+			assert.NotContains(t, args, "host-nodes")
+
+			tc.config.PlacementConfig.NUMANode = "1"
+			args = strings.Join(tc.config.ConstructQemuArgs(), " ")
+			for _, want := range tc.want {
+				assert.Contains(t, args, want)
+			}
+			assert.Equal(t, 1, strings.Count(args, "host-nodes"), args)
+		})
+	}
+}
+
+func TestPinVCPUs(t *testing.T) {
+	socket, _ := fakeQMP(t, func(c map[string]any) string {
+		if c["execute"] == qmpQueryCPUsFastCmd {
+			return `{"return": [{"cpu-index": 0, "thread-id": 1001}, {"cpu-index": 1, "thread-id": 1002}]}`
+		}
+		return `{"return": {}}`
+	})
+
+	orig := runCommand
+	defer func() { runCommand = orig }()
+
+	var calls []string
+	runCommand = func(_ bool, name string, args ...string) error {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+
+	qmp, err := dialQMP(socket, func(qmpEvent) {})
+	require.NoError(t, err)
+	defer qmp.Close()
+
+	qvm := NewVM(VMInfo{Config: Config{PlacementConfig: PlacementConfig{HostCPUs: "8,12"}}}, "cvm", slog.Default()).(*qemuVM)
+	require.NoError(t, qvm.pinVCPUs(qmp))
+	assert.Equal(t, []string{"taskset -pc 8 1001", "taskset -pc 12 1002"}, calls)
+
+	calls = nil
+	qvm = NewVM(VMInfo{}, "cvm", slog.Default()).(*qemuVM)
+	require.NoError(t, qvm.pinVCPUs(qmp))
+	assert.Empty(t, calls)
+}
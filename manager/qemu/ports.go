@@ -0,0 +1,177 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+var (
+	// ErrInvalidPortRange indicates a host port range that is not formatted as start-end within the valid ports.
+	ErrInvalidPortRange = errors.New("invalid host port range")
+	// ErrNoFreePort indicates that every port of the range is reserved or in use.
+	ErrNoFreePort = errors.New("no free host port in range")
+	// ErrPortConflict indicates a port reserved for another VM.
+	ErrPortConflict = errors.New("host port is reserved for another VM")
+)
+
+var portRange = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// portIsFree reports whether the host port can be bound, it is a variable so tests can stub it.
+var portIsFree = func(port int) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+
+	return true
+}
+
+// ParsePortRange parses a host port range formatted as start-end.
+func ParsePortRange(input string) (int, int, error) {
+	matches := portRange.FindStringSubmatch(input)
+	if matches == nil {
+		return 0, 0, errors.Wrap(ErrInvalidPortRange, fmt.Errorf("%q is not formatted as start-end", input))
+	}
+
+	start, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, 0, errors.Wrap(ErrInvalidPortRange, err)
+	}
+
+	end, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return 0, 0, errors.Wrap(ErrInvalidPortRange, err)
+	}
+
+	if start > end || !validPort(start) || !validPort(end) {
+		return 0, 0, errors.Wrap(ErrInvalidPortRange, fmt.Errorf("%d-%d must be ascending and between 1 and %d", start, end, maxPort))
+	}
+
+	return start, end, nil
+}
+
+// PortAllocator reserves the host ports the agents of the VMs are forwarded
+// on. A port is handed out to a single VM until it is released, so VMs
+// launched concurrently do not collide, and ports bound by other processes
+// are skipped.
+type PortAllocator struct {
+	mu       sync.Mutex
+	start    int
+	end      int
+	next     int
+	reserved map[int]string
+	ports    map[string]int
+}
+
+// NewPortAllocator returns an allocator for the ports of the start-end range.
+func NewPortAllocator(input string) (*PortAllocator, error) {
+	start, end, err := ParsePortRange(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PortAllocator{
+		start:    start,
+		end:      end,
+		next:     start,
+		reserved: make(map[int]string),
+		ports:    make(map[string]int),
+	}, nil
+}
+
+// Allocate reserves a free port for the VM, or returns the one it already
+// holds. Ports are handed out round-robin, so a port released by a stopped VM
+// is reused last.
+func (pa *PortAllocator) Allocate(cvmID string) (int, error) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	if port, ok := pa.ports[cvmID]; ok {
+		return port, nil
+	}
+
+	size := pa.end - pa.start + 1
+	for i := range size {
+		port := pa.start + (pa.next-pa.start+i)%size
+		if _, ok := pa.reserved[port]; ok || !portIsFree(port) {
+			continue
+		}
+
+		pa.reserve(cvmID, port)
+		pa.next = port + 1
+		if pa.next > pa.end {
+			pa.next = pa.start
+		}
+
+		return port, nil
+	}
+
+	return 0, errors.Wrap(ErrNoFreePort, fmt.Errorf("%d-%d", pa.start, pa.end))
+}
+
+// Reserve records the port a VM already uses, e.g. one restored after a
+// manager restart. The port may be outside of the range.
+func (pa *PortAllocator) Reserve(cvmID string, port int) error {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	if owner, ok := pa.reserved[port]; ok && owner != cvmID {
+		return errors.Wrap(ErrPortConflict, fmt.Errorf("port %d is reserved for %s", port, owner))
+	}
+
+	if old, ok := pa.ports[cvmID]; ok {
+		delete(pa.reserved, old)
+	}
+	pa.reserve(cvmID, port)
+
+	return nil
+}
+
+// Release frees the port of the VM, if it holds one.
+func (pa *PortAllocator) Release(cvmID string) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	if port, ok := pa.ports[cvmID]; ok {
+		delete(pa.reserved, port)
+		delete(pa.ports, cvmID)
+	}
+}
+
+// Port returns the port reserved for the VM.
+func (pa *PortAllocator) Port(cvmID string) (int, bool) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	port, ok := pa.ports[cvmID]
+
+	return port, ok
+}
+
+// Free returns the number of ports of the range that are neither reserved nor in use.
+func (pa *PortAllocator) Free() int {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	free := 0
+	for port := pa.start; port <= pa.end; port++ {
+		if _, ok := pa.reserved[port]; !ok && portIsFree(port) {
+			free++
+		}
+	}
+
+	return free
+}
+
+func (pa *PortAllocator) reserve(cvmID string, port int) {
+	pa.reserved[port] = cvmID
+	pa.ports[cvmID] = port
+}
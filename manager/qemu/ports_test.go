@@ -0,0 +1,169 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPorts marks the given ports as bound by other processes.
+func stubPorts(t *testing.T, inUse ...int) {
+	busy := make(map[int]bool)
+	for _, port := range inUse {
+		busy[port] = true
+	}
+
+	defer func(f func(int) bool) { t.Cleanup(func() { portIsFree = f }) }(portIsFree)
+	portIsFree = func(port int) bool { return !busy[port] }
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{"Valid range", "1-5", 1, 5, false},
+		{"Single port", "6100-6100", 6100, 6100, false},
+		{"Invalid format", "1:5", 0, 0, true},
+		{"Start greater than end", "5-1", 0, 0, true},
+		{"Non-numeric input", "a-b", 0, 0, true},
+		{"Single number", "5", 0, 0, true},
+		{"Port zero", "0-5", 0, 0, true},
+		{"Port out of range", "65000-70000", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParsePortRange(tt.input)
+			if tt.wantErr {
+				assert.True(t, errors.Contains(err, ErrInvalidPortRange), "expected %v, got %v", ErrInvalidPortRange, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
+
+func TestPortAllocatorAllocate(t *testing.T) {
+	stubPorts(t, 6101)
+
+	ports, err := NewPortAllocator("6100-6103")
+	require.NoError(t, err)
+
+	port, err := ports.Allocate("vm1")
+	require.NoError(t, err)
+	assert.Equal(t, 6100, port)
+
+	port, err = ports.Allocate("vm1")
+	require.NoError(t, err)
+	assert.Equal(t, 6100, port, "a VM keeps its port")
+
+	port, err = ports.Allocate("vm2")
+	require.NoError(t, err)
+	assert.Equal(t, 6102, port, "ports in use are skipped")
+
+	port, err = ports.Allocate("vm3")
+	require.NoError(t, err)
+	assert.Equal(t, 6103, port)
+	assert.Equal(t, 0, ports.Free())
+
+	_, err = ports.Allocate("vm4")
+	assert.True(t, errors.Contains(err, ErrNoFreePort), "expected %v, got %v", ErrNoFreePort, err)
+
+	ports.Release("vm1")
+	_, ok := ports.Port("vm1")
+	assert.False(t, ok)
+	assert.Equal(t, 1, ports.Free())
+
+	port, err = ports.Allocate("vm4")
+	require.NoError(t, err)
+	assert.Equal(t, 6100, port, "released ports are reused")
+}
+
+func TestPortAllocatorRoundRobin(t *testing.T) {
+	stubPorts(t)
+
+	ports, err := NewPortAllocator("6100-6102")
+	require.NoError(t, err)
+
+	port, err := ports.Allocate("vm1")
+	require.NoError(t, err)
+	assert.Equal(t, 6100, port)
+	ports.Release("vm1")
+
+	port, err = ports.Allocate("vm2")
+	require.NoError(t, err)
+	assert.Equal(t, 6101, port, "the port of a stopped VM is reused last")
+}
+
+func TestPortAllocatorReserve(t *testing.T) {
+	stubPorts(t)
+
+	ports, err := NewPortAllocator("6100-6102")
+	require.NoError(t, err)
+
+	require.NoError(t, ports.Reserve("vm1", 6100))
+	require.NoError(t, ports.Reserve("vm1", 6100))
+	require.NoError(t, ports.Reserve("vm2", 7020), "ports outside of the range can be reserved")
+
+	err = ports.Reserve("vm3", 6100)
+	assert.True(t, errors.Contains(err, ErrPortConflict), "expected %v, got %v", ErrPortConflict, err)
+
+	port, err := ports.Allocate("vm3")
+	require.NoError(t, err)
+	assert.Equal(t, 6101, port, "reserved ports are not allocated")
+
+	port, ok := ports.Port("vm2")
+	assert.True(t, ok)
+	assert.Equal(t, 7020, port)
+}
+
+func TestPortAllocatorConcurrent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	start := listener.Addr().(*net.TCPAddr).Port + 1
+
+	ports, err := NewPortAllocator(strconv.Itoa(start) + "-" + strconv.Itoa(start+19))
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		allocated = make(map[int]string)
+	)
+	for i := range 10 {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			port, err := ports.Allocate(id)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.NotContains(t, allocated, port, "port %d allocated twice", port)
+			allocated[port] = id
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	for port, id := range allocated {
+		reserved, ok := ports.Port(id)
+		assert.True(t, ok)
+		assert.Equal(t, port, reserved)
+	}
+}
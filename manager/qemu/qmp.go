@@ -0,0 +1,258 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	qmpTimeout            = 10 * time.Second
+	qmpRetryInterval      = time.Second
+	datasetDiskNode       = "dataset%d"
+	datasetDiskPort       = "dsport%d"
+	datasetDiskSerial     = "cocos-dataset-%d"
+	datasetDiskDriver     = "virtio-blk-pci"
+	datasetDiskFormat     = "raw"
+	qmpCapabilitiesCmd    = "qmp_capabilities"
+	qmpBlockdevAddCmd     = "blockdev-add"
+	qmpDeviceAddCmd       = "device_add"
+	qmpSystemPowerdownCmd = "system_powerdown"
+	qmpQuitCmd            = "quit"
+	qmpSystemResetCmd     = "system_reset"
+	qmpQueryStatusCmd     = "query-status"
+	qmpBlockdevFileNode   = "file"
+	qmpGuestPanickedEvent = "GUEST_PANICKED"
+	// qmpStatusRunning is the run state of a VM whose guest is executing.
+	qmpStatusRunning = "running"
+)
+
+var (
+	// ErrHotplugDisabled indicates that the VM was started without dataset disk slots.
+	ErrHotplugDisabled = errors.New("dataset disk hot-adding is disabled, set DATASET_DISK_SLOTS")
+	// ErrHotplugUnsupported indicates a microvm VM, which has no PCIe ports to hot-add dataset disks into.
+	ErrHotplugUnsupported = errors.New("dataset disks cannot be hot-added to microvm VMs")
+	// ErrNoDiskSlots indicates that all the dataset disk slots of the VM are in use.
+	ErrNoDiskSlots = errors.New("no free dataset disk slots")
+	// ErrQMPClosed indicates that QEMU closed the QMP connection, usually because the VM exited.
+	ErrQMPClosed = errors.New("QMP connection closed")
+)
+
+// DatasetDiskPort returns the ID of the PCIe root port reserved for the i-th dataset disk.
+func DatasetDiskPort(i int) string {
+	return fmt.Sprintf(datasetDiskPort, i)
+}
+
+// DatasetDiskSerial returns the serial of the i-th dataset disk, used by the agent to recognize it.
+func DatasetDiskSerial(i int) string {
+	return fmt.Sprintf(datasetDiskSerial, i)
+}
+
+// qmpClient is a minimal client of the QEMU Machine Protocol. A single
+// goroutine reads the connection, handing command responses to execute and
+// asynchronous events to the event handler.
+type qmpClient struct {
+	conn      net.Conn
+	enc       *json.Encoder
+	mu        sync.Mutex
+	responses chan qmpResponse
+	done      chan struct{}
+	onEvent   func(qmpEvent)
+}
+
+type qmpCommand struct {
+	Execute   string `json:"execute"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+type qmpResponse struct {
+	Greeting json.RawMessage `json:"QMP,omitempty"`
+	Event    string          `json:"event,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Return   json.RawMessage `json:"return,omitempty"`
+	Error    *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error,omitempty"`
+}
+
+type qmpEvent struct {
+	Event string
+	Data  json.RawMessage
+}
+
+// dialQMP connects to the QMP socket and negotiates the command mode, events
+// received on the connection are passed to onEvent when it is not nil.
+func dialQMP(socket string, onEvent func(qmpEvent)) (*qmpClient, error) {
+	conn, err := net.DialTimeout("unix", socket, qmpTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to QMP socket: %w", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(qmpTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	dec := json.NewDecoder(conn)
+
+	var greeting qmpResponse
+	if err := dec.Decode(&greeting); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read QMP greeting: %w", err)
+	}
+	if greeting.Greeting == nil {
+		conn.Close()
+		return nil, errors.New("unexpected QMP greeting")
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &qmpClient{
+		conn:      conn,
+		enc:       json.NewEncoder(conn),
+		responses: make(chan qmpResponse, 1),
+		done:      make(chan struct{}),
+		onEvent:   onEvent,
+	}
+	go c.read(dec)
+
+	if err := c.execute(qmpCapabilitiesCmd, nil, nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// read dispatches the messages received from QEMU until the connection closes.
+func (c *qmpClient) read(dec *json.Decoder) {
+	defer close(c.done)
+	defer close(c.responses)
+
+	for {
+		var res qmpResponse
+		if err := dec.Decode(&res); err != nil {
+			return
+		}
+
+		if res.Event != "" {
+			if c.onEvent != nil {
+				c.onEvent(qmpEvent{Event: res.Event, Data: res.Data})
+			}
+			continue
+		}
+
+		c.responses <- res
+	}
+}
+
+// execute runs a command and waits for its result, which is decoded into result when it is not nil.
+func (c *qmpClient) execute(command string, args, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(qmpTimeout)); err != nil {
+		return err
+	}
+
+	if err := c.enc.Encode(qmpCommand{Execute: command, Arguments: args}); err != nil {
+		return fmt.Errorf("failed to send QMP command %s: %w", command, err)
+	}
+
+	select {
+	case res, ok := <-c.responses:
+		if !ok {
+			return fmt.Errorf("failed to read QMP response to %s: %w", command, ErrQMPClosed)
+		}
+		if res.Error != nil {
+			return fmt.Errorf("QMP command %s failed: %s: %s", command, res.Error.Class, res.Error.Desc)
+		}
+		if result != nil {
+			return json.Unmarshal(res.Return, result)
+		}
+
+		return nil
+	case <-time.After(qmpTimeout):
+		// A late response would be taken for the reply to the next command.
+		c.conn.Close()
+		return fmt.Errorf("timed out waiting for QMP response to %s", command)
+	}
+}
+
+// status returns the run state of the VM, e.g. running, paused or guest-panicked.
+func (c *qmpClient) status() (string, error) {
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := c.execute(qmpQueryStatusCmd, nil, &status); err != nil {
+		return "", err
+	}
+
+	return status.Status, nil
+}
+
+// powerdown presses the ACPI power button of the VM so the guest shuts down cleanly.
+func (c *qmpClient) powerdown() error {
+	return c.execute(qmpSystemPowerdownCmd, nil, nil)
+}
+
+// quit terminates QEMU without waiting for the guest.
+func (c *qmpClient) quit() error {
+	return c.execute(qmpQuitCmd, nil, nil)
+}
+
+// reset resets the VM as if its reset button was pressed.
+func (c *qmpClient) reset() error {
+	return c.execute(qmpSystemResetCmd, nil, nil)
+}
+
+// deviceAdd hot-plugs a device into the VM.
+func (c *qmpClient) deviceAdd(device map[string]any) error {
+	return c.execute(qmpDeviceAddCmd, device, nil)
+}
+
+// blockdevAdd adds a block device node that devices can be backed by.
+func (c *qmpClient) blockdevAdd(blockdev map[string]any) error {
+	return c.execute(qmpBlockdevAddCmd, blockdev, nil)
+}
+
+// addDatasetDisk attaches the image as the i-th read-only virtio disk of the VM.
+func (c *qmpClient) addDatasetDisk(i int, path string, iommuPlatform bool) error {
+	node := fmt.Sprintf(datasetDiskNode, i)
+
+	blockdev := map[string]any{
+		"driver":    datasetDiskFormat,
+		"node-name": node,
+		"read-only": true,
+		"file": map[string]any{
+			"driver":    qmpBlockdevFileNode,
+			"filename":  path,
+			"read-only": true,
+		},
+	}
+	if err := c.blockdevAdd(blockdev); err != nil {
+		return err
+	}
+
+	return c.deviceAdd(map[string]any{
+		"driver":         datasetDiskDriver,
+		"id":             node,
+		"drive":          node,
+		"bus":            DatasetDiskPort(i),
+		"serial":         DatasetDiskSerial(i),
+		"iommu_platform": iommuPlatform,
+	})
+}
+
+func (c *qmpClient) Close() error {
+	return c.conn.Close()
+}
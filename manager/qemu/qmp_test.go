@@ -0,0 +1,308 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+)
+
+// fakeQMP serves QMP on a unix socket, answering every command with the reply
+// returned by reply and recording the commands it received.
+func fakeQMP(t *testing.T, reply func(cmd map[string]any) string) (string, <-chan map[string]any) {
+	socket := filepath.Join(t.TempDir(), "qmp.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	cmds := make(chan map[string]any, 16)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprintln(conn, `{"QMP": {"version": {}, "capabilities": []}}`)
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					var cmd map[string]any
+					if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+						return
+					}
+					cmds <- cmd
+					fmt.Fprintln(conn, reply(cmd))
+				}
+			}()
+		}
+	}()
+
+	return socket, cmds
+}
+
+func TestAttachDisk(t *testing.T) {
+	socket, cmds := fakeQMP(t, func(cmd map[string]any) string {
+		if cmd["execute"] == qmpDeviceAddCmd {
+			return `{"event": "DEVICE_ADDED", "data": {}}` + "\n" + `{"return": {}}`
+		}
+		return `{"return": {}}`
+	})
+
+	vmi := VMInfo{Config: Config{EnableSEVSNP: true, QMPSocket: socket, DatasetDiskConfig: DatasetDiskConfig{DiskSlots: 1}}}
+	qvm := NewVM(vmi, "cvm", slog.Default()).(*qemuVM)
+
+	require.NoError(t, qvm.AttachDisk("/data/dataset.img"))
+
+	executed := []string{}
+	var device map[string]any
+	for range 3 {
+		cmd := <-cmds
+		executed = append(executed, cmd["execute"].(string))
+		if cmd["execute"] == qmpDeviceAddCmd {
+			device = cmd["arguments"].(map[string]any)
+		}
+	}
+	assert.Equal(t, []string{qmpCapabilitiesCmd, qmpBlockdevAddCmd, qmpDeviceAddCmd}, executed)
+	assert.Equal(t, "cocos-dataset-0", device["serial"])
+	assert.Equal(t, "dsport0", device["bus"])
+	assert.Equal(t, true, device["iommu_platform"])
+
+	assert.ErrorIs(t, qvm.AttachDisk("/data/other.img"), ErrNoDiskSlots)
+}
+
+func TestAttachDiskSandbox(t *testing.T) {
+	socket, cmds := fakeQMP(t, func(cmd map[string]any) string {
+		if cmd["execute"] == qmpDeviceAddCmd {
+			return `{"event": "DEVICE_ADDED", "data": {}}` + "\n" + `{"return": {}}`
+		}
+		return `{"return": {}}`
+	})
+
+	orig := runCommand
+	defer func() { runCommand = orig }()
+
+	var calls []string
+	runCommand = func(_ bool, name string, args ...string) error {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+
+	cfg := Config{
+		QMPSocket:         socket,
+		DatasetDiskConfig: DatasetDiskConfig{DiskSlots: 1, Dir: "/data"},
+		SandboxConfig:     SandboxConfig{User: "cocos-qemu", AppArmor: true, AppArmorProfile: "cocos-qemu-1"},
+	}
+	qvm := NewVM(VMInfo{Config: cfg}, "cvm", slog.Default()).(*qemuVM)
+
+	require.NoError(t, qvm.AttachDisk("/data/dataset.img"))
+	assert.Equal(t, []string{"setfacl --modify u:cocos-qemu:r /data/dataset.img"}, calls)
+
+	executed := []string{}
+	for range 3 {
+		executed = append(executed, (<-cmds)["execute"].(string))
+	}
+	assert.Equal(t, []string{qmpCapabilitiesCmd, qmpBlockdevAddCmd, qmpDeviceAddCmd}, executed)
+
+	profile, err := AppArmorProfile(cfg.SandboxConfig.AppArmorProfile, "/usr/bin/qemu-system-x86_64", cfg)
+	require.NoError(t, err)
+	assert.Contains(t, profile, `"/data/**" r,`, "QEMU can open the disk images of the dataset disk directory")
+
+	runCommand = func(bool, string, ...string) error { return fmt.Errorf("operation not supported") }
+	qvm = NewVM(VMInfo{Config: cfg}, "cvm", slog.Default()).(*qemuVM)
+	assert.ErrorContains(t, qvm.AttachDisk("/data/dataset.img"), "failed to give the sandbox user access to the disk image")
+	assert.Empty(t, cmds, "the disk is not hot-added")
+}
+
+func TestAttachDiskErrors(t *testing.T) {
+	socket, _ := fakeQMP(t, func(cmd map[string]any) string {
+		if cmd["execute"] == qmpBlockdevAddCmd {
+			return `{"error": {"class": "GenericError", "desc": "Could not open '/data/dataset.img'"}}`
+		}
+		return `{"return": {}}`
+	})
+
+	cases := []struct {
+		desc   string
+		slots  int
+		socket string
+		err    string
+	}{
+		{
+			desc: "hotplug disabled",
+			err:  ErrHotplugDisabled.Error(),
+		},
+		{
+			desc:   "QMP socket unavailable",
+			slots:  1,
+			socket: filepath.Join(t.TempDir(), "missing.sock"),
+			err:    "failed to connect to QMP socket",
+		},
+		{
+			desc:   "QMP command failure",
+			slots:  1,
+			socket: socket,
+			err:    "QMP command blockdev-add failed: GenericError: Could not open '/data/dataset.img'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := Config{QMPSocket: tc.socket, DatasetDiskConfig: DatasetDiskConfig{DiskSlots: tc.slots}}
+			var v vm.VM = NewVM(VMInfo{Config: cfg}, "cvm", slog.Default())
+			err := v.AttachDisk("/data/dataset.img")
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	cases := []struct {
+		desc     string
+		cfg      Config
+		executed []string
+		err      error
+	}{
+		{
+			desc:     "reset over QMP",
+			executed: []string{qmpCapabilitiesCmd, qmpSystemResetCmd},
+		},
+		{
+			desc: "SEV-SNP VM",
+			cfg:  Config{EnableSEVSNP: true},
+			err:  ErrResetUnsupported,
+		},
+		{
+			desc: "TDX VM",
+			cfg:  Config{EnableTDX: true},
+			err:  ErrResetUnsupported,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			socket, cmds := fakeQMP(t, func(cmd map[string]any) string { return `{"return": {}}` })
+			tc.cfg.QMPSocket = socket
+			v := NewVM(VMInfo{Config: tc.cfg}, "cvm", slog.Default())
+
+			assert.ErrorIs(t, v.Restart(), tc.err)
+
+			executed := []string{}
+			for range tc.executed {
+				executed = append(executed, (<-cmds)["execute"].(string))
+			}
+			assert.Equal(t, append([]string{}, tc.executed...), executed)
+			assert.Empty(t, cmds, "no other QMP command is executed")
+		})
+	}
+}
+
+func TestStopPowerdown(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   string
+		executed []string
+	}{
+		{
+			desc:     "running guest",
+			status:   "running",
+			executed: []string{qmpCapabilitiesCmd, qmpQueryStatusCmd, qmpSystemPowerdownCmd},
+		},
+		{
+			desc:     "panicked guest",
+			status:   "guest-panicked",
+			executed: []string{qmpCapabilitiesCmd, qmpQueryStatusCmd, qmpQuitCmd},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cmd := exec.Command("sleep", "30")
+			require.NoError(t, cmd.Start())
+
+			socket, cmds := fakeQMP(t, func(c map[string]any) string {
+				switch c["execute"] {
+				case qmpQueryStatusCmd:
+					return fmt.Sprintf(`{"return": {"status": %q, "running": false}}`, tc.status)
+				case qmpSystemPowerdownCmd, qmpQuitCmd:
+					_ = cmd.Process.Signal(syscall.SIGTERM)
+				}
+				return `{"return": {}}`
+			})
+
+			sm := new(mocks.StateMachine)
+			sm.On("Transition", pkgmanager.StopComputationRun).Return(nil)
+
+			qvm := &qemuVM{
+				vmi:          VMInfo{Config: Config{QMPSocket: socket}},
+				cmd:          cmd,
+				logger:       slog.Default(),
+				StateMachine: sm,
+			}
+
+			require.NoError(t, qvm.Stop())
+
+			executed := []string{}
+			for range tc.executed {
+				executed = append(executed, (<-cmds)["execute"].(string))
+			}
+			assert.Equal(t, tc.executed, executed)
+		})
+	}
+}
+
+func TestGuestPanickedEvent(t *testing.T) {
+	socket, _ := fakeQMP(t, func(c map[string]any) string {
+		if c["execute"] == qmpCapabilitiesCmd {
+			return `{"return": {}}` + "\n" + `{"event": "GUEST_PANICKED", "data": {"action": "pause"}}`
+		}
+		return `{"return": {}}`
+	})
+
+	cmd := exec.Command("sleep", "30")
+	require.NoError(t, cmd.Start())
+
+	qvm := NewVM(VMInfo{Config: Config{QMPSocket: socket}}, "cvm", slog.Default()).(*qemuVM)
+	qvm.cmd = cmd
+	go qvm.monitor()
+
+	select {
+	case event := <-qvm.Events():
+		assert.Equal(t, vm.EventGuestPanicked, event.EventType)
+		assert.Equal(t, "cvm", event.ComputationId)
+		assert.JSONEq(t, `{"action": "pause"}`, string(event.Details))
+	case <-time.After(5 * time.Second):
+		t.Fatal("guest panic was not reported")
+	}
+
+	require.NoError(t, cmd.Process.Kill())
+	_, _ = cmd.Process.Wait()
+
+	qvm.qmpMu.Lock()
+	if qvm.qmp != nil {
+		qvm.qmp.Close()
+	}
+	qvm.qmpMu.Unlock()
+
+	select {
+	case _, ok := <-qvm.Events():
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("events were not closed after the VM exited")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"text/template"
+)
+
+const (
+	appArmorProfilePrefix = "cocos-qemu-"
+	appArmorProfileFile   = "%s/%s.apparmor"
+	// seccompSandbox denies QEMU obsolete system calls, changing its privileges,
+	// spawning processes and changing its resource limits. Installing the filter
+	// also sets no_new_privs on the QEMU process.
+	seccompSandbox = "on,obsolete=deny,elevateprivileges=deny,spawn=deny,resourcecontrol=deny"
+)
+
+// SandboxConfig confines the QEMU processes, limiting what a guest escaping
+// QEMU can do on a multi-tenant host.
+type SandboxConfig struct {
+	// Seccomp enables the QEMU seccomp filter.
+	Seccomp bool `env:"SANDBOX_SECCOMP" envDefault:"false"`
+	// User is the unprivileged user QEMU runs as, without the ability to gain
+	// privileges. QEMU runs as the manager user when it is empty.
+	User string `env:"SANDBOX_USER" envDefault:""`
+	// AppArmor confines QEMU to an AppArmor profile generated for each VM.
+	AppArmor bool `env:"SANDBOX_APPARMOR" envDefault:"false"`
+	// AppArmorProfile is the name of the profile generated for the VM.
+	AppArmorProfile string
+}
+
+var appArmorTemplate = template.Must(template.New("apparmor").Parse(`#include <tunables/global>
+
+profile {{.Name}} flags=(attach_disconnected) {
+  #include <abstractions/base>
+
+  capability ipc_lock,
+  signal (receive) peer=unconfined,
+  network unix,
+
+  "{{.Qemu}}" mrix,
+  /usr/share/qemu/** r,
+  /usr/share/seabios/** r,
+  /usr/share/OVMF/** r,
+  /sys/devices/system/** r,
+  /sys/kernel/mm/** r,
+  @{PROC}/@{pid}/** r,
+
+  /dev/kvm rw,
+  /dev/sev rw,
+  /dev/vhost-vsock rw,
+  /dev/vhost-net rw,
+  /dev/net/tun rw,
+  /dev/ptmx rw,
+  /dev/pts/* rw,
+{{if .VFIO}}
+  /dev/vfio/* rw,
+  /sys/bus/pci/devices/ r,
+  /sys/devices/pci*/** rw,
+  /sys/kernel/iommu_groups/** r,{{end}}
+{{range .Read}}
+  "{{.}}" r,{{end}}
+{{range .ReadDirs}}
+  "{{.}}/" r,
+  "{{.}}/**" r,{{end}}
+{{range .Write}}
+  "{{.}}" rwk,{{end}}
+{{range .Dirs}}
+  "{{.}}/" r,
+  "{{.}}/**" rwk,{{end}}
+}
+`))
+
+// AppArmorProfile returns an AppArmor profile allowing QEMU to access only the
+// devices and the files of the VM, including the VFIO groups of its GPUs.
+func AppArmorProfile(name, qemu string, config Config) (string, error) {
+	data := struct {
+		Name     string
+		Qemu     string
+		VFIO     bool
+		Read     []string
+		ReadDirs []string
+		Write    []string
+		Dirs     []string
+	}{
+		Name: name,
+		Qemu: qemu,
+		VFIO: len(config.GPUConfig.Devices) > 0,
+	}
+
+	for _, f := range []string{
+		config.DiskImgConfig.KernelFile,
+		config.DiskImgConfig.RootFsFile,
+		config.OVMFCodeConfig.File,
+		config.SEVSNPConfig.OVMF,
+		config.IGVMConfig.File,
+		config.TDXConfig.OVMF,
+	} {
+		if f != "" {
+			data.Read = append(data.Read, f)
+		}
+	}
+
+	// Dataset disks are hot-added from the dataset disk directory, AppArmor
+	// matches the path with symlinks resolved like the manager does.
+	if dir := config.DatasetDiskConfig.Dir; dir != "" && config.DatasetDiskConfig.DiskSlots > 0 {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		data.ReadDirs = append(data.ReadDirs, filepath.Clean(dir))
+	}
+
+	for _, f := range []string{ovmfVarsFile(config), config.QMPSocket} {
+		if f != "" {
+			data.Write = append(data.Write, f)
+		}
+	}
+
+	for _, d := range []string{config.CertsMount, config.EnvMount, config.CheckpointMount} {
+		if d != "" {
+			data.Dirs = append(data.Dirs, filepath.Clean(d))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := appArmorTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// loadAppArmorProfile writes the profile of the VM and loads it into the kernel.
+func loadAppArmorProfile(name, qemu string, config Config) error {
+	profile, err := AppArmorProfile(name, qemu, config)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf(appArmorProfileFile, tmpDir, name)
+	if err := os.WriteFile(path, []byte(profile), 0o644); err != nil {
+		return err
+	}
+
+	return runCommand(config.UseSudo, "apparmor_parser", "--replace", path)
+}
+
+// unloadAppArmorProfile removes the profile of the VM from the kernel.
+func unloadAppArmorProfile(name string, useSudo bool) error {
+	path := fmt.Sprintf(appArmorProfileFile, tmpDir, name)
+	defer os.Remove(path)
+
+	return runCommand(useSudo, "apparmor_parser", "--remove", path)
+}
+
+// sandboxUserFiles gives the sandbox user ownership of the files QEMU writes.
+func sandboxUserFiles(config Config) error {
+	var paths []string
+	for _, p := range []string{ovmfVarsFile(config), config.CertsMount, config.EnvMount, config.CheckpointMount} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return runCommand(config.UseSudo, "chown", append([]string{"-R", config.SandboxConfig.User + ":"}, paths...)...)
+}
+
+// sandboxUserDisk gives the sandbox user read access to a dataset disk image
+// before QEMU opens it, without giving it the image.
+func sandboxUserDisk(config Config, path string) error {
+	return runCommand(config.UseSudo, "setfacl", "--modify", "u:"+config.SandboxConfig.User+":r", path)
+}
+
+// ovmfVarsFile returns the per-VM copy of the OVMF vars, confidential VMs have none.
+func ovmfVarsFile(config Config) string {
+	if config.EnableSEVSNP || config.EnableTDX {
+		return ""
+	}
+
+	return config.OVMFVarsConfig.File
+}
+
+// sandboxCommand wraps the QEMU command line with the commands that drop its
+// privileges and confine it to its AppArmor profile.
+func sandboxCommand(config Config, cmdline []string) ([]string, error) {
+	if config.SandboxConfig.AppArmorProfile != "" {
+		cmdline = append([]string{"aa-exec", "--profile", config.SandboxConfig.AppArmorProfile, "--"}, cmdline...)
+	}
+
+	if name := config.SandboxConfig.User; name != "" {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up sandbox user: %w", err)
+		}
+
+		cmdline = append([]string{"setpriv", "--reuid", u.Uid, "--regid", u.Gid, "--init-groups", "--no-new-privs", "--"}, cmdline...)
+	}
+
+	return cmdline, nil
+}
@@ -0,0 +1,203 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstructQemuArgs_Sandbox(t *testing.T) {
+	config := Config{}
+	assert.NotContains(t, config.ConstructQemuArgs(), "-sandbox")
+
+	config.SandboxConfig.Seccomp = true
+	args := strings.Join(config.ConstructQemuArgs(), " ")
+	assert.Contains(t, args, "-sandbox on,obsolete=deny,elevateprivileges=deny,spawn=deny,resourcecontrol=deny")
+}
+
+func TestAppArmorProfile(t *testing.T) {
+	cases := []struct {
+		desc    string
+		config  Config
+		allowed []string
+		denied  []string
+	}{
+		{
+			desc: "VM without TEE",
+			config: Config{
+				DiskImgConfig:   DiskImgConfig{KernelFile: "/img/bzImage", RootFsFile: "/img/rootfs.cpio.gz"},
+				OVMFCodeConfig:  OVMFCodeConfig{File: "/usr/share/OVMF/OVMF_CODE.fd"},
+				OVMFVarsConfig:  OVMFVarsConfig{File: "/tmp/OVMF_VARS-1.fd"},
+				QMPSocket:       "/tmp/qmp-1.sock",
+				CertsMount:      "/tmp/certs1/",
+				EnvMount:        "/tmp/env1",
+				CheckpointMount: "/var/lib/cocos/checkpoints",
+			},
+			allowed: []string{
+				`"/img/bzImage" r,`,
+				`"/img/rootfs.cpio.gz" r,`,
+				`"/tmp/OVMF_VARS-1.fd" rwk,`,
+				`"/tmp/qmp-1.sock" rwk,`,
+				`"/tmp/certs1/**" rwk,`,
+				`"/tmp/env1/**" rwk,`,
+				`"/var/lib/cocos/checkpoints/**" rwk,`,
+			},
+			denied: []string{`/dev/vfio/* rw,`},
+		},
+		{
+			desc: "VM with GPUs",
+			config: Config{
+				DiskImgConfig: DiskImgConfig{KernelFile: "/img/bzImage", RootFsFile: "/img/rootfs.cpio.gz"},
+				GPUConfig:     GPUConfig{Devices: []string{"0000:41:00.0"}},
+			},
+			allowed: []string{`/dev/vfio/* rw,`, `/sys/devices/pci*/** rw,`},
+		},
+		{
+			desc: "VM with dataset disk slots",
+			config: Config{
+				DiskImgConfig:     DiskImgConfig{KernelFile: "/img/bzImage", RootFsFile: "/img/rootfs.cpio.gz"},
+				DatasetDiskConfig: DatasetDiskConfig{DiskSlots: 2, Dir: "/srv/cocos/datasets/"},
+			},
+			allowed: []string{`"/srv/cocos/datasets/" r,`, `"/srv/cocos/datasets/**" r,`},
+			denied:  []string{`"/srv/cocos/datasets/**" rwk,`},
+		},
+		{
+			desc: "VM without dataset disk slots",
+			config: Config{
+				DiskImgConfig:     DiskImgConfig{KernelFile: "/img/bzImage", RootFsFile: "/img/rootfs.cpio.gz"},
+				DatasetDiskConfig: DatasetDiskConfig{Dir: "/srv/cocos/datasets"},
+			},
+			denied: []string{`"/srv/cocos/datasets/**" r,`},
+		},
+		{
+			desc: "SEV-SNP VM",
+			config: Config{
+				EnableSEVSNP:   true,
+				DiskImgConfig:  DiskImgConfig{KernelFile: "/img/bzImage", RootFsFile: "/img/rootfs.cpio.gz"},
+				IGVMConfig:     IGVMConfig{File: "/img/coconut-qemu.igvm"},
+				OVMFVarsConfig: OVMFVarsConfig{File: "/usr/share/OVMF/OVMF_VARS.fd"},
+			},
+			allowed: []string{`"/img/coconut-qemu.igvm" r,`},
+			denied:  []string{`"/usr/share/OVMF/OVMF_VARS.fd" rwk,`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			profile, err := AppArmorProfile("cocos-qemu-1", "/usr/bin/qemu-system-x86_64", tc.config)
+			require.NoError(t, err)
+
+			assert.Contains(t, profile, "profile cocos-qemu-1 ")
+			assert.Contains(t, profile, `"/usr/bin/qemu-system-x86_64" mrix,`)
+			for _, rule := range tc.allowed {
+				assert.Contains(t, profile, rule)
+			}
+			for _, rule := range tc.denied {
+				assert.NotContains(t, profile, rule)
+			}
+		})
+	}
+}
+
+func TestExecutableAndArgs_Sandbox(t *testing.T) {
+	root, err := user.Lookup("root")
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc    string
+		sandbox SandboxConfig
+		sudo    bool
+		prefix  []string
+		err     bool
+	}{
+		{
+			desc:   "without sandbox",
+			prefix: []string{},
+		},
+		{
+			desc:    "AppArmor profile",
+			sandbox: SandboxConfig{AppArmor: true, AppArmorProfile: "cocos-qemu-1"},
+			prefix:  []string{"aa-exec", "--profile", "cocos-qemu-1", "--"},
+		},
+		{
+			desc:    "sandbox user with sudo",
+			sandbox: SandboxConfig{User: "root", AppArmor: true, AppArmorProfile: "cocos-qemu-1"},
+			sudo:    true,
+			prefix: []string{
+				"sudo",
+				"setpriv", "--reuid", root.Uid, "--regid", root.Gid, "--init-groups", "--no-new-privs", "--",
+				"aa-exec", "--profile", "cocos-qemu-1", "--",
+			},
+		},
+		{
+			desc:    "unknown sandbox user",
+			sandbox: SandboxConfig{User: "cocos-missing-user"},
+			err:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			v := NewVM(VMInfo{Config: Config{QemuBinPath: "echo", UseSudo: tc.sudo, SandboxConfig: tc.sandbox}}, testComputationID, slog.Default()).(*qemuVM)
+
+			exe, args, err := v.executableAndArgs()
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			cmdline := append([]string{exe}, args...)
+			require.Greater(t, len(cmdline), len(tc.prefix))
+			assert.Equal(t, tc.prefix, cmdline[:len(tc.prefix)])
+			assert.True(t, strings.HasSuffix(cmdline[len(tc.prefix)], "echo"))
+		})
+	}
+}
+
+func TestStartSandbox(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-ovmf-vars")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	orig := runCommand
+	defer func() { runCommand = orig }()
+
+	var calls []string
+	runCommand = func(_ bool, name string, args ...string) error {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		if name == "apparmor_parser" && args[0] == "--replace" {
+			return fmt.Errorf("apparmor is disabled")
+		}
+		return nil
+	}
+
+	config := VMInfo{Config: Config{
+		OVMFVarsConfig: OVMFVarsConfig{File: tmpFile.Name()},
+		QemuBinPath:    "echo",
+		SandboxConfig:  SandboxConfig{User: "root", AppArmor: true},
+	}}
+	v := NewVM(config, testComputationID, slog.Default()).(*qemuVM)
+
+	err = v.Start()
+	assert.ErrorContains(t, err, "failed to load AppArmor profile")
+	assert.Empty(t, v.vmi.Config.SandboxConfig.AppArmorProfile)
+
+	require.Len(t, calls, 2)
+	assert.True(t, strings.HasPrefix(calls[0], "chown -R root: "+tmpDir), calls[0])
+	assert.True(t, strings.HasPrefix(calls[1], "apparmor_parser --replace "+tmpDir+"/"+appArmorProfilePrefix), calls[1])
+
+	profile := strings.TrimPrefix(calls[1], "apparmor_parser --replace ")
+	defer os.Remove(profile)
+	content, err := os.ReadFile(profile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "/tmp/")
+}
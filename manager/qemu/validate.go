@@ -0,0 +1,160 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+const (
+	// minGuestCID is the lowest vsock CID that can be assigned to a guest, 0-2 are reserved.
+	minGuestCID = 3
+	maxGuestCID = 1<<32 - 2
+	maxVLAN     = 4094
+	maxPort     = 65535
+	maxCBitPos  = 63
+)
+
+// ErrInvalidConfig indicates a QEMU configuration that cannot launch a VM.
+var ErrInvalidConfig = errors.New("invalid QEMU configuration")
+
+var memorySize = regexp.MustCompile(`^[0-9]+[KMGT]?$`)
+
+// Validate checks that the configuration describes a VM QEMU can launch.
+func (config Config) Validate() error {
+	if config.QemuBinPath == "" {
+		return invalid("QEMU binary path is empty")
+	}
+
+	if config.EnableSEVSNP && config.EnableTDX {
+		return invalid("SEV-SNP and TDX cannot be enabled at the same time")
+	}
+
+	switch config.Profile {
+	case "", ProfileDefault:
+	case ProfileMicroVM:
+		if config.EnableSEVSNP || config.EnableTDX {
+			return invalid("the %s profile does not support confidential computing", ProfileMicroVM)
+		}
+		if config.MicroVMConfig.Machine == "" {
+			return invalid("microvm machine is empty")
+		}
+	default:
+		return invalid("unknown machine profile %q, expected %s or %s", config.Profile, ProfileDefault, ProfileMicroVM)
+	}
+
+	if config.SMPCount < 1 {
+		return invalid("SMP count %d must be at least 1", config.SMPCount)
+	}
+	if config.MaxCPUs < config.SMPCount {
+		return invalid("max CPUs %d is lower than SMP count %d", config.MaxCPUs, config.SMPCount)
+	}
+
+	if err := config.PlacementConfig.validate(config.SMPCount); err != nil {
+		return err
+	}
+
+	if err := config.GPUConfig.validate(); err != nil {
+		return err
+	}
+
+	if !memorySize.MatchString(config.MemoryConfig.Size) {
+		return invalid("memory size %q is not a number with an optional K, M, G or T suffix", config.MemoryConfig.Size)
+	}
+	if !memorySize.MatchString(config.MemoryConfig.Max) {
+		return invalid("max memory %q is not a number with an optional K, M, G or T suffix", config.MemoryConfig.Max)
+	}
+	if config.MemoryConfig.Slots < 0 {
+		return invalid("memory slots %d cannot be negative", config.MemoryConfig.Slots)
+	}
+
+	if config.DiskImgConfig.KernelFile == "" || config.DiskImgConfig.RootFsFile == "" {
+		return invalid("kernel and root file system images are required")
+	}
+
+	switch {
+	case config.EnableSEVSNP:
+		if config.IGVMConfig.File == "" && config.SEVSNPConfig.OVMF == "" {
+			return invalid("IGVM file or OVMF file is required for SEV-SNP")
+		}
+		if config.SEVSNPConfig.CBitPos < 0 || config.SEVSNPConfig.CBitPos > maxCBitPos {
+			return invalid("SEV-SNP C-bit position %d is out of range", config.SEVSNPConfig.CBitPos)
+		}
+		if config.SEVSNPConfig.ReducedPhysBits < 1 {
+			return invalid("SEV-SNP reduced physical bits %d must be at least 1", config.SEVSNPConfig.ReducedPhysBits)
+		}
+	case config.EnableTDX:
+		if config.TDXConfig.OVMF == "" {
+			return invalid("OVMF file is required for TDX")
+		}
+		if !validPort(config.TDXConfig.QuoteGenerationPort) {
+			return invalid("TDX quote generation port %d is out of range", config.TDXConfig.QuoteGenerationPort)
+		}
+	case config.MicroVM():
+		// The kernel is booted directly, without firmware.
+	default:
+		if config.OVMFCodeConfig.File == "" || config.OVMFVarsConfig.File == "" {
+			return invalid("OVMF code and vars files are required")
+		}
+	}
+
+	if err := config.NetDevConfig.validate(); err != nil {
+		return err
+	}
+
+	if cid := config.VSockConfig.GuestCID; cid != 0 && (cid < minGuestCID || cid > maxGuestCID) {
+		return invalid("vsock guest CID %d must be between %d and %d", cid, minGuestCID, maxGuestCID)
+	}
+
+	if _, err := config.KernelCmdline(); err != nil {
+		return errors.Wrap(ErrInvalidConfig, err)
+	}
+
+	return nil
+}
+
+func (cfg NetDevConfig) validate() error {
+	if cfg.ID == "" {
+		return invalid("network device ID is empty")
+	}
+
+	switch cfg.Mode {
+	case NetModeUser:
+		if !validPort(cfg.HostFwdAgent) || !validPort(cfg.GuestFwdAgent) {
+			return invalid("agent forwarding ports %d:%d are out of range", cfg.HostFwdAgent, cfg.GuestFwdAgent)
+		}
+	case NetModeBridge:
+		if cfg.Bridge == "" {
+			return invalid("bridge name is required in bridge network mode")
+		}
+		if !validPort(cfg.GuestFwdAgent) {
+			return invalid("guest agent port %d is out of range", cfg.GuestFwdAgent)
+		}
+	default:
+		return invalid("unknown network mode %q, expected %s or %s", cfg.Mode, NetModeUser, NetModeBridge)
+	}
+
+	if cfg.MAC != "" {
+		if _, err := net.ParseMAC(cfg.MAC); err != nil {
+			return errors.Wrap(ErrInvalidConfig, err)
+		}
+	}
+
+	if cfg.VLAN < 0 || cfg.VLAN > maxVLAN {
+		return invalid("VLAN %d is out of range", cfg.VLAN)
+	}
+
+	return nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= maxPort
+}
+
+func invalid(format string, args ...any) error {
+	return errors.Wrap(ErrInvalidConfig, fmt.Errorf(format, args...))
+}
@@ -0,0 +1,261 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package qemu
+
+import (
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/caarlos0/env/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	var defaults Config
+	require.NoError(t, env.Parse(&defaults))
+
+	cases := []struct {
+		desc   string
+		modify func(*Config)
+		err    error
+	}{
+		{
+			desc:   "default configuration",
+			modify: func(*Config) {},
+		},
+		{
+			desc: "SEV-SNP configuration",
+			modify: func(c *Config) {
+				c.EnableSEVSNP = true
+			},
+		},
+		{
+			desc: "SEV-SNP direct boot configuration",
+			modify: func(c *Config) {
+				c.EnableSEVSNP = true
+				c.IGVMConfig.File = ""
+				c.SEVSNPConfig.OVMF = "OVMF.amdsev.fd"
+			},
+		},
+		{
+			desc: "TDX configuration",
+			modify: func(c *Config) {
+				c.EnableTDX = true
+			},
+		},
+		{
+			desc: "bridge network with MAC and VLAN",
+			modify: func(c *Config) {
+				c.NetDevConfig.Mode = NetModeBridge
+				c.NetDevConfig.MAC = "52:54:00:12:34:56"
+				c.NetDevConfig.VLAN = 100
+			},
+		},
+		{
+			desc: "vsock enabled",
+			modify: func(c *Config) {
+				c.VSockConfig.GuestCID = 3
+			},
+		},
+		{
+			desc: "vCPUs pinned to a NUMA node",
+			modify: func(c *Config) {
+				c.PlacementConfig.HostCPUs = "8-11"
+				c.PlacementConfig.NUMANode = "1"
+			},
+		},
+		{
+			desc: "GPUs allowed",
+			modify: func(c *Config) {
+				c.GPUConfig.Allowed = []string{"0000:41:00.0", "c1:00.0"}
+			},
+		},
+		{
+			desc: "microvm profile without OVMF",
+			modify: func(c *Config) {
+				c.Profile = ProfileMicroVM
+				c.OVMFCodeConfig.File = ""
+				c.OVMFVarsConfig.File = ""
+			},
+		},
+		{
+			desc: "microvm profile with SEV-SNP",
+			modify: func(c *Config) {
+				c.Profile = ProfileMicroVM
+				c.EnableSEVSNP = true
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "unknown machine profile",
+			modify: func(c *Config) {
+				c.Profile = "firecracker"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "empty QEMU binary",
+			modify: func(c *Config) {
+				c.QemuBinPath = ""
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "SEV-SNP and TDX enabled",
+			modify: func(c *Config) {
+				c.EnableSEVSNP = true
+				c.EnableTDX = true
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "no vCPUs",
+			modify: func(c *Config) {
+				c.SMPCount = 0
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "max CPUs lower than SMP count",
+			modify: func(c *Config) {
+				c.SMPCount = 8
+				c.MaxCPUs = 4
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid memory size",
+			modify: func(c *Config) {
+				c.MemoryConfig.Size = "2 GB"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "missing OVMF code",
+			modify: func(c *Config) {
+				c.OVMFCodeConfig.File = ""
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "missing IGVM file with SEV-SNP",
+			modify: func(c *Config) {
+				c.EnableSEVSNP = true
+				c.IGVMConfig.File = ""
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "C-bit position out of range",
+			modify: func(c *Config) {
+				c.EnableSEVSNP = true
+				c.SEVSNPConfig.CBitPos = 64
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid TDX quote generation port",
+			modify: func(c *Config) {
+				c.EnableTDX = true
+				c.TDXConfig.QuoteGenerationPort = 0
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "unknown network mode",
+			modify: func(c *Config) {
+				c.NetDevConfig.Mode = "macvtap"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid forwarded port",
+			modify: func(c *Config) {
+				c.NetDevConfig.HostFwdAgent = 70000
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "bridge network without bridge",
+			modify: func(c *Config) {
+				c.NetDevConfig.Mode = NetModeBridge
+				c.NetDevConfig.Bridge = ""
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid MAC",
+			modify: func(c *Config) {
+				c.NetDevConfig.MAC = "52:54:00"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "VLAN out of range",
+			modify: func(c *Config) {
+				c.NetDevConfig.VLAN = 4095
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "fewer host CPUs than vCPUs",
+			modify: func(c *Config) {
+				c.PlacementConfig.HostCPUs = "0,1"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid host CPU list",
+			modify: func(c *Config) {
+				c.PlacementConfig.HostCPUs = "3-0"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid NUMA node",
+			modify: func(c *Config) {
+				c.PlacementConfig.NUMANode = "-1"
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid GPU address",
+			modify: func(c *Config) {
+				c.GPUConfig.Allowed = []string{"41:00"}
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "GPU allowed twice",
+			modify: func(c *Config) {
+				c.GPUConfig.Allowed = []string{"41:00.0", "0000:41:00.0"}
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "reserved vsock CID",
+			modify: func(c *Config) {
+				c.VSockConfig.GuestCID = 2
+			},
+			err: ErrInvalidConfig,
+		},
+		{
+			desc: "invalid kernel parameters",
+			modify: func(c *Config) {
+				c.KernelParams = map[string]string{"AGENT_LOG_LEVEL": `in"fo`}
+			},
+			err: ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := defaults
+			tc.modify(&config)
+
+			err := config.Validate()
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
@@ -3,11 +3,16 @@
 package qemu
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,6 +20,7 @@ import (
 	"github.com/ultravioletrs/cocos/internal"
 	"github.com/ultravioletrs/cocos/manager/vm"
 	"github.com/ultravioletrs/cocos/pkg/manager"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -24,8 +30,23 @@ const (
 	tmpDir          = "/tmp"
 	interval        = 5 * time.Second
 	shutdownTimeout = 30 * time.Second
+	eventsBuffer    = 16
+	// adoptedPollInterval is how often an adopted process is checked for
+	// having exited.
+	adoptedPollInterval = 100 * time.Millisecond
 )
 
+// ErrNotVMProcess indicates a process that is not the QEMU process of the VM,
+// e.g. one that reused the PID of a VM that exited while the manager was down.
+var ErrNotVMProcess = errors.New("process is not the QEMU process of the VM")
+
+// ErrResetUnsupported indicates a SEV-SNP or TDX VM, whose guest a system reset
+// terminates instead of rebooting since the TEE context is not recreated.
+var ErrResetUnsupported = errors.New("confidential VMs cannot be reset")
+
+// procDir is where the command lines of processes are read from.
+var procDir = "/proc"
+
 type VMInfo struct {
 	Config    Config
 	LaunchTCB uint64 `env:"LAUNCH_TCB" envDefault:"0"`
@@ -37,14 +58,30 @@ type qemuVM struct {
 	cvmId  string
 	logger *slog.Logger
 	vm.StateMachine
+	// adopted is set for VMs started by a previous manager process, whose
+	// QEMU process is not a child of the manager and cannot be waited for.
+	adopted bool
+
+	disksMu sync.Mutex
+	disks   int
+
+	qmpMu sync.Mutex
+	qmp   *qmpClient
+
+	eventsMu     sync.Mutex
+	events       chan vm.Event
+	eventsClosed bool
 }
 
+var _ vm.EventSource = (*qemuVM)(nil)
+
 func NewVM(config any, cvmId string, logger *slog.Logger) vm.VM {
 	return &qemuVM{
 		vmi:          config.(VMInfo),
 		cvmId:        cvmId,
 		StateMachine: vm.NewStateMachine(),
 		logger:       logger,
+		events:       make(chan vm.Event, eventsBuffer),
 	}
 }
 
@@ -52,6 +89,7 @@ func (v *qemuVM) Start() (err error) {
 	defer func() {
 		if err == nil {
 			go v.checkVMProcessPeriodically()
+			go v.monitor()
 		}
 	}()
 	// Create unique qemu device identifiers
@@ -64,6 +102,8 @@ func (v *qemuVM) Start() (err error) {
 	v.vmi.Config.SEVSNPConfig.ID = fmt.Sprintf("%s-%s", v.vmi.Config.SEVSNPConfig.ID, id)
 	v.vmi.Config.TDXConfig.ID = fmt.Sprintf("%s-%s", v.vmi.Config.TDXConfig.ID, id)
 
+	v.vmi.Config.QMPSocket = fmt.Sprintf("%s/qmp-%s.sock", tmpDir, id)
+
 	if !v.vmi.Config.EnableSEVSNP && !v.vmi.Config.EnableTDX {
 		// Copy firmware vars file.
 		srcFile := v.vmi.Config.OVMFVarsConfig.File
@@ -75,16 +115,77 @@ func (v *qemuVM) Start() (err error) {
 		v.vmi.Config.OVMFVarsConfig.File = dstFile
 	}
 
+	if v.vmi.Config.NetDevConfig.Mode == NetModeBridge {
+		if v.vmi.Config.NetDevConfig.MAC == "" {
+			if v.vmi.Config.NetDevConfig.MAC, err = GenerateMAC(); err != nil {
+				return err
+			}
+		}
+
+		v.vmi.Config.NetDevConfig.Tap = TapName(v.cvmId)
+		if err = setupTap(v.vmi.Config.NetDevConfig, v.vmi.Config.UseSudo); err != nil {
+			return fmt.Errorf("failed to set up tap device: %v", err)
+		}
+
+		defer func() {
+			if err != nil {
+				if tapErr := teardownTap(v.vmi.Config.NetDevConfig, v.vmi.Config.UseSudo); tapErr != nil {
+					v.logger.Warn("failed to remove tap device", "cvm", v.cvmId, "error", tapErr)
+				}
+			}
+		}()
+	}
+
+	if v.vmi.Config.SandboxConfig.User != "" {
+		if err = sandboxUserFiles(v.vmi.Config); err != nil {
+			return fmt.Errorf("failed to give the sandbox user access to the VM files: %v", err)
+		}
+	}
+
+	if v.vmi.Config.SandboxConfig.AppArmor {
+		qemu, err := exec.LookPath(v.vmi.Config.QemuBinPath)
+		if err != nil {
+			return err
+		}
+		if qemu, err = filepath.Abs(qemu); err != nil {
+			return err
+		}
+
+		profile := appArmorProfilePrefix + id.String()
+		if err = loadAppArmorProfile(profile, qemu, v.vmi.Config); err != nil {
+			return fmt.Errorf("failed to load AppArmor profile: %v", err)
+		}
+		v.vmi.Config.SandboxConfig.AppArmorProfile = profile
+
+		defer func() {
+			if err != nil {
+				v.unloadAppArmorProfile()
+			}
+		}()
+	}
+
 	exe, args, err := v.executableAndArgs()
 	if err != nil {
 		return err
 	}
 
+	output, qemuOutput, err := newOutputPipes()
+	if err != nil {
+		return err
+	}
+	defer closeOutput(qemuOutput)
+
 	v.cmd = exec.Command(exe, args...)
-	v.cmd.Stdout = &vm.Stdout{StateMachine: v.StateMachine, Logger: v.logger.With(slog.String("cvm", v.cvmId))}
-	v.cmd.Stderr = &vm.Stderr{StateMachine: v.StateMachine, Logger: v.logger.With(slog.String("cvm", v.cvmId))}
+	v.cmd.Stdout = qemuOutput[0]
+	v.cmd.Stderr = qemuOutput[1]
 
-	return v.cmd.Start()
+	if err = v.cmd.Start(); err != nil {
+		closeOutput(output)
+		return err
+	}
+	v.relayOutput(output)
+
+	return nil
 }
 
 func (v *qemuVM) Stop() error {
@@ -94,9 +195,22 @@ func (v *qemuVM) Stop() error {
 			return
 		}
 	}()
-	err := v.cmd.Process.Signal(syscall.SIGTERM)
-	if err != nil {
-		return fmt.Errorf("failed to send SIGTERM: %v", err)
+	defer v.unloadAppArmorProfile()
+	if v.vmi.Config.NetDevConfig.Mode == NetModeBridge {
+		defer func() {
+			netCfg := v.vmi.Config.NetDevConfig
+			netCfg.Tap = TapName(v.cvmId)
+			if err := teardownTap(netCfg, v.vmi.Config.UseSudo); err != nil {
+				v.logger.Warn("failed to remove tap device", "cvm", v.cvmId, "error", err)
+			}
+		}()
+	}
+
+	graceful := v.powerdown()
+	if !graceful {
+		if err := v.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to send SIGTERM: %v", err)
+		}
 	}
 
 	if v.vmi.Config.CertsMount != "" {
@@ -113,29 +227,102 @@ func (v *qemuVM) Stop() error {
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := v.cmd.Process.Wait()
-		done <- err
+		done <- v.wait()
 	}()
 
 	select {
 	case err := <-done:
+		v.removeQMPSocket()
 		return err
 	case <-time.After(shutdownTimeout):
-		err := v.cmd.Process.Kill()
-		if err != nil {
-			return fmt.Errorf("failed to kill process: %v", err)
+	}
+
+	if graceful {
+		v.logger.Warn("VM did not power down in time, terminating it", "cvm", v.cvmId)
+		if qmp, err := v.connect(); err == nil && qmp.quit() == nil {
+			select {
+			case err := <-done:
+				v.removeQMPSocket()
+				return err
+			case <-time.After(qmpTimeout):
+			}
 		}
 	}
 
+	v.removeQMPSocket()
+
+	if err := v.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to kill process: %v", err)
+	}
+
 	return nil
 }
 
+// powerdown asks the guest to shut down over QMP. A guest that is not running,
+// e.g. paused or panicked, cannot react to the request so QEMU is asked to quit
+// instead. It reports false when the VM could not be reached over QMP.
+func (v *qemuVM) powerdown() bool {
+	if v.vmi.Config.QMPSocket == "" {
+		return false
+	}
+
+	qmp, err := v.connect()
+	if err != nil {
+		v.logger.Warn("failed to connect to QMP, terminating the VM", "cvm", v.cvmId, "error", err)
+		return false
+	}
+
+	status, err := qmp.status()
+	switch {
+	case err != nil:
+		v.logger.Warn("failed to query VM status", "cvm", v.cvmId, "error", err)
+		return false
+	case status != qmpStatusRunning:
+		err = qmp.quit()
+	default:
+		err = qmp.powerdown()
+	}
+	if err != nil {
+		v.logger.Warn("failed to power down the VM", "cvm", v.cvmId, "status", status, "error", err)
+		return false
+	}
+
+	return true
+}
+
+func (v *qemuVM) unloadAppArmorProfile() {
+	profile := v.vmi.Config.SandboxConfig.AppArmorProfile
+	if profile == "" {
+		return
+	}
+
+	if err := unloadAppArmorProfile(profile, v.vmi.Config.UseSudo); err != nil {
+		v.logger.Warn("failed to unload AppArmor profile", "cvm", v.cvmId, "error", err)
+	}
+}
+
+func (v *qemuVM) removeQMPSocket() {
+	if v.vmi.Config.QMPSocket == "" {
+		return
+	}
+
+	if err := os.Remove(v.vmi.Config.QMPSocket); err != nil && !os.IsNotExist(err) {
+		v.logger.Warn("failed to remove QMP socket", "cvm", v.cvmId, "error", err)
+	}
+}
+
+// SetProcess adopts the running QEMU process of the VM, e.g. after the
+// manager restarted, and reconnects to its QMP socket.
 func (v *qemuVM) SetProcess(pid int) error {
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		return err
 	}
 
+	if err := v.checkProcess(pid); err != nil {
+		return err
+	}
+
 	exe, args, err := v.executableAndArgs()
 	if err != nil {
 		return err
@@ -143,9 +330,206 @@ func (v *qemuVM) SetProcess(pid int) error {
 
 	v.cmd = exec.Command(exe, args...)
 	v.cmd.Process = process
+	v.adopted = true
+
+	// After an upgrade the output of QEMU is still relayed, it is lost after a manager restart.
+	if output, ok := inheritedOutput(v.cvmId); ok {
+		v.relayOutput(output)
+	}
+
+	go v.monitor()
+
 	return nil
 }
 
+// checkProcess verifies that the process runs the VM, i.e. that its command
+// line holds the QMP socket unique to the VM.
+func (v *qemuVM) checkProcess(pid int) error {
+	if v.vmi.Config.QMPSocket == "" {
+		return nil
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Contains(cmdline, []byte(v.vmi.Config.QMPSocket)) {
+		return ErrNotVMProcess
+	}
+
+	return nil
+}
+
+// wait waits for the QEMU process to exit. Adopted processes are not children
+// of the manager, so they are polled for instead.
+func (v *qemuVM) wait() error {
+	if !v.adopted {
+		_, err := v.cmd.Process.Wait()
+		return err
+	}
+
+	for processExists(v.cmd.Process.Pid) {
+		time.Sleep(adoptedPollInterval)
+	}
+
+	return nil
+}
+
+// AttachDisk hot-adds the image as a read-only dataset disk of the running VM.
+func (v *qemuVM) AttachDisk(path string) error {
+	cfg := v.vmi.Config
+	if cfg.MicroVM() {
+		return ErrHotplugUnsupported
+	}
+	if cfg.DatasetDiskConfig.DiskSlots == 0 || cfg.QMPSocket == "" {
+		return ErrHotplugDisabled
+	}
+
+	v.disksMu.Lock()
+	defer v.disksMu.Unlock()
+
+	if v.disks >= cfg.DatasetDiskConfig.DiskSlots {
+		return ErrNoDiskSlots
+	}
+
+	if cfg.SandboxConfig.User != "" {
+		if err := sandboxUserDisk(cfg, path); err != nil {
+			return fmt.Errorf("failed to give the sandbox user access to the disk image: %v", err)
+		}
+	}
+
+	qmp, err := v.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := qmp.addDatasetDisk(v.disks, path, cfg.EnableSEVSNP || cfg.EnableTDX); err != nil {
+		return err
+	}
+	v.disks++
+
+	return nil
+}
+
+// Restart resets the VM over QMP, so the guest reboots with the same QEMU process, mounts and ports.
+func (v *qemuVM) Restart() error {
+	if v.vmi.Config.EnableSEVSNP || v.vmi.Config.EnableTDX {
+		return ErrResetUnsupported
+	}
+	if v.vmi.Config.QMPSocket == "" {
+		return ErrQMPClosed
+	}
+
+	qmp, err := v.connect()
+	if err != nil {
+		return err
+	}
+
+	return qmp.reset()
+}
+
+// Events returns the hypervisor events of the VM, the channel is closed once the VM exits.
+func (v *qemuVM) Events() <-chan vm.Event {
+	return v.events
+}
+
+// connect returns the QMP connection of the VM, dialing it if needed. QEMU
+// accepts a single QMP client, so the connection is shared by all commands.
+func (v *qemuVM) connect() (*qmpClient, error) {
+	v.qmpMu.Lock()
+	defer v.qmpMu.Unlock()
+
+	if v.qmp != nil {
+		return v.qmp, nil
+	}
+
+	qmp, err := dialQMP(v.vmi.Config.QMPSocket, v.handleEvent)
+	if err != nil {
+		return nil, err
+	}
+	v.qmp = qmp
+
+	go func() {
+		<-qmp.done
+		v.qmpMu.Lock()
+		if v.qmp == qmp {
+			v.qmp = nil
+		}
+		v.qmpMu.Unlock()
+	}()
+
+	return qmp, nil
+}
+
+// monitor keeps the QMP connection of the VM open while its process runs, so
+// the events QEMU emits are received, and closes the VM events once it exits.
+// The vCPUs are pinned to the configured host CPUs once QMP is reachable.
+func (v *qemuVM) monitor() {
+	defer v.closeEvents()
+
+	if v.vmi.Config.QMPSocket == "" {
+		return
+	}
+
+	pinned := false
+	for processExists(v.GetProcess()) {
+		qmp, err := v.connect()
+		if err != nil {
+			// QEMU creates the socket after the process started.
+			time.Sleep(qmpRetryInterval)
+			continue
+		}
+
+		// The vCPU threads exist once QMP is served, adopted VMs are pinned again.
+		if !pinned {
+			pinned = true
+			if err := v.pinVCPUs(qmp); err != nil {
+				v.logger.Error("failed to pin vCPUs", "cvm", v.cvmId, "error", err)
+			}
+		}
+		<-qmp.done
+	}
+}
+
+func (v *qemuVM) handleEvent(event qmpEvent) {
+	if event.Event != qmpGuestPanickedEvent {
+		return
+	}
+
+	v.logger.Error("guest panicked", "cvm", v.cvmId, "info", string(event.Data))
+
+	v.eventsMu.Lock()
+	defer v.eventsMu.Unlock()
+
+	if v.eventsClosed {
+		return
+	}
+
+	select {
+	case v.events <- vm.Event{
+		EventType:     vm.EventGuestPanicked,
+		Timestamp:     timestamppb.Now(),
+		ComputationId: v.cvmId,
+		Details:       event.Data,
+		Originator:    "qemu",
+		Status:        manager.Failed.String(),
+	}:
+	default:
+		v.logger.Warn("dropped VM event", "cvm", v.cvmId, "event", event.Event)
+	}
+}
+
+func (v *qemuVM) closeEvents() {
+	v.eventsMu.Lock()
+	defer v.eventsMu.Unlock()
+
+	if !v.eventsClosed && v.events != nil {
+		v.eventsClosed = true
+		close(v.events)
+	}
+}
+
 func (v *qemuVM) GetProcess() int {
 	return v.cmd.Process.Pid
 }
@@ -156,14 +540,16 @@ func (v *qemuVM) executableAndArgs() (string, []string, error) {
 		return "", nil, err
 	}
 
-	args := v.vmi.Config.ConstructQemuArgs()
+	cmdline, err := sandboxCommand(v.vmi.Config, append([]string{exe}, v.vmi.Config.ConstructQemuArgs()...))
+	if err != nil {
+		return "", nil, err
+	}
 
 	if v.vmi.Config.UseSudo {
-		args = append([]string{exe}, args...)
-		exe = "sudo"
+		cmdline = append([]string{"sudo"}, cmdline...)
 	}
 
-	return exe, args, nil
+	return cmdline[0], cmdline[1:], nil
 }
 
 func (v *qemuVM) checkVMProcessPeriodically() {
@@ -6,9 +6,13 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/vm"
 	"github.com/ultravioletrs/cocos/manager/vm/mocks"
 	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
 )
@@ -87,6 +91,26 @@ func TestStop(t *testing.T) {
 		err = vm.Stop()
 		assert.NoError(t, err)
 	})
+	t.Run("adopted process", func(t *testing.T) {
+		cmd := exec.Command("sleep", "30")
+		require.NoError(t, cmd.Start())
+		// The test reaps its child, adopted processes are reaped by init.
+		go func() { _ = cmd.Wait() }()
+		sm := new(mocks.StateMachine)
+		sm.On("Transition", pkgmanager.StopComputationRun).Return(nil)
+
+		vm := &qemuVM{
+			cmd: &exec.Cmd{
+				Process: cmd.Process,
+			},
+			StateMachine: sm,
+			adopted:      true,
+		}
+
+		err := vm.Stop()
+		assert.NoError(t, err)
+		assert.False(t, processExists(cmd.Process.Pid))
+	})
 	t.Run("transition error", func(t *testing.T) {
 		cmd := exec.Command("echo", "test")
 		err := cmd.Start()
@@ -120,6 +144,55 @@ func TestSetProcess(t *testing.T) {
 	assert.NotNil(t, vm.cmd.Process)
 }
 
+func TestSetProcessChecksCommandLine(t *testing.T) {
+	procDir = t.TempDir()
+	t.Cleanup(func() { procDir = "/proc" })
+
+	pid := os.Getpid()
+	require.NoError(t, os.MkdirAll(filepath.Join(procDir, strconv.Itoa(pid)), 0o755))
+	cmdline := "qemu-system-x86_64\x00-qmp\x00unix:/tmp/qmp-1.sock,server=on,wait=off\x00"
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"), []byte(cmdline), 0o644))
+
+	tests := []struct {
+		name      string
+		qmpSocket string
+		pid       int
+		err       error
+	}{
+		{
+			name:      "process of the VM",
+			qmpSocket: "/tmp/qmp-1.sock",
+			pid:       pid,
+		},
+		{
+			name:      "process of another VM",
+			qmpSocket: "/tmp/qmp-2.sock",
+			pid:       pid,
+			err:       ErrNotVMProcess,
+		},
+		{
+			name:      "exited process",
+			qmpSocket: "/tmp/qmp-1.sock",
+			pid:       pid + 1,
+			err:       os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &qemuVM{
+				vmi:    VMInfo{Config: Config{QemuBinPath: "echo", QMPSocket: tt.qmpSocket}},
+				logger: slog.Default(),
+				events: make(chan vm.Event, eventsBuffer),
+			}
+
+			err := vm.SetProcess(tt.pid)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.err == nil, vm.adopted)
+		})
+	}
+}
+
 func TestGetProcess(t *testing.T) {
 	expectedPid := 12345
 	vm := &qemuVM{
@@ -0,0 +1,276 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/manager/audit"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+// DefaultTenant is the tenant of CVMs created without one.
+const DefaultTenant = "default"
+
+// ErrQuotaExceeded indicates a CVM that does not fit in the quota of its tenant.
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// QuotaConfig is the quota of the tenants SetTenantQuota set none for, 0
+// leaves a resource unlimited, and the subjects of the client certificates
+// allowed to call SetTenantQuota and AuditLog. Subjects hold commas, so they
+// are separated by semicolons.
+type QuotaConfig struct {
+	MaxVMs      uint32   `env:"MANAGER_TENANT_MAX_VMS"       envDefault:"0"`
+	MaxVCPUs    uint32   `env:"MANAGER_TENANT_MAX_VCPUS"     envDefault:"0"`
+	MaxMemoryMB uint64   `env:"MANAGER_TENANT_MAX_MEMORY_MB" envDefault:"0"`
+	Admins      []string `env:"MANAGER_QUOTA_ADMINS"         envDefault:"" envSeparator:";"`
+}
+
+// Admins are the subjects of the client certificates allowed to call the admin
+// RPCs, anonymous callers are never admins.
+type Admins map[string]bool
+
+// NewAdmins returns the admins with the subjects.
+func NewAdmins(subjects []string) Admins {
+	admins := make(Admins, len(subjects))
+	for _, subject := range subjects {
+		if subject != "" && subject != audit.AnonymousCaller {
+			admins[subject] = true
+		}
+	}
+
+	return admins
+}
+
+// Authorize returns an ErrUnauthorizedAccess error unless the caller of ctx
+// is an admin.
+func (a Admins) Authorize(ctx context.Context) error {
+	if caller, _ := audit.Caller(ctx); !a[caller] {
+		return fmt.Errorf("%w: %s is not an admin", ErrUnauthorizedAccess, caller)
+	}
+
+	return nil
+}
+
+// vmResources are the resources a CVM holds against the quota of its tenant.
+type vmResources struct {
+	tenant   string
+	vcpus    uint32
+	memoryMB uint64
+}
+
+// quotas limits the CVMs each tenant runs concurrently, with their vCPUs and
+// memory. Quotas set at runtime are not persisted, the manager starts with
+// the configured default quota for every tenant. A nil quotas limits nothing.
+type quotas struct {
+	mu       sync.Mutex
+	defaults *TenantQuota
+	admins   Admins
+	tenants  map[string]*TenantQuota
+	vms      map[string]vmResources
+}
+
+func newQuotas(cfg QuotaConfig) *quotas {
+	return &quotas{
+		defaults: &TenantQuota{MaxVms: cfg.MaxVMs, MaxVcpus: cfg.MaxVCPUs, MaxMemoryMb: cfg.MaxMemoryMB},
+		admins:   NewAdmins(cfg.Admins),
+		tenants:  make(map[string]*TenantQuota),
+		vms:      make(map[string]vmResources),
+	}
+}
+
+// tenantName returns the tenant CVMs created for tenant count against.
+func tenantName(tenant string) string {
+	if tenant == "" {
+		return DefaultTenant
+	}
+
+	return tenant
+}
+
+// callerTenant returns the tenant of the caller of ctx: the subject of its
+// verified client certificate, or the default tenant without mutual TLS. A
+// caller requesting another tenant than its own is refused.
+func callerTenant(ctx context.Context, requested string) (string, error) {
+	tenant := DefaultTenant
+	if caller, _ := audit.Caller(ctx); caller != audit.AnonymousCaller {
+		tenant = caller
+	}
+
+	if requested != "" && requested != tenant {
+		return "", fmt.Errorf("%w: caller of tenant %s cannot create CVMs for tenant %s", ErrUnauthorizedAccess, tenant, requested)
+	}
+
+	return tenant, nil
+}
+
+// resources returns the vCPUs and memory of CVMs launched with cfg.
+func resources(tenant string, cfg qemu.Config) (vmResources, error) {
+	memoryMB, err := cfg.MemoryConfig.SizeMB()
+	if err != nil {
+		return vmResources{}, err
+	}
+
+	return vmResources{tenant: tenantName(tenant), vcpus: uint32(cfg.SMPCount), memoryMB: memoryMB}, nil
+}
+
+// quota returns the quota of the tenant. It must be called with the mutex held.
+func (q *quotas) quota(tenant string) *TenantQuota {
+	if quota, ok := q.tenants[tenant]; ok {
+		return quota
+	}
+
+	return q.defaults
+}
+
+// usage returns the resources the CVMs of the tenant hold. It must be called
+// with the mutex held.
+func (q *quotas) usage(tenant string) *TenantUsage {
+	usage := &TenantUsage{}
+	for _, res := range q.vms {
+		if res.tenant != tenant {
+			continue
+		}
+		usage.Vms++
+		usage.Vcpus += res.vcpus
+		usage.MemoryMb += res.memoryMB
+	}
+
+	return usage
+}
+
+// reserve holds the resources of the CVM against the quota of its tenant,
+// until it is released.
+func (q *quotas) reserve(id string, res vmResources) error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	quota, usage := q.quota(res.tenant), q.usage(res.tenant)
+	switch {
+	case quota.MaxVms > 0 && usage.Vms+1 > quota.MaxVms:
+		return fmt.Errorf("%w: tenant %s already runs %d of %d CVMs", ErrQuotaExceeded, res.tenant, usage.Vms, quota.MaxVms)
+	case quota.MaxVcpus > 0 && usage.Vcpus+res.vcpus > quota.MaxVcpus:
+		return fmt.Errorf("%w: tenant %s would run %d of %d vCPUs", ErrQuotaExceeded, res.tenant, usage.Vcpus+res.vcpus, quota.MaxVcpus)
+	case quota.MaxMemoryMb > 0 && usage.MemoryMb+res.memoryMB > quota.MaxMemoryMb:
+		return fmt.Errorf("%w: tenant %s would use %d of %d MiB of memory", ErrQuotaExceeded, res.tenant, usage.MemoryMb+res.memoryMB, quota.MaxMemoryMb)
+	}
+
+	q.vms[id] = res
+
+	return nil
+}
+
+// restore holds the resources of a restored CVM, even if its tenant exceeds
+// its quota.
+func (q *quotas) restore(id string, res vmResources) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.vms[id] = res
+}
+
+// reassign moves the resources reserved under one ID to another, e.g. to the
+// pooled VM the request is served with.
+func (q *quotas) reassign(from, to string) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if res, ok := q.vms[from]; ok {
+		delete(q.vms, from)
+		q.vms[to] = res
+	}
+}
+
+// release frees the resources of the CVM, if it holds any.
+func (q *quotas) release(id string) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.vms, id)
+}
+
+// tenant returns the tenant of the CVM, the default one if it holds no resources.
+func (q *quotas) tenant(id string) string {
+	if q == nil {
+		return DefaultTenant
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if res, ok := q.vms[id]; ok {
+		return res.tenant
+	}
+
+	return DefaultTenant
+}
+
+// set replaces the quota of the tenant and returns its usage.
+func (q *quotas) set(tenant string, quota *TenantQuota) *TenantUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tenants[tenant] = &TenantQuota{MaxVms: quota.MaxVms, MaxVcpus: quota.MaxVcpus, MaxMemoryMb: quota.MaxMemoryMb}
+
+	return q.usage(tenant)
+}
+
+// reserveQuota holds the resources of a CVM created with req against the
+// quota of the tenant of the caller.
+func (ms *managerService) reserveQuota(ctx context.Context, id string, req *CreateReq) error {
+	tenant, err := callerTenant(ctx, req.Tenant)
+	if err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	cfg, err := ms.qemuCfg.WithProfile(req.MachineProfile)
+	ms.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	res, err := resources(tenant, cfg)
+	if err != nil {
+		return err
+	}
+
+	return ms.quotas.reserve(id, res)
+}
+
+// SetTenantQuota replaces the quota of the tenant, CVMs already running are
+// kept when the tenant exceeds the new quota. Only the configured admins may
+// set quotas.
+func (ms *managerService) SetTenantQuota(ctx context.Context, tenant string, quota *TenantQuota) (*TenantUsage, error) {
+	if err := ms.quotas.admins.Authorize(ctx); err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		return nil, errors.Wrap(ErrMalformedEntity, errors.New("quota is required"))
+	}
+
+	tenant = tenantName(tenant)
+	usage := ms.quotas.set(tenant, quota)
+	ms.logger.Info("Tenant quota set", "tenant", tenant, "max_vms", quota.MaxVms, "max_vcpus", quota.MaxVcpus, "max_memory_mb", quota.MaxMemoryMb)
+
+	return usage, nil
+}
@@ -0,0 +1,252 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager/audit"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+	persistenceMocks "github.com/ultravioletrs/cocos/manager/qemu/mocks"
+	"github.com/ultravioletrs/cocos/manager/vm"
+	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// clientContext returns the context of a call by the client whose verified
+// certificate has the common name.
+func clientContext(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestQuotasReserve(t *testing.T) {
+	small := vmResources{tenant: "acme", vcpus: 2, memoryMB: 2048}
+
+	tests := []struct {
+		name     string
+		cfg      QuotaConfig
+		reserved []vmResources
+		res      vmResources
+		err      error
+	}{
+		{
+			name:     "unlimited quota",
+			reserved: []vmResources{small, small},
+			res:      small,
+		},
+		{
+			name:     "within quota",
+			cfg:      QuotaConfig{MaxVMs: 2, MaxVCPUs: 4, MaxMemoryMB: 4096},
+			reserved: []vmResources{small},
+			res:      small,
+		},
+		{
+			name:     "too many CVMs",
+			cfg:      QuotaConfig{MaxVMs: 1},
+			reserved: []vmResources{small},
+			res:      small,
+			err:      ErrQuotaExceeded,
+		},
+		{
+			name:     "too many vCPUs",
+			cfg:      QuotaConfig{MaxVCPUs: 3},
+			reserved: []vmResources{small},
+			res:      small,
+			err:      ErrQuotaExceeded,
+		},
+		{
+			name:     "too much memory",
+			cfg:      QuotaConfig{MaxMemoryMB: 3072},
+			reserved: []vmResources{small},
+			res:      small,
+			err:      ErrQuotaExceeded,
+		},
+		{
+			name:     "other tenants do not count",
+			cfg:      QuotaConfig{MaxVMs: 1},
+			reserved: []vmResources{{tenant: "globex", vcpus: 2, memoryMB: 2048}},
+			res:      small,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQuotas(tt.cfg)
+			for i, res := range tt.reserved {
+				q.restore(string(rune('a'+i)), res)
+			}
+
+			err := q.reserve("new", tt.res)
+			assert.True(t, errors.Contains(err, tt.err), "expected %v, got %v", tt.err, err)
+			if tt.err != nil {
+				assert.NotContains(t, q.vms, "new")
+			}
+		})
+	}
+}
+
+func TestQuotasReassignRelease(t *testing.T) {
+	q := newQuotas(QuotaConfig{MaxVMs: 1})
+	res := vmResources{tenant: "acme", vcpus: 1, memoryMB: 1024}
+
+	require.NoError(t, q.reserve("request", res))
+	q.reassign("request", "pooled")
+	assert.Equal(t, "acme", q.tenant("pooled"))
+	assert.Equal(t, DefaultTenant, q.tenant("request"))
+
+	assert.True(t, errors.Contains(q.reserve("other", res), ErrQuotaExceeded))
+
+	q.release("pooled")
+	assert.NoError(t, q.reserve("other", res))
+}
+
+func TestCallerTenant(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		tenant    string
+		err       error
+	}{
+		{
+			name:   "anonymous caller",
+			ctx:    context.Background(),
+			tenant: DefaultTenant,
+		},
+		{
+			name:      "anonymous caller requesting the default tenant",
+			ctx:       context.Background(),
+			requested: DefaultTenant,
+			tenant:    DefaultTenant,
+		},
+		{
+			name:      "anonymous caller requesting a tenant",
+			ctx:       context.Background(),
+			requested: "CN=acme",
+			err:       ErrUnauthorizedAccess,
+		},
+		{
+			name:   "verified caller",
+			ctx:    clientContext("acme"),
+			tenant: "CN=acme",
+		},
+		{
+			name:      "verified caller requesting its tenant",
+			ctx:       clientContext("acme"),
+			requested: "CN=acme",
+			tenant:    "CN=acme",
+		},
+		{
+			name:      "verified caller requesting another tenant",
+			ctx:       clientContext("acme"),
+			requested: "CN=globex",
+			err:       ErrUnauthorizedAccess,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := callerTenant(tt.ctx, tt.requested)
+			assert.True(t, errors.Contains(err, tt.err), "expected %v, got %v", tt.err, err)
+			assert.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
+
+func TestSetTenantQuotaAdmins(t *testing.T) {
+	ms := &managerService{logger: slog.Default(), quotas: newQuotas(QuotaConfig{Admins: []string{"CN=admin", audit.AnonymousCaller}})}
+
+	_, err := ms.SetTenantQuota(context.Background(), "acme", &TenantQuota{MaxVms: 3})
+	assert.True(t, errors.Contains(err, ErrUnauthorizedAccess), "anonymous callers are never admins")
+
+	_, err = ms.SetTenantQuota(clientContext("acme"), "acme", &TenantQuota{MaxVms: 3})
+	assert.True(t, errors.Contains(err, ErrUnauthorizedAccess))
+	assert.Equal(t, ms.quotas.defaults, ms.quotas.quota("acme"))
+
+	_, err = ms.SetTenantQuota(clientContext("admin"), "acme", &TenantQuota{MaxVms: 3})
+	assert.NoError(t, err)
+}
+
+func TestSetTenantQuota(t *testing.T) {
+	ms := &managerService{logger: slog.Default(), quotas: newQuotas(QuotaConfig{MaxVMs: 1, Admins: []string{"CN=admin"}})}
+	ms.quotas.restore("vm-1", vmResources{tenant: "acme", vcpus: 2, memoryMB: 2048})
+	ctx := clientContext("admin")
+
+	usage, err := ms.SetTenantQuota(ctx, "acme", &TenantQuota{MaxVms: 3})
+	require.NoError(t, err)
+	assert.Equal(t, &TenantUsage{Vms: 1, Vcpus: 2, MemoryMb: 2048}, usage)
+	assert.NoError(t, ms.quotas.reserve("vm-2", vmResources{tenant: "acme"}))
+	assert.NoError(t, ms.quotas.reserve("vm-3", vmResources{tenant: "globex"}), "other tenants keep the default quota")
+
+	usage, err = ms.SetTenantQuota(ctx, "", &TenantQuota{})
+	require.NoError(t, err)
+	assert.Equal(t, &TenantUsage{}, usage)
+	assert.Equal(t, &TenantQuota{}, ms.quotas.quota(DefaultTenant))
+
+	_, err = ms.SetTenantQuota(ctx, "acme", nil)
+	assert.True(t, errors.Contains(err, ErrMalformedEntity))
+}
+
+func TestCreateVMTenantQuota(t *testing.T) {
+	vmf := new(mocks.Provider)
+	vmMock := new(mocks.VM)
+	persistence := new(persistenceMocks.Persistence)
+
+	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock).Run(func(args mock.Arguments) {
+		removeMounts(args.Get(0).(qemu.VMInfo))
+	})
+	vmMock.On("Start").Return(nil)
+	vmMock.On("GetProcess").Return(1234)
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+	vmMock.On("Stop").Return(nil)
+	persistence.On("SaveVM", mock.MatchedBy(func(state qemu.VMState) bool { return state.Tenant != "" })).Return(nil)
+	persistence.On("DeleteVM", mock.Anything).Return(nil)
+
+	ms := &managerService{
+		qemuCfg:     qemu.Config{SMPCount: 2, MemoryConfig: qemu.MemoryConfig{Size: "2048M"}},
+		logger:      slog.Default(),
+		vms:         make(map[string]vm.VM),
+		vmFactory:   vmf.Execute,
+		persistence: persistence,
+		ttlManager:  NewTTLManager(),
+		ports:       newTestPorts(),
+		quotas:      newQuotas(QuotaConfig{MaxVCPUs: 2}),
+	}
+
+	acme := clientContext("acme")
+
+	_, id, err := ms.CreateVM(acme, &CreateReq{AgentCvmServerUrl: "10.0.2.2:7001"})
+	require.NoError(t, err)
+	assert.Equal(t, "CN=acme", ms.quotas.tenant(id))
+
+	_, _, err = ms.CreateVM(acme, &CreateReq{AgentCvmServerUrl: "10.0.2.2:7001", Tenant: "CN=acme"})
+	assert.True(t, errors.Contains(err, ErrQuotaExceeded), "expected %v, got %v", ErrQuotaExceeded, err)
+	assert.Len(t, ms.vms, 1)
+
+	_, other, err := ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "10.0.2.2:7001"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTenant, ms.quotas.tenant(other))
+
+	_, _, err = ms.CreateVM(context.Background(), &CreateReq{AgentCvmServerUrl: "10.0.2.2:7001", Tenant: "CN=acme"})
+	assert.True(t, errors.Contains(err, ErrUnauthorizedAccess), "callers cannot claim another tenant")
+
+	require.NoError(t, ms.RemoveVM(context.Background(), id))
+	_, _, err = ms.CreateVM(acme, &CreateReq{AgentCvmServerUrl: "10.0.2.2:7001"})
+	assert.NoError(t, err)
+}
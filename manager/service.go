@@ -7,21 +7,17 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log/slog"
-	"net"
 	"os"
-	"regexp"
-	"strconv"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
-	"github.com/google/go-sev-guest/proto/check"
 	"github.com/google/uuid"
+	"github.com/ultravioletrs/cocos/internal"
 	"github.com/ultravioletrs/cocos/manager/qemu"
 	"github.com/ultravioletrs/cocos/manager/vm"
-	"github.com/ultravioletrs/cocos/pkg/attestation"
-	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 	"github.com/ultravioletrs/cocos/pkg/manager"
 	"golang.org/x/crypto/sha3"
 )
@@ -71,6 +67,39 @@ var (
 
 	// ErrMaxVMsExceeded indicates that the maximum number of VMs has been reached.
 	ErrMaxVMsExceeded = errors.New("maximum number of VMs exceeded")
+
+	// ErrInvalidKernelParams indicates that the agent configuration cannot be passed on the kernel command line.
+	ErrInvalidKernelParams = errors.New("invalid agent kernel command line parameters")
+
+	// ErrFailedToReadImage indicates that a configured guest image could not be read to compute its digest.
+	ErrFailedToReadImage = errors.New("error while reading guest image")
+
+	// ErrFailedToAttachDataset indicates that a dataset disk could not be hot-added to the CVM.
+	ErrFailedToAttachDataset = errors.New("failed to attach dataset disk")
+
+	// ErrLogsDisabled indicates that the manager does not collect the algorithm output of the agents.
+	ErrLogsDisabled = errors.New("agent log collection is disabled")
+
+	// ErrConsoleDisabled indicates that the manager does not capture the console output of the CVMs.
+	ErrConsoleDisabled = errors.New("console capture is disabled")
+
+	// ErrAuditDisabled indicates that the manager does not keep an audit log.
+	ErrAuditDisabled = errors.New("audit log is disabled")
+
+	// ErrDiagnosticsNotFound indicates that the agent of the CVM sent no diagnostic snapshot.
+	ErrDiagnosticsNotFound = errors.New("no diagnostic snapshot for the CVM")
+
+	// ErrSNPCertsDisabled indicates that the manager does not cache SEV-SNP certificates.
+	ErrSNPCertsDisabled = errors.New("SEV-SNP certificate cache is disabled")
+
+	// ErrInvalidPlacement indicates vCPU pinning or a NUMA node the host cannot satisfy.
+	ErrInvalidPlacement = errors.New("invalid CPU pinning or NUMA node")
+
+	// ErrInvalidGPU indicates GPUs that cannot be passed through to the CVM.
+	ErrInvalidGPU = errors.New("invalid GPU passthrough")
+
+	// ErrGPUInUse indicates a GPU whose IOMMU group is passed through to another CVM.
+	ErrGPUInUse = errors.New("GPU is passed through to another CVM")
 )
 
 // Service specifies an API that must be fulfilled by the domain service
@@ -80,10 +109,40 @@ type Service interface {
 	CreateVM(ctx context.Context, req *CreateReq) (string, string, error)
 	// Stop stops a computation.
 	RemoveVM(ctx context.Context, computationID string) error
+	// StopVM shuts down the CVM without removing it and returns its resulting state.
+	StopVM(ctx context.Context, computationID string) (string, error)
+	// AttachDataset hot-adds a dataset disk image to the running CVM, the agent registers
+	// the declared datasets it finds on the disk.
+	AttachDataset(ctx context.Context, computationID, diskPath string) error
 	// FetchAttestationPolicy measures and fetches the attestation policy.
 	FetchAttestationPolicy(ctx context.Context, computationID string) ([]byte, error)
 	// ReturnCVMInfo returns CVM information needed for attestation verification and validation.
 	ReturnCVMInfo(ctx context.Context) (string, int, string, string)
+	// GetImages returns the guest images the manager boots CVMs with, along with their versions and digests.
+	GetImages(ctx context.Context) ([]*Image, error)
+	// WatchComputation streams the state transitions and lifecycle events of the CVM.
+	// The channel starts with the current state and is closed when ctx is done or the CVM is removed.
+	WatchComputation(ctx context.Context, computationID string) (<-chan *ComputationEvent, error)
+	// Logs streams the algorithm output of the CVM, starting with the buffered output the filter selects.
+	// The channel is closed when ctx is done, the CVM is removed, or after the buffered output unless the filter follows it.
+	Logs(ctx context.Context, computationID string, filter LogsFilter) (<-chan *LogChunk, error)
+	// HostCapabilities returns the TEE and virtualization features detected on the host at startup.
+	HostCapabilities(ctx context.Context) (*HostCapabilities, error)
+	// Diagnostics returns the diagnostic snapshot the agent of the CVM sent when its computation run last failed.
+	Diagnostics(ctx context.Context, computationID string) (*Diagnostics, error)
+	// Timeline returns the phases the CVM went through, assembled from its events.
+	Timeline(ctx context.Context, computationID string) (*Timeline, error)
+	// DownloadLogs returns a zip archive of the manager log, console output, algorithm output, events and diagnostics of the CVM.
+	DownloadLogs(ctx context.Context, computationID string) ([]byte, error)
+	// SNPCertChain returns the certificates reports of the SEV-SNP chip at the reported TCB version are verified with.
+	SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*SNPCertChain, error)
+	// SetTenantQuota replaces the quota of the tenant at runtime and returns the resources its CVMs use.
+	SetTenantQuota(ctx context.Context, tenant string, quota *TenantQuota) (*TenantUsage, error)
+	// Console streams the serial console output of the CVM, starting with the captured output the filter selects.
+	// The channel is closed when ctx is done, the CVM is removed, or after the captured output unless the filter follows it.
+	Console(ctx context.Context, computationID string, filter ConsoleFilter) (<-chan *ConsoleChunk, error)
+	// AuditLog returns the records of the audit log of the control-plane operations.
+	AuditLog(ctx context.Context) (*AuditLogRes, error)
 	// Shutdown gracefully shuts down the service
 	Shutdown() error
 }
@@ -98,19 +157,30 @@ type managerService struct {
 	logger                      *slog.Logger
 	vms                         map[string]vm.VM
 	vmFactory                   vm.Provider
-	portRangeMin                int
-	portRangeMax                int
+	ports                       *qemu.PortAllocator
 	persistence                 qemu.Persistence
 	eosVersion                  string
 	ttlManager                  *TTLManager
 	maxVMs                      int
+	quotas                      *quotas
+	pool                        *vmPool
+	watchers                    *watchers
+	nextGuestCID                int
+	heartbeats                  *heartbeats
+	logs                        *logs
+	forwarders                  []*forwarder
+	hostCapabilities            *HostCapabilities
+	history                     map[string][]*ComputationEvent
+	vmLogs                      *vmLogs
+	consoles                    *consoles
+	snpCerts                    SNPCertificates
 }
 
 var _ Service = (*managerService)(nil)
 
 // New instantiates the manager service implementation.
-func New(cfg qemu.Config, attestationPolicyBinPath string, igvmMeasurementBinaryPath string, pcrValuesFilePath string, logger *slog.Logger, vmFactory vm.Provider, eosVersion string, maxVMs int) (Service, error) {
-	start, end, err := decodeRange(cfg.HostFwdRange)
+func New(cfg qemu.Config, attestationPolicyBinPath string, igvmMeasurementBinaryPath string, pcrValuesFilePath string, logger *slog.Logger, vmFactory vm.Provider, eosVersion string, maxVMs int, quotaCfg QuotaConfig, poolCfg PoolConfig, heartbeatCfg HeartbeatConfig, logsCfg LogsConfig, consoleCfg ConsoleConfig, publishers []EventPublisher, snpCerts SNPCertificates) (Service, error) {
+	ports, err := qemu.NewPortAllocator(cfg.HostFwdRange)
 	if err != nil {
 		return nil, err
 	}
@@ -120,26 +190,39 @@ func New(cfg qemu.Config, attestationPolicyBinPath string, igvmMeasurementBinary
 		return nil, err
 	}
 
+	consoles := newConsoles(consoleCfg)
+	vmLogs := newVMLogs(consoles)
 	ms := &managerService{
 		qemuCfg:                     cfg,
-		logger:                      logger,
+		logger:                      slog.New(newVMLogHandler(logger.Handler(), vmLogs)),
 		vms:                         make(map[string]vm.VM),
 		vmFactory:                   vmFactory,
 		attestationPolicyBinaryPath: attestationPolicyBinPath,
 		igvmMeasurementBinaryPath:   igvmMeasurementBinaryPath,
 		pcrValuesFilePath:           pcrValuesFilePath,
-		portRangeMin:                start,
-		portRangeMax:                end,
+		ports:                       ports,
 		persistence:                 persistence,
 		eosVersion:                  eosVersion,
 		ttlManager:                  NewTTLManager(),
 		maxVMs:                      maxVMs,
+		quotas:                      newQuotas(quotaCfg),
+		watchers:                    newWatchers(),
+		forwarders:                  newForwarders(publishers, logger),
+		hostCapabilities:            DetectHostCapabilities(),
+		vmLogs:                      vmLogs,
+		consoles:                    consoles,
+		snpCerts:                    snpCerts,
 	}
+	ms.logHostCapabilities()
 
 	if err := ms.restoreVMs(); err != nil {
 		return nil, err
 	}
 
+	ms.startHeartbeats(heartbeatCfg)
+	ms.startLogs(logsCfg)
+	ms.startPool(poolCfg)
+
 	return ms, nil
 }
 
@@ -151,111 +234,247 @@ func (ms *managerService) CreateVM(ctx context.Context, req *CreateReq) (string,
 		ms.mu.Unlock()
 		return "", id, ErrMaxVMsExceeded
 	}
-
-	cfg := qemu.VMInfo{
-		Config:    ms.qemuCfg,
-		LaunchTCB: 0,
-	}
 	ms.mu.Unlock()
 
-	tmpCertsDir, err := tempCertMount(id, req)
-	if err != nil {
-		return "", id, err
+	// The TTL is checked before a VM is registered, activateVM cannot undo it.
+	if req.Ttl != "" {
+		if _, err := time.ParseDuration(req.Ttl); err != nil {
+			return "", id, errors.Wrap(ErrMalformedEntity, err)
+		}
 	}
 
-	tmpEnvDir, err := tmpEnvironment(id, req)
-	if err != nil {
+	if err := ms.reserveQuota(ctx, id, req); err != nil {
 		return "", id, err
 	}
 
-	cfg.Config.CertsMount = tmpCertsDir
-	cfg.Config.EnvMount = tmpEnvDir
+	// Pooled VMs run with the configured vCPU pinning and NUMA node, without GPUs.
+	if !placementRequested(req) && !gpusRequested(req) {
+		if pvm, ok := ms.pool.take(req.MachineProfile); ok {
+			ms.quotas.reassign(id, pvm.id)
+			port, id, err := ms.assignPooledVM(pvm, req)
+			if err == nil {
+				ms.traceComputation(ctx, id)
+			} else {
+				ms.quotas.release(id)
+			}
 
-	if ms.qemuCfg.EnableSEVSNP {
-		attestPolicyCmd, err := fetchSNPAttestationPolicy(ms)
-		if err != nil {
-			return "", id, err
+			return port, id, err
 		}
+	}
 
-		var stdOutByte []byte
-		ms.ap.Lock()
-		stdOutByte, err = attestPolicyCmd.Run(ms.attestationPolicyBinaryPath)
-		ms.ap.Unlock()
-		if err != nil {
-			return "", id, errors.Wrap(ErrFailedToCreateAttestationPolicy, err)
+	// The port and quota are released unless the VM is registered, RemoveVM releases them then.
+	registered := false
+	defer func() {
+		if !registered {
+			ms.ports.Release(id)
+			ms.quotas.release(id)
 		}
+	}()
 
-		attestationPolicy := attestation.Config{Config: &check.Config{RootOfTrust: &check.RootOfTrust{}, Policy: &check.Policy{}}, PcrConfig: &attestation.PcrConfig{}}
+	cfg, agentPort, err := ms.prepareVM(id, req)
+	if err != nil {
+		return "", id, err
+	}
 
-		if err = vtpm.ReadPolicyFromByte(stdOutByte, &attestationPolicy); err != nil {
-			return "", id, errors.Wrap(ErrUnmarshalFailed, err)
-		}
+	if err := writeCerts(cfg.Config.CertsMount, req); err != nil {
+		return "", id, err
+	}
 
-		// Define the TCB that was present at launch of the VM.
-		cfg.LaunchTCB = attestationPolicy.Config.Policy.MinimumLaunchTcb
+	envMap := agentEnvironment(id, req)
+	ms.heartbeatEnvironment(envMap)
+	ms.logsEnvironment(envMap)
+	if cfg.Config.AgentCmdline {
+		cfg.Config.AgentParams = measuredAgentParams(envMap)
 	}
 
-	agentPort, err := getFreePort(ms.portRangeMin, ms.portRangeMax)
-	if err != nil {
-		return "", id, errors.Wrap(ErrFailedToAllocatePort, err)
+	if _, err := cfg.Config.KernelCmdline(); err != nil {
+		return "", id, errors.Wrap(ErrInvalidKernelParams, err)
 	}
-	cfg.Config.HostFwdAgent = agentPort
 
-	if cfg.Config.EnableSEVSNP {
-		todo := sha3.Sum256([]byte("TODO"))
-		// Define host-data value of QEMU for SEV-SNP, with a base64 encoding of the computation hash.
-		cfg.Config.SEVSNPConfig.HostData = base64.StdEncoding.EncodeToString(todo[:])
+	if err := writeEnvironment(cfg.Config.EnvMount, envMap); err != nil {
+		return "", id, err
 	}
 
 	cvm := ms.vmFactory(cfg, id, ms.logger)
+	ms.mu.Lock()
+	ms.publishEvent(id, EventVMProvisioning, cvm, "")
+	ms.mu.Unlock()
+
 	if err = cvm.Start(); err != nil {
-		return "", id, err
+		ms.mu.Lock()
+		delete(ms.history, id)
+		ms.mu.Unlock()
+		ms.vmLogs.drop(id)
+		return "", id, ms.launchError(cfg.Config, err)
 	}
 
 	ms.mu.Lock()
 	if ms.maxVMs > 0 && len(ms.vms) >= ms.maxVMs {
+		delete(ms.history, id)
 		ms.mu.Unlock()
+		ms.vmLogs.drop(id)
 		if stopErr := cvm.Stop(); stopErr != nil {
 			ms.logger.Error("Failed to stop VM after exceeding max limit", "vmID", id, "error", stopErr)
 		}
 		return "", id, ErrMaxVMsExceeded
 	}
 	ms.vms[id] = cvm
+	registered = true
 	ms.mu.Unlock()
 
-	if req.Ttl != "" {
-		ttl, err := time.ParseDuration(req.Ttl)
+	if err := ms.activateVM(id, cvm, cfg, req.Ttl); err != nil {
+		return "", id, err
+	}
+	ms.traceComputation(ctx, id)
+
+	return fmt.Sprint(agentPort), id, nil
+}
+
+// prepareVM builds the QEMU configuration for a new VM, creating its (empty)
+// mount directories and allocating the agent port. The VM is launched with
+// the machine profile, vCPU pinning and NUMA node of the request, the
+// configured ones when the request leaves them empty, and the GPUs it requests.
+func (ms *managerService) prepareVM(id string, req *CreateReq) (qemu.VMInfo, int, error) {
+	ms.mu.Lock()
+	config, err := ms.qemuCfg.WithProfile(req.MachineProfile)
+	ms.mu.Unlock()
+	if err != nil {
+		return qemu.VMInfo{}, 0, err
+	}
+
+	config = config.WithPlacement(req.HostCpus, req.NumaNode)
+	if err := ms.checkPlacement(config); err != nil {
+		return qemu.VMInfo{}, 0, err
+	}
+
+	config, err = config.WithGPUs(req.GpuDevices, req.GpuNvidiaCc)
+	if err != nil {
+		return qemu.VMInfo{}, 0, fmt.Errorf("%w: %v", ErrInvalidGPU, err)
+	}
+	if err := ms.checkGPUs(config); err != nil {
+		return qemu.VMInfo{}, 0, err
+	}
+
+	cfg := qemu.VMInfo{
+		Config:    config,
+		LaunchTCB: 0,
+	}
+
+	tmpCertsDir, err := os.MkdirTemp("/tmp", id)
+	if err != nil {
+		return cfg, 0, err
+	}
+
+	tmpEnvDir, err := os.MkdirTemp("/tmp", id)
+	if err != nil {
+		return cfg, 0, err
+	}
+
+	cfg.Config.CertsMount = tmpCertsDir
+	cfg.Config.EnvMount = tmpEnvDir
+
+	// Define the TCB that was present at launch of the VM.
+	cfg.LaunchTCB, err = ms.backend(cfg.Config).LaunchTCB()
+	if err != nil {
+		return cfg, 0, err
+	}
+
+	// In bridge mode the agent is reached directly on the guest address, so no host port is forwarded.
+	agentPort := cfg.Config.GuestFwdAgent
+	if cfg.Config.NetDevConfig.Mode != qemu.NetModeBridge {
+		agentPort, err = ms.ports.Allocate(id)
 		if err != nil {
-			return "", id, err
+			return cfg, 0, errors.Wrap(ErrFailedToAllocatePort, err)
 		}
+		cfg.Config.HostFwdAgent = agentPort
+	}
 
-		ms.ttlManager.SetTTL(id, ttl, func() { //nolint:contextcheck
-			if err := ms.RemoveVM(context.Background(), id); err != nil {
-				ms.logger.Error("Failed to remove VM after TTL expiry", "vmID", id, "error", err)
-			} else {
-				ms.logger.Info("Successfully removed VM after TTL expiry", "vmID", id)
-			}
-		})
+	if cfg.Config.VSockConfig.GuestCID != 0 {
+		cfg.Config.VSockConfig.GuestCID = ms.allocateGuestCID()
 	}
 
-	pid := cvm.GetProcess()
+	if cfg.Config.EnableSEVSNP {
+		todo := sha3.Sum256([]byte("TODO"))
+		// Define host-data value of QEMU for SEV-SNP, with a base64 encoding of the computation hash.
+		cfg.Config.SEVSNPConfig.HostData = base64.StdEncoding.EncodeToString(todo[:])
+	}
+
+	return cfg, agentPort, nil
+}
+
+// allocateGuestCID returns the lowest vsock CID from the configured one onwards
+// that is neither in use by another CVM nor handed out before.
+func (ms *managerService) allocateGuestCID() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	used := make(map[int]bool, len(ms.vms))
+	for _, cvm := range ms.vms {
+		if vmi, ok := cvm.GetConfig().(qemu.VMInfo); ok {
+			used[vmi.Config.VSockConfig.GuestCID] = true
+		}
+	}
+
+	cid := max(ms.nextGuestCID, ms.qemuCfg.VSockConfig.GuestCID)
+	for used[cid] {
+		cid++
+	}
+	ms.nextGuestCID = cid + 1
+
+	return cid
+}
 
+// activateVM sets the TTL of a registered VM, persists it and marks it as running.
+func (ms *managerService) activateVM(id string, cvm vm.VM, cfg qemu.VMInfo, ttl string) error {
 	state := qemu.VMState{
 		ID:     id,
 		VMinfo: cfg,
-		PID:    pid,
+		PID:    cvm.GetProcess(),
+		Tenant: ms.quotas.tenant(id),
 	}
+
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return err
+		}
+
+		state.TTL = ttl
+		state.Expiry = time.Now().Add(d)
+		ms.setTTL(id, ttl, d)
+	}
+
 	if err := ms.persistence.SaveVM(state); err != nil {
 		ms.logger.Error("Failed to persist VM state", "error", err)
 	}
 
 	ms.mu.Lock()
-	if err := ms.vms[id].Transition(manager.VmRunning); err != nil {
+	if err := cvm.Transition(manager.VmRunning); err != nil {
 		ms.logger.Warn("Failed to transition VM state", "cvm", id, "error", err)
 	}
+	ms.publishEvent(id, EventVMRunning, cvm, "")
 	ms.mu.Unlock()
 
-	return fmt.Sprint(agentPort), id, nil
+	ms.relayVMEvents(id, cvm)
+
+	return nil
+}
+
+// setTTL removes the VM once d elapses.
+func (ms *managerService) setTTL(id, ttl string, d time.Duration) {
+	ms.ttlManager.SetTTL(id, d, func() { //nolint:contextcheck
+		ms.mu.Lock()
+		if cvm, ok := ms.vms[id]; ok {
+			ms.publishEvent(id, EventTTLExpired, cvm, ttl)
+		}
+		ms.mu.Unlock()
+
+		if err := ms.RemoveVM(context.Background(), id); err != nil {
+			ms.logger.Error("Failed to remove VM after TTL expiry", "vmID", id, "error", err)
+		} else {
+			ms.logger.Info("Successfully removed VM after TTL expiry", "vmID", id)
+		}
+	})
 }
 
 func (ms *managerService) RemoveVM(ctx context.Context, computationID string) error {
@@ -268,102 +487,179 @@ func (ms *managerService) RemoveVM(ctx context.Context, computationID string) er
 	if !ok {
 		return ErrNotFound
 	}
-	if err := cvm.Stop(); err != nil {
-		return err
+	if cvm.State() != manager.StopComputationRun.String() {
+		if err := cvm.Stop(); err != nil {
+			return err
+		}
 	}
 	delete(ms.vms, computationID)
+	ms.ports.Release(computationID)
+	ms.quotas.release(computationID)
+	ms.forgetHeartbeats(computationID, cvm)
+
+	ms.publishEvent(computationID, EventVMRemoved, cvm, "")
+	ms.watchers.close(computationID)
+	delete(ms.history, computationID)
+	ms.vmLogs.drop(computationID)
+	ms.consoles.remove(computationID)
+	ms.logs.close(computationID)
 
 	if err := ms.persistence.DeleteVM(computationID); err != nil {
 		ms.logger.Error("Failed to delete persisted VM state", "error", err)
 	}
 
-	return nil
-}
+	// Recycle the freed capacity into the pool.
+	if ms.pool != nil {
+		go ms.fillPool()
+	}
 
-func (ms *managerService) ReturnCVMInfo(ctx context.Context) (string, int, string, string) {
-	return ms.qemuCfg.OVMFCodeConfig.Version, ms.qemuCfg.SMPCount, ms.qemuCfg.CPU, ms.eosVersion
+	return nil
 }
 
-// Shutdown gracefully shuts down the service.
-func (ms *managerService) Shutdown() error {
-	ms.logger.Info("Shutting down manager service")
-
-	ms.ttlManager.CancelAll()
-
+func (ms *managerService) StopVM(ctx context.Context, computationID string) (string, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	ms.vms = make(map[string]vm.VM)
+	cvm, ok := ms.vms[computationID]
+	if !ok {
+		return "", ErrNotFound
+	}
 
-	return nil
-}
+	if cvm.State() == manager.StopComputationRun.String() {
+		return cvm.State(), nil
+	}
+
+	ms.ttlManager.CancelTTL(computationID)
 
-func getFreePort(minPort, maxPort int) (int, error) {
-	if checkPortisFree(minPort) {
-		return minPort, nil
+	if err := cvm.Stop(); err != nil {
+		return cvm.State(), err
 	}
+	ms.ports.Release(computationID)
+	// Stopped VMs do not count against the quota of their tenant.
+	ms.quotas.release(computationID)
 
-	var wg sync.WaitGroup
-	portCh := make(chan int, 1)
+	ms.publishEvent(computationID, EventVMStopped, cvm, "")
 
-	for port := minPort; port <= maxPort; port++ {
-		wg.Add(1)
-		go func(p int) {
-			defer wg.Done()
-			if checkPortisFree(p) {
-				select {
-				case portCh <- p:
-				default:
-				}
-			}
-		}(port)
+	if err := ms.persistence.DeleteVM(computationID); err != nil {
+		ms.logger.Error("Failed to delete persisted VM state", "error", err)
 	}
 
-	go func() {
-		wg.Wait()
-		close(portCh)
-	}()
+	return cvm.State(), nil
+}
 
-	port, ok := <-portCh
+func (ms *managerService) AttachDataset(ctx context.Context, computationID, diskPath string) error {
+	ms.mu.Lock()
+	cvm, ok := ms.vms[computationID]
+	ms.mu.Unlock()
 	if !ok {
-		return 0, fmt.Errorf("failed to find free port in range %d-%d", minPort, maxPort)
+		return ErrNotFound
 	}
 
-	return port, nil
-}
+	if cvm.State() != manager.VmRunning.String() {
+		return errors.Wrap(ErrFailedToAttachDataset, fmt.Errorf("cvm is in state %s", cvm.State()))
+	}
 
-func checkPortisFree(port int) bool {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	path, err := ms.datasetDiskPath(diskPath)
 	if err != nil {
-		return false
+		return err
+	}
+
+	// Hot-adding waits on QMP, the manager lock is not held so other CVMs are not blocked meanwhile.
+	if err := cvm.AttachDisk(path); err != nil {
+		return errors.Wrap(ErrFailedToAttachDataset, err)
 	}
-	defer listener.Close()
 
-	return true
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if cvm, ok := ms.vms[computationID]; ok {
+		ms.publishEvent(computationID, EventDatasetAttached, cvm, path)
+	}
+
+	return nil
 }
 
-func decodeRange(input string) (int, int, error) {
-	re := regexp.MustCompile(`(\d+)-(\d+)`)
-	matches := re.FindStringSubmatch(input)
-	if len(matches) != 3 {
-		return 0, 0, fmt.Errorf("invalid input format: %s", input)
+// datasetDiskPath resolves the disk image path, relative paths are relative to
+// the dataset disk directory, and checks that the image is a regular file in
+// that directory.
+func (ms *managerService) datasetDiskPath(diskPath string) (string, error) {
+	dir := ms.qemuCfg.DatasetDiskConfig.Dir
+	if dir == "" {
+		return "", errors.Wrap(ErrFailedToAttachDataset, fmt.Errorf("no dataset disk directory is configured"))
+	}
+
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", errors.Wrap(ErrFailedToAttachDataset, err)
+	}
+	if !filepath.IsAbs(diskPath) {
+		diskPath = filepath.Join(dir, diskPath)
 	}
 
-	start, err := strconv.Atoi(matches[1])
+	path, err := filepath.EvalSymlinks(diskPath)
 	if err != nil {
-		return 0, 0, err
+		return "", errors.Wrap(ErrFailedToAttachDataset, err)
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", errors.Wrap(ErrMalformedEntity, fmt.Errorf("%s is not in the dataset disk directory %s", diskPath, dir))
 	}
 
-	end, err := strconv.Atoi(matches[2])
+	info, err := os.Stat(path)
 	if err != nil {
-		return 0, 0, err
+		return "", errors.Wrap(ErrFailedToAttachDataset, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.Wrap(ErrMalformedEntity, fmt.Errorf("%s is not a disk image file", diskPath))
+	}
+
+	return path, nil
+}
+
+func (ms *managerService) ReturnCVMInfo(ctx context.Context) (string, int, string, string) {
+	return ms.qemuCfg.OVMFCodeConfig.Version, ms.qemuCfg.SMPCount, ms.qemuCfg.CPU, ms.eosVersion
+}
+
+// AuditLog is served by the audit middleware when the manager keeps an audit log.
+func (ms *managerService) AuditLog(ctx context.Context) (*AuditLogRes, error) {
+	return nil, ErrAuditDisabled
+}
+
+func (ms *managerService) GetImages(ctx context.Context) ([]*Image, error) {
+	images := []*Image{
+		{Name: "kernel", Path: ms.qemuCfg.DiskImgConfig.KernelFile, Version: ms.eosVersion},
+		{Name: "rootfs", Path: ms.qemuCfg.DiskImgConfig.RootFsFile, Version: ms.eosVersion},
 	}
 
-	if start > end {
-		return 0, 0, fmt.Errorf("invalid range: %d-%d", start, end)
+	images = append(images, ms.backend(ms.qemuCfg).Firmware())
+
+	for _, img := range images {
+		digest, err := internal.ChecksumHex(img.Path)
+		if err != nil {
+			return nil, errors.Wrap(ErrFailedToReadImage, err)
+		}
+		img.Digest = digest
 	}
 
-	return start, end, nil
+	return images, nil
+}
+
+// Shutdown gracefully shuts down the service.
+func (ms *managerService) Shutdown() error {
+	ms.logger.Info("Shutting down manager service")
+
+	ms.ttlManager.CancelAll()
+	ms.stopPool()
+	ms.stopHeartbeats()
+	ms.stopLogs()
+
+	ms.mu.Lock()
+	ms.vms = make(map[string]vm.VM)
+	ms.mu.Unlock()
+
+	for _, f := range ms.forwarders {
+		f.close()
+	}
+
+	return nil
 }
 
 func (ms *managerService) restoreVMs() error {
@@ -371,6 +667,7 @@ func (ms *managerService) restoreVMs() error {
 	if err != nil {
 		return err
 	}
+	defer qemu.CloseInheritedOutputs()
 
 	for _, state := range states {
 		if !ms.processExists(state.PID) {
@@ -384,16 +681,42 @@ func (ms *managerService) restoreVMs() error {
 		cvm := ms.vmFactory(state.VMinfo, state.ID, ms.logger)
 
 		if err = cvm.SetProcess(state.PID); err != nil {
+			if errors.Contains(err, qemu.ErrNotVMProcess) {
+				if err := ms.persistence.DeleteVM(state.ID); err != nil {
+					ms.logger.Error("Failed to delete persisted VM state", "computation", state.ID, "error", err)
+				}
+				ms.logger.Info("Deleted persisted state of VM whose PID was reused", "computation", state.ID, "pid", state.PID)
+				continue
+			}
 			ms.logger.Warn("Failed to reattach to process", "computation", state.ID, "pid", state.PID, "error", err)
 			continue
 		}
 
+		if state.VMinfo.Config.NetDevConfig.Mode != qemu.NetModeBridge {
+			if err := ms.ports.Reserve(state.ID, state.VMinfo.Config.HostFwdAgent); err != nil {
+				ms.logger.Warn("Agent port of restored VM conflicts with another VM", "computation", state.ID, "port", state.VMinfo.Config.HostFwdAgent, "error", err)
+			}
+		}
+
 		if err := cvm.Transition(manager.VmRunning); err != nil {
 			ms.logger.Warn("Failed to transition VM state", "computation", state.ID, "error", err)
 		}
 
+		ms.mu.Lock()
 		ms.vms[state.ID] = cvm
-		ms.logger.Info("Successfully restored VM state", "id", state.ID, "computationId", state.ID, "pid", state.PID)
+		ms.mu.Unlock()
+		if res, err := resources(state.Tenant, state.VMinfo.Config); err == nil {
+			ms.quotas.restore(state.ID, res)
+		}
+		ms.vmLogs.open(state.ID)
+		ms.relayVMEvents(state.ID, cvm)
+		ms.publishEvent(state.ID, EventVMAdopted, cvm, "")
+
+		if !state.Expiry.IsZero() {
+			ms.setTTL(state.ID, state.TTL, time.Until(state.Expiry))
+		}
+
+		ms.logger.Info("Successfully restored VM state", "id", state.ID, "computationId", state.ID, "pid", state.PID, "cid", state.VMinfo.Config.VSockConfig.GuestCID, "port", state.VMinfo.Config.HostFwdAgent)
 	}
 
 	return nil
@@ -415,33 +738,20 @@ func (ms *managerService) processExists(pid int) bool {
 	return false
 }
 
-func tempCertMount(id string, req *CreateReq) (string, error) {
-	dir, err := os.MkdirTemp("/tmp", id)
-	if err != nil {
-		return "", err
-	}
-
-	if err = os.WriteFile(fmt.Sprintf("%s/%s", dir, "cert.pem"), req.AgentCvmClientCert, 0o644); err != nil {
-		return "", err
-	}
-
-	if err = os.WriteFile(fmt.Sprintf("%s/%s", dir, "key.pem"), req.AgentCvmClientKey, 0o644); err != nil {
-		return "", err
+func writeCerts(dir string, req *CreateReq) error {
+	if err := os.WriteFile(fmt.Sprintf("%s/%s", dir, "cert.pem"), req.AgentCvmClientCert, 0o644); err != nil {
+		return err
 	}
 
-	if err = os.WriteFile(fmt.Sprintf("%s/%s", dir, "ca.pem"), req.AgentCvmServerCaCert, 0o644); err != nil {
-		return "", err
+	if err := os.WriteFile(fmt.Sprintf("%s/%s", dir, "key.pem"), req.AgentCvmClientKey, 0o644); err != nil {
+		return err
 	}
 
-	return dir, nil
+	return os.WriteFile(fmt.Sprintf("%s/%s", dir, "ca.pem"), req.AgentCvmServerCaCert, 0o644)
 }
 
-func tmpEnvironment(id string, req *CreateReq) (string, error) {
-	dir, err := os.MkdirTemp("/tmp", id)
-	if err != nil {
-		return "", err
-	}
-
+// agentEnvironment returns the agent configuration of the CVM as environment variables.
+func agentEnvironment(id string, req *CreateReq) map[string]string {
 	envMap := map[string]string{
 		agentLogLevelKey:   req.AgentLogLevel,
 		agentCvmGrpcUrlKey: req.AgentCvmServerUrl,
@@ -460,20 +770,37 @@ func tmpEnvironment(id string, req *CreateReq) (string, error) {
 		envMap[agentCvmServerCaCertKey] = defServerCaCertPath
 	}
 
+	return envMap
+}
+
+// measuredAgentParams moves the agent configuration that is safe to measure from
+// envMap to the returned kernel parameters. The certificates token is a secret
+// and stays in the environment file.
+func measuredAgentParams(envMap map[string]string) map[string]string {
+	params := make(map[string]string)
+	for k, v := range envMap {
+		if k == agentCaToken {
+			continue
+		}
+		params[k] = v
+		delete(envMap, k)
+	}
+
+	return params
+}
+
+func writeEnvironment(dir string, envMap map[string]string) error {
 	envFile, err := os.OpenFile(fmt.Sprintf("%s/%s", dir, cvmEnvironmentFile), os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	for k, v := range envMap {
 		if _, err = envFile.WriteString(fmt.Sprintf("%s=%s\n", k, v)); err != nil {
-			return "", err
+			envFile.Close()
+			return err
 		}
 	}
 
-	if err = envFile.Close(); err != nil {
-		return "", err
-	}
-
-	return dir, nil
+	return envFile.Close()
 }
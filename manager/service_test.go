@@ -6,11 +6,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
 
 	mglog "github.com/absmach/supermq/logger"
 	"github.com/absmach/supermq/pkg/errors"
@@ -21,8 +22,15 @@ import (
 	persistenceMocks "github.com/ultravioletrs/cocos/manager/qemu/mocks"
 	"github.com/ultravioletrs/cocos/manager/vm"
 	"github.com/ultravioletrs/cocos/manager/vm/mocks"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
 )
 
+// newTestPorts returns an allocator for the agent ports of the test VMs.
+func newTestPorts() *qemu.PortAllocator {
+	ports, _ := qemu.NewPortAllocator("6000-6100")
+	return ports
+}
+
 func TestNew(t *testing.T) {
 	cfg := qemu.Config{
 		HostFwdRange: "6000-6100",
@@ -30,7 +38,7 @@ func TestNew(t *testing.T) {
 	logger := slog.Default()
 	vmf := new(mocks.Provider)
 
-	service, err := New(cfg, "", "", "", logger, vmf.Execute, "", 10)
+	service, err := New(cfg, "", "", "", logger, vmf.Execute, "", 10, QuotaConfig{}, PoolConfig{}, HeartbeatConfig{}, LogsConfig{}, ConsoleConfig{}, nil, nil)
 	require.NoError(t, err)
 
 	assert.NotNil(t, service)
@@ -117,6 +125,7 @@ func TestRun(t *testing.T) {
 				vmFactory:                   vmf.Execute,
 				persistence:                 persistence,
 				ttlManager:                  NewTTLManager(),
+				ports:                       newTestPorts(),
 			}
 
 			if tt.name == "with exceeded max vms" {
@@ -143,6 +152,139 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestCreateVMAgentCmdline(t *testing.T) {
+	tests := []struct {
+		name     string
+		logLevel string
+		err      error
+	}{
+		{
+			name:     "agent configuration on kernel command line",
+			logLevel: "info",
+		},
+		{
+			name:     "agent configuration not representable on kernel command line",
+			logLevel: "\"info\"",
+			err:      ErrInvalidKernelParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmf := new(mocks.Provider)
+			vmMock := new(mocks.VM)
+			persistence := new(persistenceMocks.Persistence)
+
+			var info qemu.VMInfo
+			vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock).Run(func(args mock.Arguments) {
+				info = args.Get(0).(qemu.VMInfo)
+			}).Maybe()
+			vmMock.On("Start").Return(nil).Maybe()
+			vmMock.On("GetProcess").Return(1234).Maybe()
+			vmMock.On("Transition", mock.Anything).Return(nil).Maybe()
+			vmMock.On("State").Return(pkgmanager.VmRunning.String()).Maybe()
+			persistence.On("SaveVM", mock.Anything).Return(nil).Maybe()
+
+			ms := &managerService{
+				qemuCfg:     qemu.Config{AgentCmdline: true},
+				logger:      slog.Default(),
+				vms:         make(map[string]vm.VM),
+				vmFactory:   vmf.Execute,
+				persistence: persistence,
+				ttlManager:  NewTTLManager(),
+				ports:       newTestPorts(),
+			}
+
+			_, id, err := ms.CreateVM(context.Background(), &CreateReq{
+				AgentLogLevel:     tt.logLevel,
+				AgentCvmServerUrl: "10.0.2.2:7001",
+				AgentCertsToken:   "token",
+			})
+			if tt.err != nil {
+				assert.True(t, errors.Contains(err, tt.err), "expected %v, got %v", tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]string{
+				agentLogLevelKey:   tt.logLevel,
+				agentCvmGrpcUrlKey: "10.0.2.2:7001",
+				agentCvmId:         id,
+				agentCvmCaUrl:      "",
+			}, info.Config.AgentParams)
+
+			envFile, err := os.ReadFile(path.Join(info.Config.EnvMount, cvmEnvironmentFile))
+			require.NoError(t, err)
+			assert.Equal(t, agentCaToken+"=token\n", string(envFile))
+		})
+	}
+}
+
+func TestCreateVMMachineProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		expected string
+		err      error
+	}{
+		{
+			name:     "configured profile",
+			expected: qemu.ProfileDefault,
+		},
+		{
+			name:     "microvm profile",
+			profile:  qemu.ProfileMicroVM,
+			expected: qemu.ProfileMicroVM,
+		},
+		{
+			name:    "unknown profile",
+			profile: "firecracker",
+			err:     qemu.ErrInvalidConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmf := new(mocks.Provider)
+			vmMock := new(mocks.VM)
+			persistence := new(persistenceMocks.Persistence)
+
+			var info qemu.VMInfo
+			vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock).Run(func(args mock.Arguments) {
+				info = args.Get(0).(qemu.VMInfo)
+			}).Maybe()
+			vmMock.On("Start").Return(nil).Maybe()
+			vmMock.On("GetProcess").Return(1234).Maybe()
+			vmMock.On("Transition", mock.Anything).Return(nil).Maybe()
+			vmMock.On("State").Return(pkgmanager.VmRunning.String()).Maybe()
+			persistence.On("SaveVM", mock.Anything).Return(nil).Maybe()
+
+			ms := &managerService{
+				qemuCfg:     qemu.Config{Profile: qemu.ProfileDefault},
+				logger:      slog.Default(),
+				vms:         make(map[string]vm.VM),
+				vmFactory:   vmf.Execute,
+				persistence: persistence,
+				ttlManager:  NewTTLManager(),
+				ports:       newTestPorts(),
+			}
+
+			_, _, err := ms.CreateVM(context.Background(), &CreateReq{
+				AgentCvmServerUrl: "10.0.2.2:7001",
+				MachineProfile:    tt.profile,
+			})
+			if tt.err != nil {
+				assert.True(t, errors.Contains(err, tt.err), "expected %v, got %v", tt.err, err)
+				vmf.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, info.Config.Profile)
+			removeMounts(info)
+		})
+	}
+}
+
 func TestStop(t *testing.T) {
 	vmf := new(mocks.Provider)
 	vmMock := new(mocks.VM)
@@ -187,9 +329,11 @@ func TestStop(t *testing.T) {
 				vms:         make(map[string]vm.VM),
 				persistence: persistence,
 				ttlManager:  NewTTLManager(),
+				ports:       newTestPorts(),
 			}
 			vmMock := new(mocks.VM)
 
+			vmMock.On("State").Return(pkgmanager.VmRunning.String())
 			if tt.vmStopError == nil {
 				vmMock.On("Stop").Return(nil).Once()
 			} else {
@@ -215,44 +359,230 @@ func TestStop(t *testing.T) {
 	}
 }
 
-func TestGetFreePort(t *testing.T) {
-	port, err := getFreePort(6000, 6100)
+func TestStopVM(t *testing.T) {
+	tests := []struct {
+		name          string
+		computationID string
+		initialState  pkgmanager.ManagerState
+		registered    bool
+		vmStopError   error
+		expectedState string
+		expectedError error
+		released      bool
+	}{
+		{
+			name:          "Successful stop",
+			computationID: "running-computation",
+			initialState:  pkgmanager.VmRunning,
+			registered:    true,
+			expectedState: pkgmanager.StopComputationRun.String(),
+			released:      true,
+		},
+		{
+			name:          "Already stopped",
+			computationID: "stopped-computation",
+			initialState:  pkgmanager.StopComputationRun,
+			registered:    true,
+			expectedState: pkgmanager.StopComputationRun.String(),
+		},
+		{
+			name:          "Non-existent computation",
+			computationID: "non-existent-computation",
+			expectedError: ErrNotFound,
+		},
+		{
+			name:          "VM stop error",
+			computationID: "error-computation",
+			initialState:  pkgmanager.VmRunning,
+			registered:    true,
+			vmStopError:   assert.AnError,
+			expectedState: pkgmanager.VmRunning.String(),
+			expectedError: assert.AnError,
+		},
+	}
 
-	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, port, 6000)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			persistence := new(persistenceMocks.Persistence)
+			ms := &managerService{
+				logger:      slog.Default(),
+				vms:         make(map[string]vm.VM),
+				persistence: persistence,
+				ttlManager:  NewTTLManager(),
+				ports:       newTestPorts(),
+			}
 
-	_, err = net.Listen("tcp", net.JoinHostPort("localhost", fmt.Sprint(port)))
-	assert.NoError(t, err)
+			state := tt.initialState
+			vmMock := new(mocks.VM)
+			vmMock.On("State").Return(func() string { return state.String() })
+			vmMock.On("Stop").Return(func() error {
+				if tt.vmStopError != nil {
+					return tt.vmStopError
+				}
+				state = pkgmanager.StopComputationRun
+				return nil
+			})
+			persistence.On("DeleteVM", tt.computationID).Return(nil)
 
-	port, err = getFreePort(6000, 6100)
-	assert.NoError(t, err)
-	assert.Greater(t, port, 6000)
+			if tt.registered {
+				ms.vms[tt.computationID] = vmMock
+				require.NoError(t, ms.ports.Reserve(tt.computationID, 6001))
+			}
+
+			res, err := ms.StopVM(context.Background(), tt.computationID)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedState, res)
+			if tt.registered {
+				assert.Contains(t, ms.vms, tt.computationID)
+				_, reserved := ms.ports.Port(tt.computationID)
+				assert.Equal(t, !tt.released, reserved)
+			}
+			if tt.initialState == pkgmanager.StopComputationRun {
+				vmMock.AssertNotCalled(t, "Stop")
+			}
+		})
+	}
 }
 
-func TestDecodeRange(t *testing.T) {
+func TestAttachDataset(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	disk := filepath.Join(dir, "dataset.img")
+	require.NoError(t, os.WriteFile(disk, []byte("dataset"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "images"), 0o755))
+
+	outside := filepath.Join(t.TempDir(), "host.img")
+	require.NoError(t, os.WriteFile(outside, []byte("host"), 0o644))
+	escape, err := filepath.Rel(dir, outside)
+	require.NoError(t, err)
+	link := filepath.Join(dir, "link.img")
+	require.NoError(t, os.Symlink(outside, link))
+
 	tests := []struct {
-		name      string
-		input     string
-		wantStart int
-		wantEnd   int
-		wantErr   bool
+		name          string
+		computationID string
+		diskPath      string
+		attachedPath  string
+		state         pkgmanager.ManagerState
+		registered    bool
+		attachErr     error
+		expectedError error
 	}{
-		{"Valid range", "1-5", 1, 5, false},
-		{"Invalid format", "1:5", 0, 0, true},
-		{"Start greater than end", "5-1", 0, 0, true},
-		{"Non-numeric input", "a-b", 0, 0, true},
-		{"Single number", "5", 0, 0, true},
+		{
+			name:          "Successful attach",
+			computationID: "running-computation",
+			diskPath:      disk,
+			attachedPath:  disk,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+		},
+		{
+			name:          "Path relative to the dataset disk directory",
+			computationID: "running-computation",
+			diskPath:      "dataset.img",
+			attachedPath:  disk,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+		},
+		{
+			name:          "Disk image outside the dataset disk directory",
+			computationID: "running-computation",
+			diskPath:      outside,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			expectedError: ErrMalformedEntity,
+		},
+		{
+			name:          "Relative path leaving the dataset disk directory",
+			computationID: "running-computation",
+			diskPath:      escape,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			expectedError: ErrMalformedEntity,
+		},
+		{
+			name:          "Symlink leaving the dataset disk directory",
+			computationID: "running-computation",
+			diskPath:      link,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			expectedError: ErrMalformedEntity,
+		},
+		{
+			name:          "Non-existent computation",
+			computationID: "non-existent-computation",
+			diskPath:      disk,
+			expectedError: ErrNotFound,
+		},
+		{
+			name:          "Stopped computation",
+			computationID: "stopped-computation",
+			diskPath:      disk,
+			state:         pkgmanager.StopComputationRun,
+			registered:    true,
+			expectedError: ErrFailedToAttachDataset,
+		},
+		{
+			name:          "Missing disk image",
+			computationID: "running-computation",
+			diskPath:      disk + ".missing",
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			expectedError: ErrFailedToAttachDataset,
+		},
+		{
+			name:          "Disk image is a directory",
+			computationID: "running-computation",
+			diskPath:      filepath.Join(dir, "images"),
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			expectedError: ErrMalformedEntity,
+		},
+		{
+			name:          "Hotplug failure",
+			computationID: "running-computation",
+			diskPath:      disk,
+			attachedPath:  disk,
+			state:         pkgmanager.VmRunning,
+			registered:    true,
+			attachErr:     assert.AnError,
+			expectedError: ErrFailedToAttachDataset,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			start, end, err := decodeRange(tt.input)
-			if tt.wantErr {
-				assert.Error(t, err)
-			} else {
+			ms := &managerService{
+				logger:   slog.Default(),
+				vms:      make(map[string]vm.VM),
+				watchers: newWatchers(),
+				qemuCfg:  qemu.Config{DatasetDiskConfig: qemu.DatasetDiskConfig{Dir: dir}},
+			}
+
+			vmMock := new(mocks.VM)
+			vmMock.On("State").Return(tt.state.String())
+			vmMock.On("AttachDisk", tt.attachedPath).Run(func(mock.Arguments) {
+				locked := !ms.mu.TryLock()
+				if !locked {
+					ms.mu.Unlock()
+				}
+				assert.False(t, locked, "the disk is hot-added without holding the manager lock")
+			}).Return(tt.attachErr)
+
+			if tt.registered {
+				ms.vms[tt.computationID] = vmMock
+			}
+
+			err := ms.AttachDataset(context.Background(), tt.computationID, tt.diskPath)
+			if tt.expectedError == nil {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.wantStart, start)
-				assert.Equal(t, tt.wantEnd, end)
+				vmMock.AssertCalled(t, "AttachDisk", tt.attachedPath)
+				return
+			}
+			assert.True(t, errors.Contains(err, tt.expectedError), "expected %v, got %v", tt.expectedError, err)
+			if tt.attachedPath == "" {
+				vmMock.AssertNotCalled(t, "AttachDisk", mock.Anything)
 			}
 		})
 	}
@@ -265,11 +595,13 @@ func TestRestoreVMs(t *testing.T) {
 	vmf.On("Execute", mock.Anything, mock.Anything, mock.Anything).Return(vmMock)
 	vmMock.On("SetProcess", mock.Anything).Return(nil)
 	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
 	ms := &managerService{
 		persistence: mockPersistence,
 		vms:         make(map[string]vm.VM),
 		vmFactory:   vmf.Execute,
 		logger:      mglog.NewMock(),
+		ports:       newTestPorts(),
 	}
 
 	cmd := exec.Command("echo", "test")
@@ -281,7 +613,7 @@ func TestRestoreVMs(t *testing.T) {
 	assert.NoError(t, err)
 
 	mockPersistence.On("LoadVMs").Return([]qemu.VMState{
-		{ID: "vm1", PID: cmd.Process.Pid},
+		{ID: "vm1", PID: cmd.Process.Pid, VMinfo: qemu.VMInfo{Config: qemu.Config{NetDevConfig: qemu.NetDevConfig{HostFwdAgent: 6001}}}},
 		{ID: "vm2", PID: cmd2.Process.Pid},
 		{ID: "vm3", PID: cmd2.Process.Pid},
 	}, nil)
@@ -295,9 +627,102 @@ func TestRestoreVMs(t *testing.T) {
 	assert.Len(t, ms.vms, 1)
 	assert.Contains(t, ms.vms, "vm1")
 
+	port, ok := ms.ports.Port("vm1")
+	assert.True(t, ok)
+	assert.Equal(t, 6001, port, "the agent port of restored VMs stays reserved")
+	_, ok = ms.ports.Port("vm2")
+	assert.False(t, ok)
+
+	mockPersistence.AssertExpectations(t)
+}
+
+func TestRestoreVMsAdoption(t *testing.T) {
+	mockPersistence := new(persistenceMocks.Persistence)
+	adopted := new(mocks.VM)
+	adopted.On("SetProcess", os.Getpid()).Return(nil)
+	adopted.On("Transition", mock.Anything).Return(nil)
+	adopted.On("State").Return(pkgmanager.VmRunning.String())
+	reused := new(mocks.VM)
+	reused.On("SetProcess", os.Getpid()).Return(qemu.ErrNotVMProcess)
+
+	ms := &managerService{
+		persistence: mockPersistence,
+		vms:         make(map[string]vm.VM),
+		vmFactory: func(_ any, id string, _ *slog.Logger) vm.VM {
+			if id == "vm1" {
+				return adopted
+			}
+			return reused
+		},
+		logger: mglog.NewMock(),
+		ports:  newTestPorts(),
+	}
+
+	agentPort := func(port int) qemu.VMInfo {
+		return qemu.VMInfo{Config: qemu.Config{NetDevConfig: qemu.NetDevConfig{HostFwdAgent: port}}}
+	}
+	mockPersistence.On("LoadVMs").Return([]qemu.VMState{
+		{ID: "vm1", PID: os.Getpid(), VMinfo: agentPort(6001)},
+		{ID: "vm2", PID: os.Getpid(), VMinfo: agentPort(6002)},
+	}, nil)
+	mockPersistence.On("DeleteVM", "vm2").Return(nil)
+
+	require.NoError(t, ms.restoreVMs())
+
+	assert.Contains(t, ms.vms, "vm1")
+	require.NotEmpty(t, ms.history["vm1"])
+	assert.Equal(t, EventVMAdopted, ms.history["vm1"][len(ms.history["vm1"])-1].EventType)
+
+	assert.NotContains(t, ms.vms, "vm2", "a process that reused the PID of the VM is not adopted")
+	_, ok := ms.ports.Port("vm2")
+	assert.False(t, ok)
 	mockPersistence.AssertExpectations(t)
 }
 
+func TestRestoreVMsTTL(t *testing.T) {
+	mockPersistence := new(persistenceMocks.Persistence)
+	vmMock := new(mocks.VM)
+	vmMock.On("SetProcess", mock.Anything).Return(nil)
+	vmMock.On("Transition", mock.Anything).Return(nil)
+	vmMock.On("State").Return(pkgmanager.VmRunning.String())
+	ms := &managerService{
+		persistence: mockPersistence,
+		vms:         make(map[string]vm.VM),
+		vmFactory:   func(any, string, *slog.Logger) vm.VM { return vmMock },
+		logger:      mglog.NewMock(),
+		ttlManager:  NewTTLManager(),
+		ports:       newTestPorts(),
+	}
+	defer ms.ttlManager.CancelAll()
+
+	mockPersistence.On("LoadVMs").Return([]qemu.VMState{
+		{ID: "vm1", PID: os.Getpid(), TTL: "1h", Expiry: time.Now().Add(time.Hour)},
+		{ID: "vm2", PID: os.Getpid()},
+	}, nil)
+
+	err := ms.restoreVMs()
+	assert.NoError(t, err)
+
+	ms.ttlManager.mu.RLock()
+	defer ms.ttlManager.mu.RUnlock()
+	assert.Contains(t, ms.ttlManager.timers, "vm1")
+	assert.NotContains(t, ms.ttlManager.timers, "vm2")
+}
+
+func TestAllocateGuestCID(t *testing.T) {
+	running := new(mocks.VM)
+	running.On("GetConfig").Return(qemu.VMInfo{Config: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: 4}}})
+
+	ms := &managerService{
+		qemuCfg: qemu.Config{VSockConfig: qemu.VSockConfig{GuestCID: 3}},
+		vms:     map[string]vm.VM{"vm1": running},
+	}
+
+	assert.Equal(t, 3, ms.allocateGuestCID())
+	assert.Equal(t, 5, ms.allocateGuestCID())
+	assert.Equal(t, 6, ms.allocateGuestCID())
+}
+
 func TestProcessExists(t *testing.T) {
 	ms := &managerService{}
 
@@ -310,10 +735,66 @@ func TestProcessExists(t *testing.T) {
 	}
 }
 
+func TestGetImages(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	kernel := path.Join(tmpDir, "bzImage")
+	rootfs := path.Join(tmpDir, "rootfs.cpio.gz")
+	ovmf := path.Join(tmpDir, "OVMF_CODE.fd")
+	for _, f := range []string{kernel, rootfs, ovmf} {
+		require.NoError(t, os.WriteFile(f, []byte(f), 0o644))
+	}
+
+	tests := []struct {
+		name     string
+		cfg      qemu.Config
+		expected []string
+		err      error
+	}{
+		{
+			name: "images with OVMF firmware",
+			cfg: qemu.Config{
+				DiskImgConfig:  qemu.DiskImgConfig{KernelFile: kernel, RootFsFile: rootfs},
+				OVMFCodeConfig: qemu.OVMFCodeConfig{File: ovmf, Version: "edk2-stable202408"},
+			},
+			expected: []string{"kernel", "rootfs", "ovmf"},
+		},
+		{
+			name: "missing kernel image",
+			cfg: qemu.Config{
+				DiskImgConfig:  qemu.DiskImgConfig{KernelFile: path.Join(tmpDir, "missing"), RootFsFile: rootfs},
+				OVMFCodeConfig: qemu.OVMFCodeConfig{File: ovmf},
+			},
+			err: ErrFailedToReadImage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := &managerService{qemuCfg: tt.cfg, eosVersion: "v0.1.0"}
+
+			images, err := ms.GetImages(context.Background())
+			assert.True(t, errors.Contains(err, tt.err), fmt.Sprintf("expected error %v, got %v", tt.err, err))
+			if tt.err != nil {
+				return
+			}
+
+			require.Len(t, images, len(tt.expected))
+			for i, img := range images {
+				assert.Equal(t, tt.expected[i], img.Name)
+				assert.Len(t, img.Digest, 64)
+			}
+			assert.Equal(t, "v0.1.0", images[0].Version)
+			assert.Equal(t, "edk2-stable202408", images[2].Version)
+		})
+	}
+}
+
 func TestShutdown(t *testing.T) {
 	ms := &managerService{
 		vms:        make(map[string]vm.VM),
 		ttlManager: NewTTLManager(),
+		ports:      newTestPorts(),
 		logger:     mglog.NewMock(),
 	}
 
@@ -0,0 +1,303 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package snpcerts keeps the AMD SEV-SNP certificates attestation reports are
+// verified with in a cache, so that the AMD Key Distribution Service (KDS) is
+// asked once for the ARK and ASK of a product and for the VCEK of a chip at a
+// TCB version, instead of on every attestation.
+package snpcerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+)
+
+const (
+	// DefaultKDSURL is the AMD Key Distribution Service.
+	DefaultKDSURL = "https://kdsintf.amd.com"
+
+	certChainFile = "cert_chain.pem"
+	vcekDir       = "vcek"
+	// maxCertSize bounds the responses of the KDS, certificates are a few KiB.
+	maxCertSize = 1 << 20
+)
+
+var (
+	// ErrInvalidRequest indicates a chain requested for an unknown product or a malformed chip ID.
+	ErrInvalidRequest = errors.New("invalid certificate chain request")
+	// ErrFetch indicates a certificate that could not be fetched from the KDS.
+	ErrFetch = errors.New("failed to fetch certificate from KDS")
+	// ErrInvalidCertificate indicates a certificate that does not match the chip and TCB it is cached for.
+	ErrInvalidCertificate = errors.New("invalid certificate")
+	// ErrInvalidCertTable indicates an extended certificate table without a VCEK.
+	ErrInvalidCertTable = errors.New("invalid certificate table")
+)
+
+// Config is where the manager keeps and fetches certificates from.
+type Config struct {
+	// Dir holds the cached certificates, by product, chip ID and TCB version.
+	Dir string `env:"MANAGER_SNP_CERTS_DIR"       envDefault:"/var/cache/cocos/snp-certs"`
+	// KDSURL is the KDS, or a mirror of it, the certificates missing from the cache are fetched from.
+	KDSURL string `env:"MANAGER_SNP_KDS_URL"         envDefault:"https://kdsintf.amd.com"`
+	// CertTable is the extended certificate table of the host the cache is preloaded with.
+	CertTable string `env:"MANAGER_SNP_CERT_TABLE"      envDefault:""`
+}
+
+// Chain is the DER encoded certificate chain of an attestation report.
+type Chain struct {
+	ARK  []byte
+	ASK  []byte
+	VCEK []byte
+}
+
+// Cache fetches certificates from the KDS and keeps them on disk.
+type Cache struct {
+	// mu serializes fetches, so concurrent attestations of a chip ask the KDS once.
+	mu     sync.Mutex
+	dir    string
+	kds    string
+	client *http.Client
+}
+
+// New returns the cache of the configuration, certificates are fetched with
+// http.DefaultClient if client is nil.
+func New(cfg Config, client *http.Client) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	kdsURL := strings.TrimSuffix(cfg.KDSURL, "/")
+	if kdsURL == "" {
+		kdsURL = DefaultKDSURL
+	}
+
+	return &Cache{dir: cfg.Dir, kds: kdsURL, client: client}
+}
+
+// Chain returns the certificate chain of reports of the chip at the reported
+// TCB version, fetching the certificates missing from the cache.
+func (c *Cache) Chain(ctx context.Context, productLine string, chipID []byte, reportedTCB uint64) (Chain, error) {
+	if _, err := kds.ParseProductLine(productLine); err != nil {
+		return Chain{}, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if len(chipID) != abi.ChipIDSize {
+		return Chain{}, fmt.Errorf("%w: chip ID is %d bytes, expected %d", ErrInvalidRequest, len(chipID), abi.ChipIDSize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ask, ark, err := c.productChain(ctx, productLine)
+	if err != nil {
+		return Chain{}, err
+	}
+
+	vcek, err := c.vcek(ctx, productLine, chipID, reportedTCB)
+	if err != nil {
+		return Chain{}, err
+	}
+
+	return Chain{ARK: ark, ASK: ask, VCEK: vcek}, nil
+}
+
+// Preload adds the certificates of the extended certificate table at path,
+// the table the host hands to guests with their attestation reports, to the
+// cache. It returns the product line of the host.
+func (c *Cache) Preload(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var table abi.CertTable
+	if err := table.Unmarshal(data); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCertTable, err)
+	}
+
+	vcek, err := table.GetByGUIDString(abi.VcekGUID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCertTable, err)
+	}
+
+	ext, err := vcekExtensions(vcek)
+	if err != nil {
+		return "", err
+	}
+	productLine := kds.ProductLineOfProductName(ext.ProductName)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := write(c.vcekPath(productLine, ext.HWID, uint64(ext.TCBVersion)), vcek); err != nil {
+		return "", err
+	}
+
+	// Tables may hold the VCEK only, the ARK and ASK are then fetched on first use.
+	ark, arkErr := table.GetByGUIDString(abi.ArkGUID)
+	ask, askErr := table.GetByGUIDString(abi.AskGUID)
+	if arkErr != nil || askErr != nil {
+		return productLine, nil
+	}
+
+	bundle := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ask}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ark})...)
+
+	return productLine, write(c.certChainPath(productLine), bundle)
+}
+
+// productChain returns the ASK and ARK of the product.
+func (c *Cache) productChain(ctx context.Context, productLine string) ([]byte, []byte, error) {
+	path := c.certChainPath(productLine)
+	if bundle, err := os.ReadFile(path); err == nil {
+		// A corrupted bundle is fetched anew.
+		if ask, ark, err := kds.ParseProductCertChain(bundle); err == nil {
+			return ask, ark, nil
+		}
+	}
+
+	bundle, err := c.fetch(ctx, kds.ProductCertChainURL(abi.VcekReportSigner, productLine))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ask, ark, err := kds.ParseProductCertChain(bundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	if err := write(path, bundle); err != nil {
+		return nil, nil, err
+	}
+
+	return ask, ark, nil
+}
+
+// vcek returns the VCEK of the chip at the TCB version.
+func (c *Cache) vcek(ctx context.Context, productLine string, chipID []byte, tcb uint64) ([]byte, error) {
+	path := c.vcekPath(productLine, chipID, tcb)
+	if cert, err := os.ReadFile(path); err == nil {
+		if err := checkVCEK(cert, chipID, tcb); err == nil {
+			return cert, nil
+		}
+	}
+
+	cert, err := c.fetch(ctx, kds.VCEKCertURL(productLine, chipID, kds.TCBVersion(tcb)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVCEK(cert, chipID, tcb); err != nil {
+		return nil, err
+	}
+
+	if err := write(path, cert); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// fetch returns the response of the KDS to the KDS URL, asking the configured
+// KDS instead.
+func (c *Cache) fetch(ctx context.Context, kdsURL string) ([]byte, error) {
+	source := c.kds + strings.TrimPrefix(kdsURL, DefaultKDSURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s: %w", ErrFetch, source, err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s: %w", ErrFetch, source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w from %s: %s", ErrFetch, source, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCertSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s: %w", ErrFetch, source, err)
+	}
+
+	return body, nil
+}
+
+func (c *Cache) certChainPath(productLine string) string {
+	return filepath.Join(c.dir, productLine, certChainFile)
+}
+
+func (c *Cache) vcekPath(productLine string, chipID []byte, tcb uint64) string {
+	return filepath.Join(c.dir, productLine, vcekDir, hex.EncodeToString(chipID), fmt.Sprintf("%016x.der", tcb))
+}
+
+// checkVCEK verifies that the VCEK was issued for the chip at the TCB version.
+func checkVCEK(cert, chipID []byte, tcb uint64) error {
+	ext, err := vcekExtensions(cert)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(ext.HWID, chipID) || uint64(ext.TCBVersion) != tcb {
+		return fmt.Errorf("%w: VCEK is for chip %x at TCB %#x", ErrInvalidCertificate, ext.HWID, uint64(ext.TCBVersion))
+	}
+
+	return nil
+}
+
+func vcekExtensions(cert []byte) (*kds.Extensions, error) {
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	ext, err := kds.VcekCertificateExtensions(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	return ext, nil
+}
+
+// write writes the file atomically, so that a failed write does not leave a
+// truncated certificate in the cache.
+func write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cert-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package snpcerts
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+	sevtest "github.com/google/go-sev-guest/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const productLine = "Milan"
+
+var tcbParts = kds.TCBParts{BlSpl: 3, TeeSpl: 0, SnpSpl: 8, UcodeSpl: 115}
+
+// signer returns certificates of the chip at the TCB version, as issued by the KDS.
+func signer(t *testing.T, chipID []byte, tcb kds.TCBParts) *sevtest.AmdSigner {
+	b := &sevtest.AmdSignerBuilder{
+		Keys:             sevtest.DefaultAmdKeys(),
+		ProductName:      "Milan-B0",
+		CSPID:            "cocos",
+		ArkCreationTime:  time.Now(),
+		AskCreationTime:  time.Now(),
+		AsvkCreationTime: time.Now(),
+		VcekCreationTime: time.Now(),
+		VlekCreationTime: time.Now(),
+		VcekCustom:       sevtest.CertOverride{Extensions: sevtest.CustomExtensions(tcb, chipID, "", "Milan-B0")},
+	}
+	s, err := b.TestOnlyCertChain()
+	require.NoError(t, err)
+
+	return s
+}
+
+func chipID(b byte) []byte {
+	id := make([]byte, abi.ChipIDSize)
+	id[0] = b
+
+	return id
+}
+
+func composeTCB(t *testing.T, parts kds.TCBParts) uint64 {
+	tcb, err := kds.ComposeTCBParts(parts)
+	require.NoError(t, err)
+
+	return uint64(tcb)
+}
+
+// serveKDS serves the certificates of the signer as the KDS does and counts the requests.
+func serveKDS(t *testing.T, s *sevtest.AmdSigner) (*httptest.Server, *atomic.Int32) {
+	bundle := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Ask.Raw}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Ark.Raw})...)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/vcek/v1/" + productLine + "/cert_chain":
+			_, _ = w.Write(bundle)
+		case "/vcek/v1/" + productLine + "/" + hex.EncodeToString(chipID(1)):
+			_, _ = w.Write(s.Vcek.Raw)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestChain(t *testing.T) {
+	s := signer(t, chipID(1), tcbParts)
+	srv, requests := serveKDS(t, s)
+	tcb := composeTCB(t, tcbParts)
+
+	cases := []struct {
+		desc        string
+		productLine string
+		chipID      []byte
+		tcb         uint64
+		err         error
+	}{
+		{
+			desc:        "certificates of the chip",
+			productLine: productLine,
+			chipID:      chipID(1),
+			tcb:         tcb,
+		},
+		{
+			desc:        "unknown product",
+			productLine: "Naples",
+			chipID:      chipID(1),
+			tcb:         tcb,
+			err:         ErrInvalidRequest,
+		},
+		{
+			desc:        "truncated chip ID",
+			productLine: productLine,
+			chipID:      chipID(1)[:32],
+			tcb:         tcb,
+			err:         ErrInvalidRequest,
+		},
+		{
+			desc:        "chip unknown to the KDS",
+			productLine: productLine,
+			chipID:      chipID(2),
+			tcb:         tcb,
+			err:         ErrFetch,
+		},
+		{
+			desc:        "VCEK of another TCB version",
+			productLine: productLine,
+			chipID:      chipID(1),
+			tcb:         composeTCB(t, kds.TCBParts{BlSpl: 2, SnpSpl: 8, UcodeSpl: 115}),
+			err:         ErrInvalidCertificate,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := New(Config{Dir: t.TempDir(), KDSURL: srv.URL}, nil)
+
+			chain, err := c.Chain(context.Background(), tc.productLine, tc.chipID, tc.tcb)
+			assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+
+			assert.Equal(t, s.Ark.Raw, chain.ARK)
+			assert.Equal(t, s.Ask.Raw, chain.ASK)
+			assert.Equal(t, s.Vcek.Raw, chain.VCEK)
+		})
+	}
+
+	t.Run("cached certificates", func(t *testing.T) {
+		c := New(Config{Dir: t.TempDir(), KDSURL: srv.URL}, nil)
+
+		first, err := c.Chain(context.Background(), productLine, chipID(1), tcb)
+		require.NoError(t, err)
+		fetched := requests.Load()
+
+		second, err := c.Chain(context.Background(), productLine, chipID(1), tcb)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, fetched, requests.Load(), "cached certificates are not fetched again")
+	})
+}
+
+func TestPreload(t *testing.T) {
+	s := signer(t, chipID(1), tcbParts)
+	srv, requests := serveKDS(t, s)
+
+	table, err := s.CertTableBytes()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "certs.bin")
+	require.NoError(t, os.WriteFile(path, table, 0o644))
+
+	c := New(Config{Dir: t.TempDir(), KDSURL: srv.URL}, nil)
+
+	product, err := c.Preload(path)
+	require.NoError(t, err)
+	assert.Equal(t, productLine, product)
+
+	chain, err := c.Chain(context.Background(), productLine, chipID(1), composeTCB(t, tcbParts))
+	require.NoError(t, err)
+	assert.Equal(t, s.Vcek.Raw, chain.VCEK)
+	assert.Equal(t, s.Ask.Raw, chain.ASK)
+	assert.Equal(t, s.Ark.Raw, chain.ARK)
+	assert.Zero(t, requests.Load(), "preloaded certificates are not fetched")
+
+	invalid := filepath.Join(t.TempDir(), "invalid.bin")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate table"), 0o644))
+	_, err = c.Preload(invalid)
+	assert.True(t, errors.Is(err, ErrInvalidCertTable), "expected %v, got %v", ErrInvalidCertTable, err)
+}
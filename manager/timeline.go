@@ -0,0 +1,81 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// historySize is the number of events kept per CVM for its timeline, the
+// oldest events are dropped first.
+const historySize = 256
+
+// timelinePhases names the phase each event starts, the other events are
+// milestones within the current phase.
+var timelinePhases = map[string]string{
+	EventVMProvisioning: "provisioning",
+	EventVMRunning:      "running",
+	EventVMUnhealthy:    "unhealthy",
+	EventVMRestarted:    "restarted",
+	EventVMStopped:      "stopped",
+}
+
+// recordEvent keeps the event in the history of its CVM, callers hold ms.mu.
+func (ms *managerService) recordEvent(event *ComputationEvent) {
+	if ms.history == nil {
+		ms.history = make(map[string][]*ComputationEvent)
+	}
+	// Log records are kept from the first event of the CVM on, for its log bundle.
+	ms.vmLogs.open(event.CvmId)
+
+	events := append(ms.history[event.CvmId], event)
+	if len(events) > historySize {
+		events = events[len(events)-historySize:]
+	}
+	ms.history[event.CvmId] = events
+}
+
+func (ms *managerService) Timeline(ctx context.Context, computationID string) (*Timeline, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.vms[computationID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return buildTimeline(computationID, ms.history[computationID], timestamppb.Now()), nil
+}
+
+// buildTimeline assembles the phases of the CVM from its events in order. A
+// phase ends when the next one starts, the last one is ongoing.
+func buildTimeline(id string, events []*ComputationEvent, now *timestamppb.Timestamp) *Timeline {
+	timeline := &Timeline{CvmId: id, GeneratedAt: now}
+
+	var current *TimelinePhase
+	for _, event := range events {
+		name, ok := timelinePhases[event.EventType]
+		if !ok {
+			timeline.Milestones = append(timeline.Milestones, &TimelineMilestone{
+				EventType: event.EventType,
+				Timestamp: event.Timestamp,
+				Details:   event.Details,
+			})
+			continue
+		}
+
+		// Repeated events, e.g. a CVM reported unhealthy again, extend the phase.
+		if current != nil && current.Name == name {
+			continue
+		}
+		if current != nil {
+			current.End = event.Timestamp
+		}
+
+		current = &TimelinePhase{Name: name, Start: event.Timestamp, Details: event.Details}
+		timeline.Phases = append(timeline.Phases, current)
+	}
+
+	return timeline
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pkgmanager "github.com/ultravioletrs/cocos/pkg/manager"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	event := func(eventType, details string, offset time.Duration) *ComputationEvent {
+		return &ComputationEvent{CvmId: "vm1", EventType: eventType, Details: details, Timestamp: timestamppb.New(at.Add(offset))}
+	}
+	ts := func(offset time.Duration) *timestamppb.Timestamp {
+		return timestamppb.New(at.Add(offset))
+	}
+	now := ts(time.Hour)
+
+	tests := []struct {
+		name       string
+		events     []*ComputationEvent
+		phases     []*TimelinePhase
+		milestones []*TimelineMilestone
+	}{
+		{
+			name: "no events",
+		},
+		{
+			name: "running computation",
+			events: []*ComputationEvent{
+				event(EventVMProvisioning, "", 0),
+				event(EventVMRunning, "", 30*time.Second),
+				event(EventDatasetAttached, "/tmp/dataset.img", time.Minute),
+			},
+			phases: []*TimelinePhase{
+				{Name: "provisioning", Start: ts(0), End: ts(30 * time.Second)},
+				{Name: "running", Start: ts(30 * time.Second)},
+			},
+			milestones: []*TimelineMilestone{
+				{EventType: EventDatasetAttached, Timestamp: ts(time.Minute), Details: "/tmp/dataset.img"},
+			},
+		},
+		{
+			name: "restarted unhealthy computation",
+			events: []*ComputationEvent{
+				event(EventVMProvisioning, "", 0),
+				event(EventVMRunning, "", 30*time.Second),
+				event(EventVMUnhealthy, "missed 3 heartbeats", time.Minute),
+				event(EventVMUnhealthy, "missed 3 heartbeats", 2*time.Minute),
+				event(EventVMRestarted, "", 3*time.Minute),
+				event(EventVMStopped, "", 10*time.Minute),
+			},
+			phases: []*TimelinePhase{
+				{Name: "provisioning", Start: ts(0), End: ts(30 * time.Second)},
+				{Name: "running", Start: ts(30 * time.Second), End: ts(time.Minute)},
+				{Name: "unhealthy", Start: ts(time.Minute), End: ts(3 * time.Minute), Details: "missed 3 heartbeats"},
+				{Name: "restarted", Start: ts(3 * time.Minute), End: ts(10 * time.Minute)},
+				{Name: "stopped", Start: ts(10 * time.Minute)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeline := buildTimeline("vm1", tt.events, now)
+
+			assert.Equal(t, "vm1", timeline.CvmId)
+			assert.Equal(t, now, timeline.GeneratedAt)
+			assert.Equal(t, tt.phases, timeline.Phases)
+			assert.Equal(t, tt.milestones, timeline.Milestones)
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	ms, cvm := newWatchService(t, "vm1")
+	cvm.On("State").Return(pkgmanager.VmRunning.String())
+	cvm.On("Stop").Return(nil)
+
+	_, err := ms.Timeline(context.Background(), "vm2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ms.mu.Lock()
+	ms.publishEvent("vm1", EventVMProvisioning, cvm, "")
+	ms.publishEvent("vm1", EventVMRunning, cvm, "")
+	for i := range historySize {
+		ms.publishEvent("vm1", EventDatasetAttached, cvm, fmt.Sprintf("/tmp/dataset-%d.img", i))
+	}
+	ms.mu.Unlock()
+
+	timeline, err := ms.Timeline(context.Background(), "vm1")
+	require.NoError(t, err)
+	assert.NotNil(t, timeline.GeneratedAt)
+	assert.Empty(t, timeline.Phases, "the phase events were dropped from the bounded history")
+	require.Len(t, timeline.Milestones, historySize)
+	assert.Equal(t, "/tmp/dataset-0.img", timeline.Milestones[0].Details)
+
+	require.NoError(t, ms.RemoveVM(context.Background(), "vm1"))
+	assert.NotContains(t, ms.history, "vm1")
+	_, err = ms.Timeline(context.Background(), "vm1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
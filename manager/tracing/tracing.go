@@ -35,6 +35,20 @@ func (tm *tracingMiddleware) RemoveVM(ctx context.Context, id string) error {
 	return tm.svc.RemoveVM(ctx, id)
 }
 
+func (tm *tracingMiddleware) StopVM(ctx context.Context, id string) (string, error) {
+	ctx, span := tm.tracer.Start(ctx, "stop_vm")
+	defer span.End()
+
+	return tm.svc.StopVM(ctx, id)
+}
+
+func (tm *tracingMiddleware) AttachDataset(ctx context.Context, id, diskPath string) error {
+	ctx, span := tm.tracer.Start(ctx, "attach_dataset")
+	defer span.End()
+
+	return tm.svc.AttachDataset(ctx, id, diskPath)
+}
+
 func (tm *tracingMiddleware) FetchAttestationPolicy(ctx context.Context, computationId string) ([]byte, error) {
 	_, span := tm.tracer.Start(ctx, "fetch_attestation_policy")
 	defer span.End()
@@ -49,6 +63,83 @@ func (tm *tracingMiddleware) ReturnCVMInfo(ctx context.Context) (string, int, st
 	return tm.svc.ReturnCVMInfo(ctx)
 }
 
+func (tm *tracingMiddleware) GetImages(ctx context.Context) ([]*manager.Image, error) {
+	ctx, span := tm.tracer.Start(ctx, "get_images")
+	defer span.End()
+
+	return tm.svc.GetImages(ctx)
+}
+
+func (tm *tracingMiddleware) HostCapabilities(ctx context.Context) (*manager.HostCapabilities, error) {
+	ctx, span := tm.tracer.Start(ctx, "host_capabilities")
+	defer span.End()
+
+	return tm.svc.HostCapabilities(ctx)
+}
+
+func (tm *tracingMiddleware) Diagnostics(ctx context.Context, computationID string) (*manager.Diagnostics, error) {
+	ctx, span := tm.tracer.Start(ctx, "diagnostics")
+	defer span.End()
+
+	return tm.svc.Diagnostics(ctx, computationID)
+}
+
+func (tm *tracingMiddleware) Timeline(ctx context.Context, computationID string) (*manager.Timeline, error) {
+	ctx, span := tm.tracer.Start(ctx, "timeline")
+	defer span.End()
+
+	return tm.svc.Timeline(ctx, computationID)
+}
+
+func (tm *tracingMiddleware) DownloadLogs(ctx context.Context, computationID string) ([]byte, error) {
+	ctx, span := tm.tracer.Start(ctx, "download_logs")
+	defer span.End()
+
+	return tm.svc.DownloadLogs(ctx, computationID)
+}
+
+func (tm *tracingMiddleware) SNPCertChain(ctx context.Context, product string, chipID []byte, reportedTCB uint64) (*manager.SNPCertChain, error) {
+	ctx, span := tm.tracer.Start(ctx, "snp_cert_chain")
+	defer span.End()
+
+	return tm.svc.SNPCertChain(ctx, product, chipID, reportedTCB)
+}
+
+func (tm *tracingMiddleware) SetTenantQuota(ctx context.Context, tenant string, quota *manager.TenantQuota) (*manager.TenantUsage, error) {
+	ctx, span := tm.tracer.Start(ctx, "set_tenant_quota")
+	defer span.End()
+
+	return tm.svc.SetTenantQuota(ctx, tenant, quota)
+}
+
+func (tm *tracingMiddleware) WatchComputation(ctx context.Context, computationID string) (<-chan *manager.ComputationEvent, error) {
+	ctx, span := tm.tracer.Start(ctx, "watch_computation")
+	defer span.End()
+
+	return tm.svc.WatchComputation(ctx, computationID)
+}
+
+func (tm *tracingMiddleware) Logs(ctx context.Context, computationID string, filter manager.LogsFilter) (<-chan *manager.LogChunk, error) {
+	ctx, span := tm.tracer.Start(ctx, "logs")
+	defer span.End()
+
+	return tm.svc.Logs(ctx, computationID, filter)
+}
+
+func (tm *tracingMiddleware) Console(ctx context.Context, computationID string, filter manager.ConsoleFilter) (<-chan *manager.ConsoleChunk, error) {
+	ctx, span := tm.tracer.Start(ctx, "console")
+	defer span.End()
+
+	return tm.svc.Console(ctx, computationID, filter)
+}
+
+func (tm *tracingMiddleware) AuditLog(ctx context.Context) (*manager.AuditLogRes, error) {
+	ctx, span := tm.tracer.Start(ctx, "audit_log")
+	defer span.End()
+
+	return tm.svc.AuditLog(ctx)
+}
+
 func (tm *tracingMiddleware) Shutdown() error {
 	_, span := tm.tracer.Start(context.Background(), "shutdown")
 	defer span.End()
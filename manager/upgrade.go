@@ -0,0 +1,90 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/manager/qemu"
+)
+
+// heartbeatFDEnv holds the descriptor of the heartbeat listener a manager
+// inherits from the manager it replaced during an upgrade.
+const heartbeatFDEnv = "MANAGER_HEARTBEAT_LISTENER_FD"
+
+var errHandoff = errors.New("failed to hand off the heartbeat listener")
+
+// HeartbeatListener returns the listener for the agent heartbeats on the host
+// vsock port, which is inherited from the previous manager after an upgrade.
+func HeartbeatListener(port uint32) (net.Listener, error) {
+	value, ok := os.LookupEnv(heartbeatFDEnv)
+	if !ok {
+		return vsock.Listen(port)
+	}
+
+	os.Unsetenv(heartbeatFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 0 {
+		return nil, fmt.Errorf("invalid %s %q", heartbeatFDEnv, value)
+	}
+
+	return vsock.FileListener(os.NewFile(uintptr(fd), "vsock-listener"))
+}
+
+// HandoffFile returns a copy of the heartbeat listener l to hand off to the
+// upgraded manager, it must be taken before the service is shut down.
+func HandoffFile(l net.Listener) (*os.File, error) {
+	hl, ok := l.(*vsock.Listener)
+	if !ok {
+		return nil, errHandoff
+	}
+
+	f, err := hl.File()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errHandoff, err)
+	}
+
+	return f, nil
+}
+
+// Upgrade replaces the manager with the binary at path once the service was
+// shut down. The process keeps its PID and so remains the parent of the running
+// QEMU processes, and the new manager restores the VMs from their persisted
+// state. The heartbeat listener f, when not nil, is handed off so that agent
+// connections queue on it until the new manager serves it, and so are the
+// output pipes of the QEMU processes. Upgrade only returns on failure.
+func Upgrade(path string, f *os.File) error {
+	env := withoutEnv(withoutEnv(os.Environ(), heartbeatFDEnv), qemu.OutputFDsEnv)
+	if f != nil {
+		env = append(env, fmt.Sprintf("%s=%d", heartbeatFDEnv, f.Fd()))
+	}
+
+	outputs, err := qemu.HandoffOutputs()
+	if err != nil {
+		return err
+	}
+	if outputs != "" {
+		env = append(env, fmt.Sprintf("%s=%s", qemu.OutputFDsEnv, outputs))
+	}
+
+	return syscall.Exec(path, os.Args, env)
+}
+
+func withoutEnv(env []string, key string) []string {
+	filtered := make([]string, 0, len(env))
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			filtered = append(filtered, kv)
+		}
+	}
+
+	return filtered
+}
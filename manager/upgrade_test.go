@@ -0,0 +1,46 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package manager
+
+import (
+	"net"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatListenerInvalidFD(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+	}{
+		{name: "not a number", value: "socket"},
+		{name: "negative descriptor", value: "-1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(heartbeatFDEnv, tc.value)
+
+			_, err := HeartbeatListener(9999)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandoffFile(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = HandoffFile(l)
+	assert.True(t, errors.Contains(err, errHandoff), "expected %v, got %v", errHandoff, err)
+}
+
+func TestWithoutEnv(t *testing.T) {
+	env := []string{"A=1", heartbeatFDEnv + "=3", heartbeatFDEnv + "_OTHER=4"}
+
+	assert.Equal(t, []string{"A=1", heartbeatFDEnv + "_OTHER=4"}, withoutEnv(env, heartbeatFDEnv))
+}
@@ -16,6 +16,9 @@ var (
 
 const bufSize = 1024
 
+// StreamKey is the log attribute naming the QEMU output stream, stdout or stderr, a record was written to.
+const StreamKey = "stream"
+
 type Stdout struct {
 	StateMachine StateMachine
 	Logger       *slog.Logger
@@ -38,6 +41,7 @@ func (s *Stdout) Write(p []byte) (n int, err error) {
 
 		args := []any{
 			slog.String("state", s.StateMachine.State()),
+			slog.String(StreamKey, "stdout"),
 		}
 
 		s.Logger.Info(string(buf[:n]), args...)
@@ -68,6 +72,7 @@ func (s *Stderr) Write(p []byte) (n int, err error) {
 
 		args := []any{
 			slog.String("state", s.StateMachine.State()),
+			slog.String(StreamKey, "stderr"),
 		}
 
 		if strings.Contains(string(buf[:n]), "Error") {
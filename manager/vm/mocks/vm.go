@@ -39,6 +39,57 @@ func (_m *VM) EXPECT() *VM_Expecter {
 	return &VM_Expecter{mock: &_m.Mock}
 }
 
+// AttachDisk provides a mock function for the type VM
+func (_mock *VM) AttachDisk(path string) error {
+	ret := _mock.Called(path)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AttachDisk")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(path)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// VM_AttachDisk_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AttachDisk'
+type VM_AttachDisk_Call struct {
+	*mock.Call
+}
+
+// AttachDisk is a helper method to define mock.On call
+//   - path string
+func (_e *VM_Expecter) AttachDisk(path interface{}) *VM_AttachDisk_Call {
+	return &VM_AttachDisk_Call{Call: _e.mock.On("AttachDisk", path)}
+}
+
+func (_c *VM_AttachDisk_Call) Run(run func(path string)) *VM_AttachDisk_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *VM_AttachDisk_Call) Return(err error) *VM_AttachDisk_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *VM_AttachDisk_Call) RunAndReturn(run func(path string) error) *VM_AttachDisk_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetConfig provides a mock function for the type VM
 func (_mock *VM) GetConfig() any {
 	ret := _mock.Called()
@@ -129,6 +180,50 @@ func (_c *VM_GetProcess_Call) RunAndReturn(run func() int) *VM_GetProcess_Call {
 	return _c
 }
 
+// Restart provides a mock function for the type VM
+func (_mock *VM) Restart() error {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restart")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func() error); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// VM_Restart_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Restart'
+type VM_Restart_Call struct {
+	*mock.Call
+}
+
+// Restart is a helper method to define mock.On call
+func (_e *VM_Expecter) Restart() *VM_Restart_Call {
+	return &VM_Restart_Call{Call: _e.mock.On("Restart")}
+}
+
+func (_c *VM_Restart_Call) Run(run func()) *VM_Restart_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *VM_Restart_Call) Return(err error) *VM_Restart_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *VM_Restart_Call) RunAndReturn(run func() error) *VM_Restart_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetProcess provides a mock function for the type VM
 func (_mock *VM) SetProcess(pid int) error {
 	ret := _mock.Called(pid)
@@ -18,6 +18,19 @@ type VM interface {
 	Transition(newState pkgmanager.ManagerState) error
 	State() string
 	GetConfig() any
+	// AttachDisk hot-adds a read-only disk image to the running VM.
+	AttachDisk(path string) error
+	// Restart resets the running VM, rebooting its guest.
+	Restart() error
+}
+
+// EventGuestPanicked is reported when the guest kernel of the VM panics.
+const EventGuestPanicked = "guest-panicked"
+
+// EventSource is implemented by VMs that report hypervisor events, such as
+// guest panics. The channel is closed once the VM exits.
+type EventSource interface {
+	Events() <-chan Event
 }
 
 type Provider func(config any, computationId string, logger *slog.Logger) VM
@@ -0,0 +1,173 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webhook posts the computation events of the manager to an HTTP
+// endpoint, so that external systems without gRPC stream or message broker
+// support can react to the lifecycle of the computations.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// EventHeader carries the type of the posted event.
+	EventHeader = "X-Cocos-Event"
+	// TimestampHeader carries the Unix time the delivery was signed at.
+	TimestampHeader = "X-Cocos-Timestamp"
+	// SignatureHeader carries the signature of the delivery, sha256=<hex HMAC>.
+	SignatureHeader = "X-Cocos-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	// ErrInvalidURL indicates a webhook URL that is not an http or https URL.
+	ErrInvalidURL = errors.New("invalid webhook URL")
+
+	// ErrDeliveryFailed indicates an event the webhook endpoint did not accept.
+	ErrDeliveryFailed = errors.New("webhook delivery failed")
+)
+
+// Config is the webhook the manager posts computation events to.
+type Config struct {
+	// URL of the webhook endpoint, posting is disabled when it is empty.
+	URL string `env:"MANAGER_EVENTS_WEBHOOK_URL"     envDefault:""`
+	// Secret is the HMAC-SHA256 key deliveries are signed with, they are unsigned when it is empty.
+	Secret string `env:"MANAGER_EVENTS_WEBHOOK_SECRET"  envDefault:""`
+	// Events are the event types posted, every event is posted when it is empty.
+	Events []string `env:"MANAGER_EVENTS_WEBHOOK_EVENTS"  envDefault:""`
+	// Retries is the number of times a failed delivery is retried.
+	Retries int `env:"MANAGER_EVENTS_WEBHOOK_RETRIES" envDefault:"3"`
+	// Backoff is the delay before the first retry, it doubles with every retry.
+	Backoff time.Duration `env:"MANAGER_EVENTS_WEBHOOK_BACKOFF" envDefault:"500ms"`
+	// Timeout bounds every delivery attempt.
+	Timeout time.Duration `env:"MANAGER_EVENTS_WEBHOOK_TIMEOUT" envDefault:"3s"`
+}
+
+type webhook struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New returns the publisher posting events to the webhook with the client, or
+// http.DefaultClient when client is nil. It returns nil without an error when
+// no webhook is configured.
+func New(cfg Config, client *http.Client) (manager.EventPublisher, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q is not an http or https URL", ErrInvalidURL, cfg.URL)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &webhook{cfg: cfg, client: client}, nil
+}
+
+// Publish posts the JSON encoding of the event, retrying with exponential
+// backoff while the endpoint is unreachable or answers with 429 or a 5xx status.
+func (w *webhook) Publish(ctx context.Context, event *manager.ComputationEvent) error {
+	if len(w.cfg.Events) > 0 && !slices.Contains(w.cfg.Events, event.EventType) {
+		return nil
+	}
+
+	body, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.deliver(ctx, event.EventType, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.cfg.Retries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// Close is a no-op, deliveries are not buffered.
+func (w *webhook) Close() error {
+	return nil
+}
+
+// deliver posts the body once and reports whether a failed delivery may be retried.
+func (w *webhook) deliver(ctx context.Context, eventType string, body []byte) (bool, error) {
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	if w.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign([]byte(w.cfg.Secret), timestamp, body))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+
+	return retry, fmt.Errorf("%w: %s", ErrDeliveryFailed, res.Status)
+}
+
+// Sign returns the signature of a delivery, the HMAC-SHA256 of
+// <timestamp>.<body> with the secret, which receivers recompute to
+// authenticate the delivery and reject replayed ones by their timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,205 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/manager"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const secret = "webhook secret"
+
+type delivery struct {
+	eventType string
+	timestamp string
+	signature string
+	body      []byte
+}
+
+// endpoint records the deliveries it receives and answers with the statuses in order,
+// repeating the last one.
+type endpoint struct {
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []delivery
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.deliveries = append(e.deliveries, delivery{
+		eventType: r.Header.Get(EventHeader),
+		timestamp: r.Header.Get(TimestampHeader),
+		signature: r.Header.Get(SignatureHeader),
+		body:      body,
+	})
+
+	status := http.StatusNoContent
+	if len(e.statuses) > 0 {
+		status = e.statuses[0]
+		if len(e.statuses) > 1 {
+			e.statuses = e.statuses[1:]
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		desc      string
+		url       string
+		publisher bool
+		err       error
+	}{
+		{
+			desc: "no webhook configured",
+			url:  "",
+		},
+		{
+			desc:      "https webhook",
+			url:       "https://hooks.example.com/cocos",
+			publisher: true,
+		},
+		{
+			desc: "unsupported scheme",
+			url:  "ftp://hooks.example.com/cocos",
+			err:  ErrInvalidURL,
+		},
+		{
+			desc: "invalid URL",
+			url:  "http://[::1",
+			err:  ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p, err := New(Config{URL: tc.url}, nil)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			assert.Equal(t, tc.publisher, p != nil)
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	cases := []struct {
+		desc       string
+		secret     string
+		events     []string
+		eventType  string
+		statuses   []int
+		deliveries int
+		err        error
+	}{
+		{
+			desc:       "signed delivery",
+			secret:     secret,
+			eventType:  manager.EventVMProvisioning,
+			deliveries: 1,
+		},
+		{
+			desc:       "unsigned delivery",
+			eventType:  manager.EventVMProvisioning,
+			deliveries: 1,
+		},
+		{
+			desc:       "subscribed event",
+			events:     []string{manager.EventVMProvisioning, manager.EventDiagnosticsReceived},
+			eventType:  manager.EventDiagnosticsReceived,
+			deliveries: 1,
+		},
+		{
+			desc:      "unsubscribed event",
+			events:    []string{manager.EventVMProvisioning},
+			eventType: manager.EventVMRunning,
+		},
+		{
+			desc:       "retried until accepted",
+			eventType:  manager.EventVMRunning,
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			deliveries: 3,
+		},
+		{
+			desc:       "retries exhausted",
+			eventType:  manager.EventVMRunning,
+			statuses:   []int{http.StatusInternalServerError},
+			deliveries: 3,
+			err:        ErrDeliveryFailed,
+		},
+		{
+			desc:       "rejected delivery is not retried",
+			eventType:  manager.EventVMRunning,
+			statuses:   []int{http.StatusBadRequest},
+			deliveries: 1,
+			err:        ErrDeliveryFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := &endpoint{statuses: tc.statuses}
+			server := httptest.NewServer(e)
+			defer server.Close()
+
+			p, err := New(Config{URL: server.URL, Secret: tc.secret, Events: tc.events, Retries: 2, Backoff: time.Millisecond, Timeout: time.Second}, server.Client())
+			require.NoError(t, err)
+
+			event := &manager.ComputationEvent{CvmId: "vm1", EventType: tc.eventType, State: "running"}
+			err = p.Publish(context.Background(), event)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			require.NoError(t, p.Close())
+
+			require.Len(t, e.deliveries, tc.deliveries)
+			for _, d := range e.deliveries {
+				assert.Equal(t, tc.eventType, d.eventType)
+
+				var got manager.ComputationEvent
+				require.NoError(t, protojson.Unmarshal(d.body, &got))
+				assert.Equal(t, "vm1", got.CvmId)
+
+				if tc.secret == "" {
+					assert.Empty(t, d.signature)
+					continue
+				}
+				assert.Equal(t, Sign([]byte(tc.secret), d.timestamp, d.body), d.signature)
+			}
+		})
+	}
+}
+
+func TestPublishCancelled(t *testing.T) {
+	server := httptest.NewServer(&endpoint{statuses: []int{http.StatusBadGateway}})
+	defer server.Close()
+
+	p, err := New(Config{URL: server.URL, Retries: 5, Backoff: time.Hour}, server.Client())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = p.Publish(ctx, &manager.ComputationEvent{CvmId: "vm1", EventType: manager.EventVMRunning})
+	assert.True(t, errors.Contains(err, context.DeadlineExceeded), "expected %v, got %v", context.DeadlineExceeded, err)
+	assert.True(t, errors.Contains(err, ErrDeliveryFailed), "expected %v, got %v", ErrDeliveryFailed, err)
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign([]byte(secret), "1700000000", []byte(`{"cvmId":"vm1"}`))
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.NotEqual(t, signature, Sign([]byte(secret), "1700000001", []byte(`{"cvmId":"vm1"}`)), "the timestamp is signed")
+	assert.NotEqual(t, signature, Sign([]byte("other secret"), "1700000000", []byte(`{"cvmId":"vm1"}`)))
+}
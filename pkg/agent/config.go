@@ -0,0 +1,217 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/caarlos0/env/v11"
+	"github.com/ultravioletrs/cocos/agent/cvms/server"
+	"github.com/ultravioletrs/cocos/internal/cmdline"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/pkg/clients"
+	pkgserver "github.com/ultravioletrs/cocos/pkg/server"
+)
+
+const (
+	// DefaultStorageDir is the directory the agent keeps its state in.
+	DefaultStorageDir = "/var/lib/cocos/agent"
+	// DefaultDatasetDiskDir is the directory hot-added dataset disks are mounted under.
+	DefaultDatasetDiskDir = "/run/cocos/datasets"
+	// DefaultJournalKeyFile is the key the computation journal is encrypted with,
+	// in memory so the journal does not outlive the CVM boot.
+	DefaultJournalKeyFile = "/run/cocos/agent/journal.key"
+	// DefaultEventsQueueSize is the number of events and logs buffered while they wait to be sent to the manager.
+	DefaultEventsQueueSize = 1000
+)
+
+// fetchableKeys are the variables the fetched configuration may set: the log
+// level, intervals, queue sizes and limits. The host serves the configuration,
+// so the variables that weaken what the agent verifies or where it keeps its
+// state, e.g. AGENT_ALLOW_UNHASHED_DATASETS or AGENT_TRUSTED_KEYS_FILE, are
+// only taken from the environment and the kernel command line.
+var fetchableKeys = map[string]bool{
+	"AGENT_LOG_LEVEL":                   true,
+	"AGENT_HEARTBEAT_INTERVAL":          true,
+	"AGENT_SHUTDOWN_GRACE_PERIOD":       true,
+	"AGENT_LOGS_WINDOW":                 true,
+	"AGENT_EVENTS_QUEUE_SIZE":           true,
+	"AGENT_GRPC_MAX_RECV_MSG_SIZE":      true,
+	"AGENT_GRPC_MAX_SEND_MSG_SIZE":      true,
+	"AGENT_GRPC_MAX_CONCURRENT_UPLOADS": true,
+	"AGENT_GRPC_MAX_UPLOAD_RATE":        true,
+}
+
+// ErrInvalidConfig indicates an agent configuration the server cannot run with.
+var ErrInvalidConfig = errors.New("invalid agent configuration")
+
+// Config is the configuration of the agent, the cocos-agent binary loads it with LoadConfig.
+// The zero value of the ports, TLS mode, directories and limits selects their default.
+type Config struct {
+	LogLevel                 string        `env:"AGENT_LOG_LEVEL"              envDefault:"debug"`
+	Vmpl                     int           `env:"AGENT_VMPL"                   envDefault:"2"`
+	AgentGrpcHost            string        `env:"AGENT_GRPC_HOST"              envDefault:"0.0.0.0"`
+	AgentGrpcPort            string        `env:"AGENT_GRPC_PORT"              envDefault:"7002"`
+	TLSMode                  string        `env:"AGENT_TLS_MODE"               envDefault:"manifest"`
+	CAUrl                    string        `env:"AGENT_CVM_CA_URL"             envDefault:""`
+	CVMId                    string        `env:"AGENT_CVM_ID"                 envDefault:""`
+	CertsToken               string        `env:"AGENT_CERTS_TOKEN"            envDefault:""`
+	AgentMaaURL              string        `env:"AGENT_MAA_URL"                envDefault:"https://sharedeus2.eus2.attest.azure.net"`
+	AgentOSBuild             string        `env:"AGENT_OS_BUILD"               envDefault:"UVC"`
+	AgentOSDistro            string        `env:"AGENT_OS_DISTRO"              envDefault:"UVC"`
+	AgentOSType              string        `env:"AGENT_OS_TYPE"                envDefault:"UVC"`
+	AttestationServiceSocket string        `env:"ATTESTATION_SERVICE_SOCKET" envDefault:"/run/cocos/attestation.sock"`
+	AttestationCacheMaxAge   time.Duration `env:"AGENT_ATTESTATION_CACHE_MAX_AGE" envDefault:"30s"`
+	TrustedKeysFile          string        `env:"AGENT_TRUSTED_KEYS_FILE"      envDefault:""`
+	AllowUnsignedManifests   bool          `env:"AGENT_ALLOW_UNSIGNED_MANIFESTS" envDefault:"false"`
+	AllowUnhashedDatasets    bool          `env:"AGENT_ALLOW_UNHASHED_DATASETS" envDefault:"false"`
+	HeartbeatPort            uint32        `env:"AGENT_HEARTBEAT_PORT"         envDefault:"0"`
+	HeartbeatInterval        time.Duration `env:"AGENT_HEARTBEAT_INTERVAL"     envDefault:"5s"`
+	LogsPort                 uint32        `env:"AGENT_LOGS_PORT"              envDefault:"0"`
+	LogsWindow               int           `env:"AGENT_LOGS_WINDOW"            envDefault:"256"`
+	// ConfigPort is the host vsock port the agent requests its configuration from at startup, none is requested when it is 0.
+	ConfigPort          uint32        `env:"AGENT_CONFIG_PORT"            envDefault:"0"`
+	StateDir            string        `env:"AGENT_STATE_DIR"              envDefault:""`
+	StorageDir          string        `env:"AGENT_STORAGE_DIR"            envDefault:"/var/lib/cocos/agent"`
+	DatasetDiskDir      string        `env:"AGENT_DATASET_DISK_DIR"       envDefault:"/run/cocos/datasets"`
+	JournalKeyFile      string        `env:"AGENT_JOURNAL_KEY_FILE"       envDefault:"/run/cocos/agent/journal.key"`
+	EventsQueueSize     int           `env:"AGENT_EVENTS_QUEUE_SIZE"      envDefault:"1000"`
+	ShutdownGracePeriod time.Duration `env:"AGENT_SHUTDOWN_GRACE_PERIOD"  envDefault:"20s"`
+
+	GrpcLimits  pkgserver.MessageLimits      `envPrefix:"AGENT_GRPC_"`
+	GrpcUploads pkgserver.UploadLimits       `envPrefix:"AGENT_GRPC_"`
+	CVMGrpc     clients.StandardClientConfig `envPrefix:"AGENT_CVM_GRPC_"`
+}
+
+// Validate returns an ErrInvalidConfig error if the server cannot run with the configuration.
+func (c Config) Validate() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return errors.Wrap(ErrInvalidConfig, err)
+	}
+
+	if c.Vmpl < 0 || c.Vmpl > 3 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("vmpl level must be in a range [0, 3]"))
+	}
+
+	if c.AgentGrpcPort != "" {
+		if port, err := strconv.ParseUint(c.AgentGrpcPort, 10, 16); err != nil || port == 0 {
+			return errors.Wrap(ErrInvalidConfig, fmt.Errorf("grpc port %q must be in a range [1, 65535]", c.AgentGrpcPort))
+		}
+	}
+
+	if c.TrustedKeysFile == "" && !c.AllowUnsignedManifests {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("trusted manifest keys file must be set unless unsigned manifests are allowed"))
+	}
+
+	if !server.TLSMode(c.TLSMode).Valid() {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("tls mode %q must be one of %s, %s or %s", c.TLSMode, server.TLSModeManifest, server.TLSModeTLS, server.TLSModeAttested))
+	}
+
+	if c.HeartbeatPort != 0 && c.HeartbeatInterval <= 0 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("heartbeat interval must be positive"))
+	}
+
+	if c.ShutdownGracePeriod < 0 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("shutdown grace period must not be negative"))
+	}
+
+	if c.LogsWindow < 0 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("logs window must not be negative"))
+	}
+
+	if c.EventsQueueSize < 0 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("events queue size must not be negative"))
+	}
+
+	if c.AttestationCacheMaxAge < 0 {
+		return errors.Wrap(ErrInvalidConfig, fmt.Errorf("attestation cache max age must not be negative"))
+	}
+
+	return nil
+}
+
+// ConfigFetcher returns the configuration the manager serves on the host vsock port as environment variables.
+type ConfigFetcher func(ctx context.Context, port uint32) (map[string]string, error)
+
+// FetchVsockConfig requests the configuration of the agent from the manager over vsock.
+func FetchVsockConfig(ctx context.Context, port uint32) (map[string]string, error) {
+	return vsock.FetchConfig(ctx, func() (net.Conn, error) { return vsock.DialHost(port) })
+}
+
+// LoadConfig returns the validated agent configuration. Each source overrides
+// the previous ones: the defaults, the environment, the configuration fetch
+// returns when AGENT_CONFIG_PORT is set and the cocos parameters of the kernel
+// command line at cmdlinePath, which win because they are part of the launch
+// measurement. The fetched configuration only sets the fetchable variables.
+func LoadConfig(ctx context.Context, cmdlinePath string, fetch ConfigFetcher) (Config, error) {
+	measured, err := cmdline.Load(cmdlinePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read kernel command line: %w", err)
+	}
+
+	environ := env.ToMap(os.Environ())
+	merge(environ, measured)
+
+	cfg, err := parseConfig(environ)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if cfg.ConfigPort != 0 && fetch != nil {
+		fetched, err := fetch(ctx, cfg.ConfigPort)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to fetch configuration from vsock port %d: %w", cfg.ConfigPort, err)
+		}
+		if err := checkFetchedConfig(fetched); err != nil {
+			return Config{}, err
+		}
+
+		merge(environ, fetched)
+		merge(environ, measured)
+
+		if cfg, err = parseConfig(environ); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func parseConfig(environ map[string]string) (Config, error) {
+	var cfg Config
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: environ}); err != nil {
+		return Config{}, errors.Wrap(ErrInvalidConfig, err)
+	}
+
+	return cfg, nil
+}
+
+// checkFetchedConfig returns an ErrInvalidConfig error if the fetched
+// configuration sets a variable that is not fetchable.
+func checkFetchedConfig(fetched map[string]string) error {
+	for k := range fetched {
+		if !fetchableKeys[k] {
+			return errors.Wrap(ErrInvalidConfig, fmt.Errorf("fetched configuration sets %s", k))
+		}
+	}
+
+	return nil
+}
+
+func merge(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc string
+		cfg  Config
+		err  error
+	}{
+		{
+			desc: "defaults",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info"},
+		},
+		{
+			desc: "valid configuration",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "warn", AgentGrpcPort: "7020", TLSMode: "attested", HeartbeatPort: 9998, HeartbeatInterval: time.Second, EventsQueueSize: 10},
+		},
+		{
+			desc: "trusted manifest keys",
+			cfg:  Config{LogLevel: "info", TrustedKeysFile: "/etc/cocos/manifest-keys.pem"},
+		},
+		{
+			desc: "no trusted manifest keys",
+			cfg:  Config{LogLevel: "info"},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "non numeric grpc port",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", AgentGrpcPort: "grpc"},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "out of range grpc port",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", AgentGrpcPort: "70000"},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "unknown tls mode",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", TLSMode: "plaintext"},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "heartbeats without interval",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", HeartbeatPort: 9998},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "negative events queue size",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", EventsQueueSize: -1},
+			err:  ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cases := []struct {
+		desc     string
+		env      map[string]string
+		cmdline  string
+		fetched  map[string]string
+		fetchErr error
+		check    func(t *testing.T, cfg Config)
+		fetches  int
+		err      bool
+	}{
+		{
+			desc: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "debug", cfg.LogLevel)
+				assert.Equal(t, "7002", cfg.AgentGrpcPort)
+				assert.Equal(t, "manifest", cfg.TLSMode)
+				assert.Equal(t, DefaultStorageDir, cfg.StorageDir)
+				assert.Equal(t, DefaultDatasetDiskDir, cfg.DatasetDiskDir)
+				assert.Equal(t, DefaultJournalKeyFile, cfg.JournalKeyFile)
+				assert.Equal(t, DefaultEventsQueueSize, cfg.EventsQueueSize)
+			},
+		},
+		{
+			desc:    "kernel command line overrides the environment",
+			env:     map[string]string{"AGENT_LOG_LEVEL": "info", "AGENT_STORAGE_DIR": "/srv/agent"},
+			cmdline: "quiet cocos.agent_log_level=warn",
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "warn", cfg.LogLevel)
+				assert.Equal(t, "/srv/agent", cfg.StorageDir)
+			},
+		},
+		{
+			desc:    "fetched configuration overrides the environment",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997", "AGENT_LOG_LEVEL": "info", "AGENT_GRPC_PORT": "7020"},
+			cmdline: "cocos.agent_logs_window=32",
+			fetched: map[string]string{"AGENT_LOG_LEVEL": "error", "AGENT_LOGS_WINDOW": "64", "AGENT_EVENTS_QUEUE_SIZE": "50"},
+			fetches: 1,
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, "error", cfg.LogLevel)
+				assert.Equal(t, "7020", cfg.AgentGrpcPort)
+				assert.Equal(t, 32, cfg.LogsWindow, "measured parameters override the fetched configuration")
+				assert.Equal(t, 50, cfg.EventsQueueSize)
+			},
+		},
+		{
+			desc:    "configuration port on the kernel command line",
+			cmdline: "cocos.agent_config_port=9997",
+			fetched: map[string]string{"AGENT_LOGS_WINDOW": "64"},
+			fetches: 1,
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, 64, cfg.LogsWindow)
+			},
+		},
+		{
+			desc:    "fetched configuration with an unknown variable",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"LD_PRELOAD": "/tmp/lib.so"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:    "fetched configuration allowing unhashed datasets",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_ALLOW_UNHASHED_DATASETS": "true"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:    "fetched configuration allowing unsigned manifests",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_ALLOW_UNSIGNED_MANIFESTS": "true"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:    "fetched configuration replacing the trusted keys",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_TRUSTED_KEYS_FILE": "/tmp/keys.pem"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:    "fetched configuration changing the TLS mode",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_TLS_MODE": "tls"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:    "fetched configuration moving the configuration port",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_CONFIG_PORT": "9000"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc:     "configuration fetch failure",
+			env:      map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetchErr: fmt.Errorf("connection refused"),
+			fetches:  1,
+			err:      true,
+		},
+		{
+			desc:    "invalid fetched configuration",
+			env:     map[string]string{"AGENT_CONFIG_PORT": "9997"},
+			fetched: map[string]string{"AGENT_LOGS_WINDOW": "-1"},
+			fetches: 1,
+			err:     true,
+		},
+		{
+			desc: "malformed environment",
+			env:  map[string]string{"AGENT_EVENTS_QUEUE_SIZE": "many"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("AGENT_ALLOW_UNSIGNED_MANIFESTS", "true")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			path := filepath.Join(t.TempDir(), "cmdline")
+			require.NoError(t, os.WriteFile(path, []byte(tc.cmdline), 0o644))
+
+			fetches := 0
+			fetch := func(ctx context.Context, port uint32) (map[string]string, error) {
+				fetches++
+				assert.Equal(t, uint32(9997), port)
+				return tc.fetched, tc.fetchErr
+			}
+
+			cfg, err := LoadConfig(context.Background(), path, fetch)
+			assert.Equal(t, tc.fetches, fetches)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, cfg)
+		})
+	}
+}
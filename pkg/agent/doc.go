@@ -0,0 +1,7 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package agent embeds the computation agent in custom guest images. It wires
+// the agent service the way the cocos-agent binary does, so guest images
+// can run the agent from their own init without forking its command.
+package agent
@@ -0,0 +1,98 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.38.0"
+)
+
+// agentMetrics are the agent metrics served on /metrics besides the API request count and latency.
+type agentMetrics struct {
+	uploads     metrics.Counter
+	uploadBytes metrics.Counter
+	runtime     metrics.Histogram
+	resent      metrics.Counter
+	attCache    metrics.Counter
+}
+
+// makeAgentMetrics registers the agent metrics, and the depth of the queue of
+// events and logs waiting to be sent to the manager.
+func makeAgentMetrics(queue chan *cvms.ClientStreamMessage) agentMetrics {
+	stdprometheus.MustRegister(stdprometheus.NewGaugeFunc(stdprometheus.GaugeOpts{
+		Namespace: svcName,
+		Subsystem: "events",
+		Name:      "queue_depth",
+		Help:      "Number of events and logs waiting to be sent to the manager.",
+	}, func() float64 { return float64(len(queue)) }))
+
+	return agentMetrics{
+		uploads: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "uploads",
+			Name:      "received_total",
+			Help:      "Number of accepted algorithm and dataset uploads.",
+		}, []string{"kind"}),
+		uploadBytes: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "uploads",
+			Name:      "bytes_total",
+			Help:      "Size of the accepted algorithm and dataset uploads in bytes.",
+		}, []string{"kind"}),
+		runtime: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: svcName,
+			Subsystem: "algorithm",
+			Name:      "runtime_seconds",
+			Help:      "Runtime of the algorithm, labelled with the event that ended the run.",
+			Buckets:   stdprometheus.ExponentialBuckets(1, 4, 10),
+		}, []string{"event"}),
+		resent: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "events",
+			Name:      "retransmissions_total",
+			Help:      "Number of events and logs sent again to the manager after a failed send.",
+		}, nil),
+		attCache: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "attestation",
+			Name:      "cache_lookups_total",
+			Help:      "Number of attestation report cache lookups, labelled with the hit or miss result.",
+		}, []string{"result"}),
+	}
+}
+
+// newTracerProvider registers a tracer provider exporting spans to the manager
+// over vsock. Spans are sampled as the manager trace they continue.
+func newTracerProvider(ctx context.Context, dial func() (net.Conn, error), cvmID string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptrace.New(ctx, vsock.NewSpanClient(dial))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.NeverSample())),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(svcName),
+			attribute.String("cvm.id", cvmID),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp, nil
+}
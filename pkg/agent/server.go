@@ -0,0 +1,404 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/absmach/certs/sdk"
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/absmach/supermq/pkg/prometheus"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm/logging"
+	"github.com/ultravioletrs/cocos/agent/api"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	cvmsapi "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
+	"github.com/ultravioletrs/cocos/agent/cvms/server"
+	"github.com/ultravioletrs/cocos/agent/datasetdisk"
+	"github.com/ultravioletrs/cocos/agent/events"
+	"github.com/ultravioletrs/cocos/agent/tracing"
+	agentlogger "github.com/ultravioletrs/cocos/internal/logger"
+	"github.com/ultravioletrs/cocos/internal/vsock"
+	"github.com/ultravioletrs/cocos/pkg/atls"
+	"github.com/ultravioletrs/cocos/pkg/attestation"
+	"github.com/ultravioletrs/cocos/pkg/attestation/azure"
+	pkggrpc "github.com/ultravioletrs/cocos/pkg/clients/grpc"
+	attestation_client "github.com/ultravioletrs/cocos/pkg/clients/grpc/attestation"
+	cvmsgrpc "github.com/ultravioletrs/cocos/pkg/clients/grpc/cvm"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
+)
+
+const svcName = "agent"
+
+// Hook is called with the agent service at a stage of the server lifecycle.
+type Hook func(ctx context.Context, svc agent.Service) error
+
+// Option configures optional behavior of a Server.
+type Option func(*Server)
+
+// WithLogOutput writes the agent logs to w, os.Stdout by default.
+func WithLogOutput(w io.Writer) Option {
+	return func(s *Server) {
+		s.logOutput = w
+	}
+}
+
+// WithStorageDir keeps the agent state in dir, Config.StorageDir by default.
+func WithStorageDir(dir string) Option {
+	return func(s *Server) {
+		s.storageDir = dir
+	}
+}
+
+// WithDatasetDiskDir mounts hot-added dataset disks under dir, Config.DatasetDiskDir by default.
+func WithDatasetDiskDir(dir string) Option {
+	return func(s *Server) {
+		s.datasetDiskDir = dir
+	}
+}
+
+// WithJournalKeyFile encrypts the computation journal with the key in file,
+// Config.JournalKeyFile by default. The journal is kept in Config.StateDir.
+func WithJournalKeyFile(file string) Option {
+	return func(s *Server) {
+		s.journalKeyFile = file
+	}
+}
+
+// WithMiddleware wraps the agent service with mw, on top of the logging,
+// metrics and tracing middlewares. Middlewares are applied in order, so the
+// last one is the outermost.
+func WithMiddleware(mw func(agent.Service) agent.Service) Option {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, mw)
+	}
+}
+
+// WithStartHook calls hook once the agent is connected to the computation
+// management service and its components run, before the agent reports its
+// attestation. The server stops if the hook fails.
+func WithStartHook(hook Hook) Option {
+	return func(s *Server) {
+		s.startHooks = append(s.startHooks, hook)
+	}
+}
+
+// WithStopHook calls hook once the agent components stopped, before Run
+// returns. Failures are logged.
+func WithStopHook(hook Hook) Option {
+	return func(s *Server) {
+		s.stopHooks = append(s.stopHooks, hook)
+	}
+}
+
+// Server runs the agent. Its metrics are registered with the default
+// Prometheus registry, so a process runs a single server.
+type Server struct {
+	cfg            Config
+	level          slog.Level
+	logOutput      io.Writer
+	storageDir     string
+	datasetDiskDir string
+	journalKeyFile string
+	middlewares    []func(agent.Service) agent.Service
+	startHooks     []Hook
+	stopHooks      []Hook
+}
+
+// NewServer returns a server running the agent with the configuration.
+func NewServer(cfg Config, opts ...Option) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cfg:            cfg,
+		logOutput:      os.Stdout,
+		storageDir:     orDefault(cfg.StorageDir, DefaultStorageDir),
+		datasetDiskDir: orDefault(cfg.DatasetDiskDir, DefaultDatasetDiskDir),
+		journalKeyFile: orDefault(cfg.JournalKeyFile, DefaultJournalKeyFile),
+	}
+	if s.cfg.EventsQueueSize == 0 {
+		s.cfg.EventsQueueSize = DefaultEventsQueueSize
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, errors.Wrap(ErrInvalidConfig, err)
+	}
+
+	return s, nil
+}
+
+// Run connects the agent to the computation management service and serves
+// computations until the agent fails or is shut down. Once ctx is done the
+// agent drains, as for a shutdown Stop request: the running algorithm gets the
+// shutdown grace period to end and the queued events are flushed before Run
+// returns.
+func (s *Server) Run(ctx context.Context) error {
+	// The computation outlives ctx while the agent drains.
+	stopCtx := ctx
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	g, ctx := errgroup.WithContext(runCtx)
+
+	cfg := s.cfg
+	eventsLogsQueue := make(chan *cvms.ClientStreamMessage, cfg.EventsQueueSize)
+
+	handler := agentlogger.NewProtoHandler(s.logOutput, &slog.HandlerOptions{Level: s.level}, eventsLogsQueue)
+	logger := slog.New(handler)
+
+	eventSvc, err := events.New(svcName, eventsLogsQueue)
+	if err != nil {
+		return fmt.Errorf("failed to create events service %s", err.Error())
+	}
+
+	am := makeAgentMetrics(eventsLogsQueue)
+	eventSvc = events.MetricsMiddleware(eventSvc, am.runtime)
+
+	var provider attestation.Provider
+	ccPlatform := attestation.CCPlatform()
+
+	azureConfig := azure.NewEnvConfigFromAgent(
+		cfg.AgentOSBuild,
+		cfg.AgentOSType,
+		cfg.AgentOSDistro,
+		cfg.AgentMaaURL,
+	)
+	azure.InitializeDefaultMAAVars(azureConfig)
+
+	cvmGRPCClient, cvmsClient, err := cvmsgrpc.NewCVMClient(cfg.CVMGrpc)
+	if err != nil {
+		return err
+	}
+	defer cvmGRPCClient.Close()
+
+	reconnectFn := func(ctx context.Context) (pkggrpc.Client, cvms.Service_ProcessClient, error) {
+		grpcClient, newClient, err := cvmsgrpc.NewCVMClient(cfg.CVMGrpc)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Don't defer close here as we want to keep the connection open
+
+		pc, err := newClient.Process(ctx)
+		if err != nil {
+			grpcClient.Close()
+			return nil, nil, err
+		}
+		return grpcClient, pc, nil
+	}
+
+	pc, err := cvmsClient.Process(ctx)
+	if err != nil {
+		return err
+	}
+
+	attClient, err := attestation_client.NewClient(cfg.AttestationServiceSocket)
+	if err != nil {
+		return fmt.Errorf("failed to create attestation client: %s", err)
+	}
+	defer attClient.Close()
+	attClient = agent.NewAttestationCache(attClient, cfg.AttestationCacheMaxAge, am.attCache)
+
+	var trustedKeys []crypto.PublicKey
+	if cfg.TrustedKeysFile != "" {
+		trustedKeys, err = agent.LoadTrustedKeys(cfg.TrustedKeysFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Warn("unsigned manifests are allowed, computation manifests are not verified")
+	}
+
+	var heartbeater *vsock.Heartbeater
+	var sendDiagnostics agent.DiagnosticsSender
+	dialHost := func() (net.Conn, error) { return vsock.DialHost(cfg.HeartbeatPort) }
+	if cfg.HeartbeatPort != 0 {
+		heartbeater = vsock.NewHeartbeater(dialHost, cfg.HeartbeatInterval, logger)
+		sendDiagnostics = func(ctx context.Context, snapshot []byte) error {
+			return vsock.SendDiagnostics(ctx, dialHost, snapshot)
+		}
+
+		tp, err := newTracerProvider(ctx, dialHost, cfg.CVMId)
+		if err != nil {
+			return fmt.Errorf("failed to init tracing: %s", err)
+		}
+		defer func() {
+			if err := tp.Shutdown(context.Background()); err != nil {
+				logger.Error(fmt.Sprintf("error shutting down tracer provider: %v", err))
+			}
+		}()
+	}
+
+	var logShipper *vsock.LogShipper
+	if cfg.LogsPort != 0 {
+		logShipper = vsock.NewLogShipper(func() (net.Conn, error) { return vsock.DialHost(cfg.LogsPort) }, logger, vsock.WithLogWindow(cfg.LogsWindow))
+	}
+
+	var journal *agent.Journal
+	if cfg.StateDir != "" {
+		if journal, err = agent.NewJournal(cfg.StateDir, s.journalKeyFile); err != nil {
+			return fmt.Errorf("failed to open computation journal: %s", err)
+		}
+	}
+
+	svc := newDrainingService(s.newService(ctx, logger, eventSvc, attClient, trustedKeys, heartbeater, logShipper, sendDiagnostics, journal, am), cfg.ShutdownGracePeriod)
+
+	if err := os.MkdirAll(s.storageDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %s", err)
+	}
+
+	var certProvider atls.CertificateProvider
+
+	if ccPlatform != attestation.NoCC {
+		var certsSDK sdk.SDK
+		if cfg.CAUrl != "" {
+			certsSDK = sdk.NewSDK(sdk.Config{
+				CertsURL: cfg.CAUrl,
+			})
+		}
+		certProvider, err = atls.NewProvider(provider, ccPlatform, cfg.CertsToken, cfg.CVMId, certsSDK)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate provider: %s", err)
+		}
+	}
+
+	serverCfg := server.Config{
+		Host:    cfg.AgentGrpcHost,
+		Port:    cfg.AgentGrpcPort,
+		TLSMode: server.TLSMode(cfg.TLSMode),
+		Limits:  cfg.GrpcLimits,
+		Uploads: cfg.GrpcUploads,
+	}
+	mc, err := cvmsapi.NewClient(pc, svc, eventsLogsQueue, logger, server.NewServer(logger, svc, serverCfg, eventSvc, certProvider), s.storageDir, reconnectFn, cvmGRPCClient, am.resent)
+	if err != nil {
+		return err
+	}
+
+	// The stop hooks run once the agent components returned.
+	defer s.runStopHooks(context.WithoutCancel(ctx), logger, svc)
+
+	g.Go(func() error {
+		return mc.Process(ctx, cancel)
+	})
+
+	g.Go(func() error {
+		return drain(ctx, stopCtx, svc, mc, logger, cancel)
+	})
+
+	g.Go(func() error {
+		return datasetdisk.NewWatcher(svc, logger, s.datasetDiskDir).Run(ctx)
+	})
+
+	if heartbeater != nil {
+		g.Go(func() error {
+			return heartbeater.Run(ctx)
+		})
+	}
+
+	if logShipper != nil {
+		g.Go(func() error {
+			return logShipper.Run(ctx)
+		})
+	}
+
+	if err := s.start(ctx, svc, ccPlatform, eventsLogsQueue); err != nil {
+		cancel()
+		_ = g.Wait()
+		return err
+	}
+
+	// Components return the context error once the agent is stopped, by the caller or the computation management service.
+	if err := g.Wait(); err != nil && runCtx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
+
+// start runs the start hooks and reports the attestation of the agent to the computation management service.
+func (s *Server) start(ctx context.Context, svc agent.Service, ccPlatform attestation.PlatformType, queue chan *cvms.ClientStreamMessage) error {
+	for _, hook := range s.startHooks {
+		if err := hook(ctx, svc); err != nil {
+			return fmt.Errorf("failed to run start hook: %w", err)
+		}
+	}
+
+	attest, certSerialNumber, err := attestationFromCert(ctx, s.cfg.CVMGrpc.ClientCert, svc)
+	if err != nil {
+		return fmt.Errorf("failed to get attestation: %s", err)
+	}
+
+	if ccPlatform == attestation.Azure {
+		azureAttestationToken, azureCertSerialNumber, err := azureAttestationFromCert(ctx, s.cfg.CVMGrpc.ClientCert, svc)
+		if err != nil {
+			return fmt.Errorf("failed to get attestation: %s", err)
+		}
+		queue <- &cvms.ClientStreamMessage{
+			Message: &cvms.ClientStreamMessage_AzureAttestationToken{
+				AzureAttestationToken: &cvms.AzureAttestationToken{
+					File:             azureAttestationToken,
+					CertSerialNumber: azureCertSerialNumber,
+				},
+			},
+		}
+	}
+
+	queue <- &cvms.ClientStreamMessage{
+		Message: &cvms.ClientStreamMessage_VTPMattestationReport{
+			VTPMattestationReport: &cvms.AttestationResponse{
+				File:             attest,
+				CertSerialNumber: certSerialNumber,
+			},
+		},
+	}
+
+	return nil
+}
+
+func (s *Server) runStopHooks(ctx context.Context, logger *slog.Logger, svc agent.Service) {
+	for _, hook := range s.stopHooks {
+		if err := hook(ctx, svc); err != nil {
+			logger.Error(fmt.Sprintf("failed to run stop hook: %s", err))
+		}
+	}
+}
+
+func (s *Server) newService(ctx context.Context, logger *slog.Logger, eventSvc events.Service, attClient attestation_client.Client, trustedKeys []crypto.PublicKey, heartbeater *vsock.Heartbeater, logShipper *vsock.LogShipper, sendDiagnostics agent.DiagnosticsSender, journal *agent.Journal, am agentMetrics) agent.Service {
+	var output logging.Output
+	if logShipper != nil {
+		output = logShipper
+	}
+	svc := agent.New(ctx, logger, eventSvc, attClient, s.cfg.Vmpl, trustedKeys, s.cfg.AllowUnhashedDatasets, output, sendDiagnostics, journal)
+
+	svc = api.LoggingMiddleware(svc, logger)
+	counter, latency := prometheus.MakeMetrics(svcName, "api")
+	svc = api.MetricsMiddleware(svc, counter, latency, am.uploads, am.uploadBytes)
+	if heartbeater != nil {
+		svc = tracing.New(svc, otel.Tracer(svcName), heartbeater.SpanContext)
+	}
+
+	for _, mw := range s.middlewares {
+		svc = mw(svc)
+	}
+
+	return svc
+}
+
+// orDefault returns value, or def when it is empty.
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
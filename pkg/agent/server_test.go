@@ -0,0 +1,120 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ultravioletrs/cocos/agent"
+)
+
+func TestNewServer(t *testing.T) {
+	var out bytes.Buffer
+	hook := func(ctx context.Context, svc agent.Service) error { return nil }
+	mw := func(svc agent.Service) agent.Service { return svc }
+
+	cases := []struct {
+		desc           string
+		cfg            Config
+		opts           []Option
+		storageDir     string
+		datasetDiskDir string
+		journalKeyFile string
+		hooks          int
+		err            error
+	}{
+		{
+			desc:           "default options",
+			cfg:            Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 2},
+			storageDir:     DefaultStorageDir,
+			datasetDiskDir: DefaultDatasetDiskDir,
+			journalKeyFile: DefaultJournalKeyFile,
+		},
+		{
+			desc: "custom options",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "debug"},
+			opts: []Option{
+				WithLogOutput(&out),
+				WithStorageDir("/tmp/agent"),
+				WithDatasetDiskDir("/tmp/datasets"),
+				WithJournalKeyFile("/tmp/journal.key"),
+				WithMiddleware(mw),
+				WithStartHook(hook),
+				WithStopHook(hook),
+			},
+			storageDir:     "/tmp/agent",
+			datasetDiskDir: "/tmp/datasets",
+			journalKeyFile: "/tmp/journal.key",
+			hooks:          1,
+		},
+		{
+			desc: "configured directories",
+			cfg: Config{
+				LogLevel:               "info",
+				AllowUnsignedManifests: true,
+				StorageDir:             "/srv/agent",
+				DatasetDiskDir:         "/srv/datasets",
+				JournalKeyFile:         "/srv/journal.key",
+			},
+			storageDir:     "/srv/agent",
+			datasetDiskDir: "/srv/datasets",
+			journalKeyFile: "/srv/journal.key",
+		},
+		{
+			desc: "invalid log level",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "verbose", Vmpl: 2},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "no trusted manifest keys",
+			cfg:  Config{LogLevel: "info", Vmpl: 2},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "invalid vmpl",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 4},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "negative shutdown grace period",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 2, ShutdownGracePeriod: -time.Second},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "negative logs window",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 2, LogsWindow: -1},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "negative attestation cache max age",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 2, AttestationCacheMaxAge: -time.Second},
+			err:  ErrInvalidConfig,
+		},
+		{
+			desc: "invalid tls mode",
+			cfg:  Config{AllowUnsignedManifests: true, LogLevel: "info", Vmpl: 2, TLSMode: "mtls"},
+			err:  ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewServer(tc.cfg, tc.opts...)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+			assert.Equal(t, tc.storageDir, s.storageDir)
+			assert.Equal(t, tc.datasetDiskDir, s.datasetDiskDir)
+			assert.Equal(t, tc.journalKeyFile, s.journalKeyFile)
+			assert.Len(t, s.middlewares, tc.hooks)
+			assert.Len(t, s.startHooks, tc.hooks)
+			assert.Len(t, s.stopHooks, tc.hooks)
+		})
+	}
+}
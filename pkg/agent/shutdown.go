@@ -0,0 +1,77 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ultravioletrs/cocos/agent"
+	cvmsapi "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
+)
+
+// eventsFlushTimeout bounds the wait for the events queued by a drained agent
+// to reach the computation management service, a variable so tests can wait
+// less.
+var eventsFlushTimeout = 5 * time.Second
+
+// drainingService drains the agent service once, for the shutdown grace
+// period at most, whether the drain is requested by a Stop request or the
+// caller of Run. The server exits once the agent is drained.
+type drainingService struct {
+	agent.Service
+	grace   time.Duration
+	once    sync.Once
+	err     error
+	drained chan struct{}
+}
+
+func newDrainingService(svc agent.Service, grace time.Duration) *drainingService {
+	return &drainingService{
+		Service: svc,
+		grace:   grace,
+		drained: make(chan struct{}),
+	}
+}
+
+// Shutdown drains the agent, the grace period does not end early when the
+// request that triggered it is canceled. Concurrent calls return once the
+// first drain ended.
+func (ds *drainingService) Shutdown(ctx context.Context) error {
+	ds.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ds.grace)
+		defer cancel()
+
+		ds.err = ds.Service.Shutdown(ctx)
+		close(ds.drained)
+	})
+
+	return ds.err
+}
+
+// drain waits for the agent to be shut down, by the caller of Run through
+// stopCtx or by a Stop request, then flushes its events and stops the agent
+// components with cancel. It returns when ctx is done first.
+func drain(ctx, stopCtx context.Context, svc *drainingService, mc *cvmsapi.CVMSClient, logger *slog.Logger, cancel context.CancelFunc) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-stopCtx.Done():
+		if err := svc.Shutdown(stopCtx); err != nil {
+			logger.Warn("failed to drain the agent", "error", err)
+		}
+	case <-svc.drained:
+	}
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), eventsFlushTimeout)
+	defer flushCancel()
+	if err := mc.Flush(flushCtx); err != nil {
+		logger.Warn("failed to flush the agent events", "error", err)
+	}
+
+	cancel()
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mglog "github.com/absmach/supermq/logger"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent/cvms"
+	cvmsapi "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
+	servermocks "github.com/ultravioletrs/cocos/agent/cvms/server/mocks"
+	"github.com/ultravioletrs/cocos/agent/mocks"
+)
+
+func TestDrainingServiceShutdown(t *testing.T) {
+	svc := new(mocks.Service)
+	var calls atomic.Int32
+	svc.On("Shutdown", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		calls.Add(1)
+		ctx := args.Get(0).(context.Context)
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "drain has no grace period")
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		assert.NoError(t, ctx.Err(), "drain ended with the request that triggered it")
+		time.Sleep(10 * time.Millisecond)
+	})
+	ds := newDrainingService(svc, time.Minute)
+
+	// The drain outlives the canceled request.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, ds.Shutdown(ctx))
+	}()
+	assert.NoError(t, ds.Shutdown(ctx))
+	<-done
+
+	assert.Equal(t, int32(1), calls.Load())
+	select {
+	case <-ds.drained:
+	default:
+		t.Fatal("drained is not closed after the drain")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	// The client does not process messages, so flushing them times out.
+	flushTimeout := eventsFlushTimeout
+	eventsFlushTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { eventsFlushTimeout = flushTimeout })
+
+	cases := []struct {
+		desc     string
+		stop     bool
+		request  bool
+		canceled bool
+	}{
+		{
+			desc:     "caller context done",
+			stop:     true,
+			canceled: true,
+		},
+		{
+			desc:     "shutdown request",
+			request:  true,
+			canceled: true,
+		},
+		{
+			desc: "agent stopped",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			svc := new(mocks.Service)
+			svc.On("Shutdown", mock.Anything).Return(nil)
+			ds := newDrainingService(svc, time.Second)
+
+			queue := make(chan *cvms.ClientStreamMessage, 1)
+			mc, err := cvmsapi.NewClient(nil, svc, queue, mglog.NewMock(), new(servermocks.AgentServer), t.TempDir(), nil, nil, discard.NewCounter())
+			require.NoError(t, err)
+
+			runCtx, runCancel := context.WithCancel(context.Background())
+			defer runCancel()
+			stopCtx, stop := context.WithCancel(context.Background())
+			defer stop()
+
+			canceled := false
+			cancel := func() { canceled = true }
+
+			switch {
+			case tc.stop:
+				stop()
+			case tc.request:
+				require.NoError(t, ds.Shutdown(context.Background()))
+			default:
+				runCancel()
+			}
+
+			assert.NoError(t, drain(runCtx, stopCtx, ds, mc, mglog.NewMock(), cancel))
+			assert.Equal(t, tc.canceled, canceled)
+			if tc.canceled {
+				svc.AssertNumberOfCalls(t, "Shutdown", 1)
+			}
+		})
+	}
+}
@@ -424,7 +424,7 @@ func TestPlatformVerifier(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			verifier, err := platformVerifier(c.platformType)
+			verifier, err := PlatformVerifier(c.platformType)
 
 			if c.expectedError {
 				assert.Error(t, err)
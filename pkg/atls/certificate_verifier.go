@@ -75,7 +75,7 @@ func (v *certificateVerifier) verifyAttestationExtension(cert *x509.Certificate,
 }
 
 func (v *certificateVerifier) verifyCertificateExtension(extension []byte, pubKey []byte, nonce []byte, platformType attestation.PlatformType) error {
-	verifier, err := platformVerifier(platformType)
+	verifier, err := PlatformVerifier(platformType)
 	if err != nil {
 		return fmt.Errorf("failed to get platform verifier: %w", err)
 	}
@@ -103,7 +103,9 @@ func platformTypeFromOID(oid asn1.ObjectIdentifier) (attestation.PlatformType, e
 	}
 }
 
-func platformVerifier(platformType attestation.PlatformType) (attestation.Verifier, error) {
+// PlatformVerifier returns the verifier of attestation reports of the platform,
+// configured with the attestation policy at attestation.AttestationPolicyPath.
+func PlatformVerifier(platformType attestation.PlatformType) (attestation.Verifier, error) {
 	var verifier attestation.Verifier
 
 	switch platformType {
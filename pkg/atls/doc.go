@@ -0,0 +1,18 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package atls implements attested TLS (aTLS) between the agent and its clients.
+//
+// The client sends a random 64 byte nonce, hex encoded with a ".nonce" suffix,
+// as the TLS server name. For every handshake the agent generates a key pair
+// and a certificate, self-signed or issued by the certificates service, that
+// embeds the attestation report of the CVM in a platform specific extension.
+// The report data binds the report to the handshake: it is the SHA3-512 hash
+// of the certificate public key followed by the nonce. The client verifies the
+// report against its attestation policy and the report data against the
+// certificate it received, so the connection is trusted because of the TEE
+// rather than a CA.
+//
+// aTLS is enabled with the ATTESTED_TLS flag of the agent server and client
+// configurations, when it is disabled they fall back to plain TLS or mTLS.
+package atls
@@ -21,6 +21,10 @@ type StandardClientConfig struct {
 	ClientCert   string        `env:"CLIENT_CERT"     envDefault:""`
 	ClientKey    string        `env:"CLIENT_KEY"      envDefault:""`
 	ServerCAFile string        `env:"SERVER_CA_CERTS" envDefault:""`
+	// MaxRecvMsgSize and MaxSendMsgSize bound the size in bytes of the messages
+	// the client receives and sends, gRPC defaults apply when they are 0.
+	MaxRecvMsgSize int `env:"MAX_RECV_MSG_SIZE" envDefault:"0"`
+	MaxSendMsgSize int `env:"MAX_SEND_MSG_SIZE" envDefault:"0"`
 }
 
 // AttestedClientConfig represents a client configuration with attested TLS capabilities.
@@ -8,8 +8,8 @@ import (
 
 	attestation_v1 "github.com/ultravioletrs/cocos/internal/proto/attestation/v1"
 	"github.com/ultravioletrs/cocos/pkg/attestation"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/ultravioletrs/cocos/pkg/clients"
+	"github.com/ultravioletrs/cocos/pkg/clients/grpc"
 )
 
 type Client interface {
@@ -19,19 +19,19 @@ type Client interface {
 }
 
 type client struct {
-	conn   *grpc.ClientConn
+	conn   grpc.Client
 	client attestation_v1.AttestationServiceClient
 }
 
 func NewClient(socketPath string) (Client, error) {
-	conn, err := grpc.NewClient("unix://"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(clients.StandardClientConfig{URL: "unix://" + socketPath})
 	if err != nil {
 		return nil, err
 	}
 
 	return &client{
 		conn:   conn,
-		client: attestation_v1.NewAttestationServiceClient(conn),
+		client: attestation_v1.NewAttestationServiceClient(conn.Connection()),
 	}, nil
 }
 
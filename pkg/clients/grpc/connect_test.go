@@ -166,6 +166,86 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestDialOptions(t *testing.T) {
+	caCertFile, clientCertFile, clientKeyFile, err := createCertificatesFiles()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		os.Remove(caCertFile)
+		os.Remove(clientCertFile)
+		os.Remove(clientKeyFile)
+	})
+
+	policy := "../../../scripts/attestation_policy/sev-snp/attestation_policy.json"
+
+	tests := []struct {
+		name     string
+		cfg      clients.ClientConfiguration
+		security clients.Security
+	}{
+		{
+			name:     "without TLS",
+			cfg:      clients.StandardClientConfig{URL: "localhost:7001"},
+			security: clients.WithoutTLS,
+		},
+		{
+			name:     "without TLS with message size limits",
+			cfg:      clients.StandardClientConfig{URL: "localhost:7001", MaxRecvMsgSize: 16 << 20, MaxSendMsgSize: 16 << 20},
+			security: clients.WithoutTLS,
+		},
+		{
+			name:     "TLS with server CA",
+			cfg:      clients.StandardClientConfig{URL: "localhost:7001", ServerCAFile: caCertFile, Timeout: time.Second},
+			security: clients.WithTLS,
+		},
+		{
+			name: "mTLS with client certificate",
+			cfg: clients.StandardClientConfig{
+				URL:          "localhost:7001",
+				ServerCAFile: caCertFile,
+				ClientCert:   clientCertFile,
+				ClientKey:    clientKeyFile,
+			},
+			security: clients.WithMTLS,
+		},
+		{
+			name: "attested client without aTLS",
+			cfg: clients.AttestedClientConfig{
+				StandardClientConfig: clients.StandardClientConfig{URL: "localhost:7001", ServerCAFile: caCertFile},
+				AttestationPolicy:    policy,
+			},
+			security: clients.WithTLS,
+		},
+		{
+			name: "aTLS",
+			cfg: clients.AttestedClientConfig{
+				StandardClientConfig: clients.StandardClientConfig{URL: "localhost:7001"},
+				AttestationPolicy:    policy,
+				AttestedTLS:          true,
+			},
+			security: clients.WithATLS,
+		},
+		{
+			name: "aTLS with server CA",
+			cfg: clients.AttestedClientConfig{
+				StandardClientConfig: clients.StandardClientConfig{URL: "localhost:7001", ServerCAFile: caCertFile},
+				AttestationPolicy:    policy,
+				AttestedTLS:          true,
+			},
+			security: clients.WithMATLS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, security, err := dialOptions(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.security, security)
+			assert.NotEmpty(t, opts)
+		})
+	}
+}
+
 func TestClientSecure(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1,6 +1,8 @@
 // Copyright (c) Ultraviolet
 // SPDX-License-Identifier: Apache-2.0
 
-// Package grpc contains the domain concept definitions needed to support
-// Agent Client grpc functionality.
+// Package grpc contains the gRPC client factory shared by the CLI, the agent
+// and the manager. NewClient dials the URL of a client configuration with
+// attested TLS, mTLS, TLS or without TLS, and the agent, manager and CVM
+// subpackages wrap the connection into service clients.
 package grpc
@@ -8,6 +8,7 @@ import (
 	"github.com/ultravioletrs/cocos/pkg/clients"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -17,6 +18,7 @@ var (
 	errGrpcClose   = errors.New("failed to close grpc connection")
 )
 
+// Client is a gRPC client connection secured according to its configuration.
 type Client interface {
 	Close() error
 	Secure() string
@@ -31,6 +33,8 @@ type client struct {
 
 var _ Client = (*client)(nil)
 
+// NewClient returns a client connection to the configured URL, dialing with
+// attested TLS, mTLS, TLS or without TLS depending on the configuration.
 func NewClient(cfg clients.ClientConfiguration) (Client, error) {
 	conn, security, err := connect(cfg)
 	if err != nil {
@@ -60,11 +64,45 @@ func (c *client) Connection() *grpc.ClientConn {
 }
 
 func connect(cfg clients.ClientConfiguration) (*grpc.ClientConn, clients.Security, error) {
+	opts, security, err := dialOptions(cfg)
+	if err != nil {
+		return nil, security, err
+	}
+
+	conn, err := grpc.NewClient(cfg.Config().URL, opts...)
+	if err != nil {
+		return nil, security, errors.Wrap(errGrpcConnect, err)
+	}
+	return conn, security, nil
+}
+
+// dialOptions selects the security mode of the configuration, attested TLS when
+// it is enabled, otherwise mTLS, TLS or no TLS depending on the certificates set,
+// and returns the options dialing with it.
+func dialOptions(cfg clients.ClientConfiguration) ([]grpc.DialOption, clients.Security, error) {
 	opts := []grpc.DialOption{
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	}
 	security := clients.WithoutTLS
 
+	if timeout := cfg.Config().Timeout; timeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: timeout,
+		}))
+	}
+
+	if conf := cfg.Config(); conf.MaxRecvMsgSize > 0 || conf.MaxSendMsgSize > 0 {
+		var callOpts []grpc.CallOption
+		if conf.MaxRecvMsgSize > 0 {
+			callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(conf.MaxRecvMsgSize))
+		}
+		if conf.MaxSendMsgSize > 0 {
+			callOpts = append(callOpts, grpc.MaxCallSendMsgSize(conf.MaxSendMsgSize))
+		}
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
 	if agcfg, ok := cfg.(clients.AttestedClientConfig); ok && agcfg.AttestedTLS {
 		result, err := clients.LoadATLSConfig(agcfg)
 		if err != nil {
@@ -72,22 +110,16 @@ func connect(cfg clients.ClientConfiguration) (*grpc.ClientConn, clients.Securit
 		}
 
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(result.Config)))
-		security = result.Security
-	} else {
-		conf := cfg.Config()
-		transportCreds, sec, err := loadTLSConfig(conf.ServerCAFile, conf.ClientCert, conf.ClientKey)
-		if err != nil {
-			return nil, security, err
-		}
-		opts = append(opts, grpc.WithTransportCredentials(transportCreds))
-		security = sec
+		return opts, result.Security, nil
 	}
 
-	conn, err := grpc.NewClient(cfg.Config().URL, opts...)
+	conf := cfg.Config()
+	transportCreds, security, err := loadTLSConfig(conf.ServerCAFile, conf.ClientCert, conf.ClientKey)
 	if err != nil {
-		return nil, security, errors.Wrap(errGrpcConnect, err)
+		return nil, security, err
 	}
-	return conn, security, nil
+
+	return append(opts, grpc.WithTransportCredentials(transportCreds)), security, nil
 }
 
 func loadTLSConfig(serverCAFile, clientCert, clientKey string) (credentials.TransportCredentials, clients.Security, error) {
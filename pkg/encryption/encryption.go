@@ -0,0 +1,145 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+const (
+	keySize = 32
+	// hkdfInfo binds derived keys to result encryption.
+	hkdfInfo = "cocos result encryption"
+)
+
+var (
+	ErrInvalidPublicKey   = errors.New("invalid X25519 public key")
+	ErrInvalidPrivateKey  = errors.New("invalid X25519 private key")
+	ErrCiphertextTooShort = errors.New("ciphertext is too short")
+)
+
+// ParsePublicKey parses a PEM encoded PKIX or raw 32 byte X25519 public key.
+func ParsePublicKey(data []byte) (*ecdh.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		key, err := ecdh.X25519().NewPublicKey(data)
+		if err != nil {
+			return nil, ErrInvalidPublicKey
+		}
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	pub, ok := key.(*ecdh.PublicKey)
+	if !ok || pub.Curve() != ecdh.X25519() {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return pub, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 X25519 private key.
+func ParsePrivateKey(data []byte) (*ecdh.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	priv, ok := key.(*ecdh.PrivateKey)
+	if !ok || priv.Curve() != ecdh.X25519() {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	return priv, nil
+}
+
+// Encrypt encrypts plaintext for the holder of the private key matching pub.
+// An ephemeral X25519 key is agreed with pub and the shared secret is expanded
+// with HKDF-SHA256 into an AES-256-GCM key. The output is the ephemeral public
+// key, followed by the nonce and the sealed plaintext.
+func Encrypt(pub *ecdh.PublicKey, plaintext []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(ephemeral, pub, ephemeral.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+
+	out := make([]byte, 0, len(ephemeralPub)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, ephemeralPub...)
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, plaintext, ephemeralPub), nil
+}
+
+// Decrypt reverses Encrypt using the recipient private key.
+func Decrypt(priv *ecdh.PrivateKey, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < keySize {
+		return nil, ErrCiphertextTooShort
+	}
+
+	ephemeralPub, err := ecdh.X25519().NewPublicKey(ciphertext[:keySize])
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	aead, err := newAEAD(priv, ephemeralPub, ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := ciphertext[keySize:]
+	if len(rest) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+
+	nonce, sealed := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, sealed, ephemeralPub.Bytes())
+}
+
+func newAEAD(priv *ecdh.PrivateKey, peer, ephemeral *ecdh.PublicKey) (cipher.AEAD, error) {
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, ephemeral.Bytes(), hkdfInfo, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package encryption
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKeyPair(t *testing.T) (*ecdh.PrivateKey, []byte, []byte) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pubDer, err := x509.MarshalPKIXPublicKey(priv.PublicKey())
+	require.NoError(t, err)
+
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	return priv,
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDer})
+}
+
+func TestParsePublicKey(t *testing.T) {
+	priv, pubPem, _ := generateKeyPair(t)
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDer, err := x509.MarshalPKIXPublicKey(edPub)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc string
+		data []byte
+		err  error
+	}{
+		{
+			desc: "PEM encoded key",
+			data: pubPem,
+		},
+		{
+			desc: "raw key",
+			data: priv.PublicKey().Bytes(),
+		},
+		{
+			desc: "non X25519 key",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edDer}),
+			err:  ErrInvalidPublicKey,
+		},
+		{
+			desc: "malformed key",
+			data: []byte("invalid"),
+			err:  ErrInvalidPublicKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			pub, err := ParsePublicKey(tc.data)
+			assert.ErrorIs(t, err, tc.err)
+			if tc.err == nil {
+				assert.True(t, pub.Equal(priv.PublicKey()))
+			}
+		})
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	priv, pubPem, privPem := generateKeyPair(t)
+
+	key, err := ParsePrivateKey(privPem)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(priv))
+
+	_, err = ParsePrivateKey(pubPem)
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+
+	_, err = ParsePrivateKey([]byte("invalid"))
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv, _, _ := generateKeyPair(t)
+	other, _, _ := generateKeyPair(t)
+
+	plaintext := []byte("computation result")
+
+	ciphertext, err := Encrypt(priv.PublicKey(), plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), string(plaintext))
+
+	decrypted, err := Decrypt(priv, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	_, err = Decrypt(other, ciphertext)
+	assert.Error(t, err)
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = Decrypt(priv, tampered)
+	assert.Error(t, err)
+
+	_, err = Decrypt(priv, ciphertext[:keySize+4])
+	assert.ErrorIs(t, err, ErrCiphertextTooShort)
+}
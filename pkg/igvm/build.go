@@ -0,0 +1,153 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package igvm
+
+import (
+	"fmt"
+
+	"github.com/virtee/sev-snp-measure-go/gctx"
+	"github.com/virtee/sev-snp-measure-go/ovmf"
+	"github.com/virtee/sev-snp-measure-go/sevhashes"
+	"github.com/virtee/sev-snp-measure-go/vmmtypes"
+	"github.com/virtee/sev-snp-measure-go/vmsa"
+)
+
+const (
+	// DefaultSNPPolicy is the SEV-SNP guest policy of QEMU, SMT allowed and ABI 0.0 or later.
+	DefaultSNPPolicy = 0x30000
+
+	snpCompatibilityMask = 0x1
+)
+
+// SNPConfig describes a SEV-SNP guest booting a kernel directly from OVMF.
+type SNPConfig struct {
+	// OVMF is the path of the OVMF firmware built for SEV-SNP (AmdSev).
+	OVMF string
+	// Kernel, Initrd and Append are the kernel, initrd and command line the
+	// guest boots. Their hashes are loaded in the OVMF kernel hashes page so
+	// OVMF refuses to boot anything else. The kernel hashes are omitted when
+	// Kernel is empty.
+	Kernel string
+	Initrd string
+	Append string
+	// VCPUs is the number of vCPUs the guest is launched with, each gets a VMSA.
+	VCPUs int
+	// VCPUSig is the CPUID signature of the vCPU type.
+	VCPUSig uint64
+	// GuestFeatures are the SEV features enabled in the VMSAs.
+	GuestFeatures uint64
+	// Policy is the guest policy, DefaultSNPPolicy when it is 0.
+	Policy uint64
+}
+
+// BuildSNP merges the OVMF firmware, the hashes of the kernel, initrd and
+// command line and the initial vCPU state into an IGVM file. Its launch digest
+// is the one of a direct boot of the same components.
+func BuildSNP(cfg SNPConfig) (*File, error) {
+	if cfg.VCPUs < 1 {
+		return nil, fmt.Errorf("at least one vCPU is required, got %d", cfg.VCPUs)
+	}
+
+	fw, err := ovmf.New(cfg.OVMF, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OVMF: %w", err)
+	}
+
+	var hashes *sevhashes.SevHashes
+	if cfg.Kernel != "" {
+		if !fw.HasMetadataSection(ovmf.SNPKernelHashes) {
+			return nil, fmt.Errorf("OVMF has no SNP kernel hashes section")
+		}
+		if hashes, err = sevhashes.New(cfg.Kernel, cfg.Initrd, cfg.Append); err != nil {
+			return nil, fmt.Errorf("hashing the kernel: %w", err)
+		}
+	}
+
+	policy := cfg.Policy
+	if policy == 0 {
+		policy = DefaultSNPPolicy
+	}
+
+	file := &File{
+		Headers: []Header{
+			&SupportedPlatform{CompatibilityMask: snpCompatibilityMask, PlatformType: PlatformSEVSNP, PlatformVersion: 1},
+			&GuestPolicy{Policy: policy, CompatibilityMask: snpCompatibilityMask},
+		},
+	}
+
+	data := fw.Data()
+	if len(data)%PageSize != 0 {
+		return nil, fmt.Errorf("OVMF size %d is not a multiple of the page size", len(data))
+	}
+	for offset := 0; offset < len(data); offset += PageSize {
+		file.add(PageDataNormal, uint64(fw.GPA()+offset), data[offset:offset+PageSize])
+	}
+
+	for _, section := range fw.MetadataItems() {
+		sectionType, err := section.SectionType()
+		if err != nil {
+			return nil, err
+		}
+
+		gpa, size := uint64(section.GPA), int(section.Size)
+		switch sectionType {
+		case ovmf.SNPSECMEM, ovmf.SVSMCAA:
+			file.addZero(gpa, size)
+		case ovmf.SNPSecrets:
+			file.add(PageDataSecrets, gpa, nil)
+		case ovmf.CPUID:
+			file.add(PageDataCPUID, gpa, nil)
+		case ovmf.SNPKernelHashes:
+			if hashes == nil {
+				file.addZero(gpa, size)
+				continue
+			}
+			page, err := hashes.ConstructPage(fw.SevHashesTableGPA() & (PageSize - 1))
+			if err != nil {
+				return nil, err
+			}
+			if size != len(page) {
+				return nil, fmt.Errorf("kernel hashes section of %d bytes, expected %d", size, len(page))
+			}
+			file.add(PageDataNormal, gpa, page)
+		}
+	}
+
+	eip, err := fw.SevESResetEIP()
+	if err != nil {
+		return nil, err
+	}
+	state, err := vmsa.New(eip, cfg.GuestFeatures, cfg.VCPUSig, vmmtypes.QEMU)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := state.Pages(cfg.VCPUs)
+	if err != nil {
+		return nil, err
+	}
+	for i, page := range pages {
+		file.Headers = append(file.Headers, &VPContext{
+			GPA:               gctx.VMSA_GPA,
+			CompatibilityMask: snpCompatibilityMask,
+			VPIndex:           uint16(i),
+			Data:              page,
+		})
+	}
+
+	return file, nil
+}
+
+func (f *File) add(dataType uint16, gpa uint64, data []byte) {
+	f.Headers = append(f.Headers, &PageData{
+		GPA:               gpa,
+		CompatibilityMask: snpCompatibilityMask,
+		DataType:          dataType,
+		Data:              data,
+	})
+}
+
+func (f *File) addZero(gpa uint64, size int) {
+	for offset := 0; offset < size; offset += PageSize {
+		f.add(PageDataNormal, gpa+uint64(offset), nil)
+	}
+}
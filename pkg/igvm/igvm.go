@@ -0,0 +1,499 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package igvm reads and writes Independent Guest Virtual Machine (IGVM) files,
+// builds IGVM files for SEV-SNP guests from OVMF and the hashes of the kernel
+// they boot, and computes the SEV-SNP launch digest of IGVM files.
+package igvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+const (
+	// Magic is the signature of IGVM files, "IGVM" in little-endian.
+	Magic = 0x4D564749
+	// FormatVersion is the IGVM format version files are written with.
+	FormatVersion = 1
+
+	// PageSize is the size of the pages IGVM directives load.
+	PageSize = 4096
+
+	fixedHeaderSize = 24
+	// fixedHeaderV2Size is the size of the fixed header of version 2 files,
+	// which adds the architecture and page size.
+	fixedHeaderV2Size = 32
+	headerAlignment   = 8
+)
+
+// Variable header types.
+const (
+	TypeSupportedPlatform uint32 = 0x1
+	TypeGuestPolicy       uint32 = 0x101
+	TypeParameterArea     uint32 = 0x301
+	TypePageData          uint32 = 0x302
+	TypeParameterInsert   uint32 = 0x303
+	TypeVPContext         uint32 = 0x304
+)
+
+// PlatformSEVSNP is the supported platform type of SEV-SNP guests.
+const PlatformSEVSNP uint8 = 0x2
+
+// Page data flags.
+const (
+	PageFlag2MB        uint32 = 1 << 0
+	PageFlagUnmeasured uint32 = 1 << 1
+	PageFlagShared     uint32 = 1 << 2
+)
+
+// Page data types.
+const (
+	PageDataNormal  uint16 = 0
+	PageDataSecrets uint16 = 1
+	PageDataCPUID   uint16 = 2
+	PageDataCPUIDXF uint16 = 3
+)
+
+var (
+	// ErrInvalidFile indicates data that is not a well formed IGVM file.
+	ErrInvalidFile = errors.New("invalid IGVM file")
+	// ErrChecksum indicates an IGVM file whose headers do not match their checksum.
+	ErrChecksum = errors.New("IGVM header checksum mismatch")
+	// ErrUnsupported indicates an IGVM file with directives that cannot be measured.
+	ErrUnsupported = errors.New("unsupported IGVM directive")
+)
+
+// Header is a variable header of an IGVM file, one of *SupportedPlatform,
+// *GuestPolicy, *ParameterArea, *PageData, *ParameterInsert, *VPContext or
+// *Unknown.
+type Header interface {
+	Type() uint32
+}
+
+// SupportedPlatform declares a platform the file can be loaded on, directives
+// apply to the platforms whose compatibility mask they match.
+type SupportedPlatform struct {
+	CompatibilityMask uint32
+	HighestVTL        uint8
+	PlatformType      uint8
+	PlatformVersion   uint16
+	SharedGPABoundary uint64
+}
+
+// GuestPolicy is the guest policy the platform launches the guest with.
+type GuestPolicy struct {
+	Policy            uint64
+	CompatibilityMask uint32
+}
+
+// ParameterArea declares an area the loader fills with parameters, the area
+// is zeroed when Data is empty.
+type ParameterArea struct {
+	NumberOfBytes uint64
+	Index         uint32
+	Data          []byte
+}
+
+// PageData loads a page at GPA, the page is zeroed when Data is empty and
+// padded with zeros when Data is shorter than a page.
+type PageData struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	Flags             uint32
+	DataType          uint16
+	Data              []byte
+}
+
+// ParameterInsert maps the parameter area Index into the guest at GPA.
+type ParameterInsert struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	Index             uint32
+}
+
+// VPContext sets the initial register state of a virtual processor, on
+// SEV-SNP Data is the VMSA page loaded at GPA.
+type VPContext struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	VPIndex           uint16
+	Data              []byte
+}
+
+// Unknown keeps the body of a header this package does not interpret. File
+// offsets in the body are not relocated when the file is marshaled.
+type Unknown struct {
+	HeaderType uint32
+	Body       []byte
+}
+
+func (*SupportedPlatform) Type() uint32 { return TypeSupportedPlatform }
+func (*GuestPolicy) Type() uint32       { return TypeGuestPolicy }
+func (*ParameterArea) Type() uint32     { return TypeParameterArea }
+func (*PageData) Type() uint32          { return TypePageData }
+func (*ParameterInsert) Type() uint32   { return TypeParameterInsert }
+func (*VPContext) Type() uint32         { return TypeVPContext }
+func (u *Unknown) Type() uint32         { return u.HeaderType }
+
+// File is an IGVM file, its headers in file order.
+type File struct {
+	Headers []Header
+}
+
+type fixedHeader struct {
+	Magic                uint32
+	FormatVersion        uint32
+	VariableHeaderOffset uint32
+	VariableHeaderSize   uint32
+	TotalFileSize        uint32
+	Checksum             uint32
+}
+
+type supportedPlatformBody struct {
+	CompatibilityMask uint32
+	HighestVTL        uint8
+	PlatformType      uint8
+	PlatformVersion   uint16
+	SharedGPABoundary uint64
+}
+
+type guestPolicyBody struct {
+	Policy            uint64
+	CompatibilityMask uint32
+	Reserved          uint32
+}
+
+type parameterAreaBody struct {
+	NumberOfBytes uint64
+	Index         uint32
+	FileOffset    uint32
+}
+
+type pageDataBody struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	FileOffset        uint32
+	Flags             uint32
+	DataType          uint16
+	Reserved          uint16
+}
+
+type parameterInsertBody struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	Index             uint32
+}
+
+type vpContextBody struct {
+	GPA               uint64
+	CompatibilityMask uint32
+	FileOffset        uint32
+	VPIndex           uint16
+	Reserved          uint16
+	Padding           uint32
+}
+
+// ReadFile parses the IGVM file at path.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses an IGVM file and verifies the checksum of its headers.
+func Parse(data []byte) (*File, error) {
+	var fh fixedHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &fh); err != nil {
+		return nil, errors.Wrap(ErrInvalidFile, err)
+	}
+
+	if fh.Magic != Magic {
+		return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("magic %#x", fh.Magic))
+	}
+
+	fixedSize := fixedHeaderSize
+	switch fh.FormatVersion {
+	case 1:
+	case 2:
+		fixedSize = fixedHeaderV2Size
+	default:
+		return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("format version %d", fh.FormatVersion))
+	}
+
+	start, end := int(fh.VariableHeaderOffset), int(fh.VariableHeaderOffset)+int(fh.VariableHeaderSize)
+	if start < fixedSize || end > len(data) || int(fh.TotalFileSize) != len(data) {
+		return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("headers %d-%d of a %d bytes file", start, end, len(data)))
+	}
+
+	if checksum(data[:fixedSize], data[start:end]) != fh.Checksum {
+		return nil, ErrChecksum
+	}
+
+	file := &File{}
+	for offset := start; offset < end; {
+		if end-offset < headerAlignment {
+			return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("truncated header at %d", offset))
+		}
+		typ := binary.LittleEndian.Uint32(data[offset:])
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		offset += headerAlignment
+
+		if length > end-offset {
+			return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("header %#x of %d bytes at %d", typ, length, offset))
+		}
+
+		header, err := parseHeader(data, typ, data[offset:offset+length])
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("header %#x at %d: %w", typ, offset, err))
+		}
+		file.Headers = append(file.Headers, header)
+
+		offset += align(length)
+	}
+
+	return file, nil
+}
+
+func parseHeader(data []byte, typ uint32, body []byte) (Header, error) {
+	r := bytes.NewReader(body)
+
+	switch typ {
+	case TypeSupportedPlatform:
+		var b supportedPlatformBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+
+		return &SupportedPlatform{
+			CompatibilityMask: b.CompatibilityMask,
+			HighestVTL:        b.HighestVTL,
+			PlatformType:      b.PlatformType,
+			PlatformVersion:   b.PlatformVersion,
+			SharedGPABoundary: b.SharedGPABoundary,
+		}, nil
+	case TypeGuestPolicy:
+		var b guestPolicyBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+
+		return &GuestPolicy{Policy: b.Policy, CompatibilityMask: b.CompatibilityMask}, nil
+	case TypeParameterArea:
+		var b parameterAreaBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+		area, err := fileData(data, b.FileOffset, b.NumberOfBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ParameterArea{NumberOfBytes: b.NumberOfBytes, Index: b.Index, Data: area}, nil
+	case TypePageData:
+		var b pageDataBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+		page, err := fileData(data, b.FileOffset, pageSize(b.Flags))
+		if err != nil {
+			return nil, err
+		}
+
+		return &PageData{
+			GPA:               b.GPA,
+			CompatibilityMask: b.CompatibilityMask,
+			Flags:             b.Flags,
+			DataType:          b.DataType,
+			Data:              page,
+		}, nil
+	case TypeParameterInsert:
+		var b parameterInsertBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+
+		return &ParameterInsert{GPA: b.GPA, CompatibilityMask: b.CompatibilityMask, Index: b.Index}, nil
+	case TypeVPContext:
+		var b vpContextBody
+		if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+			return nil, err
+		}
+		context, err := fileData(data, b.FileOffset, PageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		return &VPContext{GPA: b.GPA, CompatibilityMask: b.CompatibilityMask, VPIndex: b.VPIndex, Data: context}, nil
+	default:
+		return &Unknown{HeaderType: typ, Body: bytes.Clone(body)}, nil
+	}
+}
+
+// fileData returns up to size bytes of the file data at offset, nil for the
+// zero offset. Data stored at the end of the file may be shorter than size.
+func fileData(data []byte, offset uint32, size uint64) ([]byte, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if int(offset) >= len(data) {
+		return nil, fmt.Errorf("file offset %d is beyond the end of the file", offset)
+	}
+
+	end := min(uint64(len(data)), uint64(offset)+size)
+
+	return bytes.Clone(data[offset:end]), nil
+}
+
+// Marshal encodes the file. The data of the directives is stored after the
+// headers, in the order of the headers.
+func (f *File) Marshal() ([]byte, error) {
+	headersSize := 0
+	dataSize := 0
+	for _, h := range f.Headers {
+		body, err := marshalBody(h, 0)
+		if err != nil {
+			return nil, err
+		}
+		headersSize += headerAlignment + align(len(body))
+		dataSize += len(headerData(h))
+	}
+
+	var headers, data bytes.Buffer
+	dataOffset := fixedHeaderSize + headersSize
+	for _, h := range f.Headers {
+		offset := uint32(0)
+		if d := headerData(h); len(d) > 0 {
+			offset = uint32(dataOffset + data.Len())
+			data.Write(d)
+		}
+
+		body, err := marshalBody(h, offset)
+		if err != nil {
+			return nil, err
+		}
+		writeHeader(&headers, h.Type(), body)
+	}
+
+	fh := fixedHeader{
+		Magic:                Magic,
+		FormatVersion:        FormatVersion,
+		VariableHeaderOffset: fixedHeaderSize,
+		VariableHeaderSize:   uint32(headersSize),
+		TotalFileSize:        uint32(dataOffset + dataSize),
+	}
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, fh); err != nil {
+		return nil, err
+	}
+	out.Write(headers.Bytes())
+	out.Write(data.Bytes())
+
+	encoded := out.Bytes()
+	binary.LittleEndian.PutUint32(encoded[20:], checksum(encoded[:fixedHeaderSize], headers.Bytes()))
+
+	return encoded, nil
+}
+
+// headerData returns the file data the header refers to.
+func headerData(h Header) []byte {
+	switch h := h.(type) {
+	case *ParameterArea:
+		return h.Data
+	case *PageData:
+		return h.Data
+	case *VPContext:
+		return h.Data
+	default:
+		return nil
+	}
+}
+
+func marshalBody(h Header, fileOffset uint32) ([]byte, error) {
+	var body any
+
+	switch h := h.(type) {
+	case *SupportedPlatform:
+		body = supportedPlatformBody{
+			CompatibilityMask: h.CompatibilityMask,
+			HighestVTL:        h.HighestVTL,
+			PlatformType:      h.PlatformType,
+			PlatformVersion:   h.PlatformVersion,
+			SharedGPABoundary: h.SharedGPABoundary,
+		}
+	case *GuestPolicy:
+		body = guestPolicyBody{Policy: h.Policy, CompatibilityMask: h.CompatibilityMask}
+	case *ParameterArea:
+		if uint64(len(h.Data)) > h.NumberOfBytes {
+			return nil, fmt.Errorf("parameter area %d holds %d of %d bytes", h.Index, len(h.Data), h.NumberOfBytes)
+		}
+		body = parameterAreaBody{NumberOfBytes: h.NumberOfBytes, Index: h.Index, FileOffset: fileOffset}
+	case *PageData:
+		if uint64(len(h.Data)) > pageSize(h.Flags) {
+			return nil, fmt.Errorf("page %#x holds %d bytes", h.GPA, len(h.Data))
+		}
+		body = pageDataBody{
+			GPA:               h.GPA,
+			CompatibilityMask: h.CompatibilityMask,
+			FileOffset:        fileOffset,
+			Flags:             h.Flags,
+			DataType:          h.DataType,
+		}
+	case *ParameterInsert:
+		body = parameterInsertBody{GPA: h.GPA, CompatibilityMask: h.CompatibilityMask, Index: h.Index}
+	case *VPContext:
+		if len(h.Data) > PageSize {
+			return nil, fmt.Errorf("VP context %d holds %d bytes", h.VPIndex, len(h.Data))
+		}
+		body = vpContextBody{GPA: h.GPA, CompatibilityMask: h.CompatibilityMask, FileOffset: fileOffset, VPIndex: h.VPIndex}
+	case *Unknown:
+		return h.Body, nil
+	default:
+		return nil, fmt.Errorf("unknown header %T", h)
+	}
+
+	var raw bytes.Buffer
+	if err := binary.Write(&raw, binary.LittleEndian, body); err != nil {
+		return nil, err
+	}
+
+	return raw.Bytes(), nil
+}
+
+func writeHeader(w *bytes.Buffer, typ uint32, body []byte) {
+	var prefix [headerAlignment]byte
+	binary.LittleEndian.PutUint32(prefix[:], typ)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(body)))
+	w.Write(prefix[:])
+	w.Write(body)
+	w.Write(make([]byte, align(len(body))-len(body)))
+}
+
+// checksum is the CRC32 of the fixed header, with a zero checksum, and of the variable headers.
+func checksum(fixed, headers []byte) uint32 {
+	fixed = bytes.Clone(fixed)
+	binary.LittleEndian.PutUint32(fixed[20:], 0)
+
+	return crc32.Update(crc32.ChecksumIEEE(fixed), crc32.IEEETable, headers)
+}
+
+func pageSize(flags uint32) uint64 {
+	if flags&PageFlag2MB != 0 {
+		return 2 << 20
+	}
+
+	return PageSize
+}
+
+func align(n int) int {
+	return (n + headerAlignment - 1) &^ (headerAlignment - 1)
+}
@@ -0,0 +1,249 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package igvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/virtee/sev-snp-measure-go/cpuid"
+	"github.com/virtee/sev-snp-measure-go/guest"
+	"github.com/virtee/sev-snp-measure-go/vmmtypes"
+)
+
+func TestMarshalParse(t *testing.T) {
+	file := &File{
+		Headers: []Header{
+			&SupportedPlatform{CompatibilityMask: 1, PlatformType: PlatformSEVSNP, PlatformVersion: 1},
+			&GuestPolicy{Policy: DefaultSNPPolicy, CompatibilityMask: 1},
+			&ParameterArea{NumberOfBytes: 2 * PageSize, Index: 3},
+			&PageData{GPA: 0x1000, CompatibilityMask: 1, Data: bytes.Repeat([]byte{0xAB}, PageSize)},
+			&PageData{GPA: 0x2000, CompatibilityMask: 1},
+			&PageData{GPA: 0x3000, CompatibilityMask: 1, DataType: PageDataSecrets},
+			&ParameterInsert{GPA: 0x4000, CompatibilityMask: 1, Index: 3},
+			&Unknown{HeaderType: 0x305, Body: []byte{1, 2, 3, 4, 5}},
+			&VPContext{GPA: 0xFFFFFFFFF000, CompatibilityMask: 1, VPIndex: 1, Data: bytes.Repeat([]byte{0xCD}, PageSize)},
+		},
+	}
+
+	data, err := file.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(Magic), binary.LittleEndian.Uint32(data))
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, file, parsed)
+
+	again, err := parsed.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestParseInvalid(t *testing.T) {
+	file := &File{Headers: []Header{&PageData{GPA: 0x1000, CompatibilityMask: 1, Data: []byte{1, 2, 3}}}}
+	data, err := file.Marshal()
+	require.NoError(t, err)
+
+	corrupt := func(offset int, value byte) []byte {
+		c := bytes.Clone(data)
+		c[offset] = value
+		return c
+	}
+
+	cases := []struct {
+		desc string
+		data []byte
+		err  error
+	}{
+		{desc: "truncated", data: data[:10], err: ErrInvalidFile},
+		{desc: "bad magic", data: corrupt(0, 'X'), err: ErrInvalidFile},
+		{desc: "unknown format version", data: corrupt(4, 9), err: ErrInvalidFile},
+		{desc: "corrupt header", data: corrupt(fixedHeaderSize+headerAlignment, 0xFF), err: ErrChecksum},
+		{desc: "truncated data", data: data[:len(data)-1], err: ErrInvalidFile},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Parse(tc.data)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
+
+func TestMarshalOversizedData(t *testing.T) {
+	file := &File{Headers: []Header{&PageData{GPA: 0x1000, Data: make([]byte, PageSize+1)}}}
+	_, err := file.Marshal()
+	assert.Error(t, err)
+}
+
+func TestSNPLaunchDigest(t *testing.T) {
+	cases := []struct {
+		desc    string
+		headers []Header
+		err     error
+	}{
+		{
+			desc:    "no SEV-SNP platform",
+			headers: []Header{&SupportedPlatform{CompatibilityMask: 1, PlatformType: 0x3}},
+			err:     ErrUnsupported,
+		},
+		{
+			desc: "2MB page",
+			headers: []Header{
+				&SupportedPlatform{CompatibilityMask: 1, PlatformType: PlatformSEVSNP},
+				&PageData{GPA: 0x200000, CompatibilityMask: 1, Flags: PageFlag2MB},
+			},
+			err: ErrUnsupported,
+		},
+		{
+			desc: "undeclared parameter area",
+			headers: []Header{
+				&SupportedPlatform{CompatibilityMask: 1, PlatformType: PlatformSEVSNP},
+				&ParameterInsert{GPA: 0x1000, CompatibilityMask: 1, Index: 7},
+			},
+			err: ErrInvalidFile,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := (&File{Headers: tc.headers}).SNPLaunchDigest()
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+
+	// Directives of other platforms are not measured.
+	snp := []Header{
+		&SupportedPlatform{CompatibilityMask: 1, PlatformType: PlatformSEVSNP},
+		&SupportedPlatform{CompatibilityMask: 2, PlatformType: 0x3},
+		&PageData{GPA: 0x1000, CompatibilityMask: 1, Data: []byte{1}},
+	}
+	want, err := (&File{Headers: snp}).SNPLaunchDigest()
+	require.NoError(t, err)
+
+	got, err := (&File{Headers: append(snp, &PageData{GPA: 0x2000, CompatibilityMask: 2, Data: []byte{2}})}).SNPLaunchDigest()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestBuildSNP(t *testing.T) {
+	dir := t.TempDir()
+	fw := writeOVMF(t, dir, true)
+	kernel := filepath.Join(dir, "bzImage")
+	initrd := filepath.Join(dir, "rootfs.cpio.gz")
+	require.NoError(t, os.WriteFile(kernel, []byte("kernel"), 0o644))
+	require.NoError(t, os.WriteFile(initrd, []byte("initrd"), 0o644))
+	vcpuSig := uint64(cpuid.CpuSigs["EPYC-v4"])
+
+	for _, vcpus := range []int{1, 4} {
+		file, err := BuildSNP(SNPConfig{
+			OVMF:          fw,
+			Kernel:        kernel,
+			Initrd:        initrd,
+			Append:        "quiet console=null",
+			VCPUs:         vcpus,
+			VCPUSig:       vcpuSig,
+			GuestFeatures: 0x1,
+		})
+		require.NoError(t, err)
+
+		data, err := file.Marshal()
+		require.NoError(t, err)
+		parsed, err := Parse(data)
+		require.NoError(t, err)
+
+		measurement, err := parsed.SNPLaunchDigest()
+		require.NoError(t, err)
+
+		expected, err := guest.CalcLaunchDigest(guest.SEV_SNP, vcpus, vcpuSig, fw, kernel, initrd, "quiet console=null", 0x1, "", vmmtypes.QEMU, false, "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, hex.EncodeToString(expected), hex.EncodeToString(measurement), "%d vCPUs", vcpus)
+	}
+
+	cases := []struct {
+		desc string
+		cfg  SNPConfig
+	}{
+		{desc: "no vCPUs", cfg: SNPConfig{OVMF: fw}},
+		{desc: "missing OVMF", cfg: SNPConfig{OVMF: filepath.Join(dir, "missing.fd"), VCPUs: 1}},
+		{desc: "missing kernel", cfg: SNPConfig{OVMF: fw, Kernel: filepath.Join(dir, "missing"), VCPUs: 1}},
+		{desc: "OVMF without kernel hashes", cfg: SNPConfig{OVMF: writeOVMF(t, t.TempDir(), false), Kernel: kernel, VCPUs: 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := BuildSNP(tc.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// writeOVMF writes a minimal AmdSev OVMF image: a footer table with the SEV-ES
+// reset block, the kernel hashes table and the SEV metadata.
+func writeOVMF(t *testing.T, dir string, kernelHashes bool) string {
+	t.Helper()
+
+	const size = 16 * PageSize
+	data := make([]byte, size)
+	for i := range data[:size/2] {
+		data[i] = byte(i)
+	}
+
+	type section struct{ gpa, size, typ uint32 }
+	sections := []section{
+		{gpa: 0x800000, size: 3 * PageSize, typ: 1},
+		{gpa: 0x803000, size: PageSize, typ: 2},
+		{gpa: 0x804000, size: PageSize, typ: 3},
+	}
+	if kernelHashes {
+		sections = append(sections, section{gpa: 0x805000, size: PageSize, typ: 0x10})
+	}
+
+	metadata := []byte("ASEV")
+	metadata = binary.LittleEndian.AppendUint32(metadata, uint32(16+12*len(sections)))
+	metadata = binary.LittleEndian.AppendUint32(metadata, 1)
+	metadata = binary.LittleEndian.AppendUint32(metadata, uint32(len(sections)))
+	for _, s := range sections {
+		metadata = binary.LittleEndian.AppendUint32(metadata, s.gpa)
+		metadata = binary.LittleEndian.AppendUint32(metadata, s.size)
+		metadata = binary.LittleEndian.AppendUint32(metadata, s.typ)
+	}
+	metadataOffset := size - 4*PageSize
+	copy(data[metadataOffset:], metadata)
+
+	entry := func(guid string, body []byte) []byte {
+		e := binary.LittleEndian.AppendUint16(bytes.Clone(body), uint16(len(body)+18))
+		return append(e, guidLE(t, guid)...)
+	}
+	var table []byte
+	table = append(table, entry("00f771de-1a7e-4fcb-890e-68c77e2fb44e", binary.LittleEndian.AppendUint32(nil, 0xFFFFF000))...)
+	table = append(table, entry("7255371f-3a3b-4b04-927b-1da6efa8d454", binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, 0x805C00), 0x400))...)
+	table = append(table, entry("dc886566-984a-4798-a75e-5585a7bf67cc", binary.LittleEndian.AppendUint32(nil, uint32(size-metadataOffset)))...)
+	footer := binary.LittleEndian.AppendUint16(nil, uint16(len(table)+18))
+	footer = append(footer, guidLE(t, "96b582de-1fb2-45f7-baea-a366c55a082d")...)
+
+	footerOffset := size - 32 - len(footer)
+	copy(data[footerOffset-len(table):], table)
+	copy(data[footerOffset:], footer)
+
+	path := filepath.Join(dir, "OVMF.fd")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	return path
+}
+
+// guidLE encodes a GUID with its first three fields in little-endian, as EDK2 does.
+func guidLE(t *testing.T, guid string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(guid, "-", ""))
+	require.NoError(t, err)
+
+	return []byte{b[3], b[2], b[1], b[0], b[5], b[4], b[7], b[6], b[8], b[9], b[10], b[11], b[12], b[13], b[14], b[15]}
+}
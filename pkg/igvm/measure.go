@@ -0,0 +1,124 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package igvm
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/absmach/supermq/pkg/errors"
+)
+
+// SEV-SNP page types of the PAGE_INFO structure the launch digest is extended with.
+const (
+	snpPageNormal     = 0x1
+	snpPageVMSA       = 0x2
+	snpPageZero       = 0x3
+	snpPageUnmeasured = 0x4
+	snpPageSecrets    = 0x5
+	snpPageCPUID      = 0x6
+
+	snpPageInfoSize = 0x70
+)
+
+// launchDigest replays the SNP_LAUNCH_UPDATE commands of the loader to
+// compute the launch digest the PSP reports.
+type launchDigest struct {
+	ld []byte
+}
+
+func newLaunchDigest() *launchDigest {
+	return &launchDigest{ld: make([]byte, sha512.Size384)}
+}
+
+func (d *launchDigest) update(pageType byte, gpa uint64, contents []byte) {
+	info := make([]byte, 0, snpPageInfoSize)
+	info = append(info, d.ld...)
+	info = append(info, contents...)
+	info = binary.LittleEndian.AppendUint16(info, snpPageInfoSize)
+	// The page is not an IMI page and grants no permissions to the lower VMPLs.
+	info = append(info, pageType, 0, 0, 0, 0, 0)
+	info = binary.LittleEndian.AppendUint64(info, gpa)
+
+	sum := sha512.Sum384(info)
+	d.ld = sum[:]
+}
+
+func (d *launchDigest) page(pageType byte, gpa uint64, data []byte) {
+	page := make([]byte, PageSize)
+	copy(page, data)
+	sum := sha512.Sum384(page)
+	d.update(pageType, gpa, sum[:])
+}
+
+func (d *launchDigest) empty(pageType byte, gpa uint64) {
+	d.update(pageType, gpa, make([]byte, sha512.Size384))
+}
+
+// SNPLaunchDigest computes the SEV-SNP launch measurement of a guest loaded
+// from the file, replaying the directives of the SEV-SNP platform in order.
+func (f *File) SNPLaunchDigest() ([]byte, error) {
+	mask := uint32(0)
+	areas := make(map[uint32]uint64)
+	for _, h := range f.Headers {
+		switch h := h.(type) {
+		case *SupportedPlatform:
+			if h.PlatformType == PlatformSEVSNP {
+				mask = h.CompatibilityMask
+			}
+		case *ParameterArea:
+			areas[h.Index] = h.NumberOfBytes
+		}
+	}
+	if mask == 0 {
+		return nil, errors.Wrap(ErrUnsupported, fmt.Errorf("the file does not support the SEV-SNP platform"))
+	}
+
+	digest := newLaunchDigest()
+	for _, h := range f.Headers {
+		switch h := h.(type) {
+		case *PageData:
+			if h.CompatibilityMask&mask == 0 {
+				continue
+			}
+			if h.Flags&PageFlag2MB != 0 {
+				return nil, errors.Wrap(ErrUnsupported, fmt.Errorf("2MB page at %#x", h.GPA))
+			}
+
+			switch {
+			case h.Flags&PageFlagUnmeasured != 0:
+				digest.empty(snpPageUnmeasured, h.GPA)
+			case h.DataType == PageDataSecrets:
+				digest.empty(snpPageSecrets, h.GPA)
+			case h.DataType == PageDataCPUID:
+				digest.empty(snpPageCPUID, h.GPA)
+			case h.DataType != PageDataNormal:
+				return nil, errors.Wrap(ErrUnsupported, fmt.Errorf("page data type %d at %#x", h.DataType, h.GPA))
+			case len(h.Data) == 0:
+				digest.empty(snpPageZero, h.GPA)
+			default:
+				digest.page(snpPageNormal, h.GPA, h.Data)
+			}
+		case *ParameterInsert:
+			if h.CompatibilityMask&mask == 0 {
+				continue
+			}
+			size, ok := areas[h.Index]
+			if !ok {
+				return nil, errors.Wrap(ErrInvalidFile, fmt.Errorf("parameter area %d is not declared", h.Index))
+			}
+			// Parameters are filled in by the loader, so their pages are not measured.
+			for offset := uint64(0); offset < size; offset += PageSize {
+				digest.empty(snpPageUnmeasured, h.GPA+offset)
+			}
+		case *VPContext:
+			if h.CompatibilityMask&mask == 0 {
+				continue
+			}
+			digest.page(snpPageVMSA, h.GPA, h.Data)
+		}
+	}
+
+	return digest.ld, nil
+}
@@ -77,13 +77,99 @@ func TestSendAlgorithm(t *testing.T) {
 			req, err = os.Open(req.Name())
 			assert.NoError(t, err)
 
+			spec := &agent.AlgorithmSpec{Type: agent.AlgorithmType_ALGORITHM_TYPE_PYTHON}
+
+			var sent []*agent.AlgoRequest
 			algoStream := new(mocks.AgentService_AlgoClient)
-			algoStream.On("Send", mock.Anything).Return(tc.sendError)
+			algoStream.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+				sent = append(sent, args.Get(0).(*agent.AlgoRequest))
+			}).Return(tc.sendError)
 			algoStream.On("CloseAndRecv").Return(&agent.AlgoResponse{}, tc.closeRecvError)
 			mockStream := &mockAlgoStream{stream: algoStream}
 
-			err = pb.SendAlgorithm("Test Algorithm", algo, req, mockStream.stream)
+			err = pb.SendAlgorithm("Test Algorithm", algo, req, spec, mockStream.stream)
 			assert.True(t, errors.Contains(err, tc.err), fmt.Sprintf("expected error: %v, got: %v", tc.err, err))
+			assert.Equal(t, spec, sent[0].Spec, "the spec is sent first")
+		})
+	}
+}
+
+func TestSendResumableAlgorithm(t *testing.T) {
+	testCases := []struct {
+		name      string
+		handshake int64
+		sendError error
+		ackOffset func(chunk *agent.ResumableAlgoRequest) int64
+		acks      []int64
+		err       error
+	}{
+		{
+			name:      "upload from start",
+			handshake: 0,
+			acks:      []int64{0, 14},
+		},
+		{
+			name:      "resume from acknowledged offset",
+			handshake: 5,
+			acks:      []int64{5, 14},
+		},
+		{
+			name:      "offset beyond file size",
+			handshake: 20,
+			err:       ErrResumeOffset,
+		},
+		{
+			name:      "unexpected acknowledgement",
+			ackOffset: func(*agent.ResumableAlgoRequest) int64 { return 1 },
+			acks:      []int64{0},
+			err:       ErrUnexpectedAck,
+		},
+		{
+			name:      "send failure",
+			sendError: fmt.Errorf("network error during send"),
+			err:       fmt.Errorf("network error during send"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pb := New(false)
+
+			algo, err := os.CreateTemp("", "test_algo")
+			assert.NoError(t, err)
+			defer os.Remove(algo.Name())
+
+			_, err = algo.WriteString("test algorithm")
+			assert.NoError(t, err)
+			_, err = algo.Seek(0, io.SeekStart)
+			assert.NoError(t, err)
+
+			var last *agent.ResumableAlgoRequest
+			stream := new(mocks.AgentService_ResumableAlgoClient)
+			stream.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+				last = args.Get(0).(*agent.ResumableAlgoRequest)
+			}).Return(tc.sendError)
+			stream.On("Recv").Return(func() (*agent.ResumableAlgoResponse, error) {
+				if len(last.Algorithm) == 0 && !last.IsLast {
+					return &agent.ResumableAlgoResponse{Offset: tc.handshake}, nil
+				}
+				if tc.ackOffset != nil {
+					return &agent.ResumableAlgoResponse{Offset: tc.ackOffset(last)}, nil
+				}
+				return &agent.ResumableAlgoResponse{Offset: last.Offset + int64(len(last.Algorithm))}, nil
+			})
+			stream.On("CloseSend").Return(nil)
+
+			var acks []int64
+			spec := &agent.AlgorithmSpec{Type: agent.AlgorithmType_ALGORITHM_TYPE_WASM, Entrypoint: "train"}
+			err = pb.SendResumableAlgorithm("Test Algorithm", "id", algo, nil, spec, stream, func(offset int64) {
+				acks = append(acks, offset)
+			})
+			assert.True(t, errors.Contains(err, tc.err), fmt.Sprintf("expected error: %v, got: %v", tc.err, err))
+			assert.Equal(t, tc.acks, acks)
+			if tc.err == nil {
+				assert.Equal(t, spec, last.Spec, "the spec is sent with the last chunk")
+			}
 		})
 	}
 }
@@ -3,6 +3,7 @@
 package progressbar
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -20,6 +21,11 @@ const (
 	bufferSize   = 1024 * 1024
 )
 
+var (
+	ErrResumeOffset  = errors.New("agent acknowledged more bytes than the algorithm file holds")
+	ErrUnexpectedAck = errors.New("agent acknowledged an unexpected offset")
+)
+
 var (
 	_            streamSender = (*algoClientWrapper)(nil)
 	_            streamSender = (*dataClientWrapper)(nil)
@@ -72,7 +78,9 @@ type ProgressBar struct {
 	description             string
 	maxWidth                int
 	TerminalWidthFunc       func() (int, error)
-	isDownload              bool
+	// ChunkSize is the number of file bytes sent per upload message, 1 MiB when it is 0.
+	ChunkSize  int
+	isDownload bool
 }
 
 func New(isDownload bool) *ProgressBar {
@@ -82,7 +90,9 @@ func New(isDownload bool) *ProgressBar {
 	}
 }
 
-func (p *ProgressBar) SendAlgorithm(description string, algo, req *os.File, stream agent.AgentService_AlgoClient) error {
+// SendAlgorithm uploads the requirements and the algorithm, the spec of the
+// algorithm is sent first when it is not nil.
+func (p *ProgressBar) SendAlgorithm(description string, algo, req *os.File, spec *agent.AlgorithmSpec, stream agent.AgentService_AlgoClient) error {
 	algoFileInfo, err := algo.Stat()
 	if err != nil {
 		return err
@@ -102,6 +112,12 @@ func (p *ProgressBar) SendAlgorithm(description string, algo, req *os.File, stre
 
 	wrapper := &algoClientWrapper{client: stream}
 
+	if spec != nil {
+		if err := stream.Send(&agent.AlgoRequest{Spec: spec}); err != nil {
+			return err
+		}
+	}
+
 	// Send req first
 	if req != nil {
 		if err := p.sendBuffer(req, wrapper, func(data []byte) any {
@@ -130,6 +146,109 @@ func (p *ProgressBar) SendAlgorithm(description string, algo, req *os.File, stre
 	return nil
 }
 
+// SendResumableAlgorithm uploads the algorithm from the offset the agent holds
+// for uploadID, waiting for each chunk to be acknowledged. The requirements
+// and spec are sent with the last chunk. onAck is called with every
+// acknowledged offset so callers can persist the upload progress.
+func (p *ProgressBar) SendResumableAlgorithm(description, uploadID string, algo, req *os.File, spec *agent.AlgorithmSpec, stream agent.AgentService_ResumableAlgoClient, onAck func(offset int64)) error {
+	algoFileInfo, err := algo.Stat()
+	if err != nil {
+		return err
+	}
+	size := algoFileInfo.Size()
+
+	var requirements []byte
+	if req != nil {
+		if _, err := req.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if requirements, err = io.ReadAll(req); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Send(&agent.ResumableAlgoRequest{UploadId: uploadID}); err != nil {
+		return err
+	}
+
+	ack, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	offset := ack.Offset
+	if offset > size {
+		return ErrResumeOffset
+	}
+
+	if _, err := algo.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	p.reset(description, int(size))
+	if offset > 0 {
+		if err := p.updateProgress(int(offset)); err != nil {
+			return err
+		}
+	}
+	onAck(offset)
+
+	buf := make([]byte, p.chunkSize())
+
+	for {
+		n, err := io.ReadFull(algo, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		chunk := &agent.ResumableAlgoRequest{
+			UploadId:  uploadID,
+			Offset:    offset,
+			Algorithm: buf[:n],
+			IsLast:    offset+int64(n) >= size,
+		}
+		if chunk.IsLast {
+			chunk.Requirements = requirements
+			chunk.Spec = spec
+		}
+
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+
+		ack, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if ack.Offset != offset+int64(n) {
+			return ErrUnexpectedAck
+		}
+		offset = ack.Offset
+
+		if n > 0 {
+			if err := p.updateProgress(n); err != nil {
+				return err
+			}
+		}
+		onAck(offset)
+
+		if err := p.renderProgressBar(); err != nil {
+			return err
+		}
+
+		if chunk.IsLast {
+			break
+		}
+	}
+
+	if _, err := io.WriteString(os.Stdout, "\n"); err != nil {
+		return err
+	}
+
+	return stream.CloseSend()
+}
+
 func (p *ProgressBar) SendData(description, filename string, file *os.File, stream agent.AgentService_DataClient) error {
 	return p.sendData(description, file, &dataClientWrapper{client: stream}, func(data []byte) any {
 		return &agent.DataRequest{Dataset: data, Filename: filename}
@@ -144,7 +263,7 @@ func (p *ProgressBar) sendData(description string, file *os.File, stream streamS
 
 	p.reset(description, int(dataInfo.Size()))
 
-	buf := make([]byte, bufferSize)
+	buf := make([]byte, p.chunkSize())
 
 	for {
 		n, err := file.Read(buf)
@@ -177,7 +296,7 @@ func (p *ProgressBar) sendData(description string, file *os.File, stream streamS
 }
 
 func (p *ProgressBar) sendBuffer(file *os.File, stream streamSender, createRequest func([]byte) any) error {
-	buf := make([]byte, bufferSize)
+	buf := make([]byte, p.chunkSize())
 
 	for {
 		n, err := file.Read(buf)
@@ -205,6 +324,14 @@ func (p *ProgressBar) sendBuffer(file *os.File, stream streamSender, createReque
 	return nil
 }
 
+func (p *ProgressBar) chunkSize() int {
+	if p.ChunkSize > 0 {
+		return p.ChunkSize
+	}
+
+	return bufferSize
+}
+
 func (p *ProgressBar) reset(description string, totalBytes int) {
 	p.currentUploadedBytes = 0
 	p.currentUploadPercentage = 0
@@ -3,6 +3,7 @@
 package sdk
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
@@ -11,21 +12,46 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/absmach/supermq/pkg/errors"
 	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/agent/api/grpc"
 	"github.com/ultravioletrs/cocos/agent/auth"
 	"github.com/ultravioletrs/cocos/pkg/progressbar"
+	"github.com/ultravioletrs/cocos/pkg/server"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type SDK interface {
-	Algo(ctx context.Context, algorithm, requirements *os.File, privKey any) error
+	// Algo uploads the algorithm, which the agent runs as the spec describes.
+	Algo(ctx context.Context, algorithm, requirements *os.File, spec *agent.AlgorithmSpec, privKey any) error
+	ResumableAlgo(ctx context.Context, algorithm, requirements *os.File, spec *agent.AlgorithmSpec, privKey any, uploadID string, onAck func(offset int64)) error
 	Data(ctx context.Context, dataset *os.File, filename string, privKey any) error
 	Result(ctx context.Context, privKey any, resultFile *os.File) error
+	// Stop cancels the running computation as its algorithm provider.
+	Stop(ctx context.Context, privKey any) error
+	// Shutdown drains the agent as the algorithm provider of its computation:
+	// the running algorithm gets the shutdown grace period to end before the
+	// agent exits.
+	Shutdown(ctx context.Context, privKey any) error
+	// Restore resumes the computation from its last checkpoint, as its
+	// algorithm provider, refusing checkpoints older than the sequence.
+	Restore(ctx context.Context, sequence uint64, privKey any) error
+	// ApproveAttestation releases the algorithm and datasets of a computation
+	// with the approval signature of its owner.
+	ApproveAttestation(ctx context.Context, report, signature []byte) error
+	// Secrets provisions the runtime secrets of the computation with the
+	// signature of its owner.
+	Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error
 	Attestation(ctx context.Context, reportData [size64]byte, nonce [size32]byte, attType int, attestationFile *os.File) error
 	IMAMeasurements(ctx context.Context, resultFile *os.File) ([]byte, error)
 	AttestationToken(ctx context.Context, nonce [size32]byte, attType int, attestationFile *os.File) error
@@ -39,20 +65,54 @@ const (
 	resultProgressDescription          = "Downloading result"
 	attestationProgressDescription     = "Downloading attestation"
 	imaMeasurementsProgressDescription = "Downloading Linux IMA measurements"
+	// defaultChunkSize is the number of file bytes sent per upload message.
+	defaultChunkSize = 1024 * 1024
+	// messageOverhead is reserved in upload messages for the protobuf encoding.
+	messageOverhead = 1024
+	// cancelUploadTimeout bounds the request canceling an upload on the agent.
+	cancelUploadTimeout = 10 * time.Second
+)
+
+var (
+	// ErrMessageTooLarge indicates that an upload message cannot fit the message size limits.
+	ErrMessageTooLarge = errors.New("upload message does not fit the message size limit of the agent or the client")
+	// ErrUnsupportedAlgorithm indicates an algorithm type the agent does not advertise a runtime for.
+	ErrUnsupportedAlgorithm = errors.New("algorithm type is not supported by the agent")
 )
 
 type agentSDK struct {
-	client agent.AgentServiceClient
+	client         agent.AgentServiceClient
+	maxSendMsgSize int
+}
+
+// Option configures optional behavior of the agent SDK.
+type Option func(*agentSDK)
+
+// WithMaxSendMsgSize limits upload messages to the send limit of the client connection.
+func WithMaxSendMsgSize(size int) Option {
+	return func(sdk *agentSDK) {
+		sdk.maxSendMsgSize = size
+	}
 }
 
-func NewAgentSDK(agentClient agent.AgentServiceClient) SDK {
-	return &agentSDK{
+func NewAgentSDK(agentClient agent.AgentServiceClient, opts ...Option) SDK {
+	sdk := &agentSDK{
 		client: agentClient,
 	}
+	for _, opt := range opts {
+		opt(sdk)
+	}
+
+	return sdk
 }
 
-func (sdk *agentSDK) Algo(ctx context.Context, algorithm, requirements *os.File, privKey any) error {
-	md, err := generateMetadata(string(auth.AlgorithmProviderRole), privKey)
+func (sdk *agentSDK) Algo(ctx context.Context, algorithm, requirements *os.File, spec *agent.AlgorithmSpec, privKey any) error {
+	digest, err := RequestDigest([]*os.File{algorithm, requirements})
+	if err != nil {
+		return err
+	}
+
+	md, err := generateMetadata(string(auth.AlgorithmProviderRole), digest, privKey)
 	if err != nil {
 		return err
 	}
@@ -61,17 +121,119 @@ func (sdk *agentSDK) Algo(ctx context.Context, algorithm, requirements *os.File,
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v[0])
 	}
 
+	caps, err := sdk.capabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAlgorithm(caps, spec); err != nil {
+		return err
+	}
+
+	chunkSize, err := sdk.chunkSize(caps, 0)
+	if err != nil {
+		return err
+	}
+
 	stream, err := sdk.client.Algo(ctx)
 	if err != nil {
 		return err
 	}
 
 	pb := progressbar.New(false)
-	return pb.SendAlgorithm(algoProgressBarDescription, algorithm, requirements, stream)
+	pb.ChunkSize = chunkSize
+	return pb.SendAlgorithm(algoProgressBarDescription, algorithm, requirements, spec, stream)
+}
+
+func (sdk *agentSDK) ResumableAlgo(ctx context.Context, algorithm, requirements *os.File, spec *agent.AlgorithmSpec, privKey any, uploadID string, onAck func(offset int64)) error {
+	// A resumed upload signs the digest of the whole algorithm, which the agent
+	// checks once the upload is complete.
+	digest, err := RequestDigest([]*os.File{algorithm, requirements})
+	if err != nil {
+		return err
+	}
+
+	md, err := generateMetadata(string(auth.AlgorithmProviderRole), digest, privKey)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range md {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v[0])
+	}
+
+	// The last message also carries the requirements and spec, and every message the upload ID.
+	reserved := len(uploadID) + proto.Size(spec)
+	if requirements != nil {
+		info, err := requirements.Stat()
+		if err != nil {
+			return err
+		}
+		reserved += int(info.Size())
+	}
+
+	caps, err := sdk.capabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAlgorithm(caps, spec); err != nil {
+		return err
+	}
+
+	chunkSize, err := sdk.chunkSize(caps, reserved)
+	if err != nil {
+		return err
+	}
+
+	stream, err := sdk.client.ResumableAlgo(ctx)
+	if err != nil {
+		return err
+	}
+
+	pb := progressbar.New(false)
+	pb.ChunkSize = chunkSize
+	err = pb.SendResumableAlgorithm(algoProgressBarDescription, uploadID, algorithm, requirements, spec, stream, onAck)
+
+	// An interrupted upload is kept by the agent so it can be resumed, unless
+	// the caller canceled it.
+	if err != nil && ctx.Err() == context.Canceled {
+		if cerr := sdk.cancelUpload(ctx, uploadID); cerr != nil {
+			return fmt.Errorf("%w, failed to cancel the upload on the agent: %w", err, cerr)
+		}
+	}
+
+	return err
+}
+
+// cancelUpload makes the agent abort the upload and drop the bytes it received.
+func (sdk *agentSDK) cancelUpload(ctx context.Context, uploadID string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelUploadTimeout)
+	defer cancel()
+
+	stream, err := sdk.client.ResumableAlgo(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Send(&agent.ResumableAlgoRequest{UploadId: uploadID, Cancel: true}); err != nil {
+		return err
+	}
+
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+
+	return stream.CloseSend()
 }
 
 func (sdk *agentSDK) Data(ctx context.Context, dataset *os.File, filename string, privKey any) error {
-	md, err := generateMetadata(string(auth.DataProviderRole), privKey)
+	digest, err := RequestDigest([]*os.File{dataset}, filename)
+	if err != nil {
+		return err
+	}
+
+	md, err := generateMetadata(string(auth.DataProviderRole), digest, privKey)
 	if err != nil {
 		return err
 	}
@@ -80,19 +242,30 @@ func (sdk *agentSDK) Data(ctx context.Context, dataset *os.File, filename string
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v[0])
 	}
 
+	caps, err := sdk.capabilities(ctx)
+	if err != nil {
+		return err
+	}
+
+	chunkSize, err := sdk.chunkSize(caps, len(filename))
+	if err != nil {
+		return err
+	}
+
 	stream, err := sdk.client.Data(ctx)
 	if err != nil {
 		return err
 	}
 
 	pb := progressbar.New(false)
+	pb.ChunkSize = chunkSize
 	return pb.SendData(dataProgressBarDescription, filename, dataset, stream)
 }
 
 func (sdk *agentSDK) Result(ctx context.Context, privKey any, resultFile *os.File) error {
 	request := &agent.ResultRequest{}
 
-	md, err := generateMetadata(string(auth.ConsumerRole), privKey)
+	md, err := generateMetadata(string(auth.ConsumerRole), auth.BodyDigest(), privKey)
 	if err != nil {
 		return err
 	}
@@ -124,6 +297,59 @@ func (sdk *agentSDK) Result(ctx context.Context, privKey any, resultFile *os.Fil
 	return pb.ReceiveResult(resultProgressDescription, fileSize, stream, resultFile)
 }
 
+func (sdk *agentSDK) Stop(ctx context.Context, privKey any) error {
+	md, err := generateMetadata(string(auth.AlgorithmProviderRole), auth.BodyDigest(), privKey)
+	if err != nil {
+		return err
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	_, err = sdk.client.Stop(ctx, &agent.StopRequest{})
+
+	return err
+}
+
+func (sdk *agentSDK) Shutdown(ctx context.Context, privKey any) error {
+	md, err := generateMetadata(string(auth.AlgorithmProviderRole), auth.BodyDigest([]byte(strconv.FormatBool(true))), privKey)
+	if err != nil {
+		return err
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	_, err = sdk.client.Stop(ctx, &agent.StopRequest{Shutdown: true})
+
+	return err
+}
+
+func (sdk *agentSDK) Restore(ctx context.Context, sequence uint64, privKey any) error {
+	md, err := generateMetadata(string(auth.AlgorithmProviderRole), auth.BodyDigest([]byte(strconv.FormatUint(sequence, 10))), privKey)
+	if err != nil {
+		return err
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	_, err = sdk.client.Restore(ctx, &agent.RestoreRequest{Sequence: sequence})
+
+	return err
+}
+
+func (sdk *agentSDK) ApproveAttestation(ctx context.Context, report, signature []byte) error {
+	_, err := sdk.client.ApproveAttestation(ctx, &agent.ApproveAttestationRequest{Signature: signature, Report: report})
+
+	return err
+}
+
+func (sdk *agentSDK) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error {
+	req := &agent.SecretsRequest{Signature: signature}
+	for _, s := range secrets {
+		req.Secrets = append(req.Secrets, &agent.RuntimeSecret{Name: s.Name, Value: s.Value, Env: s.Env})
+	}
+
+	_, err := sdk.client.Secrets(ctx, req)
+
+	return err
+}
+
 func (sdk *agentSDK) Attestation(ctx context.Context, reportData [size64]byte, nonce [size32]byte, attType int, attestationFile *os.File) error {
 	request := &agent.AttestationRequest{
 		TeeNonce:  reportData[:],
@@ -205,15 +431,71 @@ func (sdk *agentSDK) IMAMeasurements(ctx context.Context, resultFile *os.File) (
 	return pb.ReceiveIMAMeasurements(imaMeasurementsProgressDescription, fileSize, stream, resultFile)
 }
 
-func signData(userID string, privKey crypto.Signer) ([]byte, error) {
+// capabilities returns the capabilities the agent advertises, which are empty
+// for agents without the capabilities RPC.
+func (sdk *agentSDK) capabilities(ctx context.Context) (*agent.CapabilitiesResponse, error) {
+	caps, err := sdk.client.Capabilities(ctx, &agent.CapabilitiesRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return &agent.CapabilitiesResponse{}, nil
+	}
+
+	return caps, err
+}
+
+// chunkSize negotiates the number of file bytes sent per upload message, so that
+// messages carrying reserved other bytes fit both the receive limit the agent
+// advertises in its capabilities and the send limit of the client. Agents
+// without the capabilities RPC are assumed to use the gRPC default limit.
+func (sdk *agentSDK) chunkSize(caps *agent.CapabilitiesResponse, reserved int) (int, error) {
+	limit := server.DefaultMaxRecvMsgSize
+	if size := caps.GetMaxRecvMsgSize(); size > 0 {
+		limit = int(size)
+	}
+
+	if sdk.maxSendMsgSize > 0 {
+		limit = min(limit, sdk.maxSendMsgSize)
+	}
+
+	size := min(defaultChunkSize, limit-messageOverhead-reserved)
+	if size <= 0 {
+		return 0, errors.Wrap(ErrMessageTooLarge, fmt.Errorf("limit of %d bytes, %d bytes reserved", limit, reserved))
+	}
+
+	return size, nil
+}
+
+// checkAlgorithm checks that the agent advertises a runtime for the algorithm
+// type of the spec before it is uploaded. Agents advertising no runtimes are
+// left to reject the algorithm themselves.
+func checkAlgorithm(caps *agent.CapabilitiesResponse, spec *agent.AlgorithmSpec) error {
+	runtimes := caps.GetAlgorithmRuntimes()
+	if len(runtimes) == 0 {
+		return nil
+	}
+
+	algoType := spec.GetType()
+	if algoType == agent.AlgorithmType_ALGORITHM_TYPE_UNSPECIFIED {
+		algoType = agent.AlgorithmType_ALGORITHM_TYPE_BINARY
+	}
+
+	for _, runtime := range runtimes {
+		if runtime.GetType() == algoType {
+			return nil
+		}
+	}
+
+	return errors.Wrap(ErrUnsupportedAlgorithm, fmt.Errorf("no %s runtime", algoType))
+}
+
+func signData(payload []byte, privKey crypto.Signer) ([]byte, error) {
 	var signature []byte
 	var err error
 
 	switch k := privKey.(type) {
 	case ed25519.PrivateKey:
-		signature, err = k.Sign(rand.Reader, []byte(userID), crypto.Hash(0))
+		signature, err = k.Sign(rand.Reader, payload, crypto.Hash(0))
 	case *rsa.PrivateKey, *ecdsa.PrivateKey:
-		hash := sha256.Sum256([]byte(userID))
+		hash := sha256.Sum256(payload)
 		signature, err = privKey.Sign(rand.Reader, hash[:], crypto.SHA256)
 	default:
 		return nil, errors.New("unsupported key type")
@@ -226,8 +508,12 @@ func signData(userID string, privKey crypto.Signer) ([]byte, error) {
 	return signature, nil
 }
 
-func generateMetadata(userID string, privateKey crypto.PrivateKey) (metadata.MD, error) {
-	signature, err := signData(userID, privateKey.(crypto.Signer))
+// generateMetadata signs the role, the current time and the digest of the
+// request body, so the agent can check who sent the request and what it held.
+func generateMetadata(userID, digest string, privateKey crypto.PrivateKey) (metadata.MD, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	signature, err := signData(auth.SigningPayload(auth.UserRole(userID), timestamp, digest), privateKey.(crypto.Signer))
 	if err != nil {
 		return nil, err
 	}
@@ -235,5 +521,46 @@ func generateMetadata(userID string, privateKey crypto.PrivateKey) (metadata.MD,
 	kv := make(map[string]string)
 	kv[auth.UserMetadataKey] = userID
 	kv[auth.SignatureMetadataKey] = base64.StdEncoding.EncodeToString(signature)
+	kv[auth.TimestampMetadataKey] = timestamp
+	kv[auth.BodyDigestMetadataKey] = digest
 	return metadata.New(kv), nil
 }
+
+// SignedMetadata returns the authentication metadata of a request sent as the
+// role whose body has the digest, which HTTP clients send as headers.
+func SignedMetadata(role auth.UserRole, digest string, privKey any) (metadata.MD, error) {
+	return generateMetadata(string(role), digest, privKey)
+}
+
+// RequestDigest returns the body digest of a request made of the files, a nil
+// file being an empty part, followed by the fields. The files are rewound so
+// they can be streamed afterwards.
+func RequestDigest(files []*os.File, fields ...string) (string, error) {
+	var parts []io.Reader
+	for _, f := range files {
+		if f == nil {
+			parts = append(parts, bytes.NewReader(nil))
+			continue
+		}
+		parts = append(parts, f)
+	}
+	for _, field := range fields {
+		parts = append(parts, strings.NewReader(field))
+	}
+
+	digest, err := auth.ReaderDigest(parts...)
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+
+	return digest, nil
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"net"
 	"os"
 	"testing"
 
@@ -19,13 +20,17 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/ultravioletrs/cocos/agent"
+	agentgrpc "github.com/ultravioletrs/cocos/agent/api/grpc"
 	"github.com/ultravioletrs/cocos/pkg/attestation/quoteprovider"
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 	"github.com/ultravioletrs/cocos/pkg/sdk"
+	"github.com/ultravioletrs/cocos/pkg/server"
 	"golang.org/x/crypto/sha3"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 var (
@@ -131,7 +136,7 @@ func TestAlgo(t *testing.T) {
 			algo, err = os.Open(algo.Name())
 			require.NoError(t, err)
 
-			err = sdk.Algo(context.Background(), algo, nil, tc.userKey)
+			err = sdk.Algo(context.Background(), algo, nil, nil, tc.userKey)
 
 			st, _ := status.FromError(err)
 
@@ -146,6 +151,168 @@ func TestAlgo(t *testing.T) {
 	}
 }
 
+func TestResumableAlgo(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := agent.NewAgentServiceClient(conn)
+	sdk := sdk.NewAgentSDK(client)
+
+	content, err := os.ReadFile(algoPath)
+	require.NoError(t, err)
+
+	algorithmProviderKey, _ := generateKeys(t, "ed25519")
+
+	cases := []struct {
+		name      string
+		uploadID  string
+		interrupt int
+		svcErr    error
+		firstAck  int64
+		err       error
+	}{
+		{
+			name:     "upload algorithm successfully",
+			uploadID: "upload-1",
+		},
+		{
+			name:      "resume interrupted upload",
+			uploadID:  "upload-2",
+			interrupt: 10,
+			firstAck:  10,
+		},
+		{
+			name:     "upload rejected by service",
+			uploadID: "upload-3",
+			svcErr:   errors.New("hash mismatch"),
+			err:      errors.New("hash mismatch"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received []byte
+			svcCall := svc.On("Algo", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				received = args.Get(1).(agent.Algorithm).Algorithm
+			}).Return(tc.svcErr)
+
+			if tc.interrupt > 0 {
+				ctx, cancel := context.WithCancel(context.Background())
+				stream, err := client.ResumableAlgo(ctx)
+				require.NoError(t, err)
+				require.NoError(t, stream.Send(&agent.ResumableAlgoRequest{UploadId: tc.uploadID}))
+				_, err = stream.Recv()
+				require.NoError(t, err)
+				require.NoError(t, stream.Send(&agent.ResumableAlgoRequest{UploadId: tc.uploadID, Algorithm: content[:tc.interrupt]}))
+				ack, err := stream.Recv()
+				require.NoError(t, err)
+				require.Equal(t, int64(tc.interrupt), ack.Offset)
+				cancel()
+			}
+
+			algo, err := os.Open(algoPath)
+			require.NoError(t, err)
+			defer algo.Close()
+
+			var acks []int64
+			err = sdk.ResumableAlgo(context.Background(), algo, nil, nil, algorithmProviderKey, tc.uploadID, func(offset int64) {
+				acks = append(acks, offset)
+			})
+
+			if tc.err != nil {
+				st, _ := status.FromError(err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, content, received)
+				require.NotEmpty(t, acks)
+				assert.Equal(t, tc.firstAck, acks[0])
+				assert.Equal(t, int64(len(content)), acks[len(acks)-1])
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestResumableAlgoCancel(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const uploadID = "upload-canceled"
+
+	client := agent.NewAgentServiceClient(conn)
+	// Small messages split the algorithm into several chunks.
+	sdk := sdk.NewAgentSDK(client, sdk.WithMaxSendMsgSize(1024+len(uploadID)+64))
+
+	algorithmProviderKey, _ := generateKeys(t, "ed25519")
+
+	algo, err := os.Open(algoPath)
+	require.NoError(t, err)
+	defer algo.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = sdk.ResumableAlgo(ctx, algo, nil, nil, algorithmProviderKey, uploadID, func(offset int64) {
+		if offset > 0 {
+			cancel()
+		}
+	})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	// The agent dropped the partial upload, a new upload starts from the beginning.
+	stream, err := client.ResumableAlgo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&agent.ResumableAlgoRequest{UploadId: uploadID}))
+	ack, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), ack.Offset)
+	require.NoError(t, stream.CloseSend())
+}
+
+// binaryOnlyServer advertises only the binary algorithm runtime.
+type binaryOnlyServer struct {
+	agent.AgentServiceServer
+}
+
+func (s binaryOnlyServer) Capabilities(context.Context, *agent.CapabilitiesRequest) (*agent.CapabilitiesResponse, error) {
+	return &agent.CapabilitiesResponse{
+		AlgorithmRuntimes: []*agent.AlgorithmRuntime{{Type: agent.AlgorithmType_ALGORITHM_TYPE_BINARY}},
+	}, nil
+}
+
+func TestAlgoUnsupportedType(t *testing.T) {
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	agent.RegisterAgentServiceServer(s, binaryOnlyServer{agentgrpc.NewServer(svc)})
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	agentSDK := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+	algorithmProviderKey, _ := generateKeys(t, "ed25519")
+
+	algo, err := os.Open(algoPath)
+	require.NoError(t, err)
+	defer algo.Close()
+
+	spec := &agent.AlgorithmSpec{Type: agent.AlgorithmType_ALGORITHM_TYPE_PYTHON}
+
+	err = agentSDK.Algo(context.Background(), algo, nil, spec, algorithmProviderKey)
+	assert.True(t, errors.Contains(err, sdk.ErrUnsupportedAlgorithm), "expected %v, got %v", sdk.ErrUnsupportedAlgorithm, err)
+
+	err = agentSDK.ResumableAlgo(context.Background(), algo, nil, spec, algorithmProviderKey, "upload-unsupported", func(int64) {})
+	assert.True(t, errors.Contains(err, sdk.ErrUnsupportedAlgorithm), "expected %v, got %v", sdk.ErrUnsupportedAlgorithm, err)
+}
+
 func TestData(t *testing.T) {
 	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
 	if err != nil {
@@ -227,6 +394,7 @@ func TestData(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			dataCall := svc.On("Data", mock.Anything, mock.Anything).Return(tc.svcErr)
+			datasetsCall := svc.On("Datasets").Return([]agent.DatasetStatus{})
 
 			data, err := os.CreateTemp("", "data")
 			require.NoError(t, err)
@@ -251,6 +419,7 @@ func TestData(t *testing.T) {
 			}
 
 			dataCall.Unset()
+			datasetsCall.Unset()
 		})
 	}
 }
@@ -361,6 +530,204 @@ func TestResult(t *testing.T) {
 	}
 }
 
+func TestStop(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sdk := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+
+	algoProviderKey, _ := generateKeys(t, "ed25519")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "Test stop successfully",
+		},
+		{
+			name: "Agent not ready",
+			err:  agent.ErrStateNotReady,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svcCall := svc.On("StopComputation", mock.Anything).Return(tc.err)
+
+			err := sdk.Stop(context.Background(), algoProviderKey)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "expected gRPC status error, got %v", err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sdk := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+
+	algoProviderKey, _ := generateKeys(t, "ed25519")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "Test shutdown successfully",
+		},
+		{
+			name: "Agent fails to drain",
+			err:  errors.New("failed to drain"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svcCall := svc.On("Shutdown", mock.Anything).Return(tc.err)
+
+			err := sdk.Shutdown(context.Background(), algoProviderKey)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "expected gRPC status error, got %v", err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sdk := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+
+	algoProviderKey, _ := generateKeys(t, "ed25519")
+	sequence := uint64(3)
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "Test restore successfully",
+		},
+		{
+			name: "Checkpoint not found",
+			err:  agent.ErrCheckpointNotFound,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svcCall := svc.On("Restore", mock.Anything, sequence).Return(tc.err)
+
+			err := sdk.Restore(context.Background(), sequence, algoProviderKey)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "expected gRPC status error, got %v", err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestApproveAttestation(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sdk := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+
+	signature := []byte("approval signature")
+	report := []byte("attestation report")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "Test approve attestation successfully",
+		},
+		{
+			name: "Approval not signed by the computation owner",
+			err:  agent.ErrApprovalSignature,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svcCall := svc.On("ApproveAttestation", mock.Anything, report, signature).Return(tc.err)
+
+			err := sdk.ApproveAttestation(context.Background(), report, signature)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "expected gRPC status error, got %v", err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(bufDialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sdk := sdk.NewAgentSDK(agent.NewAgentServiceClient(conn))
+
+	secrets := []agent.Secret{{Name: "api_token", Value: []byte("token")}, {Name: "MODEL_KEY", Value: []byte("key"), Env: true}}
+	signature := []byte("secrets signature")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "Test provision secrets successfully",
+		},
+		{
+			name: "Secrets not signed by the computation owner",
+			err:  agent.ErrSecretsSignature,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svcCall := svc.On("Secrets", mock.Anything, secrets, signature).Return(tc.err)
+
+			err := sdk.Secrets(context.Background(), secrets, signature)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "expected gRPC status error, got %v", err)
+				assert.Equal(t, tc.err.Error(), st.Message())
+			}
+
+			svcCall.Unset()
+		})
+	}
+}
+
 func TestAttestation(t *testing.T) {
 	resultConsumerKey, _ := generateKeys(t, "rsa")
 	resultConsumer1Key, _ := generateKeys(t, "ed25519")
@@ -661,3 +1028,66 @@ func generateKeys(t *testing.T, keyType string) (priv any, pub []byte) {
 		return privKey, pubKeyBytes
 	}
 }
+
+func TestDataMessageLimits(t *testing.T) {
+	const maxRecvMsgSize = 64 * 1024
+
+	limitedLis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(grpc.MaxRecvMsgSize(maxRecvMsgSize))
+	agent.RegisterAgentServiceServer(s, agentgrpc.NewServer(svc, agentgrpc.WithMessageLimits(server.MessageLimits{MaxRecvMsgSize: maxRecvMsgSize})))
+	go func() {
+		_ = s.Serve(limitedLis)
+	}()
+	defer s.Stop()
+
+	conn, err := grpc.NewClient("passthrough://bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return limitedLis.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := agent.NewAgentServiceClient(conn)
+
+	content := make([]byte, 4*maxRecvMsgSize)
+	_, err = rand.Read(content)
+	require.NoError(t, err)
+
+	dataProviderKey, _ := generateKeys(t, "ed25519")
+
+	cases := []struct {
+		name string
+		sdk  sdk.SDK
+		err  error
+	}{
+		{
+			name: "chunks fit the agent limit",
+			sdk:  sdk.NewAgentSDK(client),
+		},
+		{
+			name: "client limit too small",
+			sdk:  sdk.NewAgentSDK(client, sdk.WithMaxSendMsgSize(512)),
+			err:  sdk.ErrMessageTooLarge,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dataCall := svc.On("Data", mock.Anything, agent.Dataset{Dataset: content, Filename: "data.bin"}).Return(nil)
+			datasetsCall := svc.On("Datasets").Return([]agent.DatasetStatus{})
+			defer dataCall.Unset()
+			defer datasetsCall.Unset()
+
+			f, err := os.CreateTemp(t.TempDir(), "data")
+			require.NoError(t, err)
+			defer f.Close()
+
+			_, err = f.Write(content)
+			require.NoError(t, err)
+			_, err = f.Seek(0, 0)
+			require.NoError(t, err)
+
+			err = tc.sdk.Data(context.Background(), f, "data.bin", dataProviderKey)
+			assert.True(t, errors.Contains(err, tc.err), "expected %v, got %v", tc.err, err)
+		})
+	}
+}
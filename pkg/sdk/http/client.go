@@ -0,0 +1,376 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/ultravioletrs/cocos/agent"
+	"github.com/ultravioletrs/cocos/agent/algorithm"
+	"github.com/ultravioletrs/cocos/agent/algorithm/python"
+	"github.com/ultravioletrs/cocos/agent/auth"
+	"github.com/ultravioletrs/cocos/pkg/sdk"
+)
+
+const (
+	algorithmField    = "algorithm"
+	requirementsField = "requirements"
+	datasetField      = "dataset"
+	filenameField     = "filename"
+
+	defaultRetries    = 3
+	defaultRetryDelay = time.Second
+)
+
+// ErrUnexpectedResponse indicates a response the agent HTTP API does not send.
+var ErrUnexpectedResponse = errors.New("unexpected response from the agent")
+
+// Error is an error returned by the agent HTTP API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("agent responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// State is the state of the agent and the delivery status of the datasets.
+type State struct {
+	State    string                `json:"state"`
+	Datasets []agent.DatasetStatus `json:"datasets,omitempty"`
+}
+
+// AlgoOptions selects how the agent runs the algorithm.
+type AlgoOptions struct {
+	// Type is the algorithm runtime, e.g. "python", a binary if empty.
+	Type string
+	// Entrypoint is the exported function of a wasm module, or the command of
+	// a docker image, run instead of the default one.
+	Entrypoint string
+	// PythonRuntime is the interpreter Python algorithms run with, python3 if empty.
+	PythonRuntime string
+	// MinRuntimeVersion is the lowest Python version the algorithm supports.
+	MinRuntimeVersion string
+	// Args are the arguments the algorithm runs with.
+	Args []string
+}
+
+// Client is a typed client of the agent HTTP API. Requests are signed with the
+// private key of the caller role, as for the SDK.
+type Client interface {
+	// Algo uploads the algorithm, with its requirements file if it is not nil.
+	Algo(ctx context.Context, algorithm, requirements *os.File, opts AlgoOptions, privKey any) error
+	// Data uploads a dataset under the filename declared in the manifest.
+	Data(ctx context.Context, dataset *os.File, filename string, decompress bool, privKey any) error
+	// State returns the state of the agent.
+	State(ctx context.Context) (State, error)
+	// WaitForState polls the state of the agent every interval until it is one of the states.
+	WaitForState(ctx context.Context, interval time.Duration, states ...string) (State, error)
+	// Result downloads the result into the file. A dropped download is resumed
+	// from the bytes already received with a range request.
+	Result(ctx context.Context, privKey any, resultFile *os.File) error
+}
+
+// Option configures the client.
+type Option func(*client)
+
+// WithRetries sets how many times in a row a result download dropped before
+// receiving any byte is retried, and the delay before each attempt.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+type client struct {
+	url        string
+	http       *http.Client
+	retries    int
+	retryDelay time.Duration
+}
+
+var _ Client = (*client)(nil)
+
+// NewClient returns a client of the agent HTTP API at url. The http client
+// carries the transport, e.g. the attested TLS one of pkg/clients/http, and
+// http.DefaultClient is used if it is nil.
+func NewClient(url string, httpClient *http.Client, opts ...Option) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	c := &client{
+		url:        strings.TrimSuffix(url, "/"),
+		http:       httpClient,
+		retries:    defaultRetries,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *client) Algo(ctx context.Context, algo, requirements *os.File, opts AlgoOptions, privKey any) error {
+	digest, err := sdk.RequestDigest([]*os.File{algo, requirements})
+	if err != nil {
+		return err
+	}
+
+	fields := [][2]string{}
+	if opts.Type != "" {
+		fields = append(fields, [2]string{algorithm.AlgoTypeKey, opts.Type})
+	}
+	if opts.Entrypoint != "" {
+		fields = append(fields, [2]string{algorithm.AlgoEntrypointKey, opts.Entrypoint})
+	}
+	if opts.PythonRuntime != "" {
+		fields = append(fields, [2]string{python.PyRuntimeKey, opts.PythonRuntime})
+	}
+	if opts.MinRuntimeVersion != "" {
+		fields = append(fields, [2]string{algorithm.AlgoMinRuntimeVersionKey, opts.MinRuntimeVersion})
+	}
+	for _, arg := range opts.Args {
+		fields = append(fields, [2]string{algorithm.AlgoArgsKey, arg})
+	}
+
+	files := []formFile{{field: algorithmField, file: algo}}
+	if requirements != nil {
+		files = append(files, formFile{field: requirementsField, file: requirements})
+	}
+
+	return c.upload(ctx, "/algo", auth.AlgorithmProviderRole, digest, privKey, files, fields)
+}
+
+func (c *client) Data(ctx context.Context, dataset *os.File, filename string, decompress bool, privKey any) error {
+	digest, err := sdk.RequestDigest([]*os.File{dataset}, filename)
+	if err != nil {
+		return err
+	}
+
+	fields := [][2]string{{filenameField, filename}}
+	if decompress {
+		fields = append(fields, [2]string{agent.DecompressKey, strconv.FormatBool(decompress)})
+	}
+
+	return c.upload(ctx, "/data", auth.DataProviderRole, digest, privKey, []formFile{{field: datasetField, file: dataset}}, fields)
+}
+
+func (c *client) State(ctx context.Context) (State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/state", nil)
+	if err != nil {
+		return State{}, err
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return State{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return State{}, decodeError(res)
+	}
+
+	var state State
+	if err := json.NewDecoder(res.Body).Decode(&state); err != nil {
+		return State{}, errors.Wrap(ErrUnexpectedResponse, err)
+	}
+
+	return state, nil
+}
+
+func (c *client) WaitForState(ctx context.Context, interval time.Duration, states ...string) (State, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := c.State(ctx)
+		if err != nil {
+			return State{}, err
+		}
+		if slices.Contains(states, state.State) {
+			return state, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return state, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *client) Result(ctx context.Context, privKey any, resultFile *os.File) error {
+	var (
+		offset int64
+		etag   string
+	)
+
+	// Only the attempts that receive no byte of the result count as retries.
+	for retries := 0; ; {
+		n, tag, err := c.downloadResult(ctx, privKey, resultFile, offset, etag)
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *Error
+		if stderrors.As(err, &apiErr) || ctx.Err() != nil {
+			return err
+		}
+		if n > offset {
+			retries = 0
+		}
+		if retries >= c.retries {
+			return err
+		}
+		retries++
+		offset, etag = n, tag
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// downloadResult downloads the result from offset into the file and returns
+// the offset it reached with the ETag of the result. The If-Range header makes
+// the agent send the whole result again if it changed, which restarts the file.
+func (c *client) downloadResult(ctx context.Context, privKey any, resultFile *os.File, offset int64, etag string) (int64, string, error) {
+	req, err := c.signedRequest(ctx, http.MethodGet, "/result", nil, auth.ConsumerRole, auth.BodyDigest(), privKey)
+	if err != nil {
+		return offset, etag, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", etag)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return offset, etag, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		offset = 0
+		if err := resultFile.Truncate(0); err != nil {
+			return offset, etag, err
+		}
+	case http.StatusPartialContent:
+	default:
+		return offset, etag, decodeError(res)
+	}
+	etag = res.Header.Get("ETag")
+
+	if _, err := resultFile.Seek(offset, io.SeekStart); err != nil {
+		return offset, etag, err
+	}
+
+	n, err := io.Copy(resultFile, res.Body)
+
+	return offset + n, etag, err
+}
+
+type formFile struct {
+	field string
+	file  *os.File
+}
+
+// upload sends the files and fields as a multipart form, streaming the files
+// instead of buffering them.
+func (c *client) upload(ctx context.Context, path string, role auth.UserRole, digest string, privKey any, files []formFile, fields [][2]string) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		writer.CloseWithError(writeForm(form, files, fields))
+	}()
+
+	req, err := c.signedRequest(ctx, http.MethodPost, path, body, role, digest, privKey)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return decodeError(res)
+	}
+
+	return nil
+}
+
+func writeForm(form *multipart.Writer, files []formFile, fields [][2]string) error {
+	for _, f := range files {
+		part, err := form.CreateFormFile(f.field, f.file.Name())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.file); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	return form.Close()
+}
+
+// signedRequest builds a request carrying the authentication headers of the role.
+func (c *client) signedRequest(ctx context.Context, method, path string, body io.Reader, role auth.UserRole, digest string, privKey any) (*http.Request, error) {
+	md, err := sdk.SignedMetadata(role, digest, privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range md {
+		req.Header.Set(key, values[0])
+	}
+
+	return req, nil
+}
+
+func decodeError(res *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(res.Body)
+	if err := json.Unmarshal(data, &body); err != nil || body.Error == "" {
+		body.Error = strings.TrimSpace(string(data))
+	}
+
+	return &Error{StatusCode: res.StatusCode, Message: body.Error}
+}
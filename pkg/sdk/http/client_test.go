@@ -0,0 +1,274 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/absmach/supermq/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ultravioletrs/cocos/agent"
+	agenthttp "github.com/ultravioletrs/cocos/agent/api/http"
+	"github.com/ultravioletrs/cocos/agent/auth"
+	"github.com/ultravioletrs/cocos/agent/mocks"
+	sdkhttp "github.com/ultravioletrs/cocos/pkg/sdk/http"
+)
+
+// newServer serves the agent HTTP API, authenticating every role with the returned key.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) (*httptest.Server, *mocks.Service, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	authSvc, err := auth.New(agent.Computation{
+		Algorithm:       agent.Algorithm{UserKey: pub},
+		Datasets:        agent.Datasets{{UserKey: pub}},
+		ResultConsumers: []agent.ResultConsumer{{UserKey: pub}},
+	})
+	require.NoError(t, err)
+
+	svc := new(mocks.Service)
+	handler := agenthttp.MakeHandler(svc, authSvc, "agent", "test")
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	return ts, svc, key
+}
+
+func tempFile(t *testing.T, name, content string) *os.File {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+func TestAlgo(t *testing.T) {
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc         string
+		requirements bool
+		otherKey     bool
+		svcErr       error
+		status       int
+	}{
+		{
+			desc: "upload algorithm",
+		},
+		{
+			desc:         "upload algorithm with requirements",
+			requirements: true,
+		},
+		{
+			desc:     "upload algorithm signed with another key",
+			otherKey: true,
+			status:   http.StatusUnauthorized,
+		},
+		{
+			desc:   "upload algorithm in the wrong state",
+			svcErr: agent.ErrStateNotReady,
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ts, svc, key := newServer(t, nil)
+			if tc.otherKey {
+				key = otherKey
+			}
+
+			algo := tempFile(t, "algo.py", "print('hello')")
+			var requirements *os.File
+			if tc.requirements {
+				requirements = tempFile(t, "requirements.txt", "numpy")
+			}
+
+			svc.On("Algo", mock.Anything, mock.MatchedBy(func(a agent.Algorithm) bool {
+				return string(a.Algorithm) == "print('hello')" && tc.requirements == (string(a.Requirements) == "numpy") &&
+					a.Spec.MinRuntimeVersion == "3.10" && len(a.Spec.Args) == 2
+			})).Return(tc.svcErr)
+
+			client := sdkhttp.NewClient(ts.URL, nil)
+			opts := sdkhttp.AlgoOptions{Type: "python", Args: []string{"--epochs", "2"}, MinRuntimeVersion: "3.10"}
+			err := client.Algo(context.Background(), algo, requirements, opts, key)
+			assertStatus(t, tc.status, err)
+		})
+	}
+}
+
+func TestData(t *testing.T) {
+	cases := []struct {
+		desc   string
+		svcErr error
+		status int
+	}{
+		{
+			desc: "upload dataset",
+		},
+		{
+			desc:   "upload dataset not in the manifest",
+			svcErr: agent.ErrUndeclaredDataset,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ts, svc, key := newServer(t, nil)
+			dataset := tempFile(t, "iris.csv", "5.1,3.5,1.4,0.2")
+
+			svc.On("Data", mock.Anything, mock.MatchedBy(func(d agent.Dataset) bool {
+				return string(d.Dataset) == "5.1,3.5,1.4,0.2" && d.Filename == "iris.csv"
+			})).Return(tc.svcErr)
+
+			client := sdkhttp.NewClient(ts.URL, nil)
+			err := client.Data(context.Background(), dataset, "iris.csv", false, key)
+			assertStatus(t, tc.status, err)
+		})
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	ts, svc, _ := newServer(t, nil)
+
+	svc.On("State").Return("ReceivingData").Twice()
+	svc.On("State").Return("ConsumingResults")
+	svc.On("Datasets").Return([]agent.DatasetStatus{{Index: 0, Filename: "iris.csv", Received: true}})
+
+	client := sdkhttp.NewClient(ts.URL, nil)
+
+	state, err := client.WaitForState(context.Background(), time.Millisecond, "ConsumingResults", "Complete")
+	require.NoError(t, err)
+	assert.Equal(t, "ConsumingResults", state.State)
+	assert.Equal(t, []agent.DatasetStatus{{Index: 0, Filename: "iris.csv", Received: true}}, state.Datasets)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.WaitForState(ctx, time.Millisecond, "Complete")
+	assert.True(t, errors.Contains(err, context.DeadlineExceeded), "expected %v, got %v", context.DeadlineExceeded, err)
+}
+
+func TestResult(t *testing.T) {
+	result := []byte("the result of the computation")
+
+	cases := []struct {
+		desc    string
+		drops   int
+		chunk   int
+		retries int
+		svcErr  error
+		status  int
+		err     bool
+	}{
+		{
+			desc: "download result",
+		},
+		{
+			desc:    "resume dropped download",
+			drops:   2,
+			chunk:   4,
+			retries: 1,
+		},
+		{
+			desc:    "download dropped more than the retries",
+			drops:   3,
+			retries: 2,
+			err:     true,
+		},
+		{
+			desc:   "result not ready",
+			svcErr: agent.ErrResultsNotReady,
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var ranges []string
+			drops := tc.drops
+			ts, svc, key := newServer(t, func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					if drops == 0 {
+						next.ServeHTTP(w, r)
+						return
+					}
+					drops--
+
+					// Send the first chunk of the body and drop the connection.
+					rec := httptest.NewRecorder()
+					next.ServeHTTP(rec, r)
+					for k, v := range rec.Header() {
+						w.Header()[k] = v
+					}
+					w.WriteHeader(rec.Code)
+					_, _ = w.Write(rec.Body.Bytes()[:tc.chunk])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				})
+			})
+			svc.On("Result", mock.Anything).Return(result, tc.svcErr)
+
+			resultFile, err := os.Create(filepath.Join(t.TempDir(), "results.zip"))
+			require.NoError(t, err)
+			defer resultFile.Close()
+
+			client := sdkhttp.NewClient(ts.URL, nil, sdkhttp.WithRetries(tc.retries, time.Millisecond))
+			err = client.Result(context.Background(), key, resultFile)
+			switch {
+			case tc.status != 0:
+				assertStatus(t, tc.status, err)
+				return
+			case tc.err:
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(resultFile.Name())
+			require.NoError(t, err)
+			assert.Equal(t, result, data)
+
+			for i, r := range ranges[1:] {
+				assert.Equal(t, "bytes="+[]string{"4-", "8-"}[i], r)
+			}
+		})
+	}
+}
+
+func assertStatus(t *testing.T, status int, err error) {
+	t.Helper()
+
+	if status == 0 {
+		assert.NoError(t, err)
+		return
+	}
+
+	var apiErr *sdkhttp.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, status, apiErr.StatusCode)
+	assert.NotEmpty(t, apiErr.Message)
+}
@@ -0,0 +1,6 @@
+// Copyright (c) Ultraviolet
+// SPDX-License-Identifier: Apache-2.0
+
+// Package http contains a typed client of the agent HTTP API, for environments
+// where proxies block the gRPC traffic the SDK relies on.
+package http
@@ -12,6 +12,7 @@ import (
 	"os"
 
 	mock "github.com/stretchr/testify/mock"
+	"github.com/ultravioletrs/cocos/agent"
 )
 
 // NewSDK creates a new instance of SDK. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -42,16 +43,16 @@ func (_m *SDK) EXPECT() *SDK_Expecter {
 }
 
 // Algo provides a mock function for the type SDK
-func (_mock *SDK) Algo(ctx context.Context, algorithm *os.File, requirements *os.File, privKey any) error {
-	ret := _mock.Called(ctx, algorithm, requirements, privKey)
+func (_mock *SDK) Algo(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any) error {
+	ret := _mock.Called(ctx, algorithm, requirements, spec, privKey)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Algo")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *os.File, *os.File, any) error); ok {
-		r0 = returnFunc(ctx, algorithm, requirements, privKey)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *os.File, *os.File, *agent.AlgorithmSpec, any) error); ok {
+		r0 = returnFunc(ctx, algorithm, requirements, spec, privKey)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -67,12 +68,13 @@ type SDK_Algo_Call struct {
 //   - ctx context.Context
 //   - algorithm *os.File
 //   - requirements *os.File
+//   - spec *agent.AlgorithmSpec
 //   - privKey any
-func (_e *SDK_Expecter) Algo(ctx interface{}, algorithm interface{}, requirements interface{}, privKey interface{}) *SDK_Algo_Call {
-	return &SDK_Algo_Call{Call: _e.mock.On("Algo", ctx, algorithm, requirements, privKey)}
+func (_e *SDK_Expecter) Algo(ctx interface{}, algorithm interface{}, requirements interface{}, spec interface{}, privKey interface{}) *SDK_Algo_Call {
+	return &SDK_Algo_Call{Call: _e.mock.On("Algo", ctx, algorithm, requirements, spec, privKey)}
 }
 
-func (_c *SDK_Algo_Call) Run(run func(ctx context.Context, algorithm *os.File, requirements *os.File, privKey any)) *SDK_Algo_Call {
+func (_c *SDK_Algo_Call) Run(run func(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any)) *SDK_Algo_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -86,15 +88,20 @@ func (_c *SDK_Algo_Call) Run(run func(ctx context.Context, algorithm *os.File, r
 		if args[2] != nil {
 			arg2 = args[2].(*os.File)
 		}
-		var arg3 any
+		var arg3 *agent.AlgorithmSpec
 		if args[3] != nil {
-			arg3 = args[3].(any)
+			arg3 = args[3].(*agent.AlgorithmSpec)
+		}
+		var arg4 any
+		if args[4] != nil {
+			arg4 = args[4].(any)
 		}
 		run(
 			arg0,
 			arg1,
 			arg2,
 			arg3,
+			arg4,
 		)
 	})
 	return _c
@@ -105,7 +112,70 @@ func (_c *SDK_Algo_Call) Return(err error) *SDK_Algo_Call {
 	return _c
 }
 
-func (_c *SDK_Algo_Call) RunAndReturn(run func(ctx context.Context, algorithm *os.File, requirements *os.File, privKey any) error) *SDK_Algo_Call {
+func (_c *SDK_Algo_Call) RunAndReturn(run func(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any) error) *SDK_Algo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ApproveAttestation provides a mock function for the type SDK
+func (_mock *SDK) ApproveAttestation(ctx context.Context, report []byte, signature []byte) error {
+	ret := _mock.Called(ctx, report, signature)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApproveAttestation")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []byte, []byte) error); ok {
+		r0 = returnFunc(ctx, report, signature)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_ApproveAttestation_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApproveAttestation'
+type SDK_ApproveAttestation_Call struct {
+	*mock.Call
+}
+
+// ApproveAttestation is a helper method to define mock.On call
+//   - ctx context.Context
+//   - report []byte
+//   - signature []byte
+func (_e *SDK_Expecter) ApproveAttestation(ctx interface{}, report interface{}, signature interface{}) *SDK_ApproveAttestation_Call {
+	return &SDK_ApproveAttestation_Call{Call: _e.mock.On("ApproveAttestation", ctx, report, signature)}
+}
+
+func (_c *SDK_ApproveAttestation_Call) Run(run func(ctx context.Context, report []byte, signature []byte)) *SDK_ApproveAttestation_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []byte
+		if args[1] != nil {
+			arg1 = args[1].([]byte)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_ApproveAttestation_Call) Return(err error) *SDK_ApproveAttestation_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_ApproveAttestation_Call) RunAndReturn(run func(ctx context.Context, report []byte, signature []byte) error) *SDK_ApproveAttestation_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -391,6 +461,69 @@ func (_c *SDK_IMAMeasurements_Call) RunAndReturn(run func(ctx context.Context, r
 	return _c
 }
 
+// Restore provides a mock function for the type SDK
+func (_mock *SDK) Restore(ctx context.Context, sequence uint64, privKey any) error {
+	ret := _mock.Called(ctx, sequence, privKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint64, any) error); ok {
+		r0 = returnFunc(ctx, sequence, privKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_Restore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Restore'
+type SDK_Restore_Call struct {
+	*mock.Call
+}
+
+// Restore is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sequence uint64
+//   - privKey any
+func (_e *SDK_Expecter) Restore(ctx interface{}, sequence interface{}, privKey interface{}) *SDK_Restore_Call {
+	return &SDK_Restore_Call{Call: _e.mock.On("Restore", ctx, sequence, privKey)}
+}
+
+func (_c *SDK_Restore_Call) Run(run func(ctx context.Context, sequence uint64, privKey any)) *SDK_Restore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint64
+		if args[1] != nil {
+			arg1 = args[1].(uint64)
+		}
+		var arg2 any
+		if args[2] != nil {
+			arg2 = args[2].(any)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_Restore_Call) Return(err error) *SDK_Restore_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_Restore_Call) RunAndReturn(run func(ctx context.Context, sequence uint64, privKey any) error) *SDK_Restore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Result provides a mock function for the type SDK
 func (_mock *SDK) Result(ctx context.Context, privKey any, resultFile *os.File) error {
 	ret := _mock.Called(ctx, privKey, resultFile)
@@ -453,3 +586,267 @@ func (_c *SDK_Result_Call) RunAndReturn(run func(ctx context.Context, privKey an
 	_c.Call.Return(run)
 	return _c
 }
+
+// ResumableAlgo provides a mock function for the type SDK
+func (_mock *SDK) ResumableAlgo(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any, uploadID string, onAck func(offset int64)) error {
+	ret := _mock.Called(ctx, algorithm, requirements, spec, privKey, uploadID, onAck)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResumableAlgo")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *os.File, *os.File, *agent.AlgorithmSpec, any, string, func(offset int64)) error); ok {
+		r0 = returnFunc(ctx, algorithm, requirements, spec, privKey, uploadID, onAck)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_ResumableAlgo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResumableAlgo'
+type SDK_ResumableAlgo_Call struct {
+	*mock.Call
+}
+
+// ResumableAlgo is a helper method to define mock.On call
+//   - ctx context.Context
+//   - algorithm *os.File
+//   - requirements *os.File
+//   - spec *agent.AlgorithmSpec
+//   - privKey any
+//   - uploadID string
+//   - onAck func(offset int64)
+func (_e *SDK_Expecter) ResumableAlgo(ctx interface{}, algorithm interface{}, requirements interface{}, spec interface{}, privKey interface{}, uploadID interface{}, onAck interface{}) *SDK_ResumableAlgo_Call {
+	return &SDK_ResumableAlgo_Call{Call: _e.mock.On("ResumableAlgo", ctx, algorithm, requirements, spec, privKey, uploadID, onAck)}
+}
+
+func (_c *SDK_ResumableAlgo_Call) Run(run func(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any, uploadID string, onAck func(offset int64))) *SDK_ResumableAlgo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *os.File
+		if args[1] != nil {
+			arg1 = args[1].(*os.File)
+		}
+		var arg2 *os.File
+		if args[2] != nil {
+			arg2 = args[2].(*os.File)
+		}
+		var arg3 *agent.AlgorithmSpec
+		if args[3] != nil {
+			arg3 = args[3].(*agent.AlgorithmSpec)
+		}
+		var arg4 any
+		if args[4] != nil {
+			arg4 = args[4].(any)
+		}
+		var arg5 string
+		if args[5] != nil {
+			arg5 = args[5].(string)
+		}
+		var arg6 func(offset int64)
+		if args[6] != nil {
+			arg6 = args[6].(func(offset int64))
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+			arg5,
+			arg6,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_ResumableAlgo_Call) Return(err error) *SDK_ResumableAlgo_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_ResumableAlgo_Call) RunAndReturn(run func(ctx context.Context, algorithm *os.File, requirements *os.File, spec *agent.AlgorithmSpec, privKey any, uploadID string, onAck func(offset int64)) error) *SDK_ResumableAlgo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Secrets provides a mock function for the type SDK
+func (_mock *SDK) Secrets(ctx context.Context, secrets []agent.Secret, signature []byte) error {
+	ret := _mock.Called(ctx, secrets, signature)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Secrets")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []agent.Secret, []byte) error); ok {
+		r0 = returnFunc(ctx, secrets, signature)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_Secrets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Secrets'
+type SDK_Secrets_Call struct {
+	*mock.Call
+}
+
+// Secrets is a helper method to define mock.On call
+//   - ctx context.Context
+//   - secrets []agent.Secret
+//   - signature []byte
+func (_e *SDK_Expecter) Secrets(ctx interface{}, secrets interface{}, signature interface{}) *SDK_Secrets_Call {
+	return &SDK_Secrets_Call{Call: _e.mock.On("Secrets", ctx, secrets, signature)}
+}
+
+func (_c *SDK_Secrets_Call) Run(run func(ctx context.Context, secrets []agent.Secret, signature []byte)) *SDK_Secrets_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []agent.Secret
+		if args[1] != nil {
+			arg1 = args[1].([]agent.Secret)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_Secrets_Call) Return(err error) *SDK_Secrets_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_Secrets_Call) RunAndReturn(run func(ctx context.Context, secrets []agent.Secret, signature []byte) error) *SDK_Secrets_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Shutdown provides a mock function for the type SDK
+func (_mock *SDK) Shutdown(ctx context.Context, privKey any) error {
+	ret := _mock.Called(ctx, privKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, any) error); ok {
+		r0 = returnFunc(ctx, privKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_Shutdown_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Shutdown'
+type SDK_Shutdown_Call struct {
+	*mock.Call
+}
+
+// Shutdown is a helper method to define mock.On call
+//   - ctx context.Context
+//   - privKey any
+func (_e *SDK_Expecter) Shutdown(ctx interface{}, privKey interface{}) *SDK_Shutdown_Call {
+	return &SDK_Shutdown_Call{Call: _e.mock.On("Shutdown", ctx, privKey)}
+}
+
+func (_c *SDK_Shutdown_Call) Run(run func(ctx context.Context, privKey any)) *SDK_Shutdown_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 any
+		if args[1] != nil {
+			arg1 = args[1].(any)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_Shutdown_Call) Return(err error) *SDK_Shutdown_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_Shutdown_Call) RunAndReturn(run func(ctx context.Context, privKey any) error) *SDK_Shutdown_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Stop provides a mock function for the type SDK
+func (_mock *SDK) Stop(ctx context.Context, privKey any) error {
+	ret := _mock.Called(ctx, privKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stop")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, any) error); ok {
+		r0 = returnFunc(ctx, privKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SDK_Stop_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stop'
+type SDK_Stop_Call struct {
+	*mock.Call
+}
+
+// Stop is a helper method to define mock.On call
+//   - ctx context.Context
+//   - privKey any
+func (_e *SDK_Expecter) Stop(ctx interface{}, privKey interface{}) *SDK_Stop_Call {
+	return &SDK_Stop_Call{Call: _e.mock.On("Stop", ctx, privKey)}
+}
+
+func (_c *SDK_Stop_Call) Run(run func(ctx context.Context, privKey any)) *SDK_Stop_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 any
+		if args[1] != nil {
+			arg1 = args[1].(any)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *SDK_Stop_Call) Return(err error) *SDK_Stop_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SDK_Stop_Call) RunAndReturn(run func(ctx context.Context, privKey any) error) *SDK_Stop_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -28,6 +28,33 @@ const (
 	stopWaitTime = 5 * time.Second
 )
 
+// healthCheckInterval is how often the health checks of the served services run.
+var healthCheckInterval = 5 * time.Second
+
+// HealthCheck reports why a service cannot serve requests, it returns nil
+// while the service is serving.
+type HealthCheck func() error
+
+// Option configures the gRPC server.
+type Option func(*Server)
+
+// WithServerOptions applies the gRPC server options after the authentication
+// interceptors, so chained interceptors only see authorized calls.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(s *Server) {
+		s.options = append(s.options, opts...)
+	}
+}
+
+// WithHealthCheck reports the status of the service over the grpc.health.v1
+// Health service as the check returns, SERVING while it returns nil and
+// NOT_SERVING otherwise.
+func WithHealthCheck(service string, check HealthCheck) Option {
+	return func(s *Server) {
+		s.healthChecks[service] = check
+	}
+}
+
 type Server struct {
 	server.BaseServer
 	mu                 sync.RWMutex
@@ -37,6 +64,8 @@ type Server struct {
 	authSvc            auth.Authenticator
 	certProvider       atls.CertificateProvider
 	attestedTLSEnabled bool
+	options            []grpc.ServerOption
+	healthChecks       map[string]HealthCheck
 	started            bool
 	stopped            bool
 }
@@ -45,9 +74,13 @@ type serviceRegister func(srv *grpc.Server)
 
 var _ server.Server = (*Server)(nil)
 
+// New returns the gRPC server. Besides the registered services, it serves the
+// grpc.health.v1 Health service, which reports the server name and the empty
+// service as SERVING until the server stops.
 func New(
 	ctx context.Context, cancel context.CancelFunc, name string, config server.ServerConfiguration,
 	registerService serviceRegister, logger *slog.Logger, authSvc auth.Authenticator, certProvider atls.CertificateProvider,
+	opts ...Option,
 ) server.Server {
 	base := config.GetBaseConfig()
 	listenFullAddress := fmt.Sprintf("%s:%s", base.Host, base.Port)
@@ -62,7 +95,7 @@ func New(
 		}
 	}
 
-	return &Server{
+	s := &Server{
 		BaseServer: server.BaseServer{
 			Ctx:     ctx,
 			Cancel:  cancel,
@@ -75,7 +108,13 @@ func New(
 		authSvc:            authSvc,
 		certProvider:       certProvider,
 		attestedTLSEnabled: attestedTLS,
+		healthChecks:       make(map[string]HealthCheck),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 func (s *Server) Start() error {
@@ -92,8 +131,11 @@ func (s *Server) Start() error {
 	s.mu.Unlock()
 
 	errCh := make(chan error)
+	limits := s.Config.GetBaseConfig().MessageLimits
 	grpcServerOptions := []grpc.ServerOption{
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.MaxRecvMsgSize(limits.Recv()),
+		grpc.MaxSendMsgSize(limits.Send()),
 	}
 
 	// Add authentication interceptors if auth service is available
@@ -103,6 +145,8 @@ func (s *Server) Start() error {
 		grpcServerOptions = append(grpcServerOptions, grpc.StreamInterceptor(stream))
 	}
 
+	grpcServerOptions = append(grpcServerOptions, s.options...)
+
 	// Configure credentials
 	creds, err := s.configureCredentials()
 	if err != nil {
@@ -124,8 +168,14 @@ func (s *Server) Start() error {
 	grpchealth.RegisterHealthServer(s.server, s.health)
 	s.registerService(s.server)
 	s.health.SetServingStatus(s.Name, grpchealth.HealthCheckResponse_SERVING)
+	failing := make(map[string]bool)
+	s.checkHealth(failing)
 	s.mu.Unlock()
 
+	if len(s.healthChecks) > 0 {
+		go s.watchHealth(failing)
+	}
+
 	// Start server
 	go func() {
 		s.mu.RLock()
@@ -146,6 +196,47 @@ func (s *Server) Start() error {
 	}
 }
 
+// watchHealth runs the health checks every healthCheckInterval until the server stops.
+func (s *Server) watchHealth(failing map[string]bool) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.Ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			s.checkHealth(failing)
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// checkHealth sets the status of every checked service and logs the services
+// that stop or resume serving, failing tracks the services that are not
+// serving. It must be called with the server mutex held.
+func (s *Server) checkHealth(failing map[string]bool) {
+	for service, check := range s.healthChecks {
+		err := check()
+		status := grpchealth.HealthCheckResponse_SERVING
+		if err != nil {
+			status = grpchealth.HealthCheckResponse_NOT_SERVING
+		}
+		s.health.SetServingStatus(service, status)
+
+		if failing[service] == (err != nil) {
+			continue
+		}
+		failing[service] = err != nil
+		if err != nil {
+			s.Logger.Warn(fmt.Sprintf("%s service %s is not serving: %s", s.Name, service, err))
+		} else {
+			s.Logger.Info(fmt.Sprintf("%s service %s is serving again", s.Name, service))
+		}
+	}
+}
+
 func (s *Server) configureCredentials() (grpc.ServerOption, error) {
 	baseConfig := s.Config.GetBaseConfig()
 
@@ -9,12 +9,14 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/ultravioletrs/cocos/pkg/attestation/vtpm"
 	"github.com/ultravioletrs/cocos/pkg/server"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -203,6 +206,70 @@ func TestServerStop(t *testing.T) {
 	assert.Contains(t, buf.String(), "TestServer gRPC service shutdown at localhost:0")
 }
 
+func TestServerHealthCheck(t *testing.T) {
+	orig := healthCheckInterval
+	t.Cleanup(func() { healthCheckInterval = orig })
+	healthCheckInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := server.AgentConfig{
+		ServerConfig: server.ServerConfig{
+			Config: server.Config{
+				Host: "localhost",
+				Port: "0",
+			},
+		},
+	}
+	buf := &ThreadSafeBuffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	var unavailable atomic.Bool
+	unavailable.Store(true)
+	check := func() error {
+		if unavailable.Load() {
+			return errors.New("qemu is missing")
+		}
+		return nil
+	}
+
+	srv := New(ctx, cancel, "TestServer", config, func(srv *grpc.Server) {}, logger, nil, nil, WithHealthCheck("test.Service", check)).(*Server)
+
+	go func() {
+		err := srv.Start()
+		assert.NoError(t, err)
+	}()
+
+	status := func(service string) grpchealth.HealthCheckResponse_ServingStatus {
+		srv.mu.RLock()
+		defer srv.mu.RUnlock()
+		if srv.health == nil {
+			return grpchealth.HealthCheckResponse_UNKNOWN
+		}
+		res, err := srv.health.Check(context.Background(), &grpchealth.HealthCheckRequest{Service: service})
+		if err != nil {
+			return grpchealth.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		return res.Status
+	}
+
+	assert.Eventually(t, func() bool {
+		return status("test.Service") == grpchealth.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, grpchealth.HealthCheckResponse_SERVING, status(""), "the server serves while a service does not")
+	assert.Contains(t, buf.String(), "TestServer service test.Service is not serving: qemu is missing")
+
+	unavailable.Store(false)
+	assert.Eventually(t, func() bool {
+		return status("test.Service") == grpchealth.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, buf.String(), "TestServer service test.Service is serving again")
+
+	assert.NoError(t, srv.Stop())
+	assert.Equal(t, grpchealth.HealthCheckResponse_NOT_SERVING, status("test.Service"))
+}
+
 func generateSelfSignedCert() ([]byte, []byte, error) {
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -6,11 +6,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
+const (
+	// DefaultMaxRecvMsgSize is the gRPC limit on received messages when none is configured.
+	DefaultMaxRecvMsgSize = 4 * 1024 * 1024
+	// DefaultMaxSendMsgSize is the gRPC limit on sent messages when none is configured.
+	DefaultMaxSendMsgSize = math.MaxInt32
+)
+
 type Server interface {
 	Start() error
 	Stop() error
@@ -27,6 +35,41 @@ type Config struct {
 	CertFile     string `env:"SERVER_CERT"        envDefault:""`
 	KeyFile      string `env:"SERVER_KEY"         envDefault:""`
 	ClientCAFile string `env:"CLIENT_CA_CERTS"    envDefault:""`
+	MessageLimits
+}
+
+// MessageLimits bounds the size in bytes of the gRPC messages a server
+// receives and sends, gRPC defaults apply to unset limits.
+type MessageLimits struct {
+	MaxRecvMsgSize int `env:"MAX_RECV_MSG_SIZE" envDefault:"0"`
+	MaxSendMsgSize int `env:"MAX_SEND_MSG_SIZE" envDefault:"0"`
+}
+
+// Recv returns the effective limit on received messages.
+func (l MessageLimits) Recv() int {
+	if l.MaxRecvMsgSize > 0 {
+		return l.MaxRecvMsgSize
+	}
+
+	return DefaultMaxRecvMsgSize
+}
+
+// Send returns the effective limit on sent messages.
+func (l MessageLimits) Send() int {
+	if l.MaxSendMsgSize > 0 {
+		return l.MaxSendMsgSize
+	}
+
+	return DefaultMaxSendMsgSize
+}
+
+// UploadLimits bounds the algorithm and dataset uploads an agent receives,
+// unset limits do not apply.
+type UploadLimits struct {
+	// MaxConcurrentUploads is the number of upload streams served at once.
+	MaxConcurrentUploads int `env:"MAX_CONCURRENT_UPLOADS" envDefault:"0"`
+	// MaxUploadRate is the number of bytes per second a connection uploads at.
+	MaxUploadRate int `env:"MAX_UPLOAD_RATE"        envDefault:"0"`
 }
 
 type ServerConfig struct {
@@ -15,6 +15,7 @@ import (
 
 	mglog "github.com/absmach/supermq/logger"
 	"github.com/caarlos0/env/v11"
+	"github.com/ultravioletrs/cocos/agent"
 	"github.com/ultravioletrs/cocos/agent/cvms"
 	cvmsgrpc "github.com/ultravioletrs/cocos/agent/cvms/api/grpc"
 	"github.com/ultravioletrs/cocos/internal"
@@ -40,7 +41,11 @@ var (
 	attestedTLSString string
 	attestedTLS       bool
 	pubKeyFile        string
+	resultKeyFile     string
+	resultCodec       string
+	ttl               string
 	clientCAFile      string
+	httpPort          string
 )
 
 type svc struct {
@@ -72,6 +77,16 @@ func (s *svc) Run(ctx context.Context, ipAddress string, sendMessage cvmsgrpc.Se
 		datasets = append(datasets, &cvms.Dataset{Hash: dataHash[:], UserKey: pubPem.Bytes})
 	}
 
+	resultConsumer := &cvms.ResultConsumer{UserKey: pubPem.Bytes}
+	if resultKeyFile != "" {
+		resultKey, err := os.ReadFile(resultKeyFile)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("failed to read result encryption key file: %s", err))
+			return
+		}
+		resultConsumer.EncryptionKey = resultKey
+	}
+
 	algoHash, err := internal.Checksum(algoPath)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("failed to calculate checksum: %s", err))
@@ -86,11 +101,15 @@ func (s *svc) Run(ctx context.Context, ipAddress string, sendMessage cvmsgrpc.Se
 				Description:     "sample descrption",
 				Datasets:        datasets,
 				Algorithm:       &cvms.Algorithm{Hash: algoHash[:], UserKey: pubPem.Bytes},
-				ResultConsumers: []*cvms.ResultConsumer{{UserKey: pubPem.Bytes}},
+				ResultConsumers: []*cvms.ResultConsumer{resultConsumer},
+				ResultCodec:     resultCodec,
+				Version:         agent.ManifestVersion,
+				Ttl:             ttl,
 				AgentConfig: &cvms.AgentConfig{
 					Port:         "7002",
 					AttestedTls:  attestedTLS,
 					ClientCaFile: clientCAFile,
+					HttpPort:     httpPort,
 				},
 			},
 		},
@@ -104,9 +123,13 @@ func main() {
 	flagSet := flag.NewFlagSet("tests/cvms/main.go", flag.ContinueOnError)
 	flagSet.StringVar(&algoPath, "algo-path", "", "Path to the algorithm")
 	flagSet.StringVar(&pubKeyFile, "public-key-path", "", "Path to the public key file")
+	flagSet.StringVar(&resultKeyFile, "result-key-path", "", "Path to the X25519 public key the result is encrypted with, the result is not encrypted if empty")
+	flagSet.StringVar(&resultCodec, "result-codec", "", "Compression codec of the result archive, deflate or zstd, deflate if empty")
+	flagSet.StringVar(&ttl, "ttl", "", "Lifetime of the computation once the agent receives it, e.g. 2h, unlimited if empty")
 	flagSet.StringVar(&attestedTLSString, "attested-tls-bool", "", "Should aTLS be used, must be 'true' or 'false'")
 	flagSet.StringVar(&dataPathString, "data-paths", "", "Paths to data sources, list of string separated with commas")
 	flagSet.StringVar(&clientCAFile, "client-ca-file", "", "Client CA root certificate file path")
+	flagSet.StringVar(&httpPort, "http-port", "", "Agent HTTP API port, HTTP API is disabled if empty")
 
 	flagSetParseError := flagSet.Parse(os.Args[1:])
 	if flagSetParseError != nil {